@@ -28,6 +28,7 @@ import (
 	"github.com/urfave/cli/v2"
 
 	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 	"github.com/livekit/protocol/logger"
 
@@ -242,6 +243,15 @@ func getConfig(c *cli.Context) (*config.Config, error) {
 	return conf, nil
 }
 
+// reloadConfig reads the config again for a running server, only the sections that support reloading are applied
+func reloadConfig(c *cli.Context) (*config.Config, error) {
+	confString, err := getConfigString(c.String("config"), c.String("config-body"))
+	if err != nil {
+		return nil, err
+	}
+	return config.NewConfig(confString, !c.Bool("disable-strict-config"), c, baseFlags)
+}
+
 func startServer(c *cli.Context) error {
 	conf, err := getConfig(c)
 	if err != nil {
@@ -272,14 +282,31 @@ func startServer(c *cli.Context) error {
 		return err
 	}
 
+	build := currentNode.Build()
+	prometheus.SetBuildLabels(build.Version, build.Canary)
+	if tm := conf.Prometheus.TrackMetrics; tm.Enabled {
+		prometheus.EnableTrackStats(prometheus.TrackStatsParams{
+			PerParticipant: tm.Level == config.TrackMetricsLevelParticipant,
+			Rooms:          tm.Rooms,
+			MaxSeries:      tm.MaxSeries,
+		})
+	}
 	if err := prometheus.Init(string(currentNode.NodeID()), currentNode.NodeType()); err != nil {
 		return err
 	}
+	stopTracing, err := telemetry.InitTracing(conf.Tracing, currentNode.NodeID(), build.Version)
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
 
-	server, err := service.InitializeServer(conf, currentNode)
+	server, err := service.InitializeServer(conf, currentNode, nil)
 	if err != nil {
 		return err
 	}
+	server.WatchConfig(c.String("config"), func() (*config.Config, error) {
+		return reloadConfig(c)
+	})
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
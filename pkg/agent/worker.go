@@ -30,6 +30,8 @@ import (
 	"github.com/livekit/protocol/utils"
 	"github.com/livekit/protocol/utils/guid"
 	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 )
 
 var (
@@ -50,6 +52,10 @@ const CurrentProtocol = 1
 const (
 	RegisterTimeout  = 10 * time.Second
 	AssignJobTimeout = 10 * time.Second
+
+	// JobTokenRefreshInterval is the interval at which running jobs are assigned again with a new token, agent
+	// tokens are valid for an hour so that jobs outliving them can still reconnect
+	JobTokenRefreshInterval = 30 * time.Minute
 )
 
 type SignalConn interface {
@@ -237,6 +243,8 @@ type Worker struct {
 
 	runningJobs  map[livekit.JobID]*livekit.Job
 	availability map[livekit.JobID]chan *livekit.AvailabilityResponse
+	// participant the worker accepted each running job with, kept to refresh job tokens
+	jobParticipants map[livekit.JobID]*livekit.AvailabilityResponse
 }
 
 func NewWorker(
@@ -248,7 +256,7 @@ func NewWorker(
 ) *Worker {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Worker{
+	w := &Worker{
 		WorkerPingHandler:  WorkerPingHandler{conn: conn},
 		WorkerRegistration: registration,
 		apiKey:             apiKey,
@@ -263,9 +271,12 @@ func NewWorker(
 		cancel: cancel,
 		closed: make(chan struct{}),
 
-		runningJobs:  make(map[livekit.JobID]*livekit.Job),
-		availability: make(map[livekit.JobID]chan *livekit.AvailabilityResponse),
+		runningJobs:     make(map[livekit.JobID]*livekit.Job),
+		availability:    make(map[livekit.JobID]chan *livekit.AvailabilityResponse),
+		jobParticipants: make(map[livekit.JobID]*livekit.AvailabilityResponse),
 	}
+	go w.refreshJobTokensWorker()
+	return w
 }
 
 func (w *Worker) sendRequest(req *livekit.ServerMessage) {
@@ -360,16 +371,7 @@ func (w *Worker) AssignJob(ctx context.Context, job *livekit.Job) (*livekit.JobS
 
 		job.State.ParticipantIdentity = res.ParticipantIdentity
 
-		token, err := pagent.BuildAgentToken(
-			w.apiKey,
-			w.apiSecret,
-			job.Room.Name,
-			res.ParticipantIdentity,
-			res.ParticipantName,
-			res.ParticipantMetadata,
-			res.ParticipantAttributes,
-			w.Permissions,
-		)
+		token, err := w.buildJobToken(job, res)
 		if err != nil {
 			w.logger.Errorw("failed to build agent token", err)
 			return nil, err
@@ -384,6 +386,7 @@ func (w *Worker) AssignJob(ctx context.Context, job *livekit.Job) (*livekit.JobS
 
 		w.mu.Lock()
 		w.runningJobs[jobID] = job
+		w.jobParticipants[jobID] = res
 		w.mu.Unlock()
 
 		// TODO sweep jobs that are never started. We can't do this until all SDKs actually update the the JOB state
@@ -398,6 +401,65 @@ func (w *Worker) AssignJob(ctx context.Context, job *livekit.Job) (*livekit.JobS
 	}
 }
 
+func (w *Worker) buildJobToken(job *livekit.Job, res *livekit.AvailabilityResponse) (string, error) {
+	return pagent.BuildAgentToken(
+		w.apiKey,
+		w.apiSecret,
+		job.Room.Name,
+		res.ParticipantIdentity,
+		res.ParticipantName,
+		res.ParticipantMetadata,
+		res.ParticipantAttributes,
+		w.Permissions,
+	)
+}
+
+func (w *Worker) refreshJobTokensWorker() {
+	ticker := time.NewTicker(JobTokenRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.RefreshJobTokens()
+		}
+	}
+}
+
+// RefreshJobTokens assigns every running job again with a new token. Workers already running a job only
+// replace its token, the agent participant reconnects with it if needed.
+func (w *Worker) RefreshJobTokens() {
+	type jobParticipant struct {
+		job *livekit.Job
+		res *livekit.AvailabilityResponse
+	}
+	w.mu.Lock()
+	jobs := make([]jobParticipant, 0, len(w.runningJobs))
+	for jobID, job := range w.runningJobs {
+		if res := w.jobParticipants[jobID]; res != nil {
+			jobs = append(jobs, jobParticipant{utils.CloneProto(job), res})
+		}
+	}
+	w.mu.Unlock()
+
+	for _, j := range jobs {
+		token, err := w.buildJobToken(j.job, j.res)
+		if err == nil {
+			_, err = w.conn.WriteServerMessage(&livekit.ServerMessage{Message: &livekit.ServerMessage_Assignment{
+				Assignment: &livekit.JobAssignment{Job: j.job, Url: nil, Token: token},
+			}})
+		}
+		prometheus.RecordAgentTokenRefresh(w.AgentName, err)
+		if err != nil {
+			w.logger.Warnw("could not refresh job token", err, "jobID", j.job.Id)
+			continue
+		}
+		w.logger.Debugw("refreshed job token", "jobID", j.job.Id)
+	}
+}
+
 func (w *Worker) TerminateJob(jobID livekit.JobID, reason rpc.JobTerminateReason) (*livekit.JobState, error) {
 	w.mu.Lock()
 	_, ok := w.runningJobs[jobID]
@@ -503,6 +565,7 @@ func (w *Worker) UpdateJobStatus(update *livekit.UpdateJobStatus) (*livekit.JobS
 	if JobStatusIsEnded(update.Status) {
 		job.State.EndedAt = now.UnixNano()
 		delete(w.runningJobs, jobID)
+		delete(w.jobParticipants, jobID)
 
 		w.logger.Infow("job ended", "jobID", update.JobId, "status", update.Status, "error", update.Error)
 	}
@@ -0,0 +1,207 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/agent"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+func TestRefreshJobTokens(t *testing.T) {
+	prometheus.Init("test", livekit.NodeType_SERVER)
+
+	t.Run("running jobs are assigned a new token before it expires", func(t *testing.T) {
+		conn := &testSignalConn{}
+		w := newTestWorker(t, "refresh", conn)
+		job := assignTestJob(t, w, conn)
+		refreshes := agentMetricValue(t, "livekit_agent_token_refreshes", "refresh")
+
+		w.RefreshJobTokens()
+
+		assignment := conn.lastAssignment()
+		require.NotNil(t, assignment)
+		require.Equal(t, job.Id, assignment.Job.Id)
+
+		v, err := auth.ParseAPIToken(assignment.Token)
+		require.NoError(t, err)
+		grants, err := v.Verify("secret")
+		require.NoError(t, err)
+		require.Equal(t, "agent-participant", grants.Identity)
+		require.Equal(t, "room", grants.Video.Room)
+		require.True(t, grants.Video.Agent)
+
+		// the next refresh must happen while the token is still valid
+		tok, err := jwt.ParseSigned(assignment.Token)
+		require.NoError(t, err)
+		claims := jwt.Claims{}
+		require.NoError(t, tok.UnsafeClaimsWithoutVerification(&claims))
+		require.True(t, claims.Expiry.Time().After(time.Now().Add(agent.JobTokenRefreshInterval)))
+
+		require.Equal(t, refreshes+1, agentMetricValue(t, "livekit_agent_token_refreshes", "refresh"))
+	})
+
+	t.Run("failed refreshes are counted", func(t *testing.T) {
+		conn := &testSignalConn{}
+		w := newTestWorker(t, "refresh-failure", conn)
+		assignTestJob(t, w, conn)
+		refreshes := agentMetricValue(t, "livekit_agent_token_refreshes", "refresh-failure")
+		failures := agentMetricValue(t, "livekit_agent_token_refresh_failures", "refresh-failure")
+
+		conn.setWriteErr(errors.New("connection closed"))
+		w.RefreshJobTokens()
+
+		require.Nil(t, conn.lastAssignment())
+		require.Equal(t, refreshes, agentMetricValue(t, "livekit_agent_token_refreshes", "refresh-failure"))
+		require.Equal(t, failures+1, agentMetricValue(t, "livekit_agent_token_refresh_failures", "refresh-failure"))
+	})
+}
+
+func newTestWorker(t *testing.T, agentName string, conn *testSignalConn) *agent.Worker {
+	w := agent.NewWorker(agent.WorkerRegistration{
+		ID:        guid.New(guid.AgentWorkerPrefix),
+		AgentName: agentName,
+		JobType:   livekit.JobType_JT_ROOM,
+		Permissions: &livekit.ParticipantPermission{
+			CanSubscribe: true,
+			CanPublish:   true,
+		},
+	}, "key", "secret", conn, logger.GetLogger())
+	t.Cleanup(w.Close)
+	return w
+}
+
+// assignTestJob assigns a job to the worker, answering its availability request, and clears the written messages
+func assignTestJob(t *testing.T, w *agent.Worker, conn *testSignalConn) *livekit.Job {
+	job := &livekit.Job{
+		Id:         guid.New(guid.AgentJobPrefix),
+		DispatchId: guid.New(guid.AgentDispatchPrefix),
+		Type:       livekit.JobType_JT_ROOM,
+		Room:       &livekit.Room{Name: "room"},
+		AgentName:  w.AgentName,
+	}
+
+	go func() {
+		for !conn.availabilityRequested(job.Id) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		_ = w.HandleAvailability(&livekit.AvailabilityResponse{
+			JobId:               job.Id,
+			Available:           true,
+			ParticipantIdentity: "agent-participant",
+		})
+	}()
+
+	_, err := w.AssignJob(context.Background(), job)
+	require.NoError(t, err)
+	require.NotNil(t, conn.lastAssignment())
+	conn.reset()
+	return job
+}
+
+func agentMetricValue(t *testing.T, name string, agentName string) float64 {
+	families, err := prom.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "agent_name" && l.GetValue() == agentName {
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+type testSignalConn struct {
+	mu       sync.Mutex
+	messages []*livekit.ServerMessage
+	writeErr error
+}
+
+func (c *testSignalConn) WriteServerMessage(msg *livekit.ServerMessage) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.writeErr != nil {
+		return 0, c.writeErr
+	}
+	c.messages = append(c.messages, msg)
+	return 0, nil
+}
+
+func (c *testSignalConn) ReadWorkerMessage() (*livekit.WorkerMessage, int, error) {
+	return nil, 0, errors.New("not supported")
+}
+
+func (c *testSignalConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func (c *testSignalConn) Close() error {
+	return nil
+}
+
+func (c *testSignalConn) setWriteErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeErr = err
+}
+
+func (c *testSignalConn) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = nil
+}
+
+func (c *testSignalConn) availabilityRequested(jobID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, msg := range c.messages {
+		if msg.GetAvailability().GetJob().GetId() == jobID {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *testSignalConn) lastAssignment() *livekit.JobAssignment {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := len(c.messages) - 1; i >= 0; i-- {
+		if a := c.messages[i].GetAssignment(); a != nil {
+			return a
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archiver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Store keeps archived objects by key
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns ErrNotFound when there is no object with the key
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// Archiver serializes the final state of closed rooms to object storage and retrieves it
+type Archiver struct {
+	store   Store
+	prefix  string
+	timeout time.Duration
+}
+
+// NewArchiver returns nil when room archival is not configured
+func NewArchiver(conf *config.Config) *Archiver {
+	if conf.RoomArchive.Bucket == "" {
+		return nil
+	}
+	return NewArchiverWithStore(NewS3Store(conf.RoomArchive), conf.RoomArchive.Prefix, conf.RoomArchive.Timeout)
+}
+
+func NewArchiverWithStore(store Store, prefix string, timeout time.Duration) *Archiver {
+	return &Archiver{
+		store:   store,
+		prefix:  prefix,
+		timeout: timeout,
+	}
+}
+
+func (a *Archiver) Archive(ctx context.Context, archive *rtc.RoomArchive) error {
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := a.withTimeout(ctx)
+	defer cancel()
+	return a.store.Put(ctx, a.key(livekit.RoomName(archive.Name), livekit.RoomID(archive.Sid)), data)
+}
+
+// GetArchivedRoom returns ErrNotFound when the room session was not archived
+func (a *Archiver) GetArchivedRoom(ctx context.Context, roomName livekit.RoomName, roomID livekit.RoomID) (*rtc.RoomArchive, error) {
+	ctx, cancel := a.withTimeout(ctx)
+	defer cancel()
+	data, err := a.store.Get(ctx, a.key(roomName, roomID))
+	if err != nil {
+		return nil, err
+	}
+	archive := &rtc.RoomArchive{}
+	if err := json.Unmarshal(data, archive); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+func (a *Archiver) key(roomName livekit.RoomName, roomID livekit.RoomID) string {
+	return a.prefix + string(roomName) + "/" + string(roomID) + ".json"
+}
+
+func (a *Archiver) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, a.timeout)
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archiver_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/archiver"
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+func TestArchiver(t *testing.T) {
+	var (
+		lock    sync.Mutex
+		objects = make(map[string][]byte)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=key/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			objects[r.URL.EscapedPath()] = body
+		case http.MethodGet:
+			body, ok := objects[r.URL.EscapedPath()]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(body)
+		}
+	}))
+	defer server.Close()
+
+	a := archiver.NewArchiver(&config.Config{RoomArchive: config.RoomArchiveConfig{
+		Bucket:         "archives",
+		Region:         "eu-west-1",
+		Endpoint:       server.URL,
+		ForcePathStyle: true,
+		AccessKey:      "key",
+		Secret:         "secret",
+		Prefix:         "livekit/",
+		Timeout:        time.Second,
+	}})
+	require.NotNil(t, a)
+
+	ctx := context.Background()
+	archive := &rtc.RoomArchive{
+		Name:         "my room",
+		Sid:          "RM_1",
+		Participants: []*rtc.ArchivedParticipant{{Identity: "pa", Sid: "PA_1", JoinedAt: 1000}},
+	}
+	require.NoError(t, a.Archive(ctx, archive))
+	require.Contains(t, objects, "/archives/livekit/my%20room/RM_1.json")
+
+	loaded, err := a.GetArchivedRoom(ctx, "my room", "RM_1")
+	require.NoError(t, err)
+	require.Equal(t, archive.Name, loaded.Name)
+	require.Equal(t, archive.Participants, loaded.Participants)
+
+	_, err = a.GetArchivedRoom(ctx, "my room", "RM_2")
+	require.ErrorIs(t, err, archiver.ErrNotFound)
+
+	require.Nil(t, archiver.NewArchiver(&config.Config{}))
+}
@@ -0,0 +1,239 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archiver
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	s3Service       = "s3"
+	s3SignAlgorithm = "AWS4-HMAC-SHA256"
+	s3SignedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	// payload hash of presigned URLs, the body is not known when signing
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// longest validity of presigned URLs allowed by signature version 4
+	MaxS3PresignExpiry = 7 * 24 * time.Hour
+
+	// limit of an error body included in errors
+	maxS3ErrorBody = 1024
+)
+
+// S3Store stores objects in a bucket of an S3 compatible API, signing requests with AWS signature version 4
+type S3Store struct {
+	conf   config.RoomArchiveConfig
+	client *http.Client
+}
+
+func NewS3Store(conf config.RoomArchiveConfig) *S3Store {
+	if conf.Region == "" {
+		conf.Region = "us-east-1"
+	}
+	return &S3Store{
+		conf:   conf,
+		client: &http.Client{},
+	}
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, data []byte) error {
+	return s.PutContent(ctx, key, "application/json", data)
+}
+
+// PutContent stores an object of the given content type
+func (s *S3Store) PutContent(ctx context.Context, key string, contentType string, data []byte) error {
+	res, err := s.do(ctx, http.MethodPut, key, contentType, data)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return s3Error(res)
+	}
+	return nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	res, err := s.do(ctx, http.MethodGet, key, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case res.StatusCode/100 != 2:
+		return nil, s3Error(res)
+	}
+	return io.ReadAll(res.Body)
+}
+
+func (s *S3Store) do(ctx context.Context, method, key, contentType string, body []byte) (*http.Response, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, body, time.Now().UTC())
+	return s.client.Do(req)
+}
+
+func (s *S3Store) objectURL(key string) (*url.URL, error) {
+	endpoint := s.conf.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", s.conf.Region)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	path := "/" + key
+	if s.conf.ForcePathStyle {
+		path = "/" + s.conf.Bucket + path
+	} else {
+		u.Host = s.conf.Bucket + "." + u.Host
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawPath = escapeS3Path(u.Path)
+	return u, nil
+}
+
+// sign adds the authorization headers of signature version 4, signing the host, date and payload hash
+func (s *S3Store) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256Hex(body)
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		s3SignedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{date, s.conf.Region, s3Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{s3SignAlgorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	signature := hex.EncodeToString(hmacSHA256(s.signingKey(date), stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SignAlgorithm, s.conf.AccessKey, scope, s3SignedHeaders, signature,
+	))
+}
+
+// PresignPut returns a URL uploading the object with a PUT request, without further credentials, until it expires
+func (s *S3Store) PresignPut(key string, expires time.Duration) (string, error) {
+	return s.presign(http.MethodPut, key, expires, time.Now().UTC())
+}
+
+// presign signs the host only in the query of the URL, as presigned URLs of signature version 4 do
+func (s *S3Store) presign(method, key string, expires time.Duration, now time.Time) (string, error) {
+	if expires <= 0 || expires > MaxS3PresignExpiry {
+		return "", fmt.Errorf("presigned url expiry %v must be positive and at most %v", expires, MaxS3PresignExpiry)
+	}
+	u, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := strings.Join([]string{date, s.conf.Region, s3Service, "aws4_request"}, "/")
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3SignAlgorithm)
+	query.Set("X-Amz-Credential", s.conf.AccessKey+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+	// encoded as expected in canonical requests, with sorted keys and spaces as %20
+	u.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		u.RawQuery,
+		"host:" + u.Host,
+		"",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+	stringToSign := strings.Join([]string{s3SignAlgorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+	u.RawQuery += "&X-Amz-Signature=" + hex.EncodeToString(hmacSHA256(s.signingKey(date), stringToSign))
+	return u.String(), nil
+}
+
+func (s *S3Store) signingKey(date string) []byte {
+	key := hmacSHA256([]byte("AWS4"+s.conf.Secret), date)
+	key = hmacSHA256(key, s.conf.Region)
+	key = hmacSHA256(key, s3Service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// escapeS3Path encodes all but the unreserved characters and slashes, as expected in canonical requests
+func escapeS3Path(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func s3Error(res *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxS3ErrorBody))
+	return fmt.Errorf("object storage request failed with status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+}
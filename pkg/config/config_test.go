@@ -16,7 +16,9 @@ package config
 
 import (
 	"flag"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/urfave/cli/v2"
@@ -86,3 +88,66 @@ func TestGeneratedFlags(t *testing.T) {
 func TestYAMLTag(t *testing.T) {
 	require.NoError(t, configtest.CheckYAMLTags(Config{}))
 }
+
+func TestConfig_Relay(t *testing.T) {
+	conf, err := NewConfig("relay:\n  enabled: true", true, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, 500, conf.Relay.OriginMaxParticipants)
+	require.Equal(t, "high", conf.Relay.Quality)
+
+	_, err = NewConfig("relay:\n  enabled: true\n  quality: off", true, nil, nil)
+	require.Error(t, err)
+}
+
+func TestConfig_Bus(t *testing.T) {
+	conf, err := NewConfig("bus:\n  kind: nats\n  nats:\n    url: nats://localhost:4222", true, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, BusKindNATS, conf.Bus.Kind)
+
+	_, err = NewConfig("bus:\n  kind: nats", true, nil, nil)
+	require.Error(t, err)
+
+	_, err = NewConfig("bus:\n  kind: kafka", true, nil, nil)
+	require.Error(t, err)
+}
+
+func TestConfig_Etcd(t *testing.T) {
+	// requires a bus between nodes
+	_, err := NewConfig("etcd:\n  endpoints: [localhost:2379]", true, nil, nil)
+	require.Error(t, err)
+
+	conf, err := NewConfig("etcd:\n  endpoints: [localhost:2379]\nredis:\n  address: localhost:6379", true, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, 10, conf.Etcd.LeaseTTL)
+
+	_, err = NewConfig("etcd:\n  endpoints: [localhost:2379]\n  lease_ttl: 1\nredis:\n  address: localhost:6379", true, nil, nil)
+	require.Error(t, err)
+}
+
+func TestConfig_RoomResume(t *testing.T) {
+	conf, err := NewConfig("room:\n  resume:\n    rules:\n      - rooms: [\"kiosk-*\"]\n        window: 2m", true, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Minute, conf.Room.Resume.WindowFor("kiosk-lobby"))
+	require.Equal(t, 5*time.Second, conf.Room.Resume.WindowFor("meeting"))
+
+	_, err = NewConfig("room:\n  resume:\n    window: 2h", true, nil, nil)
+	require.Error(t, err)
+
+	_, err = NewConfig("room:\n  resume:\n    rules:\n      - rooms: [\"kiosk-[\"]\n        window: 2m", true, nil, nil)
+	require.Error(t, err)
+}
+
+func TestConfig_RoomClasses(t *testing.T) {
+	classes := "rtc:\n  room_classes:\n    classes:\n      realtime:\n        udp_port_start: 50000\n        udp_port_end: 50009\n        dscp: 46\n      broadcast:\n        udp_port_start: %d\n        udp_port_end: %d\n    rooms:\n      - room: \"webinar-*\"\n        class: broadcast\n    default_class: realtime"
+	conf, err := NewConfig(fmt.Sprintf(classes, 50010, 50019), true, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "broadcast", conf.RTC.RoomClasses.ClassOf("webinar-1"))
+	require.Equal(t, "realtime", conf.RTC.RoomClasses.ClassOf("meeting"))
+
+	// ports of classes cannot overlap
+	_, err = NewConfig(fmt.Sprintf(classes, 50005, 50019), true, nil, nil)
+	require.Error(t, err)
+
+	_, err = NewConfig("rtc:\n  room_classes:\n    classes:\n      realtime:\n        udp_port_start: 50000\n        udp_port_end: 50009\n        dscp: 64", true, nil, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package embedded runs LiveKit server inside another Go application. The config is built in code and the
+// events of the server are delivered to in-process hooks:
+//
+//	conf, _ := embedded.NewConfig("")
+//	conf.Keys = map[string]string{"key": "secret"}
+//	s, _ := embedded.New(conf, service.ServerHooks{Webhook: onEvent})
+//	if err := s.Start(); err != nil { ... }
+//	defer s.Stop(false)
+package embedded
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const startPollInterval = 10 * time.Millisecond
+
+// NewConfig parses a YAML config, empty for the defaults. Fields can be changed before calling New.
+func NewConfig(yaml string) (*config.Config, error) {
+	return config.NewConfig(yaml, true, nil, nil)
+}
+
+// Server is a LiveKit server running in the process
+type Server struct {
+	server *service.LivekitServer
+
+	lock    sync.Mutex
+	started bool
+	done    chan struct{}
+	err     error
+}
+
+func New(conf *config.Config, hooks service.ServerHooks) (*Server, error) {
+	if err := conf.ValidateKeys(); err != nil {
+		return nil, err
+	}
+
+	currentNode, err := routing.NewLocalNode(conf)
+	if err != nil {
+		return nil, err
+	}
+	build := currentNode.Build()
+	prometheus.SetBuildLabels(build.Version, build.Canary)
+	if tm := conf.Prometheus.TrackMetrics; tm.Enabled {
+		prometheus.EnableTrackStats(prometheus.TrackStatsParams{
+			PerParticipant: tm.Level == config.TrackMetricsLevelParticipant,
+			Rooms:          tm.Rooms,
+			MaxSeries:      tm.MaxSeries,
+		})
+	}
+	if err := prometheus.Init(string(currentNode.NodeID()), currentNode.NodeType()); err != nil {
+		return nil, err
+	}
+
+	server, err := service.InitializeServer(conf, currentNode, &hooks)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		server: server,
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// Start returns once the server accepts connections
+func (s *Server) Start() error {
+	s.lock.Lock()
+	if s.started {
+		s.lock.Unlock()
+		return errors.New("already started")
+	}
+	s.started = true
+	s.lock.Unlock()
+
+	go func() {
+		err := s.server.Start()
+		s.lock.Lock()
+		s.err = err
+		s.lock.Unlock()
+		close(s.done)
+	}()
+
+	ticker := time.NewTicker(startPollInterval)
+	defer ticker.Stop()
+	for !s.server.IsRunning() {
+		select {
+		case <-s.done:
+			if err := s.Err(); err != nil {
+				return err
+			}
+			return errors.New("server stopped while starting")
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Stop waits for participants to leave unless forced, then shuts the server down
+func (s *Server) Stop(force bool) {
+	s.server.Stop(force)
+}
+
+// Done is closed once the server stopped
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the error the server stopped with, nil while running or when it was stopped
+func (s *Server) Err() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.err
+}
+
+func (s *Server) Node() *livekit.Node {
+	return s.server.Node()
+}
+
+func (s *Server) HTTPPort() int {
+	return s.server.HTTPPort()
+}
+
+// WatchConfig reloads the config read by load on SIGHUP, and when the config file at path changes
+func (s *Server) WatchConfig(path string, load func() (*config.Config, error)) {
+	s.server.WatchConfig(path, load)
+}
+
+// RoomManager gives access to the rooms hosted by the server
+func (s *Server) RoomManager() *service.RoomManager {
+	return s.server.RoomManager()
+}
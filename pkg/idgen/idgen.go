@@ -0,0 +1,154 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package idgen generates the IDs of rooms and participants following the ids section of the config.
+// Deployments with their own ID scheme register a generator from init() in a package imported in
+// cmd/server/plugins.go, and select it with ids.strategy.
+package idgen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	deterministicHashLength = 16
+	sessionSuffixLength     = 6
+)
+
+// Generator returns the ID of a new room or participant session. Participant IDs must differ between the
+// sessions of a participant, as the IDs tell a session apart from the one it replaced.
+type Generator interface {
+	RoomID(roomName livekit.RoomName) livekit.RoomID
+	ParticipantID(roomID livekit.RoomID, identity livekit.ParticipantIdentity) livekit.ParticipantID
+}
+
+type Factory func(conf config.IDConfig) (Generator, error)
+
+var (
+	factoriesLock sync.Mutex
+	factories     = map[string]Factory{
+		config.IDStrategyRandom:        newRandomGenerator,
+		config.IDStrategyDeterministic: newDeterministicGenerator,
+	}
+)
+
+// Register adds a generator selected by setting ids.strategy to its name, it panics when the name is taken
+func Register(name string, factory Factory) {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("id generator %s registered twice", name))
+	}
+	factories[name] = factory
+}
+
+// New returns the generator of the configured strategy, random IDs when none is set
+func New(conf config.IDConfig) (Generator, error) {
+	strategy := conf.Strategy
+	if strategy == "" {
+		strategy = config.IDStrategyRandom
+	}
+	factoriesLock.Lock()
+	factory, ok := factories[strategy]
+	factoriesLock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown id strategy %q", strategy)
+	}
+	return factory(conf)
+}
+
+// Default generates random IDs with the usual prefixes
+var Default Generator = &randomGenerator{
+	roomPrefix:        utils.RoomPrefix,
+	participantPrefix: utils.ParticipantPrefix,
+}
+
+type randomGenerator struct {
+	roomPrefix        string
+	participantPrefix string
+}
+
+func newRandomGenerator(conf config.IDConfig) (Generator, error) {
+	roomPrefix, participantPrefix := prefixes(conf)
+	return &randomGenerator{
+		roomPrefix:        roomPrefix,
+		participantPrefix: participantPrefix,
+	}, nil
+}
+
+func (g *randomGenerator) RoomID(livekit.RoomName) livekit.RoomID {
+	return livekit.RoomID(guid.New(g.roomPrefix))
+}
+
+func (g *randomGenerator) ParticipantID(livekit.RoomID, livekit.ParticipantIdentity) livekit.ParticipantID {
+	return livekit.ParticipantID(guid.New(g.participantPrefix))
+}
+
+type deterministicGenerator struct {
+	namespace         string
+	roomPrefix        string
+	participantPrefix string
+}
+
+func newDeterministicGenerator(conf config.IDConfig) (Generator, error) {
+	roomPrefix, participantPrefix := prefixes(conf)
+	return &deterministicGenerator{
+		namespace:         conf.Namespace,
+		roomPrefix:        roomPrefix,
+		participantPrefix: participantPrefix,
+	}, nil
+}
+
+// RoomID is the same for every session of a room
+func (g *deterministicGenerator) RoomID(roomName livekit.RoomName) livekit.RoomID {
+	return livekit.RoomID(g.roomPrefix + g.hash("room", string(roomName)))
+}
+
+// ParticipantID starts with a part derived from the room and identity, which external systems match on
+func (g *deterministicGenerator) ParticipantID(roomID livekit.RoomID, identity livekit.ParticipantIdentity) livekit.ParticipantID {
+	session := guid.New("")
+	return livekit.ParticipantID(g.participantPrefix + g.hash("participant", string(roomID), string(identity)) + "_" + session[:sessionSuffixLength])
+}
+
+func (g *deterministicGenerator) hash(kind string, values ...string) string {
+	h := sha256.New()
+	h.Write([]byte(g.namespace))
+	h.Write([]byte{0})
+	h.Write([]byte(kind))
+	for _, v := range values {
+		h.Write([]byte{0})
+		h.Write([]byte(v))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:deterministicHashLength]
+}
+
+func prefixes(conf config.IDConfig) (string, string) {
+	roomPrefix, participantPrefix := conf.RoomPrefix, conf.ParticipantPrefix
+	if roomPrefix == "" {
+		roomPrefix = utils.RoomPrefix
+	}
+	if participantPrefix == "" {
+		participantPrefix = utils.ParticipantPrefix
+	}
+	return roomPrefix + conf.Namespace, participantPrefix + conf.Namespace
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idgen_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/idgen"
+)
+
+func TestRandom(t *testing.T) {
+	g, err := idgen.New(config.IDConfig{RoomPrefix: "R_", Namespace: "eu1_"})
+	require.NoError(t, err)
+
+	roomID := g.RoomID("room")
+	require.True(t, strings.HasPrefix(string(roomID), "R_eu1_"))
+	require.NotEqual(t, roomID, g.RoomID("room"))
+	require.True(t, strings.HasPrefix(string(g.ParticipantID(roomID, "alice")), "PA_eu1_"))
+}
+
+func TestDeterministic(t *testing.T) {
+	g, err := idgen.New(config.IDConfig{Strategy: config.IDStrategyDeterministic, Namespace: "eu1_"})
+	require.NoError(t, err)
+
+	roomID := g.RoomID("room")
+	require.True(t, strings.HasPrefix(string(roomID), "RM_eu1_"))
+	require.Equal(t, roomID, g.RoomID("room"))
+	require.NotEqual(t, roomID, g.RoomID("other"))
+
+	other, err := idgen.New(config.IDConfig{Strategy: config.IDStrategyDeterministic, Namespace: "us1_"})
+	require.NoError(t, err)
+	require.NotEqual(t, strings.TrimPrefix(string(roomID), "RM_eu1_"), strings.TrimPrefix(string(other.RoomID("room")), "RM_us1_"))
+
+	// sessions share the part derived from the identity, and differ by their suffix
+	first := g.ParticipantID(roomID, "alice")
+	second := g.ParticipantID(roomID, "alice")
+	require.NotEqual(t, first, second)
+	require.Equal(t, withoutSession(first), withoutSession(second))
+	require.NotEqual(t, withoutSession(first), withoutSession(g.ParticipantID(roomID, "bob")))
+}
+
+func withoutSession(id livekit.ParticipantID) string {
+	return string(id[:strings.LastIndex(string(id), "_")])
+}
+
+type fixedGenerator struct{}
+
+func (fixedGenerator) RoomID(roomName livekit.RoomName) livekit.RoomID {
+	return livekit.RoomID("RM_" + roomName)
+}
+
+func (fixedGenerator) ParticipantID(_ livekit.RoomID, identity livekit.ParticipantIdentity) livekit.ParticipantID {
+	return livekit.ParticipantID("PA_" + identity)
+}
+
+func TestRegister(t *testing.T) {
+	_, err := idgen.New(config.IDConfig{Strategy: "fixed"})
+	require.Error(t, err)
+
+	idgen.Register("fixed", func(config.IDConfig) (idgen.Generator, error) {
+		return fixedGenerator{}, nil
+	})
+	g, err := idgen.New(config.IDConfig{Strategy: "fixed"})
+	require.NoError(t, err)
+	require.Equal(t, livekit.RoomID("RM_room"), g.RoomID("room"))
+
+	require.Panics(t, func() {
+		idgen.Register(config.IDStrategyRandom, nil)
+	})
+}
@@ -0,0 +1,396 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routing
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/atomic"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+)
+
+const (
+	// prefix/nodes/node_id => Node proto
+	EtcdNodesPrefix = "/nodes/"
+
+	// prefix/rooms/encoded room_name => node_id, under the lease of the node
+	EtcdNodeRoomPrefix = "/rooms/"
+
+	// prefix/node_builds/node_id => NodeBuild json
+	EtcdNodeBuildsPrefix = "/node_builds/"
+
+	etcdRequestTimeout = 5 * time.Second
+)
+
+var (
+	_ Router          = (*EtcdRouter)(nil)
+	_ NodeBuildLister = (*EtcdRouter)(nil)
+)
+
+// EtcdRouter is the RedisRouter of deployments keeping their routing state in etcd. Nodes are registered
+// under a lease renewed by the node, their keys and the placement of their rooms are removed by etcd once the
+// lease expires.
+type EtcdRouter struct {
+	*LocalRouter
+
+	client    *clientv3.Client
+	prefix    string
+	leaseTTL  int64
+	kps       rpc.KeepalivePubSub
+	ctx       context.Context
+	isStarted atomic.Bool
+
+	leaseLock sync.Mutex
+	leaseID   clientv3.LeaseID
+
+	cancel func()
+}
+
+func NewEtcdRouter(lr *LocalRouter, conf config.EtcdConfig, kps rpc.KeepalivePubSub) (*EtcdRouter, error) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   conf.Endpoints,
+		Username:    conf.Username,
+		Password:    conf.Password,
+		DialTimeout: conf.DialTimeout,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to etcd")
+	}
+
+	er := &EtcdRouter{
+		LocalRouter: lr,
+		client:      client,
+		prefix:      strings.TrimSuffix(conf.Prefix, "/"),
+		leaseTTL:    int64(conf.LeaseTTL),
+		kps:         kps,
+	}
+	er.ctx, er.cancel = context.WithCancel(context.Background())
+	return er, nil
+}
+
+func (r *EtcdRouter) RegisterNode() error {
+	data, err := proto.Marshal(r.currentNode.Clone())
+	if err != nil {
+		return err
+	}
+	build, err := json.Marshal(r.currentNode.Build())
+	if err != nil {
+		return err
+	}
+	leaseID, err := r.lease()
+	if err != nil {
+		return errors.Wrap(err, "could not register node")
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, etcdRequestTimeout)
+	defer cancel()
+	nodeID := string(r.currentNode.NodeID())
+	_, err = r.client.Txn(ctx).Then(
+		clientv3.OpPut(r.key(EtcdNodesPrefix, nodeID), string(data), clientv3.WithLease(leaseID)),
+		clientv3.OpPut(r.key(EtcdNodeBuildsPrefix, nodeID), string(build), clientv3.WithLease(leaseID)),
+	).Commit()
+	if err != nil {
+		return errors.Wrap(err, "could not register node")
+	}
+	return nil
+}
+
+// lease returns the lease of the node, granting a new one when it expired
+func (r *EtcdRouter) lease() (clientv3.LeaseID, error) {
+	r.leaseLock.Lock()
+	defer r.leaseLock.Unlock()
+	if r.leaseID != 0 {
+		return r.leaseID, nil
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, etcdRequestTimeout)
+	defer cancel()
+	res, err := r.client.Grant(ctx, r.leaseTTL)
+	if err != nil {
+		return 0, err
+	}
+	keepalive, err := r.client.KeepAlive(r.ctx, res.ID)
+	if err != nil {
+		return 0, err
+	}
+	r.leaseID = res.ID
+
+	go func() {
+		for range keepalive {
+		}
+		// closed when the lease could not be renewed or the router stopped
+		r.leaseLock.Lock()
+		if r.leaseID == res.ID {
+			r.leaseID = 0
+		}
+		r.leaseLock.Unlock()
+		if r.ctx.Err() == nil {
+			logger.Warnw("etcd lease expired", nil, "nodeID", r.currentNode.NodeID())
+		}
+	}()
+	return res.ID, nil
+}
+
+func (r *EtcdRouter) UnregisterNode() error {
+	// could be called after Stop(), so we'd want to use an unrelated context
+	ctx, cancel := context.WithTimeout(context.Background(), etcdRequestTimeout)
+	defer cancel()
+	if err := r.deleteNode(ctx, string(r.currentNode.NodeID())); err != nil {
+		return err
+	}
+
+	r.leaseLock.Lock()
+	leaseID := r.leaseID
+	r.leaseID = 0
+	r.leaseLock.Unlock()
+	if leaseID != 0 {
+		if _, err := r.client.Revoke(ctx, leaseID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *EtcdRouter) deleteNode(ctx context.Context, nodeID string) error {
+	_, err := r.client.Txn(ctx).Then(
+		clientv3.OpDelete(r.key(EtcdNodesPrefix, nodeID)),
+		clientv3.OpDelete(r.key(EtcdNodeBuildsPrefix, nodeID)),
+	).Commit()
+	return err
+}
+
+func (r *EtcdRouter) RemoveDeadNodes() error {
+	nodes, err := r.ListNodes()
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		if !selector.IsAvailable(n) {
+			if err := r.deleteNode(r.ctx, n.Id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetNodeForRoom finds the node where the room is hosted at
+func (r *EtcdRouter) GetNodeForRoom(ctx context.Context, roomName livekit.RoomName) (*livekit.Node, error) {
+	ctx, cancel := context.WithTimeout(ctx, etcdRequestTimeout)
+	defer cancel()
+	res, err := r.client.Get(ctx, r.key(EtcdNodeRoomPrefix, etcdRoomKey(roomName)))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get node for room")
+	}
+	if len(res.Kvs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return r.GetNode(livekit.NodeID(res.Kvs[0].Value))
+}
+
+// SetNodeForRoom places the room on the node unless another node claimed it first, in which case the room
+// stays on that node while it is available. The room key is attached to the lease of the node, so that it is
+// removed along the node.
+func (r *EtcdRouter) SetNodeForRoom(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) error {
+	ctx, cancel := context.WithTimeout(ctx, etcdRequestTimeout)
+	defer cancel()
+	nodeRes, err := r.client.Get(ctx, r.key(EtcdNodesPrefix, string(nodeID)))
+	if err != nil {
+		return errors.Wrap(err, "could not set node for room")
+	}
+	if len(nodeRes.Kvs) == 0 {
+		return ErrNotFound
+	}
+	leaseID := clientv3.LeaseID(nodeRes.Kvs[0].Lease)
+
+	key := r.key(EtcdNodeRoomPrefix, etcdRoomKey(roomName))
+	cmp := clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
+	for {
+		res, err := r.client.Txn(ctx).
+			If(cmp).
+			Then(clientv3.OpPut(key, string(nodeID), clientv3.WithLease(leaseID))).
+			Else(clientv3.OpGet(key)).
+			Commit()
+		if err != nil {
+			return errors.Wrap(err, "could not set node for room")
+		}
+		if res.Succeeded {
+			return nil
+		}
+
+		kvs := res.Responses[0].GetResponseRange().Kvs
+		if len(kvs) == 0 {
+			// cleared meanwhile
+			cmp = clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
+			continue
+		}
+		winner := livekit.NodeID(kvs[0].Value)
+		if winner == nodeID {
+			return nil
+		}
+		if node, err := r.GetNode(winner); err == nil && selector.IsAvailable(node) {
+			logger.Infow("room placed on another node", "room", roomName, "nodeID", winner, "selectedNodeID", nodeID)
+			return nil
+		}
+		// the node the room was placed on is gone, it is replaced unless the room moved again meanwhile
+		cmp = clientv3.Compare(clientv3.ModRevision(key), "=", kvs[0].ModRevision)
+	}
+}
+
+func (r *EtcdRouter) ClearRoomState(_ context.Context, roomName livekit.RoomName) error {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdRequestTimeout)
+	defer cancel()
+	if _, err := r.client.Delete(ctx, r.key(EtcdNodeRoomPrefix, etcdRoomKey(roomName))); err != nil {
+		return errors.Wrap(err, "could not clear room state")
+	}
+	return nil
+}
+
+func (r *EtcdRouter) GetNode(nodeID livekit.NodeID) (*livekit.Node, error) {
+	ctx, cancel := context.WithTimeout(r.ctx, etcdRequestTimeout)
+	defer cancel()
+	res, err := r.client.Get(ctx, r.key(EtcdNodesPrefix, string(nodeID)))
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Kvs) == 0 {
+		return nil, ErrNotFound
+	}
+	n := livekit.Node{}
+	if err = proto.Unmarshal(res.Kvs[0].Value, &n); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (r *EtcdRouter) ListNodes() ([]*livekit.Node, error) {
+	items, err := r.list(EtcdNodesPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list nodes")
+	}
+	nodes := make([]*livekit.Node, 0, len(items))
+	for _, item := range items {
+		n := livekit.Node{}
+		if err := proto.Unmarshal(item, &n); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, &n)
+	}
+	return nodes, nil
+}
+
+func (r *EtcdRouter) ListNodeBuilds() (map[livekit.NodeID]NodeBuild, error) {
+	items, err := r.list(EtcdNodeBuildsPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list node builds")
+	}
+	builds := make(map[livekit.NodeID]NodeBuild, len(items))
+	for nodeID, item := range items {
+		var build NodeBuild
+		if err := json.Unmarshal(item, &build); err != nil {
+			return nil, err
+		}
+		builds[livekit.NodeID(nodeID)] = build
+	}
+	return builds, nil
+}
+
+func (r *EtcdRouter) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (res *livekit.Room, err error) {
+	rtcNode, err := r.GetNodeForRoom(ctx, livekit.RoomName(req.Name))
+	if err != nil {
+		return
+	}
+
+	return r.CreateRoomWithNodeID(ctx, req, livekit.NodeID(rtcNode.Id))
+}
+
+// StartParticipantSignal signal connection sets up paths to the RTC node, and starts to route messages to that message queue
+func (r *EtcdRouter) StartParticipantSignal(ctx context.Context, roomName livekit.RoomName, pi ParticipantInit) (res StartParticipantSignalResults, err error) {
+	rtcNode, err := r.GetNodeForRoom(ctx, roomName)
+	if err != nil {
+		return
+	}
+
+	return r.StartParticipantSignalWithNodeID(ctx, roomName, pi, livekit.NodeID(rtcNode.Id))
+}
+
+func (r *EtcdRouter) Start() error {
+	if r.isStarted.Swap(true) {
+		return nil
+	}
+
+	workerStarted := make(chan error)
+	go runStatsWorker(r.ctx, r.currentNode, r.kps)
+	go runKeepaliveWorker(r.ctx, r.currentNode, r.kps, r.RegisterNode, workerStarted)
+
+	// wait until worker is running
+	return <-workerStarted
+}
+
+func (r *EtcdRouter) Drain() {
+	r.currentNode.SetState(livekit.NodeState_SHUTTING_DOWN)
+	if err := r.RegisterNode(); err != nil {
+		logger.Errorw("failed to mark as draining", err, "nodeID", r.currentNode.NodeID())
+	}
+}
+
+func (r *EtcdRouter) Stop() {
+	if !r.isStarted.Swap(false) {
+		return
+	}
+	logger.Debugw("stopping EtcdRouter")
+	_ = r.UnregisterNode()
+	r.cancel()
+	_ = r.client.Close()
+}
+
+func (r *EtcdRouter) key(prefix, id string) string {
+	return r.prefix + prefix + id
+}
+
+// list returns the values under a prefix by the rest of their keys
+func (r *EtcdRouter) list(prefix string) (map[string][]byte, error) {
+	ctx, cancel := context.WithTimeout(r.ctx, etcdRequestTimeout)
+	defer cancel()
+	res, err := r.client.Get(ctx, r.key(prefix, ""), clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string][]byte, len(res.Kvs))
+	for _, kv := range res.Kvs {
+		values[strings.TrimPrefix(string(kv.Key), r.key(prefix, ""))] = kv.Value
+	}
+	return values, nil
+}
+
+// room names may hold slashes, which would mix with the key hierarchy
+func etcdRoomKey(roomName livekit.RoomName) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(roomName))
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/json"
 
+	"github.com/nats-io/nats.go"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/atomic"
 	"google.golang.org/protobuf/proto"
@@ -26,6 +27,8 @@ import (
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/rpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -155,6 +158,12 @@ type Router interface {
 	Stop()
 }
 
+// NodeBuildLister is implemented by routers registering the build of each node, used to place rooms on
+// canary nodes
+type NodeBuildLister interface {
+	ListNodeBuilds() (map[livekit.NodeID]NodeBuild, error)
+}
+
 type StartParticipantSignalResults struct {
 	ConnectionID        livekit.ConnectionID
 	RequestSink         MessageSink
@@ -168,24 +177,44 @@ type MessageRouter interface {
 	CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (res *livekit.Room, err error)
 	// StartParticipantSignal participant signal connection is ready to start
 	StartParticipantSignal(ctx context.Context, roomName livekit.RoomName, pi ParticipantInit) (res StartParticipantSignalResults, err error)
+	// StartParticipantSignalWithNodeID starts the signal connection on a given node, e.g. a relay node of the room
+	StartParticipantSignalWithNodeID(ctx context.Context, roomName livekit.RoomName, pi ParticipantInit, nodeID livekit.NodeID) (res StartParticipantSignalResults, err error)
 }
 
 func CreateRouter(
+	conf *config.Config,
 	rc redis.UniversalClient,
+	nc *nats.Conn,
 	node LocalNode,
 	signalClient SignalClient,
 	roomManagerClient RoomManagerClient,
 	kps rpc.KeepalivePubSub,
-) Router {
+) (Router, error) {
 	lr := NewLocalRouter(node, signalClient, roomManagerClient)
 
+	if conf.Etcd.IsConfigured() {
+		er, err := NewEtcdRouter(lr, conf.Etcd, kps)
+		if err != nil {
+			return nil, err
+		}
+		return er, nil
+	}
+
+	if nc != nil {
+		nr, err := NewNatsRouter(lr, nc, conf.Bus.NATS, kps)
+		if err != nil {
+			return nil, err
+		}
+		return nr, nil
+	}
+
 	if rc != nil {
-		return NewRedisRouter(lr, rc, kps)
+		return NewRedisRouter(lr, rc, kps), nil
 	}
 
 	// local routing and store
 	logger.Infow("using single-node routing")
-	return lr
+	return lr, nil
 }
 
 func (pi *ParticipantInit) ToStartSession(roomName livekit.RoomName, connectionID livekit.ConnectionID) (*livekit.StartSession, error) {
@@ -91,6 +91,12 @@ func (r *LocalRouter) ListNodes() ([]*livekit.Node, error) {
 	}, nil
 }
 
+func (r *LocalRouter) ListNodeBuilds() (map[livekit.NodeID]NodeBuild, error) {
+	return map[livekit.NodeID]NodeBuild{
+		r.currentNode.NodeID(): r.currentNode.Build(),
+	}, nil
+}
+
 func (r *LocalRouter) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (res *livekit.Room, err error) {
 	return r.CreateRoomWithNodeID(ctx, req, r.currentNode.NodeID())
 }
@@ -0,0 +1,298 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routing
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/pkg/errors"
+	"go.uber.org/atomic"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+)
+
+const (
+	// bucket of node_id => Node proto
+	NatsNodesBucket = "livekit_nodes"
+
+	// bucket of encoded room_name => node_id
+	NatsNodeRoomBucket = "livekit_room_node_map"
+
+	// bucket of node_id => NodeBuild json
+	NatsNodeBuildsBucket = "livekit_node_builds"
+
+	natsSetupTimeout = 10 * time.Second
+)
+
+var (
+	_ Router          = (*NatsRouter)(nil)
+	_ NodeBuildLister = (*NatsRouter)(nil)
+)
+
+// NatsRouter is the RedisRouter of deployments running on NATS. Nodes and the rooms they host are kept in
+// JetStream key-value buckets, signaling is relayed over the psrpc NATS bus.
+type NatsRouter struct {
+	*LocalRouter
+
+	nodes     jetstream.KeyValue
+	rooms     jetstream.KeyValue
+	builds    jetstream.KeyValue
+	kps       rpc.KeepalivePubSub
+	ctx       context.Context
+	isStarted atomic.Bool
+
+	cancel func()
+}
+
+func NewNatsRouter(lr *LocalRouter, nc *nats.Conn, conf config.NATSConfig, kps rpc.KeepalivePubSub) (*NatsRouter, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create jetstream context")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), natsSetupTimeout)
+	defer cancel()
+	bucket := func(name string) (jetstream.KeyValue, error) {
+		kv, err := js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+			Bucket:   name,
+			Replicas: conf.Replicas,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not create bucket %s", name)
+		}
+		return kv, nil
+	}
+
+	nr := &NatsRouter{
+		LocalRouter: lr,
+		kps:         kps,
+	}
+	if nr.nodes, err = bucket(NatsNodesBucket); err != nil {
+		return nil, err
+	}
+	if nr.rooms, err = bucket(NatsNodeRoomBucket); err != nil {
+		return nil, err
+	}
+	if nr.builds, err = bucket(NatsNodeBuildsBucket); err != nil {
+		return nil, err
+	}
+	nr.ctx, nr.cancel = context.WithCancel(context.Background())
+	return nr, nil
+}
+
+func (r *NatsRouter) RegisterNode() error {
+	data, err := proto.Marshal(r.currentNode.Clone())
+	if err != nil {
+		return err
+	}
+	build, err := json.Marshal(r.currentNode.Build())
+	if err != nil {
+		return err
+	}
+	nodeID := string(r.currentNode.NodeID())
+	if _, err := r.nodes.Put(r.ctx, nodeID, data); err != nil {
+		return errors.Wrap(err, "could not register node")
+	}
+	if _, err := r.builds.Put(r.ctx, nodeID, build); err != nil {
+		return errors.Wrap(err, "could not register node")
+	}
+	return nil
+}
+
+func (r *NatsRouter) UnregisterNode() error {
+	// could be called after Stop(), so we'd want to use an unrelated context
+	return r.deleteNode(context.Background(), string(r.currentNode.NodeID()))
+}
+
+func (r *NatsRouter) deleteNode(ctx context.Context, nodeID string) error {
+	if err := r.nodes.Delete(ctx, nodeID); err != nil {
+		return err
+	}
+	return r.builds.Delete(ctx, nodeID)
+}
+
+func (r *NatsRouter) RemoveDeadNodes() error {
+	nodes, err := r.ListNodes()
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		if !selector.IsAvailable(n) {
+			if err := r.deleteNode(context.Background(), n.Id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetNodeForRoom finds the node where the room is hosted at
+func (r *NatsRouter) GetNodeForRoom(_ context.Context, roomName livekit.RoomName) (*livekit.Node, error) {
+	entry, err := r.rooms.Get(r.ctx, natsRoomKey(roomName))
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "could not get node for room")
+	}
+
+	return r.GetNode(livekit.NodeID(entry.Value()))
+}
+
+func (r *NatsRouter) SetNodeForRoom(_ context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) error {
+	_, err := r.rooms.Put(r.ctx, natsRoomKey(roomName), []byte(nodeID))
+	return err
+}
+
+func (r *NatsRouter) ClearRoomState(_ context.Context, roomName livekit.RoomName) error {
+	if err := r.rooms.Delete(context.Background(), natsRoomKey(roomName)); err != nil {
+		return errors.Wrap(err, "could not clear room state")
+	}
+	return nil
+}
+
+func (r *NatsRouter) GetNode(nodeID livekit.NodeID) (*livekit.Node, error) {
+	entry, err := r.nodes.Get(r.ctx, string(nodeID))
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	n := livekit.Node{}
+	if err = proto.Unmarshal(entry.Value(), &n); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (r *NatsRouter) ListNodes() ([]*livekit.Node, error) {
+	items, err := listNatsValues(r.ctx, r.nodes)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list nodes")
+	}
+	nodes := make([]*livekit.Node, 0, len(items))
+	for _, item := range items {
+		n := livekit.Node{}
+		if err := proto.Unmarshal(item, &n); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, &n)
+	}
+	return nodes, nil
+}
+
+func (r *NatsRouter) ListNodeBuilds() (map[livekit.NodeID]NodeBuild, error) {
+	items, err := listNatsValues(r.ctx, r.builds)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list node builds")
+	}
+	builds := make(map[livekit.NodeID]NodeBuild, len(items))
+	for nodeID, item := range items {
+		var build NodeBuild
+		if err := json.Unmarshal(item, &build); err != nil {
+			return nil, err
+		}
+		builds[livekit.NodeID(nodeID)] = build
+	}
+	return builds, nil
+}
+
+func (r *NatsRouter) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (res *livekit.Room, err error) {
+	rtcNode, err := r.GetNodeForRoom(ctx, livekit.RoomName(req.Name))
+	if err != nil {
+		return
+	}
+
+	return r.CreateRoomWithNodeID(ctx, req, livekit.NodeID(rtcNode.Id))
+}
+
+// StartParticipantSignal signal connection sets up paths to the RTC node, and starts to route messages to that message queue
+func (r *NatsRouter) StartParticipantSignal(ctx context.Context, roomName livekit.RoomName, pi ParticipantInit) (res StartParticipantSignalResults, err error) {
+	rtcNode, err := r.GetNodeForRoom(ctx, roomName)
+	if err != nil {
+		return
+	}
+
+	return r.StartParticipantSignalWithNodeID(ctx, roomName, pi, livekit.NodeID(rtcNode.Id))
+}
+
+func (r *NatsRouter) Start() error {
+	if r.isStarted.Swap(true) {
+		return nil
+	}
+
+	workerStarted := make(chan error)
+	go runStatsWorker(r.ctx, r.currentNode, r.kps)
+	go runKeepaliveWorker(r.ctx, r.currentNode, r.kps, r.RegisterNode, workerStarted)
+
+	// wait until worker is running
+	return <-workerStarted
+}
+
+func (r *NatsRouter) Drain() {
+	r.currentNode.SetState(livekit.NodeState_SHUTTING_DOWN)
+	if err := r.RegisterNode(); err != nil {
+		logger.Errorw("failed to mark as draining", err, "nodeID", r.currentNode.NodeID())
+	}
+}
+
+func (r *NatsRouter) Stop() {
+	if !r.isStarted.Swap(false) {
+		return
+	}
+	logger.Debugw("stopping NatsRouter")
+	_ = r.UnregisterNode()
+	r.cancel()
+}
+
+// room names may hold characters not allowed in keys
+func natsRoomKey(roomName livekit.RoomName) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(roomName))
+}
+
+func listNatsValues(ctx context.Context, kv jetstream.KeyValue) (map[string][]byte, error) {
+	lister, err := kv.ListKeys(ctx)
+	if errors.Is(err, jetstream.ErrNoKeysFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = lister.Stop()
+	}()
+
+	values := make(map[string][]byte)
+	for key := range lister.Keys() {
+		entry, err := kv.Get(ctx, key)
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			// deleted since listed
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		values[key] = entry.Value()
+	}
+	return values, nil
+}
@@ -26,8 +26,16 @@ import (
 
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	sutils "github.com/livekit/livekit-server/pkg/utils"
+	"github.com/livekit/livekit-server/version"
 )
 
+// NodeBuild is the build a node runs, registered along with the node
+type NodeBuild struct {
+	Version string `json:"version"`
+	Canary  bool   `json:"canary,omitempty"`
+}
+
 type LocalNode interface {
 	Clone() *livekit.Node
 	SetNodeID(nodeID livekit.NodeID)
@@ -35,6 +43,7 @@ type LocalNode interface {
 	NodeType() livekit.NodeType
 	NodeIP() string
 	Region() string
+	Build() NodeBuild
 	SetState(state livekit.NodeState)
 	SetStats(stats *livekit.NodeStats)
 	UpdateNodeStats() bool
@@ -42,8 +51,9 @@ type LocalNode interface {
 }
 
 type LocalNodeImpl struct {
-	lock sync.RWMutex
-	node *livekit.Node
+	lock  sync.RWMutex
+	node  *livekit.Node
+	build NodeBuild
 
 	// previous stats for computing averages
 	prevStats *livekit.NodeStats
@@ -64,16 +74,18 @@ func NewLocalNode(conf *config.Config) (*LocalNodeImpl, error) {
 				UpdatedAt: time.Now().Unix(),
 			},
 		},
+		build: NodeBuild{Version: version.Version},
 	}
 	if conf != nil {
 		l.node.Ip = conf.RTC.NodeIP
 		l.node.Region = conf.Region
+		l.build.Canary = conf.Canary.Node
 	}
 	return l, nil
 }
 
 func NewLocalNodeFromNodeProto(node *livekit.Node) (*LocalNodeImpl, error) {
-	return &LocalNodeImpl{node: utils.CloneProto(node)}, nil
+	return &LocalNodeImpl{node: utils.CloneProto(node), build: NodeBuild{Version: version.Version}}, nil
 }
 
 func (l *LocalNodeImpl) Clone() *livekit.Node {
@@ -119,6 +131,10 @@ func (l *LocalNodeImpl) Region() string {
 	return l.node.Region
 }
 
+func (l *LocalNodeImpl) Build() NodeBuild {
+	return l.build
+}
+
 func (l *LocalNodeImpl) SetState(state livekit.NodeState) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
@@ -157,5 +173,5 @@ func (l *LocalNodeImpl) SecondsSinceNodeStatsUpdate() float64 {
 	l.lock.RLock()
 	defer l.lock.RUnlock()
 
-	return time.Since(time.Unix(l.node.Stats.UpdatedAt, 0)).Seconds()
+	return sutils.ClusterNow().Sub(time.Unix(l.node.Stats.UpdatedAt, 0)).Seconds()
 }
@@ -17,6 +17,7 @@ package routing
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"runtime/pprof"
 	"time"
 
@@ -43,9 +44,15 @@ const (
 
 	// hash of room_name => node_id
 	NodeRoomKey = "room_node_map"
+
+	// hash of node_id => NodeBuild json
+	NodeBuildsKey = "node_builds"
 )
 
-var _ Router = (*RedisRouter)(nil)
+var (
+	_ Router          = (*RedisRouter)(nil)
+	_ NodeBuildLister = (*RedisRouter)(nil)
+)
 
 // RedisRouter uses Redis pub/sub to route signaling messages across different nodes
 // It relies on the RTC node to be the primary driver of the participant connection.
@@ -76,7 +83,15 @@ func (r *RedisRouter) RegisterNode() error {
 	if err != nil {
 		return err
 	}
-	if err := r.rc.HSet(r.ctx, NodesKey, string(r.currentNode.NodeID()), data).Err(); err != nil {
+	build, err := json.Marshal(r.currentNode.Build())
+	if err != nil {
+		return err
+	}
+	nodeID := string(r.currentNode.NodeID())
+	pp := r.rc.Pipeline()
+	pp.HSet(r.ctx, NodesKey, nodeID, data)
+	pp.HSet(r.ctx, NodeBuildsKey, nodeID, build)
+	if _, err := pp.Exec(r.ctx); err != nil {
 		return errors.Wrap(err, "could not register node")
 	}
 	return nil
@@ -84,7 +99,12 @@ func (r *RedisRouter) RegisterNode() error {
 
 func (r *RedisRouter) UnregisterNode() error {
 	// could be called after Stop(), so we'd want to use an unrelated context
-	return r.rc.HDel(context.Background(), NodesKey, string(r.currentNode.NodeID())).Err()
+	nodeID := string(r.currentNode.NodeID())
+	pp := r.rc.Pipeline()
+	pp.HDel(context.Background(), NodesKey, nodeID)
+	pp.HDel(context.Background(), NodeBuildsKey, nodeID)
+	_, err := pp.Exec(context.Background())
+	return err
 }
 
 func (r *RedisRouter) RemoveDeadNodes() error {
@@ -97,6 +117,9 @@ func (r *RedisRouter) RemoveDeadNodes() error {
 			if err := r.rc.HDel(context.Background(), NodesKey, n.Id).Err(); err != nil {
 				return err
 			}
+			if err := r.rc.HDel(context.Background(), NodeBuildsKey, n.Id).Err(); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -155,6 +178,23 @@ func (r *RedisRouter) ListNodes() ([]*livekit.Node, error) {
 	return nodes, nil
 }
 
+// ListNodeBuilds returns the builds registered by nodes. Nodes registered before builds were tracked are missing.
+func (r *RedisRouter) ListNodeBuilds() (map[livekit.NodeID]NodeBuild, error) {
+	items, err := r.rc.HGetAll(r.ctx, NodeBuildsKey).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list node builds")
+	}
+	builds := make(map[livekit.NodeID]NodeBuild, len(items))
+	for nodeID, item := range items {
+		var build NodeBuild
+		if err := json.Unmarshal([]byte(item), &build); err != nil {
+			return nil, err
+		}
+		builds[livekit.NodeID(nodeID)] = build
+	}
+	return builds, nil
+}
+
 func (r *RedisRouter) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (res *livekit.Room, err error) {
 	rtcNode, err := r.GetNodeForRoom(ctx, livekit.RoomName(req.Name))
 	if err != nil {
@@ -180,8 +220,8 @@ func (r *RedisRouter) Start() error {
 	}
 
 	workerStarted := make(chan error)
-	go r.statsWorker()
-	go r.keepaliveWorker(workerStarted)
+	go runStatsWorker(r.ctx, r.currentNode, r.kps)
+	go runKeepaliveWorker(r.ctx, r.currentNode, r.kps, r.RegisterNode, workerStarted)
 
 	// wait until worker is running
 	return <-workerStarted
@@ -204,15 +244,15 @@ func (r *RedisRouter) Stop() {
 }
 
 // update node stats and cleanup
-func (r *RedisRouter) statsWorker() {
+func runStatsWorker(ctx context.Context, currentNode LocalNode, kps rpc.KeepalivePubSub) {
 	goroutineDumped := false
-	for r.ctx.Err() == nil {
+	for ctx.Err() == nil {
 		// update periodically
 		select {
 		case <-time.After(statsUpdateInterval):
-			r.kps.PublishPing(r.ctx, r.currentNode.NodeID(), &rpc.KeepalivePing{Timestamp: time.Now().Unix()})
+			kps.PublishPing(ctx, currentNode.NodeID(), &rpc.KeepalivePing{Timestamp: time.Now().Unix()})
 
-			delaySeconds := r.currentNode.SecondsSinceNodeStatsUpdate()
+			delaySeconds := currentNode.SecondsSinceNodeStatsUpdate()
 			if delaySeconds > statsMaxDelaySeconds {
 				if !goroutineDumped {
 					goroutineDumped = true
@@ -225,14 +265,15 @@ func (r *RedisRouter) statsWorker() {
 			} else {
 				goroutineDumped = false
 			}
-		case <-r.ctx.Done():
+		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-func (r *RedisRouter) keepaliveWorker(startedChan chan error) {
-	pings, err := r.kps.SubscribePing(r.ctx, r.currentNode.NodeID())
+// runKeepaliveWorker registers the node again with updated stats on every ping
+func runKeepaliveWorker(ctx context.Context, currentNode LocalNode, kps rpc.KeepalivePubSub, registerNode func() error, startedChan chan error) {
+	pings, err := kps.SubscribePing(ctx, currentNode.NodeID())
 	if err != nil {
 		startedChan <- err
 		return
@@ -245,12 +286,12 @@ func (r *RedisRouter) keepaliveWorker(startedChan chan error) {
 			continue
 		}
 
-		if !r.currentNode.UpdateNodeStats() {
+		if !currentNode.UpdateNodeStats() {
 			continue
 		}
 
 		// TODO: check stats against config.Limit values
-		if err := r.RegisterNode(); err != nil {
+		if err := registerNode(); err != nil {
 			logger.Errorw("could not update node", err)
 		}
 	}
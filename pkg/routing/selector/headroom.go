@@ -0,0 +1,95 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package selector
+
+import (
+	"math/rand"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// HeadroomSelector picks a node at random, weighted by its headroom, so that rooms go to the nodes with
+// the most capacity left while joins arriving between two stats updates are spread over several nodes.
+// Nodes with less than MinHeadroom are only selected when no other node has more.
+type HeadroomSelector struct {
+	Capacity config.HeadroomConfig
+}
+
+func NewHeadroomSelector(conf *config.Config) *HeadroomSelector {
+	capacity := conf.NodeSelector.Headroom
+	if capacity.BytesPerSec == 0 && conf.Limit.BytesPerSec > 0 {
+		capacity.BytesPerSec = conf.Limit.BytesPerSec
+	}
+	return &HeadroomSelector{Capacity: capacity}
+}
+
+func (s *HeadroomSelector) SelectNode(nodes []*livekit.Node) (*livekit.Node, error) {
+	nodes = GetAvailableNodes(nodes)
+	if len(nodes) == 0 {
+		return nil, ErrNoAvailableNodes
+	}
+
+	headrooms := make([]float32, len(nodes))
+	var (
+		total float32
+		best  int
+	)
+	for i, node := range nodes {
+		headrooms[i] = GetNodeHeadroom(node, s.Capacity)
+		if headrooms[i] > headrooms[best] {
+			best = i
+		}
+		if headrooms[i] > s.Capacity.MinHeadroom {
+			total += headrooms[i]
+		}
+	}
+	if total == 0 {
+		return nodes[best], nil
+	}
+
+	pick := rand.Float32() * total
+	for i, headroom := range headrooms {
+		if headroom <= s.Capacity.MinHeadroom {
+			continue
+		}
+		if pick -= headroom; pick < 0 {
+			return nodes[i], nil
+		}
+	}
+	return nodes[best], nil
+}
+
+// GetNodeHeadroom returns the share left, between 0 and 1, of the most used resource of a node. Resources
+// without capacity are not considered.
+func GetNodeHeadroom(node *livekit.Node, capacity config.HeadroomConfig) float32 {
+	stats := node.Stats
+	if stats == nil {
+		return 1
+	}
+
+	used := stats.CpuLoad
+	if capacity.BytesPerSec > 0 {
+		used = max(used, (stats.BytesInPerSec+stats.BytesOutPerSec)/capacity.BytesPerSec)
+	}
+	if capacity.PacketsPerSec > 0 {
+		used = max(used, (stats.PacketsInPerSec+stats.PacketsOutPerSec)/capacity.PacketsPerSec)
+	}
+	if capacity.TracksPerCPU > 0 && stats.NumCpus > 0 {
+		used = max(used, float32(stats.NumTracksIn+stats.NumTracksOut)/(capacity.TracksPerCPU*float32(stats.NumCpus)))
+	}
+	return min(max(1-used, 0), 1)
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package selector_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+)
+
+func newHeadroomNode(cpuLoad float32, bytesPerSec float32, numTracks int32) *livekit.Node {
+	return &livekit.Node{
+		State: livekit.NodeState_SERVING,
+		Stats: &livekit.NodeStats{
+			UpdatedAt:      time.Now().Unix(),
+			NumCpus:        4,
+			CpuLoad:        cpuLoad,
+			NumTracksOut:   numTracks,
+			BytesOutPerSec: bytesPerSec,
+		},
+	}
+}
+
+func TestGetNodeHeadroom(t *testing.T) {
+	capacity := config.HeadroomConfig{BytesPerSec: 1000, TracksPerCPU: 100}
+
+	// most used resource wins
+	require.InDelta(t, 0.8, selector.GetNodeHeadroom(newHeadroomNode(0.2, 100, 0), capacity), 0.001)
+	require.InDelta(t, 0.3, selector.GetNodeHeadroom(newHeadroomNode(0.2, 700, 0), capacity), 0.001)
+	require.InDelta(t, 0.5, selector.GetNodeHeadroom(newHeadroomNode(0.2, 100, 200), capacity), 0.001)
+	// overloaded
+	require.Equal(t, float32(0), selector.GetNodeHeadroom(newHeadroomNode(0.2, 2000, 0), capacity))
+	// resources without capacity are not considered
+	require.InDelta(t, 0.8, selector.GetNodeHeadroom(newHeadroomNode(0.2, 2000, 0), config.HeadroomConfig{}), 0.001)
+}
+
+func TestHeadroomSelector_SelectNode(t *testing.T) {
+	sel := selector.HeadroomSelector{Capacity: config.HeadroomConfig{BytesPerSec: 1000, MinHeadroom: 0.1}}
+
+	_, err := sel.SelectNode(nil)
+	require.ErrorIs(t, err, selector.ErrNoAvailableNodes)
+
+	// a node busy with high bitrate streams is avoided even though its CPU is idle
+	busy := newHeadroomNode(0.1, 950, 2)
+	idle := newHeadroomNode(0.3, 100, 40)
+	for i := 0; i < 20; i++ {
+		node, err := sel.SelectNode([]*livekit.Node{busy, idle})
+		require.NoError(t, err)
+		require.Same(t, idle, node)
+	}
+
+	// the node with the most headroom is used when all are below the minimum
+	full := newHeadroomNode(0.1, 1000, 0)
+	node, err := sel.SelectNode([]*livekit.Node{full, busy})
+	require.NoError(t, err)
+	require.Same(t, busy, node)
+
+	// load is spread over nodes with headroom
+	other := newHeadroomNode(0.3, 100, 40)
+	selected := make(map[*livekit.Node]int)
+	for i := 0; i < 200; i++ {
+		node, err := sel.SelectNode([]*livekit.Node{idle, other})
+		require.NoError(t, err)
+		selected[node]++
+	}
+	require.Len(t, selected, 2)
+}
@@ -55,6 +55,18 @@ func CreateNodeSelector(conf *config.Config) (NodeSelector, error) {
 		}
 		s.SysloadLimit = conf.NodeSelector.SysloadLimit
 		return s, nil
+	case "latencyaware":
+		s, err := NewLatencyAwareSelector(conf.Region, conf.NodeSelector.Regions, conf.NodeSelector.SortBy)
+		if err != nil {
+			return nil, err
+		}
+		s.SysloadLimit = conf.NodeSelector.SysloadLimit
+		if conf.NodeSelector.LatencyAware.Fallback != "" {
+			s.Fallback = conf.NodeSelector.LatencyAware.Fallback
+		}
+		return s, nil
+	case "headroom":
+		return NewHeadroomSelector(conf), nil
 	case "random":
 		logger.Warnw("random node selector is deprecated, please switch to \"any\" or another selector", nil)
 		return &AnySelector{conf.NodeSelector.SortBy}, nil
@@ -0,0 +1,235 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package selector
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	FallbackRegionAware = "regionaware"
+	FallbackAny         = "any"
+
+	// round trip over fiber takes about a millisecond per 100km of distance
+	metersPerRTTMillisecond = 100_000
+
+	// measurements older than this are not used
+	nodeLatencyTTL = 5 * time.Minute
+	// weight of a new measurement in the smoothed round trip time
+	nodeLatencyAlpha = 0.3
+)
+
+// ClientLocation tells where a participant connects from, either from a hint of the client
+// or from the geolocation of its signal connection
+type ClientLocation struct {
+	Region         string
+	Lat            float64
+	Lon            float64
+	HasCoordinates bool
+}
+
+// ClientAwareSelector is implemented by selectors that place rooms according to where the participant
+// creating them connects from
+type ClientAwareSelector interface {
+	NodeSelector
+	SelectNodeForClient(nodes []*livekit.Node, client *ClientLocation) (*livekit.Node, error)
+}
+
+// NodeLatencies holds the smoothed round trip times measured from this node to the other nodes
+type NodeLatencies struct {
+	lock sync.RWMutex
+	rtts map[string]nodeLatency
+}
+
+type nodeLatency struct {
+	rtt       time.Duration
+	updatedAt time.Time
+}
+
+func NewNodeLatencies() *NodeLatencies {
+	return &NodeLatencies{
+		rtts: make(map[string]nodeLatency),
+	}
+}
+
+func (l *NodeLatencies) Update(nodeID string, rtt time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if prev, ok := l.rtts[nodeID]; ok && time.Since(prev.updatedAt) < nodeLatencyTTL {
+		rtt = time.Duration(nodeLatencyAlpha*float64(rtt) + (1-nodeLatencyAlpha)*float64(prev.rtt))
+	}
+	l.rtts[nodeID] = nodeLatency{rtt: rtt, updatedAt: time.Now()}
+}
+
+// Get returns the round trip time to a node, ok is false when it was not measured recently
+func (l *NodeLatencies) Get(nodeID string) (time.Duration, bool) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	latency, ok := l.rtts[nodeID]
+	if !ok || time.Since(latency.updatedAt) >= nodeLatencyTTL {
+		return 0, false
+	}
+	return latency.rtt, true
+}
+
+// Retain drops the measurements of nodes that are no longer listed
+func (l *NodeLatencies) Retain(nodeIDs map[string]bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for nodeID := range l.rtts {
+		if !nodeIDs[nodeID] {
+			delete(l.rtts, nodeID)
+		}
+	}
+}
+
+// LatencyAwareSelector places rooms in the region with the lowest estimated latency to the participant
+// creating them. When the location of the client is known, the latency is estimated from its distance to
+// the regions. Otherwise the client is assumed to be near the node handling its signal connection, and the
+// round trip times measured from this node to the others are used. Without either, Fallback applies.
+type LatencyAwareSelector struct {
+	SystemLoadSelector
+	CurrentRegion string
+	Fallback      string
+	Latencies     *NodeLatencies
+	regions       map[string]config.RegionConfig
+}
+
+func NewLatencyAwareSelector(currentRegion string, regions []config.RegionConfig, sortBy string) (*LatencyAwareSelector, error) {
+	if currentRegion == "" {
+		return nil, ErrCurrentRegionNotSet
+	}
+	s := &LatencyAwareSelector{
+		SystemLoadSelector: SystemLoadSelector{SortBy: sortBy},
+		CurrentRegion:      currentRegion,
+		Fallback:           FallbackRegionAware,
+		regions:            make(map[string]config.RegionConfig, len(regions)),
+	}
+	for _, region := range regions {
+		s.regions[region.Name] = region
+	}
+	if _, ok := s.regions[currentRegion]; !ok && len(regions) > 0 {
+		return nil, ErrCurrentRegionUnknownLatLon
+	}
+	return s, nil
+}
+
+func (s *LatencyAwareSelector) SelectNode(nodes []*livekit.Node) (*livekit.Node, error) {
+	return s.SelectNodeForClient(nodes, nil)
+}
+
+func (s *LatencyAwareSelector) SelectNodeForClient(nodes []*livekit.Node, client *ClientLocation) (*livekit.Node, error) {
+	nodes, err := s.filterNodes(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	byRegion := make(map[string][]*livekit.Node)
+	for _, node := range nodes {
+		byRegion[node.Region] = append(byRegion[node.Region], node)
+	}
+
+	bestRegion, found := "", false
+	bestRTT := math.MaxFloat64
+	for region, regionNodes := range byRegion {
+		rtt, ok := s.estimateRTT(region, regionNodes, client)
+		if !ok {
+			continue
+		}
+		if rtt < bestRTT || (rtt == bestRTT && region < bestRegion) {
+			bestRegion, bestRTT, found = region, rtt, true
+		}
+	}
+
+	if !found && s.Fallback != FallbackAny {
+		bestRegion, found = s.nearestRegion(byRegion)
+	}
+	if found {
+		nodes = byRegion[bestRegion]
+	}
+
+	return SelectSortedNode(nodes, s.SortBy)
+}
+
+// estimateRTT returns the round trip time expected between the client and the nodes of a region in milliseconds
+func (s *LatencyAwareSelector) estimateRTT(region string, regionNodes []*livekit.Node, client *ClientLocation) (float64, bool) {
+	if client != nil {
+		if client.Region != "" && client.Region == region {
+			return 0, true
+		}
+
+		lat, lon, ok := client.Lat, client.Lon, client.HasCoordinates
+		if !ok {
+			if rc, found := s.regions[client.Region]; found {
+				lat, lon, ok = rc.Lat, rc.Lon, true
+			}
+		}
+		if ok {
+			rc, found := s.regions[region]
+			if !found {
+				return 0, false
+			}
+			return distanceBetween(lat, lon, rc.Lat, rc.Lon) / metersPerRTTMillisecond, true
+		}
+	}
+
+	// the client is near this node
+	if region == s.CurrentRegion {
+		return 0, true
+	}
+	if s.Latencies == nil {
+		return 0, false
+	}
+	minRTT, measured := time.Duration(math.MaxInt64), false
+	for _, node := range regionNodes {
+		if rtt, ok := s.Latencies.Get(node.Id); ok && rtt < minRTT {
+			minRTT, measured = rtt, true
+		}
+	}
+	if !measured {
+		return 0, false
+	}
+	return float64(minRTT) / float64(time.Millisecond), true
+}
+
+// nearestRegion returns the region nearest to the region of this node
+func (s *LatencyAwareSelector) nearestRegion(byRegion map[string][]*livekit.Node) (string, bool) {
+	current, ok := s.regions[s.CurrentRegion]
+	if !ok {
+		return "", false
+	}
+
+	nearest, found := "", false
+	minDist := math.MaxFloat64
+	for region := range byRegion {
+		rc, ok := s.regions[region]
+		if !ok {
+			continue
+		}
+		if dist := distanceBetween(current.Lat, current.Lon, rc.Lat, rc.Lon); dist < minDist || (dist == minDist && region < nearest) {
+			nearest, minDist, found = region, dist, true
+		}
+	}
+	return nearest, found
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package selector_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+)
+
+func TestLatencyAwareRouting(t *testing.T) {
+	rc := []config.RegionConfig{
+		{Name: regionWest, Lat: 37.64046607830567, Lon: -120.88026233189062},
+		{Name: regionEast, Lat: 40.68914362140307, Lon: -74.04445748616385},
+		{Name: regionSeattle, Lat: 47.620426730945454, Lon: -122.34938468973702},
+	}
+	westNode := newTestNodeInRegion(regionWest, true)
+	eastNode := newTestNodeInRegion(regionEast, true)
+	seattleNode := newTestNodeInRegion(regionSeattle, true)
+	nodes := []*livekit.Node{westNode, eastNode, seattleNode}
+
+	newSelector := func(t *testing.T, currentRegion string) *selector.LatencyAwareSelector {
+		s, err := selector.NewLatencyAwareSelector(currentRegion, rc, sortBy)
+		require.NoError(t, err)
+		s.SysloadLimit = loadLimit
+		s.Latencies = selector.NewNodeLatencies()
+		return s
+	}
+
+	t.Run("prefers the region hinted by the client", func(t *testing.T) {
+		s := newSelector(t, regionEast)
+		node, err := s.SelectNodeForClient(nodes, &selector.ClientLocation{Region: regionSeattle})
+		require.NoError(t, err)
+		require.Equal(t, seattleNode, node)
+	})
+
+	t.Run("prefers the region nearest to the client coordinates", func(t *testing.T) {
+		s := newSelector(t, regionEast)
+		// Portland, Oregon
+		node, err := s.SelectNodeForClient(nodes, &selector.ClientLocation{Lat: 45.5152, Lon: -122.6784, HasCoordinates: true})
+		require.NoError(t, err)
+		require.Equal(t, seattleNode, node)
+	})
+
+	t.Run("uses measured latencies when the client location is unknown", func(t *testing.T) {
+		s := newSelector(t, regionEast)
+		s.Latencies.Update(westNode.Id, 20*time.Millisecond)
+		s.Latencies.Update(seattleNode.Id, 60*time.Millisecond)
+		node, err := s.SelectNode([]*livekit.Node{seattleNode, westNode})
+		require.NoError(t, err)
+		require.Equal(t, westNode, node)
+
+		// nodes of the region of this node are the nearest to the client
+		node, err = s.SelectNode(nodes)
+		require.NoError(t, err)
+		require.Equal(t, eastNode, node)
+	})
+
+	t.Run("skips overloaded nodes of the best region", func(t *testing.T) {
+		s := newSelector(t, regionEast)
+		loaded := newTestNodeInRegion(regionSeattle, false)
+		node, err := s.SelectNodeForClient([]*livekit.Node{loaded, westNode}, &selector.ClientLocation{Region: regionSeattle})
+		require.NoError(t, err)
+		require.Equal(t, westNode, node)
+	})
+
+	t.Run("falls back to the nearest region without hints or measurements", func(t *testing.T) {
+		s := newSelector(t, regionSeattle)
+		node, err := s.SelectNode([]*livekit.Node{eastNode, westNode})
+		require.NoError(t, err)
+		require.Equal(t, westNode, node)
+	})
+}
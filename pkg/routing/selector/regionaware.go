@@ -15,7 +15,9 @@
 package selector
 
 import (
+	"cmp"
 	"math"
+	"slices"
 
 	"github.com/livekit/protocol/livekit"
 
@@ -97,6 +99,42 @@ func (s *RegionAwareSelector) SelectNode(nodes []*livekit.Node) (*livekit.Node,
 	return SelectSortedNode(nodes, s.SortBy)
 }
 
+// RankRegions returns the regions with a signaling URL and nodes available to host rooms, nearest to
+// currentRegion first, so clients can fail over to the next region when theirs goes down
+func RankRegions(currentRegion string, regions []config.RegionConfig, nodes []*livekit.Node) []*livekit.RegionInfo {
+	available := make(map[string]bool)
+	for _, node := range GetAvailableNodes(nodes) {
+		available[node.Region] = true
+	}
+
+	var currentRC *config.RegionConfig
+	for i := range regions {
+		if regions[i].Name == currentRegion {
+			currentRC = &regions[i]
+			break
+		}
+	}
+
+	var ranked []*livekit.RegionInfo
+	for _, region := range regions {
+		if region.URL == "" || !available[region.Name] {
+			continue
+		}
+		info := &livekit.RegionInfo{
+			Region: region.Name,
+			Url:    region.URL,
+		}
+		if currentRC != nil {
+			info.Distance = int64(distanceBetween(currentRC.Lat, currentRC.Lon, region.Lat, region.Lon))
+		}
+		ranked = append(ranked, info)
+	}
+	slices.SortStableFunc(ranked, func(a, b *livekit.RegionInfo) int {
+		return cmp.Compare(a.Distance, b.Distance)
+	})
+	return ranked
+}
+
 // haversine(θ) function
 func hsin(theta float64) float64 {
 	return math.Pow(math.Sin(theta/2), 2)
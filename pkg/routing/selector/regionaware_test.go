@@ -147,6 +147,37 @@ func TestRegionAwareRouting(t *testing.T) {
 	})
 }
 
+func TestRankRegions(t *testing.T) {
+	rc := []config.RegionConfig{
+		{Name: regionWest, Lat: 37.64046607830567, Lon: -120.88026233189062, URL: "wss://west.example.com"},
+		{Name: regionEast, Lat: 40.68914362140307, Lon: -74.04445748616385, URL: "wss://east.example.com"},
+		{Name: regionSeattle, Lat: 47.620426730945454, Lon: -122.34938468973702, URL: "wss://seattle.example.com"},
+		{Name: "no-url", Lat: 47.6, Lon: -122.3},
+	}
+	dead := newTestNodeInRegion(regionEast, true)
+	dead.Stats.UpdatedAt = time.Now().Add(-time.Minute).Unix()
+	nodes := []*livekit.Node{
+		newTestNodeInRegion(regionWest, true),
+		newTestNodeInRegion(regionSeattle, true),
+		newTestNodeInRegion("no-url", true),
+		dead,
+	}
+
+	ranked := selector.RankRegions(regionWest, rc, nodes)
+	require.Len(t, ranked, 2)
+	require.Equal(t, regionWest, ranked[0].Region)
+	require.Equal(t, int64(0), ranked[0].Distance)
+	require.Equal(t, regionSeattle, ranked[1].Region)
+	require.Equal(t, "wss://seattle.example.com", ranked[1].Url)
+	require.Greater(t, ranked[1].Distance, int64(0))
+
+	// the east region is ranked once it has live nodes, after the nearer regions
+	nodes = append(nodes, newTestNodeInRegion(regionEast, true))
+	ranked = selector.RankRegions(regionWest, rc, nodes)
+	require.Len(t, ranked, 3)
+	require.Equal(t, regionEast, ranked[2].Region)
+}
+
 func newTestNodeInRegion(region string, available bool) *livekit.Node {
 	load := float32(0.4)
 	if !available {
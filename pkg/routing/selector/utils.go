@@ -16,13 +16,13 @@ package selector
 
 import (
 	"sort"
-	"time"
 
 	"github.com/thoas/go-funk"
 
 	"github.com/livekit/protocol/livekit"
 
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/utils"
 )
 
 const AvailableSeconds = 5
@@ -34,7 +34,8 @@ func IsAvailable(node *livekit.Node) bool {
 		return true
 	}
 
-	delta := time.Now().Unix() - node.Stats.UpdatedAt
+	// nodes timestamp their stats with the clock of the cluster
+	delta := utils.ClusterNow().Unix() - node.Stats.UpdatedAt
 	return int(delta) < AvailableSeconds
 }
 
@@ -35,6 +35,7 @@ import (
 
 var ErrSignalWriteFailed = errors.New("signal write failed")
 var ErrSignalMessageDropped = errors.New("signal message dropped")
+var ErrSignalSlowConsumer = errors.New("signal consumer too slow")
 
 //counterfeiter:generate . SignalClient
 type SignalClient interface {
@@ -310,8 +311,7 @@ func (s *signalMessageSink[SendType, RecvType]) write() {
 				s.Logger.Warnw("could not send signal message", err)
 
 				s.mu.Lock()
-				s.seq += uint64(len(s.queue))
-				s.queue = nil
+				s.dequeueLocked(len(s.queue))
 				break
 			}
 
@@ -326,8 +326,7 @@ func (s *signalMessageSink[SendType, RecvType]) write() {
 			interval = s.Config.MinRetryInterval
 			deadline = time.Now().Add(s.Config.RetryTimeout)
 
-			s.seq += uint64(n)
-			s.queue = s.queue[n:]
+			s.dequeueLocked(n)
 
 			if close {
 				break
@@ -335,6 +334,10 @@ func (s *signalMessageSink[SendType, RecvType]) write() {
 		}
 	}
 
+	// messages left once the stream closed are never sent
+	if s.IsClosed() && len(s.queue) != 0 {
+		s.dequeueLocked(len(s.queue))
+	}
 	s.writing = false
 	if s.draining {
 		s.Stream.Close(nil)
@@ -355,7 +358,16 @@ func (s *signalMessageSink[SendType, RecvType]) WriteMessage(msg proto.Message)
 		return psrpc.ErrStreamClosed
 	}
 
+	// a consumer which can't keep up would otherwise hold on to an ever growing queue
+	if s.Config.MaxQueueSize > 0 && len(s.queue) >= s.Config.MaxQueueSize {
+		s.Logger.Warnw("closing signal connection of slow consumer", ErrSignalSlowConsumer, "queued", len(s.queue))
+		prometheus.RecordSignalSlowConsumer(prometheus.SignalSlowConsumerQueue)
+		s.Stream.Close(ErrSignalSlowConsumer)
+		return ErrSignalSlowConsumer
+	}
+
 	s.queue = append(s.queue, msg)
+	prometheus.AddSignalWriteQueueDepth(1)
 	if !s.writing {
 		s.writing = true
 		go s.write()
@@ -363,6 +375,16 @@ func (s *signalMessageSink[SendType, RecvType]) WriteMessage(msg proto.Message)
 	return nil
 }
 
+func (s *signalMessageSink[SendType, RecvType]) dequeueLocked(n int) {
+	s.seq += uint64(n)
+	if n == len(s.queue) {
+		s.queue = nil
+	} else {
+		s.queue = s.queue[n:]
+	}
+	prometheus.AddSignalWriteQueueDepth(-n)
+}
+
 func (s *signalMessageSink[SendType, RecvType]) ConnectionID() livekit.ConnectionID {
 	return s.SignalSinkParams.ConnectionID
 }
@@ -0,0 +1,96 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// AdaptiveStreamHintsTopic is the topic subscribers report how they render video tracks on, the data packets
+// are consumed by the server and not forwarded to the room
+const AdaptiveStreamHintsTopic = "lk.adaptive_stream_hints"
+
+const (
+	maxAdaptiveStreamHints = 100
+	maxPixelDensity        = 3
+)
+
+// AdaptiveStreamHints is the payload of the data packets sent on AdaptiveStreamHintsTopic
+type AdaptiveStreamHints struct {
+	Hints []*AdaptiveStreamTrackHint `json:"hints"`
+}
+
+type AdaptiveStreamTrackHint struct {
+	TrackSid string `json:"track_sid"`
+	types.AdaptiveStreamHint
+}
+
+func (r *Room) handleAdaptiveStreamHints(source types.LocalParticipant, payload []byte) {
+	var report AdaptiveStreamHints
+	err := json.Unmarshal(payload, &report)
+	if err == nil && len(report.Hints) > maxAdaptiveStreamHints {
+		err = errors.New("too many hints")
+	}
+	if err != nil {
+		source.GetLogger().Infow("ignoring invalid adaptive stream hints", "error", err)
+		return
+	}
+
+	for _, hint := range report.Hints {
+		if hint == nil || hint.TrackSid == "" {
+			continue
+		}
+		h := hint.AdaptiveStreamHint
+		source.UpdateAdaptiveStreamHint(livekit.TrackID(hint.TrackSid), &h)
+	}
+}
+
+// applyAdaptiveStreamHint returns the settings of a subscription once the rendering hint of the subscriber is
+// applied to them. Tracks which are off screen are disabled, the size they are rendered at replaces the
+// dimensions of the settings. Tracks disabled by the settings stay disabled.
+func applyAdaptiveStreamHint(settings *livekit.UpdateTrackSettings, hint *types.AdaptiveStreamHint) *livekit.UpdateTrackSettings {
+	if hint == nil {
+		return settings
+	}
+
+	var hinted *livekit.UpdateTrackSettings
+	if settings != nil {
+		hinted = utils.CloneProto(settings)
+	} else {
+		hinted = &livekit.UpdateTrackSettings{Quality: livekit.VideoQuality_HIGH}
+	}
+	if !hint.Visible {
+		hinted.Disabled = true
+		return hinted
+	}
+	if hint.Width > 0 && hint.Height > 0 {
+		density := float64(hint.PixelDensity)
+		if density < 1 {
+			density = 1
+		} else if density > maxPixelDensity {
+			density = maxPixelDensity
+		}
+		hinted.Width = uint32(math.Ceil(float64(hint.Width) * density))
+		hinted.Height = uint32(math.Ceil(float64(hint.Height) * density))
+	}
+	return hinted
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+func TestApplyAdaptiveStreamHint(t *testing.T) {
+	settings := &livekit.UpdateTrackSettings{Quality: livekit.VideoQuality_MEDIUM, Width: 1280, Height: 720}
+	require.Equal(t, settings, applyAdaptiveStreamHint(settings, nil))
+
+	t.Run("off screen", func(t *testing.T) {
+		hinted := applyAdaptiveStreamHint(settings, &types.AdaptiveStreamHint{Visible: false})
+		require.True(t, hinted.Disabled)
+		require.False(t, settings.Disabled)
+	})
+
+	t.Run("rendered size", func(t *testing.T) {
+		hinted := applyAdaptiveStreamHint(settings, &types.AdaptiveStreamHint{Visible: true, Width: 320, Height: 180, PixelDensity: 2})
+		require.False(t, hinted.Disabled)
+		require.Equal(t, uint32(640), hinted.Width)
+		require.Equal(t, uint32(360), hinted.Height)
+		require.Equal(t, livekit.VideoQuality_MEDIUM, hinted.Quality)
+
+		// pixel density is capped
+		hinted = applyAdaptiveStreamHint(nil, &types.AdaptiveStreamHint{Visible: true, Width: 100, Height: 100, PixelDensity: 10})
+		require.Equal(t, uint32(300), hinted.Width)
+	})
+
+	t.Run("disabled by settings", func(t *testing.T) {
+		hinted := applyAdaptiveStreamHint(&livekit.UpdateTrackSettings{Disabled: true}, &types.AdaptiveStreamHint{Visible: true})
+		require.True(t, hinted.Disabled)
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// agentParticipantPermission returns the permission with the overrides of the agent config applied
+func agentParticipantPermission(conf config.AgentParticipantConfig, permission *livekit.ParticipantPermission) *livekit.ParticipantPermission {
+	permission = proto.Clone(permission).(*livekit.ParticipantPermission)
+	if conf.CanSubscribe != nil {
+		permission.CanSubscribe = *conf.CanSubscribe
+	}
+	if conf.CanPublish != nil {
+		permission.CanPublish = *conf.CanPublish
+	}
+	if conf.CanPublishData != nil {
+		permission.CanPublishData = *conf.CanPublishData
+	}
+	if conf.CanUpdateMetadata != nil {
+		permission.CanUpdateMetadata = *conf.CanUpdateMetadata
+	}
+	if conf.CanPublishSources != nil {
+		permission.CanPublishSources = nil
+		for _, name := range conf.CanPublishSources {
+			if source, ok := livekit.TrackSource_value[strings.ToUpper(name)]; ok {
+				permission.CanPublishSources = append(permission.CanPublishSources, livekit.TrackSource(source))
+			}
+		}
+	}
+	return permission
+}
+
+// agentParticipantConfig returns the overrides for the participant of an agent job, the job is known once
+// a worker accepted it. Assumes room lock is held.
+func (r *Room) agentParticipantConfig(identity livekit.ParticipantIdentity) (config.AgentParticipantConfig, bool) {
+	job := r.agentParticpants[identity]
+	if job == nil {
+		return config.AgentParticipantConfig{}, false
+	}
+	conf, ok := r.agentParticipantConfigs[job.AgentName]
+	return conf, ok
+}
+
+// applyAgentParticipantConfig applies the overrides configured for the agent of a joining participant, after
+// the ones of its role. Returns the options the participant joins with. Assumes room lock is held.
+func (r *Room) applyAgentParticipantConfig(p types.LocalParticipant, opts *ParticipantOptions) *ParticipantOptions {
+	conf, ok := r.agentParticipantConfig(p.Identity())
+	if !ok {
+		return opts
+	}
+
+	if conf.AutoSubscribe != nil {
+		opts = &ParticipantOptions{AutoSubscribe: *conf.AutoSubscribe}
+	}
+	applyAgentParticipantOverrides(p, conf)
+	return opts
+}
+
+// updateAgentParticipant applies the overrides to an agent participant that joined before its job was reported
+func (r *Room) updateAgentParticipant(p types.LocalParticipant) {
+	r.lock.Lock()
+	conf, ok := r.agentParticipantConfig(p.Identity())
+	if ok && conf.AutoSubscribe != nil && r.participants[p.Identity()] == p {
+		r.participantOpts[p.Identity()] = &ParticipantOptions{AutoSubscribe: *conf.AutoSubscribe}
+	}
+	r.lock.Unlock()
+
+	if ok {
+		applyAgentParticipantOverrides(p, conf)
+	}
+}
+
+func applyAgentParticipantOverrides(p types.LocalParticipant, conf config.AgentParticipantConfig) {
+	permission := p.ToProto().Permission
+	if permission == nil {
+		permission = p.ClaimGrants().Video.ToPermission()
+	}
+	p.SetPermission(agentParticipantPermission(conf, permission))
+	if len(conf.Attributes) != 0 {
+		p.SetAttributes(conf.Attributes)
+	}
+	p.GetLogger().Infow("applied agent participant overrides")
+}
@@ -0,0 +1,234 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// AudioFeedbackTopic is the topic of the data messages sent to the participants of a feedback loop
+	AudioFeedbackTopic = "lk.audio_feedback"
+
+	// longest delay between the audio of a participant and the echo of it published by another one
+	audioFeedbackMaxDelay = 1200 * time.Millisecond
+	// share of the window both participants need to be active in
+	audioFeedbackMinActive = 0.5
+)
+
+// AudioFeedback is the payload of the messages sent on AudioFeedbackTopic
+type AudioFeedback struct {
+	// participant whose audio is picked up by the other one
+	Source livekit.ParticipantIdentity `json:"source"`
+	// participant publishing the audio of the source again
+	Echo livekit.ParticipantIdentity `json:"echo"`
+	// the microphone of the echo participant was muted
+	Muted bool `json:"muted"`
+}
+
+type audioFeedbackLoop struct {
+	source      livekit.ParticipantIdentity
+	echo        livekit.ParticipantIdentity
+	correlation float64
+}
+
+// audioFeedbackDetector compares the audio levels of participants over a window. Two participants whose
+// levels follow each other closely, possibly delayed, are taken to be in a feedback loop.
+type audioFeedbackDetector struct {
+	samples     int
+	maxLag      int
+	correlation float64
+	levels      map[livekit.ParticipantIdentity][]float64
+}
+
+func newAudioFeedbackDetector(conf config.AudioFeedbackConfig, interval time.Duration) *audioFeedbackDetector {
+	return &audioFeedbackDetector{
+		samples:     max(int(conf.Window/interval), 2),
+		maxLag:      int(audioFeedbackMaxDelay / interval),
+		correlation: conf.Correlation,
+		levels:      make(map[livekit.ParticipantIdentity][]float64),
+	}
+}
+
+// observe adds a sample of the audio levels of the participants, 0 for inactive ones, and returns the loops
+// detected. Participants of a detected loop start over with a new window.
+func (d *audioFeedbackDetector) observe(levels map[livekit.ParticipantIdentity]float64) []audioFeedbackLoop {
+	for identity := range d.levels {
+		if _, ok := levels[identity]; !ok {
+			delete(d.levels, identity)
+		}
+	}
+	for identity, level := range levels {
+		history := append(d.levels[identity], level)
+		if len(history) > d.samples+d.maxLag {
+			history = history[len(history)-d.samples-d.maxLag:]
+		}
+		d.levels[identity] = history
+	}
+
+	var loops []audioFeedbackLoop
+	var candidates []livekit.ParticipantIdentity
+	for identity, history := range d.levels {
+		if len(history) == d.samples+d.maxLag && activeShare(history) >= audioFeedbackMinActive {
+			candidates = append(candidates, identity)
+		}
+	}
+	for i := 0; i < len(candidates); i++ {
+		for j := i + 1; j < len(candidates); j++ {
+			a, b := candidates[i], candidates[j]
+			if d.levels[a] == nil || d.levels[b] == nil {
+				// already part of a loop
+				continue
+			}
+			if loop, ok := d.detect(a, b); ok {
+				loops = append(loops, loop)
+				delete(d.levels, a)
+				delete(d.levels, b)
+			}
+		}
+	}
+	return loops
+}
+
+func (d *audioFeedbackDetector) detect(a, b livekit.ParticipantIdentity) (audioFeedbackLoop, bool) {
+	la, lb := d.levels[a], d.levels[b]
+	best := audioFeedbackLoop{correlation: -1}
+	bestLag := 0
+	for lag := 0; lag <= d.maxLag; lag++ {
+		// b following a
+		if c := levelCorrelation(la[d.maxLag-lag:len(la)-lag], lb[d.maxLag:]); c > best.correlation {
+			best, bestLag = audioFeedbackLoop{source: a, echo: b, correlation: c}, lag
+		}
+		// a following b
+		if c := levelCorrelation(lb[d.maxLag-lag:len(lb)-lag], la[d.maxLag:]); c > best.correlation {
+			best, bestLag = audioFeedbackLoop{source: b, echo: a, correlation: c}, lag
+		}
+	}
+	if bestLag == 0 && meanLevel(la) < meanLevel(lb) {
+		// without a delay, the quieter participant is taken to pick up the louder one
+		best.source, best.echo = b, a
+	}
+	return best, best.correlation >= d.correlation
+}
+
+// levelCorrelation returns the Pearson correlation of two series of the same length,
+// 0 when one of them is constant
+func levelCorrelation(x, y []float64) float64 {
+	mx, my := meanLevel(x), meanLevel(y)
+	var sxy, sxx, syy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return 0
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+func meanLevel(x []float64) float64 {
+	var sum float64
+	for _, v := range x {
+		sum += v
+	}
+	return sum / float64(len(x))
+}
+
+func activeShare(levels []float64) float64 {
+	active := 0
+	for _, level := range levels {
+		if level > 0 {
+			active++
+		}
+	}
+	return float64(active) / float64(len(levels))
+}
+
+// ------------------------------------------------
+
+func (r *Room) OnAudioFeedback(f func(source, echo types.LocalParticipant, muted bool)) {
+	r.onAudioFeedback = f
+}
+
+func (r *Room) detectAudioFeedback() {
+	participants := r.GetParticipants()
+	levels := make(map[livekit.ParticipantIdentity]float64, len(participants))
+	for _, p := range participants {
+		if !p.CanPublishSource(livekit.TrackSource_MICROPHONE) {
+			continue
+		}
+		level, active := p.GetAudioLevel()
+		if !active {
+			level = 0
+		}
+		levels[p.Identity()] = level
+	}
+
+	for _, loop := range r.audioFeedback.observe(levels) {
+		source, echo := r.GetParticipant(loop.source), r.GetParticipant(loop.echo)
+		if source == nil || echo == nil {
+			continue
+		}
+		r.handleAudioFeedback(source, echo, loop.correlation)
+	}
+}
+
+func (r *Room) handleAudioFeedback(source, echo types.LocalParticipant, correlation float64) {
+	muted := false
+	if r.audioFeedbackConfig.Action == config.AudioFeedbackActionMute {
+		for _, track := range echo.GetPublishedTracks() {
+			if track.Source() == livekit.TrackSource_MICROPHONE && !track.IsMuted() {
+				echo.SetTrackMuted(track.ID(), true, true)
+				muted = true
+			}
+		}
+	}
+	r.Logger.Infow(
+		"audio feedback loop detected",
+		"source", source.Identity(),
+		"echo", echo.Identity(),
+		"correlation", correlation,
+		"muted", muted,
+	)
+
+	payload, err := json.Marshal(AudioFeedback{Source: source.Identity(), Echo: echo.Identity(), Muted: muted})
+	if err != nil {
+		return
+	}
+	topic := AudioFeedbackTopic
+	BroadcastDataPacketForRoom(r, nil, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+		Kind:                  livekit.DataPacket_RELIABLE,
+		DestinationIdentities: []string{string(source.Identity()), string(echo.Identity())},
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload: payload,
+				Topic:   &topic,
+			},
+		},
+	}, r.Logger)
+
+	if r.onAudioFeedback != nil {
+		r.onAudioFeedback(source, echo, muted)
+	}
+}
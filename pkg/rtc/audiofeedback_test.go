@@ -0,0 +1,56 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestAudioFeedbackDetector(t *testing.T) {
+	d := newAudioFeedbackDetector(config.AudioFeedbackConfig{
+		Window:      4 * time.Second,
+		Correlation: 0.9,
+	}, 400*time.Millisecond)
+
+	speech := []float64{0.2, 0.8, 0.5, 0.9, 0.1, 0.7, 0.3, 0.6, 0.9, 0.2, 0.4, 0.8, 0.5, 0.3, 0.9}
+	var loops []audioFeedbackLoop
+	for i := range speech {
+		echo := 0.0
+		if i > 0 {
+			// picked up a sample later, quieter
+			echo = speech[i-1] * 0.5
+		}
+		loops = append(loops, d.observe(map[livekit.ParticipantIdentity]float64{
+			"speaker":  speech[i],
+			"listener": echo,
+			// active, unrelated to the speaker
+			"other": float64(i%2) + 0.1,
+		})...)
+	}
+	require.Len(t, loops, 1)
+	require.Equal(t, livekit.ParticipantIdentity("speaker"), loops[0].source)
+	require.Equal(t, livekit.ParticipantIdentity("listener"), loops[0].echo)
+
+	// participants of the loop start over with a new window
+	require.Empty(t, d.observe(map[livekit.ParticipantIdentity]float64{"speaker": 0.5, "listener": 0.5}))
+	require.NotContains(t, d.levels, livekit.ParticipantIdentity("other"))
+}
@@ -0,0 +1,267 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pion/rtcp"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const (
+	// BitrateOvershootTopic is the topic of the server generated data message sent to a publisher
+	// when a video layer it publishes is measured far above the bitrate declared for it
+	BitrateOvershootTopic = "lk.bitrate_overshoot"
+
+	bitrateOvershootCheckInterval = 5 * time.Second
+	// REMB sent to lift the clamp once the overshooting tracks are unpublished
+	bitrateOvershootReleaseBitrate = 1_000_000_000
+	// allowance of a clamped publisher for each of its audio tracks, audio does not declare bitrates
+	bitrateOvershootAudioAllowance = 128_000
+)
+
+// BitrateOvershoot is the JSON payload of a BitrateOvershootTopic message.
+// ClampBitrate is the send rate the publisher is limited to, 0 when it is not clamped.
+type BitrateOvershoot struct {
+	TrackSid        string `json:"track_sid"`
+	Quality         string `json:"quality"`
+	DeclaredBitrate uint32 `json:"declared_bitrate"`
+	MeasuredBitrate int64  `json:"measured_bitrate"`
+	ClampBitrate    int64  `json:"clamp_bitrate,omitempty"`
+}
+
+// bitrateOvershootWorker periodically compares the bitrates measured for the video layers published by
+// the participant with the bitrates declared for them. A layer that keeps overshooting gets its publisher
+// warned once per track, and with clamping, the publisher is limited with REMB to its declared bitrates
+// for as long as the track is published.
+func (p *ParticipantImpl) bitrateOvershootWorker() {
+	conf := p.params.VideoConfig.BitrateOvershoot
+	ticker := time.NewTicker(bitrateOvershootCheckInterval)
+	defer ticker.Stop()
+
+	overshootingSince := make(map[livekit.TrackID]time.Time)
+	warned := make(map[livekit.TrackID]bool)
+	clamped := false
+	for {
+		select {
+		case <-p.disconnected:
+			return
+		case <-ticker.C:
+		}
+
+		tracks := p.GetPublishedTracks()
+		published := make(map[livekit.TrackID]bool, len(tracks))
+		for _, track := range tracks {
+			published[track.ID()] = true
+			if track.Kind() != livekit.TrackType_VIDEO || warned[track.ID()] {
+				continue
+			}
+
+			ti := track.ToProto()
+			overshoot := getBitrateOvershoot(ti, track.Receivers(), conf.Factor)
+			if overshoot == nil {
+				delete(overshootingSince, track.ID())
+				continue
+			}
+			since, ok := overshootingSince[track.ID()]
+			if !ok {
+				overshootingSince[track.ID()] = time.Now()
+				continue
+			}
+			if time.Since(since) < conf.Duration {
+				continue
+			}
+
+			warned[track.ID()] = true
+			if conf.Clamp {
+				overshoot.ClampBitrate = getPublisherClampBitrate(tracks)
+			}
+			p.notifyBitrateOvershoot(ti, overshoot)
+		}
+		for trackID := range overshootingSince {
+			if !published[trackID] {
+				delete(overshootingSince, trackID)
+			}
+		}
+		for trackID := range warned {
+			if !published[trackID] {
+				delete(warned, trackID)
+			}
+		}
+
+		if !conf.Clamp {
+			continue
+		}
+		// REMB is sent on every check as receivers may expire it. A publisher with a bitrate cap gets
+		// the lower of the cap and the clamp from the cap worker.
+		if len(warned) != 0 {
+			if bitrate := getPublisherClampBitrate(tracks); bitrate > 0 {
+				p.bitrateOvershootClamp.Store(bitrate)
+				if p.PublishBitrateCap() == 0 {
+					p.postRtcp([]rtcp.Packet{getClampREMB(tracks, bitrate)})
+				}
+				clamped = true
+			}
+		} else if clamped {
+			p.bitrateOvershootClamp.Store(0)
+			if p.PublishBitrateCap() == 0 {
+				p.postRtcp([]rtcp.Packet{getClampREMB(tracks, bitrateOvershootReleaseBitrate)})
+			}
+			clamped = false
+		}
+	}
+}
+
+func (p *ParticipantImpl) notifyBitrateOvershoot(ti *livekit.TrackInfo, overshoot *BitrateOvershoot) {
+	p.pubLogger.Warnw(
+		"publisher exceeds declared bitrate", nil,
+		"trackID", ti.Sid,
+		"quality", overshoot.Quality,
+		"declaredBitrate", overshoot.DeclaredBitrate,
+		"measuredBitrate", overshoot.MeasuredBitrate,
+		"clampBitrate", overshoot.ClampBitrate,
+	)
+	prometheus.RecordTrackBitrateOvershoot(overshoot.Quality)
+
+	if dpData, err := bitrateOvershootPacket(overshoot); err == nil {
+		if err = p.SendDataPacket(livekit.DataPacket_RELIABLE, dpData); err != nil {
+			p.pubLogger.Debugw("could not send bitrate overshoot warning", "error", err)
+		}
+	}
+	if onBitrateOvershoot := p.params.OnBitrateOvershoot; onBitrateOvershoot != nil {
+		onBitrateOvershoot(p, ti, overshoot)
+	}
+}
+
+// bitrateOvershootPacket builds the data packet of an overshoot warning, it is originated by the server
+func bitrateOvershootPacket(overshoot *BitrateOvershoot) ([]byte, error) {
+	payload, err := json.Marshal(overshoot)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&livekit.DataPacket{
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload: payload,
+				Topic:   proto.String(BitrateOvershootTopic),
+			},
+		},
+	})
+}
+
+// getBitrateOvershoot returns the layer of a track with the highest ratio of measured to declared bitrate
+// when that ratio exceeds factor, layers without a declared bitrate are skipped
+func getBitrateOvershoot(ti *livekit.TrackInfo, receivers []sfu.TrackReceiver, factor float64) *BitrateOvershoot {
+	if factor <= 0 {
+		return nil
+	}
+
+	var (
+		overshoot *BitrateOvershoot
+		maxRatio  float64
+	)
+	for _, receiver := range receivers {
+		_, brs := receiver.GetLayeredBitrate()
+		for _, layer := range getDeclaredLayers(ti, receiver.Codec().MimeType) {
+			if layer.Bitrate == 0 {
+				continue
+			}
+			spatial := buffer.VideoQualityToSpatialLayer(layer.Quality, ti)
+			if spatial < 0 || int(spatial) >= len(brs) {
+				continue
+			}
+			// temporal bitrates are cumulative, the highest one covers the whole layer
+			var measured int64
+			for _, br := range brs[spatial] {
+				if br > measured {
+					measured = br
+				}
+			}
+			ratio := float64(measured) / float64(layer.Bitrate)
+			if ratio > factor && ratio > maxRatio {
+				maxRatio = ratio
+				overshoot = &BitrateOvershoot{
+					TrackSid:        ti.Sid,
+					Quality:         layer.Quality.String(),
+					DeclaredBitrate: layer.Bitrate,
+					MeasuredBitrate: measured,
+				}
+			}
+		}
+	}
+	return overshoot
+}
+
+// getDeclaredLayers returns the layers declared for a codec of a track, the layers of the track
+// are those of its primary codec
+func getDeclaredLayers(ti *livekit.TrackInfo, mimeType string) []*livekit.VideoLayer {
+	for _, codec := range ti.Codecs {
+		if strings.EqualFold(codec.MimeType, mimeType) && len(codec.Layers) != 0 {
+			return codec.Layers
+		}
+	}
+	return ti.Layers
+}
+
+// getPublisherClampBitrate returns the send rate a publisher is limited to: the declared bitrates of its
+// video tracks and an allowance for its audio tracks. It is 0 when a video track declares none.
+func getPublisherClampBitrate(tracks []types.MediaTrack) int64 {
+	var total int64
+	for _, track := range tracks {
+		if track.Kind() != livekit.TrackType_VIDEO {
+			total += bitrateOvershootAudioAllowance
+			continue
+		}
+
+		var declared int64
+		for _, layer := range track.ToProto().Layers {
+			declared += int64(layer.Bitrate)
+		}
+		if declared == 0 {
+			return 0
+		}
+		total += declared
+	}
+	return total
+}
+
+func getClampREMB(tracks []types.MediaTrack, bitrate int64) *rtcp.ReceiverEstimatedMaximumBitrate {
+	var ssrcs []uint32
+	for _, track := range tracks {
+		if track.Kind() != livekit.TrackType_VIDEO {
+			continue
+		}
+		ti := track.ToProto()
+		for _, layer := range ti.Layers {
+			if layer.Ssrc != 0 {
+				ssrcs = append(ssrcs, layer.Ssrc)
+			}
+		}
+	}
+	return &rtcp.ReceiverEstimatedMaximumBitrate{
+		Bitrate: float32(bitrate),
+		SSRCs:   ssrcs,
+	}
+}
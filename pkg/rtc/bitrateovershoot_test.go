@@ -0,0 +1,95 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/sfu"
+)
+
+type bitrateTestReceiver struct {
+	sfu.TrackReceiver
+	mimeType string
+	bitrates sfu.Bitrates
+}
+
+func (r *bitrateTestReceiver) Codec() webrtc.RTPCodecParameters {
+	return webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: r.mimeType}}
+}
+
+func (r *bitrateTestReceiver) GetLayeredBitrate() ([]int32, sfu.Bitrates) {
+	return nil, r.bitrates
+}
+
+func TestGetBitrateOvershoot(t *testing.T) {
+	ti := &livekit.TrackInfo{
+		Sid:  "TR_video",
+		Type: livekit.TrackType_VIDEO,
+		Layers: []*livekit.VideoLayer{
+			{Quality: livekit.VideoQuality_LOW, Width: 320, Height: 180, Bitrate: 150_000},
+			{Quality: livekit.VideoQuality_MEDIUM, Width: 640, Height: 360, Bitrate: 500_000},
+			{Quality: livekit.VideoQuality_HIGH, Width: 1280, Height: 720},
+		},
+	}
+	receiver := &bitrateTestReceiver{mimeType: webrtc.MimeTypeVP8}
+
+	// within the declared bitrates, the high layer declares none
+	receiver.bitrates[0] = [4]int64{100_000, 150_000, 200_000, 0}
+	receiver.bitrates[1] = [4]int64{400_000, 600_000, 0, 0}
+	receiver.bitrates[2] = [4]int64{10_000_000, 0, 0, 0}
+	require.Nil(t, getBitrateOvershoot(ti, []sfu.TrackReceiver{receiver}, 3))
+
+	// medium layer overshoots
+	receiver.bitrates[1] = [4]int64{1_000_000, 2_000_000, 0, 0}
+	overshoot := getBitrateOvershoot(ti, []sfu.TrackReceiver{receiver}, 3)
+	require.Equal(t, &BitrateOvershoot{
+		TrackSid:        "TR_video",
+		Quality:         livekit.VideoQuality_MEDIUM.String(),
+		DeclaredBitrate: 500_000,
+		MeasuredBitrate: 2_000_000,
+	}, overshoot)
+
+	// disabled
+	require.Nil(t, getBitrateOvershoot(ti, []sfu.TrackReceiver{receiver}, 0))
+}
+
+func TestBitrateOvershootPacket(t *testing.T) {
+	overshoot := &BitrateOvershoot{
+		TrackSid:        "TR_video",
+		Quality:         livekit.VideoQuality_HIGH.String(),
+		DeclaredBitrate: 1_000_000,
+		MeasuredBitrate: 5_000_000,
+		ClampBitrate:    1_700_000,
+	}
+	data, err := bitrateOvershootPacket(overshoot)
+	require.NoError(t, err)
+
+	dp := &livekit.DataPacket{}
+	require.NoError(t, proto.Unmarshal(data, dp))
+	require.Equal(t, BitrateOvershootTopic, dp.GetUser().GetTopic())
+	require.Empty(t, dp.ParticipantIdentity)
+
+	var decoded BitrateOvershoot
+	require.NoError(t, json.Unmarshal(dp.GetUser().GetPayload(), &decoded))
+	require.Equal(t, *overshoot, decoded)
+}
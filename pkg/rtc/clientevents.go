@@ -0,0 +1,52 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+// ClientEventsTopic is the topic clients report quality of experience events on, the data packets are
+// consumed by the server and not forwarded to the room
+const ClientEventsTopic = "lk.client_events"
+
+// ClientEvents is the payload of the data packets sent on ClientEventsTopic
+type ClientEvents struct {
+	Events []*telemetry.ClientEvent `json:"events"`
+}
+
+func (r *Room) handleClientEvents(source types.LocalParticipant, payload []byte) {
+	var report ClientEvents
+	err := json.Unmarshal(payload, &report)
+	if err == nil {
+		err = r.RecordClientEvents(source, report.Events)
+	}
+	if err != nil {
+		source.GetLogger().Infow("ignoring invalid client events", "error", err)
+	}
+}
+
+// RecordClientEvents aggregates the events reported by a participant with the stats of its session
+func (r *Room) RecordClientEvents(p types.LocalParticipant, events []*telemetry.ClientEvent) error {
+	if err := telemetry.ValidateClientEvents(events); err != nil {
+		return err
+	}
+	r.telemetry.ClientEvents(context.Background(), p.ID(), events)
+	return nil
+}
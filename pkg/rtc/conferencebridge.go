@@ -0,0 +1,218 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// digits dialed further apart start a new sequence
+const conferenceBridgeDigitTimeout = 3 * time.Second
+
+type dtmfInput struct {
+	digits string
+	at     time.Time
+}
+
+// conferenceBridge subscribes the SIP participants of a room to the audio of the loudest speakers only,
+// so that the number of subscriptions grows with the number of legs rather than with its square. The SIP
+// bridge mixes the few tracks each leg receives.
+type conferenceBridge struct {
+	maxSpeakers  int
+	speakerHold  time.Duration
+	muteSequence string
+
+	// only accessed by the audio update worker
+	speakers      map[livekit.ParticipantID]time.Time
+	subscriptions map[livekit.ParticipantIdentity]map[livekit.TrackID]struct{}
+
+	lock   sync.Mutex
+	inputs map[livekit.ParticipantIdentity]*dtmfInput
+}
+
+func newConferenceBridge(conf config.ConferenceBridgeConfig) *conferenceBridge {
+	return &conferenceBridge{
+		maxSpeakers:   conf.MaxSpeakers,
+		speakerHold:   conf.SpeakerHold,
+		muteSequence:  conf.MuteSequence,
+		speakers:      make(map[livekit.ParticipantID]time.Time),
+		subscriptions: make(map[livekit.ParticipantIdentity]map[livekit.TrackID]struct{}),
+		inputs:        make(map[livekit.ParticipantIdentity]*dtmfInput),
+	}
+}
+
+// selectSpeakers updates the speakers legs receive from the active ones, loudest first, and returns them.
+// A speaker is kept while quiet for the hold time, unless a new one needs its place.
+func (b *conferenceBridge) selectSpeakers(active []livekit.ParticipantID, now time.Time) map[livekit.ParticipantID]time.Time {
+	for _, id := range active {
+		if _, ok := b.speakers[id]; ok {
+			b.speakers[id] = now
+		}
+	}
+	for id, heard := range b.speakers {
+		if now.Sub(heard) > b.speakerHold {
+			delete(b.speakers, id)
+		}
+	}
+
+	for _, id := range active {
+		if _, ok := b.speakers[id]; ok {
+			continue
+		}
+		if len(b.speakers) >= b.maxSpeakers {
+			// replace the speaker quiet for the longest time
+			var quietest livekit.ParticipantID
+			for sid, heard := range b.speakers {
+				if heard.Before(now) && (quietest == "" || heard.Before(b.speakers[quietest])) {
+					quietest = sid
+				}
+			}
+			if quietest == "" {
+				break
+			}
+			delete(b.speakers, quietest)
+		}
+		b.speakers[id] = now
+	}
+	return b.speakers
+}
+
+// subscribe makes the subscriptions of a leg to the tracks of the speakers those passed. Subscriptions
+// made otherwise, e.g. through the API, are left alone.
+func (b *conferenceBridge) subscribe(p types.LocalParticipant, tracks []types.MediaTrack) {
+	current := b.subscriptions[p.Identity()]
+	next := make(map[livekit.TrackID]struct{}, len(tracks))
+	for _, track := range tracks {
+		next[track.ID()] = struct{}{}
+		if _, ok := current[track.ID()]; !ok {
+			p.SubscribeToTrack(track.ID())
+		}
+	}
+	for trackID := range current {
+		if _, ok := next[trackID]; !ok {
+			p.UnsubscribeFromTrack(trackID)
+		}
+	}
+	b.subscriptions[p.Identity()] = next
+}
+
+func (b *conferenceBridge) prune(present map[livekit.ParticipantIdentity]bool) {
+	for identity := range b.subscriptions {
+		if !present[identity] {
+			delete(b.subscriptions, identity)
+		}
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for identity := range b.inputs {
+		if !present[identity] {
+			delete(b.inputs, identity)
+		}
+	}
+}
+
+// dial adds a digit dialed by a leg and returns whether it completes the mute sequence
+func (b *conferenceBridge) dial(identity livekit.ParticipantIdentity, digit string, now time.Time) bool {
+	if b.muteSequence == "" {
+		return false
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	input := b.inputs[identity]
+	if input == nil || now.Sub(input.at) > conferenceBridgeDigitTimeout {
+		input = &dtmfInput{}
+		b.inputs[identity] = input
+	}
+	input.digits += digit
+	input.at = now
+	if len(input.digits) > len(b.muteSequence) {
+		input.digits = input.digits[len(input.digits)-len(b.muteSequence):]
+	}
+	if input.digits != b.muteSequence {
+		return false
+	}
+	delete(b.inputs, identity)
+	return true
+}
+
+// ------------------------------------------------
+
+func (r *Room) updateConferenceBridge(activeSpeakers []*livekit.SpeakerInfo) {
+	active := make([]livekit.ParticipantID, 0, len(activeSpeakers))
+	for _, speaker := range activeSpeakers {
+		active = append(active, livekit.ParticipantID(speaker.Sid))
+	}
+	speakers := r.conferenceBridge.selectSpeakers(active, time.Now())
+
+	participants := r.GetParticipants()
+	present := make(map[livekit.ParticipantIdentity]bool, len(participants))
+	var tracks []types.MediaTrack
+	for _, p := range participants {
+		present[p.Identity()] = true
+		if _, ok := speakers[p.ID()]; !ok {
+			continue
+		}
+		for _, track := range p.GetPublishedTracks() {
+			if track.Kind() == livekit.TrackType_AUDIO {
+				tracks = append(tracks, track)
+			}
+		}
+	}
+
+	for _, p := range participants {
+		if p.Kind() != livekit.ParticipantInfo_SIP || p.State() != livekit.ParticipantInfo_ACTIVE {
+			continue
+		}
+		// legs do not hear themselves
+		legTracks := make([]types.MediaTrack, 0, len(tracks))
+		for _, track := range tracks {
+			if track.PublisherID() != p.ID() {
+				legTracks = append(legTracks, track)
+			}
+		}
+		r.conferenceBridge.subscribe(p, legTracks)
+	}
+	r.conferenceBridge.prune(present)
+}
+
+func (r *Room) handleConferenceBridgeDTMF(p types.LocalParticipant, digit string) {
+	if !r.conferenceBridge.dial(p.Identity(), digit, time.Now()) {
+		return
+	}
+
+	// mute when any microphone is live, unmute otherwise
+	var microphones []types.MediaTrack
+	muted := true
+	for _, track := range p.GetPublishedTracks() {
+		if track.Kind() == livekit.TrackType_AUDIO {
+			microphones = append(microphones, track)
+			if !track.IsMuted() {
+				muted = false
+			}
+		}
+	}
+	for _, track := range microphones {
+		p.SetTrackMuted(track.ID(), !muted, true)
+	}
+	r.Logger.Infow("conference bridge participant toggled mute", "participant", p.Identity(), "muted", !muted)
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestConferenceBridge(t *testing.T) {
+	newBridge := func() *conferenceBridge {
+		return newConferenceBridge(config.ConferenceBridgeConfig{
+			MaxSpeakers:  2,
+			SpeakerHold:  2 * time.Second,
+			MuteSequence: "*6",
+		})
+	}
+
+	t.Run("selects the loudest speakers", func(t *testing.T) {
+		b := newBridge()
+		now := time.Now()
+		speakers := b.selectSpeakers([]livekit.ParticipantID{"PA_a", "PA_b", "PA_c"}, now)
+		require.Len(t, speakers, 2)
+		require.Contains(t, speakers, livekit.ParticipantID("PA_a"))
+		require.Contains(t, speakers, livekit.ParticipantID("PA_b"))
+
+		// quiet speakers are kept for the hold time
+		speakers = b.selectSpeakers(nil, now.Add(time.Second))
+		require.Len(t, speakers, 2)
+
+		// unless a new speaker needs their place
+		speakers = b.selectSpeakers([]livekit.ParticipantID{"PA_b", "PA_c"}, now.Add(1500*time.Millisecond))
+		require.Len(t, speakers, 2)
+		require.Contains(t, speakers, livekit.ParticipantID("PA_b"))
+		require.Contains(t, speakers, livekit.ParticipantID("PA_c"))
+
+		speakers = b.selectSpeakers(nil, now.Add(4*time.Second))
+		require.Empty(t, speakers)
+	})
+
+	t.Run("recognizes the mute sequence", func(t *testing.T) {
+		b := newBridge()
+		now := time.Now()
+		require.False(t, b.dial("leg", "*", now))
+		require.True(t, b.dial("leg", "6", now.Add(time.Second)))
+
+		// only the last digits count
+		require.False(t, b.dial("leg", "1", now))
+		require.False(t, b.dial("leg", "*", now))
+		require.True(t, b.dial("leg", "6", now))
+
+		// too slow
+		require.False(t, b.dial("leg", "*", now))
+		require.False(t, b.dial("leg", "6", now.Add(conferenceBridgeDigitTimeout+time.Second)))
+
+		// per participant
+		require.False(t, b.dial("leg", "*", now))
+		require.False(t, b.dial("other", "6", now))
+	})
+}
@@ -36,6 +36,8 @@ type WebRTCConfig struct {
 	Receiver      ReceiverConfig
 	Publisher     DirectionConfig
 	Subscriber    DirectionConfig
+	ICEPolicy     *ICEPolicy
+	RoomClasses   *RoomClasses
 }
 
 type ReceiverConfig struct {
@@ -141,14 +143,21 @@ func NewWebRTCConfig(conf *config.Config) (*WebRTCConfig, error) {
 		subscriberConfig.RTCPFeedback.Video = append(subscriberConfig.RTCPFeedback.Video, webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBGoogREMB})
 	}
 
+	roomClasses, err := NewRoomClasses(rtcConf.RoomClasses)
+	if err != nil {
+		return nil, err
+	}
+
 	return &WebRTCConfig{
 		WebRTCConfig: *webRTCConfig,
 		Receiver: ReceiverConfig{
 			PacketBufferSizeVideo: rtcConf.PacketBufferSizeVideo,
 			PacketBufferSizeAudio: rtcConf.PacketBufferSizeAudio,
 		},
-		Publisher:  publisherConfig,
-		Subscriber: subscriberConfig,
+		Publisher:   publisherConfig,
+		Subscriber:  subscriberConfig,
+		ICEPolicy:   NewICEPolicy(rtcConf.ICEPolicy),
+		RoomClasses: roomClasses,
 	}, nil
 }
 
@@ -0,0 +1,212 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// DataHistoryTopic is the data topic of history requests sent by participants, and of the
+	// history sent back to them
+	DataHistoryTopic = "lk.data_history"
+
+	// number of messages per history data packet
+	dataHistoryBatchSize = 20
+)
+
+// DataMessage is a reliable user data message kept in the history of a room
+type DataMessage struct {
+	Timestamp      int64  `json:"timestamp"`
+	Identity       string `json:"identity,omitempty"`
+	ParticipantSid string `json:"participant_sid,omitempty"`
+	Topic          string `json:"topic,omitempty"`
+	Payload        []byte `json:"payload"`
+}
+
+// DataHistoryRequest is the JSON payload of a DataHistoryTopic message sent by a participant, limit is
+// capped by the configured history size and topic restricts the history to the messages of a topic
+type DataHistoryRequest struct {
+	Limit int    `json:"limit,omitempty"`
+	Topic string `json:"topic,omitempty"`
+}
+
+// DataHistory is the JSON payload of a DataHistoryTopic message sent to a participant. Messages are
+// oldest first, histories are split in several messages and Final is set on the last one.
+type DataHistory struct {
+	Messages []*DataMessage `json:"messages"`
+	Final    bool           `json:"final"`
+}
+
+// DataMessageStore keeps the data message history of a room. The in memory store is used by default,
+// other stores can be set with Room.SetDataMessageStore to persist histories out of the node.
+type DataMessageStore interface {
+	StoreDataMessage(msg *DataMessage)
+	// LoadDataMessages returns up to limit of the most recent messages, oldest first
+	LoadDataMessages(topic string, limit int) []*DataMessage
+}
+
+// memoryDataMessageStore keeps the recent data messages of a room, bounded in number and age
+type memoryDataMessageStore struct {
+	conf config.DataHistoryConfig
+
+	lock     sync.Mutex
+	messages []*DataMessage
+}
+
+func newMemoryDataMessageStore(conf config.DataHistoryConfig) *memoryDataMessageStore {
+	return &memoryDataMessageStore{conf: conf}
+}
+
+func (s *memoryDataMessageStore) StoreDataMessage(msg *DataMessage) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.messages = append(s.messages, msg)
+	s.pruneLocked(msg.Timestamp)
+}
+
+func (s *memoryDataMessageStore) LoadDataMessages(topic string, limit int) []*DataMessage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.pruneLocked(time.Now().UnixMilli())
+	messages := make([]*DataMessage, 0, min(limit, len(s.messages)))
+	for i := len(s.messages) - 1; i >= 0 && len(messages) < limit; i-- {
+		if topic == "" || s.messages[i].Topic == topic {
+			messages = append(messages, s.messages[i])
+		}
+	}
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages
+}
+
+func (s *memoryDataMessageStore) pruneLocked(now int64) {
+	drop := max(len(s.messages)-s.conf.MaxMessages, 0)
+	if s.conf.MaxAge > 0 {
+		oldest := now - s.conf.MaxAge.Milliseconds()
+		for drop < len(s.messages) && s.messages[drop].Timestamp < oldest {
+			drop++
+		}
+	}
+	s.messages = s.messages[drop:]
+}
+
+// SetDataMessageStore replaces the store keeping the data message history of the room
+func (r *Room) SetDataMessageStore(store DataMessageStore) {
+	r.lock.Lock()
+	r.dataMessageStore = store
+	r.lock.Unlock()
+}
+
+func (r *Room) getDataMessageStore() DataMessageStore {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.dataMessageStore
+}
+
+// recordDataMessage keeps reliable user messages sent to the whole room. Messages sent to some participants
+// only and the ones of server topics are not kept.
+func (r *Room) recordDataMessage(source types.LocalParticipant, kind livekit.DataPacket_Kind, dp *livekit.DataPacket) {
+	user := dp.GetUser()
+	if r.dataHistoryConfig.MaxMessages <= 0 || kind != livekit.DataPacket_RELIABLE || user == nil {
+		return
+	}
+	if len(dp.DestinationIdentities) != 0 || len(user.DestinationIdentities) != 0 || len(user.DestinationSids) != 0 {
+		return
+	}
+	topic := user.GetTopic()
+	if strings.HasPrefix(topic, "lk.") {
+		return
+	}
+	if len(r.dataHistoryConfig.Topics) != 0 && !slices.Contains(r.dataHistoryConfig.Topics, topic) {
+		return
+	}
+
+	msg := &DataMessage{
+		Timestamp: time.Now().UnixMilli(),
+		Topic:     topic,
+		Payload:   user.Payload,
+	}
+	if source != nil {
+		msg.Identity = string(source.Identity())
+		msg.ParticipantSid = string(source.ID())
+	} else {
+		msg.Identity = dp.ParticipantIdentity
+	}
+	r.getDataMessageStore().StoreDataMessage(msg)
+}
+
+// sendDataHistory answers the history request of a participant
+func (r *Room) sendDataHistory(p types.LocalParticipant, payload []byte) {
+	if r.dataHistoryConfig.MaxMessages <= 0 {
+		return
+	}
+	var req DataHistoryRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		p.GetLogger().Infow("ignoring invalid data history request", "error", err)
+		return
+	}
+	limit := r.dataHistoryConfig.MaxMessages
+	if req.Limit > 0 {
+		limit = min(req.Limit, limit)
+	}
+
+	messages := r.getDataMessageStore().LoadDataMessages(req.Topic, limit)
+	for {
+		history := &DataHistory{Messages: messages[:min(len(messages), dataHistoryBatchSize)]}
+		messages = messages[len(history.Messages):]
+		history.Final = len(messages) == 0
+
+		dp, err := dataHistoryPacket(history)
+		if err != nil {
+			r.Logger.Errorw("could not encode data history", err)
+			return
+		}
+		dp.DestinationIdentities = []string{string(p.Identity())}
+		BroadcastDataPacketForRoom(r, nil, livekit.DataPacket_RELIABLE, dp, r.Logger)
+		if history.Final {
+			return
+		}
+	}
+}
+
+func dataHistoryPacket(history *DataHistory) (*livekit.DataPacket, error) {
+	payload, err := json.Marshal(history)
+	if err != nil {
+		return nil, err
+	}
+	topic := DataHistoryTopic
+	return &livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload: payload,
+				Topic:   &topic,
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,40 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// DataPacketLimiter decides whether the data packets of a participant are delivered, packets of participants
+// flooding their room are dropped
+type DataPacketLimiter interface {
+	AllowDataPacket(roomName livekit.RoomName, p types.LocalParticipant) bool
+}
+
+func (r *Room) SetDataPacketLimiter(limiter DataPacketLimiter) {
+	r.lock.Lock()
+	r.dataPacketLimiter = limiter
+	r.lock.Unlock()
+}
+
+func (r *Room) allowDataPacket(p types.LocalParticipant) bool {
+	r.lock.RLock()
+	limiter := r.dataPacketLimiter
+	r.lock.RUnlock()
+	return limiter == nil || limiter.AllowDataPacket(r.Name(), p)
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DeviceAttributePrefix is the reserved attribute namespace for the state of the client devices.
+// Only the keys below are accepted in it, with one of their values, so that backends get the same
+// diagnostics whatever the SDK. Setting a key to an empty value clears it.
+const DeviceAttributePrefix = "lk.device."
+
+const (
+	// permission given by the user/OS to capture devices: granted, denied or prompt
+	DeviceAttributeCameraPermission      = DeviceAttributePrefix + "camera_permission"
+	DeviceAttributeMicrophonePermission  = DeviceAttributePrefix + "microphone_permission"
+	DeviceAttributeScreenSharePermission = DeviceAttributePrefix + "screen_share_permission"
+	// microphone is muted by the OS or a hardware switch: true or false
+	DeviceAttributeMicrophoneOSMuted = DeviceAttributePrefix + "microphone_os_muted"
+	// camera is held by another application: true or false
+	DeviceAttributeCameraInUse = DeviceAttributePrefix + "camera_in_use"
+)
+
+var (
+	devicePermissionValues = []string{"granted", "denied", "prompt"}
+	deviceBoolValues       = []string{"true", "false"}
+
+	deviceAttributeValues = map[string][]string{
+		DeviceAttributeCameraPermission:      devicePermissionValues,
+		DeviceAttributeMicrophonePermission:  devicePermissionValues,
+		DeviceAttributeScreenSharePermission: devicePermissionValues,
+		DeviceAttributeMicrophoneOSMuted:     deviceBoolValues,
+		DeviceAttributeCameraInUse:           deviceBoolValues,
+	}
+)
+
+func IsDeviceAttribute(key string) bool {
+	return strings.HasPrefix(key, DeviceAttributePrefix)
+}
+
+// ValidateDeviceAttributes checks the attributes in the device namespace, other attributes are ignored
+func ValidateDeviceAttributes(attributes map[string]string) error {
+	for k, v := range attributes {
+		if !IsDeviceAttribute(k) {
+			continue
+		}
+		values, ok := deviceAttributeValues[k]
+		if !ok {
+			return fmt.Errorf("%w: unknown key %s", ErrInvalidDeviceAttribute, k)
+		}
+		if v != "" && !slices.Contains(values, v) {
+			return fmt.Errorf("%w: %s must be one of %s", ErrInvalidDeviceAttribute, k, strings.Join(values, ", "))
+		}
+	}
+	return nil
+}
+
+// OnlyDeviceAttributes returns true when every attribute is in the device namespace
+func OnlyDeviceAttributes(attributes map[string]string) bool {
+	if len(attributes) == 0 {
+		return false
+	}
+	for k := range attributes {
+		if !IsDeviceAttribute(k) {
+			return false
+		}
+	}
+	return true
+}
+
+// DeviceAttributes returns the device state of a participant
+func DeviceAttributes(attributes map[string]string) map[string]string {
+	state := make(map[string]string)
+	for k, v := range attributes {
+		if IsDeviceAttribute(k) {
+			state[k] = v
+		}
+	}
+	return state
+}
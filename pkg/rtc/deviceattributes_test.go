@@ -0,0 +1,51 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceAttributes(t *testing.T) {
+	t.Run("validates reserved keys and values", func(t *testing.T) {
+		require.NoError(t, ValidateDeviceAttributes(map[string]string{
+			DeviceAttributeCameraPermission:  "denied",
+			DeviceAttributeMicrophoneOSMuted: "true",
+			DeviceAttributeCameraInUse:       "",
+			"custom":                         "anything",
+		}))
+		require.ErrorIs(t, ValidateDeviceAttributes(map[string]string{
+			DeviceAttributeCameraPermission: "blocked",
+		}), ErrInvalidDeviceAttribute)
+		require.ErrorIs(t, ValidateDeviceAttributes(map[string]string{
+			DeviceAttributePrefix + "speaker": "true",
+		}), ErrInvalidDeviceAttribute)
+	})
+
+	t.Run("device only updates", func(t *testing.T) {
+		require.False(t, OnlyDeviceAttributes(nil))
+		require.True(t, OnlyDeviceAttributes(map[string]string{DeviceAttributeCameraInUse: "true"}))
+		require.False(t, OnlyDeviceAttributes(map[string]string{
+			DeviceAttributeCameraInUse: "true",
+			"custom":                   "value",
+		}))
+		require.Equal(t, map[string]string{DeviceAttributeCameraInUse: "true"}, DeviceAttributes(map[string]string{
+			DeviceAttributeCameraInUse: "true",
+			"custom":                   "value",
+		}))
+	})
+}
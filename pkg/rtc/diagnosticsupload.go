@@ -0,0 +1,109 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// DiagnosticsUploadTopic is the data topic the server asks a client to upload its local diagnostics and
+	// logs on, with a DiagnosticsUploadRequest payload. Clients report the outcome on the same topic, with a
+	// DiagnosticsUploadResult payload, which is consumed by the server.
+	DiagnosticsUploadTopic = "lk.diagnostics_upload"
+
+	// EventClientDiagnosticsUploaded is sent when a client reports its diagnostics were uploaded
+	EventClientDiagnosticsUploaded = "client_diagnostics_uploaded"
+	// EventClientDiagnosticsFailed is sent when a client reports it could not upload its diagnostics
+	EventClientDiagnosticsFailed = "client_diagnostics_failed"
+)
+
+// DiagnosticsUploadRequest asks a client to upload its diagnostics to a presigned URL, with a PUT request
+// before the URL expires
+type DiagnosticsUploadRequest struct {
+	RequestID string `json:"request_id"`
+	URL       string `json:"url"`
+	Method    string `json:"method"`
+	// unix seconds
+	ExpiresAt int64  `json:"expires_at"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// DiagnosticsUploadResult is the outcome of a DiagnosticsUploadRequest reported by the client
+type DiagnosticsUploadResult struct {
+	RequestID string `json:"request_id"`
+	Uploaded  bool   `json:"uploaded"`
+	// bytes uploaded
+	Size  int64  `json:"size,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// RequestDiagnosticsUpload sends an upload request to a participant, it does not wait for the upload
+func (r *Room) RequestDiagnosticsUpload(identity livekit.ParticipantIdentity, req *DiagnosticsUploadRequest) (types.LocalParticipant, error) {
+	p := r.GetParticipant(identity)
+	if p == nil {
+		return nil, ErrParticipantNotFound
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := proto.Marshal(&livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload: payload,
+				Topic:   proto.String(DiagnosticsUploadTopic),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = p.SendDataPacket(livekit.DataPacket_RELIABLE, data); err != nil {
+		return nil, err
+	}
+	p.GetLogger().Infow("requested diagnostics upload", "requestID", req.RequestID, "reason", req.Reason)
+	return p, nil
+}
+
+func (r *Room) handleDiagnosticsUploadResult(p types.LocalParticipant, payload []byte) {
+	var result DiagnosticsUploadResult
+	if err := json.Unmarshal(payload, &result); err != nil || result.RequestID == "" {
+		p.GetLogger().Infow("ignoring invalid diagnostics upload result", "error", err)
+		return
+	}
+
+	event := EventClientDiagnosticsUploaded
+	if result.Uploaded {
+		p.GetLogger().Infow("diagnostics uploaded", "requestID", result.RequestID, "size", result.Size)
+	} else {
+		event = EventClientDiagnosticsFailed
+		p.GetLogger().Infow("diagnostics upload failed", "requestID", result.RequestID, "error", result.Error)
+	}
+	r.telemetry.NotifyEvent(context.Background(), &livekit.WebhookEvent{
+		Event:       event,
+		Room:        r.ToProto(),
+		Participant: p.ToProto(),
+	})
+}
@@ -39,7 +39,9 @@ type DynacastManager struct {
 
 	lock                          sync.RWMutex
 	dynacastQuality               map[string]*DynacastQuality // mime type => DynacastQuality
+	subscribedQuality             map[string]livekit.VideoQuality
 	maxSubscribedQuality          map[string]livekit.VideoQuality
+	maxQuality                    livekit.VideoQuality
 	committedMaxSubscribedQuality map[string]livekit.VideoQuality
 
 	maxSubscribedQualityDebounce        func(func())
@@ -59,7 +61,9 @@ func NewDynacastManager(params DynacastManagerParams) *DynacastManager {
 	d := &DynacastManager{
 		params:                        params,
 		dynacastQuality:               make(map[string]*DynacastQuality),
+		subscribedQuality:             make(map[string]livekit.VideoQuality),
 		maxSubscribedQuality:          make(map[string]livekit.VideoQuality),
+		maxQuality:                    livekit.VideoQuality_HIGH,
 		committedMaxSubscribedQuality: make(map[string]livekit.VideoQuality),
 		qualityNotifyOpQueue: utils.NewOpsQueue(utils.OpsQueueParams{
 			Name:        "quality-notify",
@@ -135,6 +139,30 @@ func (d *DynacastManager) ForceQuality(quality livekit.VideoQuality) {
 	d.enqueueSubscribedQualityChange()
 }
 
+// SetMaxQuality caps the quality requested from the publisher whatever the subscribers request,
+// livekit.VideoQuality_HIGH lifts the cap
+func (d *DynacastManager) SetMaxQuality(quality livekit.VideoQuality) {
+	d.lock.Lock()
+	if d.maxQuality == quality {
+		d.lock.Unlock()
+		return
+	}
+	d.maxQuality = quality
+	for mime, subscribedQuality := range d.subscribedQuality {
+		d.maxSubscribedQuality[mime] = d.capQualityLocked(subscribedQuality)
+	}
+	d.lock.Unlock()
+
+	d.update(false)
+}
+
+func (d *DynacastManager) capQualityLocked(quality livekit.VideoQuality) livekit.VideoQuality {
+	if quality != livekit.VideoQuality_OFF && quality > d.maxQuality {
+		return d.maxQuality
+	}
+	return quality
+}
+
 func (d *DynacastManager) NotifySubscriberMaxQuality(subscriberID livekit.ParticipantID, mime string, quality livekit.VideoQuality) {
 	dq := d.getOrCreateDynacastQuality(mime)
 	if dq != nil {
@@ -183,7 +211,8 @@ func (d *DynacastManager) getDynacastQualitiesLocked() []*DynacastQuality {
 
 func (d *DynacastManager) updateMaxQualityForMime(mime string, maxQuality livekit.VideoQuality) {
 	d.lock.Lock()
-	d.maxSubscribedQuality[mime] = maxQuality
+	d.subscribedQuality[mime] = maxQuality
+	d.maxSubscribedQuality[mime] = d.capQualityLocked(maxQuality)
 	d.lock.Unlock()
 
 	d.update(false)
@@ -284,4 +284,38 @@ func TestSubscribedMaxQuality(t *testing.T) {
 			return subscribedCodecsAsString(expectedSubscribedQualities) == subscribedCodecsAsString(actualSubscribedQualities)
 		}, 10*time.Second, 100*time.Millisecond)
 	})
+
+	t.Run("max quality", func(t *testing.T) {
+		dm := NewDynacastManager(DynacastManagerParams{})
+
+		var lock sync.Mutex
+		var actualMaxSubscribedQualities []types.SubscribedCodecQuality
+		dm.OnSubscribedMaxQualityChange(func(_subscribedQualities []*livekit.SubscribedCodec, maxSubscribedQualities []types.SubscribedCodecQuality) {
+			lock.Lock()
+			actualMaxSubscribedQualities = maxSubscribedQualities
+			lock.Unlock()
+		})
+		maxQualityIs := func(quality livekit.VideoQuality) func() bool {
+			return func() bool {
+				lock.Lock()
+				defer lock.Unlock()
+				return len(actualMaxSubscribedQualities) == 1 && actualMaxSubscribedQualities[0].Quality == quality
+			}
+		}
+
+		dm.NotifySubscriberMaxQuality("s1", webrtc.MimeTypeVP8, livekit.VideoQuality_HIGH)
+		require.Eventually(t, maxQualityIs(livekit.VideoQuality_HIGH), 10*time.Second, 100*time.Millisecond)
+
+		// the cap applies whatever subscribers request
+		dm.SetMaxQuality(livekit.VideoQuality_LOW)
+		require.Eventually(t, maxQualityIs(livekit.VideoQuality_LOW), 10*time.Second, 100*time.Millisecond)
+
+		// subscribers requesting less than the cap are followed
+		dm.NotifySubscriberMaxQuality("s1", webrtc.MimeTypeVP8, livekit.VideoQuality_OFF)
+		require.Eventually(t, maxQualityIs(livekit.VideoQuality_OFF), 10*time.Second, 100*time.Millisecond)
+
+		dm.NotifySubscriberMaxQuality("s1", webrtc.MimeTypeVP8, livekit.VideoQuality_HIGH)
+		dm.SetMaxQuality(livekit.VideoQuality_HIGH)
+		require.Eventually(t, maxQualityIs(livekit.VideoQuality_HIGH), 10*time.Second, 100*time.Millisecond)
+	})
 }
@@ -19,22 +19,46 @@ import (
 )
 
 var (
-	ErrRoomClosed               = errors.New("room has already closed")
-	ErrParticipantSessionClosed = errors.New("participant session is already closed")
-	ErrPermissionDenied         = errors.New("no permissions to access the room")
-	ErrMaxParticipantsExceeded  = errors.New("room has exceeded its max participants")
-	ErrLimitExceeded            = errors.New("node has exceeded its configured limit")
-	ErrAlreadyJoined            = errors.New("a participant with the same identity is already in the room")
-	ErrDataChannelUnavailable   = errors.New("data channel is not available")
-	ErrDataChannelBufferFull    = errors.New("data channel buffer is full")
-	ErrTransportFailure         = errors.New("transport failure")
-	ErrEmptyIdentity            = errors.New("participant identity cannot be empty")
-	ErrEmptyParticipantID       = errors.New("participant ID cannot be empty")
-	ErrMissingGrants            = errors.New("VideoGrant is missing")
-	ErrInternalError            = errors.New("internal error")
-	ErrNameExceedsLimits        = errors.New("name length exceeds limits")
-	ErrMetadataExceedsLimits    = errors.New("metadata size exceeds limits")
-	ErrAttributesExceedsLimits  = errors.New("attributes size exceeds limits")
+	ErrRoomClosed                 = errors.New("room has already closed")
+	ErrParticipantSessionClosed   = errors.New("participant session is already closed")
+	ErrPermissionDenied           = errors.New("no permissions to access the room")
+	ErrMaxParticipantsExceeded    = errors.New("room has exceeded its max participants")
+	ErrLimitExceeded              = errors.New("node has exceeded its configured limit")
+	ErrAlreadyJoined              = errors.New("a participant with the same identity is already in the room")
+	ErrDataChannelUnavailable     = errors.New("data channel is not available")
+	ErrDataChannelBufferFull      = errors.New("data channel buffer is full")
+	ErrTransportFailure           = errors.New("transport failure")
+	ErrEmptyIdentity              = errors.New("participant identity cannot be empty")
+	ErrEmptyParticipantID         = errors.New("participant ID cannot be empty")
+	ErrMissingGrants              = errors.New("VideoGrant is missing")
+	ErrInternalError              = errors.New("internal error")
+	ErrNameExceedsLimits          = errors.New("name length exceeds limits")
+	ErrMetadataExceedsLimits      = errors.New("metadata size exceeds limits")
+	ErrAttributesExceedsLimits    = errors.New("attributes size exceeds limits")
+	ErrInvalidDeviceAttribute     = errors.New("invalid device attribute")
+	ErrVirtualParticipantNotFound = errors.New("virtual participant not found")
+	ErrParticipantNotPending      = errors.New("participant is not waiting for admission")
+	ErrParticipantNotFound        = errors.New("participant not found")
+	ErrInvalidParticipantRole     = errors.New("invalid participant role")
+	ErrRoleAttributeNotAllowed    = errors.New("participant role can only be changed by the server")
+	ErrInvalidRecordingConsent    = errors.New("invalid recording consent")
+	ErrConsentAttributeNotAllowed = errors.New("recording consent can only be changed by the server")
+	ErrBitrateAttributeNotAllowed = errors.New("publish bitrate cap can only be changed by the server")
+
+	ErrNoSharedPlayback            = errors.New("no shared playback in the room")
+	ErrInvalidSharedPlaybackAction = errors.New("invalid shared playback action")
+
+	ErrInvalidTrackForward  = errors.New("invalid track forward request")
+	ErrTrackForwardNotFound = errors.New("track forward not found")
+
+	ErrInvalidTrackRecording  = errors.New("invalid track recording request")
+	ErrTrackRecordingNotFound = errors.New("track recording not found")
+	ErrTrackRecordingDisabled = errors.New("track recording is not configured")
+
+	ErrInvalidRTPIngest  = errors.New("invalid rtp ingest request")
+	ErrRTPIngestNotFound = errors.New("rtp ingest not found")
+
+	ErrUnsupportedSimulcastRid = errors.New("simulcast rids must be q, or q and h, or q, h and f")
 
 	// Track subscription related
 	ErrNoTrackPermission         = errors.New("participant is not allowed to subscribe to this track")
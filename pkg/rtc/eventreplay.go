@@ -0,0 +1,225 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// EventReplayTopic is the data topic carrying replayed room events to a participant
+	EventReplayTopic = "lk.event_replay"
+	// EventReplayAttribute is set in the token of a participant, agents and bots usually, to the number of
+	// seconds of past room events replayed to it once connected
+	EventReplayAttribute = "lk.event_replay"
+
+	// number of events per replay data packet
+	eventReplayBatchSize = 100
+)
+
+type RoomEventType string
+
+const (
+	RoomEventParticipantJoined   RoomEventType = "participant_joined"
+	RoomEventParticipantLeft     RoomEventType = "participant_left"
+	RoomEventParticipantUpdated  RoomEventType = "participant_updated"
+	RoomEventTrackPublished      RoomEventType = "track_published"
+	RoomEventTrackUnpublished    RoomEventType = "track_unpublished"
+	RoomEventRoomMetadataChanged RoomEventType = "room_metadata_changed"
+)
+
+// RoomEvent is a past room event, participant joined and updated events carry the name, metadata and
+// attributes the participant had at the time
+type RoomEvent struct {
+	Type           RoomEventType     `json:"type"`
+	Timestamp      int64             `json:"timestamp"`
+	Identity       string            `json:"identity,omitempty"`
+	ParticipantSid string            `json:"participant_sid,omitempty"`
+	Kind           string            `json:"kind,omitempty"`
+	Name           string            `json:"name,omitempty"`
+	Metadata       string            `json:"metadata,omitempty"`
+	Attributes     map[string]string `json:"attributes,omitempty"`
+	TrackSid       string            `json:"track_sid,omitempty"`
+	TrackName      string            `json:"track_name,omitempty"`
+	TrackType      string            `json:"track_type,omitempty"`
+	TrackSource    string            `json:"track_source,omitempty"`
+}
+
+// RoomEventReplay is the JSON payload of an EventReplayTopic message. Events are in the order they happened,
+// only the latest update of each participant and of the room metadata is kept. Replays are split in several
+// messages, Final is set on the last one.
+type RoomEventReplay struct {
+	Since  int64        `json:"since"`
+	Events []*RoomEvent `json:"events"`
+	Final  bool         `json:"final"`
+}
+
+// roomEventLog keeps the recent events of a room, bounded in number and age
+type roomEventLog struct {
+	conf config.EventReplayConfig
+
+	lock   sync.Mutex
+	events []*RoomEvent
+}
+
+func newRoomEventLog(conf config.EventReplayConfig) *roomEventLog {
+	return &roomEventLog{conf: conf}
+}
+
+func (l *roomEventLog) add(e *RoomEvent) {
+	if l.conf.MaxEvents <= 0 {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.events = append(l.events, e)
+	drop := max(len(l.events)-l.conf.MaxEvents, 0)
+	if l.conf.MaxAge > 0 {
+		oldest := e.Timestamp - l.conf.MaxAge.Milliseconds()
+		for drop < len(l.events) && l.events[drop].Timestamp < oldest {
+			drop++
+		}
+	}
+	l.events = l.events[drop:]
+}
+
+// since returns the condensed events that happened at or after the timestamp, in milliseconds
+func (l *roomEventLog) since(ts int64) []*RoomEvent {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var (
+		events      = make([]*RoomEvent, 0)
+		updated     = make(map[string]bool)
+		roomUpdated bool
+	)
+	for i := len(l.events) - 1; i >= 0 && l.events[i].Timestamp >= ts; i-- {
+		e := l.events[i]
+		switch e.Type {
+		case RoomEventParticipantUpdated:
+			if updated[e.Identity] {
+				continue
+			}
+			updated[e.Identity] = true
+		case RoomEventRoomMetadataChanged:
+			if roomUpdated {
+				continue
+			}
+			roomUpdated = true
+		}
+		events = append(events, e)
+	}
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events
+}
+
+func newParticipantEvent(t RoomEventType, p types.LocalParticipant) *RoomEvent {
+	e := &RoomEvent{
+		Type:           t,
+		Timestamp:      time.Now().UnixMilli(),
+		Identity:       string(p.Identity()),
+		ParticipantSid: string(p.ID()),
+		Kind:           p.Kind().String(),
+	}
+	if t != RoomEventParticipantLeft {
+		pi := p.ToProto()
+		e.Name = pi.Name
+		e.Metadata = pi.Metadata
+		e.Attributes = pi.Attributes
+	}
+	return e
+}
+
+func newTrackEvent(t RoomEventType, p types.LocalParticipant, track types.MediaTrack) *RoomEvent {
+	return &RoomEvent{
+		Type:           t,
+		Timestamp:      time.Now().UnixMilli(),
+		Identity:       string(p.Identity()),
+		ParticipantSid: string(p.ID()),
+		Kind:           p.Kind().String(),
+		TrackSid:       string(track.ID()),
+		TrackName:      track.Name(),
+		TrackType:      track.Kind().String(),
+		TrackSource:    track.Source().String(),
+	}
+}
+
+func (r *Room) recordEvent(e *RoomEvent) {
+	r.eventLog.add(e)
+	if recorder := r.archive.Load(); recorder != nil {
+		recorder.addEvent(e)
+	}
+}
+
+// sendEventReplay sends the events of the window asked for by the participant in its attributes
+func (r *Room) sendEventReplay(p types.LocalParticipant) {
+	grants := p.ClaimGrants()
+	if grants == nil || grants.Attributes[EventReplayAttribute] == "" || r.eventLog.conf.MaxEvents <= 0 {
+		return
+	}
+	seconds, err := strconv.Atoi(grants.Attributes[EventReplayAttribute])
+	if err != nil || seconds <= 0 {
+		p.GetLogger().Infow("ignoring invalid event replay window", "value", grants.Attributes[EventReplayAttribute])
+		return
+	}
+
+	since := time.Now().Add(-time.Duration(seconds) * time.Second).UnixMilli()
+	events := r.eventLog.since(since)
+	for {
+		replay := &RoomEventReplay{Since: since, Events: events[:min(len(events), eventReplayBatchSize)]}
+		events = events[len(replay.Events):]
+		replay.Final = len(events) == 0
+
+		dp, err := eventReplayPacket(replay)
+		if err != nil {
+			r.Logger.Errorw("could not encode event replay", err)
+			return
+		}
+		dp.DestinationIdentities = []string{string(p.Identity())}
+		BroadcastDataPacketForRoom(r, nil, livekit.DataPacket_RELIABLE, dp, r.Logger)
+		if replay.Final {
+			return
+		}
+	}
+}
+
+func eventReplayPacket(replay *RoomEventReplay) (*livekit.DataPacket, error) {
+	payload, err := json.Marshal(replay)
+	if err != nil {
+		return nil, err
+	}
+	topic := EventReplayTopic
+	return &livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload: payload,
+				Topic:   &topic,
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,249 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"net"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// addresses of the host, matched against the networks of external IP mappings
+var interfaceAddrs = net.InterfaceAddrs
+
+// ICEPolicy restricts the candidates of the peer connections of this node as set in config.ICEPolicyConfig.
+// The policy can be replaced at runtime, peer connections use the one in place when they are created.
+type ICEPolicy struct {
+	lock  sync.RWMutex
+	conf  config.ICEPolicyConfig
+	rules *iceRules
+}
+
+type iceRules struct {
+	allowedInterfaces     map[string]struct{}
+	deniedInterfaces      map[string]struct{}
+	allowedSubnets        []*net.IPNet
+	deniedSubnets         []*net.IPNet
+	nat1To1IPs            []string
+	relayOnlyRooms        []string
+	relayOnlyParticipants []string
+}
+
+// NewICEPolicy expects a validated config, invalid networks are skipped
+func NewICEPolicy(conf config.ICEPolicyConfig) *ICEPolicy {
+	return &ICEPolicy{
+		conf:  conf,
+		rules: newICERules(conf),
+	}
+}
+
+func newICERules(conf config.ICEPolicyConfig) *iceRules {
+	r := &iceRules{
+		allowedInterfaces:     make(map[string]struct{}),
+		deniedInterfaces:      make(map[string]struct{}),
+		allowedSubnets:        parseSubnets(conf.AllowedSubnets),
+		deniedSubnets:         parseSubnets(conf.DeniedSubnets),
+		relayOnlyRooms:        conf.RelayOnlyRooms,
+		relayOnlyParticipants: conf.RelayOnlyParticipants,
+	}
+	for _, name := range conf.AllowedInterfaces {
+		r.allowedInterfaces[name] = struct{}{}
+	}
+	for _, name := range conf.DeniedInterfaces {
+		r.deniedInterfaces[name] = struct{}{}
+	}
+
+	if len(conf.ExternalIPs) == 0 {
+		return r
+	}
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		logger.Warnw("could not list host addresses, external ip mappings are ignored", err)
+		return r
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		for _, mapping := range conf.ExternalIPs {
+			if _, network, err := net.ParseCIDR(mapping.Subnet); err == nil && network.Contains(ipNet.IP) {
+				r.nat1To1IPs = append(r.nat1To1IPs, mapping.ExternalIP+"/"+ipNet.IP.String())
+				break
+			}
+		}
+	}
+	return r
+}
+
+func parseSubnets(cidrs []string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+func (p *ICEPolicy) Config() config.ICEPolicyConfig {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.conf
+}
+
+// Update replaces the policy, peer connections which already exist keep their candidates
+func (p *ICEPolicy) Update(conf config.ICEPolicyConfig) error {
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+	rules := newICERules(conf)
+
+	p.lock.Lock()
+	p.conf = conf
+	p.rules = rules
+	p.lock.Unlock()
+
+	logger.Infow("updated ice policy",
+		"allowedInterfaces", conf.AllowedInterfaces,
+		"deniedInterfaces", conf.DeniedInterfaces,
+		"allowedSubnets", conf.AllowedSubnets,
+		"deniedSubnets", conf.DeniedSubnets,
+		"nat1To1IPs", rules.nat1To1IPs,
+		"relayOnlyRooms", conf.RelayOnlyRooms,
+		"relayOnlyParticipants", conf.RelayOnlyParticipants,
+	)
+	return nil
+}
+
+func (p *ICEPolicy) getRules() *iceRules {
+	if p == nil {
+		return nil
+	}
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.rules
+}
+
+// IsRelayOnly returns true when the clients of a participant must only use relay candidates
+func (p *ICEPolicy) IsRelayOnly(roomName livekit.RoomName, identity livekit.ParticipantIdentity) bool {
+	r := p.getRules()
+	if r == nil {
+		return false
+	}
+	for _, pattern := range r.relayOnlyRooms {
+		if ok, _ := path.Match(pattern, string(roomName)); ok {
+			return true
+		}
+	}
+	for _, pattern := range r.relayOnlyParticipants {
+		if ok, _ := path.Match(pattern, string(identity)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// configure sets the interface and address filters of the policy on the setting engine of a peer connection,
+// and adds its external IP mappings to nat1To1IPs. It returns the address filter it set, if any, and the
+// NAT 1:1 mappings of the peer connection.
+func (p *ICEPolicy) configure(se *webrtc.SettingEngine, nat1To1IPs []string, lgr logger.Logger) (func(net.IP) bool, []string) {
+	r := p.getRules()
+	if r == nil {
+		return nil, nat1To1IPs
+	}
+
+	if len(r.allowedInterfaces) != 0 || len(r.deniedInterfaces) != 0 {
+		se.SetInterfaceFilter(r.isInterfaceAllowed)
+	}
+	var ipFilter func(net.IP) bool
+	if len(r.allowedSubnets) != 0 || len(r.deniedSubnets) != 0 {
+		ipFilter = r.isIPAllowed
+		se.SetIPFilter(ipFilter)
+	}
+	if len(r.nat1To1IPs) != 0 {
+		merged, ok := mergeNAT1To1IPs(nat1To1IPs, r.nat1To1IPs)
+		if !ok {
+			lgr.Warnw("external ip mappings of the ice policy cannot be combined with a single external ip", nil, "ips", nat1To1IPs)
+		} else {
+			nat1To1IPs = merged
+			se.SetNAT1To1IPs(nat1To1IPs, webrtc.ICECandidateTypeHost)
+		}
+	}
+	return ipFilter, nat1To1IPs
+}
+
+func (r *iceRules) isInterfaceAllowed(name string) bool {
+	if _, ok := r.deniedInterfaces[name]; ok {
+		return false
+	}
+	if len(r.allowedInterfaces) == 0 {
+		return true
+	}
+	_, ok := r.allowedInterfaces[name]
+	return ok
+}
+
+func (r *iceRules) isIPAllowed(ip net.IP) bool {
+	for _, network := range r.deniedSubnets {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	if len(r.allowedSubnets) == 0 {
+		return true
+	}
+	for _, network := range r.allowedSubnets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeNAT1To1IPs adds "external/local" mappings to the ones of the config, replacing those of the same local
+// addresses. Mappings can only be combined when every one of them names its local address.
+func mergeNAT1To1IPs(base []string, extra []string) ([]string, bool) {
+	localIP := func(mapping string) string {
+		if ips := strings.Split(mapping, "/"); len(ips) == 2 {
+			return ips[1]
+		}
+		return ""
+	}
+
+	replaced := make(map[string]struct{}, len(extra))
+	for _, mapping := range extra {
+		replaced[localIP(mapping)] = struct{}{}
+	}
+	merged := make([]string, 0, len(base)+len(extra))
+	for _, mapping := range base {
+		local := localIP(mapping)
+		if local == "" {
+			return nil, false
+		}
+		if _, ok := replaced[local]; !ok {
+			merged = append(merged, mapping)
+		}
+	}
+	return append(merged, extra...), true
+}
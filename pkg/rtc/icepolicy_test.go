@@ -0,0 +1,78 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestICEPolicy(t *testing.T) {
+	t.Run("filters", func(t *testing.T) {
+		r := NewICEPolicy(config.ICEPolicyConfig{
+			DeniedInterfaces: []string{"docker0"},
+			AllowedSubnets:   []string{"10.0.0.0/8"},
+			DeniedSubnets:    []string{"10.99.0.0/16"},
+		}).getRules()
+		require.True(t, r.isInterfaceAllowed("eth0"))
+		require.False(t, r.isInterfaceAllowed("docker0"))
+		require.True(t, r.isIPAllowed(net.ParseIP("10.1.2.3")))
+		require.False(t, r.isIPAllowed(net.ParseIP("10.99.2.3")))
+		require.False(t, r.isIPAllowed(net.ParseIP("192.168.1.2")))
+	})
+
+	t.Run("relay only", func(t *testing.T) {
+		p := NewICEPolicy(config.ICEPolicyConfig{
+			RelayOnlyRooms:        []string{"secure-*"},
+			RelayOnlyParticipants: []string{"guest-*"},
+		})
+		require.True(t, p.IsRelayOnly("secure-1", "alice"))
+		require.True(t, p.IsRelayOnly("room", "guest-1"))
+		require.False(t, p.IsRelayOnly("room", "alice"))
+
+		require.Error(t, p.Update(config.ICEPolicyConfig{RelayOnlyRooms: []string{"["}}))
+		require.True(t, p.IsRelayOnly("secure-1", "alice"))
+		require.NoError(t, p.Update(config.ICEPolicyConfig{}))
+		require.False(t, p.IsRelayOnly("secure-1", "alice"))
+
+		var unset *ICEPolicy
+		require.False(t, unset.IsRelayOnly("secure-1", "alice"))
+	})
+
+	t.Run("external ips", func(t *testing.T) {
+		defer func(f func() ([]net.Addr, error)) { interfaceAddrs = f }(interfaceAddrs)
+		interfaceAddrs = func() ([]net.Addr, error) {
+			return []net.Addr{
+				&net.IPNet{IP: net.ParseIP("10.1.0.5"), Mask: net.CIDRMask(16, 32)},
+				&net.IPNet{IP: net.ParseIP("10.2.0.5"), Mask: net.CIDRMask(16, 32)},
+			}, nil
+		}
+		r := NewICEPolicy(config.ICEPolicyConfig{
+			ExternalIPs: []config.ICEExternalIPMapping{{Subnet: "10.1.0.0/16", ExternalIP: "203.0.113.10"}},
+		}).getRules()
+		require.Equal(t, []string{"203.0.113.10/10.1.0.5"}, r.nat1To1IPs)
+
+		merged, ok := mergeNAT1To1IPs([]string{"198.51.100.1/10.1.0.5", "10.2.0.5/10.2.0.5"}, r.nat1To1IPs)
+		require.True(t, ok)
+		require.Equal(t, []string{"10.2.0.5/10.2.0.5", "203.0.113.10/10.1.0.5"}, merged)
+
+		_, ok = mergeNAT1To1IPs([]string{"198.51.100.1"}, r.nat1To1IPs)
+		require.False(t, ok)
+	})
+}
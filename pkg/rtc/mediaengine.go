@@ -68,6 +68,24 @@ func registerCodecs(me *webrtc.MediaEngine, codecs []*livekit.Codec, rtcpFeedbac
 		}
 	}
 
+	// G.711 and G.722 for SIP participants, only registered when listed in enabled codecs
+	for _, codec := range []webrtc.RTPCodecParameters{
+		sfu.PCMUCodecParameters,
+		sfu.PCMACodecParameters,
+		{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeG722, ClockRate: 8000},
+			PayloadType:        9,
+		},
+	} {
+		if !IsCodecEnabled(codecs, codec.RTPCodecCapability) {
+			continue
+		}
+		codec.RTCPFeedback = rtcpFeedback.Audio
+		if err := me.RegisterCodec(codec, webrtc.RTPCodecTypeAudio); err != nil {
+			return err
+		}
+	}
+
 	rtxEnabled := IsCodecEnabled(codecs, videoRTX)
 
 	h264HighProfileFmtp := "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=640032"
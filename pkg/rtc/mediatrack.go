@@ -73,6 +73,7 @@ type MediaTrackParams struct {
 	SimTracks             map[uint32]SimulcastTrackInfo
 	OnRTCP                func([]rtcp.Packet)
 	ForwardStats          *sfu.ForwardStats
+	G711Transcoder        *sfu.G711Transcoder
 	OnTrackEverSubscribed func(livekit.TrackID)
 }
 
@@ -90,6 +91,7 @@ func NewMediaTrack(params MediaTrackParams, ti *livekit.TrackInfo) *MediaTrack {
 		ReceiverConfig:      params.ReceiverConfig,
 		SubscriberConfig:    params.SubscriberConfig,
 		AudioConfig:         params.AudioConfig,
+		G711Transcoder:      params.G711Transcoder,
 		Telemetry:           params.Telemetry,
 		Logger:              params.Logger,
 	}, ti)
@@ -156,6 +158,13 @@ func (t *MediaTrack) OnSubscribedMaxQualityChange(
 	t.dynacastManager.OnSubscribedMaxQualityChange(handler)
 }
 
+// SetMaxQuality caps the layers requested from the publisher of a video track
+func (t *MediaTrack) SetMaxQuality(quality livekit.VideoQuality) {
+	if t.dynacastManager != nil {
+		t.dynacastManager.SetMaxQuality(quality)
+	}
+}
+
 func (t *MediaTrack) NotifySubscriberNodeMaxQuality(nodeID livekit.NodeID, qualities []types.SubscribedCodecQuality) {
 	if t.dynacastManager != nil {
 		t.dynacastManager.NotifySubscriberNodeMaxQuality(nodeID, qualities)
@@ -286,6 +295,9 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 			sfu.WithLoadBalanceThreshold(20),
 			sfu.WithStreamTrackers(),
 			sfu.WithForwardStats(t.params.ForwardStats),
+			sfu.WithKeyFrameCache(t.params.VideoConfig.KeyFrameCacheSize),
+			sfu.WithSubscribeRamp(t.params.VideoConfig.SubscribeRamp),
+			sfu.WithG711Transcoder(t.params.G711Transcoder),
 		)
 		newWR.OnCloseHandler(func() {
 			t.MediaTrackReceiver.SetClosing()
@@ -91,6 +91,7 @@ type MediaTrackReceiverParams struct {
 	ReceiverConfig      ReceiverConfig
 	SubscriberConfig    DirectionConfig
 	AudioConfig         sfu.AudioConfig
+	G711Transcoder      *sfu.G711Transcoder
 	Telemetry           telemetry.TelemetryService
 	Logger              logger.Logger
 }
@@ -477,6 +478,7 @@ func (t *MediaTrackReceiver) AddSubscriber(sub types.LocalParticipant) (types.Su
 		UpstreamCodecs: potentialCodecs,
 		Logger:         tLogger,
 		DisableRed:     t.TrackInfo().GetDisableRed() || !t.params.AudioConfig.ActiveREDEncoding,
+		G711Transcoder: t.params.G711Transcoder,
 	})
 	subTrack, err := t.MediaTrackSubscriptions.AddSubscriber(sub, wr)
 
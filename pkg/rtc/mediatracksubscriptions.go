@@ -213,6 +213,8 @@ func (t *MediaTrackSubscriptions) AddSubscriber(sub types.LocalParticipant, wr *
 		if t.onSubscriberMaxQualityChange != nil {
 			t.onSubscriberMaxQualityChange(dt.SubscriberID(), dt.Codec(), layer)
 		}
+		// also fired when the forwarder switches layers
+		subTrack.CheckLayerDowngrade()
 	})
 
 	downTrack.OnRttUpdate(func(_ *sfu.DownTrack, rtt uint32) {
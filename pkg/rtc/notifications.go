@@ -0,0 +1,140 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// NotificationsTopic is the topic of the server generated data messages carrying a types.Notification
+const NotificationsTopic = "lk.notifications"
+
+// notifier deduplicates the notifications sent to a participant by key, and limits their rate
+type notifier struct {
+	conf config.NotificationsConfig
+
+	lock       sync.Mutex
+	tokens     float64
+	refilledAt time.Time
+	keys       map[string]*notifiedKey
+}
+
+type notifiedKey struct {
+	sentAt     time.Time
+	suppressed int
+}
+
+func newNotifier(conf config.NotificationsConfig) *notifier {
+	return &notifier{
+		conf:   conf,
+		tokens: float64(conf.Burst),
+		keys:   make(map[string]*notifiedKey),
+	}
+}
+
+// admit returns the notification to send, or nil when it is suppressed. Notifications suppressed by the rate
+// limit are counted against their key as well.
+func (n *notifier) admit(notification *types.Notification, now time.Time) *types.Notification {
+	if n.conf.RateLimit <= 0 {
+		return nil
+	}
+	key := notification.Key
+	if key == "" {
+		key = notification.Code
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	for k, nk := range n.keys {
+		if nk.suppressed == 0 && now.Sub(nk.sentAt) >= n.conf.DedupWindow {
+			delete(n.keys, k)
+		}
+	}
+
+	nk := n.keys[key]
+	if nk != nil && now.Sub(nk.sentAt) < n.conf.DedupWindow {
+		nk.suppressed++
+		return nil
+	}
+
+	if !n.refilledAt.IsZero() {
+		n.tokens = min(float64(n.conf.Burst), n.tokens+now.Sub(n.refilledAt).Seconds()*n.conf.RateLimit)
+	}
+	n.refilledAt = now
+	if n.tokens < 1 {
+		if nk != nil {
+			nk.suppressed++
+		} else {
+			// the first notification of the key is reported with the next one
+			n.keys[key] = &notifiedKey{suppressed: 1}
+		}
+		return nil
+	}
+	n.tokens--
+
+	sent := *notification
+	sent.Key = key
+	if nk != nil {
+		sent.Suppressed = nk.suppressed
+	}
+	n.keys[key] = &notifiedKey{sentAt: now}
+	return &sent
+}
+
+// SendNotification sends an error or warning to the participant on the NotificationsTopic. Notifications of the
+// same key are deduplicated and their rate is limited, as set in config.NotificationsConfig.
+func (p *ParticipantImpl) SendNotification(notification *types.Notification) {
+	sent := p.notifier.admit(notification, time.Now())
+	prometheus.RecordNotification(notification.Code, sent != nil)
+	if sent == nil {
+		return
+	}
+
+	dpData, err := notificationPacket(sent)
+	if err != nil {
+		p.params.Logger.Warnw("could not marshal notification", err)
+		return
+	}
+	if err = p.SendDataPacket(livekit.DataPacket_RELIABLE, dpData); err != nil {
+		p.params.Logger.Debugw("could not send notification", "error", err, "code", sent.Code)
+	}
+}
+
+// notificationPacket builds the data packet of a notification, it is originated by the server
+func notificationPacket(notification *types.Notification) ([]byte, error) {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&livekit.DataPacket{
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload: payload,
+				Topic:   proto.String(NotificationsTopic),
+			},
+		},
+	})
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+func TestNotifier(t *testing.T) {
+	denied := &types.Notification{
+		Severity: types.NotificationSeverityError,
+		Code:     types.NotificationPublishDenied,
+		Message:  "denied",
+	}
+
+	t.Run("dedup", func(t *testing.T) {
+		n := newNotifier(config.NotificationsConfig{DedupWindow: 10 * time.Second, RateLimit: 1, Burst: 5})
+		now := time.Now()
+
+		sent := n.admit(denied, now)
+		require.NotNil(t, sent)
+		require.Equal(t, types.NotificationPublishDenied, sent.Key)
+		require.Zero(t, sent.Suppressed)
+
+		require.Nil(t, n.admit(denied, now.Add(time.Second)))
+		require.Nil(t, n.admit(denied, now.Add(2*time.Second)))
+
+		// other keys are not deduplicated with it
+		other := *denied
+		other.Key = "other"
+		require.NotNil(t, n.admit(&other, now.Add(2*time.Second)))
+
+		sent = n.admit(denied, now.Add(10*time.Second))
+		require.NotNil(t, sent)
+		require.Equal(t, 2, sent.Suppressed)
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		n := newNotifier(config.NotificationsConfig{RateLimit: 1, Burst: 2})
+		now := time.Now()
+		notification := func(key string) *types.Notification {
+			return &types.Notification{Code: types.NotificationCodecNotAllowed, Key: key}
+		}
+
+		require.NotNil(t, n.admit(notification("a"), now))
+		require.NotNil(t, n.admit(notification("b"), now))
+		require.Nil(t, n.admit(notification("c"), now))
+
+		sent := n.admit(notification("c"), now.Add(time.Second))
+		require.NotNil(t, sent)
+		require.Equal(t, 1, sent.Suppressed)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		n := newNotifier(config.NotificationsConfig{})
+		require.Nil(t, n.admit(denied, time.Now()))
+	})
+}
@@ -123,22 +123,24 @@ type ParticipantParams struct {
 	PLIThrottleConfig       sfu.PLIThrottleConfig
 	CongestionControlConfig config.CongestionControlConfig
 	// codecs that are enabled for this room
-	PublishEnabledCodecs           []*livekit.Codec
-	SubscribeEnabledCodecs         []*livekit.Codec
-	Logger                         logger.Logger
-	SimTracks                      map[uint32]SimulcastTrackInfo
-	Grants                         *auth.ClaimGrants
-	InitialVersion                 uint32
-	ClientConf                     *livekit.ClientConfiguration
-	ClientInfo                     ClientInfo
-	Region                         string
-	Migration                      bool
-	Reconnect                      bool
-	AdaptiveStream                 bool
-	AllowTCPFallback               bool
-	TCPFallbackRTTThreshold        int
-	AllowUDPUnstableFallback       bool
-	TURNSEnabled                   bool
+	PublishEnabledCodecs     []*livekit.Codec
+	SubscribeEnabledCodecs   []*livekit.Codec
+	Logger                   logger.Logger
+	SimTracks                map[uint32]SimulcastTrackInfo
+	Grants                   *auth.ClaimGrants
+	InitialVersion           uint32
+	ClientConf               *livekit.ClientConfiguration
+	ClientInfo               ClientInfo
+	Region                   string
+	Migration                bool
+	Reconnect                bool
+	AdaptiveStream           bool
+	AllowTCPFallback         bool
+	TCPFallbackRTTThreshold  int
+	AllowUDPUnstableFallback bool
+	TURNSEnabled             bool
+	// clients only use relay candidates, as set by the ice policy of the node
+	ForceRelay                     bool
 	GetParticipantInfo             func(pID livekit.ParticipantID) *livekit.ParticipantInfo
 	GetRegionSettings              func(ip string) *livekit.RegionSettings
 	GetSubscriberForwarderState    func(p types.LocalParticipant) (map[livekit.TrackID]*livekit.RTPForwarderState, error)
@@ -156,11 +158,24 @@ type ParticipantParams struct {
 	PlayoutDelay                   *livekit.PlayoutDelay
 	SyncStreams                    bool
 	ForwardStats                   *sfu.ForwardStats
+	G711Transcoder                 *sfu.G711Transcoder
 	DisableSenderReportPassThrough bool
 	MetricConfig                   metric.MetricConfig
 	UseSendSideBWEInterceptor      bool
 	UseSendSideBWE                 bool
 	UseOneShotSignallingMode       bool
+	// how long the participant can resume after its connection failed, disconnectCleanupDuration when unset
+	ResumeWindow time.Duration
+	// optional check run before a new track is accepted for publishing, ctx is cancelled when the participant leaves
+	TrackPublishPolicy func(ctx context.Context, req *livekit.AddTrackRequest) error
+	// optional, called when the participant is warned that a video track exceeds its declared bitrate
+	OnBitrateOvershoot func(p types.LocalParticipant, ti *livekit.TrackInfo, overshoot *BitrateOvershoot)
+	// cap of the total bitrate the participant publishes, 0 is uncapped. MaxPublishBitrateAttribute may lower it
+	MaxPublishBitrate int64
+	// optional, called when the connection quality of the participant, the lowest of its tracks, changes
+	OnConnectionQualityChanged func(p types.LocalParticipant, prev, curr livekit.ConnectionQuality)
+	// limits of the notifications sent to the participant, none are sent with a zero rate limit
+	NotificationsConfig config.NotificationsConfig
 }
 
 type ParticipantImpl struct {
@@ -182,6 +197,11 @@ type ParticipantImpl struct {
 	grants      atomic.Pointer[auth.ClaimGrants]
 	isPublisher atomic.Bool
 
+	// send rate a publisher overshooting its declared bitrates is clamped to, 0 when it is not
+	bitrateOvershootClamp atomic.Int64
+
+	notifier *notifier
+
 	sessionStartRecorded atomic.Bool
 	lastActiveAt         atomic.Pointer[time.Time]
 	// when first connected
@@ -252,6 +272,7 @@ type ParticipantImpl struct {
 	supervisor *supervisor.ParticipantSupervisor
 
 	tracksQuality map[livekit.TrackID]livekit.ConnectionQuality
+	quality       livekit.ConnectionQuality
 
 	metricTimestamper *metric.MetricTimestamper
 
@@ -289,12 +310,14 @@ func NewParticipant(params ParticipantParams) (*ParticipantImpl, error) {
 			params.Telemetry,
 		),
 		tracksQuality: make(map[livekit.TrackID]livekit.ConnectionQuality),
+		quality:       livekit.ConnectionQuality_EXCELLENT,
 		metricTimestamper: metric.NewMetricTimestamper(metric.MetricTimestamperParams{
 			Config: params.MetricConfig.Timestamper,
 			Logger: params.Logger,
 		}),
 		pubLogger: params.Logger.WithComponent(sutils.ComponentPub),
 		subLogger: params.Logger.WithComponent(sutils.ComponentSub),
+		notifier:  newNotifier(params.NotificationsConfig),
 	}
 	if !params.DisableSupervisor {
 		p.supervisor = supervisor.NewParticipantSupervisor(supervisor.ParticipantSupervisorParams{Logger: params.Logger})
@@ -461,7 +484,7 @@ func (p *ParticipantImpl) CheckMetadataLimits(
 		return ErrAttributesExceedsLimits
 	}
 
-	return nil
+	return ValidateDeviceAttributes(attributes)
 }
 
 // SetName attaches name to the participant
@@ -546,6 +569,13 @@ func (p *ParticipantImpl) SetAttributes(attrs map[string]string) {
 	onClaimsChanged := p.onClaimsChanged
 	p.lock.Unlock()
 
+	if _, ok := attrs[SubscriberPriorityAttribute]; ok {
+		frameDecimation := IsFrameDecimated(grants.Attributes)
+		for _, subTrack := range p.SubscriptionManager.GetSubscribedTracks() {
+			subTrack.DownTrack().SetFrameDecimation(frameDecimation)
+		}
+	}
+
 	if onParticipantUpdate != nil {
 		onParticipantUpdate(p)
 	}
@@ -591,8 +621,13 @@ func (p *ParticipantImpl) SetPermission(permission *livekit.ParticipantPermissio
 	for _, track := range p.GetPublishedTracks() {
 		if !grants.Video.GetCanPublishSource(track.Source()) {
 			p.removePublishedTrack(track)
+			p.notifySourceRevoked(track.ID(), track.Source())
 		}
 	}
+	for _, ti := range p.removeDisallowedPendingTracks(grants) {
+		p.sendTrackUnpublished(livekit.TrackID(ti.Sid))
+		p.notifySourceRevoked(livekit.TrackID(ti.Sid), ti.Source)
+	}
 
 	if canSubscribe {
 		// reconcile everything
@@ -616,6 +651,47 @@ func (p *ParticipantImpl) SetPermission(permission *livekit.ParticipantPermissio
 	return true
 }
 
+// removeDisallowedPendingTracks drops the tracks requested but not published yet whose source is not allowed
+// by the grants, and returns them
+func (p *ParticipantImpl) removeDisallowedPendingTracks(grants *auth.ClaimGrants) []*livekit.TrackInfo {
+	var removed []*livekit.TrackInfo
+	p.pendingTracksLock.Lock()
+	for cid, pti := range p.pendingTracks {
+		trackInfos := pti.trackInfos[:0]
+		for _, ti := range pti.trackInfos {
+			if grants.Video.GetCanPublishSource(ti.Source) {
+				trackInfos = append(trackInfos, ti)
+			} else {
+				removed = append(removed, ti)
+			}
+		}
+		if len(trackInfos) == 0 {
+			delete(p.pendingTracks, cid)
+		} else {
+			pti.trackInfos = trackInfos
+		}
+	}
+	p.pendingTracksLock.Unlock()
+
+	for _, ti := range removed {
+		p.pubLogger.Infow("pending track removed, source is not allowed", "trackID", ti.Sid, "source", ti.Source)
+		if p.supervisor != nil {
+			p.supervisor.RemovePublication(livekit.TrackID(ti.Sid))
+		}
+	}
+	return removed
+}
+
+func (p *ParticipantImpl) notifySourceRevoked(trackID livekit.TrackID, source livekit.TrackSource) {
+	p.SendNotification(&types.Notification{
+		Severity: types.NotificationSeverityError,
+		Code:     types.NotificationPublishNotAllowed,
+		Message:  fmt.Sprintf("permission to publish tracks of source %s was revoked", source),
+		Key:      types.NotificationPublishNotAllowed + ":" + string(trackID),
+		TrackSid: string(trackID),
+	})
+}
+
 func (p *ParticipantImpl) CanSkipBroadcast() bool {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
@@ -822,9 +898,18 @@ func (p *ParticipantImpl) synthesizeAddTrackRequests(offer webrtc.SessionDescrip
 		return err
 	}
 
+	// tracks are named after the participant, as set by its token
+	trackName := string(p.Identity())
+	if grants := p.ClaimGrants(); grants != nil && grants.Name != "" {
+		trackName = grants.Name
+	}
 	for _, m := range parsed.MediaDescriptions {
-		if !strings.EqualFold(m.MediaName.Media, "audio") {
-			// ONE-SHOT-SIGNALLING-MODE-TODO: support video
+		isAudio := strings.EqualFold(m.MediaName.Media, "audio")
+		if !isAudio && !strings.EqualFold(m.MediaName.Media, "video") {
+			continue
+		}
+		if isReceiveOnly(m) {
+			// sections of the tracks subscribed to
 			continue
 		}
 
@@ -851,20 +936,104 @@ func (p *ParticipantImpl) synthesizeAddTrackRequests(offer webrtc.SessionDescrip
 			trackID = guid.New(utils.TrackPrefix)
 		}
 
-		req := &livekit.AddTrackRequest{
-			Cid:        trackID,
-			Name:       "synthesized-microphone",
-			Source:     livekit.TrackSource_MICROPHONE,
-			Type:       livekit.TrackType_AUDIO,
-			DisableDtx: true,
-			Stereo:     false,
-			Stream:     "camera",
+		var req *livekit.AddTrackRequest
+		if isAudio {
+			req = &livekit.AddTrackRequest{
+				Cid:        trackID,
+				Name:       trackName,
+				Source:     livekit.TrackSource_MICROPHONE,
+				Type:       livekit.TrackType_AUDIO,
+				DisableDtx: true,
+				Stereo:     false,
+				Stream:     "camera",
+			}
+		} else {
+			layers, err := synthesizeVideoLayers(m)
+			if err != nil {
+				return err
+			}
+			req = &livekit.AddTrackRequest{
+				Cid:    trackID,
+				Name:   trackName,
+				Source: livekit.TrackSource_CAMERA,
+				Type:   livekit.TrackType_VIDEO,
+				Layers: layers,
+				Stream: "camera",
+			}
+			if len(layers) != 0 {
+				req.Width, req.Height = layers[len(layers)-1].Width, layers[len(layers)-1].Height
+			}
 		}
 		p.AddTrack(req)
 	}
 	return nil
 }
 
+func isReceiveOnly(m *sdp.MediaDescription) bool {
+	for _, a := range m.Attributes {
+		if a.Key == sdp.AttrKeyRecvOnly || a.Key == sdp.AttrKeyInactive {
+			return true
+		}
+	}
+	return false
+}
+
+// synthesizeVideoLayers returns the simulcast layers of a video section from its send rids, lowest first,
+// sized by their max-width and max-height restrictions when given. It returns no layers without simulcast.
+func synthesizeVideoLayers(m *sdp.MediaDescription) ([]*livekit.VideoLayer, error) {
+	type ridLayer struct {
+		width, height uint32
+	}
+	rids := make(map[string]ridLayer)
+	for _, a := range m.Attributes {
+		if a.Key != "rid" {
+			continue
+		}
+		// <rid> send [restrictions]
+		fields := strings.Fields(a.Value)
+		if len(fields) < 2 || fields[1] != "send" {
+			continue
+		}
+		switch fields[0] {
+		case buffer.QuarterResolution, buffer.HalfResolution, buffer.FullResolution:
+		default:
+			return nil, ErrUnsupportedSimulcastRid
+		}
+		var layer ridLayer
+		if len(fields) > 2 {
+			for _, restriction := range strings.Split(fields[2], ";") {
+				name, value, _ := strings.Cut(restriction, "=")
+				v, _ := strconv.ParseUint(value, 10, 32)
+				switch name {
+				case "max-width":
+					layer.width = uint32(v)
+				case "max-height":
+					layer.height = uint32(v)
+				}
+			}
+		}
+		rids[fields[0]] = layer
+	}
+
+	// layers are told apart by rid, a higher rid requires the lower ones
+	var layers []*livekit.VideoLayer
+	for _, rid := range []string{buffer.QuarterResolution, buffer.HalfResolution, buffer.FullResolution} {
+		layer, ok := rids[rid]
+		if !ok {
+			if len(layers) != len(rids) {
+				return nil, ErrUnsupportedSimulcastRid
+			}
+			break
+		}
+		layers = append(layers, &livekit.VideoLayer{
+			Quality: livekit.VideoQuality(len(layers)),
+			Width:   layer.width,
+			Height:  layer.height,
+		})
+	}
+	return layers, nil
+}
+
 // HandleOffer an offer from remote participant, used when clients make the initial connection
 func (p *ParticipantImpl) HandleOffer(offer webrtc.SessionDescription) error {
 	p.pubLogger.Debugw("received offer", "transport", livekit.SignalTarget_PUBLISHER, "offer", offer)
@@ -978,9 +1147,57 @@ func (p *ParticipantImpl) handleMigrateTracks() {
 func (p *ParticipantImpl) AddTrack(req *livekit.AddTrackRequest) {
 	if !p.CanPublishSource(req.Source) {
 		p.pubLogger.Warnw("no permission to publish track", nil)
+		p.SendNotification(&types.Notification{
+			Severity: types.NotificationSeverityError,
+			Code:     types.NotificationPublishNotAllowed,
+			Message:  fmt.Sprintf("no permission to publish tracks of source %s", req.Source),
+			Key:      types.NotificationPublishNotAllowed + ":" + req.Source.String(),
+		})
+		return
+	}
+
+	if req.Sid == "" && p.params.TrackPublishPolicy != nil {
+		// policy check may take a while, keep it off the signal handling path
+		go p.addTrackWithPolicy(req)
+		return
+	}
+
+	p.addTrack(req)
+}
+
+func (p *ParticipantImpl) addTrackWithPolicy(req *livekit.AddTrackRequest) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-p.disconnected:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := p.params.TrackPublishPolicy(ctx, req)
+	if p.IsClosed() {
 		return
 	}
+	if err != nil {
+		p.pubLogger.Warnw("track publish denied by policy", err, "cid", req.Cid, "source", req.Source)
+		if err := p.sendTrackPublishDenied(req.Cid, err); err != nil {
+			p.pubLogger.Warnw("could not send track publish denied", err, "cid", req.Cid)
+		}
+		p.SendNotification(&types.Notification{
+			Severity: types.NotificationSeverityError,
+			Code:     types.NotificationPublishDenied,
+			Message:  fmt.Sprintf("publishing track %s was denied: %v", req.Cid, err),
+			Key:      types.NotificationPublishDenied + ":" + req.Source.String(),
+		})
+		return
+	}
+
+	p.addTrack(req)
+}
 
+func (p *ParticipantImpl) addTrack(req *livekit.AddTrackRequest) {
 	p.pendingTracksLock.Lock()
 	ti := p.addPendingTrackLocked(req)
 	p.pendingTracksLock.Unlock()
@@ -1311,8 +1528,17 @@ func (p *ParticipantImpl) GetConnectionQuality() *livekit.ConnectionQualityInfo
 			delete(p.tracksQuality, trackID)
 		}
 	}
+	prevQuality := p.quality
+	p.quality = minQuality
 	p.lock.Unlock()
 
+	if prevQuality != minQuality {
+		prometheus.RecordQualityTransition(prevQuality, minQuality)
+		if p.params.OnConnectionQualityChanged != nil {
+			p.params.OnConnectionQualityChanged(p, prevQuality, minQuality)
+		}
+	}
+
 	if minQuality == livekit.ConnectionQuality_LOST && !p.ProtocolVersion().SupportsConnectionQualityLost() {
 		minQuality = livekit.ConnectionQuality_POOR
 	}
@@ -1383,6 +1609,9 @@ func (p *ParticipantImpl) onTrackSubscribed(subTrack types.SubscribedTrack) {
 	if p.params.ClientInfo.FireTrackByRTPPacket() {
 		subTrack.DownTrack().SetActivePaddingOnMuteUpTrack()
 	}
+	if IsFrameDecimated(p.ClaimGrants().Attributes) {
+		subTrack.DownTrack().SetFrameDecimation(true)
+	}
 
 	subTrack.AddOnBind(func(err error) {
 		if err != nil {
@@ -1394,15 +1623,14 @@ func (p *ParticipantImpl) onTrackSubscribed(subTrack types.SubscribedTrack) {
 				dt.SeedState(sfu.DownTrackState{ForwarderState: p.getAndDeleteForwarderState(subTrack.ID())})
 				dt.SetConnected()
 			}
-			// ONE-SHOT-SIGNALLING-MODE-TODO: video support should add to publisher PC for congestion control
 		} else {
 			if p.TransportManager.HasSubscriberEverConnected() {
 				dt := subTrack.DownTrack()
 				dt.SeedState(sfu.DownTrackState{ForwarderState: p.getAndDeleteForwarderState(subTrack.ID())})
 				dt.SetConnected()
 			}
-			p.TransportManager.AddSubscribedTrack(subTrack)
 		}
+		p.TransportManager.AddSubscribedTrack(subTrack)
 	})
 }
 
@@ -1587,7 +1815,7 @@ func (p *ParticipantImpl) setupTransportManager() error {
 		if p.params.ClientConf == nil {
 			p.params.ClientConf = &livekit.ClientConfiguration{}
 		}
-		if iceConfig.PreferenceSubscriber == livekit.ICECandidateType_ICT_TLS {
+		if p.params.ForceRelay || iceConfig.PreferenceSubscriber == livekit.ICECandidateType_ICT_TLS {
 			p.params.ClientConf.ForceRelay = livekit.ClientConfigSetting_ENABLED
 		} else {
 			// UNSET indicates that clients could override RTCConfiguration to forceRelay
@@ -1761,7 +1989,15 @@ func (p *ParticipantImpl) handlePendingRemoteTracks() {
 }
 
 func (p *ParticipantImpl) onDataMessage(kind livekit.DataPacket_Kind, data []byte) {
-	if p.IsDisconnected() || !p.CanPublishData() {
+	if p.IsDisconnected() {
+		return
+	}
+	if !p.CanPublishData() {
+		p.SendNotification(&types.Notification{
+			Severity: types.NotificationSeverityWarning,
+			Code:     types.NotificationPublishDataNotAllowed,
+			Message:  "no permission to publish data, data packets are dropped",
+		})
 		return
 	}
 
@@ -1898,6 +2134,11 @@ func (p *ParticipantImpl) onPublisherInitialConnected() {
 	}
 
 	p.pubRTCPQueue.Start()
+
+	if p.params.VideoConfig.BitrateOvershoot.Factor > 0 {
+		go p.bitrateOvershootWorker()
+	}
+	go p.publishBitrateCapWorker()
 }
 
 func (p *ParticipantImpl) onSubscriberInitialConnected() {
@@ -1933,8 +2174,13 @@ func (p *ParticipantImpl) clearDisconnectTimer() {
 func (p *ParticipantImpl) setupDisconnectTimer() {
 	p.clearDisconnectTimer()
 
+	window := p.params.ResumeWindow
+	if window <= 0 {
+		window = disconnectCleanupDuration
+	}
+
 	p.lock.Lock()
-	p.disconnectTimer = time.AfterFunc(disconnectCleanupDuration, func() {
+	p.disconnectTimer = time.AfterFunc(window, func() {
 		p.clearDisconnectTimer()
 
 		if p.IsClosed() || p.IsDisconnected() {
@@ -2178,6 +2424,13 @@ func (p *ParticipantImpl) addPendingTrackLocked(req *livekit.AddTrackRequest) *l
 						"altCodec", altCodec,
 						"trackID", ti.Sid,
 					)
+					p.SendNotification(&types.Notification{
+						Severity: types.NotificationSeverityWarning,
+						Code:     types.NotificationCodecNotAllowed,
+						Message:  fmt.Sprintf("codec %s is not enabled in the room, %s is used instead", mime, altCodec),
+						Key:      types.NotificationCodecNotAllowed + ":" + mime,
+						TrackSid: ti.Sid,
+					})
 					// select an alternative MIME type that's generally supported
 					mime = altCodec
 				}
@@ -2476,6 +2729,7 @@ func (p *ParticipantImpl) addMediaTrack(signalCid string, sdpCid string, ti *liv
 		SimTracks:             p.params.SimTracks,
 		OnRTCP:                p.postRtcp,
 		ForwardStats:          p.params.ForwardStats,
+		G711Transcoder:        p.params.G711Transcoder,
 		OnTrackEverSubscribed: p.sendTrackHasBeenSubscribed,
 	}, ti)
 
@@ -2833,7 +3087,10 @@ func (p *ParticipantImpl) onSubscriptionError(trackID livekit.TrackID, fatal boo
 	case errors.Is(err, ErrTrackNotFound):
 		signalErr = livekit.SubscriptionError_SE_TRACK_NOTFOUND
 	}
+	p.SendSubscriptionError(trackID, signalErr, fatal)
+}
 
+func (p *ParticipantImpl) SendSubscriptionError(trackID livekit.TrackID, signalErr livekit.SubscriptionError, fatal bool) {
 	_ = p.writeMessage(&livekit.SignalResponse{
 		Message: &livekit.SignalResponse_SubscriptionResponse{
 			SubscriptionResponse: &livekit.SubscriptionResponse{
@@ -20,6 +20,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
@@ -218,6 +219,39 @@ func TestTrackPublishing(t *testing.T) {
 		})
 		require.Equal(t, 1, sink.WriteMessageCallCount())
 	})
+
+	t.Run("should remove pending tracks of revoked sources", func(t *testing.T) {
+		p := newParticipantForTest("test")
+		sink := p.params.Sink.(*routingfakes.FakeMessageSink)
+		p.AddTrack(&livekit.AddTrackRequest{
+			Cid:    "mic",
+			Name:   "mic",
+			Source: livekit.TrackSource_MICROPHONE,
+			Type:   livekit.TrackType_AUDIO,
+		})
+		p.AddTrack(&livekit.AddTrackRequest{
+			Cid:    "screen",
+			Name:   "screen",
+			Source: livekit.TrackSource_SCREEN_SHARE,
+			Type:   livekit.TrackType_VIDEO,
+		})
+		require.Equal(t, 2, sink.WriteMessageCallCount())
+		screenSid := p.pendingTracks["screen"].trackInfos[0].Sid
+
+		p.SetPermission(&livekit.ParticipantPermission{
+			CanPublish: true,
+			CanPublishSources: []livekit.TrackSource{
+				livekit.TrackSource_MICROPHONE,
+				livekit.TrackSource_CAMERA,
+			},
+		})
+		require.NotNil(t, p.pendingTracks["mic"])
+		require.Nil(t, p.pendingTracks["screen"])
+
+		require.Equal(t, 3, sink.WriteMessageCallCount())
+		res := sink.WriteMessageArgsForCall(2).(*livekit.SignalResponse)
+		require.Equal(t, screenSid, res.GetTrackUnpublished().GetTrackSid())
+	})
 }
 
 func TestOutOfOrderUpdates(t *testing.T) {
@@ -639,6 +673,129 @@ func TestPreferAudioCodecForRed(t *testing.T) {
 	}
 }
 
+func TestSynthesizeVideoLayers(t *testing.T) {
+	tests := []struct {
+		name   string
+		rids   []string
+		layers []*livekit.VideoLayer
+		err    error
+	}{
+		{
+			name: "no simulcast",
+		},
+		{
+			name: "all layers",
+			rids: []string{"f send", "h send", "q send"},
+			layers: []*livekit.VideoLayer{
+				{Quality: livekit.VideoQuality_LOW},
+				{Quality: livekit.VideoQuality_MEDIUM},
+				{Quality: livekit.VideoQuality_HIGH},
+			},
+		},
+		{
+			name: "lower layers",
+			rids: []string{"q send", "h send"},
+			layers: []*livekit.VideoLayer{
+				{Quality: livekit.VideoQuality_LOW},
+				{Quality: livekit.VideoQuality_MEDIUM},
+			},
+		},
+		{
+			name: "restrictions",
+			rids: []string{"q send max-width=320;max-height=180", "h send max-fr=30;max-width=640;max-height=360", "f send max-width=1280"},
+			layers: []*livekit.VideoLayer{
+				{Quality: livekit.VideoQuality_LOW, Width: 320, Height: 180},
+				{Quality: livekit.VideoQuality_MEDIUM, Width: 640, Height: 360},
+				{Quality: livekit.VideoQuality_HIGH, Width: 1280},
+			},
+		},
+		{
+			name:   "receive rids are ignored",
+			rids:   []string{"q send", "x recv"},
+			layers: []*livekit.VideoLayer{{Quality: livekit.VideoQuality_LOW}},
+		},
+		{
+			name: "unsupported rid",
+			rids: []string{"q send", "hi send"},
+			err:  ErrUnsupportedSimulcastRid,
+		},
+		{
+			name: "missing lower layer",
+			rids: []string{"q send", "f send"},
+			err:  ErrUnsupportedSimulcastRid,
+		},
+		{
+			name: "missing lowest layer",
+			rids: []string{"h send"},
+			err:  ErrUnsupportedSimulcastRid,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &sdp.MediaDescription{}
+			for _, rid := range test.rids {
+				m.Attributes = append(m.Attributes, sdp.Attribute{Key: "rid", Value: rid})
+			}
+			layers, err := synthesizeVideoLayers(m)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, layers, len(test.layers))
+			for i, layer := range layers {
+				require.True(t, proto.Equal(test.layers[i], layer), "layer %d: %v", i, layer)
+			}
+		})
+	}
+}
+
+func TestSynthesizeAddTrackRequests(t *testing.T) {
+	offer := strings.Join([]string{
+		"v=0",
+		"o=- 0 0 IN IP4 127.0.0.1",
+		"s=-",
+		"t=0 0",
+		"m=audio 9 UDP/TLS/RTP/SAVPF 111",
+		"c=IN IP4 0.0.0.0",
+		"a=mid:0",
+		"a=sendonly",
+		"a=msid:stream microphone",
+		"a=rtpmap:111 opus/48000/2",
+		"m=video 9 UDP/TLS/RTP/SAVPF 96",
+		"c=IN IP4 0.0.0.0",
+		"a=mid:1",
+		"a=sendonly",
+		"a=msid:stream camera",
+		"a=rtpmap:96 VP8/90000",
+		"a=rid:q send max-width=640;max-height=360",
+		"a=rid:h send max-width=1280;max-height=720",
+		"",
+	}, "\r\n")
+
+	t.Run("tracks are named after the participant", func(t *testing.T) {
+		p := newParticipantForTestWithOpts("encoder", &participantOpts{permissions: &livekit.ParticipantPermission{CanPublish: true}})
+		p.SetName("Stage")
+		require.NoError(t, p.synthesizeAddTrackRequests(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer}))
+
+		audio := p.pendingTracks["microphone"].trackInfos[0]
+		require.Equal(t, "Stage", audio.Name)
+		require.Equal(t, livekit.TrackSource_MICROPHONE, audio.Source)
+		video := p.pendingTracks["camera"].trackInfos[0]
+		require.Equal(t, "Stage", video.Name)
+		require.Equal(t, livekit.TrackSource_CAMERA, video.Source)
+		require.Equal(t, uint32(1280), video.Width)
+		require.Equal(t, uint32(720), video.Height)
+	})
+
+	t.Run("tracks are named after the identity without a name", func(t *testing.T) {
+		p := newParticipantForTestWithOpts("encoder", &participantOpts{permissions: &livekit.ParticipantPermission{CanPublish: true}})
+		require.NoError(t, p.synthesizeAddTrackRequests(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer}))
+		require.Equal(t, "encoder", p.pendingTracks["microphone"].trackInfos[0].Name)
+		require.Equal(t, "encoder", p.pendingTracks["camera"].trackInfos[0].Name)
+	})
+}
+
 type participantOpts struct {
 	permissions     *livekit.ParticipantPermission
 	protocolVersion types.ProtocolVersion
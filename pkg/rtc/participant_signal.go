@@ -197,6 +197,16 @@ func (p *ParticipantImpl) SendRefreshToken(token string) error {
 	})
 }
 
+// SendTimeSyncBeacon sends an unsolicited pong carrying the room clock,
+// clients answering pings through pong_resp are unaffected by it
+func (p *ParticipantImpl) SendTimeSyncBeacon(timestamp int64) error {
+	return p.writeMessage(&livekit.SignalResponse{
+		Message: &livekit.SignalResponse_Pong{
+			Pong: timestamp,
+		},
+	})
+}
+
 func (p *ParticipantImpl) SendRequestResponse(requestResponse *livekit.RequestResponse) error {
 	if requestResponse.RequestId == 0 || !p.params.ClientInfo.SupportErrorResponse() {
 		return nil
@@ -213,6 +223,23 @@ func (p *ParticipantImpl) SendRequestResponse(requestResponse *livekit.RequestRe
 	})
 }
 
+// sendTrackPublishDenied lets the client fail a pending publish instead of waiting for it to time out.
+// AddTrackRequest carries no request id, so the track cid is included in the message for correlation.
+func (p *ParticipantImpl) sendTrackPublishDenied(cid string, err error) error {
+	if !p.params.ClientInfo.SupportErrorResponse() {
+		return nil
+	}
+
+	return p.writeMessage(&livekit.SignalResponse{
+		Message: &livekit.SignalResponse_RequestResponse{
+			RequestResponse: &livekit.RequestResponse{
+				Reason:  livekit.RequestResponse_NOT_ALLOWED,
+				Message: fmt.Sprintf("publishing track %s is not allowed: %v", cid, err),
+			},
+		},
+	})
+}
+
 func (p *ParticipantImpl) HandleReconnectAndSendResponse(reconnectReason livekit.ReconnectReason, reconnectResponse *livekit.ReconnectResponse) error {
 	p.TransportManager.HandleClientReconnect(reconnectReason)
 
@@ -331,7 +358,15 @@ func (p *ParticipantImpl) writeMessage(msg *livekit.SignalResponse) error {
 	}
 
 	err := sink.WriteMessage(msg)
-	if errors.Is(err, psrpc.Canceled) {
+	if errors.Is(err, routing.ErrSignalSlowConsumer) {
+		// the client can't keep up with its signal messages, its state can't be kept in sync anymore
+		p.params.Logger.Warnw("closing participant with slow signal connection", err,
+			"messageType", fmt.Sprintf("%T", msg.Message))
+		go func() {
+			_ = p.Close(false, types.ParticipantCloseReasonSignalSlowConsumer, false)
+		}()
+		return err
+	} else if errors.Is(err, psrpc.Canceled) {
 		p.params.Logger.Debugw("could not send message to participant",
 			"error", err, "messageType", fmt.Sprintf("%T", msg.Message))
 		return nil
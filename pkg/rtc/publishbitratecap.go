@@ -0,0 +1,178 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/pion/rtcp"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// MaxPublishBitrateAttribute caps the total bitrate a participant publishes, in bits per second. It can be
+	// given in the token and changed by the server, the lower of it and the cap of the room applies.
+	MaxPublishBitrateAttribute = "lk.max_publish_bitrate"
+
+	publishBitrateCapCheckInterval = 5 * time.Second
+	// publishers ignoring REMB, e.g. when they estimate bandwidth with TWCC, get layers rejected once they
+	// publish over the cap by more than the tolerance
+	publishBitrateCapTolerance = 1.1
+	// a rejected layer is requested again once it fits under the cap, or under this share of the cap
+	// when its bitrate is not declared
+	publishBitrateCapRecovery = 0.5
+)
+
+// PublishBitrateCap returns the cap of the total bitrate the participant publishes, 0 when it is not capped
+func (p *ParticipantImpl) PublishBitrateCap() int64 {
+	limit := p.params.MaxPublishBitrate
+	if grants := p.grants.Load(); grants != nil {
+		if attrLimit := maxPublishBitrateFromAttributes(grants.Attributes); attrLimit > 0 && (limit == 0 || attrLimit < limit) {
+			limit = attrLimit
+		}
+	}
+	return limit
+}
+
+func maxPublishBitrateFromAttributes(attributes map[string]string) int64 {
+	limit, err := strconv.ParseInt(attributes[MaxPublishBitrateAttribute], 10, 64)
+	if err != nil || limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// publishBitrateCapWorker keeps the participant under its publish bitrate cap. The publisher is limited with
+// REMB, and the highest video layers stop being requested while it keeps publishing over the cap.
+// Layers are rejected through dynacast, they are not when dynacast is disabled.
+func (p *ParticipantImpl) publishBitrateCapWorker() {
+	ticker := time.NewTicker(publishBitrateCapCheckInterval)
+	defer ticker.Stop()
+
+	maxQuality := livekit.VideoQuality_HIGH
+	capped := false
+	for {
+		select {
+		case <-p.disconnected:
+			return
+		case <-ticker.C:
+		}
+
+		tracks := p.GetPublishedTracks()
+		limit := p.PublishBitrateCap()
+		if limit == 0 {
+			if capped {
+				// hand the REMB back to the overshoot clamp when there is one
+				bitrate := p.bitrateOvershootClamp.Load()
+				if bitrate == 0 {
+					bitrate = bitrateOvershootReleaseBitrate
+				}
+				p.postRtcp([]rtcp.Packet{getClampREMB(tracks, bitrate)})
+				maxQuality = livekit.VideoQuality_HIGH
+				setPublishMaxQuality(tracks, maxQuality)
+				capped = false
+			}
+			continue
+		}
+		capped = true
+
+		// REMB is sent on every check as receivers may expire it
+		bitrate := limit
+		if clamp := p.bitrateOvershootClamp.Load(); clamp > 0 && clamp < bitrate {
+			bitrate = clamp
+		}
+		p.postRtcp([]rtcp.Packet{getClampREMB(tracks, bitrate)})
+
+		measured := getPublishedBitrate(tracks)
+		switch {
+		case maxQuality > livekit.VideoQuality_LOW && float64(measured) > float64(limit)*publishBitrateCapTolerance:
+			maxQuality--
+			p.pubLogger.Infow(
+				"publisher exceeds bitrate cap, rejecting layers",
+				"limit", limit,
+				"measured", measured,
+				"maxQuality", maxQuality,
+			)
+
+		case maxQuality < livekit.VideoQuality_HIGH && publishBitrateCapFits(tracks, maxQuality+1, measured, limit):
+			maxQuality++
+			p.pubLogger.Infow(
+				"publisher back under bitrate cap, accepting layers",
+				"limit", limit,
+				"measured", measured,
+				"maxQuality", maxQuality,
+			)
+		}
+		// tracks published since the last check get the cap too
+		setPublishMaxQuality(tracks, maxQuality)
+	}
+}
+
+// getPublishedBitrate returns the bitrate measured for the video tracks, with an allowance for audio tracks
+func getPublishedBitrate(tracks []types.MediaTrack) int64 {
+	var total int64
+	for _, track := range tracks {
+		if track.Kind() != livekit.TrackType_VIDEO {
+			total += bitrateOvershootAudioAllowance
+			continue
+		}
+		for _, receiver := range track.Receivers() {
+			_, brs := receiver.GetLayeredBitrate()
+			for _, layer := range brs {
+				// temporal bitrates are cumulative, the highest one covers the whole layer
+				var measured int64
+				for _, br := range layer {
+					measured = max(measured, br)
+				}
+				total += measured
+			}
+		}
+	}
+	return total
+}
+
+// publishBitrateCapFits returns whether accepting quality again keeps the publisher under limit, from the
+// bitrates declared for the layers of that quality
+func publishBitrateCapFits(tracks []types.MediaTrack, quality livekit.VideoQuality, measured int64, limit int64) bool {
+	var declared int64
+	for _, track := range tracks {
+		if track.Kind() != livekit.TrackType_VIDEO {
+			continue
+		}
+		var bitrate uint32
+		for _, layer := range track.ToProto().Layers {
+			if layer.Quality == quality {
+				bitrate = layer.Bitrate
+			}
+		}
+		if bitrate == 0 {
+			return float64(measured) < float64(limit)*publishBitrateCapRecovery
+		}
+		declared += int64(bitrate)
+	}
+	return measured+declared < limit
+}
+
+func setPublishMaxQuality(tracks []types.MediaTrack, quality livekit.VideoQuality) {
+	for _, track := range tracks {
+		if mt, ok := track.(*MediaTrack); ok && track.Kind() == livekit.TrackType_VIDEO {
+			mt.SetMaxQuality(quality)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// kinds of quality alerts
+const (
+	// the connection quality stayed at or below the configured quality
+	QualityAlertPoorQuality = "poor_quality"
+	// the streams of the participant lost more packets than the configured percentage
+	QualityAlertPacketLoss = "packet_loss"
+	// the video tracks of a publisher were received below the configured bitrate
+	QualityAlertLowBitrate = "low_bitrate"
+
+	// fewer packets between two samples are not enough to measure loss
+	qualityAlertMinPackets = 50
+)
+
+// QualityAlert is a snapshot of the stats of a participant whose connection stayed impaired
+type QualityAlert struct {
+	Kind string
+	// start of the impairment
+	Since             time.Time
+	Quality           livekit.ConnectionQuality
+	Score             float32
+	PacketLossPercent float64
+	// bits per second received for the video tracks of the participant
+	PublishedBitrate int64
+}
+
+type qualitySample struct {
+	quality livekit.ConnectionQuality
+	score   float32
+	// cumulative counts of the published and subscribed streams
+	packets     uint64
+	packetsLost uint64
+	// the participant publishes unmuted video
+	publishesVideo   bool
+	publishedBitrate int64
+}
+
+type qualityAlertState struct {
+	packets       uint64
+	packetsLost   uint64
+	impairedSince map[string]time.Time
+	alertedAt     map[string]time.Time
+}
+
+// qualityAlertDetector tracks how long the connection of each participant stays past the thresholds, and
+// alerts once per cooldown for every kind of impairment lasting longer than the configured duration
+type qualityAlertDetector struct {
+	conf         config.QualityAlertsConfig
+	quality      livekit.ConnectionQuality
+	participants map[livekit.ParticipantID]*qualityAlertState
+}
+
+func newQualityAlertDetector(conf config.QualityAlertsConfig) *qualityAlertDetector {
+	d := &qualityAlertDetector{
+		conf:         conf,
+		quality:      -1,
+		participants: make(map[livekit.ParticipantID]*qualityAlertState),
+	}
+	if v, ok := livekit.ConnectionQuality_value[conf.Quality]; ok {
+		d.quality = livekit.ConnectionQuality(v)
+	}
+	return d
+}
+
+// observe adds a sample of the active participants and returns the alerts raised by it
+func (d *qualityAlertDetector) observe(now time.Time, samples map[livekit.ParticipantID]*qualitySample) map[livekit.ParticipantID][]*QualityAlert {
+	for pID := range d.participants {
+		if _, ok := samples[pID]; !ok {
+			delete(d.participants, pID)
+		}
+	}
+
+	var alerts map[livekit.ParticipantID][]*QualityAlert
+	for pID, sample := range samples {
+		state := d.participants[pID]
+		if state == nil {
+			state = &qualityAlertState{
+				packets:       sample.packets,
+				packetsLost:   sample.packetsLost,
+				impairedSince: make(map[string]time.Time),
+				alertedAt:     make(map[string]time.Time),
+			}
+			d.participants[pID] = state
+		}
+
+		// loss since the previous sample, counts go down when tracks are removed
+		lossPercent := 0.0
+		lossMeasured := false
+		if sample.packets >= state.packets && sample.packetsLost >= state.packetsLost {
+			packets := sample.packets - state.packets
+			lost := sample.packetsLost - state.packetsLost
+			if packets+lost >= qualityAlertMinPackets {
+				lossPercent = float64(lost) * 100 / float64(packets+lost)
+				lossMeasured = true
+			}
+		}
+		if lossMeasured || sample.packets < state.packets || sample.packetsLost < state.packetsLost {
+			state.packets, state.packetsLost = sample.packets, sample.packetsLost
+		}
+
+		impaired := map[string]bool{
+			QualityAlertPoorQuality: d.quality >= 0 &&
+				(sample.quality == d.quality || utils.IsConnectionQualityLower(d.quality, sample.quality)),
+			QualityAlertPacketLoss: d.conf.PacketLossPercent > 0 && lossMeasured && lossPercent >= d.conf.PacketLossPercent,
+			QualityAlertLowBitrate: d.conf.MinPublishedBitrate > 0 && sample.publishesVideo &&
+				sample.publishedBitrate < d.conf.MinPublishedBitrate,
+		}
+		for kind, isImpaired := range impaired {
+			if !isImpaired {
+				// loss is only known with enough packets, it is kept impaired until measured again
+				if kind != QualityAlertPacketLoss || lossMeasured {
+					delete(state.impairedSince, kind)
+				}
+				continue
+			}
+			since, ok := state.impairedSince[kind]
+			if !ok {
+				since = now
+				state.impairedSince[kind] = now
+			}
+			if now.Sub(since) < d.conf.Duration {
+				continue
+			}
+			if alertedAt, ok := state.alertedAt[kind]; ok && now.Sub(alertedAt) < d.conf.Cooldown {
+				continue
+			}
+			state.alertedAt[kind] = now
+			if alerts == nil {
+				alerts = make(map[livekit.ParticipantID][]*QualityAlert)
+			}
+			alerts[pID] = append(alerts[pID], &QualityAlert{
+				Kind:              kind,
+				Since:             since,
+				Quality:           sample.quality,
+				Score:             sample.score,
+				PacketLossPercent: lossPercent,
+				PublishedBitrate:  sample.publishedBitrate,
+			})
+		}
+	}
+	return alerts
+}
+
+// ------------------------------------------------
+
+func (r *Room) OnQualityAlert(f func(p types.LocalParticipant, alert *QualityAlert)) {
+	r.onQualityAlert = f
+}
+
+func (r *Room) detectQualityAlerts(participants []types.LocalParticipant, infos map[livekit.ParticipantID]*livekit.ConnectionQualityInfo) {
+	samples := make(map[livekit.ParticipantID]*qualitySample, len(infos))
+	for _, p := range participants {
+		info, ok := infos[p.ID()]
+		if !ok {
+			continue
+		}
+		sample := &qualitySample{
+			quality: info.Quality,
+			score:   info.Score,
+		}
+		var videoTracks []types.MediaTrack
+		for _, track := range p.GetPublishedTracks() {
+			if lt, ok := track.(types.LocalMediaTrack); ok {
+				stats := lt.GetTrackStats()
+				sample.packets += uint64(stats.GetPackets())
+				sample.packetsLost += uint64(stats.GetPacketsLost())
+			}
+			if track.Kind() == livekit.TrackType_VIDEO && !track.IsMuted() {
+				videoTracks = append(videoTracks, track)
+			}
+		}
+		for _, st := range p.GetSubscribedTracks() {
+			if dt := st.DownTrack(); dt != nil {
+				stats := dt.GetTrackStats()
+				sample.packets += uint64(stats.GetPackets())
+				sample.packetsLost += uint64(stats.GetPacketsLost())
+			}
+		}
+		if len(videoTracks) != 0 {
+			sample.publishesVideo = true
+			sample.publishedBitrate = getPublishedBitrate(videoTracks)
+		}
+		samples[p.ID()] = sample
+	}
+
+	for pID, alerts := range r.qualityAlerts.observe(time.Now(), samples) {
+		p := r.GetParticipantByID(pID)
+		if p == nil {
+			continue
+		}
+		for _, alert := range alerts {
+			r.handleQualityAlert(p, alert)
+		}
+	}
+}
+
+func (r *Room) handleQualityAlert(p types.LocalParticipant, alert *QualityAlert) {
+	r.Logger.Infow(
+		"participant quality alert",
+		"participant", p.Identity(),
+		"kind", alert.Kind,
+		"since", alert.Since,
+		"quality", alert.Quality,
+		"score", alert.Score,
+		"packetLossPercent", alert.PacketLossPercent,
+		"publishedBitrate", alert.PublishedBitrate,
+	)
+	prometheus.RecordQualityAlert(alert.Kind)
+
+	if r.onQualityAlert != nil {
+		r.onQualityAlert(p, alert)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestQualityAlertDetector(t *testing.T) {
+	conf := config.QualityAlertsConfig{
+		Enabled:             true,
+		Quality:             livekit.ConnectionQuality_POOR.String(),
+		PacketLossPercent:   10,
+		MinPublishedBitrate: 100_000,
+		Duration:            30 * time.Second,
+		Cooldown:            5 * time.Minute,
+	}
+	start := time.Now()
+
+	t.Run("poor quality", func(t *testing.T) {
+		d := newQualityAlertDetector(conf)
+		sample := &qualitySample{quality: livekit.ConnectionQuality_POOR, score: 2}
+		for i := 0; i < 6; i++ {
+			require.Empty(t, d.observe(start.Add(time.Duration(i)*5*time.Second), map[livekit.ParticipantID]*qualitySample{"PA_1": sample}))
+		}
+		alerts := d.observe(start.Add(30*time.Second), map[livekit.ParticipantID]*qualitySample{"PA_1": sample})
+		require.Len(t, alerts["PA_1"], 1)
+		require.Equal(t, QualityAlertPoorQuality, alerts["PA_1"][0].Kind)
+		require.Equal(t, start, alerts["PA_1"][0].Since)
+
+		// not again within the cooldown
+		require.Empty(t, d.observe(start.Add(time.Minute), map[livekit.ParticipantID]*qualitySample{"PA_1": sample}))
+		require.Len(t, d.observe(start.Add(6*time.Minute), map[livekit.ParticipantID]*qualitySample{"PA_1": sample}), 1)
+	})
+
+	t.Run("recovering resets the duration", func(t *testing.T) {
+		d := newQualityAlertDetector(conf)
+		poor := &qualitySample{quality: livekit.ConnectionQuality_POOR}
+		good := &qualitySample{quality: livekit.ConnectionQuality_GOOD}
+		require.Empty(t, d.observe(start, map[livekit.ParticipantID]*qualitySample{"PA_1": poor}))
+		require.Empty(t, d.observe(start.Add(20*time.Second), map[livekit.ParticipantID]*qualitySample{"PA_1": good}))
+		require.Empty(t, d.observe(start.Add(40*time.Second), map[livekit.ParticipantID]*qualitySample{"PA_1": poor}))
+		require.Empty(t, d.observe(start.Add(60*time.Second), map[livekit.ParticipantID]*qualitySample{"PA_1": poor}))
+		require.Len(t, d.observe(start.Add(70*time.Second), map[livekit.ParticipantID]*qualitySample{"PA_1": poor}), 1)
+	})
+
+	t.Run("packet loss", func(t *testing.T) {
+		d := newQualityAlertDetector(conf)
+		var alerts map[livekit.ParticipantID][]*QualityAlert
+		// the first sample is the baseline of the counts
+		for i := 0; i <= 7; i++ {
+			alerts = d.observe(start.Add(time.Duration(i)*5*time.Second), map[livekit.ParticipantID]*qualitySample{
+				// 20% of the packets lost since the previous sample
+				"PA_1": {quality: livekit.ConnectionQuality_GOOD, packets: uint64(i) * 400, packetsLost: uint64(i) * 100},
+				// 1% lost
+				"PA_2": {quality: livekit.ConnectionQuality_GOOD, packets: uint64(i) * 990, packetsLost: uint64(i) * 10},
+			})
+		}
+		require.Len(t, alerts, 1)
+		require.Equal(t, QualityAlertPacketLoss, alerts["PA_1"][0].Kind)
+		require.Equal(t, start.Add(5*time.Second), alerts["PA_1"][0].Since)
+		require.InDelta(t, 20, alerts["PA_1"][0].PacketLossPercent, 0.01)
+	})
+
+	t.Run("low bitrate", func(t *testing.T) {
+		d := newQualityAlertDetector(conf)
+		var alerts map[livekit.ParticipantID][]*QualityAlert
+		for i := 0; i <= 6; i++ {
+			alerts = d.observe(start.Add(time.Duration(i)*5*time.Second), map[livekit.ParticipantID]*qualitySample{
+				"PA_1": {quality: livekit.ConnectionQuality_GOOD, publishesVideo: true, publishedBitrate: 50_000},
+				"PA_2": {quality: livekit.ConnectionQuality_GOOD, publishesVideo: true, publishedBitrate: 500_000},
+				// not publishing video
+				"PA_3": {quality: livekit.ConnectionQuality_GOOD},
+			})
+		}
+		require.Len(t, alerts, 1)
+		require.Equal(t, QualityAlertLowBitrate, alerts["PA_1"][0].Kind)
+		require.Equal(t, int64(50_000), alerts["PA_1"][0].PublishedBitrate)
+	})
+
+	t.Run("participants leaving are forgotten", func(t *testing.T) {
+		d := newQualityAlertDetector(conf)
+		poor := &qualitySample{quality: livekit.ConnectionQuality_POOR}
+		require.Empty(t, d.observe(start, map[livekit.ParticipantID]*qualitySample{"PA_1": poor}))
+		require.Empty(t, d.observe(start.Add(20*time.Second), nil))
+		require.Empty(t, d.observe(start.Add(40*time.Second), map[livekit.ParticipantID]*qualitySample{"PA_1": poor}))
+	})
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// RecordingConsentAttribute holds the recording consent of a participant. It can be given in the token,
+	// and is changed by the server only, when the participant answers or through the API.
+	RecordingConsentAttribute = "lk.recording_consent"
+	// RecordingConsentTopic is the data topic participants answer recording consent requests on, with a
+	// RecordingConsentAnswer payload
+	RecordingConsentTopic = "lk.recording_consent"
+)
+
+type RecordingConsent string
+
+const (
+	// RecordingConsentPending is set on participants that have not answered once the room is being recorded
+	RecordingConsentPending RecordingConsent = "pending"
+	RecordingConsentGranted RecordingConsent = "granted"
+	RecordingConsentDenied  RecordingConsent = "denied"
+)
+
+func (c RecordingConsent) IsValid() bool {
+	switch c {
+	case RecordingConsentPending, RecordingConsentGranted, RecordingConsentDenied:
+		return true
+	}
+	return false
+}
+
+// RecordingConsentAnswer is the JSON payload of a RecordingConsentTopic message sent by a participant
+type RecordingConsentAnswer struct {
+	Granted bool `json:"granted"`
+}
+
+func recordingConsentOf(p types.LocalParticipant) RecordingConsent {
+	grants := p.ClaimGrants()
+	if grants == nil {
+		return ""
+	}
+	return RecordingConsent(grants.Attributes[RecordingConsentAttribute])
+}
+
+// needsRecordingConsent tells if the tracks of a participant are recorded only with its consent, recorders
+// and agents are run by the application and never asked
+func needsRecordingConsent(p types.LocalParticipant) bool {
+	return !p.IsRecorder() && !p.IsAgent() && !p.Hidden()
+}
+
+// requestRecordingConsent marks participants that have no consent state as pending once the room is being
+// recorded: everyone when a recorder becomes active, the participant otherwise. Everyone is asked as well when
+// a recording job starts, see UpdateRecordingJob.
+func (r *Room) requestRecordingConsent(p types.LocalParticipant) {
+	var participants []types.LocalParticipant
+	if p.IsRecorder() {
+		participants = r.GetParticipants()
+	} else {
+		r.lock.RLock()
+		recording := r.protoRoom.ActiveRecording
+		r.lock.RUnlock()
+		if recording {
+			participants = []types.LocalParticipant{p}
+		}
+	}
+
+	r.askRecordingConsent(participants)
+}
+
+func (r *Room) askRecordingConsent(participants []types.LocalParticipant) {
+	for _, op := range participants {
+		if needsRecordingConsent(op) && recordingConsentOf(op) == "" {
+			op.SetAttributes(map[string]string{RecordingConsentAttribute: string(RecordingConsentPending)})
+		}
+	}
+}
+
+// SetRecordingConsent records the consent of a participant to the recording of its tracks. When recorders
+// are limited to consenting participants, withdrawing consent stops the subscriptions of recorders to the
+// tracks of the participant, and granting it resumes the subscriptions waiting for the permission.
+func (r *Room) SetRecordingConsent(identity livekit.ParticipantIdentity, consent RecordingConsent) (types.LocalParticipant, error) {
+	if consent != RecordingConsentGranted && consent != RecordingConsentDenied {
+		return nil, ErrInvalidRecordingConsent
+	}
+	p := r.GetParticipant(identity)
+	if p == nil {
+		return nil, ErrParticipantNotFound
+	}
+
+	p.SetAttributes(map[string]string{RecordingConsentAttribute: string(consent)})
+	p.GetLogger().Infow("recording consent updated", "consent", consent)
+	if r.recordingConsentConfig.ExcludeWithoutConsent {
+		if consent != RecordingConsentGranted {
+			r.revokeRecorderSubscriptions(p)
+		}
+		// subscriptions refused for lack of consent wait for the tracks to change before they are retried
+		for _, track := range p.GetPublishedTracks() {
+			r.trackManager.NotifyTrackChanged(track.ID())
+		}
+	}
+	return p, nil
+}
+
+func (r *Room) handleRecordingConsentAnswer(p types.LocalParticipant, payload []byte) {
+	var answer RecordingConsentAnswer
+	if err := json.Unmarshal(payload, &answer); err != nil {
+		p.GetLogger().Infow("ignoring invalid recording consent answer", "error", err)
+		return
+	}
+	consent := RecordingConsentDenied
+	if answer.Granted {
+		consent = RecordingConsentGranted
+	}
+	_, _ = r.SetRecordingConsent(p.Identity(), consent)
+}
+
+// hasRecordingConsent tells if the subscriber may receive the tracks of the publisher, only recorders need
+// the consent of publishers and only when recorders are limited to consenting participants
+func (r *Room) hasRecordingConsent(subIdentity livekit.ParticipantIdentity, pub types.LocalParticipant) bool {
+	if !r.recordingConsentConfig.ExcludeWithoutConsent || !needsRecordingConsent(pub) {
+		return true
+	}
+	sub := r.GetParticipant(subIdentity)
+	if sub == nil || !sub.IsRecorder() {
+		return true
+	}
+	return recordingConsentOf(pub) == RecordingConsentGranted
+}
+
+// revokeRecorderSubscriptions removes recorders from the subscribers of the tracks of a participant, they
+// resubscribe once the participant consents
+func (r *Room) revokeRecorderSubscriptions(p types.LocalParticipant) {
+	for _, track := range p.GetPublishedTracks() {
+		for _, subID := range track.GetAllSubscribers() {
+			if sub := r.GetParticipantByID(subID); sub != nil && sub.IsRecorder() {
+				track.RemoveSubscriber(subID, false)
+			}
+		}
+	}
+}
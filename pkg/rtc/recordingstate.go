@@ -0,0 +1,117 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"slices"
+	"time"
+)
+
+// RecordingJob is an egress job recording the room, some of its tracks or its SIP calls
+type RecordingJob struct {
+	ID string `json:"id"`
+	// kind of the egress, e.g. room_composite or track
+	Kind      string    `json:"kind"`
+	Active    bool      `json:"active"`
+	StartedAt time.Time `json:"started_at,omitempty"`
+}
+
+// RecordingState is the authoritative recording state of a room. It is set by the server only: the room is
+// recorded while a recording job is active or a recorder participant is in it. Participants learn it from the
+// ActiveRecording field of the room in their join response and room updates.
+type RecordingState struct {
+	Active    bool           `json:"active"`
+	Jobs      []RecordingJob `json:"jobs,omitempty"`
+	Recorders int            `json:"recorders"`
+}
+
+// UpdateRecordingJob adds an active recording job to the room or removes an ended one, and returns whether the
+// recording state of the room changed
+func (r *Room) UpdateRecordingJob(job RecordingJob) bool {
+	r.lock.Lock()
+	if job.Active {
+		if existing, ok := r.recordingJobs[job.ID]; ok {
+			job.StartedAt = existing.StartedAt
+		} else if job.StartedAt.IsZero() {
+			job.StartedAt = time.Now()
+		}
+		r.recordingJobs[job.ID] = job
+	} else {
+		delete(r.recordingJobs, job.ID)
+	}
+	changed := r.updateActiveRecordingLocked()
+	active := r.protoRoom.ActiveRecording
+	r.lock.Unlock()
+
+	if !changed {
+		return false
+	}
+	r.Logger.Infow("recording state changed", "active", active, "jobID", job.ID, "kind", job.Kind)
+	r.protoProxy.MarkDirty(true)
+	if active {
+		r.askRecordingConsent(r.GetParticipants())
+	}
+	return true
+}
+
+// GetRecordingState returns the recording state of the room, with its active recording jobs oldest first
+func (r *Room) GetRecordingState() RecordingState {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	state := RecordingState{Active: r.protoRoom.ActiveRecording}
+	for _, job := range r.recordingJobs {
+		state.Jobs = append(state.Jobs, job)
+	}
+	slices.SortFunc(state.Jobs, func(a, b RecordingJob) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+	for _, p := range r.participants {
+		if p.IsRecorder() {
+			state.Recorders++
+		}
+	}
+	return state
+}
+
+// OnRecordingChanged is called with the recording state of the room whenever recording starts or ends
+func (r *Room) OnRecordingChanged(f func(active bool)) {
+	r.lock.Lock()
+	r.onRecordingChanged = f
+	r.lock.Unlock()
+}
+
+// updateActiveRecordingLocked aggregates the recording jobs and the recorder participants of the room into its
+// recording state, it returns whether the state changed
+func (r *Room) updateActiveRecordingLocked() bool {
+	active := len(r.recordingJobs) != 0
+	if !active {
+		for _, p := range r.participants {
+			if p.IsRecorder() {
+				active = true
+				break
+			}
+		}
+	}
+	if r.protoRoom.ActiveRecording == active {
+		return false
+	}
+
+	r.protoRoom.ActiveRecording = active
+	if f := r.onRecordingChanged; f != nil {
+		go f(active)
+	}
+	return true
+}
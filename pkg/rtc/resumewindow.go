@@ -0,0 +1,56 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// SessionResumeTopic carries the resume window of the room to participants once they are active,
+// clients give up resuming and rejoin past it
+const SessionResumeTopic = "lk.session_resume"
+
+type SessionResume struct {
+	WindowMs int64 `json:"resume_window_ms"`
+}
+
+// ResumeWindow returns how long a participant whose connection failed can resume its session
+func (r *Room) ResumeWindow() time.Duration {
+	return r.resumeWindow
+}
+
+func (r *Room) sendSessionResume(p types.LocalParticipant) {
+	payload, err := json.Marshal(SessionResume{WindowMs: r.resumeWindow.Milliseconds()})
+	if err != nil {
+		return
+	}
+
+	topic := SessionResumeTopic
+	BroadcastDataPacketForRoom(r, nil, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+		Kind:                  livekit.DataPacket_RELIABLE,
+		DestinationIdentities: []string{string(p.Identity())},
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload: payload,
+				Topic:   &topic,
+			},
+		},
+	}, r.Logger)
+}
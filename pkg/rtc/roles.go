@@ -0,0 +1,170 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// RoleAttribute holds the role of a participant. It can be given in the token, and is changed by the server
+// only, through Room.UpdateParticipantRole, so that the permission bundle of the role stays in effect.
+const RoleAttribute = "lk.role"
+
+type ParticipantRole string
+
+const (
+	ParticipantRoleHost    ParticipantRole = "host"
+	ParticipantRoleCoHost  ParticipantRole = "co_host"
+	ParticipantRoleSpeaker ParticipantRole = "speaker"
+	ParticipantRoleViewer  ParticipantRole = "viewer"
+)
+
+type RoleAction int
+
+const (
+	RoleActionMuteOthers RoleAction = iota
+	RoleActionRemoveOthers
+	RoleActionChangeRoles
+)
+
+// RolePermissions is the bundle of capabilities given by a role
+type RolePermissions struct {
+	CanPublish      bool
+	CanSubscribe    bool
+	CanMuteOthers   bool
+	CanRemoveOthers bool
+	CanChangeRoles  bool
+	// participants can only act on participants with the same rank or below
+	rank int
+}
+
+var rolePermissions = map[ParticipantRole]RolePermissions{
+	ParticipantRoleHost: {
+		CanPublish:      true,
+		CanSubscribe:    true,
+		CanMuteOthers:   true,
+		CanRemoveOthers: true,
+		CanChangeRoles:  true,
+		rank:            3,
+	},
+	ParticipantRoleCoHost: {
+		CanPublish:      true,
+		CanSubscribe:    true,
+		CanMuteOthers:   true,
+		CanRemoveOthers: true,
+		rank:            2,
+	},
+	ParticipantRoleSpeaker: {
+		CanPublish:   true,
+		CanSubscribe: true,
+		rank:         1,
+	},
+	ParticipantRoleViewer: {
+		CanSubscribe: true,
+	},
+}
+
+func (r ParticipantRole) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Permissions returns the bundle of the role, participants without a role have none
+func (r ParticipantRole) Permissions() RolePermissions {
+	return rolePermissions[r]
+}
+
+// Allows returns true when the role may perform the action
+func (p RolePermissions) Allows(action RoleAction) bool {
+	switch action {
+	case RoleActionMuteOthers:
+		return p.CanMuteOthers
+	case RoleActionRemoveOthers:
+		return p.CanRemoveOthers
+	case RoleActionChangeRoles:
+		return p.CanChangeRoles
+	}
+	return false
+}
+
+// ApplyTo returns the participant permission with the publish and subscribe capabilities of the role
+func (p RolePermissions) ApplyTo(permission *livekit.ParticipantPermission) *livekit.ParticipantPermission {
+	permission = proto.Clone(permission).(*livekit.ParticipantPermission)
+	permission.CanPublish = p.CanPublish
+	permission.CanSubscribe = p.CanSubscribe
+	return permission
+}
+
+func RoleFromAttributes(attributes map[string]string) ParticipantRole {
+	return ParticipantRole(attributes[RoleAttribute])
+}
+
+// CanModerate checks that a participant may perform an action on another one, given their attributes
+func CanModerate(actor, target map[string]string, action RoleAction) bool {
+	actorPermissions := RoleFromAttributes(actor).Permissions()
+	if !actorPermissions.Allows(action) {
+		return false
+	}
+	return RoleFromAttributes(target).Permissions().rank <= actorPermissions.rank
+}
+
+// applyParticipantRole gives a participant joining with a role in its token the permission bundle of that role.
+// Assumes room lock is held.
+func (r *Room) applyParticipantRole(p types.LocalParticipant) {
+	grants := p.ClaimGrants()
+	if grants == nil {
+		return
+	}
+	role := RoleFromAttributes(grants.Attributes)
+	if role == "" {
+		return
+	}
+	if !role.IsValid() {
+		r.Logger.Warnw("ignoring invalid participant role", nil, "participant", p.Identity(), "role", role)
+		return
+	}
+	p.SetPermission(role.Permissions().ApplyTo(grants.Video.ToPermission()))
+}
+
+// UpdateParticipantRole changes the role of a participant and applies its permission bundle. Participants
+// waiting for admission get the permission of their role once admitted.
+func (r *Room) UpdateParticipantRole(identity livekit.ParticipantIdentity, role ParticipantRole) (types.LocalParticipant, error) {
+	if !role.IsValid() {
+		return nil, ErrInvalidParticipantRole
+	}
+
+	r.lock.Lock()
+	p := r.participants[identity]
+	if p == nil {
+		r.lock.Unlock()
+		return nil, ErrParticipantNotFound
+	}
+	pending, isPending := r.pendingParticipants[identity]
+	if isPending {
+		r.pendingParticipants[identity] = role.Permissions().ApplyTo(pending)
+	}
+	r.lock.Unlock()
+
+	p.SetAttributes(map[string]string{RoleAttribute: string(role)})
+	if !isPending {
+		p.SetPermission(role.Permissions().ApplyTo(p.ClaimGrants().Video.ToPermission()))
+	}
+	p.GetLogger().Infow("participant role updated", "role", role)
+	return p, nil
+}
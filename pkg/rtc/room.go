@@ -37,6 +37,7 @@ import (
 
 	"github.com/livekit/livekit-server/pkg/agent"
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/idgen"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/rtc/types"
 	"github.com/livekit/livekit-server/pkg/sfu"
@@ -108,6 +109,8 @@ type Room struct {
 	Logger     logger.Logger
 
 	config          WebRTCConfig
+	timeSyncConfig  config.TimeSyncConfig
+	eventLog        *roomEventLog
 	audioConfig     *sfu.AudioConfig
 	serverInfo      *livekit.ServerInfo
 	telemetry       telemetry.TelemetryService
@@ -115,6 +118,40 @@ type Room struct {
 	trackManager    *RoomTrackManager
 	agentDispatches map[string]*agentDispatch
 
+	// latest connection quality of the active participants, for stats
+	connectionInfos atomic.Pointer[map[livekit.ParticipantID]*livekit.ConnectionQualityInfo]
+
+	// feedback loop detection, only accessed by the audio update worker
+	audioFeedback       *audioFeedbackDetector
+	audioFeedbackConfig config.AudioFeedbackConfig
+
+	// set in conference bridge rooms
+	conferenceBridge *conferenceBridge
+
+	// impaired connection alerts, only accessed by the connection quality worker
+	qualityAlerts *qualityAlertDetector
+
+	// data message history
+	dataHistoryConfig config.DataHistoryConfig
+	dataMessageStore  DataMessageStore
+
+	recordingConsentConfig config.RecordingConsentConfig
+	dataPacketLimiter      DataPacketLimiter
+
+	// history kept for the archive of the room, when enabled
+	archive atomic.Pointer[roomArchiveRecorder]
+	// connection quality of participants over their sessions, when enabled
+	sessionQuality atomic.Pointer[sessionQualityRecorder]
+
+	// signaling URL of another region clients can fail over to
+	alternativeURL func() string
+
+	// share of a subscriber's channel capacity a single publisher may use, overrides the config when set
+	maxPublisherShare atomic.Pointer[float64]
+
+	// how long participants whose connection failed can resume their session
+	resumeWindow time.Duration
+
 	// agents
 	agentClient agent.Client
 	agentStore  AgentStore
@@ -125,6 +162,24 @@ type Room struct {
 	participantRequestSources map[livekit.ParticipantIdentity]routing.MessageSource
 	hasPublished              map[livekit.ParticipantIdentity]bool
 	agentParticpants          map[livekit.ParticipantIdentity]*agentJob
+	agentParticipantConfigs   map[string]config.AgentParticipantConfig
+	virtualParticipants       map[livekit.ParticipantIdentity]*VirtualParticipant
+	pendingParticipants       map[livekit.ParticipantIdentity]*livekit.ParticipantPermission
+	sharedPlayback            *SharedPlaybackState
+	trackForwarders           map[string]*TrackForwarder
+	onTrackForwardEnded       func(info *TrackForwardInfo)
+	trackRecorders            map[string]*TrackRecorder
+	trackRecordingStore       TrackRecordingStore
+	trackRecordingConfig      config.TrackRecordingConfig
+	onTrackRecordingEnded     func(info *TrackRecordingInfo)
+	rtpIngests                map[string]*RTPIngest
+	onRTPIngestEnded          func(info *RTPIngestInfo)
+	recordingJobs             map[string]RecordingJob
+	onRecordingChanged        func(active bool)
+	relayForwarders           map[string]*TrackForwarder
+	onRelayTrackPublished     func(track *RelayTrack)
+	sdkBlocklist              *SDKBlocklist
+	idGenerator               idgen.Generator
 	bufferFactory             *buffer.FactoryOfBufferFactory
 
 	// batch update participant info for non-publishers
@@ -138,6 +193,8 @@ type Room struct {
 	onParticipantChanged func(p types.LocalParticipant)
 	onRoomUpdated        func()
 	onClose              func()
+	onAudioFeedback      func(source, echo types.LocalParticipant, muted bool)
+	onQualityAlert       func(p types.LocalParticipant, alert *QualityAlert)
 
 	simulationLock                                 sync.Mutex
 	disconnectSignalOnResumeParticipants           map[livekit.ParticipantIdentity]time.Time
@@ -251,7 +308,16 @@ func NewRoom(
 			livekit.RoomID(room.Sid),
 		),
 		config:                               config,
+		idGenerator:                          idgen.Default,
+		timeSyncConfig:                       roomConfig.TimeSync,
+		eventLog:                             newRoomEventLog(roomConfig.EventReplay),
+		dataHistoryConfig:                    roomConfig.DataHistory,
+		dataMessageStore:                     newMemoryDataMessageStore(roomConfig.DataHistory),
+		recordingConsentConfig:               roomConfig.RecordingConsent,
+		agentParticipantConfigs:              roomConfig.AgentParticipants,
+		resumeWindow:                         roomConfig.Resume.WindowFor(room.Name),
 		audioConfig:                          audioConfig,
+		audioFeedbackConfig:                  roomConfig.AudioFeedback,
 		telemetry:                            telemetry,
 		egressLauncher:                       egressLauncher,
 		agentClient:                          agentClient,
@@ -264,6 +330,13 @@ func NewRoom(
 		participantRequestSources:            make(map[livekit.ParticipantIdentity]routing.MessageSource),
 		hasPublished:                         make(map[livekit.ParticipantIdentity]bool),
 		agentParticpants:                     make(map[livekit.ParticipantIdentity]*agentJob),
+		virtualParticipants:                  make(map[livekit.ParticipantIdentity]*VirtualParticipant),
+		pendingParticipants:                  make(map[livekit.ParticipantIdentity]*livekit.ParticipantPermission),
+		trackForwarders:                      make(map[string]*TrackForwarder),
+		trackRecorders:                       make(map[string]*TrackRecorder),
+		relayForwarders:                      make(map[string]*TrackForwarder),
+		rtpIngests:                           make(map[string]*RTPIngest),
+		recordingJobs:                        make(map[string]RecordingJob),
 		bufferFactory:                        buffer.NewFactoryOfBufferFactory(config.Receiver.PacketBufferSizeVideo, config.Receiver.PacketBufferSizeAudio),
 		batchedUpdates:                       make(map[livekit.ParticipantIdentity]*participantUpdate),
 		closed:                               make(chan struct{}),
@@ -283,6 +356,21 @@ func NewRoom(
 	}
 	r.protoProxy = utils.NewProtoProxy[*livekit.Room](roomUpdateInterval, r.updateProto)
 
+	if roomConfig.AudioFeedback.EnabledFor(room.Name) && audioConfig.UpdateInterval > 0 {
+		r.audioFeedback = newAudioFeedbackDetector(
+			roomConfig.AudioFeedback,
+			time.Duration(audioConfig.UpdateInterval)*time.Millisecond,
+		)
+	}
+
+	if roomConfig.ConferenceBridge.EnabledFor(room.Name) {
+		r.conferenceBridge = newConferenceBridge(roomConfig.ConferenceBridge)
+	}
+
+	if roomConfig.QualityAlerts.EnabledFor(room.Name) {
+		r.qualityAlerts = newQualityAlertDetector(roomConfig.QualityAlerts)
+	}
+
 	r.createAgentDispatchesFromRoomAgent()
 
 	r.launchRoomAgents(maps.Values(r.agentDispatches))
@@ -291,6 +379,9 @@ func NewRoom(
 	go r.connectionQualityWorker()
 	go r.changeUpdateWorker()
 	go r.simulationCleanupWorker()
+	if r.timeSyncConfig.Enabled {
+		go r.timeSyncWorker()
+	}
 
 	return r
 }
@@ -420,7 +511,7 @@ func (r *Room) Join(participant types.LocalParticipant, requestSource routing.Me
 		return ErrRoomClosed
 	}
 
-	if r.participants[participant.Identity()] != nil {
+	if r.participants[participant.Identity()] != nil || r.virtualParticipants[participant.Identity()] != nil {
 		return ErrAlreadyJoined
 	}
 	if r.protoRoom.MaxParticipants > 0 && !participant.IsDependent() {
@@ -439,6 +530,13 @@ func (r *Room) Join(participant types.LocalParticipant, requestSource routing.Me
 		r.joinedAt.Store(time.Now().Unix())
 	}
 
+	r.applyParticipantRole(participant)
+	opts = r.applyAgentParticipantConfig(participant, opts)
+	if share := r.maxPublisherShare.Load(); share != nil {
+		participant.SetSubscriberMaxPublisherShare(*share)
+	}
+	r.holdPendingParticipant(participant)
+
 	participant.OnStateChange(func(p types.LocalParticipant, state livekit.ParticipantInfo_State) {
 		if r.onParticipantChanged != nil {
 			r.onParticipantChanged(p)
@@ -449,6 +547,18 @@ func (r *Room) Join(participant types.LocalParticipant, requestSource routing.Me
 			// subscribe participant to existing published tracks
 			r.subscribeToExistingTracks(p)
 
+			if r.timeSyncConfig.Enabled {
+				r.sendTimeSyncBeacon(p)
+			}
+			r.sendSharedPlayback(p)
+			r.sendSDKUpgrade(p)
+			r.sendSessionResume(p)
+			r.sendEventReplay(p)
+			r.requestRecordingConsent(p)
+			if !p.Hidden() {
+				r.recordEvent(newParticipantEvent(RoomEventParticipantJoined, p))
+			}
+
 			meta := &livekit.AnalyticsClientMeta{
 				ClientConnectTime: uint32(time.Since(p.ConnectedAt()).Milliseconds()),
 			}
@@ -523,16 +633,10 @@ func (r *Room) Join(participant types.LocalParticipant, requestSource routing.Me
 		"numParticipants", len(r.participants),
 	)
 
-	if participant.IsRecorder() && !r.protoRoom.ActiveRecording {
-		r.protoRoom.ActiveRecording = true
-		r.protoProxy.MarkDirty(true)
-	} else {
-		r.protoProxy.MarkDirty(false)
-	}
-
 	r.participants[participant.Identity()] = participant
 	r.participantOpts[participant.Identity()] = opts
 	r.participantRequestSources[participant.Identity()] = requestSource
+	r.protoProxy.MarkDirty(participant.IsRecorder() && r.updateActiveRecordingLocked())
 
 	if r.onParticipantChanged != nil {
 		r.onParticipantChanged(participant)
@@ -669,25 +773,12 @@ func (r *Room) RemoveParticipant(identity livekit.ParticipantIdentity, pID livek
 	delete(r.participantRequestSources, identity)
 	delete(r.hasPublished, identity)
 	delete(r.agentParticpants, identity)
+	delete(r.pendingParticipants, identity)
 	if !p.Hidden() {
 		r.protoRoom.NumParticipants--
 	}
 
-	immediateChange := false
-	if p.IsRecorder() {
-		activeRecording := false
-		for _, op := range r.participants {
-			if op.IsRecorder() {
-				activeRecording = true
-				break
-			}
-		}
-
-		if r.protoRoom.ActiveRecording != activeRecording {
-			r.protoRoom.ActiveRecording = activeRecording
-			immediateChange = true
-		}
-	}
+	immediateChange := p.IsRecorder() && r.updateActiveRecordingLocked()
 	r.lock.Unlock()
 	r.protoProxy.MarkDirty(immediateChange)
 
@@ -700,6 +791,9 @@ func (r *Room) RemoveParticipant(identity livekit.ParticipantIdentity, pID livek
 
 	// send broadcast only if it's not already closed
 	sendUpdates := !p.IsDisconnected()
+	if !p.Hidden() {
+		r.recordEvent(newParticipantEvent(RoomEventParticipantLeft, p))
+	}
 
 	// remove all published tracks
 	for _, t := range p.GetPublishedTracks() {
@@ -848,7 +942,10 @@ func (r *Room) ResolveMediaTrackForSubscriber(subIdentity livekit.ParticipantIde
 	pub := r.GetParticipantByID(info.PublisherID)
 	// when publisher is not found, we will assume it doesn't have permission to access
 	if pub != nil {
-		res.HasPermission = pub.HasPermission(trackID, subIdentity)
+		res.HasPermission = pub.HasPermission(trackID, subIdentity) && r.hasRecordingConsent(subIdentity, pub)
+	} else {
+		// tracks of RTP ingests are open to everyone in the room
+		res.HasPermission = r.hasRTPIngestTrack(trackID)
 	}
 
 	return res
@@ -909,9 +1006,16 @@ func (r *Room) Close(reason types.ParticipantCloseReason) {
 	r.lock.Unlock()
 
 	r.Logger.Infow("closing room")
+	r.closeTrackForwards()
+	r.closeTrackRecordings()
+	r.closeRelayForwards()
+	r.closeRTPIngests()
 	for _, p := range r.GetParticipants() {
 		_ = p.Close(true, reason, false)
 	}
+	for _, vp := range r.GetVirtualParticipants() {
+		vp.close()
+	}
 
 	r.protoProxy.Stop()
 
@@ -932,10 +1036,114 @@ func (r *Room) SendDataPacket(dp *livekit.DataPacket, kind livekit.DataPacket_Ki
 	r.onDataPacket(nil, kind, dp)
 }
 
+// AddVirtualParticipant adds a participant without transports, see VirtualParticipant
+func (r *Room) AddVirtualParticipant(vp *VirtualParticipant) error {
+	r.lock.Lock()
+	if r.IsClosed() {
+		r.lock.Unlock()
+		return ErrRoomClosed
+	}
+	identity := vp.Identity()
+	if identity == "" {
+		r.lock.Unlock()
+		return ErrEmptyIdentity
+	}
+	if r.participants[identity] != nil || r.virtualParticipants[identity] != nil {
+		r.lock.Unlock()
+		return ErrAlreadyJoined
+	}
+	r.virtualParticipants[identity] = vp
+	r.lock.Unlock()
+
+	r.Logger.Infow("virtual participant added", "participant", identity, "pID", vp.ID())
+	r.broadcastVirtualParticipantState(vp.ToProto())
+	return nil
+}
+
+func (r *Room) GetVirtualParticipant(identity livekit.ParticipantIdentity) *VirtualParticipant {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.virtualParticipants[identity]
+}
+
+func (r *Room) GetVirtualParticipants() []*VirtualParticipant {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return maps.Values(r.virtualParticipants)
+}
+
+// UpdateVirtualParticipant applies non-empty fields and notifies the room
+func (r *Room) UpdateVirtualParticipant(identity livekit.ParticipantIdentity, name string, metadata string, attributes map[string]string) (*livekit.ParticipantInfo, error) {
+	vp := r.GetVirtualParticipant(identity)
+	if vp == nil {
+		return nil, ErrVirtualParticipantNotFound
+	}
+	pi := vp.Update(name, metadata, attributes)
+	r.broadcastVirtualParticipantState(pi)
+	return pi, nil
+}
+
+func (r *Room) RemoveVirtualParticipant(identity livekit.ParticipantIdentity) error {
+	r.lock.Lock()
+	vp := r.virtualParticipants[identity]
+	delete(r.virtualParticipants, identity)
+	r.lock.Unlock()
+	if vp == nil {
+		return ErrVirtualParticipantNotFound
+	}
+
+	r.Logger.Infow("virtual participant removed", "participant", identity, "pID", vp.ID())
+	r.broadcastVirtualParticipantState(vp.close())
+	return nil
+}
+
+// SendVirtualParticipantData sends a data packet on behalf of a virtual participant
+func (r *Room) SendVirtualParticipantData(identity livekit.ParticipantIdentity, kind livekit.DataPacket_Kind, dp *livekit.DataPacket) error {
+	vp := r.GetVirtualParticipant(identity)
+	if vp == nil {
+		return ErrVirtualParticipantNotFound
+	}
+	dp.ParticipantIdentity = string(identity)
+	if u := dp.GetUser(); u != nil {
+		u.ParticipantSid = string(vp.ID())
+		u.ParticipantIdentity = string(identity)
+	}
+	BroadcastDataPacketForRoom(r, nil, kind, dp, r.Logger)
+	r.deliverToVirtualParticipants(identity, dp)
+	return nil
+}
+
+func (r *Room) broadcastVirtualParticipantState(pi *livekit.ParticipantInfo) {
+	r.protoProxy.MarkDirty(false)
+	updates := r.pushAndDequeueUpdates(pi, types.ParticipantCloseReasonNone, true)
+	r.sendParticipantUpdates(updates)
+}
+
+// deliverToVirtualParticipants hands a data packet already broadcast to the room to its virtual recipients
+func (r *Room) deliverToVirtualParticipants(source livekit.ParticipantIdentity, dp *livekit.DataPacket) {
+	u := dp.GetUser()
+	if u == nil {
+		return
+	}
+	for _, vp := range r.GetVirtualParticipants() {
+		identity := vp.Identity()
+		if identity == source {
+			continue
+		}
+		if len(u.DestinationSids) > 0 || len(dp.DestinationIdentities) > 0 {
+			if !slices.Contains(u.DestinationSids, string(vp.ID())) && !slices.Contains(dp.DestinationIdentities, string(identity)) {
+				continue
+			}
+		}
+		vp.handleDataPacket(dp)
+	}
+}
+
 func (r *Room) SetMetadata(metadata string) <-chan struct{} {
 	r.lock.Lock()
 	r.protoRoom.Metadata = metadata
 	r.lock.Unlock()
+	r.recordEvent(&RoomEvent{Type: RoomEventRoomMetadataChanged, Timestamp: time.Now().UnixMilli(), Metadata: metadata})
 	return r.protoProxy.MarkDirty(true)
 }
 
@@ -1114,12 +1322,19 @@ func (r *Room) getOtherParticipantInfo(identity livekit.ParticipantIdentity) []*
 			pi = append(pi, p.ToProto())
 		}
 	}
+	for _, vp := range r.GetVirtualParticipants() {
+		pi = append(pi, vp.ToProto())
+	}
 
 	return pi
 }
 
 // checks if participant should be autosubscribed to new tracks, assumes lock is already acquired
 func (r *Room) autoSubscribe(participant types.LocalParticipant) bool {
+	if r.conferenceBridge != nil && participant.Kind() == livekit.ParticipantInfo_SIP {
+		// subscribed to the loudest speakers by the conference bridge
+		return false
+	}
 	opts := r.participantOpts[participant.Identity()]
 	// default to true if no options are set
 	if opts != nil && !opts.AutoSubscribe {
@@ -1128,6 +1343,52 @@ func (r *Room) autoSubscribe(participant types.LocalParticipant) bool {
 	return true
 }
 
+// SetAlternativeURLProvider sets the source of the signaling URL sent to joining participants for region failover
+func (r *Room) SetAlternativeURLProvider(alternativeURL func() string) {
+	r.lock.Lock()
+	r.alternativeURL = alternativeURL
+	r.lock.Unlock()
+}
+
+// SetIDGenerator sets the generator of the IDs of virtual participants created in the room
+func (r *Room) SetIDGenerator(g idgen.Generator) {
+	r.lock.Lock()
+	r.idGenerator = g
+	r.lock.Unlock()
+}
+
+func (r *Room) newParticipantID(identity livekit.ParticipantIdentity) livekit.ParticipantID {
+	r.lock.RLock()
+	g := r.idGenerator
+	r.lock.RUnlock()
+	return g.ParticipantID(r.ID(), identity)
+}
+
+// SetMaxPublisherShare overrides the share of a subscriber's channel capacity a single publisher may use,
+// for the participants of the room and the ones joining later. 0 disables the limit.
+func (r *Room) SetMaxPublisherShare(share float64) {
+	r.maxPublisherShare.Store(&share)
+	for _, participant := range r.GetParticipants() {
+		participant.SetSubscriberMaxPublisherShare(share)
+	}
+}
+
+// ClearMaxPublisherShare removes the override of the room, participants go back to defaultShare
+func (r *Room) ClearMaxPublisherShare(defaultShare float64) {
+	r.maxPublisherShare.Store(nil)
+	for _, participant := range r.GetParticipants() {
+		participant.SetSubscriberMaxPublisherShare(defaultShare)
+	}
+}
+
+// MaxPublisherShare returns the override of the room, ok is false when the config applies
+func (r *Room) MaxPublisherShare() (share float64, ok bool) {
+	if p := r.maxPublisherShare.Load(); p != nil {
+		return *p, true
+	}
+	return 0, false
+}
+
 func (r *Room) createJoinResponseLocked(participant types.LocalParticipant, iceServers []*livekit.ICEServer) *livekit.JoinResponse {
 	// gather other participants and send join response
 	otherParticipants := make([]*livekit.ParticipantInfo, 0, len(r.participants))
@@ -1136,6 +1397,14 @@ func (r *Room) createJoinResponseLocked(participant types.LocalParticipant, iceS
 			otherParticipants = append(otherParticipants, p.ToProto())
 		}
 	}
+	for _, vp := range r.virtualParticipants {
+		otherParticipants = append(otherParticipants, vp.ToProto())
+	}
+
+	var alternativeURL string
+	if r.alternativeURL != nil {
+		alternativeURL = r.alternativeURL()
+	}
 
 	iceConfig := participant.GetICEConfig()
 	hasICEFallback := iceConfig.GetPreferencePublisher() != livekit.ICECandidateType_ICT_NONE || iceConfig.GetPreferenceSubscriber() != livekit.ICECandidateType_ICT_NONE
@@ -1153,6 +1422,7 @@ func (r *Room) createJoinResponseLocked(participant types.LocalParticipant, iceS
 		ServerInfo:           r.serverInfo,
 		ServerVersion:        r.serverInfo.Version,
 		ServerRegion:         r.serverInfo.Region,
+		AlternativeUrl:       alternativeURL,
 		SifTrailer:           r.trailer,
 		EnabledPublishCodecs: participant.GetEnabledPublishCodecs(),
 		FastPublish:          participant.CanPublish() && !hasICEFallback,
@@ -1161,8 +1431,18 @@ func (r *Room) createJoinResponseLocked(participant types.LocalParticipant, iceS
 
 // a ParticipantImpl in the room added a new track, subscribe other participants to it
 func (r *Room) onTrackPublished(participant types.LocalParticipant, track types.MediaTrack) {
+	if participant.Identity() == SharedPlaybackIdentity {
+		// streams published while the shared playback is paused start muted
+		if state := r.SharedPlayback(); state != nil && !state.Playing {
+			participant.SetTrackMuted(track.ID(), true, true)
+		}
+	}
+
 	// publish participant update, since track state is changed
 	r.broadcastParticipantState(participant, broadcastOptions{skipSource: true})
+	if !participant.Hidden() {
+		r.recordEvent(newTrackEvent(RoomEventTrackPublished, participant, track))
+	}
 
 	r.lock.RLock()
 	// subscribe all existing participants to this MediaTrack
@@ -1237,6 +1517,15 @@ func (r *Room) onTrackPublished(participant types.LocalParticipant, track types.
 			}
 		}()
 	}
+
+	r.lock.RLock()
+	onRelayTrackPublished := r.onRelayTrackPublished
+	r.lock.RUnlock()
+	if onRelayTrackPublished != nil {
+		if relayTrack, ok := newRelayTrack(participant, track); ok {
+			onRelayTrackPublished(relayTrack)
+		}
+	}
 }
 
 func (r *Room) onTrackUpdated(p types.LocalParticipant, _ types.MediaTrack) {
@@ -1249,6 +1538,9 @@ func (r *Room) onTrackUpdated(p types.LocalParticipant, _ types.MediaTrack) {
 
 func (r *Room) onTrackUnpublished(p types.LocalParticipant, track types.MediaTrack) {
 	r.trackManager.RemoveTrack(track)
+	if !p.Hidden() {
+		r.recordEvent(newTrackEvent(RoomEventTrackUnpublished, p, track))
+	}
 	if !p.IsClosed() {
 		r.broadcastParticipantState(p, broadcastOptions{skipSource: true})
 	}
@@ -1261,13 +1553,44 @@ func (r *Room) onParticipantUpdate(p types.LocalParticipant) {
 	r.protoProxy.MarkDirty(false)
 	// immediately notify when permissions or metadata changed
 	r.broadcastParticipantState(p, broadcastOptions{immediate: true})
+	if !p.Hidden() {
+		r.recordEvent(newParticipantEvent(RoomEventParticipantUpdated, p))
+	}
 	if r.onParticipantChanged != nil {
 		r.onParticipantChanged(p)
 	}
 }
 
 func (r *Room) onDataPacket(source types.LocalParticipant, kind livekit.DataPacket_Kind, dp *livekit.DataPacket) {
+	if source != nil {
+		if !r.allowDataPacket(source) {
+			return
+		}
+		switch dp.GetUser().GetTopic() {
+		case DataHistoryTopic:
+			r.sendDataHistory(source, dp.GetUser().GetPayload())
+			return
+		case RecordingConsentTopic:
+			r.handleRecordingConsentAnswer(source, dp.GetUser().GetPayload())
+			return
+		case ClientEventsTopic:
+			r.handleClientEvents(source, dp.GetUser().GetPayload())
+			return
+		case DiagnosticsUploadTopic:
+			r.handleDiagnosticsUploadResult(source, dp.GetUser().GetPayload())
+			return
+		case AdaptiveStreamHintsTopic:
+			r.handleAdaptiveStreamHints(source, dp.GetUser().GetPayload())
+			return
+		}
+		if dtmf := dp.GetSipDtmf(); dtmf != nil && r.conferenceBridge != nil && source.Kind() == livekit.ParticipantInfo_SIP {
+			r.handleConferenceBridgeDTMF(source, dtmf.Digit)
+		}
+	}
+	r.recordDataMessage(source, kind, dp)
+	r.recordArchiveDataPacket(source, dp)
 	BroadcastDataPacketForRoom(r, source, kind, dp, r.Logger)
+	r.deliverToVirtualParticipants("", dp)
 }
 
 func (r *Room) onMetrics(source types.LocalParticipant, dp *livekit.DataPacket) {
@@ -1295,6 +1618,10 @@ func (r *Room) subscribeToExistingTracks(p types.LocalParticipant) {
 			p.SubscribeToTrack(track.ID())
 		}
 	}
+	for _, track := range r.getRTPIngestTracks() {
+		trackIDs = append(trackIDs, track.ID())
+		p.SubscribeToTrack(track.ID())
+	}
 	if len(trackIDs) > 0 {
 		r.Logger.Debugw("subscribed participant to existing tracks", "trackID", trackIDs)
 	}
@@ -1509,6 +1836,13 @@ func (r *Room) audioUpdateWorker() {
 
 		lastActiveMap = nextActiveMap
 
+		if r.audioFeedback != nil {
+			r.detectAudioFeedback()
+		}
+		if r.conferenceBridge != nil {
+			r.updateConferenceBridge(activeSpeakers)
+		}
+
 		time.Sleep(time.Duration(r.audioConfig.UpdateInterval) * time.Millisecond)
 	}
 }
@@ -1534,6 +1868,12 @@ func (r *Room) connectionQualityWorker() {
 				nowConnectionInfos[p.ID()] = q
 			}
 		}
+		r.connectionInfos.Store(&nowConnectionInfos)
+		r.recordSessionQuality(nowConnectionInfos)
+
+		if r.qualityAlerts != nil {
+			r.detectQualityAlerts(participants, nowConnectionInfos)
+		}
 
 		// send an update if there is a change
 		//   - new participant
@@ -1666,12 +2006,19 @@ func (r *Room) launchPublisherAgents(ads []*agentDispatch, p types.Participant)
 func (r *Room) handleNewJobs(ad *livekit.AgentDispatch, inc *sutils.IncrementalDispatcher[*livekit.Job]) {
 	inc.ForEach(func(job *livekit.Job) {
 		r.agentStore.StoreAgentJob(context.Background(), job)
+		var joined types.LocalParticipant
 		r.lock.Lock()
 		ad.State.Jobs = append(ad.State.Jobs, job)
 		if job.State != nil && job.State.ParticipantIdentity != "" {
 			r.agentParticpants[livekit.ParticipantIdentity(job.State.ParticipantIdentity)] = newAgentJob(job)
+			joined = r.participants[livekit.ParticipantIdentity(job.State.ParticipantIdentity)]
 		}
 		r.lock.Unlock()
+
+		// the agent may have joined before the job was reported
+		if joined != nil {
+			r.updateAgentParticipant(joined)
+		}
 	})
 }
 
@@ -1813,40 +2160,7 @@ func connectionDetailsFields(infos []*types.ICEConnectionInfo) []interface{} {
 	var fields []interface{}
 	connectionType := types.ICEConnectionTypeUnknown
 	for _, info := range infos {
-		candidates := make([]string, 0, len(info.Remote)+len(info.Local))
-		for _, c := range info.Local {
-			cStr := "[local]"
-			if c.SelectedOrder != 0 {
-				cStr += fmt.Sprintf("[selected:%d]", c.SelectedOrder)
-			} else if c.Filtered {
-				cStr += "[filtered]"
-			}
-			if c.Trickle {
-				cStr += "[trickle]"
-			}
-			cStr += " " + c.Local.String()
-			candidates = append(candidates, cStr)
-		}
-		for _, c := range info.Remote {
-			cStr := "[remote]"
-			if c.SelectedOrder != 0 {
-				cStr += fmt.Sprintf("[selected:%d]", c.SelectedOrder)
-			} else if c.Filtered {
-				cStr += "[filtered]"
-			}
-			if c.Trickle {
-				cStr += "[trickle]"
-			}
-			cStr += " " + fmt.Sprintf("%s %s %s:%d", c.Remote.NetworkType(), c.Remote.Type(), MaybeTruncateIP(c.Remote.Address()), c.Remote.Port())
-			if relatedAddress := c.Remote.RelatedAddress(); relatedAddress != nil {
-				relatedAddr := MaybeTruncateIP(relatedAddress.Address)
-				if relatedAddr != "" {
-					cStr += " " + fmt.Sprintf(" related %s:%d", relatedAddr, relatedAddress.Port)
-				}
-			}
-			candidates = append(candidates, cStr)
-		}
-		if len(candidates) > 0 {
+		if candidates := iceCandidateStrings(info); len(candidates) > 0 {
 			fields = append(fields, fmt.Sprintf("%sCandidates", strings.ToLower(info.Transport.String())), candidates)
 		}
 		if info.Type != types.ICEConnectionTypeUnknown {
@@ -1856,3 +2170,40 @@ func connectionDetailsFields(infos []*types.ICEConnectionInfo) []interface{} {
 	fields = append(fields, "connectionType", connectionType)
 	return fields
 }
+
+func iceCandidateStrings(info *types.ICEConnectionInfo) []string {
+	candidates := make([]string, 0, len(info.Remote)+len(info.Local))
+	for _, c := range info.Local {
+		cStr := "[local]"
+		if c.SelectedOrder != 0 {
+			cStr += fmt.Sprintf("[selected:%d]", c.SelectedOrder)
+		} else if c.Filtered {
+			cStr += "[filtered]"
+		}
+		if c.Trickle {
+			cStr += "[trickle]"
+		}
+		cStr += " " + c.Local.String()
+		candidates = append(candidates, cStr)
+	}
+	for _, c := range info.Remote {
+		cStr := "[remote]"
+		if c.SelectedOrder != 0 {
+			cStr += fmt.Sprintf("[selected:%d]", c.SelectedOrder)
+		} else if c.Filtered {
+			cStr += "[filtered]"
+		}
+		if c.Trickle {
+			cStr += "[trickle]"
+		}
+		cStr += " " + fmt.Sprintf("%s %s %s:%d", c.Remote.NetworkType(), c.Remote.Type(), MaybeTruncateIP(c.Remote.Address()), c.Remote.Port())
+		if relatedAddress := c.Remote.RelatedAddress(); relatedAddress != nil {
+			relatedAddr := MaybeTruncateIP(relatedAddress.Address)
+			if relatedAddr != "" {
+				cStr += " " + fmt.Sprintf(" related %s:%d", relatedAddr, relatedAddress.Port)
+			}
+		}
+		candidates = append(candidates, cStr)
+	}
+	return candidates
+}
@@ -15,13 +15,16 @@
 package rtc
 
 import (
+	"encoding/json"
 	"fmt"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/utils"
 	"github.com/livekit/protocol/webhook"
@@ -721,6 +724,102 @@ func TestDataChannel(t *testing.T) {
 	})
 }
 
+func TestVirtualParticipants(t *testing.T) {
+	t.Run("virtual participant is announced and listed for joiners", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		p := rm.GetParticipants()[0].(*typesfakes.FakeLocalParticipant)
+		// latest update about the bot received by p
+		botUpdate := func() *livekit.ParticipantInfo {
+			for i := p.SendParticipantUpdateCallCount() - 1; i >= 0; i-- {
+				for _, pi := range p.SendParticipantUpdateArgsForCall(i) {
+					if pi.Identity == "bot" {
+						return pi
+					}
+				}
+			}
+			return nil
+		}
+
+		vp := NewVirtualParticipant("PA_bot", "bot", "Bot", "", map[string]string{"role": "scorekeeper"})
+		require.NoError(t, rm.AddVirtualParticipant(vp))
+		require.ErrorIs(t, rm.AddVirtualParticipant(NewVirtualParticipant("PA_bot", "bot", "", "", nil)), ErrAlreadyJoined)
+		require.ErrorIs(t, rm.Join(NewMockParticipant("bot", types.CurrentProtocol, false, false), nil, nil, iceServersForRoom), ErrAlreadyJoined)
+
+		require.Equal(t, livekit.ParticipantInfo_ACTIVE, botUpdate().State)
+
+		pNew := NewMockParticipant("new", types.CurrentProtocol, false, false)
+		require.NoError(t, rm.Join(pNew, nil, nil, iceServersForRoom))
+		res := pNew.SendJoinResponseArgsForCall(0)
+		require.True(t, slices.ContainsFunc(res.OtherParticipants, func(pi *livekit.ParticipantInfo) bool {
+			return pi.Identity == "bot" && pi.Attributes["role"] == "scorekeeper"
+		}))
+
+		closed := false
+		vp.OnClose(func(*VirtualParticipant) {
+			closed = true
+		})
+		require.NoError(t, rm.RemoveVirtualParticipant("bot"))
+		require.True(t, closed)
+		require.Nil(t, rm.GetVirtualParticipant("bot"))
+		require.Equal(t, livekit.ParticipantInfo_DISCONNECTED, botUpdate().State)
+		require.ErrorIs(t, rm.RemoveVirtualParticipant("bot"), ErrVirtualParticipantNotFound)
+	})
+
+	t.Run("virtual participant exchanges data", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		participants := rm.GetParticipants()
+		p := participants[0].(*typesfakes.FakeLocalParticipant)
+
+		vp := NewVirtualParticipant("PA_bot", "bot", "", "", nil)
+		var received []*livekit.DataPacket
+		vp.OnDataPacket(func(_ *VirtualParticipant, dp *livekit.DataPacket) {
+			received = append(received, dp)
+		})
+		require.NoError(t, rm.AddVirtualParticipant(vp))
+
+		// addressed to another participant
+		packet := &livekit.DataPacket{
+			ParticipantIdentity:   string(p.Identity()),
+			DestinationIdentities: []string{string(participants[1].Identity())},
+			Value: &livekit.DataPacket_User{
+				User: &livekit.UserPacket{Payload: []byte("other")},
+			},
+		}
+		p.OnDataPacketArgsForCall(0)(p, livekit.DataPacket_RELIABLE, packet)
+		require.Empty(t, received)
+
+		packet = &livekit.DataPacket{
+			ParticipantIdentity:   string(p.Identity()),
+			DestinationIdentities: []string{"bot"},
+			Value: &livekit.DataPacket_User{
+				User: &livekit.UserPacket{Payload: []byte("hello bot")},
+			},
+		}
+		p.OnDataPacketArgsForCall(0)(p, livekit.DataPacket_RELIABLE, packet)
+		require.Len(t, received, 1)
+		require.Equal(t, []byte("hello bot"), received[0].GetUser().Payload)
+
+		// sent by the virtual participant to everyone else
+		sent := p.SendDataPacketCallCount()
+		require.NoError(t, rm.SendVirtualParticipantData("bot", livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+			Value: &livekit.DataPacket_User{
+				User: &livekit.UserPacket{Payload: []byte("score")},
+			},
+		}))
+		require.Equal(t, sent+1, p.SendDataPacketCallCount())
+		_, encoded := p.SendDataPacketArgsForCall(sent)
+		var dp livekit.DataPacket
+		require.NoError(t, proto.Unmarshal(encoded, &dp))
+		require.Equal(t, "bot", dp.ParticipantIdentity)
+		require.Equal(t, string(vp.ID()), dp.GetUser().ParticipantSid)
+		require.Len(t, received, 1)
+
+		require.ErrorIs(t, rm.SendVirtualParticipantData("unknown", livekit.DataPacket_RELIABLE, &livekit.DataPacket{}), ErrVirtualParticipantNotFound)
+	})
+}
+
 func TestHiddenParticipants(t *testing.T) {
 	t.Run("other participants don't receive hidden updates", func(t *testing.T) {
 		rm := newRoomWithParticipants(t, testRoomOpts{num: 2, numHidden: 1})
@@ -753,6 +852,495 @@ func TestHiddenParticipants(t *testing.T) {
 	})
 }
 
+func TestTimeSyncBeacons(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+	participants := rm.GetParticipants()
+	p0 := participants[0].(*typesfakes.FakeLocalParticipant)
+	p1 := participants[1].(*typesfakes.FakeLocalParticipant)
+	p0.SignalingRTTReturns(200)
+	p1.StateReturns(livekit.ParticipantInfo_JOINED)
+
+	before := time.Now().UnixMilli()
+	rm.sendTimeSyncBeacons()
+	after := time.Now().UnixMilli()
+
+	// beacon is compensated by half of the signaling rtt
+	require.Equal(t, 1, p0.SendTimeSyncBeaconCallCount())
+	ts := p0.SendTimeSyncBeaconArgsForCall(0)
+	require.GreaterOrEqual(t, ts, before+100)
+	require.LessOrEqual(t, ts, after+100)
+
+	// only active participants receive beacons
+	require.Zero(t, p1.SendTimeSyncBeaconCallCount())
+}
+
+func TestSharedPlayback(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+	p := rm.GetParticipants()[0].(*typesfakes.FakeLocalParticipant)
+
+	_, err := rm.UpdateSharedPlayback(&SharedPlaybackCommand{Action: SharedPlaybackPause})
+	require.ErrorIs(t, err, ErrNoSharedPlayback)
+
+	state, err := rm.UpdateSharedPlayback(&SharedPlaybackCommand{Action: SharedPlaybackStart, URL: "https://example.com/movie.mp4"})
+	require.NoError(t, err)
+	require.True(t, state.Playing)
+	require.Equal(t, 1, p.SendDataPacketCallCount())
+	_, data := p.SendDataPacketArgsForCall(0)
+	dp := &livekit.DataPacket{}
+	require.NoError(t, proto.Unmarshal(data, dp))
+	require.Equal(t, SharedPlaybackTopic, dp.GetUser().GetTopic())
+
+	time.Sleep(20 * time.Millisecond)
+	state, err = rm.UpdateSharedPlayback(&SharedPlaybackCommand{Action: SharedPlaybackPause})
+	require.NoError(t, err)
+	require.False(t, state.Playing)
+	require.GreaterOrEqual(t, state.PositionMs, int64(20))
+
+	// position stays frozen while paused
+	paused := state.PositionMs
+	time.Sleep(20 * time.Millisecond)
+	state, err = rm.UpdateSharedPlayback(&SharedPlaybackCommand{Action: SharedPlaybackPause})
+	require.NoError(t, err)
+	require.Equal(t, paused, state.PositionMs)
+
+	state, err = rm.UpdateSharedPlayback(&SharedPlaybackCommand{Action: SharedPlaybackSeek, PositionMs: 60_000})
+	require.NoError(t, err)
+	require.False(t, state.Playing)
+	require.EqualValues(t, 60_000, state.PositionMs)
+
+	state, err = rm.UpdateSharedPlayback(&SharedPlaybackCommand{Action: SharedPlaybackPlay})
+	require.NoError(t, err)
+	require.True(t, state.Playing)
+	require.Equal(t, state, rm.SharedPlayback())
+
+	_, err = rm.UpdateSharedPlayback(&SharedPlaybackCommand{Action: "rewind"})
+	require.ErrorIs(t, err, ErrInvalidSharedPlaybackAction)
+
+	state, err = rm.UpdateSharedPlayback(&SharedPlaybackCommand{Action: SharedPlaybackStop})
+	require.NoError(t, err)
+	require.True(t, state.Stopped)
+	require.Nil(t, rm.SharedPlayback())
+	require.Equal(t, 6, p.SendDataPacketCallCount())
+}
+
+func TestEventReplay(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+	p := rm.GetParticipants()[0].(*typesfakes.FakeLocalParticipant)
+	rm.eventLog = newRoomEventLog(config.EventReplayConfig{MaxEvents: 4, MaxAge: time.Minute})
+
+	now := time.Now().UnixMilli()
+	rm.recordEvent(&RoomEvent{Type: RoomEventParticipantJoined, Timestamp: now - 2*time.Minute.Milliseconds(), Identity: "expired"})
+	rm.recordEvent(&RoomEvent{Type: RoomEventParticipantJoined, Timestamp: now - 2000, Identity: "dropped"})
+	rm.recordEvent(&RoomEvent{Type: RoomEventParticipantJoined, Timestamp: now - 1000, Identity: "agent"})
+	rm.recordEvent(&RoomEvent{Type: RoomEventParticipantUpdated, Timestamp: now - 900, Identity: "agent", Metadata: "old"})
+	rm.recordEvent(&RoomEvent{Type: RoomEventTrackPublished, Timestamp: now - 800, Identity: "agent", TrackSid: "TR_audio"})
+	rm.recordEvent(&RoomEvent{Type: RoomEventParticipantUpdated, Timestamp: now - 700, Identity: "agent", Metadata: "new"})
+
+	// bounded in number, condensed to the latest update
+	events := rm.eventLog.since(0)
+	require.Len(t, events, 3)
+	require.Equal(t, RoomEventParticipantJoined, events[0].Type)
+	require.Equal(t, RoomEventTrackPublished, events[1].Type)
+	require.Equal(t, "new", events[2].Metadata)
+	require.Len(t, rm.eventLog.since(now-750), 1)
+
+	// only sent to participants asking for it
+	rm.sendEventReplay(p)
+	require.Zero(t, p.SendDataPacketCallCount())
+
+	p.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: map[string]string{EventReplayAttribute: "60"}})
+	rm.sendEventReplay(p)
+	require.Equal(t, 1, p.SendDataPacketCallCount())
+	_, data := p.SendDataPacketArgsForCall(0)
+	dp := &livekit.DataPacket{}
+	require.NoError(t, proto.Unmarshal(data, dp))
+	require.Equal(t, EventReplayTopic, dp.GetUser().GetTopic())
+	var replay RoomEventReplay
+	require.NoError(t, json.Unmarshal(dp.GetUser().GetPayload(), &replay))
+	require.True(t, replay.Final)
+	require.Len(t, replay.Events, 3)
+}
+
+func TestDataHistory(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+	participants := rm.GetParticipants()
+	sender := participants[0].(*typesfakes.FakeLocalParticipant)
+	joiner := participants[1].(*typesfakes.FakeLocalParticipant)
+	rm.dataHistoryConfig = config.DataHistoryConfig{MaxMessages: 2, MaxAge: time.Minute}
+	rm.SetDataMessageStore(newMemoryDataMessageStore(rm.dataHistoryConfig))
+
+	userPacket := func(topic string, payload string, destinations ...string) *livekit.DataPacket {
+		return &livekit.DataPacket{
+			DestinationIdentities: destinations,
+			Value: &livekit.DataPacket_User{
+				User: &livekit.UserPacket{Payload: []byte(payload), Topic: &topic},
+			},
+		}
+	}
+	rm.onDataPacket(sender, livekit.DataPacket_RELIABLE, userPacket("chat", "dropped"))
+	rm.onDataPacket(sender, livekit.DataPacket_RELIABLE, userPacket("chat", "hello"))
+	rm.onDataPacket(sender, livekit.DataPacket_LOSSY, userPacket("chat", "lossy"))
+	rm.onDataPacket(sender, livekit.DataPacket_RELIABLE, userPacket("chat", "private", string(joiner.Identity())))
+	rm.onDataPacket(sender, livekit.DataPacket_RELIABLE, userPacket("reactions", "clap"))
+	sent := joiner.SendDataPacketCallCount()
+
+	// only sent back to the participant asking for it
+	rm.onDataPacket(joiner, livekit.DataPacket_RELIABLE, userPacket(DataHistoryTopic, `{"topic":"chat"}`))
+	require.Equal(t, sent+1, joiner.SendDataPacketCallCount())
+	require.Zero(t, sender.SendDataPacketCallCount())
+	_, data := joiner.SendDataPacketArgsForCall(sent)
+	dp := &livekit.DataPacket{}
+	require.NoError(t, proto.Unmarshal(data, dp))
+	require.Equal(t, DataHistoryTopic, dp.GetUser().GetTopic())
+	var history DataHistory
+	require.NoError(t, json.Unmarshal(dp.GetUser().GetPayload(), &history))
+	require.True(t, history.Final)
+	require.Len(t, history.Messages, 1)
+	require.Equal(t, "hello", string(history.Messages[0].Payload))
+	require.Equal(t, string(sender.Identity()), history.Messages[0].Identity)
+
+	rm.onDataPacket(joiner, livekit.DataPacket_RELIABLE, userPacket(DataHistoryTopic, `{"limit":1}`))
+	_, data = joiner.SendDataPacketArgsForCall(sent + 1)
+	require.NoError(t, proto.Unmarshal(data, dp))
+	require.NoError(t, json.Unmarshal(dp.GetUser().GetPayload(), &history))
+	require.Len(t, history.Messages, 1)
+	require.Equal(t, "clap", string(history.Messages[0].Payload))
+}
+
+func TestRecordingConsent(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+	rm.recordingConsentConfig = config.RecordingConsentConfig{ExcludeWithoutConsent: true}
+	participants := rm.GetParticipants()
+	publisher := participants[0].(*typesfakes.FakeLocalParticipant)
+	viewer := participants[1].(*typesfakes.FakeLocalParticipant)
+
+	recorder := NewMockParticipant("recorder", types.CurrentProtocol, false, false)
+	recorder.IsRecorderReturns(true)
+	require.NoError(t, rm.Join(recorder, nil, nil, iceServersForRoom))
+
+	// everyone is asked once a recorder is active
+	rm.requestRecordingConsent(recorder)
+	require.Zero(t, recorder.SetAttributesCallCount())
+	for _, p := range []*typesfakes.FakeLocalParticipant{publisher, viewer} {
+		require.Equal(t, 1, p.SetAttributesCallCount())
+		require.Equal(t, map[string]string{RecordingConsentAttribute: string(RecordingConsentPending)}, p.SetAttributesArgsForCall(0))
+	}
+
+	// only recorders need consent
+	require.False(t, rm.hasRecordingConsent(recorder.Identity(), publisher))
+	require.True(t, rm.hasRecordingConsent(viewer.Identity(), publisher))
+
+	_, err := rm.SetRecordingConsent(publisher.Identity(), RecordingConsentPending)
+	require.ErrorIs(t, err, ErrInvalidRecordingConsent)
+	_, err = rm.SetRecordingConsent("unknown", RecordingConsentGranted)
+	require.ErrorIs(t, err, ErrParticipantNotFound)
+
+	// participants answer on the consent topic
+	topic := RecordingConsentTopic
+	rm.onDataPacket(publisher, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{Payload: []byte(`{"granted":true}`), Topic: &topic},
+		},
+	})
+	require.Equal(t, 2, publisher.SetAttributesCallCount())
+	require.Equal(t, map[string]string{RecordingConsentAttribute: string(RecordingConsentGranted)}, publisher.SetAttributesArgsForCall(1))
+	require.Zero(t, viewer.SendDataPacketCallCount())
+
+	publisher.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: map[string]string{RecordingConsentAttribute: string(RecordingConsentGranted)}})
+	require.True(t, rm.hasRecordingConsent(recorder.Identity(), publisher))
+}
+
+func TestRecordingConsentResumesSubscriptions(t *testing.T) {
+	// subscriptions refused for lack of consent are otherwise retried once the timeout elapsed
+	timeout := subscriptionTimeout
+	subscriptionTimeout = time.Minute
+	defer func() { subscriptionTimeout = timeout }()
+
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+	rm.recordingConsentConfig = config.RecordingConsentConfig{ExcludeWithoutConsent: true}
+	publisher := rm.GetParticipants()[0].(*typesfakes.FakeLocalParticipant)
+	publisher.HasPermissionReturns(true)
+	track := NewMockTrack(livekit.TrackType_VIDEO, "webcam")
+	track.IsOpenReturns(true)
+	track.AddSubscriberCalls(func(sub types.LocalParticipant) (types.SubscribedTrack, error) {
+		st := &typesfakes.FakeSubscribedTrack{}
+		st.IDReturns(track.ID())
+		st.SubscriberReturns(sub)
+		st.MediaTrackReturns(track)
+		return st, nil
+	})
+	publisher.GetPublishedTracksReturns([]types.MediaTrack{track})
+	rm.trackManager.AddTrack(track, publisher.Identity(), publisher.ID())
+
+	sm := newTestSubscriptionManager(t)
+	defer sm.Close(false)
+	recorder := sm.params.Participant.(*typesfakes.FakeLocalParticipant)
+	recorder.IsRecorderReturns(true)
+	require.NoError(t, rm.Join(recorder, nil, nil, iceServersForRoom))
+	sm.params.TrackResolver = rm.ResolveMediaTrackForSubscriber
+
+	sm.SubscribeToTrack(track.ID())
+	s := sm.subscriptions[track.ID()]
+	require.Eventually(t, func() bool {
+		_, waiting := s.getEventWaitUntil(time.Now())
+		return waiting
+	}, subSettleTimeout, subCheckInterval, "subscription should wait for consent")
+
+	publisher.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: map[string]string{RecordingConsentAttribute: string(RecordingConsentGranted)}})
+	_, err := rm.SetRecordingConsent(publisher.Identity(), RecordingConsentGranted)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return !s.needsSubscribe()
+	}, subSettleTimeout, subCheckInterval, "recorder should be subscribed once consent is granted")
+}
+
+func TestRecordingState(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+	require.False(t, rm.GetRecordingState().Active)
+
+	changed := make(chan bool, 4)
+	rm.OnRecordingChanged(func(active bool) {
+		changed <- active
+	})
+
+	// the first active job starts recording, further jobs do not change it
+	startedAt := time.Now().Add(-time.Minute)
+	require.True(t, rm.UpdateRecordingJob(RecordingJob{ID: "EG_1", Kind: "room_composite", Active: true, StartedAt: startedAt}))
+	require.True(t, <-changed)
+	// room updates are refreshed asynchronously
+	require.Eventually(t, func() bool { return rm.ToProto().ActiveRecording }, 5*time.Second, 10*time.Millisecond)
+	require.False(t, rm.UpdateRecordingJob(RecordingJob{ID: "EG_2", Kind: "track", Active: true}))
+
+	state := rm.GetRecordingState()
+	require.True(t, state.Active)
+	require.Len(t, state.Jobs, 2)
+	require.Equal(t, "EG_1", state.Jobs[0].ID)
+	require.True(t, state.Jobs[0].StartedAt.Equal(startedAt))
+
+	// recording continues while a recorder is in the room
+	recorder := NewMockParticipant("recorder", types.CurrentProtocol, false, false)
+	recorder.IsRecorderReturns(true)
+	require.NoError(t, rm.Join(recorder, nil, nil, iceServersForRoom))
+	require.False(t, rm.UpdateRecordingJob(RecordingJob{ID: "EG_1"}))
+	require.False(t, rm.UpdateRecordingJob(RecordingJob{ID: "EG_2"}))
+	state = rm.GetRecordingState()
+	require.True(t, state.Active)
+	require.Empty(t, state.Jobs)
+	require.Equal(t, 1, state.Recorders)
+
+	rm.RemoveParticipant(recorder.Identity(), recorder.ID(), types.ParticipantCloseReasonClientRequestLeave)
+	require.False(t, <-changed)
+	require.False(t, rm.GetRecordingState().Active)
+}
+
+func TestRoomArchive(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+	require.Nil(t, rm.Archive())
+
+	rm.EnableArchive(2)
+	sender := rm.GetParticipants()[0]
+	rm.recordEvent(&RoomEvent{Type: RoomEventParticipantJoined, Timestamp: 1000, Identity: "pa", ParticipantSid: "PA_1", Name: "first"})
+	rm.recordEvent(&RoomEvent{Type: RoomEventTrackPublished, Timestamp: 1100, Identity: "pa", ParticipantSid: "PA_1", TrackSid: "TR_1"})
+	rm.recordEvent(&RoomEvent{Type: RoomEventParticipantUpdated, Timestamp: 1200, Identity: "pa", ParticipantSid: "PA_1", Name: "renamed"})
+	rm.recordEvent(&RoomEvent{Type: RoomEventTrackUnpublished, Timestamp: 1300, Identity: "pa", ParticipantSid: "PA_1", TrackSid: "TR_1"})
+	rm.recordEvent(&RoomEvent{Type: RoomEventParticipantLeft, Timestamp: 1400, Identity: "pa", ParticipantSid: "PA_1"})
+	rm.recordEvent(&RoomEvent{Type: RoomEventParticipantJoined, Timestamp: 1500, Identity: "pb", ParticipantSid: "PA_2"})
+	rm.recordEvent(&RoomEvent{Type: RoomEventParticipantJoined, Timestamp: 1600, Identity: "pc", ParticipantSid: "PA_3"})
+	rm.recordEvent(&RoomEvent{Type: RoomEventRoomMetadataChanged, Timestamp: 1700, Metadata: "meta"})
+
+	topic := "chat"
+	for _, payload := range []string{"hi", "there"} {
+		rm.onDataPacket(sender, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+			Value: &livekit.DataPacket_User{User: &livekit.UserPacket{Payload: []byte(payload), Topic: &topic}},
+		})
+	}
+
+	archive := rm.Archive()
+	require.NotNil(t, archive)
+	require.Equal(t, rm.ToProto().Sid, archive.Sid)
+	require.True(t, archive.Truncated)
+
+	require.Len(t, archive.Participants, 2)
+	require.Equal(t, "renamed", archive.Participants[0].Name)
+	require.Equal(t, int64(1400), archive.Participants[0].LeftAt)
+	// still present when the room closed
+	require.Equal(t, archive.ClosedAt, archive.Participants[1].LeftAt)
+
+	require.Len(t, archive.Tracks, 1)
+	require.Equal(t, int64(1100), archive.Tracks[0].PublishedAt)
+	require.Equal(t, int64(1300), archive.Tracks[0].UnpublishedAt)
+
+	require.Len(t, archive.MetadataChanges, 2)
+	require.Equal(t, RoomEventRoomMetadataChanged, archive.MetadataChanges[1].Type)
+
+	require.Equal(t, []*ArchivedDataStats{
+		{Identity: string(sender.Identity()), Topic: "chat", Packets: 2, Bytes: 7},
+	}, archive.DataMessages)
+}
+
+func TestWaitingRoom(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+
+	granted := &auth.VideoGrant{RoomJoin: true}
+	granted.SetCanSubscribe(true)
+	granted.SetCanPublish(true)
+	pending := NewMockParticipant("pending", types.CurrentProtocol, false, false)
+	pending.ClaimGrantsReturns(&auth.ClaimGrants{
+		Video:      granted,
+		Attributes: map[string]string{PendingAttribute: "true"},
+	})
+	other := NewMockParticipant("other", types.CurrentProtocol, false, false)
+	require.NoError(t, rm.Join(pending, nil, nil, iceServersForRoom))
+	require.NoError(t, rm.Join(other, nil, nil, iceServersForRoom))
+
+	// waits hidden, without permission to subscribe or publish
+	require.Equal(t, 1, pending.SetPermissionCallCount())
+	require.True(t, pending.SetPermissionArgsForCall(0).Hidden)
+	require.False(t, pending.SetPermissionArgsForCall(0).CanSubscribe)
+	require.Zero(t, other.SetPermissionCallCount())
+	require.Len(t, rm.GetPendingParticipants(), 1)
+	require.True(t, rm.PendingParticipantPermission("pending").CanSubscribe)
+
+	_, err := rm.AdmitParticipants([]livekit.ParticipantIdentity{"other"})
+	require.ErrorIs(t, err, ErrParticipantNotPending)
+
+	// admitting everyone restores the granted permission
+	admitted, err := rm.AdmitParticipants(nil)
+	require.NoError(t, err)
+	require.Len(t, admitted, 1)
+	require.Equal(t, 2, pending.SetPermissionCallCount())
+	require.True(t, granted.MatchesPermission(pending.SetPermissionArgsForCall(1)))
+	require.Equal(t, map[string]string{PendingAttribute: ""}, pending.SetAttributesArgsForCall(0))
+	require.Empty(t, rm.GetPendingParticipants())
+	require.Nil(t, rm.PendingParticipantPermission("pending"))
+
+	_, err = rm.AdmitParticipants([]livekit.ParticipantIdentity{"pending"})
+	require.ErrorIs(t, err, ErrParticipantNotPending)
+}
+
+func TestParticipantRoles(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+
+	granted := &auth.VideoGrant{RoomJoin: true}
+	granted.SetCanSubscribe(true)
+	granted.SetCanPublish(true)
+	viewer := NewMockParticipant("viewer", types.CurrentProtocol, false, false)
+	viewer.ClaimGrantsReturns(&auth.ClaimGrants{
+		Video:      granted,
+		Attributes: map[string]string{RoleAttribute: string(ParticipantRoleViewer)},
+	})
+	require.NoError(t, rm.Join(viewer, nil, nil, iceServersForRoom))
+
+	// the role in the token overrides the granted permission
+	require.Equal(t, 1, viewer.SetPermissionCallCount())
+	require.False(t, viewer.SetPermissionArgsForCall(0).CanPublish)
+	require.True(t, viewer.SetPermissionArgsForCall(0).CanSubscribe)
+
+	_, err := rm.UpdateParticipantRole("viewer", "owner")
+	require.ErrorIs(t, err, ErrInvalidParticipantRole)
+	_, err = rm.UpdateParticipantRole("unknown", ParticipantRoleSpeaker)
+	require.ErrorIs(t, err, ErrParticipantNotFound)
+
+	_, err = rm.UpdateParticipantRole("viewer", ParticipantRoleSpeaker)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{RoleAttribute: string(ParticipantRoleSpeaker)}, viewer.SetAttributesArgsForCall(0))
+	require.Equal(t, 2, viewer.SetPermissionCallCount())
+	require.True(t, viewer.SetPermissionArgsForCall(1).CanPublish)
+
+	t.Run("moderation follows role rank", func(t *testing.T) {
+		host := map[string]string{RoleAttribute: string(ParticipantRoleHost)}
+		coHost := map[string]string{RoleAttribute: string(ParticipantRoleCoHost)}
+		speaker := map[string]string{RoleAttribute: string(ParticipantRoleSpeaker)}
+
+		require.True(t, CanModerate(host, coHost, RoleActionRemoveOthers))
+		require.True(t, CanModerate(coHost, speaker, RoleActionMuteOthers))
+		require.True(t, CanModerate(coHost, nil, RoleActionMuteOthers))
+		require.False(t, CanModerate(coHost, host, RoleActionMuteOthers))
+		require.False(t, CanModerate(coHost, speaker, RoleActionChangeRoles))
+		require.False(t, CanModerate(speaker, nil, RoleActionRemoveOthers))
+		require.False(t, CanModerate(nil, nil, RoleActionMuteOthers))
+	})
+}
+
+func TestAgentParticipantConfig(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+	rm.agentParticipantConfigs = map[string]config.AgentParticipantConfig{
+		"moderator": {
+			AutoSubscribe:     proto.Bool(false),
+			CanPublish:        proto.Bool(false),
+			CanPublishSources: []string{"microphone"},
+			Attributes:        map[string]string{"agent.type": "moderator"},
+		},
+	}
+
+	granted := &auth.VideoGrant{RoomJoin: true, Agent: true}
+	granted.SetCanSubscribe(true)
+	granted.SetCanPublish(true)
+	newAgent := func(identity string, agentName string) *typesfakes.FakeLocalParticipant {
+		p := NewMockParticipant(livekit.ParticipantIdentity(identity), types.CurrentProtocol, false, false)
+		p.ClaimGrantsReturns(&auth.ClaimGrants{Video: granted})
+		rm.lock.Lock()
+		rm.agentParticpants[p.Identity()] = newAgentJob(&livekit.Job{AgentName: agentName})
+		rm.lock.Unlock()
+		return p
+	}
+
+	moderator := newAgent("moderator-agent", "moderator")
+	require.NoError(t, rm.Join(moderator, nil, &ParticipantOptions{AutoSubscribe: true}, iceServersForRoom))
+	require.Equal(t, 1, moderator.SetPermissionCallCount())
+	permission := moderator.SetPermissionArgsForCall(0)
+	require.False(t, permission.CanPublish)
+	require.True(t, permission.CanSubscribe)
+	require.Equal(t, []livekit.TrackSource{livekit.TrackSource_MICROPHONE}, permission.CanPublishSources)
+	require.Equal(t, map[string]string{"agent.type": "moderator"}, moderator.SetAttributesArgsForCall(0))
+	rm.lock.RLock()
+	require.False(t, rm.autoSubscribe(moderator))
+	rm.lock.RUnlock()
+
+	// agents without overrides keep the defaults
+	assistant := newAgent("assistant-agent", "assistant")
+	require.NoError(t, rm.Join(assistant, nil, &ParticipantOptions{AutoSubscribe: true}, iceServersForRoom))
+	require.Zero(t, assistant.SetPermissionCallCount())
+	require.Zero(t, assistant.SetAttributesCallCount())
+}
+
+func TestMaxPublisherShare(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+
+	_, ok := rm.MaxPublisherShare()
+	require.False(t, ok)
+
+	rm.SetMaxPublisherShare(0.5)
+	for _, p := range rm.GetParticipants() {
+		fp := p.(*typesfakes.FakeLocalParticipant)
+		require.Equal(t, 1, fp.SetSubscriberMaxPublisherShareCallCount())
+		require.Equal(t, 0.5, fp.SetSubscriberMaxPublisherShareArgsForCall(0))
+	}
+
+	// participants joining later get the override of the room
+	p := NewMockParticipant("late", types.CurrentProtocol, false, false)
+	require.NoError(t, rm.Join(p, nil, &ParticipantOptions{AutoSubscribe: true}, iceServersForRoom))
+	require.Equal(t, 1, p.SetSubscriberMaxPublisherShareCallCount())
+	require.Equal(t, 0.5, p.SetSubscriberMaxPublisherShareArgsForCall(0))
+
+	rm.ClearMaxPublisherShare(0.8)
+	_, ok = rm.MaxPublisherShare()
+	require.False(t, ok)
+	require.Equal(t, 0.8, p.SetSubscriberMaxPublisherShareArgsForCall(1))
+}
+
 func TestRoomUpdate(t *testing.T) {
 	t.Run("updates are sent when participant joined", func(t *testing.T) {
 		rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
@@ -0,0 +1,253 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// RoomArchive is the final state of a closed room, with the history of its participants and tracks.
+// Timestamps are in milliseconds. Truncated is set when history was dropped to stay within limits.
+type RoomArchive struct {
+	Name            string                 `json:"name"`
+	Sid             string                 `json:"sid"`
+	Metadata        string                 `json:"metadata,omitempty"`
+	CreatedAt       int64                  `json:"created_at"`
+	ClosedAt        int64                  `json:"closed_at"`
+	Participants    []*ArchivedParticipant `json:"participants"`
+	Tracks          []*ArchivedTrack       `json:"tracks"`
+	MetadataChanges []*RoomEvent           `json:"metadata_changes"`
+	DataMessages    []*ArchivedDataStats   `json:"data_messages"`
+	Truncated       bool                   `json:"truncated,omitempty"`
+}
+
+// ArchivedParticipant is a session of a participant, with the name, metadata and attributes it had last
+type ArchivedParticipant struct {
+	Identity   string            `json:"identity"`
+	Sid        string            `json:"sid"`
+	Kind       string            `json:"kind"`
+	Name       string            `json:"name,omitempty"`
+	Metadata   string            `json:"metadata,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	JoinedAt   int64             `json:"joined_at"`
+	LeftAt     int64             `json:"left_at,omitempty"`
+}
+
+type ArchivedTrack struct {
+	Sid            string `json:"sid"`
+	Name           string `json:"name,omitempty"`
+	Type           string `json:"type"`
+	Source         string `json:"source"`
+	Identity       string `json:"identity"`
+	ParticipantSid string `json:"participant_sid"`
+	PublishedAt    int64  `json:"published_at"`
+	UnpublishedAt  int64  `json:"unpublished_at,omitempty"`
+}
+
+// ArchivedDataStats counts the user data packets a participant sent on a topic,
+// the identity is empty for packets sent by the server
+type ArchivedDataStats struct {
+	Identity string `json:"identity,omitempty"`
+	Topic    string `json:"topic,omitempty"`
+	Packets  int64  `json:"packets"`
+	Bytes    int64  `json:"bytes"`
+}
+
+type archivedDataKey struct {
+	identity livekit.ParticipantIdentity
+	topic    string
+}
+
+// roomArchiveRecorder collects the history of a room for its archive, each kind of history bounded by maxEntries
+type roomArchiveRecorder struct {
+	maxEntries int
+
+	lock            sync.Mutex
+	participants    []*ArchivedParticipant
+	participantSids map[string]*ArchivedParticipant
+	tracks          []*ArchivedTrack
+	trackSids       map[string]*ArchivedTrack
+	metadataChanges []*RoomEvent
+	dataStats       map[archivedDataKey]*ArchivedDataStats
+	truncated       bool
+}
+
+func newRoomArchiveRecorder(maxEntries int) *roomArchiveRecorder {
+	return &roomArchiveRecorder{
+		maxEntries:      maxEntries,
+		participantSids: make(map[string]*ArchivedParticipant),
+		trackSids:       make(map[string]*ArchivedTrack),
+		dataStats:       make(map[archivedDataKey]*ArchivedDataStats),
+	}
+}
+
+func (a *roomArchiveRecorder) addEvent(e *RoomEvent) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	switch e.Type {
+	case RoomEventParticipantJoined:
+		if len(a.participants) >= a.maxEntries {
+			a.truncated = true
+			return
+		}
+		p := &ArchivedParticipant{
+			Identity:   e.Identity,
+			Sid:        e.ParticipantSid,
+			Kind:       e.Kind,
+			Name:       e.Name,
+			Metadata:   e.Metadata,
+			Attributes: e.Attributes,
+			JoinedAt:   e.Timestamp,
+		}
+		a.participants = append(a.participants, p)
+		a.participantSids[p.Sid] = p
+
+	case RoomEventParticipantLeft:
+		if p := a.participantSids[e.ParticipantSid]; p != nil {
+			p.LeftAt = e.Timestamp
+		}
+
+	case RoomEventParticipantUpdated:
+		if p := a.participantSids[e.ParticipantSid]; p != nil {
+			p.Name = e.Name
+			p.Metadata = e.Metadata
+			p.Attributes = e.Attributes
+		}
+		a.addMetadataChangeLocked(e)
+
+	case RoomEventRoomMetadataChanged:
+		a.addMetadataChangeLocked(e)
+
+	case RoomEventTrackPublished:
+		if len(a.tracks) >= a.maxEntries {
+			a.truncated = true
+			return
+		}
+		t := &ArchivedTrack{
+			Sid:            e.TrackSid,
+			Name:           e.TrackName,
+			Type:           e.TrackType,
+			Source:         e.TrackSource,
+			Identity:       e.Identity,
+			ParticipantSid: e.ParticipantSid,
+			PublishedAt:    e.Timestamp,
+		}
+		a.tracks = append(a.tracks, t)
+		a.trackSids[t.Sid] = t
+
+	case RoomEventTrackUnpublished:
+		if t := a.trackSids[e.TrackSid]; t != nil {
+			t.UnpublishedAt = e.Timestamp
+		}
+	}
+}
+
+func (a *roomArchiveRecorder) addMetadataChangeLocked(e *RoomEvent) {
+	if len(a.metadataChanges) >= a.maxEntries {
+		a.truncated = true
+		return
+	}
+	a.metadataChanges = append(a.metadataChanges, e)
+}
+
+func (a *roomArchiveRecorder) addDataPacket(identity livekit.ParticipantIdentity, user *livekit.UserPacket) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	key := archivedDataKey{identity: identity, topic: user.GetTopic()}
+	stats := a.dataStats[key]
+	if stats == nil {
+		if len(a.dataStats) >= a.maxEntries {
+			a.truncated = true
+			return
+		}
+		stats = &ArchivedDataStats{Identity: string(identity), Topic: key.topic}
+		a.dataStats[key] = stats
+	}
+	stats.Packets++
+	stats.Bytes += int64(len(user.Payload))
+}
+
+func (a *roomArchiveRecorder) archive(room *livekit.Room) *RoomArchive {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	archive := &RoomArchive{
+		Name:            room.Name,
+		Sid:             room.Sid,
+		Metadata:        room.Metadata,
+		CreatedAt:       room.CreationTime * 1000,
+		ClosedAt:        time.Now().UnixMilli(),
+		Participants:    make([]*ArchivedParticipant, 0, len(a.participants)),
+		Tracks:          make([]*ArchivedTrack, 0, len(a.tracks)),
+		MetadataChanges: append([]*RoomEvent{}, a.metadataChanges...),
+		DataMessages:    make([]*ArchivedDataStats, 0, len(a.dataStats)),
+		Truncated:       a.truncated,
+	}
+	// participants and tracks still present when the room closed end with it
+	for _, p := range a.participants {
+		pc := *p
+		if pc.LeftAt == 0 {
+			pc.LeftAt = archive.ClosedAt
+		}
+		archive.Participants = append(archive.Participants, &pc)
+	}
+	for _, t := range a.tracks {
+		tc := *t
+		if tc.UnpublishedAt == 0 {
+			tc.UnpublishedAt = archive.ClosedAt
+		}
+		archive.Tracks = append(archive.Tracks, &tc)
+	}
+	for _, stats := range a.dataStats {
+		sc := *stats
+		archive.DataMessages = append(archive.DataMessages, &sc)
+	}
+	return archive
+}
+
+// EnableArchive starts collecting the history of the room for its archive, it has to be called
+// before participants join
+func (r *Room) EnableArchive(maxEntries int) {
+	r.archive.Store(newRoomArchiveRecorder(maxEntries))
+}
+
+// Archive returns the final state of the room, nil when archival is not enabled
+func (r *Room) Archive() *RoomArchive {
+	recorder := r.archive.Load()
+	if recorder == nil {
+		return nil
+	}
+	return recorder.archive(r.ToProto())
+}
+
+func (r *Room) recordArchiveDataPacket(source types.LocalParticipant, dp *livekit.DataPacket) {
+	recorder := r.archive.Load()
+	user := dp.GetUser()
+	if recorder == nil || user == nil {
+		return
+	}
+	var identity livekit.ParticipantIdentity
+	if source != nil {
+		identity = source.Identity()
+	}
+	recorder.addDataPacket(identity, user)
+}
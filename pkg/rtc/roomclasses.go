@@ -0,0 +1,163 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/pion/ice/v2"
+	"go.uber.org/atomic"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/logger/pionlogger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// RoomClasses holds the UDP muxes of the room classes of config.RoomClassesConfig. The peer connections of
+// the participants of a room with a class use the ports of the class in turn.
+type RoomClasses struct {
+	conf    config.RoomClassesConfig
+	classes map[string]*roomClass
+}
+
+type roomClass struct {
+	muxes []ice.UDPMux
+	next  atomic.Uint32
+}
+
+// NewRoomClasses listens on the ports of every class, it returns nil when no class is configured
+func NewRoomClasses(conf config.RoomClassesConfig) (*RoomClasses, error) {
+	if len(conf.Classes) == 0 {
+		return nil, nil
+	}
+
+	ips, err := roomClassIPs()
+	if err != nil {
+		return nil, err
+	}
+	c := &RoomClasses{
+		conf:    conf,
+		classes: make(map[string]*roomClass, len(conf.Classes)),
+	}
+	for name, classConf := range conf.Classes {
+		class := &roomClass{}
+		c.classes[name] = class
+		for port := classConf.UDPPortStart; port <= classConf.UDPPortEnd; port++ {
+			mux, err := newRoomClassMux(ips, port, classConf.DSCP)
+			if err != nil {
+				c.Close()
+				return nil, fmt.Errorf("could not listen on udp port %d of room class %q: %w", port, name, err)
+			}
+			class.muxes = append(class.muxes, mux)
+		}
+		logger.Infow("listening for room class",
+			"class", name,
+			"udpPorts", []int{classConf.UDPPortStart, classConf.UDPPortEnd},
+			"dscp", classConf.DSCP,
+		)
+	}
+	return c, nil
+}
+
+// roomClassIPs returns the addresses the ports of room classes are bound to
+func roomClassIPs() ([]net.IP, error) {
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && !ipNet.IP.IsLinkLocalUnicast() {
+			ips = append(ips, ipNet.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no address to listen on")
+	}
+	return ips, nil
+}
+
+// newRoomClassMux binds a port on every address, a mux on an unspecified address could not tell the
+// addresses of its candidates
+func newRoomClassMux(ips []net.IP, port int, dscp int) (ice.UDPMux, error) {
+	muxLogger := pionlogger.NewLoggerFactory(logger.GetLogger()).NewLogger("room_class_mux")
+	var muxes []ice.UDPMux
+	closeAll := func() {
+		for _, mux := range muxes {
+			_ = mux.Close()
+		}
+	}
+	for _, ip := range ips {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: ip, Port: port})
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		if dscp != 0 {
+			if err := setDSCP(conn, ip, dscp); err != nil {
+				logger.Warnw("could not set dscp", err, "addr", conn.LocalAddr(), "dscp", dscp)
+			}
+		}
+		muxes = append(muxes, ice.NewUDPMuxDefault(ice.UDPMuxParams{
+			Logger:  muxLogger,
+			UDPConn: conn,
+		}))
+	}
+	return ice.NewMultiUDPMuxDefault(muxes...), nil
+}
+
+// setDSCP marks the packets sent from conn, DSCP is the upper six bits of the traffic class
+func setDSCP(conn *net.UDPConn, ip net.IP, dscp int) error {
+	if ip.To4() != nil {
+		return ipv4.NewConn(conn).SetTOS(dscp << 2)
+	}
+	return ipv6.NewConn(conn).SetTrafficClass(dscp << 2)
+}
+
+// ClassOf returns the class of a room, or an empty string when it has none
+func (c *RoomClasses) ClassOf(roomName livekit.RoomName) string {
+	if c == nil {
+		return ""
+	}
+	return c.conf.ClassOf(string(roomName))
+}
+
+// UDPMux returns the mux the next peer connection of a class uses, nil for rooms without a class
+func (c *RoomClasses) UDPMux(class string) ice.UDPMux {
+	if c == nil {
+		return nil
+	}
+	rc := c.classes[class]
+	if rc == nil || len(rc.muxes) == 0 {
+		return nil
+	}
+	return rc.muxes[int(rc.next.Inc()-1)%len(rc.muxes)]
+}
+
+func (c *RoomClasses) Close() {
+	if c == nil {
+		return
+	}
+	for _, class := range c.classes {
+		for _, mux := range class.muxes {
+			_ = mux.Close()
+		}
+	}
+}
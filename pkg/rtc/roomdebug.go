@@ -0,0 +1,159 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+// RoomDebugSnapshot is the state of a live room on its node, for debugging
+type RoomDebugSnapshot struct {
+	Room         string                      `json:"room"`
+	RoomID       string                      `json:"room_id"`
+	CreatedAt    int64                       `json:"created_at"`
+	Participants []*ParticipantDebugSnapshot `json:"participants"`
+	// participants whose session is being migrated to or from this node
+	PendingMigrations []livekit.ParticipantIdentity `json:"pending_migrations,omitempty"`
+	// the room itself is being moved to another node
+	Migrating  bool      `json:"migrating"`
+	CapturedAt time.Time `json:"captured_at"`
+}
+
+type ParticipantDebugSnapshot struct {
+	Identity     livekit.ParticipantIdentity `json:"identity"`
+	ID           livekit.ParticipantID       `json:"id"`
+	State        string                      `json:"state"`
+	MigrateState string                      `json:"migrate_state"`
+	Ready        bool                        `json:"ready"`
+	ConnectedAt  time.Time                   `json:"connected_at"`
+	Transports   []*TransportDebugSnapshot   `json:"transports"`
+	Tracks       []*TrackDebugSnapshot       `json:"tracks,omitempty"`
+	// tracks this participant is subscribed to
+	Subscriptions []*SubscriptionDebugSnapshot `json:"subscriptions,omitempty"`
+}
+
+type TransportDebugSnapshot struct {
+	Transport      string `json:"transport"`
+	ConnectionType string `json:"connection_type"`
+	// local and remote candidates, selected pairs are marked with their order
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+type TrackDebugSnapshot struct {
+	ID     livekit.TrackID       `json:"id"`
+	Kind   string                `json:"kind"`
+	Source string                `json:"source"`
+	Muted  bool                  `json:"muted"`
+	Layers []*livekit.VideoLayer `json:"layers,omitempty"`
+	// bits per second needed to forward each layer by spatial then temporal layer, for each codec
+	Bitrates map[string][][]int64 `json:"bitrates,omitempty"`
+}
+
+type SubscriptionDebugSnapshot struct {
+	TrackID   livekit.TrackID             `json:"track_id"`
+	Publisher livekit.ParticipantIdentity `json:"publisher"`
+	Muted     bool                        `json:"muted"`
+	Bound     bool                        `json:"bound"`
+	// spatial/temporal layers, -1 when none
+	CurrentLayer buffer.VideoLayer `json:"current_layer"`
+	MaxLayer     buffer.VideoLayer `json:"max_layer"`
+	Deficient    bool              `json:"deficient"`
+	// bits per second requested by the current layer
+	BandwidthRequested int64 `json:"bandwidth_requested"`
+}
+
+func (r *Room) DebugSnapshot() *RoomDebugSnapshot {
+	info := r.ToProto()
+	snapshot := &RoomDebugSnapshot{
+		Room:       info.Name,
+		RoomID:     info.Sid,
+		CreatedAt:  info.CreationTime,
+		CapturedAt: time.Now(),
+	}
+	for _, p := range r.GetParticipants() {
+		snapshot.Participants = append(snapshot.Participants, participantDebugSnapshot(p))
+		if p.MigrateState() != types.MigrateStateComplete {
+			snapshot.PendingMigrations = append(snapshot.PendingMigrations, p.Identity())
+		}
+	}
+	return snapshot
+}
+
+func participantDebugSnapshot(p types.LocalParticipant) *ParticipantDebugSnapshot {
+	snapshot := &ParticipantDebugSnapshot{
+		Identity:     p.Identity(),
+		ID:           p.ID(),
+		State:        p.State().String(),
+		MigrateState: p.MigrateState().String(),
+		Ready:        p.IsReady(),
+		ConnectedAt:  p.ConnectedAt(),
+	}
+	for _, info := range p.GetICEConnectionInfo() {
+		snapshot.Transports = append(snapshot.Transports, &TransportDebugSnapshot{
+			Transport:      info.Transport.String(),
+			ConnectionType: string(info.Type),
+			Candidates:     iceCandidateStrings(info),
+		})
+	}
+	for _, track := range p.GetPublishedTracks() {
+		snapshot.Tracks = append(snapshot.Tracks, trackDebugSnapshot(track))
+	}
+	for _, st := range p.GetSubscribedTracks() {
+		sub := &SubscriptionDebugSnapshot{
+			TrackID:      st.ID(),
+			Publisher:    st.PublisherIdentity(),
+			Muted:        st.IsMuted(),
+			Bound:        st.IsBound(),
+			CurrentLayer: buffer.InvalidLayer,
+			MaxLayer:     buffer.InvalidLayer,
+		}
+		if dt := st.DownTrack(); dt != nil {
+			sub.CurrentLayer = dt.CurrentLayer()
+			sub.MaxLayer = dt.MaxLayer()
+			sub.Deficient = dt.IsDeficient()
+			sub.BandwidthRequested = dt.BandwidthRequested()
+		}
+		snapshot.Subscriptions = append(snapshot.Subscriptions, sub)
+	}
+	return snapshot
+}
+
+func trackDebugSnapshot(track types.MediaTrack) *TrackDebugSnapshot {
+	ti := track.ToProto()
+	snapshot := &TrackDebugSnapshot{
+		ID:     track.ID(),
+		Kind:   track.Kind().String(),
+		Source: track.Source().String(),
+		Muted:  track.IsMuted(),
+		Layers: ti.Layers,
+	}
+	for _, receiver := range track.Receivers() {
+		_, brs := receiver.GetLayeredBitrate()
+		bitrates := make([][]int64, 0, len(brs))
+		for _, spatial := range brs {
+			bitrates = append(bitrates, append([]int64(nil), spatial[:]...))
+		}
+		if snapshot.Bitrates == nil {
+			snapshot.Bitrates = make(map[string][][]int64)
+		}
+		snapshot.Bitrates[receiver.Codec().MimeType] = bitrates
+	}
+	return snapshot
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// RelayTrack describes a track of a room relayed to a relay node of the room. The relay node publishes it
+// through an RTP ingest, as a track of a virtual participant with the identity of the publisher, and the
+// hosting node forwards a single layer of the track to that ingest.
+type RelayTrack struct {
+	TrackSid    string `json:"track_sid"`
+	Identity    string `json:"identity"`
+	Name        string `json:"name,omitempty"`
+	TrackName   string `json:"track_name,omitempty"`
+	MimeType    string `json:"mime_type"`
+	Source      string `json:"source,omitempty"`
+	PayloadType uint8  `json:"payload_type"`
+	SSRC        uint32 `json:"ssrc"`
+	Width       uint32 `json:"width,omitempty"`
+	Height      uint32 `json:"height,omitempty"`
+}
+
+// newRelayTrack returns false for tracks which are not bound yet, or use a codec RTP ingests do not accept
+func newRelayTrack(participant types.Participant, track types.MediaTrack) (*RelayTrack, bool) {
+	receivers := track.Receivers()
+	if len(receivers) == 0 {
+		return nil, false
+	}
+	codec := receivers[0].GetPrimaryReceiverForRed().Codec()
+	if _, ok := rtpIngestCodec(&RTPIngestRequest{MimeType: codec.MimeType}); !ok {
+		return nil, false
+	}
+
+	var ssrc [4]byte
+	if _, err := rand.Read(ssrc[:]); err != nil {
+		return nil, false
+	}
+	ti := track.ToProto()
+	return &RelayTrack{
+		TrackSid:    string(track.ID()),
+		Identity:    string(participant.Identity()),
+		Name:        participant.ToProto().Name,
+		TrackName:   ti.Name,
+		MimeType:    codec.MimeType,
+		Source:      ti.Source.String(),
+		PayloadType: uint8(codec.PayloadType),
+		SSRC:        binary.BigEndian.Uint32(ssrc[:]) | 1,
+		Width:       ti.Width,
+		Height:      ti.Height,
+	}, true
+}
+
+// RelayTracks returns the tracks published by the participants of the room that can be relayed
+func (r *Room) RelayTracks() []*RelayTrack {
+	var tracks []*RelayTrack
+	for _, p := range r.GetParticipants() {
+		for _, track := range p.GetPublishedTracks() {
+			if relayTrack, ok := newRelayTrack(p, track); ok {
+				tracks = append(tracks, relayTrack)
+			}
+		}
+	}
+	return tracks
+}
+
+// OnRelayTrackPublished is called when a participant publishes a track that can be relayed
+func (r *Room) OnRelayTrackPublished(f func(track *RelayTrack)) {
+	r.lock.Lock()
+	r.onRelayTrackPublished = f
+	r.lock.Unlock()
+}
+
+// StartRelayTrack publishes a relayed track in the relay room, with an RTP ingest the hosting node
+// forwards the track to
+func (r *Room) StartRelayTrack(host string, track *RelayTrack, transport string) (*RTPIngestInfo, error) {
+	return r.StartRTPIngest(host, &RTPIngestRequest{
+		Transport:   transport,
+		Identity:    track.Identity,
+		Name:        track.Name,
+		TrackName:   track.TrackName,
+		MimeType:    track.MimeType,
+		Source:      track.Source,
+		PayloadType: track.PayloadType,
+		SSRC:        track.SSRC,
+		Width:       track.Width,
+		Height:      track.Height,
+	})
+}
+
+// ForwardRelayTrack forwards a relayed track to the RTP ingest publishing it on a relay node. Relay forwards
+// are not listed with the track forwards of the room, they end with the track or the room and onEnded is
+// called then.
+func (r *Room) ForwardRelayTrack(track *RelayTrack, ingest *RTPIngestInfo, quality livekit.VideoQuality, onEnded func()) (string, error) {
+	if r.IsClosed() {
+		return "", ErrRoomClosed
+	}
+	info := r.trackManager.GetTrackInfo(livekit.TrackID(track.TrackSid))
+	if info == nil {
+		return "", ErrTrackNotFound
+	}
+
+	transport := ingest.Transport
+	if transport == "" {
+		// ingests of nodes running an earlier version
+		transport = TrackForwardTransportRTP
+	}
+	f, err := NewTrackForwarder(r.Name(), info, &TrackForwardRequest{
+		TrackSid:  track.TrackSid,
+		Transport: transport,
+		Address:   ingest.Address,
+		Quality:   quality.String(),
+		SRTPKey:   ingest.SRTPKey,
+		SSRC:      track.SSRC,
+		Token:     ingest.Token,
+	}, r.Logger)
+	if err != nil {
+		return "", err
+	}
+	f.OnClose(func(f *TrackForwarder) {
+		r.lock.Lock()
+		delete(r.relayForwarders, f.ID())
+		r.lock.Unlock()
+		if onEnded != nil {
+			onEnded()
+		}
+	})
+
+	r.lock.Lock()
+	r.relayForwarders[f.ID()] = f
+	r.lock.Unlock()
+	if err = f.Start(); err != nil {
+		r.lock.Lock()
+		delete(r.relayForwarders, f.ID())
+		r.lock.Unlock()
+		return "", err
+	}
+	return f.ID(), nil
+}
+
+// StopRelayTrackForward stops forwarding a relayed track, e.g. when its relay node left the room
+func (r *Room) StopRelayTrackForward(forwardID string) {
+	r.lock.RLock()
+	f := r.relayForwarders[forwardID]
+	r.lock.RUnlock()
+	if f != nil {
+		f.Close()
+	}
+}
+
+func (r *Room) closeRelayForwards() {
+	r.lock.RLock()
+	forwarders := make([]*TrackForwarder, 0, len(r.relayForwarders))
+	for _, f := range r.relayForwarders {
+		forwarders = append(forwarders, f)
+	}
+	r.lock.RUnlock()
+
+	for _, f := range forwarders {
+		f.closeWithError("room closed")
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// RoomStats are the statistics of a live room aggregated on its node, for operations dashboards
+type RoomStats struct {
+	Room            string `json:"room"`
+	NumParticipants int    `json:"num_participants"`
+	NumPublishers   int    `json:"num_publishers"`
+	// bits per second received from and requested by the participants of the room
+	PublishedBitrate  int64               `json:"published_bitrate"`
+	SubscribedBitrate int64               `json:"subscribed_bitrate"`
+	Participants      []*ParticipantStats `json:"participants"`
+	CapturedAt        time.Time           `json:"captured_at"`
+}
+
+type ParticipantStats struct {
+	Identity livekit.ParticipantIdentity `json:"identity"`
+	State    string                      `json:"state"`
+	// unset until the participant is active
+	ConnectionQuality string  `json:"connection_quality,omitempty"`
+	ConnectionScore   float32 `json:"connection_score,omitempty"`
+	AudioLevel        float64 `json:"audio_level"`
+	Speaking          bool    `json:"speaking"`
+	PublishedTracks   int     `json:"published_tracks"`
+	SubscribedTracks  int     `json:"subscribed_tracks"`
+	// bits per second received for the published video tracks
+	PublishedBitrate int64 `json:"published_bitrate"`
+	// bits per second requested by the layers forwarded to the participant
+	SubscribedBitrate int64 `json:"subscribed_bitrate"`
+}
+
+func (r *Room) Stats() *RoomStats {
+	var connectionInfos map[livekit.ParticipantID]*livekit.ConnectionQualityInfo
+	if infos := r.connectionInfos.Load(); infos != nil {
+		connectionInfos = *infos
+	}
+
+	stats := &RoomStats{
+		Room:       string(r.Name()),
+		CapturedAt: time.Now(),
+	}
+	for _, p := range r.GetParticipants() {
+		if p.Hidden() {
+			continue
+		}
+		ps := participantStats(p, connectionInfos[p.ID()])
+		stats.Participants = append(stats.Participants, ps)
+		stats.NumParticipants++
+		if ps.PublishedTracks > 0 {
+			stats.NumPublishers++
+		}
+		stats.PublishedBitrate += ps.PublishedBitrate
+		stats.SubscribedBitrate += ps.SubscribedBitrate
+	}
+	return stats
+}
+
+func participantStats(p types.LocalParticipant, quality *livekit.ConnectionQualityInfo) *ParticipantStats {
+	tracks := p.GetPublishedTracks()
+	subscribed := p.GetSubscribedTracks()
+	level, speaking := p.GetAudioLevel()
+	ps := &ParticipantStats{
+		Identity:         p.Identity(),
+		State:            p.State().String(),
+		AudioLevel:       level,
+		Speaking:         speaking,
+		PublishedTracks:  len(tracks),
+		SubscribedTracks: len(subscribed),
+	}
+	if quality != nil {
+		ps.ConnectionQuality = quality.Quality.String()
+		ps.ConnectionScore = quality.Score
+	}
+	for _, track := range tracks {
+		if track.Kind() == livekit.TrackType_VIDEO {
+			ps.PublishedBitrate += getPublishedBitrate([]types.MediaTrack{track})
+		}
+	}
+	for _, st := range subscribed {
+		if dt := st.DownTrack(); dt != nil {
+			ps.SubscribedBitrate += dt.BandwidthRequested()
+		}
+	}
+	return ps
+}
@@ -18,12 +18,18 @@ package rtc
 
 import (
 	"sync"
+	"time"
 
 	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 	"github.com/livekit/livekit-server/pkg/utils"
 	"github.com/livekit/protocol/livekit"
 )
 
+// window to collect track changes in, so that a publisher adding or removing many tracks
+// results in a single resolve pass per subscriber
+var trackNotifierBatchWindow = 20 * time.Millisecond
+
 // RoomTrackManager holds tracks that are published to the room
 type RoomTrackManager struct {
 	lock            sync.RWMutex
@@ -41,8 +47,8 @@ type TrackInfo struct {
 func NewRoomTrackManager() *RoomTrackManager {
 	return &RoomTrackManager{
 		tracks:          make(map[livekit.TrackID]*TrackInfo),
-		changedNotifier: utils.NewChangeNotifierManager(),
-		removedNotifier: utils.NewChangeNotifierManager(),
+		changedNotifier: utils.NewBatchedChangeNotifierManager(trackNotifierBatchParams()),
+		removedNotifier: utils.NewBatchedChangeNotifierManager(trackNotifierBatchParams()),
 	}
 }
 
@@ -123,3 +129,11 @@ func (r *RoomTrackManager) GetOrCreateTrackChangeNotifier(trackID livekit.TrackI
 func (r *RoomTrackManager) GetOrCreateTrackRemoveNotifier(trackID livekit.TrackID) *utils.ChangeNotifier {
 	return r.removedNotifier.GetOrCreateNotifier(string(trackID))
 }
+
+func trackNotifierBatchParams() utils.ChangeNotifierBatchParams {
+	return utils.ChangeNotifierBatchParams{
+		Window:              trackNotifierBatchWindow,
+		OnQueueDepthChanged: prometheus.AddTrackNotifierQueueDepth,
+		OnBatchDelivered:    prometheus.RecordTrackNotifierBatch,
+	}
+}
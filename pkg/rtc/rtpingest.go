@@ -0,0 +1,816 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/srtp/v2"
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/mediatransportutil/pkg/bucket"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/streamtracker"
+)
+
+const (
+	RTPIngestPrefix = "RI_"
+
+	// an ingest ends when no packet is received for this long, including before the first one
+	rtpIngestTimeout = time.Minute
+)
+
+// codecs accepted by RTP ingests, with the payload type expected when the request does not set one
+var rtpIngestCodecs = []webrtc.RTPCodecParameters{
+	{RTPCodecCapability: OpusCodecCapability, PayloadType: 111},
+	{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000}, PayloadType: 96},
+	{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP9, ClockRate: 90000, SDPFmtpLine: "profile-id=0"}, PayloadType: 98},
+	{
+		RTPCodecCapability: webrtc.RTPCodecCapability{
+			MimeType:    webrtc.MimeTypeH264,
+			ClockRate:   90000,
+			SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f",
+		},
+		PayloadType: 125,
+	},
+}
+
+// RTPIngestRequest allocates a UDP port on the node hosting the room, the RTP stream received on it is
+// published in the room as a track of a virtual participant
+type RTPIngestRequest struct {
+	// Transport is rtp, the default, or srtp. The SRTP key is generated by the ingest.
+	Transport string `json:"transport,omitempty"`
+	// Identity of the publishing virtual participant, created along with the ingest when not in the room
+	Identity string `json:"identity"`
+	// Name of the participant when it is created
+	Name      string `json:"name,omitempty"`
+	TrackName string `json:"track_name,omitempty"`
+	// MimeType is one of audio/opus, video/VP8, video/VP9 or video/H264
+	MimeType string `json:"mime_type"`
+	// Source is a TrackSource name, MICROPHONE or CAMERA when unset
+	Source string `json:"source,omitempty"`
+	// PayloadType and SSRC the stream is sent with, SSRC is generated when unset
+	PayloadType uint8  `json:"payload_type,omitempty"`
+	SSRC        uint32 `json:"ssrc,omitempty"`
+	Width       uint32 `json:"width,omitempty"`
+	Height      uint32 `json:"height,omitempty"`
+}
+
+// RTPIngestInfo describes an RTP ingest, with what the sender needs to reach it and the counters of what
+// was received. The sender sends the token in a datagram of its own before any RTP, packets are then only
+// accepted from the address it came from. RTCP feedback is sent back to that address. The token can be
+// sent again as a keepalive, an ingest ends after a minute without datagrams. With srtp, packets and
+// feedback are protected with AES_CM_128_HMAC_SHA1_80 and SRTPKey, the base64 master key and salt.
+type RTPIngestInfo struct {
+	IngestID        string `json:"ingest_id"`
+	Room            string `json:"room"`
+	Identity        string `json:"identity"`
+	TrackSid        string `json:"track_sid"`
+	MimeType        string `json:"mime_type"`
+	PayloadType     uint8  `json:"payload_type"`
+	ClockRate       uint32 `json:"clock_rate"`
+	SSRC            uint32 `json:"ssrc"`
+	Address         string `json:"address"`
+	Token           string `json:"token"`
+	Transport       string `json:"transport"`
+	SRTPKey         string `json:"srtp_key,omitempty"`
+	Source          string `json:"source,omitempty"`
+	PacketsReceived uint64 `json:"packets_received"`
+	BytesReceived   uint64 `json:"bytes_received"`
+	PacketsDropped  uint64 `json:"packets_dropped"`
+	StartedAt       int64  `json:"started_at"`
+	EndedAt         int64  `json:"ended_at,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// RTPIngest receives an RTP stream over UDP and publishes it as a track. The track has a single layer and
+// is published by a virtual participant, the ingest ends when it is stopped, times out or the room closes.
+type RTPIngest struct {
+	info      RTPIngestInfo
+	trackInfo *livekit.TrackInfo
+	conn      *net.UDPConn
+	buff      *buffer.Buffer
+	receiver  *rtpIngestReceiver
+	// contexts of received packets, used by the read loop, and of sent feedback
+	srtpIn  *srtp.Context
+	srtpOut *srtp.Context
+	track   *rtpIngestTrack
+	logger  logger.Logger
+
+	lock   sync.Mutex
+	source *net.UDPAddr
+
+	packetsReceived atomic.Uint64
+	bytesReceived   atomic.Uint64
+	packetsDropped  atomic.Uint64
+	closed          atomic.Bool
+	onClose         func(*RTPIngest)
+}
+
+func rtpIngestCodec(req *RTPIngestRequest) (webrtc.RTPCodecParameters, bool) {
+	for _, codec := range rtpIngestCodecs {
+		if strings.EqualFold(codec.MimeType, req.MimeType) {
+			if req.PayloadType != 0 {
+				codec.PayloadType = webrtc.PayloadType(req.PayloadType)
+			}
+			return codec, true
+		}
+	}
+	return webrtc.RTPCodecParameters{}, false
+}
+
+func rtpIngestTrackInfo(req *RTPIngestRequest, codec webrtc.RTPCodecParameters) (*livekit.TrackInfo, error) {
+	ti := &livekit.TrackInfo{
+		Sid:        guid.New(utils.TrackPrefix),
+		Name:       req.TrackName,
+		MimeType:   codec.MimeType,
+		Codecs:     []*livekit.SimulcastCodecInfo{{MimeType: codec.MimeType}},
+		DisableRed: true,
+	}
+	if strings.HasPrefix(codec.MimeType, "audio/") {
+		ti.Type = livekit.TrackType_AUDIO
+		ti.Source = livekit.TrackSource_MICROPHONE
+		ti.Stereo = true
+	} else {
+		ti.Type = livekit.TrackType_VIDEO
+		ti.Source = livekit.TrackSource_CAMERA
+		ti.Width = req.Width
+		ti.Height = req.Height
+		ti.Layers = []*livekit.VideoLayer{{Quality: livekit.VideoQuality_HIGH, Width: req.Width, Height: req.Height}}
+	}
+	if req.Source != "" {
+		source, ok := livekit.TrackSource_value[strings.ToUpper(req.Source)]
+		if !ok {
+			return nil, ErrInvalidRTPIngest
+		}
+		ti.Source = livekit.TrackSource(source)
+	}
+	return ti, nil
+}
+
+// NewRTPIngest validates the request and listens on a UDP port of host, the track is created by Start
+func NewRTPIngest(roomName livekit.RoomName, host string, req *RTPIngestRequest, conf ReceiverConfig, l logger.Logger) (*RTPIngest, error) {
+	if req.Identity == "" {
+		return nil, ErrInvalidRTPIngest
+	}
+	transport := req.Transport
+	if transport == "" {
+		transport = TrackForwardTransportRTP
+	} else if transport != TrackForwardTransportRTP && transport != TrackForwardTransportSRTP {
+		return nil, ErrInvalidRTPIngest
+	}
+	codec, ok := rtpIngestCodec(req)
+	if !ok {
+		return nil, ErrInvalidRTPIngest
+	}
+	if strings.HasPrefix(codec.MimeType, "video/") {
+		codec.RTCPFeedback = []webrtc.RTCPFeedback{{Type: webrtc.TypeRTCPFBNACK}, {Type: webrtc.TypeRTCPFBNACK, Parameter: "pli"}}
+	}
+	ti, err := rtpIngestTrackInfo(req, codec)
+	if err != nil {
+		return nil, err
+	}
+
+	ssrc := req.SSRC
+	if ssrc == 0 {
+		var b [4]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, err
+		}
+		ssrc = binary.BigEndian.Uint32(b[:]) | 1
+	}
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+
+	i := &RTPIngest{
+		info: RTPIngestInfo{
+			IngestID:    guid.New(RTPIngestPrefix),
+			Room:        string(roomName),
+			Identity:    req.Identity,
+			TrackSid:    ti.Sid,
+			MimeType:    codec.MimeType,
+			PayloadType: uint8(codec.PayloadType),
+			ClockRate:   codec.ClockRate,
+			SSRC:        ssrc,
+			Address:     net.JoinHostPort(host, strconv.Itoa(port)),
+			Token:       utils.RandomSecret(),
+			Transport:   transport,
+			Source:      ti.Source.String(),
+			StartedAt:   time.Now().UnixNano(),
+		},
+		trackInfo: ti,
+		conn:      conn,
+	}
+	if transport == TrackForwardTransportSRTP {
+		if err = i.createSRTPContexts(); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	i.logger = l.WithValues("ingestID", i.info.IngestID, "trackID", ti.Sid, "participant", req.Identity)
+
+	i.buff = buffer.NewBuffer(ssrc, conf.PacketBufferSizeVideo, conf.PacketBufferSizeAudio)
+	i.buff.SetLogger(i.logger)
+	i.buff.Bind(webrtc.RTPParameters{Codecs: []webrtc.RTPCodecParameters{codec}}, codec.RTPCodecCapability, 0)
+	i.buff.OnRtcpFeedback(i.sendRTCP)
+	i.receiver = newRTPIngestReceiver(ti, codec, i.buff, i.logger)
+	return i, nil
+}
+
+func (i *RTPIngest) createSRTPContexts() error {
+	key, err := srtpKey("")
+	if err != nil {
+		return err
+	}
+	if i.srtpIn, err = srtp.CreateContext(key[:srtpMasterKeyLen], key[srtpMasterKeyLen:], srtp.ProtectionProfileAes128CmHmacSha1_80); err != nil {
+		return err
+	}
+	if i.srtpOut, err = srtp.CreateContext(key[:srtpMasterKeyLen], key[srtpMasterKeyLen:], srtp.ProtectionProfileAes128CmHmacSha1_80); err != nil {
+		return err
+	}
+	i.info.SRTPKey = base64.StdEncoding.EncodeToString(key)
+	return nil
+}
+
+// Start creates the track with the publisher set in params and starts receiving
+func (i *RTPIngest) Start(params MediaTrackReceiverParams) *rtpIngestTrack {
+	i.track = newRTPIngestTrack(params, i.trackInfo)
+	i.track.SetupReceiver(i.receiver, 0, "")
+
+	go i.receiver.forwardRTP()
+	go i.readLoop()
+	i.logger.Infow("rtp ingest started", "address", i.info.Address, "mime", i.info.MimeType, "ssrc", i.info.SSRC)
+	return i.track
+}
+
+func (i *RTPIngest) OnClose(fn func(*RTPIngest)) {
+	i.onClose = fn
+}
+
+func (i *RTPIngest) ID() string {
+	return i.info.IngestID
+}
+
+func (i *RTPIngest) Identity() livekit.ParticipantIdentity {
+	return livekit.ParticipantIdentity(i.info.Identity)
+}
+
+func (i *RTPIngest) Track() types.MediaTrack {
+	if i.track == nil {
+		return nil
+	}
+	return i.track
+}
+
+func (i *RTPIngest) Info() *RTPIngestInfo {
+	i.lock.Lock()
+	info := i.info
+	i.lock.Unlock()
+	info.PacketsReceived = i.packetsReceived.Load()
+	info.BytesReceived = i.bytesReceived.Load()
+	info.PacketsDropped = i.packetsDropped.Load()
+	return &info
+}
+
+func (i *RTPIngest) readLoop() {
+	buf := make([]byte, bucket.MaxPktSize)
+	for {
+		_ = i.conn.SetReadDeadline(time.Now().Add(rtpIngestTimeout))
+		n, addr, err := i.conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				i.closeWithError("no packets received")
+			} else {
+				i.closeWithError(err.Error())
+			}
+			return
+		}
+		pkt := buf[:n]
+
+		i.lock.Lock()
+		source := i.source
+		isToken := subtle.ConstantTimeCompare(pkt, []byte(i.info.Token)) == 1
+		if source == nil && isToken {
+			i.source = addr
+			i.lock.Unlock()
+			i.logger.Infow("rtp ingest source authenticated", "source", addr)
+			continue
+		}
+		i.lock.Unlock()
+
+		if source == nil || !source.IP.Equal(addr.IP) || source.Port != addr.Port {
+			i.packetsDropped.Inc()
+			continue
+		}
+		if isToken {
+			// keepalive of a sender with nothing to send
+			continue
+		}
+		if n >= 2 && pkt[1] >= 192 && pkt[1] <= 223 {
+			// RTCP multiplexed on the same port, payload types 64-95 are not valid for RTP
+			if i.srtpIn != nil {
+				if pkt, err = i.srtpIn.DecryptRTCP(nil, pkt, nil); err != nil {
+					i.packetsDropped.Inc()
+					continue
+				}
+			}
+			i.handleRTCP(pkt)
+			continue
+		}
+		if n < 12 || binary.BigEndian.Uint32(pkt[8:12]) != i.info.SSRC || pkt[1]&0x7f != i.info.PayloadType {
+			i.packetsDropped.Inc()
+			continue
+		}
+		if i.srtpIn != nil {
+			if pkt, err = i.srtpIn.DecryptRTP(nil, pkt, nil); err != nil {
+				i.packetsDropped.Inc()
+				continue
+			}
+		}
+		if _, err = i.buff.Write(pkt); err != nil {
+			if err == io.EOF {
+				return
+			}
+			i.packetsDropped.Inc()
+			continue
+		}
+		i.packetsReceived.Inc()
+		i.bytesReceived.Add(uint64(n))
+	}
+}
+
+func (i *RTPIngest) handleRTCP(pkt []byte) {
+	pkts, err := rtcp.Unmarshal(pkt)
+	if err != nil {
+		i.packetsDropped.Inc()
+		return
+	}
+	for _, p := range pkts {
+		if sr, ok := p.(*rtcp.SenderReport); ok && sr.SSRC == i.info.SSRC {
+			i.buff.SetSenderReportData(sr.RTPTime, sr.NTPTime, sr.PacketCount, sr.OctetCount)
+		}
+	}
+}
+
+// sendRTCP sends the feedback of the buffer, NACKs, PLIs and receiver reports, to the sender
+func (i *RTPIngest) sendRTCP(pkts []rtcp.Packet) {
+	i.lock.Lock()
+	source := i.source
+	i.lock.Unlock()
+	if source == nil || i.closed.Load() {
+		return
+	}
+
+	buf, err := rtcp.Marshal(pkts)
+	if err == nil && i.srtpOut != nil {
+		// feedback is sent from the buffer's goroutines
+		i.lock.Lock()
+		buf, err = i.srtpOut.EncryptRTCP(nil, buf, nil)
+		i.lock.Unlock()
+	}
+	if err != nil {
+		return
+	}
+	_, _ = i.conn.WriteToUDP(buf, source)
+}
+
+func (i *RTPIngest) Close() {
+	i.closeWithError("")
+}
+
+func (i *RTPIngest) closeWithError(reason string) {
+	if i.closed.Swap(true) {
+		return
+	}
+	i.lock.Lock()
+	i.info.EndedAt = time.Now().UnixNano()
+	i.info.Error = reason
+	i.lock.Unlock()
+
+	_ = i.conn.Close()
+	_ = i.buff.Close()
+	if i.track != nil {
+		i.track.ClearAllReceivers(false)
+		i.track.Close(false)
+	}
+	i.logger.Infow("rtp ingest ended", "packetsReceived", i.packetsReceived.Load(), "packetsDropped", i.packetsDropped.Load(), "error", reason)
+	if i.onClose != nil {
+		i.onClose(i)
+	}
+}
+
+// ---------------------------------------------
+
+// rtpIngestTrack is the published track of an RTP ingest
+type rtpIngestTrack struct {
+	*MediaTrackReceiver
+}
+
+var _ types.MediaTrack = (*rtpIngestTrack)(nil)
+
+func newRTPIngestTrack(params MediaTrackReceiverParams, ti *livekit.TrackInfo) *rtpIngestTrack {
+	t := &rtpIngestTrack{}
+	params.MediaTrack = t
+	t.MediaTrackReceiver = NewMediaTrackReceiver(params, ti)
+	return t
+}
+
+func (t *rtpIngestTrack) ToProto() *livekit.TrackInfo {
+	return t.TrackInfoClone()
+}
+
+func (t *rtpIngestTrack) OnTrackSubscribed() {}
+
+// ---------------------------------------------
+
+// rtpIngestReceiver forwards the packets of an RTP ingest to the down tracks of its subscribers
+type rtpIngestReceiver struct {
+	codec  webrtc.RTPCodecParameters
+	buff   *buffer.Buffer
+	logger logger.Logger
+
+	trackInfo            atomic.Pointer[livekit.TrackInfo]
+	downTrackSpreader    *sfu.DownTrackSpreader
+	streamTrackerManager *sfu.StreamTrackerManager
+	streamTracker        streamtracker.StreamTrackerWorker
+	closed               atomic.Bool
+}
+
+func newRTPIngestReceiver(ti *livekit.TrackInfo, codec webrtc.RTPCodecParameters, buff *buffer.Buffer, l logger.Logger) *rtpIngestReceiver {
+	r := &rtpIngestReceiver{
+		codec:  codec,
+		buff:   buff,
+		logger: l,
+		downTrackSpreader: sfu.NewDownTrackSpreader(sfu.DownTrackSpreaderParams{
+			Logger: l,
+		}),
+	}
+	r.trackInfo.Store(utils.CloneProto(ti))
+	r.streamTrackerManager = sfu.NewStreamTrackerManager(l, ti, false, codec.ClockRate, sfu.DefaultStreamTrackerManagerConfig)
+	r.streamTrackerManager.SetListener(r)
+	if ti.Type == livekit.TrackType_VIDEO {
+		r.streamTracker = r.streamTrackerManager.AddTracker(0)
+	}
+	return r
+}
+
+func (r *rtpIngestReceiver) forwardRTP() {
+	defer func() {
+		r.closed.Store(true)
+		r.streamTrackerManager.Close()
+		for _, dt := range r.downTrackSpreader.ResetAndGetDownTracks() {
+			dt.Close()
+		}
+	}()
+
+	pktBuf := make([]byte, bucket.MaxPktSize)
+	for {
+		pkt, err := r.buff.ReadExtended(pktBuf)
+		if err == io.EOF {
+			return
+		}
+		if pkt == nil {
+			continue
+		}
+
+		r.downTrackSpreader.Broadcast(func(dt sfu.TrackSender) {
+			_ = dt.WriteRTP(pkt, 0)
+		})
+		if r.streamTracker != nil {
+			r.streamTracker.Observe(
+				pkt.Temporal,
+				len(pkt.RawPacket),
+				len(pkt.Packet.Payload),
+				pkt.Packet.Marker,
+				pkt.Packet.Timestamp,
+				pkt.DependencyDescriptor,
+			)
+		}
+	}
+}
+
+func (r *rtpIngestReceiver) TrackID() livekit.TrackID {
+	return livekit.TrackID(r.trackInfo.Load().Sid)
+}
+
+func (r *rtpIngestReceiver) StreamID() string {
+	return r.trackInfo.Load().Sid
+}
+
+func (r *rtpIngestReceiver) Codec() webrtc.RTPCodecParameters {
+	return r.codec
+}
+
+func (r *rtpIngestReceiver) HeaderExtensions() []webrtc.RTPHeaderExtensionParameter {
+	return nil
+}
+
+func (r *rtpIngestReceiver) IsClosed() bool {
+	return r.closed.Load()
+}
+
+func (r *rtpIngestReceiver) ReadRTP(buf []byte, _ uint8, esn uint64) (int, error) {
+	return r.buff.GetPacket(buf, esn)
+}
+
+func (r *rtpIngestReceiver) GetLayeredBitrate() ([]int32, sfu.Bitrates) {
+	return r.streamTrackerManager.GetLayeredBitrate()
+}
+
+func (r *rtpIngestReceiver) GetAudioLevel() (float64, bool) {
+	return r.buff.GetAudioLevel()
+}
+
+func (r *rtpIngestReceiver) SendPLI(_ int32, force bool) {
+	r.buff.SendPLI(force)
+}
+
+// ReplayKeyFrame is not supported, key frames are not cached
+func (r *rtpIngestReceiver) ReplayKeyFrame(int32, sfu.TrackSender) bool {
+	return false
+}
+
+func (r *rtpIngestReceiver) SetUpTrackPaused(paused bool) {
+	r.streamTrackerManager.SetPaused(paused)
+	r.buff.SetPaused(paused)
+}
+
+func (r *rtpIngestReceiver) SetMaxExpectedSpatialLayer(layer int32) {
+	r.streamTrackerManager.SetMaxExpectedSpatialLayer(layer)
+}
+
+func (r *rtpIngestReceiver) AddDownTrack(track sfu.TrackSender) error {
+	if r.closed.Load() {
+		return sfu.ErrReceiverClosed
+	}
+
+	track.UpTrackMaxPublishedLayerChange(r.streamTrackerManager.GetMaxPublishedLayer())
+	r.downTrackSpreader.Store(track)
+	return nil
+}
+
+func (r *rtpIngestReceiver) DeleteDownTrack(subscriberID livekit.ParticipantID) {
+	if r.closed.Load() {
+		return
+	}
+	r.downTrackSpreader.Free(subscriberID)
+}
+
+func (r *rtpIngestReceiver) DebugInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"RTPIngest": true,
+		"SSRC":      r.buff.GetMediaSSRC(),
+	}
+}
+
+func (r *rtpIngestReceiver) TrackInfo() *livekit.TrackInfo {
+	return r.trackInfo.Load()
+}
+
+func (r *rtpIngestReceiver) UpdateTrackInfo(ti *livekit.TrackInfo) {
+	r.trackInfo.Store(utils.CloneProto(ti))
+	r.streamTrackerManager.UpdateTrackInfo(ti)
+}
+
+func (r *rtpIngestReceiver) GetPrimaryReceiverForRed() sfu.TrackReceiver {
+	return r
+}
+
+// GetRedReceiver returns the receiver itself, ingests are published with RED disabled
+func (r *rtpIngestReceiver) GetRedReceiver() sfu.TrackReceiver {
+	return r
+}
+
+func (r *rtpIngestReceiver) GetTemporalLayerFpsForSpatial(int32) []float32 {
+	return r.buff.GetTemporalLayerFpsForSpatial(0)
+}
+
+func (r *rtpIngestReceiver) GetTrackStats() *livekit.RTPStats {
+	return r.buff.GetStats()
+}
+
+func (r *rtpIngestReceiver) AddOnReady(fn func()) {
+	fn()
+}
+
+// StreamTrackerManagerListener
+
+func (r *rtpIngestReceiver) OnAvailableLayersChanged() {
+	r.downTrackSpreader.Broadcast(func(dt sfu.TrackSender) {
+		dt.UpTrackLayersChange()
+	})
+}
+
+func (r *rtpIngestReceiver) OnBitrateAvailabilityChanged() {
+	r.downTrackSpreader.Broadcast(func(dt sfu.TrackSender) {
+		dt.UpTrackBitrateAvailabilityChange()
+	})
+}
+
+func (r *rtpIngestReceiver) OnMaxPublishedLayerChanged(maxPublishedLayer int32) {
+	r.downTrackSpreader.Broadcast(func(dt sfu.TrackSender) {
+		dt.UpTrackMaxPublishedLayerChange(maxPublishedLayer)
+	})
+}
+
+func (r *rtpIngestReceiver) OnMaxTemporalLayerSeenChanged(maxTemporalLayerSeen int32) {
+	r.downTrackSpreader.Broadcast(func(dt sfu.TrackSender) {
+		dt.UpTrackMaxTemporalLayerSeenChange(maxTemporalLayerSeen)
+	})
+}
+
+func (r *rtpIngestReceiver) OnMaxAvailableLayerChanged(int32) {}
+
+func (r *rtpIngestReceiver) OnBitrateReport(availableLayers []int32, bitrates sfu.Bitrates) {
+	r.downTrackSpreader.Broadcast(func(dt sfu.TrackSender) {
+		dt.UpTrackBitrateReport(availableLayers, bitrates)
+	})
+}
+
+// ---------------------------------------------
+
+// StartRTPIngest allocates an RTP ingest published by a virtual participant, the participant is created
+// when it is not in the room and removed along with the ingest
+func (r *Room) StartRTPIngest(host string, req *RTPIngestRequest) (*RTPIngestInfo, error) {
+	if r.IsClosed() {
+		return nil, ErrRoomClosed
+	}
+	identity := livekit.ParticipantIdentity(req.Identity)
+	if r.GetParticipant(identity) != nil {
+		return nil, ErrInvalidRTPIngest
+	}
+
+	i, err := NewRTPIngest(r.Name(), host, req, r.config.Receiver, r.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	vp := r.GetVirtualParticipant(identity)
+	created := vp == nil
+	if created {
+		vp = NewVirtualParticipant(r.newParticipantID(identity), identity, req.Name, "", nil)
+		if err = r.AddVirtualParticipant(vp); err != nil {
+			_ = i.conn.Close()
+			_ = i.buff.Close()
+			return nil, err
+		}
+	}
+
+	i.OnClose(func(i *RTPIngest) {
+		r.lock.Lock()
+		delete(r.rtpIngests, i.ID())
+		onEnded := r.onRTPIngestEnded
+		r.lock.Unlock()
+
+		if track := i.Track(); track != nil {
+			r.trackManager.RemoveTrack(track)
+			if !created {
+				if pi, ok := vp.RemoveTrack(track.ID()); ok {
+					r.broadcastVirtualParticipantState(pi)
+				}
+			}
+		}
+		if created {
+			_ = r.RemoveVirtualParticipant(identity)
+		}
+		if onEnded != nil {
+			onEnded(i.Info())
+		}
+	})
+
+	r.lock.Lock()
+	r.rtpIngests[i.ID()] = i
+	r.lock.Unlock()
+
+	track := i.Start(MediaTrackReceiverParams{
+		ParticipantID:       vp.ID(),
+		ParticipantIdentity: identity,
+		ReceiverConfig:      r.config.Receiver,
+		SubscriberConfig:    r.config.Subscriber,
+		AudioConfig:         *r.audioConfig,
+		Telemetry:           r.telemetry,
+		Logger:              LoggerWithTrack(r.Logger.WithValues("participant", identity), livekit.TrackID(i.info.TrackSid), false),
+	})
+	r.broadcastVirtualParticipantState(vp.AddTrack(track.ToProto()))
+	r.trackManager.AddTrack(track, identity, vp.ID())
+	r.subscribeToTrack(track)
+	return i.Info(), nil
+}
+
+// subscribeToTrack subscribes the participants of the room to a track published by a virtual participant
+func (r *Room) subscribeToTrack(track types.MediaTrack) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, p := range r.participants {
+		if p.State() != livekit.ParticipantInfo_ACTIVE || !r.autoSubscribe(p) {
+			continue
+		}
+		p.SubscribeToTrack(track.ID())
+	}
+}
+
+// StopRTPIngest stops an RTP ingest and unpublishes its track
+func (r *Room) StopRTPIngest(ingestID string) (*RTPIngestInfo, error) {
+	r.lock.RLock()
+	i := r.rtpIngests[ingestID]
+	r.lock.RUnlock()
+	if i == nil {
+		return nil, ErrRTPIngestNotFound
+	}
+	i.Close()
+	return i.Info(), nil
+}
+
+func (r *Room) GetRTPIngests() []*RTPIngestInfo {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	ingests := make([]*RTPIngestInfo, 0, len(r.rtpIngests))
+	for _, i := range r.rtpIngests {
+		ingests = append(ingests, i.Info())
+	}
+	return ingests
+}
+
+// getRTPIngestTracks returns the tracks published by RTP ingests
+func (r *Room) getRTPIngestTracks() []types.MediaTrack {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	tracks := make([]types.MediaTrack, 0, len(r.rtpIngests))
+	for _, i := range r.rtpIngests {
+		if track := i.Track(); track != nil {
+			tracks = append(tracks, track)
+		}
+	}
+	return tracks
+}
+
+func (r *Room) hasRTPIngestTrack(trackID livekit.TrackID) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, i := range r.rtpIngests {
+		if livekit.TrackID(i.info.TrackSid) == trackID {
+			return true
+		}
+	}
+	return false
+}
+
+// OnRTPIngestEnded is called when an RTP ingest stops, on request, on timeout or because the room closed
+func (r *Room) OnRTPIngestEnded(f func(info *RTPIngestInfo)) {
+	r.lock.Lock()
+	r.onRTPIngestEnded = f
+	r.lock.Unlock()
+}
+
+func (r *Room) closeRTPIngests() {
+	r.lock.RLock()
+	ingests := make([]*RTPIngest, 0, len(r.rtpIngests))
+	for _, i := range r.rtpIngests {
+		ingests = append(ingests, i)
+	}
+	r.lock.RUnlock()
+
+	for _, i := range ingests {
+		i.closeWithError("room closed")
+	}
+}
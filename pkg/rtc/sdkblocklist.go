@@ -0,0 +1,172 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// SDKUpgradeTopic is the data topic warning clients that their SDK version should be upgraded
+	SDKUpgradeTopic = "lk.sdk_upgrade"
+
+	sdkUpgradeRequired = "upgrade_required"
+)
+
+// SDKUpgrade tells a client its SDK version is blocklisted. It is the JSON body of the response refusing
+// the client at join, or the payload of the data message warning it.
+type SDKUpgrade struct {
+	Code       string `json:"error"`
+	Action     string `json:"action"`
+	SDK        string `json:"sdk"`
+	Version    string `json:"version"`
+	Message    string `json:"message,omitempty"`
+	UpgradeURL string `json:"upgrade_url,omitempty"`
+}
+
+func (u *SDKUpgrade) Error() string {
+	return u.Message
+}
+
+func (u *SDKUpgrade) Refused() bool {
+	return u.Action == config.SDKBlocklistRefuse
+}
+
+// SDKBlocklist matches clients against the rules of config.SDKBlocklistConfig
+type SDKBlocklist struct {
+	upgradeURL string
+	rules      []sdkBlocklistRule
+}
+
+type sdkBlocklistRule struct {
+	config.SDKBlocklistRule
+	sdk         livekit.ClientInfo_SDK
+	constraints goversion.Constraints
+}
+
+// NewSDKBlocklist returns nil without rules. Rules are validated with the config, invalid ones are skipped.
+func NewSDKBlocklist(conf config.SDKBlocklistConfig) *SDKBlocklist {
+	if len(conf.Rules) == 0 {
+		return nil
+	}
+
+	b := &SDKBlocklist{upgradeURL: conf.UpgradeURL}
+	for _, rule := range conf.Rules {
+		sdk, ok := livekit.ClientInfo_SDK_value[strings.ToUpper(rule.SDK)]
+		if !ok {
+			continue
+		}
+		constraints, err := goversion.NewConstraint(rule.Versions)
+		if err != nil {
+			continue
+		}
+		if rule.Action == "" {
+			rule.Action = config.SDKBlocklistRefuse
+		}
+		b.rules = append(b.rules, sdkBlocklistRule{
+			SDKBlocklistRule: rule,
+			sdk:              livekit.ClientInfo_SDK(sdk),
+			constraints:      constraints,
+		})
+	}
+	return b
+}
+
+// Match returns the upgrade of the first rule matching a client joining a room, or nil
+func (b *SDKBlocklist) Match(roomName livekit.RoomName, ci *livekit.ClientInfo) *SDKUpgrade {
+	if b == nil || ci == nil {
+		return nil
+	}
+	version, err := goversion.NewVersion(ci.Version)
+	if err != nil {
+		return nil
+	}
+
+	for _, rule := range b.rules {
+		if rule.sdk != ci.Sdk || !rule.constraints.Check(version) || !matchesRoom(rule.Rooms, roomName) {
+			continue
+		}
+		message := rule.Message
+		if message == "" {
+			message = fmt.Sprintf("%s SDK %s is not supported, please upgrade", strings.ToLower(ci.Sdk.String()), ci.Version)
+		}
+		return &SDKUpgrade{
+			Code:       sdkUpgradeRequired,
+			Action:     rule.Action,
+			SDK:        strings.ToLower(ci.Sdk.String()),
+			Version:    ci.Version,
+			Message:    message,
+			UpgradeURL: b.upgradeURL,
+		}
+	}
+	return nil
+}
+
+func matchesRoom(patterns []string, roomName livekit.RoomName) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, string(roomName)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSDKBlocklist sets the rules participants are warned by once active
+func (r *Room) SetSDKBlocklist(b *SDKBlocklist) {
+	r.lock.Lock()
+	r.sdkBlocklist = b
+	r.lock.Unlock()
+}
+
+func (r *Room) sendSDKUpgrade(p types.LocalParticipant) {
+	r.lock.RLock()
+	b := r.sdkBlocklist
+	r.lock.RUnlock()
+
+	upgrade := b.Match(r.Name(), p.GetClientInfo())
+	if upgrade == nil || upgrade.Refused() {
+		return
+	}
+	payload, err := json.Marshal(upgrade)
+	if err != nil {
+		return
+	}
+
+	p.GetLogger().Infow("warning participant of blocklisted sdk", "sdk", upgrade.SDK, "version", upgrade.Version)
+	topic := SDKUpgradeTopic
+	BroadcastDataPacketForRoom(r, nil, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+		Kind:                  livekit.DataPacket_RELIABLE,
+		DestinationIdentities: []string{string(p.Identity())},
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload: payload,
+				Topic:   &topic,
+			},
+		},
+	}, r.Logger)
+}
@@ -0,0 +1,61 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestSDKBlocklist(t *testing.T) {
+	b := NewSDKBlocklist(config.SDKBlocklistConfig{
+		UpgradeURL: "https://example.com/upgrade",
+		Rules: []config.SDKBlocklistRule{
+			{SDK: "js", Versions: ">= 2.1.0, < 2.1.3", Message: "broken reconnects"},
+			{SDK: "android", Versions: "< 2.0.0", Action: config.SDKBlocklistWarn, Rooms: []string{"webinar-*"}},
+		},
+	})
+
+	js := func(version string) *livekit.ClientInfo {
+		return &livekit.ClientInfo{Sdk: livekit.ClientInfo_JS, Version: version}
+	}
+
+	upgrade := b.Match("room", js("2.1.1"))
+	require.NotNil(t, upgrade)
+	require.True(t, upgrade.Refused())
+	require.Equal(t, "js", upgrade.SDK)
+	require.Equal(t, "broken reconnects", upgrade.Message)
+	require.Equal(t, "https://example.com/upgrade", upgrade.UpgradeURL)
+
+	require.Nil(t, b.Match("room", js("2.1.3")))
+	require.Nil(t, b.Match("room", js("2.0.9")))
+	require.Nil(t, b.Match("room", js("not a version")))
+	require.Nil(t, b.Match("room", &livekit.ClientInfo{Sdk: livekit.ClientInfo_SWIFT, Version: "2.1.1"}))
+
+	android := &livekit.ClientInfo{Sdk: livekit.ClientInfo_ANDROID, Version: "1.5.0"}
+	require.Nil(t, b.Match("room", android))
+	upgrade = b.Match("webinar-1", android)
+	require.NotNil(t, upgrade)
+	require.False(t, upgrade.Refused())
+	require.NotEmpty(t, upgrade.Message)
+
+	// disabled
+	require.Nil(t, NewSDKBlocklist(config.SDKBlocklistConfig{}).Match("room", js("2.1.1")))
+}
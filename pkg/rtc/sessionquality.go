@@ -0,0 +1,111 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"sync"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// SessionQuality aggregates the connection quality of a participant over its session, sampled every
+// connection quality update interval while the participant is active
+type SessionQuality struct {
+	Samples  int     `json:"samples"`
+	AvgScore float32 `json:"avg_score"`
+	MinScore float32 `json:"min_score"`
+	// number of samples of each quality, by quality name
+	Qualities map[string]int `json:"qualities"`
+}
+
+// sessionQualityRecorder keeps the quality aggregates of the participants of a room until they leave
+type sessionQualityRecorder struct {
+	lock         sync.Mutex
+	participants map[livekit.ParticipantID]*sessionQualityAggregate
+}
+
+type sessionQualityAggregate struct {
+	samples   int
+	scoreSum  float64
+	minScore  float32
+	qualities map[string]int
+}
+
+func newSessionQualityRecorder() *sessionQualityRecorder {
+	return &sessionQualityRecorder{
+		participants: make(map[livekit.ParticipantID]*sessionQualityAggregate),
+	}
+}
+
+func (s *sessionQualityRecorder) add(infos map[livekit.ParticipantID]*livekit.ConnectionQualityInfo) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for pID, info := range infos {
+		agg := s.participants[pID]
+		if agg == nil {
+			agg = &sessionQualityAggregate{
+				minScore:  info.Score,
+				qualities: make(map[string]int),
+			}
+			s.participants[pID] = agg
+		}
+		agg.samples++
+		agg.scoreSum += float64(info.Score)
+		agg.minScore = min(agg.minScore, info.Score)
+		agg.qualities[info.Quality.String()]++
+	}
+}
+
+func (s *sessionQualityRecorder) take(pID livekit.ParticipantID) *SessionQuality {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	agg := s.participants[pID]
+	if agg == nil {
+		return nil
+	}
+	delete(s.participants, pID)
+	return &SessionQuality{
+		Samples:   agg.samples,
+		AvgScore:  float32(agg.scoreSum / float64(agg.samples)),
+		MinScore:  agg.minScore,
+		Qualities: agg.qualities,
+	}
+}
+
+// ------------------------------------------------
+
+// EnableSessionQuality starts aggregating the connection quality of participants, taken by TakeSessionQuality
+// once they leave
+func (r *Room) EnableSessionQuality() {
+	r.sessionQuality.Store(newSessionQualityRecorder())
+}
+
+// TakeSessionQuality returns the quality aggregate of a participant and forgets it, nil when the participant
+// was never active or aggregation is not enabled
+func (r *Room) TakeSessionQuality(pID livekit.ParticipantID) *SessionQuality {
+	recorder := r.sessionQuality.Load()
+	if recorder == nil {
+		return nil
+	}
+	return recorder.take(pID)
+}
+
+func (r *Room) recordSessionQuality(infos map[livekit.ParticipantID]*livekit.ConnectionQualityInfo) {
+	if recorder := r.sessionQuality.Load(); recorder != nil {
+		recorder.add(infos)
+	}
+}
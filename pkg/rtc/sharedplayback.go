@@ -0,0 +1,196 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// SharedPlaybackTopic is the data topic carrying the shared playback state to clients
+	SharedPlaybackTopic = "lk.shared_playback"
+	// SharedPlaybackIdentity is the identity of the ingress participant streaming the shared media
+	SharedPlaybackIdentity = "shared_playback"
+)
+
+type SharedPlaybackAction string
+
+const (
+	SharedPlaybackStart SharedPlaybackAction = "start"
+	SharedPlaybackPlay  SharedPlaybackAction = "play"
+	SharedPlaybackPause SharedPlaybackAction = "pause"
+	SharedPlaybackSeek  SharedPlaybackAction = "seek"
+	SharedPlaybackStop  SharedPlaybackAction = "stop"
+)
+
+// SharedPlaybackCommand changes the shared playback state of a room
+type SharedPlaybackCommand struct {
+	Action     SharedPlaybackAction `json:"action"`
+	URL        string               `json:"url,omitempty"`
+	PositionMs int64                `json:"position_ms,omitempty"`
+}
+
+// SharedPlaybackState is the authoritative playback state of a room, it is kept by the node hosting the room
+// and sent to every participant whenever it changes and when they join.
+//
+// Clients compute the current position as PositionMs + (now - UpdatedAt) while playing, using the room clock
+// from time sync beacons for now.
+type SharedPlaybackState struct {
+	URL        string `json:"url"`
+	Playing    bool   `json:"playing"`
+	PositionMs int64  `json:"position_ms"`
+	UpdatedAt  int64  `json:"updated_at"`
+	Stopped    bool   `json:"stopped,omitempty"`
+}
+
+func (s *SharedPlaybackState) positionAt(now int64) int64 {
+	if !s.Playing {
+		return s.PositionMs
+	}
+	return s.PositionMs + now - s.UpdatedAt
+}
+
+// UpdateSharedPlayback applies a command and broadcasts the resulting state. Pausing mutes the tracks of the
+// shared playback participant so that the server fed stream stops for everyone at once.
+func (r *Room) UpdateSharedPlayback(cmd *SharedPlaybackCommand) (*SharedPlaybackState, error) {
+	now := time.Now().UnixMilli()
+
+	r.lock.Lock()
+	state := r.sharedPlayback
+	if cmd.Action == SharedPlaybackStart {
+		state = &SharedPlaybackState{
+			URL:        cmd.URL,
+			Playing:    true,
+			PositionMs: cmd.PositionMs,
+			UpdatedAt:  now,
+		}
+	} else if state == nil {
+		r.lock.Unlock()
+		return nil, ErrNoSharedPlayback
+	} else {
+		next := *state
+		state = &next
+	}
+
+	switch cmd.Action {
+	case SharedPlaybackPlay:
+		if !state.Playing {
+			state.Playing = true
+			state.UpdatedAt = now
+		}
+	case SharedPlaybackPause:
+		if state.Playing {
+			state.PositionMs = state.positionAt(now)
+			state.Playing = false
+			state.UpdatedAt = now
+		}
+	case SharedPlaybackSeek:
+		state.PositionMs = max(cmd.PositionMs, 0)
+		state.UpdatedAt = now
+	case SharedPlaybackStop:
+		state.PositionMs = state.positionAt(now)
+		state.Playing = false
+		state.Stopped = true
+		state.UpdatedAt = now
+	case SharedPlaybackStart:
+	default:
+		r.lock.Unlock()
+		return nil, ErrInvalidSharedPlaybackAction
+	}
+
+	if state.Stopped {
+		r.sharedPlayback = nil
+	} else {
+		r.sharedPlayback = state
+	}
+	r.lock.Unlock()
+
+	if p := r.GetParticipant(SharedPlaybackIdentity); p != nil {
+		if state.Stopped {
+			go r.RemoveParticipant(p.Identity(), p.ID(), types.ParticipantCloseReasonServiceRequestRemoveParticipant)
+		} else {
+			r.muteSharedPlaybackTracks(p, !state.Playing)
+		}
+	}
+
+	r.broadcastSharedPlayback(state)
+	return state, nil
+}
+
+// SharedPlayback returns the current state, nil when nothing is shared
+func (r *Room) SharedPlayback() *SharedPlaybackState {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if r.sharedPlayback == nil {
+		return nil
+	}
+	state := *r.sharedPlayback
+	return &state
+}
+
+func (r *Room) muteSharedPlaybackTracks(p types.LocalParticipant, muted bool) {
+	for _, t := range p.GetPublishedTracks() {
+		if t.IsMuted() != muted {
+			p.SetTrackMuted(t.ID(), muted, true)
+		}
+	}
+}
+
+func (r *Room) broadcastSharedPlayback(state *SharedPlaybackState) {
+	dp, err := sharedPlaybackPacket(state)
+	if err != nil {
+		r.Logger.Errorw("could not encode shared playback state", err)
+		return
+	}
+	BroadcastDataPacketForRoom(r, nil, livekit.DataPacket_RELIABLE, dp, r.Logger)
+}
+
+// sendSharedPlayback brings a participant that just became active up to date
+func (r *Room) sendSharedPlayback(p types.LocalParticipant) {
+	state := r.SharedPlayback()
+	if state == nil {
+		return
+	}
+	dp, err := sharedPlaybackPacket(state)
+	if err != nil {
+		r.Logger.Errorw("could not encode shared playback state", err)
+		return
+	}
+	dp.DestinationIdentities = []string{string(p.Identity())}
+	BroadcastDataPacketForRoom(r, nil, livekit.DataPacket_RELIABLE, dp, r.Logger)
+}
+
+func sharedPlaybackPacket(state *SharedPlaybackState) (*livekit.DataPacket, error) {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	topic := SharedPlaybackTopic
+	return &livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload: payload,
+				Topic:   &topic,
+			},
+		},
+	}, nil
+}
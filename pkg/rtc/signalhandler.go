@@ -15,6 +15,8 @@
 package rtc
 
 import (
+	"errors"
+
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 
@@ -105,7 +107,18 @@ func HandleParticipantSignal(room types.Room, participant types.LocalParticipant
 			RequestId: msg.UpdateMetadata.RequestId,
 			Reason:    livekit.RequestResponse_OK,
 		}
-		if participant.ClaimGrants().Video.GetCanUpdateOwnMetadata() {
+		// device state is reported by SDKs whether or not the application may update the participant
+		isDeviceUpdate := msg.UpdateMetadata.Name == "" && msg.UpdateMetadata.Metadata == "" && OnlyDeviceAttributes(msg.UpdateMetadata.Attributes)
+		if _, ok := msg.UpdateMetadata.Attributes[RoleAttribute]; ok {
+			requestResponse.Reason = livekit.RequestResponse_NOT_ALLOWED
+			requestResponse.Message = ErrRoleAttributeNotAllowed.Error()
+		} else if _, ok := msg.UpdateMetadata.Attributes[RecordingConsentAttribute]; ok {
+			requestResponse.Reason = livekit.RequestResponse_NOT_ALLOWED
+			requestResponse.Message = ErrConsentAttributeNotAllowed.Error()
+		} else if _, ok := msg.UpdateMetadata.Attributes[MaxPublishBitrateAttribute]; ok {
+			requestResponse.Reason = livekit.RequestResponse_NOT_ALLOWED
+			requestResponse.Message = ErrBitrateAttributeNotAllowed.Error()
+		} else if isDeviceUpdate || participant.ClaimGrants().Video.GetCanUpdateOwnMetadata() {
 			if err := participant.CheckMetadataLimits(
 				msg.UpdateMetadata.Name,
 				msg.UpdateMetadata.Metadata,
@@ -135,6 +148,12 @@ func HandleParticipantSignal(room types.Room, participant types.LocalParticipant
 				case ErrAttributesExceedsLimits:
 					requestResponse.Reason = livekit.RequestResponse_LIMIT_EXCEEDED
 					requestResponse.Message = "exceeds attributes size limit"
+
+				default:
+					if errors.Is(err, ErrInvalidDeviceAttribute) {
+						requestResponse.Reason = livekit.RequestResponse_NOT_ALLOWED
+						requestResponse.Message = err.Error()
+					}
 				}
 
 			}
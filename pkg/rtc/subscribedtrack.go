@@ -15,6 +15,7 @@
 package rtc
 
 import (
+	"encoding/json"
 	"sync"
 	"time"
 
@@ -31,12 +32,29 @@ import (
 	"github.com/livekit/livekit-server/pkg/rtc/types"
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 )
 
 const (
 	subscriptionDebounceInterval = 100 * time.Millisecond
+
+	// VideoLayerDowngradeTopic is the topic of the server generated data message sent to a subscriber
+	// when the dimensions it requested for a track cannot be satisfied by the layers being published
+	VideoLayerDowngradeTopic = "lk.video_layer_downgrade"
 )
 
+// VideoLayerDowngrade is the JSON payload of a VideoLayerDowngradeTopic message.
+// Width/Height/Quality describe the layer actually delivered, an empty notification
+// (zero dimensions) signals that the requested dimensions are satisfied again.
+type VideoLayerDowngrade struct {
+	TrackSid        string `json:"track_sid"`
+	RequestedWidth  uint32 `json:"requested_width"`
+	RequestedHeight uint32 `json:"requested_height"`
+	Width           uint32 `json:"width"`
+	Height          uint32 `json:"height"`
+	Quality         string `json:"quality"`
+}
+
 type SubscribedTrackParams struct {
 	PublisherID       livekit.ParticipantID
 	PublisherIdentity livekit.ParticipantIdentity
@@ -58,6 +76,10 @@ type SubscribedTrack struct {
 	settings         *livekit.UpdateTrackSettings
 	settingsVersion  utils.TimedVersion
 
+	// serializes layer downgrade notifications, notifiedLayer is the downgrade last delivered to the subscriber
+	downgradeLock sync.Mutex
+	notifiedLayer *livekit.VideoLayer
+
 	bindLock        sync.Mutex
 	bound           bool
 	onBindCallbacks []func(error)
@@ -285,6 +307,128 @@ func (t *SubscribedTrack) applySettings() {
 		}
 	}
 	t.settingsLock.Unlock()
+
+	// the forwarder switches layers asynchronously, CheckLayerDowngrade is called again on each switch
+	t.CheckLayerDowngrade()
+}
+
+// CheckLayerDowngrade compares the layer the forwarder is sending with the dimensions requested by the subscriber,
+// and notifies the subscriber when a smaller layer is delivered, or when the requested dimensions are satisfied again.
+// A notification that could not be sent is retried on the next check.
+func (t *SubscribedTrack) CheckLayerDowngrade() {
+	dt := t.DownTrack()
+	if dt == nil || dt.Kind() != webrtc.RTPCodecTypeVideo {
+		return
+	}
+
+	// evaluated under the lock so that a stale layer never overwrites a newer notification
+	t.downgradeLock.Lock()
+	defer t.downgradeLock.Unlock()
+
+	t.settingsLock.Lock()
+	var requestedWidth, requestedHeight uint32
+	if t.settings != nil && !t.settings.Disabled {
+		requestedWidth, requestedHeight = t.settings.Width, t.settings.Height
+	}
+	t.settingsLock.Unlock()
+
+	var layer *livekit.VideoLayer
+	if requestedWidth > 0 {
+		current := dt.CurrentLayer()
+		if !current.IsValid() {
+			// nothing is forwarded yet (or paused), keep the last notification
+			return
+		}
+		trackInfo := t.MediaTrack().ToProto()
+		quality := buffer.SpatialLayerToVideoQuality(current.Spatial, trackInfo)
+		layer = getDowngradedLayer(trackInfo, quality, requestedWidth, requestedHeight)
+	}
+
+	if proto.Equal(layer, t.notifiedLayer) {
+		return
+	}
+	if err := t.notifyLayerDowngrade(requestedWidth, requestedHeight, layer); err != nil {
+		t.logger.Debugw("could not send layer downgrade", "error", err)
+		return
+	}
+	t.notifiedLayer = layer
+}
+
+// notifyLayerDowngrade lets the subscriber know which layer is delivered when the requested
+// dimensions are not available, or that they are satisfied again when layer is nil
+func (t *SubscribedTrack) notifyLayerDowngrade(requestedWidth, requestedHeight uint32, layer *livekit.VideoLayer) error {
+	dpData, err := layerDowngradePacket(t.ID(), requestedWidth, requestedHeight, layer)
+	if err != nil {
+		return err
+	}
+	if err = t.params.Subscriber.SendDataPacket(livekit.DataPacket_RELIABLE, dpData); err != nil {
+		return err
+	}
+
+	if layer != nil {
+		t.logger.Infow(
+			"requested dimensions unavailable, delivering lower layer",
+			"requestedWidth", requestedWidth,
+			"requestedHeight", requestedHeight,
+			"layer", logger.Proto(layer),
+		)
+		prometheus.RecordTrackLayerDowngrade(layer.Quality.String())
+	}
+	return nil
+}
+
+// layerDowngradePacket builds the data packet of a downgrade notification. It is originated by the server,
+// so it carries no participant identity.
+func layerDowngradePacket(trackID livekit.TrackID, requestedWidth, requestedHeight uint32, layer *livekit.VideoLayer) ([]byte, error) {
+	downgrade := VideoLayerDowngrade{
+		TrackSid:        string(trackID),
+		RequestedWidth:  requestedWidth,
+		RequestedHeight: requestedHeight,
+	}
+	if layer != nil {
+		downgrade.Width = layer.Width
+		downgrade.Height = layer.Height
+		downgrade.Quality = layer.Quality.String()
+	}
+
+	payload, err := json.Marshal(&downgrade)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&livekit.DataPacket{
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload: payload,
+				Topic:   proto.String(VideoLayerDowngradeTopic),
+			},
+		},
+	})
+}
+
+// getDowngradedLayer returns the layer that will be delivered for quality if it is smaller than
+// the requested dimensions, i. e. the publisher does not provide a layer satisfying the request
+func getDowngradedLayer(trackInfo *livekit.TrackInfo, quality livekit.VideoQuality, width, height uint32) *livekit.VideoLayer {
+	var delivered *livekit.VideoLayer
+	for _, layer := range trackInfo.Layers {
+		if layer.Quality == quality {
+			delivered = layer
+			break
+		}
+	}
+	if delivered == nil || delivered.Height == 0 {
+		return nil
+	}
+
+	deliveredSize, requestedSize := delivered.Height, height
+	if trackInfo.Width < trackInfo.Height {
+		// for portrait videos
+		deliveredSize, requestedSize = delivered.Width, width
+	}
+	if float32(deliveredSize) >= float32(requestedSize)*layerSelectionTolerance {
+		return nil
+	}
+
+	return utils.CloneProto(delivered)
 }
 
 func (t *SubscribedTrack) NeedsNegotiation() bool {
@@ -0,0 +1,102 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestGetDowngradedLayer(t *testing.T) {
+	ti := &livekit.TrackInfo{
+		Type:   livekit.TrackType_VIDEO,
+		Width:  640,
+		Height: 360,
+		Layers: []*livekit.VideoLayer{
+			{Quality: livekit.VideoQuality_LOW, Width: 320, Height: 180},
+			{Quality: livekit.VideoQuality_MEDIUM, Width: 640, Height: 360},
+		},
+	}
+
+	// satisfied by the medium layer
+	require.Nil(t, getDowngradedLayer(ti, livekit.VideoQuality_MEDIUM, 640, 360))
+	// within tolerance
+	require.Nil(t, getDowngradedLayer(ti, livekit.VideoQuality_MEDIUM, 680, 390))
+
+	// publisher does not provide 720p
+	layer := getDowngradedLayer(ti, livekit.VideoQuality_MEDIUM, 1280, 720)
+	require.NotNil(t, layer)
+	require.Equal(t, uint32(640), layer.Width)
+	require.Equal(t, uint32(360), layer.Height)
+	require.Equal(t, livekit.VideoQuality_MEDIUM, layer.Quality)
+
+	// no layer information
+	require.Nil(t, getDowngradedLayer(&livekit.TrackInfo{Width: 640, Height: 360}, livekit.VideoQuality_HIGH, 1280, 720))
+
+	// portrait compares widths
+	portrait := &livekit.TrackInfo{
+		Type:   livekit.TrackType_VIDEO,
+		Width:  360,
+		Height: 640,
+		Layers: []*livekit.VideoLayer{
+			{Quality: livekit.VideoQuality_LOW, Width: 360, Height: 640},
+		},
+	}
+	require.Nil(t, getDowngradedLayer(portrait, livekit.VideoQuality_LOW, 360, 1000))
+	require.NotNil(t, getDowngradedLayer(portrait, livekit.VideoQuality_LOW, 720, 640))
+}
+
+func TestLayerDowngradePacket(t *testing.T) {
+	data, err := layerDowngradePacket("TR_video", 1280, 720, &livekit.VideoLayer{
+		Quality: livekit.VideoQuality_MEDIUM,
+		Width:   640,
+		Height:  360,
+	})
+	require.NoError(t, err)
+
+	var dp livekit.DataPacket
+	require.NoError(t, proto.Unmarshal(data, &dp))
+	user := dp.GetUser()
+	require.NotNil(t, user)
+	// server originated, must not look like it was sent by the publisher
+	require.Empty(t, user.ParticipantSid)
+	require.Empty(t, user.ParticipantIdentity)
+	require.Empty(t, dp.ParticipantIdentity)
+	require.Equal(t, VideoLayerDowngradeTopic, user.GetTopic())
+
+	var downgrade VideoLayerDowngrade
+	require.NoError(t, json.Unmarshal(user.Payload, &downgrade))
+	require.Equal(t, VideoLayerDowngrade{
+		TrackSid:        "TR_video",
+		RequestedWidth:  1280,
+		RequestedHeight: 720,
+		Width:           640,
+		Height:          360,
+		Quality:         livekit.VideoQuality_MEDIUM.String(),
+	}, downgrade)
+
+	// restored
+	data, err = layerDowngradePacket("TR_video", 1280, 720, nil)
+	require.NoError(t, err)
+	require.NoError(t, proto.Unmarshal(data, &dp))
+	require.NoError(t, json.Unmarshal(dp.GetUser().Payload, &downgrade))
+	require.Zero(t, downgrade.Width)
+	require.Empty(t, downgrade.Quality)
+}
@@ -0,0 +1,36 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+// SubscriberPriorityAttribute is the reserved attribute tagging how a participant views the video it subscribes to.
+// It can be set in the token or by the participant.
+const SubscriberPriorityAttribute = "lk.subscriber_priority"
+
+const (
+	// video is secondary to the participant, e. g. an audience member or a monitoring client
+	SubscriberPriorityLow = "low"
+	// video is shown as small tiles only
+	SubscriberPriorityThumbnail = "thumbnail"
+)
+
+// IsFrameDecimated returns true when the frame rate of the video sent to a subscriber with these attributes
+// is halved by dropping frames that are not used for reference, where the codec of the track allows it
+func IsFrameDecimated(attributes map[string]string) bool {
+	switch attributes[SubscriberPriorityAttribute] {
+	case SubscriberPriorityLow, SubscriberPriorityThumbnail:
+		return true
+	}
+	return false
+}
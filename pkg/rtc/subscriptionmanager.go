@@ -38,17 +38,25 @@ import (
 // using var instead of const to override in tests
 var (
 	reconcileInterval = 3 * time.Second
+	// upper bound of the periodic reconcile interval when all subscriptions are settled,
+	// changes are handled via notifications and the periodic pass is only a safety net
+	maxReconcileInterval = 30 * time.Second
 	// amount of time to give up if a track or publisher isn't found
 	// ensuring this is longer than iceFailedTimeout so we are certain the participant won't return
 	notFoundTimeout = time.Minute
 	// amount of time to try otherwise before flagging subscription as failed
-	subscriptionTimeout    = iceFailedTimeoutTotal
+	subscriptionTimeout = iceFailedTimeoutTotal
+	// how long a one shot signalling subscription is kept after its subscribed track closed expecting to resume
+	resumeHoldTimeout      = notFoundTimeout
 	trackRemoveGracePeriod = time.Second
 	maxUnsubscribeWait     = time.Second
 )
 
 const (
 	trackIDForReconcileSubscriptions = livekit.TrackID("subscriptions_reconcile")
+
+	// number of settled subscriptions per additional reconcileInterval of periodic reconcile
+	reconcileIntervalScaleSubscriptions = 50
 )
 
 type SubscriptionManagerParams struct {
@@ -75,21 +83,28 @@ type SubscriptionManager struct {
 	subscribedVideoCount, subscribedAudioCount atomic.Int32
 
 	subscribedTo map[livekit.ParticipantID]map[livekit.TrackID]struct{}
-	reconcileCh  chan livekit.TrackID
-	closeCh      chan struct{}
-	doneCh       chan struct{}
+
+	// pending reconciles are coalesced, so that a burst of notifications wakes the worker once
+	reconcileLock    sync.Mutex
+	pendingReconcile map[livekit.TrackID]struct{}
+	reconcileAll     bool
+	reconcileCh      chan struct{}
+
+	closeCh chan struct{}
+	doneCh  chan struct{}
 
 	onSubscribeStatusChanged func(publisherID livekit.ParticipantID, subscribed bool)
 }
 
 func NewSubscriptionManager(params SubscriptionManagerParams) *SubscriptionManager {
 	m := &SubscriptionManager{
-		params:        params,
-		subscriptions: make(map[livekit.TrackID]*trackSubscription),
-		subscribedTo:  make(map[livekit.ParticipantID]map[livekit.TrackID]struct{}),
-		reconcileCh:   make(chan livekit.TrackID, 50),
-		closeCh:       make(chan struct{}),
-		doneCh:        make(chan struct{}),
+		params:           params,
+		subscriptions:    make(map[livekit.TrackID]*trackSubscription),
+		subscribedTo:     make(map[livekit.ParticipantID]map[livekit.TrackID]struct{}),
+		pendingReconcile: make(map[livekit.TrackID]struct{}),
+		reconcileCh:      make(chan struct{}, 1),
+		closeCh:          make(chan struct{}),
+		doneCh:           make(chan struct{}),
 	}
 
 	go m.reconcileWorker()
@@ -269,6 +284,23 @@ func (m *SubscriptionManager) UpdateSubscribedTrackSettings(trackID livekit.Trac
 	sub.setSettings(settings)
 }
 
+// UpdateAdaptiveStreamHint sets how the subscriber renders a video track, the hint is kept with the
+// subscription like its settings
+func (m *SubscriptionManager) UpdateAdaptiveStreamHint(trackID livekit.TrackID, hint *types.AdaptiveStreamHint) {
+	m.lock.Lock()
+	sub, ok := m.subscriptions[trackID]
+	if !ok {
+		sLogger := m.params.Logger.WithValues(
+			"trackID", trackID,
+		)
+		sub = newTrackSubscription(m.params.Participant.ID(), trackID, sLogger)
+		m.subscriptions[trackID] = sub
+	}
+	m.lock.Unlock()
+
+	sub.setHint(hint)
+}
+
 // OnSubscribeStatusChanged callback will be notified when a participant subscribes or unsubscribes to another participant
 // it will only fire once per publisher. If current participant is subscribed to multiple tracks from another, this
 // callback will only fire once.
@@ -323,25 +355,62 @@ func (m *SubscriptionManager) canReconcile() bool {
 	return true
 }
 
-func (m *SubscriptionManager) reconcileSubscriptions() {
-	var needsToReconcile []*trackSubscription
+// reconcileSubscriptions reconciles all unsettled subscriptions and returns when the next periodic pass is due.
+// Unless includeWaiting is set, subscriptions waiting for an event are skipped until their wait expires.
+func (m *SubscriptionManager) reconcileSubscriptions(includeWaiting bool) time.Duration {
+	now := time.Now()
+	var (
+		needsToReconcile []*trackSubscription
+		nextWakeup       time.Time
+	)
 	m.lock.RLock()
+	numSubscriptions := len(m.subscriptions)
 	for _, sub := range m.subscriptions {
-		if sub.needsSubscribe() || sub.needsUnsubscribe() || sub.needsBind() || sub.needsCleanup() {
-			needsToReconcile = append(needsToReconcile, sub)
+		if !sub.needsSubscribe() && !sub.needsUnsubscribe() && !sub.needsBind() && !sub.needsCleanup() {
+			continue
+		}
+		if !includeWaiting {
+			if until, ok := sub.getEventWaitUntil(now); ok {
+				if nextWakeup.IsZero() || until.Before(nextWakeup) {
+					nextWakeup = until
+				}
+				continue
+			}
 		}
+		needsToReconcile = append(needsToReconcile, sub)
 	}
 	m.lock.RUnlock()
 
 	for _, s := range needsToReconcile {
 		m.reconcileSubscription(s)
 	}
+
+	interval := getReconcileInterval(numSubscriptions, len(needsToReconcile))
+	if !nextWakeup.IsZero() {
+		interval = min(interval, max(time.Until(nextWakeup), 0))
+	}
+	return interval
+}
+
+// isResolvedByEvent returns true for subscribe failures that are resolved by an event which queues a reconcile
+// of the subscription: the track being published or its permissions changing notify the track's change notifier,
+// subscribe permission changes and freed subscription slots reconcile all subscriptions.
+// Subscriptions failing with these are left out of the periodic pass until their failure needs to be reported.
+func isResolvedByEvent(err error) bool {
+	switch err {
+	case ErrTrackNotFound, ErrNoTrackPermission, ErrNoSubscribePermission, ErrSubscriptionLimitExceeded:
+		return true
+	default:
+		return false
+	}
 }
 
 func (m *SubscriptionManager) reconcileSubscription(s *trackSubscription) {
 	if !m.canReconcile() {
 		return
 	}
+	s.stopWaitingForEvent()
+
 	if s.needsSubscribe() {
 		if m.pendingUnsubscribes.Load() != 0 && s.durationSinceStart() < maxUnsubscribeWait {
 			// enqueue this in a bit, after pending unsubscribes are complete
@@ -364,6 +433,13 @@ func (m *SubscriptionManager) reconcileSubscription(s *trackSubscription) {
 		}
 		if err := m.subscribe(s); err != nil {
 			s.recordAttempt(false)
+			if isResolvedByEvent(err) {
+				if err == ErrTrackNotFound {
+					s.waitForEvent(notFoundTimeout)
+				} else {
+					s.waitForEvent(subscriptionTimeout)
+				}
+			}
 
 			switch err {
 			case ErrNoTrackPermission, ErrNoSubscribePermission, ErrNoReceiver, ErrNotOpen, ErrSubscriptionLimitExceeded:
@@ -443,34 +519,123 @@ func (m *SubscriptionManager) reconcileSubscription(s *trackSubscription) {
 	m.lock.Unlock()
 }
 
-// trigger an immediate reconciliation, when trackID is empty, will reconcile all subscriptions
+// trigger an immediate reconciliation, when trackID is not a known subscription, will reconcile all subscriptions
 func (m *SubscriptionManager) queueReconcile(trackID livekit.TrackID) {
+	m.reconcileLock.Lock()
+	if trackID == trackIDForReconcileSubscriptions {
+		m.reconcileAll = true
+	} else {
+		m.pendingReconcile[trackID] = struct{}{}
+	}
+	m.reconcileLock.Unlock()
+
+	select {
+	case m.reconcileCh <- struct{}{}:
+	default:
+		// worker already signalled, pending reconciles will be picked up together
+	}
+}
+
+// onTracksChanged is the batch observer of track change notifiers, keys are the IDs of the changed tracks
+func (m *SubscriptionManager) onTracksChanged(trackIDs []string) {
+	m.reconcileLock.Lock()
+	for _, trackID := range trackIDs {
+		m.pendingReconcile[livekit.TrackID(trackID)] = struct{}{}
+	}
+	m.reconcileLock.Unlock()
+
 	select {
-	case m.reconcileCh <- trackID:
+	case m.reconcileCh <- struct{}{}:
 	default:
-		// queue is full, will reconcile based on timer
 	}
 }
 
+// onTracksRemoved is the batch observer of track remove notifiers, keys are the IDs of the removed tracks
+func (m *SubscriptionManager) onTracksRemoved(trackIDs []string) {
+	for _, id := range trackIDs {
+		trackID := livekit.TrackID(id)
+		// re-resolve the track in case the same track had been re-published
+		res := m.params.TrackResolver(m.params.Participant.Identity(), trackID)
+		if res.Track != nil {
+			// do not unsubscribe, track is still available
+			continue
+		}
+		m.handleSourceTrackRemoved(trackID)
+	}
+}
+
+func (m *SubscriptionManager) drainReconcileQueue() ([]livekit.TrackID, bool) {
+	m.reconcileLock.Lock()
+	defer m.reconcileLock.Unlock()
+
+	reconcileAll := m.reconcileAll
+	m.reconcileAll = false
+	if len(m.pendingReconcile) == 0 {
+		return nil, reconcileAll
+	}
+
+	trackIDs := make([]livekit.TrackID, 0, len(m.pendingReconcile))
+	for trackID := range m.pendingReconcile {
+		trackIDs = append(trackIDs, trackID)
+	}
+	m.pendingReconcile = make(map[livekit.TrackID]struct{})
+	return trackIDs, reconcileAll
+}
+
+// getReconcileInterval backs off the periodic reconcile when all subscriptions are settled,
+// the more subscriptions there are, the less frequently they are walked
+func getReconcileInterval(numSubscriptions int, numUnsettled int) time.Duration {
+	if numUnsettled != 0 {
+		return reconcileInterval
+	}
+	return min(
+		reconcileInterval*time.Duration(1+numSubscriptions/reconcileIntervalScaleSubscriptions),
+		max(reconcileInterval, maxReconcileInterval),
+	)
+}
+
 func (m *SubscriptionManager) reconcileWorker() {
-	reconcileTicker := time.NewTicker(reconcileInterval)
-	defer reconcileTicker.Stop()
+	reconcileTimer := time.NewTimer(reconcileInterval)
+	defer reconcileTimer.Stop()
 	defer close(m.doneCh)
 
+	nextReconcileAt := time.Now().Add(reconcileInterval)
+	resetTimer := func(interval time.Duration) {
+		if !reconcileTimer.Stop() {
+			select {
+			case <-reconcileTimer.C:
+			default:
+			}
+		}
+		reconcileTimer.Reset(interval)
+		nextReconcileAt = time.Now().Add(interval)
+	}
+
 	for {
 		select {
 		case <-m.closeCh:
 			return
-		case <-reconcileTicker.C:
-			m.reconcileSubscriptions()
-		case trackID := <-m.reconcileCh:
-			m.lock.Lock()
-			s := m.subscriptions[trackID]
-			m.lock.Unlock()
-			if s != nil {
-				m.reconcileSubscription(s)
-			} else {
-				m.reconcileSubscriptions()
+		case <-reconcileTimer.C:
+			resetTimer(m.reconcileSubscriptions(false))
+		case <-m.reconcileCh:
+			trackIDs, reconcileAll := m.drainReconcileQueue()
+			for _, trackID := range trackIDs {
+				m.lock.RLock()
+				s := m.subscriptions[trackID]
+				m.lock.RUnlock()
+				if s != nil {
+					m.reconcileSubscription(s)
+				} else {
+					reconcileAll = true
+				}
+			}
+			if reconcileAll {
+				m.reconcileSubscriptions(true)
+			}
+
+			// activity may have left subscriptions to be retried, do not wait for a backed off interval
+			if time.Until(nextReconcileAt) > reconcileInterval {
+				resetTimer(reconcileInterval)
 			}
 		}
 	}
@@ -510,20 +675,10 @@ func (m *SubscriptionManager) subscribe(s *trackSubscription) error {
 		// set callback only when we haven't done it before
 		// we set the observer before checking for existence of track, so that we may get notified
 		// when the track becomes available
-		res.TrackChangedNotifier.AddObserver(string(m.params.Participant.ID()), func() {
-			m.queueReconcile(trackID)
-		})
+		res.TrackChangedNotifier.AddBatchObserver(string(m.params.Participant.ID()), m.onTracksChanged)
 	}
 	if res.TrackRemovedNotifier != nil && s.setRemovedNotifier(res.TrackRemovedNotifier) {
-		res.TrackRemovedNotifier.AddObserver(string(m.params.Participant.ID()), func() {
-			// re-resolve the track in case the same track had been re-published
-			res := m.params.TrackResolver(m.params.Participant.Identity(), trackID)
-			if res.Track != nil {
-				// do not unsubscribe, track is still available
-				return
-			}
-			m.handleSourceTrackRemoved(trackID)
-		})
+		res.TrackRemovedNotifier.AddBatchObserver(string(m.params.Participant.ID()), m.onTracksRemoved)
 	}
 
 	track := res.Track
@@ -662,9 +817,17 @@ func (m *SubscriptionManager) subscribeSynchronous(trackID livekit.TrackID) erro
 		subTrack.OnClose(func(isExpectedToResume bool) {
 			m.handleSubscribedTrackClose(sub, isExpectedToResume)
 
-			m.lock.Lock()
-			delete(m.subscriptions, trackID)
-			m.lock.Unlock()
+			// keep the subscription (and its settings) around when the subscribed track is expected to resume,
+			// it is not desired in this mode and would otherwise be cleaned up by the next reconcile
+			if isExpectedToResume {
+				sub.holdForResume(resumeHoldTimeout)
+			} else {
+				m.lock.Lock()
+				if m.subscriptions[trackID] == sub {
+					delete(m.subscriptions, trackID)
+				}
+				m.lock.Unlock()
+			}
 		})
 		subTrack.AddOnBind(func(err error) {
 			if err != nil {
@@ -871,6 +1034,8 @@ type trackSubscription struct {
 	publisherID              livekit.ParticipantID
 	publisherIdentity        livekit.ParticipantIdentity
 	settings                 *livekit.UpdateTrackSettings
+	hint                     *types.AdaptiveStreamHint
+	settingsSeq              uint64
 	changedNotifier          types.ChangeNotifier
 	removedNotifier          types.ChangeNotifier
 	hasPermissionInitialized bool
@@ -888,6 +1053,18 @@ type trackSubscription struct {
 	// the timestamp when the subscription was started, will be reset when downtrack is closed with expected resume
 	subscribeAt       atomic.Pointer[time.Time]
 	succRecordCounter atomic.Int32
+
+	// set while a failed subscription waits for an event to retry it, see isResolvedByEvent
+	eventWaitUntil atomic.Pointer[time.Time]
+
+	// set while a one shot signalling subscription waits for its subscribed track to resume
+	resumeHoldUntil atomic.Pointer[time.Time]
+
+	// serializes pushing settings to the subscribed track so that stale settings never overwrite newer ones,
+	// tracks what was last pushed to avoid re-applying unchanged settings
+	settingsApplyLock    sync.Mutex
+	appliedSettingsSeq   uint64
+	appliedSettingsTrack types.SubscribedTrack
 }
 
 func newTrackSubscription(subscriberID livekit.ParticipantID, trackID livekit.TrackID, l logger.Logger) *trackSubscription {
@@ -975,17 +1152,16 @@ func (s *trackSubscription) isDesired() bool {
 }
 
 func (s *trackSubscription) setSubscribedTrack(track types.SubscribedTrack) {
+	if track != nil {
+		s.resumeHoldUntil.Store(nil)
+	}
 	s.lock.Lock()
 	oldTrack := s.subscribedTrack
 	s.subscribedTrack = track
 	s.bound = false
-	settings := s.settings
 	s.lock.Unlock()
 
-	if settings != nil && track != nil {
-		s.logger.Debugw("restoring subscriber settings", "settings", logger.Proto(settings))
-		track.UpdateSubscriberSettings(settings, true)
-	}
+	s.applySettings(true)
 	if oldTrack != nil {
 		oldTrack.OnClose(nil)
 	}
@@ -1052,18 +1228,67 @@ func (s *trackSubscription) setRemovedNotifierLocked(notifier types.ChangeNotifi
 func (s *trackSubscription) setSettings(settings *livekit.UpdateTrackSettings) {
 	s.lock.Lock()
 	s.settings = settings
-	subTrack := s.subscribedTrack
+	s.settingsSeq++
 	s.lock.Unlock()
-	if subTrack != nil {
-		subTrack.UpdateSubscriberSettings(settings, false)
+
+	s.applySettings(false)
+}
+
+func (s *trackSubscription) setHint(hint *types.AdaptiveStreamHint) {
+	if kind := s.kind.Load(); kind != nil && *kind != livekit.TrackType_VIDEO {
+		return
 	}
+
+	s.lock.Lock()
+	s.hint = hint
+	s.settingsSeq++
+	s.lock.Unlock()
+
+	s.applySettings(false)
+}
+
+func (s *trackSubscription) getSettings() *livekit.UpdateTrackSettings {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.settings
+}
+
+// applySettings pushes the latest settings to the current subscribed track, if they have not been already.
+// Settings updated while the subscription is pending or while the subscribed track is being replaced
+// (resume/migration) are held in the subscription and applied once a subscribed track is available.
+func (s *trackSubscription) applySettings(isImmediate bool) {
+	s.settingsApplyLock.Lock()
+	defer s.settingsApplyLock.Unlock()
+
+	s.lock.RLock()
+	settings := applyAdaptiveStreamHint(s.settings, s.hint)
+	settingsSeq := s.settingsSeq
+	subTrack := s.subscribedTrack
+	s.lock.RUnlock()
+
+	if settings == nil || subTrack == nil {
+		return
+	}
+	if subTrack == s.appliedSettingsTrack && settingsSeq == s.appliedSettingsSeq {
+		return
+	}
+
+	if subTrack != s.appliedSettingsTrack {
+		s.logger.Debugw("restoring subscriber settings", "settings", logger.Proto(settings))
+	}
+	subTrack.UpdateSubscriberSettings(settings, isImmediate)
+	s.appliedSettingsTrack = subTrack
+	s.appliedSettingsSeq = settingsSeq
 }
 
 // mark the subscription as bound - when we've received the client's answer
 func (s *trackSubscription) setBound() {
 	s.lock.Lock()
-	defer s.lock.Unlock()
 	s.bound = true
+	s.lock.Unlock()
+
+	// settings could have changed while binding, ensure latest are applied to the bound track
+	s.applySettings(true)
 }
 
 func (s *trackSubscription) isBound() bool {
@@ -1142,6 +1367,29 @@ func (s *trackSubscription) maybeRecordSuccess(ts telemetry.TelemetryService, pI
 	ts.TrackSubscribed(context.Background(), pID, mediaTrack.ToProto(), pi, !eventSent)
 }
 
+// waitForEvent excludes the subscription from periodic reconciles until timeout has passed since it started,
+// when the failure is due to be reported. After that it is retried at the maximum periodic interval.
+func (s *trackSubscription) waitForEvent(timeout time.Duration) {
+	wait := timeout - s.durationSinceStart()
+	if wait <= 0 {
+		wait = maxReconcileInterval
+	}
+	until := time.Now().Add(wait)
+	s.eventWaitUntil.Store(&until)
+}
+
+func (s *trackSubscription) stopWaitingForEvent() {
+	s.eventWaitUntil.Store(nil)
+}
+
+func (s *trackSubscription) getEventWaitUntil(now time.Time) (time.Time, bool) {
+	until := s.eventWaitUntil.Load()
+	if until == nil || !now.Before(*until) {
+		return time.Time{}, false
+	}
+	return *until, true
+}
+
 func (s *trackSubscription) durationSinceStart() time.Duration {
 	t := s.subStartedAt.Load()
 	if t == nil {
@@ -1169,7 +1417,20 @@ func (s *trackSubscription) needsBind() bool {
 }
 
 func (s *trackSubscription) needsCleanup() bool {
+	if s.isHeldForResume() {
+		return false
+	}
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 	return !s.desired && s.subscribedTrack == nil
 }
+
+func (s *trackSubscription) holdForResume(timeout time.Duration) {
+	until := time.Now().Add(timeout)
+	s.resumeHoldUntil.Store(&until)
+}
+
+func (s *trackSubscription) isHeldForResume() bool {
+	until := s.resumeHoldUntil.Load()
+	return until != nil && time.Now().Before(*until)
+}
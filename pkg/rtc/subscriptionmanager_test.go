@@ -36,6 +36,7 @@ func init() {
 	reconcileInterval = 50 * time.Millisecond
 	notFoundTimeout = 200 * time.Millisecond
 	subscriptionTimeout = 200 * time.Millisecond
+	resumeHoldTimeout = 300 * time.Millisecond
 }
 
 const (
@@ -147,9 +148,7 @@ func TestSubscribe(t *testing.T) {
 		require.Equal(t, 0, tm.TrackSubscribedCallCount())
 
 		// give permissions now
-		resolver.lock.Lock()
-		resolver.hasPermission = true
-		resolver.lock.Unlock()
+		resolver.SetHasPermission(true)
 
 		require.Eventually(t, func() bool {
 			return !s.needsSubscribe()
@@ -350,6 +349,206 @@ func TestUpdateSettingsBeforeSubscription(t *testing.T) {
 	require.Equal(t, settings.Height, applied.Height)
 }
 
+func TestSettingsPreservedAcrossResubscribe(t *testing.T) {
+	lastAppliedSettings := func(st types.SubscribedTrack) *livekit.UpdateTrackSettings {
+		fst := st.(*typesfakes.FakeSubscribedTrack)
+		count := fst.UpdateSubscriberSettingsCallCount()
+		if count == 0 {
+			return nil
+		}
+		applied, _ := fst.UpdateSubscriberSettingsArgsForCall(count - 1)
+		return applied
+	}
+
+	t.Run("updated while pending", func(t *testing.T) {
+		sm := newTestSubscriptionManager(t)
+		defer sm.Close(false)
+		resolver := newTestResolver(true, true, "pub", "pubID")
+		resolver.SetPause(true)
+		sm.params.TrackResolver = resolver.Resolve
+
+		sm.SubscribeToTrack("track")
+		s := sm.subscriptions["track"]
+		sm.UpdateSubscribedTrackSettings("track", &livekit.UpdateTrackSettings{Width: 100, Height: 100})
+		sm.UpdateSubscribedTrackSettings("track", &livekit.UpdateTrackSettings{Width: 200, Height: 200})
+		require.True(t, s.needsSubscribe())
+
+		resolver.SetPause(false)
+		require.Eventually(t, func() bool {
+			return !s.needsSubscribe()
+		}, subSettleTimeout, subCheckInterval, "track should be subscribed")
+
+		st := s.getSubscribedTrack().(*typesfakes.FakeSubscribedTrack)
+		require.Equal(t, 1, st.UpdateSubscriberSettingsCallCount())
+		require.Equal(t, uint32(200), lastAppliedSettings(st).Width)
+
+		// binding with unchanged settings should not re-apply
+		setTestSubscribedTrackBound(t, st)
+		require.Eventually(t, func() bool {
+			return !s.needsBind()
+		}, subSettleTimeout, subCheckInterval, "track was not bound")
+		require.Equal(t, 1, st.UpdateSubscriberSettingsCallCount())
+	})
+
+	t.Run("updated during resume", func(t *testing.T) {
+		sm := newTestSubscriptionManager(t)
+		defer sm.Close(false)
+		resolver := newTestResolver(true, true, "pub", "pubID")
+		sm.params.TrackResolver = resolver.Resolve
+
+		sm.SubscribeToTrack("track")
+		sm.UpdateSubscribedTrackSettings("track", &livekit.UpdateTrackSettings{Quality: livekit.VideoQuality_LOW})
+		s := sm.subscriptions["track"]
+		require.Eventually(t, func() bool {
+			return !s.needsSubscribe()
+		}, subSettleTimeout, subCheckInterval, "track should be subscribed")
+		st1 := s.getSubscribedTrack()
+		setTestSubscribedTrackBound(t, st1)
+
+		// subscribed track goes away expecting to resume, settings change while it is being replaced
+		resolver.SetPause(true)
+		setTestSubscribedTrackClosed(t, st1, true)
+		require.Eventually(t, func() bool {
+			return s.needsSubscribe()
+		}, subSettleTimeout, subCheckInterval, "track should need subscribe")
+		sm.UpdateSubscribedTrackSettings("track", &livekit.UpdateTrackSettings{Quality: livekit.VideoQuality_HIGH, Fps: 15})
+		resolver.SetPause(false)
+
+		require.Eventually(t, func() bool {
+			return !s.needsSubscribe()
+		}, subSettleTimeout, subCheckInterval, "track should be resubscribed")
+		st2 := s.getSubscribedTrack()
+		require.NotEqual(t, st1, st2)
+
+		applied := lastAppliedSettings(st2)
+		require.NotNil(t, applied)
+		require.Equal(t, livekit.VideoQuality_HIGH, applied.Quality)
+		require.Equal(t, uint32(15), applied.Fps)
+	})
+
+	t.Run("one shot signalling resume keeps settings", func(t *testing.T) {
+		sm := newTestSubscriptionManager(t)
+		sm.params.UseOneShotSignallingMode = true
+		defer sm.Close(false)
+		resolver := newTestResolver(true, true, "pub", "pubID")
+		sm.params.TrackResolver = resolver.Resolve
+
+		sm.SubscribeToTrack("track")
+		s := sm.subscriptions["track"]
+		require.NotNil(t, s.getSubscribedTrack())
+		sm.UpdateSubscribedTrackSettings("track", &livekit.UpdateTrackSettings{Disabled: true})
+
+		setTestSubscribedTrackClosed(t, s.getSubscribedTrack(), true)
+		sm.lock.RLock()
+		require.Equal(t, s, sm.subscriptions["track"])
+		sm.lock.RUnlock()
+		require.True(t, s.getSettings().Disabled)
+
+		sm.SubscribeToTrack("track")
+		applied := lastAppliedSettings(s.getSubscribedTrack())
+		require.NotNil(t, applied)
+		require.True(t, applied.Disabled)
+	})
+}
+
+func TestSubscribeWaitsForEvent(t *testing.T) {
+	sm := newTestSubscriptionManager(t)
+	defer sm.Close(false)
+	resolver := newTestResolver(true, false, "pub", "pubID")
+	sm.params.TrackResolver = resolver.Resolve
+
+	sm.SubscribeToTrack("track")
+	s := sm.subscriptions["track"]
+	require.Eventually(t, func() bool {
+		_, waiting := s.getEventWaitUntil(time.Now())
+		return waiting
+	}, subSettleTimeout, subCheckInterval, "subscription should wait for the track to be published")
+
+	// periodic passes leave the subscription alone while it waits
+	numResolves := resolver.NumResolves()
+	time.Sleep(2 * reconcileInterval)
+	require.Equal(t, numResolves, resolver.NumResolves())
+	require.True(t, s.needsSubscribe())
+
+	// publishing the track wakes it up
+	resolver.SetHasTrack(true)
+	require.Eventually(t, func() bool {
+		return !s.needsSubscribe()
+	}, subSettleTimeout, subCheckInterval, "should be subscribed")
+	_, waiting := s.getEventWaitUntil(time.Now())
+	require.False(t, waiting)
+}
+
+func TestOneShotResumeSurvivesReconcile(t *testing.T) {
+	sm := newTestSubscriptionManager(t)
+	sm.params.UseOneShotSignallingMode = true
+	defer sm.Close(false)
+	resolver := newTestResolver(true, true, "pub", "pubID")
+	sm.params.TrackResolver = resolver.Resolve
+
+	sm.SubscribeToTrack("track")
+	s := sm.subscriptions["track"]
+	require.NotNil(t, s.getSubscribedTrack())
+
+	setTestSubscribedTrackClosed(t, s.getSubscribedTrack(), true)
+	hasSubscription := func() bool {
+		sm.lock.RLock()
+		defer sm.lock.RUnlock()
+		return sm.subscriptions["track"] == s
+	}
+
+	// periodic reconciles within the resume window must not clean up the subscription
+	require.Never(t, func() bool {
+		return !hasSubscription()
+	}, 3*reconcileInterval, reconcileInterval/5, "subscription was cleaned up while waiting to resume")
+
+	// resuming in the window keeps it for good
+	sm.SubscribeToTrack("track")
+	require.NotNil(t, s.getSubscribedTrack())
+	time.Sleep(resumeHoldTimeout + 2*reconcileInterval)
+	require.True(t, hasSubscription())
+
+	// once the window passes without a resume, it is cleaned up
+	setTestSubscribedTrackClosed(t, s.getSubscribedTrack(), true)
+	require.Eventually(t, func() bool {
+		return !hasSubscription()
+	}, resumeHoldTimeout+subSettleTimeout, subCheckInterval, "subscription was not cleaned up after the resume window")
+}
+
+func TestReconcileInterval(t *testing.T) {
+	require.Equal(t, reconcileInterval, getReconcileInterval(0, 0))
+	require.Equal(t, reconcileInterval, getReconcileInterval(reconcileIntervalScaleSubscriptions-1, 0))
+	require.Equal(t, 2*reconcileInterval, getReconcileInterval(reconcileIntervalScaleSubscriptions, 0))
+
+	// unsettled subscriptions are retried at the base interval regardless of size
+	require.Equal(t, reconcileInterval, getReconcileInterval(100*reconcileIntervalScaleSubscriptions, 1))
+
+	// capped for very large rooms
+	require.Equal(t, maxReconcileInterval, getReconcileInterval(1000*reconcileIntervalScaleSubscriptions, 0))
+}
+
+func TestQueueReconcileCoalesces(t *testing.T) {
+	sm := &SubscriptionManager{
+		pendingReconcile: make(map[livekit.TrackID]struct{}),
+		reconcileCh:      make(chan struct{}, 1),
+	}
+
+	for i := 0; i < 100; i++ {
+		sm.queueReconcile("track1")
+		sm.queueReconcile("track2")
+	}
+	require.Len(t, sm.reconcileCh, 1)
+
+	trackIDs, reconcileAll := sm.drainReconcileQueue()
+	require.ElementsMatch(t, []livekit.TrackID{"track1", "track2"}, trackIDs)
+	require.False(t, reconcileAll)
+
+	sm.ReconcileAll()
+	trackIDs, reconcileAll = sm.drainReconcileQueue()
+	require.Empty(t, trackIDs)
+	require.True(t, reconcileAll)
+}
+
 func TestSubscriptionLimits(t *testing.T) {
 	sm := newTestSubscriptionManagerWithParams(t, testSubscriptionParams{
 		SubscriptionLimitAudio: 1,
@@ -483,30 +682,57 @@ type testResolver struct {
 	hasTrack      bool
 	pubIdentity   livekit.ParticipantIdentity
 	pubID         livekit.ParticipantID
+	numResolves   int
 
 	paused bool
+
+	// like the room's track manager, notified when the track or its permissions change
+	changedNotifier *utils.ChangeNotifier
 }
 
 func newTestResolver(hasPermission bool, hasTrack bool, pubIdentity livekit.ParticipantIdentity, pubID livekit.ParticipantID) *testResolver {
 	return &testResolver{
-		hasPermission: hasPermission,
-		hasTrack:      hasTrack,
-		pubIdentity:   pubIdentity,
-		pubID:         pubID,
+		hasPermission:   hasPermission,
+		hasTrack:        hasTrack,
+		pubIdentity:     pubIdentity,
+		pubID:           pubID,
+		changedNotifier: utils.NewChangeNotifier(),
 	}
 }
 
 func (t *testResolver) SetPause(paused bool) {
 	t.lock.Lock()
-	defer t.lock.Unlock()
 	t.paused = paused
+	t.lock.Unlock()
+	t.changedNotifier.NotifyChanged()
+}
+
+func (t *testResolver) SetHasPermission(hasPermission bool) {
+	t.lock.Lock()
+	t.hasPermission = hasPermission
+	t.lock.Unlock()
+	t.changedNotifier.NotifyChanged()
+}
+
+func (t *testResolver) SetHasTrack(hasTrack bool) {
+	t.lock.Lock()
+	t.hasTrack = hasTrack
+	t.lock.Unlock()
+	t.changedNotifier.NotifyChanged()
+}
+
+func (t *testResolver) NumResolves() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.numResolves
 }
 
 func (t *testResolver) Resolve(identity livekit.ParticipantIdentity, trackID livekit.TrackID) types.MediaResolverResult {
 	t.lock.Lock()
 	defer t.lock.Unlock()
+	t.numResolves++
 	res := types.MediaResolverResult{
-		TrackChangedNotifier: utils.NewChangeNotifier(),
+		TrackChangedNotifier: t.changedNotifier,
 		TrackRemovedNotifier: utils.NewChangeNotifier(),
 		HasPermission:        t.hasPermission,
 		PublisherID:          t.pubID,
@@ -0,0 +1,62 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// Time sync beacons give every participant of a room the same clock to align against.
+// Pings may be answered by different signal nodes, beacons are always sent by the node
+// hosting the room. Each beacon carries the room clock (unix milliseconds) advanced by half
+// of the participant's signaling RTT, i.e. the expected room time when the beacon arrives.
+
+func (r *Room) timeSyncWorker() {
+	interval := r.timeSyncConfig.BeaconInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.closed:
+			return
+		case <-ticker.C:
+			r.sendTimeSyncBeacons()
+		}
+	}
+}
+
+func (r *Room) sendTimeSyncBeacons() {
+	for _, p := range r.GetParticipants() {
+		if p.State() != livekit.ParticipantInfo_ACTIVE {
+			continue
+		}
+		r.sendTimeSyncBeacon(p)
+	}
+}
+
+func (r *Room) sendTimeSyncBeacon(p types.LocalParticipant) {
+	timestamp := time.Now().UnixMilli() + int64(p.SignalingRTT()/2)
+	if err := p.SendTimeSyncBeacon(timestamp); err != nil {
+		r.Logger.Debugw("could not send time sync beacon", "error", err, "participant", p.Identity())
+	}
+}
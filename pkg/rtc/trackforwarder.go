@@ -0,0 +1,476 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/srtp/v2"
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+const (
+	TrackForwardPrefix = "TF_"
+
+	TrackForwardTransportRTP  = "rtp"
+	TrackForwardTransportSRTP = "srtp"
+
+	// SRTP master key and salt of AES_CM_128_HMAC_SHA1_80, sent base64 encoded as in SDES inline keys
+	srtpMasterKeyLen  = 16
+	srtpMasterSaltLen = 14
+
+	// the token of forwards to RTP ingests is repeated at this interval, so muted tracks do not time out
+	trackForwardKeepaliveInterval = rtpIngestTimeout / 4
+)
+
+// TrackForwardRequest forwards a published track to an external address
+type TrackForwardRequest struct {
+	TrackSid  string `json:"track_sid"`
+	Transport string `json:"transport"`
+	// Address is the host:port the packets are sent to over UDP
+	Address string `json:"address"`
+	// Quality selects the simulcast layer of video tracks: low, medium or high when unset
+	Quality string `json:"quality,omitempty"`
+	// SRTPKey is the base64 master key and salt of srtp forwards, generated when unset
+	SRTPKey string `json:"srtp_key,omitempty"`
+	// SSRC the stream is sent with, generated when unset
+	SSRC uint32 `json:"ssrc,omitempty"`
+	// Token is sent in a datagram of its own before any RTP and repeated while the stream is idle,
+	// as expected by RTP ingests
+	Token string `json:"token,omitempty"`
+}
+
+// TrackForwardInfo describes a track forward, with the parameters the receiving end needs to decode
+// the stream and the counters of what was sent
+type TrackForwardInfo struct {
+	ForwardID   string `json:"forward_id"`
+	Room        string `json:"room"`
+	TrackSid    string `json:"track_sid"`
+	Transport   string `json:"transport"`
+	Address     string `json:"address"`
+	SRTPKey     string `json:"srtp_key,omitempty"`
+	MimeType    string `json:"mime_type"`
+	PayloadType uint8  `json:"payload_type"`
+	ClockRate   uint32 `json:"clock_rate"`
+	SSRC        uint32 `json:"ssrc"`
+	PacketsSent uint64 `json:"packets_sent"`
+	BytesSent   uint64 `json:"bytes_sent"`
+	WriteErrors uint64 `json:"write_errors"`
+	StartedAt   int64  `json:"started_at"`
+	EndedAt     int64  `json:"ended_at,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// TrackForwarder sends the packets of one layer of a published track as plain RTP, or SRTP, to an
+// external address. It is attached to the track receiver like a down track, so it is closed along with
+// the track. Packets are sent with their original payload and timestamp, with a sequence number and
+// SSRC of their own and without header extensions. PLIs and FIRs received from the address request
+// a key frame.
+type TrackForwarder struct {
+	info     TrackForwardInfo
+	receiver sfu.TrackReceiver
+	layer    int32
+	isVideo  bool
+	conn     net.Conn
+	srtp     *srtp.Context
+	srtcp    *srtp.Context
+	token    string
+	logger   logger.Logger
+
+	lock            sync.Mutex
+	sn              uint16
+	waitingKeyFrame bool
+
+	packetsSent atomic.Uint64
+	bytesSent   atomic.Uint64
+	writeErrors atomic.Uint64
+	closed      atomic.Bool
+	onClose     func(*TrackForwarder)
+}
+
+func NewTrackForwarder(roomName livekit.RoomName, track *TrackInfo, req *TrackForwardRequest, l logger.Logger) (*TrackForwarder, error) {
+	if req.Transport != TrackForwardTransportRTP && req.Transport != TrackForwardTransportSRTP {
+		return nil, ErrInvalidTrackForward
+	}
+	if _, _, err := net.SplitHostPort(req.Address); err != nil {
+		return nil, ErrInvalidTrackForward
+	}
+	receivers := track.Track.Receivers()
+	if len(receivers) == 0 {
+		return nil, ErrTrackNotBound
+	}
+	receiver := receivers[0].GetPrimaryReceiverForRed()
+
+	ssrc := req.SSRC
+	if ssrc == 0 {
+		var b [4]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, err
+		}
+		ssrc = binary.BigEndian.Uint32(b[:])
+	}
+	codec := receiver.Codec()
+	f := &TrackForwarder{
+		info: TrackForwardInfo{
+			ForwardID:   guid.New(TrackForwardPrefix),
+			Room:        string(roomName),
+			TrackSid:    req.TrackSid,
+			Transport:   req.Transport,
+			Address:     req.Address,
+			MimeType:    codec.MimeType,
+			PayloadType: uint8(codec.PayloadType),
+			ClockRate:   codec.ClockRate,
+			SSRC:        ssrc,
+			StartedAt:   time.Now().UnixNano(),
+		},
+		receiver: receiver,
+		isVideo:  track.Track.Kind() == livekit.TrackType_VIDEO,
+		token:    req.Token,
+	}
+	f.logger = l.WithValues("forwardID", f.info.ForwardID, "trackID", req.TrackSid, "address", req.Address)
+	if f.isVideo {
+		quality := livekit.VideoQuality_HIGH
+		if req.Quality != "" {
+			q, ok := livekit.VideoQuality_value[strings.ToUpper(req.Quality)]
+			if !ok || livekit.VideoQuality(q) == livekit.VideoQuality_OFF {
+				return nil, ErrInvalidTrackForward
+			}
+			quality = livekit.VideoQuality(q)
+		}
+		f.layer = buffer.VideoQualityToSpatialLayer(quality, track.Track.ToProto())
+		f.waitingKeyFrame = true
+	}
+
+	if req.Transport == TrackForwardTransportSRTP {
+		key, err := srtpKey(req.SRTPKey)
+		if err != nil {
+			return nil, err
+		}
+		f.srtp, err = srtp.CreateContext(key[:srtpMasterKeyLen], key[srtpMasterKeyLen:], srtp.ProtectionProfileAes128CmHmacSha1_80)
+		if err != nil {
+			return nil, ErrInvalidTrackForward
+		}
+		// feedback is decrypted by the read loop with a context of its own
+		f.srtcp, err = srtp.CreateContext(key[:srtpMasterKeyLen], key[srtpMasterKeyLen:], srtp.ProtectionProfileAes128CmHmacSha1_80)
+		if err != nil {
+			return nil, ErrInvalidTrackForward
+		}
+		f.info.SRTPKey = base64.StdEncoding.EncodeToString(key)
+	}
+
+	conn, err := net.Dial("udp", req.Address)
+	if err != nil {
+		return nil, err
+	}
+	f.conn = conn
+	return f, nil
+}
+
+func srtpKey(encoded string) ([]byte, error) {
+	if encoded == "" {
+		key := make([]byte, srtpMasterKeyLen+srtpMasterSaltLen)
+		_, err := rand.Read(key)
+		return key, err
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != srtpMasterKeyLen+srtpMasterSaltLen {
+		return nil, ErrInvalidTrackForward
+	}
+	return key, nil
+}
+
+// Start attaches the forwarder to the track, video is sent from the next key frame
+func (f *TrackForwarder) Start() error {
+	if f.token != "" {
+		if _, err := f.conn.Write([]byte(f.token)); err != nil {
+			_ = f.conn.Close()
+			return err
+		}
+	}
+	if err := f.receiver.AddDownTrack(f); err != nil {
+		_ = f.conn.Close()
+		return err
+	}
+	if f.isVideo {
+		f.receiver.SendPLI(f.layer, true)
+	}
+	go f.readRTCP()
+	if f.token != "" {
+		go f.keepalive()
+	}
+	f.logger.Infow("track forward started")
+	return nil
+}
+
+// readRTCP requests a key frame from the publisher when the receiving end asks for one
+func (f *TrackForwarder) readRTCP() {
+	buf := make([]byte, 1500)
+	for {
+		n, err := f.conn.Read(buf)
+		if err != nil {
+			if f.closed.Load() || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			// errors of an unreachable address are reported on reads, keep reading until closed
+			continue
+		}
+		pkt := buf[:n]
+		if f.srtcp != nil {
+			// receivers may send their feedback as plain RTCP
+			if decrypted, err := f.srtcp.DecryptRTCP(nil, pkt, nil); err == nil {
+				pkt = decrypted
+			}
+		}
+		pkts, err := rtcp.Unmarshal(pkt)
+		if err != nil || !f.isVideo {
+			continue
+		}
+		for _, p := range pkts {
+			switch p.(type) {
+			case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
+				f.receiver.SendPLI(f.layer, false)
+			}
+		}
+	}
+}
+
+func (f *TrackForwarder) keepalive() {
+	ticker := time.NewTicker(trackForwardKeepaliveInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if f.closed.Load() {
+			return
+		}
+		_, _ = f.conn.Write([]byte(f.token))
+	}
+}
+
+func (f *TrackForwarder) OnClose(fn func(*TrackForwarder)) {
+	f.onClose = fn
+}
+
+func (f *TrackForwarder) Info() *TrackForwardInfo {
+	f.lock.Lock()
+	info := f.info
+	f.lock.Unlock()
+	info.PacketsSent = f.packetsSent.Load()
+	info.BytesSent = f.bytesSent.Load()
+	info.WriteErrors = f.writeErrors.Load()
+	return &info
+}
+
+func (f *TrackForwarder) WriteRTP(p *buffer.ExtPacket, layer int32) error {
+	if f.closed.Load() || layer != f.layer || len(p.Packet.Payload) == 0 {
+		return nil
+	}
+
+	f.lock.Lock()
+	if f.waitingKeyFrame {
+		if !p.KeyFrame {
+			f.lock.Unlock()
+			return nil
+		}
+		f.waitingKeyFrame = false
+	}
+	f.sn++
+	hdr := rtp.Header{
+		Version:        2,
+		Marker:         p.Packet.Marker,
+		PayloadType:    f.info.PayloadType,
+		SequenceNumber: f.sn,
+		Timestamp:      p.Packet.Timestamp,
+		SSRC:           f.info.SSRC,
+	}
+	pkt := &rtp.Packet{Header: hdr, Payload: p.Packet.Payload}
+	buf, err := pkt.Marshal()
+	if err == nil && f.srtp != nil {
+		// the SRTP context keeps the rollover counter, packets are encrypted in sequence order
+		buf, err = f.srtp.EncryptRTP(nil, buf, &hdr)
+	}
+	f.lock.Unlock()
+
+	if err == nil {
+		_, err = f.conn.Write(buf)
+	}
+	if err != nil {
+		if f.writeErrors.Inc() == 1 {
+			f.logger.Warnw("could not forward packet", err)
+		}
+		return err
+	}
+	f.packetsSent.Inc()
+	f.bytesSent.Add(uint64(len(buf)))
+	return nil
+}
+
+func (f *TrackForwarder) WriteKeyFrameCache(pkts []*buffer.ExtPacket, layer int32) {
+	for _, p := range pkts {
+		_ = f.WriteRTP(p, layer)
+	}
+}
+
+func (f *TrackForwarder) Resync() {
+	if !f.isVideo {
+		return
+	}
+	f.lock.Lock()
+	f.waitingKeyFrame = true
+	f.lock.Unlock()
+	f.receiver.SendPLI(f.layer, true)
+}
+
+func (f *TrackForwarder) Close() {
+	f.closeWithError("")
+}
+
+func (f *TrackForwarder) closeWithError(reason string) {
+	if f.closed.Swap(true) {
+		return
+	}
+	f.lock.Lock()
+	f.info.EndedAt = time.Now().UnixNano()
+	f.info.Error = reason
+	f.lock.Unlock()
+	f.receiver.DeleteDownTrack(f.SubscriberID())
+	_ = f.conn.Close()
+	f.logger.Infow("track forward ended", "packetsSent", f.packetsSent.Load(), "writeErrors", f.writeErrors.Load())
+	if f.onClose != nil {
+		f.onClose(f)
+	}
+}
+
+func (f *TrackForwarder) IsClosed() bool {
+	return f.closed.Load()
+}
+
+func (f *TrackForwarder) ID() string {
+	return f.info.ForwardID
+}
+
+// SubscriberID identifies the forwarder among the down tracks of the receiver
+func (f *TrackForwarder) SubscriberID() livekit.ParticipantID {
+	return livekit.ParticipantID(f.info.ForwardID)
+}
+
+// the forwarder sends a fixed layer and no RTCP, layer and sender report updates are ignored
+
+func (f *TrackForwarder) UpTrackLayersChange()                       {}
+func (f *TrackForwarder) UpTrackBitrateAvailabilityChange()          {}
+func (f *TrackForwarder) UpTrackMaxPublishedLayerChange(int32)       {}
+func (f *TrackForwarder) UpTrackMaxTemporalLayerSeenChange(int32)    {}
+func (f *TrackForwarder) UpTrackBitrateReport([]int32, sfu.Bitrates) {}
+
+func (f *TrackForwarder) HandleRTCPSenderReportData(webrtc.PayloadType, bool, int32, *livekit.RTCPSenderReportState) error {
+	return nil
+}
+
+// ---------------------------------------------
+
+// ForwardTrack starts forwarding a published track to an external address
+func (r *Room) ForwardTrack(req *TrackForwardRequest) (*TrackForwardInfo, error) {
+	if r.IsClosed() {
+		return nil, ErrRoomClosed
+	}
+	track := r.trackManager.GetTrackInfo(livekit.TrackID(req.TrackSid))
+	if track == nil {
+		return nil, ErrTrackNotFound
+	}
+
+	f, err := NewTrackForwarder(r.Name(), track, req, r.Logger)
+	if err != nil {
+		return nil, err
+	}
+	f.OnClose(func(f *TrackForwarder) {
+		r.lock.Lock()
+		delete(r.trackForwarders, f.ID())
+		onEnded := r.onTrackForwardEnded
+		r.lock.Unlock()
+		if onEnded != nil {
+			onEnded(f.Info())
+		}
+	})
+
+	r.lock.Lock()
+	r.trackForwarders[f.ID()] = f
+	r.lock.Unlock()
+	if err = f.Start(); err != nil {
+		r.lock.Lock()
+		delete(r.trackForwarders, f.ID())
+		r.lock.Unlock()
+		return nil, err
+	}
+	return f.Info(), nil
+}
+
+// StopTrackForward stops a track forward and returns its final counters
+func (r *Room) StopTrackForward(forwardID string) (*TrackForwardInfo, error) {
+	r.lock.RLock()
+	f := r.trackForwarders[forwardID]
+	r.lock.RUnlock()
+	if f == nil {
+		return nil, ErrTrackForwardNotFound
+	}
+	f.Close()
+	return f.Info(), nil
+}
+
+func (r *Room) GetTrackForwards() []*TrackForwardInfo {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	forwards := make([]*TrackForwardInfo, 0, len(r.trackForwarders))
+	for _, f := range r.trackForwarders {
+		forwards = append(forwards, f.Info())
+	}
+	return forwards
+}
+
+// OnTrackForwardEnded is called when a track forward stops, on request or because the track was unpublished
+func (r *Room) OnTrackForwardEnded(f func(info *TrackForwardInfo)) {
+	r.lock.Lock()
+	r.onTrackForwardEnded = f
+	r.lock.Unlock()
+}
+
+func (r *Room) closeTrackForwards() {
+	r.lock.RLock()
+	forwarders := make([]*TrackForwarder, 0, len(r.trackForwarders))
+	for _, f := range r.trackForwarders {
+		forwarders = append(forwarders, f)
+	}
+	r.lock.RUnlock()
+
+	for _, f := range forwarders {
+		f.closeWithError("room closed")
+	}
+}
@@ -0,0 +1,575 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+const (
+	TrackRecordingPrefix = "TREC_"
+
+	// RecordingJobKindTrackRecording is the kind of the recording jobs of track recordings
+	RecordingJobKindTrackRecording = "track_recording"
+)
+
+// TrackRecordingStore stores the segments of track recordings, see archiver.S3Store
+type TrackRecordingStore interface {
+	PutContent(ctx context.Context, key string, contentType string, data []byte) error
+}
+
+// TrackRecordingRequest records a published track
+type TrackRecordingRequest struct {
+	TrackSid string `json:"track_sid"`
+	// Quality selects the simulcast layer of video tracks: low, medium or high when unset
+	Quality string `json:"quality,omitempty"`
+}
+
+// TrackRecordingInfo describes a track recording, with the location of its segments and the counters of
+// what was recorded. Segments are stored at <location><index>.webm, indexes start at 0.
+type TrackRecordingInfo struct {
+	RecordingID   string `json:"recording_id"`
+	Room          string `json:"room"`
+	TrackSid      string `json:"track_sid"`
+	MimeType      string `json:"mime_type"`
+	Location      string `json:"location"`
+	Segments      uint64 `json:"segments"`
+	BytesUploaded uint64 `json:"bytes_uploaded"`
+	// Duration of the media uploaded, in nanoseconds
+	Duration        int64  `json:"duration"`
+	FramesDropped   uint64 `json:"frames_dropped"`
+	SegmentsDropped uint64 `json:"segments_dropped"`
+	UploadErrors    uint64 `json:"upload_errors"`
+	StartedAt       int64  `json:"started_at"`
+	EndedAt         int64  `json:"ended_at,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+type trackRecordingSegment struct {
+	key      string
+	data     []byte
+	duration time.Duration
+}
+
+// TrackRecorder writes one layer of a published Opus or VP8 track into WebM segments uploaded to object
+// storage, for simple archival without an egress. It is attached to the track receiver like a down track,
+// so it is closed along with the track. Frames are depacketized as they are forwarded: video frames missing
+// packets are dropped until the next key frame, which is requested from the publisher. Segments are uploaded
+// in order by a worker of their own, the last one after the recording is closed.
+type TrackRecorder struct {
+	info      TrackRecordingInfo
+	receiver  sfu.TrackReceiver
+	layer     int32
+	isVideo   bool
+	clockRate uint64
+	track     webmTrack
+	store     TrackRecordingStore
+	conf      config.TrackRecordingConfig
+	logger    logger.Logger
+
+	lock            sync.Mutex
+	writer          *webmWriter
+	segmentIndex    int
+	segmentStart    int64
+	firstTS         uint64
+	started         bool
+	frame           []byte
+	inFrame         bool
+	frameTS         uint64
+	frameSN         uint64
+	frameKey        bool
+	pictureID       uint16
+	hasPictureID    bool
+	waitingKeyFrame bool
+	keyFrameAsked   bool
+	uploads         chan *trackRecordingSegment
+	uploadsDone     chan struct{}
+
+	segments        atomic.Uint64
+	bytesUploaded   atomic.Uint64
+	duration        atomic.Int64
+	framesDropped   atomic.Uint64
+	segmentsDropped atomic.Uint64
+	uploadErrors    atomic.Uint64
+	closed          atomic.Bool
+	onClose         func(*TrackRecorder)
+}
+
+func NewTrackRecorder(
+	roomName livekit.RoomName,
+	track *TrackInfo,
+	req *TrackRecordingRequest,
+	store TrackRecordingStore,
+	conf config.TrackRecordingConfig,
+	l logger.Logger,
+) (*TrackRecorder, error) {
+	receivers := track.Track.Receivers()
+	if len(receivers) == 0 {
+		return nil, ErrTrackNotBound
+	}
+	ti := track.Track.ToProto()
+	if ti.Encryption != livekit.Encryption_NONE {
+		return nil, fmt.Errorf("%w: encrypted tracks cannot be recorded", ErrInvalidTrackRecording)
+	}
+	receiver := receivers[0].GetPrimaryReceiverForRed()
+
+	codec := receiver.Codec()
+	r := &TrackRecorder{
+		receiver:  receiver,
+		isVideo:   track.Track.Kind() == livekit.TrackType_VIDEO,
+		clockRate: uint64(codec.ClockRate),
+		store:     store,
+		conf:      conf,
+	}
+	switch {
+	case strings.EqualFold(codec.MimeType, webrtc.MimeTypeOpus):
+		channels := uint8(1)
+		if ti.Stereo {
+			channels = 2
+		}
+		r.track = webmTrack{
+			CodecID:      webmCodecOpus,
+			CodecPrivate: opusHead(channels, codec.ClockRate),
+			SampleRate:   codec.ClockRate,
+			Channels:     channels,
+		}
+	case strings.EqualFold(codec.MimeType, webrtc.MimeTypeVP8):
+		quality := livekit.VideoQuality_HIGH
+		if req.Quality != "" {
+			q, ok := livekit.VideoQuality_value[strings.ToUpper(req.Quality)]
+			if !ok || livekit.VideoQuality(q) == livekit.VideoQuality_OFF {
+				return nil, ErrInvalidTrackRecording
+			}
+			quality = livekit.VideoQuality(q)
+		}
+		r.layer = buffer.VideoQualityToSpatialLayer(quality, ti)
+		r.track = webmTrack{CodecID: webmCodecVP8, Video: true, Width: ti.Width, Height: ti.Height}
+		for _, layer := range ti.Layers {
+			if layer.Quality == buffer.SpatialLayerToVideoQuality(r.layer, ti) {
+				r.track.Width, r.track.Height = layer.Width, layer.Height
+			}
+		}
+		r.waitingKeyFrame = true
+	default:
+		return nil, fmt.Errorf("%w: %s tracks cannot be recorded", ErrInvalidTrackRecording, codec.MimeType)
+	}
+	if r.clockRate == 0 {
+		return nil, ErrInvalidTrackRecording
+	}
+
+	recordingID := guid.New(TrackRecordingPrefix)
+	r.info = TrackRecordingInfo{
+		RecordingID: recordingID,
+		Room:        string(roomName),
+		TrackSid:    req.TrackSid,
+		MimeType:    codec.MimeType,
+		Location:    fmt.Sprintf("%s%s/%s/%s/", conf.S3.Prefix, roomName, req.TrackSid, recordingID),
+		StartedAt:   time.Now().UnixNano(),
+	}
+	r.writer = newWebMWriter(r.track)
+	r.uploads = make(chan *trackRecordingSegment, conf.MaxPendingSegments)
+	r.uploadsDone = make(chan struct{})
+	r.logger = l.WithValues("recordingID", recordingID, "trackID", req.TrackSid)
+	return r, nil
+}
+
+// Start attaches the recorder to the track, video is recorded from the next key frame
+func (r *TrackRecorder) Start() error {
+	if err := r.receiver.AddDownTrack(r); err != nil {
+		return err
+	}
+	go r.uploadWorker()
+	if r.isVideo {
+		r.receiver.SendPLI(r.layer, true)
+	}
+	r.logger.Infow("track recording started", "location", r.info.Location)
+	return nil
+}
+
+func (r *TrackRecorder) OnClose(fn func(*TrackRecorder)) {
+	r.onClose = fn
+}
+
+func (r *TrackRecorder) Info() *TrackRecordingInfo {
+	r.lock.Lock()
+	info := r.info
+	r.lock.Unlock()
+	info.Segments = r.segments.Load()
+	info.BytesUploaded = r.bytesUploaded.Load()
+	info.Duration = r.duration.Load()
+	info.FramesDropped = r.framesDropped.Load()
+	info.SegmentsDropped = r.segmentsDropped.Load()
+	info.UploadErrors = r.uploadErrors.Load()
+	return &info
+}
+
+func (r *TrackRecorder) WriteRTP(p *buffer.ExtPacket, layer int32) error {
+	if r.closed.Load() || layer != r.layer || len(p.Packet.Payload) == 0 {
+		return nil
+	}
+
+	r.lock.Lock()
+	var askKeyFrame bool
+	if r.closed.Load() {
+		r.lock.Unlock()
+		return nil
+	}
+	if r.isVideo {
+		askKeyFrame = r.pushVideoLocked(p)
+	} else {
+		askKeyFrame = r.writeFrameLocked(p.ExtTimestamp, false, p.Packet.Payload)
+	}
+	r.lock.Unlock()
+
+	if askKeyFrame {
+		r.receiver.SendPLI(r.layer, false)
+	}
+	return nil
+}
+
+// pushVideoLocked adds a packet to the frame it belongs to and writes the frame on its last packet,
+// it returns whether a key frame should be requested
+func (r *TrackRecorder) pushVideoLocked(p *buffer.ExtPacket) bool {
+	vp8, ok := p.Payload.(buffer.VP8)
+	if !ok || len(p.Packet.Payload) <= vp8.HeaderSize {
+		return false
+	}
+	payload := p.Packet.Payload[vp8.HeaderSize:]
+
+	if vp8.S && vp8.FirstByte&0x07 == 0 {
+		// the last packet of the previous frame was lost
+		lost := r.inFrame
+		if vp8.I {
+			mask := uint16(0x7f)
+			if vp8.M {
+				mask = 0x7fff
+			}
+			lost = lost || (r.hasPictureID && vp8.PictureID != (r.pictureID+1)&mask)
+			r.pictureID, r.hasPictureID = vp8.PictureID, true
+		}
+		if lost {
+			r.inFrame = false
+			if !p.KeyFrame {
+				return r.dropFrameLocked()
+			}
+		}
+		if r.waitingKeyFrame && !p.KeyFrame {
+			return false
+		}
+		r.waitingKeyFrame = false
+		r.inFrame = true
+		r.frame = append(r.frame[:0], payload...)
+		r.frameTS = p.ExtTimestamp
+		r.frameSN = p.ExtSequenceNumber
+		r.frameKey = p.KeyFrame
+	} else {
+		if !r.inFrame {
+			return false
+		}
+		if p.ExtSequenceNumber != r.frameSN+1 || p.ExtTimestamp != r.frameTS {
+			// a packet of the frame was lost
+			r.inFrame = false
+			return r.dropFrameLocked()
+		}
+		r.frame = append(r.frame, payload...)
+		r.frameSN = p.ExtSequenceNumber
+	}
+
+	if !p.Packet.Marker {
+		return false
+	}
+	r.inFrame = false
+	return r.writeFrameLocked(r.frameTS, r.frameKey, r.frame)
+}
+
+// dropFrameLocked drops a video frame missing packets, the following frames depend on it so recording
+// resumes at the next key frame
+func (r *TrackRecorder) dropFrameLocked() bool {
+	r.framesDropped.Inc()
+	r.waitingKeyFrame = true
+	return true
+}
+
+// writeFrameLocked adds a frame to the current segment, cutting the segment once it is long enough. Video
+// segments are cut at key frames, one is requested when a segment is due.
+func (r *TrackRecorder) writeFrameLocked(ts uint64, keyFrame bool, frame []byte) bool {
+	if !r.started {
+		r.started = true
+		r.firstTS = ts
+	}
+	if ts < r.firstTS {
+		return false
+	}
+	ms := int64((ts - r.firstTS) * 1000 / r.clockRate)
+
+	var askKeyFrame bool
+	if r.writer.Frames() != 0 && ms-r.segmentStart >= r.conf.SegmentDuration.Milliseconds() {
+		switch {
+		case !r.isVideo || keyFrame:
+			r.cutSegmentLocked()
+		case !r.keyFrameAsked:
+			r.keyFrameAsked = true
+			askKeyFrame = true
+		}
+	}
+	if r.writer.Frames() == 0 {
+		r.segmentStart = ms
+	}
+	r.writer.WriteFrame(ms, keyFrame, frame)
+	return askKeyFrame
+}
+
+// cutSegmentLocked queues the current segment for upload, it is dropped when too many segments are pending
+func (r *TrackRecorder) cutSegmentLocked() {
+	if r.writer.Frames() == 0 {
+		return
+	}
+	segment := &trackRecordingSegment{
+		key:      fmt.Sprintf("%s%05d.webm", r.info.Location, r.segmentIndex),
+		data:     r.writer.Bytes(),
+		duration: time.Duration(r.writer.Duration()) * time.Millisecond,
+	}
+	r.segmentIndex++
+	r.writer = newWebMWriter(r.track)
+	r.keyFrameAsked = false
+
+	select {
+	case r.uploads <- segment:
+	default:
+		if r.segmentsDropped.Inc() == 1 {
+			r.logger.Warnw("dropping track recording segment, uploads are falling behind", nil, "key", segment.key)
+		}
+	}
+}
+
+func (r *TrackRecorder) uploadWorker() {
+	defer close(r.uploadsDone)
+
+	contentType := "audio/webm"
+	if r.isVideo {
+		contentType = "video/webm"
+	}
+	for segment := range r.uploads {
+		ctx, cancel := context.WithTimeout(context.Background(), r.conf.S3.Timeout)
+		err := r.store.PutContent(ctx, segment.key, contentType, segment.data)
+		cancel()
+		if err != nil {
+			if r.uploadErrors.Inc() == 1 {
+				r.logger.Warnw("could not upload track recording segment", err, "key", segment.key)
+			}
+			continue
+		}
+		r.segments.Inc()
+		r.bytesUploaded.Add(uint64(len(segment.data)))
+		r.duration.Add(int64(segment.duration))
+	}
+}
+
+func (r *TrackRecorder) WriteKeyFrameCache(pkts []*buffer.ExtPacket, layer int32) {
+	for _, p := range pkts {
+		_ = r.WriteRTP(p, layer)
+	}
+}
+
+func (r *TrackRecorder) Resync() {
+	if !r.isVideo {
+		return
+	}
+	r.lock.Lock()
+	r.inFrame = false
+	r.waitingKeyFrame = true
+	r.lock.Unlock()
+	r.receiver.SendPLI(r.layer, true)
+}
+
+func (r *TrackRecorder) Close() {
+	r.closeWithError("")
+}
+
+// closeWithError detaches the recorder and uploads its last segment, the recording ends once all its
+// segments are uploaded
+func (r *TrackRecorder) closeWithError(reason string) {
+	r.lock.Lock()
+	if r.closed.Swap(true) {
+		r.lock.Unlock()
+		return
+	}
+	r.cutSegmentLocked()
+	close(r.uploads)
+	r.lock.Unlock()
+	r.receiver.DeleteDownTrack(r.SubscriberID())
+
+	go func() {
+		<-r.uploadsDone
+		r.lock.Lock()
+		r.info.EndedAt = time.Now().UnixNano()
+		r.info.Error = reason
+		r.lock.Unlock()
+		r.logger.Infow("track recording ended",
+			"segments", r.segments.Load(),
+			"framesDropped", r.framesDropped.Load(),
+			"segmentsDropped", r.segmentsDropped.Load(),
+			"uploadErrors", r.uploadErrors.Load(),
+		)
+		if r.onClose != nil {
+			r.onClose(r)
+		}
+	}()
+}
+
+func (r *TrackRecorder) IsClosed() bool {
+	return r.closed.Load()
+}
+
+func (r *TrackRecorder) ID() string {
+	return r.info.RecordingID
+}
+
+// SubscriberID identifies the recorder among the down tracks of the receiver
+func (r *TrackRecorder) SubscriberID() livekit.ParticipantID {
+	return livekit.ParticipantID(r.info.RecordingID)
+}
+
+// the recorder records a fixed layer and sends no RTCP, layer and sender report updates are ignored
+
+func (r *TrackRecorder) UpTrackLayersChange()                       {}
+func (r *TrackRecorder) UpTrackBitrateAvailabilityChange()          {}
+func (r *TrackRecorder) UpTrackMaxPublishedLayerChange(int32)       {}
+func (r *TrackRecorder) UpTrackMaxTemporalLayerSeenChange(int32)    {}
+func (r *TrackRecorder) UpTrackBitrateReport([]int32, sfu.Bitrates) {}
+
+func (r *TrackRecorder) HandleRTCPSenderReportData(webrtc.PayloadType, bool, int32, *livekit.RTCPSenderReportState) error {
+	return nil
+}
+
+// ---------------------------------------------
+
+// EnableTrackRecording allows the tracks of the room to be recorded to a store
+func (r *Room) EnableTrackRecording(store TrackRecordingStore, conf config.TrackRecordingConfig) {
+	r.lock.Lock()
+	r.trackRecordingStore = store
+	r.trackRecordingConfig = conf
+	r.lock.Unlock()
+}
+
+// RecordTrack starts recording a published track. Track recordings are recording jobs of the room, which is
+// recorded while they run.
+func (r *Room) RecordTrack(req *TrackRecordingRequest) (*TrackRecordingInfo, error) {
+	if r.IsClosed() {
+		return nil, ErrRoomClosed
+	}
+	r.lock.RLock()
+	store, conf := r.trackRecordingStore, r.trackRecordingConfig
+	r.lock.RUnlock()
+	if store == nil {
+		return nil, ErrTrackRecordingDisabled
+	}
+	track := r.trackManager.GetTrackInfo(livekit.TrackID(req.TrackSid))
+	if track == nil {
+		return nil, ErrTrackNotFound
+	}
+
+	rec, err := NewTrackRecorder(r.Name(), track, req, store, conf, r.Logger)
+	if err != nil {
+		return nil, err
+	}
+	rec.OnClose(func(rec *TrackRecorder) {
+		r.lock.Lock()
+		delete(r.trackRecorders, rec.ID())
+		onEnded := r.onTrackRecordingEnded
+		r.lock.Unlock()
+		r.UpdateRecordingJob(RecordingJob{ID: rec.ID(), Kind: RecordingJobKindTrackRecording})
+		if onEnded != nil {
+			onEnded(rec.Info())
+		}
+	})
+
+	r.lock.Lock()
+	r.trackRecorders[rec.ID()] = rec
+	r.lock.Unlock()
+	if err = rec.Start(); err != nil {
+		r.lock.Lock()
+		delete(r.trackRecorders, rec.ID())
+		r.lock.Unlock()
+		return nil, err
+	}
+	info := rec.Info()
+	r.UpdateRecordingJob(RecordingJob{
+		ID:        rec.ID(),
+		Kind:      RecordingJobKindTrackRecording,
+		Active:    true,
+		StartedAt: time.Unix(0, info.StartedAt),
+	})
+	return info, nil
+}
+
+// StopTrackRecording stops a track recording, its last segment is uploaded in the background
+func (r *Room) StopTrackRecording(recordingID string) (*TrackRecordingInfo, error) {
+	r.lock.RLock()
+	rec := r.trackRecorders[recordingID]
+	r.lock.RUnlock()
+	if rec == nil {
+		return nil, ErrTrackRecordingNotFound
+	}
+	rec.Close()
+	return rec.Info(), nil
+}
+
+func (r *Room) GetTrackRecordings() []*TrackRecordingInfo {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	recordings := make([]*TrackRecordingInfo, 0, len(r.trackRecorders))
+	for _, rec := range r.trackRecorders {
+		recordings = append(recordings, rec.Info())
+	}
+	return recordings
+}
+
+// OnTrackRecordingEnded is called when a track recording ended and its segments are uploaded, whether it
+// was stopped on request or because the track was unpublished
+func (r *Room) OnTrackRecordingEnded(f func(info *TrackRecordingInfo)) {
+	r.lock.Lock()
+	r.onTrackRecordingEnded = f
+	r.lock.Unlock()
+}
+
+func (r *Room) closeTrackRecordings() {
+	r.lock.RLock()
+	recorders := make([]*TrackRecorder, 0, len(r.trackRecorders))
+	for _, rec := range r.trackRecorders {
+		recorders = append(recorders, rec)
+	}
+	r.lock.RUnlock()
+
+	for _, rec := range recorders {
+		rec.closeWithError("room closed")
+	}
+}
@@ -317,11 +317,13 @@ func newPeerConnection(params TransportParams, onBandwidthEstimator func(estimat
 	se.SetDTLSRetransmissionInterval(dtlsRetransmissionInterval)
 	se.SetICETimeouts(iceDisconnectedTimeout, iceFailedTimeout, iceKeepaliveInterval)
 
+	policyIPFilter, nat1To1IPs := params.Config.ICEPolicy.configure(&se, params.Config.NAT1To1IPs, params.Logger)
+
 	// if client don't support prflx over relay, we should not expose private address to it, use single external ip as host candidate
-	if !params.ClientInfo.SupportPrflxOverRelay() && len(params.Config.NAT1To1IPs) > 0 {
+	if !params.ClientInfo.SupportPrflxOverRelay() && len(nat1To1IPs) > 0 {
 		var nat1to1Ips []string
 		var includeIps []string
-		for _, mapping := range params.Config.NAT1To1IPs {
+		for _, mapping := range nat1To1IPs {
 			if ips := strings.Split(mapping, "/"); len(ips) == 2 {
 				if ips[0] != ips[1] {
 					nat1to1Ips = append(nat1to1Ips, mapping)
@@ -333,6 +335,9 @@ func newPeerConnection(params TransportParams, onBandwidthEstimator func(estimat
 			params.Logger.Infow("client doesn't support prflx over relay, use external ip only as host candidate", "ips", nat1to1Ips)
 			se.SetNAT1To1IPs(nat1to1Ips, webrtc.ICECandidateTypeHost)
 			se.SetIPFilter(func(ip net.IP) bool {
+				if policyIPFilter != nil && !policyIPFilter(ip) {
+					return false
+				}
 				if ip.To4() == nil {
 					return true
 				}
@@ -451,7 +456,8 @@ func NewPCTransport(params TransportParams) (*PCTransport, error) {
 		connectionDetails:        types.NewICEConnectionDetails(params.Transport, params.Logger),
 		lastNegotiate:            time.Now(),
 	}
-	if params.IsSendSide {
+	// in one shot signalling mode, subscribed tracks are sent on the publisher peer connection
+	if params.IsSendSide || params.UseOneShotSignallingMode {
 		t.streamAllocator = streamallocator.NewStreamAllocator(streamallocator.StreamAllocatorParams{
 			Config: params.CongestionControlConfig.StreamAllocator,
 			Logger: params.Logger.WithComponent(utils.ComponentCongestionControl),
@@ -1224,6 +1230,14 @@ func (t *PCTransport) SetChannelCapacityOfStreamAllocator(channelCapacity int64)
 	t.streamAllocator.SetChannelCapacity(channelCapacity)
 }
 
+func (t *PCTransport) SetMaxPublisherShareOfStreamAllocator(share float64) {
+	if t.streamAllocator == nil {
+		return
+	}
+
+	t.streamAllocator.SetMaxPublisherShare(share)
+}
+
 func (t *PCTransport) preparePC(previousAnswer webrtc.SessionDescription) error {
 	// sticky data channel to first m-lines, if someday we don't send sdp without media streams to
 	// client's subscribe pc after joining, should change this step
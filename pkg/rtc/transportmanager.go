@@ -277,11 +277,19 @@ func (t *TransportManager) GetSubscriberPacer() pacer.Pacer {
 }
 
 func (t *TransportManager) AddSubscribedTrack(subTrack types.SubscribedTrack) {
-	t.subscriber.AddTrackToStreamAllocator(subTrack)
+	if t.params.UseOneShotSignallingMode {
+		t.publisher.AddTrackToStreamAllocator(subTrack)
+	} else {
+		t.subscriber.AddTrackToStreamAllocator(subTrack)
+	}
 }
 
 func (t *TransportManager) RemoveSubscribedTrack(subTrack types.SubscribedTrack) {
-	t.subscriber.RemoveTrackFromStreamAllocator(subTrack)
+	if t.params.UseOneShotSignallingMode {
+		t.publisher.RemoveTrackFromStreamAllocator(subTrack)
+	} else {
+		t.subscriber.RemoveTrackFromStreamAllocator(subTrack)
+	}
 }
 
 func (t *TransportManager) SendDataPacket(kind livekit.DataPacket_Kind, encoded []byte) error {
@@ -755,6 +763,12 @@ func (t *TransportManager) UpdateSignalingRTT(rtt uint32) {
 	// Currently, most cases reported is that ice connected but subsequent connection, so left the thinking for now.
 }
 
+func (t *TransportManager) SignalingRTT() uint32 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.signalingRTT
+}
+
 func (t *TransportManager) UpdateMediaRTT(rtt uint32) {
 	t.lock.Lock()
 	if t.udpRTT == 0 {
@@ -800,6 +814,10 @@ func (t *TransportManager) SetSubscriberChannelCapacity(channelCapacity int64) {
 	t.subscriber.SetChannelCapacityOfStreamAllocator(channelCapacity)
 }
 
+func (t *TransportManager) SetSubscriberMaxPublisherShare(share float64) {
+	t.subscriber.SetMaxPublisherShareOfStreamAllocator(share)
+}
+
 func (t *TransportManager) hasRecentSignalLocked() bool {
 	return time.Since(t.lastSignalAt) < PingTimeoutSeconds*time.Second
 }
@@ -0,0 +1,27 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+// AdaptiveStreamHint describes how a subscriber renders a video track. It is combined with the explicit
+// settings of the subscription to select the layer the track is forwarded at.
+type AdaptiveStreamHint struct {
+	// false when no element rendering the track is on screen
+	Visible bool `json:"visible"`
+	// size of the largest element rendering the track, in CSS pixels
+	Width  uint32 `json:"width,omitempty"`
+	Height uint32 `json:"height,omitempty"`
+	// device pixels per CSS pixel of the screen, 1 when unset
+	PixelDensity float32 `json:"pixel_density,omitempty"`
+}
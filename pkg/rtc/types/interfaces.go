@@ -110,6 +110,8 @@ const (
 	ParticipantCloseReasonRoomClosed
 	ParticipantCloseReasonUserUnavailable
 	ParticipantCloseReasonUserRejected
+	ParticipantCloseReasonMovedToRoom
+	ParticipantCloseReasonSignalSlowConsumer
 )
 
 func (p ParticipantCloseReason) String() string {
@@ -168,6 +170,10 @@ func (p ParticipantCloseReason) String() string {
 		return "USER_UNAVAILABLE"
 	case ParticipantCloseReasonUserRejected:
 		return "USER_REJECTED"
+	case ParticipantCloseReasonMovedToRoom:
+		return "MOVED_TO_ROOM"
+	case ParticipantCloseReasonSignalSlowConsumer:
+		return "SIGNAL_SLOW_CONSUMER"
 	default:
 		return fmt.Sprintf("%d", int(p))
 	}
@@ -186,7 +192,9 @@ func (p ParticipantCloseReason) ToDisconnectReason() livekit.DisconnectReason {
 		return livekit.DisconnectReason_STATE_MISMATCH
 	case ParticipantCloseReasonDuplicateIdentity, ParticipantCloseReasonStale:
 		return livekit.DisconnectReason_DUPLICATE_IDENTITY
-	case ParticipantCloseReasonMigrationRequested, ParticipantCloseReasonMigrationComplete, ParticipantCloseReasonSimulateMigration:
+	case ParticipantCloseReasonMigrationRequested, ParticipantCloseReasonMigrationComplete, ParticipantCloseReasonSimulateMigration,
+		ParticipantCloseReasonMovedToRoom:
+		// moved participants reconnect to another room, like after a migration
 		return livekit.DisconnectReason_MIGRATION
 	case ParticipantCloseReasonServiceRequestRemoveParticipant:
 		return livekit.DisconnectReason_PARTICIPANT_REMOVED
@@ -196,7 +204,7 @@ func (p ParticipantCloseReason) ToDisconnectReason() livekit.DisconnectReason {
 		return livekit.DisconnectReason_SERVER_SHUTDOWN
 	case ParticipantCloseReasonNegotiateFailed, ParticipantCloseReasonPublicationError, ParticipantCloseReasonSubscriptionError, ParticipantCloseReasonDataChannelError, ParticipantCloseReasonMigrateCodecMismatch:
 		return livekit.DisconnectReason_STATE_MISMATCH
-	case ParticipantCloseReasonSignalSourceClose:
+	case ParticipantCloseReasonSignalSourceClose, ParticipantCloseReasonSignalSlowConsumer:
 		return livekit.DisconnectReason_SIGNAL_CLOSE
 	case ParticipantCloseReasonRoomClosed:
 		return livekit.DisconnectReason_ROOM_CLOSED
@@ -380,6 +388,7 @@ type LocalParticipant interface {
 	SubscribeToTrack(trackID livekit.TrackID)
 	UnsubscribeFromTrack(trackID livekit.TrackID)
 	UpdateSubscribedTrackSettings(trackID livekit.TrackID, settings *livekit.UpdateTrackSettings)
+	UpdateAdaptiveStreamHint(trackID livekit.TrackID, hint *AdaptiveStreamHint)
 	GetSubscribedTracks() []SubscribedTrack
 	IsTrackNameSubscribed(publisherIdentity livekit.ParticipantIdentity, trackName string) bool
 	Verify() bool
@@ -404,7 +413,13 @@ type LocalParticipant interface {
 	SendConnectionQualityUpdate(update *livekit.ConnectionQualityUpdate) error
 	SubscriptionPermissionUpdate(publisherID livekit.ParticipantID, trackID livekit.TrackID, allowed bool)
 	SendRefreshToken(token string) error
+	SendTimeSyncBeacon(timestamp int64) error
+	// SendSubscriptionError tells the participant subscribing to the track failed, issuing a full reconnect when
+	// fatal and configured to
+	SendSubscriptionError(trackID livekit.TrackID, signalErr livekit.SubscriptionError, fatal bool)
 	SendRequestResponse(requestResponse *livekit.RequestResponse) error
+	// SendNotification sends an error or warning about a request of the participant, deduplicated and rate limited
+	SendNotification(notification *Notification)
 	HandleReconnectAndSendResponse(reconnectReason livekit.ReconnectReason, reconnectResponse *livekit.ReconnectResponse) error
 	IssueFullReconnect(reason ParticipantCloseReason)
 
@@ -441,6 +456,7 @@ type LocalParticipant interface {
 
 	UpdateMediaRTT(rtt uint32)
 	UpdateSignalingRTT(rtt uint32)
+	SignalingRTT() uint32
 
 	CacheDownTrack(trackID livekit.TrackID, rtpTransceiver *webrtc.RTPTransceiver, downTrackState sfu.DownTrackState)
 	UncacheDownTrack(rtpTransceiver *webrtc.RTPTransceiver)
@@ -456,6 +472,7 @@ type LocalParticipant interface {
 	// down stream bandwidth management
 	SetSubscriberAllowPause(allowPause bool)
 	SetSubscriberChannelCapacity(channelCapacity int64)
+	SetSubscriberMaxPublisherShare(share float64)
 
 	GetPacer() pacer.Pacer
 
@@ -576,6 +593,7 @@ type SubscribedTrack interface {
 
 type ChangeNotifier interface {
 	AddObserver(key string, onChanged func())
+	AddBatchObserver(key string, onChanged func(changedKeys []string))
 	RemoveObserver(key string)
 	HasObservers() bool
 	NotifyChanged()
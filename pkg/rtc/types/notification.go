@@ -0,0 +1,47 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+type NotificationSeverity string
+
+const (
+	NotificationSeverityInfo    NotificationSeverity = "info"
+	NotificationSeverityWarning NotificationSeverity = "warning"
+	NotificationSeverityError   NotificationSeverity = "error"
+)
+
+// codes of the notifications sent by the server
+const (
+	// a track of a source the participant has no permission to publish
+	NotificationPublishNotAllowed = "publish_not_allowed"
+	// a track publication denied by the policy webhook or a quota
+	NotificationPublishDenied = "publish_denied"
+	// a track published with a codec that is not enabled in the room, another codec is used instead
+	NotificationCodecNotAllowed = "codec_not_allowed"
+	// data packets dropped as the participant has no permission to publish data
+	NotificationPublishDataNotAllowed = "publish_data_not_allowed"
+)
+
+// Notification tells a participant about a request of theirs the server did not fulfill as asked
+type Notification struct {
+	Severity NotificationSeverity `json:"severity"`
+	Code     string               `json:"code"`
+	Message  string               `json:"message"`
+	// notifications of the same key are deduplicated, the code is the key when it is empty
+	Key      string `json:"key,omitempty"`
+	TrackSid string `json:"track_sid,omitempty"`
+	// notifications of the key suppressed since the previous one was sent
+	Suppressed int `json:"suppressed,omitempty"`
+}
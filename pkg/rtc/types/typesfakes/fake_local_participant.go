@@ -808,6 +808,11 @@ type FakeLocalParticipant struct {
 	sendJoinResponseReturnsOnCall map[int]struct {
 		result1 error
 	}
+	SendNotificationStub        func(*types.Notification)
+	sendNotificationMutex       sync.RWMutex
+	sendNotificationArgsForCall []struct {
+		arg1 *types.Notification
+	}
 	SendParticipantUpdateStub        func([]*livekit.ParticipantInfo) error
 	sendParticipantUpdateMutex       sync.RWMutex
 	sendParticipantUpdateArgsForCall []struct {
@@ -864,6 +869,24 @@ type FakeLocalParticipant struct {
 	sendSpeakerUpdateReturnsOnCall map[int]struct {
 		result1 error
 	}
+	SendSubscriptionErrorStub        func(livekit.TrackID, livekit.SubscriptionError, bool)
+	sendSubscriptionErrorMutex       sync.RWMutex
+	sendSubscriptionErrorArgsForCall []struct {
+		arg1 livekit.TrackID
+		arg2 livekit.SubscriptionError
+		arg3 bool
+	}
+	SendTimeSyncBeaconStub        func(int64) error
+	sendTimeSyncBeaconMutex       sync.RWMutex
+	sendTimeSyncBeaconArgsForCall []struct {
+		arg1 int64
+	}
+	sendTimeSyncBeaconReturns struct {
+		result1 error
+	}
+	sendTimeSyncBeaconReturnsOnCall map[int]struct {
+		result1 error
+	}
 	SetAttributesStub        func(map[string]string)
 	setAttributesMutex       sync.RWMutex
 	setAttributesArgsForCall []struct {
@@ -928,6 +951,11 @@ type FakeLocalParticipant struct {
 	setSubscriberChannelCapacityArgsForCall []struct {
 		arg1 int64
 	}
+	SetSubscriberMaxPublisherShareStub        func(float64)
+	setSubscriberMaxPublisherShareMutex       sync.RWMutex
+	setSubscriberMaxPublisherShareArgsForCall []struct {
+		arg1 float64
+	}
 	SetTrackMutedStub        func(livekit.TrackID, bool, bool) *livekit.TrackInfo
 	setTrackMutedMutex       sync.RWMutex
 	setTrackMutedArgsForCall []struct {
@@ -941,6 +969,16 @@ type FakeLocalParticipant struct {
 	setTrackMutedReturnsOnCall map[int]struct {
 		result1 *livekit.TrackInfo
 	}
+	SignalingRTTStub        func() uint32
+	signalingRTTMutex       sync.RWMutex
+	signalingRTTArgsForCall []struct {
+	}
+	signalingRTTReturns struct {
+		result1 uint32
+	}
+	signalingRTTReturnsOnCall map[int]struct {
+		result1 uint32
+	}
 	StateStub        func() livekit.ParticipantInfo_State
 	stateMutex       sync.RWMutex
 	stateArgsForCall []struct {
@@ -1047,6 +1085,12 @@ type FakeLocalParticipant struct {
 	unsubscribeFromTrackArgsForCall []struct {
 		arg1 livekit.TrackID
 	}
+	UpdateAdaptiveStreamHintStub        func(livekit.TrackID, *types.AdaptiveStreamHint)
+	updateAdaptiveStreamHintMutex       sync.RWMutex
+	updateAdaptiveStreamHintArgsForCall []struct {
+		arg1 livekit.TrackID
+		arg2 *types.AdaptiveStreamHint
+	}
 	UpdateAudioTrackStub        func(*livekit.UpdateLocalAudioTrack) error
 	updateAudioTrackMutex       sync.RWMutex
 	updateAudioTrackArgsForCall []struct {
@@ -5414,6 +5458,38 @@ func (fake *FakeLocalParticipant) SendJoinResponseReturnsOnCall(i int, result1 e
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) SendNotification(arg1 *types.Notification) {
+	fake.sendNotificationMutex.Lock()
+	fake.sendNotificationArgsForCall = append(fake.sendNotificationArgsForCall, struct {
+		arg1 *types.Notification
+	}{arg1})
+	stub := fake.SendNotificationStub
+	fake.recordInvocation("SendNotification", []interface{}{arg1})
+	fake.sendNotificationMutex.Unlock()
+	if stub != nil {
+		fake.SendNotificationStub(arg1)
+	}
+}
+
+func (fake *FakeLocalParticipant) SendNotificationCallCount() int {
+	fake.sendNotificationMutex.RLock()
+	defer fake.sendNotificationMutex.RUnlock()
+	return len(fake.sendNotificationArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) SendNotificationCalls(stub func(*types.Notification)) {
+	fake.sendNotificationMutex.Lock()
+	defer fake.sendNotificationMutex.Unlock()
+	fake.SendNotificationStub = stub
+}
+
+func (fake *FakeLocalParticipant) SendNotificationArgsForCall(i int) *types.Notification {
+	fake.sendNotificationMutex.RLock()
+	defer fake.sendNotificationMutex.RUnlock()
+	argsForCall := fake.sendNotificationArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeLocalParticipant) SendParticipantUpdate(arg1 []*livekit.ParticipantInfo) error {
 	var arg1Copy []*livekit.ParticipantInfo
 	if arg1 != nil {
@@ -5730,6 +5806,103 @@ func (fake *FakeLocalParticipant) SendSpeakerUpdateReturnsOnCall(i int, result1
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) SendSubscriptionError(arg1 livekit.TrackID, arg2 livekit.SubscriptionError, arg3 bool) {
+	fake.sendSubscriptionErrorMutex.Lock()
+	fake.sendSubscriptionErrorArgsForCall = append(fake.sendSubscriptionErrorArgsForCall, struct {
+		arg1 livekit.TrackID
+		arg2 livekit.SubscriptionError
+		arg3 bool
+	}{arg1, arg2, arg3})
+	stub := fake.SendSubscriptionErrorStub
+	fake.recordInvocation("SendSubscriptionError", []interface{}{arg1, arg2, arg3})
+	fake.sendSubscriptionErrorMutex.Unlock()
+	if stub != nil {
+		fake.SendSubscriptionErrorStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeLocalParticipant) SendSubscriptionErrorCallCount() int {
+	fake.sendSubscriptionErrorMutex.RLock()
+	defer fake.sendSubscriptionErrorMutex.RUnlock()
+	return len(fake.sendSubscriptionErrorArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) SendSubscriptionErrorCalls(stub func(livekit.TrackID, livekit.SubscriptionError, bool)) {
+	fake.sendSubscriptionErrorMutex.Lock()
+	defer fake.sendSubscriptionErrorMutex.Unlock()
+	fake.SendSubscriptionErrorStub = stub
+}
+
+func (fake *FakeLocalParticipant) SendSubscriptionErrorArgsForCall(i int) (livekit.TrackID, livekit.SubscriptionError, bool) {
+	fake.sendSubscriptionErrorMutex.RLock()
+	defer fake.sendSubscriptionErrorMutex.RUnlock()
+	argsForCall := fake.sendSubscriptionErrorArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeLocalParticipant) SendTimeSyncBeacon(arg1 int64) error {
+	fake.sendTimeSyncBeaconMutex.Lock()
+	ret, specificReturn := fake.sendTimeSyncBeaconReturnsOnCall[len(fake.sendTimeSyncBeaconArgsForCall)]
+	fake.sendTimeSyncBeaconArgsForCall = append(fake.sendTimeSyncBeaconArgsForCall, struct {
+		arg1 int64
+	}{arg1})
+	stub := fake.SendTimeSyncBeaconStub
+	fakeReturns := fake.sendTimeSyncBeaconReturns
+	fake.recordInvocation("SendTimeSyncBeacon", []interface{}{arg1})
+	fake.sendTimeSyncBeaconMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) SendTimeSyncBeaconCallCount() int {
+	fake.sendSubscriptionErrorMutex.RLock()
+	defer fake.sendSubscriptionErrorMutex.RUnlock()
+	fake.sendTimeSyncBeaconMutex.RLock()
+	defer fake.sendTimeSyncBeaconMutex.RUnlock()
+	return len(fake.sendTimeSyncBeaconArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) SendTimeSyncBeaconCalls(stub func(int64) error) {
+	fake.sendTimeSyncBeaconMutex.Lock()
+	defer fake.sendTimeSyncBeaconMutex.Unlock()
+	fake.SendTimeSyncBeaconStub = stub
+}
+
+func (fake *FakeLocalParticipant) SendTimeSyncBeaconArgsForCall(i int) int64 {
+	fake.sendTimeSyncBeaconMutex.RLock()
+	defer fake.sendTimeSyncBeaconMutex.RUnlock()
+	argsForCall := fake.sendTimeSyncBeaconArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeLocalParticipant) SendTimeSyncBeaconReturns(result1 error) {
+	fake.sendTimeSyncBeaconMutex.Lock()
+	defer fake.sendTimeSyncBeaconMutex.Unlock()
+	fake.SendTimeSyncBeaconStub = nil
+	fake.sendTimeSyncBeaconReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) SendTimeSyncBeaconReturnsOnCall(i int, result1 error) {
+	fake.sendTimeSyncBeaconMutex.Lock()
+	defer fake.sendTimeSyncBeaconMutex.Unlock()
+	fake.SendTimeSyncBeaconStub = nil
+	if fake.sendTimeSyncBeaconReturnsOnCall == nil {
+		fake.sendTimeSyncBeaconReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.sendTimeSyncBeaconReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) SetAttributes(arg1 map[string]string) {
 	fake.setAttributesMutex.Lock()
 	fake.setAttributesArgsForCall = append(fake.setAttributesArgsForCall, struct {
@@ -6124,6 +6297,38 @@ func (fake *FakeLocalParticipant) SetSubscriberChannelCapacityArgsForCall(i int)
 	return argsForCall.arg1
 }
 
+func (fake *FakeLocalParticipant) SetSubscriberMaxPublisherShare(arg1 float64) {
+	fake.setSubscriberMaxPublisherShareMutex.Lock()
+	fake.setSubscriberMaxPublisherShareArgsForCall = append(fake.setSubscriberMaxPublisherShareArgsForCall, struct {
+		arg1 float64
+	}{arg1})
+	stub := fake.SetSubscriberMaxPublisherShareStub
+	fake.recordInvocation("SetSubscriberMaxPublisherShare", []interface{}{arg1})
+	fake.setSubscriberMaxPublisherShareMutex.Unlock()
+	if stub != nil {
+		fake.SetSubscriberMaxPublisherShareStub(arg1)
+	}
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberMaxPublisherShareCallCount() int {
+	fake.setSubscriberMaxPublisherShareMutex.RLock()
+	defer fake.setSubscriberMaxPublisherShareMutex.RUnlock()
+	return len(fake.setSubscriberMaxPublisherShareArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberMaxPublisherShareCalls(stub func(float64)) {
+	fake.setSubscriberMaxPublisherShareMutex.Lock()
+	defer fake.setSubscriberMaxPublisherShareMutex.Unlock()
+	fake.SetSubscriberMaxPublisherShareStub = stub
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberMaxPublisherShareArgsForCall(i int) float64 {
+	fake.setSubscriberMaxPublisherShareMutex.RLock()
+	defer fake.setSubscriberMaxPublisherShareMutex.RUnlock()
+	argsForCall := fake.setSubscriberMaxPublisherShareArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeLocalParticipant) SetTrackMuted(arg1 livekit.TrackID, arg2 bool, arg3 bool) *livekit.TrackInfo {
 	fake.setTrackMutedMutex.Lock()
 	ret, specificReturn := fake.setTrackMutedReturnsOnCall[len(fake.setTrackMutedArgsForCall)]
@@ -6187,6 +6392,59 @@ func (fake *FakeLocalParticipant) SetTrackMutedReturnsOnCall(i int, result1 *liv
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) SignalingRTT() uint32 {
+	fake.signalingRTTMutex.Lock()
+	ret, specificReturn := fake.signalingRTTReturnsOnCall[len(fake.signalingRTTArgsForCall)]
+	fake.signalingRTTArgsForCall = append(fake.signalingRTTArgsForCall, struct {
+	}{})
+	stub := fake.SignalingRTTStub
+	fakeReturns := fake.signalingRTTReturns
+	fake.recordInvocation("SignalingRTT", []interface{}{})
+	fake.signalingRTTMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) SignalingRTTCallCount() int {
+	fake.signalingRTTMutex.RLock()
+	defer fake.signalingRTTMutex.RUnlock()
+	return len(fake.signalingRTTArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) SignalingRTTCalls(stub func() uint32) {
+	fake.signalingRTTMutex.Lock()
+	defer fake.signalingRTTMutex.Unlock()
+	fake.SignalingRTTStub = stub
+}
+
+func (fake *FakeLocalParticipant) SignalingRTTReturns(result1 uint32) {
+	fake.signalingRTTMutex.Lock()
+	defer fake.signalingRTTMutex.Unlock()
+	fake.SignalingRTTStub = nil
+	fake.signalingRTTReturns = struct {
+		result1 uint32
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) SignalingRTTReturnsOnCall(i int, result1 uint32) {
+	fake.signalingRTTMutex.Lock()
+	defer fake.signalingRTTMutex.Unlock()
+	fake.SignalingRTTStub = nil
+	if fake.signalingRTTReturnsOnCall == nil {
+		fake.signalingRTTReturnsOnCall = make(map[int]struct {
+			result1 uint32
+		})
+	}
+	fake.signalingRTTReturnsOnCall[i] = struct {
+		result1 uint32
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) State() livekit.ParticipantInfo_State {
 	fake.stateMutex.Lock()
 	ret, specificReturn := fake.stateReturnsOnCall[len(fake.stateArgsForCall)]
@@ -6747,6 +7005,39 @@ func (fake *FakeLocalParticipant) UnsubscribeFromTrackArgsForCall(i int) livekit
 	return argsForCall.arg1
 }
 
+func (fake *FakeLocalParticipant) UpdateAdaptiveStreamHint(arg1 livekit.TrackID, arg2 *types.AdaptiveStreamHint) {
+	fake.updateAdaptiveStreamHintMutex.Lock()
+	fake.updateAdaptiveStreamHintArgsForCall = append(fake.updateAdaptiveStreamHintArgsForCall, struct {
+		arg1 livekit.TrackID
+		arg2 *types.AdaptiveStreamHint
+	}{arg1, arg2})
+	stub := fake.UpdateAdaptiveStreamHintStub
+	fake.recordInvocation("UpdateAdaptiveStreamHint", []interface{}{arg1, arg2})
+	fake.updateAdaptiveStreamHintMutex.Unlock()
+	if stub != nil {
+		fake.UpdateAdaptiveStreamHintStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeLocalParticipant) UpdateAdaptiveStreamHintCallCount() int {
+	fake.updateAdaptiveStreamHintMutex.RLock()
+	defer fake.updateAdaptiveStreamHintMutex.RUnlock()
+	return len(fake.updateAdaptiveStreamHintArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) UpdateAdaptiveStreamHintCalls(stub func(livekit.TrackID, *types.AdaptiveStreamHint)) {
+	fake.updateAdaptiveStreamHintMutex.Lock()
+	defer fake.updateAdaptiveStreamHintMutex.Unlock()
+	fake.UpdateAdaptiveStreamHintStub = stub
+}
+
+func (fake *FakeLocalParticipant) UpdateAdaptiveStreamHintArgsForCall(i int) (livekit.TrackID, *types.AdaptiveStreamHint) {
+	fake.updateAdaptiveStreamHintMutex.RLock()
+	defer fake.updateAdaptiveStreamHintMutex.RUnlock()
+	argsForCall := fake.updateAdaptiveStreamHintArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
 func (fake *FakeLocalParticipant) UpdateAudioTrack(arg1 *livekit.UpdateLocalAudioTrack) error {
 	fake.updateAudioTrackMutex.Lock()
 	ret, specificReturn := fake.updateAudioTrackReturnsOnCall[len(fake.updateAudioTrackArgsForCall)]
@@ -7625,6 +7916,8 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.sendDataPacketMutex.RUnlock()
 	fake.sendJoinResponseMutex.RLock()
 	defer fake.sendJoinResponseMutex.RUnlock()
+	fake.sendNotificationMutex.RLock()
+	defer fake.sendNotificationMutex.RUnlock()
 	fake.sendParticipantUpdateMutex.RLock()
 	defer fake.sendParticipantUpdateMutex.RUnlock()
 	fake.sendRefreshTokenMutex.RLock()
@@ -7635,6 +7928,8 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.sendRoomUpdateMutex.RUnlock()
 	fake.sendSpeakerUpdateMutex.RLock()
 	defer fake.sendSpeakerUpdateMutex.RUnlock()
+	fake.sendTimeSyncBeaconMutex.RLock()
+	defer fake.sendTimeSyncBeaconMutex.RUnlock()
 	fake.setAttributesMutex.RLock()
 	defer fake.setAttributesMutex.RUnlock()
 	fake.setICEConfigMutex.RLock()
@@ -7657,8 +7952,12 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.setSubscriberAllowPauseMutex.RUnlock()
 	fake.setSubscriberChannelCapacityMutex.RLock()
 	defer fake.setSubscriberChannelCapacityMutex.RUnlock()
+	fake.setSubscriberMaxPublisherShareMutex.RLock()
+	defer fake.setSubscriberMaxPublisherShareMutex.RUnlock()
 	fake.setTrackMutedMutex.RLock()
 	defer fake.setTrackMutedMutex.RUnlock()
+	fake.signalingRTTMutex.RLock()
+	defer fake.signalingRTTMutex.RUnlock()
 	fake.stateMutex.RLock()
 	defer fake.stateMutex.RUnlock()
 	fake.stopAndGetSubscribedTracksForwarderStateMutex.RLock()
@@ -7683,6 +7982,8 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.uncacheDownTrackMutex.RUnlock()
 	fake.unsubscribeFromTrackMutex.RLock()
 	defer fake.unsubscribeFromTrackMutex.RUnlock()
+	fake.updateAdaptiveStreamHintMutex.RLock()
+	defer fake.updateAdaptiveStreamHintMutex.RUnlock()
 	fake.updateAudioTrackMutex.RLock()
 	defer fake.updateAudioTrackMutex.RUnlock()
 	fake.updateLastSeenSignalMutex.RLock()
@@ -0,0 +1,161 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+)
+
+// VirtualParticipant is a server owned participant without transports. It is visible to others in the room,
+// exchanges data messages and carries metadata and attributes. It cannot subscribe to tracks, the only tracks
+// it publishes are those of RTP ingests.
+type VirtualParticipant struct {
+	lock sync.RWMutex
+	info *livekit.ParticipantInfo
+
+	onDataPacket func(vp *VirtualParticipant, dp *livekit.DataPacket)
+	onClose      func(vp *VirtualParticipant)
+}
+
+func NewVirtualParticipant(sid livekit.ParticipantID, identity livekit.ParticipantIdentity, name string, metadata string, attributes map[string]string) *VirtualParticipant {
+	return &VirtualParticipant{
+		info: &livekit.ParticipantInfo{
+			Sid:        string(sid),
+			Identity:   string(identity),
+			Name:       name,
+			Metadata:   metadata,
+			Attributes: attributes,
+			State:      livekit.ParticipantInfo_ACTIVE,
+			JoinedAt:   time.Now().Unix(),
+			Version:    1,
+			Permission: &livekit.ParticipantPermission{
+				CanPublishData: true,
+			},
+		},
+	}
+}
+
+func (vp *VirtualParticipant) ID() livekit.ParticipantID {
+	vp.lock.RLock()
+	defer vp.lock.RUnlock()
+	return livekit.ParticipantID(vp.info.Sid)
+}
+
+func (vp *VirtualParticipant) Identity() livekit.ParticipantIdentity {
+	vp.lock.RLock()
+	defer vp.lock.RUnlock()
+	return livekit.ParticipantIdentity(vp.info.Identity)
+}
+
+func (vp *VirtualParticipant) ToProto() *livekit.ParticipantInfo {
+	vp.lock.RLock()
+	defer vp.lock.RUnlock()
+	return utils.CloneProto(vp.info)
+}
+
+// Update applies non-empty fields, attributes with empty values are removed
+func (vp *VirtualParticipant) Update(name string, metadata string, attributes map[string]string) *livekit.ParticipantInfo {
+	vp.lock.Lock()
+	defer vp.lock.Unlock()
+
+	if name != "" {
+		vp.info.Name = name
+	}
+	if metadata != "" {
+		vp.info.Metadata = metadata
+	}
+	if len(attributes) != 0 {
+		if vp.info.Attributes == nil {
+			vp.info.Attributes = make(map[string]string, len(attributes))
+		}
+		for k, v := range attributes {
+			if v == "" {
+				delete(vp.info.Attributes, k)
+			} else {
+				vp.info.Attributes[k] = v
+			}
+		}
+	}
+	vp.info.Version++
+	return utils.CloneProto(vp.info)
+}
+
+// AddTrack lists a track published on behalf of the participant
+func (vp *VirtualParticipant) AddTrack(ti *livekit.TrackInfo) *livekit.ParticipantInfo {
+	vp.lock.Lock()
+	defer vp.lock.Unlock()
+
+	vp.info.Tracks = append(vp.info.Tracks, ti)
+	vp.info.Version++
+	return utils.CloneProto(vp.info)
+}
+
+// RemoveTrack unlists a track, returns false when the participant has no such track
+func (vp *VirtualParticipant) RemoveTrack(trackID livekit.TrackID) (*livekit.ParticipantInfo, bool) {
+	vp.lock.Lock()
+	defer vp.lock.Unlock()
+
+	for idx, ti := range vp.info.Tracks {
+		if livekit.TrackID(ti.Sid) == trackID {
+			vp.info.Tracks = append(vp.info.Tracks[:idx], vp.info.Tracks[idx+1:]...)
+			vp.info.Version++
+			return utils.CloneProto(vp.info), true
+		}
+	}
+	return nil, false
+}
+
+// OnDataPacket is called with data packets delivered to the participant
+func (vp *VirtualParticipant) OnDataPacket(f func(vp *VirtualParticipant, dp *livekit.DataPacket)) {
+	vp.lock.Lock()
+	vp.onDataPacket = f
+	vp.lock.Unlock()
+}
+
+// OnClose is called once the participant is removed, either explicitly or when the room closes
+func (vp *VirtualParticipant) OnClose(f func(vp *VirtualParticipant)) {
+	vp.lock.Lock()
+	vp.onClose = f
+	vp.lock.Unlock()
+}
+
+func (vp *VirtualParticipant) close() *livekit.ParticipantInfo {
+	vp.lock.Lock()
+	vp.info.State = livekit.ParticipantInfo_DISCONNECTED
+	vp.info.Version++
+	pi := utils.CloneProto(vp.info)
+	onClose := vp.onClose
+	vp.onClose = nil
+	vp.lock.Unlock()
+
+	if onClose != nil {
+		onClose(vp)
+	}
+	return pi
+}
+
+func (vp *VirtualParticipant) handleDataPacket(dp *livekit.DataPacket) {
+	vp.lock.RLock()
+	onDataPacket := vp.onDataPacket
+	vp.lock.RUnlock()
+
+	if onDataPacket != nil {
+		onDataPacket(vp, dp)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// PendingAttribute is set to "true" in the token attributes of participants that have to wait in the lobby
+// until they are admitted. It is removed from the participant on admission.
+const PendingAttribute = "lk.pending"
+
+// pendingPermission is applied to participants while they wait, they are hidden and can neither subscribe nor publish
+var pendingPermission = &livekit.ParticipantPermission{
+	Hidden: true,
+}
+
+func IsPendingGrant(grants *auth.ClaimGrants) bool {
+	return grants != nil && grants.Attributes[PendingAttribute] == "true"
+}
+
+// holdPendingParticipant restricts a participant joining with a pending grant, remembering the permission it
+// was granted so that it can be restored on admission. Assumes room lock is held.
+func (r *Room) holdPendingParticipant(p types.LocalParticipant) {
+	grants := p.ClaimGrants()
+	if !IsPendingGrant(grants) {
+		return
+	}
+	if _, ok := r.pendingParticipants[p.Identity()]; !ok {
+		r.pendingParticipants[p.Identity()] = grants.Video.ToPermission()
+	}
+	p.SetPermission(pendingPermission)
+	r.Logger.Infow("participant waiting for admission", "participant", p.Identity())
+}
+
+// GetPendingParticipants returns the participants waiting for admission
+func (r *Room) GetPendingParticipants() []types.LocalParticipant {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	participants := make([]types.LocalParticipant, 0, len(r.pendingParticipants))
+	for identity := range r.pendingParticipants {
+		if p := r.participants[identity]; p != nil {
+			participants = append(participants, p)
+		}
+	}
+	return participants
+}
+
+// PendingParticipantPermission returns the permission a waiting participant will get once admitted,
+// nil if the participant is not waiting
+func (r *Room) PendingParticipantPermission(identity livekit.ParticipantIdentity) *livekit.ParticipantPermission {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.pendingParticipants[identity]
+}
+
+// AdmitParticipants lets waiting participants into the room, all of them when no identity is given.
+// Admitted participants get their permission back, which makes them visible and subscribes them to
+// existing tracks, and are signaled with an update of their own info without PendingAttribute.
+func (r *Room) AdmitParticipants(identities []livekit.ParticipantIdentity) ([]types.LocalParticipant, error) {
+	r.lock.Lock()
+	if len(identities) == 0 {
+		for identity := range r.pendingParticipants {
+			identities = append(identities, identity)
+		}
+	}
+	admitted := make([]types.LocalParticipant, 0, len(identities))
+	permissions := make([]*livekit.ParticipantPermission, 0, len(identities))
+	for _, identity := range identities {
+		permission, ok := r.pendingParticipants[identity]
+		p := r.participants[identity]
+		if !ok || p == nil {
+			r.lock.Unlock()
+			return nil, ErrParticipantNotPending
+		}
+		admitted = append(admitted, p)
+		permissions = append(permissions, permission)
+	}
+	for _, p := range admitted {
+		delete(r.pendingParticipants, p.Identity())
+	}
+	r.lock.Unlock()
+
+	for i, p := range admitted {
+		p.SetAttributes(map[string]string{PendingAttribute: ""})
+		p.SetPermission(permissions[i])
+		p.GetLogger().Infow("participant admitted")
+	}
+	return admitted, nil
+}
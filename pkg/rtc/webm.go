@@ -0,0 +1,257 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// EBML element IDs of the subset of WebM written by track recordings
+const (
+	ebmlIDHeader             = 0x1A45DFA3
+	ebmlIDVersion            = 0x4286
+	ebmlIDReadVersion        = 0x42F7
+	ebmlIDMaxIDLength        = 0x42F2
+	ebmlIDMaxSizeLength      = 0x42F3
+	ebmlIDDocType            = 0x4282
+	ebmlIDDocTypeVersion     = 0x4287
+	ebmlIDDocTypeReadVersion = 0x4285
+
+	webmIDSegment           = 0x18538067
+	webmIDInfo              = 0x1549A966
+	webmIDTimecodeScale     = 0x2AD7B1
+	webmIDMuxingApp         = 0x4D80
+	webmIDWritingApp        = 0x5741
+	webmIDDuration          = 0x4489
+	webmIDTracks            = 0x1654AE6B
+	webmIDTrackEntry        = 0xAE
+	webmIDTrackNumber       = 0xD7
+	webmIDTrackUID          = 0x73C5
+	webmIDTrackType         = 0x83
+	webmIDCodecID           = 0x86
+	webmIDCodecPrivate      = 0x63A2
+	webmIDVideo             = 0xE0
+	webmIDPixelWidth        = 0xB0
+	webmIDPixelHeight       = 0xBA
+	webmIDAudio             = 0xE1
+	webmIDSamplingFrequency = 0xB5
+	webmIDChannels          = 0x9F
+	webmIDCluster           = 0x1F43B675
+	webmIDTimecode          = 0xE7
+	webmIDSimpleBlock       = 0xA3
+
+	webmTrackTypeVideo = 1
+	webmTrackTypeAudio = 2
+
+	webmCodecOpus = "A_OPUS"
+	webmCodecVP8  = "V_VP8"
+
+	// block timecodes are signed 16 bit offsets from the timecode of their cluster, in milliseconds
+	webmMaxClusterDuration = 30000
+
+	webmMuxingApp = "livekit-server"
+)
+
+// webmTrack describes the single track of a WebM file
+type webmTrack struct {
+	CodecID      string
+	CodecPrivate []byte
+	Video        bool
+	Width        uint32
+	Height       uint32
+	SampleRate   uint32
+	Channels     uint8
+}
+
+// opusHead returns the identification header of Opus streams, the codec private data of Opus tracks
+func opusHead(channels uint8, sampleRate uint32) []byte {
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1
+	head[9] = channels
+	binary.LittleEndian.PutUint32(head[12:16], sampleRate)
+	return head
+}
+
+// webmWriter buffers the frames of a track as a WebM file, frame timestamps are in milliseconds. Clusters
+// start at video key frames, and at least every 30 seconds.
+type webmWriter struct {
+	track webmTrack
+
+	clusters    bytes.Buffer
+	cluster     bytes.Buffer
+	clusterTime int64
+	firstTime   int64
+	lastTime    int64
+	frames      int
+}
+
+func newWebMWriter(track webmTrack) *webmWriter {
+	return &webmWriter{track: track}
+}
+
+// WriteFrame adds a frame to the file, frames older than the last one are written with its timestamp
+func (w *webmWriter) WriteFrame(ts int64, keyFrame bool, frame []byte) {
+	if w.frames == 0 {
+		w.firstTime = ts
+	} else if ts < w.lastTime {
+		ts = w.lastTime
+	}
+	if w.cluster.Len() == 0 || (w.track.Video && keyFrame) || ts-w.clusterTime > webmMaxClusterDuration {
+		w.flushCluster()
+		w.clusterTime = ts
+		writeUintElement(&w.cluster, webmIDTimecode, uint64(ts))
+	}
+
+	// track number 1, timecode relative to the cluster and flags
+	block := make([]byte, 4, 4+len(frame))
+	block[0] = 0x81
+	binary.BigEndian.PutUint16(block[1:3], uint16(int16(ts-w.clusterTime)))
+	if keyFrame || !w.track.Video {
+		block[3] = 0x80
+	}
+	writeElement(&w.cluster, webmIDSimpleBlock, append(block, frame...))
+
+	w.lastTime = ts
+	w.frames++
+}
+
+// Frames returns the number of frames written
+func (w *webmWriter) Frames() int {
+	return w.frames
+}
+
+// Duration returns the time between the first and the last frame, in milliseconds
+func (w *webmWriter) Duration() int64 {
+	return w.lastTime - w.firstTime
+}
+
+// Bytes returns the WebM file of the frames written
+func (w *webmWriter) Bytes() []byte {
+	w.flushCluster()
+
+	var header bytes.Buffer
+	writeUintElement(&header, ebmlIDVersion, 1)
+	writeUintElement(&header, ebmlIDReadVersion, 1)
+	writeUintElement(&header, ebmlIDMaxIDLength, 4)
+	writeUintElement(&header, ebmlIDMaxSizeLength, 8)
+	writeStringElement(&header, ebmlIDDocType, "webm")
+	writeUintElement(&header, ebmlIDDocTypeVersion, 4)
+	writeUintElement(&header, ebmlIDDocTypeReadVersion, 2)
+
+	var info bytes.Buffer
+	writeUintElement(&info, webmIDTimecodeScale, 1000000)
+	writeStringElement(&info, webmIDMuxingApp, webmMuxingApp)
+	writeStringElement(&info, webmIDWritingApp, webmMuxingApp)
+	writeFloatElement(&info, webmIDDuration, float64(w.Duration()))
+
+	var entry bytes.Buffer
+	writeUintElement(&entry, webmIDTrackNumber, 1)
+	writeUintElement(&entry, webmIDTrackUID, 1)
+	writeStringElement(&entry, webmIDCodecID, w.track.CodecID)
+	if len(w.track.CodecPrivate) != 0 {
+		writeElement(&entry, webmIDCodecPrivate, w.track.CodecPrivate)
+	}
+	if w.track.Video {
+		writeUintElement(&entry, webmIDTrackType, webmTrackTypeVideo)
+		var video bytes.Buffer
+		writeUintElement(&video, webmIDPixelWidth, uint64(w.track.Width))
+		writeUintElement(&video, webmIDPixelHeight, uint64(w.track.Height))
+		writeElement(&entry, webmIDVideo, video.Bytes())
+	} else {
+		writeUintElement(&entry, webmIDTrackType, webmTrackTypeAudio)
+		var audio bytes.Buffer
+		writeFloatElement(&audio, webmIDSamplingFrequency, float64(w.track.SampleRate))
+		writeUintElement(&audio, webmIDChannels, uint64(w.track.Channels))
+		writeElement(&entry, webmIDAudio, audio.Bytes())
+	}
+	var tracks bytes.Buffer
+	writeElement(&tracks, webmIDTrackEntry, entry.Bytes())
+
+	var segment bytes.Buffer
+	writeElement(&segment, webmIDInfo, info.Bytes())
+	writeElement(&segment, webmIDTracks, tracks.Bytes())
+	segment.Write(w.clusters.Bytes())
+
+	var file bytes.Buffer
+	writeElement(&file, ebmlIDHeader, header.Bytes())
+	writeElement(&file, webmIDSegment, segment.Bytes())
+	return file.Bytes()
+}
+
+func (w *webmWriter) flushCluster() {
+	if w.cluster.Len() == 0 {
+		return
+	}
+	writeElement(&w.clusters, webmIDCluster, w.cluster.Bytes())
+	w.cluster.Reset()
+}
+
+func writeElement(b *bytes.Buffer, id uint32, data []byte) {
+	writeEBMLID(b, id)
+	writeEBMLSize(b, uint64(len(data)))
+	b.Write(data)
+}
+
+func writeUintElement(b *bytes.Buffer, id uint32, v uint64) {
+	n := 1
+	for n < 8 && v >= 1<<(8*n) {
+		n++
+	}
+	data := make([]byte, n)
+	for i := 0; i < n; i++ {
+		data[n-1-i] = byte(v >> (8 * i))
+	}
+	writeElement(b, id, data)
+}
+
+func writeFloatElement(b *bytes.Buffer, id uint32, v float64) {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, math.Float64bits(v))
+	writeElement(b, id, data)
+}
+
+func writeStringElement(b *bytes.Buffer, id uint32, v string) {
+	writeElement(b, id, []byte(v))
+}
+
+// writeEBMLID writes an element ID, IDs include their length marker
+func writeEBMLID(b *bytes.Buffer, id uint32) {
+	switch {
+	case id >= 1<<24:
+		b.Write([]byte{byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)})
+	case id >= 1<<16:
+		b.Write([]byte{byte(id >> 16), byte(id >> 8), byte(id)})
+	case id >= 1<<8:
+		b.Write([]byte{byte(id >> 8), byte(id)})
+	default:
+		b.WriteByte(byte(id))
+	}
+}
+
+// writeEBMLSize writes a size as a variable length integer of the fewest bytes, sizes of all ones are
+// reserved for unknown sizes
+func writeEBMLSize(b *bytes.Buffer, size uint64) {
+	n := 1
+	for n < 8 && size >= 1<<(7*n)-1 {
+		n++
+	}
+	v := size | 1<<(7*n)
+	for i := n - 1; i >= 0; i-- {
+		b.WriteByte(byte(v >> (8 * i)))
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type ebmlElement struct {
+	id   uint32
+	data []byte
+}
+
+// readEBML splits data into its top level elements
+func readEBML(t *testing.T, data []byte) []ebmlElement {
+	var elements []ebmlElement
+	for len(data) != 0 {
+		idLen := 1
+		for data[0]&(0x80>>(idLen-1)) == 0 {
+			idLen++
+		}
+		var id uint32
+		for _, b := range data[:idLen] {
+			id = id<<8 | uint32(b)
+		}
+		data = data[idLen:]
+
+		sizeLen := 1
+		for data[0]&(0x80>>(sizeLen-1)) == 0 {
+			sizeLen++
+		}
+		size := uint64(data[0] & (0xff >> sizeLen))
+		for _, b := range data[1:sizeLen] {
+			size = size<<8 | uint64(b)
+		}
+		data = data[sizeLen:]
+		require.LessOrEqual(t, size, uint64(len(data)))
+
+		elements = append(elements, ebmlElement{id: id, data: data[:size]})
+		data = data[size:]
+	}
+	return elements
+}
+
+func findEBML(elements []ebmlElement, id uint32) []ebmlElement {
+	var found []ebmlElement
+	for _, e := range elements {
+		if e.id == id {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+func TestWebMWriter(t *testing.T) {
+	t.Run("audio", func(t *testing.T) {
+		w := newWebMWriter(webmTrack{CodecID: webmCodecOpus, CodecPrivate: opusHead(2, 48000), SampleRate: 48000, Channels: 2})
+		for i := 0; i < 100; i++ {
+			w.WriteFrame(1000+int64(i)*20, false, []byte{byte(i)})
+		}
+		require.Equal(t, 100, w.Frames())
+		require.EqualValues(t, 1980, w.Duration())
+
+		file := readEBML(t, w.Bytes())
+		require.Len(t, file, 2)
+		header := readEBML(t, file[0].data)
+		require.Equal(t, "webm", string(findEBML(header, ebmlIDDocType)[0].data))
+
+		segment := readEBML(t, file[1].data)
+		tracks := readEBML(t, findEBML(segment, webmIDTracks)[0].data)
+		entry := readEBML(t, tracks[0].data)
+		require.Equal(t, webmCodecOpus, string(findEBML(entry, webmIDCodecID)[0].data))
+		require.True(t, bytes.HasPrefix(findEBML(entry, webmIDCodecPrivate)[0].data, []byte("OpusHead")))
+		require.Equal(t, []byte{webmTrackTypeAudio}, findEBML(entry, webmIDTrackType)[0].data)
+
+		// audio frames all fit in a cluster
+		clusters := findEBML(segment, webmIDCluster)
+		require.Len(t, clusters, 1)
+		cluster := readEBML(t, clusters[0].data)
+		require.Equal(t, []byte{0x03, 0xe8}, findEBML(cluster, webmIDTimecode)[0].data)
+		blocks := findEBML(cluster, webmIDSimpleBlock)
+		require.Len(t, blocks, 100)
+		require.EqualValues(t, 20, binary.BigEndian.Uint16(blocks[1].data[1:3]))
+		require.Equal(t, byte(0x80), blocks[1].data[3])
+		require.Equal(t, []byte{1}, blocks[1].data[4:])
+	})
+
+	t.Run("video", func(t *testing.T) {
+		w := newWebMWriter(webmTrack{CodecID: webmCodecVP8, Video: true, Width: 1280, Height: 720})
+		for i := 0; i < 90; i++ {
+			w.WriteFrame(int64(i)*33, i%30 == 0, make([]byte, 1000))
+		}
+		// frames out of order are kept in order
+		w.WriteFrame(0, false, []byte{1})
+
+		segment := readEBML(t, readEBML(t, w.Bytes())[1].data)
+		clusters := findEBML(segment, webmIDCluster)
+		require.Len(t, clusters, 3)
+		last := findEBML(readEBML(t, clusters[2].data), webmIDSimpleBlock)
+		require.Len(t, last, 31)
+		require.Equal(t, byte(0x80), last[0].data[3])
+		require.Zero(t, last[1].data[3])
+		require.EqualValues(t, 29*33, binary.BigEndian.Uint16(last[30].data[1:3]))
+
+		tracks := readEBML(t, findEBML(segment, webmIDTracks)[0].data)
+		video := readEBML(t, findEBML(readEBML(t, tracks[0].data), webmIDVideo)[0].data)
+		require.Equal(t, []byte{0x05, 0x00}, findEBML(video, webmIDPixelWidth)[0].data)
+	})
+
+	t.Run("long clusters are split", func(t *testing.T) {
+		w := newWebMWriter(webmTrack{CodecID: webmCodecOpus, SampleRate: 48000, Channels: 1})
+		for i := 0; i < 4000; i++ {
+			w.WriteFrame(int64(i)*20, false, []byte{0})
+		}
+		segment := readEBML(t, readEBML(t, w.Bytes())[1].data)
+		require.Len(t, findEBML(segment, webmIDCluster), 3)
+	})
+}
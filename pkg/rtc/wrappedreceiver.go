@@ -38,6 +38,7 @@ type WrappedReceiverParams struct {
 	UpstreamCodecs []webrtc.RTPCodecParameters
 	Logger         logger.Logger
 	DisableRed     bool
+	G711Transcoder *sfu.G711Transcoder
 }
 
 type WrappedReceiver struct {
@@ -71,6 +72,10 @@ func NewWrappedReceiver(params WrappedReceiverParams) *WrappedReceiver {
 			})
 			// prefer red codec
 			codecs[0], codecs[1] = codecs[1], codecs[0]
+		} else if params.G711Transcoder.CanTranscode(codecs[0].MimeType) {
+			// the other law of G.711 upstream, to match SIP legs which only support that one
+			counterpart, _ := sfu.G711Counterpart(codecs[0].MimeType)
+			codecs = append(codecs, counterpart)
 		}
 	}
 
@@ -103,6 +108,14 @@ func (r *WrappedReceiver) DetermineReceiver(codec webrtc.RTPCodecCapability) boo
 		} else if strings.EqualFold(c.MimeType, webrtc.MimeTypeOpus) && strings.EqualFold(codec.MimeType, sfu.MimeTypeAudioRed) {
 			r.TrackReceiver = receiver.GetRedReceiver()
 			break
+		} else if counterpart, ok := sfu.G711Counterpart(c.MimeType); ok && strings.EqualFold(counterpart.MimeType, codec.MimeType) {
+			// G.711 of the other law, unless the node converts as many tracks as it may
+			if wr, ok := receiver.(interface{ GetG711Receiver() sfu.TrackReceiver }); ok {
+				if gr := wr.GetG711Receiver(); gr != nil {
+					r.TrackReceiver = gr
+					break
+				}
+			}
 		}
 	}
 	if r.TrackReceiver == nil {
@@ -267,6 +280,13 @@ func (d *DummyReceiver) SendPLI(layer int32, force bool) {
 	}
 }
 
+func (d *DummyReceiver) ReplayKeyFrame(layer int32, track sfu.TrackSender) bool {
+	if r, ok := d.receiver.Load().(sfu.TrackReceiver); ok {
+		return r.ReplayKeyFrame(layer, track)
+	}
+	return false
+}
+
 func (d *DummyReceiver) SetUpTrackPaused(paused bool) {
 	d.settingsLock.Lock()
 	defer d.settingsLock.Unlock()
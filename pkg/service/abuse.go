@@ -0,0 +1,363 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/urfave/negroni/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	AbusePenaltyBan      = "ban"
+	AbusePenaltyThrottle = "throttle"
+
+	AbuseReasonJoinAttempts = "join_attempts"
+	AbuseReasonAuthFailures = "auth_failures"
+	AbuseReasonDataFlood    = "data_flood"
+
+	abuseSweepInterval = time.Minute
+
+	maxAbuseRequest = 4 * 1024
+)
+
+// AbusePenalty is a ban of a client IP, keyed "ip:<address>", or a throttle of a participant, keyed
+// "participant:<room>/<identity>"
+type AbusePenalty struct {
+	Key       string    `json:"key"`
+	Kind      string    `json:"kind"`
+	Reason    string    `json:"reason"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AbuseDetector watches clients for abusive patterns and penalizes them for a while. It is consulted for
+// every HTTP request and every data packet, implementations have to be cheap.
+type AbuseDetector interface {
+	rtc.DataPacketLimiter
+
+	// CheckIP returns ErrAbuseBanned while the IP is banned
+	CheckIP(ip string) error
+	// RecordJoinAttempt returns ErrAbuseBanned when the attempt gets the IP banned
+	RecordJoinAttempt(ip string) error
+	RecordAuthFailure(ip string)
+
+	ListPenalties() []*AbusePenalty
+	// ClearPenalties lifts the penalty with the key, or all of them when key is empty, and returns their number
+	ClearPenalties(key string) int
+}
+
+type abuseCounter struct {
+	windowStart time.Time
+	count       int
+}
+
+// add counts an event in a fixed window and returns the number of events of the window
+func (c *abuseCounter) add(now time.Time, window time.Duration) int {
+	if now.Sub(c.windowStart) >= window {
+		c.windowStart = now
+		c.count = 0
+	}
+	c.count++
+	return c.count
+}
+
+// LocalAbuseDetector keeps counters and penalties in memory, each node penalizing the clients it serves
+type LocalAbuseDetector struct {
+	conf config.AbuseDetectionConfig
+
+	lock      sync.Mutex
+	counters  map[string]*abuseCounter
+	penalties map[string]*AbusePenalty
+	lastSweep time.Time
+}
+
+// NewAbuseDetector returns nil when abuse detection is not enabled
+func NewAbuseDetector(conf *config.Config) AbuseDetector {
+	if !conf.AbuseDetection.Enabled {
+		return nil
+	}
+	return NewLocalAbuseDetector(conf.AbuseDetection)
+}
+
+func NewLocalAbuseDetector(conf config.AbuseDetectionConfig) *LocalAbuseDetector {
+	return &LocalAbuseDetector{
+		conf:      conf,
+		counters:  make(map[string]*abuseCounter),
+		penalties: make(map[string]*AbusePenalty),
+		lastSweep: time.Now(),
+	}
+}
+
+func abuseIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+func abuseParticipantKey(roomName livekit.RoomName, identity livekit.ParticipantIdentity) string {
+	return "participant:" + string(roomName) + "/" + string(identity)
+}
+
+func (d *LocalAbuseDetector) CheckIP(ip string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.penalizedLocked(abuseIPKey(ip), time.Now()) {
+		return ErrAbuseBanned
+	}
+	return nil
+}
+
+func (d *LocalAbuseDetector) RecordJoinAttempt(ip string) error {
+	if d.record(abuseIPKey(ip), AbuseReasonJoinAttempts, d.conf.JoinAttemptsPerMinute, time.Minute, AbusePenaltyBan, d.conf.BanDuration) {
+		return ErrAbuseBanned
+	}
+	return nil
+}
+
+func (d *LocalAbuseDetector) RecordAuthFailure(ip string) {
+	d.record(abuseIPKey(ip), AbuseReasonAuthFailures, d.conf.AuthFailuresPerMinute, time.Minute, AbusePenaltyBan, d.conf.BanDuration)
+}
+
+func (d *LocalAbuseDetector) AllowDataPacket(roomName livekit.RoomName, p types.LocalParticipant) bool {
+	return !d.record(abuseParticipantKey(roomName, p.Identity()), AbuseReasonDataFlood, d.conf.DataPacketsPerSecond, time.Second, AbusePenaltyThrottle, d.conf.ThrottleDuration)
+}
+
+// record counts an event of the key and penalizes the key once over the limit, returns whether the key is
+// penalized. A limit of 0 disables the heuristic.
+func (d *LocalAbuseDetector) record(key string, reason string, limit int, window time.Duration, kind string, duration time.Duration) bool {
+	if limit <= 0 {
+		return false
+	}
+	now := time.Now()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.maybeSweepLocked(now)
+	if d.penalizedLocked(key, now) {
+		return true
+	}
+
+	counterKey := key + "|" + reason
+	c := d.counters[counterKey]
+	if c == nil {
+		c = &abuseCounter{windowStart: now}
+		d.counters[counterKey] = c
+	}
+	if c.add(now, window) <= limit {
+		return false
+	}
+
+	delete(d.counters, counterKey)
+	d.penalties[key] = &AbusePenalty{
+		Key:       key,
+		Kind:      kind,
+		Reason:    reason,
+		ExpiresAt: now.Add(duration),
+	}
+	logger.Infow("abuse detected, penalizing client", "key", key, "kind", kind, "reason", reason, "duration", duration)
+	return true
+}
+
+func (d *LocalAbuseDetector) penalizedLocked(key string, now time.Time) bool {
+	p := d.penalties[key]
+	if p == nil {
+		return false
+	}
+	if now.After(p.ExpiresAt) {
+		delete(d.penalties, key)
+		return false
+	}
+	return true
+}
+
+// maybeSweepLocked drops expired penalties and stale counters, so that one off clients are not kept forever
+func (d *LocalAbuseDetector) maybeSweepLocked(now time.Time) {
+	if now.Sub(d.lastSweep) < abuseSweepInterval {
+		return
+	}
+	d.lastSweep = now
+	for key, c := range d.counters {
+		if now.Sub(c.windowStart) >= abuseSweepInterval {
+			delete(d.counters, key)
+		}
+	}
+	for key, p := range d.penalties {
+		if now.After(p.ExpiresAt) {
+			delete(d.penalties, key)
+		}
+	}
+}
+
+func (d *LocalAbuseDetector) ListPenalties() []*AbusePenalty {
+	now := time.Now()
+
+	d.lock.Lock()
+	penalties := make([]*AbusePenalty, 0, len(d.penalties))
+	for _, p := range d.penalties {
+		if !now.After(p.ExpiresAt) {
+			penalties = append(penalties, &AbusePenalty{Key: p.Key, Kind: p.Kind, Reason: p.Reason, ExpiresAt: p.ExpiresAt})
+		}
+	}
+	d.lock.Unlock()
+
+	slices.SortFunc(penalties, func(a, b *AbusePenalty) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return penalties
+}
+
+func (d *LocalAbuseDetector) ClearPenalties(key string) int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if key == "" {
+		n := len(d.penalties)
+		clear(d.penalties)
+		return n
+	}
+	if _, ok := d.penalties[key]; !ok {
+		return 0
+	}
+	delete(d.penalties, key)
+	return 1
+}
+
+// ---------------------------------------------
+
+// AbuseMiddleware rejects requests of banned IPs, and reports join attempts and authentication failures to
+// the detector. It has to run before authentication.
+type AbuseMiddleware struct {
+	detector  AbuseDetector
+	addresses *ClientAddresses
+}
+
+func NewAbuseMiddleware(conf *config.Config, detector AbuseDetector) *AbuseMiddleware {
+	return &AbuseMiddleware{
+		detector:  detector,
+		addresses: NewClientAddresses(conf),
+	}
+}
+
+func (m *AbuseMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	ip := m.addresses.ClientIPString(r)
+	if err := m.detector.CheckIP(ip); err != nil {
+		handleError(w, r, http.StatusTooManyRequests, err)
+		return
+	}
+	if r.URL != nil && r.URL.Path == "/rtc" {
+		if err := m.detector.RecordJoinAttempt(ip); err != nil {
+			handleError(w, r, http.StatusTooManyRequests, err)
+			return
+		}
+	}
+
+	next(w, r)
+
+	if rw, ok := w.(negroni.ResponseWriter); ok && rw.Status() == http.StatusUnauthorized {
+		m.detector.RecordAuthFailure(ip)
+	}
+}
+
+// ---------------------------------------------
+
+// ClearAbusePenaltiesRequest is the JSON body of POST /abuse/clear, all penalties are cleared without a key
+type ClearAbusePenaltiesRequest struct {
+	Key string `json:"key,omitempty"`
+}
+
+type ListAbusePenaltiesResponse struct {
+	Penalties []*AbusePenalty `json:"penalties"`
+}
+
+type ClearAbusePenaltiesResponse struct {
+	Cleared int `json:"cleared"`
+}
+
+// AbuseService serves the HTTP API inspecting and clearing the penalties of the node answering the request.
+// Listing requires roomList, clearing requires roomCreate.
+type AbuseService struct {
+	detector AbuseDetector
+}
+
+func NewAbuseService(detector AbuseDetector) *AbuseService {
+	return &AbuseService{
+		detector: detector,
+	}
+}
+
+func (s *AbuseService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/abuse/") {
+	case "list":
+		res, err = s.ListPenalties(r.Context())
+	case "clear":
+		var req ClearAbusePenaltiesRequest
+		if err = decodeJSONRequest(r, &req, maxAbuseRequest); err == nil {
+			res, err = s.ClearPenalties(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *AbuseService) ListPenalties(ctx context.Context) (*ListAbusePenaltiesResponse, error) {
+	if err := EnsureListPermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.detector == nil {
+		return nil, ErrAbuseDetectionDisabled
+	}
+	return &ListAbusePenaltiesResponse{Penalties: s.detector.ListPenalties()}, nil
+}
+
+func (s *AbuseService) ClearPenalties(ctx context.Context, req *ClearAbusePenaltiesRequest) (*ClearAbusePenaltiesResponse, error) {
+	AppendLogFields(ctx, "key", req.Key)
+	if err := EnsureCreatePermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.detector == nil {
+		return nil, ErrAbuseDetectionDisabled
+	}
+	return &ClearAbusePenaltiesResponse{Cleared: s.detector.ClearPenalties(req.Key)}, nil
+}
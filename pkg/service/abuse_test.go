@@ -0,0 +1,127 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/negroni/v3"
+
+	"github.com/livekit/protocol/auth"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestAbuseDetector(t *testing.T) {
+	d := service.NewLocalAbuseDetector(config.AbuseDetectionConfig{
+		JoinAttemptsPerMinute: 2,
+		AuthFailuresPerMinute: 2,
+		BanDuration:           time.Minute,
+		DataPacketsPerSecond:  3,
+		ThrottleDuration:      time.Minute,
+	})
+
+	t.Run("join attempts", func(t *testing.T) {
+		require.NoError(t, d.RecordJoinAttempt("10.0.0.1"))
+		require.NoError(t, d.RecordJoinAttempt("10.0.0.1"))
+		require.ErrorIs(t, d.RecordJoinAttempt("10.0.0.1"), service.ErrAbuseBanned)
+		require.ErrorIs(t, d.CheckIP("10.0.0.1"), service.ErrAbuseBanned)
+		require.NoError(t, d.CheckIP("10.0.0.2"))
+	})
+
+	t.Run("data flood", func(t *testing.T) {
+		p := &typesfakes.FakeLocalParticipant{}
+		p.IdentityReturns("flooder")
+		for i := 0; i < 3; i++ {
+			require.True(t, d.AllowDataPacket("room", p))
+		}
+		require.False(t, d.AllowDataPacket("room", p))
+		// throttled until the penalty expires
+		require.False(t, d.AllowDataPacket("room", p))
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		svc := service.NewAbuseService(d)
+		_, err := svc.ListPenalties(context.Background())
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+
+		ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomList: true, RoomCreate: true},
+		}, "")
+		res, err := svc.ListPenalties(ctx)
+		require.NoError(t, err)
+		require.Len(t, res.Penalties, 2)
+		require.Equal(t, "ip:10.0.0.1", res.Penalties[0].Key)
+		require.Equal(t, service.AbuseReasonJoinAttempts, res.Penalties[0].Reason)
+		require.Equal(t, "participant:room/flooder", res.Penalties[1].Key)
+		require.Equal(t, service.AbusePenaltyThrottle, res.Penalties[1].Kind)
+
+		cleared, err := svc.ClearPenalties(ctx, &service.ClearAbusePenaltiesRequest{Key: "ip:10.0.0.1"})
+		require.NoError(t, err)
+		require.Equal(t, 1, cleared.Cleared)
+		require.NoError(t, d.CheckIP("10.0.0.1"))
+
+		cleared, err = svc.ClearPenalties(ctx, &service.ClearAbusePenaltiesRequest{})
+		require.NoError(t, err)
+		require.Equal(t, 1, cleared.Cleared)
+
+		_, err = service.NewAbuseService(nil).ListPenalties(ctx)
+		require.ErrorIs(t, err, service.ErrAbuseDetectionDisabled)
+	})
+}
+
+func TestAbuseMiddleware(t *testing.T) {
+	d := service.NewLocalAbuseDetector(config.AbuseDetectionConfig{
+		AuthFailuresPerMinute: 2,
+		BanDuration:           time.Minute,
+	})
+	n := negroni.New(service.NewAbuseMiddleware(&config.Config{TrustedProxies: []string{"10.1.0.0/16"}}, d))
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	serve := func(peer, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/twirp/livekit.RoomService/ListRooms", nil)
+		req.RemoteAddr = peer
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		n.ServeHTTP(w, req)
+		return w.Code
+	}
+	// the failure over the limit bans the IP, later requests are rejected before authentication
+	for i := 0; i < 3; i++ {
+		require.Equal(t, http.StatusUnauthorized, serve("10.0.0.3:5000", ""))
+	}
+	require.Equal(t, http.StatusTooManyRequests, serve("10.0.0.3:5000", ""))
+
+	// forwarded addresses set by clients neither lift a ban nor get others banned
+	require.Equal(t, http.StatusTooManyRequests, serve("10.0.0.3:5000", "10.0.0.4"))
+	for i := 0; i < 3; i++ {
+		require.Equal(t, http.StatusUnauthorized, serve("10.0.0.5:5000", "10.0.0.6"))
+	}
+	require.Equal(t, http.StatusUnauthorized, serve("10.1.0.1:5000", "10.0.0.6"))
+
+	// clients behind a trusted proxy are banned by their own address
+	require.Equal(t, http.StatusTooManyRequests, serve("10.1.0.1:5000", "10.0.0.3"))
+}
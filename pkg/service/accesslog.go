@@ -0,0 +1,106 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/urfave/negroni/v3"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/utils/guid"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+const (
+	RequestIDHeader = "X-Request-Id"
+	RequestIDPrefix = "REQ_"
+
+	maxRequestIDLength = 128
+)
+
+// AccessLogMiddleware identifies every request, either with the X-Request-Id it was sent with or a new ID.
+// The ID is returned in the response, added to the logger of the request and passed on in psrpc metadata.
+type AccessLogMiddleware struct {
+	enabled bool
+}
+
+func NewAccessLogMiddleware(enabled bool) *AccessLogMiddleware {
+	return &AccessLogMiddleware{
+		enabled: enabled,
+	}
+}
+
+func (m *AccessLogMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	requestID := r.Header.Get(RequestIDHeader)
+	if !isValidRequestID(requestID) {
+		requestID = guid.New(RequestIDPrefix)
+	}
+	w.Header().Set(RequestIDHeader, requestID)
+
+	ctx := utils.ContextWithRequestID(r.Context(), requestID)
+	l := utils.GetLogger(ctx).WithValues("requestID", requestID)
+	r = r.WithContext(utils.ContextWithLogger(ctx, l))
+
+	startedAt := time.Now()
+	next.ServeHTTP(w, r)
+	if !m.enabled {
+		return
+	}
+
+	fields := []interface{}{
+		"method", r.Method,
+		"path", r.URL.Path,
+		"duration", time.Since(startedAt),
+		"clientIP", GetClientIP(r),
+		"userAgent", r.UserAgent(),
+	}
+	if rw, ok := w.(negroni.ResponseWriter); ok {
+		fields = append(fields, "status", rw.Status(), "size", rw.Size())
+	}
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		fields = append(fields, "websocket", true)
+	}
+	l.WithComponent(utils.ComponentAPI).Infow("access", fields...)
+}
+
+// request IDs from clients are kept when they are safe to log and pass on
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDInterceptor adds the request ID received with psrpc requests to the logger of the handler
+func RequestIDInterceptor(ctx context.Context, req proto.Message, info psrpc.RPCInfo, handler psrpc.ServerRPCHandler) (proto.Message, error) {
+	if requestID := utils.GetRequestID(ctx); requestID != "" {
+		ctx = utils.ContextWithLogger(ctx, utils.GetLogger(ctx).WithValues("requestID", requestID))
+	}
+	return handler(ctx, req)
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/negroni/v3"
+
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var handled string
+	n := negroni.New(service.NewAccessLogMiddleware(true))
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handled = utils.GetRequestID(r.Context())
+	})
+
+	serve := func(requestID string) string {
+		req := httptest.NewRequest(http.MethodPost, "/twirp/livekit.RoomService/ListRooms", nil)
+		if requestID != "" {
+			req.Header.Set(service.RequestIDHeader, requestID)
+		}
+		w := httptest.NewRecorder()
+		n.ServeHTTP(w, req)
+		require.Equal(t, handled, w.Header().Get(service.RequestIDHeader))
+		return handled
+	}
+
+	// kept from the client
+	require.Equal(t, "trace-1234", serve("trace-1234"))
+
+	// generated when missing or invalid
+	require.True(t, strings.HasPrefix(serve(""), service.RequestIDPrefix))
+	require.True(t, strings.HasPrefix(serve("bad id\n"), service.RequestIDPrefix))
+	require.True(t, strings.HasPrefix(serve(strings.Repeat("a", 200)), service.RequestIDPrefix))
+}
@@ -0,0 +1,169 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// heavy admin operations limited by config.AdminLimitsConfig
+const (
+	AdminOpBulkParticipants = "bulk_participants"
+	AdminOpRoomArchive      = "room_archive"
+	AdminOpProfile          = "profile"
+)
+
+// AdminOperationState is the queue of a kind of admin operation, returned by POST /node/admin_operations
+type AdminOperationState struct {
+	// 0 when unlimited
+	Limit     int    `json:"limit"`
+	Running   int    `json:"running"`
+	Queued    int    `json:"queued"`
+	Completed uint64 `json:"completed"`
+	Rejected  uint64 `json:"rejected"`
+}
+
+// AdminLimiter queues the heavy admin operations of this node so that only a few of each kind run at once
+type AdminLimiter struct {
+	maxQueued    int
+	queueTimeout time.Duration
+
+	lock sync.Mutex
+	ops  map[string]*adminOperationQueue
+}
+
+type adminOperationQueue struct {
+	AdminOperationState
+	slots chan struct{}
+}
+
+func NewAdminLimiter(conf *config.Config) *AdminLimiter {
+	limits := conf.Admin.Limits
+	l := &AdminLimiter{
+		maxQueued:    limits.MaxQueued,
+		queueTimeout: limits.QueueTimeout,
+		ops:          make(map[string]*adminOperationQueue),
+	}
+	for op, limit := range map[string]int{
+		AdminOpBulkParticipants: limits.BulkParticipants,
+		AdminOpRoomArchive:      limits.RoomArchive,
+		AdminOpProfile:          limits.Profile,
+	} {
+		q := &adminOperationQueue{AdminOperationState: AdminOperationState{Limit: limit}}
+		if limit > 0 {
+			q.slots = make(chan struct{}, limit)
+		}
+		l.ops[op] = q
+	}
+	return l
+}
+
+// Acquire waits for a slot to run an operation, the returned function releases it. It fails with
+// ErrAdminOperationBusy when the queue of the operation is full or the slot isn't free in time.
+func (l *AdminLimiter) Acquire(ctx context.Context, op string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.lock.Lock()
+	q := l.ops[op]
+	if q == nil || q.slots == nil {
+		if q != nil {
+			q.Running++
+		}
+		l.lock.Unlock()
+		return l.releaser(q), nil
+	}
+	select {
+	case q.slots <- struct{}{}:
+		q.Running++
+		l.lock.Unlock()
+		return l.releaser(q), nil
+	default:
+	}
+	if l.maxQueued > 0 && q.Queued >= l.maxQueued {
+		q.Rejected++
+		l.lock.Unlock()
+		logger.Infow("admin operation refused, queue is full", "operation", op)
+		return nil, ErrAdminOperationBusy
+	}
+	q.Queued++
+	l.lock.Unlock()
+
+	var timeout <-chan time.Time
+	if l.queueTimeout > 0 {
+		timer := time.NewTimer(l.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case q.slots <- struct{}{}:
+	case <-timeout:
+		err = ErrAdminOperationBusy
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	q.Queued--
+	if err != nil {
+		q.Rejected++
+		return nil, err
+	}
+	q.Running++
+	return l.releaser(q), nil
+}
+
+func (l *AdminLimiter) releaser(q *adminOperationQueue) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if q == nil {
+				return
+			}
+			l.lock.Lock()
+			q.Running--
+			q.Completed++
+			l.lock.Unlock()
+			if q.slots != nil {
+				<-q.slots
+			}
+		})
+	}
+}
+
+// State returns the queues of the admin operations by kind
+func (l *AdminLimiter) State() map[string]AdminOperationState {
+	if l == nil {
+		return nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	state := make(map[string]AdminOperationState, len(l.ops))
+	for op, q := range l.ops {
+		state[op] = q.AdminOperationState
+	}
+	return state
+}
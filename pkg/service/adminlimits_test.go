@@ -0,0 +1,68 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestAdminLimiter(t *testing.T) {
+	conf := &config.Config{}
+	conf.Admin.Limits = config.AdminLimitsConfig{
+		BulkParticipants: 1,
+		MaxQueued:        1,
+		QueueTimeout:     time.Second,
+	}
+	l := service.NewAdminLimiter(conf)
+	ctx := context.Background()
+
+	release, err := l.Acquire(ctx, service.AdminOpBulkParticipants)
+	require.NoError(t, err)
+
+	// the second operation waits for the first one
+	acquired := make(chan func())
+	go func() {
+		release, err := l.Acquire(ctx, service.AdminOpBulkParticipants)
+		require.NoError(t, err)
+		acquired <- release
+	}()
+	require.Eventually(t, func() bool {
+		return l.State()[service.AdminOpBulkParticipants].Queued == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// the queue is full
+	_, err = l.Acquire(ctx, service.AdminOpBulkParticipants)
+	require.ErrorIs(t, err, service.ErrAdminOperationBusy)
+
+	release()
+	(<-acquired)()
+
+	state := l.State()[service.AdminOpBulkParticipants]
+	require.Equal(t, service.AdminOperationState{Limit: 1, Completed: 2, Rejected: 1}, state)
+
+	// unlimited
+	for i := 0; i < 5; i++ {
+		_, err = l.Acquire(ctx, service.AdminOpRoomArchive)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 5, l.State()[service.AdminOpRoomArchive].Running)
+}
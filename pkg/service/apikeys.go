@@ -0,0 +1,445 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+	"github.com/livekit/protocol/utils/guid"
+)
+
+const (
+	// tokens of keys with the all scope keep their grants, other keys only keep the grants of their scopes
+	APIKeyScopeAll       = "all"
+	APIKeyScopeRoomJoin  = "room_join"
+	APIKeyScopeRoomAdmin = "room_admin"
+	APIKeyScopeSIPAdmin  = "sip_admin"
+	APIKeyScopeEgress    = "egress"
+	APIKeyScopeIngress   = "ingress"
+
+	apiKeyPrefix = "API"
+	// uses of keys are written to the store in a batch this often
+	apiKeyLastUsedInterval = time.Minute
+
+	maxAPIKeyRequest = 4 * 1024
+)
+
+var apiKeyScopes = []string{
+	APIKeyScopeAll,
+	APIKeyScopeRoomJoin,
+	APIKeyScopeRoomAdmin,
+	APIKeyScopeSIPAdmin,
+	APIKeyScopeEgress,
+	APIKeyScopeIngress,
+}
+
+// APIKey is a key managed through the API, times are unix seconds
+type APIKey struct {
+	Key         string   `json:"key"`
+	Secret      string   `json:"secret,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Scopes      []string `json:"scopes"`
+	CreatedAt   int64    `json:"created_at"`
+	// 0 when the key does not expire
+	ExpiresAt  int64 `json:"expires_at,omitempty"`
+	LastUsedAt int64 `json:"last_used_at,omitempty"`
+}
+
+func (k *APIKey) expired(now time.Time) bool {
+	return k.ExpiresAt != 0 && now.Unix() >= k.ExpiresAt
+}
+
+func (k *APIKey) hasScope(scope string) bool {
+	return slices.Contains(k.Scopes, APIKeyScopeAll) || slices.Contains(k.Scopes, scope)
+}
+
+// restrictGrants removes the grants outside of the scopes of the key from the grants of a token
+func (k *APIKey) restrictGrants(grants *auth.ClaimGrants) *auth.ClaimGrants {
+	if slices.Contains(k.Scopes, APIKeyScopeAll) {
+		return grants
+	}
+	restricted := grants.Clone()
+	if v := restricted.Video; v != nil {
+		if !k.hasScope(APIKeyScopeRoomJoin) {
+			v.RoomJoin = false
+		}
+		if !k.hasScope(APIKeyScopeRoomAdmin) {
+			v.RoomCreate = false
+			v.RoomList = false
+			v.RoomAdmin = false
+		}
+		if !k.hasScope(APIKeyScopeEgress) {
+			v.RoomRecord = false
+		}
+		if !k.hasScope(APIKeyScopeIngress) {
+			v.IngressAdmin = false
+		}
+	}
+	if !k.hasScope(APIKeyScopeSIPAdmin) {
+		restricted.SIP = nil
+	}
+	return restricted
+}
+
+func (k *APIKey) clone() *APIKey {
+	clone := *k
+	clone.Scopes = slices.Clone(k.Scopes)
+	return &clone
+}
+
+// withoutSecret is returned when listing keys, secrets are only returned when a key is created
+func (k *APIKey) withoutSecret() *APIKey {
+	clone := k.clone()
+	clone.Secret = ""
+	return clone
+}
+
+// ManagedKeyProvider serves the keys of the config and the keys managed through the API. Managed keys are
+// served from a snapshot, refreshed in the background when a key is created or revoked on any node, and
+// checked against the store once the cache expires in case a change notification was missed.
+type ManagedKeyProvider struct {
+	static   auth.KeyProvider
+	store    APIKeyStore
+	cacheTTL time.Duration
+
+	keys atomic.Pointer[managedKeys]
+	// serializes refreshes, lookups never wait for the store
+	refreshLock sync.Mutex
+
+	// uses of keys since the last flush, written to the store in a batch
+	usedLock sync.Mutex
+	used     map[string]time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type managedKeys struct {
+	keys    map[string]*APIKey
+	version int64
+}
+
+func NewManagedKeyProvider(static auth.KeyProvider, store APIKeyStore, cacheTTL time.Duration) *ManagedKeyProvider {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &ManagedKeyProvider{
+		static:   static,
+		store:    store,
+		cacheTTL: cacheTTL,
+		used:     make(map[string]time.Time),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	p.keys.Store(&managedKeys{})
+	// before loading the keys, so that no change is missed
+	changes := store.WatchAPIKeys(ctx)
+	p.refresh(ctx, true)
+	go p.worker(ctx, changes)
+	return p
+}
+
+// Stop records the pending uses of keys and stops refreshing them
+func (p *ManagedKeyProvider) Stop() {
+	p.cancel()
+	<-p.done
+}
+
+func (p *ManagedKeyProvider) GetSecret(key string) string {
+	if secret := p.static.GetSecret(key); secret != "" {
+		return secret
+	}
+	k := p.load(key)
+	if k == nil || k.expired(time.Now()) {
+		return ""
+	}
+	return k.Secret
+}
+
+func (p *ManagedKeyProvider) NumKeys() int {
+	return p.static.NumKeys() + len(p.keys.Load().keys)
+}
+
+// authorize limits the grants of a verified token to the scopes of its key, and records the use of the key
+func (p *ManagedKeyProvider) authorize(apiKey string, grants *auth.ClaimGrants) *auth.ClaimGrants {
+	if p.static.GetSecret(apiKey) != "" {
+		return grants
+	}
+
+	k := p.load(apiKey)
+	if k == nil {
+		return grants
+	}
+	p.usedLock.Lock()
+	p.used[apiKey] = time.Now()
+	p.usedLock.Unlock()
+	return k.restrictGrants(grants)
+}
+
+// unrestricted returns whether tokens of a key keep all of their grants
+func (p *ManagedKeyProvider) unrestricted(apiKey string) bool {
+	if p.static.GetSecret(apiKey) != "" {
+		return true
+	}
+	k := p.load(apiKey)
+	return k != nil && slices.Contains(k.Scopes, APIKeyScopeAll)
+}
+
+func (p *ManagedKeyProvider) isStatic(apiKey string) bool {
+	return p.static.GetSecret(apiKey) != ""
+}
+
+func (p *ManagedKeyProvider) load(key string) *APIKey {
+	return p.keys.Load().keys[key]
+}
+
+// invalidate reloads the keys after a change made on this node, other nodes are notified by the store
+func (p *ManagedKeyProvider) invalidate(ctx context.Context) {
+	p.refresh(ctx, true)
+}
+
+func (p *ManagedKeyProvider) worker(ctx context.Context, changes <-chan struct{}) {
+	defer close(p.done)
+
+	checkTicker := time.NewTicker(p.cacheTTL)
+	defer checkTicker.Stop()
+	flushTicker := time.NewTicker(apiKeyLastUsedInterval)
+	defer flushTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.flushUsed()
+			return
+		case <-changes:
+			p.refresh(ctx, true)
+		case <-checkTicker.C:
+			p.refresh(ctx, false)
+		case <-flushTicker.C:
+			p.flushUsed()
+		}
+	}
+}
+
+// refresh loads the keys when forced or when their version changed, cached keys keep being served when the
+// store cannot be reached
+func (p *ManagedKeyProvider) refresh(ctx context.Context, force bool) {
+	p.refreshLock.Lock()
+	defer p.refreshLock.Unlock()
+
+	version, err := p.store.LoadAPIKeysVersion(ctx)
+	if err != nil {
+		logger.Warnw("could not check api keys", err)
+		return
+	}
+	if current := p.keys.Load(); !force && current.keys != nil && version == current.version {
+		return
+	}
+	keys, err := p.store.ListAPIKeys(ctx)
+	if err != nil {
+		logger.Warnw("could not load api keys", err)
+		return
+	}
+	snapshot := &managedKeys{
+		keys:    make(map[string]*APIKey, len(keys)),
+		version: version,
+	}
+	for _, k := range keys {
+		snapshot.keys[k.Key] = k
+	}
+	p.keys.Store(snapshot)
+}
+
+func (p *ManagedKeyProvider) flushUsed() {
+	p.usedLock.Lock()
+	used := p.used
+	if len(used) == 0 {
+		p.usedLock.Unlock()
+		return
+	}
+	p.used = make(map[string]time.Time, len(used))
+	p.usedLock.Unlock()
+
+	if err := p.store.UpdateAPIKeysLastUsed(context.Background(), used); err != nil {
+		logger.Warnw("could not record api key uses", err, "keys", len(used))
+	}
+}
+
+// ---------------------------------------------
+
+// CreateAPIKeyRequest is the JSON body of POST /api_keys/create
+type CreateAPIKeyRequest struct {
+	Description string   `json:"description,omitempty"`
+	Scopes      []string `json:"scopes"`
+	// seconds until the key expires, it does not expire when 0
+	TTL uint32 `json:"ttl,omitempty"`
+}
+
+// RevokeAPIKeyRequest is the JSON body of POST /api_keys/revoke
+type RevokeAPIKeyRequest struct {
+	Key string `json:"key"`
+}
+
+type ListAPIKeysResponse struct {
+	Keys []*APIKey `json:"keys"`
+}
+
+// APIKeyService serves the API managing keys: POST /api_keys/create, /api_keys/revoke and /api_keys/list.
+// Calls require roomCreate, with a token of a key of the config or of a managed key with the all scope.
+type APIKeyService struct {
+	provider *ManagedKeyProvider
+}
+
+func NewAPIKeyService(keyProvider auth.KeyProvider) *APIKeyService {
+	provider, _ := keyProvider.(*ManagedKeyProvider)
+	return &APIKeyService{
+		provider: provider,
+	}
+}
+
+func (s *APIKeyService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/api_keys/") {
+	case "create":
+		var req CreateAPIKeyRequest
+		if err = decodeJSONRequest(r, &req, maxAPIKeyRequest); err == nil {
+			res, err = s.CreateAPIKey(r.Context(), &req)
+		}
+	case "revoke":
+		var req RevokeAPIKeyRequest
+		if err = decodeJSONRequest(r, &req, maxAPIKeyRequest); err == nil {
+			err = s.RevokeAPIKey(r.Context(), &req)
+			res = struct{}{}
+		}
+	case "list":
+		res, err = s.ListAPIKeys(r.Context())
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// CreateAPIKey returns the new key with its secret, which cannot be retrieved afterwards
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, req *CreateAPIKeyRequest) (*APIKey, error) {
+	AppendLogFields(ctx, "scopes", req.Scopes, "ttl", req.TTL)
+	if err := s.ensurePermission(ctx); err != nil {
+		return nil, err
+	}
+	if len(req.Scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrAPIKeyInvalid)
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(apiKeyScopes, scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrAPIKeyInvalid, scope)
+		}
+	}
+
+	scopes := slices.Clone(req.Scopes)
+	slices.Sort(scopes)
+
+	now := time.Now()
+	k := &APIKey{
+		Key:         guid.New(apiKeyPrefix),
+		Secret:      utils.RandomSecret(),
+		Description: req.Description,
+		Scopes:      slices.Compact(scopes),
+		CreatedAt:   now.Unix(),
+	}
+	if req.TTL > 0 {
+		k.ExpiresAt = now.Add(time.Duration(req.TTL) * time.Second).Unix()
+	}
+	if err := s.provider.store.StoreAPIKey(ctx, k); err != nil {
+		return nil, err
+	}
+	s.provider.invalidate(ctx)
+	AppendLogFields(ctx, "apiKeyCreated", k.Key)
+	return k, nil
+}
+
+// RevokeAPIKey deletes a managed key, its tokens are refused by every node once notified of the change
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, req *RevokeAPIKeyRequest) error {
+	AppendLogFields(ctx, "apiKeyRevoked", req.Key)
+	if err := s.ensurePermission(ctx); err != nil {
+		return err
+	}
+	if s.provider.isStatic(req.Key) {
+		return fmt.Errorf("%w: keys of the config are revoked by removing them from it", ErrAPIKeyInvalid)
+	}
+	if _, err := s.provider.store.LoadAPIKey(ctx, req.Key); err != nil {
+		return err
+	}
+	if err := s.provider.store.DeleteAPIKey(ctx, req.Key); err != nil {
+		return err
+	}
+	s.provider.invalidate(ctx)
+	return nil
+}
+
+func (s *APIKeyService) ListAPIKeys(ctx context.Context) (*ListAPIKeysResponse, error) {
+	if err := s.ensurePermission(ctx); err != nil {
+		return nil, err
+	}
+	keys, err := s.provider.store.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &ListAPIKeysResponse{Keys: make([]*APIKey, 0, len(keys))}
+	for _, k := range keys {
+		res.Keys = append(res.Keys, k.withoutSecret())
+	}
+	slices.SortFunc(res.Keys, func(a, b *APIKey) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return res, nil
+}
+
+func (s *APIKeyService) ensurePermission(ctx context.Context) error {
+	if s.provider == nil {
+		return ErrAPIKeyManagementDisabled
+	}
+	if err := EnsureCreatePermission(ctx); err != nil {
+		return err
+	}
+	if !s.provider.unrestricted(GetAPIKey(ctx)) {
+		return ErrPermissionDenied
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+func TestAPIKeyService(t *testing.T) {
+	const (
+		staticKey    = "APIstatic"
+		staticSecret = "staticsecretencodedinbase62extendto32bytes"
+	)
+	store := service.NewLocalStore()
+	provider := service.NewManagedKeyProvider(
+		auth.NewFileBasedKeyProviderFromMap(map[string]string{staticKey: staticSecret}),
+		store,
+		time.Hour,
+	)
+	defer provider.Stop()
+	s := service.NewAPIKeyService(provider)
+	adminCtx := func(apiKey string) context.Context {
+		return service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomCreate: true},
+		}, apiKey)
+	}
+	ctx := adminCtx(staticKey)
+
+	key, err := s.CreateAPIKey(ctx, &service.CreateAPIKeyRequest{
+		Scopes:      []string{service.APIKeyScopeRoomJoin, service.APIKeyScopeRoomJoin},
+		Description: "frontend",
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, key.Secret)
+	require.Equal(t, []string{service.APIKeyScopeRoomJoin}, key.Scopes)
+	require.Equal(t, key.Secret, provider.GetSecret(key.Key))
+	require.Equal(t, staticSecret, provider.GetSecret(staticKey))
+	require.Equal(t, 2, provider.NumKeys())
+
+	t.Run("restricts grants to scopes", func(t *testing.T) {
+		m := service.NewAPIKeyAuthMiddleware(provider)
+		var grants *auth.ClaimGrants
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			grants = service.GetGrants(r.Context())
+		})
+		token, err := auth.NewAccessToken(key.Key, key.Secret).
+			AddGrant(&auth.VideoGrant{Room: "room", RoomJoin: true, RoomAdmin: true}).
+			AddSIPGrant(&auth.SIPGrant{Admin: true}).
+			ToJWT()
+		require.NoError(t, err)
+
+		r := &http.Request{Header: http.Header{}}
+		service.SetAuthorizationToken(r, token)
+		m.ServeHTTP(httptest.NewRecorder(), r, handler)
+		require.NotNil(t, grants)
+		require.True(t, grants.Video.RoomJoin)
+		require.False(t, grants.Video.RoomAdmin)
+		require.Nil(t, grants.SIP)
+	})
+
+	t.Run("restricted keys cannot manage keys", func(t *testing.T) {
+		_, err := s.CreateAPIKey(adminCtx(key.Key), &service.CreateAPIKeyRequest{Scopes: []string{service.APIKeyScopeAll}})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+	})
+
+	t.Run("invalid scope", func(t *testing.T) {
+		_, err := s.CreateAPIKey(ctx, &service.CreateAPIKeyRequest{Scopes: []string{"everything"}})
+		require.ErrorIs(t, err, service.ErrAPIKeyInvalid)
+	})
+
+	t.Run("lists keys without secrets", func(t *testing.T) {
+		res, err := s.ListAPIKeys(ctx)
+		require.NoError(t, err)
+		require.Len(t, res.Keys, 1)
+		require.Equal(t, key.Key, res.Keys[0].Key)
+		require.Empty(t, res.Keys[0].Secret)
+	})
+
+	t.Run("expired keys are refused", func(t *testing.T) {
+		require.NoError(t, store.StoreAPIKey(ctx, &service.APIKey{
+			Key:       "APIexpired",
+			Secret:    "expiredsecret",
+			Scopes:    []string{service.APIKeyScopeAll},
+			CreatedAt: time.Now().Add(-time.Hour).Unix(),
+			ExpiresAt: time.Now().Add(-time.Minute).Unix(),
+		}))
+		other := service.NewManagedKeyProvider(auth.NewFileBasedKeyProviderFromMap(nil), store, time.Hour)
+		defer other.Stop()
+		require.Empty(t, other.GetSecret("APIexpired"))
+		require.Equal(t, key.Secret, other.GetSecret(key.Key))
+	})
+
+	t.Run("revoked on other nodes when notified", func(t *testing.T) {
+		other := service.NewManagedKeyProvider(auth.NewFileBasedKeyProviderFromMap(nil), store, time.Hour)
+		defer other.Stop()
+		require.Equal(t, key.Secret, other.GetSecret(key.Key))
+
+		require.NoError(t, s.RevokeAPIKey(ctx, &service.RevokeAPIKeyRequest{Key: key.Key}))
+		require.Empty(t, provider.GetSecret(key.Key))
+		require.Eventually(t, func() bool {
+			return other.GetSecret(key.Key) == ""
+		}, time.Second, 10*time.Millisecond)
+
+		require.ErrorIs(t, s.RevokeAPIKey(ctx, &service.RevokeAPIKeyRequest{Key: key.Key}), service.ErrAPIKeyNotFound)
+		require.ErrorIs(t, s.RevokeAPIKey(ctx, &service.RevokeAPIKeyRequest{Key: staticKey}), service.ErrAPIKeyInvalid)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s := service.NewAPIKeyService(auth.NewFileBasedKeyProviderFromMap(map[string]string{staticKey: staticSecret}))
+		_, err := s.ListAPIKeys(ctx)
+		require.ErrorIs(t, err, service.ErrAPIKeyManagementDisabled)
+	})
+}
+
+func TestManagedKeyProvider(t *testing.T) {
+	key := &service.APIKey{Key: "APIkey", Secret: "secret", Scopes: []string{service.APIKeyScopeRoomJoin}}
+
+	t.Run("serves keys without store round trips", func(t *testing.T) {
+		store := &servicefakes.FakeAPIKeyStore{}
+		store.ListAPIKeysReturns([]*service.APIKey{key}, nil)
+		p := service.NewManagedKeyProvider(auth.NewFileBasedKeyProviderFromMap(nil), store, time.Hour)
+		defer p.Stop()
+
+		m := service.NewAPIKeyAuthMiddleware(p)
+		token, err := auth.NewAccessToken(key.Key, key.Secret).AddGrant(&auth.VideoGrant{Room: "room", RoomJoin: true}).ToJWT()
+		require.NoError(t, err)
+		for i := 0; i < 10; i++ {
+			require.Equal(t, key.Secret, p.GetSecret(key.Key))
+			r := &http.Request{Header: http.Header{}}
+			service.SetAuthorizationToken(r, token)
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {})
+			require.Equal(t, http.StatusOK, w.Code)
+		}
+		require.Equal(t, 1, store.LoadAPIKeysVersionCallCount())
+		require.Equal(t, 1, store.ListAPIKeysCallCount())
+		require.Zero(t, store.UpdateAPIKeysLastUsedCallCount())
+
+		// uses are written in a batch
+		p.Stop()
+		require.Equal(t, 1, store.UpdateAPIKeysLastUsedCallCount())
+		_, used := store.UpdateAPIKeysLastUsedArgsForCall(0)
+		require.Contains(t, used, key.Key)
+	})
+
+	t.Run("reloads keys when notified", func(t *testing.T) {
+		rs := redisStore(t)
+		ctx := context.Background()
+		require.NoError(t, rs.StoreAPIKey(ctx, key))
+		defer rs.DeleteAPIKey(ctx, key.Key)
+
+		p := service.NewManagedKeyProvider(auth.NewFileBasedKeyProviderFromMap(nil), rs, time.Hour)
+		defer p.Stop()
+		require.Equal(t, key.Secret, p.GetSecret(key.Key))
+
+		require.NoError(t, rs.DeleteAPIKey(ctx, key.Key))
+		require.Eventually(t, func() bool {
+			return p.GetSecret(key.Key) == ""
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,329 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+const auditResultOK = "ok"
+
+// AuditRecord describes an API call. Hash covers the whole record including PrevHash, the hash of the record
+// written before it.
+type AuditRecord struct {
+	Seq       uint64           `json:"seq"`
+	Time      time.Time        `json:"time"`
+	NodeID    livekit.NodeID   `json:"node_id"`
+	RequestID string           `json:"request_id,omitempty"`
+	Service   string           `json:"service"`
+	Method    string           `json:"method"`
+	APIKey    string           `json:"api_key,omitempty"`
+	Identity  string           `json:"identity,omitempty"`
+	Video     *auth.VideoGrant `json:"video,omitempty"`
+	SIP       *auth.SIPGrant   `json:"sip,omitempty"`
+	// sha256 of the deterministic protobuf encoding of the request, arguments are not kept as they may hold
+	// personal data
+	ArgumentsDigest string `json:"arguments_digest"`
+	// ok, or the twirp error code returned
+	Result     string `json:"result"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	PrevHash   string `json:"prev_hash,omitempty"`
+	Hash       string `json:"hash,omitempty"`
+}
+
+// AuditSink stores audit records, it is called with one record at a time
+type AuditSink interface {
+	Write(record *AuditRecord) error
+	Close() error
+}
+
+// AuditChainResumer is implemented by sinks persisting records, so that the chain continues from the last
+// record written by a previous run
+type AuditChainResumer interface {
+	LastRecord() *AuditRecord
+}
+
+type AuditSinkFactory func(conf *config.AuditConfig) (AuditSink, error)
+
+var (
+	auditSinksLock sync.Mutex
+	auditSinks     = map[string]AuditSinkFactory{
+		config.AuditSinkLog:  newLogAuditSink,
+		config.AuditSinkFile: newFileAuditSink,
+	}
+)
+
+// RegisterAuditSink makes a sink available to the audit.sink setting, it panics when the name is taken.
+// Like http plugins, sinks are registered from init().
+func RegisterAuditSink(name string, factory AuditSinkFactory) {
+	auditSinksLock.Lock()
+	defer auditSinksLock.Unlock()
+	if _, ok := auditSinks[name]; ok {
+		panic(fmt.Sprintf("audit sink %s registered twice", name))
+	}
+	auditSinks[name] = factory
+}
+
+// AuditLogger records the Twirp API calls served by the node
+type AuditLogger struct {
+	nodeID livekit.NodeID
+	sink   AuditSink
+
+	lock     sync.Mutex
+	seq      uint64
+	lastHash string
+}
+
+// NewAuditLogger returns nil when auditing is disabled
+func NewAuditLogger(conf *config.AuditConfig, nodeID livekit.NodeID) (*AuditLogger, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+	name := conf.Sink
+	if name == "" {
+		name = config.AuditSinkLog
+	}
+	auditSinksLock.Lock()
+	factory, ok := auditSinks[name]
+	auditSinksLock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown audit sink %q", name)
+	}
+	sink, err := factory(conf)
+	if err != nil {
+		return nil, fmt.Errorf("could not create audit sink %s: %w", name, err)
+	}
+	return newAuditLogger(sink, nodeID), nil
+}
+
+func newAuditLogger(sink AuditSink, nodeID livekit.NodeID) *AuditLogger {
+	a := &AuditLogger{
+		nodeID: nodeID,
+		sink:   sink,
+	}
+	if r, ok := sink.(AuditChainResumer); ok {
+		if last := r.LastRecord(); last != nil {
+			a.seq, a.lastHash = last.Seq, last.Hash
+		}
+	}
+	return a
+}
+
+// Interceptor records every call of the Twirp services it is added to, once it returned
+func (a *AuditLogger) Interceptor() twirp.Interceptor {
+	return func(next twirp.Method) twirp.Method {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			startedAt := time.Now()
+			res, err := next(ctx, req)
+			a.recordCall(ctx, req, err, startedAt)
+			return res, err
+		}
+	}
+}
+
+func (a *AuditLogger) recordCall(ctx context.Context, req interface{}, err error, startedAt time.Time) {
+	record := &AuditRecord{
+		Time:       startedAt.UTC(),
+		NodeID:     a.nodeID,
+		RequestID:  utils.GetRequestID(ctx),
+		APIKey:     GetAPIKey(ctx),
+		Result:     auditResultOK,
+		DurationMs: time.Since(startedAt).Milliseconds(),
+	}
+	record.Service, _ = twirp.ServiceName(ctx)
+	record.Method, _ = twirp.MethodName(ctx)
+	if claims := GetGrants(ctx); claims != nil {
+		record.Identity = claims.Identity
+		record.Video = claims.Video
+		record.SIP = claims.SIP
+	}
+	if msg, ok := req.(proto.Message); ok {
+		if b, merr := (proto.MarshalOptions{Deterministic: true}).Marshal(msg); merr == nil {
+			digest := sha256.Sum256(b)
+			record.ArgumentsDigest = hex.EncodeToString(digest[:])
+		}
+	}
+	if err != nil {
+		// twirp reports errors of other types as internal
+		var terr twirp.Error
+		if errors.As(err, &terr) {
+			record.Result = string(terr.Code())
+		} else {
+			record.Result = string(twirp.Internal)
+		}
+		record.Error = err.Error()
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	record.Seq = a.seq + 1
+	record.PrevHash = a.lastHash
+	record.Hash = hashAuditRecord(record)
+	if werr := a.sink.Write(record); werr != nil {
+		logger.Errorw("could not write audit record", werr, "service", record.Service, "method", record.Method)
+		return
+	}
+	a.seq, a.lastHash = record.Seq, record.Hash
+}
+
+func (a *AuditLogger) Close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.sink.Close()
+}
+
+func hashAuditRecord(record *AuditRecord) string {
+	unhashed := *record
+	unhashed.Hash = ""
+	b, _ := json.Marshal(&unhashed)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyAuditChain checks that records were written in sequence and were not altered
+func VerifyAuditChain(records []*AuditRecord) error {
+	for i, record := range records {
+		if hashAuditRecord(record) != record.Hash {
+			return fmt.Errorf("audit record %d was altered", record.Seq)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := records[i-1]
+		if record.Seq != prev.Seq+1 || record.PrevHash != prev.Hash {
+			return fmt.Errorf("audit chain broken between records %d and %d", prev.Seq, record.Seq)
+		}
+	}
+	return nil
+}
+
+// ---------------------------------------------
+
+type logAuditSink struct {
+	logger logger.Logger
+}
+
+func newLogAuditSink(_ *config.AuditConfig) (AuditSink, error) {
+	return &logAuditSink{logger: logger.GetLogger().WithComponent(utils.ComponentAPI)}, nil
+}
+
+func (s *logAuditSink) Write(record *AuditRecord) error {
+	s.logger.Infow("audit",
+		"seq", record.Seq,
+		"requestID", record.RequestID,
+		"service", record.Service,
+		"method", record.Method,
+		"apiKey", record.APIKey,
+		"identity", record.Identity,
+		"video", record.Video,
+		"sip", record.SIP,
+		"argumentsDigest", record.ArgumentsDigest,
+		"result", record.Result,
+		"error", record.Error,
+		"durationMs", record.DurationMs,
+		"prevHash", record.PrevHash,
+		"hash", record.Hash,
+	)
+	return nil
+}
+
+func (s *logAuditSink) Close() error {
+	return nil
+}
+
+// fileAuditSink appends records to a file as JSON lines
+type fileAuditSink struct {
+	file *os.File
+	last *AuditRecord
+}
+
+func newFileAuditSink(conf *config.AuditConfig) (AuditSink, error) {
+	last, err := readLastAuditRecord(conf.File)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(conf.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &fileAuditSink{file: f, last: last}, nil
+}
+
+func readLastAuditRecord(path string) (*AuditRecord, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var last []byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) != 0 {
+			last = append(last[:0], scanner.Bytes()...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return nil, nil
+	}
+	var record AuditRecord
+	if err := json.Unmarshal(last, &record); err != nil {
+		return nil, fmt.Errorf("invalid last audit record: %w", err)
+	}
+	return &record, nil
+}
+
+func (s *fileAuditSink) LastRecord() *AuditRecord {
+	return s.last
+}
+
+func (s *fileAuditSink) Write(record *AuditRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write(append(b, '\n'))
+	return err
+}
+
+func (s *fileAuditSink) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/twitchtv/twirp"
+	"github.com/twitchtv/twirp/ctxsetters"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestAuditLogger(t *testing.T) {
+	conf := &config.AuditConfig{
+		Enabled: true,
+		Sink:    config.AuditSinkFile,
+		File:    filepath.Join(t.TempDir(), "audit.jsonl"),
+	}
+	call := func(t *testing.T, a *service.AuditLogger, method string, req any, err error) {
+		ctx := service.WithAPIKey(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomCreate: true},
+		}, "APIkey")
+		ctx = ctxsetters.WithServiceName(ctx, "RoomService")
+		ctx = ctxsetters.WithMethodName(ctx, method)
+		_, _ = a.Interceptor()(func(context.Context, interface{}) (interface{}, error) {
+			return nil, err
+		})(ctx, req)
+	}
+	readRecords := func(t *testing.T) []*service.AuditRecord {
+		f, err := os.Open(conf.File)
+		require.NoError(t, err)
+		defer f.Close()
+		var records []*service.AuditRecord
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var record service.AuditRecord
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+			records = append(records, &record)
+		}
+		return records
+	}
+
+	a, err := service.NewAuditLogger(conf, "node")
+	require.NoError(t, err)
+	call(t, a, "CreateRoom", &livekit.CreateRoomRequest{Name: "room"}, nil)
+	call(t, a, "DeleteRoom", &livekit.DeleteRoomRequest{Room: "room"}, twirp.NotFoundError("room not found"))
+	require.NoError(t, a.Close())
+
+	records := readRecords(t)
+	require.Len(t, records, 2)
+	require.Equal(t, "RoomService", records[0].Service)
+	require.Equal(t, "CreateRoom", records[0].Method)
+	require.Equal(t, "APIkey", records[0].APIKey)
+	require.True(t, records[0].Video.RoomCreate)
+	require.Equal(t, "ok", records[0].Result)
+	require.Len(t, records[0].ArgumentsDigest, 64)
+	require.Equal(t, string(twirp.NotFound), records[1].Result)
+	require.NoError(t, service.VerifyAuditChain(records))
+
+	t.Run("continues the chain of the file", func(t *testing.T) {
+		a, err := service.NewAuditLogger(conf, "node")
+		require.NoError(t, err)
+		call(t, a, "ListRooms", &livekit.ListRoomsRequest{}, nil)
+		require.NoError(t, a.Close())
+
+		records := readRecords(t)
+		require.Len(t, records, 3)
+		require.Equal(t, uint64(3), records[2].Seq)
+		require.NoError(t, service.VerifyAuditChain(records))
+	})
+
+	t.Run("detects tampering", func(t *testing.T) {
+		records := readRecords(t)
+		altered := *records[1]
+		altered.Result = "ok"
+		require.Error(t, service.VerifyAuditChain([]*service.AuditRecord{records[0], &altered, records[2]}))
+		require.Error(t, service.VerifyAuditChain([]*service.AuditRecord{records[0], records[2]}))
+	})
+
+	t.Run("unknown sink", func(t *testing.T) {
+		_, err := service.NewAuditLogger(&config.AuditConfig{Enabled: true, Sink: "nowhere"}, "node")
+		require.Error(t, err)
+	})
+}
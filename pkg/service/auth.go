@@ -20,10 +20,13 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/go-jose/go-jose/v3/jwt"
 	"github.com/twitchtv/twirp"
 
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/utils"
 )
 
 const (
@@ -37,6 +40,8 @@ type grantsKey struct{}
 type grantsValue struct {
 	claims *auth.ClaimGrants
 	apiKey string
+	// verified token the grants come from, empty when they were not set by the middleware
+	token string
 }
 
 var (
@@ -46,9 +51,15 @@ var (
 	ErrInvalidAPIKey             = errors.New("invalid API key")
 )
 
+// grantsAuthorizer is implemented by key providers limiting what the tokens of a key may grant
+type grantsAuthorizer interface {
+	authorize(apiKey string, grants *auth.ClaimGrants) *auth.ClaimGrants
+}
+
 // authentication middleware
 type APIKeyAuthMiddleware struct {
 	provider auth.KeyProvider
+	oidc     *OIDCVerifier
 }
 
 func NewAPIKeyAuthMiddleware(provider auth.KeyProvider) *APIKeyAuthMiddleware {
@@ -57,6 +68,11 @@ func NewAPIKeyAuthMiddleware(provider auth.KeyProvider) *APIKeyAuthMiddleware {
 	}
 }
 
+// SetOIDCVerifier has tokens of the identity provider verified by it, rather than with the secret of an API key
+func (m *APIKeyAuthMiddleware) SetOIDCVerifier(v *OIDCVerifier) {
+	m.oidc = v
+}
+
 func (m *APIKeyAuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	if r.URL != nil && r.URL.Path == "/rtc/validate" {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -84,29 +100,71 @@ func (m *APIKeyAuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request,
 			return
 		}
 
-		secret := m.provider.GetSecret(v.APIKey())
-		if secret == "" {
-			handleError(w, r, http.StatusUnauthorized, errors.New("invalid API key: "+v.APIKey()))
-			return
-		}
-
-		grants, err := v.Verify(secret)
-		if err != nil {
-			handleError(w, r, http.StatusUnauthorized, errors.New("invalid token: "+authToken+", error: "+err.Error()))
-			return
+		var grants *auth.ClaimGrants
+		apiKey := v.APIKey()
+		if m.oidc != nil && m.oidc.Handles(v.APIKey()) {
+			// the issuer of identity provider tokens is not an API key
+			apiKey = ""
+			grants, err = m.oidc.Verify(r.Context(), authToken, r)
+			if err != nil {
+				handleError(w, r, http.StatusUnauthorized, err)
+				return
+			}
+		} else {
+			secret := m.provider.GetSecret(v.APIKey())
+			if secret == "" {
+				handleError(w, r, http.StatusUnauthorized, errors.New("invalid API key: "+v.APIKey()))
+				return
+			}
+
+			grants, err = verifyAPIToken(v, authToken, secret)
+			if err != nil {
+				handleError(w, r, http.StatusUnauthorized, errors.New("invalid token: "+authToken+", error: "+err.Error()))
+				return
+			}
+			if a, ok := m.provider.(grantsAuthorizer); ok {
+				grants = a.authorize(v.APIKey(), grants)
+			}
 		}
 
 		// set grants in context
 		ctx := r.Context()
 		r = r.WithContext(context.WithValue(ctx, grantsKey{}, &grantsValue{
 			claims: grants,
-			apiKey: v.APIKey(),
+			apiKey: apiKey,
+			token:  authToken,
 		}))
 	}
 
 	next.ServeHTTP(w, r)
 }
 
+// verifyAPIToken verifies a token signed with an API key. Tokens expired or not yet valid by the clock of a
+// node skewed from the cluster are validated again with the clock of the cluster.
+func verifyAPIToken(v *auth.APIKeyTokenVerifier, authToken string, secret string) (*auth.ClaimGrants, error) {
+	grants, err := v.Verify(secret)
+	if err == nil || utils.ClockOffset() == 0 || !(errors.Is(err, jwt.ErrExpired) || errors.Is(err, jwt.ErrNotValidYet)) {
+		return grants, err
+	}
+
+	tok, perr := jwt.ParseSigned(authToken)
+	if perr != nil {
+		return nil, err
+	}
+	var (
+		std    jwt.Claims
+		claims auth.ClaimGrants
+	)
+	if perr = tok.Claims([]byte(secret), &std, &claims); perr != nil {
+		return nil, err
+	}
+	if err = std.Validate(jwt.Expected{Issuer: v.APIKey(), Time: utils.ClusterNow()}); err != nil {
+		return nil, err
+	}
+	claims.Identity = v.Identity()
+	return &claims, nil
+}
+
 func WithAPIKey(ctx context.Context, grants *auth.ClaimGrants, apiKey string) context.Context {
 	return context.WithValue(ctx, grantsKey{}, &grantsValue{
 		claims: grants,
@@ -132,6 +190,15 @@ func GetAPIKey(ctx context.Context) string {
 	return v.apiKey
 }
 
+func getAuthToken(ctx context.Context) string {
+	val := ctx.Value(grantsKey{})
+	v, ok := val.(*grantsValue)
+	if !ok {
+		return ""
+	}
+	return v.token
+}
+
 func WithGrants(ctx context.Context, grants *auth.ClaimGrants, apiKey string) context.Context {
 	return context.WithValue(ctx, grantsKey{}, &grantsValue{
 		claims: grants,
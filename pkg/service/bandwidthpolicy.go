@@ -0,0 +1,273 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+const (
+	bandwidthPolicyRPCService = "BandwidthPolicy"
+	bandwidthPolicyRPC        = "BandwidthPolicy"
+
+	bandwidthPolicyGet    = "get"
+	bandwidthPolicyUpdate = "update"
+
+	maxBandwidthPolicyRequest = 4 * 1024
+)
+
+// BandwidthPolicy describes how the downstream bandwidth of the subscribers of a room is shared between publishers
+type BandwidthPolicy struct {
+	Room string `json:"room"`
+	// fraction of a subscriber's channel capacity the tracks of a single publisher may use while several
+	// publishers compete for it, 0 when unlimited
+	MaxPublisherShare float64 `json:"max_publisher_share"`
+	// set when the room overrides the share of the config
+	Overridden bool `json:"overridden"`
+}
+
+// bandwidthPolicyCommand is carried as JSON in the payload of a user data packet, the response carries
+// the resulting BandwidthPolicy
+type bandwidthPolicyCommand struct {
+	Action            string   `json:"action"`
+	MaxPublisherShare *float64 `json:"max_publisher_share,omitempty"`
+}
+
+// BandwidthPolicyClient reaches the node hosting a room to read or change its bandwidth policy
+type BandwidthPolicyClient interface {
+	BandwidthPolicy(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error)
+}
+
+type BandwidthPolicyServerImpl interface {
+	GetBandwidthPolicy(ctx context.Context, roomName livekit.RoomName) (*BandwidthPolicy, error)
+	UpdateBandwidthPolicy(ctx context.Context, roomName livekit.RoomName, maxPublisherShare *float64) (*BandwidthPolicy, error)
+}
+
+type bandwidthPolicyClient struct {
+	client *client.RPCClient
+}
+
+func NewBandwidthPolicyClient(params rpc.ClientParams) (BandwidthPolicyClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: bandwidthPolicyRPCService,
+		ID:   rand.NewClientID(),
+	}
+	sd.RegisterMethod(bandwidthPolicyRPC, false, false, true, true)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &bandwidthPolicyClient{client: rpcClient}, nil
+}
+
+func (c *bandwidthPolicyClient) BandwidthPolicy(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return client.RequestSingle[*livekit.DataPacket](ctx, c.client, bandwidthPolicyRPC, []string{string(room)}, req, opts...)
+}
+
+// bandwidthPolicyServer handles bandwidth policy commands for a room hosted on this node
+type bandwidthPolicyServer struct {
+	svc      BandwidthPolicyServerImpl
+	roomName livekit.RoomName
+	rpc      *server.RPCServer
+}
+
+func newBandwidthPolicyServer(svc BandwidthPolicyServerImpl, roomName livekit.RoomName, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *bandwidthPolicyServer {
+	sd := &info.ServiceDefinition{
+		Name: bandwidthPolicyRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	sd.RegisterMethod(bandwidthPolicyRPC, false, false, true, true)
+	return &bandwidthPolicyServer{
+		svc:      svc,
+		roomName: roomName,
+		rpc:      s,
+	}
+}
+
+func (s *bandwidthPolicyServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	return server.RegisterHandler(s.rpc, bandwidthPolicyRPC, []string{string(room)}, s.handle, nil)
+}
+
+func (s *bandwidthPolicyServer) handle(ctx context.Context, req *livekit.DataPacket) (*livekit.DataPacket, error) {
+	var cmd bandwidthPolicyCommand
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &cmd); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+
+	var (
+		policy *BandwidthPolicy
+		err    error
+	)
+	switch cmd.Action {
+	case bandwidthPolicyGet:
+		policy, err = s.svc.GetBandwidthPolicy(ctx, s.roomName)
+	case bandwidthPolicyUpdate:
+		policy, err = s.svc.UpdateBandwidthPolicy(ctx, s.roomName, cmd.MaxPublisherShare)
+	default:
+		return nil, ErrBandwidthPolicyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return encodeBandwidthPolicy(policy)
+}
+
+func (s *bandwidthPolicyServer) Kill() {
+	s.rpc.Close(true)
+}
+
+func encodeBandwidthPolicy(v any) (*livekit.DataPacket, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{Payload: payload},
+		},
+	}, nil
+}
+
+// ---------------------------------------------
+
+// BandwidthPolicyRequest is the JSON body of POST /bandwidth_policy/get
+type BandwidthPolicyRequest struct {
+	Room string `json:"room"`
+}
+
+// UpdateBandwidthPolicyRequest is the JSON body of POST /bandwidth_policy/update,
+// a missing max_publisher_share removes the override of the room
+type UpdateBandwidthPolicyRequest struct {
+	Room              string   `json:"room"`
+	MaxPublisherShare *float64 `json:"max_publisher_share"`
+}
+
+// BandwidthPolicyService serves the HTTP API overriding, per room, the share of a subscriber's bandwidth
+// a single publisher may use, so that one publisher such as a screen share cannot starve the others in
+// mixed-content rooms. Overrides last as long as the room on its node. Calls require roomAdmin.
+type BandwidthPolicyService struct {
+	roomStore      ServiceStore
+	topicFormatter rpc.TopicFormatter
+	client         BandwidthPolicyClient
+}
+
+func NewBandwidthPolicyService(
+	roomStore ServiceStore,
+	topicFormatter rpc.TopicFormatter,
+	client BandwidthPolicyClient,
+) *BandwidthPolicyService {
+	return &BandwidthPolicyService{
+		roomStore:      roomStore,
+		topicFormatter: topicFormatter,
+		client:         client,
+	}
+}
+
+func (s *BandwidthPolicyService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/bandwidth_policy/") {
+	case bandwidthPolicyGet:
+		var req BandwidthPolicyRequest
+		if err = decodeJSONRequest(r, &req, maxBandwidthPolicyRequest); err == nil {
+			res, err = s.GetBandwidthPolicy(r.Context(), &req)
+		}
+	case bandwidthPolicyUpdate:
+		var req UpdateBandwidthPolicyRequest
+		if err = decodeJSONRequest(r, &req, maxBandwidthPolicyRequest); err == nil {
+			res, err = s.UpdateBandwidthPolicy(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *BandwidthPolicyService) GetBandwidthPolicy(ctx context.Context, req *BandwidthPolicyRequest) (*BandwidthPolicy, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName)
+	if err := s.ensureRoom(ctx, roomName); err != nil {
+		return nil, err
+	}
+	return s.send(ctx, roomName, &bandwidthPolicyCommand{Action: bandwidthPolicyGet})
+}
+
+func (s *BandwidthPolicyService) UpdateBandwidthPolicy(ctx context.Context, req *UpdateBandwidthPolicyRequest) (*BandwidthPolicy, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName, "maxPublisherShare", req.MaxPublisherShare)
+	if err := s.ensureRoom(ctx, roomName); err != nil {
+		return nil, err
+	}
+	if share := req.MaxPublisherShare; share != nil && (*share < 0 || *share > 1) {
+		return nil, fmt.Errorf("%w: max_publisher_share must be between 0 and 1", ErrBandwidthPolicyInvalid)
+	}
+	return s.send(ctx, roomName, &bandwidthPolicyCommand{Action: bandwidthPolicyUpdate, MaxPublisherShare: req.MaxPublisherShare})
+}
+
+func (s *BandwidthPolicyService) send(ctx context.Context, roomName livekit.RoomName, cmd *bandwidthPolicyCommand) (*BandwidthPolicy, error) {
+	req, err := encodeBandwidthPolicy(cmd)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.BandwidthPolicy(ctx, s.topicFormatter.RoomTopic(ctx, roomName), req)
+	if err != nil {
+		return nil, err
+	}
+	var policy BandwidthPolicy
+	if err = json.Unmarshal(res.GetUser().GetPayload(), &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (s *BandwidthPolicyService) ensureRoom(ctx context.Context, roomName livekit.RoomName) error {
+	if err := EnsureAdminPermission(ctx, roomName); err != nil {
+		return err
+	}
+	// the policy is applied by the node hosting the room
+	_, _, err := s.roomStore.LoadRoom(ctx, roomName, false)
+	return err
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+// testBandwidthPolicyClient answers every command with the policy it was given
+type testBandwidthPolicyClient struct {
+	commands []map[string]any
+	policy   *service.BandwidthPolicy
+}
+
+func (c *testBandwidthPolicyClient) BandwidthPolicy(_ context.Context, _ rpc.RoomTopic, req *livekit.DataPacket, _ ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	var cmd map[string]any
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &cmd); err != nil {
+		return nil, err
+	}
+	c.commands = append(c.commands, cmd)
+	payload, err := json.Marshal(c.policy)
+	if err != nil {
+		return nil, err
+	}
+	return &livekit.DataPacket{Value: &livekit.DataPacket_User{User: &livekit.UserPacket{Payload: payload}}}, nil
+}
+
+func TestBandwidthPolicy(t *testing.T) {
+	store := service.NewLocalStore()
+	client := &testBandwidthPolicyClient{}
+	svc := service.NewBandwidthPolicyService(store, rpc.NewTopicFormatter(), client)
+
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomAdmin: true, Room: "class"},
+	}, "")
+	require.NoError(t, store.StoreRoom(ctx, &livekit.Room{Name: "class"}, nil))
+
+	t.Run("requires admin of the room", func(t *testing.T) {
+		other := service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomAdmin: true, Room: "other"},
+		}, "")
+		_, err := svc.GetBandwidthPolicy(other, &service.BandwidthPolicyRequest{Room: "class"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+	})
+
+	t.Run("validates the share", func(t *testing.T) {
+		share := 1.5
+		_, err := svc.UpdateBandwidthPolicy(ctx, &service.UpdateBandwidthPolicyRequest{Room: "class", MaxPublisherShare: &share})
+		require.ErrorIs(t, err, service.ErrBandwidthPolicyInvalid)
+		require.Empty(t, client.commands)
+	})
+
+	t.Run("updates on the node hosting the room", func(t *testing.T) {
+		share := 0.5
+		client.policy = &service.BandwidthPolicy{Room: "class", MaxPublisherShare: share, Overridden: true}
+		policy, err := svc.UpdateBandwidthPolicy(ctx, &service.UpdateBandwidthPolicyRequest{Room: "class", MaxPublisherShare: &share})
+		require.NoError(t, err)
+		require.True(t, policy.Overridden)
+		require.Equal(t, "update", client.commands[0]["action"])
+		require.Equal(t, share, client.commands[0]["max_publisher_share"])
+
+		// clearing the override sends no share
+		_, err = svc.UpdateBandwidthPolicy(ctx, &service.UpdateBandwidthPolicyRequest{Room: "class"})
+		require.NoError(t, err)
+		require.NotContains(t, client.commands[1], "max_publisher_share")
+	})
+}
@@ -0,0 +1,410 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+const (
+	// webhook events sent for breakout rooms, the moved participant is set on participant_moved
+	EventBreakoutRoomsCreated = "breakout_rooms_created"
+	EventParticipantMoved     = "participant_moved"
+	EventBreakoutRoomsClosed  = "breakout_rooms_closed"
+
+	breakoutRPCService = "Breakout"
+	moveParticipantRPC = "MoveParticipant"
+
+	maxBreakoutRequest = 64 * 1024
+)
+
+// BreakoutClient reaches the node hosting the room a participant is in to move it to another room.
+// The Room of the request is the destination, the source room is implied by the topic.
+type BreakoutClient interface {
+	MoveParticipant(ctx context.Context, room rpc.RoomTopic, req *livekit.RoomParticipantIdentity, opts ...psrpc.RequestOption) (*livekit.ParticipantInfo, error)
+}
+
+type BreakoutServerImpl interface {
+	MoveParticipant(ctx context.Context, roomName livekit.RoomName, req *livekit.RoomParticipantIdentity) (*livekit.ParticipantInfo, error)
+}
+
+type breakoutClient struct {
+	client *client.RPCClient
+}
+
+func NewBreakoutClient(params rpc.ClientParams) (BreakoutClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: breakoutRPCService,
+		ID:   rand.NewClientID(),
+	}
+	sd.RegisterMethod(moveParticipantRPC, false, false, true, true)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &breakoutClient{client: rpcClient}, nil
+}
+
+func (c *breakoutClient) MoveParticipant(ctx context.Context, room rpc.RoomTopic, req *livekit.RoomParticipantIdentity, opts ...psrpc.RequestOption) (*livekit.ParticipantInfo, error) {
+	return client.RequestSingle[*livekit.ParticipantInfo](ctx, c.client, moveParticipantRPC, []string{string(room)}, req, opts...)
+}
+
+// breakoutServer moves participants out of a room hosted on this node
+type breakoutServer struct {
+	svc      BreakoutServerImpl
+	roomName livekit.RoomName
+	rpc      *server.RPCServer
+}
+
+func newBreakoutServer(svc BreakoutServerImpl, roomName livekit.RoomName, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *breakoutServer {
+	sd := &info.ServiceDefinition{
+		Name: breakoutRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	sd.RegisterMethod(moveParticipantRPC, false, false, true, true)
+	return &breakoutServer{
+		svc:      svc,
+		roomName: roomName,
+		rpc:      s,
+	}
+}
+
+func (s *breakoutServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	return server.RegisterHandler(s.rpc, moveParticipantRPC, []string{string(room)}, s.move, nil)
+}
+
+func (s *breakoutServer) move(ctx context.Context, req *livekit.RoomParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	return s.svc.MoveParticipant(ctx, s.roomName, req)
+}
+
+func (s *breakoutServer) Kill() {
+	s.rpc.Close(true)
+}
+
+// ---------------------------------------------
+
+type BreakoutRoom struct {
+	Name         string   `json:"name"`
+	Participants []string `json:"participants,omitempty"`
+}
+
+// CreateBreakoutRoomsRequest is the JSON body of POST /breakout_rooms/create
+type CreateBreakoutRoomsRequest struct {
+	Room          string          `json:"room"`
+	BreakoutRooms []*BreakoutRoom `json:"breakout_rooms"`
+	EmptyTimeout  uint32          `json:"empty_timeout,omitempty"`
+}
+
+// MoveParticipantToBreakoutRequest is the JSON body of POST /breakout_rooms/move, To is either a breakout room
+// or the parent room itself
+type MoveParticipantToBreakoutRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	To       string `json:"to"`
+}
+
+// CloseBreakoutRoomsRequest is the JSON body of POST /breakout_rooms/close
+type CloseBreakoutRoomsRequest struct {
+	Room string `json:"room"`
+}
+
+type BreakoutRoomsResponse struct {
+	Room          string   `json:"room"`
+	BreakoutRooms []string `json:"breakout_rooms"`
+}
+
+// BreakoutService serves the breakout rooms API. Participants are moved by the node hosting their current room,
+// which hands them a token for the destination room and has them reconnect right away, keeping the gap
+// much shorter than a client side leave and join. All calls require roomAdmin on the parent room.
+type BreakoutService struct {
+	limitConf   config.LimitConfig
+	store       BreakoutStore
+	roomService *RoomService
+	client      BreakoutClient
+	telemetry   telemetry.TelemetryService
+}
+
+func NewBreakoutService(
+	limitConf config.LimitConfig,
+	store BreakoutStore,
+	roomService *RoomService,
+	client BreakoutClient,
+	ts telemetry.TelemetryService,
+) *BreakoutService {
+	return &BreakoutService{
+		limitConf:   limitConf,
+		store:       store,
+		roomService: roomService,
+		client:      client,
+		telemetry:   ts,
+	}
+}
+
+func (s *BreakoutService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/breakout_rooms/") {
+	case "create":
+		var req CreateBreakoutRoomsRequest
+		if err = decodeJSONRequest(r, &req, maxBreakoutRequest); err == nil {
+			res, err = s.CreateBreakoutRooms(r.Context(), &req)
+		}
+	case "move":
+		var req MoveParticipantToBreakoutRequest
+		if err = decodeJSONRequest(r, &req, maxBreakoutRequest); err == nil {
+			res, err = s.MoveParticipantToBreakout(r.Context(), &req)
+		}
+	case "close":
+		var req CloseBreakoutRoomsRequest
+		if err = decodeJSONRequest(r, &req, maxBreakoutRequest); err == nil {
+			res, err = s.CloseBreakoutRooms(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	body, err := json.Marshal(res)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+// CreateBreakoutRooms creates the breakout rooms and moves their participants out of the parent room.
+// Every participant is checked to be in the parent room before anything is created.
+func (s *BreakoutService) CreateBreakoutRooms(ctx context.Context, req *CreateBreakoutRoomsRequest) (*BreakoutRoomsResponse, error) {
+	parent := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", parent)
+	if err := EnsureAdminPermission(ctx, parent); err != nil {
+		return nil, err
+	}
+	if err := EnsureCreatePermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrBreakoutRoomsNotSupported
+	}
+	if len(req.BreakoutRooms) == 0 {
+		return nil, fmt.Errorf("%w: no breakout room", ErrBreakoutRoomsInvalid)
+	}
+
+	existing, err := s.store.LoadBreakoutRooms(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) != 0 {
+		return nil, ErrBreakoutRoomsExist
+	}
+	if _, _, err := s.roomService.roomStore.LoadRoom(ctx, parent, false); err != nil {
+		return nil, err
+	}
+
+	rooms := make([]livekit.RoomName, 0, len(req.BreakoutRooms))
+	var moved []string
+	for _, br := range req.BreakoutRooms {
+		switch {
+		case br.Name == "" || br.Name == req.Room:
+			return nil, fmt.Errorf("%w: invalid name %q", ErrBreakoutRoomsInvalid, br.Name)
+		case !s.limitConf.CheckRoomNameLength(br.Name):
+			return nil, fmt.Errorf("%w: max length %d", ErrRoomNameExceedsLimits, s.limitConf.MaxRoomNameLength)
+		case slices.Contains(rooms, livekit.RoomName(br.Name)):
+			return nil, fmt.Errorf("%w: duplicate room %q", ErrBreakoutRoomsInvalid, br.Name)
+		}
+		for _, identity := range br.Participants {
+			if slices.Contains(moved, identity) {
+				return nil, fmt.Errorf("%w: participant %q is in multiple rooms", ErrBreakoutRoomsInvalid, identity)
+			}
+			if _, err := s.roomService.roomStore.LoadParticipant(ctx, parent, livekit.ParticipantIdentity(identity)); err != nil {
+				return nil, err
+			}
+			moved = append(moved, identity)
+		}
+		rooms = append(rooms, livekit.RoomName(br.Name))
+	}
+
+	for _, room := range rooms {
+		if _, err := s.roomService.createRoom(ctx, &livekit.CreateRoomRequest{
+			Name:         string(room),
+			EmptyTimeout: req.EmptyTimeout,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.store.StoreBreakoutRooms(ctx, parent, rooms); err != nil {
+		return nil, err
+	}
+	s.notify(ctx, EventBreakoutRoomsCreated, &livekit.Room{Name: req.Room}, nil)
+
+	for _, br := range req.BreakoutRooms {
+		for _, identity := range br.Participants {
+			if _, err := s.moveParticipant(ctx, parent, livekit.RoomName(br.Name), livekit.ParticipantIdentity(identity)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return breakoutRoomsResponse(parent, rooms), nil
+}
+
+// MoveParticipantToBreakout moves a participant between the parent room and its breakout rooms
+func (s *BreakoutService) MoveParticipantToBreakout(ctx context.Context, req *MoveParticipantToBreakoutRequest) (*livekit.ParticipantInfo, error) {
+	parent := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", parent, "participant", req.Identity, "to", req.To)
+	if err := EnsureAdminPermission(ctx, parent); err != nil {
+		return nil, err
+	}
+	if req.Identity == "" {
+		return nil, ErrIdentityEmpty
+	}
+	rooms, err := s.loadBreakoutRooms(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	to := livekit.RoomName(req.To)
+	if to != parent && !slices.Contains(rooms, to) {
+		return nil, fmt.Errorf("%w: %q is not a breakout room of %q", ErrBreakoutRoomsInvalid, req.To, req.Room)
+	}
+
+	identity := livekit.ParticipantIdentity(req.Identity)
+	for _, from := range append([]livekit.RoomName{parent}, rooms...) {
+		pi, err := s.roomService.roomStore.LoadParticipant(ctx, from, identity)
+		if errors.Is(err, ErrParticipantNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if from == to {
+			return pi, nil
+		}
+		return s.moveParticipant(ctx, from, to, identity)
+	}
+	return nil, ErrParticipantNotFound
+}
+
+// CloseBreakoutRooms moves everyone back to the parent room and deletes the breakout rooms
+func (s *BreakoutService) CloseBreakoutRooms(ctx context.Context, req *CloseBreakoutRoomsRequest) (*BreakoutRoomsResponse, error) {
+	parent := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", parent)
+	if err := EnsureAdminPermission(ctx, parent); err != nil {
+		return nil, err
+	}
+	rooms, err := s.loadBreakoutRooms(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, room := range rooms {
+		participants, err := s.roomService.roomStore.ListParticipants(ctx, room)
+		if err != nil {
+			return nil, err
+		}
+		for _, pi := range participants {
+			if _, err := s.moveParticipant(ctx, room, parent, livekit.ParticipantIdentity(pi.Identity)); err != nil {
+				logger.Warnw("could not move participant back from breakout room", err,
+					"room", parent, "breakoutRoom", room, "participant", pi.Identity)
+			}
+		}
+		err = s.roomService.deleteRoom(ctx, &livekit.DeleteRoomRequest{Room: string(room)})
+		if err != nil && !errors.Is(err, ErrRoomNotFound) {
+			return nil, err
+		}
+	}
+	if err := s.store.DeleteBreakoutRooms(ctx, parent); err != nil {
+		return nil, err
+	}
+	s.notify(ctx, EventBreakoutRoomsClosed, &livekit.Room{Name: req.Room}, nil)
+
+	return breakoutRoomsResponse(parent, rooms), nil
+}
+
+func (s *BreakoutService) loadBreakoutRooms(ctx context.Context, parent livekit.RoomName) ([]livekit.RoomName, error) {
+	if s.store == nil {
+		return nil, ErrBreakoutRoomsNotSupported
+	}
+	rooms, err := s.store.LoadBreakoutRooms(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	if len(rooms) == 0 {
+		return nil, ErrBreakoutRoomsNotFound
+	}
+	return rooms, nil
+}
+
+func (s *BreakoutService) moveParticipant(ctx context.Context, from, to livekit.RoomName, identity livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	pi, err := s.client.MoveParticipant(ctx, s.roomService.topicFormatter.RoomTopic(ctx, from), &livekit.RoomParticipantIdentity{
+		Room:     string(to),
+		Identity: string(identity),
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.notify(ctx, EventParticipantMoved, &livekit.Room{Name: string(to)}, pi)
+	return pi, nil
+}
+
+func (s *BreakoutService) notify(ctx context.Context, event string, room *livekit.Room, pi *livekit.ParticipantInfo) {
+	if s.telemetry == nil {
+		return
+	}
+	s.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{
+		Event:       event,
+		Room:        room,
+		Participant: pi,
+	})
+}
+
+func breakoutRoomsResponse(parent livekit.RoomName, rooms []livekit.RoomName) *BreakoutRoomsResponse {
+	res := &BreakoutRoomsResponse{Room: string(parent)}
+	for _, room := range rooms {
+		res.BreakoutRooms = append(res.BreakoutRooms, string(room))
+	}
+	return res
+}
@@ -0,0 +1,205 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/rpc/rpcfakes"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+type breakoutMove struct {
+	from     rpc.RoomTopic
+	to       string
+	identity string
+}
+
+// testBreakoutClient moves participants in the store, like the reconnect to the destination room would
+type testBreakoutClient struct {
+	store *service.LocalStore
+	moves []breakoutMove
+}
+
+func (c *testBreakoutClient) MoveParticipant(ctx context.Context, room rpc.RoomTopic, req *livekit.RoomParticipantIdentity, _ ...psrpc.RequestOption) (*livekit.ParticipantInfo, error) {
+	c.moves = append(c.moves, breakoutMove{from: room, to: req.Room, identity: req.Identity})
+	from := livekit.RoomName(room)
+	pi, err := c.store.LoadParticipant(ctx, from, livekit.ParticipantIdentity(req.Identity))
+	if err != nil {
+		return nil, err
+	}
+	_ = c.store.DeleteParticipant(ctx, from, livekit.ParticipantIdentity(req.Identity))
+	_ = c.store.StoreParticipant(ctx, livekit.RoomName(req.Room), pi)
+	return pi, nil
+}
+
+func TestBreakoutRooms(t *testing.T) {
+	store := service.NewLocalStore()
+	router := &routingfakes.FakeRouter{}
+	router.CreateRoomCalls(func(ctx context.Context, req *livekit.CreateRoomRequest) (*livekit.Room, error) {
+		room := &livekit.Room{Name: req.Name}
+		return room, store.StoreRoom(ctx, room, nil)
+	})
+	roomClient := &rpcfakes.FakeTypedRoomClient{}
+	roomService, err := service.NewRoomService(
+		config.LimitConfig{},
+		config.APIConfig{ExecutionTimeout: 2},
+		router,
+		&servicefakes.FakeRoomAllocator{},
+		store,
+		nil,
+		&identityTopicFormatter{},
+		roomClient,
+		&rpcfakes.FakeTypedParticipantClient{},
+	)
+	require.NoError(t, err)
+
+	client := &testBreakoutClient{store: store}
+	ts := &telemetryfakes.FakeTelemetryService{}
+	svc := service.NewBreakoutService(config.LimitConfig{}, store, roomService, client, ts)
+
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomAdmin: true, RoomCreate: true, Room: "class"},
+	}, "")
+	require.NoError(t, store.StoreRoom(ctx, &livekit.Room{Name: "class"}, nil))
+	for _, identity := range []string{"teacher", "alice", "bob", "carol"} {
+		require.NoError(t, store.StoreParticipant(ctx, "class", &livekit.ParticipantInfo{Identity: identity}))
+	}
+	events := func() []string {
+		var events []string
+		for i := 0; i < ts.NotifyEventCallCount(); i++ {
+			_, event := ts.NotifyEventArgsForCall(i)
+			events = append(events, event.Event)
+		}
+		return events
+	}
+
+	t.Run("requires admin of the parent room", func(t *testing.T) {
+		other := service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomAdmin: true, RoomCreate: true, Room: "other"},
+		}, "")
+		_, err := svc.CreateBreakoutRooms(other, &service.CreateBreakoutRoomsRequest{Room: "class"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+	})
+
+	t.Run("checks every participant before creating rooms", func(t *testing.T) {
+		_, err := svc.CreateBreakoutRooms(ctx, &service.CreateBreakoutRoomsRequest{
+			Room: "class",
+			BreakoutRooms: []*service.BreakoutRoom{
+				{Name: "group-1", Participants: []string{"alice"}},
+				{Name: "group-2", Participants: []string{"dave"}},
+			},
+		})
+		require.ErrorIs(t, err, service.ErrParticipantNotFound)
+
+		_, err = svc.CreateBreakoutRooms(ctx, &service.CreateBreakoutRoomsRequest{
+			Room: "class",
+			BreakoutRooms: []*service.BreakoutRoom{
+				{Name: "group-1", Participants: []string{"alice"}},
+				{Name: "group-2", Participants: []string{"alice"}},
+			},
+		})
+		require.ErrorIs(t, err, service.ErrBreakoutRoomsInvalid)
+		require.Zero(t, router.CreateRoomCallCount())
+		require.Empty(t, client.moves)
+	})
+
+	t.Run("create, move and close", func(t *testing.T) {
+		res, err := svc.CreateBreakoutRooms(ctx, &service.CreateBreakoutRoomsRequest{
+			Room: "class",
+			BreakoutRooms: []*service.BreakoutRoom{
+				{Name: "group-1", Participants: []string{"alice", "bob"}},
+				{Name: "group-2", Participants: []string{"carol"}},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"group-1", "group-2"}, res.BreakoutRooms)
+		require.Equal(t, 2, router.CreateRoomCallCount())
+		require.Equal(t, []breakoutMove{
+			{from: "class", to: "group-1", identity: "alice"},
+			{from: "class", to: "group-1", identity: "bob"},
+			{from: "class", to: "group-2", identity: "carol"},
+		}, client.moves)
+
+		_, err = svc.CreateBreakoutRooms(ctx, &service.CreateBreakoutRoomsRequest{
+			Room:          "class",
+			BreakoutRooms: []*service.BreakoutRoom{{Name: "group-3"}},
+		})
+		require.ErrorIs(t, err, service.ErrBreakoutRoomsExist)
+
+		// moves from whichever room the participant is in
+		client.moves = nil
+		pi, err := svc.MoveParticipantToBreakout(ctx, &service.MoveParticipantToBreakoutRequest{Room: "class", Identity: "bob", To: "group-2"})
+		require.NoError(t, err)
+		require.Equal(t, "bob", pi.Identity)
+		require.Equal(t, []breakoutMove{{from: "group-1", to: "group-2", identity: "bob"}}, client.moves)
+
+		_, err = svc.MoveParticipantToBreakout(ctx, &service.MoveParticipantToBreakoutRequest{Room: "class", Identity: "bob", To: "elsewhere"})
+		require.ErrorIs(t, err, service.ErrBreakoutRoomsInvalid)
+
+		// everyone comes back to the parent room
+		client.moves = nil
+		_, err = svc.CloseBreakoutRooms(ctx, &service.CloseBreakoutRoomsRequest{Room: "class"})
+		require.NoError(t, err)
+		require.Len(t, client.moves, 3)
+		for _, move := range client.moves {
+			require.Equal(t, "class", move.to)
+		}
+		participants, err := store.ListParticipants(ctx, "class")
+		require.NoError(t, err)
+		require.Len(t, participants, 4)
+		require.Equal(t, 2, roomClient.DeleteRoomCallCount())
+
+		_, err = svc.CloseBreakoutRooms(ctx, &service.CloseBreakoutRoomsRequest{Room: "class"})
+		require.ErrorIs(t, err, service.ErrBreakoutRoomsNotFound)
+
+		require.Equal(t, []string{
+			service.EventBreakoutRoomsCreated,
+			service.EventParticipantMoved,
+			service.EventParticipantMoved,
+			service.EventParticipantMoved,
+			service.EventParticipantMoved,
+			service.EventParticipantMoved,
+			service.EventParticipantMoved,
+			service.EventParticipantMoved,
+			service.EventBreakoutRoomsClosed,
+		}, events())
+	})
+}
+
+// identityTopicFormatter uses room names as topics, so that moves can be traced back to rooms
+type identityTopicFormatter struct {
+	rpc.TopicFormatter
+}
+
+func (identityTopicFormatter) RoomTopic(_ context.Context, roomName livekit.RoomName) rpc.RoomTopic {
+	return rpc.RoomTopic(roomName)
+}
+
+func (identityTopicFormatter) ParticipantTopic(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) rpc.ParticipantTopic {
+	return rpc.ParticipantTopic(string(roomName) + "/" + string(identity))
+}
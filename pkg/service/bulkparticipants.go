@@ -0,0 +1,408 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	bulkParticipantsRPCService = "BulkParticipants"
+	bulkParticipantsRPC        = "BulkParticipants"
+
+	bulkParticipantsMuteAll        = "mute_all"
+	bulkParticipantsRemove         = "remove"
+	bulkParticipantsUpdateMetadata = "update_metadata"
+
+	maxBulkParticipantsRequest = 256 * 1024
+)
+
+// bulkParticipantsCommand is carried as JSON in the payload of a user data packet, the response carries
+// a BulkParticipantsResponse as JSON
+type bulkParticipantsCommand struct {
+	Action string            `json:"action"`
+	Filter ParticipantFilter `json:"filter"`
+	// Actor is the identity of the moderating participant, empty for room admins
+	Actor      string            `json:"actor,omitempty"`
+	Sources    []string          `json:"sources,omitempty"`
+	Metadata   string            `json:"metadata,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+func (c *bulkParticipantsCommand) roleAction() rtc.RoleAction {
+	if c.Action == bulkParticipantsRemove {
+		return rtc.RoleActionRemoveOthers
+	}
+	return rtc.RoleActionMuteOthers
+}
+
+// BulkParticipantsClient reaches the node hosting a room to apply an operation to many of its participants
+type BulkParticipantsClient interface {
+	BulkParticipants(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error)
+}
+
+type BulkParticipantsServerImpl interface {
+	UpdateParticipants(ctx context.Context, roomName livekit.RoomName, cmd *bulkParticipantsCommand) (*BulkParticipantsResponse, error)
+}
+
+type bulkParticipantsClient struct {
+	client *client.RPCClient
+}
+
+func NewBulkParticipantsClient(params rpc.ClientParams) (BulkParticipantsClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: bulkParticipantsRPCService,
+		ID:   rand.NewClientID(),
+	}
+	sd.RegisterMethod(bulkParticipantsRPC, false, false, true, true)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &bulkParticipantsClient{client: rpcClient}, nil
+}
+
+func (c *bulkParticipantsClient) BulkParticipants(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return client.RequestSingle[*livekit.DataPacket](ctx, c.client, bulkParticipantsRPC, []string{string(room)}, req, opts...)
+}
+
+// bulkParticipantsServer handles bulk operations for a room hosted on this node
+type bulkParticipantsServer struct {
+	svc      BulkParticipantsServerImpl
+	roomName livekit.RoomName
+	rpc      *server.RPCServer
+}
+
+func newBulkParticipantsServer(svc BulkParticipantsServerImpl, roomName livekit.RoomName, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *bulkParticipantsServer {
+	sd := &info.ServiceDefinition{
+		Name: bulkParticipantsRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	sd.RegisterMethod(bulkParticipantsRPC, false, false, true, true)
+	return &bulkParticipantsServer{
+		svc:      svc,
+		roomName: roomName,
+		rpc:      s,
+	}
+}
+
+func (s *bulkParticipantsServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	return server.RegisterHandler(s.rpc, bulkParticipantsRPC, []string{string(room)}, s.handle, nil)
+}
+
+func (s *bulkParticipantsServer) handle(ctx context.Context, req *livekit.DataPacket) (*livekit.DataPacket, error) {
+	var cmd bulkParticipantsCommand
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &cmd); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+	res, err := s.svc.UpdateParticipants(ctx, s.roomName, &cmd)
+	if err != nil {
+		return nil, err
+	}
+	return encodeBulkParticipants(res)
+}
+
+func (s *bulkParticipantsServer) Kill() {
+	s.rpc.Close(true)
+}
+
+func encodeBulkParticipants(v any) (*livekit.DataPacket, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{Payload: payload},
+		},
+	}, nil
+}
+
+// ---------------------------------------------
+
+// ParticipantFilter selects the participants of a room targeted by a bulk operation. Identities selects
+// participants explicitly and cannot be combined with the other fields, which select every participant
+// matching all of them. Participants selected by the other fields never include the caller.
+type ParticipantFilter struct {
+	Identities []string `json:"identities,omitempty"`
+	// Kinds are ParticipantInfo kind names, e.g. STANDARD or SIP
+	Kinds            []string          `json:"kinds,omitempty"`
+	Attributes       map[string]string `json:"attributes,omitempty"`
+	ExceptIdentities []string          `json:"except_identities,omitempty"`
+	ExceptRoles      []string          `json:"except_roles,omitempty"`
+}
+
+func (f *ParticipantFilter) validate() error {
+	if len(f.Identities) != 0 && (len(f.Kinds) != 0 || len(f.Attributes) != 0 || len(f.ExceptIdentities) != 0 || len(f.ExceptRoles) != 0) {
+		return fmt.Errorf("%w: identities cannot be combined with a filter", ErrBulkParticipantsInvalid)
+	}
+	for _, kind := range f.Kinds {
+		if _, ok := livekit.ParticipantInfo_Kind_value[kind]; !ok {
+			return fmt.Errorf("%w: unknown participant kind %s", ErrBulkParticipantsInvalid, kind)
+		}
+	}
+	for _, role := range f.ExceptRoles {
+		if !rtc.ParticipantRole(role).IsValid() {
+			return fmt.Errorf("%w: unknown role %s", ErrBulkParticipantsInvalid, role)
+		}
+	}
+	return nil
+}
+
+// Matches returns true when the participant matches the filter fields other than identities
+func (f *ParticipantFilter) Matches(p types.LocalParticipant) bool {
+	if len(f.Kinds) != 0 && !slices.Contains(f.Kinds, p.Kind().String()) {
+		return false
+	}
+	if slices.Contains(f.ExceptIdentities, string(p.Identity())) {
+		return false
+	}
+
+	var attributes map[string]string
+	if grants := p.ClaimGrants(); grants != nil {
+		attributes = grants.Attributes
+	}
+	for k, v := range f.Attributes {
+		if value, ok := attributes[k]; !ok || value != v {
+			return false
+		}
+	}
+	return !slices.Contains(f.ExceptRoles, string(rtc.RoleFromAttributes(attributes)))
+}
+
+// BulkParticipantsResponse lists the participants an operation was applied to, and the ones it failed for
+type BulkParticipantsResponse struct {
+	Succeeded []string                  `json:"succeeded"`
+	Failed    []*BulkParticipantFailure `json:"failed,omitempty"`
+}
+
+type BulkParticipantFailure struct {
+	Identity string `json:"identity"`
+	Error    string `json:"error"`
+}
+
+func (r *BulkParticipantsResponse) fail(identity livekit.ParticipantIdentity, err error) {
+	r.Failed = append(r.Failed, &BulkParticipantFailure{Identity: string(identity), Error: err.Error()})
+}
+
+// MuteAllParticipantsRequest is the JSON body of POST /bulk_participants/mute_all. Sources are TrackSource
+// names, e.g. MICROPHONE, every published track is muted when empty.
+type MuteAllParticipantsRequest struct {
+	Room string `json:"room"`
+	ParticipantFilter
+	Sources []string `json:"sources,omitempty"`
+}
+
+// RemoveParticipantsRequest is the JSON body of POST /bulk_participants/remove
+type RemoveParticipantsRequest struct {
+	Room string `json:"room"`
+	ParticipantFilter
+}
+
+// UpdateParticipantsMetadataRequest is the JSON body of POST /bulk_participants/update_metadata, attributes
+// are merged into the ones of each participant
+type UpdateParticipantsMetadataRequest struct {
+	Room string `json:"room"`
+	ParticipantFilter
+	Metadata   string            `json:"metadata,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// BulkParticipantsService serves the HTTP API applying an operation to many participants of a room in a
+// single pass on the node hosting it. Muting and removing require roomAdmin, or a participant token of the
+// room whose role may moderate the selected participants. Updating metadata requires roomAdmin.
+type BulkParticipantsService struct {
+	limitConf      config.LimitConfig
+	roomStore      ServiceStore
+	topicFormatter rpc.TopicFormatter
+	client         BulkParticipantsClient
+}
+
+func NewBulkParticipantsService(
+	limitConf config.LimitConfig,
+	roomStore ServiceStore,
+	topicFormatter rpc.TopicFormatter,
+	client BulkParticipantsClient,
+) *BulkParticipantsService {
+	return &BulkParticipantsService{
+		limitConf:      limitConf,
+		roomStore:      roomStore,
+		topicFormatter: topicFormatter,
+		client:         client,
+	}
+}
+
+func (s *BulkParticipantsService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res *BulkParticipantsResponse
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/bulk_participants/") {
+	case bulkParticipantsMuteAll:
+		var req MuteAllParticipantsRequest
+		if err = decodeJSONRequest(r, &req, maxBulkParticipantsRequest); err == nil {
+			res, err = s.MuteAllParticipants(r.Context(), &req)
+		}
+	case bulkParticipantsRemove:
+		var req RemoveParticipantsRequest
+		if err = decodeJSONRequest(r, &req, maxBulkParticipantsRequest); err == nil {
+			res, err = s.RemoveParticipants(r.Context(), &req)
+		}
+	case bulkParticipantsUpdateMetadata:
+		var req UpdateParticipantsMetadataRequest
+		if err = decodeJSONRequest(r, &req, maxBulkParticipantsRequest); err == nil {
+			res, err = s.UpdateParticipantsMetadata(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// MuteAllParticipants mutes the published tracks of the selected participants
+func (s *BulkParticipantsService) MuteAllParticipants(ctx context.Context, req *MuteAllParticipantsRequest) (*BulkParticipantsResponse, error) {
+	for _, source := range req.Sources {
+		if _, ok := livekit.TrackSource_value[source]; !ok {
+			return nil, fmt.Errorf("%w: unknown track source %s", ErrBulkParticipantsInvalid, source)
+		}
+	}
+	return s.send(ctx, livekit.RoomName(req.Room), &bulkParticipantsCommand{
+		Action:  bulkParticipantsMuteAll,
+		Filter:  req.ParticipantFilter,
+		Sources: req.Sources,
+	})
+}
+
+// RemoveParticipants disconnects the selected participants from the room
+func (s *BulkParticipantsService) RemoveParticipants(ctx context.Context, req *RemoveParticipantsRequest) (*BulkParticipantsResponse, error) {
+	return s.send(ctx, livekit.RoomName(req.Room), &bulkParticipantsCommand{
+		Action: bulkParticipantsRemove,
+		Filter: req.ParticipantFilter,
+	})
+}
+
+// UpdateParticipantsMetadata sets the metadata and attributes of the selected participants
+func (s *BulkParticipantsService) UpdateParticipantsMetadata(ctx context.Context, req *UpdateParticipantsMetadataRequest) (*BulkParticipantsResponse, error) {
+	if req.Metadata == "" && len(req.Attributes) == 0 {
+		return nil, fmt.Errorf("%w: metadata or attributes are required", ErrBulkParticipantsInvalid)
+	}
+	if !s.limitConf.CheckMetadataSize(req.Metadata) {
+		return nil, fmt.Errorf("%w: max size %d", ErrMetadataExceedsLimits, s.limitConf.MaxMetadataSize)
+	}
+	if !s.limitConf.CheckAttributesSize(req.Attributes) {
+		return nil, fmt.Errorf("%w: max size %d", ErrAttributeExceedsLimits, s.limitConf.MaxAttributesSize)
+	}
+	if err := rtc.ValidateDeviceAttributes(req.Attributes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBulkParticipantsInvalid, err)
+	}
+	if _, ok := req.Attributes[rtc.RoleAttribute]; ok {
+		return nil, fmt.Errorf("%w: %v", ErrBulkParticipantsInvalid, rtc.ErrRoleAttributeNotAllowed)
+	}
+	if _, ok := req.Attributes[rtc.RecordingConsentAttribute]; ok {
+		return nil, fmt.Errorf("%w: %v", ErrBulkParticipantsInvalid, rtc.ErrConsentAttributeNotAllowed)
+	}
+	return s.send(ctx, livekit.RoomName(req.Room), &bulkParticipantsCommand{
+		Action:     bulkParticipantsUpdateMetadata,
+		Filter:     req.ParticipantFilter,
+		Metadata:   req.Metadata,
+		Attributes: req.Attributes,
+	})
+}
+
+func (s *BulkParticipantsService) send(ctx context.Context, roomName livekit.RoomName, cmd *bulkParticipantsCommand) (*BulkParticipantsResponse, error) {
+	AppendLogFields(ctx, "room", roomName, "action", cmd.Action)
+	if err := cmd.Filter.validate(); err != nil {
+		return nil, err
+	}
+	actor, err := s.ensurePermission(ctx, roomName, cmd)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Actor = string(actor)
+
+	req, err := encodeBulkParticipants(cmd)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.BulkParticipants(ctx, s.topicFormatter.RoomTopic(ctx, roomName), req)
+	if err != nil {
+		return nil, err
+	}
+	var resp BulkParticipantsResponse
+	if err = json.Unmarshal(res.GetUser().GetPayload(), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ensurePermission returns the identity of the moderating participant, empty for room admins. The node
+// hosting the room checks the role of the moderator against each selected participant.
+func (s *BulkParticipantsService) ensurePermission(ctx context.Context, roomName livekit.RoomName, cmd *bulkParticipantsCommand) (livekit.ParticipantIdentity, error) {
+	err := EnsureAdminPermission(ctx, roomName)
+	if err == nil {
+		_, _, err = s.roomStore.LoadRoom(ctx, roomName, false)
+		return "", err
+	}
+	if cmd.Action == bulkParticipantsUpdateMetadata {
+		return "", err
+	}
+
+	claims := GetGrants(ctx)
+	if claims == nil || claims.Video == nil || claims.Identity == "" || roomName != livekit.RoomName(claims.Video.Room) {
+		return "", ErrPermissionDenied
+	}
+	actor, err := s.roomStore.LoadParticipant(ctx, roomName, livekit.ParticipantIdentity(claims.Identity))
+	if err != nil {
+		return "", ErrPermissionDenied
+	}
+	if !rtc.RoleFromAttributes(actor.Attributes).Permissions().Allows(cmd.roleAction()) {
+		return "", ErrPermissionDenied
+	}
+	return livekit.ParticipantIdentity(claims.Identity), nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+// testBulkParticipantsClient answers every command with the response it was given
+type testBulkParticipantsClient struct {
+	commands []map[string]any
+	res      *service.BulkParticipantsResponse
+}
+
+func (c *testBulkParticipantsClient) BulkParticipants(_ context.Context, _ rpc.RoomTopic, req *livekit.DataPacket, _ ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	var cmd map[string]any
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &cmd); err != nil {
+		return nil, err
+	}
+	c.commands = append(c.commands, cmd)
+	payload, err := json.Marshal(c.res)
+	if err != nil {
+		return nil, err
+	}
+	return &livekit.DataPacket{Value: &livekit.DataPacket_User{User: &livekit.UserPacket{Payload: payload}}}, nil
+}
+
+func TestBulkParticipants(t *testing.T) {
+	store := service.NewLocalStore()
+	client := &testBulkParticipantsClient{res: &service.BulkParticipantsResponse{Succeeded: []string{"viewer"}}}
+	svc := service.NewBulkParticipantsService(config.LimitConfig{MaxMetadataSize: 16}, store, rpc.NewTopicFormatter(), client)
+
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomAdmin: true, Room: "webinar"},
+	}, "")
+	require.NoError(t, store.StoreRoom(ctx, &livekit.Room{Name: "webinar"}, nil))
+	require.NoError(t, store.StoreParticipant(ctx, "webinar", &livekit.ParticipantInfo{
+		Identity:   "host",
+		Attributes: map[string]string{rtc.RoleAttribute: string(rtc.ParticipantRoleHost)},
+	}))
+	require.NoError(t, store.StoreParticipant(ctx, "webinar", &livekit.ParticipantInfo{
+		Identity:   "speaker",
+		Attributes: map[string]string{rtc.RoleAttribute: string(rtc.ParticipantRoleSpeaker)},
+	}))
+	participantCtx := func(identity string) context.Context {
+		return service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Identity: identity,
+			Video:    &auth.VideoGrant{RoomJoin: true, Room: "webinar"},
+		}, "")
+	}
+
+	t.Run("validates requests", func(t *testing.T) {
+		_, err := svc.RemoveParticipants(ctx, &service.RemoveParticipantsRequest{
+			Room:              "webinar",
+			ParticipantFilter: service.ParticipantFilter{Identities: []string{"a"}, ExceptRoles: []string{"host"}},
+		})
+		require.ErrorIs(t, err, service.ErrBulkParticipantsInvalid)
+
+		_, err = svc.MuteAllParticipants(ctx, &service.MuteAllParticipantsRequest{Room: "webinar", Sources: []string{"webcam"}})
+		require.ErrorIs(t, err, service.ErrBulkParticipantsInvalid)
+
+		_, err = svc.UpdateParticipantsMetadata(ctx, &service.UpdateParticipantsMetadataRequest{Room: "webinar"})
+		require.ErrorIs(t, err, service.ErrBulkParticipantsInvalid)
+
+		_, err = svc.UpdateParticipantsMetadata(ctx, &service.UpdateParticipantsMetadataRequest{Room: "webinar", Metadata: "too long for the limit"})
+		require.ErrorIs(t, err, service.ErrMetadataExceedsLimits)
+
+		_, err = svc.UpdateParticipantsMetadata(ctx, &service.UpdateParticipantsMetadataRequest{
+			Room:       "webinar",
+			Attributes: map[string]string{rtc.RoleAttribute: "host"},
+		})
+		require.ErrorIs(t, err, service.ErrBulkParticipantsInvalid)
+		require.Empty(t, client.commands)
+	})
+
+	t.Run("admins", func(t *testing.T) {
+		res, err := svc.MuteAllParticipants(ctx, &service.MuteAllParticipantsRequest{
+			Room:              "webinar",
+			ParticipantFilter: service.ParticipantFilter{ExceptRoles: []string{"host", "co_host"}},
+			Sources:           []string{"MICROPHONE"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"viewer"}, res.Succeeded)
+		require.Equal(t, "mute_all", client.commands[0]["action"])
+		require.Nil(t, client.commands[0]["actor"])
+	})
+
+	t.Run("moderators", func(t *testing.T) {
+		_, err := svc.RemoveParticipants(participantCtx("speaker"), &service.RemoveParticipantsRequest{Room: "webinar"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+
+		_, err = svc.UpdateParticipantsMetadata(participantCtx("host"), &service.UpdateParticipantsMetadataRequest{Room: "webinar", Metadata: "m"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+
+		_, err = svc.RemoveParticipants(participantCtx("host"), &service.RemoveParticipantsRequest{Room: "webinar"})
+		require.NoError(t, err)
+		require.Equal(t, "host", client.commands[len(client.commands)-1]["actor"])
+	})
+}
+
+func TestParticipantFilter(t *testing.T) {
+	participant := func(identity string, kind livekit.ParticipantInfo_Kind, attributes map[string]string) *typesfakes.FakeLocalParticipant {
+		p := &typesfakes.FakeLocalParticipant{}
+		p.IdentityReturns(livekit.ParticipantIdentity(identity))
+		p.KindReturns(kind)
+		p.ClaimGrantsReturns(&auth.ClaimGrants{Identity: identity, Attributes: attributes})
+		return p
+	}
+	host := participant("host", livekit.ParticipantInfo_STANDARD, map[string]string{rtc.RoleAttribute: "host"})
+	viewer := participant("viewer", livekit.ParticipantInfo_STANDARD, map[string]string{rtc.RoleAttribute: "viewer", "team": "blue"})
+	phone := participant("phone", livekit.ParticipantInfo_SIP, nil)
+
+	all := &service.ParticipantFilter{}
+	require.True(t, all.Matches(host))
+	require.True(t, all.Matches(phone))
+
+	exceptHosts := &service.ParticipantFilter{ExceptRoles: []string{"host"}}
+	require.False(t, exceptHosts.Matches(host))
+	require.True(t, exceptHosts.Matches(viewer))
+	require.True(t, exceptHosts.Matches(phone))
+
+	sip := &service.ParticipantFilter{Kinds: []string{"SIP"}}
+	require.False(t, sip.Matches(viewer))
+	require.True(t, sip.Matches(phone))
+
+	blue := &service.ParticipantFilter{Attributes: map[string]string{"team": "blue"}, ExceptIdentities: []string{"host"}}
+	require.True(t, blue.Matches(viewer))
+	require.False(t, blue.Matches(phone))
+	require.False(t, blue.Matches(host))
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// ClientAddresses finds the address of the client of a request for decisions clients must not be able to
+// influence, like bans, rate limits and address filters. Unlike GetClientIP, forwarding headers are only read
+// on requests coming from the configured trusted proxies, the address of the peer is used otherwise.
+type ClientAddresses struct {
+	proxies []*net.IPNet
+	// the proxies set CF-Connecting-IP and X-Real-IP, most proxies forward them from clients unchanged
+	ipHeaders bool
+}
+
+func NewClientAddresses(conf *config.Config) *ClientAddresses {
+	c := &ClientAddresses{ipHeaders: conf.TrustProxyIPHeaders}
+	// networks are checked when the config is loaded
+	for _, cidr := range conf.TrustedProxies {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			c.proxies = append(c.proxies, network)
+		}
+	}
+	return c
+}
+
+func (c *ClientAddresses) trusted(ip net.IP) bool {
+	for _, network := range c.proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func peerIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// FromTrustedProxy returns true when the request was forwarded by a trusted proxy, whose headers can be used
+func (c *ClientAddresses) FromTrustedProxy(r *http.Request) bool {
+	ip := peerIP(r)
+	return ip != nil && c.trusted(ip)
+}
+
+// ClientIP returns the address of the client, nil when the address of the peer cannot be parsed
+func (c *ClientAddresses) ClientIP(r *http.Request) net.IP {
+	peer := peerIP(r)
+	if peer == nil || !c.trusted(peer) {
+		return peer
+	}
+
+	if c.ipHeaders {
+		// CF proxy typically is first thing the user reaches
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("CF-Connecting-IP"))); ip != nil {
+			return ip
+		}
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+			return ip
+		}
+	}
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) != 0 {
+		// proxies append the address they received the request from, the client is the last address which is
+		// not one of the trusted proxies. Addresses before it could have been set by the client.
+		chain := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(chain) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(chain[i]))
+			if ip == nil {
+				break
+			}
+			if !c.trusted(ip) || i == 0 {
+				return ip
+			}
+		}
+	}
+	return peer
+}
+
+// ClientIPString returns the address of the client in its canonical form, used as a key of counters and bans
+func (c *ClientAddresses) ClientIPString(r *http.Request) string {
+	if ip := c.ClientIP(r); ip != nil {
+		return ip.String()
+	}
+	return r.RemoteAddr
+}
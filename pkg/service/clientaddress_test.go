@@ -0,0 +1,104 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestClientAddresses(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		peer      string
+		headers   map[string]string
+		ipHeaders bool
+		want      string
+	}{
+		{
+			name: "direct",
+			peer: "203.0.113.1:5000",
+			want: "203.0.113.1",
+		},
+		{
+			name:    "headers of an untrusted peer",
+			peer:    "203.0.113.1:5000",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2", "CF-Connecting-IP": "198.51.100.3"},
+			want:    "203.0.113.1",
+		},
+		{
+			name:      "cloudflare",
+			peer:      "10.0.0.1:5000",
+			headers:   map[string]string{"CF-Connecting-IP": "198.51.100.3", "X-Forwarded-For": "198.51.100.1"},
+			ipHeaders: true,
+			want:      "198.51.100.3",
+		},
+		{
+			name:      "real ip set by the proxy",
+			peer:      "10.0.0.1:5000",
+			headers:   map[string]string{"X-Real-IP": "198.51.100.2", "X-Forwarded-For": "198.51.100.1"},
+			ipHeaders: true,
+			want:      "198.51.100.2",
+		},
+		{
+			name:    "address headers forwarded from the client",
+			peer:    "10.0.0.1:5000",
+			headers: map[string]string{"CF-Connecting-IP": "192.0.2.1", "X-Real-IP": "192.0.2.2", "X-Forwarded-For": "192.0.2.3, 198.51.100.1"},
+			want:    "198.51.100.1",
+		},
+		{
+			name:    "forwarded chain",
+			peer:    "10.0.0.1:5000",
+			headers: map[string]string{"X-Forwarded-For": "192.0.2.1, 198.51.100.1, 10.0.0.2"},
+			want:    "198.51.100.1",
+		},
+		{
+			name:    "forwarded by trusted proxies only",
+			peer:    "10.0.0.1:5000",
+			headers: map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"},
+			want:    "10.0.0.3",
+		},
+		{
+			name:    "invalid forwarded address",
+			peer:    "10.0.0.1:5000",
+			headers: map[string]string{"X-Forwarded-For": "unknown", "X-Real-IP": "198.51.100.2"},
+			want:    "10.0.0.1",
+		},
+		{
+			name: "proxy without headers",
+			peer: "10.0.0.1:5000",
+			want: "10.0.0.1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			addresses := service.NewClientAddresses(&config.Config{
+				TrustedProxies:      []string{"10.0.0.0/8"},
+				TrustProxyIPHeaders: tc.ipHeaders,
+			})
+			r := httptest.NewRequest(http.MethodGet, "/rtc", nil)
+			r.RemoteAddr = tc.peer
+			for name, value := range tc.headers {
+				r.Header.Set(name, value)
+			}
+			require.Equal(t, tc.want, addresses.ClientIPString(r))
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/archiver"
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	clientDiagnosticsPrefix = "DG_"
+
+	maxClientDiagnosticsRequest = 4 * 1024
+	maxClientDiagnosticsReason  = 256
+)
+
+// ClientDiagnosticsRequest is the JSON body of POST /client_diagnostics/request
+type ClientDiagnosticsRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	// passed on to the client, e.g. the support ticket the diagnostics are requested for
+	Reason string `json:"reason,omitempty"`
+}
+
+// ClientDiagnosticsUpload tells where the diagnostics of the participant will be stored once it uploaded them
+type ClientDiagnosticsUpload struct {
+	RequestID string `json:"request_id"`
+	Room      string `json:"room"`
+	Identity  string `json:"identity"`
+	Bucket    string `json:"bucket"`
+	Key       string `json:"key"`
+	// unix seconds, the client cannot upload past this time
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+// ClientDiagnosticsService serves the HTTP API asking the client SDK of a participant to upload its local
+// diagnostics and logs, for support investigations. The request is sent to the client by the node hosting the
+// room, on rtc.DiagnosticsUploadTopic, with a presigned URL of the configured bucket. Clients report the outcome
+// on the same topic, which is sent in the client_diagnostics_uploaded and client_diagnostics_failed webhooks.
+// Calls require roomAdmin.
+type ClientDiagnosticsService struct {
+	conf           config.ClientDiagnosticsConfig
+	store          *archiver.S3Store
+	roomStore      ServiceStore
+	topicFormatter rpc.TopicFormatter
+	client         RoomDebugClient
+}
+
+func NewClientDiagnosticsService(
+	conf *config.Config,
+	roomStore ServiceStore,
+	topicFormatter rpc.TopicFormatter,
+	client RoomDebugClient,
+) *ClientDiagnosticsService {
+	s := &ClientDiagnosticsService{
+		conf:           conf.ClientDiagnostics,
+		roomStore:      roomStore,
+		topicFormatter: topicFormatter,
+		client:         client,
+	}
+	if conf.ClientDiagnostics.S3.Bucket != "" {
+		s.store = archiver.NewS3Store(conf.ClientDiagnostics.S3)
+	}
+	return s
+}
+
+func (s *ClientDiagnosticsService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	if strings.TrimPrefix(r.URL.Path, "/client_diagnostics/") != "request" {
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	var req ClientDiagnosticsRequest
+	err := decodeJSONRequest(r, &req, maxClientDiagnosticsRequest)
+	var res *ClientDiagnosticsUpload
+	if err == nil {
+		res, err = s.RequestUpload(r.Context(), &req)
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// RequestUpload sends an upload request with a presigned URL to the participant, through the node hosting its
+// room. It returns once the request was sent, the client uploads in the background.
+func (s *ClientDiagnosticsService) RequestUpload(ctx context.Context, req *ClientDiagnosticsRequest) (*ClientDiagnosticsUpload, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName, "participant", req.Identity)
+	if err := EnsureAdminPermission(ctx, roomName); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrClientDiagnosticsDisabled
+	}
+	switch {
+	case req.Identity == "":
+		return nil, fmt.Errorf("%w: identity is required", ErrClientDiagnosticsInvalid)
+	case len(req.Reason) > maxClientDiagnosticsReason:
+		return nil, fmt.Errorf("%w: reason is longer than %d bytes", ErrClientDiagnosticsInvalid, maxClientDiagnosticsReason)
+	}
+	if _, _, err := s.roomStore.LoadRoom(ctx, roomName, false); err != nil {
+		return nil, err
+	}
+
+	upload := &ClientDiagnosticsUpload{
+		RequestID: guid.New(clientDiagnosticsPrefix),
+		Room:      req.Room,
+		Identity:  req.Identity,
+		Bucket:    s.conf.S3.Bucket,
+		ExpiresAt: time.Now().Add(s.conf.URLExpiry).Unix(),
+	}
+	// escaped so that names cannot add path segments
+	upload.Key = fmt.Sprintf("%s%s/%s/%s", s.conf.S3.Prefix, url.PathEscape(req.Room), url.PathEscape(req.Identity), upload.RequestID)
+	u, err := s.store.PresignPut(upload.Key, s.conf.URLExpiry)
+	if err != nil {
+		return nil, err
+	}
+	AppendLogFields(ctx, "requestID", upload.RequestID)
+
+	dp, err := jsonDataPacket(&clientDiagnosticsRPCRequest{
+		Identity: livekit.ParticipantIdentity(req.Identity),
+		DiagnosticsUploadRequest: &rtc.DiagnosticsUploadRequest{
+			RequestID: upload.RequestID,
+			URL:       u,
+			Method:    http.MethodPut,
+			ExpiresAt: upload.ExpiresAt,
+			Reason:    req.Reason,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = s.client.ClientDiagnostics(ctx, s.topicFormatter.RoomTopic(ctx, roomName), dp); err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
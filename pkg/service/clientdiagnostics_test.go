@@ -0,0 +1,110 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestClientDiagnosticsService(t *testing.T) {
+	store := service.NewLocalStore()
+	client := &testRoomDebugClient{}
+	conf := &config.Config{ClientDiagnostics: config.ClientDiagnosticsConfig{
+		S3: config.RoomArchiveConfig{
+			Bucket:    "diagnostics",
+			Region:    "us-east-1",
+			AccessKey: "key",
+			Secret:    "secret",
+			Prefix:    "support/",
+		},
+		URLExpiry: 15 * time.Minute,
+	}}
+	svc := service.NewClientDiagnosticsService(conf, store, rpc.NewTopicFormatter(), client)
+
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomAdmin: true, Room: "support"},
+	}, "")
+	require.NoError(t, store.StoreRoom(ctx, &livekit.Room{Name: "support"}, nil))
+
+	t.Run("sent to the participant with a presigned url", func(t *testing.T) {
+		upload, err := svc.RequestUpload(ctx, &service.ClientDiagnosticsRequest{
+			Room:     "support",
+			Identity: "user/1",
+			Reason:   "ticket 42",
+		})
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(upload.RequestID, "DG_"))
+		require.Equal(t, "diagnostics", upload.Bucket)
+		require.Equal(t, "support/support/user%2F1/"+upload.RequestID, upload.Key)
+		require.WithinDuration(t, time.Now().Add(15*time.Minute), time.Unix(upload.ExpiresAt, 0), 2*time.Second)
+
+		require.Len(t, client.diagnostics, 1)
+		var sent struct {
+			Identity  string `json:"identity"`
+			RequestID string `json:"request_id"`
+			URL       string `json:"url"`
+			Method    string `json:"method"`
+			Reason    string `json:"reason"`
+		}
+		require.NoError(t, json.Unmarshal(client.diagnostics[0].GetUser().GetPayload(), &sent))
+		require.Equal(t, "user/1", sent.Identity)
+		require.Equal(t, upload.RequestID, sent.RequestID)
+		require.Equal(t, "PUT", sent.Method)
+		require.Equal(t, "ticket 42", sent.Reason)
+
+		u, err := url.Parse(sent.URL)
+		require.NoError(t, err)
+		require.Equal(t, "diagnostics.s3.us-east-1.amazonaws.com", u.Host)
+		require.Equal(t, "900", u.Query().Get("X-Amz-Expires"))
+		require.NotEmpty(t, u.Query().Get("X-Amz-Signature"))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := svc.RequestUpload(ctx, &service.ClientDiagnosticsRequest{Room: "support"})
+		require.ErrorIs(t, err, service.ErrClientDiagnosticsInvalid)
+		_, err = svc.RequestUpload(ctx, &service.ClientDiagnosticsRequest{Room: "support", Identity: "user", Reason: strings.Repeat("x", 300)})
+		require.ErrorIs(t, err, service.ErrClientDiagnosticsInvalid)
+		_, err = svc.RequestUpload(ctx, &service.ClientDiagnosticsRequest{Room: "missing", Identity: "user"})
+		require.Error(t, err)
+	})
+
+	t.Run("requires room admin", func(t *testing.T) {
+		other := service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomJoin: true, Room: "support"},
+		}, "")
+		_, err := svc.RequestUpload(other, &service.ClientDiagnosticsRequest{Room: "support", Identity: "user"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+	})
+
+	t.Run("disabled without a bucket", func(t *testing.T) {
+		disabled := service.NewClientDiagnosticsService(&config.Config{}, store, rpc.NewTopicFormatter(), client)
+		_, err := disabled.RequestUpload(ctx, &service.ClientDiagnosticsRequest{Room: "support", Identity: "user"})
+		require.ErrorIs(t, err, service.ErrClientDiagnosticsDisabled)
+	})
+}
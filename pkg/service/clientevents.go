@@ -0,0 +1,198 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+const (
+	clientEventsRPCService = "ClientEvents"
+	recordClientEventsRPC  = "RecordClientEvents"
+
+	maxClientEventsRequest = 32 * 1024
+)
+
+// ClientEventsClient reaches the node hosting a room, where the events of a participant are aggregated with
+// the stats of its session. Events are carried as rtc.ClientEvents JSON in the payload of a user data packet
+// on rtc.ClientEventsTopic, from the participant reporting them.
+type ClientEventsClient interface {
+	RecordClientEvents(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error)
+}
+
+type ClientEventsServerImpl interface {
+	RecordClientEvents(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, events []*telemetry.ClientEvent) error
+}
+
+type clientEventsClient struct {
+	client *client.RPCClient
+}
+
+func NewClientEventsClient(params rpc.ClientParams) (ClientEventsClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: clientEventsRPCService,
+		ID:   rand.NewClientID(),
+	}
+	sd.RegisterMethod(recordClientEventsRPC, false, false, true, true)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &clientEventsClient{client: rpcClient}, nil
+}
+
+func (c *clientEventsClient) RecordClientEvents(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return client.RequestSingle[*livekit.DataPacket](ctx, c.client, recordClientEventsRPC, []string{string(room)}, req, opts...)
+}
+
+// clientEventsServer records the events of participants of a room hosted on this node
+type clientEventsServer struct {
+	svc      ClientEventsServerImpl
+	roomName livekit.RoomName
+	rpc      *server.RPCServer
+}
+
+func newClientEventsServer(svc ClientEventsServerImpl, roomName livekit.RoomName, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *clientEventsServer {
+	sd := &info.ServiceDefinition{
+		Name: clientEventsRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	sd.RegisterMethod(recordClientEventsRPC, false, false, true, true)
+	return &clientEventsServer{
+		svc:      svc,
+		roomName: roomName,
+		rpc:      s,
+	}
+}
+
+func (s *clientEventsServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	return server.RegisterHandler(s.rpc, recordClientEventsRPC, []string{string(room)}, s.record, nil)
+}
+
+func (s *clientEventsServer) record(ctx context.Context, req *livekit.DataPacket) (*livekit.DataPacket, error) {
+	var report rtc.ClientEvents
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &report); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+	if err := s.svc.RecordClientEvents(ctx, s.roomName, livekit.ParticipantIdentity(req.ParticipantIdentity), report.Events); err != nil {
+		return nil, err
+	}
+	return &livekit.DataPacket{}, nil
+}
+
+func (s *clientEventsServer) Kill() {
+	s.rpc.Close(true)
+}
+
+// ---------------------------------------------
+
+// RecordClientEventsRequest is the JSON body of POST /client_events/record
+type RecordClientEventsRequest struct {
+	Room     string                   `json:"room"`
+	Identity string                   `json:"identity"`
+	Events   []*telemetry.ClientEvent `json:"events"`
+}
+
+// ClientEventsService serves the HTTP API clients report quality of experience events with, for clients
+// which cannot send them on rtc.ClientEventsTopic, e.g. after leaving the room. Events are aggregated with
+// the stats the server measures for the session of the participant. Calls require the token of the
+// participant itself.
+type ClientEventsService struct {
+	topicFormatter rpc.TopicFormatter
+	client         ClientEventsClient
+}
+
+func NewClientEventsService(
+	topicFormatter rpc.TopicFormatter,
+	client ClientEventsClient,
+) *ClientEventsService {
+	return &ClientEventsService{
+		topicFormatter: topicFormatter,
+		client:         client,
+	}
+}
+
+func (s *ClientEventsService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	if strings.TrimPrefix(r.URL.Path, "/client_events/") != "record" {
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	var req RecordClientEventsRequest
+	if err := decodeJSONRequest(r, &req, maxClientEventsRequest); err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+	if err := s.RecordClientEvents(r.Context(), &req); err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *ClientEventsService) RecordClientEvents(ctx context.Context, req *RecordClientEventsRequest) error {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName, "participant", req.Identity, "events", len(req.Events))
+	if req.Identity == "" {
+		return ErrIdentityEmpty
+	}
+	// participants report for themselves
+	claims := GetGrants(ctx)
+	if claims == nil || claims.Video == nil || claims.Identity != req.Identity || claims.Video.Room != req.Room {
+		return ErrPermissionDenied
+	}
+	if err := telemetry.ValidateClientEvents(req.Events); err != nil {
+		return psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+
+	payload, err := json.Marshal(&rtc.ClientEvents{Events: req.Events})
+	if err != nil {
+		return err
+	}
+	topic := rtc.ClientEventsTopic
+	_, err = s.client.RecordClientEvents(ctx, s.topicFormatter.RoomTopic(ctx, roomName), &livekit.DataPacket{
+		Kind:                livekit.DataPacket_RELIABLE,
+		ParticipantIdentity: req.Identity,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload: payload,
+				Topic:   &topic,
+			},
+		},
+	})
+	return err
+}
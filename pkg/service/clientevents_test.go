@@ -0,0 +1,83 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+type testClientEventsClient struct {
+	identities []string
+	reports    []*rtc.ClientEvents
+}
+
+func (c *testClientEventsClient) RecordClientEvents(_ context.Context, _ rpc.RoomTopic, req *livekit.DataPacket, _ ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	var report rtc.ClientEvents
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &report); err != nil {
+		return nil, err
+	}
+	c.identities = append(c.identities, req.ParticipantIdentity)
+	c.reports = append(c.reports, &report)
+	return &livekit.DataPacket{}, nil
+}
+
+func TestClientEvents(t *testing.T) {
+	client := &testClientEventsClient{}
+	svc := service.NewClientEventsService(rpc.NewTopicFormatter(), client)
+
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Identity: "alice",
+		Video:    &auth.VideoGrant{RoomJoin: true, Room: "class"},
+	}, "")
+
+	t.Run("participants report for themselves", func(t *testing.T) {
+		err := svc.RecordClientEvents(ctx, &service.RecordClientEventsRequest{Room: "class", Identity: "bob"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+	})
+
+	t.Run("validates the events", func(t *testing.T) {
+		err := svc.RecordClientEvents(ctx, &service.RecordClientEventsRequest{
+			Room:     "class",
+			Identity: "alice",
+			Events:   []*telemetry.ClientEvent{{Type: telemetry.ClientEventFrozenFrame, Count: 1}},
+		})
+		require.ErrorIs(t, err, telemetry.ErrClientEventTrack)
+		require.Empty(t, client.reports)
+	})
+
+	t.Run("records on the node hosting the room", func(t *testing.T) {
+		events := []*telemetry.ClientEvent{
+			{Type: telemetry.ClientEventFrozenFrame, TrackID: "TR_video", Count: 2, DurationMs: 800},
+			{Type: telemetry.ClientEventJoinTime, DurationMs: 1200},
+		}
+		err := svc.RecordClientEvents(ctx, &service.RecordClientEventsRequest{Room: "class", Identity: "alice", Events: events})
+		require.NoError(t, err)
+		require.Equal(t, []string{"alice"}, client.identities)
+		require.Equal(t, events, client.reports[0].Events)
+	})
+}
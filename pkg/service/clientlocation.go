@@ -0,0 +1,102 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+)
+
+// latitude and longitude headers of CDNs and load balancers, in order of preference
+var geoHeaders = [][2]string{
+	{"CloudFront-Viewer-Latitude", "CloudFront-Viewer-Longitude"},
+	{"Cf-Iplatitude", "Cf-Iplongitude"},
+	{"X-Client-Latitude", "X-Client-Longitude"},
+}
+
+type clientLocationKey struct{}
+
+func withClientLocation(ctx context.Context, location *selector.ClientLocation) context.Context {
+	if location == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, clientLocationKey{}, location)
+}
+
+func clientLocationFromContext(ctx context.Context) *selector.ClientLocation {
+	location, _ := ctx.Value(clientLocationKey{}).(*selector.ClientLocation)
+	return location
+}
+
+type clientNetwork struct {
+	network *net.IPNet
+	region  string
+}
+
+// clientLocator finds where a participant connects from: the region hint of the client first, then the
+// geolocation headers of the proxy in front of the server, then the configured client networks
+type clientLocator struct {
+	geoHeaders bool
+	networks   []clientNetwork
+}
+
+func newClientLocator(conf config.LatencyAwareConfig) *clientLocator {
+	l := &clientLocator{
+		geoHeaders: conf.GeoHeaders,
+	}
+	for _, cn := range conf.ClientNetworks {
+		// networks are checked when the config is loaded
+		if _, network, err := net.ParseCIDR(cn.CIDR); err == nil {
+			l.networks = append(l.networks, clientNetwork{network: network, region: cn.Region})
+		}
+	}
+	return l
+}
+
+// Locate returns nil when nothing tells where the client is
+func (l *clientLocator) Locate(r *http.Request) *selector.ClientLocation {
+	if region := r.FormValue("region"); region != "" {
+		return &selector.ClientLocation{Region: region}
+	}
+
+	if l.geoHeaders {
+		for _, names := range geoHeaders {
+			lat, errLat := strconv.ParseFloat(r.Header.Get(names[0]), 64)
+			lon, errLon := strconv.ParseFloat(r.Header.Get(names[1]), 64)
+			if errLat == nil && errLon == nil {
+				return &selector.ClientLocation{Lat: lat, Lon: lon, HasCoordinates: true}
+			}
+		}
+	}
+
+	if len(l.networks) != 0 {
+		// the first address of a forwarded chain is the client
+		address, _, _ := strings.Cut(GetClientIP(r), ",")
+		if ip := net.ParseIP(strings.TrimSpace(address)); ip != nil {
+			for _, cn := range l.networks {
+				if cn.network.Contains(ip) {
+					return &selector.ClientLocation{Region: cn.region}
+				}
+			}
+		}
+	}
+	return nil
+}
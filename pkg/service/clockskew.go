@@ -0,0 +1,249 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+const (
+	clockSkewListInterval     = 30 * time.Second
+	defaultClockSkewThreshold = 2 * time.Second
+	// keepalive samples kept for each node, nodes send a keepalive every 2 seconds
+	clockSkewSamples = 30
+)
+
+// ClockSkewMonitor estimates the offset of the node clock from the clocks of the other nodes, from the
+// timestamps of the keepalives the nodes publish on the bus. Samples include the delay of the bus and up to a
+// second of truncation, so the offset from a node is the smallest of its recent samples, and the offset from
+// the cluster is the median of the offsets from all nodes, this node included. Nodes skewed by more than the
+// threshold are logged and counted in metrics, and when this node is, the offset is set as the clock offset of
+// the node, used to validate tokens and to timestamp stats.
+type ClockSkewMonitor struct {
+	threshold   time.Duration
+	compensate  bool
+	router      routing.Router
+	currentNode routing.LocalNode
+	kps         rpc.KeepalivePubSub
+
+	lock   sync.Mutex
+	peers  map[livekit.NodeID]*clockPeer
+	skewed bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type clockPeer struct {
+	sub     psrpc.Subscription[*rpc.KeepalivePing]
+	samples []time.Duration
+	skewed  bool
+}
+
+// NewClockSkewMonitor returns nil when the node is not part of a cluster
+func NewClockSkewMonitor(
+	conf *config.Config,
+	router routing.Router,
+	currentNode routing.LocalNode,
+	kps rpc.KeepalivePubSub,
+) *ClockSkewMonitor {
+	if _, ok := router.(*routing.LocalRouter); ok {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ClockSkewMonitor{
+		threshold:   withDefault(conf.ClockSkew.Threshold, defaultClockSkewThreshold),
+		compensate:  conf.ClockSkew.Compensate,
+		router:      router,
+		currentNode: currentNode,
+		kps:         kps,
+		peers:       make(map[livekit.NodeID]*clockPeer),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+func (m *ClockSkewMonitor) Start() {
+	go m.worker()
+}
+
+func (m *ClockSkewMonitor) Stop() {
+	m.cancel()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for nodeID, peer := range m.peers {
+		_ = peer.sub.Close()
+		delete(m.peers, nodeID)
+	}
+}
+
+func (m *ClockSkewMonitor) worker() {
+	ticker := time.NewTicker(clockSkewListInterval)
+	defer ticker.Stop()
+
+	m.subscribeNodes()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.subscribeNodes()
+		}
+	}
+}
+
+// subscribeNodes follows the keepalives of the nodes of the cluster, and forgets nodes that left
+func (m *ClockSkewMonitor) subscribeNodes() {
+	nodes, err := m.router.ListNodes()
+	if err != nil {
+		logger.Warnw("could not list nodes to compare clocks", err)
+		return
+	}
+
+	listed := make(map[livekit.NodeID]bool)
+	for _, node := range selector.GetAvailableNodes(nodes) {
+		nodeID := livekit.NodeID(node.Id)
+		if nodeID == m.currentNode.NodeID() {
+			continue
+		}
+		listed[nodeID] = true
+
+		m.lock.Lock()
+		_, ok := m.peers[nodeID]
+		m.lock.Unlock()
+		if ok {
+			continue
+		}
+
+		sub, err := m.kps.SubscribePing(m.ctx, nodeID)
+		if err != nil {
+			logger.Warnw("could not subscribe to node keepalives", err, "nodeID", nodeID)
+			continue
+		}
+		m.lock.Lock()
+		if m.ctx.Err() != nil {
+			m.lock.Unlock()
+			_ = sub.Close()
+			return
+		}
+		m.peers[nodeID] = &clockPeer{sub: sub}
+		m.lock.Unlock()
+
+		go func() {
+			for ping := range sub.Channel() {
+				m.addSample(nodeID, time.Since(time.Unix(ping.Timestamp, 0)))
+			}
+		}()
+	}
+
+	m.lock.Lock()
+	for nodeID, peer := range m.peers {
+		if !listed[nodeID] {
+			_ = peer.sub.Close()
+			delete(m.peers, nodeID)
+		}
+	}
+	m.lock.Unlock()
+	m.update()
+}
+
+// addSample records the time from the timestamp of a keepalive of a node to its reception
+func (m *ClockSkewMonitor) addSample(nodeID livekit.NodeID, sample time.Duration) {
+	m.lock.Lock()
+	peer, ok := m.peers[nodeID]
+	if !ok {
+		m.lock.Unlock()
+		return
+	}
+	if len(peer.samples) == clockSkewSamples {
+		peer.samples = peer.samples[1:]
+	}
+	peer.samples = append(peer.samples, sample)
+	m.lock.Unlock()
+
+	m.update()
+}
+
+func (m *ClockSkewMonitor) update() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	peerOffsets := make(map[string]time.Duration, len(m.peers))
+	offsets := []time.Duration{0}
+	skewedPeers := 0
+	for nodeID, peer := range m.peers {
+		if len(peer.samples) == 0 {
+			continue
+		}
+		offset := slices.Min(peer.samples)
+		peerOffsets[string(nodeID)] = offset
+		offsets = append(offsets, offset)
+
+		skewed := offset.Abs() >= m.threshold
+		if skewed {
+			skewedPeers++
+		}
+		if skewed != peer.skewed {
+			peer.skewed = skewed
+			if skewed {
+				logger.Warnw("clock of node is skewed", nil, "nodeID", nodeID, "offset", offset)
+			} else {
+				logger.Infow("clock of node is no longer skewed", "nodeID", nodeID, "offset", offset)
+			}
+		}
+	}
+
+	offset := medianClockOffset(offsets)
+	skewed := offset.Abs() >= m.threshold
+	if skewed != m.skewed {
+		m.skewed = skewed
+		if skewed {
+			logger.Warnw("node clock is skewed from the cluster", nil, "offset", offset, "compensating", m.compensate)
+		} else {
+			logger.Infow("node clock is no longer skewed from the cluster", "offset", offset)
+		}
+	}
+	if skewed && m.compensate {
+		utils.SetClockOffset(offset)
+	} else {
+		utils.SetClockOffset(0)
+	}
+	prometheus.RecordClockOffset(offset, peerOffsets, skewedPeers)
+}
+
+func medianClockOffset(offsets []time.Duration) time.Duration {
+	offsets = slices.Clone(offsets)
+	slices.Sort(offsets)
+	n := len(offsets)
+	if n%2 != 0 {
+		return offsets[n/2]
+	}
+	return (offsets[n/2-1] + offsets[n/2]) / 2
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+func TestClockSkewMonitor(t *testing.T) {
+	newMonitor := func(compensate bool, peers ...livekit.NodeID) *ClockSkewMonitor {
+		m := &ClockSkewMonitor{
+			threshold:  2 * time.Second,
+			compensate: compensate,
+			peers:      make(map[livekit.NodeID]*clockPeer),
+		}
+		for _, nodeID := range peers {
+			m.peers[nodeID] = &clockPeer{}
+		}
+		return m
+	}
+	t.Cleanup(func() { utils.SetClockOffset(0) })
+
+	t.Run("offsets from nodes are their smallest samples", func(t *testing.T) {
+		m := newMonitor(true, "ND_a", "ND_b")
+		for _, sample := range []time.Duration{900 * time.Millisecond, 100 * time.Millisecond, 600 * time.Millisecond} {
+			m.addSample("ND_a", sample)
+			m.addSample("ND_b", sample-time.Second)
+		}
+		// unknown nodes are ignored
+		m.addSample("ND_c", time.Hour)
+		require.False(t, m.skewed)
+		require.Zero(t, utils.ClockOffset())
+	})
+
+	t.Run("node ahead of the cluster compensates", func(t *testing.T) {
+		m := newMonitor(true, "ND_a", "ND_b")
+		for i := 0; i < 3; i++ {
+			m.addSample("ND_a", 5*time.Second+time.Duration(i)*300*time.Millisecond)
+			m.addSample("ND_b", 5*time.Second+time.Duration(i)*100*time.Millisecond)
+		}
+		require.True(t, m.skewed)
+		require.Equal(t, 5*time.Second, utils.ClockOffset())
+		require.WithinDuration(t, time.Now().Add(-5*time.Second), utils.ClusterNow(), time.Second)
+
+		// keeps the recent samples only
+		for i := 0; i < clockSkewSamples; i++ {
+			m.addSample("ND_a", 0)
+			m.addSample("ND_b", 0)
+		}
+		require.False(t, m.skewed)
+		require.Zero(t, utils.ClockOffset())
+	})
+
+	t.Run("a skewed node does not move the cluster", func(t *testing.T) {
+		m := newMonitor(true, "ND_a", "ND_b")
+		m.addSample("ND_a", -10*time.Second)
+		m.addSample("ND_b", 0)
+		require.True(t, m.peers["ND_a"].skewed)
+		require.False(t, m.skewed)
+		require.Zero(t, utils.ClockOffset())
+	})
+
+	t.Run("compensation can be disabled", func(t *testing.T) {
+		m := newMonitor(false, "ND_a")
+		m.addSample("ND_a", 6*time.Second)
+		require.True(t, m.skewed)
+		require.Zero(t, utils.ClockOffset())
+	})
+}
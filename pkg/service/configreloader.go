@@ -0,0 +1,151 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// how often the config file is checked for changes
+const configWatchInterval = 5 * time.Second
+
+// ConfigReloadable is implemented by services applying a reloaded config while running
+type ConfigReloadable interface {
+	ReloadConfig(conf *config.Config) error
+}
+
+// ConfigReloader applies the parts of the config that can change without a restart: log levels, webhook URLs,
+// room defaults, limits and the node selector with its regions. Each service swaps its settings at once, rooms and participants
+// keep the settings they were created with. Changes to other parts of the config need a restart.
+type ConfigReloader struct {
+	conf    *config.Config
+	targets []ConfigReloadable
+
+	lock     sync.Mutex
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func NewConfigReloader(
+	conf *config.Config,
+	webhookNotifier *WebhookNotifier,
+	roomAllocator RoomAllocator,
+	roomService *RoomService,
+	rtcService *RTCService,
+	roomManager *RoomManager,
+	regionSettings *RegionSettingsService,
+) *ConfigReloader {
+	r := &ConfigReloader{
+		conf:    conf,
+		targets: []ConfigReloadable{webhookNotifier, roomService, rtcService, roomManager, regionSettings},
+		done:    make(chan struct{}),
+	}
+	if reloadable, ok := roomAllocator.(ConfigReloadable); ok {
+		r.targets = append(r.targets, reloadable)
+	}
+	return r
+}
+
+// Reload applies the reloadable parts of conf, services failing to apply it keep their previous settings
+func (r *ConfigReloader) Reload(conf *config.Config) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err := r.conf.Logging.Update(&conf.Logging.Config); err != nil {
+		logger.Errorw("could not reload logging config", err)
+	}
+	for _, target := range r.targets {
+		if err := target.ReloadConfig(conf); err != nil {
+			logger.Errorw("could not reload config", err)
+		}
+	}
+	logger.Infow("config reloaded")
+}
+
+// WithLogging calls fn with the logging config, which it may change. Calls are serialized with reloads, which
+// change the same config.
+func (r *ConfigReloader) WithLogging(fn func(conf *logger.Config) error) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return fn(&r.conf.Logging.Config)
+}
+
+// Watch reloads the config on SIGHUP, and when the file at path changes if it is set
+func (r *ConfigReloader) Watch(path string, load func() (*config.Config, error)) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(sigChan)
+
+		ticker := time.NewTicker(configWatchInterval)
+		defer ticker.Stop()
+
+		modTime := configModTime(path)
+		for {
+			select {
+			case <-r.done:
+				return
+
+			case <-sigChan:
+				logger.Infow("reloading config on signal")
+
+			case <-ticker.C:
+				if path == "" {
+					continue
+				}
+				t := configModTime(path)
+				if t.Equal(modTime) {
+					continue
+				}
+				modTime = t
+				logger.Infow("config file changed, reloading", "path", path)
+			}
+
+			conf, err := load()
+			if err != nil {
+				logger.Errorw("could not load config, keeping the current one", err)
+				continue
+			}
+			r.Reload(conf)
+		}
+	}()
+}
+
+func (r *ConfigReloader) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.done)
+	})
+}
+
+func configModTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,206 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// test cases forced on a participant by the conformance test mode
+const (
+	// the server starts a new offer to the subscriber transport
+	ConformanceRenegotiate = "renegotiate"
+	// the server restarts ICE on both transports
+	ConformanceICERestart = "ice_restart"
+	// the participant is dropped as when its room migrates to another node, and is expected to resume
+	ConformanceMigrate = "migrate"
+	// the participant is dropped as when its node fails, and is expected to resume
+	ConformanceNodeFailure = "node_failure"
+	// the server sends a leave, the participant is not expected to come back
+	ConformanceServerLeave = "server_leave"
+	// the server asks the participant to join again with a new session
+	ConformanceFullReconnect = "full_reconnect"
+	// subscribing to a track is reported as failed
+	ConformanceFailSubscription = "fail_subscription"
+	// the participant is handed a refresh token expiring shortly
+	ConformanceExpireToken = "expire_token"
+
+	defaultConformanceTokenTTL = time.Second
+	maxConformanceRequest      = 4 * 1024
+)
+
+var conformanceActions = []string{
+	ConformanceRenegotiate,
+	ConformanceICERestart,
+	ConformanceMigrate,
+	ConformanceNodeFailure,
+	ConformanceServerLeave,
+	ConformanceFullReconnect,
+	ConformanceFailSubscription,
+	ConformanceExpireToken,
+}
+
+// ConformanceRequest is the JSON body of POST /conformance/<action>
+type ConformanceRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	// track the subscription of which fails, for fail_subscription
+	TrackSid string `json:"track_sid,omitempty"`
+	// error signaled for fail_subscription, as the name of a livekit.SubscriptionError, SE_UNKNOWN by default
+	SubscriptionError string `json:"subscription_error,omitempty"`
+	// issue a full reconnect once the failure was signaled, like fatal subscription errors do, for fail_subscription
+	Reconnect bool `json:"reconnect,omitempty"`
+	// validity of the refresh token, one second by default, for expire_token. Tokens are accepted up to a minute
+	// past their expiry, and the periodic refresh hands out a valid token again within five minutes.
+	ExpiresInSeconds int `json:"expires_in_seconds,omitempty"`
+}
+
+// ConformanceResult tells which test case was applied to the participant
+type ConformanceResult struct {
+	Action   string `json:"action"`
+	Identity string `json:"identity"`
+	// refresh token sent for expire_token, and its expiry as unix seconds
+	Token     string `json:"token,omitempty"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+}
+
+// conformanceRPCRequest is carried to the node hosting the room
+type conformanceRPCRequest struct {
+	Action string `json:"action"`
+	*ConformanceRequest
+}
+
+// ConformanceService lets SDK test suites force edge cases on a participant connected to a real server:
+// renegotiation, ICE restarts, migration, node failure, server initiated leaves and reconnects, subscription
+// failures and token expiry. Test cases are applied by the node hosting the room, and only when the conformance
+// test mode is enabled. Calls require roomAdmin.
+type ConformanceService struct {
+	conf           config.ConformanceConfig
+	roomStore      ServiceStore
+	topicFormatter rpc.TopicFormatter
+	client         RoomDebugClient
+}
+
+func NewConformanceService(
+	conf *config.Config,
+	roomStore ServiceStore,
+	topicFormatter rpc.TopicFormatter,
+	client RoomDebugClient,
+) *ConformanceService {
+	return &ConformanceService{
+		conf:           conf.Conformance,
+		roomStore:      roomStore,
+		topicFormatter: topicFormatter,
+		client:         client,
+	}
+}
+
+func (s *ConformanceService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	action := strings.TrimPrefix(r.URL.Path, "/conformance/")
+	if !slices.Contains(conformanceActions, action) {
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	var req ConformanceRequest
+	err := decodeJSONRequest(r, &req, maxConformanceRequest)
+	var res *ConformanceResult
+	if err == nil {
+		res, err = s.Apply(r.Context(), action, &req)
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// Apply forces the test case on the participant through the node hosting its room
+func (s *ConformanceService) Apply(ctx context.Context, action string, req *ConformanceRequest) (*ConformanceResult, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName, "participant", req.Identity, "action", action)
+	if err := EnsureAdminPermission(ctx, roomName); err != nil {
+		return nil, err
+	}
+	if !s.conf.Enabled {
+		return nil, ErrConformanceDisabled
+	}
+	if err := validateConformanceRequest(action, req); err != nil {
+		return nil, err
+	}
+	if _, _, err := s.roomStore.LoadRoom(ctx, roomName, false); err != nil {
+		return nil, err
+	}
+
+	dp, err := jsonDataPacket(&conformanceRPCRequest{Action: action, ConformanceRequest: req})
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.Conformance(ctx, s.topicFormatter.RoomTopic(ctx, roomName), dp)
+	if err != nil {
+		return nil, err
+	}
+	var result ConformanceResult
+	if err = json.Unmarshal(res.GetUser().GetPayload(), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func validateConformanceRequest(action string, req *ConformanceRequest) error {
+	switch {
+	case !slices.Contains(conformanceActions, action):
+		return fmt.Errorf("%w: unknown action %q", ErrConformanceInvalid, action)
+	case req.Identity == "":
+		return fmt.Errorf("%w: identity is required", ErrConformanceInvalid)
+	case action == ConformanceFailSubscription && req.TrackSid == "":
+		return fmt.Errorf("%w: track_sid is required", ErrConformanceInvalid)
+	case req.ExpiresInSeconds < 0:
+		return fmt.Errorf("%w: expires_in_seconds cannot be negative", ErrConformanceInvalid)
+	}
+	if _, err := conformanceSubscriptionError(req); err != nil {
+		return err
+	}
+	return nil
+}
+
+func conformanceSubscriptionError(req *ConformanceRequest) (livekit.SubscriptionError, error) {
+	if req.SubscriptionError == "" {
+		return livekit.SubscriptionError_SE_UNKNOWN, nil
+	}
+	v, ok := livekit.SubscriptionError_value[req.SubscriptionError]
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown subscription_error %q", ErrConformanceInvalid, req.SubscriptionError)
+	}
+	return livekit.SubscriptionError(v), nil
+}
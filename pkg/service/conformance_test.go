@@ -0,0 +1,99 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestConformanceService(t *testing.T) {
+	store := service.NewLocalStore()
+	client := &testRoomDebugClient{}
+	conf := &config.Config{Conformance: config.ConformanceConfig{Enabled: true}}
+	svc := service.NewConformanceService(conf, store, rpc.NewTopicFormatter(), client)
+
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomAdmin: true, Room: "sdk"},
+	}, "")
+	require.NoError(t, store.StoreRoom(ctx, &livekit.Room{Name: "sdk"}, nil))
+
+	t.Run("applied by the node of the room", func(t *testing.T) {
+		res, err := svc.Apply(ctx, service.ConformanceFailSubscription, &service.ConformanceRequest{
+			Room:              "sdk",
+			Identity:          "client",
+			TrackSid:          "TR_1",
+			SubscriptionError: "SE_TRACK_NOTFOUND",
+			Reconnect:         true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, service.ConformanceFailSubscription, res.Action)
+		require.Equal(t, "client", res.Identity)
+
+		var sent map[string]any
+		require.NoError(t, json.Unmarshal(client.conformance[len(client.conformance)-1].GetUser().GetPayload(), &sent))
+		require.Equal(t, "TR_1", sent["track_sid"])
+		require.Equal(t, true, sent["reconnect"])
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := svc.Apply(ctx, service.ConformanceRenegotiate, &service.ConformanceRequest{Room: "sdk"})
+		require.ErrorIs(t, err, service.ErrConformanceInvalid)
+		_, err = svc.Apply(ctx, service.ConformanceFailSubscription, &service.ConformanceRequest{Room: "sdk", Identity: "client"})
+		require.ErrorIs(t, err, service.ErrConformanceInvalid)
+		_, err = svc.Apply(ctx, service.ConformanceFailSubscription, &service.ConformanceRequest{
+			Room: "sdk", Identity: "client", TrackSid: "TR_1", SubscriptionError: "SE_BOGUS",
+		})
+		require.ErrorIs(t, err, service.ErrConformanceInvalid)
+		_, err = svc.Apply(ctx, service.ConformanceExpireToken, &service.ConformanceRequest{Room: "sdk", Identity: "client", ExpiresInSeconds: -1})
+		require.ErrorIs(t, err, service.ErrConformanceInvalid)
+	})
+
+	t.Run("requires room admin", func(t *testing.T) {
+		other := service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomJoin: true, Room: "sdk"},
+		}, "")
+		_, err := svc.Apply(other, service.ConformanceMigrate, &service.ConformanceRequest{Room: "sdk", Identity: "client"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+	})
+
+	t.Run("unknown action", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/conformance/explode", strings.NewReader(`{"room":"sdk","identity":"client"}`))
+		w := httptest.NewRecorder()
+		svc.ServeHTTP(w, r.WithContext(ctx))
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		n := len(client.conformance)
+		disabled := service.NewConformanceService(&config.Config{}, store, rpc.NewTopicFormatter(), client)
+		_, err := disabled.Apply(ctx, service.ConformanceMigrate, &service.ConformanceRequest{Room: "sdk", Identity: "client"})
+		require.ErrorIs(t, err, service.ErrConformanceDisabled)
+		require.Len(t, client.conformance, n)
+	})
+}
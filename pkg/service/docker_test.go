@@ -77,3 +77,24 @@ func runRedis(t testing.TB) string {
 	t.Log("Redis running on", addr)
 	return addr
 }
+
+var postgresLast uint32
+
+func runPostgres(t testing.TB) string {
+	c, err := Docker.RunWithOptions(&dockertest.RunOptions{
+		Name:       fmt.Sprintf("lktest-postgres-%d", atomic.AddUint32(&postgresLast, 1)),
+		Repository: "postgres", Tag: "latest",
+		Env: []string{"POSTGRES_PASSWORD=livekit", "POSTGRES_DB=livekit"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = Docker.Purge(c)
+	})
+	addr := c.GetHostPort("5432/tcp")
+	waitTCPPort(t, addr)
+
+	t.Log("Postgres running on", addr)
+	return fmt.Sprintf("postgres://postgres:livekit@%s/livekit?sslmode=disable", addr)
+}
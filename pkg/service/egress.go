@@ -34,6 +34,7 @@ type EgressService struct {
 	io          IOClient
 	roomService livekit.RoomService
 	store       ServiceStore
+	quotas      *QuotaEnforcer
 }
 
 func NewEgressService(
@@ -42,6 +43,7 @@ func NewEgressService(
 	store ServiceStore,
 	io IOClient,
 	rs livekit.RoomService,
+	quotas *QuotaEnforcer,
 ) *EgressService {
 	return &EgressService{
 		client:      client,
@@ -49,6 +51,7 @@ func NewEgressService(
 		io:          io,
 		roomService: rs,
 		launcher:    launcher,
+		quotas:      quotas,
 	}
 }
 
@@ -169,6 +172,11 @@ func (s *EgressService) startEgress(ctx context.Context, roomName livekit.RoomNa
 		}
 		req.RoomId = room.Sid
 	}
+	if s.quotas != nil {
+		if err := s.quotas.CheckEgress(ctx, roomName); err != nil {
+			return nil, err
+		}
+	}
 	return s.launcher.StartEgress(ctx, req)
 }
 
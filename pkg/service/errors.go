@@ -25,21 +25,106 @@ var (
 	ErrIngressNotConnected              = psrpc.NewErrorf(psrpc.Internal, "ingress not connected (redis required)")
 	ErrIngressNotFound                  = psrpc.NewErrorf(psrpc.NotFound, "ingress does not exist")
 	ErrIngressNonReusable               = psrpc.NewErrorf(psrpc.InvalidArgument, "ingress is not reusable and cannot be modified")
+	ErrIngressHealthNotFound            = psrpc.NewErrorf(psrpc.NotFound, "ingress health does not exist")
 	ErrNameExceedsLimits                = psrpc.NewErrorf(psrpc.InvalidArgument, "name length exceeds limits")
 	ErrMetadataExceedsLimits            = psrpc.NewErrorf(psrpc.InvalidArgument, "metadata size exceeds limits")
 	ErrAttributeExceedsLimits           = psrpc.NewErrorf(psrpc.InvalidArgument, "attribute size exceeds limits")
 	ErrRoomNameExceedsLimits            = psrpc.NewErrorf(psrpc.InvalidArgument, "room name length exceeds limits")
 	ErrParticipantIdentityExceedsLimits = psrpc.NewErrorf(psrpc.InvalidArgument, "participant identity length exceeds limits")
+	ErrPublishSourceInvalid             = psrpc.NewErrorf(psrpc.InvalidArgument, "publish permission has an invalid track source")
 	ErrOperationFailed                  = psrpc.NewErrorf(psrpc.Internal, "operation cannot be completed")
 	ErrParticipantNotFound              = psrpc.NewErrorf(psrpc.NotFound, "participant does not exist")
+	ErrParticipantExists                = psrpc.NewErrorf(psrpc.AlreadyExists, "a participant with the same identity is already in the room")
+	ErrVirtualParticipantPermission     = psrpc.NewErrorf(psrpc.InvalidArgument, "permissions of virtual participants cannot be changed")
 	ErrRoomNotFound                     = psrpc.NewErrorf(psrpc.NotFound, "requested room does not exist")
+	ErrRoomNotStarted                   = psrpc.NewErrorf(psrpc.FailedPrecondition, "room is scheduled and has not started yet")
+	ErrRoomScheduleNotFound             = psrpc.NewErrorf(psrpc.NotFound, "room schedule does not exist")
+	ErrRoomScheduleExists               = psrpc.NewErrorf(psrpc.AlreadyExists, "room is already scheduled")
+	ErrRoomScheduleInvalid              = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid room schedule")
+	ErrRoomScheduleNotSupported         = psrpc.NewErrorf(psrpc.Unimplemented, "room scheduling is not supported by the store")
+	ErrRoomTemplateNotFound             = psrpc.NewErrorf(psrpc.NotFound, "room template does not exist")
+	ErrRoomTemplateExists               = psrpc.NewErrorf(psrpc.AlreadyExists, "room template already exists")
+	ErrRoomTemplateInvalid              = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid room template")
+	ErrRoomTemplateNotSupported         = psrpc.NewErrorf(psrpc.Unimplemented, "room templates are not supported by the store")
+	ErrSharedPlaybackNotFound           = psrpc.NewErrorf(psrpc.NotFound, "no shared playback in room")
+	ErrSharedPlaybackInvalid            = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid shared playback request")
+	ErrParticipantNotPending            = psrpc.NewErrorf(psrpc.NotFound, "participant is not waiting for admission")
+	ErrParticipantRoleInvalid           = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid participant role")
+	ErrBreakoutRoomsNotFound            = psrpc.NewErrorf(psrpc.NotFound, "room has no breakout rooms")
+	ErrBreakoutRoomsExist               = psrpc.NewErrorf(psrpc.AlreadyExists, "room already has breakout rooms")
+	ErrBreakoutRoomsInvalid             = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid breakout rooms request")
+	ErrBreakoutRoomsNotSupported        = psrpc.NewErrorf(psrpc.Unimplemented, "breakout rooms are not supported by the store")
+	ErrRoomMergeInvalid                 = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid room merge request")
+	ErrBulkParticipantsInvalid          = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid bulk participants request")
+	ErrParticipantListInvalid           = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid list participants request")
+	ErrRecordingJobInvalid              = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid recording job")
+	ErrRecordingConsentInvalid          = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid recording consent")
+	ErrAbuseBanned                      = psrpc.NewErrorf(psrpc.ResourceExhausted, "too many requests, client is temporarily banned")
+	ErrAbuseDetectionDisabled           = psrpc.NewErrorf(psrpc.Unimplemented, "abuse detection is not enabled")
+	ErrSignalRateLimited                = psrpc.NewErrorf(psrpc.ResourceExhausted, "too many connection attempts, retry later")
+	ErrIPFiltered                       = psrpc.NewErrorf(psrpc.PermissionDenied, "connections from this address are not allowed")
+	ErrNodeAdminInvalid                 = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid node admin request")
+	ErrAdminOperationBusy               = psrpc.NewErrorf(psrpc.ResourceExhausted, "too many admin operations running on the node, try again later")
+	ErrNoMigrationTarget                = psrpc.NewErrorf(psrpc.Unavailable, "no other node available to migrate rooms to")
+	ErrSignalConnectionLimit            = psrpc.NewErrorf(psrpc.Unavailable, "node reached its signal connection limit")
+	ErrQuotaExceeded                    = psrpc.NewErrorf(psrpc.ResourceExhausted, "quota exceeded")
+	ErrRoomArchiveDisabled              = psrpc.NewErrorf(psrpc.Unimplemented, "room archival is not enabled")
+	ErrRoomArchiveInvalid               = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid room archive request")
+	ErrRoomArchiveNotFound              = psrpc.NewErrorf(psrpc.NotFound, "room archive not found")
+	ErrSessionsDisabled                 = psrpc.NewErrorf(psrpc.Unimplemented, "session history is not enabled")
+	ErrSessionsInvalid                  = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid sessions request")
+	ErrSessionNotFound                  = psrpc.NewErrorf(psrpc.NotFound, "session not found")
 	ErrRoomLockFailed                   = psrpc.NewErrorf(psrpc.Internal, "could not lock room")
 	ErrRoomUnlockFailed                 = psrpc.NewErrorf(psrpc.Internal, "could not unlock room, lock token does not match")
 	ErrRemoteUnmuteNoteEnabled          = psrpc.NewErrorf(psrpc.FailedPrecondition, "remote unmute not enabled")
+	ErrWebhookDeliveryNotFound          = psrpc.NewErrorf(psrpc.NotFound, "webhook delivery not found")
+	ErrSignalCaptureDisabled            = psrpc.NewErrorf(psrpc.Unimplemented, "signal capture is not enabled")
+	ErrSignalCaptureInvalid             = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid signal capture request")
+	ErrSignalCaptureNotFound            = psrpc.NewErrorf(psrpc.NotFound, "no signal capture for participant")
+	ErrConformanceDisabled              = psrpc.NewErrorf(psrpc.Unimplemented, "conformance test mode is not enabled")
+	ErrConformanceInvalid               = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid conformance request")
+	ErrClientDiagnosticsDisabled        = psrpc.NewErrorf(psrpc.Unimplemented, "client diagnostics uploads are not configured")
+	ErrClientDiagnosticsInvalid         = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid client diagnostics request")
 	ErrTrackNotFound                    = psrpc.NewErrorf(psrpc.NotFound, "track is not found")
+	ErrTrackForwardNotFound             = psrpc.NewErrorf(psrpc.NotFound, "track forward does not exist")
+	ErrTrackForwardInvalid              = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid track forward request")
+	ErrTrackRecordingNotFound           = psrpc.NewErrorf(psrpc.NotFound, "track recording does not exist")
+	ErrTrackRecordingInvalid            = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid track recording request")
+	ErrTrackRecordingDisabled           = psrpc.NewErrorf(psrpc.Unimplemented, "track recording is not configured")
+	ErrRTMPStreamNotFound               = psrpc.NewErrorf(psrpc.NotFound, "rtmp stream does not exist")
+	ErrRTMPStreamInvalid                = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid rtmp stream request")
+	ErrRTMPStreamsUnavailable           = psrpc.NewErrorf(psrpc.Unimplemented, "rtmp streams require redis or a local store")
+	ErrRTPIngestNotFound                = psrpc.NewErrorf(psrpc.NotFound, "rtp ingest does not exist")
+	ErrRTPIngestInvalid                 = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid rtp ingest request")
+	ErrWHIPSessionNotFound              = psrpc.NewErrorf(psrpc.NotFound, "whip session does not exist")
+	ErrWHIPInvalid                      = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid whip offer")
+	ErrWHIPRoomMissing                  = psrpc.NewErrorf(psrpc.InvalidArgument, "token must grant joining a room to publish with whip")
+	ErrWHEPSessionNotFound              = psrpc.NewErrorf(psrpc.NotFound, "whep session does not exist")
+	ErrWHEPInvalid                      = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid whep offer")
+	ErrWHEPRoomMissing                  = psrpc.NewErrorf(psrpc.InvalidArgument, "token must grant joining a room to play with whep")
+	ErrWHEPPatchNotSupported            = psrpc.NewErrorf(psrpc.Unimplemented, "trickle ice and ice restarts are not supported by whep")
+	ErrAPIKeyNotFound                   = psrpc.NewErrorf(psrpc.NotFound, "api key not found")
+	ErrAPIKeyInvalid                    = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid api key request")
+	ErrAPIKeyManagementDisabled         = psrpc.NewErrorf(psrpc.Unimplemented, "api key management is not enabled")
+	ErrTokenRevoked                     = psrpc.NewErrorf(psrpc.Unauthenticated, "token was revoked")
+	ErrTokenRevocationInvalid           = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid token revocation request")
+	ErrTokenRevocationDisabled          = psrpc.NewErrorf(psrpc.Unimplemented, "token revocation is not enabled")
+	ErrBandwidthPolicyInvalid           = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid bandwidth policy")
+	ErrRoomRelayInvalid                 = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid room relay command")
+	ErrPolicyDenied                     = psrpc.NewErrorf(psrpc.PermissionDenied, "request denied by policy webhook")
+	ErrTokenRoomConfigNotAllowed        = psrpc.NewErrorf(psrpc.PermissionDenied, "room configuration of token is not allowed")
 	ErrWebHookMissingAPIKey             = psrpc.NewErrorf(psrpc.InvalidArgument, "api_key is required to use webhooks")
 	ErrSIPNotConnected                  = psrpc.NewErrorf(psrpc.Internal, "sip not connected (redis required)")
 	ErrSIPTrunkNotFound                 = psrpc.NewErrorf(psrpc.NotFound, "requested sip trunk does not exist")
 	ErrSIPDispatchRuleNotFound          = psrpc.NewErrorf(psrpc.NotFound, "requested sip dispatch rule does not exist")
 	ErrSIPParticipantNotFound           = psrpc.NewErrorf(psrpc.NotFound, "requested sip participant does not exist")
+	ErrSIPRevisionMismatch              = psrpc.NewErrorf(psrpc.Aborted, "sip object was modified concurrently, reload and retry")
+	ErrSIPNumberInvalid                 = psrpc.NewErrorf(psrpc.InvalidArgument, "sip number is invalid")
+	ErrSIPNumberBlocked                 = psrpc.NewErrorf(psrpc.PermissionDenied, "sip number is blocked by outbound policy")
+	ErrSIPNumberNotAllowed              = psrpc.NewErrorf(psrpc.PermissionDenied, "sip number is not allowed by outbound policy")
+	ErrSIPEmergencyNumberBlocked        = psrpc.NewErrorf(psrpc.PermissionDenied, "dialing emergency numbers is not allowed")
+	ErrSIPCountryNotAllowed             = psrpc.NewErrorf(psrpc.PermissionDenied, "sip trunk is not allowed to dial this country")
+	ErrSIPCallRecordsDisabled           = psrpc.NewErrorf(psrpc.Unimplemented, "sip call records are not enabled")
+	ErrSIPUsageReportInvalid            = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid sip usage report request")
+	ErrSIPUpdateInvalid                 = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid sip update request")
 )
@@ -40,9 +40,11 @@ type IngressService struct {
 	bus         psrpc.MessageBus
 	psrpcClient rpc.IngressClient
 	store       IngressStore
+	health      IngressHealthStore
 	io          IOClient
 	telemetry   telemetry.TelemetryService
 	launcher    IngressLauncher
+	quotas      *QuotaEnforcer
 }
 
 func NewIngressServiceWithIngressLauncher(
@@ -51,9 +53,11 @@ func NewIngressServiceWithIngressLauncher(
 	bus psrpc.MessageBus,
 	psrpcClient rpc.IngressClient,
 	store IngressStore,
+	health IngressHealthStore,
 	io IOClient,
 	ts telemetry.TelemetryService,
 	launcher IngressLauncher,
+	quotas *QuotaEnforcer,
 ) *IngressService {
 
 	return &IngressService{
@@ -62,9 +66,11 @@ func NewIngressServiceWithIngressLauncher(
 		bus:         bus,
 		psrpcClient: psrpcClient,
 		store:       store,
+		health:      health,
 		io:          io,
 		telemetry:   ts,
 		launcher:    launcher,
+		quotas:      quotas,
 	}
 }
 
@@ -74,10 +80,12 @@ func NewIngressService(
 	bus psrpc.MessageBus,
 	psrpcClient rpc.IngressClient,
 	store IngressStore,
+	health IngressHealthStore,
 	io IOClient,
 	ts telemetry.TelemetryService,
+	quotas *QuotaEnforcer,
 ) *IngressService {
-	s := NewIngressServiceWithIngressLauncher(conf, nodeID, bus, psrpcClient, store, io, ts, nil)
+	s := NewIngressServiceWithIngressLauncher(conf, nodeID, bus, psrpcClient, store, health, io, ts, nil, quotas)
 
 	s.launcher = s
 
@@ -139,6 +147,11 @@ func (s *IngressService) CreateIngressWithUrl(ctx context.Context, urlStr string
 		// Marshall the URL again for sanitization
 		urlStr = urlObj.String()
 	}
+	if s.quotas != nil {
+		if err := s.quotas.CheckIngress(ctx, livekit.RoomName(req.RoomName)); err != nil {
+			return nil, err
+		}
+	}
 
 	var sk string
 	if req.InputType != livekit.IngressInput_URL_INPUT {
@@ -363,10 +376,44 @@ func (s *IngressService) ListIngress(ctx context.Context, req *livekit.ListIngre
 			return nil, err
 		}
 	}
+	s.describeHealth(ctx, req, infos)
 
 	return &livekit.ListIngressResponse{Items: infos}, nil
 }
 
+// describeHealth sets the state errors of ingresses which stalled, are restarting or failed to restart
+func (s *IngressService) describeHealth(ctx context.Context, req *livekit.ListIngressRequest, infos []*livekit.IngressInfo) {
+	if s.health == nil || len(infos) == 0 {
+		return
+	}
+	var healths []*IngressHealth
+	if req.IngressId != "" {
+		if health, err := s.health.LoadIngressHealth(ctx, req.IngressId); err == nil {
+			healths = []*IngressHealth{health}
+		}
+	} else {
+		var err error
+		if healths, err = s.health.ListIngressHealth(ctx, livekit.RoomName(req.RoomName)); err != nil {
+			logger.Warnw("could not list ingress health", err)
+			return
+		}
+	}
+
+	byID := make(map[string]*IngressHealth, len(healths))
+	for _, health := range healths {
+		byID[health.IngressID] = health
+	}
+	for _, info := range infos {
+		health := byID[info.IngressId]
+		if health == nil || info.State == nil {
+			continue
+		}
+		if desc := describeIngressHealth(health); desc != "" {
+			info.State.Error = desc
+		}
+	}
+}
+
 func (s *IngressService) DeleteIngress(ctx context.Context, req *livekit.DeleteIngressRequest) (*livekit.IngressInfo, error) {
 	AppendLogFields(ctx, "ingressID", req.IngressId)
 	if err := EnsureIngressAdminPermission(ctx); err != nil {
@@ -395,6 +442,11 @@ func (s *IngressService) DeleteIngress(ctx context.Context, req *livekit.DeleteI
 		logger.Errorw("could not delete ingress info", err)
 		return nil, err
 	}
+	if s.health != nil {
+		if err = s.health.DeleteIngressHealth(ctx, info.IngressId); err != nil {
+			logger.Warnw("could not delete ingress health", err)
+		}
+	}
 
 	info.State.Status = livekit.IngressState_ENDPOINT_INACTIVE
 
@@ -0,0 +1,497 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	sutils "github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+const (
+	// EventIngressStalled is sent when a publishing ingress receives no media, or a URL ingress fails
+	EventIngressStalled = "ingress_stalled"
+	// EventIngressRestarting is sent when a restart of a stalled ingress is scheduled
+	EventIngressRestarting = "ingress_restarting"
+	// EventIngressRestartFailed is sent when a stalled ingress is not restarted anymore
+	EventIngressRestartFailed = "ingress_restart_failed"
+	// EventIngressRecovered is sent when a stalled ingress receives media again
+	EventIngressRecovered = "ingress_recovered"
+
+	ingressHealthList = "list"
+
+	maxIngressHealthRequest    = 64 * 1024
+	ingressHealthCheckInterval = 5 * time.Second
+	ingressHealthUpdateTimeout = 10 * time.Second
+	ingressNoMediaError        = "no media received"
+)
+
+type IngressHealthStatus string
+
+const (
+	// ingress is not publishing
+	IngressHealthInactive IngressHealthStatus = "inactive"
+	// ingress is publishing and receiving media
+	IngressHealthHealthy IngressHealthStatus = "healthy"
+	// ingress is publishing without media, or its URL could not be pulled
+	IngressHealthStalled IngressHealthStatus = "stalled"
+	// restart is scheduled, or was made and no media was received since
+	IngressHealthRestarting IngressHealthStatus = "restarting"
+	// ingress stalled again after the restarts allowed
+	IngressHealthFailed IngressHealthStatus = "failed"
+)
+
+// IngressHealth follows an ingress from its state updates. Times are unix seconds by the clock of the cluster.
+type IngressHealth struct {
+	IngressID string              `json:"ingress_id"`
+	Room      string              `json:"room"`
+	Status    IngressHealthStatus `json:"status"`
+	Error     string              `json:"error,omitempty"`
+	// URL ingresses are pulled by the ingress service and started again on restarts, the sources of other
+	// ingresses are expected to reconnect when their session is stopped
+	Pull bool `json:"pull,omitempty"`
+	// ingress state is buffering or publishing
+	Active       bool   `json:"active"`
+	VideoBitrate uint32 `json:"video_bitrate"`
+	AudioBitrate uint32 `json:"audio_bitrate"`
+	// last state update with a bitrate, or start of the session. Ingress states carry bitrates but not
+	// keyframes, so media is considered received while a bitrate is reported.
+	LastMediaAt int64 `json:"last_media_at"`
+	// first time the ingress published
+	StartedAt int64 `json:"started_at,omitempty"`
+	// times the ingress published again after it stopped, from its source reconnecting or from restarts
+	Reconnects int `json:"reconnects"`
+	// consecutive restarts since media was last received
+	Restarts int `json:"restarts"`
+	// time of the scheduled restart, 0 when none is scheduled
+	RestartAt int64 `json:"restart_at,omitempty"`
+	UpdatedAt int64 `json:"updated_at"`
+	// node which received the last state update, and checks the ingress for stalls
+	NodeID string `json:"node_id"`
+}
+
+// IngressHealthRequest is the JSON body of POST /ingress_health/list
+type IngressHealthRequest struct {
+	Room      string `json:"room,omitempty"`
+	IngressID string `json:"ingress_id,omitempty"`
+}
+
+// IngressHealthService follows the health of ingresses from the state updates received by this node, and
+// checks the ingresses it last heard of for stalls. When auto restart is enabled, a stalled ingress is
+// restarted after a delay, doubled for each consecutive restart, until it receives media again or the
+// restarts allowed are exhausted. Health is listed with POST /ingress_health/list, which requires
+// ingressAdmin, and described in the state errors of ListIngress.
+type IngressHealthService struct {
+	conf      config.IngressAutoRestartConfig
+	nodeID    livekit.NodeID
+	store     IngressHealthStore
+	ingresses IngressStore
+	client    rpc.IngressClient
+	telemetry telemetry.TelemetryService
+
+	// serializes updates of health by this node
+	lock sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewIngressHealthService(
+	conf *config.Config,
+	nodeID livekit.NodeID,
+	store IngressHealthStore,
+	ingresses IngressStore,
+	client rpc.IngressClient,
+	ioService *IOInfoService,
+	ts telemetry.TelemetryService,
+) *IngressHealthService {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &IngressHealthService{
+		conf:      conf.Ingress.AutoRestart,
+		nodeID:    nodeID,
+		store:     store,
+		ingresses: ingresses,
+		client:    client,
+		telemetry: ts,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	if store != nil && ioService != nil {
+		ioService.OnIngressUpdated(s.ingressUpdated)
+	}
+	return s
+}
+
+func (s *IngressHealthService) Start() {
+	if s.store != nil && s.ingresses != nil {
+		go s.worker()
+	}
+}
+
+func (s *IngressHealthService) Stop() {
+	s.cancel()
+}
+
+func (s *IngressHealthService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/ingress_health/") {
+	case ingressHealthList:
+		var req IngressHealthRequest
+		if err = decodeJSONRequest(r, &req, maxIngressHealthRequest); err == nil {
+			res, err = s.ListIngressHealth(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *IngressHealthService) ListIngressHealth(ctx context.Context, req *IngressHealthRequest) ([]*IngressHealth, error) {
+	AppendLogFields(ctx, "room", req.Room, "ingressID", req.IngressID)
+	if err := EnsureIngressAdminPermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrIngressNotConnected
+	}
+	if req.IngressID != "" {
+		health, err := s.store.LoadIngressHealth(ctx, req.IngressID)
+		if err != nil {
+			return nil, err
+		}
+		return []*IngressHealth{health}, nil
+	}
+	healths, err := s.store.ListIngressHealth(ctx, livekit.RoomName(req.Room))
+	if err != nil {
+		return nil, err
+	}
+	if healths == nil {
+		healths = []*IngressHealth{}
+	}
+	return healths, nil
+}
+
+// ingressUpdated follows the health of an ingress from an update of its state
+func (s *IngressHealthService) ingressUpdated(info *livekit.IngressInfo) {
+	if info.State == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ingressHealthUpdateTimeout)
+	defer cancel()
+
+	s.lock.Lock()
+	health, err := s.store.LoadIngressHealth(ctx, info.IngressId)
+	if errors.Is(err, ErrIngressHealthNotFound) {
+		health, err = &IngressHealth{IngressID: info.IngressId}, nil
+	}
+	if err != nil {
+		s.lock.Unlock()
+		logger.Warnw("could not load ingress health", err, "ingressID", info.IngressId)
+		return
+	}
+	event := updateIngressHealth(health, info, utils.ClusterNow())
+	health.NodeID = string(s.nodeID)
+	err = s.store.StoreIngressHealth(ctx, health)
+	s.lock.Unlock()
+	if err != nil {
+		logger.Warnw("could not store ingress health", err, "ingressID", info.IngressId)
+	}
+
+	if event != "" {
+		s.notify(ctx, event, info, health)
+	}
+}
+
+func (s *IngressHealthService) worker() {
+	ticker := time.NewTicker(ingressHealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkIngresses()
+		}
+	}
+}
+
+// checkIngresses checks the ingresses this node received the last state update of
+func (s *IngressHealthService) checkIngresses() {
+	ctx, cancel := context.WithTimeout(s.ctx, ingressHealthUpdateTimeout)
+	defer cancel()
+
+	healths, err := s.store.ListIngressHealth(ctx, "")
+	if err != nil {
+		logger.Warnw("could not list ingress health", err)
+		return
+	}
+	for _, health := range healths {
+		if health.NodeID == string(s.nodeID) {
+			s.checkIngress(ctx, health.IngressID)
+		}
+	}
+}
+
+func (s *IngressHealthService) checkIngress(ctx context.Context, ingressID string) {
+	s.lock.Lock()
+	// reloaded, in case a state update was received since the health was listed
+	health, err := s.store.LoadIngressHealth(ctx, ingressID)
+	if err != nil || health.NodeID != string(s.nodeID) {
+		s.lock.Unlock()
+		return
+	}
+	event, restart := checkIngressHealth(health, s.conf, utils.ClusterNow())
+	if event == "" && !restart {
+		s.lock.Unlock()
+		return
+	}
+	err = s.store.StoreIngressHealth(ctx, health)
+	s.lock.Unlock()
+	if err != nil {
+		logger.Warnw("could not store ingress health", err, "ingressID", ingressID)
+	}
+
+	info, err := s.ingresses.LoadIngress(ctx, ingressID)
+	if errors.Is(err, ErrIngressNotFound) {
+		_ = s.store.DeleteIngressHealth(ctx, ingressID)
+		return
+	} else if err != nil {
+		logger.Warnw("could not load ingress", err, "ingressID", ingressID)
+		return
+	}
+	if event != "" {
+		s.notify(ctx, event, info, health)
+	}
+	if restart {
+		s.restart(ctx, info)
+	}
+}
+
+// restart stops the session of an ingress, and starts URL ingresses again. Sources of other ingresses are
+// expected to reconnect.
+func (s *IngressHealthService) restart(ctx context.Context, info *livekit.IngressInfo) {
+	if s.client == nil {
+		return
+	}
+	logger.Infow("restarting stalled ingress", "ingressID", info.IngressId, "inputType", info.InputType)
+
+	switch info.State.GetStatus() {
+	case livekit.IngressState_ENDPOINT_BUFFERING,
+		livekit.IngressState_ENDPOINT_PUBLISHING:
+		if _, err := s.client.DeleteIngress(ctx, info.IngressId, &livekit.DeleteIngressRequest{IngressId: info.IngressId}); err != nil {
+			logger.Warnw("could not stop stalled ingress", err, "ingressID", info.IngressId)
+		}
+	}
+	if info.InputType != livekit.IngressInput_URL_INPUT {
+		return
+	}
+
+	info = sutils.CloneProto(info)
+	info.State = &livekit.IngressState{}
+	if _, err := s.client.StartIngress(ctx, &rpc.StartIngressRequest{Info: info}); err != nil {
+		logger.Warnw("could not restart ingress", err, "ingressID", info.IngressId)
+
+		// stalled again, the next restart is scheduled by the following check
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		health, lerr := s.store.LoadIngressHealth(ctx, info.IngressId)
+		if lerr != nil || health.Status != IngressHealthRestarting {
+			return
+		}
+		health.Status = IngressHealthStalled
+		health.Error = err.Error()
+		if err = s.store.StoreIngressHealth(ctx, health); err != nil {
+			logger.Warnw("could not store ingress health", err, "ingressID", info.IngressId)
+		}
+	}
+}
+
+// notify sends a health webhook with the ingress, its state error describing the health
+func (s *IngressHealthService) notify(ctx context.Context, event string, info *livekit.IngressInfo, health *IngressHealth) {
+	info = sutils.CloneProto(info)
+	if info.State == nil {
+		info.State = &livekit.IngressState{}
+	}
+	if desc := describeIngressHealth(health); desc != "" {
+		info.State.Error = desc
+	}
+	s.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{Event: event, IngressInfo: info})
+}
+
+// updateIngressHealth updates the health of an ingress from an update of its state, it returns the webhook
+// to send if any
+func updateIngressHealth(h *IngressHealth, info *livekit.IngressInfo, now time.Time) string {
+	state := info.State
+	n := now.Unix()
+	h.Room = info.RoomName
+	h.Pull = info.InputType == livekit.IngressInput_URL_INPUT
+	h.VideoBitrate = state.Video.GetAverageBitrate()
+	h.AudioBitrate = state.Audio.GetAverageBitrate()
+	h.UpdatedAt = n
+
+	switch state.Status {
+	case livekit.IngressState_ENDPOINT_BUFFERING,
+		livekit.IngressState_ENDPOINT_PUBLISHING:
+		if !h.Active {
+			h.Active = true
+			if h.StartedAt == 0 {
+				h.StartedAt = n
+			} else {
+				h.Reconnects++
+			}
+			// media is expected within the stall timeout of the start
+			h.LastMediaAt = n
+			if h.Status == "" || h.Status == IngressHealthInactive {
+				h.Status = IngressHealthHealthy
+			}
+		}
+		if h.VideoBitrate > 0 || h.AudioBitrate > 0 {
+			h.LastMediaAt = n
+			if h.Status != IngressHealthHealthy {
+				h.Status = IngressHealthHealthy
+				h.Error = ""
+				h.Restarts = 0
+				h.RestartAt = 0
+				return EventIngressRecovered
+			}
+		}
+
+	case livekit.IngressState_ENDPOINT_ERROR:
+		h.Active = false
+		h.Error = state.Error
+		switch h.Status {
+		case "", IngressHealthInactive, IngressHealthHealthy:
+			if h.Pull {
+				h.Status = IngressHealthStalled
+				return EventIngressStalled
+			}
+			h.Status = IngressHealthInactive
+		}
+
+	default:
+		h.Active = false
+		h.Error = state.Error
+		// sessions stopped by a restart are followed until their source reconnects
+		if h.Status != IngressHealthRestarting && h.Status != IngressHealthFailed {
+			h.Status = IngressHealthInactive
+		}
+	}
+	return ""
+}
+
+// checkIngressHealth detects stalls of an ingress and schedules its restarts. It returns the webhook to send
+// if any, and whether the ingress is to be restarted now.
+func checkIngressHealth(h *IngressHealth, conf config.IngressAutoRestartConfig, now time.Time) (string, bool) {
+	n := now.Unix()
+	timedOut := now.Sub(time.Unix(h.LastMediaAt, 0)) >= conf.StallTimeout
+
+	switch h.Status {
+	case IngressHealthHealthy:
+		if h.Active && timedOut {
+			h.Status = IngressHealthStalled
+			h.Error = ingressNoMediaError
+			return EventIngressStalled, false
+		}
+
+	case IngressHealthRestarting:
+		if h.RestartAt != 0 {
+			if n < h.RestartAt {
+				return "", false
+			}
+			h.RestartAt = 0
+			// media is expected within the stall timeout of the restart
+			h.LastMediaAt = n
+			return "", true
+		}
+		if !timedOut {
+			return "", false
+		}
+		if !h.Active && !h.Pull {
+			// source did not reconnect after its session was stopped
+			h.Status = IngressHealthInactive
+			return "", false
+		}
+		h.Status = IngressHealthStalled
+		if h.Error == "" {
+			h.Error = ingressNoMediaError
+		}
+		return EventIngressStalled, false
+
+	case IngressHealthStalled:
+		if !conf.Enabled {
+			return "", false
+		}
+		if h.Restarts >= conf.MaxRestarts {
+			h.Status = IngressHealthFailed
+			return EventIngressRestartFailed, false
+		}
+		h.Restarts++
+		h.Status = IngressHealthRestarting
+		h.RestartAt = now.Add(ingressRestartDelay(conf, h.Restarts)).Unix()
+		return EventIngressRestarting, false
+	}
+	return "", false
+}
+
+func ingressRestartDelay(conf config.IngressAutoRestartConfig, restarts int) time.Duration {
+	delay := conf.Delay
+	for i := 1; i < restarts && delay < conf.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, conf.MaxDelay)
+}
+
+// describeIngressHealth describes the health of an ingress which is not healthy, for state errors
+func describeIngressHealth(h *IngressHealth) string {
+	switch h.Status {
+	case IngressHealthStalled:
+		return fmt.Sprintf("ingress stalled: %s", h.Error)
+	case IngressHealthRestarting:
+		return fmt.Sprintf("ingress restarting, restart %d: %s", h.Restarts, h.Error)
+	case IngressHealthFailed:
+		return fmt.Sprintf("ingress failed after %d restarts: %s", h.Restarts, h.Error)
+	}
+	return ""
+}
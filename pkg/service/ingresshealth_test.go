@@ -0,0 +1,139 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestIngressHealth(t *testing.T) {
+	conf := config.IngressAutoRestartConfig{
+		Enabled:      true,
+		StallTimeout: 30 * time.Second,
+		MaxRestarts:  2,
+		Delay:        5 * time.Second,
+		MaxDelay:     time.Minute,
+	}
+	start := time.Unix(1700000000, 0)
+
+	newInfo := func(inputType livekit.IngressInput, status livekit.IngressState_Status, bitrate uint32) *livekit.IngressInfo {
+		return &livekit.IngressInfo{
+			IngressId: "ingress",
+			RoomName:  "room",
+			InputType: inputType,
+			State: &livekit.IngressState{
+				Status: status,
+				Video:  &livekit.InputVideoState{AverageBitrate: bitrate},
+			},
+		}
+	}
+
+	t.Run("push ingress stalls and recovers", func(t *testing.T) {
+		h := &IngressHealth{IngressID: "ingress"}
+		require.Equal(t, "", updateIngressHealth(h, newInfo(livekit.IngressInput_RTMP_INPUT, livekit.IngressState_ENDPOINT_PUBLISHING, 2000000), start))
+		require.Equal(t, IngressHealthHealthy, h.Status)
+		require.Equal(t, uint32(2000000), h.VideoBitrate)
+		require.Equal(t, 0, h.Reconnects)
+
+		// bitrate drops to nothing while publishing
+		require.Equal(t, "", updateIngressHealth(h, newInfo(livekit.IngressInput_RTMP_INPUT, livekit.IngressState_ENDPOINT_PUBLISHING, 0), start.Add(10*time.Second)))
+		event, restart := checkIngressHealth(h, conf, start.Add(20*time.Second))
+		require.Equal(t, "", event)
+		require.False(t, restart)
+		event, _ = checkIngressHealth(h, conf, start.Add(30*time.Second))
+		require.Equal(t, EventIngressStalled, event)
+		require.Equal(t, IngressHealthStalled, h.Status)
+
+		event, _ = checkIngressHealth(h, conf, start.Add(35*time.Second))
+		require.Equal(t, EventIngressRestarting, event)
+		require.Equal(t, IngressHealthRestarting, h.Status)
+		require.Equal(t, 1, h.Restarts)
+		require.Equal(t, start.Add(40*time.Second).Unix(), h.RestartAt)
+
+		_, restart = checkIngressHealth(h, conf, start.Add(40*time.Second))
+		require.True(t, restart)
+		require.Zero(t, h.RestartAt)
+
+		// session stopped by the restart, then the source reconnects
+		require.Equal(t, "", updateIngressHealth(h, newInfo(livekit.IngressInput_RTMP_INPUT, livekit.IngressState_ENDPOINT_INACTIVE, 0), start.Add(41*time.Second)))
+		require.Equal(t, IngressHealthRestarting, h.Status)
+		require.Equal(t, "", updateIngressHealth(h, newInfo(livekit.IngressInput_RTMP_INPUT, livekit.IngressState_ENDPOINT_BUFFERING, 0), start.Add(45*time.Second)))
+		require.Equal(t, 1, h.Reconnects)
+		require.Equal(t, EventIngressRecovered, updateIngressHealth(h, newInfo(livekit.IngressInput_RTMP_INPUT, livekit.IngressState_ENDPOINT_PUBLISHING, 1000000), start.Add(50*time.Second)))
+		require.Equal(t, IngressHealthHealthy, h.Status)
+		require.Zero(t, h.Restarts)
+		require.Equal(t, "", describeIngressHealth(h))
+	})
+
+	t.Run("url ingress restarts with backoff until it fails", func(t *testing.T) {
+		h := &IngressHealth{IngressID: "ingress"}
+		updateIngressHealth(h, newInfo(livekit.IngressInput_URL_INPUT, livekit.IngressState_ENDPOINT_PUBLISHING, 1000000), start)
+
+		info := newInfo(livekit.IngressInput_URL_INPUT, livekit.IngressState_ENDPOINT_ERROR, 0)
+		info.State.Error = "connection reset"
+		require.Equal(t, EventIngressStalled, updateIngressHealth(h, info, start.Add(time.Second)))
+		require.Equal(t, "ingress stalled: connection reset", describeIngressHealth(h))
+
+		now := start.Add(time.Second)
+		for restarts := 1; restarts <= conf.MaxRestarts; restarts++ {
+			event, _ := checkIngressHealth(h, conf, now)
+			require.Equal(t, EventIngressRestarting, event)
+			delay := time.Duration(h.RestartAt-now.Unix()) * time.Second
+			require.Equal(t, ingressRestartDelay(conf, restarts), delay)
+
+			now = now.Add(delay)
+			_, restart := checkIngressHealth(h, conf, now)
+			require.True(t, restart)
+
+			// no media after the restart
+			now = now.Add(conf.StallTimeout)
+			event, _ = checkIngressHealth(h, conf, now)
+			require.Equal(t, EventIngressStalled, event)
+		}
+
+		event, restart := checkIngressHealth(h, conf, now)
+		require.Equal(t, EventIngressRestartFailed, event)
+		require.False(t, restart)
+		require.Equal(t, IngressHealthFailed, h.Status)
+		require.Equal(t, "ingress failed after 2 restarts: connection reset", describeIngressHealth(h))
+	})
+
+	t.Run("stalls are reported without restarts when disabled", func(t *testing.T) {
+		conf := conf
+		conf.Enabled = false
+		h := &IngressHealth{IngressID: "ingress"}
+		updateIngressHealth(h, newInfo(livekit.IngressInput_WHIP_INPUT, livekit.IngressState_ENDPOINT_PUBLISHING, 0), start)
+		event, _ := checkIngressHealth(h, conf, start.Add(time.Minute))
+		require.Equal(t, EventIngressStalled, event)
+		event, restart := checkIngressHealth(h, conf, start.Add(2*time.Minute))
+		require.Equal(t, "", event)
+		require.False(t, restart)
+		require.Equal(t, IngressHealthStalled, h.Status)
+	})
+
+	t.Run("restart delay", func(t *testing.T) {
+		require.Equal(t, 5*time.Second, ingressRestartDelay(conf, 1))
+		require.Equal(t, 10*time.Second, ingressRestartDelay(conf, 2))
+		require.Equal(t, 40*time.Second, ingressRestartDelay(conf, 4))
+		require.Equal(t, time.Minute, ingressRestartDelay(conf, 10))
+	})
+}
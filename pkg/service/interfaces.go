@@ -71,6 +71,15 @@ type IngressStore interface {
 	DeleteIngress(ctx context.Context, info *livekit.IngressInfo) error
 }
 
+//counterfeiter:generate . IngressHealthStore
+type IngressHealthStore interface {
+	StoreIngressHealth(ctx context.Context, health *IngressHealth) error
+	LoadIngressHealth(ctx context.Context, ingressID string) (*IngressHealth, error)
+	// ListIngressHealth lists the health of the ingresses of a room, or of all ingresses when roomName is empty
+	ListIngressHealth(ctx context.Context, roomName livekit.RoomName) ([]*IngressHealth, error)
+	DeleteIngressHealth(ctx context.Context, ingressID string) error
+}
+
 //counterfeiter:generate . RoomAllocator
 type RoomAllocator interface {
 	AutoCreateEnabled(ctx context.Context) bool
@@ -91,11 +100,111 @@ type SIPStore interface {
 	ListSIPInboundTrunk(ctx context.Context) ([]*livekit.SIPInboundTrunkInfo, error)
 	ListSIPOutboundTrunk(ctx context.Context) ([]*livekit.SIPOutboundTrunkInfo, error)
 	DeleteSIPTrunk(ctx context.Context, sipTrunkID string) error
+	UpdateSIPInboundTrunk(ctx context.Context, info *livekit.SIPInboundTrunkInfo, expectedRevision uint64) (uint64, error)
+	UpdateSIPOutboundTrunk(ctx context.Context, info *livekit.SIPOutboundTrunkInfo, expectedRevision uint64) (uint64, error)
 
 	StoreSIPDispatchRule(ctx context.Context, info *livekit.SIPDispatchRuleInfo) error
 	LoadSIPDispatchRule(ctx context.Context, sipDispatchRuleID string) (*livekit.SIPDispatchRuleInfo, error)
 	ListSIPDispatchRule(ctx context.Context) ([]*livekit.SIPDispatchRuleInfo, error)
 	DeleteSIPDispatchRule(ctx context.Context, info *livekit.SIPDispatchRuleInfo) error
+	UpdateSIPDispatchRule(ctx context.Context, info *livekit.SIPDispatchRuleInfo, expectedRevision uint64) (uint64, error)
+
+	// LoadSIPRevision returns the current revision of a trunk or dispatch rule, used for optimistic concurrency.
+	// Revisions are exposed by SIPUpdateService, the SIP API has no update requests to carry them.
+	LoadSIPRevision(ctx context.Context, id string) (uint64, error)
+
+	// UpdateSIPCallMetricsState records which metrics were reported for a call across nodes, see RedisStore
+	UpdateSIPCallMetricsState(ctx context.Context, callID string, fields map[string]string) (map[string]bool, map[string]string, error)
+
+	StoreSIPVoicemailRoom(ctx context.Context, info *SIPVoicemailRoom, ttl time.Duration) error
+	LoadSIPVoicemailRoom(ctx context.Context, roomName string) (*SIPVoicemailRoom, error)
+	ClaimSIPVoicemailPrompt(ctx context.Context, roomName string) (bool, error)
+}
+
+//counterfeiter:generate . SIPCallRecordStore
+type SIPCallRecordStore interface {
+	// StoreSIPCallRecord adds the record of an ended call, dropping records of calls placed before expireBefore
+	StoreSIPCallRecord(ctx context.Context, record *SIPCallRecord, expireBefore time.Time) error
+	// ListSIPCallRecords returns the records of calls placed in [from, to)
+	ListSIPCallRecords(ctx context.Context, from, to time.Time) ([]*SIPCallRecord, error)
+}
+
+//counterfeiter:generate . SessionStore
+type SessionStore interface {
+	// StoreRoomSession adds the session of a closed room, kept for retention
+	StoreRoomSession(ctx context.Context, session *RoomSession, retention time.Duration) error
+	// StoreParticipantSession adds the session of a participant to the sessions of its room, kept for retention
+	StoreParticipantSession(ctx context.Context, session *ParticipantSession, retention time.Duration) error
+	// ListRoomSessions returns up to limit sessions of rooms which ended in [from, to), latest first
+	ListRoomSessions(ctx context.Context, from, to time.Time, limit int) ([]*RoomSession, error)
+	// LoadRoomSession returns ErrSessionNotFound when the session is unknown or expired
+	LoadRoomSession(ctx context.Context, roomSid livekit.RoomID) (*RoomSession, error)
+	// ListParticipantSessions returns the sessions of the participants of a room session, in the order they ended
+	ListParticipantSessions(ctx context.Context, roomSid livekit.RoomID) ([]*ParticipantSession, error)
+}
+
+//counterfeiter:generate . RoomScheduleStore
+type RoomScheduleStore interface {
+	StoreRoomSchedule(ctx context.Context, schedule *RoomSchedule) error
+	// LoadRoomSchedule returns nil when the room is not scheduled
+	LoadRoomSchedule(ctx context.Context, roomName livekit.RoomName) (*RoomSchedule, error)
+	ListRoomSchedules(ctx context.Context) ([]*RoomSchedule, error)
+	DeleteRoomSchedule(ctx context.Context, roomName livekit.RoomName) error
+	// UpdateRoomScheduleState moves a schedule from one state to the next, returning false when
+	// the schedule is gone or another node already made the transition
+	UpdateRoomScheduleState(ctx context.Context, roomName livekit.RoomName, from, to RoomScheduleState) (bool, error)
+}
+
+//counterfeiter:generate . RoomTemplateStore
+type RoomTemplateStore interface {
+	StoreRoomTemplate(ctx context.Context, template *RoomTemplate) error
+	// LoadRoomTemplate returns ErrRoomTemplateNotFound when there is no template with that name
+	LoadRoomTemplate(ctx context.Context, name string) (*RoomTemplate, error)
+	ListRoomTemplates(ctx context.Context) ([]*RoomTemplate, error)
+	DeleteRoomTemplate(ctx context.Context, name string) error
+}
+
+//counterfeiter:generate . BreakoutStore
+type BreakoutStore interface {
+	// StoreBreakoutRooms adds breakout rooms to a parent room
+	StoreBreakoutRooms(ctx context.Context, parent livekit.RoomName, rooms []livekit.RoomName) error
+	LoadBreakoutRooms(ctx context.Context, parent livekit.RoomName) ([]livekit.RoomName, error)
+	DeleteBreakoutRooms(ctx context.Context, parent livekit.RoomName) error
+}
+
+//counterfeiter:generate . APIKeyStore
+type APIKeyStore interface {
+	StoreAPIKey(ctx context.Context, key *APIKey) error
+	// LoadAPIKey returns ErrAPIKeyNotFound when the key does not exist
+	LoadAPIKey(ctx context.Context, key string) (*APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]*APIKey, error)
+	DeleteAPIKey(ctx context.Context, key string) error
+	// LoadAPIKeysVersion returns a counter incremented whenever a key is stored or deleted
+	LoadAPIKeysVersion(ctx context.Context) (int64, error)
+	// WatchAPIKeys is notified whenever a key is stored or deleted, until ctx is done
+	WatchAPIKeys(ctx context.Context) <-chan struct{}
+	UpdateAPIKeysLastUsed(ctx context.Context, lastUsed map[string]time.Time) error
+}
+
+//counterfeiter:generate . TokenRevocationStore
+type TokenRevocationStore interface {
+	// StoreTokenRevocation replaces the revocation with the same ID
+	StoreTokenRevocation(ctx context.Context, revocation *TokenRevocation) error
+	// ListTokenRevocations omits expired revocations
+	ListTokenRevocations(ctx context.Context) ([]*TokenRevocation, error)
+	// LoadTokenRevocationsVersion returns a counter incremented whenever a revocation is stored
+	LoadTokenRevocationsVersion(ctx context.Context) (int64, error)
+}
+
+//counterfeiter:generate . RTMPStreamStore
+type RTMPStreamStore interface {
+	// StoreRTMPStream replaces the stream with the same egress ID
+	StoreRTMPStream(ctx context.Context, stream *RTMPStream) error
+	// LoadRTMPStream returns ErrRTMPStreamNotFound when the egress is not an RTMP stream
+	LoadRTMPStream(ctx context.Context, egressID string) (*RTMPStream, error)
+	// ListRTMPStreams returns the streams of a room, or of all rooms when roomName is empty
+	ListRTMPStreams(ctx context.Context, roomName livekit.RoomName) ([]*RTMPStream, error)
+	DeleteRTMPStream(ctx context.Context, egressID string) error
 }
 
 //counterfeiter:generate . AgentStore
@@ -0,0 +1,205 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/livekit/protocol/logger"
+	redisLiveKit "github.com/livekit/protocol/redis"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const defaultInternalTLSReloadInterval = time.Minute
+
+// InternalTLS holds the certificates of the connections to redis and NATS. The files are checked when
+// connecting, at most once per reload interval, and loaded again when any of them changed.
+type InternalTLS struct {
+	conf     config.InternalTLSConfig
+	interval time.Duration
+
+	lock      sync.Mutex
+	checkedAt time.Time
+	modTimes  []time.Time
+	cert      *tls.Certificate
+	roots     *x509.CertPool
+}
+
+// NewInternalTLS returns nil when internal TLS is disabled
+func NewInternalTLS(conf *config.Config) (*InternalTLS, error) {
+	if !conf.InternalTLS.Enabled {
+		return nil, nil
+	}
+	t := &InternalTLS{
+		conf:     conf.InternalTLS,
+		interval: withDefault(conf.InternalTLS.ReloadInterval, defaultInternalTLSReloadInterval),
+	}
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	t.checkedAt = time.Now()
+	return t, nil
+}
+
+func (t *InternalTLS) files() []string {
+	files := []string{t.conf.CertFile, t.conf.KeyFile}
+	if t.conf.CAFile != "" {
+		files = append(files, t.conf.CAFile)
+	}
+	return files
+}
+
+// load reads the files when they changed since they were read, called with the lock held or on creation
+func (t *InternalTLS) load() error {
+	files := t.files()
+	modTimes := make([]time.Time, 0, len(files))
+	changed := t.cert == nil
+	for i, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes = append(modTimes, fi.ModTime())
+		if i < len(t.modTimes) && !t.modTimes[i].Equal(fi.ModTime()) {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(t.conf.CertFile, t.conf.KeyFile)
+	if err != nil {
+		return err
+	}
+	var roots *x509.CertPool
+	if t.conf.CAFile != "" {
+		pem, err := os.ReadFile(t.conf.CAFile)
+		if err != nil {
+			return err
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", t.conf.CAFile)
+		}
+	}
+
+	if t.cert != nil {
+		logger.Infow("reloaded internal TLS certificates", "certFile", t.conf.CertFile)
+	}
+	t.cert = &cert
+	t.roots = roots
+	t.modTimes = modTimes
+	return nil
+}
+
+func (t *InternalTLS) current() (*tls.Certificate, *x509.CertPool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if now := time.Now(); now.Sub(t.checkedAt) >= t.interval {
+		t.checkedAt = now
+		if err := t.load(); err != nil {
+			// files are usually replaced one by one, the previous certificates are used until the next check
+			logger.Warnw("could not reload internal TLS certificates", err)
+		}
+	}
+	return t.cert, t.roots
+}
+
+// ClientConfig returns the TLS config of connections to redis and NATS. The server certificate is verified
+// against the CA certificates current at the time of the handshake.
+func (t *InternalTLS) ClientConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: t.conf.ServerName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := t.current()
+			return cert, nil
+		},
+		// verified by VerifyConnection instead, the roots of the config could not be reloaded
+		InsecureSkipVerify: true,
+		VerifyConnection:   t.verifyServer,
+	}
+}
+
+func (t *InternalTLS) verifyServer(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server did not send a certificate")
+	}
+	_, roots := t.current()
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       cs.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// RedisClient connects to redis as GetRedisClient does, with the client config of internal TLS
+func (t *InternalTLS) RedisClient(conf *redisLiveKit.RedisConfig) (redis.UniversalClient, error) {
+	var opts *redis.UniversalOptions
+	switch {
+	case len(conf.SentinelAddresses) > 0:
+		logger.Infow("connecting to redis", "sentinel", true, "addr", conf.SentinelAddresses, "masterName", conf.MasterName, "internalTLS", true)
+		opts = &redis.UniversalOptions{
+			Addrs:            conf.SentinelAddresses,
+			SentinelUsername: conf.SentinelUsername,
+			SentinelPassword: conf.SentinelPassword,
+			MasterName:       conf.MasterName,
+			DialTimeout:      time.Duration(withDefault(conf.DialTimeout, 2000)) * time.Millisecond,
+			ReadTimeout:      time.Duration(withDefault(conf.ReadTimeout, 200)) * time.Millisecond,
+			WriteTimeout:     time.Duration(withDefault(conf.WriteTimeout, 200)) * time.Millisecond,
+		}
+	case len(conf.ClusterAddresses) > 0:
+		logger.Infow("connecting to redis", "cluster", true, "addr", conf.ClusterAddresses, "internalTLS", true)
+		opts = &redis.UniversalOptions{
+			Addrs:        conf.ClusterAddresses,
+			MaxRedirects: conf.GetMaxRedirects(),
+		}
+	default:
+		logger.Infow("connecting to redis", "simple", true, "addr", conf.Address, "internalTLS", true)
+		opts = &redis.UniversalOptions{
+			Addrs: []string{conf.Address},
+		}
+	}
+	opts.Username = conf.Username
+	opts.Password = conf.Password
+	opts.DB = conf.DB
+	opts.PoolTimeout = conf.PoolTimeout
+	opts.PoolSize = conf.PoolSize
+	opts.TLSConfig = t.ClientConfig()
+
+	rc := redis.NewUniversalClient(opts)
+	if err := rc.Ping(context.Background()).Err(); err != nil {
+		_ = rc.Close()
+		return nil, fmt.Errorf("unable to connect to redis: %w", err)
+	}
+	return rc, nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM certificate and key of name
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestInternalTLS(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	conf := config.InternalTLSConfig{
+		Enabled:        true,
+		CertFile:       filepath.Join(dir, "node.crt"),
+		KeyFile:        filepath.Join(dir, "node.key"),
+		CAFile:         filepath.Join(dir, "ca.crt"),
+		ServerName:     "redis.internal",
+		ReloadInterval: time.Millisecond,
+	}
+	writeClientCert := func(name string, modTime time.Time) {
+		certPEM, keyPEM := ca.issue(t, name, x509.ExtKeyUsageClientAuth)
+		require.NoError(t, os.WriteFile(conf.CertFile, certPEM, 0600))
+		require.NoError(t, os.WriteFile(conf.KeyFile, keyPEM, 0600))
+		require.NoError(t, os.Chtimes(conf.CertFile, modTime, modTime))
+		require.NoError(t, os.Chtimes(conf.KeyFile, modTime, modTime))
+	}
+	writeClientCert("node-1", time.Now().Add(-time.Minute))
+	require.NoError(t, os.WriteFile(conf.CAFile, ca.pem, 0600))
+
+	internalTLS, err := service.NewInternalTLS(&config.Config{InternalTLS: conf})
+	require.NoError(t, err)
+
+	// server requiring client certificates of the CA, answers with the common name of the client
+	serve := func(serverCA *testCA) string {
+		certPEM, keyPEM := serverCA.issue(t, "redis.internal", x509.ExtKeyUsageServerAuth)
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		require.NoError(t, err)
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(ca.cert)
+		ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = ln.Close() })
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				tc := conn.(*tls.Conn)
+				if tc.Handshake() == nil {
+					_, _ = tc.Write([]byte(tc.ConnectionState().PeerCertificates[0].Subject.CommonName))
+				}
+				_ = tc.Close()
+			}
+		}()
+		return ln.Addr().String()
+	}
+	dial := func(addr string) (string, error) {
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: time.Second}, "tcp", addr, internalTLS.ClientConfig())
+		if err != nil {
+			return "", err
+		}
+		defer conn.Close()
+		buf := make([]byte, 64)
+		n, err := conn.Read(buf)
+		return string(buf[:n]), err
+	}
+
+	addr := serve(ca)
+	name, err := dial(addr)
+	require.NoError(t, err)
+	require.Equal(t, "node-1", name)
+
+	t.Run("renewed certificates are used without a restart", func(t *testing.T) {
+		writeClientCert("node-2", time.Now())
+		time.Sleep(5 * time.Millisecond)
+		name, err := dial(addr)
+		require.NoError(t, err)
+		require.Equal(t, "node-2", name)
+	})
+
+	t.Run("servers of another CA are rejected", func(t *testing.T) {
+		_, err := dial(serve(newTestCA(t)))
+		require.Error(t, err)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		internalTLS, err := service.NewInternalTLS(&config.Config{})
+		require.NoError(t, err)
+		require.Nil(t, internalTLS)
+	})
+}
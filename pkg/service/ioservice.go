@@ -24,6 +24,7 @@ import (
 	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/psrpc"
 
+	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 )
 
@@ -34,6 +35,20 @@ type IOInfoService struct {
 	is        IngressStore
 	ss        SIPStore
 	telemetry telemetry.TelemetryService
+	screener  *SIPCallScreener
+	cnam      *SIPCallerNameLookup
+	voicemail config.SIPVoicemailConfig
+	vmHook    *SIPVoicemailHook
+	recorder  *SIPCallRecorder
+	// starts the ingress playing voicemail prompts
+	ingressClient rpc.IngressClient
+	// updates the recording state of rooms as their egress starts and ends
+	recordingClient RecordingStateClient
+	topicFormatter  rpc.TopicFormatter
+	// run with each egress update, set before the service starts
+	onEgressUpdated []func(info *livekit.EgressInfo)
+	// run with each ingress state update, set before the service starts
+	onIngressUpdated []func(info *livekit.IngressInfo)
 
 	shutdown chan struct{}
 }
@@ -44,17 +59,31 @@ func NewIOInfoService(
 	is IngressStore,
 	ss SIPStore,
 	ts telemetry.TelemetryService,
+	sipConf *config.SIPConfig,
+	ingressClient rpc.IngressClient,
+	recorder *SIPCallRecorder,
+	recordingClient RecordingStateClient,
+	topicFormatter rpc.TopicFormatter,
 ) (*IOInfoService, error) {
 	s := &IOInfoService{
 		es:        es,
 		is:        is,
 		ss:        ss,
 		telemetry: ts,
-		shutdown:  make(chan struct{}),
+		screener:  NewSIPCallScreener(sipConf.InboundScreening),
+		cnam:      NewSIPCallerNameLookup(sipConf.CallerName),
+		voicemail: sipConf.Voicemail,
+		vmHook:    NewSIPVoicemailHook(sipConf.Voicemail),
+		recorder:  recorder,
+
+		ingressClient:   ingressClient,
+		recordingClient: recordingClient,
+		topicFormatter:  topicFormatter,
+		shutdown:        make(chan struct{}),
 	}
 
 	if bus != nil {
-		ioServer, err := rpc.NewIOInfoServer(s, bus)
+		ioServer, err := rpc.NewIOInfoServer(s, bus, psrpc.WithServerRPCInterceptors(telemetry.PSRPCTracingInterceptor))
 		if err != nil {
 			return nil, err
 		}
@@ -85,6 +114,17 @@ func (s *IOInfoService) Stop() {
 	}
 }
 
+// OnEgressUpdated adds a callback run with the egress updates received by this node, in the order received
+func (s *IOInfoService) OnEgressUpdated(f func(info *livekit.EgressInfo)) {
+	s.onEgressUpdated = append(s.onEgressUpdated, f)
+}
+
+// OnIngressUpdated adds a callback run with the ingress state updates received by this node, with the info of
+// the ingress and its new state
+func (s *IOInfoService) OnIngressUpdated(f func(info *livekit.IngressInfo)) {
+	s.onIngressUpdated = append(s.onIngressUpdated, f)
+}
+
 func (s *IOInfoService) CreateEgress(ctx context.Context, info *livekit.EgressInfo) (*emptypb.Empty, error) {
 	// check if egress already exists to avoid duplicate EgressStarted event
 	if _, err := s.es.LoadEgress(ctx, info.EgressId); err == nil {
@@ -98,12 +138,17 @@ func (s *IOInfoService) CreateEgress(ctx context.Context, info *livekit.EgressIn
 	}
 
 	s.telemetry.EgressStarted(ctx, info)
+	go s.updateRecordingJob(info)
 
 	return &emptypb.Empty{}, nil
 }
 
 func (s *IOInfoService) UpdateEgress(ctx context.Context, info *livekit.EgressInfo) (*emptypb.Empty, error) {
 	err := s.es.UpdateEgress(ctx, info)
+	go s.updateRecordingJob(info)
+	for _, f := range s.onEgressUpdated {
+		f(info)
+	}
 
 	switch info.Status {
 	case livekit.EgressStatus_EGRESS_ACTIVE,
@@ -115,6 +160,9 @@ func (s *IOInfoService) UpdateEgress(ctx context.Context, info *livekit.EgressIn
 		livekit.EgressStatus_EGRESS_ABORTED,
 		livekit.EgressStatus_EGRESS_LIMIT_REACHED:
 		s.telemetry.EgressEnded(ctx, info)
+		if s.vmHook != nil && s.ss != nil && info.Status == livekit.EgressStatus_EGRESS_COMPLETE {
+			go s.deliverSIPVoicemail(info)
+		}
 	}
 
 	if err != nil {
@@ -165,6 +213,18 @@ func (s *IOInfoService) UpdateMetrics(ctx context.Context, req *rpc.UpdateMetric
 }
 
 func (s *IOInfoService) UpdateSIPCallState(ctx context.Context, req *rpc.UpdateSIPCallStateRequest) (*emptypb.Empty, error) {
-	// TODO: placeholder
+	if req.CallInfo != nil && s.ss != nil {
+		if err := updateSIPCallMetrics(ctx, s.ss, req.CallInfo); err != nil {
+			logger.Warnw("could not update sip call metrics", err, "callID", req.CallInfo.CallId)
+		}
+		if s.recorder != nil {
+			if err := s.recorder.record(ctx, s.ss, req.CallInfo); err != nil {
+				logger.Warnw("could not record sip call", err, "callID", req.CallInfo.CallId)
+			}
+		}
+		if req.CallInfo.CallStatus == livekit.SIPCallStatus_SCS_ACTIVE && req.CallInfo.RoomName != "" {
+			s.startSIPVoicemailPrompt(ctx, req.CallInfo.RoomName)
+		}
+	}
 	return &emptypb.Empty{}, nil
 }
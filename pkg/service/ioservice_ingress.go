@@ -100,5 +100,9 @@ func (s *IOInfoService) UpdateIngressState(ctx context.Context, req *rpc.UpdateI
 		logger.Infow("ingress state updated", "ingressID", req.IngressId, "status", info.State.Status)
 	}
 
+	for _, f := range s.onIngressUpdated {
+		f(info)
+	}
+
 	return &emptypb.Empty{}, nil
 }
@@ -75,9 +75,35 @@ func (s *IOInfoService) EvaluateSIPDispatchRules(ctx context.Context, req *rpc.E
 	} else {
 		log.Debugw("No SIP trunk matched")
 	}
+	var screening *SIPScreeningResponse
+	if s.screener != nil {
+		screening, err = s.screener.Screen(ctx, trunkID, req)
+		if err != nil {
+			if !s.screener.AllowOnError(trunkID) {
+				log.Warnw("SIP call screening failed, rejecting call", err)
+				return &rpc.EvaluateSIPDispatchRulesResponse{
+					SipTrunkId: trunkID,
+					Result:     rpc.SIPDispatchResult_REJECT,
+				}, nil
+			}
+			log.Warnw("SIP call screening failed, using dispatch rules", err)
+		} else if screening != nil && screening.Action == SIPScreeningActionReject {
+			log.Infow("SIP call rejected by screening")
+			return screening.RejectResponse(trunkID), nil
+		}
+	}
 	best, err := s.matchSIPDispatchRule(ctx, trunk, req)
 	if err != nil {
 		if e := (*sip.ErrNoDispatchMatched)(nil); errors.As(err, &e) {
+			if screening != nil && screening.RoomName != "" {
+				log.Debugw("SIP call routed by screening", "room", screening.RoomName)
+				resp, err := screening.AcceptResponse(trunk, req)
+				if err != nil {
+					return nil, err
+				}
+				s.applySIPCallerName(ctx, log, trunkID, req, resp)
+				return resp, nil
+			}
 			return &rpc.EvaluateSIPDispatchRulesResponse{
 				SipTrunkId: trunkID,
 				Result:     rpc.SIPDispatchResult_DROP,
@@ -91,9 +117,105 @@ func (s *IOInfoService) EvaluateSIPDispatchRules(ctx context.Context, req *rpc.E
 		return nil, err
 	}
 	resp.SipTrunkId = trunkID
+	if IsSIPVoicemailRule(best) && resp.Result == rpc.SIPDispatchResult_ACCEPT {
+		if screening != nil && screening.RoomName != "" {
+			// the screening endpoint routed the call elsewhere, it takes precedence over the rule
+			log.Infow("SIP call routed by screening, skipping voicemail", "room", screening.RoomName)
+			// the rule attributes copied to the participant would still mark it as a voicemail caller
+			delete(resp.ParticipantAttributes, SIPVoicemailAttribute)
+			delete(resp.ParticipantAttributes, SIPVoicemailPromptAttribute)
+		} else {
+			s.applySIPVoicemail(ctx, log, best, req, resp)
+		}
+	}
+	if screening != nil && resp.Result == rpc.SIPDispatchResult_ACCEPT {
+		screening.Apply(resp)
+	}
+	s.applySIPCallerName(ctx, log, trunkID, req, resp)
+	if s.recorder != nil && resp.Result == rpc.SIPDispatchResult_ACCEPT {
+		if err := s.recorder.recordDispatchRule(ctx, s.ss, req.SipCallId, best.SipDispatchRuleId); err != nil {
+			log.Warnw("could not record SIP dispatch rule", err)
+		}
+	}
 	return resp, err
 }
 
+// applySIPCallerName looks up the name of the caller of an accepted call. Lookup failures do not affect the call,
+// and numbers hidden by the dispatch rule are not looked up.
+func (s *IOInfoService) applySIPCallerName(ctx context.Context, log logger.Logger, trunkID string, req *rpc.EvaluateSIPDispatchRulesRequest, resp *rpc.EvaluateSIPDispatchRulesResponse) {
+	if s.cnam == nil || resp.Result != rpc.SIPDispatchResult_ACCEPT || req.CallingNumber == "" {
+		return
+	}
+	if _, ok := resp.ParticipantAttributes[livekit.AttrSIPPhoneNumber]; !ok {
+		return
+	}
+	name, err := s.cnam.Lookup(ctx, trunkID, req)
+	if err != nil {
+		log.Warnw("SIP caller name lookup failed", err)
+		return
+	}
+	name.Apply(req, resp)
+}
+
+func (s *IOInfoService) applySIPVoicemail(ctx context.Context, log logger.Logger, rule *livekit.SIPDispatchRuleInfo, req *rpc.EvaluateSIPDispatchRulesRequest, resp *rpc.EvaluateSIPDispatchRulesResponse) {
+	vm := ApplySIPVoicemail(s.voicemail, rule, req, resp)
+	log = log.WithValues("room", vm.RoomName)
+	if s.voicemail.RoomPreset == "" {
+		log.Warnw("SIP voicemail room preset is not configured, the call will not be recorded", nil)
+	}
+	if err := s.ss.StoreSIPVoicemailRoom(ctx, vm, sipVoicemailRecordTTL(s.voicemail)); err != nil {
+		log.Errorw("could not store SIP voicemail room", err)
+	}
+	log.Debugw("SIP call routed to voicemail")
+}
+
+// startSIPVoicemailPrompt plays the prompt of a voicemail room once its caller is connected
+func (s *IOInfoService) startSIPVoicemailPrompt(ctx context.Context, roomName string) {
+	vm, err := s.ss.LoadSIPVoicemailRoom(ctx, roomName)
+	if err != nil {
+		logger.Warnw("could not load SIP voicemail room", err, "room", roomName)
+		return
+	}
+	if vm == nil || vm.PromptURL == "" {
+		return
+	}
+	if s.ingressClient == nil {
+		logger.Warnw("cannot play SIP voicemail prompt, ingress is not available", nil, "room", roomName)
+		return
+	}
+	if claimed, err := s.ss.ClaimSIPVoicemailPrompt(ctx, roomName); err != nil || !claimed {
+		if err != nil {
+			logger.Warnw("could not claim SIP voicemail prompt", err, "room", roomName)
+		}
+		return
+	}
+
+	info, err := newSIPVoicemailPromptIngress(vm)
+	if err != nil {
+		logger.Warnw("invalid SIP voicemail prompt", err, "room", roomName, "prompt", vm.PromptURL)
+		return
+	}
+	if _, err = s.ingressClient.StartIngress(ctx, &rpc.StartIngressRequest{Info: info}); err != nil {
+		logger.Warnw("could not play SIP voicemail prompt", err, "room", roomName, "prompt", vm.PromptURL)
+	}
+}
+
+// deliverSIPVoicemail delivers the completed recording of a voicemail room
+func (s *IOInfoService) deliverSIPVoicemail(info *livekit.EgressInfo) {
+	ctx := context.Background()
+	vm, err := s.ss.LoadSIPVoicemailRoom(ctx, info.RoomName)
+	if err != nil {
+		logger.Warnw("could not load SIP voicemail room", err, "room", info.RoomName)
+		return
+	}
+	if vm == nil {
+		return
+	}
+	if err = s.vmHook.Deliver(ctx, info); err != nil {
+		logger.Warnw("could not deliver voicemail", err, "egressID", info.EgressId, "room", info.RoomName, "callID", vm.CallID)
+	}
+}
+
 func (s *IOInfoService) GetSIPTrunkAuthentication(ctx context.Context, req *rpc.GetSIPTrunkAuthenticationRequest) (*rpc.GetSIPTrunkAuthenticationResponse, error) {
 	log := logger.GetLogger()
 	log = log.WithValues("toUser", req.To, "fromUser", req.From, "src", req.SrcAddress)
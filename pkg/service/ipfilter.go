@@ -0,0 +1,246 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/urfave/negroni/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// transports checked by the IP filter, used as the label of refused addresses
+const (
+	IPFilterTransportSignal = "signal"
+	IPFilterTransportTURN   = "turn"
+
+	ipFilterReasonNetwork = "network"
+	ipFilterReasonCountry = "country"
+)
+
+// country headers of CDNs, in order of preference
+var countryHeaders = []string{
+	"CloudFront-Viewer-Country",
+	"Cf-Ipcountry",
+	"X-Client-Country",
+}
+
+// countryLookup returns the ISO code of the country of an address, or an empty string when it is unknown
+type countryLookup interface {
+	country(ip net.IP) string
+}
+
+type maxmindCountryLookup struct {
+	reader *maxminddb.Reader
+}
+
+func (l *maxmindCountryLookup) country(ip net.IP) string {
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+	if err := l.reader.Lookup(ip, &record); err != nil {
+		return ""
+	}
+	return strings.ToUpper(record.Country.ISOCode)
+}
+
+type ipFilterRules struct {
+	allow          []*net.IPNet
+	deny           []*net.IPNet
+	allowCountries map[string]struct{}
+	denyCountries  map[string]struct{}
+}
+
+func newIPFilterRules(conf config.IPFilterRules) *ipFilterRules {
+	r := &ipFilterRules{
+		allowCountries: make(map[string]struct{}),
+		denyCountries:  make(map[string]struct{}),
+	}
+	// networks are checked when the config is loaded
+	for _, cidr := range conf.AllowCIDRs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			r.allow = append(r.allow, network)
+		}
+	}
+	for _, cidr := range conf.DenyCIDRs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			r.deny = append(r.deny, network)
+		}
+	}
+	for _, country := range conf.AllowCountries {
+		r.allowCountries[strings.ToUpper(country)] = struct{}{}
+	}
+	for _, country := range conf.DenyCountries {
+		r.denyCountries[strings.ToUpper(country)] = struct{}{}
+	}
+	return r
+}
+
+// check returns the kind of rule refusing the address, or an empty string when it is allowed
+func (r *ipFilterRules) check(ip net.IP, country string) string {
+	for _, network := range r.deny {
+		if network.Contains(ip) {
+			return ipFilterReasonNetwork
+		}
+	}
+	if _, ok := r.denyCountries[country]; ok && country != "" {
+		return ipFilterReasonCountry
+	}
+
+	if len(r.allow) == 0 && len(r.allowCountries) == 0 {
+		return ""
+	}
+	for _, network := range r.allow {
+		if network.Contains(ip) {
+			return ""
+		}
+	}
+	if _, ok := r.allowCountries[country]; ok && country != "" {
+		return ""
+	}
+	if len(r.allowCountries) != 0 {
+		return ipFilterReasonCountry
+	}
+	return ipFilterReasonNetwork
+}
+
+// IPFilter refuses signal connections and TURN allocations from networks and countries which are not allowed,
+// so that restricted deployments can keep media from terminating in embargoed regions. Rooms may replace the
+// global rules with their own.
+type IPFilter struct {
+	global     *ipFilterRules
+	rooms      map[livekit.RoomName]*ipFilterRules
+	geo        countryLookup
+	geoHeaders bool
+	addresses  *ClientAddresses
+}
+
+// NewIPFilter returns nil when no rules are configured
+func NewIPFilter(conf *config.Config) (*IPFilter, error) {
+	if !conf.IPFilter.Enabled() {
+		return nil, nil
+	}
+	f := &IPFilter{
+		global:     newIPFilterRules(conf.IPFilter.IPFilterRules),
+		rooms:      make(map[livekit.RoomName]*ipFilterRules),
+		geoHeaders: conf.IPFilter.GeoHeaders,
+		addresses:  NewClientAddresses(conf),
+	}
+	for room, rules := range conf.IPFilter.Rooms {
+		f.rooms[livekit.RoomName(room)] = newIPFilterRules(rules)
+	}
+	if conf.IPFilter.GeoIPDatabase != "" {
+		reader, err := maxminddb.Open(conf.IPFilter.GeoIPDatabase)
+		if err != nil {
+			return nil, fmt.Errorf("could not open geoip database: %w", err)
+		}
+		f.geo = &maxmindCountryLookup{reader: reader}
+	}
+	return f, nil
+}
+
+func (f *IPFilter) rules(roomName livekit.RoomName) *ipFilterRules {
+	if rules, ok := f.rooms[roomName]; ok && roomName != "" {
+		return rules
+	}
+	return f.global
+}
+
+func (f *IPFilter) check(transport string, ip net.IP, country string, roomName livekit.RoomName) error {
+	reason := f.rules(roomName).check(ip, country)
+	if reason == "" {
+		return nil
+	}
+	logger.Infow("address refused by ip filter",
+		"transport", transport,
+		"ip", ip,
+		"country", country,
+		"room", roomName,
+		"reason", reason,
+	)
+	prometheus.RecordIPFilterBlocked(transport, reason)
+	return ErrIPFiltered
+}
+
+func (f *IPFilter) lookupCountry(ip net.IP) string {
+	if f.geo == nil || ip == nil {
+		return ""
+	}
+	return f.geo.country(ip)
+}
+
+// CheckSignal returns ErrIPFiltered when the client of the request is not allowed to join the room
+func (f *IPFilter) CheckSignal(r *http.Request, roomName livekit.RoomName) error {
+	ip := f.addresses.ClientIP(r)
+
+	country := ""
+	// the headers could be set by the client when the request was not forwarded by a proxy
+	if f.geoHeaders && f.addresses.FromTrustedProxy(r) {
+		for _, name := range countryHeaders {
+			if country = strings.ToUpper(r.Header.Get(name)); country != "" {
+				break
+			}
+		}
+	}
+	if country == "" {
+		country = f.lookupCountry(ip)
+	}
+	return f.check(IPFilterTransportSignal, ip, country, roomName)
+}
+
+// CheckTURN returns ErrIPFiltered when the source of a TURN allocation is not allowed for the room its
+// credentials were issued for
+func (f *IPFilter) CheckTURN(srcAddr net.Addr, roomName livekit.RoomName) error {
+	var ip net.IP
+	switch addr := srcAddr.(type) {
+	case *net.UDPAddr:
+		ip = addr.IP
+	case *net.TCPAddr:
+		ip = addr.IP
+	}
+	return f.check(IPFilterTransportTURN, ip, f.lookupCountry(ip), roomName)
+}
+
+// Middleware refuses signal connections of clients which are not allowed, it has to run after authentication
+// to apply the rules of the room of the token
+func (f *IPFilter) Middleware() negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		claims := GetGrants(r.Context())
+		if !isSignalRequest(r) || claims == nil {
+			next(w, r)
+			return
+		}
+		var roomName livekit.RoomName
+		if claims.Video != nil {
+			roomName = livekit.RoomName(claims.Video.Room)
+		}
+		if err := f.CheckSignal(r, roomName); err != nil {
+			handleError(w, r, http.StatusForbidden, err)
+			return
+		}
+		next(w, r)
+	})
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/negroni/v3"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+func TestIPFilter(t *testing.T) {
+	prometheus.Init("test", livekit.NodeType_SERVER)
+	const (
+		apiKey = "APIkey"
+		secret = "somesecretencodedinbase62extendto32bytes"
+	)
+	keyProvider := auth.NewFileBasedKeyProviderFromMap(map[string]string{apiKey: secret})
+
+	f, err := service.NewIPFilter(&config.Config{TrustedProxies: []string{"192.0.2.100/32"}, IPFilter: config.IPFilterConfig{
+		IPFilterRules: config.IPFilterRules{
+			DenyCIDRs:     []string{"10.1.0.0/16"},
+			DenyCountries: []string{"kp"},
+		},
+		GeoHeaders: true,
+		Rooms: map[string]config.IPFilterRules{
+			"restricted": {AllowCIDRs: []string{"10.2.0.0/16"}, AllowCountries: []string{"US"}},
+		},
+	}})
+	require.NoError(t, err)
+
+	n := negroni.New(service.NewAPIKeyAuthMiddleware(keyProvider), f.Middleware())
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	// requests are forwarded by the trusted proxy unless peer is set
+	serveFrom := func(peer, room, ip, country string) int {
+		token, err := auth.NewAccessToken(apiKey, secret).
+			SetIdentity("alice").
+			AddGrant(&auth.VideoGrant{RoomJoin: true, Room: room}).
+			ToJWT()
+		require.NoError(t, err)
+		r := httptest.NewRequest(http.MethodGet, "/rtc", nil)
+		r.RemoteAddr = peer
+		r.Header.Set("X-Forwarded-For", ip)
+		if country != "" {
+			r.Header.Set("Cf-Ipcountry", country)
+		}
+		service.SetAuthorizationToken(r, token)
+		w := httptest.NewRecorder()
+		n.ServeHTTP(w, r)
+		return w.Code
+	}
+	serve := func(room, ip, country string) int {
+		return serveFrom("192.0.2.100:5000", room, ip, country)
+	}
+
+	t.Run("global rules", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, serve("main", "10.0.0.1", ""))
+		require.Equal(t, http.StatusForbidden, serve("main", "10.1.0.1", ""))
+		require.Equal(t, http.StatusForbidden, serve("main", "10.0.0.1", "KP"))
+		require.Equal(t, http.StatusOK, serve("main", "10.0.0.1", "FR"))
+	})
+
+	t.Run("room rules", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, serve("restricted", "10.2.0.1", ""))
+		require.Equal(t, http.StatusOK, serve("restricted", "192.0.2.1", "US"))
+		require.Equal(t, http.StatusForbidden, serve("restricted", "192.0.2.1", "FR"))
+		// addresses of unknown countries are not allowed
+		require.Equal(t, http.StatusForbidden, serve("restricted", "192.0.2.1", ""))
+	})
+
+	t.Run("headers of clients are ignored", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, serveFrom("10.1.0.1:5000", "main", "10.0.0.1", ""))
+		require.Equal(t, http.StatusOK, serveFrom("10.0.0.1:5000", "main", "10.1.0.1", "KP"))
+	})
+
+	t.Run("TURN", func(t *testing.T) {
+		h := service.NewTURNAuthHandler(keyProvider, f, nil)
+		username := h.CreateUsername(apiKey, "PA_1", "restricted")
+		_, ok := h.HandleAuth(username, service.LivekitRealm, &net.UDPAddr{IP: net.ParseIP("10.2.0.1"), Port: 5000})
+		require.True(t, ok)
+		_, ok = h.HandleAuth(username, service.LivekitRealm, &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000})
+		require.False(t, ok)
+
+		username = h.CreateUsername(apiKey, "PA_1", "main")
+		_, ok = h.HandleAuth(username, service.LivekitRealm, &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000})
+		require.True(t, ok)
+		_, ok = h.HandleAuth(username, service.LivekitRealm, &net.TCPAddr{IP: net.ParseIP("10.1.0.1"), Port: 5000})
+		require.False(t, ok)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		f, err := service.NewIPFilter(&config.Config{})
+		require.NoError(t, err)
+		require.Nil(t, f)
+	})
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const maxJSONHookResponse = 64 * 1024
+
+// jsonHook posts JSON documents to an external endpoint configured by the operator,
+// used by the synchronous decision hooks (call screening, policy) and for delivering results.
+type jsonHook struct {
+	name    string
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newJSONHook(name string, url string, headers map[string]string, timeout time.Duration) *jsonHook {
+	return &jsonHook{
+		name:    name,
+		url:     url,
+		headers: headers,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// post sends body and, when out is not nil, decodes the JSON response into it
+func (h *jsonHook) post(ctx context.Context, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range h.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s endpoint returned status %d", h.name, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxJSONHookResponse)).Decode(out)
+}
+
+// postJSON marshals req and decodes the response into out
+func (h *jsonHook) postJSON(ctx context.Context, req any, out any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return h.post(ctx, body, out)
+}
+
+func (h *jsonHook) checkAction(action string, allowed ...string) error {
+	if !slices.Contains(allowed, action) {
+		return fmt.Errorf("unknown %s action %q", h.name, action)
+	}
+	return nil
+}
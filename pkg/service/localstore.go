@@ -16,6 +16,7 @@ package service
 
 import (
 	"context"
+	"slices"
 	"sync"
 	"time"
 
@@ -36,6 +37,17 @@ type LocalStore struct {
 	agentDispatches map[livekit.RoomName]map[string]*livekit.AgentDispatch
 	agentJobs       map[livekit.RoomName]map[string]*livekit.Job
 
+	roomSchedules map[livekit.RoomName]*RoomSchedule
+	roomTemplates map[string]*RoomTemplate
+	breakoutRooms map[livekit.RoomName][]livekit.RoomName
+	apiKeys       map[string]*APIKey
+	apiKeysVer    int64
+	// notified when keys change
+	apiKeyWatchers []chan struct{}
+	revocations    map[string]*TokenRevocation
+	revocationVer  int64
+	rtmpStreams    map[string]*RTMPStream
+
 	lock       sync.RWMutex
 	globalLock sync.Mutex
 }
@@ -47,6 +59,12 @@ func NewLocalStore() *LocalStore {
 		participants:    make(map[livekit.RoomName]map[livekit.ParticipantIdentity]*livekit.ParticipantInfo),
 		agentDispatches: make(map[livekit.RoomName]map[string]*livekit.AgentDispatch),
 		agentJobs:       make(map[livekit.RoomName]map[string]*livekit.Job),
+		roomSchedules:   make(map[livekit.RoomName]*RoomSchedule),
+		roomTemplates:   make(map[string]*RoomTemplate),
+		breakoutRooms:   make(map[livekit.RoomName][]livekit.RoomName),
+		apiKeys:         make(map[string]*APIKey),
+		revocations:     make(map[string]*TokenRevocation),
+		rtmpStreams:     make(map[string]*RTMPStream),
 		lock:            sync.RWMutex{},
 	}
 }
@@ -279,3 +297,275 @@ func (s *LocalStore) DeleteAgentJob(ctx context.Context, job *livekit.Job) error
 
 	return nil
 }
+
+func (s *LocalStore) StoreRoomSchedule(_ context.Context, schedule *RoomSchedule) error {
+	clone := *schedule
+	clone.Request = utils.CloneProto(schedule.Request)
+	if clone.State == "" {
+		clone.State = RoomScheduleScheduled
+	}
+
+	s.lock.Lock()
+	s.roomSchedules[livekit.RoomName(schedule.Request.Name)] = &clone
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *LocalStore) LoadRoomSchedule(_ context.Context, roomName livekit.RoomName) (*RoomSchedule, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	schedule := s.roomSchedules[roomName]
+	if schedule == nil {
+		return nil, nil
+	}
+	clone := *schedule
+	return &clone, nil
+}
+
+func (s *LocalStore) ListRoomSchedules(_ context.Context) ([]*RoomSchedule, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	schedules := make([]*RoomSchedule, 0, len(s.roomSchedules))
+	for _, schedule := range s.roomSchedules {
+		clone := *schedule
+		schedules = append(schedules, &clone)
+	}
+	return schedules, nil
+}
+
+func (s *LocalStore) DeleteRoomSchedule(_ context.Context, roomName livekit.RoomName) error {
+	s.lock.Lock()
+	delete(s.roomSchedules, roomName)
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *LocalStore) UpdateRoomScheduleState(_ context.Context, roomName livekit.RoomName, from, to RoomScheduleState) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	schedule := s.roomSchedules[roomName]
+	if schedule == nil || schedule.State != from {
+		return false, nil
+	}
+	schedule.State = to
+	return true, nil
+}
+
+func (s *LocalStore) StoreRoomTemplate(_ context.Context, template *RoomTemplate) error {
+	s.lock.Lock()
+	s.roomTemplates[template.Config.Name] = template.clone()
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *LocalStore) LoadRoomTemplate(_ context.Context, name string) (*RoomTemplate, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	template := s.roomTemplates[name]
+	if template == nil {
+		return nil, ErrRoomTemplateNotFound
+	}
+	return template.clone(), nil
+}
+
+func (s *LocalStore) ListRoomTemplates(_ context.Context) ([]*RoomTemplate, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	templates := make([]*RoomTemplate, 0, len(s.roomTemplates))
+	for _, template := range s.roomTemplates {
+		templates = append(templates, template.clone())
+	}
+	return templates, nil
+}
+
+func (s *LocalStore) DeleteRoomTemplate(_ context.Context, name string) error {
+	s.lock.Lock()
+	delete(s.roomTemplates, name)
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *LocalStore) StoreBreakoutRooms(_ context.Context, parent livekit.RoomName, rooms []livekit.RoomName) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, room := range rooms {
+		if !slices.Contains(s.breakoutRooms[parent], room) {
+			s.breakoutRooms[parent] = append(s.breakoutRooms[parent], room)
+		}
+	}
+	return nil
+}
+
+func (s *LocalStore) LoadBreakoutRooms(_ context.Context, parent livekit.RoomName) ([]livekit.RoomName, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return slices.Clone(s.breakoutRooms[parent]), nil
+}
+
+func (s *LocalStore) DeleteBreakoutRooms(_ context.Context, parent livekit.RoomName) error {
+	s.lock.Lock()
+	delete(s.breakoutRooms, parent)
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *LocalStore) StoreAPIKey(_ context.Context, key *APIKey) error {
+	s.lock.Lock()
+	s.apiKeys[key.Key] = key.clone()
+	s.apiKeysVer++
+	s.notifyAPIKeyWatchersLocked()
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *LocalStore) LoadAPIKey(_ context.Context, key string) (*APIKey, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	k := s.apiKeys[key]
+	if k == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+	return k.clone(), nil
+}
+
+func (s *LocalStore) ListAPIKeys(_ context.Context) ([]*APIKey, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	keys := make([]*APIKey, 0, len(s.apiKeys))
+	for _, k := range s.apiKeys {
+		keys = append(keys, k.clone())
+	}
+	return keys, nil
+}
+
+func (s *LocalStore) DeleteAPIKey(_ context.Context, key string) error {
+	s.lock.Lock()
+	delete(s.apiKeys, key)
+	s.apiKeysVer++
+	s.notifyAPIKeyWatchersLocked()
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *LocalStore) LoadAPIKeysVersion(_ context.Context) (int64, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.apiKeysVer, nil
+}
+
+func (s *LocalStore) WatchAPIKeys(ctx context.Context) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	s.lock.Lock()
+	s.apiKeyWatchers = append(s.apiKeyWatchers, changes)
+	s.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.lock.Lock()
+		s.apiKeyWatchers = slices.DeleteFunc(s.apiKeyWatchers, func(c chan struct{}) bool { return c == changes })
+		s.lock.Unlock()
+	}()
+	return changes
+}
+
+func (s *LocalStore) notifyAPIKeyWatchersLocked() {
+	for _, c := range s.apiKeyWatchers {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *LocalStore) UpdateAPIKeysLastUsed(_ context.Context, lastUsed map[string]time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for key, at := range lastUsed {
+		if k := s.apiKeys[key]; k != nil {
+			k.LastUsedAt = at.Unix()
+		}
+	}
+	return nil
+}
+
+func (s *LocalStore) StoreTokenRevocation(_ context.Context, revocation *TokenRevocation) error {
+	r := *revocation
+	s.lock.Lock()
+	s.revocations[r.ID()] = &r
+	s.revocationVer++
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *LocalStore) ListTokenRevocations(_ context.Context) ([]*TokenRevocation, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	revocations := make([]*TokenRevocation, 0, len(s.revocations))
+	for id, r := range s.revocations {
+		if r.expired(now) {
+			delete(s.revocations, id)
+			continue
+		}
+		clone := *r
+		revocations = append(revocations, &clone)
+	}
+	return revocations, nil
+}
+
+func (s *LocalStore) LoadTokenRevocationsVersion(_ context.Context) (int64, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.revocationVer, nil
+}
+
+func (s *LocalStore) StoreRTMPStream(_ context.Context, stream *RTMPStream) error {
+	s.lock.Lock()
+	s.rtmpStreams[stream.EgressID] = stream.clone()
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *LocalStore) LoadRTMPStream(_ context.Context, egressID string) (*RTMPStream, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	stream := s.rtmpStreams[egressID]
+	if stream == nil {
+		return nil, ErrRTMPStreamNotFound
+	}
+	return stream.clone(), nil
+}
+
+func (s *LocalStore) ListRTMPStreams(_ context.Context, roomName livekit.RoomName) ([]*RTMPStream, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var streams []*RTMPStream
+	for _, stream := range s.rtmpStreams {
+		if roomName == "" || stream.Room == string(roomName) {
+			streams = append(streams, stream.clone())
+		}
+	}
+	return streams, nil
+}
+
+func (s *LocalStore) DeleteRTMPStream(_ context.Context, egressID string) error {
+	s.lock.Lock()
+	delete(s.rtmpStreams, egressID)
+	s.lock.Unlock()
+	return nil
+}
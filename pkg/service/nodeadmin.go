@@ -0,0 +1,459 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"go.uber.org/atomic"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+	"github.com/livekit/livekit-server/version"
+)
+
+const maxNodeAdminRequest = 4 * 1024
+
+type NodeStatusResponse struct {
+	NodeID          string            `json:"node_id"`
+	IP              string            `json:"ip"`
+	Region          string            `json:"region,omitempty"`
+	State           string            `json:"state"`
+	Version         string            `json:"version"`
+	Canary          bool              `json:"canary,omitempty"`
+	StartedAt       int64             `json:"started_at"`
+	NumRooms        int               `json:"num_rooms"`
+	NumParticipants int               `json:"num_participants"`
+	CPULoad         float32           `json:"cpu_load"`
+	BitrateIn       float32           `json:"bitrate_in"`
+	BitrateOut      float32           `json:"bitrate_out"`
+	PacketsInRate   float32           `json:"packets_in_per_sec"`
+	PacketsOutRate  float32           `json:"packets_out_per_sec"`
+	NumTracksIn     int32             `json:"num_tracks_in"`
+	NumTracksOut    int32             `json:"num_tracks_out"`
+	Headroom        float32           `json:"headroom"`
+	LogLevel        string            `json:"log_level"`
+	ComponentLevels map[string]string `json:"component_levels,omitempty"`
+	PProfEnabled    bool              `json:"pprof_enabled"`
+}
+
+type DrainNodeRequest struct {
+	// migrate rooms to other nodes, defaults to drain.migrate of the config
+	Migrate *bool `json:"migrate,omitempty"`
+}
+
+type SetLogLevelRequest struct {
+	Level string `json:"level"`
+	// sets the level of a component such as "transport.pion" instead of the default level
+	Component string `json:"component,omitempty"`
+}
+
+type SetLogLevelResponse struct {
+	LogLevel        string            `json:"log_level"`
+	ComponentLevels map[string]string `json:"component_levels,omitempty"`
+}
+
+type SetPProfRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+type SetPProfResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+type ListWebhookDeliveriesRequest struct {
+	// filters by event name and delivery status when set
+	Event  string `json:"event,omitempty"`
+	Status string `json:"status,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+type ListWebhookDeliveriesResponse struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+}
+
+type ResendWebhookRequest struct {
+	ID string `json:"id"`
+}
+
+// ICEPolicy is the ice candidate policy of the node, as set in the ice_policy of the rtc config
+type ICEPolicy struct {
+	AllowedInterfaces     []string        `json:"allowed_interfaces,omitempty"`
+	DeniedInterfaces      []string        `json:"denied_interfaces,omitempty"`
+	AllowedSubnets        []string        `json:"allowed_subnets,omitempty"`
+	DeniedSubnets         []string        `json:"denied_subnets,omitempty"`
+	ExternalIPs           []ICEExternalIP `json:"external_ips,omitempty"`
+	RelayOnlyRooms        []string        `json:"relay_only_rooms,omitempty"`
+	RelayOnlyParticipants []string        `json:"relay_only_participants,omitempty"`
+}
+
+type ICEExternalIP struct {
+	Subnet     string `json:"subnet"`
+	ExternalIP string `json:"external_ip"`
+}
+
+func icePolicyFromConfig(conf config.ICEPolicyConfig) *ICEPolicy {
+	policy := &ICEPolicy{
+		AllowedInterfaces:     conf.AllowedInterfaces,
+		DeniedInterfaces:      conf.DeniedInterfaces,
+		AllowedSubnets:        conf.AllowedSubnets,
+		DeniedSubnets:         conf.DeniedSubnets,
+		RelayOnlyRooms:        conf.RelayOnlyRooms,
+		RelayOnlyParticipants: conf.RelayOnlyParticipants,
+	}
+	for _, mapping := range conf.ExternalIPs {
+		policy.ExternalIPs = append(policy.ExternalIPs, ICEExternalIP{Subnet: mapping.Subnet, ExternalIP: mapping.ExternalIP})
+	}
+	return policy
+}
+
+func (p *ICEPolicy) toConfig() config.ICEPolicyConfig {
+	conf := config.ICEPolicyConfig{
+		AllowedInterfaces:     p.AllowedInterfaces,
+		DeniedInterfaces:      p.DeniedInterfaces,
+		AllowedSubnets:        p.AllowedSubnets,
+		DeniedSubnets:         p.DeniedSubnets,
+		RelayOnlyRooms:        p.RelayOnlyRooms,
+		RelayOnlyParticipants: p.RelayOnlyParticipants,
+	}
+	for _, mapping := range p.ExternalIPs {
+		conf.ExternalIPs = append(conf.ExternalIPs, config.ICEExternalIPMapping{Subnet: mapping.Subnet, ExternalIP: mapping.ExternalIP})
+	}
+	return conf
+}
+
+type AdminOperationsResponse struct {
+	Operations map[string]AdminOperationState `json:"operations"`
+}
+
+// NodeAdminService serves the admin API of this node on the admin port.
+// Status, admin operations, webhook deliveries and the ice policy require roomList, draining, log levels,
+// pprof, webhook resends and ice policy updates require roomCreate.
+type NodeAdminService struct {
+	conf         *config.Config
+	currentNode  routing.LocalNode
+	drainer      *NodeDrainer
+	roomManager  *RoomManager
+	notifier     *WebhookNotifier
+	adminLimiter *AdminLimiter
+	// log levels are changed through the reloader, which also changes them on reloads
+	reloader *ConfigReloader
+
+	pprofEnabled atomic.Bool
+}
+
+func NewNodeAdminService(
+	conf *config.Config,
+	currentNode routing.LocalNode,
+	drainer *NodeDrainer,
+	roomManager *RoomManager,
+	notifier *WebhookNotifier,
+	adminLimiter *AdminLimiter,
+	reloader *ConfigReloader,
+) *NodeAdminService {
+	s := &NodeAdminService{
+		conf:         conf,
+		currentNode:  currentNode,
+		drainer:      drainer,
+		roomManager:  roomManager,
+		notifier:     notifier,
+		adminLimiter: adminLimiter,
+		reloader:     reloader,
+	}
+	s.pprofEnabled.Store(conf.Admin.PProf)
+	return s
+}
+
+func (s *NodeAdminService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/debug/pprof/") {
+		s.servePProf(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/node/") {
+	case "status":
+		res, err = s.GetStatus(r.Context())
+	case "drain":
+		var req DrainNodeRequest
+		if r.ContentLength != 0 {
+			err = decodeJSONRequest(r, &req, maxNodeAdminRequest)
+		}
+		if err == nil {
+			res, err = s.Drain(r.Context(), &req)
+		}
+	case "drain_status":
+		res, err = s.GetDrainStatus(r.Context())
+	case "log_level":
+		var req SetLogLevelRequest
+		if err = decodeJSONRequest(r, &req, maxNodeAdminRequest); err == nil {
+			res, err = s.SetLogLevel(r.Context(), &req)
+		}
+	case "pprof":
+		var req SetPProfRequest
+		if err = decodeJSONRequest(r, &req, maxNodeAdminRequest); err == nil {
+			res, err = s.SetPProf(r.Context(), &req)
+		}
+	case "admin_operations":
+		res, err = s.GetAdminOperations(r.Context())
+	case "webhook_deliveries":
+		var req ListWebhookDeliveriesRequest
+		if r.ContentLength != 0 {
+			err = decodeJSONRequest(r, &req, maxNodeAdminRequest)
+		}
+		if err == nil {
+			res, err = s.ListWebhookDeliveries(r.Context(), &req)
+		}
+	case "webhook_deliveries/resend":
+		var req ResendWebhookRequest
+		if err = decodeJSONRequest(r, &req, maxNodeAdminRequest); err == nil {
+			res, err = s.ResendWebhook(r.Context(), &req)
+		}
+	case "ice_policy":
+		res, err = s.GetICEPolicy(r.Context())
+	case "ice_policy/update":
+		var req ICEPolicy
+		if err = decodeJSONRequest(r, &req, maxNodeAdminRequest); err == nil {
+			res, err = s.UpdateICEPolicy(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *NodeAdminService) GetStatus(ctx context.Context) (*NodeStatusResponse, error) {
+	if err := EnsureListPermission(ctx); err != nil {
+		return nil, err
+	}
+
+	node := s.currentNode.Clone()
+	numRooms, numParticipants := s.roomManager.LocalCounts()
+	res := &NodeStatusResponse{
+		NodeID:          node.Id,
+		IP:              node.Ip,
+		Region:          node.Region,
+		State:           node.State.String(),
+		Version:         version.Version,
+		Canary:          s.currentNode.Build().Canary,
+		NumRooms:        numRooms,
+		NumParticipants: numParticipants,
+		PProfEnabled:    s.pprofEnabled.Load(),
+	}
+	if node.Stats != nil {
+		res.StartedAt = node.Stats.StartedAt
+		res.CPULoad = node.Stats.CpuLoad
+		res.BitrateIn = node.Stats.BytesInPerSec * 8
+		res.BitrateOut = node.Stats.BytesOutPerSec * 8
+		res.PacketsInRate = node.Stats.PacketsInPerSec
+		res.PacketsOutRate = node.Stats.PacketsOutPerSec
+		res.NumTracksIn = node.Stats.NumTracksIn
+		res.NumTracksOut = node.Stats.NumTracksOut
+	}
+	res.Headroom = selector.GetNodeHeadroom(node, selector.NewHeadroomSelector(s.conf).Capacity)
+
+	_ = s.reloader.WithLogging(func(conf *logger.Config) error {
+		res.LogLevel = conf.Level
+		res.ComponentLevels = maps.Clone(conf.ComponentLevels)
+		return nil
+	})
+	return res, nil
+}
+
+// Drain stops new rooms from being allocated to this node, then migrates the rooms on it when requested
+func (s *NodeAdminService) Drain(ctx context.Context, req *DrainNodeRequest) (*DrainProgress, error) {
+	if err := EnsureCreatePermission(ctx); err != nil {
+		return nil, err
+	}
+
+	migrate := s.conf.Drain.Migrate
+	if req.Migrate != nil {
+		migrate = *req.Migrate
+	}
+	logger.Infow("draining node on admin request", "nodeID", s.currentNode.NodeID(), "migrate", migrate)
+	s.drainer.Start(migrate)
+	progress := s.drainer.Progress()
+	return &progress, nil
+}
+
+func (s *NodeAdminService) GetDrainStatus(ctx context.Context) (*DrainProgress, error) {
+	if err := EnsureListPermission(ctx); err != nil {
+		return nil, err
+	}
+	progress := s.drainer.Progress()
+	return &progress, nil
+}
+
+func (s *NodeAdminService) SetLogLevel(ctx context.Context, req *SetLogLevelRequest) (*SetLogLevelResponse, error) {
+	AppendLogFields(ctx, "level", req.Level, "component", req.Component)
+	if err := EnsureCreatePermission(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := zapcore.ParseLevel(req.Level); err != nil || req.Level == "" {
+		return nil, fmt.Errorf("%w: unknown log level %q", ErrNodeAdminInvalid, req.Level)
+	}
+
+	res := &SetLogLevelResponse{}
+	err := s.reloader.WithLogging(func(current *logger.Config) error {
+		updated := &logger.Config{
+			JSON:               current.JSON,
+			Level:              current.Level,
+			ComponentLevels:    maps.Clone(current.ComponentLevels),
+			Sample:             current.Sample,
+			SampleInitial:      current.SampleInitial,
+			SampleInterval:     current.SampleInterval,
+			ItemSampleSeconds:  current.ItemSampleSeconds,
+			ItemSampleInitial:  current.ItemSampleInitial,
+			ItemSampleInterval: current.ItemSampleInterval,
+		}
+		if req.Component == "" {
+			updated.Level = req.Level
+		} else {
+			if updated.ComponentLevels == nil {
+				updated.ComponentLevels = map[string]string{}
+			}
+			updated.ComponentLevels[req.Component] = req.Level
+		}
+		if err := current.Update(updated); err != nil {
+			return err
+		}
+		res.LogLevel = current.Level
+		res.ComponentLevels = maps.Clone(current.ComponentLevels)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *NodeAdminService) SetPProf(ctx context.Context, req *SetPProfRequest) (*SetPProfResponse, error) {
+	AppendLogFields(ctx, "enabled", req.Enabled)
+	if err := EnsureCreatePermission(ctx); err != nil {
+		return nil, err
+	}
+
+	s.pprofEnabled.Store(req.Enabled)
+	return &SetPProfResponse{Enabled: req.Enabled}, nil
+}
+
+// GetAdminOperations returns the queues of the heavy admin operations of this node
+func (s *NodeAdminService) GetAdminOperations(ctx context.Context) (*AdminOperationsResponse, error) {
+	if err := EnsureListPermission(ctx); err != nil {
+		return nil, err
+	}
+	return &AdminOperationsResponse{Operations: s.adminLimiter.State()}, nil
+}
+
+// ListWebhookDeliveries returns the recent webhook deliveries of this node, most recent first
+func (s *NodeAdminService) ListWebhookDeliveries(ctx context.Context, req *ListWebhookDeliveriesRequest) (*ListWebhookDeliveriesResponse, error) {
+	if err := EnsureListPermission(ctx); err != nil {
+		return nil, err
+	}
+	if req.Limit < 0 {
+		return nil, fmt.Errorf("%w: limit cannot be negative", ErrNodeAdminInvalid)
+	}
+	return &ListWebhookDeliveriesResponse{
+		Deliveries: s.notifier.ListDeliveries(req.Event, req.Status, req.Limit),
+	}, nil
+}
+
+// ResendWebhook delivers the event of a recent delivery again
+func (s *NodeAdminService) ResendWebhook(ctx context.Context, req *ResendWebhookRequest) (*WebhookDelivery, error) {
+	AppendLogFields(ctx, "deliveryID", req.ID)
+	if err := EnsureCreatePermission(ctx); err != nil {
+		return nil, err
+	}
+	if req.ID == "" {
+		return nil, fmt.Errorf("%w: id is required", ErrNodeAdminInvalid)
+	}
+	return s.notifier.Resend(req.ID)
+}
+
+func (s *NodeAdminService) GetICEPolicy(ctx context.Context) (*ICEPolicy, error) {
+	if err := EnsureListPermission(ctx); err != nil {
+		return nil, err
+	}
+	return icePolicyFromConfig(s.roomManager.ICEPolicy().Config()), nil
+}
+
+// UpdateICEPolicy replaces the ice policy of this node until it restarts, it applies to new peer connections
+func (s *NodeAdminService) UpdateICEPolicy(ctx context.Context, req *ICEPolicy) (*ICEPolicy, error) {
+	if err := EnsureCreatePermission(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.roomManager.ICEPolicy().Update(req.toConfig()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNodeAdminInvalid, err)
+	}
+	return icePolicyFromConfig(s.roomManager.ICEPolicy().Config()), nil
+}
+
+func (s *NodeAdminService) servePProf(w http.ResponseWriter, r *http.Request) {
+	if !s.pprofEnabled.Load() {
+		http.NotFound(w, r)
+		return
+	}
+	if err := EnsureCreatePermission(r.Context()); err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	switch name := strings.TrimPrefix(r.URL.Path, "/debug/pprof/"); name {
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile", "trace":
+		release, err := s.adminLimiter.Acquire(r.Context(), AdminOpProfile)
+		if err != nil {
+			handleError(w, r, apiErrorStatus(err), err)
+			return
+		}
+		defer release()
+		if name == "profile" {
+			pprof.Profile(w, r)
+		} else {
+			pprof.Trace(w, r)
+		}
+	case "symbol":
+		pprof.Symbol(w, r)
+	default:
+		pprof.Index(w, r)
+	}
+}
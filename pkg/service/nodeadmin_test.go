@@ -0,0 +1,82 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestNodeAdminService(t *testing.T) {
+	conf := &config.Config{}
+	conf.Logging.Level = "info"
+	node, err := routing.NewLocalNode(nil)
+	require.NoError(t, err)
+	router := &routingfakes.FakeRouter{}
+	svc := service.NewNodeAdminService(conf, node, service.NewNodeDrainer(conf, router, nil), nil, nil, service.NewAdminLimiter(conf), service.NewConfigReloader(conf, nil, nil, nil, nil, nil, nil))
+
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomList: true, RoomCreate: true},
+	}, "")
+
+	t.Run("drain", func(t *testing.T) {
+		_, err := svc.Drain(context.Background(), &service.DrainNodeRequest{})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+		_, err = svc.GetDrainStatus(context.Background())
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+		require.Equal(t, 0, router.DrainCallCount())
+	})
+
+	t.Run("log level", func(t *testing.T) {
+		_, err := svc.SetLogLevel(ctx, &service.SetLogLevelRequest{Level: "verbose"})
+		require.ErrorIs(t, err, service.ErrNodeAdminInvalid)
+
+		res, err := svc.SetLogLevel(ctx, &service.SetLogLevelRequest{Level: "debug"})
+		require.NoError(t, err)
+		require.Equal(t, "debug", res.LogLevel)
+		require.Equal(t, "debug", conf.Logging.Level)
+
+		res, err = svc.SetLogLevel(ctx, &service.SetLogLevelRequest{Level: "warn", Component: "transport.pion"})
+		require.NoError(t, err)
+		require.Equal(t, "debug", res.LogLevel)
+		require.Equal(t, map[string]string{"transport.pion": "warn"}, res.ComponentLevels)
+	})
+
+	t.Run("pprof", func(t *testing.T) {
+		serve := func(ctx context.Context) int {
+			w := httptest.NewRecorder()
+			svc.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil).WithContext(ctx))
+			return w.Code
+		}
+		require.Equal(t, http.StatusNotFound, serve(ctx))
+
+		res, err := svc.SetPProf(ctx, &service.SetPProfRequest{Enabled: true})
+		require.NoError(t, err)
+		require.True(t, res.Enabled)
+		require.Equal(t, http.StatusOK, serve(ctx))
+		require.Equal(t, http.StatusUnauthorized, serve(context.Background()))
+	})
+}
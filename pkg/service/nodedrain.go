@@ -0,0 +1,151 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	DrainStateServing   = "serving"
+	DrainStateDraining  = "draining"
+	DrainStateMigrating = "migrating"
+	DrainStateDrained   = "drained"
+)
+
+type DrainProgress struct {
+	State     string `json:"state"`
+	StartedAt int64  `json:"started_at,omitempty"`
+	// rooms considered for migration, and how many of them were moved or could not be
+	RoomsTotal    int `json:"rooms_total"`
+	RoomsMigrated int `json:"rooms_migrated"`
+	RoomsFailed   int `json:"rooms_failed"`
+	// still hosted on this node
+	RoomsRemaining        int `json:"rooms_remaining"`
+	ParticipantsRemaining int `json:"participants_remaining"`
+}
+
+// NodeDrainer stops new rooms from being allocated to this node, and optionally migrates the rooms
+// it hosts to other nodes, one at a time
+type NodeDrainer struct {
+	conf        config.DrainConfig
+	router      routing.Router
+	roomManager *RoomManager
+
+	lock     sync.Mutex
+	draining bool
+	// rooms are being migrated
+	migrating bool
+	progress  DrainProgress
+}
+
+func NewNodeDrainer(conf *config.Config, router routing.Router, roomManager *RoomManager) *NodeDrainer {
+	return &NodeDrainer{
+		conf:        conf.Drain,
+		router:      router,
+		roomManager: roomManager,
+	}
+}
+
+// Start drains the node, rooms are migrated when migrate is set. It can be called again to migrate
+// the rooms of a node that is already draining, including rooms that failed to migrate before.
+func (d *NodeDrainer) Start(migrate bool) {
+	d.lock.Lock()
+	if !d.draining {
+		d.draining = true
+		d.progress.StartedAt = time.Now().Unix()
+		d.router.Drain()
+	}
+	startMigration := migrate && !d.migrating
+	if startMigration {
+		d.migrating = true
+	}
+	d.lock.Unlock()
+
+	if startMigration {
+		go d.migrateRooms()
+	}
+}
+
+func (d *NodeDrainer) Progress() DrainProgress {
+	d.lock.Lock()
+	progress := d.progress
+	draining, migrating := d.draining, d.migrating
+	d.lock.Unlock()
+
+	progress.RoomsRemaining, progress.ParticipantsRemaining = d.roomManager.LocalCounts()
+	switch {
+	case !draining:
+		progress.State = DrainStateServing
+	case progress.RoomsRemaining == 0:
+		progress.State = DrainStateDrained
+	case migrating:
+		progress.State = DrainStateMigrating
+	default:
+		progress.State = DrainStateDraining
+	}
+	return progress
+}
+
+func (d *NodeDrainer) migrateRooms() {
+	d.roomManager.lock.RLock()
+	rooms := make([]*rtc.Room, 0, len(d.roomManager.rooms))
+	for _, room := range d.roomManager.rooms {
+		rooms = append(rooms, room)
+	}
+	d.roomManager.lock.RUnlock()
+
+	d.lock.Lock()
+	d.progress.RoomsTotal = len(rooms)
+	d.progress.RoomsMigrated = 0
+	d.progress.RoomsFailed = 0
+	d.lock.Unlock()
+	logger.Infow("migrating rooms of draining node", "rooms", len(rooms))
+
+	for i, room := range rooms {
+		if room.IsClosed() {
+			continue
+		}
+		if i > 0 && d.conf.MigrationInterval > 0 {
+			time.Sleep(d.conf.MigrationInterval)
+		}
+
+		err := d.roomManager.migrateRoom(context.Background(), room)
+		d.lock.Lock()
+		if err != nil {
+			d.progress.RoomsFailed++
+		} else {
+			d.progress.RoomsMigrated++
+		}
+		d.lock.Unlock()
+		if err != nil {
+			room.Logger.Warnw("could not migrate room", err)
+		}
+	}
+
+	d.lock.Lock()
+	d.migrating = false
+	progress := d.progress
+	d.lock.Unlock()
+	logger.Infow("room migration finished", "migrated", progress.RoomsMigrated, "failed", progress.RoomsFailed)
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+)
+
+const nodeLatencyProbeTimeout = 2 * time.Second
+
+// NodeLatencyProber measures the round trip times from this node to the other nodes of the cluster for the
+// latencyaware node selector. It times TCP handshakes with the HTTP port of the nodes, which all nodes are
+// expected to share.
+type NodeLatencyProber struct {
+	interval    time.Duration
+	port        uint32
+	router      routing.Router
+	currentNode routing.LocalNode
+	latencies   *selector.NodeLatencies
+
+	done chan struct{}
+}
+
+// NewNodeLatencyProber returns nil unless the latencyaware node selector is configured
+func NewNodeLatencyProber(
+	conf *config.Config,
+	router routing.Router,
+	currentNode routing.LocalNode,
+	latencies *selector.NodeLatencies,
+) *NodeLatencyProber {
+	if conf.NodeSelector.Kind != "latencyaware" {
+		return nil
+	}
+	return &NodeLatencyProber{
+		interval:    conf.NodeSelector.LatencyAware.ProbeInterval,
+		port:        conf.Port,
+		router:      router,
+		currentNode: currentNode,
+		latencies:   latencies,
+		done:        make(chan struct{}),
+	}
+}
+
+func (p *NodeLatencyProber) Start() {
+	go p.worker()
+}
+
+func (p *NodeLatencyProber) Stop() {
+	select {
+	case <-p.done:
+	default:
+		close(p.done)
+	}
+}
+
+func (p *NodeLatencyProber) worker() {
+	interval := p.interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	p.probeNodes()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.probeNodes()
+		}
+	}
+}
+
+func (p *NodeLatencyProber) probeNodes() {
+	nodes, err := p.router.ListNodes()
+	if err != nil {
+		logger.Warnw("could not list nodes to measure latencies", err)
+		return
+	}
+
+	listed := make(map[string]bool)
+	var wg sync.WaitGroup
+	for _, node := range selector.GetAvailableNodes(nodes) {
+		if livekit.NodeID(node.Id) == p.currentNode.NodeID() || node.Ip == "" {
+			continue
+		}
+		listed[node.Id] = true
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rtt, err := p.probe(node.Ip)
+			if err != nil {
+				logger.Debugw("could not measure node latency", "nodeID", node.Id, "region", node.Region, "error", err)
+				return
+			}
+			p.latencies.Update(node.Id, rtt)
+		}()
+	}
+	wg.Wait()
+	p.latencies.Retain(listed)
+}
+
+func (p *NodeLatencyProber) probe(ip string) (time.Duration, error) {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip, strconv.Itoa(int(p.port))), nodeLatencyProbeTimeout)
+	if err != nil {
+		return 0, err
+	}
+	rtt := time.Since(start)
+	_ = conn.Close()
+	return rtt, nil
+}
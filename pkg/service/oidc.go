@@ -0,0 +1,365 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+const (
+	defaultOIDCJWKSRefresh   = time.Hour
+	defaultOIDCIdentityClaim = "sub"
+	defaultOIDCNameClaim     = "name"
+	// keys are fetched again for unknown key IDs at most this often
+	oidcJWKSMinRefresh = time.Minute
+	// failed fetches are retried after this delay, doubling up to oidcMaxFetchBackoff
+	oidcFetchBackoff    = 5 * time.Second
+	oidcMaxFetchBackoff = 5 * time.Minute
+	oidcFetchTimeout    = 5 * time.Second
+	oidcClockLeeway     = time.Minute
+	maxOIDCResponse     = 1024 * 1024
+
+	oidcRoomParam = "room"
+)
+
+var ErrOIDCInvalidToken = errors.New("invalid identity provider token")
+
+// asymmetric algorithms only, a token signed with a shared secret would have to be signed with the public key
+var oidcAlgorithms = []string{
+	string(jose.RS256), string(jose.RS384), string(jose.RS512),
+	string(jose.PS256), string(jose.PS384), string(jose.PS512),
+	string(jose.ES256), string(jose.ES384), string(jose.ES512),
+	string(jose.EdDSA),
+}
+
+// OIDCVerifier verifies access tokens of an OpenID Connect identity provider and maps their claims to grants
+type OIDCVerifier struct {
+	conf   config.OIDCConfig
+	client *http.Client
+
+	lock      sync.Mutex
+	keys      *jose.JSONWebKeySet
+	fetchedAt time.Time
+	// last fetch attempt and failed attempts since the last success, to throttle fetches
+	attemptedAt time.Time
+	failures    int
+	// closed when the fetch in progress completes, shared by all requests waiting for keys
+	fetching chan struct{}
+}
+
+// NewOIDCVerifier returns nil when OIDC is not configured
+func NewOIDCVerifier(conf *config.Config) *OIDCVerifier {
+	if !conf.OIDC.Enabled() {
+		return nil
+	}
+	return &OIDCVerifier{
+		conf:   conf.OIDC,
+		client: &http.Client{Timeout: oidcFetchTimeout},
+	}
+}
+
+// Handles returns whether tokens of the issuer are verified by the identity provider
+func (v *OIDCVerifier) Handles(issuer string) bool {
+	return issuer == v.conf.Issuer
+}
+
+// Verify checks the signature, issuer, audience and validity of a token and returns the grants of its user.
+// The room of the request is used when the token has none and room_from_request is set.
+func (v *OIDCVerifier) Verify(ctx context.Context, raw string, r *http.Request) (*auth.ClaimGrants, error) {
+	tok, err := jwt.ParseSigned(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCInvalidToken, err)
+	}
+	if len(tok.Headers) != 1 || !slices.Contains(oidcAlgorithms, tok.Headers[0].Algorithm) {
+		return nil, fmt.Errorf("%w: unsupported signature algorithm", ErrOIDCInvalidToken)
+	}
+	key, err := v.key(ctx, tok.Headers[0].KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		std    jwt.Claims
+		claims map[string]any
+	)
+	if err = tok.Claims(key.Key, &std, &claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCInvalidToken, err)
+	}
+	if err = std.ValidateWithLeeway(jwt.Expected{
+		Issuer:   v.conf.Issuer,
+		Audience: jwt.Audience{v.conf.Audience},
+		Time:     utils.ClusterNow(),
+	}, oidcClockLeeway); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCInvalidToken, err)
+	}
+	return v.grants(claims, r)
+}
+
+func (v *OIDCVerifier) grants(claims map[string]any, r *http.Request) (*auth.ClaimGrants, error) {
+	identity, _ := claims[withDefault(v.conf.IdentityClaim, defaultOIDCIdentityClaim)].(string)
+	if identity == "" {
+		return nil, fmt.Errorf("%w: no identity claim", ErrOIDCInvalidToken)
+	}
+	name, _ := claims[withDefault(v.conf.NameClaim, defaultOIDCNameClaim)].(string)
+
+	grant := v.conf.Grants
+	for _, role := range oidcRoles(claims[v.conf.RolesClaim]) {
+		if roleGrant, ok := v.conf.RoleGrants[role]; ok {
+			grant = mergeOIDCGrants(grant, roleGrant)
+		}
+	}
+
+	video := &auth.VideoGrant{
+		RoomJoin:             grant.RoomJoin,
+		RoomAdmin:            grant.RoomAdmin,
+		RoomList:             grant.RoomList,
+		RoomCreate:           grant.RoomCreate,
+		CanPublish:           grant.CanPublish,
+		CanSubscribe:         grant.CanSubscribe,
+		CanPublishData:       grant.CanPublishData,
+		CanPublishSources:    grant.CanPublishSources,
+		CanUpdateOwnMetadata: &grant.CanUpdateOwnMetadata,
+		Hidden:               grant.Hidden,
+	}
+	if v.conf.RoomClaim != "" {
+		video.Room, _ = claims[v.conf.RoomClaim].(string)
+	}
+	if video.Room == "" && v.conf.RoomFromRequest && r != nil {
+		video.Room = r.URL.Query().Get(oidcRoomParam)
+	}
+
+	return &auth.ClaimGrants{
+		Identity: identity,
+		Name:     name,
+		Video:    video,
+	}, nil
+}
+
+// key returns the key a token was signed with, fetching the keys again when it is unknown
+func (v *OIDCVerifier) key(ctx context.Context, kid string) (*jose.JSONWebKey, error) {
+	v.lock.Lock()
+	keys, fetchedAt := v.keys, v.fetchedAt
+	v.lock.Unlock()
+
+	refresh := withDefault(v.conf.JWKSRefresh, defaultOIDCJWKSRefresh)
+	if keys == nil || time.Since(fetchedAt) > refresh {
+		keys = v.refreshKeys(ctx)
+	}
+	if k := findOIDCKey(keys, kid); k != nil {
+		return k, nil
+	}
+	// keys may have been rotated, refreshes are throttled as any token can carry an unknown key ID
+	if refreshed := v.refreshKeys(ctx); refreshed != keys {
+		if k := findOIDCKey(refreshed, kid); k != nil {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown signing key", ErrOIDCInvalidToken)
+}
+
+// refreshKeys fetches the keys unless they were fetched recently or fetches are backing off after failures,
+// and returns the current keys. Concurrent requests wait for the same fetch.
+func (v *OIDCVerifier) refreshKeys(ctx context.Context) *jose.JSONWebKeySet {
+	v.lock.Lock()
+	if v.fetching == nil {
+		if !v.attemptedAt.IsZero() && time.Since(v.attemptedAt) < v.fetchIntervalLocked() {
+			keys := v.keys
+			v.lock.Unlock()
+			return keys
+		}
+		v.attemptedAt = time.Now()
+		v.fetching = make(chan struct{})
+		go v.fetchKeys(v.fetching)
+	}
+	done := v.fetching
+	v.lock.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.keys
+}
+
+func (v *OIDCVerifier) fetchIntervalLocked() time.Duration {
+	if v.failures == 0 {
+		return oidcJWKSMinRefresh
+	}
+	return min(oidcFetchBackoff<<min(v.failures-1, 16), oidcMaxFetchBackoff)
+}
+
+// fetchKeys keeps the previous keys when the identity provider cannot be reached
+func (v *OIDCVerifier) fetchKeys(done chan struct{}) {
+	// not bound to the request starting it, other requests wait for the result
+	ctx, cancel := context.WithTimeout(context.Background(), 2*oidcFetchTimeout)
+	defer cancel()
+
+	keys, err := v.getKeys(ctx)
+	v.lock.Lock()
+	if err != nil {
+		v.failures++
+		logger.Warnw("could not fetch identity provider keys", err, "issuer", v.conf.Issuer, "failures", v.failures)
+	} else {
+		v.keys = keys
+		v.fetchedAt = time.Now()
+		v.failures = 0
+	}
+	v.fetching = nil
+	v.lock.Unlock()
+	close(done)
+}
+
+func (v *OIDCVerifier) getKeys(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	url := v.conf.JWKSURL
+	if url == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := v.getJSON(ctx, strings.TrimSuffix(v.conf.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, fmt.Errorf("discovery failed: %w", err)
+		}
+		url = discovery.JWKSURI
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := v.getJSON(ctx, url, &keys); err != nil {
+		return nil, err
+	}
+	return &keys, nil
+}
+
+// findOIDCKey returns the signature key with the key ID, keys for other uses are ignored
+func findOIDCKey(keys *jose.JSONWebKeySet, kid string) *jose.JSONWebKey {
+	if keys == nil {
+		return nil
+	}
+	var found *jose.JSONWebKey
+	for i := range keys.Keys {
+		k := &keys.Keys[i]
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if kid == "" {
+			// only unambiguous without a key ID
+			if found != nil {
+				return nil
+			}
+			found = k
+		} else if k.KeyID == kid {
+			return k
+		}
+	}
+	return found
+}
+
+func (v *OIDCVerifier) getJSON(ctx context.Context, url string, out any) error {
+	if url == "" {
+		return errors.New("no url")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxOIDCResponse)).Decode(out)
+}
+
+func oidcRoles(claim any) []string {
+	switch roles := claim.(type) {
+	case string:
+		return strings.Fields(roles)
+	case []any:
+		out := make([]string, 0, len(roles))
+		for _, role := range roles {
+			if s, ok := role.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// mergeOIDCGrants grants a permission when either grant does
+func mergeOIDCGrants(a, b config.OIDCGrantConfig) config.OIDCGrantConfig {
+	// unset means allowed, like in VideoGrant
+	anyTrue := func(x, y *bool) *bool {
+		switch {
+		case x == nil || y == nil:
+			return nil
+		case *x:
+			return x
+		default:
+			return y
+		}
+	}
+	merged := config.OIDCGrantConfig{
+		RoomJoin:             a.RoomJoin || b.RoomJoin,
+		RoomAdmin:            a.RoomAdmin || b.RoomAdmin,
+		RoomList:             a.RoomList || b.RoomList,
+		RoomCreate:           a.RoomCreate || b.RoomCreate,
+		CanPublish:           anyTrue(a.CanPublish, b.CanPublish),
+		CanSubscribe:         anyTrue(a.CanSubscribe, b.CanSubscribe),
+		CanPublishData:       anyTrue(a.CanPublishData, b.CanPublishData),
+		CanUpdateOwnMetadata: a.CanUpdateOwnMetadata || b.CanUpdateOwnMetadata,
+		Hidden:               a.Hidden || b.Hidden,
+	}
+	// no sources means all of them
+	if len(a.CanPublishSources) != 0 && len(b.CanPublishSources) != 0 {
+		merged.CanPublishSources = slices.Clone(a.CanPublishSources)
+		for _, source := range b.CanPublishSources {
+			if !slices.Contains(merged.CanPublishSources, source) {
+				merged.CanPublishSources = append(merged.CanPublishSources, source)
+			}
+		}
+	}
+	return merged
+}
+
+func withDefault[T comparable](value, def T) T {
+	var zero T
+	if value == zero {
+		return def
+	}
+	return value
+}
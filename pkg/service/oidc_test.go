@@ -0,0 +1,238 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/auth"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestOIDCAuthentication(t *testing.T) {
+	const (
+		apiKey   = "APIkey"
+		secret   = "somesecretencodedinbase62extendto32bytes"
+		audience = "livekit"
+	)
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	encryption, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var (
+		issuer      string
+		keysFetched atomic.Int32
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		keysFetched.Inc()
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &priv.PublicKey, KeyID: "k1", Algorithm: string(jose.RS256), Use: "sig"},
+			{Key: &encryption.PublicKey, KeyID: "k2", Algorithm: string(jose.RSA_OAEP), Use: "enc"},
+		}})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	issuer = srv.URL
+
+	newTokenWithKey := func(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+		sig, err := jose.NewSigner(
+			jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: key, KeyID: kid}},
+			(&jose.SignerOptions{}).WithType("JWT"),
+		)
+		require.NoError(t, err)
+		c := map[string]any{
+			"iss": issuer,
+			"aud": audience,
+			"exp": time.Now().Add(time.Hour).Unix(),
+			"iat": time.Now().Unix(),
+		}
+		for k, v := range claims {
+			c[k] = v
+		}
+		token, err := jwt.Signed(sig).Claims(c).CompactSerialize()
+		require.NoError(t, err)
+		return token
+	}
+	newToken := func(t *testing.T, claims map[string]any) string {
+		return newTokenWithKey(t, priv, "k1", claims)
+	}
+
+	yes, no := true, false
+	authn := service.NewAPIKeyAuthMiddleware(auth.NewFileBasedKeyProviderFromMap(map[string]string{apiKey: secret}))
+	authn.SetOIDCVerifier(service.NewOIDCVerifier(&config.Config{OIDC: config.OIDCConfig{
+		Issuer:          issuer,
+		Audience:        audience,
+		RoomClaim:       "room",
+		RoomFromRequest: true,
+		RolesClaim:      "roles",
+		Grants:          config.OIDCGrantConfig{RoomJoin: true, CanPublish: &no},
+		RoleGrants: map[string]config.OIDCGrantConfig{
+			"host": {RoomAdmin: true, CanPublish: &yes},
+		},
+	}}))
+	serve := func(token, query string) (int, *auth.ClaimGrants, string) {
+		r := httptest.NewRequest(http.MethodGet, "/rtc?"+query, nil)
+		service.SetAuthorizationToken(r, token)
+		w := httptest.NewRecorder()
+		var (
+			grants *auth.ClaimGrants
+			key    string
+		)
+		authn.ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+			grants = service.GetGrants(r.Context())
+			key = service.GetAPIKey(r.Context())
+			w.WriteHeader(http.StatusOK)
+		})
+		return w.Code, grants, key
+	}
+
+	t.Run("maps claims to grants", func(t *testing.T) {
+		code, grants, key := serve(newToken(t, map[string]any{"sub": "alice", "name": "Alice", "room": "main"}), "room=other")
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, key)
+		require.Equal(t, "alice", grants.Identity)
+		require.Equal(t, "Alice", grants.Name)
+		require.True(t, grants.Video.RoomJoin)
+		require.False(t, grants.Video.RoomAdmin)
+		require.Equal(t, "main", grants.Video.Room)
+		require.False(t, grants.Video.GetCanPublish())
+		require.True(t, grants.Video.GetCanSubscribe())
+	})
+
+	t.Run("room of the request", func(t *testing.T) {
+		code, grants, _ := serve(newToken(t, map[string]any{"sub": "alice"}), "room=other")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "other", grants.Video.Room)
+	})
+
+	t.Run("role grants", func(t *testing.T) {
+		code, grants, _ := serve(newToken(t, map[string]any{"sub": "bob", "room": "main", "roles": []string{"viewer", "host"}}), "")
+		require.Equal(t, http.StatusOK, code)
+		require.True(t, grants.Video.RoomAdmin)
+		require.True(t, grants.Video.GetCanPublish())
+	})
+
+	t.Run("rejects invalid tokens", func(t *testing.T) {
+		code, _, _ := serve(newToken(t, map[string]any{"sub": "alice", "aud": "other"}), "")
+		require.Equal(t, http.StatusUnauthorized, code)
+
+		code, _, _ = serve(newToken(t, map[string]any{"sub": "alice", "exp": time.Now().Add(-time.Hour).Unix()}), "")
+		require.Equal(t, http.StatusUnauthorized, code)
+
+		code, _, _ = serve(newToken(t, map[string]any{"room": "main"}), "")
+		require.Equal(t, http.StatusUnauthorized, code)
+
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: other, KeyID: "k1"}}, nil)
+		require.NoError(t, err)
+		forged, err := jwt.Signed(sig).Claims(map[string]any{
+			"iss": issuer, "aud": audience, "sub": "mallory", "exp": time.Now().Add(time.Hour).Unix(),
+		}).CompactSerialize()
+		require.NoError(t, err)
+		code, _, _ = serve(forged, "")
+		require.Equal(t, http.StatusUnauthorized, code)
+	})
+
+	t.Run("ignores keys not used for signatures", func(t *testing.T) {
+		code, _, _ := serve(newTokenWithKey(t, encryption, "k2", map[string]any{"sub": "mallory"}), "")
+		require.Equal(t, http.StatusUnauthorized, code)
+	})
+
+	t.Run("throttles fetches for unknown key IDs", func(t *testing.T) {
+		fetched := keysFetched.Load()
+		require.NotZero(t, fetched)
+
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		token := newTokenWithKey(t, other, "unknown", map[string]any{"sub": "mallory"})
+		codes := make([]int, 10)
+		var wg sync.WaitGroup
+		for i := range codes {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				codes[i], _, _ = serve(token, "")
+			}()
+		}
+		wg.Wait()
+		for _, code := range codes {
+			require.Equal(t, http.StatusUnauthorized, code)
+		}
+		require.Equal(t, fetched, keysFetched.Load())
+	})
+
+	t.Run("API key tokens", func(t *testing.T) {
+		token, err := auth.NewAccessToken(apiKey, secret).
+			SetIdentity("carol").
+			AddGrant(&auth.VideoGrant{RoomJoin: true, Room: "main"}).
+			ToJWT()
+		require.NoError(t, err)
+		code, grants, key := serve(token, "")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, apiKey, key)
+		require.Equal(t, "carol", grants.Identity)
+	})
+}
+
+func TestOIDCKeyFetchBackoff(t *testing.T) {
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Inc()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: priv, KeyID: "k1"}}, nil)
+	require.NoError(t, err)
+	token, err := jwt.Signed(sig).Claims(map[string]any{
+		"iss": srv.URL, "aud": "livekit", "sub": "alice", "exp": time.Now().Add(time.Hour).Unix(),
+	}).CompactSerialize()
+	require.NoError(t, err)
+
+	v := service.NewOIDCVerifier(&config.Config{OIDC: config.OIDCConfig{
+		Issuer:   srv.URL,
+		Audience: "livekit",
+		JWKSURL:  srv.URL + "/keys",
+	}})
+	// the identity provider is not called again until the backoff has passed
+	for i := 0; i < 5; i++ {
+		_, err = v.Verify(context.Background(), token, nil)
+		require.ErrorIs(t, err, service.ErrOIDCInvalidToken)
+	}
+	require.Equal(t, int32(1), fetches.Load())
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestMergeOIDCGrants(t *testing.T) {
+	allow, deny := true, false
+
+	for _, tc := range []struct {
+		name string
+		a, b *bool
+		want bool
+	}{
+		{"unset and unset", nil, nil, true},
+		{"unset and false", nil, &deny, true},
+		{"false and unset", &deny, nil, true},
+		{"unset and true", nil, &allow, true},
+		{"true and unset", &allow, nil, true},
+		{"false and false", &deny, &deny, false},
+		{"false and true", &deny, &allow, true},
+		{"true and false", &allow, &deny, true},
+		{"true and true", &allow, &allow, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			merged := mergeOIDCGrants(
+				config.OIDCGrantConfig{CanPublish: tc.a, CanSubscribe: tc.a, CanPublishData: tc.a},
+				config.OIDCGrantConfig{CanPublish: tc.b, CanSubscribe: tc.b, CanPublishData: tc.b},
+			)
+			// read the way the grants of the token are
+			video := &auth.VideoGrant{
+				CanPublish:     merged.CanPublish,
+				CanSubscribe:   merged.CanSubscribe,
+				CanPublishData: merged.CanPublishData,
+			}
+			require.Equal(t, tc.want, video.GetCanPublish())
+			require.Equal(t, tc.want, video.GetCanSubscribe())
+			require.Equal(t, tc.want, video.GetCanPublishData())
+		})
+	}
+
+	t.Run("publish sources", func(t *testing.T) {
+		merged := mergeOIDCGrants(
+			config.OIDCGrantConfig{CanPublishSources: []string{"camera"}},
+			config.OIDCGrantConfig{CanPublishSources: []string{"microphone", "camera"}},
+		)
+		require.Equal(t, []string{"camera", "microphone"}, merged.CanPublishSources)
+
+		// no sources means all of them
+		merged = mergeOIDCGrants(
+			config.OIDCGrantConfig{CanPublishSources: []string{"camera"}},
+			config.OIDCGrantConfig{},
+		)
+		require.Empty(t, merged.CanPublishSources)
+	})
+}
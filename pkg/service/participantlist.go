@@ -0,0 +1,180 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const (
+	defaultParticipantsPageSize = 100
+	maxParticipantsPageSize     = 1000
+
+	maxListParticipantsRequest = 16 * 1024
+)
+
+// ListParticipantsPageRequest is the JSON body of POST /participants/list. Participants are ordered by identity,
+// the next page starts after the identity given as page_token. With counts_only, only the totals of the
+// participants matching the filters are returned.
+type ListParticipantsPageRequest struct {
+	Room           string `json:"room"`
+	IdentityPrefix string `json:"identity_prefix,omitempty"`
+	// Metadata matches participants whose metadata contains it
+	Metadata string `json:"metadata,omitempty"`
+	// Attributes match participants having all of them with the same values
+	Attributes map[string]string `json:"attributes,omitempty"`
+	PageSize   int               `json:"page_size,omitempty"`
+	PageToken  string            `json:"page_token,omitempty"`
+	CountsOnly bool              `json:"counts_only,omitempty"`
+}
+
+// ParticipantCounts are the totals of the participants matching a request. Publishers have at least one
+// published track, subscribers are allowed to subscribe.
+type ParticipantCounts struct {
+	Total       int `json:"total"`
+	Publishers  int `json:"publishers"`
+	Subscribers int `json:"subscribers"`
+}
+
+// ListParticipantsPageResponse holds a page of participants in the JSON encoding of the room service,
+// next_page_token is empty on the last page
+type ListParticipantsPageResponse struct {
+	Participants  []json.RawMessage `json:"participants,omitempty"`
+	NextPageToken string            `json:"next_page_token,omitempty"`
+	Counts        ParticipantCounts `json:"counts"`
+}
+
+func (req *ListParticipantsPageRequest) matches(pi *livekit.ParticipantInfo) bool {
+	if !strings.HasPrefix(pi.Identity, req.IdentityPrefix) {
+		return false
+	}
+	if req.Metadata != "" && !strings.Contains(pi.Metadata, req.Metadata) {
+		return false
+	}
+	for k, v := range req.Attributes {
+		if pi.Attributes[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ParticipantListService serves the HTTP API listing the participants of large rooms page by page, or only
+// counting them. Calls require roomAdmin.
+type ParticipantListService struct {
+	roomStore ServiceStore
+}
+
+func NewParticipantListService(roomStore ServiceStore) *ParticipantListService {
+	return &ParticipantListService{
+		roomStore: roomStore,
+	}
+}
+
+func (s *ParticipantListService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/participants/") {
+	case "list":
+		var req ListParticipantsPageRequest
+		if err = decodeJSONRequest(r, &req, maxListParticipantsRequest); err == nil {
+			res, err = s.ListParticipants(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *ParticipantListService) ListParticipants(ctx context.Context, req *ListParticipantsPageRequest) (*ListParticipantsPageResponse, error) {
+	AppendLogFields(ctx, "room", req.Room)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
+		return nil, err
+	}
+	if req.PageSize < 0 {
+		return nil, ErrParticipantListInvalid
+	}
+	pageSize := req.PageSize
+	if pageSize == 0 {
+		pageSize = defaultParticipantsPageSize
+	}
+	pageSize = min(pageSize, maxParticipantsPageSize)
+
+	participants, err := s.roomStore.ListParticipants(ctx, livekit.RoomName(req.Room))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ListParticipantsPageResponse{}
+	matched := participants[:0]
+	for _, pi := range participants {
+		if !req.matches(pi) {
+			continue
+		}
+		res.Counts.Total++
+		if len(pi.Tracks) != 0 {
+			res.Counts.Publishers++
+		}
+		if pi.Permission == nil || pi.Permission.CanSubscribe {
+			res.Counts.Subscribers++
+		}
+		if !req.CountsOnly && pi.Identity > req.PageToken {
+			matched = append(matched, pi)
+		}
+	}
+	if req.CountsOnly {
+		return res, nil
+	}
+
+	slices.SortFunc(matched, func(a, b *livekit.ParticipantInfo) int {
+		return strings.Compare(a.Identity, b.Identity)
+	})
+	if len(matched) > pageSize {
+		matched = matched[:pageSize]
+		res.NextPageToken = matched[pageSize-1].Identity
+	}
+	res.Participants = make([]json.RawMessage, 0, len(matched))
+	for _, pi := range matched {
+		b, err := protojson.Marshal(pi)
+		if err != nil {
+			return nil, err
+		}
+		res.Participants = append(res.Participants, b)
+	}
+	return res, nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestParticipantList(t *testing.T) {
+	store := service.NewLocalStore()
+	svc := service.NewParticipantListService(store)
+
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomAdmin: true, Room: "webinar"},
+	}, "")
+	for i := 0; i < 5; i++ {
+		require.NoError(t, store.StoreParticipant(ctx, "webinar", &livekit.ParticipantInfo{
+			Identity:   fmt.Sprintf("viewer-%d", i),
+			Metadata:   fmt.Sprintf(`{"seat": %d}`, i),
+			Permission: &livekit.ParticipantPermission{CanSubscribe: true},
+			Attributes: map[string]string{"team": []string{"blue", "red"}[i%2]},
+		}))
+	}
+	require.NoError(t, store.StoreParticipant(ctx, "webinar", &livekit.ParticipantInfo{
+		Identity:   "speaker",
+		Permission: &livekit.ParticipantPermission{CanSubscribe: true, CanPublish: true},
+		Tracks:     []*livekit.TrackInfo{{Sid: "TR_audio"}},
+	}))
+
+	identities := func(res *service.ListParticipantsPageResponse) []string {
+		var ids []string
+		for _, p := range res.Participants {
+			pi := &livekit.ParticipantInfo{}
+			require.NoError(t, protojson.Unmarshal(p, pi))
+			ids = append(ids, pi.Identity)
+		}
+		return ids
+	}
+
+	t.Run("requires admin of the room", func(t *testing.T) {
+		other := service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomAdmin: true, Room: "other"},
+		}, "")
+		_, err := svc.ListParticipants(other, &service.ListParticipantsPageRequest{Room: "webinar"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+
+		_, err = svc.ListParticipants(ctx, &service.ListParticipantsPageRequest{Room: "webinar", PageSize: -1})
+		require.ErrorIs(t, err, service.ErrParticipantListInvalid)
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		res, err := svc.ListParticipants(ctx, &service.ListParticipantsPageRequest{Room: "webinar", PageSize: 4})
+		require.NoError(t, err)
+		require.Equal(t, []string{"speaker", "viewer-0", "viewer-1", "viewer-2"}, identities(res))
+		require.Equal(t, "viewer-2", res.NextPageToken)
+		require.Equal(t, service.ParticipantCounts{Total: 6, Publishers: 1, Subscribers: 6}, res.Counts)
+
+		res, err = svc.ListParticipants(ctx, &service.ListParticipantsPageRequest{Room: "webinar", PageSize: 4, PageToken: res.NextPageToken})
+		require.NoError(t, err)
+		require.Equal(t, []string{"viewer-3", "viewer-4"}, identities(res))
+		require.Empty(t, res.NextPageToken)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		res, err := svc.ListParticipants(ctx, &service.ListParticipantsPageRequest{
+			Room:           "webinar",
+			IdentityPrefix: "viewer-",
+			Attributes:     map[string]string{"team": "blue"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"viewer-0", "viewer-2", "viewer-4"}, identities(res))
+
+		res, err = svc.ListParticipants(ctx, &service.ListParticipantsPageRequest{Room: "webinar", Metadata: `"seat": 3`})
+		require.NoError(t, err)
+		require.Equal(t, []string{"viewer-3"}, identities(res))
+	})
+
+	t.Run("counts only", func(t *testing.T) {
+		res, err := svc.ListParticipants(ctx, &service.ListParticipantsPageRequest{Room: "webinar", CountsOnly: true})
+		require.NoError(t, err)
+		require.Empty(t, res.Participants)
+		require.Equal(t, service.ParticipantCounts{Total: 6, Publishers: 1, Subscribers: 6}, res.Counts)
+	})
+}
@@ -0,0 +1,211 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	participantRoleRPCService = "ParticipantRole"
+	updateParticipantRoleRPC  = "UpdateParticipantRole"
+
+	maxParticipantRoleRequest = 4 * 1024
+)
+
+// ParticipantRoleClient reaches the node hosting a room to change the role of one of its participants.
+// The role is carried in the attributes of the request, under rtc.RoleAttribute.
+type ParticipantRoleClient interface {
+	UpdateParticipantRole(ctx context.Context, room rpc.RoomTopic, req *livekit.UpdateParticipantRequest, opts ...psrpc.RequestOption) (*livekit.ParticipantInfo, error)
+}
+
+type ParticipantRoleServerImpl interface {
+	UpdateParticipantRole(ctx context.Context, req *livekit.UpdateParticipantRequest) (*livekit.ParticipantInfo, error)
+}
+
+type participantRoleClient struct {
+	client *client.RPCClient
+}
+
+func NewParticipantRoleClient(params rpc.ClientParams) (ParticipantRoleClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: participantRoleRPCService,
+		ID:   rand.NewClientID(),
+	}
+	registerParticipantRoleMethods(sd)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &participantRoleClient{client: rpcClient}, nil
+}
+
+func (c *participantRoleClient) UpdateParticipantRole(ctx context.Context, room rpc.RoomTopic, req *livekit.UpdateParticipantRequest, opts ...psrpc.RequestOption) (*livekit.ParticipantInfo, error) {
+	return client.RequestSingle[*livekit.ParticipantInfo](ctx, c.client, updateParticipantRoleRPC, []string{string(room)}, req, opts...)
+}
+
+// participantRoleServer handles role changes for a room hosted on this node
+type participantRoleServer struct {
+	svc ParticipantRoleServerImpl
+	rpc *server.RPCServer
+}
+
+func newParticipantRoleServer(svc ParticipantRoleServerImpl, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *participantRoleServer {
+	sd := &info.ServiceDefinition{
+		Name: participantRoleRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	registerParticipantRoleMethods(sd)
+	return &participantRoleServer{
+		svc: svc,
+		rpc: s,
+	}
+}
+
+func (s *participantRoleServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	return server.RegisterHandler(s.rpc, updateParticipantRoleRPC, []string{string(room)}, s.svc.UpdateParticipantRole, nil)
+}
+
+func (s *participantRoleServer) Kill() {
+	s.rpc.Close(true)
+}
+
+func registerParticipantRoleMethods(sd *info.ServiceDefinition) {
+	sd.RegisterMethod(updateParticipantRoleRPC, false, false, true, true)
+}
+
+// ---------------------------------------------
+
+// UpdateParticipantRoleRequest is the JSON body of POST /participant_role/update
+type UpdateParticipantRoleRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	Role     string `json:"role"`
+}
+
+// ParticipantRoleService serves the HTTP API changing participant roles. Roles map to permission bundles
+// applied by the room, so capabilities change without a new token or a reconnect. Calls require roomAdmin,
+// or a participant token of the room whose role may change roles.
+type ParticipantRoleService struct {
+	roomStore      ServiceStore
+	topicFormatter rpc.TopicFormatter
+	client         ParticipantRoleClient
+}
+
+func NewParticipantRoleService(
+	roomStore ServiceStore,
+	topicFormatter rpc.TopicFormatter,
+	client ParticipantRoleClient,
+) *ParticipantRoleService {
+	return &ParticipantRoleService{
+		roomStore:      roomStore,
+		topicFormatter: topicFormatter,
+		client:         client,
+	}
+}
+
+func (s *ParticipantRoleService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	if strings.TrimPrefix(r.URL.Path, "/participant_role/") != "update" {
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	var req UpdateParticipantRoleRequest
+	if err := decodeJSONRequest(r, &req, maxParticipantRoleRequest); err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+	res, err := s.UpdateParticipantRole(r.Context(), &req)
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	body, err := protojson.Marshal(res)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+func (s *ParticipantRoleService) UpdateParticipantRole(ctx context.Context, req *UpdateParticipantRoleRequest) (*livekit.ParticipantInfo, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName, "participant", req.Identity, "role", req.Role)
+	if req.Identity == "" {
+		return nil, ErrIdentityEmpty
+	}
+	if !rtc.ParticipantRole(req.Role).IsValid() {
+		return nil, ErrParticipantRoleInvalid
+	}
+	if err := EnsureModerationPermission(ctx, s.roomStore, roomName, livekit.ParticipantIdentity(req.Identity), rtc.RoleActionChangeRoles); err != nil {
+		return nil, err
+	}
+
+	return s.client.UpdateParticipantRole(ctx, s.topicFormatter.RoomTopic(ctx, roomName), &livekit.UpdateParticipantRequest{
+		Room:       req.Room,
+		Identity:   req.Identity,
+		Attributes: map[string]string{rtc.RoleAttribute: req.Role},
+	})
+}
+
+// EnsureModerationPermission allows room admins, and participants of the room whose role may perform
+// the action on the target participant. ErrParticipantNotFound is only returned to participants whose role
+// allows the action.
+func EnsureModerationPermission(ctx context.Context, roomStore ServiceStore, room livekit.RoomName, target livekit.ParticipantIdentity, action rtc.RoleAction) error {
+	if err := EnsureAdminPermission(ctx, room); err == nil {
+		return nil
+	}
+
+	claims := GetGrants(ctx)
+	if claims == nil || claims.Video == nil || claims.Identity == "" || room != livekit.RoomName(claims.Video.Room) {
+		return ErrPermissionDenied
+	}
+	actor, err := roomStore.LoadParticipant(ctx, room, livekit.ParticipantIdentity(claims.Identity))
+	if err != nil || !rtc.RoleFromAttributes(actor.Attributes).Permissions().Allows(action) {
+		return ErrPermissionDenied
+	}
+	targetInfo, err := roomStore.LoadParticipant(ctx, room, target)
+	if err != nil {
+		return err
+	}
+	if !rtc.CanModerate(actor.Attributes, targetInfo.Attributes, action) {
+		return ErrPermissionDenied
+	}
+	return nil
+}
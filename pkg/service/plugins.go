@@ -0,0 +1,75 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/urfave/negroni/v3"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// HTTPPlugin adds deployment specific behavior to the API server without patching its setup. Plugins are
+// compiled in: a package calling RegisterHTTPPlugin from init() is imported in cmd/server/plugins.go.
+type HTTPPlugin struct {
+	Name string
+	// called once when the server is created, an error stops the server from starting
+	Setup func(conf *config.Config) error
+	// run before API key authentication, e.g. to turn custom auth headers into a bearer token
+	PreAuth []negroni.Handler
+	// run after API key authentication, grants of the request are available with GetGrants
+	PostAuth []negroni.Handler
+	// extra routes by http.ServeMux pattern, they must not overlap the routes of the server
+	Routes map[string]http.Handler
+}
+
+var (
+	httpPluginsLock sync.Mutex
+	httpPlugins     []*HTTPPlugin
+)
+
+// RegisterHTTPPlugin adds a plugin to the servers created afterwards, it panics when the name is taken
+func RegisterHTTPPlugin(p HTTPPlugin) {
+	httpPluginsLock.Lock()
+	defer httpPluginsLock.Unlock()
+	for _, registered := range httpPlugins {
+		if registered.Name == p.Name {
+			panic(fmt.Sprintf("http plugin %s registered twice", p.Name))
+		}
+	}
+	httpPlugins = append(httpPlugins, &p)
+}
+
+// HTTPPlugins returns the registered plugins in registration order
+func HTTPPlugins() []*HTTPPlugin {
+	httpPluginsLock.Lock()
+	defer httpPluginsLock.Unlock()
+	return append([]*HTTPPlugin(nil), httpPlugins...)
+}
+
+func setupHTTPPlugins(conf *config.Config, plugins []*HTTPPlugin) error {
+	for _, p := range plugins {
+		if p.Setup == nil {
+			continue
+		}
+		if err := p.Setup(conf); err != nil {
+			return fmt.Errorf("could not set up http plugin %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
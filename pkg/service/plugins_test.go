@@ -0,0 +1,46 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestRegisterHTTPPlugin(t *testing.T) {
+	service.RegisterHTTPPlugin(service.HTTPPlugin{
+		Name: "test-plugin",
+		Routes: map[string]http.Handler{
+			"/test_plugin/": http.NotFoundHandler(),
+		},
+	})
+
+	var found *service.HTTPPlugin
+	for _, p := range service.HTTPPlugins() {
+		if p.Name == "test-plugin" {
+			found = p
+		}
+	}
+	require.NotNil(t, found)
+	require.Len(t, found.Routes, 1)
+
+	require.Panics(t, func() {
+		service.RegisterHTTPPlugin(service.HTTPPlugin{Name: "test-plugin"})
+	})
+}
@@ -0,0 +1,185 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	PolicyEventRoomCreate      = "room_create"
+	PolicyEventParticipantJoin = "participant_join"
+	PolicyEventTrackPublish    = "track_publish"
+
+	PolicyActionAllow = "allow"
+	PolicyActionDeny  = "deny"
+
+	defaultPolicyWebhookTimeout = 2 * time.Second
+)
+
+// PolicyRequest is posted as JSON to the policy endpoint. Only the fields relevant to the event are set.
+type PolicyRequest struct {
+	Event                 string            `json:"event"`
+	RoomName              string            `json:"room_name"`
+	RoomMetadata          string            `json:"room_metadata,omitempty"`
+	MaxParticipants       uint32            `json:"max_participants,omitempty"`
+	ParticipantIdentity   string            `json:"participant_identity,omitempty"`
+	ParticipantName       string            `json:"participant_name,omitempty"`
+	ParticipantMetadata   string            `json:"participant_metadata,omitempty"`
+	ParticipantAttributes map[string]string `json:"participant_attributes,omitempty"`
+	TrackName             string            `json:"track_name,omitempty"`
+	TrackType             string            `json:"track_type,omitempty"`
+	TrackSource           string            `json:"track_source,omitempty"`
+}
+
+// PolicyResponse is the decision returned by the policy endpoint. On allow, any of the room or participant
+// fields that are set replace the requested values; they are ignored for events they don't apply to.
+type PolicyResponse struct {
+	Action                string            `json:"action"`
+	Reason                string            `json:"reason,omitempty"`
+	RoomMetadata          *string           `json:"room_metadata,omitempty"`
+	MaxParticipants       *uint32           `json:"max_participants,omitempty"`
+	EmptyTimeout          *uint32           `json:"empty_timeout,omitempty"`
+	ParticipantName       *string           `json:"participant_name,omitempty"`
+	ParticipantMetadata   *string           `json:"participant_metadata,omitempty"`
+	ParticipantAttributes map[string]string `json:"participant_attributes,omitempty"`
+}
+
+type PolicyWebhook struct {
+	conf config.PolicyWebhookConfig
+	hook *jsonHook
+}
+
+// NewPolicyWebhook returns nil when the policy webhook is not configured
+func NewPolicyWebhook(conf *config.Config) *PolicyWebhook {
+	if conf.PolicyWebhook.URL == "" {
+		return nil
+	}
+	timeout := conf.PolicyWebhook.Timeout
+	if timeout <= 0 {
+		timeout = defaultPolicyWebhookTimeout
+	}
+	return &PolicyWebhook{
+		conf: conf.PolicyWebhook,
+		hook: newJSONHook("policy", conf.PolicyWebhook.URL, conf.PolicyWebhook.Headers, timeout),
+	}
+}
+
+// CheckCreateRoom is called before a new room is stored, room is updated in place with the endpoint's changes
+func (p *PolicyWebhook) CheckCreateRoom(ctx context.Context, room *livekit.Room) error {
+	resp, err := p.evaluate(ctx, &PolicyRequest{
+		Event:           PolicyEventRoomCreate,
+		RoomName:        room.Name,
+		RoomMetadata:    room.Metadata,
+		MaxParticipants: room.MaxParticipants,
+	})
+	if err != nil || resp == nil {
+		return err
+	}
+	if resp.RoomMetadata != nil {
+		room.Metadata = *resp.RoomMetadata
+	}
+	if resp.MaxParticipants != nil {
+		room.MaxParticipants = *resp.MaxParticipants
+	}
+	if resp.EmptyTimeout != nil {
+		room.EmptyTimeout = *resp.EmptyTimeout
+	}
+	return nil
+}
+
+// CheckParticipantJoin is called before a participant session is started, grants are updated in place
+func (p *PolicyWebhook) CheckParticipantJoin(ctx context.Context, roomName livekit.RoomName, grants *auth.ClaimGrants) error {
+	resp, err := p.evaluate(ctx, &PolicyRequest{
+		Event:                 PolicyEventParticipantJoin,
+		RoomName:              string(roomName),
+		ParticipantIdentity:   grants.Identity,
+		ParticipantName:       grants.Name,
+		ParticipantMetadata:   grants.Metadata,
+		ParticipantAttributes: grants.Attributes,
+	})
+	if err != nil || resp == nil {
+		return err
+	}
+	if resp.ParticipantName != nil {
+		grants.Name = *resp.ParticipantName
+	}
+	if resp.ParticipantMetadata != nil {
+		grants.Metadata = *resp.ParticipantMetadata
+	}
+	if len(resp.ParticipantAttributes) != 0 {
+		attrs := make(map[string]string, len(grants.Attributes)+len(resp.ParticipantAttributes))
+		for k, v := range grants.Attributes {
+			attrs[k] = v
+		}
+		for k, v := range resp.ParticipantAttributes {
+			attrs[k] = v
+		}
+		grants.Attributes = attrs
+	}
+	return nil
+}
+
+// CheckTrackPublish is called when a participant requests to publish a track
+func (p *PolicyWebhook) CheckTrackPublish(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, req *livekit.AddTrackRequest) error {
+	_, err := p.evaluate(ctx, &PolicyRequest{
+		Event:               PolicyEventTrackPublish,
+		RoomName:            string(roomName),
+		ParticipantIdentity: string(identity),
+		TrackName:           req.Name,
+		TrackType:           req.Type.String(),
+		TrackSource:         req.Source.String(),
+	})
+	return err
+}
+
+// evaluate returns the endpoint's response for allowed requests, or nil when the endpoint failed and
+// requests are allowed on error
+func (p *PolicyWebhook) evaluate(ctx context.Context, req *PolicyRequest) (*PolicyResponse, error) {
+	resp, err := p.post(ctx, req)
+	if err != nil {
+		logger.Warnw("policy webhook failed", err, "event", req.Event, "room", req.RoomName)
+		if p.conf.AllowOnError {
+			return nil, nil
+		}
+		return nil, ErrPolicyDenied
+	}
+	if resp.Action == PolicyActionDeny {
+		if resp.Reason != "" {
+			return nil, fmt.Errorf("%w: %s", ErrPolicyDenied, resp.Reason)
+		}
+		return nil, ErrPolicyDenied
+	}
+	return resp, nil
+}
+
+func (p *PolicyWebhook) post(ctx context.Context, req *PolicyRequest) (*PolicyResponse, error) {
+	var resp PolicyResponse
+	if err := p.hook.postJSON(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+	if err := p.hook.checkAction(resp.Action, PolicyActionAllow, PolicyActionDeny); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
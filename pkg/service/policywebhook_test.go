@@ -0,0 +1,105 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestPolicyWebhook(t *testing.T) {
+	require.Nil(t, service.NewPolicyWebhook(&config.Config{}))
+
+	var (
+		lastReq  service.PolicyRequest
+		decision service.PolicyResponse
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "secret", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&lastReq))
+		_ = json.NewEncoder(w).Encode(&decision)
+	}))
+	defer srv.Close()
+
+	p := service.NewPolicyWebhook(&config.Config{
+		PolicyWebhook: config.PolicyWebhookConfig{
+			URL:     srv.URL,
+			Headers: map[string]string{"Authorization": "secret"},
+		},
+	})
+
+	t.Run("room create is capped", func(t *testing.T) {
+		maxParticipants := uint32(10)
+		decision = service.PolicyResponse{Action: service.PolicyActionAllow, MaxParticipants: &maxParticipants}
+		room := &livekit.Room{Name: "myroom", Metadata: "meta"}
+		require.NoError(t, p.CheckCreateRoom(context.Background(), room))
+		require.Equal(t, service.PolicyEventRoomCreate, lastReq.Event)
+		require.Equal(t, "myroom", lastReq.RoomName)
+		require.Equal(t, uint32(10), room.MaxParticipants)
+		require.Equal(t, "meta", room.Metadata)
+	})
+
+	t.Run("join is mutated", func(t *testing.T) {
+		metadata := "injected"
+		decision = service.PolicyResponse{
+			Action:                service.PolicyActionAllow,
+			ParticipantMetadata:   &metadata,
+			ParticipantAttributes: map[string]string{"role": "guest"},
+		}
+		grants := &auth.ClaimGrants{Identity: "alice", Attributes: map[string]string{"team": "a"}}
+		require.NoError(t, p.CheckParticipantJoin(context.Background(), "myroom", grants))
+		require.Equal(t, service.PolicyEventParticipantJoin, lastReq.Event)
+		require.Equal(t, "alice", lastReq.ParticipantIdentity)
+		require.Equal(t, "injected", grants.Metadata)
+		require.Equal(t, map[string]string{"team": "a", "role": "guest"}, grants.Attributes)
+	})
+
+	t.Run("publish is denied", func(t *testing.T) {
+		decision = service.PolicyResponse{Action: service.PolicyActionDeny, Reason: "no screen share"}
+		err := p.CheckTrackPublish(context.Background(), "myroom", "alice", &livekit.AddTrackRequest{
+			Type:   livekit.TrackType_VIDEO,
+			Source: livekit.TrackSource_SCREEN_SHARE,
+		})
+		require.ErrorIs(t, err, service.ErrPolicyDenied)
+		require.Equal(t, service.PolicyEventTrackPublish, lastReq.Event)
+		require.Equal(t, livekit.TrackSource_SCREEN_SHARE.String(), lastReq.TrackSource)
+	})
+
+	t.Run("endpoint failure", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer failing.Close()
+
+		conf := config.PolicyWebhookConfig{URL: failing.URL}
+		closed := service.NewPolicyWebhook(&config.Config{PolicyWebhook: conf})
+		require.ErrorIs(t, closed.CheckCreateRoom(context.Background(), &livekit.Room{Name: "myroom"}), service.ErrPolicyDenied)
+
+		conf.AllowOnError = true
+		open := service.NewPolicyWebhook(&config.Config{PolicyWebhook: conf})
+		require.NoError(t, open.CheckCreateRoom(context.Background(), &livekit.Room{Name: "myroom"}))
+	})
+}
@@ -0,0 +1,726 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/ingress"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+	"github.com/livekit/protocol/utils/guid"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// postgresSchema is applied on startup, tables mirror the keys of RedisStore and hold protobuf encoded values
+var postgresSchema = []string{
+	`CREATE TABLE IF NOT EXISTS livekit_rooms (
+		name       TEXT PRIMARY KEY,
+		room       BYTEA NOT NULL,
+		internal   BYTEA,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`CREATE TABLE IF NOT EXISTS livekit_participants (
+		room_name TEXT NOT NULL,
+		identity  TEXT NOT NULL,
+		info      BYTEA NOT NULL,
+		PRIMARY KEY (room_name, identity)
+	)`,
+	`CREATE TABLE IF NOT EXISTS livekit_room_locks (
+		room_name  TEXT PRIMARY KEY,
+		token      TEXT NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS livekit_egress (
+		egress_id TEXT PRIMARY KEY,
+		room_name TEXT NOT NULL,
+		info      BYTEA NOT NULL,
+		active    BOOLEAN NOT NULL,
+		ended_at  TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS livekit_egress_room_name ON livekit_egress (room_name)`,
+	`CREATE TABLE IF NOT EXISTS livekit_ingress (
+		ingress_id TEXT PRIMARY KEY,
+		room_name  TEXT NOT NULL,
+		stream_key TEXT UNIQUE,
+		info       BYTEA NOT NULL,
+		state      BYTEA
+	)`,
+	`CREATE INDEX IF NOT EXISTS livekit_ingress_room_name ON livekit_ingress (room_name)`,
+	// health is JSON, like in RedisStore
+	`CREATE TABLE IF NOT EXISTS livekit_ingress_health (
+		ingress_id TEXT PRIMARY KEY,
+		room_name  TEXT NOT NULL,
+		health     BYTEA NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS livekit_agent_dispatches (
+		room_name   TEXT NOT NULL,
+		dispatch_id TEXT NOT NULL,
+		dispatch    BYTEA NOT NULL,
+		PRIMARY KEY (room_name, dispatch_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS livekit_agent_jobs (
+		room_name TEXT NOT NULL,
+		job_id    TEXT NOT NULL,
+		job       BYTEA NOT NULL,
+		PRIMARY KEY (room_name, job_id)
+	)`,
+}
+
+var (
+	_ ObjectStore        = (*PostgresStore)(nil)
+	_ EgressStore        = (*PostgresStore)(nil)
+	_ IngressStore       = (*PostgresStore)(nil)
+	_ IngressHealthStore = (*PostgresStore)(nil)
+	_ AgentStore         = (*PostgresStore)(nil)
+)
+
+// PostgresStore is the ObjectStore of deployments keeping their state in postgres rather than redis
+type PostgresStore struct {
+	db   *sql.DB
+	done chan struct{}
+}
+
+func NewPostgresStore(conf config.PostgresConfig) (*PostgresStore, error) {
+	db, err := sql.Open("postgres", conf.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open postgres")
+	}
+	if conf.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(conf.MaxOpenConns)
+	}
+	if conf.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(conf.MaxIdleConns)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for _, stmt := range postgresSchema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			_ = db.Close()
+			return nil, errors.Wrap(err, "could not create postgres schema")
+		}
+	}
+
+	s := &PostgresStore{
+		db:   db,
+		done: make(chan struct{}),
+	}
+	go s.egressWorker()
+	return s, nil
+}
+
+func (s *PostgresStore) Stop() {
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+		_ = s.db.Close()
+	}
+}
+
+func (s *PostgresStore) StoreRoom(ctx context.Context, room *livekit.Room, internal *livekit.RoomInternal) error {
+	if room.CreationTime == 0 {
+		room.CreationTime = time.Now().Unix()
+	}
+
+	roomData, err := proto.Marshal(room)
+	if err != nil {
+		return err
+	}
+	var internalData []byte
+	if internal != nil {
+		if internalData, err = proto.Marshal(internal); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO livekit_rooms (name, room, internal) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET room = excluded.room, internal = excluded.internal, updated_at = now()`,
+		room.Name, roomData, internalData)
+	if err != nil {
+		return errors.Wrap(err, "could not create room")
+	}
+	return nil
+}
+
+func (s *PostgresStore) LoadRoom(ctx context.Context, roomName livekit.RoomName, includeInternal bool) (*livekit.Room, *livekit.RoomInternal, error) {
+	var roomData, internalData []byte
+	err := s.db.QueryRowContext(ctx, `SELECT room, internal FROM livekit_rooms WHERE name = $1`, string(roomName)).
+		Scan(&roomData, &internalData)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrRoomNotFound
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	room := &livekit.Room{}
+	if err = proto.Unmarshal(roomData, room); err != nil {
+		return nil, nil, err
+	}
+
+	var internal *livekit.RoomInternal
+	if includeInternal && internalData != nil {
+		internal = &livekit.RoomInternal{}
+		if err = proto.Unmarshal(internalData, internal); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return room, internal, nil
+}
+
+func (s *PostgresStore) ListRooms(ctx context.Context, roomNames []livekit.RoomName) ([]*livekit.Room, error) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	if roomNames == nil {
+		rows, err = s.db.QueryContext(ctx, `SELECT room FROM livekit_rooms`)
+	} else {
+		rows, err = s.db.QueryContext(ctx, `SELECT room FROM livekit_rooms WHERE name = ANY($1)`,
+			pq.Array(livekit.IDsAsStrings(roomNames)))
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get rooms")
+	}
+	return postgresScanMany[livekit.Room](rows)
+}
+
+func (s *PostgresStore) DeleteRoom(ctx context.Context, roomName livekit.RoomName) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, stmt := range []string{
+		`DELETE FROM livekit_rooms WHERE name = $1`,
+		`DELETE FROM livekit_participants WHERE room_name = $1`,
+		`DELETE FROM livekit_agent_dispatches WHERE room_name = $1`,
+		`DELETE FROM livekit_agent_jobs WHERE room_name = $1`,
+	} {
+		if _, err = tx.ExecContext(ctx, stmt, string(roomName)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) LockRoom(ctx context.Context, roomName livekit.RoomName, duration time.Duration) (string, error) {
+	token := guid.New("LOCK")
+
+	startTime := time.Now()
+	for {
+		// the lock is taken when missing or expired
+		res, err := s.db.ExecContext(ctx, `INSERT INTO livekit_room_locks (room_name, token, expires_at) VALUES ($1, $2, $3)
+			ON CONFLICT (room_name) DO UPDATE SET token = excluded.token, expires_at = excluded.expires_at
+			WHERE livekit_room_locks.expires_at < now()`,
+			string(roomName), token, time.Now().Add(duration))
+		if err != nil {
+			return "", err
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			return token, nil
+		}
+
+		// stop waiting past lock duration
+		if time.Since(startTime) > duration {
+			break
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return "", ErrRoomLockFailed
+}
+
+func (s *PostgresStore) UnlockRoom(ctx context.Context, roomName livekit.RoomName, uid string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM livekit_room_locks WHERE room_name = $1 AND token = $2`, string(roomName), uid)
+	if err != nil {
+		return err
+	}
+
+	// uid does not match
+	if n, _ := res.RowsAffected(); n != 1 {
+		return ErrRoomUnlockFailed
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) StoreParticipant(ctx context.Context, roomName livekit.RoomName, participant *livekit.ParticipantInfo) error {
+	data, err := proto.Marshal(participant)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO livekit_participants (room_name, identity, info) VALUES ($1, $2, $3)
+		ON CONFLICT (room_name, identity) DO UPDATE SET info = excluded.info`,
+		string(roomName), participant.Identity, data)
+	return err
+}
+
+func (s *PostgresStore) LoadParticipant(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT info FROM livekit_participants WHERE room_name = $1 AND identity = $2`,
+		string(roomName), string(identity)).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrParticipantNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	pi := livekit.ParticipantInfo{}
+	if err := proto.Unmarshal(data, &pi); err != nil {
+		return nil, err
+	}
+	return &pi, nil
+}
+
+func (s *PostgresStore) ListParticipants(ctx context.Context, roomName livekit.RoomName) ([]*livekit.ParticipantInfo, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT info FROM livekit_participants WHERE room_name = $1`, string(roomName))
+	if err != nil {
+		return nil, err
+	}
+	return postgresScanMany[livekit.ParticipantInfo](rows)
+}
+
+func (s *PostgresStore) DeleteParticipant(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM livekit_participants WHERE room_name = $1 AND identity = $2`,
+		string(roomName), string(identity))
+	return err
+}
+
+func (s *PostgresStore) StoreEgress(ctx context.Context, info *livekit.EgressInfo) error {
+	if err := s.storeEgress(ctx, info); err != nil {
+		return errors.Wrap(err, "could not store egress info")
+	}
+	return nil
+}
+
+func (s *PostgresStore) storeEgress(ctx context.Context, info *livekit.EgressInfo) error {
+	data, err := proto.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	var endedAt *time.Time
+	if info.EndedAt != 0 {
+		t := time.Unix(0, info.EndedAt)
+		endedAt = &t
+	}
+	// starting, active and ending egresses are active
+	active := int32(info.Status) < int32(livekit.EgressStatus_EGRESS_COMPLETE)
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO livekit_egress (egress_id, room_name, info, active, ended_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (egress_id) DO UPDATE SET info = excluded.info, active = excluded.active, ended_at = excluded.ended_at`,
+		info.EgressId, info.RoomName, data, active, endedAt)
+	return err
+}
+
+func (s *PostgresStore) LoadEgress(ctx context.Context, egressID string) (*livekit.EgressInfo, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT info FROM livekit_egress WHERE egress_id = $1`, egressID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrEgressNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	info := &livekit.EgressInfo{}
+	if err = proto.Unmarshal(data, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (s *PostgresStore) ListEgress(ctx context.Context, roomName livekit.RoomName, active bool) ([]*livekit.EgressInfo, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT info FROM livekit_egress
+		WHERE ($1 = '' OR room_name = $1) AND (NOT $2 OR active)`, string(roomName), active)
+	if err != nil {
+		return nil, err
+	}
+	return postgresScanMany[livekit.EgressInfo](rows)
+}
+
+func (s *PostgresStore) UpdateEgress(ctx context.Context, info *livekit.EgressInfo) error {
+	if err := s.storeEgress(ctx, info); err != nil {
+		return errors.Wrap(err, "could not update egress info")
+	}
+	return nil
+}
+
+// Deletes egress info 24h after the egress has ended
+func (s *PostgresStore) egressWorker() {
+	ticker := time.NewTicker(time.Minute * 30)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			err := s.CleanEndedEgress()
+			if err != nil {
+				logger.Errorw("could not clean egress info", err)
+			}
+		}
+	}
+}
+
+func (s *PostgresStore) CleanEndedEgress() error {
+	_, err := s.db.Exec(`DELETE FROM livekit_egress WHERE ended_at < $1`, time.Now().Add(-24*time.Hour))
+	return err
+}
+
+func (s *PostgresStore) StoreIngress(ctx context.Context, info *livekit.IngressInfo) error {
+	if err := s.storeIngress(ctx, info, true); err != nil {
+		return errors.Wrap(err, "could not store ingress info")
+	}
+	return nil
+}
+
+// storeIngress stores the info without its state, which is reset when requested
+func (s *PostgresStore) storeIngress(ctx context.Context, info *livekit.IngressInfo, resetState bool) error {
+	if info.IngressId == "" {
+		return errors.New("Missing IngressId")
+	}
+	if info.StreamKey == "" && info.InputType != livekit.IngressInput_URL_INPUT {
+		return errors.New("Missing StreamKey")
+	}
+
+	// ignore state
+	infoCopy := utils.CloneProto(info)
+	infoCopy.State = nil
+
+	data, err := proto.Marshal(infoCopy)
+	if err != nil {
+		return err
+	}
+	var streamKey *string
+	if info.StreamKey != "" {
+		streamKey = &info.StreamKey
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO livekit_ingress (ingress_id, room_name, stream_key, info) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (ingress_id) DO UPDATE SET room_name = excluded.room_name, stream_key = excluded.stream_key,
+		info = excluded.info, state = CASE WHEN $5 THEN NULL ELSE livekit_ingress.state END`,
+		info.IngressId, info.RoomName, streamKey, data, resetState)
+	return err
+}
+
+func (s *PostgresStore) LoadIngress(ctx context.Context, ingressID string) (*livekit.IngressInfo, error) {
+	return s.loadIngress(ctx, `SELECT info, state FROM livekit_ingress WHERE ingress_id = $1`, ingressID)
+}
+
+func (s *PostgresStore) LoadIngressFromStreamKey(ctx context.Context, streamKey string) (*livekit.IngressInfo, error) {
+	return s.loadIngress(ctx, `SELECT info, state FROM livekit_ingress WHERE stream_key = $1`, streamKey)
+}
+
+func (s *PostgresStore) loadIngress(ctx context.Context, query string, arg string) (*livekit.IngressInfo, error) {
+	var data, state []byte
+	err := s.db.QueryRowContext(ctx, query, arg).Scan(&data, &state)
+	if err == sql.ErrNoRows {
+		return nil, ErrIngressNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return unmarshalPostgresIngress(data, state)
+}
+
+func (s *PostgresStore) ListIngress(ctx context.Context, roomName livekit.RoomName) ([]*livekit.IngressInfo, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT info, state FROM livekit_ingress WHERE $1 = '' OR room_name = $1`, string(roomName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var infos []*livekit.IngressInfo
+	for rows.Next() {
+		var data, state []byte
+		if err = rows.Scan(&data, &state); err != nil {
+			return nil, err
+		}
+		info, err := unmarshalPostgresIngress(data, state)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, rows.Err()
+}
+
+func (s *PostgresStore) UpdateIngress(ctx context.Context, info *livekit.IngressInfo) error {
+	if err := s.storeIngress(ctx, info, false); err != nil {
+		return errors.Wrap(err, "could not update ingress info")
+	}
+	return nil
+}
+
+func (s *PostgresStore) UpdateIngressState(ctx context.Context, ingressID string, state *livekit.IngressState) error {
+	if ingressID == "" {
+		return errors.New("Missing IngressId")
+	}
+	if state == nil {
+		state = &livekit.IngressState{}
+	}
+	data, err := proto.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var oldData []byte
+	err = tx.QueryRowContext(ctx, `SELECT state FROM livekit_ingress WHERE ingress_id = $1 FOR UPDATE`, ingressID).Scan(&oldData)
+	if err == sql.ErrNoRows {
+		return ErrIngressNotFound
+	} else if err != nil {
+		return err
+	}
+	oldState := &livekit.IngressState{}
+	if err = proto.Unmarshal(oldData, oldState); err != nil {
+		return err
+	}
+	if state.StartedAt < oldState.StartedAt {
+		// Do not overwrite the info and state of a more recent session
+		return ingress.ErrIngressOutOfDate
+	}
+	if state.StartedAt == oldState.StartedAt && state.UpdatedAt < oldState.UpdatedAt {
+		// Do not overwrite with an old state in case RPCs were delivered out of order.
+		return nil
+	}
+
+	if _, err = tx.ExecContext(ctx, `UPDATE livekit_ingress SET state = $2 WHERE ingress_id = $1`, ingressID, data); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) DeleteIngress(ctx context.Context, info *livekit.IngressInfo) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM livekit_ingress WHERE ingress_id = $1`, info.IngressId); err != nil {
+		return errors.Wrap(err, "could not delete ingress info")
+	}
+	return nil
+}
+
+func (s *PostgresStore) StoreIngressHealth(ctx context.Context, health *IngressHealth) error {
+	data, err := json.Marshal(health)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO livekit_ingress_health (ingress_id, room_name, health) VALUES ($1, $2, $3)
+		ON CONFLICT (ingress_id) DO UPDATE SET room_name = excluded.room_name, health = excluded.health`,
+		health.IngressID, health.Room, data)
+	return err
+}
+
+func (s *PostgresStore) LoadIngressHealth(ctx context.Context, ingressID string) (*IngressHealth, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT health FROM livekit_ingress_health WHERE ingress_id = $1`, ingressID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrIngressHealthNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	health := &IngressHealth{}
+	if err = json.Unmarshal(data, health); err != nil {
+		return nil, err
+	}
+	return health, nil
+}
+
+func (s *PostgresStore) ListIngressHealth(ctx context.Context, roomName livekit.RoomName) ([]*IngressHealth, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT health FROM livekit_ingress_health WHERE $1 = '' OR room_name = $1`, string(roomName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var healths []*IngressHealth
+	for rows.Next() {
+		var data []byte
+		if err = rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		health := &IngressHealth{}
+		if err = json.Unmarshal(data, health); err != nil {
+			return nil, err
+		}
+		healths = append(healths, health)
+	}
+	return healths, rows.Err()
+}
+
+func (s *PostgresStore) DeleteIngressHealth(ctx context.Context, ingressID string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM livekit_ingress_health WHERE ingress_id = $1`, ingressID)
+	return err
+}
+
+func unmarshalPostgresIngress(data, state []byte) (*livekit.IngressInfo, error) {
+	info := &livekit.IngressInfo{}
+	if err := proto.Unmarshal(data, info); err != nil {
+		return nil, err
+	}
+	// the state is empty until the first update
+	info.State = &livekit.IngressState{}
+	if err := proto.Unmarshal(state, info.State); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (s *PostgresStore) StoreAgentDispatch(ctx context.Context, dispatch *livekit.AgentDispatch) error {
+	di := utils.CloneProto(dispatch)
+
+	// Do not store jobs with the dispatch
+	if di.State != nil {
+		di.State.Jobs = nil
+	}
+
+	data, err := proto.Marshal(di)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO livekit_agent_dispatches (room_name, dispatch_id, dispatch) VALUES ($1, $2, $3)
+		ON CONFLICT (room_name, dispatch_id) DO UPDATE SET dispatch = excluded.dispatch`,
+		dispatch.Room, di.Id, data)
+	return err
+}
+
+// This will not delete the jobs created by the dispatch
+func (s *PostgresStore) DeleteAgentDispatch(ctx context.Context, dispatch *livekit.AgentDispatch) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM livekit_agent_dispatches WHERE room_name = $1 AND dispatch_id = $2`,
+		dispatch.Room, dispatch.Id)
+	return err
+}
+
+func (s *PostgresStore) ListAgentDispatches(ctx context.Context, roomName livekit.RoomName) ([]*livekit.AgentDispatch, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT dispatch FROM livekit_agent_dispatches WHERE room_name = $1`, string(roomName))
+	if err != nil {
+		return nil, err
+	}
+	dispatches, err := postgresScanMany[livekit.AgentDispatch](rows)
+	if err != nil {
+		return nil, err
+	}
+
+	dMap := make(map[string]*livekit.AgentDispatch)
+	for _, di := range dispatches {
+		dMap[di.Id] = di
+	}
+
+	rows, err = s.db.QueryContext(ctx, `SELECT job FROM livekit_agent_jobs WHERE room_name = $1`, string(roomName))
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := postgresScanMany[livekit.Job](rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Associate job to dispatch
+	for _, jb := range jobs {
+		di := dMap[jb.DispatchId]
+		if di == nil {
+			continue
+		}
+		if di.State == nil {
+			di.State = &livekit.AgentDispatchState{}
+		}
+		di.State.Jobs = append(di.State.Jobs, jb)
+	}
+
+	return dispatches, nil
+}
+
+func (s *PostgresStore) StoreAgentJob(ctx context.Context, job *livekit.Job) error {
+	if job.Room == nil {
+		return psrpc.NewErrorf(psrpc.InvalidArgument, "job doesn't have a valid Room field")
+	}
+
+	jb := utils.CloneProto(job)
+
+	// Do not store room with the job
+	jb.Room = nil
+
+	// Only store the participant identity
+	if jb.Participant != nil {
+		jb.Participant = &livekit.ParticipantInfo{
+			Identity: jb.Participant.Identity,
+		}
+	}
+
+	data, err := proto.Marshal(jb)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO livekit_agent_jobs (room_name, job_id, job) VALUES ($1, $2, $3)
+		ON CONFLICT (room_name, job_id) DO UPDATE SET job = excluded.job`,
+		job.Room.Name, job.Id, data)
+	return err
+}
+
+func (s *PostgresStore) DeleteAgentJob(ctx context.Context, job *livekit.Job) error {
+	if job.Room == nil {
+		return psrpc.NewErrorf(psrpc.InvalidArgument, "job doesn't have a valid Room field")
+	}
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM livekit_agent_jobs WHERE room_name = $1 AND job_id = $2`,
+		job.Room.Name, job.Id)
+	return err
+}
+
+func postgresScanMany[T any, P interface {
+	*T
+	proto.Message
+}](rows *sql.Rows) ([]P, error) {
+	defer rows.Close()
+
+	var list []P
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var p P = new(T)
+		if err := proto.Unmarshal(data, p); err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, rows.Err()
+}
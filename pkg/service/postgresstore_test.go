@@ -0,0 +1,143 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/ingress"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func postgresStore(t testing.TB) *service.PostgresStore {
+	url := runPostgres(t)
+
+	// postgres accepts connections before the database is ready
+	var s *service.PostgresStore
+	require.NoError(t, Docker.Retry(func() (err error) {
+		s, err = service.NewPostgresStore(config.PostgresConfig{URL: url})
+		return err
+	}))
+	t.Cleanup(s.Stop)
+	return s
+}
+
+func TestPostgresStore(t *testing.T) {
+	ctx := context.Background()
+	s := postgresStore(t)
+
+	t.Run("rooms", func(t *testing.T) {
+		room := &livekit.Room{Sid: "RM_1", Name: "room"}
+		internal := &livekit.RoomInternal{TrackEgress: &livekit.AutoTrackEgress{Filepath: "egress"}}
+		require.NoError(t, s.StoreRoom(ctx, room, internal))
+
+		loaded, loadedInternal, err := s.LoadRoom(ctx, "room", true)
+		require.NoError(t, err)
+		require.Equal(t, room.Sid, loaded.Sid)
+		require.Equal(t, "egress", loadedInternal.TrackEgress.Filepath)
+
+		// remove internal
+		require.NoError(t, s.StoreRoom(ctx, room, nil))
+		_, loadedInternal, err = s.LoadRoom(ctx, "room", true)
+		require.NoError(t, err)
+		require.Nil(t, loadedInternal)
+
+		require.NoError(t, s.StoreParticipant(ctx, "room", &livekit.ParticipantInfo{Identity: "alice"}))
+		participants, err := s.ListParticipants(ctx, "room")
+		require.NoError(t, err)
+		require.Len(t, participants, 1)
+
+		rooms, err := s.ListRooms(ctx, []livekit.RoomName{"room", "other"})
+		require.NoError(t, err)
+		require.Len(t, rooms, 1)
+
+		require.NoError(t, s.DeleteRoom(ctx, "room"))
+		_, _, err = s.LoadRoom(ctx, "room", false)
+		require.ErrorIs(t, err, service.ErrRoomNotFound)
+		_, err = s.LoadParticipant(ctx, "room", "alice")
+		require.ErrorIs(t, err, service.ErrParticipantNotFound)
+	})
+
+	t.Run("lock", func(t *testing.T) {
+		token, err := s.LockRoom(ctx, "room", 200*time.Millisecond)
+		require.NoError(t, err)
+
+		// taken again once expired
+		start := time.Now()
+		token2, err := s.LockRoom(ctx, "room", time.Second)
+		require.NoError(t, err)
+		require.NotEqual(t, token, token2)
+		require.Greater(t, time.Since(start), 100*time.Millisecond)
+
+		require.ErrorIs(t, s.UnlockRoom(ctx, "room", token), service.ErrRoomUnlockFailed)
+		require.NoError(t, s.UnlockRoom(ctx, "room", token2))
+	})
+
+	t.Run("egress", func(t *testing.T) {
+		active := &livekit.EgressInfo{EgressId: "EG_1", RoomName: "room", Status: livekit.EgressStatus_EGRESS_ACTIVE}
+		ended := &livekit.EgressInfo{EgressId: "EG_2", RoomName: "room", Status: livekit.EgressStatus_EGRESS_ACTIVE}
+		require.NoError(t, s.StoreEgress(ctx, active))
+		require.NoError(t, s.StoreEgress(ctx, ended))
+		ended.Status = livekit.EgressStatus_EGRESS_COMPLETE
+		ended.EndedAt = time.Now().Add(-25 * time.Hour).UnixNano()
+		require.NoError(t, s.UpdateEgress(ctx, ended))
+
+		infos, err := s.ListEgress(ctx, "room", true)
+		require.NoError(t, err)
+		require.Len(t, infos, 1)
+		infos, err = s.ListEgress(ctx, "", false)
+		require.NoError(t, err)
+		require.Len(t, infos, 2)
+
+		require.NoError(t, s.CleanEndedEgress())
+		_, err = s.LoadEgress(ctx, "EG_2")
+		require.ErrorIs(t, err, service.ErrEgressNotFound)
+	})
+
+	t.Run("ingress", func(t *testing.T) {
+		info := &livekit.IngressInfo{IngressId: "IN_1", StreamKey: "key", RoomName: "room"}
+		require.NoError(t, s.StoreIngress(ctx, info))
+
+		state := &livekit.IngressState{Status: livekit.IngressState_ENDPOINT_PUBLISHING, StartedAt: 2, UpdatedAt: 2}
+		require.NoError(t, s.UpdateIngressState(ctx, "IN_1", state))
+		require.ErrorIs(t, s.UpdateIngressState(ctx, "IN_1", &livekit.IngressState{StartedAt: 1}), ingress.ErrIngressOutOfDate)
+
+		loaded, err := s.LoadIngressFromStreamKey(ctx, "key")
+		require.NoError(t, err)
+		require.Equal(t, livekit.IngressState_ENDPOINT_PUBLISHING, loaded.State.Status)
+
+		require.NoError(t, s.DeleteIngress(ctx, info))
+		_, err = s.LoadIngress(ctx, "IN_1")
+		require.ErrorIs(t, err, service.ErrIngressNotFound)
+	})
+
+	t.Run("agents", func(t *testing.T) {
+		dispatch := &livekit.AgentDispatch{Id: "AD_1", Room: "room", AgentName: "agent"}
+		require.NoError(t, s.StoreAgentDispatch(ctx, dispatch))
+		require.NoError(t, s.StoreAgentJob(ctx, &livekit.Job{Id: "AJ_1", DispatchId: "AD_1", Room: &livekit.Room{Name: "room"}}))
+
+		dispatches, err := s.ListAgentDispatches(ctx, "room")
+		require.NoError(t, err)
+		require.Len(t, dispatches, 1)
+		require.Len(t, dispatches[0].State.Jobs, 1)
+	})
+}
@@ -0,0 +1,272 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+// webhook events sent when a request is denied because it would exceed a quota
+const (
+	EventPublisherQuotaExceeded      = "publisher_quota_exceeded"
+	EventBitrateQuotaExceeded        = "bitrate_quota_exceeded"
+	EventEgressQuotaExceeded         = "egress_quota_exceeded"
+	EventIngressQuotaExceeded        = "ingress_quota_exceeded"
+	EventSIPParticipantQuotaExceeded = "sip_participant_quota_exceeded"
+)
+
+const (
+	quotaScopeRoom    = "room"
+	quotaScopeProject = "project"
+)
+
+// PublishUsage is the usage of the room and node a track is published to, gathered by the room manager
+type PublishUsage struct {
+	// the publishing participant already publishes tracks and is counted in RoomPublishers
+	IsPublisher    bool
+	RoomPublishers int
+	// bitrate of the tracks published to the room, and to all rooms of the node
+	RoomBitrate int64
+	NodeBitrate int64
+}
+
+// QuotaEnforcer denies requests that would exceed the configured room or project quotas.
+// Project publishers, egresses, ingresses and SIP participants are counted from the store,
+// the project bitrate can only be measured for the rooms hosted by the node.
+type QuotaEnforcer struct {
+	conf         config.QuotaConfig
+	store        ServiceStore
+	egressStore  EgressStore
+	ingressStore IngressStore
+	telemetry    telemetry.TelemetryService
+}
+
+// NewQuotaEnforcer returns nil when no quota is configured
+func NewQuotaEnforcer(
+	conf *config.Config,
+	store ServiceStore,
+	egressStore EgressStore,
+	ingressStore IngressStore,
+	ts telemetry.TelemetryService,
+) *QuotaEnforcer {
+	if conf.Quotas.Room.IsEmpty() && conf.Quotas.Project.IsEmpty() {
+		return nil
+	}
+	return &QuotaEnforcer{
+		conf:         conf.Quotas,
+		store:        store,
+		egressStore:  egressStore,
+		ingressStore: ingressStore,
+		telemetry:    ts,
+	}
+}
+
+// CheckTrackPublish checks the publisher and bitrate quotas before a track is published
+func (q *QuotaEnforcer) CheckTrackPublish(
+	ctx context.Context,
+	roomName livekit.RoomName,
+	identity livekit.ParticipantIdentity,
+	usage PublishUsage,
+	req *livekit.AddTrackRequest,
+) error {
+	participant := &livekit.ParticipantInfo{Identity: string(identity)}
+
+	if !usage.IsPublisher {
+		if limit := q.conf.Room.MaxPublishers; limit > 0 && usage.RoomPublishers >= limit {
+			return q.exceeded(ctx, EventPublisherQuotaExceeded, quotaScopeRoom, roomName, participant, int64(limit))
+		}
+		if limit := q.conf.Project.MaxPublishers; limit > 0 {
+			publishers, err := q.projectPublishers(ctx, roomName, usage.RoomPublishers)
+			if err != nil {
+				return err
+			}
+			if publishers >= limit {
+				return q.exceeded(ctx, EventPublisherQuotaExceeded, quotaScopeProject, roomName, participant, int64(limit))
+			}
+		}
+	}
+
+	bitrate := trackRequestBitrate(req)
+	if limit := q.conf.Room.MaxBitrate; limit > 0 && usage.RoomBitrate+bitrate > limit {
+		return q.exceeded(ctx, EventBitrateQuotaExceeded, quotaScopeRoom, roomName, participant, limit)
+	}
+	if limit := q.conf.Project.MaxBitrate; limit > 0 && usage.NodeBitrate+bitrate > limit {
+		return q.exceeded(ctx, EventBitrateQuotaExceeded, quotaScopeProject, roomName, participant, limit)
+	}
+	return nil
+}
+
+// CheckEgress checks the egress quotas before an egress is started. roomName is empty for track-less
+// egresses, which only count against the project.
+func (q *QuotaEnforcer) CheckEgress(ctx context.Context, roomName livekit.RoomName) error {
+	if q.egressStore == nil {
+		return nil
+	}
+	if limit := q.conf.Room.MaxEgresses; limit > 0 && roomName != "" {
+		egresses, err := q.egressStore.ListEgress(ctx, roomName, true)
+		if err != nil {
+			return err
+		}
+		if len(egresses) >= limit {
+			return q.exceeded(ctx, EventEgressQuotaExceeded, quotaScopeRoom, roomName, nil, int64(limit))
+		}
+	}
+	if limit := q.conf.Project.MaxEgresses; limit > 0 {
+		egresses, err := q.egressStore.ListEgress(ctx, "", true)
+		if err != nil {
+			return err
+		}
+		if len(egresses) >= limit {
+			return q.exceeded(ctx, EventEgressQuotaExceeded, quotaScopeProject, roomName, nil, int64(limit))
+		}
+	}
+	return nil
+}
+
+// CheckIngress checks the ingress quotas before an ingress is created
+func (q *QuotaEnforcer) CheckIngress(ctx context.Context, roomName livekit.RoomName) error {
+	if q.ingressStore == nil {
+		return nil
+	}
+	if limit := q.conf.Room.MaxIngresses; limit > 0 && roomName != "" {
+		ingresses, err := q.ingressStore.ListIngress(ctx, roomName)
+		if err != nil {
+			return err
+		}
+		if len(ingresses) >= limit {
+			return q.exceeded(ctx, EventIngressQuotaExceeded, quotaScopeRoom, roomName, nil, int64(limit))
+		}
+	}
+	if limit := q.conf.Project.MaxIngresses; limit > 0 {
+		ingresses, err := q.ingressStore.ListIngress(ctx, "")
+		if err != nil {
+			return err
+		}
+		if len(ingresses) >= limit {
+			return q.exceeded(ctx, EventIngressQuotaExceeded, quotaScopeProject, roomName, nil, int64(limit))
+		}
+	}
+	return nil
+}
+
+// CheckSIPParticipant checks the SIP participant quotas before a SIP participant is created
+func (q *QuotaEnforcer) CheckSIPParticipant(ctx context.Context, roomName livekit.RoomName) error {
+	if limit := q.conf.Room.MaxSIPParticipants; limit > 0 {
+		count, err := q.sipParticipants(ctx, roomName)
+		if err != nil {
+			return err
+		}
+		if count >= limit {
+			return q.exceeded(ctx, EventSIPParticipantQuotaExceeded, quotaScopeRoom, roomName, nil, int64(limit))
+		}
+	}
+	if limit := q.conf.Project.MaxSIPParticipants; limit > 0 {
+		rooms, err := q.store.ListRooms(ctx, nil)
+		if err != nil {
+			return err
+		}
+		count := 0
+		for _, room := range rooms {
+			n, err := q.sipParticipants(ctx, livekit.RoomName(room.Name))
+			if err != nil {
+				return err
+			}
+			count += n
+		}
+		if count >= limit {
+			return q.exceeded(ctx, EventSIPParticipantQuotaExceeded, quotaScopeProject, roomName, nil, int64(limit))
+		}
+	}
+	return nil
+}
+
+func (q *QuotaEnforcer) projectPublishers(ctx context.Context, roomName livekit.RoomName, roomPublishers int) (int, error) {
+	rooms, err := q.store.ListRooms(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	// the stored count of the room may lag behind, the room manager knows better
+	publishers := roomPublishers
+	for _, room := range rooms {
+		if livekit.RoomName(room.Name) != roomName {
+			publishers += int(room.NumPublishers)
+		}
+	}
+	return publishers, nil
+}
+
+func (q *QuotaEnforcer) sipParticipants(ctx context.Context, roomName livekit.RoomName) (int, error) {
+	participants, err := q.store.ListParticipants(ctx, roomName)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, p := range participants {
+		if p.Kind == livekit.ParticipantInfo_SIP {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (q *QuotaEnforcer) exceeded(
+	ctx context.Context,
+	event string,
+	scope string,
+	roomName livekit.RoomName,
+	participant *livekit.ParticipantInfo,
+	limit int64,
+) error {
+	logger.Infow("quota exceeded", "event", event, "scope", scope, "room", roomName, "limit", limit)
+	if q.telemetry != nil {
+		q.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event:       event,
+			Room:        &livekit.Room{Name: string(roomName)},
+			Participant: participant,
+		})
+	}
+	return fmt.Errorf("%w: %s limit of %d reached for the %s", ErrQuotaExceeded, quotaName(event), limit, scope)
+}
+
+func quotaName(event string) string {
+	switch event {
+	case EventPublisherQuotaExceeded:
+		return "publisher"
+	case EventBitrateQuotaExceeded:
+		return "bitrate"
+	case EventEgressQuotaExceeded:
+		return "egress"
+	case EventIngressQuotaExceeded:
+		return "ingress"
+	default:
+		return "sip participant"
+	}
+}
+
+// trackRequestBitrate is the bitrate announced for the layers of a track, zero when unknown
+func trackRequestBitrate(req *livekit.AddTrackRequest) int64 {
+	var bitrate int64
+	for _, layer := range req.Layers {
+		bitrate += int64(layer.Bitrate)
+	}
+	return bitrate
+}
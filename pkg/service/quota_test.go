@@ -0,0 +1,126 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestQuotaEnforcer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("disabled without quotas", func(t *testing.T) {
+		require.Nil(t, service.NewQuotaEnforcer(&config.Config{}, nil, nil, nil, nil))
+	})
+
+	t.Run("publishers and bitrate", func(t *testing.T) {
+		store := &servicefakes.FakeServiceStore{}
+		store.ListRoomsReturns([]*livekit.Room{
+			{Name: "room", NumPublishers: 1},
+			{Name: "other", NumPublishers: 2},
+		}, nil)
+		ts := &telemetryfakes.FakeTelemetryService{}
+		q := service.NewQuotaEnforcer(&config.Config{Quotas: config.QuotaConfig{
+			Room:    config.QuotaLimits{MaxPublishers: 2, MaxBitrate: 1000},
+			Project: config.QuotaLimits{MaxPublishers: 4},
+		}}, store, nil, nil, ts)
+		req := &livekit.AddTrackRequest{Layers: []*livekit.VideoLayer{{Bitrate: 300}, {Bitrate: 200}}}
+
+		require.NoError(t, q.CheckTrackPublish(ctx, "room", "pa", service.PublishUsage{RoomPublishers: 1}, req))
+
+		err := q.CheckTrackPublish(ctx, "room", "pa", service.PublishUsage{RoomPublishers: 2}, req)
+		require.ErrorIs(t, err, service.ErrQuotaExceeded)
+		require.Equal(t, 1, ts.NotifyEventCallCount())
+		_, event := ts.NotifyEventArgsForCall(0)
+		require.Equal(t, service.EventPublisherQuotaExceeded, event.Event)
+		require.Equal(t, "room", event.Room.Name)
+		require.Equal(t, "pa", event.Participant.Identity)
+
+		// existing publishers may add tracks
+		require.NoError(t, q.CheckTrackPublish(ctx, "room", "pa", service.PublishUsage{IsPublisher: true, RoomPublishers: 2}, req))
+
+		// other rooms count against the project
+		store.ListRoomsReturns([]*livekit.Room{{Name: "other", NumPublishers: 3}}, nil)
+		err = q.CheckTrackPublish(ctx, "room", "pa", service.PublishUsage{RoomPublishers: 1}, req)
+		require.ErrorIs(t, err, service.ErrQuotaExceeded)
+
+		err = q.CheckTrackPublish(ctx, "room", "pa", service.PublishUsage{IsPublisher: true, RoomPublishers: 1, RoomBitrate: 600}, req)
+		require.ErrorIs(t, err, service.ErrQuotaExceeded)
+		_, event = ts.NotifyEventArgsForCall(ts.NotifyEventCallCount() - 1)
+		require.Equal(t, service.EventBitrateQuotaExceeded, event.Event)
+	})
+
+	t.Run("egresses", func(t *testing.T) {
+		egressStore := &servicefakes.FakeEgressStore{}
+		egressStore.ListEgressCalls(func(_ context.Context, roomName livekit.RoomName, _ bool) ([]*livekit.EgressInfo, error) {
+			if roomName == "" {
+				return []*livekit.EgressInfo{{RoomName: "room"}, {RoomName: "other"}, {RoomName: "other"}}, nil
+			}
+			return []*livekit.EgressInfo{{RoomName: string(roomName)}}, nil
+		})
+		ts := &telemetryfakes.FakeTelemetryService{}
+		q := service.NewQuotaEnforcer(&config.Config{Quotas: config.QuotaConfig{
+			Room:    config.QuotaLimits{MaxEgresses: 2},
+			Project: config.QuotaLimits{MaxEgresses: 4},
+		}}, nil, egressStore, nil, ts)
+
+		require.NoError(t, q.CheckEgress(ctx, "room"))
+
+		egressStore.ListEgressCalls(func(_ context.Context, roomName livekit.RoomName, _ bool) ([]*livekit.EgressInfo, error) {
+			return []*livekit.EgressInfo{{}, {}, {}, {}}, nil
+		})
+		require.ErrorIs(t, q.CheckEgress(ctx, ""), service.ErrQuotaExceeded)
+		_, event := ts.NotifyEventArgsForCall(0)
+		require.Equal(t, service.EventEgressQuotaExceeded, event.Event)
+	})
+
+	t.Run("sip participants", func(t *testing.T) {
+		store := &servicefakes.FakeServiceStore{}
+		store.ListRoomsReturns([]*livekit.Room{{Name: "room"}, {Name: "other"}}, nil)
+		store.ListParticipantsCalls(func(_ context.Context, roomName livekit.RoomName) ([]*livekit.ParticipantInfo, error) {
+			if roomName == "room" {
+				return []*livekit.ParticipantInfo{
+					{Identity: "caller", Kind: livekit.ParticipantInfo_SIP},
+					{Identity: "pa", Kind: livekit.ParticipantInfo_STANDARD},
+				}, nil
+			}
+			return []*livekit.ParticipantInfo{{Identity: "caller", Kind: livekit.ParticipantInfo_SIP}}, nil
+		})
+		ts := &telemetryfakes.FakeTelemetryService{}
+		q := service.NewQuotaEnforcer(&config.Config{Quotas: config.QuotaConfig{
+			Room:    config.QuotaLimits{MaxSIPParticipants: 2},
+			Project: config.QuotaLimits{MaxSIPParticipants: 3},
+		}}, store, nil, nil, ts)
+
+		require.NoError(t, q.CheckSIPParticipant(ctx, "room"))
+
+		q = service.NewQuotaEnforcer(&config.Config{Quotas: config.QuotaConfig{
+			Project: config.QuotaLimits{MaxSIPParticipants: 2},
+		}}, store, nil, nil, ts)
+		require.ErrorIs(t, q.CheckSIPParticipant(ctx, "room"), service.ErrQuotaExceeded)
+		_, event := ts.NotifyEventArgsForCall(0)
+		require.Equal(t, service.EventSIPParticipantQuotaExceeded, event.Event)
+	})
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	recordingConsentRPCService = "RecordingConsent"
+	updateRecordingConsentRPC  = "UpdateRecordingConsent"
+
+	maxRecordingConsentRequest = 4 * 1024
+)
+
+// RecordingConsentClient reaches the node hosting a room to record the consent of one of its participants.
+// The consent is carried in the attributes of the request, under rtc.RecordingConsentAttribute.
+type RecordingConsentClient interface {
+	UpdateRecordingConsent(ctx context.Context, room rpc.RoomTopic, req *livekit.UpdateParticipantRequest, opts ...psrpc.RequestOption) (*livekit.ParticipantInfo, error)
+}
+
+type RecordingConsentServerImpl interface {
+	UpdateRecordingConsent(ctx context.Context, req *livekit.UpdateParticipantRequest) (*livekit.ParticipantInfo, error)
+}
+
+type recordingConsentClient struct {
+	client *client.RPCClient
+}
+
+func NewRecordingConsentClient(params rpc.ClientParams) (RecordingConsentClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: recordingConsentRPCService,
+		ID:   rand.NewClientID(),
+	}
+	registerRecordingConsentMethods(sd)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingConsentClient{client: rpcClient}, nil
+}
+
+func (c *recordingConsentClient) UpdateRecordingConsent(ctx context.Context, room rpc.RoomTopic, req *livekit.UpdateParticipantRequest, opts ...psrpc.RequestOption) (*livekit.ParticipantInfo, error) {
+	return client.RequestSingle[*livekit.ParticipantInfo](ctx, c.client, updateRecordingConsentRPC, []string{string(room)}, req, opts...)
+}
+
+// recordingConsentServer handles consent updates for a room hosted on this node
+type recordingConsentServer struct {
+	svc RecordingConsentServerImpl
+	rpc *server.RPCServer
+}
+
+func newRecordingConsentServer(svc RecordingConsentServerImpl, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *recordingConsentServer {
+	sd := &info.ServiceDefinition{
+		Name: recordingConsentRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	registerRecordingConsentMethods(sd)
+	return &recordingConsentServer{
+		svc: svc,
+		rpc: s,
+	}
+}
+
+func (s *recordingConsentServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	return server.RegisterHandler(s.rpc, updateRecordingConsentRPC, []string{string(room)}, s.svc.UpdateRecordingConsent, nil)
+}
+
+func (s *recordingConsentServer) Kill() {
+	s.rpc.Close(true)
+}
+
+func registerRecordingConsentMethods(sd *info.ServiceDefinition) {
+	sd.RegisterMethod(updateRecordingConsentRPC, false, false, true, true)
+}
+
+// ---------------------------------------------
+
+// UpdateRecordingConsentRequest is the JSON body of POST /recording_consent/update
+type UpdateRecordingConsentRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	Granted  bool   `json:"granted"`
+}
+
+// RecordingConsentService serves the HTTP API recording the consent of participants to the recording of
+// their tracks. Consent is exposed in the participant attributes, and recorders can be limited to the tracks
+// of consenting participants. Calls require roomAdmin, or the token of the participant itself.
+type RecordingConsentService struct {
+	topicFormatter rpc.TopicFormatter
+	client         RecordingConsentClient
+}
+
+func NewRecordingConsentService(
+	topicFormatter rpc.TopicFormatter,
+	client RecordingConsentClient,
+) *RecordingConsentService {
+	return &RecordingConsentService{
+		topicFormatter: topicFormatter,
+		client:         client,
+	}
+}
+
+func (s *RecordingConsentService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	if strings.TrimPrefix(r.URL.Path, "/recording_consent/") != "update" {
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	var req UpdateRecordingConsentRequest
+	if err := decodeJSONRequest(r, &req, maxRecordingConsentRequest); err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+	res, err := s.UpdateRecordingConsent(r.Context(), &req)
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	body, err := protojson.Marshal(res)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+func (s *RecordingConsentService) UpdateRecordingConsent(ctx context.Context, req *UpdateRecordingConsentRequest) (*livekit.ParticipantInfo, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName, "participant", req.Identity, "granted", req.Granted)
+	if req.Identity == "" {
+		return nil, ErrIdentityEmpty
+	}
+	if err := EnsureAdminPermission(ctx, roomName); err != nil {
+		// participants answer for themselves
+		claims := GetGrants(ctx)
+		if claims == nil || claims.Video == nil || claims.Identity != req.Identity || claims.Video.Room != req.Room {
+			return nil, ErrPermissionDenied
+		}
+	}
+
+	consent := rtc.RecordingConsentDenied
+	if req.Granted {
+		consent = rtc.RecordingConsentGranted
+	}
+	return s.client.UpdateRecordingConsent(ctx, s.topicFormatter.RoomTopic(ctx, roomName), &livekit.UpdateParticipantRequest{
+		Room:       req.Room,
+		Identity:   req.Identity,
+		Attributes: map[string]string{rtc.RecordingConsentAttribute: string(consent)},
+	})
+}
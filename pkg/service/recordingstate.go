@@ -0,0 +1,179 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	recordingStateRPCService = "RecordingState"
+	recordingStateRPC        = "RecordingState"
+
+	recordingStateTimeout = 5 * time.Second
+
+	// EventRecordingStarted is sent when a room starts being recorded, by an egress job or a recorder participant
+	EventRecordingStarted = "recording_started"
+	// EventRecordingEnded is sent when the last recording job or recorder participant of a room ends
+	EventRecordingEnded = "recording_ended"
+)
+
+// RecordingStateClient reaches the node hosting a room to update its recording jobs. Jobs are carried as JSON in
+// the payload of a user data packet, the response carries the rtc.RecordingState of the room.
+type RecordingStateClient interface {
+	RecordingState(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error)
+}
+
+type RecordingStateServerImpl interface {
+	UpdateRecordingJob(ctx context.Context, roomName livekit.RoomName, job rtc.RecordingJob) (*rtc.RecordingState, error)
+}
+
+type recordingStateClient struct {
+	client *client.RPCClient
+}
+
+func NewRecordingStateClient(params rpc.ClientParams) (RecordingStateClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: recordingStateRPCService,
+		ID:   rand.NewClientID(),
+	}
+	sd.RegisterMethod(recordingStateRPC, false, false, true, true)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingStateClient{client: rpcClient}, nil
+}
+
+func (c *recordingStateClient) RecordingState(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return client.RequestSingle[*livekit.DataPacket](ctx, c.client, recordingStateRPC, []string{string(room)}, req, opts...)
+}
+
+// recordingStateServer updates the recording jobs of a room hosted on this node
+type recordingStateServer struct {
+	svc      RecordingStateServerImpl
+	roomName livekit.RoomName
+	rpc      *server.RPCServer
+}
+
+func newRecordingStateServer(svc RecordingStateServerImpl, roomName livekit.RoomName, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *recordingStateServer {
+	sd := &info.ServiceDefinition{
+		Name: recordingStateRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	sd.RegisterMethod(recordingStateRPC, false, false, true, true)
+	return &recordingStateServer{
+		svc:      svc,
+		roomName: roomName,
+		rpc:      s,
+	}
+}
+
+func (s *recordingStateServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	return server.RegisterHandler(s.rpc, recordingStateRPC, []string{string(room)}, s.handle, nil)
+}
+
+func (s *recordingStateServer) handle(ctx context.Context, req *livekit.DataPacket) (*livekit.DataPacket, error) {
+	var job rtc.RecordingJob
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &job); err != nil || job.ID == "" {
+		return nil, ErrRecordingJobInvalid
+	}
+	state, err := s.svc.UpdateRecordingJob(ctx, s.roomName, job)
+	if err != nil {
+		return nil, err
+	}
+	return jsonDataPacket(state)
+}
+
+func (s *recordingStateServer) Kill() {
+	s.rpc.Close(true)
+}
+
+// ---------------------------------------------
+
+// RecordingJobFromEgress returns the recording job of an egress, active until the egress ends
+func RecordingJobFromEgress(info *livekit.EgressInfo) rtc.RecordingJob {
+	job := rtc.RecordingJob{ID: info.EgressId}
+	switch info.Request.(type) {
+	case *livekit.EgressInfo_RoomComposite:
+		job.Kind = "room_composite"
+	case *livekit.EgressInfo_Web:
+		job.Kind = "web"
+	case *livekit.EgressInfo_Participant:
+		job.Kind = "participant"
+	case *livekit.EgressInfo_TrackComposite:
+		job.Kind = "track_composite"
+	case *livekit.EgressInfo_Track:
+		job.Kind = "track"
+	}
+	switch info.Status {
+	case livekit.EgressStatus_EGRESS_STARTING,
+		livekit.EgressStatus_EGRESS_ACTIVE,
+		livekit.EgressStatus_EGRESS_ENDING:
+		job.Active = true
+	}
+	if info.StartedAt != 0 {
+		job.StartedAt = time.Unix(0, info.StartedAt)
+	}
+	return job
+}
+
+// updateRecordingJob tells the node hosting the room of an egress about the state of the egress. Rooms that
+// are not live have no recording state to update.
+func (s *IOInfoService) updateRecordingJob(info *livekit.EgressInfo) {
+	if s.recordingClient == nil || info.RoomName == "" {
+		return
+	}
+	req, err := jsonDataPacket(RecordingJobFromEgress(info))
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), recordingStateTimeout)
+	defer cancel()
+	roomName := livekit.RoomName(info.RoomName)
+	if _, err = s.recordingClient.RecordingState(ctx, s.topicFormatter.RoomTopic(ctx, roomName), req); err != nil {
+		logger.Debugw("could not update recording state", "error", err, "room", roomName, "egressID", info.EgressId)
+	}
+}
+
+// ---------------------------------------------
+
+// UpdateRecordingJob updates a recording job of a room hosted on this node
+func (r *RoomManager) UpdateRecordingJob(ctx context.Context, roomName livekit.RoomName, job rtc.RecordingJob) (*rtc.RecordingState, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	room.UpdateRecordingJob(job)
+	state := room.GetRecordingState()
+	return &state, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	// APIKeysKey is a hash of api key => JSON of the managed key
+	APIKeysKey = "api_keys"
+	// APIKeysVersionKey is incremented whenever a managed key is stored or deleted
+	APIKeysVersionKey = "api_keys_version"
+	// APIKeysLastUsedKey is a hash of api key => unix time of its last use, kept apart so that
+	// recording uses does not invalidate the keys cached by nodes
+	APIKeysLastUsedKey = "api_keys_last_used"
+	// APIKeysChannel is published the new version whenever a managed key is stored or deleted
+	APIKeysChannel = "api_keys_changed"
+)
+
+func (s *RedisStore) StoreAPIKey(ctx context.Context, key *APIKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	tx := s.rc.TxPipeline()
+	tx.HSet(s.ctx, APIKeysKey, key.Key, data)
+	version := tx.Incr(s.ctx, APIKeysVersionKey)
+	if _, err = tx.Exec(ctx); err != nil {
+		return err
+	}
+	return s.rc.Publish(s.ctx, APIKeysChannel, version.Val()).Err()
+}
+
+func (s *RedisStore) LoadAPIKey(_ context.Context, key string) (*APIKey, error) {
+	data, err := s.rc.HGet(s.ctx, APIKeysKey, key).Result()
+	if err == redis.Nil {
+		return nil, ErrAPIKeyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	k := &APIKey{}
+	if err = json.Unmarshal([]byte(data), k); err != nil {
+		return nil, err
+	}
+	if lastUsed, err := s.rc.HGet(s.ctx, APIKeysLastUsedKey, key).Int64(); err == nil {
+		k.LastUsedAt = lastUsed
+	}
+	return k, nil
+}
+
+func (s *RedisStore) ListAPIKeys(_ context.Context) ([]*APIKey, error) {
+	items, err := s.rc.HGetAll(s.ctx, APIKeysKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	lastUsed, err := s.rc.HGetAll(s.ctx, APIKeysLastUsedKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*APIKey, 0, len(items))
+	for _, data := range items {
+		k := &APIKey{}
+		if err = json.Unmarshal([]byte(data), k); err != nil {
+			return nil, err
+		}
+		if v, err := strconv.ParseInt(lastUsed[k.Key], 10, 64); err == nil {
+			k.LastUsedAt = v
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (s *RedisStore) DeleteAPIKey(ctx context.Context, key string) error {
+	tx := s.rc.TxPipeline()
+	tx.HDel(s.ctx, APIKeysKey, key)
+	tx.HDel(s.ctx, APIKeysLastUsedKey, key)
+	version := tx.Incr(s.ctx, APIKeysVersionKey)
+	if _, err := tx.Exec(ctx); err != nil {
+		return err
+	}
+	return s.rc.Publish(s.ctx, APIKeysChannel, version.Val()).Err()
+}
+
+func (s *RedisStore) LoadAPIKeysVersion(_ context.Context) (int64, error) {
+	version, err := s.rc.Get(s.ctx, APIKeysVersionKey).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return version, err
+}
+
+func (s *RedisStore) WatchAPIKeys(ctx context.Context) <-chan struct{} {
+	sub := s.rc.Subscribe(ctx, APIKeysChannel)
+	// changes are notified once the subscription is confirmed
+	if _, err := sub.Receive(ctx); err != nil {
+		logger.Warnw("could not watch api keys", err)
+	}
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer sub.Close()
+		// the subscription is restored when the connection is lost
+		msgs := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-msgs:
+				if !ok {
+					return
+				}
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return changes
+}
+
+func (s *RedisStore) UpdateAPIKeysLastUsed(_ context.Context, lastUsed map[string]time.Time) error {
+	values := make(map[string]any, len(lastUsed))
+	for key, at := range lastUsed {
+		values[key] = at.Unix()
+	}
+	return s.rc.HSet(s.ctx, APIKeysLastUsedKey, values).Err()
+}
@@ -0,0 +1,51 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// BreakoutRoomsPrefix is the prefix of the set holding the breakout rooms of a parent room
+const BreakoutRoomsPrefix = "breakout_rooms:"
+
+func (s *RedisStore) StoreBreakoutRooms(_ context.Context, parent livekit.RoomName, rooms []livekit.RoomName) error {
+	if len(rooms) == 0 {
+		return nil
+	}
+	members := make([]interface{}, 0, len(rooms))
+	for _, room := range rooms {
+		members = append(members, string(room))
+	}
+	return s.rc.SAdd(s.ctx, BreakoutRoomsPrefix+string(parent), members...).Err()
+}
+
+func (s *RedisStore) LoadBreakoutRooms(_ context.Context, parent livekit.RoomName) ([]livekit.RoomName, error) {
+	names, err := s.rc.SMembers(s.ctx, BreakoutRoomsPrefix+string(parent)).Result()
+	if err != nil {
+		return nil, err
+	}
+	rooms := make([]livekit.RoomName, 0, len(names))
+	for _, name := range names {
+		rooms = append(rooms, livekit.RoomName(name))
+	}
+	return rooms, nil
+}
+
+func (s *RedisStore) DeleteBreakoutRooms(_ context.Context, parent livekit.RoomName) error {
+	return s.rc.Del(s.ctx, BreakoutRoomsPrefix+string(parent)).Err()
+}
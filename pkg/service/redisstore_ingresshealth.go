@@ -0,0 +1,73 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// IngressHealthKey is a hash of ingressID => JSON IngressHealth
+const IngressHealthKey = "{ingress}_health"
+
+func (s *RedisStore) StoreIngressHealth(_ context.Context, health *IngressHealth) error {
+	data, err := json.Marshal(health)
+	if err != nil {
+		return err
+	}
+	return s.rc.HSet(s.ctx, IngressHealthKey, health.IngressID, data).Err()
+}
+
+func (s *RedisStore) LoadIngressHealth(_ context.Context, ingressID string) (*IngressHealth, error) {
+	data, err := s.rc.HGet(s.ctx, IngressHealthKey, ingressID).Result()
+	if err == redis.Nil {
+		return nil, ErrIngressHealthNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	health := &IngressHealth{}
+	if err = json.Unmarshal([]byte(data), health); err != nil {
+		return nil, err
+	}
+	return health, nil
+}
+
+func (s *RedisStore) ListIngressHealth(_ context.Context, roomName livekit.RoomName) ([]*IngressHealth, error) {
+	items, err := s.rc.HGetAll(s.ctx, IngressHealthKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var healths []*IngressHealth
+	for _, data := range items {
+		health := &IngressHealth{}
+		if err = json.Unmarshal([]byte(data), health); err != nil {
+			return nil, err
+		}
+		if roomName == "" || health.Room == string(roomName) {
+			healths = append(healths, health)
+		}
+	}
+	return healths, nil
+}
+
+func (s *RedisStore) DeleteIngressHealth(_ context.Context, ingressID string) error {
+	return s.rc.HDel(s.ctx, IngressHealthKey, ingressID).Err()
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const (
+	// RoomSchedulesKey is a set of scheduled room names
+	RoomSchedulesKey = "room_schedules"
+	// RoomSchedulePrefix is the prefix of the hash holding the schedule of a room
+	RoomSchedulePrefix = "room_schedule:"
+
+	roomScheduleFieldRequest = "request"
+	roomScheduleFieldStartAt = "start_at"
+	roomScheduleFieldEndAt   = "end_at"
+	roomScheduleFieldGrace   = "grace_period"
+	roomScheduleFieldState   = "state"
+)
+
+func (s *RedisStore) StoreRoomSchedule(ctx context.Context, schedule *RoomSchedule) error {
+	data, err := proto.Marshal(schedule.Request)
+	if err != nil {
+		return err
+	}
+	state := schedule.State
+	if state == "" {
+		state = RoomScheduleScheduled
+	}
+
+	key := RoomSchedulePrefix + schedule.Request.Name
+	tx := s.rc.TxPipeline()
+	tx.Del(s.ctx, key)
+	tx.HSet(s.ctx, key,
+		roomScheduleFieldRequest, data,
+		roomScheduleFieldStartAt, schedule.StartAt.UnixMilli(),
+		roomScheduleFieldEndAt, unixMilliOrZero(schedule.EndAt),
+		roomScheduleFieldGrace, int64(schedule.GracePeriod),
+		roomScheduleFieldState, string(state),
+	)
+	tx.SAdd(s.ctx, RoomSchedulesKey, schedule.Request.Name)
+	_, err = tx.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) LoadRoomSchedule(_ context.Context, roomName livekit.RoomName) (*RoomSchedule, error) {
+	fields, err := s.rc.HGetAll(s.ctx, RoomSchedulePrefix+string(roomName)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return decodeRoomSchedule(fields)
+}
+
+func (s *RedisStore) ListRoomSchedules(ctx context.Context) ([]*RoomSchedule, error) {
+	names, err := s.rc.SMembers(s.ctx, RoomSchedulesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := make([]*RoomSchedule, 0, len(names))
+	for _, name := range names {
+		schedule, err := s.LoadRoomSchedule(ctx, livekit.RoomName(name))
+		if err != nil {
+			return nil, err
+		}
+		if schedule != nil {
+			schedules = append(schedules, schedule)
+		}
+	}
+	return schedules, nil
+}
+
+func (s *RedisStore) DeleteRoomSchedule(ctx context.Context, roomName livekit.RoomName) error {
+	tx := s.rc.TxPipeline()
+	tx.Del(s.ctx, RoomSchedulePrefix+string(roomName))
+	tx.SRem(s.ctx, RoomSchedulesKey, string(roomName))
+	_, err := tx.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) UpdateRoomScheduleState(_ context.Context, roomName livekit.RoomName, from, to RoomScheduleState) (bool, error) {
+	key := RoomSchedulePrefix + string(roomName)
+	updated := false
+	txf := func(tx *redis.Tx) error {
+		state, err := tx.HGet(s.ctx, key, roomScheduleFieldState).Result()
+		if err == redis.Nil {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if RoomScheduleState(state) != from {
+			return nil
+		}
+
+		_, err = tx.TxPipelined(s.ctx, func(p redis.Pipeliner) error {
+			p.HSet(s.ctx, key, roomScheduleFieldState, string(to))
+			return nil
+		})
+		if err == nil {
+			updated = true
+		}
+		return err
+	}
+
+	err := s.rc.Watch(s.ctx, txf, key)
+	if err == redis.TxFailedErr {
+		// changed by another node in the meantime
+		return false, nil
+	}
+	return updated, err
+}
+
+func decodeRoomSchedule(fields map[string]string) (*RoomSchedule, error) {
+	req := &livekit.CreateRoomRequest{}
+	if err := proto.Unmarshal([]byte(fields[roomScheduleFieldRequest]), req); err != nil {
+		return nil, err
+	}
+	startAt, _ := strconv.ParseInt(fields[roomScheduleFieldStartAt], 10, 64)
+	endAt, _ := strconv.ParseInt(fields[roomScheduleFieldEndAt], 10, 64)
+	grace, _ := strconv.ParseInt(fields[roomScheduleFieldGrace], 10, 64)
+	schedule := &RoomSchedule{
+		Request:     req,
+		StartAt:     time.UnixMilli(startAt),
+		GracePeriod: time.Duration(grace),
+		State:       RoomScheduleState(fields[roomScheduleFieldState]),
+	}
+	if endAt != 0 {
+		schedule.EndAt = time.UnixMilli(endAt)
+	}
+	return schedule, nil
+}
+
+func unixMilliOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const (
+	// RoomTemplatesKey is a set of room template names
+	RoomTemplatesKey = "room_templates"
+	// RoomTemplatePrefix is the prefix of the hash holding a room template
+	RoomTemplatePrefix = "room_template:"
+
+	roomTemplateFieldConfig   = "config"
+	roomTemplateFieldCodecs   = "codecs"
+	roomTemplateFieldMetadata = "metadata"
+)
+
+func (s *RedisStore) StoreRoomTemplate(ctx context.Context, template *RoomTemplate) error {
+	config, err := proto.Marshal(template.Config)
+	if err != nil {
+		return err
+	}
+	codecs, err := json.Marshal(template.EnabledCodecs)
+	if err != nil {
+		return err
+	}
+
+	key := RoomTemplatePrefix + template.Config.Name
+	tx := s.rc.TxPipeline()
+	tx.Del(s.ctx, key)
+	tx.HSet(s.ctx, key,
+		roomTemplateFieldConfig, config,
+		roomTemplateFieldCodecs, codecs,
+		roomTemplateFieldMetadata, template.Metadata,
+	)
+	tx.SAdd(s.ctx, RoomTemplatesKey, template.Config.Name)
+	_, err = tx.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) LoadRoomTemplate(_ context.Context, name string) (*RoomTemplate, error) {
+	fields, err := s.rc.HGetAll(s.ctx, RoomTemplatePrefix+name).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, ErrRoomTemplateNotFound
+	}
+
+	template := &RoomTemplate{
+		Config:   &livekit.RoomConfiguration{},
+		Metadata: fields[roomTemplateFieldMetadata],
+	}
+	if err = proto.Unmarshal([]byte(fields[roomTemplateFieldConfig]), template.Config); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(fields[roomTemplateFieldCodecs]), &template.EnabledCodecs); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (s *RedisStore) ListRoomTemplates(ctx context.Context) ([]*RoomTemplate, error) {
+	names, err := s.rc.SMembers(s.ctx, RoomTemplatesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]*RoomTemplate, 0, len(names))
+	for _, name := range names {
+		template, err := s.LoadRoomTemplate(ctx, name)
+		if err == ErrRoomTemplateNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+func (s *RedisStore) DeleteRoomTemplate(ctx context.Context, name string) error {
+	tx := s.rc.TxPipeline()
+	tx.Del(s.ctx, RoomTemplatePrefix+name)
+	tx.SRem(s.ctx, RoomTemplatesKey, name)
+	_, err := tx.Exec(ctx)
+	return err
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// RTMPStreamsKey is a hash of egress ID => JSON RTMPStream, the stream keys of the targets are kept to
+// reconnect them
+const RTMPStreamsKey = "rtmp_streams"
+
+func (s *RedisStore) StoreRTMPStream(ctx context.Context, stream *RTMPStream) error {
+	data, err := json.Marshal(stream)
+	if err != nil {
+		return err
+	}
+	return s.rc.HSet(s.ctx, RTMPStreamsKey, stream.EgressID, data).Err()
+}
+
+func (s *RedisStore) LoadRTMPStream(_ context.Context, egressID string) (*RTMPStream, error) {
+	data, err := s.rc.HGet(s.ctx, RTMPStreamsKey, egressID).Result()
+	if err == redis.Nil {
+		return nil, ErrRTMPStreamNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	stream := &RTMPStream{}
+	if err = json.Unmarshal([]byte(data), stream); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (s *RedisStore) ListRTMPStreams(_ context.Context, roomName livekit.RoomName) ([]*RTMPStream, error) {
+	items, err := s.rc.HGetAll(s.ctx, RTMPStreamsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var streams []*RTMPStream
+	for _, data := range items {
+		stream := &RTMPStream{}
+		if err = json.Unmarshal([]byte(data), stream); err != nil {
+			return nil, err
+		}
+		if roomName == "" || stream.Room == string(roomName) {
+			streams = append(streams, stream)
+		}
+	}
+	return streams, nil
+}
+
+func (s *RedisStore) DeleteRTMPStream(_ context.Context, egressID string) error {
+	return s.rc.HDel(s.ctx, RTMPStreamsKey, egressID).Err()
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const (
+	// RoomSessionsKey is a sorted set of the sids of closed room sessions, scored by the time they ended in
+	// unix milliseconds
+	RoomSessionsKey = "room_sessions"
+	// RoomSessionPrefix is the prefix of the JSON of a closed room session
+	RoomSessionPrefix = "room_session:"
+	// ParticipantSessionsPrefix is the prefix of the list of the JSON of the participant sessions of a room session
+	ParticipantSessionsPrefix = "room_session_participants:"
+)
+
+func (s *RedisStore) StoreRoomSession(ctx context.Context, session *RoomSession, retention time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	expireBefore := time.Now().Add(-retention)
+	tx := s.rc.TxPipeline()
+	tx.Set(s.ctx, RoomSessionPrefix+session.Sid, data, retention)
+	tx.ZAdd(s.ctx, RoomSessionsKey, redis.Z{
+		Score:  float64(session.EndedAt),
+		Member: session.Sid,
+	})
+	tx.ZRemRangeByScore(s.ctx, RoomSessionsKey, "-inf", "("+strconv.FormatInt(expireBefore.UnixMilli(), 10))
+	_, err = tx.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) StoreParticipantSession(ctx context.Context, session *ParticipantSession, retention time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	key := ParticipantSessionsPrefix + session.RoomSid
+	tx := s.rc.TxPipeline()
+	tx.RPush(s.ctx, key, data)
+	// participants of rooms still open are kept at least as long as the room session
+	tx.Expire(s.ctx, key, retention)
+	_, err = tx.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) ListRoomSessions(_ context.Context, from, to time.Time, limit int) ([]*RoomSession, error) {
+	sids, err := s.rc.ZRevRangeByScore(s.ctx, RoomSessionsKey, &redis.ZRangeBy{
+		Min:   strconv.FormatInt(from.UnixMilli(), 10),
+		Max:   "(" + strconv.FormatInt(to.UnixMilli(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil || len(sids) == 0 {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(sids))
+	for _, sid := range sids {
+		keys = append(keys, RoomSessionPrefix+sid)
+	}
+	items, err := s.rc.MGet(s.ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*RoomSession, 0, len(items))
+	for _, item := range items {
+		data, ok := item.(string)
+		if !ok {
+			// expired
+			continue
+		}
+		session := &RoomSession{}
+		if err = json.Unmarshal([]byte(data), session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+func (s *RedisStore) LoadRoomSession(_ context.Context, roomSid livekit.RoomID) (*RoomSession, error) {
+	data, err := s.rc.Get(s.ctx, RoomSessionPrefix+string(roomSid)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrSessionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	session := &RoomSession{}
+	if err = json.Unmarshal(data, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *RedisStore) ListParticipantSessions(_ context.Context, roomSid livekit.RoomID) ([]*ParticipantSession, error) {
+	items, err := s.rc.LRange(s.ctx, ParticipantSessionsPrefix+string(roomSid), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*ParticipantSession, 0, len(items))
+	for _, data := range items {
+		session := &ParticipantSession{}
+		if err = json.Unmarshal([]byte(data), session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
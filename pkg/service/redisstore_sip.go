@@ -16,6 +16,13 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/livekit/protocol/livekit"
 )
@@ -25,18 +32,132 @@ const (
 	SIPInboundTrunkKey  = "sip_inbound_trunk"
 	SIPOutboundTrunkKey = "sip_outbound_trunk"
 	SIPDispatchRuleKey  = "sip_dispatch_rule"
+
+	// SIPRevisionKey holds the revision of every trunk and dispatch rule, incremented on each write
+	SIPRevisionKey = "sip_revision"
+
+	// SIPCallMetricsKeyPrefix is the prefix of the per call hash recording which metrics were reported for it,
+	// shared by all nodes since call state updates may be served by any of them
+	SIPCallMetricsKeyPrefix = "sip_call_metrics:"
+
+	// calls that never report their end are forgotten after this
+	sipCallMetricsTTL = 24 * time.Hour
+
+	// SIPVoicemailRoomKeyPrefix is the prefix of the hash marking a room created for a voicemail call
+	SIPVoicemailRoomKeyPrefix = "sip_voicemail_room:"
+
+	// SIPCallRecordsKey is a sorted set of the JSON of call detail records, scored by the time calls were
+	// placed in unix milliseconds
+	SIPCallRecordsKey = "sip_call_records"
+
+	sipVoicemailFieldCallID        = "call_id"
+	sipVoicemailFieldRuleID        = "rule_id"
+	sipVoicemailFieldPromptURL     = "prompt_url"
+	sipVoicemailFieldPromptStarted = "prompt_started"
 )
 
+// redisStoreSIPOne stores a SIP object unconditionally, bumping its revision.
+func redisStoreSIPOne(ctx context.Context, s *RedisStore, key, id string, p proto.Message) error {
+	if id == "" {
+		return errors.New("id is not set")
+	}
+	data, err := proto.Marshal(p)
+	if err != nil {
+		return err
+	}
+	tx := s.rc.TxPipeline()
+	tx.HSet(s.ctx, key, id, data)
+	tx.HIncrBy(s.ctx, SIPRevisionKey, id, 1)
+	_, err = tx.Exec(ctx)
+	return err
+}
+
+// redisUpdateSIPOne replaces an existing SIP object only if its revision still matches expectedRevision,
+// returning the new revision.
+func redisUpdateSIPOne(ctx context.Context, s *RedisStore, key, id string, p proto.Message, expectedRevision uint64, notFoundErr error) (uint64, error) {
+	if id == "" {
+		return 0, errors.New("id is not set")
+	}
+	data, err := proto.Marshal(p)
+	if err != nil {
+		return 0, err
+	}
+
+	var revision uint64
+	txf := func(tx *redis.Tx) error {
+		exists, err := tx.HExists(s.ctx, key, id).Result()
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return notFoundErr
+		}
+
+		current, err := loadSIPRevision(s.ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if current != expectedRevision {
+			return ErrSIPRevisionMismatch
+		}
+		revision = current + 1
+
+		_, err = tx.TxPipelined(s.ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(s.ctx, key, id, data)
+			pipe.HSet(s.ctx, SIPRevisionKey, id, revision)
+			return nil
+		})
+		return err
+	}
+
+	// Retry if another object in the same hash has been changed, the revision check decides on conflicts.
+	for i := 0; i < maxRetries; i++ {
+		err = s.rc.Watch(s.ctx, txf, key, SIPRevisionKey)
+		switch err {
+		case redis.TxFailedErr:
+			continue
+		case nil:
+			return revision, nil
+		default:
+			return 0, err
+		}
+	}
+	return 0, ErrSIPRevisionMismatch
+}
+
+func loadSIPRevision(ctx context.Context, rc redis.Cmdable, id string) (uint64, error) {
+	val, err := rc.HGet(ctx, SIPRevisionKey, id).Result()
+	if err == redis.Nil {
+		// objects stored before revisions were introduced
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(val, 10, 64)
+}
+
+func (s *RedisStore) LoadSIPRevision(ctx context.Context, id string) (uint64, error) {
+	return loadSIPRevision(s.ctx, s.rc, id)
+}
+
 func (s *RedisStore) StoreSIPTrunk(ctx context.Context, info *livekit.SIPTrunkInfo) error {
-	return redisStoreOne(s.ctx, s, SIPTrunkKey, info.SipTrunkId, info)
+	return redisStoreSIPOne(s.ctx, s, SIPTrunkKey, info.SipTrunkId, info)
 }
 
 func (s *RedisStore) StoreSIPInboundTrunk(ctx context.Context, info *livekit.SIPInboundTrunkInfo) error {
-	return redisStoreOne(s.ctx, s, SIPInboundTrunkKey, info.SipTrunkId, info)
+	return redisStoreSIPOne(s.ctx, s, SIPInboundTrunkKey, info.SipTrunkId, info)
 }
 
 func (s *RedisStore) StoreSIPOutboundTrunk(ctx context.Context, info *livekit.SIPOutboundTrunkInfo) error {
-	return redisStoreOne(s.ctx, s, SIPOutboundTrunkKey, info.SipTrunkId, info)
+	return redisStoreSIPOne(s.ctx, s, SIPOutboundTrunkKey, info.SipTrunkId, info)
+}
+
+func (s *RedisStore) UpdateSIPInboundTrunk(ctx context.Context, info *livekit.SIPInboundTrunkInfo, expectedRevision uint64) (uint64, error) {
+	return redisUpdateSIPOne(ctx, s, SIPInboundTrunkKey, info.SipTrunkId, info, expectedRevision, ErrSIPTrunkNotFound)
+}
+
+func (s *RedisStore) UpdateSIPOutboundTrunk(ctx context.Context, info *livekit.SIPOutboundTrunkInfo, expectedRevision uint64) (uint64, error) {
+	return redisUpdateSIPOne(ctx, s, SIPOutboundTrunkKey, info.SipTrunkId, info, expectedRevision, ErrSIPTrunkNotFound)
 }
 
 func (s *RedisStore) loadSIPLegacyTrunk(ctx context.Context, id string) (*livekit.SIPTrunkInfo, error) {
@@ -110,6 +231,7 @@ func (s *RedisStore) DeleteSIPTrunk(ctx context.Context, id string) error {
 	tx.HDel(s.ctx, SIPTrunkKey, id)
 	tx.HDel(s.ctx, SIPInboundTrunkKey, id)
 	tx.HDel(s.ctx, SIPOutboundTrunkKey, id)
+	tx.HDel(s.ctx, SIPRevisionKey, id)
 	_, err := tx.Exec(ctx)
 	return err
 }
@@ -179,7 +301,11 @@ func (s *RedisStore) ListSIPOutboundTrunk(ctx context.Context) (infos []*livekit
 }
 
 func (s *RedisStore) StoreSIPDispatchRule(ctx context.Context, info *livekit.SIPDispatchRuleInfo) error {
-	return redisStoreOne(ctx, s, SIPDispatchRuleKey, info.SipDispatchRuleId, info)
+	return redisStoreSIPOne(ctx, s, SIPDispatchRuleKey, info.SipDispatchRuleId, info)
+}
+
+func (s *RedisStore) UpdateSIPDispatchRule(ctx context.Context, info *livekit.SIPDispatchRuleInfo, expectedRevision uint64) (uint64, error) {
+	return redisUpdateSIPOne(ctx, s, SIPDispatchRuleKey, info.SipDispatchRuleId, info, expectedRevision, ErrSIPDispatchRuleNotFound)
 }
 
 func (s *RedisStore) LoadSIPDispatchRule(ctx context.Context, sipDispatchRuleId string) (*livekit.SIPDispatchRuleInfo, error) {
@@ -187,9 +313,113 @@ func (s *RedisStore) LoadSIPDispatchRule(ctx context.Context, sipDispatchRuleId
 }
 
 func (s *RedisStore) DeleteSIPDispatchRule(ctx context.Context, info *livekit.SIPDispatchRuleInfo) error {
-	return s.rc.HDel(s.ctx, SIPDispatchRuleKey, info.SipDispatchRuleId).Err()
+	tx := s.rc.TxPipeline()
+	tx.HDel(s.ctx, SIPDispatchRuleKey, info.SipDispatchRuleId)
+	tx.HDel(s.ctx, SIPRevisionKey, info.SipDispatchRuleId)
+	_, err := tx.Exec(ctx)
+	return err
 }
 
 func (s *RedisStore) ListSIPDispatchRule(ctx context.Context) (infos []*livekit.SIPDispatchRuleInfo, err error) {
 	return redisLoadMany[livekit.SIPDispatchRuleInfo](ctx, s, SIPDispatchRuleKey)
 }
+
+// UpdateSIPCallMetricsState sets the fields of a call's metrics state that are not set yet. It returns the names of
+// the fields that were set by this call, along with the resulting state.
+func (s *RedisStore) UpdateSIPCallMetricsState(ctx context.Context, callID string, fields map[string]string) (map[string]bool, map[string]string, error) {
+	key := SIPCallMetricsKeyPrefix + callID
+	names := make([]string, 0, len(fields))
+	setCmds := make([]*redis.BoolCmd, 0, len(fields))
+
+	pp := s.rc.TxPipeline()
+	for name, value := range fields {
+		names = append(names, name)
+		setCmds = append(setCmds, pp.HSetNX(s.ctx, key, name, value))
+	}
+	pp.Expire(s.ctx, key, sipCallMetricsTTL)
+	stateCmd := pp.HGetAll(s.ctx, key)
+	if _, err := pp.Exec(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	set := make(map[string]bool, len(names))
+	for i, name := range names {
+		if setCmds[i].Val() {
+			set[name] = true
+		}
+	}
+	return set, stateCmd.Val(), nil
+}
+
+// StoreSIPVoicemailRoom marks a room as a voicemail room until ttl passes
+func (s *RedisStore) StoreSIPVoicemailRoom(ctx context.Context, info *SIPVoicemailRoom, ttl time.Duration) error {
+	key := SIPVoicemailRoomKeyPrefix + info.RoomName
+	tx := s.rc.TxPipeline()
+	tx.HSet(s.ctx, key,
+		sipVoicemailFieldCallID, info.CallID,
+		sipVoicemailFieldRuleID, info.RuleID,
+		sipVoicemailFieldPromptURL, info.PromptURL,
+	)
+	tx.Expire(s.ctx, key, ttl)
+	_, err := tx.Exec(ctx)
+	return err
+}
+
+// LoadSIPVoicemailRoom returns nil when the room is not a voicemail room
+func (s *RedisStore) LoadSIPVoicemailRoom(ctx context.Context, roomName string) (*SIPVoicemailRoom, error) {
+	fields, err := s.rc.HGetAll(s.ctx, SIPVoicemailRoomKeyPrefix+roomName).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return &SIPVoicemailRoom{
+		RoomName:  roomName,
+		CallID:    fields[sipVoicemailFieldCallID],
+		RuleID:    fields[sipVoicemailFieldRuleID],
+		PromptURL: fields[sipVoicemailFieldPromptURL],
+	}, nil
+}
+
+// ClaimSIPVoicemailPrompt returns true for the first caller only, so that the prompt is played once
+// even if call state updates are handled by several nodes
+func (s *RedisStore) ClaimSIPVoicemailPrompt(ctx context.Context, roomName string) (bool, error) {
+	return s.rc.HSetNX(s.ctx, SIPVoicemailRoomKeyPrefix+roomName, sipVoicemailFieldPromptStarted, "1").Result()
+}
+
+func (s *RedisStore) StoreSIPCallRecord(ctx context.Context, record *SIPCallRecord, expireBefore time.Time) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	tx := s.rc.TxPipeline()
+	tx.ZAdd(s.ctx, SIPCallRecordsKey, redis.Z{
+		Score:  float64(time.Unix(0, record.CreatedAt).UnixMilli()),
+		Member: data,
+	})
+	tx.ZRemRangeByScore(s.ctx, SIPCallRecordsKey, "-inf", "("+strconv.FormatInt(expireBefore.UnixMilli(), 10))
+	_, err = tx.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) ListSIPCallRecords(_ context.Context, from, to time.Time) ([]*SIPCallRecord, error) {
+	items, err := s.rc.ZRangeByScore(s.ctx, SIPCallRecordsKey, &redis.ZRangeBy{
+		Min: strconv.FormatInt(from.UnixMilli(), 10),
+		Max: "(" + strconv.FormatInt(to.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*SIPCallRecord, 0, len(items))
+	for _, data := range items {
+		r := &SIPCallRecord{}
+		if err = json.Unmarshal([]byte(data), r); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
@@ -261,3 +261,69 @@ func TestSIPStoreTrunk(t *testing.T) {
 	require.Equal(t, service.ErrSIPTrunkNotFound, err)
 	require.Nil(t, out)
 }
+
+func TestSIPStoreRevision(t *testing.T) {
+	ctx := context.Background()
+	rs := redisStore(t)
+
+	id := guid.New(utils.SIPTrunkPrefix)
+	trunk := &livekit.SIPInboundTrunkInfo{
+		SipTrunkId: id,
+		Name:       "Inbound",
+	}
+
+	// Updating non-existent trunk should fail.
+	_, err := rs.UpdateSIPInboundTrunk(ctx, trunk, 0)
+	require.Equal(t, service.ErrSIPTrunkNotFound, err)
+
+	err = rs.StoreSIPInboundTrunk(ctx, trunk)
+	require.NoError(t, err)
+	rev, err := rs.LoadSIPRevision(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), rev)
+
+	// Two admins read the same revision, only the first update wins.
+	first := proto.Clone(trunk).(*livekit.SIPInboundTrunkInfo)
+	first.Name = "First"
+	second := proto.Clone(trunk).(*livekit.SIPInboundTrunkInfo)
+	second.Name = "Second"
+
+	rev2, err := rs.UpdateSIPInboundTrunk(ctx, first, rev)
+	require.NoError(t, err)
+	require.Equal(t, rev+1, rev2)
+
+	_, err = rs.UpdateSIPInboundTrunk(ctx, second, rev)
+	require.Equal(t, service.ErrSIPRevisionMismatch, err)
+
+	got, err := rs.LoadSIPInboundTrunk(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "First", got.Name)
+
+	// Retrying with the latest revision succeeds.
+	_, err = rs.UpdateSIPInboundTrunk(ctx, second, rev2)
+	require.NoError(t, err)
+
+	// Deleting removes the revision as well.
+	err = rs.DeleteSIPTrunk(ctx, id)
+	require.NoError(t, err)
+	rev, err = rs.LoadSIPRevision(ctx, id)
+	require.NoError(t, err)
+	require.Zero(t, rev)
+
+	// Dispatch rules follow the same rules.
+	ruleID := guid.New(utils.SIPDispatchRulePrefix)
+	rule := &livekit.SIPDispatchRuleInfo{
+		SipDispatchRuleId: ruleID,
+		Name:              "Rule",
+	}
+	err = rs.StoreSIPDispatchRule(ctx, rule)
+	require.NoError(t, err)
+
+	_, err = rs.UpdateSIPDispatchRule(ctx, rule, 0)
+	require.Equal(t, service.ErrSIPRevisionMismatch, err)
+	_, err = rs.UpdateSIPDispatchRule(ctx, rule, 1)
+	require.NoError(t, err)
+
+	err = rs.DeleteSIPDispatchRule(ctx, rule)
+	require.NoError(t, err)
+}
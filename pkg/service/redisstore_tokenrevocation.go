@@ -0,0 +1,80 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// TokenRevocationsKey is a hash of revocation ID => JSON of the revocation
+	TokenRevocationsKey = "token_revocations"
+	// TokenRevocationsVersionKey is incremented whenever a revocation is stored
+	TokenRevocationsVersionKey = "token_revocations_version"
+)
+
+func (s *RedisStore) StoreTokenRevocation(ctx context.Context, revocation *TokenRevocation) error {
+	data, err := json.Marshal(revocation)
+	if err != nil {
+		return err
+	}
+
+	tx := s.rc.TxPipeline()
+	tx.HSet(s.ctx, TokenRevocationsKey, revocation.ID(), data)
+	tx.Incr(s.ctx, TokenRevocationsVersionKey)
+	_, err = tx.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) ListTokenRevocations(_ context.Context) ([]*TokenRevocation, error) {
+	items, err := s.rc.HGetAll(s.ctx, TokenRevocationsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var expired []string
+	revocations := make([]*TokenRevocation, 0, len(items))
+	for id, data := range items {
+		r := &TokenRevocation{}
+		if err = json.Unmarshal([]byte(data), r); err != nil {
+			return nil, err
+		}
+		if r.expired(now) {
+			expired = append(expired, id)
+			continue
+		}
+		revocations = append(revocations, r)
+	}
+	// expired tokens are refused anyway, their revocations are dropped without changing the version
+	if len(expired) != 0 {
+		if err = s.rc.HDel(s.ctx, TokenRevocationsKey, expired...).Err(); err != nil {
+			return nil, err
+		}
+	}
+	return revocations, nil
+}
+
+func (s *RedisStore) LoadTokenRevocationsVersion(_ context.Context) (int64, error) {
+	version, err := s.rc.Get(s.ctx, TokenRevocationsVersionKey).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return version, err
+}
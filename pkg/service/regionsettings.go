@@ -0,0 +1,141 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+)
+
+// how long the ranking of regions is reused before nodes are listed again
+const regionSettingsCacheTTL = 5 * time.Second
+
+// RegionSettingsService ranks the signaling URLs of the regions that can host rooms, nearest to the region
+// of this node first. Clients fetch them from /settings/regions with their access token, and fail over to
+// the next region when theirs goes down. Tokens are valid in every region as all of them share API keys.
+type RegionSettingsService struct {
+	router  routing.Router
+	regions atomic.Pointer[[]config.RegionConfig]
+
+	lock      sync.Mutex
+	ranked    []*livekit.RegionInfo
+	rankedAt  time.Time
+	rankedFor *[]config.RegionConfig
+}
+
+func NewRegionSettingsService(conf *config.Config, router routing.Router) *RegionSettingsService {
+	s := &RegionSettingsService{
+		router: router,
+	}
+	_ = s.ReloadConfig(conf)
+	return s
+}
+
+func (s *RegionSettingsService) ReloadConfig(conf *config.Config) error {
+	regions := conf.NodeSelector.Regions
+	s.regions.Store(&regions)
+	return nil
+}
+
+// GetRegionSettings returns the ranked regions, nil when no region has a signaling URL
+func (s *RegionSettingsService) GetRegionSettings() *livekit.RegionSettings {
+	ranked := s.rankedRegions()
+	if len(ranked) == 0 {
+		return nil
+	}
+	return &livekit.RegionSettings{
+		Regions: utils.CloneProtoSlice(ranked),
+	}
+}
+
+// AlternativeURL returns the signaling URL of the nearest other region that can host rooms
+func (s *RegionSettingsService) AlternativeURL() string {
+	current := s.router.GetRegion()
+	for _, region := range s.rankedRegions() {
+		if region.Region != current {
+			return region.Url
+		}
+	}
+	return ""
+}
+
+func (s *RegionSettingsService) rankedRegions() []*livekit.RegionInfo {
+	regions := s.regions.Load()
+	if !hasRegionURLs(*regions) {
+		return nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.rankedFor == regions && time.Since(s.rankedAt) < regionSettingsCacheTTL {
+		return s.ranked
+	}
+
+	nodes, err := s.router.ListNodes()
+	if err != nil {
+		logger.Warnw("could not list nodes to rank regions", err)
+		return s.ranked
+	}
+	s.ranked = selector.RankRegions(s.router.GetRegion(), *regions, nodes)
+	s.rankedAt = time.Now()
+	s.rankedFor = regions
+	return s.ranked
+}
+
+func (s *RegionSettingsService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	if GetGrants(r.Context()) == nil {
+		handleError(w, r, http.StatusUnauthorized, ErrPermissionDenied)
+		return
+	}
+
+	settings := s.GetRegionSettings()
+	if settings == nil {
+		handleError(w, r, http.StatusNotFound, errors.New("no regions configured"))
+		return
+	}
+	data, err := protojson.Marshal(settings)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+func hasRegionURLs(regions []config.RegionConfig) bool {
+	for _, region := range regions {
+		if region.URL != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -17,42 +17,114 @@ package service
 import (
 	"context"
 	"errors"
+	"math/rand"
+	"path"
 	"time"
 
+	"go.uber.org/atomic"
+
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/utils"
-	"github.com/livekit/protocol/utils/guid"
 	"github.com/livekit/psrpc"
 
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/idgen"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/routing/selector"
 )
 
+// roomAllocatorSettings are the parts of the configuration replaced when it is reloaded
+type roomAllocatorSettings struct {
+	room     config.RoomConfig
+	limit    config.LimitConfig
+	canary   config.CanaryConfig
+	selector selector.NodeSelector
+}
+
 type StandardRoomAllocator struct {
-	config    *config.Config
-	router    routing.Router
-	selector  selector.NodeSelector
-	roomStore ObjectStore
+	config        *config.Config
+	settings      atomic.Pointer[roomAllocatorSettings]
+	router        routing.Router
+	roomStore     ObjectStore
+	sipStore      SIPStore
+	scheduleStore RoomScheduleStore
+	templateStore RoomTemplateStore
+	policy        *PolicyWebhook
+	latencies     *selector.NodeLatencies
+	idGenerator   idgen.Generator
 }
 
-func NewRoomAllocator(conf *config.Config, router routing.Router, rs ObjectStore) (RoomAllocator, error) {
-	ns, err := selector.CreateNodeSelector(conf)
+func NewRoomAllocator(
+	conf *config.Config,
+	router routing.Router,
+	rs ObjectStore,
+	policy *PolicyWebhook,
+	latencies *selector.NodeLatencies,
+) (RoomAllocator, error) {
+	idGenerator, err := idgen.New(conf.IDs)
 	if err != nil {
 		return nil, err
 	}
+	r := &StandardRoomAllocator{
+		config:        conf,
+		router:        router,
+		roomStore:     rs,
+		sipStore:      getSIPStore(rs),
+		scheduleStore: getRoomScheduleStore(rs),
+		templateStore: getRoomTemplateStore(rs),
+		policy:        policy,
+		latencies:     latencies,
+		idGenerator:   idGenerator,
+	}
+	if err := r.ReloadConfig(conf); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
 
-	return &StandardRoomAllocator{
-		config:    conf,
-		router:    router,
-		selector:  ns,
-		roomStore: rs,
-	}, nil
+// ReloadConfig replaces the room defaults, limits, canary placement and node selector
+func (r *StandardRoomAllocator) ReloadConfig(conf *config.Config) error {
+	ns, err := selector.CreateNodeSelector(conf)
+	if err != nil {
+		return err
+	}
+	if ls, ok := ns.(*selector.LatencyAwareSelector); ok {
+		ls.Latencies = r.latencies
+	}
+	r.settings.Store(&roomAllocatorSettings{
+		room:     conf.Room,
+		limit:    conf.Limit,
+		canary:   conf.Canary,
+		selector: ns,
+	})
+	return nil
+}
+
+// applySIPVoicemailPreset applies the voicemail room preset to rooms created for voicemail calls
+func (r *StandardRoomAllocator) applySIPVoicemailPreset(ctx context.Context, req *livekit.CreateRoomRequest) *livekit.CreateRoomRequest {
+	if r.sipStore == nil {
+		return req
+	}
+	vm, err := r.sipStore.LoadSIPVoicemailRoom(ctx, req.Name)
+	if err != nil {
+		logger.Warnw("could not load SIP voicemail room", err, "room", req.Name)
+		return req
+	}
+	if vm == nil {
+		return req
+	}
+	if r.config.SIP.Voicemail.RoomPreset == "" {
+		logger.Warnw("SIP voicemail room preset is not configured, the call will not be recorded", nil, "room", req.Name, "callID", vm.CallID)
+		return req
+	}
+	req = utils.CloneProto(req)
+	req.RoomPreset = r.config.SIP.Voicemail.RoomPreset
+	return req
 }
 
 func (r *StandardRoomAllocator) AutoCreateEnabled(context.Context) bool {
-	return r.config.Room.AutoCreate
+	return r.settings.Load().room.AutoCreate
 }
 
 // CreateRoom creates a new room from a request and allocates it to a node to handle
@@ -72,21 +144,27 @@ func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.Cre
 	if errors.Is(err, ErrRoomNotFound) {
 		created = true
 		rm = &livekit.Room{
-			Sid:          guid.New(utils.RoomPrefix),
+			Sid:          string(r.idGenerator.RoomID(livekit.RoomName(req.Name))),
 			Name:         req.Name,
 			CreationTime: time.Now().Unix(),
 			TurnPassword: utils.RandomSecret(),
 		}
 		internal = &livekit.RoomInternal{}
-		applyDefaultRoomConfig(rm, internal, &r.config.Room)
+		applyDefaultRoomConfig(rm, internal, &r.settings.Load().room)
 	} else if err != nil {
 		return nil, nil, false, err
 	}
 
-	req, err = r.applyNamedRoomConfiguration(req)
+	if created && req.RoomPreset == "" {
+		req = r.applySIPVoicemailPreset(ctx, req)
+	}
+	req, template, err := r.applyNamedRoomConfiguration(ctx, req)
 	if err != nil {
 		return nil, nil, false, err
 	}
+	if created && len(template.GetEnabledCodecs()) != 0 {
+		rm.EnabledCodecs = template.EnabledCodecs
+	}
 
 	if req.EmptyTimeout > 0 {
 		rm.EmptyTimeout = req.EmptyTimeout
@@ -122,6 +200,12 @@ func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.Cre
 		internal.SyncStreams = true
 	}
 
+	if created && r.policy != nil {
+		if err = r.policy.CheckCreateRoom(ctx, rm); err != nil {
+			return nil, nil, false, err
+		}
+	}
+
 	if err = r.roomStore.StoreRoom(ctx, rm, internal); err != nil {
 		return nil, nil, false, err
 	}
@@ -139,7 +223,7 @@ func (r *StandardRoomAllocator) SelectRoomNode(ctx context.Context, roomName liv
 	// if already assigned and still available, keep it on that node
 	if err == nil && selector.IsAvailable(existing) {
 		// if node hosting the room is full, deny entry
-		if selector.LimitsReached(r.config.Limit, existing.Stats) {
+		if selector.LimitsReached(r.settings.Load().limit, existing.Stats) {
 			return routing.ErrNodeLimitReached
 		}
 
@@ -152,8 +236,15 @@ func (r *StandardRoomAllocator) SelectRoomNode(ctx context.Context, roomName liv
 		if err != nil {
 			return err
 		}
+		nodes = r.filterNodesByBuild(roomName, nodes)
 
-		node, err := r.selector.SelectNode(nodes)
+		var node *livekit.Node
+		ns := r.settings.Load().selector
+		if cs, ok := ns.(selector.ClientAwareSelector); ok {
+			node, err = cs.SelectNodeForClient(nodes, clientLocationFromContext(ctx))
+		} else {
+			node, err = ns.SelectNode(nodes)
+		}
 		if err != nil {
 			return err
 		}
@@ -170,9 +261,58 @@ func (r *StandardRoomAllocator) SelectRoomNode(ctx context.Context, roomName liv
 	return nil
 }
 
+// filterNodesByBuild keeps the canary nodes for rooms placed on canary builds and the stable nodes for the
+// others. All nodes are kept when none of the wanted build is available.
+func (r *StandardRoomAllocator) filterNodesByBuild(roomName livekit.RoomName, nodes []*livekit.Node) []*livekit.Node {
+	lister, ok := r.router.(routing.NodeBuildLister)
+	if !ok {
+		return nodes
+	}
+	builds, err := lister.ListNodeBuilds()
+	if err != nil {
+		logger.Warnw("could not list node builds", err, "room", roomName)
+		return nodes
+	}
+
+	canary := isCanaryRoom(r.settings.Load().canary, roomName)
+	filtered := make([]*livekit.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if builds[livekit.NodeID(node.Id)].Canary == canary && selector.IsAvailable(node) {
+			filtered = append(filtered, node)
+		}
+	}
+	if len(filtered) == 0 {
+		return nodes
+	}
+	if canary {
+		logger.Infow("placing room on canary nodes", "room", roomName)
+	}
+	return filtered
+}
+
+func isCanaryRoom(conf config.CanaryConfig, roomName livekit.RoomName) bool {
+	for _, pattern := range conf.Rooms {
+		if ok, _ := path.Match(pattern, string(roomName)); ok {
+			return true
+		}
+	}
+	return conf.RoomPercentage > 0 && rand.Float64()*100 < conf.RoomPercentage
+}
+
 func (r *StandardRoomAllocator) ValidateCreateRoom(ctx context.Context, roomName livekit.RoomName) error {
+	// scheduled rooms refuse joins until they start
+	if r.scheduleStore != nil {
+		schedule, err := r.scheduleStore.LoadRoomSchedule(ctx, roomName)
+		if err != nil {
+			return err
+		}
+		if schedule != nil && schedule.State == RoomScheduleScheduled && time.Now().Before(schedule.StartAt) {
+			return ErrRoomNotStarted
+		}
+	}
+
 	// when auto create is disabled, we'll check to ensure it's already created
-	if !r.config.Room.AutoCreate {
+	if !r.settings.Load().room.AutoCreate {
 		_, _, err := r.roomStore.LoadRoom(ctx, roomName, false)
 		if err != nil {
 			return err
@@ -199,46 +339,29 @@ func applyDefaultRoomConfig(room *livekit.Room, internal *livekit.RoomInternal,
 	internal.SyncStreams = conf.SyncStreams
 }
 
-func (r *StandardRoomAllocator) applyNamedRoomConfiguration(req *livekit.CreateRoomRequest) (*livekit.CreateRoomRequest, error) {
+// applyNamedRoomConfiguration applies the preset named by the request, presets of the config file take
+// precedence over room templates. The template is returned when the preset is one.
+func (r *StandardRoomAllocator) applyNamedRoomConfiguration(ctx context.Context, req *livekit.CreateRoomRequest) (*livekit.CreateRoomRequest, *RoomTemplate, error) {
 	if req.RoomPreset == "" {
-		return req, nil
+		return req, nil, nil
 	}
 
-	conf, ok := r.config.Room.RoomConfigurations[req.RoomPreset]
-	if !ok {
-		return req, psrpc.NewErrorf(psrpc.InvalidArgument, "unknown room confguration in create room request")
+	if conf, ok := r.settings.Load().room.RoomConfigurations[req.RoomPreset]; ok {
+		return applyRoomConfiguration(req, conf), nil, nil
 	}
 
-	clone := utils.CloneProto(req)
-
-	// Request overwrites conf
-	if clone.EmptyTimeout == 0 {
-		clone.EmptyTimeout = conf.EmptyTimeout
-	}
-	if clone.DepartureTimeout == 0 {
-		clone.DepartureTimeout = req.DepartureTimeout
-	}
-	if clone.MaxParticipants == 0 {
-		clone.MaxParticipants = conf.MaxParticipants
-	}
-	if clone.Egress == nil {
-		clone.Egress = utils.CloneProto(conf.Egress)
-	}
-	if clone.Agents == nil {
-		clone.Agents = make([]*livekit.RoomAgentDispatch, 0, len(conf.Agents))
-		for _, agent := range conf.Agents {
-			clone.Agents = append(clone.Agents, utils.CloneProto(agent))
+	if r.templateStore != nil {
+		template, err := r.templateStore.LoadRoomTemplate(ctx, req.RoomPreset)
+		if err == nil {
+			req = applyRoomConfiguration(req, template.Config)
+			if req.Metadata == "" {
+				req.Metadata = template.Metadata
+			}
+			return req, template, nil
+		} else if err != ErrRoomTemplateNotFound {
+			return req, nil, err
 		}
 	}
-	if clone.MinPlayoutDelay == 0 {
-		clone.MinPlayoutDelay = conf.MinPlayoutDelay
-	}
-	if clone.MaxPlayoutDelay == 0 {
-		clone.MaxPlayoutDelay = conf.MaxPlayoutDelay
-	}
-	if !clone.SyncStreams {
-		clone.SyncStreams = conf.SyncStreams
-	}
 
-	return clone, nil
+	return req, nil, psrpc.NewErrorf(psrpc.InvalidArgument, "unknown room confguration in create room request")
 }
@@ -85,6 +85,58 @@ func SelectRoomNode(t *testing.T) {
 	})
 }
 
+type nodeBuildRouter struct {
+	*routingfakes.FakeRouter
+	builds map[livekit.NodeID]routing.NodeBuild
+}
+
+func (r *nodeBuildRouter) ListNodeBuilds() (map[livekit.NodeID]routing.NodeBuild, error) {
+	return r.builds, nil
+}
+
+func TestSelectRoomNode_Canary(t *testing.T) {
+	conf, err := config.NewConfig("", true, nil, nil)
+	require.NoError(t, err)
+	conf.Canary.Rooms = []string{"canary-*"}
+
+	newNode := func(nodeID livekit.NodeID) *livekit.Node {
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+		node.SetNodeID(nodeID)
+		return node.Clone()
+	}
+	stable, canary := newNode("stable"), newNode("canary")
+
+	router := &nodeBuildRouter{
+		FakeRouter: &routingfakes.FakeRouter{},
+		builds: map[livekit.NodeID]routing.NodeBuild{
+			"stable": {Version: "1.0.0"},
+			"canary": {Version: "1.1.0", Canary: true},
+		},
+	}
+	router.GetNodeForRoomReturns(nil, routing.ErrNotFound)
+	router.ListNodesReturns([]*livekit.Node{stable, canary}, nil)
+
+	store := &servicefakes.FakeObjectStore{}
+	ra, err := service.NewRoomAllocator(conf, router, store, nil, nil)
+	require.NoError(t, err)
+
+	selected := func(roomName livekit.RoomName) livekit.NodeID {
+		require.NoError(t, ra.SelectRoomNode(context.Background(), roomName, ""))
+		_, _, nodeID := router.SetNodeForRoomArgsForCall(router.SetNodeForRoomCallCount() - 1)
+		return nodeID
+	}
+
+	for i := 0; i < 10; i++ {
+		require.Equal(t, livekit.NodeID("canary"), selected("canary-room"))
+		require.Equal(t, livekit.NodeID("stable"), selected("room"))
+	}
+
+	// rooms fall back to canary nodes when no stable node is available
+	router.ListNodesReturns([]*livekit.Node{canary}, nil)
+	require.Equal(t, livekit.NodeID("canary"), selected("room"))
+}
+
 func newTestRoomAllocator(t *testing.T, conf *config.Config, node *livekit.Node) (service.RoomAllocator, *config.Config) {
 	store := &servicefakes.FakeObjectStore{}
 	store.LoadRoomReturns(nil, nil, service.ErrRoomNotFound)
@@ -92,7 +144,7 @@ func newTestRoomAllocator(t *testing.T, conf *config.Config, node *livekit.Node)
 
 	router.GetNodeForRoomReturns(node, nil)
 
-	ra, err := service.NewRoomAllocator(conf, router, store)
+	ra, err := service.NewRoomAllocator(conf, router, store, nil, nil)
 	require.NoError(t, err)
 	return ra, conf
 }
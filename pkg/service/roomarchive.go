@@ -0,0 +1,102 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/archiver"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const maxRoomArchiveRequest = 4 * 1024
+
+// GetArchivedRoomRequest is the JSON body of POST /room_archive/get, sid identifies the session of the room
+type GetArchivedRoomRequest struct {
+	Room string `json:"room"`
+	Sid  string `json:"sid"`
+}
+
+// RoomArchiveService serves the archives of closed rooms from object storage, calls require roomAdmin
+type RoomArchiveService struct {
+	archiver     *archiver.Archiver
+	adminLimiter *AdminLimiter
+}
+
+func NewRoomArchiveService(roomArchiver *archiver.Archiver, adminLimiter *AdminLimiter) *RoomArchiveService {
+	return &RoomArchiveService{
+		archiver:     roomArchiver,
+		adminLimiter: adminLimiter,
+	}
+}
+
+func (s *RoomArchiveService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/room_archive/") {
+	case "get":
+		var req GetArchivedRoomRequest
+		if err = decodeJSONRequest(r, &req, maxRoomArchiveRequest); err == nil {
+			res, err = s.GetArchivedRoom(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *RoomArchiveService) GetArchivedRoom(ctx context.Context, req *GetArchivedRoomRequest) (*rtc.RoomArchive, error) {
+	AppendLogFields(ctx, "room", req.Room, "roomID", req.Sid)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
+		return nil, err
+	}
+	if s.archiver == nil {
+		return nil, ErrRoomArchiveDisabled
+	}
+	if req.Room == "" || req.Sid == "" || strings.Contains(req.Sid, "/") {
+		return nil, ErrRoomArchiveInvalid
+	}
+	release, err := s.adminLimiter.Acquire(ctx, AdminOpRoomArchive)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	archive, err := s.archiver.GetArchivedRoom(ctx, livekit.RoomName(req.Room), livekit.RoomID(req.Sid))
+	if errors.Is(err, archiver.ErrNotFound) {
+		return nil, ErrRoomArchiveNotFound
+	}
+	return archive, err
+}
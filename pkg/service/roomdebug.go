@@ -0,0 +1,348 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	roomDebugRPCService  = "RoomDebug"
+	roomDebugRPC         = "DebugRoom"
+	signalCaptureRPC     = "SignalCapture"
+	conformanceRPC       = "Conformance"
+	clientDiagnosticsRPC = "ClientDiagnostics"
+
+	signalCaptureStart = "start"
+	signalCaptureStop  = "stop"
+	signalCaptureGet   = "get"
+
+	maxRoomDebugRequest = 4 * 1024
+)
+
+// RoomDebugClient reaches the node hosting a room to take a snapshot of its state, to capture the signal
+// messages of a participant, to force conformance test cases on it, or to ask it to upload its diagnostics.
+// Requests and results are carried as JSON in the payload of user data packets.
+type RoomDebugClient interface {
+	DebugRoom(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error)
+	SignalCapture(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error)
+	Conformance(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error)
+	ClientDiagnostics(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error)
+}
+
+type RoomDebugServerImpl interface {
+	DebugRoom(ctx context.Context, roomName livekit.RoomName) (*rtc.RoomDebugSnapshot, error)
+	// action is start, stop or get
+	SignalCapture(ctx context.Context, roomName livekit.RoomName, action string, identity livekit.ParticipantIdentity, duration time.Duration) (*SignalCapture, error)
+	Conformance(ctx context.Context, roomName livekit.RoomName, action string, req *ConformanceRequest) (*ConformanceResult, error)
+	RequestDiagnosticsUpload(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, req *rtc.DiagnosticsUploadRequest) error
+}
+
+// clientDiagnosticsRPCRequest is carried to the node hosting the room
+type clientDiagnosticsRPCRequest struct {
+	Identity livekit.ParticipantIdentity `json:"identity"`
+	*rtc.DiagnosticsUploadRequest
+}
+
+// signalCaptureRPCRequest is carried to the node hosting the room
+type signalCaptureRPCRequest struct {
+	Action   string                      `json:"action"`
+	Identity livekit.ParticipantIdentity `json:"identity"`
+	Duration time.Duration               `json:"duration,omitempty"`
+}
+
+type roomDebugClient struct {
+	client *client.RPCClient
+}
+
+func NewRoomDebugClient(params rpc.ClientParams) (RoomDebugClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: roomDebugRPCService,
+		ID:   rand.NewClientID(),
+	}
+	sd.RegisterMethod(roomDebugRPC, false, false, true, true)
+	sd.RegisterMethod(signalCaptureRPC, false, false, true, true)
+	sd.RegisterMethod(conformanceRPC, false, false, true, true)
+	sd.RegisterMethod(clientDiagnosticsRPC, false, false, true, true)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &roomDebugClient{client: rpcClient}, nil
+}
+
+func (c *roomDebugClient) DebugRoom(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return client.RequestSingle[*livekit.DataPacket](ctx, c.client, roomDebugRPC, []string{string(room)}, req, opts...)
+}
+
+func (c *roomDebugClient) SignalCapture(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return client.RequestSingle[*livekit.DataPacket](ctx, c.client, signalCaptureRPC, []string{string(room)}, req, opts...)
+}
+
+func (c *roomDebugClient) Conformance(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return client.RequestSingle[*livekit.DataPacket](ctx, c.client, conformanceRPC, []string{string(room)}, req, opts...)
+}
+
+func (c *roomDebugClient) ClientDiagnostics(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return client.RequestSingle[*livekit.DataPacket](ctx, c.client, clientDiagnosticsRPC, []string{string(room)}, req, opts...)
+}
+
+// roomDebugServer takes snapshots of a room hosted on this node
+type roomDebugServer struct {
+	svc      RoomDebugServerImpl
+	roomName livekit.RoomName
+	rpc      *server.RPCServer
+}
+
+func newRoomDebugServer(svc RoomDebugServerImpl, roomName livekit.RoomName, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *roomDebugServer {
+	sd := &info.ServiceDefinition{
+		Name: roomDebugRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	sd.RegisterMethod(roomDebugRPC, false, false, true, true)
+	sd.RegisterMethod(signalCaptureRPC, false, false, true, true)
+	sd.RegisterMethod(conformanceRPC, false, false, true, true)
+	sd.RegisterMethod(clientDiagnosticsRPC, false, false, true, true)
+	return &roomDebugServer{
+		svc:      svc,
+		roomName: roomName,
+		rpc:      s,
+	}
+}
+
+func (s *roomDebugServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	if err := server.RegisterHandler(s.rpc, roomDebugRPC, []string{string(room)}, s.handle, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, signalCaptureRPC, []string{string(room)}, s.handleSignalCapture, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, conformanceRPC, []string{string(room)}, s.handleConformance, nil); err != nil {
+		return err
+	}
+	return server.RegisterHandler(s.rpc, clientDiagnosticsRPC, []string{string(room)}, s.handleClientDiagnostics, nil)
+}
+
+func (s *roomDebugServer) handle(ctx context.Context, _ *livekit.DataPacket) (*livekit.DataPacket, error) {
+	snapshot, err := s.svc.DebugRoom(ctx, s.roomName)
+	if err != nil {
+		return nil, err
+	}
+	return jsonDataPacket(snapshot)
+}
+
+func (s *roomDebugServer) handleSignalCapture(ctx context.Context, dp *livekit.DataPacket) (*livekit.DataPacket, error) {
+	var req signalCaptureRPCRequest
+	if err := json.Unmarshal(dp.GetUser().GetPayload(), &req); err != nil {
+		return nil, ErrSignalCaptureInvalid
+	}
+	capture, err := s.svc.SignalCapture(ctx, s.roomName, req.Action, req.Identity, req.Duration)
+	if err != nil {
+		return nil, err
+	}
+	return jsonDataPacket(capture)
+}
+
+func (s *roomDebugServer) handleConformance(ctx context.Context, dp *livekit.DataPacket) (*livekit.DataPacket, error) {
+	var req conformanceRPCRequest
+	if err := json.Unmarshal(dp.GetUser().GetPayload(), &req); err != nil || req.ConformanceRequest == nil {
+		return nil, ErrConformanceInvalid
+	}
+	res, err := s.svc.Conformance(ctx, s.roomName, req.Action, req.ConformanceRequest)
+	if err != nil {
+		return nil, err
+	}
+	return jsonDataPacket(res)
+}
+
+func (s *roomDebugServer) handleClientDiagnostics(ctx context.Context, dp *livekit.DataPacket) (*livekit.DataPacket, error) {
+	var req clientDiagnosticsRPCRequest
+	if err := json.Unmarshal(dp.GetUser().GetPayload(), &req); err != nil || req.DiagnosticsUploadRequest == nil {
+		return nil, ErrClientDiagnosticsInvalid
+	}
+	if err := s.svc.RequestDiagnosticsUpload(ctx, s.roomName, req.Identity, req.DiagnosticsUploadRequest); err != nil {
+		return nil, err
+	}
+	return &livekit.DataPacket{}, nil
+}
+
+func jsonDataPacket(v any) (*livekit.DataPacket, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{Payload: payload},
+		},
+	}, nil
+}
+
+func (s *roomDebugServer) Kill() {
+	s.rpc.Close(true)
+}
+
+// ---------------------------------------------
+
+// DebugRoomRequest is the JSON body of POST /room_debug/snapshot
+type DebugRoomRequest struct {
+	Room string `json:"room"`
+}
+
+// SignalCaptureRequest is the JSON body of POST /room_debug/signal_capture/start, /room_debug/signal_capture/stop
+// and /room_debug/signal_capture, which returns the captured messages
+type SignalCaptureRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	// seconds the capture runs for when started, up to signal_capture.max_duration which is the default
+	DurationSeconds int `json:"duration_seconds,omitempty"`
+}
+
+// RoomDebugService serves the HTTP API returning a snapshot of a live room taken by its node: transports and
+// ICE candidates of participants, layers and bitrates of published tracks, subscriptions and pending
+// migrations. It also captures the signal messages of a participant when signal capture is enabled.
+// Calls require roomAdmin.
+type RoomDebugService struct {
+	roomStore      ServiceStore
+	topicFormatter rpc.TopicFormatter
+	client         RoomDebugClient
+}
+
+func NewRoomDebugService(
+	roomStore ServiceStore,
+	topicFormatter rpc.TopicFormatter,
+	client RoomDebugClient,
+) *RoomDebugService {
+	return &RoomDebugService{
+		roomStore:      roomStore,
+		topicFormatter: topicFormatter,
+		client:         client,
+	}
+}
+
+func (s *RoomDebugService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/room_debug/") {
+	case "snapshot":
+		var req DebugRoomRequest
+		if err = decodeJSONRequest(r, &req, maxRoomDebugRequest); err == nil {
+			res, err = s.DebugRoom(r.Context(), &req)
+		}
+	case "signal_capture/start", "signal_capture/stop", "signal_capture":
+		action := signalCaptureGet
+		switch r.URL.Path {
+		case "/room_debug/signal_capture/start":
+			action = signalCaptureStart
+		case "/room_debug/signal_capture/stop":
+			action = signalCaptureStop
+		}
+		var req SignalCaptureRequest
+		if err = decodeJSONRequest(r, &req, maxRoomDebugRequest); err == nil {
+			res, err = s.SignalCapture(r.Context(), action, &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *RoomDebugService) DebugRoom(ctx context.Context, req *DebugRoomRequest) (*rtc.RoomDebugSnapshot, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName)
+	if err := EnsureAdminPermission(ctx, roomName); err != nil {
+		return nil, err
+	}
+	// the snapshot is taken by the node hosting the room
+	if _, _, err := s.roomStore.LoadRoom(ctx, roomName, false); err != nil {
+		return nil, err
+	}
+
+	res, err := s.client.DebugRoom(ctx, s.topicFormatter.RoomTopic(ctx, roomName), &livekit.DataPacket{})
+	if err != nil {
+		return nil, err
+	}
+	var snapshot rtc.RoomDebugSnapshot
+	if err = json.Unmarshal(res.GetUser().GetPayload(), &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func (s *RoomDebugService) SignalCapture(ctx context.Context, action string, req *SignalCaptureRequest) (*SignalCapture, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName, "participant", req.Identity, "action", action)
+	if err := EnsureAdminPermission(ctx, roomName); err != nil {
+		return nil, err
+	}
+	if req.Identity == "" || req.DurationSeconds < 0 {
+		return nil, ErrSignalCaptureInvalid
+	}
+	// messages are captured by the node hosting the room
+	if _, _, err := s.roomStore.LoadRoom(ctx, roomName, false); err != nil {
+		return nil, err
+	}
+
+	dp, err := jsonDataPacket(&signalCaptureRPCRequest{
+		Action:   action,
+		Identity: livekit.ParticipantIdentity(req.Identity),
+		Duration: time.Duration(req.DurationSeconds) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.SignalCapture(ctx, s.topicFormatter.RoomTopic(ctx, roomName), dp)
+	if err != nil {
+		return nil, err
+	}
+	var capture SignalCapture
+	if err = json.Unmarshal(res.GetUser().GetPayload(), &capture); err != nil {
+		return nil, err
+	}
+	return &capture, nil
+}
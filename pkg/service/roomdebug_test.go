@@ -0,0 +1,95 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+// testRoomDebugClient answers with the snapshot it was given, and echoes conformance and client diagnostics
+// requests
+type testRoomDebugClient struct {
+	snapshot    *rtc.RoomDebugSnapshot
+	conformance []*livekit.DataPacket
+	diagnostics []*livekit.DataPacket
+}
+
+func (c *testRoomDebugClient) DebugRoom(_ context.Context, _ rpc.RoomTopic, _ *livekit.DataPacket, _ ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	payload, err := json.Marshal(c.snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return &livekit.DataPacket{Value: &livekit.DataPacket_User{User: &livekit.UserPacket{Payload: payload}}}, nil
+}
+
+func (c *testRoomDebugClient) SignalCapture(_ context.Context, _ rpc.RoomTopic, _ *livekit.DataPacket, _ ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return nil, service.ErrSignalCaptureDisabled
+}
+
+func (c *testRoomDebugClient) Conformance(_ context.Context, _ rpc.RoomTopic, req *livekit.DataPacket, _ ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	c.conformance = append(c.conformance, req)
+	return req, nil
+}
+
+func (c *testRoomDebugClient) ClientDiagnostics(_ context.Context, _ rpc.RoomTopic, req *livekit.DataPacket, _ ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	c.diagnostics = append(c.diagnostics, req)
+	return &livekit.DataPacket{}, nil
+}
+
+func TestRoomDebug(t *testing.T) {
+	store := service.NewLocalStore()
+	client := &testRoomDebugClient{
+		snapshot: &rtc.RoomDebugSnapshot{
+			Room: "class",
+			Participants: []*rtc.ParticipantDebugSnapshot{{
+				Identity:   "teacher",
+				Transports: []*rtc.TransportDebugSnapshot{{Transport: "PUBLISHER", ConnectionType: "udp"}},
+			}},
+			PendingMigrations: []livekit.ParticipantIdentity{"teacher"},
+		},
+	}
+	svc := service.NewRoomDebugService(store, rpc.NewTopicFormatter(), client)
+
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomAdmin: true, Room: "class"},
+	}, "")
+	require.NoError(t, store.StoreRoom(ctx, &livekit.Room{Name: "class"}, nil))
+
+	other := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomJoin: true, Room: "class"},
+	}, "")
+	_, err := svc.DebugRoom(other, &service.DebugRoomRequest{Room: "class"})
+	require.ErrorIs(t, err, service.ErrPermissionDenied)
+
+	_, err = svc.DebugRoom(ctx, &service.DebugRoomRequest{Room: "missing"})
+	require.Error(t, err)
+
+	snapshot, err := svc.DebugRoom(ctx, &service.DebugRoomRequest{Room: "class"})
+	require.NoError(t, err)
+	require.Equal(t, "udp", snapshot.Participants[0].Transports[0].ConnectionType)
+	require.Equal(t, []livekit.ParticipantIdentity{"teacher"}, snapshot.PendingMigrations)
+}
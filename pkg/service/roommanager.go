@@ -18,14 +18,20 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/atomic"
 	"golang.org/x/exp/maps"
 
 	"github.com/livekit/livekit-server/pkg/agent"
+	"github.com/livekit/livekit-server/pkg/archiver"
 	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/connectionquality"
 	sutils "github.com/livekit/livekit-server/pkg/utils"
 	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
 	"github.com/livekit/protocol/auth"
@@ -33,13 +39,13 @@ import (
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/protocol/utils"
-	"github.com/livekit/protocol/utils/guid"
 	"github.com/livekit/protocol/utils/must"
 	"github.com/livekit/psrpc"
 	"github.com/livekit/psrpc/pkg/middleware"
 
 	"github.com/livekit/livekit-server/pkg/clientconfiguration"
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/idgen"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/pkg/rtc/types"
@@ -49,6 +55,31 @@ import (
 )
 
 const (
+	// EventParticipantDeviceChanged is sent when a participant reports a new device state, see rtc.DeviceAttributePrefix
+	EventParticipantDeviceChanged = "participant_device_changed"
+	// EventTrackBitrateOvershoot is sent when a publisher is warned that a video track exceeds its declared bitrate
+	EventTrackBitrateOvershoot = "track_bitrate_overshoot"
+	// EventParticipantConnectionQualityChanged is sent when the connection quality of a participant changes,
+	// the participant carries the qualities in the ConnectionQualityAttribute attributes
+	EventParticipantConnectionQualityChanged = "participant_connection_quality_changed"
+
+	// EventParticipantAudioFeedback is sent when a participant is found to publish the audio of another one
+	// again, the participant carries the other one in the AudioFeedbackSourceAttribute attribute
+	EventParticipantAudioFeedback = "participant_audio_feedback"
+	// EventParticipantQualityAlert is sent when the connection of a participant stays past a quality alert
+	// threshold, the participant carries a snapshot of its stats in the QualityAlert attributes
+	EventParticipantQualityAlert = "participant_quality_alert"
+
+	ConnectionQualityAttribute         = "lk.connection_quality"
+	PreviousConnectionQualityAttribute = "lk.connection_quality.previous"
+	AudioFeedbackSourceAttribute       = "lk.audio_feedback.source"
+	QualityAlertKindAttribute          = "lk.quality_alert.kind"
+	QualityAlertSinceAttribute         = "lk.quality_alert.since"
+	QualityAlertQualityAttribute       = "lk.quality_alert.quality"
+	QualityAlertScoreAttribute         = "lk.quality_alert.score"
+	QualityAlertPacketLossAttribute    = "lk.quality_alert.packet_loss_percent"
+	QualityAlertBitrateAttribute       = "lk.quality_alert.published_bitrate"
+
 	roomPurgeSeconds     = 24 * 60 * 60
 	tokenRefreshInterval = 5 * time.Minute
 	tokenDefaultTTL      = 10 * time.Minute
@@ -83,15 +114,58 @@ type RoomManager struct {
 	turnAuthHandler   *TURNAuthHandler
 	bus               psrpc.MessageBus
 
-	rooms map[livekit.RoomName]*rtc.Room
+	// room defaults and limits, replaced when the config is reloaded
+	roomConfig  atomic.Pointer[config.RoomConfig]
+	limitConfig atomic.Pointer[config.LimitConfig]
 
-	roomServers          utils.MultitonService[rpc.RoomTopic]
-	agentDispatchServers utils.MultitonService[rpc.RoomTopic]
-	participantServers   utils.MultitonService[rpc.ParticipantTopic]
+	rooms map[livekit.RoomName]*rtc.Room
+	// rooms moving to other nodes, their state is kept when they close here
+	migratingRooms map[livekit.RoomName]bool
+
+	roomServers               utils.MultitonService[rpc.RoomTopic]
+	agentDispatchServers      utils.MultitonService[rpc.RoomTopic]
+	virtualParticipantServers utils.MultitonService[rpc.RoomTopic]
+	sharedPlaybackServers     utils.MultitonService[rpc.RoomTopic]
+	waitingRoomServers        utils.MultitonService[rpc.RoomTopic]
+	breakoutServers           utils.MultitonService[rpc.RoomTopic]
+	participantRoleServers    utils.MultitonService[rpc.RoomTopic]
+	trackForwardServers       utils.MultitonService[rpc.RoomTopic]
+	bulkParticipantsServers   utils.MultitonService[rpc.RoomTopic]
+	rtpIngestServers          utils.MultitonService[rpc.RoomTopic]
+	recordingConsentServers   utils.MultitonService[rpc.RoomTopic]
+	bandwidthPolicyServers    utils.MultitonService[rpc.RoomTopic]
+	roomDebugServers          utils.MultitonService[rpc.RoomTopic]
+	clientEventsServers       utils.MultitonService[rpc.RoomTopic]
+	roomStatsServers          utils.MultitonService[rpc.RoomTopic]
+	whipServers               utils.MultitonService[rpc.RoomTopic]
+	whepServers               utils.MultitonService[rpc.RoomTopic]
+	recordingStateServers     utils.MultitonService[rpc.RoomTopic]
+	trackRecordingServers     utils.MultitonService[rpc.RoomTopic]
+	roomRelayServers          utils.MultitonService[rpc.RoomTopic]
+	participantServers        utils.MultitonService[rpc.ParticipantTopic]
 
 	iceConfigCache *sutils.IceConfigCache[iceConfigCacheKey]
 
-	forwardStats *sfu.ForwardStats
+	forwardStats   *sfu.ForwardStats
+	g711Transcoder *sfu.G711Transcoder
+
+	policy        *PolicyWebhook
+	abuseDetector AbuseDetector
+	quotas        *QuotaEnforcer
+	archiver      *archiver.Archiver
+	sessions      *SessionRecorder
+	regions       *RegionSettingsService
+	relays        *roomRelays
+	sdkBlocklist  *rtc.SDKBlocklist
+	idGenerator   idgen.Generator
+	adminLimiter  *AdminLimiter
+
+	// segments of track recordings are uploaded to it, nil when track recording is not configured
+	trackRecordingStore rtc.TrackRecordingStore
+
+	signalCaptures *signalCaptures
+
+	virtualParticipantHook *virtualParticipantHook
 }
 
 func NewLocalRoomManager(
@@ -100,7 +174,7 @@ func NewLocalRoomManager(
 	currentNode routing.LocalNode,
 	router routing.Router,
 	roomAllocator RoomAllocator,
-	telemetry telemetry.TelemetryService,
+	telemetryService telemetry.TelemetryService,
 	clientConfManager clientconfiguration.ClientConfigurationManager,
 	agentClient agent.Client,
 	agentStore AgentStore,
@@ -109,11 +183,24 @@ func NewLocalRoomManager(
 	turnAuthHandler *TURNAuthHandler,
 	bus psrpc.MessageBus,
 	forwardStats *sfu.ForwardStats,
+	policy *PolicyWebhook,
+	abuseDetector AbuseDetector,
+	quotas *QuotaEnforcer,
+	roomArchiver *archiver.Archiver,
+	sessionRecorder *SessionRecorder,
+	regionSettings *RegionSettingsService,
+	roomRelayClient RoomRelayClient,
+	adminLimiter *AdminLimiter,
 ) (*RoomManager, error) {
 	rtcConf, err := rtc.NewWebRTCConfig(conf)
 	if err != nil {
 		return nil, err
 	}
+	connectionquality.SetDefaultScorer(connectionquality.WeightedScorer{Weights: conf.RTC.ConnectionQuality.ScoreWeights()})
+	idGenerator, err := idgen.New(conf.IDs)
+	if err != nil {
+		return nil, err
+	}
 
 	r := &RoomManager{
 		config:            conf,
@@ -122,7 +209,7 @@ func NewLocalRoomManager(
 		router:            router,
 		roomAllocator:     roomAllocator,
 		roomStore:         roomStore,
-		telemetry:         telemetry,
+		telemetry:         telemetryService,
 		clientConfManager: clientConfManager,
 		egressLauncher:    egressLauncher,
 		agentClient:       agentClient,
@@ -131,8 +218,22 @@ func NewLocalRoomManager(
 		turnAuthHandler:   turnAuthHandler,
 		bus:               bus,
 		forwardStats:      forwardStats,
-
-		rooms: make(map[livekit.RoomName]*rtc.Room),
+		g711Transcoder:    sfu.NewG711Transcoder(conf.Audio.G711Transcoding),
+		policy:            policy,
+		abuseDetector:     abuseDetector,
+		quotas:            quotas,
+		archiver:          roomArchiver,
+		sessions:          sessionRecorder,
+		regions:           regionSettings,
+		sdkBlocklist:      rtc.NewSDKBlocklist(conf.SDKBlocklist),
+		idGenerator:       idGenerator,
+		signalCaptures:    newSignalCaptures(conf.SignalCapture),
+		adminLimiter:      adminLimiter,
+
+		virtualParticipantHook: newVirtualParticipantHook(conf.VirtualParticipants),
+
+		rooms:          make(map[livekit.RoomName]*rtc.Room),
+		migratingRooms: make(map[livekit.RoomName]bool),
 
 		iceConfigCache: sutils.NewIceConfigCache[iceConfigCacheKey](0),
 
@@ -145,8 +246,16 @@ func NewLocalRoomManager(
 			NodeId:        string(currentNode.NodeID()),
 		},
 	}
+	r.roomConfig.Store(&conf.Room)
+	r.limitConfig.Store(&conf.Limit)
+	r.relays = newRoomRelays(conf, currentNode, router, roomRelayClient, func() config.LimitConfig {
+		return *r.limitConfig.Load()
+	})
+	if conf.TrackRecording.S3.Bucket != "" {
+		r.trackRecordingStore = archiver.NewS3Store(conf.TrackRecording.S3)
+	}
 
-	r.roomManagerServer, err = rpc.NewTypedRoomManagerServer(r, bus, rpc.WithServerLogger(logger.GetLogger()), middleware.WithServerMetrics(rpc.PSRPCMetricsObserver{}), psrpc.WithServerChannelSize(conf.PSRPC.BufferSize))
+	r.roomManagerServer, err = rpc.NewTypedRoomManagerServer(r, bus, rpc.WithServerLogger(logger.GetLogger()), middleware.WithServerMetrics(rpc.PSRPCMetricsObserver{}), psrpc.WithServerRPCInterceptors(RequestIDInterceptor, telemetry.PSRPCTracingInterceptor), psrpc.WithServerChannelSize(conf.PSRPC.BufferSize))
 	if err != nil {
 		return nil, err
 	}
@@ -234,6 +343,17 @@ func (r *RoomManager) HasParticipants() bool {
 	return false
 }
 
+// LocalCounts returns the number of rooms and participants hosted on this node
+func (r *RoomManager) LocalCounts() (numRooms int, numParticipants int) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, room := range r.rooms {
+		numParticipants += len(room.GetParticipants())
+	}
+	return len(r.rooms), numParticipants
+}
+
 func (r *RoomManager) Stop() {
 	// disconnect all clients
 	r.lock.RLock()
@@ -246,6 +366,24 @@ func (r *RoomManager) Stop() {
 
 	r.roomManagerServer.Kill()
 	r.roomServers.Kill()
+	r.virtualParticipantServers.Kill()
+	r.sharedPlaybackServers.Kill()
+	r.waitingRoomServers.Kill()
+	r.breakoutServers.Kill()
+	r.participantRoleServers.Kill()
+	r.trackForwardServers.Kill()
+	r.bulkParticipantsServers.Kill()
+	r.rtpIngestServers.Kill()
+	r.recordingConsentServers.Kill()
+	r.bandwidthPolicyServers.Kill()
+	r.roomDebugServers.Kill()
+	r.clientEventsServers.Kill()
+	r.roomStatsServers.Kill()
+	r.whipServers.Kill()
+	r.whepServers.Kill()
+	r.recordingStateServers.Kill()
+	r.trackRecordingServers.Kill()
+	r.roomRelayServers.Kill()
 	r.agentDispatchServers.Kill()
 	r.participantServers.Kill()
 
@@ -256,6 +394,7 @@ func (r *RoomManager) Stop() {
 		if r.rtcConfig.TCPMuxListener != nil {
 			_ = r.rtcConfig.TCPMuxListener.Close()
 		}
+		r.rtcConfig.RoomClasses.Close()
 	}
 
 	r.iceConfigCache.Stop()
@@ -282,9 +421,18 @@ func (r *RoomManager) StartSession(
 	requestSource routing.MessageSource,
 	responseSink routing.MessageSink,
 	useOneShotSignallingMode bool,
-) error {
+) (err error) {
 	sessionStartTime := time.Now()
 
+	// continues the trace of the join received from the signal node, lifecycle events of the participant
+	// raised with this context are added to it
+	ctx, span := telemetry.StartSpan(ctx, "RoomManager.StartSession",
+		attribute.String("room", pi.CreateRoom.GetName()),
+		attribute.String("participant", string(pi.Identity)),
+		attribute.Bool("reconnect", pi.Reconnect),
+	)
+	defer func() { telemetry.EndSpan(span, err) }()
+
 	createRoom := pi.CreateRoom
 	room, err := r.getOrCreateRoom(ctx, createRoom)
 	if err != nil {
@@ -298,6 +446,7 @@ func (r *RoomManager) StartSession(
 	if pi.Identity == "" {
 		return nil
 	}
+	responseSink = r.signalCaptures.wrapSink(room.Name(), pi.Identity, responseSink)
 
 	// should not error out, error is logged in iceServersForParticipant even if it fails
 	// since this is used for TURN server credentials, we don't want to fail the request even if there's no TURN for the session
@@ -355,6 +504,7 @@ func (r *RoomManager) StartSession(
 				iceConfig,
 				r.iceServersForParticipant(
 					apiKey,
+					room.Name(),
 					participant,
 					iceConfig.PreferenceSubscriber == livekit.ICECandidateType_ICT_TLS,
 				),
@@ -395,7 +545,7 @@ func (r *RoomManager) StartSession(
 		return errors.New("could not restart participant")
 	}
 
-	sid := livekit.ParticipantID(guid.New(utils.ParticipantPrefix))
+	sid := r.idGenerator.ParticipantID(room.ID(), pi.Identity)
 	pLogger := rtc.LoggerWithParticipant(
 		rtc.LoggerWithRoom(logger.GetLogger(), room.Name(), room.ID()),
 		pi.Identity,
@@ -414,6 +564,17 @@ func (r *RoomManager) StartSession(
 	)
 
 	clientConf := r.clientConfManager.GetConfiguration(pi.Client)
+	forceRelay := r.rtcConfig.ICEPolicy.IsRelayOnly(room.Name(), pi.Identity)
+	if forceRelay {
+		// the configuration can be shared by clients
+		if clientConf == nil {
+			clientConf = &livekit.ClientConfiguration{}
+		} else {
+			clientConf = utils.CloneProto(clientConf)
+		}
+		clientConf.ForceRelay = livekit.ClientConfigSetting_ENABLED
+		pLogger.Infow("forcing relay candidates by ice policy")
+	}
 
 	pv := types.ProtocolVersion(pi.Client.Protocol)
 	rtcConf := *r.rtcConfig
@@ -421,6 +582,10 @@ func (r *RoomManager) StartSession(
 	if pi.DisableICELite {
 		rtcConf.SettingEngine.SetLite(false)
 	}
+	if class := r.rtcConfig.RoomClasses.ClassOf(room.Name()); class != "" {
+		rtcConf.SettingEngine.SetICEUDPMux(r.rtcConfig.RoomClasses.UDPMux(class))
+		pLogger = pLogger.WithValues("roomClass", class)
+	}
 	// default allow forceTCP
 	allowFallback := true
 	if r.config.RTC.AllowTCPFallback != nil {
@@ -453,7 +618,7 @@ func (r *RoomManager) StartSession(
 		Sink:                    responseSink,
 		AudioConfig:             r.config.Audio,
 		VideoConfig:             r.config.Video,
-		LimitConfig:             r.config.Limit,
+		LimitConfig:             *r.limitConfig.Load(),
 		ProtocolVersion:         pv,
 		SessionStartTime:        sessionStartTime,
 		Telemetry:               r.telemetry,
@@ -471,10 +636,17 @@ func (r *RoomManager) StartSession(
 		AdaptiveStream:          pi.AdaptiveStream,
 		AllowTCPFallback:        allowFallback,
 		TURNSEnabled:            r.config.IsTURNSEnabled(),
+		ForceRelay:              forceRelay,
 		GetParticipantInfo: func(pID livekit.ParticipantID) *livekit.ParticipantInfo {
 			if p := room.GetParticipantByID(pID); p != nil {
 				return p.ToProto()
 			}
+			// publishers of RTP ingests
+			for _, vp := range room.GetVirtualParticipants() {
+				if vp.ID() == pID {
+					return vp.ToProto()
+				}
+			}
 			return nil
 		},
 		ReconnectOnPublicationError:  reconnectOnPublicationError,
@@ -484,13 +656,30 @@ func (r *RoomManager) StartSession(
 		VersionGenerator:             r.versionGenerator,
 		TrackResolver:                room.ResolveMediaTrackForSubscriber,
 		SubscriberAllowPause:         subscriberAllowPause,
-		SubscriptionLimitAudio:       r.config.Limit.SubscriptionLimitAudio,
-		SubscriptionLimitVideo:       r.config.Limit.SubscriptionLimitVideo,
+		SubscriptionLimitAudio:       r.limitConfig.Load().SubscriptionLimitAudio,
+		SubscriptionLimitVideo:       r.limitConfig.Load().SubscriptionLimitVideo,
 		PlayoutDelay:                 roomInternal.GetPlayoutDelay(),
 		SyncStreams:                  roomInternal.GetSyncStreams(),
 		ForwardStats:                 r.forwardStats,
+		G711Transcoder:               r.g711Transcoder,
 		MetricConfig:                 r.config.Metric,
 		UseOneShotSignallingMode:     useOneShotSignallingMode,
+		ResumeWindow:                 room.ResumeWindow(),
+		MaxPublishBitrate:            r.roomConfig.Load().PublishBitrate.MaxBitrateFor(string(room.Name())),
+		NotificationsConfig:          r.roomConfig.Load().Notifications,
+		TrackPublishPolicy:           r.trackPublishPolicy(room.Name(), pi.Identity),
+		OnBitrateOvershoot: func(p types.LocalParticipant, ti *livekit.TrackInfo, _ *rtc.BitrateOvershoot) {
+			r.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{
+				Event:       EventTrackBitrateOvershoot,
+				Room:        room.ToProto(),
+				Participant: p.ToProto(),
+				Track:       ti,
+			})
+		},
+		OnConnectionQualityChanged: r.connectionQualityNotifier(ctx, room),
+		GetRegionSettings: func(_ string) *livekit.RegionSettings {
+			return r.regions.GetRegionSettings()
+		},
 	})
 	if err != nil {
 		return err
@@ -501,7 +690,7 @@ func (r *RoomManager) StartSession(
 	opts := rtc.ParticipantOptions{
 		AutoSubscribe: pi.AutoSubscribe,
 	}
-	iceServers := r.iceServersForParticipant(apiKey, participant, iceConfig.PreferenceSubscriber == livekit.ICECandidateType_ICT_TLS)
+	iceServers := r.iceServersForParticipant(apiKey, room.Name(), participant, iceConfig.PreferenceSubscriber == livekit.ICECandidateType_ICT_TLS)
 	if err = room.Join(participant, requestSource, &opts, iceServers); err != nil {
 		pLogger.Errorw("could not join room", err)
 		_ = participant.Close(true, types.ParticipantCloseReasonJoinFailed, false)
@@ -547,12 +736,32 @@ func (r *RoomManager) StartSession(
 		proto := room.ToProto()
 		persistRoomForParticipantCount(proto)
 		r.telemetry.ParticipantLeft(ctx, proto, p.ToProto(), true)
+		if r.sessions != nil {
+			if err := r.sessions.recordParticipant(ctx, proto, p, room.TakeSessionQuality(p.ID())); err != nil {
+				pLogger.Warnw("could not record participant session", err)
+			}
+		}
 	})
+	var deviceLock sync.Mutex
+	deviceState := rtc.DeviceAttributes(participant.ClaimGrants().Attributes)
 	participant.OnClaimsChanged(func(participant types.LocalParticipant) {
 		pLogger.Debugw("refreshing client token after claims change")
-		if err := r.refreshToken(participant); err != nil {
+		if err := r.refreshToken(room, participant); err != nil {
 			pLogger.Errorw("could not refresh token", err)
 		}
+
+		deviceLock.Lock()
+		state := rtc.DeviceAttributes(participant.ClaimGrants().Attributes)
+		changed := !maps.Equal(state, deviceState)
+		deviceState = state
+		deviceLock.Unlock()
+		if changed {
+			r.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{
+				Event:       EventParticipantDeviceChanged,
+				Room:        room.ToProto(),
+				Participant: participant.ToProto(),
+			})
+		}
 	})
 	participant.OnICEConfigChanged(func(participant types.LocalParticipant, iceConfig *livekit.ICEConfig) {
 		r.iceConfigCache.Put(iceConfigCacheKey{room.Name(), participant.Identity()}, iceConfig)
@@ -562,6 +771,26 @@ func (r *RoomManager) StartSession(
 	return nil
 }
 
+func (r *RoomManager) connectionQualityNotifier(ctx context.Context, room *rtc.Room) func(p types.LocalParticipant, prev, curr livekit.ConnectionQuality) {
+	if !r.config.RTC.ConnectionQuality.NotifyTransitions {
+		return nil
+	}
+	return func(p types.LocalParticipant, prev, curr livekit.ConnectionQuality) {
+		pi := p.ToProto()
+		// the attributes of the proto are the ones of the participant
+		attrs := make(map[string]string, len(pi.Attributes)+2)
+		maps.Copy(attrs, pi.Attributes)
+		attrs[ConnectionQualityAttribute] = curr.String()
+		attrs[PreviousConnectionQualityAttribute] = prev.String()
+		pi.Attributes = attrs
+		r.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event:       EventParticipantConnectionQualityChanged,
+			Room:        room.ToProto(),
+			Participant: pi,
+		})
+	}
+}
+
 // create the actual room object, to be used on RTC node
 func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.CreateRoomRequest) (*rtc.Room, error) {
 	roomName := livekit.RoomName(createRoom.Name)
@@ -574,8 +803,27 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 		return lastSeenRoom, nil
 	}
 
+	var (
+		originNodeID livekit.NodeID
+		relayed      bool
+	)
+	if r.relays != nil {
+		originNodeID, relayed = r.relays.originNode(ctx, roomName)
+	}
+
 	// create new room, get details first
-	ri, internal, created, err := r.roomAllocator.CreateRoom(ctx, createRoom, true)
+	var (
+		ri       *livekit.Room
+		internal *livekit.RoomInternal
+		created  bool
+		err      error
+	)
+	if relayed {
+		// the room is hosted on another node, it is relayed here with its stored state
+		ri, internal, err = r.roomStore.LoadRoom(ctx, roomName, true)
+	} else {
+		ri, internal, created, err = r.roomAllocator.CreateRoom(ctx, createRoom, true)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -594,8 +842,27 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 		currentRoom = r.rooms[roomName]
 	}
 
+	if relayed {
+		return r.createRelayRoom(ri, internal, originNodeID)
+	}
+
 	// construct ice servers
-	newRoom := rtc.NewRoom(ri, internal, *r.rtcConfig, r.config.Room, &r.config.Audio, r.serverInfo, r.telemetry, r.agentClient, r.agentStore, r.egressLauncher)
+	newRoom := rtc.NewRoom(ri, internal, *r.rtcConfig, *r.roomConfig.Load(), &r.config.Audio, r.serverInfo, r.telemetry, r.agentClient, r.agentStore, r.egressLauncher)
+	if r.abuseDetector != nil {
+		newRoom.SetDataPacketLimiter(r.abuseDetector)
+	}
+	if r.archiver != nil {
+		newRoom.EnableArchive(r.config.RoomArchive.MaxEntries)
+	}
+	if r.sessions != nil {
+		newRoom.EnableSessionQuality()
+	}
+	if r.trackRecordingStore != nil {
+		newRoom.EnableTrackRecording(r.trackRecordingStore, r.config.TrackRecording)
+	}
+	newRoom.SetAlternativeURLProvider(r.regions.AlternativeURL)
+	newRoom.SetSDKBlocklist(r.sdkBlocklist)
+	newRoom.SetIDGenerator(r.idGenerator)
 
 	roomTopic := rpc.FormatRoomTopic(roomName)
 	roomServer := must.Get(rpc.NewTypedRoomServer(r, r.bus))
@@ -614,13 +881,392 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 		return nil, err
 	}
 
+	virtualParticipantServer := newVirtualParticipantServer(r, roomName, r.bus)
+	killVirtualParticipantServer := r.virtualParticipantServers.Replace(roomTopic, virtualParticipantServer)
+	if err := virtualParticipantServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	sharedPlaybackServer := newSharedPlaybackServer(r, roomName, r.bus)
+	killSharedPlaybackServer := r.sharedPlaybackServers.Replace(roomTopic, sharedPlaybackServer)
+	if err := sharedPlaybackServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	waitingRoomServer := newWaitingRoomServer(r, roomName, r.bus)
+	killWaitingRoomServer := r.waitingRoomServers.Replace(roomTopic, waitingRoomServer)
+	if err := waitingRoomServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		killWaitingRoomServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	breakoutServer := newBreakoutServer(r, roomName, r.bus)
+	killBreakoutServer := r.breakoutServers.Replace(roomTopic, breakoutServer)
+	if err := breakoutServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		killWaitingRoomServer()
+		killBreakoutServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	participantRoleServer := newParticipantRoleServer(r, r.bus)
+	killParticipantRoleServer := r.participantRoleServers.Replace(roomTopic, participantRoleServer)
+	if err := participantRoleServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		killWaitingRoomServer()
+		killBreakoutServer()
+		killParticipantRoleServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	trackForwardServer := newTrackForwardServer(r, roomName, r.bus)
+	killTrackForwardServer := r.trackForwardServers.Replace(roomTopic, trackForwardServer)
+	if err := trackForwardServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		killWaitingRoomServer()
+		killBreakoutServer()
+		killParticipantRoleServer()
+		killTrackForwardServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	bulkParticipantsServer := newBulkParticipantsServer(r, roomName, r.bus)
+	killBulkParticipantsServer := r.bulkParticipantsServers.Replace(roomTopic, bulkParticipantsServer)
+	if err := bulkParticipantsServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		killWaitingRoomServer()
+		killBreakoutServer()
+		killParticipantRoleServer()
+		killTrackForwardServer()
+		killBulkParticipantsServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	rtpIngestServer := newRTPIngestServer(r, roomName, r.bus)
+	killRTPIngestServer := r.rtpIngestServers.Replace(roomTopic, rtpIngestServer)
+	if err := rtpIngestServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		killWaitingRoomServer()
+		killBreakoutServer()
+		killParticipantRoleServer()
+		killTrackForwardServer()
+		killBulkParticipantsServer()
+		killRTPIngestServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	recordingConsentServer := newRecordingConsentServer(r, r.bus)
+	killRecordingConsentServer := r.recordingConsentServers.Replace(roomTopic, recordingConsentServer)
+	if err := recordingConsentServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		killWaitingRoomServer()
+		killBreakoutServer()
+		killParticipantRoleServer()
+		killTrackForwardServer()
+		killBulkParticipantsServer()
+		killRTPIngestServer()
+		killRecordingConsentServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	bandwidthPolicyServer := newBandwidthPolicyServer(r, roomName, r.bus)
+	killBandwidthPolicyServer := r.bandwidthPolicyServers.Replace(roomTopic, bandwidthPolicyServer)
+	if err := bandwidthPolicyServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		killWaitingRoomServer()
+		killBreakoutServer()
+		killParticipantRoleServer()
+		killTrackForwardServer()
+		killBulkParticipantsServer()
+		killRTPIngestServer()
+		killRecordingConsentServer()
+		killBandwidthPolicyServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	roomDebugServer := newRoomDebugServer(r, roomName, r.bus)
+	killRoomDebugServer := r.roomDebugServers.Replace(roomTopic, roomDebugServer)
+	if err := roomDebugServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		killWaitingRoomServer()
+		killBreakoutServer()
+		killParticipantRoleServer()
+		killTrackForwardServer()
+		killBulkParticipantsServer()
+		killRTPIngestServer()
+		killRecordingConsentServer()
+		killBandwidthPolicyServer()
+		killRoomDebugServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	clientEventsServer := newClientEventsServer(r, roomName, r.bus)
+	killClientEventsServer := r.clientEventsServers.Replace(roomTopic, clientEventsServer)
+	if err := clientEventsServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		killWaitingRoomServer()
+		killBreakoutServer()
+		killParticipantRoleServer()
+		killTrackForwardServer()
+		killBulkParticipantsServer()
+		killRTPIngestServer()
+		killRecordingConsentServer()
+		killBandwidthPolicyServer()
+		killRoomDebugServer()
+		killClientEventsServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	roomStatsServer := newRoomStatsServer(r, roomName, r.bus)
+	killRoomStatsServer := r.roomStatsServers.Replace(roomTopic, roomStatsServer)
+	if err := roomStatsServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		killWaitingRoomServer()
+		killBreakoutServer()
+		killParticipantRoleServer()
+		killTrackForwardServer()
+		killBulkParticipantsServer()
+		killRTPIngestServer()
+		killRecordingConsentServer()
+		killBandwidthPolicyServer()
+		killRoomDebugServer()
+		killClientEventsServer()
+		killRoomStatsServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	whipServer := newWHIPServer(r, roomName, r.bus)
+	killWHIPServer := r.whipServers.Replace(roomTopic, whipServer)
+	if err := whipServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		killWaitingRoomServer()
+		killBreakoutServer()
+		killParticipantRoleServer()
+		killTrackForwardServer()
+		killBulkParticipantsServer()
+		killRTPIngestServer()
+		killRecordingConsentServer()
+		killBandwidthPolicyServer()
+		killRoomDebugServer()
+		killClientEventsServer()
+		killRoomStatsServer()
+		killWHIPServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	whepServer := newWHEPServer(r, roomName, r.bus)
+	killWHEPServer := r.whepServers.Replace(roomTopic, whepServer)
+	if err := whepServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		killWaitingRoomServer()
+		killBreakoutServer()
+		killParticipantRoleServer()
+		killTrackForwardServer()
+		killBulkParticipantsServer()
+		killRTPIngestServer()
+		killRecordingConsentServer()
+		killBandwidthPolicyServer()
+		killRoomDebugServer()
+		killClientEventsServer()
+		killRoomStatsServer()
+		killWHIPServer()
+		killWHEPServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	recordingStateServer := newRecordingStateServer(r, roomName, r.bus)
+	killRecordingStateServer := r.recordingStateServers.Replace(roomTopic, recordingStateServer)
+	if err := recordingStateServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		killWaitingRoomServer()
+		killBreakoutServer()
+		killParticipantRoleServer()
+		killTrackForwardServer()
+		killBulkParticipantsServer()
+		killRTPIngestServer()
+		killRecordingConsentServer()
+		killBandwidthPolicyServer()
+		killRoomDebugServer()
+		killClientEventsServer()
+		killRoomStatsServer()
+		killWHIPServer()
+		killWHEPServer()
+		killRecordingStateServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	trackRecordingServer := newTrackRecordingServer(r, roomName, r.bus)
+	killTrackRecordingServer := r.trackRecordingServers.Replace(roomTopic, trackRecordingServer)
+	if err := trackRecordingServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		killWaitingRoomServer()
+		killBreakoutServer()
+		killParticipantRoleServer()
+		killTrackForwardServer()
+		killBulkParticipantsServer()
+		killRTPIngestServer()
+		killRecordingConsentServer()
+		killBandwidthPolicyServer()
+		killRoomDebugServer()
+		killClientEventsServer()
+		killRoomStatsServer()
+		killWHIPServer()
+		killWHEPServer()
+		killRecordingStateServer()
+		killTrackRecordingServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	killRoomRelayServer := func() {}
+	if r.relays != nil {
+		roomRelayServer := newRoomRelayServer(r.relays, roomName, r.bus)
+		killRoomRelayServer = r.roomRelayServers.Replace(roomTopic, roomRelayServer)
+		if err := roomRelayServer.RegisterAllRoomTopics(roomTopic); err != nil {
+			killRoomServer()
+			killDispServer()
+			killVirtualParticipantServer()
+			killSharedPlaybackServer()
+			killWaitingRoomServer()
+			killBreakoutServer()
+			killParticipantRoleServer()
+			killTrackForwardServer()
+			killBulkParticipantsServer()
+			killRTPIngestServer()
+			killRecordingConsentServer()
+			killBandwidthPolicyServer()
+			killRoomDebugServer()
+			killClientEventsServer()
+			killRoomStatsServer()
+			killWHIPServer()
+			killWHEPServer()
+			killRecordingStateServer()
+			killTrackRecordingServer()
+			killRoomRelayServer()
+			r.lock.Unlock()
+			return nil, err
+		}
+		r.relays.startOrigin(newRoom)
+	}
+
 	newRoom.OnClose(func() {
 		killRoomServer()
 		killDispServer()
+		killVirtualParticipantServer()
+		killSharedPlaybackServer()
+		killWaitingRoomServer()
+		killBreakoutServer()
+		killParticipantRoleServer()
+		killTrackForwardServer()
+		killBulkParticipantsServer()
+		killRTPIngestServer()
+		killRecordingConsentServer()
+		killBandwidthPolicyServer()
+		killRoomDebugServer()
+		killClientEventsServer()
+		killRoomStatsServer()
+		killWHIPServer()
+		killWHEPServer()
+		killRecordingStateServer()
+		killTrackRecordingServer()
+		killRoomRelayServer()
+		if r.relays != nil {
+			r.relays.closeOrigin(newRoom)
+		}
+
+		r.lock.Lock()
+		migrated := r.migratingRooms[roomName]
+		delete(r.migratingRooms, roomName)
+		if migrated && r.rooms[roomName] == newRoom {
+			delete(r.rooms, roomName)
+		}
+		r.lock.Unlock()
+		if migrated {
+			// the room goes on on another node
+			newRoom.Logger.Infow("room migrated")
+			return
+		}
 
 		roomInfo := newRoom.ToProto()
 		r.telemetry.RoomEnded(ctx, roomInfo)
 		prometheus.RoomEnded(time.Unix(roomInfo.CreationTime, 0))
+		if r.archiver != nil {
+			go r.archiveRoom(newRoom)
+		}
+		if r.sessions != nil {
+			if err := r.sessions.recordRoom(ctx, roomInfo); err != nil {
+				newRoom.Logger.Warnw("could not record room session", err)
+			}
+		}
 		if err := r.deleteRoom(ctx, roomName); err != nil {
 			newRoom.Logger.Errorw("could not delete room", err)
 		}
@@ -628,12 +1274,70 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 		newRoom.Logger.Infow("room closed")
 	})
 
+	newRoom.OnTrackRecordingEnded(func(info *rtc.TrackRecordingInfo) {
+		r.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event:      EventTrackRecordingEnded,
+			Room:       newRoom.ToProto(),
+			EgressInfo: TrackRecordingEgressInfo(info),
+		})
+	})
+
+	newRoom.OnRecordingChanged(func(active bool) {
+		event := EventRecordingEnded
+		if active {
+			event = EventRecordingStarted
+		}
+		r.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event: event,
+			Room:  newRoom.ToProto(),
+		})
+	})
+
+	newRoom.OnTrackForwardEnded(func(info *rtc.TrackForwardInfo) {
+		r.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event:      EventTrackForwardEnded,
+			Room:       newRoom.ToProto(),
+			EgressInfo: TrackForwardEgressInfo(info),
+		})
+	})
+
 	newRoom.OnRoomUpdated(func() {
 		if err := r.roomStore.StoreRoom(ctx, newRoom.ToProto(), newRoom.Internal()); err != nil {
 			newRoom.Logger.Errorw("could not handle metadata update", err)
 		}
 	})
 
+	newRoom.OnAudioFeedback(func(source, echo types.LocalParticipant, _ bool) {
+		pi := echo.ToProto()
+		attrs := make(map[string]string, len(pi.Attributes)+1)
+		maps.Copy(attrs, pi.Attributes)
+		attrs[AudioFeedbackSourceAttribute] = string(source.Identity())
+		pi.Attributes = attrs
+		r.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event:       EventParticipantAudioFeedback,
+			Room:        newRoom.ToProto(),
+			Participant: pi,
+		})
+	})
+
+	newRoom.OnQualityAlert(func(p types.LocalParticipant, alert *rtc.QualityAlert) {
+		pi := p.ToProto()
+		attrs := make(map[string]string, len(pi.Attributes)+6)
+		maps.Copy(attrs, pi.Attributes)
+		attrs[QualityAlertKindAttribute] = alert.Kind
+		attrs[QualityAlertSinceAttribute] = strconv.FormatInt(alert.Since.Unix(), 10)
+		attrs[QualityAlertQualityAttribute] = alert.Quality.String()
+		attrs[QualityAlertScoreAttribute] = strconv.FormatFloat(float64(alert.Score), 'f', 2, 32)
+		attrs[QualityAlertPacketLossAttribute] = strconv.FormatFloat(alert.PacketLossPercent, 'f', 1, 64)
+		attrs[QualityAlertBitrateAttribute] = strconv.FormatInt(alert.PublishedBitrate, 10)
+		pi.Attributes = attrs
+		r.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event:       EventParticipantQualityAlert,
+			Room:        newRoom.ToProto(),
+			Participant: pi,
+		})
+	})
+
 	newRoom.OnParticipantChanged(func(p types.LocalParticipant) {
 		if !p.IsDisconnected() {
 			if err := r.roomStore.StoreParticipant(ctx, roomName, p.ToProto()); err != nil {
@@ -689,7 +1393,7 @@ func (r *RoomManager) rtcSessionWorker(room *rtc.Room, participant types.LocalPa
 	}()
 
 	// send first refresh for cases when client token is close to expiring
-	_ = r.refreshToken(participant)
+	_ = r.refreshToken(room, participant)
 	tokenTicker := time.NewTicker(tokenRefreshInterval)
 	defer tokenTicker.Stop()
 	for {
@@ -698,7 +1402,7 @@ func (r *RoomManager) rtcSessionWorker(room *rtc.Room, participant types.LocalPa
 			return
 		case <-tokenTicker.C:
 			// refresh token with the first API Key/secret pair
-			if err := r.refreshToken(participant); err != nil {
+			if err := r.refreshToken(room, participant); err != nil {
 				pLogger.Errorw("could not refresh token", err, "connID", requestSource.ConnectionID())
 			}
 		case obj := <-requestSource.ReadChan():
@@ -710,6 +1414,7 @@ func (r *RoomManager) rtcSessionWorker(room *rtc.Room, participant types.LocalPa
 			}
 
 			req := obj.(*livekit.SignalRequest)
+			r.signalCaptures.record(room.Name(), participant.Identity(), requestSource.ConnectionID(), SignalCaptureDirectionRequest, req)
 			if err := rtc.HandleParticipantSignal(room, participant, req, pLogger); err != nil {
 				// more specific errors are already logged
 				// treat errors returned as fatal
@@ -739,6 +1444,13 @@ func (r *RoomManager) roomAndParticipantForReq(ctx context.Context, req particip
 }
 
 func (r *RoomManager) RemoveParticipant(ctx context.Context, req *livekit.RoomParticipantIdentity) (*livekit.RemoveParticipantResponse, error) {
+	if room, vp := r.virtualParticipantForReq(ctx, req); vp != nil {
+		if err := room.RemoveVirtualParticipant(vp.Identity()); err != nil {
+			return nil, ErrParticipantNotFound
+		}
+		return &livekit.RemoveParticipantResponse{}, nil
+	}
+
 	room, participant, err := r.roomAndParticipantForReq(ctx, req)
 	if err != nil {
 		return nil, err
@@ -757,7 +1469,7 @@ func (r *RoomManager) MutePublishedTrack(ctx context.Context, req *livekit.MuteR
 
 	participant.GetLogger().Debugw("setting track muted",
 		"trackID", req.TrackSid, "muted", req.Muted)
-	if !req.Muted && !r.config.Room.EnableRemoteUnmute {
+	if !req.Muted && !r.roomConfig.Load().EnableRemoteUnmute {
 		participant.GetLogger().Errorw("cannot unmute track, remote unmute is disabled", nil)
 		return nil, ErrRemoteUnmuteNoteEnabled
 	}
@@ -766,6 +1478,10 @@ func (r *RoomManager) MutePublishedTrack(ctx context.Context, req *livekit.MuteR
 }
 
 func (r *RoomManager) UpdateParticipant(ctx context.Context, req *livekit.UpdateParticipantRequest) (*livekit.ParticipantInfo, error) {
+	if room, vp := r.virtualParticipantForReq(ctx, req); vp != nil {
+		return r.updateVirtualParticipant(ctx, room, vp, req)
+	}
+
 	_, participant, err := r.roomAndParticipantForReq(ctx, req)
 	if err != nil {
 		return nil, err
@@ -910,100 +1626,843 @@ func (r *RoomManager) DeleteDispatch(ctx context.Context, req *livekit.DeleteAge
 	return disp, nil
 }
 
-func (r *RoomManager) iceServersForParticipant(apiKey string, participant types.LocalParticipant, tlsOnly bool) []*livekit.ICEServer {
-	var iceServers []*livekit.ICEServer
-	rtcConf := r.config.RTC
+func (r *RoomManager) CreateVirtualParticipant(ctx context.Context, req *livekit.UpdateParticipantRequest) (*livekit.ParticipantInfo, error) {
+	room := r.GetRoom(ctx, livekit.RoomName(req.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
 
-	if tlsOnly && r.config.TURN.TLSPort == 0 {
-		logger.Warnw("tls only enabled but no turn tls config", nil)
-		tlsOnly = false
+	identity := livekit.ParticipantIdentity(req.Identity)
+	vp := rtc.NewVirtualParticipant(r.idGenerator.ParticipantID(room.ID(), identity), identity, req.Name, req.Metadata, req.Attributes)
+	if r.virtualParticipantHook != nil {
+		vp.OnDataPacket(func(vp *rtc.VirtualParticipant, dp *livekit.DataPacket) {
+			r.virtualParticipantHook.deliver(room.Name(), vp.Identity(), dp)
+		})
 	}
 
-	hasSTUN := false
-	if r.config.TURN.Enabled {
-		var urls []string
-		if r.config.TURN.UDPPort > 0 && !tlsOnly {
-			// UDP TURN is used as STUN
-			hasSTUN = true
-			urls = append(urls, fmt.Sprintf("turn:%s:%d?transport=udp", r.config.RTC.NodeIP, r.config.TURN.UDPPort))
-		}
-		if r.config.TURN.TLSPort > 0 {
-			urls = append(urls, fmt.Sprintf("turns:%s:443?transport=tcp", r.config.TURN.Domain))
-		}
-		if len(urls) > 0 {
-			username := r.turnAuthHandler.CreateUsername(apiKey, participant.ID())
-			password, err := r.turnAuthHandler.CreatePassword(apiKey, participant.ID())
-			if err != nil {
-				participant.GetLogger().Warnw("could not create turn password", err)
-				hasSTUN = false
-			} else {
-				logger.Infow("created TURN password", "username", username, "password", password)
-				iceServers = append(iceServers, &livekit.ICEServer{
-					Urls:       urls,
-					Username:   username,
-					Credential: password,
-				})
-			}
+	// register the participant topic so RoomService can update and remove it
+	participantTopic := rpc.FormatParticipantTopic(room.Name(), vp.Identity())
+	participantServer := must.Get(rpc.NewTypedParticipantServer(r, r.bus))
+	killParticipantServer := r.participantServers.Replace(participantTopic, participantServer)
+	vp.OnClose(func(vp *rtc.VirtualParticipant) {
+		killParticipantServer()
+		if err := r.roomStore.DeleteParticipant(context.Background(), room.Name(), vp.Identity()); err != nil {
+			room.Logger.Errorw("could not delete virtual participant", err, "participant", vp.Identity())
 		}
-	}
+	})
 
-	if len(rtcConf.TURNServers) > 0 {
-		hasSTUN = true
-		for _, s := range r.config.RTC.TURNServers {
-			scheme := "turn"
-			transport := "tcp"
-			if s.Protocol == "tls" {
-				scheme = "turns"
-			} else if s.Protocol == "udp" {
-				transport = "udp"
-			}
-			is := &livekit.ICEServer{
-				Urls: []string{
-					fmt.Sprintf("%s:%s:%d?transport=%s", scheme, s.Host, s.Port, transport),
-				},
-				Username:   s.Username,
-				Credential: s.Credential,
-			}
-			iceServers = append(iceServers, is)
+	if err := room.AddVirtualParticipant(vp); err != nil {
+		killParticipantServer()
+		if errors.Is(err, rtc.ErrAlreadyJoined) {
+			return nil, ErrParticipantExists
 		}
+		return nil, err
 	}
-
-	if len(rtcConf.STUNServers) > 0 {
-		hasSTUN = true
-		iceServers = append(iceServers, iceServerForStunServers(r.config.RTC.STUNServers))
+	if err := participantServer.RegisterAllParticipantTopics(participantTopic); err != nil {
+		_ = room.RemoveVirtualParticipant(vp.Identity())
+		return nil, err
 	}
 
-	if !hasSTUN {
-		iceServers = append(iceServers, iceServerForStunServers(rtcconfig.DefaultStunServers))
+	pi := vp.ToProto()
+	if err := r.roomStore.StoreParticipant(ctx, room.Name(), pi); err != nil {
+		room.Logger.Errorw("could not store virtual participant", err, "participant", vp.Identity())
 	}
-	return iceServers
+	return pi, nil
 }
 
-func (r *RoomManager) refreshToken(participant types.LocalParticipant) error {
-	key, secret, err := r.getFirstKeyPair()
+func (r *RoomManager) SendVirtualParticipantData(ctx context.Context, roomName livekit.RoomName, req *livekit.DataPacket) (*livekit.SendDataResponse, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	room.Logger.Debugw("api send virtual participant data", "participant", req.ParticipantIdentity, "size", len(req.GetUser().GetPayload()))
+	if err := room.SendVirtualParticipantData(livekit.ParticipantIdentity(req.ParticipantIdentity), req.Kind, req); err != nil {
+		return nil, ErrParticipantNotFound
+	}
+	return &livekit.SendDataResponse{}, nil
+}
+
+func (r *RoomManager) UpdateSharedPlayback(ctx context.Context, roomName livekit.RoomName, cmd *rtc.SharedPlaybackCommand) (*rtc.SharedPlaybackState, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	room.Logger.Infow("api update shared playback", "action", cmd.Action, "position", cmd.PositionMs)
+	state, err := room.UpdateSharedPlayback(cmd)
+	switch {
+	case errors.Is(err, rtc.ErrNoSharedPlayback):
+		return nil, ErrSharedPlaybackNotFound
+	case errors.Is(err, rtc.ErrInvalidSharedPlaybackAction):
+		return nil, ErrSharedPlaybackInvalid
+	}
+	return state, err
+}
+
+// MoveParticipant hands a participant a token for the destination room and has it reconnect there right away
+func (r *RoomManager) MoveParticipant(ctx context.Context, roomName livekit.RoomName, req *livekit.RoomParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	participant := room.GetParticipant(livekit.ParticipantIdentity(req.Identity))
+	if participant == nil {
+		return nil, ErrParticipantNotFound
+	}
+
+	jwt, err := r.participantToken(room, participant, livekit.RoomName(req.Room), tokenDefaultTTL)
+	if err != nil {
+		return nil, err
+	}
+	if err := participant.SendRefreshToken(jwt); err != nil {
+		return nil, err
+	}
+
+	participant.GetLogger().Infow("moving participant", "destination", req.Room)
+	pi := participant.ToProto()
+	// clients reconnect with the latest token, which now joins the destination room
+	participant.IssueFullReconnect(types.ParticipantCloseReasonMovedToRoom)
+	return pi, nil
+}
+
+func (r *RoomManager) ListPendingParticipants(ctx context.Context, req *livekit.ListParticipantsRequest) (*livekit.ListParticipantsResponse, error) {
+	room := r.GetRoom(ctx, livekit.RoomName(req.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	res := &livekit.ListParticipantsResponse{}
+	for _, p := range room.GetPendingParticipants() {
+		res.Participants = append(res.Participants, p.ToProto())
+	}
+	return res, nil
+}
+
+func (r *RoomManager) AdmitParticipants(ctx context.Context, roomName livekit.RoomName, req *livekit.ListParticipantsResponse) (*livekit.ListParticipantsResponse, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	identities := make([]livekit.ParticipantIdentity, 0, len(req.Participants))
+	for _, pi := range req.Participants {
+		identities = append(identities, livekit.ParticipantIdentity(pi.Identity))
+	}
+	room.Logger.Infow("api admit participants", "participants", identities)
+	admitted, err := room.AdmitParticipants(identities)
+	if err != nil {
+		return nil, ErrParticipantNotPending
+	}
+
+	res := &livekit.ListParticipantsResponse{}
+	for _, p := range admitted {
+		res.Participants = append(res.Participants, p.ToProto())
+	}
+	return res, nil
+}
+
+func (r *RoomManager) ForwardTrack(ctx context.Context, roomName livekit.RoomName, req *rtc.TrackForwardRequest) (*rtc.TrackForwardInfo, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	room.Logger.Infow("api forward track", "trackID", req.TrackSid, "transport", req.Transport, "address", req.Address)
+	info, err := room.ForwardTrack(req)
+	switch {
+	case err == nil:
+		return info, nil
+	case errors.Is(err, rtc.ErrTrackNotFound), errors.Is(err, rtc.ErrTrackNotBound):
+		return nil, ErrTrackNotFound
+	case errors.Is(err, rtc.ErrInvalidTrackForward):
+		return nil, ErrTrackForwardInvalid
+	default:
+		return nil, psrpc.NewError(psrpc.Unavailable, err)
+	}
+}
+
+func (r *RoomManager) StopTrackForward(ctx context.Context, roomName livekit.RoomName, forwardID string) (*rtc.TrackForwardInfo, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	info, err := room.StopTrackForward(forwardID)
+	if err != nil {
+		return nil, ErrTrackForwardNotFound
+	}
+	return info, nil
+}
+
+func (r *RoomManager) ListTrackForwards(ctx context.Context, roomName livekit.RoomName) ([]*rtc.TrackForwardInfo, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	return room.GetTrackForwards(), nil
+}
+
+func (r *RoomManager) RecordTrack(ctx context.Context, roomName livekit.RoomName, req *rtc.TrackRecordingRequest) (*rtc.TrackRecordingInfo, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	room.Logger.Infow("api record track", "trackID", req.TrackSid, "quality", req.Quality)
+	info, err := room.RecordTrack(req)
+	switch {
+	case err == nil:
+		return info, nil
+	case errors.Is(err, rtc.ErrTrackNotFound), errors.Is(err, rtc.ErrTrackNotBound):
+		return nil, ErrTrackNotFound
+	case errors.Is(err, rtc.ErrInvalidTrackRecording):
+		return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+	case errors.Is(err, rtc.ErrTrackRecordingDisabled):
+		return nil, ErrTrackRecordingDisabled
+	default:
+		return nil, psrpc.NewError(psrpc.Unavailable, err)
+	}
+}
+
+func (r *RoomManager) StopTrackRecording(ctx context.Context, roomName livekit.RoomName, recordingID string) (*rtc.TrackRecordingInfo, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	info, err := room.StopTrackRecording(recordingID)
+	if err != nil {
+		return nil, ErrTrackRecordingNotFound
+	}
+	return info, nil
+}
+
+func (r *RoomManager) ListTrackRecordings(ctx context.Context, roomName livekit.RoomName) ([]*rtc.TrackRecordingInfo, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	return room.GetTrackRecordings(), nil
+}
+
+func (r *RoomManager) GetBandwidthPolicy(ctx context.Context, roomName livekit.RoomName) (*BandwidthPolicy, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	return r.bandwidthPolicy(room), nil
+}
+
+func (r *RoomManager) UpdateBandwidthPolicy(ctx context.Context, roomName livekit.RoomName, maxPublisherShare *float64) (*BandwidthPolicy, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	if maxPublisherShare != nil {
+		room.Logger.Infow("api update bandwidth policy", "maxPublisherShare", *maxPublisherShare)
+		room.SetMaxPublisherShare(*maxPublisherShare)
+	} else {
+		room.Logger.Infow("api clear bandwidth policy")
+		room.ClearMaxPublisherShare(r.config.RTC.CongestionControl.StreamAllocator.MaxPublisherShare)
+	}
+	return r.bandwidthPolicy(room), nil
+}
+
+func (r *RoomManager) RecordClientEvents(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, events []*telemetry.ClientEvent) error {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return ErrRoomNotFound
+	}
+	participant := room.GetParticipant(identity)
+	if participant == nil {
+		return ErrParticipantNotFound
+	}
+	if err := room.RecordClientEvents(participant, events); err != nil {
+		return psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+	return nil
+}
+
+func (r *RoomManager) RoomStats(ctx context.Context, roomName livekit.RoomName) (*rtc.RoomStats, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	return room.Stats(), nil
+}
+
+func (r *RoomManager) DebugRoom(ctx context.Context, roomName livekit.RoomName) (*rtc.RoomDebugSnapshot, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	snapshot := room.DebugSnapshot()
+	r.lock.RLock()
+	snapshot.Migrating = r.migratingRooms[roomName]
+	r.lock.RUnlock()
+	return snapshot, nil
+}
+
+func (r *RoomManager) SignalCapture(
+	ctx context.Context,
+	roomName livekit.RoomName,
+	action string,
+	identity livekit.ParticipantIdentity,
+	duration time.Duration,
+) (*SignalCapture, error) {
+	if r.signalCaptures == nil {
+		return nil, ErrSignalCaptureDisabled
+	}
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	var capture *SignalCapture
+	switch action {
+	case signalCaptureStart:
+		room.Logger.Infow("api start signal capture", "participant", identity, "duration", duration)
+		capture = r.signalCaptures.start(roomName, identity, duration)
+	case signalCaptureStop:
+		capture = r.signalCaptures.stop(roomName, identity)
+	case signalCaptureGet:
+		capture = r.signalCaptures.get(roomName, identity)
+	default:
+		return nil, ErrSignalCaptureInvalid
+	}
+	if capture == nil {
+		return nil, ErrSignalCaptureNotFound
+	}
+	return capture, nil
+}
+
+func (r *RoomManager) Conformance(
+	ctx context.Context,
+	roomName livekit.RoomName,
+	action string,
+	req *ConformanceRequest,
+) (*ConformanceResult, error) {
+	if !r.config.Conformance.Enabled {
+		return nil, ErrConformanceDisabled
+	}
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	participant := room.GetParticipant(livekit.ParticipantIdentity(req.Identity))
+	if participant == nil {
+		return nil, ErrParticipantNotFound
+	}
+
+	participant.GetLogger().Infow("applying conformance test case", "action", action)
+	res := &ConformanceResult{Action: action, Identity: req.Identity}
+	var err error
+	switch action {
+	case ConformanceRenegotiate:
+		participant.Negotiate(true)
+	case ConformanceICERestart:
+		participant.ICERestart(nil)
+	case ConformanceMigrate:
+		err = room.SimulateScenario(participant, &livekit.SimulateScenario{
+			Scenario: &livekit.SimulateScenario_Migration{Migration: true},
+		})
+	case ConformanceNodeFailure:
+		err = room.SimulateScenario(participant, &livekit.SimulateScenario{
+			Scenario: &livekit.SimulateScenario_NodeFailure{NodeFailure: true},
+		})
+	case ConformanceServerLeave:
+		err = room.SimulateScenario(participant, &livekit.SimulateScenario{
+			Scenario: &livekit.SimulateScenario_ServerLeave{ServerLeave: true},
+		})
+	case ConformanceFullReconnect:
+		participant.IssueFullReconnect(types.ParticipantCloseReasonSimulateLeaveRequest)
+	case ConformanceFailSubscription:
+		var signalErr livekit.SubscriptionError
+		if signalErr, err = conformanceSubscriptionError(req); err != nil {
+			return nil, err
+		}
+		participant.SendSubscriptionError(livekit.TrackID(req.TrackSid), signalErr, false)
+		if req.Reconnect {
+			participant.IssueFullReconnect(types.ParticipantCloseReasonSubscriptionError)
+		}
+	case ConformanceExpireToken:
+		validFor := defaultConformanceTokenTTL
+		if req.ExpiresInSeconds > 0 {
+			validFor = time.Duration(req.ExpiresInSeconds) * time.Second
+		}
+		expiresAt := time.Now().Add(validFor)
+		if res.Token, err = r.participantToken(room, participant, roomName, validFor); err == nil {
+			err = participant.SendRefreshToken(res.Token)
+		}
+		res.ExpiresAt = expiresAt.Unix()
+	default:
+		return nil, ErrConformanceInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// RequestDiagnosticsUpload asks a participant of a room hosted on this node to upload its diagnostics
+func (r *RoomManager) RequestDiagnosticsUpload(
+	ctx context.Context,
+	roomName livekit.RoomName,
+	identity livekit.ParticipantIdentity,
+	req *rtc.DiagnosticsUploadRequest,
+) error {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return ErrRoomNotFound
+	}
+	_, err := room.RequestDiagnosticsUpload(identity, req)
+	if errors.Is(err, rtc.ErrParticipantNotFound) {
+		return ErrParticipantNotFound
+	}
+	return err
+}
+
+func (r *RoomManager) bandwidthPolicy(room *rtc.Room) *BandwidthPolicy {
+	share, overridden := room.MaxPublisherShare()
+	if !overridden {
+		share = r.config.RTC.CongestionControl.StreamAllocator.MaxPublisherShare
+	}
+	return &BandwidthPolicy{
+		Room:              string(room.Name()),
+		MaxPublisherShare: share,
+		Overridden:        overridden,
+	}
+}
+
+func (r *RoomManager) StartRTPIngest(ctx context.Context, roomName livekit.RoomName, req *rtc.RTPIngestRequest) (*rtc.RTPIngestInfo, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	room.Logger.Infow("api start rtp ingest", "participant", req.Identity, "mime", req.MimeType)
+	info, err := room.StartRTPIngest(r.config.RTC.NodeIP, req)
+	switch {
+	case err == nil:
+		return info, nil
+	case errors.Is(err, rtc.ErrInvalidRTPIngest):
+		return nil, ErrRTPIngestInvalid
+	case errors.Is(err, rtc.ErrAlreadyJoined):
+		return nil, psrpc.NewErrorf(psrpc.AlreadyExists, "participant %s is already in the room", req.Identity)
+	default:
+		return nil, psrpc.NewError(psrpc.Unavailable, err)
+	}
+}
+
+func (r *RoomManager) StopRTPIngest(ctx context.Context, roomName livekit.RoomName, ingestID string) (*rtc.RTPIngestInfo, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	info, err := room.StopRTPIngest(ingestID)
+	if err != nil {
+		return nil, ErrRTPIngestNotFound
+	}
+	return info, nil
+}
+
+func (r *RoomManager) ListRTPIngests(ctx context.Context, roomName livekit.RoomName) ([]*rtc.RTPIngestInfo, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	return room.GetRTPIngests(), nil
+}
+
+// UpdateParticipantRole changes the role of a participant to the one set in the request attributes
+func (r *RoomManager) UpdateParticipantRole(ctx context.Context, req *livekit.UpdateParticipantRequest) (*livekit.ParticipantInfo, error) {
+	room := r.GetRoom(ctx, livekit.RoomName(req.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	role := rtc.RoleFromAttributes(req.Attributes)
+	room.Logger.Infow("api update participant role", "participant", req.Identity, "role", role)
+	p, err := room.UpdateParticipantRole(livekit.ParticipantIdentity(req.Identity), role)
+	switch err {
+	case nil:
+		return p.ToProto(), nil
+	case rtc.ErrInvalidParticipantRole:
+		return nil, ErrParticipantRoleInvalid
+	default:
+		return nil, ErrParticipantNotFound
+	}
+}
+
+// UpdateRecordingConsent records the consent of a participant set in the request attributes
+func (r *RoomManager) UpdateRecordingConsent(ctx context.Context, req *livekit.UpdateParticipantRequest) (*livekit.ParticipantInfo, error) {
+	room := r.GetRoom(ctx, livekit.RoomName(req.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	consent := rtc.RecordingConsent(req.Attributes[rtc.RecordingConsentAttribute])
+	room.Logger.Infow("api update recording consent", "participant", req.Identity, "consent", consent)
+	p, err := room.SetRecordingConsent(livekit.ParticipantIdentity(req.Identity), consent)
+	switch err {
+	case nil:
+		return p.ToProto(), nil
+	case rtc.ErrInvalidRecordingConsent:
+		return nil, ErrRecordingConsentInvalid
+	default:
+		return nil, ErrParticipantNotFound
+	}
+}
+
+// UpdateParticipants applies a bulk operation to the participants of a room in a single pass, failures are
+// reported per participant. Moderators may only act on participants their role allows.
+func (r *RoomManager) UpdateParticipants(ctx context.Context, roomName livekit.RoomName, cmd *bulkParticipantsCommand) (*BulkParticipantsResponse, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	release, err := r.adminLimiter.Acquire(ctx, AdminOpBulkParticipants)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var actorAttributes map[string]string
+	if cmd.Actor != "" {
+		actor := room.GetParticipant(livekit.ParticipantIdentity(cmd.Actor))
+		if actor == nil {
+			return nil, ErrPermissionDenied
+		}
+		actorAttributes = actor.ClaimGrants().Attributes
+	}
+
+	res := &BulkParticipantsResponse{Succeeded: []string{}}
+	var targets []types.LocalParticipant
+	if len(cmd.Filter.Identities) != 0 {
+		for _, identity := range cmd.Filter.Identities {
+			if p := room.GetParticipant(livekit.ParticipantIdentity(identity)); p != nil {
+				targets = append(targets, p)
+			} else {
+				res.fail(livekit.ParticipantIdentity(identity), ErrParticipantNotFound)
+			}
+		}
+	} else {
+		for _, p := range room.GetParticipants() {
+			if string(p.Identity()) != cmd.Actor && cmd.Filter.Matches(p) {
+				targets = append(targets, p)
+			}
+		}
+	}
+
+	room.Logger.Infow("api bulk participants", "action", cmd.Action, "participants", len(targets), "actor", cmd.Actor)
+	for _, p := range targets {
+		if cmd.Actor != "" && !rtc.CanModerate(actorAttributes, p.ClaimGrants().Attributes, cmd.roleAction()) {
+			res.fail(p.Identity(), ErrPermissionDenied)
+			continue
+		}
+
+		switch cmd.Action {
+		case bulkParticipantsMuteAll:
+			for _, track := range p.GetPublishedTracks() {
+				if !track.IsMuted() && (len(cmd.Sources) == 0 || slices.Contains(cmd.Sources, track.Source().String())) {
+					p.SetTrackMuted(track.ID(), true, true)
+				}
+			}
+		case bulkParticipantsRemove:
+			room.RemoveParticipant(p.Identity(), "", types.ParticipantCloseReasonServiceRequestRemoveParticipant)
+		case bulkParticipantsUpdateMetadata:
+			if err := p.CheckMetadataLimits("", cmd.Metadata, cmd.Attributes); err != nil {
+				res.fail(p.Identity(), err)
+				continue
+			}
+			if cmd.Metadata != "" {
+				p.SetMetadata(cmd.Metadata)
+			}
+			if cmd.Attributes != nil {
+				p.SetAttributes(cmd.Attributes)
+			}
+		default:
+			return nil, ErrBulkParticipantsInvalid
+		}
+		res.Succeeded = append(res.Succeeded, string(p.Identity()))
+	}
+	return res, nil
+}
+
+func (r *RoomManager) virtualParticipantForReq(ctx context.Context, req participantReq) (*rtc.Room, *rtc.VirtualParticipant) {
+	room := r.GetRoom(ctx, livekit.RoomName(req.GetRoom()))
+	if room == nil {
+		return nil, nil
+	}
+	return room, room.GetVirtualParticipant(livekit.ParticipantIdentity(req.GetIdentity()))
+}
+
+func (r *RoomManager) updateVirtualParticipant(ctx context.Context, room *rtc.Room, vp *rtc.VirtualParticipant, req *livekit.UpdateParticipantRequest) (*livekit.ParticipantInfo, error) {
+	if req.Permission != nil {
+		return nil, ErrVirtualParticipantPermission
+	}
+	pi, err := room.UpdateVirtualParticipant(vp.Identity(), req.Name, req.Metadata, req.Attributes)
+	if err != nil {
+		return nil, ErrParticipantNotFound
+	}
+	if err = r.roomStore.StoreParticipant(ctx, room.Name(), pi); err != nil {
+		room.Logger.Errorw("could not store virtual participant", err, "participant", vp.Identity())
+	}
+	return pi, nil
+}
+
+func (r *RoomManager) trackPublishPolicy(roomName livekit.RoomName, identity livekit.ParticipantIdentity) func(ctx context.Context, req *livekit.AddTrackRequest) error {
+	if r.policy == nil && r.quotas == nil {
+		return nil
+	}
+	return func(ctx context.Context, req *livekit.AddTrackRequest) error {
+		if r.quotas != nil {
+			if err := r.quotas.CheckTrackPublish(ctx, roomName, identity, r.publishUsage(roomName, identity), req); err != nil {
+				return err
+			}
+		}
+		if r.policy != nil {
+			return r.policy.CheckTrackPublish(ctx, roomName, identity, req)
+		}
+		return nil
+	}
+}
+
+// ICEPolicy returns the ice candidate policy of the peer connections of this node
+func (r *RoomManager) ICEPolicy() *rtc.ICEPolicy {
+	return r.rtcConfig.ICEPolicy
+}
+
+// ReloadConfig replaces the room defaults and limits applied to rooms and participants created from now on
+func (r *RoomManager) ReloadConfig(conf *config.Config) error {
+	roomConfig, limitConfig := conf.Room, conf.Limit
+	r.roomConfig.Store(&roomConfig)
+	r.limitConfig.Store(&limitConfig)
+	return nil
+}
+
+// migrateRoom moves a room to another node: it is assigned to a new node, then its participants are asked
+// to reconnect, which brings them to the new node, and the room is closed here without clearing its state
+func (r *RoomManager) migrateRoom(ctx context.Context, room *rtc.Room) error {
+	if r.relays != nil && r.relays.isEdge(room) {
+		// the room stays on its hosting node, which routes the participants to other relay nodes
+		r.relays.closeEdge(room)
+		for _, p := range room.GetParticipants() {
+			p.IssueFullReconnect(types.ParticipantCloseReasonMigrationRequested)
+		}
+		room.Close(types.ParticipantCloseReasonMigrationRequested)
+		return nil
+	}
+
+	roomName := room.Name()
+	nodeID := r.currentNode.NodeID()
+	if err := r.router.ClearRoomState(ctx, roomName); err != nil {
+		return err
+	}
+	err := r.roomAllocator.SelectRoomNode(ctx, roomName, "")
+	if err == nil {
+		var node *livekit.Node
+		if node, err = r.router.GetNodeForRoom(ctx, roomName); err == nil && livekit.NodeID(node.Id) == nodeID {
+			err = ErrNoMigrationTarget
+		}
+	}
 	if err != nil {
+		if err := r.router.SetNodeForRoom(ctx, roomName, nodeID); err != nil {
+			room.Logger.Errorw("could not restore node of room", err)
+		}
 		return err
 	}
 
-	grants := participant.ClaimGrants()
+	r.lock.Lock()
+	if r.rooms[roomName] != room {
+		r.lock.Unlock()
+		return nil
+	}
+	r.migratingRooms[roomName] = true
+	r.lock.Unlock()
+
+	participants := room.GetParticipants()
+	room.Logger.Infow("migrating room", "participants", len(participants))
+	for _, p := range participants {
+		p.IssueFullReconnect(types.ParticipantCloseReasonMigrationRequested)
+	}
+	room.Close(types.ParticipantCloseReasonMigrationRequested)
+	return nil
+}
+
+func (r *RoomManager) archiveRoom(room *rtc.Room) {
+	archive := room.Archive()
+	if archive == nil {
+		return
+	}
+	if err := r.archiver.Archive(context.Background(), archive); err != nil {
+		room.Logger.Errorw("could not archive room", err)
+		return
+	}
+	room.Logger.Debugw("room archived", "participants", len(archive.Participants), "tracks", len(archive.Tracks))
+}
+
+func (r *RoomManager) publishUsage(roomName livekit.RoomName, identity livekit.ParticipantIdentity) PublishUsage {
+	r.lock.RLock()
+	rooms := maps.Values(r.rooms)
+	r.lock.RUnlock()
+
+	var usage PublishUsage
+	for _, room := range rooms {
+		for _, p := range room.GetParticipants() {
+			tracks := p.GetPublishedTracks()
+			var bitrate int64
+			for _, track := range tracks {
+				bitrate += mediaTrackBitrate(track)
+			}
+			usage.NodeBitrate += bitrate
+			if room.Name() != roomName {
+				continue
+			}
+			usage.RoomBitrate += bitrate
+			if len(tracks) > 0 {
+				usage.RoomPublishers++
+				if p.Identity() == identity {
+					usage.IsPublisher = true
+				}
+			}
+		}
+	}
+	return usage
+}
+
+// mediaTrackBitrate sums the bitrate of the spatial layers received for a track,
+// using the highest temporal layer of each
+func mediaTrackBitrate(track types.MediaTrack) int64 {
+	var bitrate int64
+	for _, receiver := range track.Receivers() {
+		_, bitrates := receiver.GetLayeredBitrate()
+		for _, spatial := range bitrates {
+			var layer int64
+			for _, temporal := range spatial {
+				layer = max(layer, temporal)
+			}
+			bitrate += layer
+		}
+	}
+	return bitrate
+}
+
+func (r *RoomManager) iceServersForParticipant(
+	apiKey string,
+	roomName livekit.RoomName,
+	participant types.LocalParticipant,
+	tlsOnly bool,
+) []*livekit.ICEServer {
+	var iceServers []*livekit.ICEServer
+	rtcConf := r.config.RTC
+
+	if tlsOnly && r.config.TURN.TLSPort == 0 {
+		logger.Warnw("tls only enabled but no turn tls config", nil)
+		tlsOnly = false
+	}
+
+	hasSTUN := false
+	if r.config.TURN.Enabled {
+		var urls []string
+		if r.config.TURN.UDPPort > 0 && !tlsOnly && !r.config.TURN.TLSOnly {
+			// UDP TURN is used as STUN
+			hasSTUN = true
+			urls = append(urls, fmt.Sprintf("turn:%s:%d?transport=udp", r.config.RTC.NodeIP, r.config.TURN.UDPPort))
+		}
+		if r.config.TURN.TLSPort > 0 {
+			urls = append(urls, fmt.Sprintf("turns:%s:443?transport=tcp", r.config.TURN.Domain))
+		}
+		if len(urls) > 0 {
+			username := r.turnAuthHandler.CreateUsername(apiKey, participant.ID(), roomName)
+			password, err := r.turnAuthHandler.CreatePassword(apiKey, participant.ID(), roomName)
+			if err != nil {
+				participant.GetLogger().Warnw("could not create turn password", err)
+				hasSTUN = false
+			} else {
+				logger.Infow("created TURN password", "username", username, "password", password)
+				iceServers = append(iceServers, &livekit.ICEServer{
+					Urls:       urls,
+					Username:   username,
+					Credential: password,
+				})
+			}
+		}
+	}
+
+	if len(rtcConf.TURNServers) > 0 {
+		hasSTUN = true
+		for _, s := range r.config.RTC.TURNServers {
+			scheme := "turn"
+			transport := "tcp"
+			if s.Protocol == "tls" {
+				scheme = "turns"
+			} else if s.Protocol == "udp" {
+				transport = "udp"
+			}
+			is := &livekit.ICEServer{
+				Urls: []string{
+					fmt.Sprintf("%s:%s:%d?transport=%s", scheme, s.Host, s.Port, transport),
+				},
+				Username:   s.Username,
+				Credential: s.Credential,
+			}
+			iceServers = append(iceServers, is)
+		}
+	}
+
+	if len(rtcConf.STUNServers) > 0 {
+		hasSTUN = true
+		iceServers = append(iceServers, iceServerForStunServers(r.config.RTC.STUNServers))
+	}
+
+	if !hasSTUN {
+		iceServers = append(iceServers, iceServerForStunServers(rtcconfig.DefaultStunServers))
+	}
+	return iceServers
+}
+
+func (r *RoomManager) refreshToken(room *rtc.Room, participant types.LocalParticipant) error {
+	jwt, err := r.participantToken(room, participant, room.Name(), tokenDefaultTTL)
+	if err == nil {
+		err = participant.SendRefreshToken(jwt)
+	}
+	return err
+}
+
+// participantToken issues a token for the participant to join roomName, the room it is in unless moving
+func (r *RoomManager) participantToken(
+	room *rtc.Room,
+	participant types.LocalParticipant,
+	roomName livekit.RoomName,
+	validFor time.Duration,
+) (string, error) {
+	key, secret, err := r.getFirstKeyPair()
+	if err != nil {
+		return "", err
+	}
+
+	grants := participant.ClaimGrants().Clone()
+	if permission := room.PendingParticipantPermission(participant.Identity()); permission != nil {
+		// keep the permission granted to a waiting participant, it would otherwise be lost when reconnecting
+		grants.Video.UpdateFromPermission(permission)
+	}
+	grants.Video.Room = string(roomName)
 	token := auth.NewAccessToken(key, secret)
 	token.SetName(grants.Name).
 		SetIdentity(string(participant.Identity())).
-		SetValidFor(tokenDefaultTTL).
+		SetValidFor(validFor).
 		SetMetadata(grants.Metadata).
 		SetAttributes(grants.Attributes).
 		SetVideoGrant(grants.Video).
 		SetRoomConfig(grants.GetRoomConfiguration()).
 		SetRoomPreset(grants.RoomPreset)
-	jwt, err := token.ToJWT()
-	if err == nil {
-		err = participant.SendRefreshToken(jwt)
-	}
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return token.ToJWT()
 }
 
 func (r *RoomManager) setIceConfig(roomName livekit.RoomName, participant types.LocalParticipant) *livekit.ICEConfig {
@@ -0,0 +1,291 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+const (
+	// webhook event sent once the participants of the source room were moved, the destination room is set on it
+	EventRoomsMerged = "rooms_merged"
+
+	// a participant of the source room with the identity of one already in the destination room
+	MergeCollisionFail    = "fail"
+	MergeCollisionSkip    = "skip"
+	MergeCollisionReplace = "replace"
+
+	MergeMetadataKeep    = "keep"
+	MergeMetadataReplace = "replace"
+	MergeMetadataMerge   = "merge"
+
+	maxRoomMergeRequest = 16 * 1024
+)
+
+// MergeRoomsRequest is the JSON body of POST /rooms/merge
+type MergeRoomsRequest struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	// fail by default, nobody is moved when an identity collides.
+	// skip leaves the colliding participants in the source room, replace removes the ones of the destination room.
+	OnCollision string `json:"on_collision,omitempty"`
+	// keep by default, the metadata of the destination room is left untouched.
+	// replace sets the metadata of the source room on the destination one, merge combines both as JSON objects,
+	// keys of the destination room taking precedence.
+	Metadata string `json:"metadata,omitempty"`
+	// delete the source room once merged, disconnecting the participants left in it
+	DeleteSource bool `json:"delete_source,omitempty"`
+}
+
+type MergeRoomsResponse struct {
+	Room    *livekit.Room `json:"room"`
+	Moved   []string      `json:"moved"`
+	Skipped []string      `json:"skipped,omitempty"`
+	// participants which could not be moved, they are still in the source room unless it was deleted
+	Failed []string `json:"failed,omitempty"`
+}
+
+// RoomMergeService serves the HTTP API merging a live room into another, e.g. when overflow or breakout sessions
+// rejoin the main one. Participants are moved the way breakout rooms move them, by the node hosting the source room.
+// Calls require roomAdmin on the destination room and roomCreate, which allows deleting any room.
+type RoomMergeService struct {
+	roomService *RoomService
+	client      BreakoutClient
+	telemetry   telemetry.TelemetryService
+}
+
+func NewRoomMergeService(
+	roomService *RoomService,
+	client BreakoutClient,
+	ts telemetry.TelemetryService,
+) *RoomMergeService {
+	return &RoomMergeService{
+		roomService: roomService,
+		client:      client,
+		telemetry:   ts,
+	}
+}
+
+func (s *RoomMergeService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	if strings.TrimPrefix(r.URL.Path, "/rooms/") != "merge" {
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	var req MergeRoomsRequest
+	if err := decodeJSONRequest(r, &req, maxRoomMergeRequest); err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+	res, err := s.MergeRooms(r.Context(), &req)
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	body, err := json.Marshal(res)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+// MergeRooms moves the participants of the source room into the destination room and merges the metadata of both.
+// Collisions and metadata are checked before anyone is moved.
+func (s *RoomMergeService) MergeRooms(ctx context.Context, req *MergeRoomsRequest) (*MergeRoomsResponse, error) {
+	source, destination := livekit.RoomName(req.Source), livekit.RoomName(req.Destination)
+	AppendLogFields(ctx, "room", destination, "source", source)
+	if err := EnsureAdminPermission(ctx, destination); err != nil {
+		return nil, err
+	}
+	if err := EnsureCreatePermission(ctx); err != nil {
+		return nil, err
+	}
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	roomStore := s.roomService.roomStore
+	sourceRoom, _, err := roomStore.LoadRoom(ctx, source, false)
+	if err != nil {
+		return nil, err
+	}
+	destinationRoom, _, err := roomStore.LoadRoom(ctx, destination, false)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := mergeRoomMetadata(req.Metadata, destinationRoom.Metadata, sourceRoom.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	if maxMetadataSize := int(s.roomService.limitConf.Load().MaxMetadataSize); maxMetadataSize > 0 && len(metadata) > maxMetadataSize {
+		return nil, fmt.Errorf("%w: merged metadata is %d bytes", ErrMetadataExceedsLimits, len(metadata))
+	}
+
+	participants, err := roomStore.ListParticipants(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	present, err := roomStore.ListParticipants(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	collisions := make(map[string]bool)
+	for _, pi := range present {
+		collisions[pi.Identity] = true
+	}
+	if req.OnCollision == "" || req.OnCollision == MergeCollisionFail {
+		for _, pi := range participants {
+			if collisions[pi.Identity] {
+				return nil, fmt.Errorf("%w: %q is in both rooms", ErrParticipantExists, pi.Identity)
+			}
+		}
+	}
+
+	res := &MergeRoomsResponse{Room: destinationRoom}
+	for _, pi := range participants {
+		if collisions[pi.Identity] {
+			if req.OnCollision == MergeCollisionSkip {
+				res.Skipped = append(res.Skipped, pi.Identity)
+				continue
+			}
+			if _, err := s.roomService.participantClient.RemoveParticipant(
+				ctx,
+				s.roomService.topicFormatter.ParticipantTopic(ctx, destination, livekit.ParticipantIdentity(pi.Identity)),
+				&livekit.RoomParticipantIdentity{Room: req.Destination, Identity: pi.Identity},
+			); err != nil && !errors.Is(err, ErrParticipantNotFound) {
+				logger.Warnw("could not remove colliding participant", err,
+					"room", destination, "source", source, "participant", pi.Identity)
+				res.Failed = append(res.Failed, pi.Identity)
+				continue
+			}
+		}
+
+		moved, err := s.client.MoveParticipant(ctx, s.roomService.topicFormatter.RoomTopic(ctx, source), &livekit.RoomParticipantIdentity{
+			Room:     req.Destination,
+			Identity: pi.Identity,
+		})
+		if err != nil {
+			logger.Warnw("could not move participant to merged room", err,
+				"room", destination, "source", source, "participant", pi.Identity)
+			res.Failed = append(res.Failed, pi.Identity)
+			continue
+		}
+		res.Moved = append(res.Moved, pi.Identity)
+		s.notify(ctx, EventParticipantMoved, &livekit.Room{Name: req.Destination}, moved)
+	}
+
+	if metadata != destinationRoom.Metadata {
+		room, err := s.roomService.roomClient.UpdateRoomMetadata(ctx, s.roomService.topicFormatter.RoomTopic(ctx, destination), &livekit.UpdateRoomMetadataRequest{
+			Room:     req.Destination,
+			Metadata: metadata,
+		})
+		if err != nil {
+			return nil, err
+		}
+		res.Room = room
+	}
+
+	if req.DeleteSource {
+		err = s.roomService.deleteRoom(ctx, &livekit.DeleteRoomRequest{Room: req.Source})
+		if err != nil && !errors.Is(err, ErrRoomNotFound) {
+			return nil, err
+		}
+	}
+	s.notify(ctx, EventRoomsMerged, res.Room, nil)
+
+	return res, nil
+}
+
+func (req *MergeRoomsRequest) validate() error {
+	switch {
+	case req.Source == "" || req.Destination == "":
+		return fmt.Errorf("%w: source and destination are required", ErrRoomMergeInvalid)
+	case req.Source == req.Destination:
+		return fmt.Errorf("%w: a room cannot be merged into itself", ErrRoomMergeInvalid)
+	}
+	switch req.OnCollision {
+	case "", MergeCollisionFail, MergeCollisionSkip, MergeCollisionReplace:
+	default:
+		return fmt.Errorf("%w: unknown collision policy %q", ErrRoomMergeInvalid, req.OnCollision)
+	}
+	switch req.Metadata {
+	case "", MergeMetadataKeep, MergeMetadataReplace, MergeMetadataMerge:
+	default:
+		return fmt.Errorf("%w: unknown metadata strategy %q", ErrRoomMergeInvalid, req.Metadata)
+	}
+	return nil
+}
+
+func (s *RoomMergeService) notify(ctx context.Context, event string, room *livekit.Room, pi *livekit.ParticipantInfo) {
+	if s.telemetry == nil {
+		return
+	}
+	s.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{
+		Event:       event,
+		Room:        room,
+		Participant: pi,
+	})
+}
+
+// mergeRoomMetadata returns the metadata of the destination room once merged with the source room
+func mergeRoomMetadata(strategy, destination, source string) (string, error) {
+	switch strategy {
+	case MergeMetadataReplace:
+		return source, nil
+	case MergeMetadataMerge:
+		if source == "" {
+			return destination, nil
+		}
+		if destination == "" {
+			return source, nil
+		}
+		var dst, src map[string]any
+		if err := json.Unmarshal([]byte(destination), &dst); err != nil {
+			return "", fmt.Errorf("%w: metadata of the destination room is not a JSON object", ErrRoomMergeInvalid)
+		}
+		if err := json.Unmarshal([]byte(source), &src); err != nil {
+			return "", fmt.Errorf("%w: metadata of the source room is not a JSON object", ErrRoomMergeInvalid)
+		}
+		if src == nil {
+			src = make(map[string]any, len(dst))
+		}
+		maps.Copy(src, dst)
+		merged, err := json.Marshal(src)
+		if err != nil {
+			return "", err
+		}
+		return string(merged), nil
+	default:
+		return destination, nil
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/rpc/rpcfakes"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestMergeRooms(t *testing.T) {
+	setup := func(t *testing.T) (*service.RoomMergeService, *service.LocalStore, *testBreakoutClient, *rpcfakes.FakeTypedRoomClient, *rpcfakes.FakeTypedParticipantClient) {
+		ctx := context.Background()
+		store := service.NewLocalStore()
+		roomClient := &rpcfakes.FakeTypedRoomClient{}
+		roomClient.UpdateRoomMetadataCalls(func(_ context.Context, _ rpc.RoomTopic, req *livekit.UpdateRoomMetadataRequest, _ ...psrpc.RequestOption) (*livekit.Room, error) {
+			return &livekit.Room{Name: req.Room, Metadata: req.Metadata}, nil
+		})
+		participantClient := &rpcfakes.FakeTypedParticipantClient{}
+		roomService, err := service.NewRoomService(
+			config.LimitConfig{},
+			config.APIConfig{ExecutionTimeout: 2},
+			&routingfakes.FakeRouter{},
+			&servicefakes.FakeRoomAllocator{},
+			store,
+			nil,
+			&identityTopicFormatter{},
+			roomClient,
+			participantClient,
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, store.StoreRoom(ctx, &livekit.Room{Name: "main", Metadata: `{"topic":"keynote","stage":"on"}`}, nil))
+		require.NoError(t, store.StoreRoom(ctx, &livekit.Room{Name: "overflow", Metadata: `{"topic":"overflow","chat":"off"}`}, nil))
+		for _, identity := range []string{"host", "alice"} {
+			require.NoError(t, store.StoreParticipant(ctx, "main", &livekit.ParticipantInfo{Identity: identity}))
+		}
+		for _, identity := range []string{"alice", "bob"} {
+			require.NoError(t, store.StoreParticipant(ctx, "overflow", &livekit.ParticipantInfo{Identity: identity}))
+		}
+
+		client := &testBreakoutClient{store: store}
+		svc := service.NewRoomMergeService(roomService, client, &telemetryfakes.FakeTelemetryService{})
+		return svc, store, client, roomClient, participantClient
+	}
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomAdmin: true, RoomCreate: true, Room: "main"},
+	}, "")
+
+	t.Run("requires admin of the destination room", func(t *testing.T) {
+		svc, _, _, _, _ := setup(t)
+		other := service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomAdmin: true, RoomCreate: true, Room: "overflow"},
+		}, "")
+		_, err := svc.MergeRooms(other, &service.MergeRoomsRequest{Source: "overflow", Destination: "main"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+	})
+
+	t.Run("fails on collisions by default", func(t *testing.T) {
+		svc, _, client, _, _ := setup(t)
+		_, err := svc.MergeRooms(ctx, &service.MergeRoomsRequest{Source: "overflow", Destination: "main"})
+		require.ErrorIs(t, err, service.ErrParticipantExists)
+		require.Empty(t, client.moves)
+
+		_, err = svc.MergeRooms(ctx, &service.MergeRoomsRequest{Source: "overflow", Destination: "main", OnCollision: "rename"})
+		require.ErrorIs(t, err, service.ErrRoomMergeInvalid)
+	})
+
+	t.Run("skips colliding participants and merges metadata", func(t *testing.T) {
+		svc, store, client, roomClient, _ := setup(t)
+		res, err := svc.MergeRooms(ctx, &service.MergeRoomsRequest{
+			Source:       "overflow",
+			Destination:  "main",
+			OnCollision:  service.MergeCollisionSkip,
+			Metadata:     service.MergeMetadataMerge,
+			DeleteSource: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"bob"}, res.Moved)
+		require.Equal(t, []string{"alice"}, res.Skipped)
+		require.Equal(t, []breakoutMove{{from: "overflow", to: "main", identity: "bob"}}, client.moves)
+		require.JSONEq(t, `{"topic":"keynote","stage":"on","chat":"off"}`, res.Room.Metadata)
+		require.Equal(t, 1, roomClient.DeleteRoomCallCount())
+
+		_, _, err = store.LoadRoom(ctx, "overflow", false)
+		require.ErrorIs(t, err, service.ErrRoomNotFound)
+	})
+
+	t.Run("replaces colliding participants", func(t *testing.T) {
+		svc, _, client, roomClient, participantClient := setup(t)
+		res, err := svc.MergeRooms(ctx, &service.MergeRoomsRequest{
+			Source:      "overflow",
+			Destination: "main",
+			OnCollision: service.MergeCollisionReplace,
+		})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"alice", "bob"}, res.Moved)
+		require.Len(t, client.moves, 2)
+		require.Equal(t, 1, participantClient.RemoveParticipantCallCount())
+		_, _, req, _ := participantClient.RemoveParticipantArgsForCall(0)
+		require.Equal(t, "main", req.Room)
+		require.Equal(t, "alice", req.Identity)
+		// metadata is kept by default
+		require.Zero(t, roomClient.UpdateRoomMetadataCallCount())
+		require.Zero(t, roomClient.DeleteRoomCallCount())
+	})
+}
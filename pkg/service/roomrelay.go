@@ -0,0 +1,708 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	roomRelayRPCService = "RoomRelay"
+	roomRelayRPC        = "RoomRelay"
+
+	// sent to the node hosting a room
+	roomRelayRoute  = "route"
+	roomRelayAttach = "attach"
+	roomRelayUpdate = "update"
+	roomRelayDetach = "detach"
+	// sent to a relay node of a room
+	roomRelayTrack   = "track"
+	roomRelayUntrack = "untrack"
+	roomRelayClose   = "close"
+
+	roomRelayUpdateInterval = 5 * time.Second
+	// relay nodes not updating the hosting node for this long are dropped
+	roomRelayTimeout        = 3 * roomRelayUpdateInterval
+	roomRelayRequestTimeout = 3 * time.Second
+)
+
+// roomRelayCommand is carried as JSON in the payload of a user data packet, the response carries a
+// roomRelayResponse
+type roomRelayCommand struct {
+	Action       string          `json:"action"`
+	NodeID       string          `json:"node_id,omitempty"`
+	Participants int             `json:"participants,omitempty"`
+	Track        *rtc.RelayTrack `json:"track,omitempty"`
+}
+
+type roomRelayResponse struct {
+	// node the participant should join, the hosting node when empty
+	NodeID string             `json:"node_id,omitempty"`
+	Ingest *rtc.RTPIngestInfo `json:"ingest,omitempty"`
+}
+
+// RoomRelayClient reaches the node hosting a room, on the room topic, and its relay nodes, on their relay
+// topic, to coordinate rooms spanning several nodes
+type RoomRelayClient interface {
+	RoomRelay(ctx context.Context, topic rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error)
+}
+
+type roomRelayClient struct {
+	client *client.RPCClient
+}
+
+func NewRoomRelayClient(params rpc.ClientParams) (RoomRelayClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: roomRelayRPCService,
+		ID:   rand.NewClientID(),
+	}
+	sd.RegisterMethod(roomRelayRPC, false, false, true, true)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &roomRelayClient{client: rpcClient}, nil
+}
+
+func (c *roomRelayClient) RoomRelay(ctx context.Context, topic rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return client.RequestSingle[*livekit.DataPacket](ctx, c.client, roomRelayRPC, []string{string(topic)}, req, opts...)
+}
+
+// roomRelayTopic is the topic of a relay node of a room, the room topic belongs to the hosting node
+func roomRelayTopic(roomName livekit.RoomName, nodeID livekit.NodeID) rpc.RoomTopic {
+	return rpc.RoomTopic(strings.Join([]string{"relay", string(nodeID), string(roomName)}, "/"))
+}
+
+func sendRoomRelay(ctx context.Context, c RoomRelayClient, topic rpc.RoomTopic, cmd *roomRelayCommand) (*roomRelayResponse, error) {
+	req, err := encodeRoomRelay(cmd)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, roomRelayRequestTimeout)
+	defer cancel()
+	res, err := c.RoomRelay(ctx, topic, req)
+	if err != nil {
+		return nil, err
+	}
+	var rr roomRelayResponse
+	if err = json.Unmarshal(res.GetUser().GetPayload(), &rr); err != nil {
+		return nil, err
+	}
+	return &rr, nil
+}
+
+// RouteRelayParticipant asks the node hosting a room which node a participant joining without publish
+// permission should be served by, the hosting node is used when it returns an empty node ID
+func RouteRelayParticipant(ctx context.Context, c RoomRelayClient, roomName livekit.RoomName) (livekit.NodeID, error) {
+	res, err := sendRoomRelay(ctx, c, rpc.FormatRoomTopic(roomName), &roomRelayCommand{Action: roomRelayRoute})
+	if err != nil {
+		return "", err
+	}
+	return livekit.NodeID(res.NodeID), nil
+}
+
+func encodeRoomRelay(v any) (*livekit.DataPacket, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{Payload: payload},
+		},
+	}, nil
+}
+
+// roomRelayServer handles relay commands for a room on this node, as its hosting node or a relay node
+type roomRelayServer struct {
+	relays   *roomRelays
+	roomName livekit.RoomName
+	rpc      *server.RPCServer
+}
+
+func newRoomRelayServer(relays *roomRelays, roomName livekit.RoomName, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *roomRelayServer {
+	sd := &info.ServiceDefinition{
+		Name: roomRelayRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	sd.RegisterMethod(roomRelayRPC, false, false, true, true)
+	return &roomRelayServer{
+		relays:   relays,
+		roomName: roomName,
+		rpc:      s,
+	}
+}
+
+func (s *roomRelayServer) RegisterAllRoomTopics(topic rpc.RoomTopic) error {
+	return server.RegisterHandler(s.rpc, roomRelayRPC, []string{string(topic)}, s.handle, nil)
+}
+
+func (s *roomRelayServer) handle(ctx context.Context, req *livekit.DataPacket) (*livekit.DataPacket, error) {
+	var cmd roomRelayCommand
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &cmd); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+
+	res, err := s.relays.handle(s.roomName, &cmd)
+	if err != nil {
+		return nil, err
+	}
+	return encodeRoomRelay(res)
+}
+
+func (s *roomRelayServer) Kill() {
+	s.rpc.Close(true)
+}
+
+// ---------------------------------------------
+
+// roomRelays lets rooms span several nodes. The node hosting a room sends participants joining without
+// publish permission to relay nodes once it is full, and forwards a single layer of each published track
+// to every relay node, where it is published through an RTP ingest as a track of a virtual participant.
+// Relay nodes only serve subscribers: they don't store the room, dispatch agents or start egresses, and
+// room APIs keep reaching the hosting node.
+type roomRelays struct {
+	conf    config.RelayConfig
+	quality livekit.VideoQuality
+	nodeIP  string
+	// transport of the media relayed to this node, srtp when internal traffic is encrypted
+	transport   string
+	currentNode routing.LocalNode
+	router      routing.Router
+	client      RoomRelayClient
+	limit       func() config.LimitConfig
+
+	lock    sync.Mutex
+	origins map[livekit.RoomName]*relayOrigin
+	edges   map[livekit.RoomName]*relayEdge
+}
+
+// relayOrigin is a room hosted on this node with its relay nodes
+type relayOrigin struct {
+	room  *rtc.Room
+	nodes map[livekit.NodeID]*relayNode
+	done  chan struct{}
+}
+
+type relayNode struct {
+	participants int
+	updatedAt    time.Time
+	// forward IDs by track sid
+	forwards map[string]string
+}
+
+// relayEdge is a room relayed to this node
+type relayEdge struct {
+	room         *rtc.Room
+	originNodeID livekit.NodeID
+	// ingest IDs by track sid of the hosting node
+	ingests map[string]string
+	done    chan struct{}
+}
+
+// newRoomRelays returns nil when relays are disabled
+func newRoomRelays(
+	conf *config.Config,
+	currentNode routing.LocalNode,
+	router routing.Router,
+	client RoomRelayClient,
+	limit func() config.LimitConfig,
+) *roomRelays {
+	if !conf.Relay.Enabled || client == nil {
+		return nil
+	}
+	transport := rtc.TrackForwardTransportRTP
+	if conf.InternalTLS.Enabled {
+		transport = rtc.TrackForwardTransportSRTP
+	}
+	return &roomRelays{
+		conf:        conf.Relay,
+		quality:     livekit.VideoQuality(livekit.VideoQuality_value[strings.ToUpper(conf.Relay.Quality)]),
+		nodeIP:      conf.RTC.NodeIP,
+		transport:   transport,
+		currentNode: currentNode,
+		router:      router,
+		client:      client,
+		limit:       limit,
+		origins:     make(map[livekit.RoomName]*relayOrigin),
+		edges:       make(map[livekit.RoomName]*relayEdge),
+	}
+}
+
+// originNode returns the node hosting a room when it is another available node, the room is relayed
+// to this node then
+func (r *roomRelays) originNode(ctx context.Context, roomName livekit.RoomName) (livekit.NodeID, bool) {
+	node, err := r.router.GetNodeForRoom(ctx, roomName)
+	if err != nil || livekit.NodeID(node.Id) == r.currentNode.NodeID() || !selector.IsAvailable(node) {
+		return "", false
+	}
+	return livekit.NodeID(node.Id), true
+}
+
+func (r *roomRelays) startOrigin(room *rtc.Room) {
+	o := &relayOrigin{
+		room:  room,
+		nodes: make(map[livekit.NodeID]*relayNode),
+		done:  make(chan struct{}),
+	}
+	r.lock.Lock()
+	r.origins[room.Name()] = o
+	r.lock.Unlock()
+
+	room.OnRelayTrackPublished(func(track *rtc.RelayTrack) {
+		r.lock.Lock()
+		nodeIDs := make([]livekit.NodeID, 0, len(o.nodes))
+		for nodeID := range o.nodes {
+			nodeIDs = append(nodeIDs, nodeID)
+		}
+		r.lock.Unlock()
+
+		for _, nodeID := range nodeIDs {
+			go r.relayTrack(o, nodeID, track)
+		}
+	})
+	go r.originWorker(o)
+}
+
+// closeOrigin closes the relayed rooms with the room hosted here
+func (r *roomRelays) closeOrigin(room *rtc.Room) {
+	r.lock.Lock()
+	o := r.origins[room.Name()]
+	if o == nil || o.room != room {
+		r.lock.Unlock()
+		return
+	}
+	delete(r.origins, room.Name())
+	close(o.done)
+	nodeIDs := make([]livekit.NodeID, 0, len(o.nodes))
+	for nodeID := range o.nodes {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	o.nodes = make(map[livekit.NodeID]*relayNode)
+	r.lock.Unlock()
+
+	for _, nodeID := range nodeIDs {
+		go func(nodeID livekit.NodeID) {
+			if _, err := sendRoomRelay(context.Background(), r.client, roomRelayTopic(room.Name(), nodeID), &roomRelayCommand{Action: roomRelayClose}); err != nil {
+				room.Logger.Warnw("could not close relayed room", err, "relayNodeID", nodeID)
+			}
+		}(nodeID)
+	}
+}
+
+// originWorker drops relay nodes which stopped updating the hosting node
+func (r *roomRelays) originWorker(o *relayOrigin) {
+	ticker := time.NewTicker(roomRelayUpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.done:
+			return
+		case <-ticker.C:
+			var stale []livekit.NodeID
+			r.lock.Lock()
+			for nodeID, node := range o.nodes {
+				if time.Since(node.updatedAt) > roomRelayTimeout {
+					stale = append(stale, nodeID)
+				}
+			}
+			r.lock.Unlock()
+
+			for _, nodeID := range stale {
+				o.room.Logger.Infow("dropping relay node", "relayNodeID", nodeID)
+				r.detach(o, nodeID)
+			}
+		}
+	}
+}
+
+func (r *roomRelays) startEdge(room *rtc.Room, originNodeID livekit.NodeID) {
+	e := &relayEdge{
+		room:         room,
+		originNodeID: originNodeID,
+		ingests:      make(map[string]string),
+		done:         make(chan struct{}),
+	}
+	r.lock.Lock()
+	r.edges[room.Name()] = e
+	r.lock.Unlock()
+
+	go r.edgeWorker(e)
+}
+
+// closeEdge detaches a relayed room from its hosting node
+func (r *roomRelays) closeEdge(room *rtc.Room) {
+	r.lock.Lock()
+	e := r.edges[room.Name()]
+	if e == nil || e.room != room {
+		r.lock.Unlock()
+		return
+	}
+	delete(r.edges, room.Name())
+	close(e.done)
+	r.lock.Unlock()
+
+	_, err := sendRoomRelay(context.Background(), r.client, rpc.FormatRoomTopic(room.Name()), &roomRelayCommand{
+		Action: roomRelayDetach,
+		NodeID: string(r.currentNode.NodeID()),
+	})
+	if err != nil {
+		room.Logger.Warnw("could not detach relayed room", err, "originNodeID", e.originNodeID)
+	}
+}
+
+func (r *roomRelays) isEdge(room *rtc.Room) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	e := r.edges[room.Name()]
+	return e != nil && e.room == room
+}
+
+// edgeWorker attaches a relayed room to its hosting node and keeps it updated with the participants served here
+func (r *roomRelays) edgeWorker(e *relayEdge) {
+	send := func(action string) {
+		_, err := sendRoomRelay(context.Background(), r.client, rpc.FormatRoomTopic(e.room.Name()), &roomRelayCommand{
+			Action:       action,
+			NodeID:       string(r.currentNode.NodeID()),
+			Participants: len(e.room.GetParticipants()),
+		})
+		if err != nil {
+			e.room.Logger.Warnw("could not reach hosting node of relayed room", err, "action", action, "originNodeID", e.originNodeID)
+		}
+	}
+
+	send(roomRelayAttach)
+	ticker := time.NewTicker(roomRelayUpdateInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+			send(roomRelayUpdate)
+		}
+	}
+}
+
+func (r *roomRelays) handle(roomName livekit.RoomName, cmd *roomRelayCommand) (*roomRelayResponse, error) {
+	switch cmd.Action {
+	case roomRelayRoute, roomRelayAttach, roomRelayUpdate, roomRelayDetach:
+		r.lock.Lock()
+		o := r.origins[roomName]
+		r.lock.Unlock()
+		if o == nil {
+			return nil, ErrRoomNotFound
+		}
+
+		switch cmd.Action {
+		case roomRelayRoute:
+			return &roomRelayResponse{NodeID: string(r.route(o))}, nil
+		case roomRelayAttach, roomRelayUpdate:
+			if cmd.NodeID == "" {
+				return nil, ErrRoomRelayInvalid
+			}
+			r.update(o, livekit.NodeID(cmd.NodeID), cmd.Participants)
+		default:
+			r.detach(o, livekit.NodeID(cmd.NodeID))
+		}
+		return &roomRelayResponse{}, nil
+
+	case roomRelayTrack, roomRelayUntrack, roomRelayClose:
+		r.lock.Lock()
+		e := r.edges[roomName]
+		r.lock.Unlock()
+		if e == nil {
+			return nil, ErrRoomNotFound
+		}
+
+		switch cmd.Action {
+		case roomRelayTrack:
+			if cmd.Track == nil || cmd.Track.TrackSid == "" {
+				return nil, ErrRoomRelayInvalid
+			}
+			ingest, err := r.startTrack(e, cmd.Track)
+			if err != nil {
+				return nil, psrpc.NewError(psrpc.Unavailable, err)
+			}
+			return &roomRelayResponse{Ingest: ingest}, nil
+		case roomRelayUntrack:
+			if cmd.Track == nil {
+				return nil, ErrRoomRelayInvalid
+			}
+			r.stopTrack(e, cmd.Track.TrackSid)
+		default:
+			e.room.Logger.Infow("hosting node closed relayed room")
+			go e.room.Close(types.ParticipantCloseReasonRoomClosed)
+		}
+		return &roomRelayResponse{}, nil
+
+	default:
+		return nil, ErrRoomRelayInvalid
+	}
+}
+
+// route returns the node a participant joining without publish permission should be served by, the
+// least loaded relay node which is not full, or a new one
+func (r *roomRelays) route(o *relayOrigin) livekit.NodeID {
+	if len(o.room.GetParticipants()) < r.conf.OriginMaxParticipants {
+		return ""
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var (
+		routed *relayNode
+		nodeID livekit.NodeID
+	)
+	for id, node := range o.nodes {
+		if routed == nil || node.participants < routed.participants {
+			routed, nodeID = node, id
+		}
+	}
+	if routed == nil || routed.participants >= r.conf.RelayMaxParticipants {
+		if r.conf.MaxRelayNodes == 0 || len(o.nodes) < r.conf.MaxRelayNodes {
+			if id, ok := r.selectNode(o); ok {
+				routed, nodeID = &relayNode{updatedAt: time.Now(), forwards: make(map[string]string)}, id
+				o.nodes[id] = routed
+				o.room.Logger.Infow("adding relay node", "relayNodeID", id)
+			}
+		}
+	}
+	if routed == nil {
+		return ""
+	}
+	// counted until the relay node updates the hosting node, so joins in bursts are spread
+	routed.participants++
+	return nodeID
+}
+
+// selectNode returns the available node with the fewest clients which does not relay the room yet
+func (r *roomRelays) selectNode(o *relayOrigin) (livekit.NodeID, bool) {
+	nodes, err := r.router.ListNodes()
+	if err != nil {
+		o.room.Logger.Warnw("could not list nodes for relay", err)
+		return "", false
+	}
+
+	var selected *livekit.Node
+	for _, node := range selector.GetAvailableNodes(nodes) {
+		nodeID := livekit.NodeID(node.Id)
+		if nodeID == r.currentNode.NodeID() || o.nodes[nodeID] != nil || selector.LimitsReached(r.limit(), node.Stats) {
+			continue
+		}
+		if selected == nil || node.Stats.GetNumClients() < selected.Stats.GetNumClients() {
+			selected = node
+		}
+	}
+	if selected == nil {
+		return "", false
+	}
+	return livekit.NodeID(selected.Id), true
+}
+
+// update records the participants of a relay node, relaying the tracks of the room to it when it attaches
+func (r *roomRelays) update(o *relayOrigin, nodeID livekit.NodeID, participants int) {
+	r.lock.Lock()
+	node := o.nodes[nodeID]
+	attached := node == nil || len(node.forwards) == 0
+	if node == nil {
+		node = &relayNode{forwards: make(map[string]string)}
+		o.nodes[nodeID] = node
+		o.room.Logger.Infow("relay node attached", "relayNodeID", nodeID)
+	}
+	node.participants = participants
+	node.updatedAt = time.Now()
+	r.lock.Unlock()
+
+	if attached {
+		for _, track := range o.room.RelayTracks() {
+			go r.relayTrack(o, nodeID, track)
+		}
+	}
+}
+
+func (r *roomRelays) detach(o *relayOrigin, nodeID livekit.NodeID) {
+	r.lock.Lock()
+	node := o.nodes[nodeID]
+	delete(o.nodes, nodeID)
+	r.lock.Unlock()
+	if node == nil {
+		return
+	}
+
+	o.room.Logger.Infow("relay node detached", "relayNodeID", nodeID)
+	for _, forwardID := range node.forwards {
+		o.room.StopRelayTrackForward(forwardID)
+	}
+}
+
+// relayTrack has a relay node publish a track of the room and forwards the track to it
+func (r *roomRelays) relayTrack(o *relayOrigin, nodeID livekit.NodeID, track *rtc.RelayTrack) {
+	r.lock.Lock()
+	node := o.nodes[nodeID]
+	relayed := node != nil && node.forwards[track.TrackSid] != ""
+	if node != nil && !relayed {
+		// reserved until the forward starts
+		node.forwards[track.TrackSid] = track.TrackSid
+	}
+	r.lock.Unlock()
+	if node == nil || relayed {
+		return
+	}
+
+	release := func() {
+		r.lock.Lock()
+		if o.nodes[nodeID] == node {
+			delete(node.forwards, track.TrackSid)
+		}
+		r.lock.Unlock()
+	}
+
+	topic := roomRelayTopic(o.room.Name(), nodeID)
+	res, err := sendRoomRelay(context.Background(), r.client, topic, &roomRelayCommand{Action: roomRelayTrack, Track: track})
+	if err != nil || res.Ingest == nil {
+		o.room.Logger.Warnw("could not relay track", err, "relayNodeID", nodeID, "trackID", track.TrackSid)
+		release()
+		return
+	}
+
+	forwardID, err := o.room.ForwardRelayTrack(track, res.Ingest, r.quality, func() {
+		r.lock.Lock()
+		current := o.nodes[nodeID] == node && node.forwards[track.TrackSid] != ""
+		if current {
+			delete(node.forwards, track.TrackSid)
+		}
+		r.lock.Unlock()
+		if current {
+			// track unpublished, the relay node unpublishes it too
+			_, _ = sendRoomRelay(context.Background(), r.client, topic, &roomRelayCommand{Action: roomRelayUntrack, Track: track})
+		}
+	})
+	if err != nil {
+		o.room.Logger.Warnw("could not forward relayed track", err, "relayNodeID", nodeID, "trackID", track.TrackSid)
+		release()
+		_, _ = sendRoomRelay(context.Background(), r.client, topic, &roomRelayCommand{Action: roomRelayUntrack, Track: track})
+		return
+	}
+
+	r.lock.Lock()
+	current := o.nodes[nodeID] == node
+	if current {
+		node.forwards[track.TrackSid] = forwardID
+	}
+	r.lock.Unlock()
+	if !current {
+		// detached while starting
+		o.room.StopRelayTrackForward(forwardID)
+	}
+}
+
+func (r *roomRelays) startTrack(e *relayEdge, track *rtc.RelayTrack) (*rtc.RTPIngestInfo, error) {
+	// relayed again, e.g. after the hosting node dropped this node
+	r.stopTrack(e, track.TrackSid)
+
+	ingest, err := e.room.StartRelayTrack(r.nodeIP, track, r.transport)
+	if err != nil {
+		return nil, err
+	}
+	r.lock.Lock()
+	e.ingests[track.TrackSid] = ingest.IngestID
+	r.lock.Unlock()
+	return ingest, nil
+}
+
+func (r *roomRelays) stopTrack(e *relayEdge, trackSid string) {
+	r.lock.Lock()
+	ingestID, ok := e.ingests[trackSid]
+	delete(e.ingests, trackSid)
+	r.lock.Unlock()
+	if ok {
+		_, _ = e.room.StopRTPIngest(ingestID)
+	}
+}
+
+// ---------------------------------------------
+
+// createRelayRoom creates the room relayed to this node from the room hosted on originNodeID. Called with
+// the lock held, it is released.
+func (r *RoomManager) createRelayRoom(ri *livekit.Room, internal *livekit.RoomInternal, originNodeID livekit.NodeID) (*rtc.Room, error) {
+	roomName := livekit.RoomName(ri.Name)
+	// egresses and agents run against the hosting node
+	relayInternal := &livekit.RoomInternal{
+		PlayoutDelay: internal.GetPlayoutDelay(),
+		SyncStreams:  internal.GetSyncStreams(),
+	}
+	newRoom := rtc.NewRoom(ri, relayInternal, *r.rtcConfig, *r.roomConfig.Load(), &r.config.Audio, r.serverInfo, r.telemetry, nil, r.agentStore, r.egressLauncher)
+	if r.abuseDetector != nil {
+		newRoom.SetDataPacketLimiter(r.abuseDetector)
+	}
+	newRoom.SetAlternativeURLProvider(r.regions.AlternativeURL)
+	newRoom.SetSDKBlocklist(r.sdkBlocklist)
+
+	relayTopic := roomRelayTopic(roomName, r.currentNode.NodeID())
+	roomRelayServer := newRoomRelayServer(r.relays, roomName, r.bus)
+	killRoomRelayServer := r.roomRelayServers.Replace(relayTopic, roomRelayServer)
+	if err := roomRelayServer.RegisterAllRoomTopics(relayTopic); err != nil {
+		killRoomRelayServer()
+		r.lock.Unlock()
+		return nil, err
+	}
+
+	newRoom.OnClose(func() {
+		killRoomRelayServer()
+		r.relays.closeEdge(newRoom)
+
+		r.lock.Lock()
+		if r.rooms[roomName] == newRoom {
+			delete(r.rooms, roomName)
+		}
+		r.lock.Unlock()
+
+		newRoom.Logger.Infow("relayed room closed", "originNodeID", originNodeID)
+	})
+
+	r.rooms[roomName] = newRoom
+	r.lock.Unlock()
+
+	newRoom.Hold()
+	r.relays.startEdge(newRoom, originNodeID)
+	newRoom.Logger.Infow("relaying room", "originNodeID", originNodeID)
+
+	return newRoom, nil
+}
@@ -0,0 +1,416 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+const (
+	// webhook events sent for scheduled rooms, in addition to room_started and room_finished
+	EventRoomScheduled       = "room_scheduled"
+	EventRoomScheduleOpened  = "room_schedule_opened"
+	EventRoomScheduleEnding  = "room_schedule_ending"
+	EventRoomScheduleClosed  = "room_schedule_closed"
+	EventRoomScheduleDeleted = "room_schedule_deleted"
+
+	defaultRoomScheduleGracePeriod = 5 * time.Minute
+	roomScheduleCheckInterval      = time.Second
+	maxRoomScheduleRequest         = 64 * 1024
+)
+
+type RoomScheduleState string
+
+const (
+	// waiting for the start time, joins are refused
+	RoomScheduleScheduled RoomScheduleState = "scheduled"
+	// room was created at the start time
+	RoomScheduleOpen RoomScheduleState = "open"
+	// end time passed, the room is closed once the grace period is over
+	RoomScheduleEnding RoomScheduleState = "ending"
+	// room is being closed
+	RoomScheduleClosed RoomScheduleState = "closed"
+)
+
+// RoomSchedule opens a room at StartAt and closes it GracePeriod after EndAt.
+// A zero EndAt keeps the room open until it is deleted or times out as usual.
+type RoomSchedule struct {
+	Request     *livekit.CreateRoomRequest
+	StartAt     time.Time
+	EndAt       time.Time
+	GracePeriod time.Duration
+	State       RoomScheduleState
+}
+
+func (s *RoomSchedule) closeAt() time.Time {
+	return s.EndAt.Add(s.GracePeriod)
+}
+
+// ScheduleRoomRequest is the JSON body of POST /rooms/schedule/create, times are unix seconds
+type ScheduleRoomRequest struct {
+	Name             string `json:"name"`
+	StartTime        int64  `json:"start_time,omitempty"`
+	EndTime          int64  `json:"end_time,omitempty"`
+	GracePeriod      uint32 `json:"grace_period,omitempty"`
+	RoomPreset       string `json:"room_preset,omitempty"`
+	MaxParticipants  uint32 `json:"max_participants,omitempty"`
+	Metadata         string `json:"metadata,omitempty"`
+	EmptyTimeout     uint32 `json:"empty_timeout,omitempty"`
+	DepartureTimeout uint32 `json:"departure_timeout,omitempty"`
+}
+
+// DeleteRoomScheduleRequest is the JSON body of POST /rooms/schedule/delete
+type DeleteRoomScheduleRequest struct {
+	Name string `json:"name"`
+}
+
+// RoomScheduleInfo is returned by the schedule API
+type RoomScheduleInfo struct {
+	Name        string `json:"name"`
+	StartTime   int64  `json:"start_time"`
+	EndTime     int64  `json:"end_time,omitempty"`
+	GracePeriod uint32 `json:"grace_period"`
+	State       string `json:"state"`
+}
+
+type ListRoomSchedulesResponse struct {
+	Schedules []*RoomScheduleInfo `json:"schedules"`
+}
+
+// RoomScheduleService serves the room scheduling API and runs the schedules.
+// Every node checks the schedules, the store lets a single node make each transition.
+type RoomScheduleService struct {
+	limitConf   config.LimitConfig
+	store       RoomScheduleStore
+	roomService *RoomService
+	telemetry   telemetry.TelemetryService
+
+	done chan struct{}
+}
+
+func NewRoomScheduleService(
+	limitConf config.LimitConfig,
+	store RoomScheduleStore,
+	roomService *RoomService,
+	ts telemetry.TelemetryService,
+) *RoomScheduleService {
+	return &RoomScheduleService{
+		limitConf:   limitConf,
+		store:       store,
+		roomService: roomService,
+		telemetry:   ts,
+		done:        make(chan struct{}),
+	}
+}
+
+func (s *RoomScheduleService) Start() {
+	if s.store == nil {
+		return
+	}
+	go s.worker()
+}
+
+func (s *RoomScheduleService) Stop() {
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+}
+
+func (s *RoomScheduleService) worker() {
+	ticker := time.NewTicker(roomScheduleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.UpdateSchedules(context.Background(), now)
+		}
+	}
+}
+
+func (s *RoomScheduleService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/rooms/schedule/") {
+	case "create":
+		var req ScheduleRoomRequest
+		if err = decodeJSONRequest(r, &req, maxRoomScheduleRequest); err == nil {
+			res, err = s.ScheduleRoom(r.Context(), &req)
+		}
+	case "list":
+		res, err = s.ListRoomSchedules(r.Context())
+	case "delete":
+		var req DeleteRoomScheduleRequest
+		if err = decodeJSONRequest(r, &req, maxRoomScheduleRequest); err == nil {
+			res, err = s.DeleteRoomSchedule(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	body, err := json.Marshal(res)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+func (s *RoomScheduleService) ScheduleRoom(ctx context.Context, req *ScheduleRoomRequest) (*RoomScheduleInfo, error) {
+	AppendLogFields(ctx, "room", req.Name)
+	if err := EnsureCreatePermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrRoomScheduleNotSupported
+	}
+	if req.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrRoomScheduleInvalid)
+	}
+	if !s.limitConf.CheckRoomNameLength(req.Name) {
+		return nil, fmt.Errorf("%w: max length %d", ErrRoomNameExceedsLimits, s.limitConf.MaxRoomNameLength)
+	}
+	if !s.limitConf.CheckMetadataSize(req.Metadata) {
+		return nil, fmt.Errorf("%w: max size %d", ErrMetadataExceedsLimits, s.limitConf.MaxMetadataSize)
+	}
+
+	now := time.Now()
+	schedule := &RoomSchedule{
+		Request: &livekit.CreateRoomRequest{
+			Name:             req.Name,
+			RoomPreset:       req.RoomPreset,
+			MaxParticipants:  req.MaxParticipants,
+			Metadata:         req.Metadata,
+			EmptyTimeout:     req.EmptyTimeout,
+			DepartureTimeout: req.DepartureTimeout,
+		},
+		StartAt:     now,
+		GracePeriod: defaultRoomScheduleGracePeriod,
+		State:       RoomScheduleScheduled,
+	}
+	if req.StartTime != 0 {
+		schedule.StartAt = time.Unix(req.StartTime, 0)
+	}
+	if req.EndTime != 0 {
+		schedule.EndAt = time.Unix(req.EndTime, 0)
+		if !schedule.EndAt.After(schedule.StartAt) || !schedule.EndAt.After(now) {
+			return nil, fmt.Errorf("%w: end time must be after the start time and in the future", ErrRoomScheduleInvalid)
+		}
+	}
+	if req.GracePeriod != 0 {
+		schedule.GracePeriod = time.Duration(req.GracePeriod) * time.Second
+	}
+
+	if existing, err := s.store.LoadRoomSchedule(ctx, livekit.RoomName(req.Name)); err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, ErrRoomScheduleExists
+	}
+	if err := s.store.StoreRoomSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+	s.notify(ctx, EventRoomScheduled, roomForSchedule(schedule))
+
+	return roomScheduleInfo(schedule), nil
+}
+
+func (s *RoomScheduleService) ListRoomSchedules(ctx context.Context) (*ListRoomSchedulesResponse, error) {
+	if err := EnsureListPermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrRoomScheduleNotSupported
+	}
+
+	schedules, err := s.store.ListRoomSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &ListRoomSchedulesResponse{
+		Schedules: make([]*RoomScheduleInfo, 0, len(schedules)),
+	}
+	for _, schedule := range schedules {
+		res.Schedules = append(res.Schedules, roomScheduleInfo(schedule))
+	}
+	return res, nil
+}
+
+// DeleteRoomSchedule cancels a schedule, a room that was already opened keeps running
+func (s *RoomScheduleService) DeleteRoomSchedule(ctx context.Context, req *DeleteRoomScheduleRequest) (*RoomScheduleInfo, error) {
+	AppendLogFields(ctx, "room", req.Name)
+	if err := EnsureCreatePermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrRoomScheduleNotSupported
+	}
+
+	schedule, err := s.store.LoadRoomSchedule(ctx, livekit.RoomName(req.Name))
+	if err != nil {
+		return nil, err
+	} else if schedule == nil {
+		return nil, ErrRoomScheduleNotFound
+	}
+	if err = s.store.DeleteRoomSchedule(ctx, livekit.RoomName(req.Name)); err != nil {
+		return nil, err
+	}
+	s.notify(ctx, EventRoomScheduleDeleted, roomForSchedule(schedule))
+
+	return roomScheduleInfo(schedule), nil
+}
+
+// UpdateSchedules makes the transitions that are due at now
+func (s *RoomScheduleService) UpdateSchedules(ctx context.Context, now time.Time) {
+	schedules, err := s.store.ListRoomSchedules(ctx)
+	if err != nil {
+		logger.Warnw("could not list room schedules", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		switch schedule.State {
+		case RoomScheduleScheduled:
+			if !now.Before(schedule.StartAt) && s.transition(ctx, schedule, RoomScheduleOpen) {
+				s.openRoom(ctx, schedule, now)
+			}
+
+		case RoomScheduleOpen:
+			if !schedule.EndAt.IsZero() && !now.Before(schedule.EndAt) && s.transition(ctx, schedule, RoomScheduleEnding) {
+				room, _, err := s.roomService.roomStore.LoadRoom(ctx, livekit.RoomName(schedule.Request.Name), false)
+				if err != nil || room == nil {
+					room = roomForSchedule(schedule)
+				}
+				s.notify(ctx, EventRoomScheduleEnding, room)
+			}
+
+		case RoomScheduleEnding:
+			if !now.Before(schedule.closeAt()) && s.transition(ctx, schedule, RoomScheduleClosed) {
+				s.closeRoom(ctx, schedule)
+			}
+
+		case RoomScheduleClosed:
+			// the node closing the room failed to clean up
+			if now.Sub(schedule.closeAt()) > time.Minute {
+				_ = s.store.DeleteRoomSchedule(ctx, livekit.RoomName(schedule.Request.Name))
+			}
+		}
+	}
+}
+
+func (s *RoomScheduleService) transition(ctx context.Context, schedule *RoomSchedule, to RoomScheduleState) bool {
+	ok, err := s.store.UpdateRoomScheduleState(ctx, livekit.RoomName(schedule.Request.Name), schedule.State, to)
+	if err != nil {
+		logger.Warnw("could not update room schedule", err, "room", schedule.Request.Name, "state", to)
+		return false
+	}
+	return ok
+}
+
+func (s *RoomScheduleService) openRoom(ctx context.Context, schedule *RoomSchedule, now time.Time) {
+	req := utils.CloneProto(schedule.Request)
+	if !schedule.EndAt.IsZero() {
+		// keep the room around until it is closed by the schedule, even while it is empty
+		remaining := uint32(min(math.Ceil(schedule.closeAt().Sub(now).Seconds()), math.MaxUint32))
+		req.EmptyTimeout = max(req.EmptyTimeout, remaining)
+		req.DepartureTimeout = max(req.DepartureTimeout, remaining)
+	}
+
+	room, err := s.roomService.createRoom(ctx, req)
+	if err != nil {
+		logger.Warnw("could not open scheduled room", err, "room", req.Name)
+		// retry on the next pass
+		_, _ = s.store.UpdateRoomScheduleState(ctx, livekit.RoomName(req.Name), RoomScheduleOpen, RoomScheduleScheduled)
+		return
+	}
+	logger.Infow("opened scheduled room", "room", req.Name, "endAt", schedule.EndAt)
+	s.notify(ctx, EventRoomScheduleOpened, room)
+}
+
+func (s *RoomScheduleService) closeRoom(ctx context.Context, schedule *RoomSchedule) {
+	roomName := schedule.Request.Name
+	err := s.roomService.deleteRoom(ctx, &livekit.DeleteRoomRequest{Room: roomName})
+	if err != nil && !errors.Is(err, ErrRoomNotFound) {
+		logger.Warnw("could not close scheduled room", err, "room", roomName)
+		// retry on the next pass
+		_, _ = s.store.UpdateRoomScheduleState(ctx, livekit.RoomName(roomName), RoomScheduleClosed, RoomScheduleEnding)
+		return
+	}
+	if err = s.store.DeleteRoomSchedule(ctx, livekit.RoomName(roomName)); err != nil {
+		logger.Warnw("could not delete room schedule", err, "room", roomName)
+	}
+	logger.Infow("closed scheduled room", "room", roomName)
+	s.notify(ctx, EventRoomScheduleClosed, roomForSchedule(schedule))
+}
+
+func (s *RoomScheduleService) notify(ctx context.Context, event string, room *livekit.Room) {
+	if s.telemetry == nil {
+		return
+	}
+	s.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{
+		Event: event,
+		Room:  room,
+	})
+}
+
+func roomForSchedule(schedule *RoomSchedule) *livekit.Room {
+	return &livekit.Room{
+		Name:     schedule.Request.Name,
+		Metadata: schedule.Request.Metadata,
+	}
+}
+
+func roomScheduleInfo(schedule *RoomSchedule) *RoomScheduleInfo {
+	info := &RoomScheduleInfo{
+		Name:        schedule.Request.Name,
+		StartTime:   schedule.StartAt.Unix(),
+		GracePeriod: uint32(schedule.GracePeriod / time.Second),
+		State:       string(schedule.State),
+	}
+	if !schedule.EndAt.IsZero() {
+		info.EndTime = schedule.EndAt.Unix()
+	}
+	return info
+}
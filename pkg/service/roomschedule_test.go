@@ -0,0 +1,162 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/rpc/rpcfakes"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestRoomSchedule(t *testing.T) {
+	router := &routingfakes.FakeRouter{}
+	router.CreateRoomCalls(func(_ context.Context, req *livekit.CreateRoomRequest) (*livekit.Room, error) {
+		return &livekit.Room{Name: req.Name}, nil
+	})
+	roomClient := &rpcfakes.FakeTypedRoomClient{}
+	roomService, err := service.NewRoomService(
+		config.LimitConfig{},
+		config.APIConfig{ExecutionTimeout: 2},
+		router,
+		&servicefakes.FakeRoomAllocator{},
+		&servicefakes.FakeServiceStore{},
+		nil,
+		rpc.NewTopicFormatter(),
+		roomClient,
+		&rpcfakes.FakeTypedParticipantClient{},
+	)
+	require.NoError(t, err)
+
+	store := service.NewLocalStore()
+	ts := &telemetryfakes.FakeTelemetryService{}
+	svc := service.NewRoomScheduleService(config.LimitConfig{}, store, roomService, ts)
+
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomCreate: true, RoomList: true},
+	}, "")
+	lastEvent := func() string {
+		_, event := ts.NotifyEventArgsForCall(ts.NotifyEventCallCount() - 1)
+		return event.Event
+	}
+	state := func() service.RoomScheduleState {
+		schedule, err := store.LoadRoomSchedule(ctx, "standup")
+		require.NoError(t, err)
+		if schedule == nil {
+			return ""
+		}
+		return schedule.State
+	}
+
+	now := time.Now().Truncate(time.Second)
+	start := now.Add(time.Hour)
+	end := now.Add(2 * time.Hour)
+
+	t.Run("requires create permission", func(t *testing.T) {
+		noGrants := service.WithGrants(context.Background(), &auth.ClaimGrants{Video: &auth.VideoGrant{}}, "")
+		_, err := svc.ScheduleRoom(noGrants, &service.ScheduleRoomRequest{Name: "standup"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+	})
+
+	t.Run("validates times", func(t *testing.T) {
+		_, err := svc.ScheduleRoom(ctx, &service.ScheduleRoomRequest{Name: "standup", StartTime: end.Unix(), EndTime: start.Unix()})
+		require.ErrorIs(t, err, service.ErrRoomScheduleInvalid)
+	})
+
+	t.Run("opens and closes on schedule", func(t *testing.T) {
+		info, err := svc.ScheduleRoom(ctx, &service.ScheduleRoomRequest{
+			Name:        "standup",
+			StartTime:   start.Unix(),
+			EndTime:     end.Unix(),
+			GracePeriod: 60,
+		})
+		require.NoError(t, err)
+		require.Equal(t, string(service.RoomScheduleScheduled), info.State)
+		require.Equal(t, service.EventRoomScheduled, lastEvent())
+
+		_, err = svc.ScheduleRoom(ctx, &service.ScheduleRoomRequest{Name: "standup"})
+		require.ErrorIs(t, err, service.ErrRoomScheduleExists)
+
+		// joins are refused before the start time
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		ra, err := service.NewRoomAllocator(conf, &routingfakes.FakeRouter{}, store, nil, nil)
+		require.NoError(t, err)
+		require.ErrorIs(t, ra.ValidateCreateRoom(ctx, "standup"), service.ErrRoomNotStarted)
+
+		svc.UpdateSchedules(ctx, now)
+		require.Zero(t, router.CreateRoomCallCount())
+
+		svc.UpdateSchedules(ctx, start)
+		require.Equal(t, 1, router.CreateRoomCallCount())
+		_, req := router.CreateRoomArgsForCall(0)
+		// kept open while empty until the end of the grace period
+		require.GreaterOrEqual(t, req.EmptyTimeout, uint32(time.Hour.Seconds()+60))
+		require.Equal(t, service.RoomScheduleOpen, state())
+		require.Equal(t, service.EventRoomScheduleOpened, lastEvent())
+
+		svc.UpdateSchedules(ctx, end)
+		require.Equal(t, service.RoomScheduleEnding, state())
+		require.Equal(t, service.EventRoomScheduleEnding, lastEvent())
+
+		svc.UpdateSchedules(ctx, end.Add(30*time.Second))
+		require.Zero(t, roomClient.DeleteRoomCallCount())
+
+		svc.UpdateSchedules(ctx, end.Add(time.Minute))
+		require.Equal(t, 1, roomClient.DeleteRoomCallCount())
+		require.Empty(t, state())
+		require.Equal(t, service.EventRoomScheduleClosed, lastEvent())
+	})
+
+	t.Run("http api", func(t *testing.T) {
+		do := func(path string, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+			req = req.WithContext(ctx)
+			w := httptest.NewRecorder()
+			svc.ServeHTTP(w, req)
+			return w
+		}
+
+		w := do("/rooms/schedule/create", `{"name":"standup","start_time":`+strconv.FormatInt(start.Unix(), 10)+`}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = do("/rooms/schedule/list", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), `"name":"standup"`)
+
+		w = do("/rooms/schedule/delete", `{"name":"standup"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, service.EventRoomScheduleDeleted, lastEvent())
+
+		w = do("/rooms/schedule/delete", `{"name":"standup"}`)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -20,6 +20,7 @@ import (
 	"strconv"
 
 	"github.com/twitchtv/twirp"
+	"go.uber.org/atomic"
 
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
@@ -32,7 +33,7 @@ import (
 )
 
 type RoomService struct {
-	limitConf         config.LimitConfig
+	limitConf         atomic.Pointer[config.LimitConfig]
 	apiConf           config.APIConfig
 	router            routing.MessageRouter
 	roomAllocator     RoomAllocator
@@ -55,7 +56,6 @@ func NewRoomService(
 	participantClient rpc.TypedParticipantClient,
 ) (svc *RoomService, err error) {
 	svc = &RoomService{
-		limitConf:         limitConf,
 		apiConf:           apiConf,
 		router:            router,
 		roomAllocator:     roomAllocator,
@@ -65,9 +65,16 @@ func NewRoomService(
 		roomClient:        roomClient,
 		participantClient: participantClient,
 	}
+	svc.limitConf.Store(&limitConf)
 	return
 }
 
+func (s *RoomService) ReloadConfig(conf *config.Config) error {
+	limitConf := conf.Limit
+	s.limitConf.Store(&limitConf)
+	return nil
+}
+
 func (s *RoomService) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (*livekit.Room, error) {
 	AppendLogFields(ctx, "room", req.Name, "request", logger.Proto(redactCreateRoomRequest(req)))
 	if err := EnsureCreatePermission(ctx); err != nil {
@@ -76,10 +83,14 @@ func (s *RoomService) CreateRoom(ctx context.Context, req *livekit.CreateRoomReq
 		return nil, ErrEgressNotConnected
 	}
 
-	if !s.limitConf.CheckRoomNameLength(req.Name) {
-		return nil, fmt.Errorf("%w: max length %d", ErrRoomNameExceedsLimits, s.limitConf.MaxRoomNameLength)
+	if !s.limitConf.Load().CheckRoomNameLength(req.Name) {
+		return nil, fmt.Errorf("%w: max length %d", ErrRoomNameExceedsLimits, s.limitConf.Load().MaxRoomNameLength)
 	}
 
+	return s.createRoom(ctx, req)
+}
+
+func (s *RoomService) createRoom(ctx context.Context, req *livekit.CreateRoomRequest) (*livekit.Room, error) {
 	err := s.roomAllocator.SelectRoomNode(ctx, livekit.RoomName(req.Name), livekit.NodeID(req.NodeId))
 	if err != nil {
 		return nil, err
@@ -117,24 +128,30 @@ func (s *RoomService) DeleteRoom(ctx context.Context, req *livekit.DeleteRoomReq
 		return nil, twirpAuthError(err)
 	}
 
+	if err := s.deleteRoom(ctx, req); err != nil {
+		return nil, err
+	}
+	return &livekit.DeleteRoomResponse{}, nil
+}
+
+func (s *RoomService) deleteRoom(ctx context.Context, req *livekit.DeleteRoomRequest) error {
 	_, _, err := s.roomStore.LoadRoom(ctx, livekit.RoomName(req.Room), false)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// ensure at least one node is available to handle the request
 	_, err = s.router.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: req.Room})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	_, err = s.roomClient.DeleteRoom(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(req.Room)), req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	err = s.roomStore.DeleteRoom(ctx, livekit.RoomName(req.Room))
-	return &livekit.DeleteRoomResponse{}, err
+	return s.roomStore.DeleteRoom(ctx, livekit.RoomName(req.Room))
 }
 
 func (s *RoomService) ListParticipants(ctx context.Context, req *livekit.ListParticipantsRequest) (*livekit.ListParticipantsResponse, error) {
@@ -171,7 +188,9 @@ func (s *RoomService) GetParticipant(ctx context.Context, req *livekit.RoomParti
 func (s *RoomService) RemoveParticipant(ctx context.Context, req *livekit.RoomParticipantIdentity) (*livekit.RemoveParticipantResponse, error) {
 	AppendLogFields(ctx, "room", req.Room, "participant", req.Identity)
 
-	if err := EnsureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
+	if err := EnsureModerationPermission(ctx, s.roomStore, livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity), rtc.RoleActionRemoveOthers); err == ErrParticipantNotFound {
+		return nil, twirp.NotFoundError("participant not found")
+	} else if err != nil {
 		return nil, twirpAuthError(err)
 	}
 
@@ -184,7 +203,7 @@ func (s *RoomService) RemoveParticipant(ctx context.Context, req *livekit.RoomPa
 
 func (s *RoomService) MutePublishedTrack(ctx context.Context, req *livekit.MuteRoomTrackRequest) (*livekit.MuteRoomTrackResponse, error) {
 	AppendLogFields(ctx, "room", req.Room, "participant", req.Identity, "trackID", req.TrackSid, "muted", req.Muted)
-	if err := EnsureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
+	if err := EnsureModerationPermission(ctx, s.roomStore, livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity), rtc.RoleActionMuteOthers); err != nil {
 		return nil, twirpAuthError(err)
 	}
 
@@ -194,16 +213,33 @@ func (s *RoomService) MutePublishedTrack(ctx context.Context, req *livekit.MuteR
 func (s *RoomService) UpdateParticipant(ctx context.Context, req *livekit.UpdateParticipantRequest) (*livekit.ParticipantInfo, error) {
 	AppendLogFields(ctx, "room", req.Room, "participant", req.Identity)
 
-	if !s.limitConf.CheckParticipantNameLength(req.Name) {
-		return nil, twirp.InvalidArgumentError(ErrNameExceedsLimits.Error(), strconv.Itoa(s.limitConf.MaxParticipantNameLength))
+	if !s.limitConf.Load().CheckParticipantNameLength(req.Name) {
+		return nil, twirp.InvalidArgumentError(ErrNameExceedsLimits.Error(), strconv.Itoa(s.limitConf.Load().MaxParticipantNameLength))
+	}
+
+	if !s.limitConf.Load().CheckMetadataSize(req.Metadata) {
+		return nil, twirp.InvalidArgumentError(ErrMetadataExceedsLimits.Error(), strconv.Itoa(int(s.limitConf.Load().MaxMetadataSize)))
 	}
 
-	if !s.limitConf.CheckMetadataSize(req.Metadata) {
-		return nil, twirp.InvalidArgumentError(ErrMetadataExceedsLimits.Error(), strconv.Itoa(int(s.limitConf.MaxMetadataSize)))
+	if !s.limitConf.Load().CheckAttributesSize(req.Attributes) {
+		return nil, twirp.InvalidArgumentError(ErrAttributeExceedsLimits.Error(), strconv.Itoa(int(s.limitConf.Load().MaxAttributesSize)))
+	}
+
+	if err := rtc.ValidateDeviceAttributes(req.Attributes); err != nil {
+		return nil, twirp.InvalidArgumentError(err.Error(), "attributes")
+	}
+
+	if _, ok := req.Attributes[rtc.RoleAttribute]; ok {
+		return nil, twirp.InvalidArgumentError(rtc.ErrRoleAttributeNotAllowed.Error(), "attributes")
+	}
+	if _, ok := req.Attributes[rtc.RecordingConsentAttribute]; ok {
+		return nil, twirp.InvalidArgumentError(rtc.ErrConsentAttributeNotAllowed.Error(), "attributes")
 	}
 
-	if !s.limitConf.CheckAttributesSize(req.Attributes) {
-		return nil, twirp.InvalidArgumentError(ErrAttributeExceedsLimits.Error(), strconv.Itoa(int(s.limitConf.MaxAttributesSize)))
+	for _, source := range req.Permission.GetCanPublishSources() {
+		if _, ok := livekit.TrackSource_name[int32(source)]; !ok || source == livekit.TrackSource_UNKNOWN {
+			return nil, twirp.InvalidArgumentError(ErrPublishSourceInvalid.Error(), "permission")
+		}
 	}
 
 	if err := EnsureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
@@ -239,7 +275,7 @@ func (s *RoomService) SendData(ctx context.Context, req *livekit.SendDataRequest
 
 func (s *RoomService) UpdateRoomMetadata(ctx context.Context, req *livekit.UpdateRoomMetadataRequest) (*livekit.Room, error) {
 	AppendLogFields(ctx, "room", req.Room, "size", len(req.Metadata))
-	maxMetadataSize := int(s.limitConf.MaxMetadataSize)
+	maxMetadataSize := int(s.limitConf.Load().MaxMetadataSize)
 	if maxMetadataSize > 0 && len(req.Metadata) > maxMetadataSize {
 		return nil, twirp.InvalidArgumentError(ErrMetadataExceedsLimits.Error(), strconv.Itoa(maxMetadataSize))
 	}
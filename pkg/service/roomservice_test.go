@@ -28,6 +28,7 @@ import (
 
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/pkg/service"
 	"github.com/livekit/livekit-server/pkg/service/servicefakes"
 )
@@ -104,10 +105,77 @@ func TestMetaDataLimits(t *testing.T) {
 	}
 }
 
+func TestUpdateParticipantPublishSources(t *testing.T) {
+	svc := newTestRoomService(config.LimitConfig{})
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{Video: &auth.VideoGrant{}}, "")
+
+	_, err := svc.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
+		Room:     "testroom",
+		Identity: "123",
+		Permission: &livekit.ParticipantPermission{
+			CanPublish:        true,
+			CanPublishSources: []livekit.TrackSource{livekit.TrackSource_MICROPHONE, livekit.TrackSource_UNKNOWN},
+		},
+	})
+	terr, ok := err.(twirp.Error)
+	require.True(t, ok)
+	require.Equal(t, twirp.InvalidArgument, terr.Code())
+
+	// mic allowed, screen share denied
+	_, err = svc.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
+		Room:     "testroom",
+		Identity: "123",
+		Permission: &livekit.ParticipantPermission{
+			CanPublish:        true,
+			CanPublishSources: []livekit.TrackSource{livekit.TrackSource_MICROPHONE, livekit.TrackSource_CAMERA},
+		},
+	})
+	terr, ok = err.(twirp.Error)
+	require.True(t, ok)
+	require.NotEqual(t, twirp.InvalidArgument, terr.Code())
+}
+
+func TestRemoveParticipantModeration(t *testing.T) {
+	svc := newTestRoomService(config.LimitConfig{})
+	svc.store.LoadParticipantCalls(func(_ context.Context, _ livekit.RoomName, identity livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error) {
+		switch identity {
+		case "host":
+			return &livekit.ParticipantInfo{Identity: "host", Attributes: map[string]string{rtc.RoleAttribute: string(rtc.ParticipantRoleHost)}}, nil
+		case "viewer":
+			return &livekit.ParticipantInfo{Identity: "viewer", Attributes: map[string]string{rtc.RoleAttribute: string(rtc.ParticipantRoleViewer)}}, nil
+		}
+		return nil, service.ErrParticipantNotFound
+	})
+	participantCtx := func(identity string) context.Context {
+		return service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Identity: identity,
+			Video:    &auth.VideoGrant{RoomJoin: true, Room: "testroom"},
+		}, "")
+	}
+	removeCode := func(ctx context.Context, identity string) twirp.ErrorCode {
+		_, err := svc.RemoveParticipant(ctx, &livekit.RoomParticipantIdentity{Room: "testroom", Identity: identity})
+		terr, ok := err.(twirp.Error)
+		require.True(t, ok)
+		return terr.Code()
+	}
+
+	// a participant without moderation rights is refused whether the target is in the room or not
+	require.Equal(t, twirp.Unauthenticated, removeCode(participantCtx("viewer"), "host"))
+	require.Equal(t, twirp.Unauthenticated, removeCode(participantCtx("viewer"), "joining"))
+
+	require.Equal(t, twirp.NotFound, removeCode(participantCtx("host"), "joining"))
+	adminCtx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomAdmin: true, Room: "testroom"},
+	}, "")
+	require.Equal(t, twirp.NotFound, removeCode(adminCtx, "joining"))
+	require.Zero(t, svc.participantClient.RemoveParticipantCallCount())
+}
+
 func newTestRoomService(limitConf config.LimitConfig) *TestRoomService {
 	router := &routingfakes.FakeRouter{}
 	allocator := &servicefakes.FakeRoomAllocator{}
 	store := &servicefakes.FakeServiceStore{}
+	participantClient := &rpcfakes.FakeTypedParticipantClient{}
 	svc, err := service.NewRoomService(
 		limitConf,
 		config.APIConfig{ExecutionTimeout: 2},
@@ -117,22 +185,24 @@ func newTestRoomService(limitConf config.LimitConfig) *TestRoomService {
 		nil,
 		rpc.NewTopicFormatter(),
 		&rpcfakes.FakeTypedRoomClient{},
-		&rpcfakes.FakeTypedParticipantClient{},
+		participantClient,
 	)
 	if err != nil {
 		panic(err)
 	}
 	return &TestRoomService{
-		RoomService: *svc,
-		router:      router,
-		allocator:   allocator,
-		store:       store,
+		RoomService:       svc,
+		router:            router,
+		allocator:         allocator,
+		store:             store,
+		participantClient: participantClient,
 	}
 }
 
 type TestRoomService struct {
-	service.RoomService
-	router    *routingfakes.FakeRouter
-	allocator *servicefakes.FakeRoomAllocator
-	store     *servicefakes.FakeServiceStore
+	*service.RoomService
+	router            *routingfakes.FakeRouter
+	allocator         *servicefakes.FakeRoomAllocator
+	store             *servicefakes.FakeServiceStore
+	participantClient *rpcfakes.FakeTypedParticipantClient
 }
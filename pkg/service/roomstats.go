@@ -0,0 +1,250 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	roomStatsRPCService = "RoomStats"
+	roomStatsRPC        = "RoomStats"
+
+	defaultRoomStatsInterval = time.Second
+	minRoomStatsInterval     = 500 * time.Millisecond
+	maxRoomStatsInterval     = 5 * time.Second
+)
+
+// RoomStatsClient reaches the node hosting a room to aggregate its statistics. Stats are carried as JSON in
+// the payload of a user data packet.
+type RoomStatsClient interface {
+	RoomStats(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error)
+}
+
+type RoomStatsServerImpl interface {
+	RoomStats(ctx context.Context, roomName livekit.RoomName) (*rtc.RoomStats, error)
+}
+
+type roomStatsClient struct {
+	client *client.RPCClient
+}
+
+func NewRoomStatsClient(params rpc.ClientParams) (RoomStatsClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: roomStatsRPCService,
+		ID:   rand.NewClientID(),
+	}
+	sd.RegisterMethod(roomStatsRPC, false, false, true, true)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &roomStatsClient{client: rpcClient}, nil
+}
+
+func (c *roomStatsClient) RoomStats(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return client.RequestSingle[*livekit.DataPacket](ctx, c.client, roomStatsRPC, []string{string(room)}, req, opts...)
+}
+
+// roomStatsServer aggregates the statistics of a room hosted on this node
+type roomStatsServer struct {
+	svc      RoomStatsServerImpl
+	roomName livekit.RoomName
+	rpc      *server.RPCServer
+}
+
+func newRoomStatsServer(svc RoomStatsServerImpl, roomName livekit.RoomName, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *roomStatsServer {
+	sd := &info.ServiceDefinition{
+		Name: roomStatsRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	sd.RegisterMethod(roomStatsRPC, false, false, true, true)
+	return &roomStatsServer{
+		svc:      svc,
+		roomName: roomName,
+		rpc:      s,
+	}
+}
+
+func (s *roomStatsServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	return server.RegisterHandler(s.rpc, roomStatsRPC, []string{string(room)}, s.handle, nil)
+}
+
+func (s *roomStatsServer) handle(ctx context.Context, _ *livekit.DataPacket) (*livekit.DataPacket, error) {
+	stats, err := s.svc.RoomStats(ctx, s.roomName)
+	if err != nil {
+		return nil, err
+	}
+	return jsonDataPacket(stats)
+}
+
+func (s *roomStatsServer) Kill() {
+	s.rpc.Close(true)
+}
+
+// ---------------------------------------------
+
+// StreamRoomStatsRequest is read from the query of GET /room_stats/stream
+type StreamRoomStatsRequest struct {
+	Room string
+	// time between two stats, one second by default
+	Interval time.Duration
+}
+
+// RoomStatsService serves the HTTP API streaming the statistics of a live room to dashboards, as server-sent
+// events. Stats are aggregated by the node hosting the room at every interval, until the room closes or the
+// client goes away. Calls require roomAdmin.
+type RoomStatsService struct {
+	roomStore      ServiceStore
+	topicFormatter rpc.TopicFormatter
+	client         RoomStatsClient
+}
+
+func NewRoomStatsService(
+	roomStore ServiceStore,
+	topicFormatter rpc.TopicFormatter,
+	client RoomStatsClient,
+) *RoomStatsService {
+	return &RoomStatsService{
+		roomStore:      roomStore,
+		topicFormatter: topicFormatter,
+		client:         client,
+	}
+}
+
+func (s *RoomStatsService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	if r.URL.Path != "/room_stats/stream" {
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	req := &StreamRoomStatsRequest{Room: r.URL.Query().Get("room")}
+	if v := r.URL.Query().Get("interval_ms"); v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil {
+			handleError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		req.Interval = time.Duration(ms) * time.Millisecond
+	}
+
+	rc := http.NewResponseController(w)
+	started := false
+	err := s.StreamRoomStats(r.Context(), req, func(stats *rtc.RoomStats) error {
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		data, err := json.Marshal(stats)
+		if err != nil {
+			return err
+		}
+		if _, err = fmt.Fprintf(w, "event: stats\ndata: %s\n\n", data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	})
+	switch {
+	case !started && err != nil:
+		handleError(w, r, apiErrorStatus(err), err)
+	case started && errors.Is(err, ErrRoomNotFound):
+		// the room closed while streaming
+		_, _ = fmt.Fprint(w, "event: end\ndata: {}\n\n")
+		_ = rc.Flush()
+	}
+}
+
+// StreamRoomStats sends the stats of the room to send at every interval, until ctx is done, the room closes or
+// send fails
+func (s *RoomStatsService) StreamRoomStats(ctx context.Context, req *StreamRoomStatsRequest, send func(*rtc.RoomStats) error) error {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName)
+	if err := EnsureAdminPermission(ctx, roomName); err != nil {
+		return err
+	}
+	interval := req.Interval
+	if interval == 0 {
+		interval = defaultRoomStatsInterval
+	}
+	if interval < minRoomStatsInterval || interval > maxRoomStatsInterval {
+		return psrpc.NewErrorf(psrpc.InvalidArgument, "interval must be between %v and %v", minRoomStatsInterval, maxRoomStatsInterval)
+	}
+	// stats are aggregated by the node hosting the room
+	if _, _, err := s.roomStore.LoadRoom(ctx, roomName, false); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		stats, err := s.roomStats(ctx, roomName)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = send(stats); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *RoomStatsService) roomStats(ctx context.Context, roomName livekit.RoomName) (*rtc.RoomStats, error) {
+	res, err := s.client.RoomStats(ctx, s.topicFormatter.RoomTopic(ctx, roomName), &livekit.DataPacket{})
+	if err != nil {
+		if _, _, lerr := s.roomStore.LoadRoom(ctx, roomName, false); errors.Is(lerr, ErrRoomNotFound) {
+			// the room closed, no node answers for it anymore
+			return nil, ErrRoomNotFound
+		}
+		return nil, err
+	}
+	var stats rtc.RoomStats
+	if err = json.Unmarshal(res.GetUser().GetPayload(), &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+// testRoomStatsClient answers with the stats it was given, or fails once they are unset
+type testRoomStatsClient struct {
+	stats *rtc.RoomStats
+}
+
+func (c *testRoomStatsClient) RoomStats(_ context.Context, _ rpc.RoomTopic, _ *livekit.DataPacket, _ ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	if c.stats == nil {
+		return nil, errors.New("no response")
+	}
+	payload, err := json.Marshal(c.stats)
+	if err != nil {
+		return nil, err
+	}
+	return &livekit.DataPacket{Value: &livekit.DataPacket_User{User: &livekit.UserPacket{Payload: payload}}}, nil
+}
+
+func TestStreamRoomStats(t *testing.T) {
+	store := service.NewLocalStore()
+	client := &testRoomStatsClient{stats: &rtc.RoomStats{Room: "class", NumParticipants: 2}}
+	svc := service.NewRoomStatsService(store, rpc.NewTopicFormatter(), client)
+
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomAdmin: true, Room: "class"},
+	}, "")
+	require.NoError(t, store.StoreRoom(ctx, &livekit.Room{Name: "class"}, nil))
+	req := &service.StreamRoomStatsRequest{Room: "class", Interval: 500 * time.Millisecond}
+
+	t.Run("requires admin of the room", func(t *testing.T) {
+		other := service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomAdmin: true, Room: "other"},
+		}, "")
+		err := svc.StreamRoomStats(other, req, func(*rtc.RoomStats) error { return nil })
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+	})
+
+	t.Run("streams until the client goes away", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		var received []*rtc.RoomStats
+		err := svc.StreamRoomStats(ctx, req, func(stats *rtc.RoomStats) error {
+			received = append(received, stats)
+			if len(received) == 2 {
+				cancel()
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, received, 2)
+		require.Equal(t, 2, received[1].NumParticipants)
+	})
+
+	t.Run("ends when the room closes", func(t *testing.T) {
+		var received int
+		err := svc.StreamRoomStats(ctx, req, func(*rtc.RoomStats) error {
+			received++
+			client.stats = nil
+			return store.DeleteRoom(ctx, "class")
+		})
+		require.ErrorIs(t, err, service.ErrRoomNotFound)
+		require.Equal(t, 1, received)
+	})
+}
@@ -0,0 +1,361 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const maxRoomTemplateRequest = 64 * 1024
+
+// RoomTemplate holds the settings shared by rooms of the same kind. Rooms are created from a template by
+// setting its name as the room_preset of CreateRoom, settings given in the request take precedence.
+// Presets of the config file are looked up first.
+type RoomTemplate struct {
+	// Config holds the room settings and agent dispatches, its Name is the name of the template
+	Config        *livekit.RoomConfiguration
+	EnabledCodecs []*livekit.Codec
+	Metadata      string
+}
+
+func (t *RoomTemplate) clone() *RoomTemplate {
+	clone := &RoomTemplate{
+		Config:   utils.CloneProto(t.Config),
+		Metadata: t.Metadata,
+	}
+	for _, codec := range t.EnabledCodecs {
+		clone.EnabledCodecs = append(clone.EnabledCodecs, utils.CloneProto(codec))
+	}
+	return clone
+}
+
+func (t *RoomTemplate) GetEnabledCodecs() []*livekit.Codec {
+	if t == nil {
+		return nil
+	}
+	return t.EnabledCodecs
+}
+
+// applyRoomConfiguration fills the settings missing from a create room request with the ones of a preset
+func applyRoomConfiguration(req *livekit.CreateRoomRequest, conf *livekit.RoomConfiguration) *livekit.CreateRoomRequest {
+	clone := utils.CloneProto(req)
+
+	// Request overwrites conf
+	if clone.EmptyTimeout == 0 {
+		clone.EmptyTimeout = conf.EmptyTimeout
+	}
+	if clone.DepartureTimeout == 0 {
+		clone.DepartureTimeout = conf.DepartureTimeout
+	}
+	if clone.MaxParticipants == 0 {
+		clone.MaxParticipants = conf.MaxParticipants
+	}
+	if clone.Egress == nil {
+		clone.Egress = utils.CloneProto(conf.Egress)
+	}
+	if clone.Agents == nil {
+		clone.Agents = make([]*livekit.RoomAgentDispatch, 0, len(conf.Agents))
+		for _, agent := range conf.Agents {
+			clone.Agents = append(clone.Agents, utils.CloneProto(agent))
+		}
+	}
+	if clone.MinPlayoutDelay == 0 {
+		clone.MinPlayoutDelay = conf.MinPlayoutDelay
+	}
+	if clone.MaxPlayoutDelay == 0 {
+		clone.MaxPlayoutDelay = conf.MaxPlayoutDelay
+	}
+	if !clone.SyncStreams {
+		clone.SyncStreams = conf.SyncStreams
+	}
+
+	return clone
+}
+
+// ---------------------------------------------
+
+// RoomTemplateInfo is the JSON form of a template, the body of POST /room_templates/{create,update} and what
+// the API returns. egress and agents use the JSON mapping of RoomEgress and RoomAgentDispatch.
+type RoomTemplateInfo struct {
+	Name             string            `json:"name"`
+	EnabledCodecs    []*livekit.Codec  `json:"enabled_codecs,omitempty"`
+	MaxParticipants  uint32            `json:"max_participants,omitempty"`
+	EmptyTimeout     uint32            `json:"empty_timeout,omitempty"`
+	DepartureTimeout uint32            `json:"departure_timeout,omitempty"`
+	MinPlayoutDelay  uint32            `json:"min_playout_delay,omitempty"`
+	MaxPlayoutDelay  uint32            `json:"max_playout_delay,omitempty"`
+	SyncStreams      bool              `json:"sync_streams,omitempty"`
+	Metadata         string            `json:"metadata,omitempty"`
+	Egress           json.RawMessage   `json:"egress,omitempty"`
+	Agents           []json.RawMessage `json:"agents,omitempty"`
+}
+
+// RoomTemplateRequest is the JSON body of POST /room_templates/{get,delete}
+type RoomTemplateRequest struct {
+	Name string `json:"name"`
+}
+
+type ListRoomTemplatesResponse struct {
+	Templates []*RoomTemplateInfo `json:"templates"`
+}
+
+func (i *RoomTemplateInfo) toRoomTemplate() (*RoomTemplate, error) {
+	t := &RoomTemplate{
+		Config: &livekit.RoomConfiguration{
+			Name:             i.Name,
+			EmptyTimeout:     i.EmptyTimeout,
+			DepartureTimeout: i.DepartureTimeout,
+			MaxParticipants:  i.MaxParticipants,
+			MinPlayoutDelay:  i.MinPlayoutDelay,
+			MaxPlayoutDelay:  i.MaxPlayoutDelay,
+			SyncStreams:      i.SyncStreams,
+		},
+		EnabledCodecs: i.EnabledCodecs,
+		Metadata:      i.Metadata,
+	}
+	if len(i.Egress) != 0 {
+		t.Config.Egress = &livekit.RoomEgress{}
+		if err := protojson.Unmarshal(i.Egress, t.Config.Egress); err != nil {
+			return nil, fmt.Errorf("%w: egress: %v", ErrRoomTemplateInvalid, err)
+		}
+	}
+	for _, data := range i.Agents {
+		agent := &livekit.RoomAgentDispatch{}
+		if err := protojson.Unmarshal(data, agent); err != nil {
+			return nil, fmt.Errorf("%w: agents: %v", ErrRoomTemplateInvalid, err)
+		}
+		t.Config.Agents = append(t.Config.Agents, agent)
+	}
+	return t, nil
+}
+
+func roomTemplateInfo(t *RoomTemplate) (*RoomTemplateInfo, error) {
+	info := &RoomTemplateInfo{
+		Name:             t.Config.Name,
+		EnabledCodecs:    t.EnabledCodecs,
+		MaxParticipants:  t.Config.MaxParticipants,
+		EmptyTimeout:     t.Config.EmptyTimeout,
+		DepartureTimeout: t.Config.DepartureTimeout,
+		MinPlayoutDelay:  t.Config.MinPlayoutDelay,
+		MaxPlayoutDelay:  t.Config.MaxPlayoutDelay,
+		SyncStreams:      t.Config.SyncStreams,
+		Metadata:         t.Metadata,
+	}
+	var err error
+	if t.Config.Egress != nil {
+		if info.Egress, err = protojson.Marshal(t.Config.Egress); err != nil {
+			return nil, err
+		}
+	}
+	for _, agent := range t.Config.Agents {
+		data, err := protojson.Marshal(agent)
+		if err != nil {
+			return nil, err
+		}
+		info.Agents = append(info.Agents, data)
+	}
+	return info, nil
+}
+
+// RoomTemplateService serves the HTTP API managing room templates. Changing templates requires roomCreate,
+// reading them roomList. Templates only apply to rooms created after they change.
+type RoomTemplateService struct {
+	roomConf  config.RoomConfig
+	limitConf config.LimitConfig
+	store     RoomTemplateStore
+}
+
+func NewRoomTemplateService(roomConf config.RoomConfig, limitConf config.LimitConfig, store RoomTemplateStore) *RoomTemplateService {
+	return &RoomTemplateService{
+		roomConf:  roomConf,
+		limitConf: limitConf,
+		store:     store,
+	}
+}
+
+func (s *RoomTemplateService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/room_templates/") {
+	case "create":
+		var req RoomTemplateInfo
+		if err = decodeJSONRequest(r, &req, maxRoomTemplateRequest); err == nil {
+			res, err = s.CreateRoomTemplate(r.Context(), &req)
+		}
+	case "update":
+		var req RoomTemplateInfo
+		if err = decodeJSONRequest(r, &req, maxRoomTemplateRequest); err == nil {
+			res, err = s.UpdateRoomTemplate(r.Context(), &req)
+		}
+	case "get":
+		var req RoomTemplateRequest
+		if err = decodeJSONRequest(r, &req, maxRoomTemplateRequest); err == nil {
+			res, err = s.GetRoomTemplate(r.Context(), &req)
+		}
+	case "list":
+		res, err = s.ListRoomTemplates(r.Context())
+	case "delete":
+		var req RoomTemplateRequest
+		if err = decodeJSONRequest(r, &req, maxRoomTemplateRequest); err == nil {
+			err = s.DeleteRoomTemplate(r.Context(), &req)
+			res = struct{}{}
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *RoomTemplateService) CreateRoomTemplate(ctx context.Context, req *RoomTemplateInfo) (*RoomTemplateInfo, error) {
+	return s.storeRoomTemplate(ctx, req, false)
+}
+
+// UpdateRoomTemplate replaces every setting of an existing template
+func (s *RoomTemplateService) UpdateRoomTemplate(ctx context.Context, req *RoomTemplateInfo) (*RoomTemplateInfo, error) {
+	return s.storeRoomTemplate(ctx, req, true)
+}
+
+func (s *RoomTemplateService) storeRoomTemplate(ctx context.Context, req *RoomTemplateInfo, update bool) (*RoomTemplateInfo, error) {
+	AppendLogFields(ctx, "template", req.Name)
+	if err := EnsureCreatePermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrRoomTemplateNotSupported
+	}
+	template, err := s.validate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.store.LoadRoomTemplate(ctx, req.Name)
+	switch {
+	case err == nil && !update:
+		return nil, ErrRoomTemplateExists
+	case err == ErrRoomTemplateNotFound && update:
+		return nil, ErrRoomTemplateNotFound
+	case err != nil && err != ErrRoomTemplateNotFound:
+		return nil, err
+	}
+
+	if err = s.store.StoreRoomTemplate(ctx, template); err != nil {
+		return nil, err
+	}
+	return roomTemplateInfo(template)
+}
+
+func (s *RoomTemplateService) validate(req *RoomTemplateInfo) (*RoomTemplate, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrRoomTemplateInvalid)
+	}
+	if _, ok := s.roomConf.RoomConfigurations[req.Name]; ok {
+		return nil, fmt.Errorf("%w: %s is a preset of the server configuration", ErrRoomTemplateInvalid, req.Name)
+	}
+	if !s.limitConf.CheckRoomNameLength(req.Name) {
+		return nil, fmt.Errorf("%w: max length %d", ErrRoomNameExceedsLimits, s.limitConf.MaxRoomNameLength)
+	}
+	if !s.limitConf.CheckMetadataSize(req.Metadata) {
+		return nil, fmt.Errorf("%w: max size %d", ErrMetadataExceedsLimits, s.limitConf.MaxMetadataSize)
+	}
+	if req.MaxPlayoutDelay != 0 && req.MinPlayoutDelay > req.MaxPlayoutDelay {
+		return nil, fmt.Errorf("%w: min playout delay exceeds max playout delay", ErrRoomTemplateInvalid)
+	}
+	if slices.ContainsFunc(req.EnabledCodecs, func(codec *livekit.Codec) bool { return codec.GetMime() == "" }) {
+		return nil, fmt.Errorf("%w: codecs require a mime type", ErrRoomTemplateInvalid)
+	}
+	return req.toRoomTemplate()
+}
+
+func (s *RoomTemplateService) GetRoomTemplate(ctx context.Context, req *RoomTemplateRequest) (*RoomTemplateInfo, error) {
+	AppendLogFields(ctx, "template", req.Name)
+	if err := EnsureListPermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrRoomTemplateNotSupported
+	}
+
+	template, err := s.store.LoadRoomTemplate(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return roomTemplateInfo(template)
+}
+
+func (s *RoomTemplateService) ListRoomTemplates(ctx context.Context) (*ListRoomTemplatesResponse, error) {
+	if err := EnsureListPermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrRoomTemplateNotSupported
+	}
+
+	templates, err := s.store.ListRoomTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &ListRoomTemplatesResponse{Templates: make([]*RoomTemplateInfo, 0, len(templates))}
+	for _, template := range templates {
+		info, err := roomTemplateInfo(template)
+		if err != nil {
+			return nil, err
+		}
+		res.Templates = append(res.Templates, info)
+	}
+	slices.SortFunc(res.Templates, func(a, b *RoomTemplateInfo) int { return strings.Compare(a.Name, b.Name) })
+	return res, nil
+}
+
+func (s *RoomTemplateService) DeleteRoomTemplate(ctx context.Context, req *RoomTemplateRequest) error {
+	AppendLogFields(ctx, "template", req.Name)
+	if err := EnsureCreatePermission(ctx); err != nil {
+		return err
+	}
+	if s.store == nil {
+		return ErrRoomTemplateNotSupported
+	}
+
+	if _, err := s.store.LoadRoomTemplate(ctx, req.Name); err != nil {
+		return err
+	}
+	return s.store.DeleteRoomTemplate(ctx, req.Name)
+}
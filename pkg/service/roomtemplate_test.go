@@ -0,0 +1,131 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestRoomTemplates(t *testing.T) {
+	conf, err := config.NewConfig("", true, nil, nil)
+	require.NoError(t, err)
+	conf.Room.RoomConfigurations = map[string]*livekit.RoomConfiguration{
+		"preset": {Name: "preset", MaxParticipants: 5},
+	}
+
+	store := service.NewLocalStore()
+	svc := service.NewRoomTemplateService(conf.Room, conf.Limit, store)
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomCreate: true, RoomList: true},
+	}, "")
+
+	t.Run("requires permissions", func(t *testing.T) {
+		_, err := svc.CreateRoomTemplate(context.Background(), &service.RoomTemplateInfo{Name: "webinar"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+
+		listCtx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomList: true},
+		}, "")
+		_, err = svc.CreateRoomTemplate(listCtx, &service.RoomTemplateInfo{Name: "webinar"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+	})
+
+	t.Run("validates templates", func(t *testing.T) {
+		_, err := svc.CreateRoomTemplate(ctx, &service.RoomTemplateInfo{})
+		require.ErrorIs(t, err, service.ErrRoomTemplateInvalid)
+
+		_, err = svc.CreateRoomTemplate(ctx, &service.RoomTemplateInfo{Name: "preset"})
+		require.ErrorIs(t, err, service.ErrRoomTemplateInvalid)
+
+		_, err = svc.CreateRoomTemplate(ctx, &service.RoomTemplateInfo{Name: "webinar", Egress: json.RawMessage(`{"room": 1}`)})
+		require.ErrorIs(t, err, service.ErrRoomTemplateInvalid)
+
+		_, err = svc.UpdateRoomTemplate(ctx, &service.RoomTemplateInfo{Name: "webinar"})
+		require.ErrorIs(t, err, service.ErrRoomTemplateNotFound)
+	})
+
+	t.Run("crud", func(t *testing.T) {
+		info, err := svc.CreateRoomTemplate(ctx, &service.RoomTemplateInfo{
+			Name:            "webinar",
+			EnabledCodecs:   []*livekit.Codec{{Mime: "video/VP8"}},
+			MaxParticipants: 100,
+			EmptyTimeout:    60,
+			Metadata:        "template",
+			Agents:          []json.RawMessage{json.RawMessage(`{"agentName": "moderator"}`)},
+		})
+		require.NoError(t, err)
+		require.Equal(t, uint32(100), info.MaxParticipants)
+		require.Len(t, info.Agents, 1)
+
+		_, err = svc.CreateRoomTemplate(ctx, &service.RoomTemplateInfo{Name: "webinar"})
+		require.ErrorIs(t, err, service.ErrRoomTemplateExists)
+
+		info, err = svc.UpdateRoomTemplate(ctx, &service.RoomTemplateInfo{Name: "webinar", MaxParticipants: 50, Metadata: "template"})
+		require.NoError(t, err)
+		require.Equal(t, uint32(50), info.MaxParticipants)
+		require.Empty(t, info.EnabledCodecs)
+
+		info, err = svc.GetRoomTemplate(ctx, &service.RoomTemplateRequest{Name: "webinar"})
+		require.NoError(t, err)
+		require.Equal(t, uint32(50), info.MaxParticipants)
+
+		list, err := svc.ListRoomTemplates(ctx)
+		require.NoError(t, err)
+		require.Len(t, list.Templates, 1)
+
+		require.NoError(t, svc.DeleteRoomTemplate(ctx, &service.RoomTemplateRequest{Name: "webinar"}))
+		require.ErrorIs(t, svc.DeleteRoomTemplate(ctx, &service.RoomTemplateRequest{Name: "webinar"}), service.ErrRoomTemplateNotFound)
+	})
+
+	t.Run("rooms are created from templates", func(t *testing.T) {
+		_, err := svc.CreateRoomTemplate(ctx, &service.RoomTemplateInfo{
+			Name:            "classroom",
+			EnabledCodecs:   []*livekit.Codec{{Mime: "video/VP8"}},
+			MaxParticipants: 30,
+			EmptyTimeout:    60,
+			Metadata:        "template",
+		})
+		require.NoError(t, err)
+
+		ra, err := service.NewRoomAllocator(conf, &routingfakes.FakeRouter{}, store, nil, nil)
+		require.NoError(t, err)
+
+		room, _, _, err := ra.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: "math", RoomPreset: "classroom", EmptyTimeout: 120}, true)
+		require.NoError(t, err)
+		require.Equal(t, uint32(30), room.MaxParticipants)
+		require.Equal(t, uint32(120), room.EmptyTimeout)
+		require.Equal(t, "template", room.Metadata)
+		require.Len(t, room.EnabledCodecs, 1)
+		require.Equal(t, "video/VP8", room.EnabledCodecs[0].Mime)
+
+		room, _, _, err = ra.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: "config", RoomPreset: "preset"}, true)
+		require.NoError(t, err)
+		require.Equal(t, uint32(5), room.MaxParticipants)
+
+		_, _, _, err = ra.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: "unknown", RoomPreset: "unknown"}, true)
+		require.Error(t, err)
+	})
+}
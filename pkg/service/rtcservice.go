@@ -16,6 +16,7 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -27,6 +28,7 @@ import (
 
 	"github.com/gorilla/websocket"
 	"github.com/ua-parser/uap-go/uaparser"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/atomic"
 	"golang.org/x/exp/maps"
 
@@ -49,12 +51,19 @@ type RTCService struct {
 	currentNode   routing.LocalNode
 	config        *config.Config
 	isDev         bool
-	limits        config.LimitConfig
+	limits        atomic.Pointer[config.LimitConfig]
+	tokenPolicy   atomic.Pointer[TokenConfigPolicy]
 	parser        *uaparser.Parser
 	telemetry     telemetry.TelemetryService
+	policy        *PolicyWebhook
+	locator       *clientLocator
+	relayClient   RoomRelayClient
+	sdkBlocklist  *rtc.SDKBlocklist
 
 	mu          sync.Mutex
 	connections map[*websocket.Conn]struct{}
+	// connections being established count towards limit.max_signal_connections as well
+	numConnections atomic.Int32
 }
 
 func NewRTCService(
@@ -64,6 +73,8 @@ func NewRTCService(
 	router routing.MessageRouter,
 	currentNode routing.LocalNode,
 	telemetry telemetry.TelemetryService,
+	policy *PolicyWebhook,
+	relayClient RoomRelayClient,
 ) *RTCService {
 	s := &RTCService{
 		router:        router,
@@ -72,11 +83,16 @@ func NewRTCService(
 		currentNode:   currentNode,
 		config:        conf,
 		isDev:         conf.Development,
-		limits:        conf.Limit,
 		parser:        uaparser.NewFromSaved(),
 		telemetry:     telemetry,
+		policy:        policy,
+		locator:       newClientLocator(conf.NodeSelector.LatencyAware),
+		relayClient:   relayClient,
+		sdkBlocklist:  rtc.NewSDKBlocklist(conf.SDKBlocklist),
 		connections:   map[*websocket.Conn]struct{}{},
 	}
+	s.limits.Store(&conf.Limit)
+	s.tokenPolicy.Store(NewTokenConfigPolicy(conf.Room.TokenConfigPolicy))
 
 	s.upgrader = websocket.Upgrader{
 		EnableCompression: true,
@@ -91,10 +107,17 @@ func NewRTCService(
 	return s
 }
 
+func (s *RTCService) ReloadConfig(conf *config.Config) error {
+	limits := conf.Limit
+	s.limits.Store(&limits)
+	s.tokenPolicy.Store(NewTokenConfigPolicy(conf.Room.TokenConfigPolicy))
+	return nil
+}
+
 func (s *RTCService) Validate(w http.ResponseWriter, r *http.Request) {
 	_, _, code, err := s.validate(r)
 	if err != nil {
-		handleError(w, r, code, err)
+		handleValidateError(w, r, code, err)
 		return
 	}
 	_, _ = w.Write([]byte("success"))
@@ -117,9 +140,12 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 	if claims.Identity == "" {
 		return "", pi, http.StatusBadRequest, ErrIdentityEmpty
 	}
-	if limit := s.config.Limit.MaxParticipantIdentityLength; limit > 0 && len(claims.Identity) > limit {
+	if limit := s.limits.Load().MaxParticipantIdentityLength; limit > 0 && len(claims.Identity) > limit {
 		return "", pi, http.StatusBadRequest, fmt.Errorf("%w: max length %d", ErrParticipantIdentityExceedsLimits, limit)
 	}
+	if err := rtc.ValidateDeviceAttributes(claims.Attributes); err != nil {
+		return "", pi, http.StatusBadRequest, err
+	}
 
 	roomName := livekit.RoomName(r.FormValue("room"))
 	reconnectParam := r.FormValue("reconnect")
@@ -134,7 +160,7 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 	if onlyName != "" {
 		roomName = onlyName
 	}
-	if limit := s.config.Limit.MaxRoomNameLength; limit > 0 && len(roomName) > limit {
+	if limit := s.limits.Load().MaxRoomNameLength; limit > 0 && len(roomName) > limit {
 		return "", pi, http.StatusBadRequest, fmt.Errorf("%w: max length %d", ErrRoomNameExceedsLimits, limit)
 	}
 
@@ -154,6 +180,8 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 	if err != nil {
 		if errors.Is(err, ErrRoomNotFound) {
 			return "", pi, http.StatusNotFound, err
+		} else if errors.Is(err, ErrRoomNotStarted) {
+			return "", pi, http.StatusForbidden, err
 		} else {
 			return "", pi, http.StatusInternalServerError, err
 		}
@@ -163,7 +191,7 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 	if router, ok := s.router.(routing.Router); ok {
 		region = router.GetRegion()
 		if foundNode, err := router.GetNodeForRoom(r.Context(), roomName); err == nil {
-			if selector.LimitsReached(s.limits, foundNode.Stats) {
+			if selector.LimitsReached(*s.limits.Load(), foundNode.Stats) {
 				return "", pi, http.StatusServiceUnavailable, rtc.ErrLimitExceeded
 			}
 		}
@@ -173,7 +201,18 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 		Name:       string(roomName),
 		RoomPreset: claims.RoomPreset,
 	}
-	SetRoomConfiguration(createRequest, claims.GetRoomConfiguration())
+	roomConfig, violations := s.tokenPolicy.Load().Check(claims.GetRoomConfiguration())
+	if len(violations) != 0 {
+		if s.tokenPolicy.Load().Reject() {
+			return "", pi, http.StatusForbidden, fmt.Errorf("%w: %s", ErrTokenRoomConfigNotAllowed, violations[0])
+		}
+		utils.GetLogger(r.Context()).Warnw("stripping disallowed room configuration of token", nil,
+			"room", roomName,
+			"participant", claims.Identity,
+			"violations", violations,
+		)
+	}
+	SetRoomConfiguration(createRequest, roomConfig)
 
 	pi = routing.ParticipantInit{
 		Reconnect:       boolValue(reconnectParam),
@@ -204,9 +243,28 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 		pi.DisableICELite = boolValue(disableICELite)
 	}
 
+	if upgrade := s.sdkBlocklist.Match(roomName, pi.Client); upgrade != nil && upgrade.Refused() {
+		return "", pi, http.StatusUpgradeRequired, upgrade
+	}
+
 	return roomName, pi, http.StatusOK, nil
 }
 
+// handleValidateError responds to clients refused for their SDK version with a JSON body they can
+// surface, other errors are plain text
+func handleValidateError(w http.ResponseWriter, r *http.Request, code int, err error) {
+	var upgrade *rtc.SDKUpgrade
+	if !errors.As(err, &upgrade) {
+		handleError(w, r, code, err)
+		return
+	}
+
+	utils.GetLogger(r.Context()).Infow("refusing blocklisted sdk", "sdk", upgrade.SDK, "version", upgrade.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(upgrade)
+}
+
 func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// reject non websocket requests
 	if !websocket.IsWebSocketUpgrade(r) {
@@ -214,12 +272,30 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	numConnections := s.numConnections.Inc()
+	defer s.numConnections.Dec()
+	if limit := s.limits.Load().MaxSignalConnections; limit > 0 && int(numConnections) > limit {
+		prometheus.RecordSignalConnectionRejected()
+		handleError(w, r, http.StatusServiceUnavailable, ErrSignalConnectionLimit)
+		return
+	}
+
 	roomName, pi, code, err := s.validate(r)
 	if err != nil {
-		handleError(w, r, code, err)
+		handleValidateError(w, r, code, err)
 		return
 	}
 
+	// checked here rather than in validate so that /rtc/validate isn't reported as a join,
+	// resuming sessions were already checked when they first joined
+	if s.policy != nil && !pi.Reconnect {
+		if err = s.policy.CheckParticipantJoin(r.Context(), roomName, pi.Grants); err != nil {
+			handleError(w, r, http.StatusForbidden, err)
+			return
+		}
+		pi.Name = livekit.ParticipantName(pi.Grants.Name)
+	}
+
 	loggerFields := []any{
 		"participant", pi.Identity,
 		"pID", pi.ID,
@@ -228,13 +304,23 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	pLogger := utils.GetLogger(r.Context()).WithValues(loggerFields...)
 
+	// where the client is lets the node selector place new rooms near it
+	location := s.locator.Locate(r)
+
 	// give it a few attempts to start session
 	var cr connectionResult
 	var initialResponse *livekit.SignalResponse
 	for attempt := 0; attempt < s.config.SignalRelay.ConnectAttempts; attempt++ {
 		connectionTimeout := 3 * time.Second * time.Duration(attempt+1)
-		ctx := utils.ContextWithAttempt(r.Context(), attempt)
+		ctx := withClientLocation(utils.ContextWithAttempt(r.Context(), attempt), location)
+		ctx, span := telemetry.StartSpan(ctx, "RTCService.startConnection",
+			attribute.String("room", string(roomName)),
+			attribute.String("participant", string(pi.Identity)),
+			attribute.Bool("reconnect", pi.Reconnect),
+			attribute.Int("attempt", attempt),
+		)
 		cr, initialResponse, err = s.startConnection(ctx, roomName, pi, connectionTimeout)
+		telemetry.EndSpan(span, err)
 		if err == nil || errors.Is(err, context.Canceled) {
 			break
 		}
@@ -294,15 +380,18 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.mu.Lock()
 	s.connections[conn] = struct{}{}
 	s.mu.Unlock()
+	prometheus.AddSignalConnection(1)
 
 	defer func() {
 		s.mu.Lock()
 		delete(s.connections, conn)
 		s.mu.Unlock()
+		prometheus.AddSignalConnection(-1)
 	}()
 
 	// websocket established
 	sigConn := NewWSSignalConnection(conn)
+	sigConn.SetWriteTimeout(s.limits.Load().SignalWriteTimeout)
 	count, err := sigConn.WriteResponse(initialResponse)
 	if err != nil {
 		pLogger.Warnw("could not write initial response", err)
@@ -321,10 +410,11 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// handle responses
 	go func() {
+		closeCode, closeText := websocket.CloseNormalClosure, ""
 		defer func() {
 			// when the source is terminated, this means Participant.Close had been called and RTC connection is done
 			// we would terminate the signal connection as well
-			closeMsg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			closeMsg := websocket.FormatCloseMessage(closeCode, closeText)
 			_ = conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
 			_ = conn.Close()
 		}()
@@ -363,7 +453,13 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				}
 
 				if count, err := sigConn.WriteResponse(res); err != nil {
-					pLogger.Warnw("error writing to websocket", err)
+					if errors.Is(err, routing.ErrSignalSlowConsumer) {
+						pLogger.Warnw("closing signal connection of slow consumer", err, "connID", cr.ConnectionID)
+						prometheus.RecordSignalSlowConsumer(prometheus.SignalSlowConsumerWrite)
+						closeCode, closeText = websocket.ClosePolicyViolation, "slow consumer"
+					} else {
+						pLogger.Warnw("error writing to websocket", err)
+					}
 					return
 				} else {
 					signalStats.AddBytes(uint64(count), true)
@@ -536,8 +632,12 @@ func (s *RTCService) startConnection(
 		return cr, nil, err
 	}
 
-	// this needs to be started first *before* using router functions on this node
-	cr.StartParticipantSignalResults, err = s.router.StartParticipantSignal(ctx, roomName, pi)
+	if nodeID := s.relayNode(ctx, roomName, pi); nodeID != "" {
+		cr.StartParticipantSignalResults, err = s.router.StartParticipantSignalWithNodeID(ctx, roomName, pi, nodeID)
+	} else {
+		// this needs to be started first *before* using router functions on this node
+		cr.StartParticipantSignalResults, err = s.router.StartParticipantSignal(ctx, roomName, pi)
+	}
 	if err != nil {
 		return cr, nil, err
 	}
@@ -556,6 +656,24 @@ func (s *RTCService) startConnection(
 	return cr, initialResponse, nil
 }
 
+// relayNode returns the relay node serving a participant without publish permission, when the node
+// hosting the room is full
+func (s *RTCService) relayNode(ctx context.Context, roomName livekit.RoomName, pi routing.ParticipantInit) livekit.NodeID {
+	if !s.config.Relay.Enabled || s.relayClient == nil {
+		return ""
+	}
+	if video := pi.Grants.Video; video.GetCanPublish() || video.GetCanPublishData() {
+		return ""
+	}
+
+	nodeID, err := RouteRelayParticipant(ctx, s.relayClient, roomName)
+	if err != nil {
+		utils.GetLogger(ctx).Debugw("could not route participant to relay node", "error", err, "room", roomName, "participant", pi.Identity)
+		return ""
+	}
+	return nodeID
+}
+
 func readInitialResponse(source routing.MessageSource, timeout time.Duration) (*livekit.SignalResponse, error) {
 	responseTimer := time.NewTimer(timeout)
 	defer responseTimer.Stop()
@@ -0,0 +1,605 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+const (
+	// EventRTMPTargetActive is sent when a target of an RTMP stream starts receiving, or receives again after
+	// a reconnection
+	EventRTMPTargetActive = "rtmp_target_active"
+	// EventRTMPTargetReconnecting is sent when a target fails and is added to its egress again
+	EventRTMPTargetReconnecting = "rtmp_target_reconnecting"
+	// EventRTMPTargetFailed is sent when a target fails and is not reconnected anymore
+	EventRTMPTargetFailed = "rtmp_target_failed"
+	// EventRTMPStreamEnded is sent when the egress of an RTMP stream ends
+	EventRTMPStreamEnded = "rtmp_stream_ended"
+
+	rtmpStreamStart         = "start"
+	rtmpStreamAddTargets    = "add_targets"
+	rtmpStreamRemoveTargets = "remove_targets"
+	rtmpStreamStop          = "stop"
+	rtmpStreamList          = "list"
+
+	maxRTMPStreamRequest    = 64 * 1024
+	maxRTMPReconnectDelay   = time.Minute
+	rtmpStreamUpdateTimeout = 10 * time.Second
+)
+
+type RTMPTargetStatus string
+
+const (
+	// target was added to the egress and did not start receiving yet
+	RTMPTargetConnecting RTMPTargetStatus = "connecting"
+	RTMPTargetActive     RTMPTargetStatus = "active"
+	// target failed and will be added to the egress again
+	RTMPTargetReconnecting RTMPTargetStatus = "reconnecting"
+	// target failed more than the reconnections allowed, or could not be added again
+	RTMPTargetFailed RTMPTargetStatus = "failed"
+	// egress ended
+	RTMPTargetEnded RTMPTargetStatus = "ended"
+)
+
+// RTMPTarget is a destination of an RTMP stream. The URL includes the stream key, it is kept in the store and
+// redacted in responses.
+type RTMPTarget struct {
+	URL    string           `json:"url"`
+	Status RTMPTargetStatus `json:"status"`
+	Error  string           `json:"error,omitempty"`
+	// reconnections since the target was last active
+	Reconnects int `json:"reconnects"`
+	// failed stream results of the target the reconnections were made for
+	Failures  int   `json:"failures"`
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+// RTMPStream is a room composite egress, or a participant egress when Identity is set, pushing to targets
+// managed by the server
+type RTMPStream struct {
+	EgressID  string        `json:"egress_id"`
+	Room      string        `json:"room"`
+	Identity  string        `json:"identity,omitempty"`
+	Targets   []*RTMPTarget `json:"targets"`
+	StartedAt int64         `json:"started_at"`
+}
+
+func (s *RTMPStream) clone() *RTMPStream {
+	c := *s
+	c.Targets = make([]*RTMPTarget, 0, len(s.Targets))
+	for _, t := range s.Targets {
+		target := *t
+		c.Targets = append(c.Targets, &target)
+	}
+	return &c
+}
+
+func (s *RTMPStream) target(u string) *RTMPTarget {
+	for _, t := range s.Targets {
+		if t.URL == u {
+			return t
+		}
+	}
+	return nil
+}
+
+// StartRTMPStreamRequest is the JSON body of POST /rtmp_streams/start. The composite of the room is streamed,
+// or the tracks of a participant when identity is set.
+type StartRTMPStreamRequest struct {
+	Room        string   `json:"room"`
+	Identity    string   `json:"identity,omitempty"`
+	Layout      string   `json:"layout,omitempty"`
+	AudioOnly   bool     `json:"audio_only,omitempty"`
+	VideoOnly   bool     `json:"video_only,omitempty"`
+	ScreenShare bool     `json:"screen_share,omitempty"`
+	URLs        []string `json:"urls"`
+}
+
+// RTMPStreamRequest is the JSON body of the other /rtmp_streams/ calls, list only uses room
+type RTMPStreamRequest struct {
+	Room     string   `json:"room,omitempty"`
+	EgressID string   `json:"egress_id,omitempty"`
+	URLs     []string `json:"urls,omitempty"`
+}
+
+type RTMPTargetInfo struct {
+	URL        string           `json:"url"`
+	Status     RTMPTargetStatus `json:"status"`
+	Error      string           `json:"error,omitempty"`
+	Reconnects int              `json:"reconnects"`
+	UpdatedAt  int64            `json:"updated_at"`
+}
+
+type RTMPStreamInfo struct {
+	EgressID  string            `json:"egress_id"`
+	Room      string            `json:"room"`
+	Identity  string            `json:"identity,omitempty"`
+	Targets   []*RTMPTargetInfo `json:"targets"`
+	StartedAt int64             `json:"started_at"`
+}
+
+// RTMPStreamInfoFromStream describes a stream with the stream keys of its targets redacted
+func RTMPStreamInfoFromStream(stream *RTMPStream) *RTMPStreamInfo {
+	info := &RTMPStreamInfo{
+		EgressID:  stream.EgressID,
+		Room:      stream.Room,
+		Identity:  stream.Identity,
+		Targets:   make([]*RTMPTargetInfo, 0, len(stream.Targets)),
+		StartedAt: stream.StartedAt,
+	}
+	for _, t := range stream.Targets {
+		redacted, _ := utils.RedactStreamKey(t.URL)
+		info.Targets = append(info.Targets, &RTMPTargetInfo{
+			URL:        redacted,
+			Status:     t.Status,
+			Error:      t.Error,
+			Reconnects: t.Reconnects,
+			UpdatedAt:  t.UpdatedAt,
+		})
+	}
+	return info
+}
+
+// RTMPStreamService serves the HTTP API pushing a room, or a participant, to several RTMP(S) targets with a
+// single egress. Targets are added to and removed from the running egress, and their health follows the
+// stream results of the egress updates received by any node. A target failing while its egress runs is added
+// again after a delay, doubled for each consecutive failure, until it is active again or the reconnections
+// allowed are exhausted. Calls require roomRecord.
+type RTMPStreamService struct {
+	conf          config.RTMPStreamConfig
+	store         RTMPStreamStore
+	egressService *EgressService
+	telemetry     telemetry.TelemetryService
+}
+
+func NewRTMPStreamService(
+	conf *config.Config,
+	store RTMPStreamStore,
+	egressService *EgressService,
+	ioService *IOInfoService,
+	ts telemetry.TelemetryService,
+) *RTMPStreamService {
+	s := &RTMPStreamService{
+		conf:          conf.RTMPStreams,
+		store:         store,
+		egressService: egressService,
+		telemetry:     ts,
+	}
+	if store != nil && ioService != nil {
+		ioService.OnEgressUpdated(s.egressUpdated)
+	}
+	return s
+}
+
+func (s *RTMPStreamService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/rtmp_streams/") {
+	case rtmpStreamStart:
+		var req StartRTMPStreamRequest
+		if err = decodeJSONRequest(r, &req, maxRTMPStreamRequest); err == nil {
+			res, err = s.StartRTMPStream(r.Context(), &req)
+		}
+	case rtmpStreamAddTargets:
+		var req RTMPStreamRequest
+		if err = decodeJSONRequest(r, &req, maxRTMPStreamRequest); err == nil {
+			res, err = s.AddRTMPTargets(r.Context(), &req)
+		}
+	case rtmpStreamRemoveTargets:
+		var req RTMPStreamRequest
+		if err = decodeJSONRequest(r, &req, maxRTMPStreamRequest); err == nil {
+			res, err = s.RemoveRTMPTargets(r.Context(), &req)
+		}
+	case rtmpStreamStop:
+		var req RTMPStreamRequest
+		if err = decodeJSONRequest(r, &req, maxRTMPStreamRequest); err == nil {
+			res, err = s.StopRTMPStream(r.Context(), &req)
+		}
+	case rtmpStreamList:
+		var req RTMPStreamRequest
+		if err = decodeJSONRequest(r, &req, maxRTMPStreamRequest); err == nil {
+			res, err = s.ListRTMPStreams(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *RTMPStreamService) StartRTMPStream(ctx context.Context, req *StartRTMPStreamRequest) (*RTMPStreamInfo, error) {
+	AppendLogFields(ctx, "room", req.Room, "identity", req.Identity, "targets", len(req.URLs))
+	if err := EnsureRecordPermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrRTMPStreamsUnavailable
+	}
+	if req.Room == "" {
+		return nil, fmt.Errorf("%w: room is required", ErrRTMPStreamInvalid)
+	}
+	urls, err := s.validateURLs(req.URLs, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	output := []*livekit.StreamOutput{{
+		Protocol: livekit.StreamProtocol_RTMP,
+		Urls:     urls,
+	}}
+	var ei *livekit.EgressInfo
+	if req.Identity != "" {
+		ei, err = s.egressService.StartParticipantEgress(ctx, &livekit.ParticipantEgressRequest{
+			RoomName:      req.Room,
+			Identity:      req.Identity,
+			ScreenShare:   req.ScreenShare,
+			StreamOutputs: output,
+		})
+	} else {
+		ei, err = s.egressService.StartRoomCompositeEgress(ctx, &livekit.RoomCompositeEgressRequest{
+			RoomName:      req.Room,
+			Layout:        req.Layout,
+			AudioOnly:     req.AudioOnly,
+			VideoOnly:     req.VideoOnly,
+			StreamOutputs: output,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	AppendLogFields(ctx, "egressID", ei.EgressId)
+
+	now := time.Now().Unix()
+	stream := &RTMPStream{
+		EgressID:  ei.EgressId,
+		Room:      req.Room,
+		Identity:  req.Identity,
+		StartedAt: now,
+	}
+	for _, u := range urls {
+		stream.Targets = append(stream.Targets, &RTMPTarget{URL: u, Status: RTMPTargetConnecting, UpdatedAt: now})
+	}
+	if err = s.store.StoreRTMPStream(ctx, stream); err != nil {
+		// an egress which cannot be managed is not left running
+		_, _ = s.egressService.StopEgress(ctx, &livekit.StopEgressRequest{EgressId: ei.EgressId})
+		return nil, err
+	}
+	return RTMPStreamInfoFromStream(stream), nil
+}
+
+func (s *RTMPStreamService) AddRTMPTargets(ctx context.Context, req *RTMPStreamRequest) (*RTMPStreamInfo, error) {
+	stream, err := s.loadStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	urls, err := s.validateURLs(req.URLs, len(stream.Targets))
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range urls {
+		if stream.target(u) != nil {
+			return nil, fmt.Errorf("%w: target already added", ErrRTMPStreamInvalid)
+		}
+	}
+
+	if _, err = s.egressService.UpdateStream(ctx, &livekit.UpdateStreamRequest{
+		EgressId:      stream.EgressID,
+		AddOutputUrls: urls,
+	}); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	for _, u := range urls {
+		stream.Targets = append(stream.Targets, &RTMPTarget{URL: u, Status: RTMPTargetConnecting, UpdatedAt: now})
+	}
+	if err = s.store.StoreRTMPStream(ctx, stream); err != nil {
+		return nil, err
+	}
+	return RTMPStreamInfoFromStream(stream), nil
+}
+
+// RemoveRTMPTargets removes targets from a stream, targets are given with their full URL
+func (s *RTMPStreamService) RemoveRTMPTargets(ctx context.Context, req *RTMPStreamRequest) (*RTMPStreamInfo, error) {
+	stream, err := s.loadStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.URLs) == 0 {
+		return nil, fmt.Errorf("%w: urls are required", ErrRTMPStreamInvalid)
+	}
+
+	// failed and reconnecting targets were already dropped by the egress
+	var running []string
+	for _, u := range req.URLs {
+		t := stream.target(u)
+		if t == nil {
+			return nil, fmt.Errorf("%w: unknown target", ErrRTMPStreamInvalid)
+		}
+		if t.Status == RTMPTargetConnecting || t.Status == RTMPTargetActive {
+			running = append(running, u)
+		}
+	}
+	if len(running) != 0 {
+		if _, err = s.egressService.UpdateStream(ctx, &livekit.UpdateStreamRequest{
+			EgressId:         stream.EgressID,
+			RemoveOutputUrls: running,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	stream.Targets = slices.DeleteFunc(stream.Targets, func(t *RTMPTarget) bool {
+		return slices.Contains(req.URLs, t.URL)
+	})
+	if err = s.store.StoreRTMPStream(ctx, stream); err != nil {
+		return nil, err
+	}
+	return RTMPStreamInfoFromStream(stream), nil
+}
+
+// StopRTMPStream stops the egress of a stream, the stream is removed when the egress ends
+func (s *RTMPStreamService) StopRTMPStream(ctx context.Context, req *RTMPStreamRequest) (*RTMPStreamInfo, error) {
+	stream, err := s.loadStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = s.egressService.StopEgress(ctx, &livekit.StopEgressRequest{EgressId: stream.EgressID}); err != nil {
+		return nil, err
+	}
+	return RTMPStreamInfoFromStream(stream), nil
+}
+
+func (s *RTMPStreamService) ListRTMPStreams(ctx context.Context, req *RTMPStreamRequest) ([]*RTMPStreamInfo, error) {
+	AppendLogFields(ctx, "room", req.Room)
+	if err := EnsureRecordPermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrRTMPStreamsUnavailable
+	}
+	streams, err := s.store.ListRTMPStreams(ctx, livekit.RoomName(req.Room))
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]*RTMPStreamInfo, 0, len(streams))
+	for _, stream := range streams {
+		infos = append(infos, RTMPStreamInfoFromStream(stream))
+	}
+	return infos, nil
+}
+
+func (s *RTMPStreamService) loadStream(ctx context.Context, req *RTMPStreamRequest) (*RTMPStream, error) {
+	AppendLogFields(ctx, "egressID", req.EgressID)
+	if err := EnsureRecordPermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrRTMPStreamsUnavailable
+	}
+	if req.EgressID == "" {
+		return nil, fmt.Errorf("%w: egress_id is required", ErrRTMPStreamInvalid)
+	}
+	return s.store.LoadRTMPStream(ctx, req.EgressID)
+}
+
+// validateURLs checks the targets added to a stream with the given number of targets
+func (s *RTMPStreamService) validateURLs(urls []string, targets int) ([]string, error) {
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("%w: urls are required", ErrRTMPStreamInvalid)
+	}
+	var valid []string
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "rtmp" && u.Scheme != "rtmps") || u.Host == "" {
+			return nil, fmt.Errorf("%w: targets must be rtmp:// or rtmps:// urls", ErrRTMPStreamInvalid)
+		}
+		if !slices.Contains(valid, raw) {
+			valid = append(valid, raw)
+		}
+	}
+	if s.conf.MaxTargets > 0 && targets+len(valid) > s.conf.MaxTargets {
+		return nil, fmt.Errorf("%w: streams have at most %d targets", ErrRTMPStreamInvalid, s.conf.MaxTargets)
+	}
+	return valid, nil
+}
+
+// egressUpdated follows the health of the targets of a stream from an update of its egress
+func (s *RTMPStreamService) egressUpdated(info *livekit.EgressInfo) {
+	ctx, cancel := context.WithTimeout(context.Background(), rtmpStreamUpdateTimeout)
+	defer cancel()
+
+	stream, err := s.store.LoadRTMPStream(ctx, info.EgressId)
+	if err != nil {
+		if !errors.Is(err, ErrRTMPStreamNotFound) {
+			logger.Warnw("could not load rtmp stream", err, "egressID", info.EgressId)
+		}
+		return
+	}
+
+	ended := false
+	switch info.Status {
+	case livekit.EgressStatus_EGRESS_COMPLETE,
+		livekit.EgressStatus_EGRESS_FAILED,
+		livekit.EgressStatus_EGRESS_ABORTED,
+		livekit.EgressStatus_EGRESS_LIMIT_REACHED:
+		ended = true
+	}
+
+	events, reconnects := updateRTMPTargets(stream, info, s.conf.MaxReconnects, ended)
+	if ended {
+		err = s.store.DeleteRTMPStream(ctx, stream.EgressID)
+		events = append(events, &livekit.WebhookEvent{Event: EventRTMPStreamEnded, EgressInfo: info})
+	} else {
+		err = s.store.StoreRTMPStream(ctx, stream)
+	}
+	if err != nil {
+		logger.Warnw("could not update rtmp stream", err, "egressID", info.EgressId)
+	}
+
+	for _, event := range events {
+		s.telemetry.NotifyEvent(ctx, event)
+	}
+	for _, t := range reconnects {
+		delay := rtmpReconnectDelay(s.conf.ReconnectDelay, t.Reconnects)
+		logger.Infow("reconnecting rtmp target", "egressID", stream.EgressID, "reconnects", t.Reconnects, "delay", delay, "error", t.Error)
+		egressID, u := stream.EgressID, t.URL
+		time.AfterFunc(delay, func() {
+			s.reconnect(egressID, u)
+		})
+	}
+}
+
+// reconnect adds a failed target to its egress again, unless it was removed or its egress ended meanwhile
+func (s *RTMPStreamService) reconnect(egressID string, u string) {
+	ctx, cancel := context.WithTimeout(context.Background(), rtmpStreamUpdateTimeout)
+	defer cancel()
+
+	stream, err := s.store.LoadRTMPStream(ctx, egressID)
+	if err != nil {
+		return
+	}
+	t := stream.target(u)
+	if t == nil || t.Status != RTMPTargetReconnecting || s.egressService.client == nil {
+		return
+	}
+
+	info, err := s.egressService.client.UpdateStream(ctx, egressID, &livekit.UpdateStreamRequest{
+		EgressId:      egressID,
+		AddOutputUrls: []string{u},
+	})
+	if err == nil {
+		return
+	}
+
+	logger.Warnw("could not reconnect rtmp target", err, "egressID", egressID)
+	t.Status = RTMPTargetFailed
+	t.Error = err.Error()
+	t.UpdatedAt = time.Now().Unix()
+	if err = s.store.StoreRTMPStream(ctx, stream); err != nil {
+		logger.Warnw("could not update rtmp stream", err, "egressID", egressID)
+	}
+	if info == nil {
+		info = &livekit.EgressInfo{EgressId: egressID, RoomName: stream.Room}
+	}
+	redacted, _ := utils.RedactStreamKey(u)
+	s.telemetry.NotifyEvent(ctx, rtmpTargetEvent(EventRTMPTargetFailed, info, &livekit.StreamInfo{
+		Url:    redacted,
+		Status: livekit.StreamInfo_FAILED,
+		Error:  t.Error,
+	}))
+}
+
+// updateRTMPTargets updates the targets of a stream from the stream results of its egress. It returns the
+// webhooks of the targets whose state changed, and the targets to reconnect.
+func updateRTMPTargets(stream *RTMPStream, info *livekit.EgressInfo, maxReconnects int, ended bool) ([]*livekit.WebhookEvent, []*RTMPTarget) {
+	var (
+		events     []*livekit.WebhookEvent
+		reconnects []*RTMPTarget
+	)
+	now := time.Now().Unix()
+	for _, t := range stream.Targets {
+		redacted, _ := utils.RedactStreamKey(t.URL)
+		var (
+			latest   *livekit.StreamInfo
+			failures int
+		)
+		// egresses add a result each time a target is added
+		for _, si := range info.StreamResults {
+			if si.Url != t.URL && si.Url != redacted {
+				continue
+			}
+			latest = si
+			if si.Status == livekit.StreamInfo_FAILED {
+				failures++
+			}
+		}
+		if latest == nil {
+			if ended && t.Status != RTMPTargetFailed {
+				t.Status = RTMPTargetEnded
+				t.UpdatedAt = now
+			}
+			continue
+		}
+
+		newFailure := failures > t.Failures
+		t.Failures = failures
+		switch {
+		case latest.Status == livekit.StreamInfo_FAILED && newFailure:
+			t.Error = latest.Error
+			t.UpdatedAt = now
+			if !ended && t.Reconnects < maxReconnects {
+				t.Reconnects++
+				t.Status = RTMPTargetReconnecting
+				reconnects = append(reconnects, t)
+				events = append(events, rtmpTargetEvent(EventRTMPTargetReconnecting, info, latest))
+			} else {
+				t.Status = RTMPTargetFailed
+				events = append(events, rtmpTargetEvent(EventRTMPTargetFailed, info, latest))
+			}
+		case latest.Status == livekit.StreamInfo_ACTIVE && !ended && t.Status != RTMPTargetActive:
+			t.Status = RTMPTargetActive
+			t.Error = ""
+			t.Reconnects = 0
+			t.UpdatedAt = now
+			events = append(events, rtmpTargetEvent(EventRTMPTargetActive, info, latest))
+		case ended && t.Status != RTMPTargetFailed:
+			t.Status = RTMPTargetEnded
+			t.UpdatedAt = now
+		}
+	}
+	return events, reconnects
+}
+
+// rtmpTargetEvent describes a target with the egress of its stream, with the result of the target only
+func rtmpTargetEvent(event string, info *livekit.EgressInfo, result *livekit.StreamInfo) *livekit.WebhookEvent {
+	ei := utils.CloneProto(info)
+	ei.StreamResults = []*livekit.StreamInfo{result}
+	return &livekit.WebhookEvent{Event: event, EgressInfo: ei}
+}
+
+func rtmpReconnectDelay(delay time.Duration, reconnects int) time.Duration {
+	for i := 1; i < reconnects && delay < maxRTMPReconnectDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRTMPReconnectDelay)
+}
@@ -0,0 +1,149 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestRTMPStreams(t *testing.T) {
+	store := service.NewLocalStore()
+	ts := &telemetryfakes.FakeTelemetryService{}
+	io, err := service.NewIOInfoService(nil, &servicefakes.FakeEgressStore{}, nil, nil, ts, &config.SIPConfig{}, nil, nil, nil, nil)
+	require.NoError(t, err)
+	conf := &config.Config{RTMPStreams: config.RTMPStreamConfig{
+		MaxTargets:    2,
+		MaxReconnects: 1,
+		// reconnections are not made during the test
+		ReconnectDelay: time.Hour,
+	}}
+	svc := service.NewRTMPStreamService(conf, store, nil, io, ts)
+
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomRecord: true},
+	}, "")
+
+	t.Run("requires record permission", func(t *testing.T) {
+		admin := service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomAdmin: true, Room: "studio"},
+		}, "")
+		_, err := svc.ListRTMPStreams(admin, &service.RTMPStreamRequest{Room: "studio"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+	})
+
+	t.Run("validates targets", func(t *testing.T) {
+		for _, urls := range [][]string{
+			nil,
+			{"https://example.com/live/key"},
+			{"rtmp:///live/key"},
+			{"rtmp://a.example.com/live/1", "rtmp://b.example.com/live/2", "rtmp://c.example.com/live/3"},
+		} {
+			_, err := svc.StartRTMPStream(ctx, &service.StartRTMPStreamRequest{Room: "studio", URLs: urls})
+			require.ErrorIs(t, err, service.ErrRTMPStreamInvalid, urls)
+		}
+	})
+
+	youtube := "rtmp://a.rtmp.youtube.com/live2/abcd-efgh-ijkl"
+	twitch := "rtmps://live.twitch.tv/app/live_123456789"
+	redactedYoutube, _ := utils.RedactStreamKey(youtube)
+	redactedTwitch, _ := utils.RedactStreamKey(twitch)
+	require.NoError(t, store.StoreRTMPStream(ctx, &service.RTMPStream{
+		EgressID: "EG_1",
+		Room:     "studio",
+		Targets: []*service.RTMPTarget{
+			{URL: youtube, Status: service.RTMPTargetConnecting},
+			{URL: twitch, Status: service.RTMPTargetConnecting},
+		},
+	}))
+	update := func(status livekit.EgressStatus, results ...*livekit.StreamInfo) {
+		_, err := io.UpdateEgress(context.Background(), &livekit.EgressInfo{
+			EgressId:      "EG_1",
+			RoomName:      "studio",
+			Status:        status,
+			StreamResults: results,
+		})
+		require.NoError(t, err)
+	}
+	lastEvent := func() *livekit.WebhookEvent {
+		_, event := ts.NotifyEventArgsForCall(ts.NotifyEventCallCount() - 1)
+		return event
+	}
+
+	t.Run("failed targets are reconnected", func(t *testing.T) {
+		update(livekit.EgressStatus_EGRESS_ACTIVE,
+			&livekit.StreamInfo{Url: redactedYoutube, Status: livekit.StreamInfo_ACTIVE},
+			&livekit.StreamInfo{Url: redactedTwitch, Status: livekit.StreamInfo_FAILED, Error: "connection refused"},
+		)
+		require.Equal(t, 2, ts.NotifyEventCallCount())
+		_, active := ts.NotifyEventArgsForCall(0)
+		require.Equal(t, service.EventRTMPTargetActive, active.Event)
+		require.Equal(t, redactedYoutube, active.EgressInfo.StreamResults[0].Url)
+		reconnecting := lastEvent()
+		require.Equal(t, service.EventRTMPTargetReconnecting, reconnecting.Event)
+		require.Len(t, reconnecting.EgressInfo.StreamResults, 1)
+
+		streams, err := svc.ListRTMPStreams(ctx, &service.RTMPStreamRequest{Room: "studio"})
+		require.NoError(t, err)
+		require.Len(t, streams, 1)
+		targets := streams[0].Targets
+		require.Equal(t, redactedYoutube, targets[0].URL)
+		require.Equal(t, service.RTMPTargetActive, targets[0].Status)
+		require.Equal(t, service.RTMPTargetReconnecting, targets[1].Status)
+		require.Equal(t, 1, targets[1].Reconnects)
+		require.Equal(t, "connection refused", targets[1].Error)
+
+		// the same failure is not handled twice
+		update(livekit.EgressStatus_EGRESS_ACTIVE,
+			&livekit.StreamInfo{Url: redactedYoutube, Status: livekit.StreamInfo_ACTIVE},
+			&livekit.StreamInfo{Url: redactedTwitch, Status: livekit.StreamInfo_FAILED, Error: "connection refused"},
+		)
+		require.Equal(t, 2, ts.NotifyEventCallCount())
+	})
+
+	t.Run("targets fail after the reconnections allowed", func(t *testing.T) {
+		update(livekit.EgressStatus_EGRESS_ACTIVE,
+			&livekit.StreamInfo{Url: redactedYoutube, Status: livekit.StreamInfo_ACTIVE},
+			&livekit.StreamInfo{Url: redactedTwitch, Status: livekit.StreamInfo_FAILED, Error: "connection refused"},
+			&livekit.StreamInfo{Url: redactedTwitch, Status: livekit.StreamInfo_FAILED, Error: "stream key rejected"},
+		)
+		require.Equal(t, service.EventRTMPTargetFailed, lastEvent().Event)
+		stream, err := store.LoadRTMPStream(ctx, "EG_1")
+		require.NoError(t, err)
+		require.Equal(t, service.RTMPTargetFailed, stream.Targets[1].Status)
+		require.Equal(t, "stream key rejected", stream.Targets[1].Error)
+	})
+
+	t.Run("streams end with their egress", func(t *testing.T) {
+		update(livekit.EgressStatus_EGRESS_COMPLETE,
+			&livekit.StreamInfo{Url: redactedYoutube, Status: livekit.StreamInfo_FINISHED},
+		)
+		require.Equal(t, service.EventRTMPStreamEnded, lastEvent().Event)
+		_, err := store.LoadRTMPStream(ctx, "EG_1")
+		require.ErrorIs(t, err, service.ErrRTMPStreamNotFound)
+	})
+}
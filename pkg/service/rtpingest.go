@@ -0,0 +1,305 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	rtpIngestRPCService = "RTPIngest"
+	rtpIngestRPC        = "RTPIngest"
+
+	rtpIngestStart = "start"
+	rtpIngestStop  = "stop"
+	rtpIngestList  = "list"
+
+	maxRTPIngestRequest = 16 * 1024
+)
+
+// rtpIngestCommand is carried as JSON in the payload of a user data packet, the response carries
+// the resulting ingests as a JSON list
+type rtpIngestCommand struct {
+	Action   string                `json:"action"`
+	Start    *rtc.RTPIngestRequest `json:"start,omitempty"`
+	IngestID string                `json:"ingest_id,omitempty"`
+}
+
+// RTPIngestClient reaches the node hosting a room to start, stop and list its RTP ingests
+type RTPIngestClient interface {
+	RTPIngest(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error)
+}
+
+type RTPIngestServerImpl interface {
+	StartRTPIngest(ctx context.Context, roomName livekit.RoomName, req *rtc.RTPIngestRequest) (*rtc.RTPIngestInfo, error)
+	StopRTPIngest(ctx context.Context, roomName livekit.RoomName, ingestID string) (*rtc.RTPIngestInfo, error)
+	ListRTPIngests(ctx context.Context, roomName livekit.RoomName) ([]*rtc.RTPIngestInfo, error)
+}
+
+type rtpIngestClient struct {
+	client *client.RPCClient
+}
+
+func NewRTPIngestClient(params rpc.ClientParams) (RTPIngestClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: rtpIngestRPCService,
+		ID:   rand.NewClientID(),
+	}
+	sd.RegisterMethod(rtpIngestRPC, false, false, true, true)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &rtpIngestClient{client: rpcClient}, nil
+}
+
+func (c *rtpIngestClient) RTPIngest(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return client.RequestSingle[*livekit.DataPacket](ctx, c.client, rtpIngestRPC, []string{string(room)}, req, opts...)
+}
+
+// rtpIngestServer handles RTP ingest commands for a room hosted on this node
+type rtpIngestServer struct {
+	svc      RTPIngestServerImpl
+	roomName livekit.RoomName
+	rpc      *server.RPCServer
+}
+
+func newRTPIngestServer(svc RTPIngestServerImpl, roomName livekit.RoomName, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *rtpIngestServer {
+	sd := &info.ServiceDefinition{
+		Name: rtpIngestRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	sd.RegisterMethod(rtpIngestRPC, false, false, true, true)
+	return &rtpIngestServer{
+		svc:      svc,
+		roomName: roomName,
+		rpc:      s,
+	}
+}
+
+func (s *rtpIngestServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	return server.RegisterHandler(s.rpc, rtpIngestRPC, []string{string(room)}, s.handle, nil)
+}
+
+func (s *rtpIngestServer) handle(ctx context.Context, req *livekit.DataPacket) (*livekit.DataPacket, error) {
+	var cmd rtpIngestCommand
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &cmd); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+
+	var ingests []*rtc.RTPIngestInfo
+	switch cmd.Action {
+	case rtpIngestStart:
+		if cmd.Start == nil {
+			return nil, ErrRTPIngestInvalid
+		}
+		ii, err := s.svc.StartRTPIngest(ctx, s.roomName, cmd.Start)
+		if err != nil {
+			return nil, err
+		}
+		ingests = append(ingests, ii)
+	case rtpIngestStop:
+		ii, err := s.svc.StopRTPIngest(ctx, s.roomName, cmd.IngestID)
+		if err != nil {
+			return nil, err
+		}
+		ingests = append(ingests, ii)
+	case rtpIngestList:
+		var err error
+		if ingests, err = s.svc.ListRTPIngests(ctx, s.roomName); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrRTPIngestInvalid
+	}
+	return encodeRTPIngest(ingests)
+}
+
+func (s *rtpIngestServer) Kill() {
+	s.rpc.Close(true)
+}
+
+func encodeRTPIngest(v any) (*livekit.DataPacket, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{Payload: payload},
+		},
+	}, nil
+}
+
+// ---------------------------------------------
+
+// StartRTPIngestRequest is the JSON body of POST /rtp_ingests/start
+type StartRTPIngestRequest struct {
+	Room string `json:"room"`
+	rtc.RTPIngestRequest
+}
+
+// RTPIngestRequest is the JSON body of POST /rtp_ingests/{stop,list}, ingest_id is only used by stop
+type RTPIngestRequest struct {
+	Room     string `json:"room"`
+	IngestID string `json:"ingest_id,omitempty"`
+}
+
+// RTPIngestService serves the HTTP API publishing plain RTP streams in rooms, for media generated by
+// pipelines without a WebRTC stack. The response of start has the UDP address of the node hosting the
+// room, the payload type and SSRC to send with and the token to send first. Calls require roomAdmin.
+type RTPIngestService struct {
+	roomStore      ServiceStore
+	topicFormatter rpc.TopicFormatter
+	client         RTPIngestClient
+}
+
+func NewRTPIngestService(
+	roomStore ServiceStore,
+	topicFormatter rpc.TopicFormatter,
+	client RTPIngestClient,
+) *RTPIngestService {
+	return &RTPIngestService{
+		roomStore:      roomStore,
+		topicFormatter: topicFormatter,
+		client:         client,
+	}
+}
+
+func (s *RTPIngestService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/rtp_ingests/") {
+	case rtpIngestStart:
+		var req StartRTPIngestRequest
+		if err = decodeJSONRequest(r, &req, maxRTPIngestRequest); err == nil {
+			res, err = s.StartRTPIngest(r.Context(), &req)
+		}
+	case rtpIngestStop:
+		var req RTPIngestRequest
+		if err = decodeJSONRequest(r, &req, maxRTPIngestRequest); err == nil {
+			res, err = s.StopRTPIngest(r.Context(), &req)
+		}
+	case rtpIngestList:
+		var req RTPIngestRequest
+		if err = decodeJSONRequest(r, &req, maxRTPIngestRequest); err == nil {
+			res, err = s.ListRTPIngests(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *RTPIngestService) StartRTPIngest(ctx context.Context, req *StartRTPIngestRequest) (*rtc.RTPIngestInfo, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName, "participant", req.Identity, "mime", req.MimeType)
+	if err := s.ensureRoom(ctx, roomName); err != nil {
+		return nil, err
+	}
+	if req.Identity == "" || req.MimeType == "" {
+		return nil, ErrRTPIngestInvalid
+	}
+
+	ingests, err := s.send(ctx, roomName, &rtpIngestCommand{Action: rtpIngestStart, Start: &req.RTPIngestRequest})
+	if err != nil {
+		return nil, err
+	}
+	return ingests[0], nil
+}
+
+func (s *RTPIngestService) StopRTPIngest(ctx context.Context, req *RTPIngestRequest) (*rtc.RTPIngestInfo, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName, "ingestID", req.IngestID)
+	if err := s.ensureRoom(ctx, roomName); err != nil {
+		return nil, err
+	}
+
+	ingests, err := s.send(ctx, roomName, &rtpIngestCommand{Action: rtpIngestStop, IngestID: req.IngestID})
+	if err != nil {
+		return nil, err
+	}
+	return ingests[0], nil
+}
+
+// ListRTPIngests returns the RTP ingests of a room with their counters
+func (s *RTPIngestService) ListRTPIngests(ctx context.Context, req *RTPIngestRequest) ([]*rtc.RTPIngestInfo, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName)
+	if err := s.ensureRoom(ctx, roomName); err != nil {
+		return nil, err
+	}
+	return s.send(ctx, roomName, &rtpIngestCommand{Action: rtpIngestList})
+}
+
+func (s *RTPIngestService) send(ctx context.Context, roomName livekit.RoomName, cmd *rtpIngestCommand) ([]*rtc.RTPIngestInfo, error) {
+	req, err := encodeRTPIngest(cmd)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.RTPIngest(ctx, s.topicFormatter.RoomTopic(ctx, roomName), req)
+	if err != nil {
+		return nil, err
+	}
+	var ingests []*rtc.RTPIngestInfo
+	if err = json.Unmarshal(res.GetUser().GetPayload(), &ingests); err != nil {
+		return nil, err
+	}
+	if cmd.Action != rtpIngestList && len(ingests) == 0 {
+		return nil, ErrOperationFailed
+	}
+	return ingests, nil
+}
+
+func (s *RTPIngestService) ensureRoom(ctx context.Context, roomName livekit.RoomName) error {
+	if err := EnsureAdminPermission(ctx, roomName); err != nil {
+		return err
+	}
+	// streams are received by the node hosting the room
+	_, _, err := s.roomStore.LoadRoom(ctx, roomName, false)
+	return err
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+// testRTPIngestClient answers every command with the ingests it was given
+type testRTPIngestClient struct {
+	commands []map[string]any
+	ingests  []*rtc.RTPIngestInfo
+}
+
+func (c *testRTPIngestClient) RTPIngest(_ context.Context, _ rpc.RoomTopic, req *livekit.DataPacket, _ ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	var cmd map[string]any
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &cmd); err != nil {
+		return nil, err
+	}
+	c.commands = append(c.commands, cmd)
+	payload, err := json.Marshal(c.ingests)
+	if err != nil {
+		return nil, err
+	}
+	return &livekit.DataPacket{Value: &livekit.DataPacket_User{User: &livekit.UserPacket{Payload: payload}}}, nil
+}
+
+func TestRTPIngest(t *testing.T) {
+	store := service.NewLocalStore()
+	client := &testRTPIngestClient{}
+	svc := service.NewRTPIngestService(store, rpc.NewTopicFormatter(), client)
+
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomAdmin: true, Room: "studio"},
+	}, "")
+	require.NoError(t, store.StoreRoom(ctx, &livekit.Room{Name: "studio"}, nil))
+
+	t.Run("requires admin of the room", func(t *testing.T) {
+		other := service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomAdmin: true, Room: "other"},
+		}, "")
+		_, err := svc.ListRTPIngests(other, &service.RTPIngestRequest{Room: "studio"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+
+		_, err = svc.ListRTPIngests(ctx, &service.RTPIngestRequest{Room: "missing"})
+		require.Error(t, err)
+	})
+
+	t.Run("validates requests", func(t *testing.T) {
+		_, err := svc.StartRTPIngest(ctx, &service.StartRTPIngestRequest{
+			Room:             "studio",
+			RTPIngestRequest: rtc.RTPIngestRequest{MimeType: "audio/opus"},
+		})
+		require.ErrorIs(t, err, service.ErrRTPIngestInvalid)
+		require.Empty(t, client.commands)
+	})
+
+	t.Run("starts on the node hosting the room", func(t *testing.T) {
+		client.ingests = []*rtc.RTPIngestInfo{{IngestID: "RI_1", Identity: "tts", SSRC: 1234, PayloadType: 111, Token: "secret"}}
+		ii, err := svc.StartRTPIngest(ctx, &service.StartRTPIngestRequest{
+			Room:             "studio",
+			RTPIngestRequest: rtc.RTPIngestRequest{Identity: "tts", MimeType: "audio/opus", SSRC: 1234},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "RI_1", ii.IngestID)
+		require.Equal(t, "secret", ii.Token)
+		require.Equal(t, "start", client.commands[0]["action"])
+		require.Equal(t, "tts", client.commands[0]["start"].(map[string]any)["identity"])
+
+		ii, err = svc.StopRTPIngest(ctx, &service.RTPIngestRequest{Room: "studio", IngestID: "RI_1"})
+		require.NoError(t, err)
+		require.Equal(t, "RI_1", ii.IngestID)
+		require.Equal(t, "RI_1", client.commands[1]["ingest_id"])
+
+		client.ingests = nil
+		_, err = svc.StopRTPIngest(ctx, &service.RTPIngestRequest{Room: "studio", IngestID: "RI_2"})
+		require.ErrorIs(t, err, service.ErrOperationFailed)
+	})
+}
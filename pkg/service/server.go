@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	_ "net/http/pprof"
@@ -37,6 +38,7 @@ import (
 
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/livekit-server/version"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
@@ -44,30 +46,81 @@ import (
 )
 
 type LivekitServer struct {
-	config       *config.Config
-	ioService    *IOInfoService
-	rtcService   *RTCService
-	agentService *AgentService
-	httpServer   *http.Server
-	promServer   *http.Server
-	router       routing.Router
-	roomManager  *RoomManager
-	signalServer *SignalServer
-	turnServer   *turn.Server
-	currentNode  routing.LocalNode
-	running      atomic.Bool
-	doneChan     chan struct{}
-	closedChan   chan struct{}
+	config        *config.Config
+	ioService     *IOInfoService
+	scheduler     *RoomScheduleService
+	rtcService    *RTCService
+	agentService  *AgentService
+	httpServer    *http.Server
+	promServer    *http.Server
+	adminServer   *http.Server
+	router        routing.Router
+	roomManager   *RoomManager
+	signalServer  *SignalServer
+	turnServer    *turn.Server
+	currentNode   routing.LocalNode
+	reloader      *ConfigReloader
+	notifier      *WebhookNotifier
+	analytics     telemetry.AnalyticsService
+	drainer       *NodeDrainer
+	prober        *NodeLatencyProber
+	clockSkew     *ClockSkewMonitor
+	ingressHealth *IngressHealthService
+	keyProvider   auth.KeyProvider
+	audit         *AuditLogger
+	running       atomic.Bool
+	doneChan      chan struct{}
+	closedChan    chan struct{}
 }
 
 func NewLivekitServer(conf *config.Config,
 	roomService livekit.RoomService,
 	agentDispatchService *AgentDispatchService,
+	virtualParticipantService *VirtualParticipantService,
+	sharedPlaybackService *SharedPlaybackService,
+	waitingRoomService *WaitingRoomService,
+	roomScheduleService *RoomScheduleService,
+	breakoutService *BreakoutService,
+	roomMergeService *RoomMergeService,
+	apiKeyService *APIKeyService,
+	tokenRevocationService *TokenRevocationService,
+	oidcVerifier *OIDCVerifier,
+	participantRoleService *ParticipantRoleService,
+	trackForwardService *TrackForwardService,
+	trackRecordingService *TrackRecordingService,
+	rtmpStreamService *RTMPStreamService,
+	roomTemplateService *RoomTemplateService,
+	bulkParticipantsService *BulkParticipantsService,
+	rtpIngestService *RTPIngestService,
+	participantListService *ParticipantListService,
+	recordingConsentService *RecordingConsentService,
+	bandwidthPolicyService *BandwidthPolicyService,
+	roomDebugService *RoomDebugService,
+	conformanceService *ConformanceService,
+	clientDiagnosticsService *ClientDiagnosticsService,
+	clientEventsService *ClientEventsService,
+	roomStatsService *RoomStatsService,
+	abuseService *AbuseService,
+	abuseDetector AbuseDetector,
+	signalRateLimiter *SignalRateLimiter,
+	ipFilter *IPFilter,
+	roomArchiveService *RoomArchiveService,
+	sessionService *SessionService,
+	nodeAdminService *NodeAdminService,
+	nodeDrainer *NodeDrainer,
+	regionSettingsService *RegionSettingsService,
+	nodeLatencyProber *NodeLatencyProber,
+	clockSkewMonitor *ClockSkewMonitor,
 	egressService *EgressService,
 	ingressService *IngressService,
+	ingressHealthService *IngressHealthService,
 	sipService *SIPService,
+	sipUsageService *SIPUsageService,
+	sipUpdateService *SIPUpdateService,
 	ioService *IOInfoService,
 	rtcService *RTCService,
+	whipService *WHIPService,
+	whepService *WHEPService,
 	agentService *AgentService,
 	keyProvider auth.KeyProvider,
 	router routing.Router,
@@ -75,19 +128,31 @@ func NewLivekitServer(conf *config.Config,
 	signalServer *SignalServer,
 	turnServer *turn.Server,
 	currentNode routing.LocalNode,
+	configReloader *ConfigReloader,
+	webhookNotifier *WebhookNotifier,
+	analytics telemetry.AnalyticsService,
 ) (s *LivekitServer, err error) {
 	s = &LivekitServer{
 		config:       conf,
 		ioService:    ioService,
+		scheduler:    roomScheduleService,
 		rtcService:   rtcService,
 		agentService: agentService,
 		router:       router,
 		roomManager:  roomManager,
 		signalServer: signalServer,
 		// turn server starts automatically
-		turnServer:  turnServer,
-		currentNode: currentNode,
-		closedChan:  make(chan struct{}),
+		turnServer:    turnServer,
+		currentNode:   currentNode,
+		reloader:      configReloader,
+		notifier:      webhookNotifier,
+		analytics:     analytics,
+		drainer:       nodeDrainer,
+		prober:        nodeLatencyProber,
+		clockSkew:     clockSkewMonitor,
+		ingressHealth: ingressHealthService,
+		keyProvider:   keyProvider,
+		closedChan:    make(chan struct{}),
 	}
 
 	middlewares := []negroni.Handler{
@@ -103,9 +168,38 @@ func NewLivekitServer(conf *config.Config,
 			MaxAge: 86400,
 		}),
 		negroni.HandlerFunc(RemoveDoubleSlashes),
+		NewAccessLogMiddleware(conf.Logging.AccessLog),
+		negroni.HandlerFunc(TracingMiddleware),
+	}
+	if abuseDetector != nil {
+		middlewares = append(middlewares, NewAbuseMiddleware(conf, abuseDetector))
+	}
+	if signalRateLimiter != nil {
+		middlewares = append(middlewares, signalRateLimiter.PreAuth())
+	}
+	plugins := HTTPPlugins()
+	if err = setupHTTPPlugins(conf, plugins); err != nil {
+		return
+	}
+	for _, p := range plugins {
+		middlewares = append(middlewares, p.PreAuth...)
 	}
 	if keyProvider != nil {
-		middlewares = append(middlewares, NewAPIKeyAuthMiddleware(keyProvider))
+		authMiddleware := NewAPIKeyAuthMiddleware(keyProvider)
+		authMiddleware.SetOIDCVerifier(oidcVerifier)
+		middlewares = append(middlewares, authMiddleware)
+		if tokenRevocationService.revocations != nil {
+			middlewares = append(middlewares, NewTokenRevocationMiddleware(tokenRevocationService.revocations))
+		}
+		if signalRateLimiter != nil {
+			middlewares = append(middlewares, signalRateLimiter.PostAuth())
+		}
+		if ipFilter != nil {
+			middlewares = append(middlewares, ipFilter.Middleware())
+		}
+	}
+	for _, p := range plugins {
+		middlewares = append(middlewares, p.PostAuth...)
 	}
 
 	serverOptions := []interface{}{
@@ -114,6 +208,12 @@ func NewLivekitServer(conf *config.Config,
 			TwirpRequestStatusReporter(),
 		)),
 	}
+	if s.audit, err = NewAuditLogger(&conf.Audit, currentNode.NodeID()); err != nil {
+		return
+	}
+	if s.audit != nil {
+		serverOptions = append(serverOptions, twirp.WithServerInterceptors(s.audit.Interceptor()))
+	}
 	roomServer := livekit.NewRoomServiceServer(roomService, serverOptions...)
 	agentDispatchServer := livekit.NewAgentDispatchServiceServer(agentDispatchService, serverOptions...)
 	egressServer := livekit.NewEgressServer(egressService, serverOptions...)
@@ -132,10 +232,49 @@ func NewLivekitServer(conf *config.Config,
 	mux.Handle(agentDispatchServer.PathPrefix(), agentDispatchServer)
 	mux.Handle(egressServer.PathPrefix(), egressServer)
 	mux.Handle(ingressServer.PathPrefix(), ingressServer)
+	mux.Handle("/ingress_health/", ingressHealthService)
 	mux.Handle(sipServer.PathPrefix(), sipServer)
+	mux.Handle("/sip_usage/", sipUsageService)
+	mux.Handle("/sip_update/", sipUpdateService)
 	mux.Handle("/rtc", rtcService)
 	mux.Handle("/agent", agentService)
+	mux.Handle("/virtual_participants/", virtualParticipantService)
+	mux.Handle("/shared_playback/", sharedPlaybackService)
+	mux.Handle("/waiting_room/", waitingRoomService)
+	mux.Handle("/rooms/schedule/", roomScheduleService)
+	mux.Handle("/breakout_rooms/", breakoutService)
+	mux.Handle("/rooms/merge", roomMergeService)
+	mux.Handle("/api_keys/", apiKeyService)
+	mux.Handle("/token_revocation/", tokenRevocationService)
+	mux.Handle("/participant_role/", participantRoleService)
+	mux.Handle("/track_forwards/", trackForwardService)
+	mux.Handle("/track_recordings/", trackRecordingService)
+	mux.Handle("/rtmp_streams/", rtmpStreamService)
+	mux.Handle("/room_templates/", roomTemplateService)
+	mux.Handle("/bulk_participants/", bulkParticipantsService)
+	mux.Handle("/rtp_ingests/", rtpIngestService)
+	mux.Handle("/participants/", participantListService)
+	mux.Handle("/recording_consent/", recordingConsentService)
+	mux.Handle("/bandwidth_policy/", bandwidthPolicyService)
+	mux.Handle("/room_debug/", roomDebugService)
+	mux.Handle("/conformance/", conformanceService)
+	mux.Handle("/client_diagnostics/", clientDiagnosticsService)
+	mux.Handle("/client_events/", clientEventsService)
+	mux.Handle("/room_stats/", roomStatsService)
+	mux.Handle("/abuse/", abuseService)
+	mux.Handle("/room_archive/", roomArchiveService)
+	mux.Handle("/sessions/", sessionService)
+	mux.Handle("/settings/regions", regionSettingsService)
 	mux.HandleFunc("/rtc/validate", rtcService.Validate)
+	mux.Handle("/whip", whipService)
+	mux.Handle("/whip/", whipService)
+	mux.Handle("/whep", whepService)
+	mux.Handle("/whep/", whepService)
+	for _, p := range plugins {
+		for pattern, handler := range p.Routes {
+			mux.Handle(pattern, handler)
+		}
+	}
 	mux.HandleFunc("/", s.defaultHandler)
 
 	s.httpServer = &http.Server{
@@ -160,6 +299,19 @@ func NewLivekitServer(conf *config.Config,
 		}
 	}
 
+	if conf.Admin.Port > 0 {
+		adminMiddlewares := []negroni.Handler{negroni.NewRecovery()}
+		if keyProvider != nil {
+			adminMiddlewares = append(adminMiddlewares, NewAPIKeyAuthMiddleware(keyProvider))
+			if tokenRevocationService.revocations != nil {
+				adminMiddlewares = append(adminMiddlewares, NewTokenRevocationMiddleware(tokenRevocationService.revocations))
+			}
+		}
+		s.adminServer = &http.Server{
+			Handler: configureMiddlewares(nodeAdminService, adminMiddlewares...),
+		}
+	}
+
 	// clean up old rooms on startup
 	if err = roomManager.CleanupRooms(); err != nil {
 		return
@@ -206,6 +358,15 @@ func (s *LivekitServer) Start() error {
 		return err
 	}
 
+	s.scheduler.Start()
+	if s.prober != nil {
+		s.prober.Start()
+	}
+	if s.clockSkew != nil {
+		s.clockSkew.Start()
+	}
+	s.ingressHealth.Start()
+
 	addresses := s.config.BindAddresses
 	if addresses == nil {
 		addresses = []string{""}
@@ -214,6 +375,7 @@ func (s *LivekitServer) Start() error {
 	// ensure we could listen
 	listeners := make([]net.Listener, 0)
 	promListeners := make([]net.Listener, 0)
+	adminListeners := make([]net.Listener, 0)
 	for _, addr := range addresses {
 		ln, err := net.Listen("tcp", net.JoinHostPort(addr, strconv.Itoa(int(s.config.Port))))
 		if err != nil {
@@ -228,6 +390,14 @@ func (s *LivekitServer) Start() error {
 			}
 			promListeners = append(promListeners, ln)
 		}
+
+		if s.adminServer != nil {
+			ln, err = net.Listen("tcp", net.JoinHostPort(addr, strconv.Itoa(int(s.config.Admin.Port))))
+			if err != nil {
+				return err
+			}
+			adminListeners = append(adminListeners, ln)
+		}
 	}
 
 	values := []interface{}{
@@ -252,6 +422,9 @@ func (s *LivekitServer) Start() error {
 	if s.config.Prometheus.Port != 0 {
 		values = append(values, "portPrometheus", s.config.Prometheus.Port)
 	}
+	if s.config.Admin.Port != 0 {
+		values = append(values, "portAdmin", s.config.Admin.Port)
+	}
 	if s.config.Region != "" {
 		values = append(values, "region", s.config.Region)
 	}
@@ -264,6 +437,10 @@ func (s *LivekitServer) Start() error {
 		go s.promServer.Serve(promLn)
 	}
 
+	for _, adminLn := range adminListeners {
+		go s.adminServer.Serve(adminLn)
+	}
+
 	if err := s.signalServer.Start(); err != nil {
 		return err
 	}
@@ -295,22 +472,45 @@ func (s *LivekitServer) Start() error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 	_ = s.httpServer.Shutdown(ctx)
+	if s.adminServer != nil {
+		_ = s.adminServer.Shutdown(ctx)
+	}
 
 	if s.turnServer != nil {
 		_ = s.turnServer.Close()
 	}
 
 	s.roomManager.Stop()
+	// after the rooms closed, so that their last events are delivered or dead-lettered
+	s.notifier.Stop()
+	if c, ok := s.analytics.(io.Closer); ok {
+		_ = c.Close()
+	}
 	s.signalServer.Stop()
 	s.ioService.Stop()
+	s.scheduler.Stop()
+	if s.prober != nil {
+		s.prober.Stop()
+	}
+	if s.clockSkew != nil {
+		s.clockSkew.Stop()
+	}
+	s.ingressHealth.Stop()
+	s.reloader.Stop()
+	if p, ok := s.keyProvider.(*ManagedKeyProvider); ok {
+		p.Stop()
+	}
+	if s.audit != nil {
+		_ = s.audit.Close()
+	}
 
 	close(s.closedChan)
 	return nil
 }
 
 func (s *LivekitServer) Stop(force bool) {
-	// wait for all participants to exit
-	s.router.Drain()
+	// wait for all participants to exit, or to be migrated to other nodes
+	s.drainer.Start(!force && s.config.Drain.Migrate)
 	partTicker := time.NewTicker(5 * time.Second)
 	waitingForParticipants := !force && s.roomManager.HasParticipants()
 	for waitingForParticipants {
@@ -331,6 +531,11 @@ func (s *LivekitServer) Stop(force bool) {
 	<-s.closedChan
 }
 
+// WatchConfig reloads the config read by load on SIGHUP, and when the config file at path changes
+func (s *LivekitServer) WatchConfig(path string, load func() (*config.Config, error)) {
+	s.reloader.Watch(path, load)
+}
+
 func (s *LivekitServer) RoomManager() *RoomManager {
 	return s.roomManager
 }
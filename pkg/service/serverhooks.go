@@ -0,0 +1,71 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+// ServerHooks deliver the events of a server in-process, for applications embedding it
+type ServerHooks struct {
+	// called with every webhook event, whether webhook URLs are configured or not
+	Webhook func(ctx context.Context, event *livekit.WebhookEvent)
+	// receives analytics events and stats along with the analytics service of the server
+	Analytics telemetry.AnalyticsService
+}
+
+// analyticsTee sends analytics to the service of the server and to exporters and hooks
+type analyticsTee struct {
+	telemetry.AnalyticsService
+	sinks []telemetry.AnalyticsService
+}
+
+func (a *analyticsTee) SendStats(ctx context.Context, stats []*livekit.AnalyticsStat) {
+	a.AnalyticsService.SendStats(ctx, stats)
+	for _, sink := range a.sinks {
+		sink.SendStats(ctx, stats)
+	}
+}
+
+func (a *analyticsTee) SendEvent(ctx context.Context, event *livekit.AnalyticsEvent) {
+	a.AnalyticsService.SendEvent(ctx, event)
+	for _, sink := range a.sinks {
+		sink.SendEvent(ctx, event)
+	}
+}
+
+func (a *analyticsTee) SendNodeRoomStates(ctx context.Context, nodeRooms *livekit.AnalyticsNodeRooms) {
+	a.AnalyticsService.SendNodeRoomStates(ctx, nodeRooms)
+	for _, sink := range a.sinks {
+		sink.SendNodeRoomStates(ctx, nodeRooms)
+	}
+}
+
+// Close flushes the sinks batching analytics
+func (a *analyticsTee) Close() error {
+	var err error
+	for _, sink := range a.sinks {
+		if c, ok := sink.(io.Closer); ok {
+			err = errors.Join(err, c.Close())
+		}
+	}
+	return err
+}
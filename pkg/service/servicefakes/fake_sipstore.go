@@ -4,12 +4,27 @@ package servicefakes
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/livekit/livekit-server/pkg/service"
 	"github.com/livekit/protocol/livekit"
 )
 
 type FakeSIPStore struct {
+	ClaimSIPVoicemailPromptStub        func(context.Context, string) (bool, error)
+	claimSIPVoicemailPromptMutex       sync.RWMutex
+	claimSIPVoicemailPromptArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	claimSIPVoicemailPromptReturns struct {
+		result1 bool
+		result2 error
+	}
+	claimSIPVoicemailPromptReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
 	DeleteSIPDispatchRuleStub        func(context.Context, *livekit.SIPDispatchRuleInfo) error
 	deleteSIPDispatchRuleMutex       sync.RWMutex
 	deleteSIPDispatchRuleArgsForCall []struct {
@@ -128,6 +143,20 @@ type FakeSIPStore struct {
 		result1 *livekit.SIPOutboundTrunkInfo
 		result2 error
 	}
+	LoadSIPRevisionStub        func(context.Context, string) (uint64, error)
+	loadSIPRevisionMutex       sync.RWMutex
+	loadSIPRevisionArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	loadSIPRevisionReturns struct {
+		result1 uint64
+		result2 error
+	}
+	loadSIPRevisionReturnsOnCall map[int]struct {
+		result1 uint64
+		result2 error
+	}
 	LoadSIPTrunkStub        func(context.Context, string) (*livekit.SIPTrunkInfo, error)
 	loadSIPTrunkMutex       sync.RWMutex
 	loadSIPTrunkArgsForCall []struct {
@@ -142,6 +171,20 @@ type FakeSIPStore struct {
 		result1 *livekit.SIPTrunkInfo
 		result2 error
 	}
+	LoadSIPVoicemailRoomStub        func(context.Context, string) (*service.SIPVoicemailRoom, error)
+	loadSIPVoicemailRoomMutex       sync.RWMutex
+	loadSIPVoicemailRoomArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	loadSIPVoicemailRoomReturns struct {
+		result1 *service.SIPVoicemailRoom
+		result2 error
+	}
+	loadSIPVoicemailRoomReturnsOnCall map[int]struct {
+		result1 *service.SIPVoicemailRoom
+		result2 error
+	}
 	StoreSIPDispatchRuleStub        func(context.Context, *livekit.SIPDispatchRuleInfo) error
 	storeSIPDispatchRuleMutex       sync.RWMutex
 	storeSIPDispatchRuleArgsForCall []struct {
@@ -190,10 +233,150 @@ type FakeSIPStore struct {
 	storeSIPTrunkReturnsOnCall map[int]struct {
 		result1 error
 	}
+	StoreSIPVoicemailRoomStub        func(context.Context, *service.SIPVoicemailRoom, time.Duration) error
+	storeSIPVoicemailRoomMutex       sync.RWMutex
+	storeSIPVoicemailRoomArgsForCall []struct {
+		arg1 context.Context
+		arg2 *service.SIPVoicemailRoom
+		arg3 time.Duration
+	}
+	storeSIPVoicemailRoomReturns struct {
+		result1 error
+	}
+	storeSIPVoicemailRoomReturnsOnCall map[int]struct {
+		result1 error
+	}
+	UpdateSIPCallMetricsStateStub        func(context.Context, string, map[string]string) (map[string]bool, map[string]string, error)
+	updateSIPCallMetricsStateMutex       sync.RWMutex
+	updateSIPCallMetricsStateArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 map[string]string
+	}
+	updateSIPCallMetricsStateReturns struct {
+		result1 map[string]bool
+		result2 map[string]string
+		result3 error
+	}
+	updateSIPCallMetricsStateReturnsOnCall map[int]struct {
+		result1 map[string]bool
+		result2 map[string]string
+		result3 error
+	}
+	UpdateSIPDispatchRuleStub        func(context.Context, *livekit.SIPDispatchRuleInfo, uint64) (uint64, error)
+	updateSIPDispatchRuleMutex       sync.RWMutex
+	updateSIPDispatchRuleArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.SIPDispatchRuleInfo
+		arg3 uint64
+	}
+	updateSIPDispatchRuleReturns struct {
+		result1 uint64
+		result2 error
+	}
+	updateSIPDispatchRuleReturnsOnCall map[int]struct {
+		result1 uint64
+		result2 error
+	}
+	UpdateSIPInboundTrunkStub        func(context.Context, *livekit.SIPInboundTrunkInfo, uint64) (uint64, error)
+	updateSIPInboundTrunkMutex       sync.RWMutex
+	updateSIPInboundTrunkArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.SIPInboundTrunkInfo
+		arg3 uint64
+	}
+	updateSIPInboundTrunkReturns struct {
+		result1 uint64
+		result2 error
+	}
+	updateSIPInboundTrunkReturnsOnCall map[int]struct {
+		result1 uint64
+		result2 error
+	}
+	UpdateSIPOutboundTrunkStub        func(context.Context, *livekit.SIPOutboundTrunkInfo, uint64) (uint64, error)
+	updateSIPOutboundTrunkMutex       sync.RWMutex
+	updateSIPOutboundTrunkArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.SIPOutboundTrunkInfo
+		arg3 uint64
+	}
+	updateSIPOutboundTrunkReturns struct {
+		result1 uint64
+		result2 error
+	}
+	updateSIPOutboundTrunkReturnsOnCall map[int]struct {
+		result1 uint64
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeSIPStore) ClaimSIPVoicemailPrompt(arg1 context.Context, arg2 string) (bool, error) {
+	fake.claimSIPVoicemailPromptMutex.Lock()
+	ret, specificReturn := fake.claimSIPVoicemailPromptReturnsOnCall[len(fake.claimSIPVoicemailPromptArgsForCall)]
+	fake.claimSIPVoicemailPromptArgsForCall = append(fake.claimSIPVoicemailPromptArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.ClaimSIPVoicemailPromptStub
+	fakeReturns := fake.claimSIPVoicemailPromptReturns
+	fake.recordInvocation("ClaimSIPVoicemailPrompt", []interface{}{arg1, arg2})
+	fake.claimSIPVoicemailPromptMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSIPStore) ClaimSIPVoicemailPromptCallCount() int {
+	fake.claimSIPVoicemailPromptMutex.RLock()
+	defer fake.claimSIPVoicemailPromptMutex.RUnlock()
+	return len(fake.claimSIPVoicemailPromptArgsForCall)
+}
+
+func (fake *FakeSIPStore) ClaimSIPVoicemailPromptCalls(stub func(context.Context, string) (bool, error)) {
+	fake.claimSIPVoicemailPromptMutex.Lock()
+	defer fake.claimSIPVoicemailPromptMutex.Unlock()
+	fake.ClaimSIPVoicemailPromptStub = stub
+}
+
+func (fake *FakeSIPStore) ClaimSIPVoicemailPromptArgsForCall(i int) (context.Context, string) {
+	fake.claimSIPVoicemailPromptMutex.RLock()
+	defer fake.claimSIPVoicemailPromptMutex.RUnlock()
+	argsForCall := fake.claimSIPVoicemailPromptArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeSIPStore) ClaimSIPVoicemailPromptReturns(result1 bool, result2 error) {
+	fake.claimSIPVoicemailPromptMutex.Lock()
+	defer fake.claimSIPVoicemailPromptMutex.Unlock()
+	fake.ClaimSIPVoicemailPromptStub = nil
+	fake.claimSIPVoicemailPromptReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSIPStore) ClaimSIPVoicemailPromptReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.claimSIPVoicemailPromptMutex.Lock()
+	defer fake.claimSIPVoicemailPromptMutex.Unlock()
+	fake.ClaimSIPVoicemailPromptStub = nil
+	if fake.claimSIPVoicemailPromptReturnsOnCall == nil {
+		fake.claimSIPVoicemailPromptReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.claimSIPVoicemailPromptReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeSIPStore) DeleteSIPDispatchRule(arg1 context.Context, arg2 *livekit.SIPDispatchRuleInfo) error {
 	fake.deleteSIPDispatchRuleMutex.Lock()
 	ret, specificReturn := fake.deleteSIPDispatchRuleReturnsOnCall[len(fake.deleteSIPDispatchRuleArgsForCall)]
@@ -769,6 +952,71 @@ func (fake *FakeSIPStore) LoadSIPOutboundTrunkReturnsOnCall(i int, result1 *live
 	}{result1, result2}
 }
 
+func (fake *FakeSIPStore) LoadSIPRevision(arg1 context.Context, arg2 string) (uint64, error) {
+	fake.loadSIPRevisionMutex.Lock()
+	ret, specificReturn := fake.loadSIPRevisionReturnsOnCall[len(fake.loadSIPRevisionArgsForCall)]
+	fake.loadSIPRevisionArgsForCall = append(fake.loadSIPRevisionArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.LoadSIPRevisionStub
+	fakeReturns := fake.loadSIPRevisionReturns
+	fake.recordInvocation("LoadSIPRevision", []interface{}{arg1, arg2})
+	fake.loadSIPRevisionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSIPStore) LoadSIPRevisionCallCount() int {
+	fake.loadSIPRevisionMutex.RLock()
+	defer fake.loadSIPRevisionMutex.RUnlock()
+	return len(fake.loadSIPRevisionArgsForCall)
+}
+
+func (fake *FakeSIPStore) LoadSIPRevisionCalls(stub func(context.Context, string) (uint64, error)) {
+	fake.loadSIPRevisionMutex.Lock()
+	defer fake.loadSIPRevisionMutex.Unlock()
+	fake.LoadSIPRevisionStub = stub
+}
+
+func (fake *FakeSIPStore) LoadSIPRevisionArgsForCall(i int) (context.Context, string) {
+	fake.loadSIPRevisionMutex.RLock()
+	defer fake.loadSIPRevisionMutex.RUnlock()
+	argsForCall := fake.loadSIPRevisionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeSIPStore) LoadSIPRevisionReturns(result1 uint64, result2 error) {
+	fake.loadSIPRevisionMutex.Lock()
+	defer fake.loadSIPRevisionMutex.Unlock()
+	fake.LoadSIPRevisionStub = nil
+	fake.loadSIPRevisionReturns = struct {
+		result1 uint64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSIPStore) LoadSIPRevisionReturnsOnCall(i int, result1 uint64, result2 error) {
+	fake.loadSIPRevisionMutex.Lock()
+	defer fake.loadSIPRevisionMutex.Unlock()
+	fake.LoadSIPRevisionStub = nil
+	if fake.loadSIPRevisionReturnsOnCall == nil {
+		fake.loadSIPRevisionReturnsOnCall = make(map[int]struct {
+			result1 uint64
+			result2 error
+		})
+	}
+	fake.loadSIPRevisionReturnsOnCall[i] = struct {
+		result1 uint64
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeSIPStore) LoadSIPTrunk(arg1 context.Context, arg2 string) (*livekit.SIPTrunkInfo, error) {
 	fake.loadSIPTrunkMutex.Lock()
 	ret, specificReturn := fake.loadSIPTrunkReturnsOnCall[len(fake.loadSIPTrunkArgsForCall)]
@@ -834,6 +1082,71 @@ func (fake *FakeSIPStore) LoadSIPTrunkReturnsOnCall(i int, result1 *livekit.SIPT
 	}{result1, result2}
 }
 
+func (fake *FakeSIPStore) LoadSIPVoicemailRoom(arg1 context.Context, arg2 string) (*service.SIPVoicemailRoom, error) {
+	fake.loadSIPVoicemailRoomMutex.Lock()
+	ret, specificReturn := fake.loadSIPVoicemailRoomReturnsOnCall[len(fake.loadSIPVoicemailRoomArgsForCall)]
+	fake.loadSIPVoicemailRoomArgsForCall = append(fake.loadSIPVoicemailRoomArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.LoadSIPVoicemailRoomStub
+	fakeReturns := fake.loadSIPVoicemailRoomReturns
+	fake.recordInvocation("LoadSIPVoicemailRoom", []interface{}{arg1, arg2})
+	fake.loadSIPVoicemailRoomMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSIPStore) LoadSIPVoicemailRoomCallCount() int {
+	fake.loadSIPVoicemailRoomMutex.RLock()
+	defer fake.loadSIPVoicemailRoomMutex.RUnlock()
+	return len(fake.loadSIPVoicemailRoomArgsForCall)
+}
+
+func (fake *FakeSIPStore) LoadSIPVoicemailRoomCalls(stub func(context.Context, string) (*service.SIPVoicemailRoom, error)) {
+	fake.loadSIPVoicemailRoomMutex.Lock()
+	defer fake.loadSIPVoicemailRoomMutex.Unlock()
+	fake.LoadSIPVoicemailRoomStub = stub
+}
+
+func (fake *FakeSIPStore) LoadSIPVoicemailRoomArgsForCall(i int) (context.Context, string) {
+	fake.loadSIPVoicemailRoomMutex.RLock()
+	defer fake.loadSIPVoicemailRoomMutex.RUnlock()
+	argsForCall := fake.loadSIPVoicemailRoomArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeSIPStore) LoadSIPVoicemailRoomReturns(result1 *service.SIPVoicemailRoom, result2 error) {
+	fake.loadSIPVoicemailRoomMutex.Lock()
+	defer fake.loadSIPVoicemailRoomMutex.Unlock()
+	fake.LoadSIPVoicemailRoomStub = nil
+	fake.loadSIPVoicemailRoomReturns = struct {
+		result1 *service.SIPVoicemailRoom
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSIPStore) LoadSIPVoicemailRoomReturnsOnCall(i int, result1 *service.SIPVoicemailRoom, result2 error) {
+	fake.loadSIPVoicemailRoomMutex.Lock()
+	defer fake.loadSIPVoicemailRoomMutex.Unlock()
+	fake.LoadSIPVoicemailRoomStub = nil
+	if fake.loadSIPVoicemailRoomReturnsOnCall == nil {
+		fake.loadSIPVoicemailRoomReturnsOnCall = make(map[int]struct {
+			result1 *service.SIPVoicemailRoom
+			result2 error
+		})
+	}
+	fake.loadSIPVoicemailRoomReturnsOnCall[i] = struct {
+		result1 *service.SIPVoicemailRoom
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeSIPStore) StoreSIPDispatchRule(arg1 context.Context, arg2 *livekit.SIPDispatchRuleInfo) error {
 	fake.storeSIPDispatchRuleMutex.Lock()
 	ret, specificReturn := fake.storeSIPDispatchRuleReturnsOnCall[len(fake.storeSIPDispatchRuleArgsForCall)]
@@ -1082,7 +1395,339 @@ func (fake *FakeSIPStore) StoreSIPTrunkReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeSIPStore) StoreSIPVoicemailRoom(arg1 context.Context, arg2 *service.SIPVoicemailRoom, arg3 time.Duration) error {
+	fake.storeSIPVoicemailRoomMutex.Lock()
+	ret, specificReturn := fake.storeSIPVoicemailRoomReturnsOnCall[len(fake.storeSIPVoicemailRoomArgsForCall)]
+	fake.storeSIPVoicemailRoomArgsForCall = append(fake.storeSIPVoicemailRoomArgsForCall, struct {
+		arg1 context.Context
+		arg2 *service.SIPVoicemailRoom
+		arg3 time.Duration
+	}{arg1, arg2, arg3})
+	stub := fake.StoreSIPVoicemailRoomStub
+	fakeReturns := fake.storeSIPVoicemailRoomReturns
+	fake.recordInvocation("StoreSIPVoicemailRoom", []interface{}{arg1, arg2, arg3})
+	fake.storeSIPVoicemailRoomMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSIPStore) StoreSIPVoicemailRoomCallCount() int {
+	fake.storeSIPVoicemailRoomMutex.RLock()
+	defer fake.storeSIPVoicemailRoomMutex.RUnlock()
+	return len(fake.storeSIPVoicemailRoomArgsForCall)
+}
+
+func (fake *FakeSIPStore) StoreSIPVoicemailRoomCalls(stub func(context.Context, *service.SIPVoicemailRoom, time.Duration) error) {
+	fake.storeSIPVoicemailRoomMutex.Lock()
+	defer fake.storeSIPVoicemailRoomMutex.Unlock()
+	fake.StoreSIPVoicemailRoomStub = stub
+}
+
+func (fake *FakeSIPStore) StoreSIPVoicemailRoomArgsForCall(i int) (context.Context, *service.SIPVoicemailRoom, time.Duration) {
+	fake.storeSIPVoicemailRoomMutex.RLock()
+	defer fake.storeSIPVoicemailRoomMutex.RUnlock()
+	argsForCall := fake.storeSIPVoicemailRoomArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeSIPStore) StoreSIPVoicemailRoomReturns(result1 error) {
+	fake.storeSIPVoicemailRoomMutex.Lock()
+	defer fake.storeSIPVoicemailRoomMutex.Unlock()
+	fake.StoreSIPVoicemailRoomStub = nil
+	fake.storeSIPVoicemailRoomReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSIPStore) StoreSIPVoicemailRoomReturnsOnCall(i int, result1 error) {
+	fake.storeSIPVoicemailRoomMutex.Lock()
+	defer fake.storeSIPVoicemailRoomMutex.Unlock()
+	fake.StoreSIPVoicemailRoomStub = nil
+	if fake.storeSIPVoicemailRoomReturnsOnCall == nil {
+		fake.storeSIPVoicemailRoomReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeSIPVoicemailRoomReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSIPStore) UpdateSIPCallMetricsState(arg1 context.Context, arg2 string, arg3 map[string]string) (map[string]bool, map[string]string, error) {
+	fake.updateSIPCallMetricsStateMutex.Lock()
+	ret, specificReturn := fake.updateSIPCallMetricsStateReturnsOnCall[len(fake.updateSIPCallMetricsStateArgsForCall)]
+	fake.updateSIPCallMetricsStateArgsForCall = append(fake.updateSIPCallMetricsStateArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 map[string]string
+	}{arg1, arg2, arg3})
+	stub := fake.UpdateSIPCallMetricsStateStub
+	fakeReturns := fake.updateSIPCallMetricsStateReturns
+	fake.recordInvocation("UpdateSIPCallMetricsState", []interface{}{arg1, arg2, arg3})
+	fake.updateSIPCallMetricsStateMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2, ret.result3
+	}
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
+}
+
+func (fake *FakeSIPStore) UpdateSIPCallMetricsStateCallCount() int {
+	fake.updateSIPCallMetricsStateMutex.RLock()
+	defer fake.updateSIPCallMetricsStateMutex.RUnlock()
+	return len(fake.updateSIPCallMetricsStateArgsForCall)
+}
+
+func (fake *FakeSIPStore) UpdateSIPCallMetricsStateCalls(stub func(context.Context, string, map[string]string) (map[string]bool, map[string]string, error)) {
+	fake.updateSIPCallMetricsStateMutex.Lock()
+	defer fake.updateSIPCallMetricsStateMutex.Unlock()
+	fake.UpdateSIPCallMetricsStateStub = stub
+}
+
+func (fake *FakeSIPStore) UpdateSIPCallMetricsStateArgsForCall(i int) (context.Context, string, map[string]string) {
+	fake.updateSIPCallMetricsStateMutex.RLock()
+	defer fake.updateSIPCallMetricsStateMutex.RUnlock()
+	argsForCall := fake.updateSIPCallMetricsStateArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeSIPStore) UpdateSIPCallMetricsStateReturns(result1 map[string]bool, result2 map[string]string, result3 error) {
+	fake.updateSIPCallMetricsStateMutex.Lock()
+	defer fake.updateSIPCallMetricsStateMutex.Unlock()
+	fake.UpdateSIPCallMetricsStateStub = nil
+	fake.updateSIPCallMetricsStateReturns = struct {
+		result1 map[string]bool
+		result2 map[string]string
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeSIPStore) UpdateSIPCallMetricsStateReturnsOnCall(i int, result1 map[string]bool, result2 map[string]string, result3 error) {
+	fake.updateSIPCallMetricsStateMutex.Lock()
+	defer fake.updateSIPCallMetricsStateMutex.Unlock()
+	fake.UpdateSIPCallMetricsStateStub = nil
+	if fake.updateSIPCallMetricsStateReturnsOnCall == nil {
+		fake.updateSIPCallMetricsStateReturnsOnCall = make(map[int]struct {
+			result1 map[string]bool
+			result2 map[string]string
+			result3 error
+		})
+	}
+	fake.updateSIPCallMetricsStateReturnsOnCall[i] = struct {
+		result1 map[string]bool
+		result2 map[string]string
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeSIPStore) UpdateSIPDispatchRule(arg1 context.Context, arg2 *livekit.SIPDispatchRuleInfo, arg3 uint64) (uint64, error) {
+	fake.updateSIPDispatchRuleMutex.Lock()
+	ret, specificReturn := fake.updateSIPDispatchRuleReturnsOnCall[len(fake.updateSIPDispatchRuleArgsForCall)]
+	fake.updateSIPDispatchRuleArgsForCall = append(fake.updateSIPDispatchRuleArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.SIPDispatchRuleInfo
+		arg3 uint64
+	}{arg1, arg2, arg3})
+	stub := fake.UpdateSIPDispatchRuleStub
+	fakeReturns := fake.updateSIPDispatchRuleReturns
+	fake.recordInvocation("UpdateSIPDispatchRule", []interface{}{arg1, arg2, arg3})
+	fake.updateSIPDispatchRuleMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSIPStore) UpdateSIPDispatchRuleCallCount() int {
+	fake.updateSIPDispatchRuleMutex.RLock()
+	defer fake.updateSIPDispatchRuleMutex.RUnlock()
+	return len(fake.updateSIPDispatchRuleArgsForCall)
+}
+
+func (fake *FakeSIPStore) UpdateSIPDispatchRuleCalls(stub func(context.Context, *livekit.SIPDispatchRuleInfo, uint64) (uint64, error)) {
+	fake.updateSIPDispatchRuleMutex.Lock()
+	defer fake.updateSIPDispatchRuleMutex.Unlock()
+	fake.UpdateSIPDispatchRuleStub = stub
+}
+
+func (fake *FakeSIPStore) UpdateSIPDispatchRuleArgsForCall(i int) (context.Context, *livekit.SIPDispatchRuleInfo, uint64) {
+	fake.updateSIPDispatchRuleMutex.RLock()
+	defer fake.updateSIPDispatchRuleMutex.RUnlock()
+	argsForCall := fake.updateSIPDispatchRuleArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeSIPStore) UpdateSIPDispatchRuleReturns(result1 uint64, result2 error) {
+	fake.updateSIPDispatchRuleMutex.Lock()
+	defer fake.updateSIPDispatchRuleMutex.Unlock()
+	fake.UpdateSIPDispatchRuleStub = nil
+	fake.updateSIPDispatchRuleReturns = struct {
+		result1 uint64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSIPStore) UpdateSIPDispatchRuleReturnsOnCall(i int, result1 uint64, result2 error) {
+	fake.updateSIPDispatchRuleMutex.Lock()
+	defer fake.updateSIPDispatchRuleMutex.Unlock()
+	fake.UpdateSIPDispatchRuleStub = nil
+	if fake.updateSIPDispatchRuleReturnsOnCall == nil {
+		fake.updateSIPDispatchRuleReturnsOnCall = make(map[int]struct {
+			result1 uint64
+			result2 error
+		})
+	}
+	fake.updateSIPDispatchRuleReturnsOnCall[i] = struct {
+		result1 uint64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSIPStore) UpdateSIPInboundTrunk(arg1 context.Context, arg2 *livekit.SIPInboundTrunkInfo, arg3 uint64) (uint64, error) {
+	fake.updateSIPInboundTrunkMutex.Lock()
+	ret, specificReturn := fake.updateSIPInboundTrunkReturnsOnCall[len(fake.updateSIPInboundTrunkArgsForCall)]
+	fake.updateSIPInboundTrunkArgsForCall = append(fake.updateSIPInboundTrunkArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.SIPInboundTrunkInfo
+		arg3 uint64
+	}{arg1, arg2, arg3})
+	stub := fake.UpdateSIPInboundTrunkStub
+	fakeReturns := fake.updateSIPInboundTrunkReturns
+	fake.recordInvocation("UpdateSIPInboundTrunk", []interface{}{arg1, arg2, arg3})
+	fake.updateSIPInboundTrunkMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSIPStore) UpdateSIPInboundTrunkCallCount() int {
+	fake.updateSIPInboundTrunkMutex.RLock()
+	defer fake.updateSIPInboundTrunkMutex.RUnlock()
+	return len(fake.updateSIPInboundTrunkArgsForCall)
+}
+
+func (fake *FakeSIPStore) UpdateSIPInboundTrunkCalls(stub func(context.Context, *livekit.SIPInboundTrunkInfo, uint64) (uint64, error)) {
+	fake.updateSIPInboundTrunkMutex.Lock()
+	defer fake.updateSIPInboundTrunkMutex.Unlock()
+	fake.UpdateSIPInboundTrunkStub = stub
+}
+
+func (fake *FakeSIPStore) UpdateSIPInboundTrunkArgsForCall(i int) (context.Context, *livekit.SIPInboundTrunkInfo, uint64) {
+	fake.updateSIPInboundTrunkMutex.RLock()
+	defer fake.updateSIPInboundTrunkMutex.RUnlock()
+	argsForCall := fake.updateSIPInboundTrunkArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeSIPStore) UpdateSIPInboundTrunkReturns(result1 uint64, result2 error) {
+	fake.updateSIPInboundTrunkMutex.Lock()
+	defer fake.updateSIPInboundTrunkMutex.Unlock()
+	fake.UpdateSIPInboundTrunkStub = nil
+	fake.updateSIPInboundTrunkReturns = struct {
+		result1 uint64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSIPStore) UpdateSIPInboundTrunkReturnsOnCall(i int, result1 uint64, result2 error) {
+	fake.updateSIPInboundTrunkMutex.Lock()
+	defer fake.updateSIPInboundTrunkMutex.Unlock()
+	fake.UpdateSIPInboundTrunkStub = nil
+	if fake.updateSIPInboundTrunkReturnsOnCall == nil {
+		fake.updateSIPInboundTrunkReturnsOnCall = make(map[int]struct {
+			result1 uint64
+			result2 error
+		})
+	}
+	fake.updateSIPInboundTrunkReturnsOnCall[i] = struct {
+		result1 uint64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSIPStore) UpdateSIPOutboundTrunk(arg1 context.Context, arg2 *livekit.SIPOutboundTrunkInfo, arg3 uint64) (uint64, error) {
+	fake.updateSIPOutboundTrunkMutex.Lock()
+	ret, specificReturn := fake.updateSIPOutboundTrunkReturnsOnCall[len(fake.updateSIPOutboundTrunkArgsForCall)]
+	fake.updateSIPOutboundTrunkArgsForCall = append(fake.updateSIPOutboundTrunkArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.SIPOutboundTrunkInfo
+		arg3 uint64
+	}{arg1, arg2, arg3})
+	stub := fake.UpdateSIPOutboundTrunkStub
+	fakeReturns := fake.updateSIPOutboundTrunkReturns
+	fake.recordInvocation("UpdateSIPOutboundTrunk", []interface{}{arg1, arg2, arg3})
+	fake.updateSIPOutboundTrunkMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSIPStore) UpdateSIPOutboundTrunkCallCount() int {
+	fake.updateSIPOutboundTrunkMutex.RLock()
+	defer fake.updateSIPOutboundTrunkMutex.RUnlock()
+	return len(fake.updateSIPOutboundTrunkArgsForCall)
+}
+
+func (fake *FakeSIPStore) UpdateSIPOutboundTrunkCalls(stub func(context.Context, *livekit.SIPOutboundTrunkInfo, uint64) (uint64, error)) {
+	fake.updateSIPOutboundTrunkMutex.Lock()
+	defer fake.updateSIPOutboundTrunkMutex.Unlock()
+	fake.UpdateSIPOutboundTrunkStub = stub
+}
+
+func (fake *FakeSIPStore) UpdateSIPOutboundTrunkArgsForCall(i int) (context.Context, *livekit.SIPOutboundTrunkInfo, uint64) {
+	fake.updateSIPOutboundTrunkMutex.RLock()
+	defer fake.updateSIPOutboundTrunkMutex.RUnlock()
+	argsForCall := fake.updateSIPOutboundTrunkArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeSIPStore) UpdateSIPOutboundTrunkReturns(result1 uint64, result2 error) {
+	fake.updateSIPOutboundTrunkMutex.Lock()
+	defer fake.updateSIPOutboundTrunkMutex.Unlock()
+	fake.UpdateSIPOutboundTrunkStub = nil
+	fake.updateSIPOutboundTrunkReturns = struct {
+		result1 uint64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSIPStore) UpdateSIPOutboundTrunkReturnsOnCall(i int, result1 uint64, result2 error) {
+	fake.updateSIPOutboundTrunkMutex.Lock()
+	defer fake.updateSIPOutboundTrunkMutex.Unlock()
+	fake.UpdateSIPOutboundTrunkStub = nil
+	if fake.updateSIPOutboundTrunkReturnsOnCall == nil {
+		fake.updateSIPOutboundTrunkReturnsOnCall = make(map[int]struct {
+			result1 uint64
+			result2 error
+		})
+	}
+	fake.updateSIPOutboundTrunkReturnsOnCall[i] = struct {
+		result1 uint64
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeSIPStore) Invocations() map[string][][]interface{} {
+	fake.claimSIPVoicemailPromptMutex.RLock()
+	defer fake.claimSIPVoicemailPromptMutex.RUnlock()
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.deleteSIPDispatchRuleMutex.RLock()
@@ -1103,8 +1748,12 @@ func (fake *FakeSIPStore) Invocations() map[string][][]interface{} {
 	defer fake.loadSIPInboundTrunkMutex.RUnlock()
 	fake.loadSIPOutboundTrunkMutex.RLock()
 	defer fake.loadSIPOutboundTrunkMutex.RUnlock()
+	fake.loadSIPRevisionMutex.RLock()
+	defer fake.loadSIPRevisionMutex.RUnlock()
 	fake.loadSIPTrunkMutex.RLock()
 	defer fake.loadSIPTrunkMutex.RUnlock()
+	fake.loadSIPVoicemailRoomMutex.RLock()
+	defer fake.loadSIPVoicemailRoomMutex.RUnlock()
 	fake.storeSIPDispatchRuleMutex.RLock()
 	defer fake.storeSIPDispatchRuleMutex.RUnlock()
 	fake.storeSIPInboundTrunkMutex.RLock()
@@ -1113,6 +1762,16 @@ func (fake *FakeSIPStore) Invocations() map[string][][]interface{} {
 	defer fake.storeSIPOutboundTrunkMutex.RUnlock()
 	fake.storeSIPTrunkMutex.RLock()
 	defer fake.storeSIPTrunkMutex.RUnlock()
+	fake.storeSIPVoicemailRoomMutex.RLock()
+	defer fake.storeSIPVoicemailRoomMutex.RUnlock()
+	fake.updateSIPCallMetricsStateMutex.RLock()
+	defer fake.updateSIPCallMetricsStateMutex.RUnlock()
+	fake.updateSIPDispatchRuleMutex.RLock()
+	defer fake.updateSIPDispatchRuleMutex.RUnlock()
+	fake.updateSIPInboundTrunkMutex.RLock()
+	defer fake.updateSIPInboundTrunkMutex.RUnlock()
+	fake.updateSIPOutboundTrunkMutex.RLock()
+	defer fake.updateSIPOutboundTrunkMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -0,0 +1,326 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	defaultSessionsRetention = 7 * 24 * time.Hour
+	defaultSessionsLimit     = 100
+	maxSessionsLimit         = 1000
+	maxSessionsRequest       = 4 * 1024
+)
+
+// RoomSession is the summary of a closed room. Timestamps are in milliseconds.
+type RoomSession struct {
+	Sid       string `json:"sid"`
+	Name      string `json:"name"`
+	CreatedAt int64  `json:"created_at"`
+	EndedAt   int64  `json:"ended_at"`
+	// node the room was closed on
+	NodeID string `json:"node_id,omitempty"`
+}
+
+// ParticipantSession is the summary of a participant's time in a room. Timestamps are in milliseconds.
+type ParticipantSession struct {
+	RoomSid          string              `json:"room_sid"`
+	RoomName         string              `json:"room_name"`
+	Sid              string              `json:"sid"`
+	Identity         string              `json:"identity"`
+	Name             string              `json:"name,omitempty"`
+	Kind             string              `json:"kind"`
+	JoinedAt         int64               `json:"joined_at"`
+	LeftAt           int64               `json:"left_at"`
+	DisconnectReason string              `json:"disconnect_reason"`
+	SDK              string              `json:"sdk,omitempty"`
+	SDKVersion       string              `json:"sdk_version,omitempty"`
+	OS               string              `json:"os,omitempty"`
+	Region           string              `json:"region,omitempty"`
+	Quality          *rtc.SessionQuality `json:"quality,omitempty"`
+}
+
+// SessionRecorder keeps summaries of room and participant sessions once they end
+type SessionRecorder struct {
+	store     SessionStore
+	retention time.Duration
+	nodeID    livekit.NodeID
+}
+
+// NewSessionRecorder returns nil when session history is disabled or there is no store for it
+func NewSessionRecorder(conf *config.Config, store SessionStore, currentNode routing.LocalNode) *SessionRecorder {
+	if !conf.Sessions.Enabled || store == nil {
+		return nil
+	}
+	return &SessionRecorder{
+		store:     store,
+		retention: withDefault(conf.Sessions.Retention, defaultSessionsRetention),
+		nodeID:    currentNode.NodeID(),
+	}
+}
+
+func (r *SessionRecorder) recordRoom(ctx context.Context, room *livekit.Room) error {
+	return r.store.StoreRoomSession(ctx, &RoomSession{
+		Sid:       room.Sid,
+		Name:      room.Name,
+		CreatedAt: room.CreationTime * 1000,
+		EndedAt:   time.Now().UnixMilli(),
+		NodeID:    string(r.nodeID),
+	}, r.retention)
+}
+
+func (r *SessionRecorder) recordParticipant(ctx context.Context, room *livekit.Room, p types.LocalParticipant, quality *rtc.SessionQuality) error {
+	reason := p.CloseReason()
+	if reason == types.ParticipantCloseReasonMigrationRequested {
+		// the session goes on on another node
+		return nil
+	}
+	pi := p.ToProto()
+	session := &ParticipantSession{
+		RoomSid:          room.Sid,
+		RoomName:         room.Name,
+		Sid:              pi.Sid,
+		Identity:         pi.Identity,
+		Name:             pi.Name,
+		Kind:             pi.Kind.String(),
+		JoinedAt:         p.ConnectedAt().UnixMilli(),
+		LeftAt:           time.Now().UnixMilli(),
+		DisconnectReason: reason.ToDisconnectReason().String(),
+		Region:           pi.Region,
+		Quality:          quality,
+	}
+	if ci := p.GetClientInfo(); ci != nil {
+		session.SDK = ci.Sdk.String()
+		session.SDKVersion = ci.Version
+		session.OS = ci.Os
+	}
+	return r.store.StoreParticipantSession(ctx, session, r.retention)
+}
+
+// ---------------------------------------------
+
+// ListRoomSessionsRequest is the JSON body of POST /sessions/list_rooms
+type ListRoomSessionsRequest struct {
+	// only sessions of this room when set
+	Room string `json:"room,omitempty"`
+	// range of the times rooms ended as unix seconds, the whole retention period by default
+	StartTime int64 `json:"start_time,omitempty"`
+	EndTime   int64 `json:"end_time,omitempty"`
+	// latest sessions returned, 100 by default
+	Limit int `json:"limit,omitempty"`
+}
+
+type ListRoomSessionsResponse struct {
+	Sessions []*RoomSession `json:"sessions"`
+}
+
+// GetRoomSessionRequest is the JSON body of POST /sessions/get_room
+type GetRoomSessionRequest struct {
+	Sid string `json:"sid"`
+}
+
+type GetRoomSessionResponse struct {
+	Session      *RoomSession          `json:"session"`
+	Participants []*ParticipantSession `json:"participants"`
+}
+
+// ListParticipantSessionsRequest is the JSON body of POST /sessions/list_participants, sessions of rooms which
+// are still open are listed too
+type ListParticipantSessionsRequest struct {
+	RoomSid string `json:"room_sid"`
+	// only sessions of this participant when set
+	Identity string `json:"identity,omitempty"`
+}
+
+type ListParticipantSessionsResponse struct {
+	Sessions []*ParticipantSession `json:"sessions"`
+}
+
+// SessionService serves the history of recent room and participant sessions. Listing rooms requires roomList,
+// the sessions of a room may also be read with roomAdmin of that room.
+type SessionService struct {
+	recorder *SessionRecorder
+}
+
+func NewSessionService(recorder *SessionRecorder) *SessionService {
+	return &SessionService{
+		recorder: recorder,
+	}
+}
+
+func (s *SessionService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/sessions/") {
+	case "list_rooms":
+		var req ListRoomSessionsRequest
+		if err = decodeJSONRequest(r, &req, maxSessionsRequest); err == nil {
+			res, err = s.ListRoomSessions(r.Context(), &req)
+		}
+	case "get_room":
+		var req GetRoomSessionRequest
+		if err = decodeJSONRequest(r, &req, maxSessionsRequest); err == nil {
+			res, err = s.GetRoomSession(r.Context(), &req)
+		}
+	case "list_participants":
+		var req ListParticipantSessionsRequest
+		if err = decodeJSONRequest(r, &req, maxSessionsRequest); err == nil {
+			res, err = s.ListParticipantSessions(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *SessionService) ListRoomSessions(ctx context.Context, req *ListRoomSessionsRequest) (*ListRoomSessionsResponse, error) {
+	if err := EnsureListPermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.recorder == nil {
+		return nil, ErrSessionsDisabled
+	}
+	now := time.Now()
+	start, end := now.Add(-s.recorder.retention), now.Add(time.Second)
+	if req.StartTime != 0 {
+		start = time.Unix(req.StartTime, 0)
+	}
+	if req.EndTime != 0 {
+		end = time.Unix(req.EndTime, 0)
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultSessionsLimit
+	}
+	switch {
+	case req.StartTime < 0 || req.EndTime < 0 || !end.After(start):
+		return nil, fmt.Errorf("%w: start_time must be before end_time", ErrSessionsInvalid)
+	case limit < 0 || limit > maxSessionsLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrSessionsInvalid, maxSessionsLimit)
+	}
+	AppendLogFields(ctx, "room", req.Room, "start", start, "end", end)
+
+	count := limit
+	if req.Room != "" {
+		// filtered after listing
+		count = 0
+	}
+	sessions, err := s.recorder.store.ListRoomSessions(ctx, start, end, count)
+	if err != nil {
+		return nil, err
+	}
+	res := &ListRoomSessionsResponse{Sessions: make([]*RoomSession, 0, len(sessions))}
+	for _, session := range sessions {
+		if len(res.Sessions) == limit {
+			break
+		}
+		if req.Room == "" || session.Name == req.Room {
+			res.Sessions = append(res.Sessions, session)
+		}
+	}
+	return res, nil
+}
+
+func (s *SessionService) GetRoomSession(ctx context.Context, req *GetRoomSessionRequest) (*GetRoomSessionResponse, error) {
+	AppendLogFields(ctx, "roomID", req.Sid)
+	if s.recorder == nil {
+		return nil, ErrSessionsDisabled
+	}
+	if req.Sid == "" {
+		return nil, fmt.Errorf("%w: sid is required", ErrSessionsInvalid)
+	}
+
+	session, err := s.recorder.store.LoadRoomSession(ctx, livekit.RoomID(req.Sid))
+	if err != nil {
+		return nil, err
+	}
+	if err = ensureSessionPermission(ctx, session.Name); err != nil {
+		return nil, err
+	}
+	participants, err := s.recorder.store.ListParticipantSessions(ctx, livekit.RoomID(req.Sid))
+	if err != nil {
+		return nil, err
+	}
+	return &GetRoomSessionResponse{
+		Session:      session,
+		Participants: participants,
+	}, nil
+}
+
+func (s *SessionService) ListParticipantSessions(ctx context.Context, req *ListParticipantSessionsRequest) (*ListParticipantSessionsResponse, error) {
+	AppendLogFields(ctx, "roomID", req.RoomSid, "participant", req.Identity)
+	if s.recorder == nil {
+		return nil, ErrSessionsDisabled
+	}
+	if req.RoomSid == "" {
+		return nil, fmt.Errorf("%w: room_sid is required", ErrSessionsInvalid)
+	}
+
+	sessions, err := s.recorder.store.ListParticipantSessions(ctx, livekit.RoomID(req.RoomSid))
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		// nothing tells which room it was
+		if err = EnsureListPermission(ctx); err != nil {
+			return nil, err
+		}
+	} else if err = ensureSessionPermission(ctx, sessions[0].RoomName); err != nil {
+		return nil, err
+	}
+
+	res := &ListParticipantSessionsResponse{Sessions: make([]*ParticipantSession, 0, len(sessions))}
+	for _, session := range sessions {
+		if req.Identity == "" || session.Identity == req.Identity {
+			res.Sessions = append(res.Sessions, session)
+		}
+	}
+	return res, nil
+}
+
+func ensureSessionPermission(ctx context.Context, roomName string) error {
+	if EnsureListPermission(ctx) == nil {
+		return nil
+	}
+	return EnsureAdminPermission(ctx, livekit.RoomName(roomName))
+}
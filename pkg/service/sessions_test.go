@@ -0,0 +1,117 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+func TestSessionService(t *testing.T) {
+	node, err := routing.NewLocalNode(nil)
+	require.NoError(t, err)
+
+	now := time.Now()
+	store := &servicefakes.FakeSessionStore{}
+	store.ListRoomSessionsReturns([]*service.RoomSession{
+		{Sid: "RM_3", Name: "standup", EndedAt: now.UnixMilli()},
+		{Sid: "RM_2", Name: "review", EndedAt: now.Add(-time.Hour).UnixMilli()},
+		{Sid: "RM_1", Name: "standup", EndedAt: now.Add(-24 * time.Hour).UnixMilli()},
+	}, nil)
+	store.LoadRoomSessionStub = func(_ context.Context, sid livekit.RoomID) (*service.RoomSession, error) {
+		if sid != "RM_1" {
+			return nil, service.ErrSessionNotFound
+		}
+		return &service.RoomSession{Sid: "RM_1", Name: "standup"}, nil
+	}
+	store.ListParticipantSessionsReturns([]*service.ParticipantSession{
+		{RoomSid: "RM_1", RoomName: "standup", Identity: "alice", DisconnectReason: livekit.DisconnectReason_CLIENT_INITIATED.String()},
+		{RoomSid: "RM_1", RoomName: "standup", Identity: "bob", DisconnectReason: livekit.DisconnectReason_JOIN_FAILURE.String()},
+	}, nil)
+
+	recorder := service.NewSessionRecorder(&config.Config{Sessions: config.SessionsConfig{Enabled: true}}, store, node)
+	require.NotNil(t, recorder)
+	svc := service.NewSessionService(recorder)
+
+	lister := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomList: true},
+	}, "")
+	admin := func(room string) context.Context {
+		return service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomAdmin: true, Room: room},
+		}, "")
+	}
+
+	t.Run("list rooms", func(t *testing.T) {
+		res, err := svc.ListRoomSessions(lister, &service.ListRoomSessionsRequest{})
+		require.NoError(t, err)
+		require.Len(t, res.Sessions, 3)
+		_, from, to, limit := store.ListRoomSessionsArgsForCall(store.ListRoomSessionsCallCount() - 1)
+		require.Equal(t, 100, limit)
+		require.WithinDuration(t, now.Add(-7*24*time.Hour), from, time.Minute)
+		require.True(t, to.After(now))
+
+		res, err = svc.ListRoomSessions(lister, &service.ListRoomSessionsRequest{Room: "standup", Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, res.Sessions, 1)
+		require.Equal(t, "RM_3", res.Sessions[0].Sid)
+
+		_, err = svc.ListRoomSessions(lister, &service.ListRoomSessionsRequest{StartTime: now.Unix(), EndTime: now.Add(-time.Hour).Unix()})
+		require.ErrorIs(t, err, service.ErrSessionsInvalid)
+		_, err = svc.ListRoomSessions(lister, &service.ListRoomSessionsRequest{Limit: 5000})
+		require.ErrorIs(t, err, service.ErrSessionsInvalid)
+		_, err = svc.ListRoomSessions(admin("standup"), &service.ListRoomSessionsRequest{})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+	})
+
+	t.Run("get room", func(t *testing.T) {
+		res, err := svc.GetRoomSession(admin("standup"), &service.GetRoomSessionRequest{Sid: "RM_1"})
+		require.NoError(t, err)
+		require.Equal(t, "standup", res.Session.Name)
+		require.Len(t, res.Participants, 2)
+
+		_, err = svc.GetRoomSession(admin("review"), &service.GetRoomSessionRequest{Sid: "RM_1"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+		_, err = svc.GetRoomSession(lister, &service.GetRoomSessionRequest{Sid: "RM_9"})
+		require.ErrorIs(t, err, service.ErrSessionNotFound)
+	})
+
+	t.Run("list participants", func(t *testing.T) {
+		res, err := svc.ListParticipantSessions(lister, &service.ListParticipantSessionsRequest{RoomSid: "RM_1", Identity: "bob"})
+		require.NoError(t, err)
+		require.Len(t, res.Sessions, 1)
+		require.Equal(t, livekit.DisconnectReason_JOIN_FAILURE.String(), res.Sessions[0].DisconnectReason)
+
+		_, err = svc.ListParticipantSessions(admin("review"), &service.ListParticipantSessionsRequest{RoomSid: "RM_1"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		require.Nil(t, service.NewSessionRecorder(&config.Config{}, store, node))
+		_, err := service.NewSessionService(nil).ListRoomSessions(lister, &service.ListRoomSessionsRequest{})
+		require.ErrorIs(t, err, service.ErrSessionsDisabled)
+	})
+}
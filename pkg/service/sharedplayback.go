@@ -0,0 +1,261 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/livekit/protocol/ingress"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/utils"
+	"github.com/livekit/protocol/utils/guid"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	sharedPlaybackRPCService = "SharedPlayback"
+	updateSharedPlaybackRPC  = "UpdateSharedPlayback"
+
+	maxSharedPlaybackRequest = 16 * 1024
+)
+
+// SharedPlaybackClient reaches the node hosting a room, which keeps the authoritative playback state.
+// Commands and states are carried as JSON in the payload of user data packets on rtc.SharedPlaybackTopic,
+// the returned packet is the one broadcast to the room.
+type SharedPlaybackClient interface {
+	UpdateSharedPlayback(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error)
+}
+
+type SharedPlaybackServerImpl interface {
+	UpdateSharedPlayback(ctx context.Context, roomName livekit.RoomName, cmd *rtc.SharedPlaybackCommand) (*rtc.SharedPlaybackState, error)
+}
+
+type sharedPlaybackClient struct {
+	client *client.RPCClient
+}
+
+func NewSharedPlaybackClient(params rpc.ClientParams) (SharedPlaybackClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: sharedPlaybackRPCService,
+		ID:   rand.NewClientID(),
+	}
+	sd.RegisterMethod(updateSharedPlaybackRPC, false, false, true, true)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &sharedPlaybackClient{client: rpcClient}, nil
+}
+
+func (c *sharedPlaybackClient) UpdateSharedPlayback(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return client.RequestSingle[*livekit.DataPacket](ctx, c.client, updateSharedPlaybackRPC, []string{string(room)}, req, opts...)
+}
+
+// sharedPlaybackServer handles shared playback commands for a room hosted on this node
+type sharedPlaybackServer struct {
+	svc      SharedPlaybackServerImpl
+	roomName livekit.RoomName
+	rpc      *server.RPCServer
+}
+
+func newSharedPlaybackServer(svc SharedPlaybackServerImpl, roomName livekit.RoomName, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *sharedPlaybackServer {
+	sd := &info.ServiceDefinition{
+		Name: sharedPlaybackRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	sd.RegisterMethod(updateSharedPlaybackRPC, false, false, true, true)
+	return &sharedPlaybackServer{
+		svc:      svc,
+		roomName: roomName,
+		rpc:      s,
+	}
+}
+
+func (s *sharedPlaybackServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	return server.RegisterHandler(s.rpc, updateSharedPlaybackRPC, []string{string(room)}, s.update, nil)
+}
+
+func (s *sharedPlaybackServer) update(ctx context.Context, req *livekit.DataPacket) (*livekit.DataPacket, error) {
+	var cmd rtc.SharedPlaybackCommand
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &cmd); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+	state, err := s.svc.UpdateSharedPlayback(ctx, s.roomName, &cmd)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSharedPlayback(state)
+}
+
+func (s *sharedPlaybackServer) Kill() {
+	s.rpc.Close(true)
+}
+
+func encodeSharedPlayback(v any) (*livekit.DataPacket, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	topic := rtc.SharedPlaybackTopic
+	return &livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload: payload,
+				Topic:   &topic,
+			},
+		},
+	}, nil
+}
+
+// ---------------------------------------------
+
+// SharedPlaybackRequest is the JSON body of POST /shared_playback/{start,play,pause,seek,stop}
+type SharedPlaybackRequest struct {
+	Room       string `json:"room"`
+	URL        string `json:"url,omitempty"`
+	PositionMs int64  `json:"position_ms,omitempty"`
+}
+
+// SharedPlaybackService serves the HTTP API controlling the shared player of a room. Starting a playback
+// streams the URL into the room through a URL ingress published as rtc.SharedPlaybackIdentity, so that
+// watch-together features don't depend on the uplink of one client. All calls require roomAdmin.
+type SharedPlaybackService struct {
+	roomStore      ServiceStore
+	topicFormatter rpc.TopicFormatter
+	client         SharedPlaybackClient
+	ingressClient  rpc.IngressClient
+}
+
+func NewSharedPlaybackService(
+	roomStore ServiceStore,
+	topicFormatter rpc.TopicFormatter,
+	client SharedPlaybackClient,
+	ingressClient rpc.IngressClient,
+) *SharedPlaybackService {
+	return &SharedPlaybackService{
+		roomStore:      roomStore,
+		topicFormatter: topicFormatter,
+		client:         client,
+		ingressClient:  ingressClient,
+	}
+}
+
+func (s *SharedPlaybackService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	action := rtc.SharedPlaybackAction(strings.TrimPrefix(r.URL.Path, "/shared_playback/"))
+	switch action {
+	case rtc.SharedPlaybackStart, rtc.SharedPlaybackPlay, rtc.SharedPlaybackPause, rtc.SharedPlaybackSeek, rtc.SharedPlaybackStop:
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	var req SharedPlaybackRequest
+	if err := decodeJSONRequest(r, &req, maxSharedPlaybackRequest); err != nil {
+		handleError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	res, err := s.UpdateSharedPlayback(r.Context(), action, &req)
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(res.GetUser().GetPayload())
+}
+
+// UpdateSharedPlayback returns the data packet broadcast to the room, its payload is the new state as JSON
+func (s *SharedPlaybackService) UpdateSharedPlayback(ctx context.Context, action rtc.SharedPlaybackAction, req *SharedPlaybackRequest) (*livekit.DataPacket, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName, "action", action)
+	if err := EnsureAdminPermission(ctx, roomName); err != nil {
+		return nil, err
+	}
+	if req.PositionMs < 0 {
+		return nil, fmt.Errorf("%w: position cannot be negative", ErrSharedPlaybackInvalid)
+	}
+	// the room must be active on a node to keep the playback state
+	if _, _, err := s.roomStore.LoadRoom(ctx, roomName, false); err != nil {
+		return nil, err
+	}
+
+	if action == rtc.SharedPlaybackStart {
+		if err := s.startIngress(ctx, roomName, req.URL); err != nil {
+			return nil, err
+		}
+	}
+
+	cmd, err := encodeSharedPlayback(&rtc.SharedPlaybackCommand{
+		Action:     action,
+		URL:        req.URL,
+		PositionMs: req.PositionMs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.client.UpdateSharedPlayback(ctx, s.topicFormatter.RoomTopic(ctx, roomName), cmd)
+}
+
+func (s *SharedPlaybackService) startIngress(ctx context.Context, roomName livekit.RoomName, url string) error {
+	if url == "" {
+		return fmt.Errorf("%w: url is required", ErrSharedPlaybackInvalid)
+	}
+	if s.ingressClient == nil {
+		return ErrIngressNotConnected
+	}
+
+	info := &livekit.IngressInfo{
+		IngressId:           guid.New(utils.IngressPrefix),
+		Name:                "shared playback",
+		Url:                 url,
+		InputType:           livekit.IngressInput_URL_INPUT,
+		RoomName:            string(roomName),
+		ParticipantIdentity: rtc.SharedPlaybackIdentity,
+		ParticipantName:     "Shared playback",
+		State:               &livekit.IngressState{},
+	}
+	if err := ingress.Validate(info); err != nil {
+		return psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+	updateEnableTranscoding(info)
+
+	if _, err := s.ingressClient.StartIngress(ctx, &rpc.StartIngressRequest{Info: info}); err != nil {
+		logger.Warnw("could not start shared playback ingress", err, "room", roomName, "url", url)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+const (
+	SignalCaptureDirectionRequest  = "request"
+	SignalCaptureDirectionResponse = "response"
+)
+
+// SignalCapture holds the signal messages exchanged with a participant while the capture was running
+type SignalCapture struct {
+	Room      livekit.RoomName            `json:"room"`
+	Identity  livekit.ParticipantIdentity `json:"identity"`
+	StartedAt time.Time                   `json:"started_at"`
+	EndsAt    time.Time                   `json:"ends_at"`
+	Messages  []*SignalCaptureMessage     `json:"messages"`
+	// messages dropped once max_messages was reached
+	Dropped int `json:"dropped,omitempty"`
+}
+
+type SignalCaptureMessage struct {
+	Time time.Time `json:"time"`
+	// signal connection the message went through, it changes when the participant resumes
+	ConnectionID livekit.ConnectionID `json:"connection_id"`
+	// request from the client, or response from the server
+	Direction string `json:"direction"`
+	// field set in the message, e.g. offer or trickle
+	Type    string          `json:"type"`
+	Message json.RawMessage `json:"message"`
+}
+
+type signalCaptureKey struct {
+	room     livekit.RoomName
+	identity livekit.ParticipantIdentity
+}
+
+// signalCaptures records the signal messages of the participants of this node a capture was started for.
+// Captures are dropped once their retention passed.
+type signalCaptures struct {
+	conf config.SignalCaptureConfig
+
+	lock     sync.Mutex
+	captures map[signalCaptureKey]*SignalCapture
+}
+
+// newSignalCaptures returns nil when signal capture is disabled
+func newSignalCaptures(conf config.SignalCaptureConfig) *signalCaptures {
+	if !conf.Enabled {
+		return nil
+	}
+	return &signalCaptures{
+		conf:     conf,
+		captures: make(map[signalCaptureKey]*SignalCapture),
+	}
+}
+
+// start records the messages of current and future sessions of the participant, restarting any
+// capture of the participant
+func (s *signalCaptures) start(roomName livekit.RoomName, identity livekit.ParticipantIdentity, duration time.Duration) *SignalCapture {
+	if duration <= 0 || duration > s.conf.MaxDuration {
+		duration = s.conf.MaxDuration
+	}
+	now := time.Now()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pruneLocked(now)
+	c := &SignalCapture{
+		Room:      roomName,
+		Identity:  identity,
+		StartedAt: now,
+		EndsAt:    now.Add(duration),
+	}
+	s.captures[signalCaptureKey{roomName, identity}] = c
+	return copySignalCapture(c)
+}
+
+func (s *signalCaptures) stop(roomName livekit.RoomName, identity livekit.ParticipantIdentity) *SignalCapture {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	c := s.captures[signalCaptureKey{roomName, identity}]
+	if c == nil {
+		return nil
+	}
+	if now := time.Now(); now.Before(c.EndsAt) {
+		c.EndsAt = now
+	}
+	return copySignalCapture(c)
+}
+
+func (s *signalCaptures) get(roomName livekit.RoomName, identity livekit.ParticipantIdentity) *SignalCapture {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pruneLocked(time.Now())
+	c := s.captures[signalCaptureKey{roomName, identity}]
+	if c == nil {
+		return nil
+	}
+	return copySignalCapture(c)
+}
+
+func (s *signalCaptures) record(
+	roomName livekit.RoomName,
+	identity livekit.ParticipantIdentity,
+	connID livekit.ConnectionID,
+	direction string,
+	msg proto.Message,
+) {
+	if s == nil {
+		return
+	}
+	now := time.Now()
+	s.lock.Lock()
+	c := s.captures[signalCaptureKey{roomName, identity}]
+	active := c != nil && now.Before(c.EndsAt)
+	s.lock.Unlock()
+	if !active {
+		return
+	}
+
+	msgType, payload := encodeSignalMessage(msg, s.conf.IncludeAddresses)
+	m := &SignalCaptureMessage{
+		Time:         now,
+		ConnectionID: connID,
+		Direction:    direction,
+		Type:         msgType,
+		Message:      payload,
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	c.Messages = append(c.Messages, m)
+	if len(c.Messages) > s.conf.MaxMessages {
+		c.Messages = c.Messages[1:]
+		c.Dropped++
+	}
+}
+
+// wrapSink records the responses written to the sink of a participant
+func (s *signalCaptures) wrapSink(roomName livekit.RoomName, identity livekit.ParticipantIdentity, sink routing.MessageSink) routing.MessageSink {
+	if s == nil {
+		return sink
+	}
+	return &signalCaptureSink{
+		MessageSink: sink,
+		captures:    s,
+		roomName:    roomName,
+		identity:    identity,
+	}
+}
+
+func (s *signalCaptures) pruneLocked(now time.Time) {
+	for key, c := range s.captures {
+		if now.After(c.EndsAt.Add(s.conf.Retention)) {
+			delete(s.captures, key)
+		}
+	}
+}
+
+func copySignalCapture(c *SignalCapture) *SignalCapture {
+	cc := *c
+	cc.Messages = append([]*SignalCaptureMessage(nil), c.Messages...)
+	return &cc
+}
+
+type signalCaptureSink struct {
+	routing.MessageSink
+	captures *signalCaptures
+	roomName livekit.RoomName
+	identity livekit.ParticipantIdentity
+}
+
+func (s *signalCaptureSink) WriteMessage(msg proto.Message) error {
+	s.captures.record(s.roomName, s.identity, s.ConnectionID(), SignalCaptureDirectionResponse, msg)
+	return s.MessageSink.WriteMessage(msg)
+}
+
+// ---------------------------------------------
+
+// encodeSignalMessage returns the name of the field set in a signal request or response, and the message
+// without secrets as JSON
+func encodeSignalMessage(msg proto.Message, includeAddresses bool) (string, json.RawMessage) {
+	msg = sanitizeSignalMessage(msg, includeAddresses)
+
+	var msgType string
+	m := msg.ProtoReflect()
+	if od := m.Descriptor().Oneofs().ByName("message"); od != nil {
+		if fd := m.WhichOneof(od); fd != nil {
+			msgType = string(fd.Name())
+		}
+	}
+	payload, err := protojson.Marshal(msg)
+	if err != nil {
+		payload, _ = json.Marshal(err.Error())
+	}
+	return msgType, payload
+}
+
+func sanitizeSignalMessage(msg proto.Message, includeAddresses bool) proto.Message {
+	msg = proto.Clone(msg)
+	switch m := msg.(type) {
+	case *livekit.SignalRequest:
+		switch v := m.Message.(type) {
+		case *livekit.SignalRequest_Offer:
+			sanitizeSessionDescription(v.Offer, includeAddresses)
+		case *livekit.SignalRequest_Answer:
+			sanitizeSessionDescription(v.Answer, includeAddresses)
+		case *livekit.SignalRequest_Trickle:
+			sanitizeTrickle(v.Trickle, includeAddresses)
+		}
+	case *livekit.SignalResponse:
+		switch v := m.Message.(type) {
+		case *livekit.SignalResponse_Join:
+			sanitizeICEServers(v.Join.GetIceServers())
+		case *livekit.SignalResponse_Reconnect:
+			sanitizeICEServers(v.Reconnect.GetIceServers())
+		case *livekit.SignalResponse_RefreshToken:
+			v.RefreshToken = utils.DefaultRedactionReplacement
+		case *livekit.SignalResponse_Offer:
+			sanitizeSessionDescription(v.Offer, includeAddresses)
+		case *livekit.SignalResponse_Answer:
+			sanitizeSessionDescription(v.Answer, includeAddresses)
+		case *livekit.SignalResponse_Trickle:
+			sanitizeTrickle(v.Trickle, includeAddresses)
+		}
+	}
+	return msg
+}
+
+func sanitizeICEServers(servers []*livekit.ICEServer) {
+	for _, s := range servers {
+		if s.Username != "" {
+			s.Username = utils.DefaultRedactionReplacement
+		}
+		if s.Credential != "" {
+			s.Credential = utils.DefaultRedactionReplacement
+		}
+	}
+}
+
+func sanitizeSessionDescription(sd *livekit.SessionDescription, includeAddresses bool) {
+	if sd == nil {
+		return
+	}
+	lines := strings.Split(sd.Sdp, "\n")
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "a=ice-pwd:"):
+			line = "a=ice-pwd:" + utils.DefaultRedactionReplacement
+		case !includeAddresses && strings.HasPrefix(line, "a=candidate:"):
+			line = "a=" + sanitizeCandidate(strings.TrimPrefix(line, "a="))
+		case !includeAddresses && strings.HasPrefix(line, "c="):
+			if fields := strings.Fields(line); len(fields) == 3 {
+				line = fields[0] + " " + fields[1] + " " + utils.DefaultRedactionReplacement
+			}
+		default:
+			continue
+		}
+		if strings.HasSuffix(lines[i], "\r") {
+			line += "\r"
+		}
+		lines[i] = line
+	}
+	sd.Sdp = strings.Join(lines, "\n")
+}
+
+func sanitizeTrickle(t *livekit.TrickleRequest, includeAddresses bool) {
+	if t == nil || includeAddresses {
+		return
+	}
+	var init map[string]any
+	if err := json.Unmarshal([]byte(t.CandidateInit), &init); err != nil {
+		t.CandidateInit = utils.DefaultRedactionReplacement
+		return
+	}
+	if candidate, ok := init["candidate"].(string); ok {
+		init["candidate"] = sanitizeCandidate(candidate)
+	}
+	if data, err := json.Marshal(init); err == nil {
+		t.CandidateInit = string(data)
+	}
+}
+
+// sanitizeCandidate removes the address and related address of an ICE candidate
+func sanitizeCandidate(candidate string) string {
+	fields := strings.Fields(candidate)
+	if len(fields) < 6 {
+		return candidate
+	}
+	fields[4] = utils.DefaultRedactionReplacement
+	for i := 5; i < len(fields)-1; i++ {
+		if fields[i] == "raddr" {
+			fields[i+1] = utils.DefaultRedactionReplacement
+		}
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestSignalCaptures(t *testing.T) {
+	require.Nil(t, newSignalCaptures(config.SignalCaptureConfig{}))
+
+	s := newSignalCaptures(config.SignalCaptureConfig{
+		Enabled:     true,
+		MaxMessages: 2,
+		MaxDuration: time.Minute,
+		Retention:   time.Minute,
+	})
+	leave := &livekit.SignalRequest{Message: &livekit.SignalRequest_Leave{Leave: &livekit.LeaveRequest{}}}
+
+	// not recorded before the capture starts
+	s.record("room", "alice", "CO_1", SignalCaptureDirectionRequest, leave)
+	require.Nil(t, s.get("room", "alice"))
+
+	capture := s.start("room", "alice", time.Hour)
+	require.Equal(t, time.Minute, capture.EndsAt.Sub(capture.StartedAt))
+
+	s.record("room", "bob", "CO_2", SignalCaptureDirectionRequest, leave)
+	s.record("room", "alice", "CO_1", SignalCaptureDirectionResponse, &livekit.SignalResponse{
+		Message: &livekit.SignalResponse_RefreshToken{RefreshToken: "token"},
+	})
+	s.record("room", "alice", "CO_1", SignalCaptureDirectionRequest, leave)
+	s.record("room", "alice", "CO_1", SignalCaptureDirectionRequest, leave)
+
+	capture = s.get("room", "alice")
+	require.Len(t, capture.Messages, 2)
+	require.Equal(t, 1, capture.Dropped)
+	require.Equal(t, "leave", capture.Messages[0].Type)
+	require.Equal(t, SignalCaptureDirectionRequest, capture.Messages[0].Direction)
+
+	s.stop("room", "alice")
+	s.record("room", "alice", "CO_1", SignalCaptureDirectionRequest, leave)
+	require.Len(t, s.get("room", "alice").Messages, 2)
+	require.Nil(t, s.get("room", "bob"))
+}
+
+func TestSanitizeSignalMessage(t *testing.T) {
+	candidate := "candidate:1 1 udp 2130706431 192.168.1.10 50000 typ srflx raddr 10.0.0.1 rport 50000"
+	offer := &livekit.SignalRequest{Message: &livekit.SignalRequest_Offer{Offer: &livekit.SessionDescription{
+		Type: "offer",
+		Sdp:  "v=0\r\nc=IN IP4 192.168.1.10\r\na=ice-pwd:secret\r\na=" + candidate + "\r\n",
+	}}}
+
+	sanitized := sanitizeSignalMessage(offer, false).(*livekit.SignalRequest)
+	require.Equal(t,
+		"v=0\r\nc=IN IP4 [redacted]\r\na=ice-pwd:[redacted]\r\na=candidate:1 1 udp 2130706431 [redacted] 50000 typ srflx raddr [redacted] rport 50000\r\n",
+		sanitized.GetOffer().Sdp,
+	)
+	// the message itself is left unchanged
+	require.Contains(t, offer.GetOffer().Sdp, "secret")
+
+	sanitized = sanitizeSignalMessage(offer, true).(*livekit.SignalRequest)
+	require.Contains(t, sanitized.GetOffer().Sdp, candidate)
+	require.NotContains(t, sanitized.GetOffer().Sdp, "secret")
+
+	trickle := &livekit.SignalRequest{Message: &livekit.SignalRequest_Trickle{Trickle: &livekit.TrickleRequest{
+		CandidateInit: `{"candidate":"` + candidate + `","sdpMid":"0"}`,
+	}}}
+	sanitized = sanitizeSignalMessage(trickle, false).(*livekit.SignalRequest)
+	require.NotContains(t, sanitized.GetTrickle().CandidateInit, "192.168.1.10")
+	require.Contains(t, sanitized.GetTrickle().CandidateInit, `"sdpMid":"0"`)
+
+	join := &livekit.SignalResponse{Message: &livekit.SignalResponse_Join{Join: &livekit.JoinResponse{
+		IceServers: []*livekit.ICEServer{{Urls: []string{"turn:turn.example.com"}, Username: "user", Credential: "password"}},
+	}}}
+	res := sanitizeSignalMessage(join, false).(*livekit.SignalResponse)
+	require.Equal(t, "[redacted]", res.GetJoin().IceServers[0].Credential)
+	require.Equal(t, []string{"turn:turn.example.com"}, res.GetJoin().IceServers[0].Urls)
+}
@@ -0,0 +1,244 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/urfave/negroni/v3"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// limits of config.SignalRateLimitConfig, used as the label of refused attempts
+const (
+	SignalRateLimitIP           = "ip"
+	SignalRateLimitAPIKey       = "api_key"
+	SignalRateLimitIdentity     = "identity"
+	SignalRateLimitReconnects   = "reconnects"
+	SignalRateLimitAuthFailures = "auth_failures"
+
+	// SignalRateLimitKeyPrefix is the prefix of the counters of a window, shared by all nodes
+	SignalRateLimitKeyPrefix = "signal_rate_limit:"
+)
+
+// rateLimitCounter counts events of keys in fixed windows aligned to the clock
+type rateLimitCounter interface {
+	// add adds n events to the current window of the key and returns the count of the window
+	add(ctx context.Context, key string, window time.Duration, n int64) (int64, error)
+}
+
+func rateLimitWindow(now time.Time, window time.Duration) int64 {
+	return now.UnixNano() / int64(window)
+}
+
+type redisRateLimitCounter struct {
+	rc redis.UniversalClient
+}
+
+func (c *redisRateLimitCounter) add(ctx context.Context, key string, window time.Duration, n int64) (int64, error) {
+	key = SignalRateLimitKeyPrefix + key + ":" + strconv.FormatInt(rateLimitWindow(time.Now(), window), 10)
+	tx := c.rc.TxPipeline()
+	count := tx.IncrBy(ctx, key, n)
+	tx.Expire(ctx, key, window)
+	if _, err := tx.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+type localRateLimitCount struct {
+	window int64
+	count  int64
+}
+
+// localRateLimitCounter is used without Redis, each node counting the attempts it serves
+type localRateLimitCounter struct {
+	lock      sync.Mutex
+	counts    map[string]*localRateLimitCount
+	lastSweep time.Time
+}
+
+func (c *localRateLimitCounter) add(_ context.Context, key string, window time.Duration, n int64) (int64, error) {
+	now := time.Now()
+	w := rateLimitWindow(now, window)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// counts of past windows are dropped once a window, so that one off clients are not kept forever
+	if now.Sub(c.lastSweep) >= window {
+		c.lastSweep = now
+		for k, count := range c.counts {
+			if count.window != w {
+				delete(c.counts, k)
+			}
+		}
+	}
+	count := c.counts[key]
+	if count == nil || count.window != w {
+		count = &localRateLimitCount{window: w}
+		c.counts[key] = count
+	}
+	count.count += n
+	return count.count, nil
+}
+
+// SignalRateLimiter refuses attempts to connect to the signaling endpoints over the limits per client IP,
+// API key and participant identity, to weather join floods and credential stuffing.
+type SignalRateLimiter struct {
+	conf      config.SignalRateLimitConfig
+	counter   rateLimitCounter
+	addresses *ClientAddresses
+}
+
+// NewSignalRateLimiter returns nil when signal rate limiting is not enabled. Counters are kept in Redis when
+// a client is given.
+func NewSignalRateLimiter(conf *config.Config, rc redis.UniversalClient) *SignalRateLimiter {
+	if !conf.SignalRateLimit.Enabled {
+		return nil
+	}
+	l := &SignalRateLimiter{
+		conf:      conf.SignalRateLimit,
+		addresses: NewClientAddresses(conf),
+	}
+	if rc != nil {
+		l.counter = &redisRateLimitCounter{rc: rc}
+	} else {
+		l.counter = &localRateLimitCounter{
+			counts:    make(map[string]*localRateLimitCount),
+			lastSweep: time.Now(),
+		}
+	}
+	return l
+}
+
+// check counts an attempt of the key and returns ErrSignalRateLimited once more than limit were made in the
+// window. Attempts are allowed when counters cannot be reached, a limit of 0 disables the check.
+func (l *SignalRateLimiter) check(ctx context.Context, name string, limit int, key string) error {
+	if limit <= 0 {
+		return nil
+	}
+	count, err := l.counter.add(ctx, name+":"+key, l.conf.Window, 1)
+	if err != nil {
+		logger.Warnw("could not count signal connection attempt", err, "limit", name)
+		return nil
+	}
+	if count > int64(limit) {
+		prometheus.RecordSignalRateLimited(name)
+		return ErrSignalRateLimited
+	}
+	return nil
+}
+
+// checkAuthFailures returns ErrSignalRateLimited once the IP failed authentication as often as allowed
+func (l *SignalRateLimiter) checkAuthFailures(ctx context.Context, ip string) error {
+	if l.conf.AuthFailuresPerIP <= 0 {
+		return nil
+	}
+	failures, err := l.counter.add(ctx, SignalRateLimitAuthFailures+":"+ip, l.conf.Window, 0)
+	if err != nil {
+		logger.Warnw("could not count signal authentication failures", err)
+		return nil
+	}
+	if failures >= int64(l.conf.AuthFailuresPerIP) {
+		prometheus.RecordSignalRateLimited(SignalRateLimitAuthFailures)
+		return ErrSignalRateLimited
+	}
+	return nil
+}
+
+// PreAuth returns the middleware limiting attempts per client IP, it has to run before authentication to see
+// authentication failures
+func (l *SignalRateLimiter) PreAuth() negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if !isSignalRequest(r) {
+			next(w, r)
+			return
+		}
+		ip := l.addresses.ClientIPString(r)
+		// failures are only read here, they are counted once a request failed
+		if err := l.checkAuthFailures(r.Context(), ip); err != nil {
+			l.refuse(w, r, err)
+			return
+		}
+		if err := l.check(r.Context(), SignalRateLimitIP, l.conf.PerIP, ip); err != nil {
+			l.refuse(w, r, err)
+			return
+		}
+
+		next(w, r)
+
+		if rw, ok := w.(negroni.ResponseWriter); ok && rw.Status() == http.StatusUnauthorized && l.conf.AuthFailuresPerIP > 0 {
+			if _, err := l.counter.add(r.Context(), SignalRateLimitAuthFailures+":"+ip, l.conf.Window, 1); err != nil {
+				logger.Warnw("could not count signal authentication failure", err)
+			}
+		}
+	})
+}
+
+// PostAuth returns the middleware limiting attempts per API key and participant identity, it has to run after
+// authentication
+func (l *SignalRateLimiter) PostAuth() negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		claims := GetGrants(r.Context())
+		if !isSignalRequest(r) || claims == nil {
+			next(w, r)
+			return
+		}
+		ctx := r.Context()
+		apiKey := GetAPIKey(ctx)
+		if apiKey != "" {
+			if err := l.check(ctx, SignalRateLimitAPIKey, l.conf.PerAPIKey, apiKey); err != nil {
+				l.refuse(w, r, err)
+				return
+			}
+		}
+		if claims.Identity != "" {
+			identity := apiKey + "/" + claims.Identity
+			if err := l.check(ctx, SignalRateLimitIdentity, l.conf.PerIdentity, identity); err != nil {
+				l.refuse(w, r, err)
+				return
+			}
+			if boolValue(r.URL.Query().Get("reconnect")) {
+				if err := l.check(ctx, SignalRateLimitReconnects, l.conf.ReconnectsPerIdentity, identity); err != nil {
+					l.refuse(w, r, err)
+					return
+				}
+			}
+		}
+		next(w, r)
+	})
+}
+
+// refuse tells the client to retry once the window ends
+func (l *SignalRateLimiter) refuse(w http.ResponseWriter, r *http.Request, err error) {
+	now := time.Now()
+	end := time.Unix(0, (rateLimitWindow(now, l.conf.Window)+1)*int64(l.conf.Window))
+	w.Header().Set("Retry-After", strconv.Itoa(int(end.Sub(now).Seconds())+1))
+	handleError(w, r, http.StatusTooManyRequests, err)
+}
+
+func isSignalRequest(r *http.Request) bool {
+	return r.URL != nil && (r.URL.Path == "/rtc" || r.URL.Path == "/rtc/validate")
+}
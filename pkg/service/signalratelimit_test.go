@@ -0,0 +1,150 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/negroni/v3"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+func TestSignalRateLimiter(t *testing.T) {
+	prometheus.Init("test", livekit.NodeType_SERVER)
+	const (
+		apiKey = "APIkey"
+		secret = "somesecretencodedinbase62extendto32bytes"
+	)
+	keyProvider := auth.NewFileBasedKeyProviderFromMap(map[string]string{apiKey: secret})
+	newToken := func(t *testing.T, identity string) string {
+		token, err := auth.NewAccessToken(apiKey, secret).
+			SetIdentity(identity).
+			AddGrant(&auth.VideoGrant{RoomJoin: true, Room: "main"}).
+			ToJWT()
+		require.NoError(t, err)
+		return token
+	}
+
+	newHandler := func(conf config.SignalRateLimitConfig, rc redis.UniversalClient) http.Handler {
+		conf.Enabled = true
+		conf.Window = time.Hour
+		l := service.NewSignalRateLimiter(&config.Config{SignalRateLimit: conf, TrustedProxies: []string{"10.255.0.0/16"}}, rc)
+		n := negroni.New(l.PreAuth(), service.NewAPIKeyAuthMiddleware(keyProvider), l.PostAuth())
+		n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+		return n
+	}
+	serve := func(h http.Handler, ip, path, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.RemoteAddr = net.JoinHostPort(ip, "5000")
+		if token != "" {
+			service.SetAuthorizationToken(r, token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("per IP", func(t *testing.T) {
+		h := newHandler(config.SignalRateLimitConfig{PerIP: 2}, nil)
+		require.Equal(t, http.StatusOK, serve(h, "10.0.0.1", "/rtc", "").Code)
+		require.Equal(t, http.StatusOK, serve(h, "10.0.0.1", "/rtc/validate", "").Code)
+		w := serve(h, "10.0.0.1", "/rtc", "")
+		require.Equal(t, http.StatusTooManyRequests, w.Code)
+		require.NotEmpty(t, w.Header().Get("Retry-After"))
+		require.Equal(t, http.StatusOK, serve(h, "10.0.0.2", "/rtc", "").Code)
+		// other endpoints are not limited
+		require.Equal(t, http.StatusOK, serve(h, "10.0.0.1", "/twirp/livekit.RoomService/ListRooms", "").Code)
+	})
+
+	t.Run("forwarded addresses", func(t *testing.T) {
+		h := newHandler(config.SignalRateLimitConfig{PerIP: 2}, nil)
+		serveForwarded := func(peer, forwardedFor string) int {
+			r := httptest.NewRequest(http.MethodGet, "/rtc", nil)
+			r.RemoteAddr = net.JoinHostPort(peer, "5000")
+			r.Header.Set("X-Forwarded-For", forwardedFor)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			return w.Code
+		}
+		// clients changing the header are still counted by their address
+		require.Equal(t, http.StatusOK, serveForwarded("10.0.0.1", "192.0.2.1"))
+		require.Equal(t, http.StatusOK, serveForwarded("10.0.0.1", "192.0.2.2"))
+		require.Equal(t, http.StatusTooManyRequests, serveForwarded("10.0.0.1", "192.0.2.3"))
+
+		// clients behind a trusted proxy are counted separately
+		require.Equal(t, http.StatusOK, serveForwarded("10.255.0.1", "192.0.2.1"))
+		require.Equal(t, http.StatusOK, serveForwarded("10.255.0.2", "192.0.2.1"))
+		require.Equal(t, http.StatusTooManyRequests, serveForwarded("10.255.0.1", "192.0.2.1"))
+		require.Equal(t, http.StatusOK, serveForwarded("10.255.0.1", "192.0.2.2"))
+	})
+
+	t.Run("auth failures", func(t *testing.T) {
+		h := newHandler(config.SignalRateLimitConfig{AuthFailuresPerIP: 2}, nil)
+		require.Equal(t, http.StatusUnauthorized, serve(h, "10.0.0.1", "/rtc", "invalid").Code)
+		require.Equal(t, http.StatusUnauthorized, serve(h, "10.0.0.1", "/rtc", "invalid").Code)
+		// valid tokens of the IP are refused as well until the window ends
+		require.Equal(t, http.StatusTooManyRequests, serve(h, "10.0.0.1", "/rtc", newToken(t, "alice")).Code)
+		require.Equal(t, http.StatusOK, serve(h, "10.0.0.2", "/rtc", newToken(t, "alice")).Code)
+	})
+
+	t.Run("per identity", func(t *testing.T) {
+		h := newHandler(config.SignalRateLimitConfig{PerIdentity: 3, ReconnectsPerIdentity: 1}, nil)
+		alice := newToken(t, "alice")
+		require.Equal(t, http.StatusOK, serve(h, "10.0.0.1", "/rtc", alice).Code)
+		require.Equal(t, http.StatusOK, serve(h, "10.0.0.2", "/rtc?reconnect=1", alice).Code)
+		require.Equal(t, http.StatusTooManyRequests, serve(h, "10.0.0.3", "/rtc?reconnect=1", alice).Code)
+		require.Equal(t, http.StatusTooManyRequests, serve(h, "10.0.0.4", "/rtc", alice).Code)
+		require.Equal(t, http.StatusOK, serve(h, "10.0.0.1", "/rtc", newToken(t, "bob")).Code)
+	})
+
+	t.Run("per API key", func(t *testing.T) {
+		h := newHandler(config.SignalRateLimitConfig{PerAPIKey: 2}, nil)
+		require.Equal(t, http.StatusOK, serve(h, "10.0.0.1", "/rtc", newToken(t, "alice")).Code)
+		require.Equal(t, http.StatusOK, serve(h, "10.0.0.2", "/rtc", newToken(t, "bob")).Code)
+		require.Equal(t, http.StatusTooManyRequests, serve(h, "10.0.0.3", "/rtc", newToken(t, "carol")).Code)
+	})
+
+	t.Run("shared through redis", func(t *testing.T) {
+		rc := redisClient(t)
+		ip := fmt.Sprintf("10.%d.%d.%d", rand.Intn(255), rand.Intn(256), rand.Intn(256))
+		nodes := []http.Handler{
+			newHandler(config.SignalRateLimitConfig{PerIP: 2}, rc),
+			newHandler(config.SignalRateLimitConfig{PerIP: 2}, rc),
+		}
+		require.Equal(t, http.StatusOK, serve(nodes[0], ip, "/rtc", "").Code)
+		require.Equal(t, http.StatusOK, serve(nodes[1], ip, "/rtc", "").Code)
+		require.Equal(t, http.StatusTooManyRequests, serve(nodes[0], ip, "/rtc", "").Code)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		require.Nil(t, service.NewSignalRateLimiter(&config.Config{}, nil))
+	})
+}
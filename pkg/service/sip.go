@@ -16,9 +16,12 @@ package service
 
 import (
 	"context"
+	"errors"
+	"regexp"
 	"time"
 
 	"github.com/twitchtv/twirp"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/livekit/protocol/livekit"
@@ -33,6 +36,8 @@ import (
 	"github.com/livekit/livekit-server/pkg/telemetry"
 )
 
+var sipStatusRegexp = regexp.MustCompile(`(?i)sip status:?\s*(\d{3})`)
+
 type SIPService struct {
 	conf        *config.SIPConfig
 	nodeID      livekit.NodeID
@@ -40,6 +45,8 @@ type SIPService struct {
 	psrpcClient rpc.SIPClient
 	store       SIPStore
 	roomService livekit.RoomService
+	policy      *SIPNumberPolicy
+	quotas      *QuotaEnforcer
 }
 
 func NewSIPService(
@@ -50,7 +57,12 @@ func NewSIPService(
 	store SIPStore,
 	rs livekit.RoomService,
 	ts telemetry.TelemetryService,
-) *SIPService {
+	quotas *QuotaEnforcer,
+) (*SIPService, error) {
+	policy, err := NewSIPNumberPolicy(conf.OutboundPolicy)
+	if err != nil {
+		return nil, err
+	}
 	return &SIPService{
 		conf:        conf,
 		nodeID:      nodeID,
@@ -58,7 +70,9 @@ func NewSIPService(
 		psrpcClient: psrpcClient,
 		store:       store,
 		roomService: rs,
-	}
+		policy:      policy,
+		quotas:      quotas,
+	}, nil
 }
 
 func (s *SIPService) CreateSIPTrunk(ctx context.Context, req *livekit.CreateSIPTrunkRequest) (*livekit.SIPTrunkInfo, error) {
@@ -357,7 +371,14 @@ func (s *SIPService) DeleteSIPDispatchRule(ctx context.Context, req *livekit.Del
 	return info, nil
 }
 
-func (s *SIPService) CreateSIPParticipant(ctx context.Context, req *livekit.CreateSIPParticipantRequest) (*livekit.SIPParticipantInfo, error) {
+func (s *SIPService) CreateSIPParticipant(ctx context.Context, req *livekit.CreateSIPParticipantRequest) (info *livekit.SIPParticipantInfo, err error) {
+	// the trace is passed on to the SIP service, and from there to the join of the SIP participant
+	ctx, span := telemetry.StartSpan(ctx, "SIPService.CreateSIPParticipant",
+		attribute.String("room", req.RoomName),
+		attribute.String("trunk_id", req.SipTrunkId),
+	)
+	defer func() { telemetry.EndSpan(span, err) }()
+
 	unlikelyLogger := logger.GetLogger().WithUnlikelyValues("room", req.RoomName, "sipTrunk", req.SipTrunkId, "toUser", req.SipCallTo)
 	ireq, err := s.CreateSIPParticipantRequest(ctx, req, "", "", "", "")
 	if err != nil {
@@ -369,6 +390,7 @@ func (s *SIPService) CreateSIPParticipant(ctx context.Context, req *livekit.Crea
 		"fromUser", ireq.Number,
 		"toHost", ireq.Address,
 	)
+	span.SetAttributes(attribute.String("call_id", ireq.SipCallId))
 	AppendLogFields(ctx,
 		"room", req.RoomName,
 		"toUser", req.SipCallTo,
@@ -389,8 +411,15 @@ func (s *SIPService) CreateSIPParticipant(ctx context.Context, req *livekit.Crea
 		ctx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
 	}
+	// answered calls and post-dial delay are recorded from the call state updates of the SIP service
+	if err := recordSIPOutboundAttempt(ctx, s.store, ireq.SipCallId, req.SipTrunkId); err != nil {
+		unlikelyLogger.Warnw("could not record sip call metrics", err)
+	}
 	resp, err := s.psrpcClient.CreateSIPParticipant(ctx, "", ireq, psrpc.WithRequestTimeout(timeout))
 	if err != nil {
+		if err := recordSIPOutboundFailure(ctx, s.store, ireq.SipCallId, req.SipTrunkId, err); err != nil {
+			unlikelyLogger.Warnw("could not record sip call metrics", err)
+		}
 		unlikelyLogger.Errorw("cannot update sip participant", err)
 		return nil, err
 	}
@@ -425,6 +454,16 @@ func (s *SIPService) CreateSIPParticipantRequest(ctx context.Context, req *livek
 		log.Errorw("cannot get trunk to update sip participant", err)
 		return nil, err
 	}
+	if err = s.policy.Check(trunk.SipTrunkId, req.SipCallTo); err != nil {
+		log.Warnw("sip call rejected by outbound policy", err)
+		return nil, err
+	}
+	if s.quotas != nil {
+		if err = s.quotas.CheckSIPParticipant(ctx, livekit.RoomName(req.RoomName)); err != nil {
+			log.Warnw("sip call rejected by quota", err)
+			return nil, err
+		}
+	}
 	return rpc.NewCreateSIPParticipantRequest(projectID, callID, host, wsUrl, token, req, trunk)
 }
 
@@ -488,6 +527,9 @@ func (s *SIPService) transferSIPParticipantRequest(ctx context.Context, req *liv
 	if !ok {
 		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "no SIP session associated with participant")
 	}
+	if err = s.policy.CheckTransfer(resp.Attributes[livekit.AttrSIPTrunkID], req.TransferTo); err != nil {
+		return nil, err
+	}
 
 	return &rpc.InternalTransferSIPParticipantRequest{
 		SipCallId:    callID,
@@ -495,3 +537,23 @@ func (s *SIPService) transferSIPParticipantRequest(ctx context.Context, req *liv
 		PlayDialtone: req.PlayDialtone,
 	}, nil
 }
+
+// sipStatusCode extracts the SIP response code from an error message reported by the SIP service.
+func sipStatusCode(msg string) string {
+	if m := sipStatusRegexp.FindStringSubmatch(msg); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// sipErrorCode returns the SIP response code of a failed call if known, otherwise the psrpc error code.
+func sipErrorCode(err error) string {
+	if code := sipStatusCode(err.Error()); code != "" {
+		return code
+	}
+	var pErr psrpc.Error
+	if errors.As(err, &pErr) {
+		return string(pErr.Code())
+	}
+	return "unknown"
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	// AttrSIPCallerName is set on inbound SIP participants to the caller name returned by the lookup
+	AttrSIPCallerName = livekit.AttrSIPPrefix + "callerName"
+
+	defaultSIPCallerNameTimeout  = time.Second
+	defaultSIPCallerNameCacheTTL = time.Hour
+	sipCallerNameCacheCapacity   = 10000
+)
+
+// SIPCallerNameRequest is posted as JSON to the caller name endpoint for numbers missing from the cache.
+type SIPCallerNameRequest struct {
+	Number  string `json:"number"`
+	CallID  string `json:"call_id,omitempty"`
+	TrunkID string `json:"trunk_id,omitempty"`
+}
+
+// SIPCallerNameResponse is returned by the caller name endpoint, an empty name when the number is unknown.
+type SIPCallerNameResponse struct {
+	Name string `json:"name,omitempty"`
+	// added to the participant attributes, e.g. the line type or carrier
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// SIPCallerNameLookup resolves the name of inbound callers, caching results per number
+type SIPCallerNameLookup struct {
+	hook  *jsonHook
+	cache *ttlcache.Cache[string, *SIPCallerNameResponse]
+}
+
+// NewSIPCallerNameLookup returns nil when no endpoint is configured
+func NewSIPCallerNameLookup(conf config.SIPCallerNameConfig) *SIPCallerNameLookup {
+	if conf.URL == "" {
+		return nil
+	}
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = defaultSIPCallerNameTimeout
+	}
+	ttl := conf.CacheTTL
+	if ttl <= 0 {
+		ttl = defaultSIPCallerNameCacheTTL
+	}
+	return &SIPCallerNameLookup{
+		hook: newJSONHook("caller name", conf.URL, conf.Headers, timeout),
+		cache: ttlcache.New(
+			ttlcache.WithTTL[string, *SIPCallerNameResponse](ttl),
+			ttlcache.WithCapacity[string, *SIPCallerNameResponse](sipCallerNameCacheCapacity),
+			ttlcache.WithDisableTouchOnHit[string, *SIPCallerNameResponse](),
+		),
+	}
+}
+
+// Lookup returns the caller name of a number, failures are not cached
+func (l *SIPCallerNameLookup) Lookup(ctx context.Context, trunkID string, req *rpc.EvaluateSIPDispatchRulesRequest) (*SIPCallerNameResponse, error) {
+	if item := l.cache.Get(req.CallingNumber); item != nil {
+		return item.Value(), nil
+	}
+	var resp SIPCallerNameResponse
+	if err := l.hook.postJSON(ctx, &SIPCallerNameRequest{
+		Number:  req.CallingNumber,
+		CallID:  req.SipCallId,
+		TrunkID: trunkID,
+	}, &resp); err != nil {
+		return nil, err
+	}
+	l.cache.Set(req.CallingNumber, &resp, ttlcache.DefaultTTL)
+	return &resp, nil
+}
+
+// Apply sets the caller name on an accepted call. The participant name is only replaced when it is the
+// default one given by dispatch rules, so that names chosen by the screening endpoint are kept.
+func (r *SIPCallerNameResponse) Apply(req *rpc.EvaluateSIPDispatchRulesRequest, resp *rpc.EvaluateSIPDispatchRulesResponse) {
+	if r.Name == "" && len(r.Attributes) == 0 {
+		return
+	}
+	if resp.ParticipantAttributes == nil {
+		resp.ParticipantAttributes = make(map[string]string, len(r.Attributes)+1)
+	}
+	for k, v := range r.Attributes {
+		resp.ParticipantAttributes[k] = v
+	}
+	if r.Name == "" {
+		return
+	}
+	resp.ParticipantAttributes[AttrSIPCallerName] = r.Name
+	if resp.ParticipantName == "Phone "+req.CallingNumber {
+		resp.ParticipantName = r.Name
+	}
+}
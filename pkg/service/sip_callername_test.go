@@ -0,0 +1,119 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+func TestEvaluateSIPDispatchRulesCallerName(t *testing.T) {
+	var (
+		lookups atomic.Int32
+		fail    atomic.Bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var req service.SIPCallerNameRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		resp := service.SIPCallerNameResponse{}
+		if req.Number == "+15550100" {
+			resp.Name = "Jane Doe"
+			resp.Attributes = map[string]string{"sip.lineType": "mobile"}
+		}
+		_ = json.NewEncoder(w).Encode(&resp)
+	}))
+	defer srv.Close()
+
+	rule := &livekit.SIPDispatchRuleInfo{
+		SipDispatchRuleId: "SDR_1",
+		Rule: &livekit.SIPDispatchRule{
+			Rule: &livekit.SIPDispatchRule_DispatchRuleDirect{
+				DispatchRuleDirect: &livekit.SIPDispatchRuleDirect{RoomName: "lobby"},
+			},
+		},
+	}
+	ss := &servicefakes.FakeSIPStore{}
+	ss.ListSIPInboundTrunkReturns([]*livekit.SIPInboundTrunkInfo{{SipTrunkId: "ST_1"}}, nil)
+	ss.ListSIPDispatchRuleReturns([]*livekit.SIPDispatchRuleInfo{rule}, nil)
+
+	s, err := service.NewIOInfoService(nil, nil, nil, ss, nil, &config.SIPConfig{
+		CallerName: config.SIPCallerNameConfig{URL: srv.URL},
+	}, nil, nil, nil, nil)
+	require.NoError(t, err)
+	evaluate := func(t *testing.T, number string) *rpc.EvaluateSIPDispatchRulesResponse {
+		resp, err := s.EvaluateSIPDispatchRules(context.Background(), &rpc.EvaluateSIPDispatchRulesRequest{
+			SipCallId:     "SCL_1",
+			CallingNumber: number,
+			CalledNumber:  "+15550199",
+			SrcAddress:    "10.0.0.1",
+		})
+		require.NoError(t, err)
+		require.Equal(t, rpc.SIPDispatchResult_ACCEPT, resp.Result)
+		return resp
+	}
+
+	t.Run("known caller", func(t *testing.T) {
+		resp := evaluate(t, "+15550100")
+		require.Equal(t, "Jane Doe", resp.ParticipantName)
+		require.Equal(t, "Jane Doe", resp.ParticipantAttributes[service.AttrSIPCallerName])
+		require.Equal(t, "mobile", resp.ParticipantAttributes["sip.lineType"])
+		require.Equal(t, int32(1), lookups.Load())
+
+		// served from the cache
+		resp = evaluate(t, "+15550100")
+		require.Equal(t, "Jane Doe", resp.ParticipantName)
+		require.Equal(t, int32(1), lookups.Load())
+	})
+
+	t.Run("unknown caller", func(t *testing.T) {
+		resp := evaluate(t, "+15550101")
+		require.Equal(t, "Phone +15550101", resp.ParticipantName)
+		require.NotContains(t, resp.ParticipantAttributes, service.AttrSIPCallerName)
+	})
+
+	t.Run("lookup failure", func(t *testing.T) {
+		fail.Store(true)
+		defer fail.Store(false)
+		resp := evaluate(t, "+15550102")
+		require.Equal(t, "Phone +15550102", resp.ParticipantName)
+	})
+
+	t.Run("hidden number", func(t *testing.T) {
+		rule.HidePhoneNumber = true
+		defer func() { rule.HidePhoneNumber = false }()
+		n := lookups.Load()
+		resp := evaluate(t, "+15550103")
+		require.Equal(t, "Phone 0103", resp.ParticipantName)
+		require.Equal(t, n, lookups.Load())
+	})
+}
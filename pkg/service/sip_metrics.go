@@ -0,0 +1,130 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// fields of the shared per call metrics state, each transition is recorded by the first node to set its field
+const (
+	sipCallMetricDirection = "direction"
+	sipCallMetricTrunk     = "trunk"
+	sipCallMetricAttempted = "attempted"
+	sipCallMetricActive    = "active"
+	sipCallMetricEnded     = "ended"
+	sipCallMetricFailed    = "failed"
+	// kept with the metrics state for call detail records
+	sipCallMetricRule     = "rule"
+	sipCallMetricRecorded = "recorded"
+)
+
+// recordSIPOutboundAttempt is called before an outbound call is dialed, so that state updates for the call
+// are attributed to the outbound direction.
+func recordSIPOutboundAttempt(ctx context.Context, ss SIPStore, callID, trunkID string) error {
+	set, _, err := ss.UpdateSIPCallMetricsState(ctx, callID, map[string]string{
+		sipCallMetricDirection: prometheus.SIPDirectionOutbound,
+		sipCallMetricTrunk:     trunkID,
+		sipCallMetricAttempted: "1",
+	})
+	if err != nil {
+		return err
+	}
+	if set[sipCallMetricAttempted] {
+		prometheus.RecordSIPCallAttempt(trunkID, prometheus.SIPDirectionOutbound)
+	}
+	return nil
+}
+
+// recordSIPOutboundFailure is called when the SIP service fails to place an outbound call
+func recordSIPOutboundFailure(ctx context.Context, ss SIPStore, callID, trunkID string, callErr error) error {
+	set, _, err := ss.UpdateSIPCallMetricsState(ctx, callID, map[string]string{
+		sipCallMetricFailed: "1",
+	})
+	if err != nil {
+		return err
+	}
+	if set[sipCallMetricFailed] {
+		prometheus.RecordSIPCallFailed(trunkID, prometheus.SIPDirectionOutbound, sipErrorCode(callErr))
+	}
+	return nil
+}
+
+// updateSIPCallMetrics records call state transitions reported by the SIP service. Updates for a call may be
+// served by any node, so transitions are deduplicated through the store: the active call gauge is incremented
+// by the node seeing the call become active and decremented by the node seeing it end, which adds up across nodes.
+func updateSIPCallMetrics(ctx context.Context, ss SIPStore, info *livekit.SIPCallInfo) error {
+	fields := make(map[string]string, 3)
+	if info.TrunkId != "" {
+		fields[sipCallMetricTrunk] = info.TrunkId
+	}
+	switch info.CallStatus {
+	case livekit.SIPCallStatus_SCS_CALL_INCOMING:
+		fields[sipCallMetricDirection] = prometheus.SIPDirectionInbound
+		fields[sipCallMetricAttempted] = "1"
+	case livekit.SIPCallStatus_SCS_ACTIVE:
+		fields[sipCallMetricActive] = "1"
+	case livekit.SIPCallStatus_SCS_DISCONNECTED:
+		fields[sipCallMetricEnded] = "1"
+	case livekit.SIPCallStatus_SCS_ERROR:
+		fields[sipCallMetricEnded] = "1"
+		fields[sipCallMetricFailed] = "1"
+	default:
+		return nil
+	}
+
+	set, state, err := ss.UpdateSIPCallMetricsState(ctx, info.CallId, fields)
+	if err != nil {
+		return err
+	}
+	trunkID := state[sipCallMetricTrunk]
+	direction := state[sipCallMetricDirection]
+	if direction == "" {
+		// outbound calls are marked before dialing, so anything else was received
+		direction = prometheus.SIPDirectionInbound
+	}
+
+	if set[sipCallMetricAttempted] {
+		prometheus.RecordSIPCallAttempt(trunkID, direction)
+	}
+	if set[sipCallMetricActive] {
+		// updates may arrive out of order on different nodes, a call that already ended is not counted as active
+		if state[sipCallMetricEnded] == "" {
+			prometheus.AddSIPActiveCall(trunkID, direction)
+		}
+		var postDialDelay time.Duration
+		if info.CreatedAt != 0 && info.StartedAt > info.CreatedAt {
+			// timestamps are reported in nanoseconds
+			postDialDelay = time.Duration(info.StartedAt - info.CreatedAt)
+		}
+		prometheus.RecordSIPCallAnswered(trunkID, direction, postDialDelay)
+	}
+	if set[sipCallMetricEnded] && state[sipCallMetricActive] != "" {
+		prometheus.SubSIPActiveCall(trunkID, direction)
+	}
+	if set[sipCallMetricFailed] && state[sipCallMetricActive] == "" {
+		code := sipStatusCode(info.Error)
+		if code == "" {
+			code = "unknown"
+		}
+		prometheus.RecordSIPCallFailed(trunkID, direction, code)
+	}
+	return nil
+}
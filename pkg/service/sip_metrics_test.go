@@ -0,0 +1,99 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/utils"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+func TestSIPCallMetricsAcrossNodes(t *testing.T) {
+	prometheus.Init("test", livekit.NodeType_SERVER)
+	ctx := context.Background()
+	rs := redisStore(t)
+
+	// two nodes sharing the store, state updates for a call may be served by either
+	var nodes []*service.IOInfoService
+	for i := 0; i < 2; i++ {
+		s, err := service.NewIOInfoService(nil, nil, nil, rs, nil, &config.SIPConfig{}, nil, nil, nil, nil)
+		require.NoError(t, err)
+		nodes = append(nodes, s)
+	}
+
+	trunkID := guid.New(utils.SIPTrunkPrefix)
+	callID := guid.New(utils.SIPCallPrefix)
+	update := func(node int, status livekit.SIPCallStatus) {
+		_, err := nodes[node].UpdateSIPCallState(ctx, &rpc.UpdateSIPCallStateRequest{
+			CallInfo: &livekit.SIPCallInfo{
+				CallId:     callID,
+				TrunkId:    trunkID,
+				CallStatus: status,
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	update(0, livekit.SIPCallStatus_SCS_CALL_INCOMING)
+	update(1, livekit.SIPCallStatus_SCS_CALL_INCOMING)
+	require.Equal(t, 1.0, sipMetricValue(t, "livekit_sip_call_attempts", trunkID))
+
+	update(0, livekit.SIPCallStatus_SCS_ACTIVE)
+	update(1, livekit.SIPCallStatus_SCS_ACTIVE)
+	require.Equal(t, 1.0, sipMetricValue(t, "livekit_sip_call_answered", trunkID))
+	require.Equal(t, 1.0, sipMetricValue(t, "livekit_sip_calls_active", trunkID))
+
+	// ended on the other node, the gauge adds up to zero across nodes
+	update(1, livekit.SIPCallStatus_SCS_DISCONNECTED)
+	update(0, livekit.SIPCallStatus_SCS_DISCONNECTED)
+	require.Equal(t, 0.0, sipMetricValue(t, "livekit_sip_calls_active", trunkID))
+
+	set, state, err := rs.UpdateSIPCallMetricsState(ctx, callID, map[string]string{"ended": "1"})
+	require.NoError(t, err)
+	require.Empty(t, set)
+	require.Equal(t, prometheus.SIPDirectionInbound, state["direction"])
+}
+
+// sipMetricValue sums a SIP metric for a trunk across all other labels
+func sipMetricValue(t *testing.T, name string, trunkID string) float64 {
+	families, err := prom.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	var total float64
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "trunk_id" && l.GetValue() == trunkID {
+					total += m.GetCounter().GetValue() + m.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	return total
+}
@@ -0,0 +1,230 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+var (
+	defaultEmergencyNumbers      = []string{"911", "112", "999", "000", "110", "119", "933", "10111"}
+	defaultEmergencyDialPrefixes = []string{"1", "9"}
+
+	// ITU country calling codes shorter than three digits, all other codes have three digits.
+	// Calling codes are prefix-free, so this is enough to split a code off an E.164 number.
+	shortCountryCallingCodes = map[string]struct{}{
+		"1": {}, "7": {},
+		"20": {}, "27": {}, "30": {}, "31": {}, "32": {}, "33": {}, "34": {}, "36": {}, "39": {},
+		"40": {}, "41": {}, "43": {}, "44": {}, "45": {}, "46": {}, "47": {}, "48": {}, "49": {},
+		"51": {}, "52": {}, "53": {}, "54": {}, "55": {}, "56": {}, "57": {}, "58": {},
+		"60": {}, "61": {}, "62": {}, "63": {}, "64": {}, "65": {}, "66": {},
+		"81": {}, "82": {}, "84": {}, "86": {},
+		"90": {}, "91": {}, "92": {}, "93": {}, "94": {}, "95": {}, "98": {},
+	}
+)
+
+// countryCallingCode returns the country calling code at the start of an E.164 number without '+',
+// or an empty string if the number is too short to contain one.
+func countryCallingCode(digits string) string {
+	for l := 1; l <= 3 && l <= len(digits); l++ {
+		if _, ok := shortCountryCallingCodes[digits[:l]]; ok || l == 3 {
+			return digits[:l]
+		}
+	}
+	return ""
+}
+
+// SIPNumberPolicy decides whether an outbound call to a number is permitted before it is dialed.
+type SIPNumberPolicy struct {
+	allowed        []*regexp.Regexp
+	blocked        []*regexp.Regexp
+	emergency      map[string]struct{}
+	dialPrefixes   []string
+	trunkCountries map[string]map[string]struct{}
+}
+
+func NewSIPNumberPolicy(conf config.SIPOutboundPolicyConfig) (*SIPNumberPolicy, error) {
+	p := &SIPNumberPolicy{
+		trunkCountries: make(map[string]map[string]struct{}, len(conf.TrunkCountryCodes)),
+	}
+	for trunkID, codes := range conf.TrunkCountryCodes {
+		set := make(map[string]struct{}, len(codes))
+		for _, code := range codes {
+			code = strings.TrimPrefix(code, "+")
+			if code == "" || countryCallingCode(code) != code || normalizeSIPNumber(code) != code {
+				return nil, fmt.Errorf("invalid country calling code %q for trunk %s", code, trunkID)
+			}
+			set[code] = struct{}{}
+		}
+		p.trunkCountries[trunkID] = set
+	}
+
+	var err error
+	if p.allowed, err = compileNumberPatterns(conf.AllowedNumbers); err != nil {
+		return nil, err
+	}
+	if p.blocked, err = compileNumberPatterns(conf.BlockedNumbers); err != nil {
+		return nil, err
+	}
+
+	if conf.BlockEmergencyNumbers {
+		numbers := conf.EmergencyNumbers
+		if len(numbers) == 0 {
+			numbers = defaultEmergencyNumbers
+		}
+		p.emergency = make(map[string]struct{}, len(numbers))
+		for _, n := range numbers {
+			p.emergency[normalizeSIPNumber(n)] = struct{}{}
+		}
+		p.dialPrefixes = conf.EmergencyDialPrefixes
+		if len(p.dialPrefixes) == 0 {
+			p.dialPrefixes = defaultEmergencyDialPrefixes
+		}
+	}
+	return p, nil
+}
+
+func compileNumberPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	out := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sip number pattern %q: %w", pattern, err)
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// normalizeSIPNumber strips a sip: prefix, host part and any formatting characters, keeping a leading '+'.
+func normalizeSIPNumber(number string) string {
+	number = strings.TrimPrefix(strings.TrimPrefix(number, "sip:"), "tel:")
+	if i := strings.IndexByte(number, '@'); i >= 0 {
+		number = number[:i]
+	}
+
+	var b strings.Builder
+	for i, c := range number {
+		switch {
+		case c >= '0' && c <= '9':
+			b.WriteRune(c)
+		case c == '+' && i == 0:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// Check returns an error when the trunk is not permitted to call the number.
+func (p *SIPNumberPolicy) Check(trunkID, number string) error {
+	if p == nil {
+		return nil
+	}
+	n := normalizeSIPNumber(number)
+	if n == "" {
+		return ErrSIPNumberInvalid
+	}
+
+	if p.isEmergency(n) {
+		return ErrSIPEmergencyNumberBlocked
+	}
+	for _, re := range p.blocked {
+		if re.MatchString(n) {
+			return ErrSIPNumberBlocked
+		}
+	}
+	if len(p.allowed) != 0 {
+		allowed := false
+		for _, re := range p.allowed {
+			if re.MatchString(n) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return ErrSIPNumberNotAllowed
+		}
+	}
+
+	if codes, ok := p.trunkCountries[trunkID]; ok {
+		if !strings.HasPrefix(n, "+") {
+			return ErrSIPCountryNotAllowed
+		}
+		if _, ok := codes[countryCallingCode(n[1:])]; !ok {
+			return ErrSIPCountryNotAllowed
+		}
+	}
+	return nil
+}
+
+// CheckTransfer applies the policy to a transfer target. Targets may also be SIP URIs addressing a user
+// rather than a phone number, those are not subject to the number policy.
+func (p *SIPNumberPolicy) CheckTransfer(trunkID, target string) error {
+	if p == nil || !isSIPNumberTarget(target) {
+		return nil
+	}
+	return p.Check(trunkID, target)
+}
+
+func isSIPNumberTarget(target string) bool {
+	user := strings.TrimPrefix(strings.TrimPrefix(target, "sip:"), "tel:")
+	if i := strings.IndexByte(user, '@'); i >= 0 {
+		user = user[:i]
+	}
+	if user == "" {
+		return false
+	}
+	for _, c := range user {
+		switch {
+		case c >= '0' && c <= '9':
+		case strings.ContainsRune("+-(). ", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isEmergency matches the national part of a normalized number against the emergency numbers:
+// the country calling code is removed from E.164 numbers, and a dial prefix from other numbers.
+func (p *SIPNumberPolicy) isEmergency(n string) bool {
+	if len(p.emergency) == 0 {
+		return false
+	}
+	if strings.HasPrefix(n, "+") {
+		digits := n[1:]
+		if _, ok := p.emergency[digits]; ok {
+			return true
+		}
+		_, ok := p.emergency[strings.TrimPrefix(digits, countryCallingCode(digits))]
+		return ok
+	}
+
+	if _, ok := p.emergency[n]; ok {
+		return true
+	}
+	for _, prefix := range p.dialPrefixes {
+		if national, found := strings.CutPrefix(n, prefix); found {
+			if _, ok := p.emergency[national]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
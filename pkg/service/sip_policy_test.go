@@ -0,0 +1,108 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestSIPNumberPolicy(t *testing.T) {
+	t.Run("empty policy allows everything", func(t *testing.T) {
+		p, err := service.NewSIPNumberPolicy(config.SIPOutboundPolicyConfig{})
+		require.NoError(t, err)
+		require.NoError(t, p.Check("ST_1", "+15105550100"))
+		require.NoError(t, p.Check("ST_1", "911"))
+		require.ErrorIs(t, p.Check("ST_1", "sip:@host"), service.ErrSIPNumberInvalid)
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		_, err := service.NewSIPNumberPolicy(config.SIPOutboundPolicyConfig{
+			BlockedNumbers: []string{"(+"},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("invalid country code", func(t *testing.T) {
+		for _, code := range []string{"4", "21", "+x1"} {
+			_, err := service.NewSIPNumberPolicy(config.SIPOutboundPolicyConfig{
+				TrunkCountryCodes: map[string][]string{"ST_1": {code}},
+			})
+			require.Error(t, err, code)
+		}
+	})
+
+	t.Run("emergency dialing variants", func(t *testing.T) {
+		p, err := service.NewSIPNumberPolicy(config.SIPOutboundPolicyConfig{BlockEmergencyNumbers: true})
+		require.NoError(t, err)
+		for _, number := range []string{"911", "+1911", "9911", "1-911", "+44 999", "+61000"} {
+			require.ErrorIs(t, p.Check("ST_1", number), service.ErrSIPEmergencyNumberBlocked, number)
+		}
+		for _, number := range []string{"+15550911", "5911", "+4420911"} {
+			require.NoError(t, p.Check("ST_1", number), number)
+		}
+	})
+
+	t.Run("transfer targets", func(t *testing.T) {
+		p, err := service.NewSIPNumberPolicy(config.SIPOutboundPolicyConfig{BlockEmergencyNumbers: true})
+		require.NoError(t, err)
+		require.ErrorIs(t, p.CheckTransfer("ST_1", "tel:+1911"), service.ErrSIPEmergencyNumberBlocked)
+		require.ErrorIs(t, p.CheckTransfer("ST_1", "sip:911@pbx.example.com"), service.ErrSIPEmergencyNumberBlocked)
+		require.NoError(t, p.CheckTransfer("ST_1", "sip:frontdesk@pbx.example.com"))
+		require.NoError(t, p.CheckTransfer("ST_1", "tel:+15105550100"))
+	})
+
+	p, err := service.NewSIPNumberPolicy(config.SIPOutboundPolicyConfig{
+		AllowedNumbers:        []string{`^\+\d+$`},
+		BlockedNumbers:        []string{`^\+1900`},
+		BlockEmergencyNumbers: true,
+		TrunkCountryCodes: map[string][]string{
+			"ST_us": {"1"},
+			"ST_pt": {"+351"},
+		},
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		trunk  string
+		number string
+		err    error
+	}{
+		{name: "allowed", trunk: "ST_any", number: "+44 20 7946 0000"},
+		{name: "emergency", trunk: "ST_any", number: "911", err: service.ErrSIPEmergencyNumberBlocked},
+		{name: "emergency sip uri", trunk: "ST_any", number: "sip:112@example.com", err: service.ErrSIPEmergencyNumberBlocked},
+		{name: "blocked", trunk: "ST_any", number: "+1 (900) 555-0100", err: service.ErrSIPNumberBlocked},
+		{name: "not allowed", trunk: "ST_any", number: "5550100", err: service.ErrSIPNumberNotAllowed},
+		{name: "trunk country", trunk: "ST_us", number: "+15105550100"},
+		{name: "trunk country rejected", trunk: "ST_us", number: "+447946000000", err: service.ErrSIPCountryNotAllowed},
+		{name: "trunk three digit country", trunk: "ST_pt", number: "+351211234567"},
+		{name: "trunk country not a prefix match", trunk: "ST_pt", number: "+35212345678", err: service.ErrSIPCountryNotAllowed},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := p.Check(c.trunk, c.number)
+			if c.err == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, c.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/sip"
+)
+
+const maxSIPUpdateRequest = 256 * 1024
+
+// SIPRevisionRequest is the JSON body of POST /sip_update/revision
+type SIPRevisionRequest struct {
+	// ID of a trunk or dispatch rule
+	ID string `json:"id"`
+}
+
+// SIPUpdateRequest is the JSON body of POST /sip_update/{inbound_trunk,outbound_trunk,dispatch_rule}
+type SIPUpdateRequest struct {
+	// revision the object was read at, the update is rejected when it was modified since
+	Revision uint64 `json:"revision"`
+	// SIPInboundTrunkInfo, SIPOutboundTrunkInfo or SIPDispatchRuleInfo in protobuf JSON, replacing the stored one
+	Object json.RawMessage `json:"object"`
+}
+
+// SIPRevisionResponse carries the current revision of an object, to be passed back on its next update
+type SIPRevisionResponse struct {
+	ID       string `json:"id"`
+	Revision uint64 `json:"revision"`
+}
+
+// SIPUpdateService replaces trunks and dispatch rules with optimistic concurrency: clients read the revision
+// of an object along with it and pass it back on update, which fails with a conflict when another update came
+// in between. The SIP API has no update requests, so these are served as JSON. Calls require SIP admin.
+type SIPUpdateService struct {
+	store SIPStore
+}
+
+func NewSIPUpdateService(store SIPStore) *SIPUpdateService {
+	return &SIPUpdateService{
+		store: store,
+	}
+}
+
+func (s *SIPUpdateService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch op := strings.TrimPrefix(r.URL.Path, "/sip_update/"); op {
+	case "revision":
+		var req SIPRevisionRequest
+		if err = decodeJSONRequest(r, &req, maxSIPUpdateRequest); err == nil {
+			res, err = s.GetSIPRevision(r.Context(), &req)
+		}
+	case "inbound_trunk", "outbound_trunk", "dispatch_rule":
+		var req SIPUpdateRequest
+		if err = decodeJSONRequest(r, &req, maxSIPUpdateRequest); err == nil {
+			switch op {
+			case "inbound_trunk":
+				info := &livekit.SIPInboundTrunkInfo{}
+				if err = unmarshalSIPObject(req.Object, info); err == nil {
+					res, err = s.UpdateSIPInboundTrunk(r.Context(), info, req.Revision)
+				}
+			case "outbound_trunk":
+				info := &livekit.SIPOutboundTrunkInfo{}
+				if err = unmarshalSIPObject(req.Object, info); err == nil {
+					res, err = s.UpdateSIPOutboundTrunk(r.Context(), info, req.Revision)
+				}
+			default:
+				info := &livekit.SIPDispatchRuleInfo{}
+				if err = unmarshalSIPObject(req.Object, info); err == nil {
+					res, err = s.UpdateSIPDispatchRule(r.Context(), info, req.Revision)
+				}
+			}
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func unmarshalSIPObject(data json.RawMessage, info proto.Message) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: object is required", ErrSIPUpdateInvalid)
+	}
+	if err := protojson.Unmarshal(data, info); err != nil {
+		return fmt.Errorf("%w: object: %v", ErrSIPUpdateInvalid, err)
+	}
+	return nil
+}
+
+func (s *SIPUpdateService) GetSIPRevision(ctx context.Context, req *SIPRevisionRequest) (*SIPRevisionResponse, error) {
+	if err := EnsureSIPAdminPermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrSIPNotConnected
+	}
+	if req.ID == "" {
+		return nil, fmt.Errorf("%w: id is required", ErrSIPUpdateInvalid)
+	}
+	revision, err := s.store.LoadSIPRevision(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &SIPRevisionResponse{ID: req.ID, Revision: revision}, nil
+}
+
+// UpdateSIPInboundTrunk replaces an existing inbound trunk. The update is rejected with ErrSIPRevisionMismatch
+// if the trunk was modified after expectedRevision was read.
+func (s *SIPUpdateService) UpdateSIPInboundTrunk(ctx context.Context, info *livekit.SIPInboundTrunkInfo, expectedRevision uint64) (*SIPRevisionResponse, error) {
+	if err := EnsureSIPAdminPermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrSIPNotConnected
+	}
+	if info.SipTrunkId == "" {
+		return nil, fmt.Errorf("%w: trunk ID is required", ErrSIPUpdateInvalid)
+	}
+	if err := info.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSIPUpdateInvalid, err)
+	}
+	AppendLogFields(ctx, "trunkID", info.SipTrunkId, "expectedRevision", expectedRevision)
+
+	// Validate all trunks with the updated one replacing the existing.
+	list, err := s.store.ListSIPInboundTrunk(ctx)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for i, t := range list {
+		if t.SipTrunkId == info.SipTrunkId {
+			list[i] = info
+			found = true
+		}
+	}
+	if !found {
+		return nil, ErrSIPTrunkNotFound
+	}
+	if err = sip.ValidateTrunks(list); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSIPUpdateInvalid, err)
+	}
+
+	revision, err := s.store.UpdateSIPInboundTrunk(ctx, info, expectedRevision)
+	if err != nil {
+		return nil, err
+	}
+	return &SIPRevisionResponse{ID: info.SipTrunkId, Revision: revision}, nil
+}
+
+// UpdateSIPOutboundTrunk replaces an existing outbound trunk. The update is rejected with ErrSIPRevisionMismatch
+// if the trunk was modified after expectedRevision was read.
+func (s *SIPUpdateService) UpdateSIPOutboundTrunk(ctx context.Context, info *livekit.SIPOutboundTrunkInfo, expectedRevision uint64) (*SIPRevisionResponse, error) {
+	if err := EnsureSIPAdminPermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrSIPNotConnected
+	}
+	if info.SipTrunkId == "" {
+		return nil, fmt.Errorf("%w: trunk ID is required", ErrSIPUpdateInvalid)
+	}
+	if err := info.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSIPUpdateInvalid, err)
+	}
+	AppendLogFields(ctx, "trunkID", info.SipTrunkId, "expectedRevision", expectedRevision)
+
+	revision, err := s.store.UpdateSIPOutboundTrunk(ctx, info, expectedRevision)
+	if err != nil {
+		return nil, err
+	}
+	return &SIPRevisionResponse{ID: info.SipTrunkId, Revision: revision}, nil
+}
+
+// UpdateSIPDispatchRule replaces an existing dispatch rule. The update is rejected with ErrSIPRevisionMismatch
+// if the rule was modified after expectedRevision was read.
+func (s *SIPUpdateService) UpdateSIPDispatchRule(ctx context.Context, info *livekit.SIPDispatchRuleInfo, expectedRevision uint64) (*SIPRevisionResponse, error) {
+	if err := EnsureSIPAdminPermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrSIPNotConnected
+	}
+	if info.SipDispatchRuleId == "" {
+		return nil, fmt.Errorf("%w: dispatch rule ID is required", ErrSIPUpdateInvalid)
+	}
+	AppendLogFields(ctx, "ruleID", info.SipDispatchRuleId, "expectedRevision", expectedRevision)
+
+	// Validate all rules with the updated one replacing the existing.
+	list, err := s.store.ListSIPDispatchRule(ctx)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for i, r := range list {
+		if r.SipDispatchRuleId == info.SipDispatchRuleId {
+			list[i] = info
+			found = true
+		}
+	}
+	if !found {
+		return nil, ErrSIPDispatchRuleNotFound
+	}
+	if err = sip.ValidateDispatchRules(list); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSIPUpdateInvalid, err)
+	}
+
+	revision, err := s.store.UpdateSIPDispatchRule(ctx, info, expectedRevision)
+	if err != nil {
+		return nil, err
+	}
+	return &SIPRevisionResponse{ID: info.SipDispatchRuleId, Revision: revision}, nil
+}
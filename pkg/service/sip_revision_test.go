@@ -0,0 +1,99 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+func TestSIPUpdateService(t *testing.T) {
+	ss := &servicefakes.FakeSIPStore{}
+	ss.ListSIPInboundTrunkReturns([]*livekit.SIPInboundTrunkInfo{
+		{SipTrunkId: "ST_1", Numbers: []string{"+15550100"}},
+	}, nil)
+	ss.LoadSIPRevisionReturns(3, nil)
+	ss.UpdateSIPInboundTrunkStub = func(_ context.Context, _ *livekit.SIPInboundTrunkInfo, expectedRevision uint64) (uint64, error) {
+		if expectedRevision != 3 {
+			return 0, service.ErrSIPRevisionMismatch
+		}
+		return 4, nil
+	}
+	s := service.NewSIPUpdateService(ss)
+	adminCtx := service.WithGrants(context.Background(), &auth.ClaimGrants{SIP: &auth.SIPGrant{Admin: true}}, "")
+
+	serve := func(ctx context.Context, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+	trunk := `{"sip_trunk_id": "ST_1", "numbers": ["+15550101"]}`
+
+	t.Run("revision", func(t *testing.T) {
+		w := serve(adminCtx, "/sip_update/revision", `{"id": "ST_1"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		var res service.SIPRevisionResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+		require.Equal(t, uint64(3), res.Revision)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		w := serve(adminCtx, "/sip_update/inbound_trunk", `{"revision": 3, "object": `+trunk+`}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		var res service.SIPRevisionResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+		require.Equal(t, service.SIPRevisionResponse{ID: "ST_1", Revision: 4}, res)
+
+		_, info, _ := ss.UpdateSIPInboundTrunkArgsForCall(ss.UpdateSIPInboundTrunkCallCount() - 1)
+		require.Equal(t, []string{"+15550101"}, info.Numbers)
+	})
+
+	t.Run("concurrent update", func(t *testing.T) {
+		w := serve(adminCtx, "/sip_update/inbound_trunk", `{"revision": 2, "object": `+trunk+`}`)
+		require.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("unknown trunk", func(t *testing.T) {
+		w := serve(adminCtx, "/sip_update/inbound_trunk", `{"revision": 3, "object": {"sip_trunk_id": "ST_2", "numbers": ["+15550101"]}}`)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		w := serve(adminCtx, "/sip_update/inbound_trunk", `{"revision": 3, "object": {"sip_trunk_id": "ST_1"}}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		w = serve(adminCtx, "/sip_update/dispatch_rule", `{"revision": 3}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("requires sip admin", func(t *testing.T) {
+		calls := ss.UpdateSIPInboundTrunkCallCount()
+		w := serve(context.Background(), "/sip_update/inbound_trunk", `{"revision": 3, "object": `+trunk+`}`)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		require.Equal(t, calls, ss.UpdateSIPInboundTrunkCallCount())
+	})
+}
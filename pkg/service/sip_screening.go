@@ -0,0 +1,196 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/sip"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	SIPScreeningActionAccept = "accept"
+	SIPScreeningActionReject = "reject"
+
+	defaultSIPScreeningTimeout = 2 * time.Second
+)
+
+// SIPScreeningRequest is posted as JSON to the screening endpoint for every inbound call.
+type SIPScreeningRequest struct {
+	CallID     string `json:"call_id"`
+	TrunkID    string `json:"trunk_id,omitempty"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	ToHost     string `json:"to_host,omitempty"`
+	SrcAddress string `json:"src_address,omitempty"`
+	// STIR/SHAKEN attestation of the caller (A, B or C) when the trunk forwards it
+	Attestation string `json:"attestation,omitempty"`
+	// includes the SIP headers mapped by the headers_to_attributes of the trunk
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// SIPScreeningResponse is the decision returned by the screening endpoint. Any of the room or participant
+// fields that are set override the result of dispatch rule evaluation. Rejected calls are answered with the
+// SIP service's standard rejection, the dispatch protocol has no way to choose the response code.
+type SIPScreeningResponse struct {
+	Action                string            `json:"action"`
+	RoomName              string            `json:"room_name,omitempty"`
+	HidePhoneNumber       bool              `json:"hide_phone_number,omitempty"`
+	ParticipantIdentity   string            `json:"participant_identity,omitempty"`
+	ParticipantName       string            `json:"participant_name,omitempty"`
+	ParticipantMetadata   string            `json:"participant_metadata,omitempty"`
+	ParticipantAttributes map[string]string `json:"participant_attributes,omitempty"`
+}
+
+type sipScreeningEndpoint struct {
+	conf config.SIPScreeningConfig
+	hook *jsonHook
+}
+
+func newSIPScreeningEndpoint(conf config.SIPScreeningConfig) *sipScreeningEndpoint {
+	if conf.URL == "" {
+		return nil
+	}
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = defaultSIPScreeningTimeout
+	}
+	return &sipScreeningEndpoint{
+		conf: conf,
+		hook: newJSONHook("screening", conf.URL, conf.Headers, timeout),
+	}
+}
+
+// SIPCallScreener calls the screening endpoint of the trunk of inbound calls
+type SIPCallScreener struct {
+	endpoint *sipScreeningEndpoint
+	// nil for trunks which are not screened
+	trunks map[string]*sipScreeningEndpoint
+}
+
+// NewSIPCallScreener returns nil when screening is not configured
+func NewSIPCallScreener(conf config.SIPScreeningConfig) *SIPCallScreener {
+	if conf.URL == "" && len(conf.Trunks) == 0 {
+		return nil
+	}
+	s := &SIPCallScreener{
+		endpoint: newSIPScreeningEndpoint(conf),
+		trunks:   make(map[string]*sipScreeningEndpoint, len(conf.Trunks)),
+	}
+	for trunkID, trunkConf := range conf.Trunks {
+		s.trunks[trunkID] = newSIPScreeningEndpoint(trunkConf)
+	}
+	return s
+}
+
+func (s *SIPCallScreener) endpointFor(trunkID string) *sipScreeningEndpoint {
+	if e, ok := s.trunks[trunkID]; ok {
+		return e
+	}
+	return s.endpoint
+}
+
+// AllowOnError returns whether calls of the trunk are routed by dispatch rules when its endpoint fails
+func (s *SIPCallScreener) AllowOnError(trunkID string) bool {
+	e := s.endpointFor(trunkID)
+	return e == nil || e.conf.AllowOnError
+}
+
+// Screen returns the decision of the endpoint of the trunk, nil when calls of the trunk are not screened
+func (s *SIPCallScreener) Screen(ctx context.Context, trunkID string, req *rpc.EvaluateSIPDispatchRulesRequest) (*SIPScreeningResponse, error) {
+	e := s.endpointFor(trunkID)
+	if e == nil {
+		return nil, nil
+	}
+	var attestation string
+	if e.conf.AttestationAttribute != "" {
+		attestation = req.ExtraAttributes[e.conf.AttestationAttribute]
+	}
+
+	var resp SIPScreeningResponse
+	if err := e.hook.postJSON(ctx, &SIPScreeningRequest{
+		CallID:      req.SipCallId,
+		TrunkID:     trunkID,
+		From:        req.CallingNumber,
+		To:          req.CalledNumber,
+		ToHost:      req.CalledHost,
+		SrcAddress:  req.SrcAddress,
+		Attestation: attestation,
+		Attributes:  req.ExtraAttributes,
+	}, &resp); err != nil {
+		return nil, err
+	}
+	if err := e.hook.checkAction(resp.Action, SIPScreeningActionAccept, SIPScreeningActionReject); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Apply merges accepted screening overrides into the dispatch result.
+func (r *SIPScreeningResponse) Apply(resp *rpc.EvaluateSIPDispatchRulesResponse) {
+	if r.RoomName != "" {
+		resp.RoomName = r.RoomName
+	}
+	if r.ParticipantIdentity != "" {
+		resp.ParticipantIdentity = r.ParticipantIdentity
+	}
+	if r.ParticipantName != "" {
+		resp.ParticipantName = r.ParticipantName
+	}
+	if r.ParticipantMetadata != "" {
+		resp.ParticipantMetadata = r.ParticipantMetadata
+	}
+	if len(r.ParticipantAttributes) != 0 {
+		if resp.ParticipantAttributes == nil {
+			resp.ParticipantAttributes = make(map[string]string, len(r.ParticipantAttributes))
+		}
+		for k, v := range r.ParticipantAttributes {
+			resp.ParticipantAttributes[k] = v
+		}
+	}
+}
+
+// RejectResponse builds the dispatch result for a rejected call.
+func (r *SIPScreeningResponse) RejectResponse(trunkID string) *rpc.EvaluateSIPDispatchRulesResponse {
+	return &rpc.EvaluateSIPDispatchRulesResponse{
+		SipTrunkId: trunkID,
+		Result:     rpc.SIPDispatchResult_REJECT,
+	}
+}
+
+// AcceptResponse builds the dispatch result for a call routed by the screening endpoint alone,
+// used when no dispatch rule matched the call. It is evaluated as a direct dispatch to the screening room,
+// so the participant gets the same identity, attributes and trunk settings as with a dispatch rule.
+func (r *SIPScreeningResponse) AcceptResponse(trunk *livekit.SIPInboundTrunkInfo, req *rpc.EvaluateSIPDispatchRulesRequest) (*rpc.EvaluateSIPDispatchRulesResponse, error) {
+	rule := &livekit.SIPDispatchRuleInfo{
+		Rule: &livekit.SIPDispatchRule{
+			Rule: &livekit.SIPDispatchRule_DispatchRuleDirect{
+				DispatchRuleDirect: &livekit.SIPDispatchRuleDirect{RoomName: r.RoomName},
+			},
+		},
+		HidePhoneNumber: r.HidePhoneNumber,
+	}
+	resp, err := sip.EvaluateDispatchRule("", trunk, rule, req)
+	if err != nil {
+		return nil, err
+	}
+	r.Apply(resp)
+	return resp, nil
+}
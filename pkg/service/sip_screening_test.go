@@ -0,0 +1,225 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+func TestSIPCallScreener(t *testing.T) {
+	require.Nil(t, service.NewSIPCallScreener(config.SIPScreeningConfig{}))
+
+	var decision service.SIPScreeningResponse
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "secret", r.Header.Get("Authorization"))
+		var req service.SIPScreeningRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "+15550100", req.From)
+		require.Equal(t, "ST_1", req.TrunkID)
+		_ = json.NewEncoder(w).Encode(&decision)
+	}))
+	defer srv.Close()
+
+	s := service.NewSIPCallScreener(config.SIPScreeningConfig{
+		URL:     srv.URL,
+		Headers: map[string]string{"Authorization": "secret"},
+	})
+	req := &rpc.EvaluateSIPDispatchRulesRequest{
+		SipCallId:     "SCL_1",
+		CallingNumber: "+15550100",
+		CalledNumber:  "+15550199",
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		decision = service.SIPScreeningResponse{Action: service.SIPScreeningActionReject}
+		res, err := s.Screen(context.Background(), "ST_1", req)
+		require.NoError(t, err)
+		resp := res.RejectResponse("ST_1")
+		require.Equal(t, rpc.SIPDispatchResult_REJECT, resp.Result)
+	})
+
+	t.Run("accept with override", func(t *testing.T) {
+		decision = service.SIPScreeningResponse{
+			Action:                service.SIPScreeningActionAccept,
+			RoomName:              "support",
+			ParticipantAttributes: map[string]string{"tier": "gold"},
+		}
+		res, err := s.Screen(context.Background(), "ST_1", req)
+		require.NoError(t, err)
+		resp, err := res.AcceptResponse(&livekit.SIPInboundTrunkInfo{SipTrunkId: "ST_1"}, req)
+		require.NoError(t, err)
+		require.Equal(t, rpc.SIPDispatchResult_ACCEPT, resp.Result)
+		require.Equal(t, "support", resp.RoomName)
+		require.Equal(t, "sip_+15550100", resp.ParticipantIdentity)
+		require.Equal(t, "gold", resp.ParticipantAttributes["tier"])
+		require.Equal(t, "ST_1", resp.ParticipantAttributes[livekit.AttrSIPTrunkID])
+		require.Equal(t, "+15550100", resp.ParticipantAttributes[livekit.AttrSIPPhoneNumber])
+	})
+
+	t.Run("accept with hidden number", func(t *testing.T) {
+		decision = service.SIPScreeningResponse{
+			Action:          service.SIPScreeningActionAccept,
+			RoomName:        "support",
+			HidePhoneNumber: true,
+		}
+		res, err := s.Screen(context.Background(), "ST_1", req)
+		require.NoError(t, err)
+		resp, err := res.AcceptResponse(&livekit.SIPInboundTrunkInfo{SipTrunkId: "ST_1"}, req)
+		require.NoError(t, err)
+		require.NotContains(t, resp.ParticipantIdentity, "15550100")
+		require.Equal(t, "Phone 0100", resp.ParticipantName)
+		require.NotContains(t, resp.ParticipantAttributes, livekit.AttrSIPPhoneNumber)
+	})
+
+	t.Run("unknown action", func(t *testing.T) {
+		decision = service.SIPScreeningResponse{Action: "maybe"}
+		_, err := s.Screen(context.Background(), "ST_1", req)
+		require.Error(t, err)
+	})
+}
+
+func TestEvaluateSIPDispatchRulesScreening(t *testing.T) {
+	var (
+		decision service.SIPScreeningResponse
+		fail     bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&decision)
+	}))
+	defer srv.Close()
+
+	ss := &servicefakes.FakeSIPStore{}
+	ss.ListSIPInboundTrunkReturns([]*livekit.SIPInboundTrunkInfo{{SipTrunkId: "ST_1"}}, nil)
+	ss.ListSIPDispatchRuleReturns([]*livekit.SIPDispatchRuleInfo{{
+		SipDispatchRuleId: "SDR_1",
+		Rule: &livekit.SIPDispatchRule{
+			Rule: &livekit.SIPDispatchRule_DispatchRuleDirect{
+				DispatchRuleDirect: &livekit.SIPDispatchRuleDirect{RoomName: "lobby"},
+			},
+		},
+	}}, nil)
+
+	newService := func(allowOnError bool) *service.IOInfoService {
+		s, err := service.NewIOInfoService(nil, nil, nil, ss, nil, &config.SIPConfig{
+			InboundScreening: config.SIPScreeningConfig{URL: srv.URL, AllowOnError: allowOnError},
+		}, nil, nil, nil, nil)
+		require.NoError(t, err)
+		return s
+	}
+	req := &rpc.EvaluateSIPDispatchRulesRequest{
+		SipCallId:     "SCL_1",
+		CallingNumber: "+15550100",
+		CalledNumber:  "+15550199",
+		SrcAddress:    "10.0.0.1",
+	}
+
+	t.Run("rejected by endpoint", func(t *testing.T) {
+		fail = false
+		decision = service.SIPScreeningResponse{Action: service.SIPScreeningActionReject}
+		resp, err := newService(false).EvaluateSIPDispatchRules(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, rpc.SIPDispatchResult_REJECT, resp.Result)
+		require.Equal(t, "ST_1", resp.SipTrunkId)
+	})
+
+	t.Run("endpoint failure rejects", func(t *testing.T) {
+		fail = true
+		resp, err := newService(false).EvaluateSIPDispatchRules(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, rpc.SIPDispatchResult_REJECT, resp.Result)
+	})
+
+	t.Run("endpoint failure with allow_on_error", func(t *testing.T) {
+		fail = true
+		resp, err := newService(true).EvaluateSIPDispatchRules(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, rpc.SIPDispatchResult_ACCEPT, resp.Result)
+		require.Equal(t, "lobby", resp.RoomName)
+		require.Equal(t, "SDR_1", resp.SipDispatchRuleId)
+	})
+
+	t.Run("accepted with room override", func(t *testing.T) {
+		fail = false
+		decision = service.SIPScreeningResponse{Action: service.SIPScreeningActionAccept, RoomName: "support"}
+		resp, err := newService(false).EvaluateSIPDispatchRules(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, rpc.SIPDispatchResult_ACCEPT, resp.Result)
+		require.Equal(t, "support", resp.RoomName)
+	})
+}
+
+func TestSIPCallScreenerTrunks(t *testing.T) {
+	var received []service.SIPScreeningRequest
+	newEndpoint := func(action string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req service.SIPScreeningRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			received = append(received, req)
+			_ = json.NewEncoder(w).Encode(&service.SIPScreeningResponse{Action: action})
+		}))
+	}
+	accept := newEndpoint(service.SIPScreeningActionAccept)
+	defer accept.Close()
+	reject := newEndpoint(service.SIPScreeningActionReject)
+	defer reject.Close()
+
+	s := service.NewSIPCallScreener(config.SIPScreeningConfig{
+		URL: accept.URL,
+		Trunks: map[string]config.SIPScreeningConfig{
+			"ST_strict":   {URL: reject.URL, AttestationAttribute: "sip.attestation"},
+			"ST_internal": {},
+		},
+	})
+	req := &rpc.EvaluateSIPDispatchRulesRequest{
+		SipCallId:       "SCL_1",
+		CallingNumber:   "+15550100",
+		CalledNumber:    "+15550199",
+		ExtraAttributes: map[string]string{"sip.attestation": "C"},
+	}
+
+	res, err := s.Screen(context.Background(), "ST_strict", req)
+	require.NoError(t, err)
+	require.Equal(t, service.SIPScreeningActionReject, res.Action)
+	require.Equal(t, "C", received[0].Attestation)
+	require.False(t, s.AllowOnError("ST_strict"))
+
+	res, err = s.Screen(context.Background(), "ST_other", req)
+	require.NoError(t, err)
+	require.Equal(t, service.SIPScreeningActionAccept, res.Action)
+	require.Empty(t, received[1].Attestation)
+
+	res, err = s.Screen(context.Background(), "ST_internal", req)
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.True(t, s.AllowOnError("ST_internal"))
+	require.Len(t, received, 2)
+}
@@ -0,0 +1,311 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const (
+	defaultSIPCallRecordsRetention = 90 * 24 * time.Hour
+	defaultSIPUsageBucket          = time.Hour
+	maxSIPUsageBuckets             = 10000
+	maxSIPUsageRequest             = 64 * 1024
+)
+
+// SIPCallRecord is the call detail record of an ended call. Times are unix nanoseconds, as reported by the SIP service.
+type SIPCallRecord struct {
+	CallID         string `json:"call_id"`
+	TrunkID        string `json:"trunk_id,omitempty"`
+	DispatchRuleID string `json:"dispatch_rule_id,omitempty"`
+	Direction      string `json:"direction"`
+	Answered       bool   `json:"answered,omitempty"`
+	// SIP status code of calls which failed before being answered, unknown when the SIP service gave none
+	FailureCode string `json:"failure_code,omitempty"`
+	CreatedAt   int64  `json:"created_at"`
+	StartedAt   int64  `json:"started_at,omitempty"`
+	EndedAt     int64  `json:"ended_at"`
+}
+
+func (r *SIPCallRecord) duration() time.Duration {
+	if !r.Answered || r.StartedAt == 0 || r.EndedAt <= r.StartedAt {
+		return 0
+	}
+	return time.Duration(r.EndedAt - r.StartedAt)
+}
+
+// SIPCallRecorder keeps the call detail records of ended calls, from which usage reports are computed
+type SIPCallRecorder struct {
+	store     SIPCallRecordStore
+	retention time.Duration
+}
+
+// NewSIPCallRecorder returns nil when call records are disabled or there is no store for them
+func NewSIPCallRecorder(conf *config.SIPConfig, store SIPCallRecordStore) *SIPCallRecorder {
+	if !conf.CallRecords.Enabled || store == nil {
+		return nil
+	}
+	return &SIPCallRecorder{
+		store:     store,
+		retention: withDefault(conf.CallRecords.Retention, defaultSIPCallRecordsRetention),
+	}
+}
+
+// recordDispatchRule keeps the dispatch rule of an inbound call until it ends, state updates do not carry it
+func (r *SIPCallRecorder) recordDispatchRule(ctx context.Context, ss SIPStore, callID, ruleID string) error {
+	if callID == "" || ruleID == "" {
+		return nil
+	}
+	_, _, err := ss.UpdateSIPCallMetricsState(ctx, callID, map[string]string{
+		sipCallMetricRule: ruleID,
+	})
+	return err
+}
+
+// record stores the record of a call once it ended. State updates of a call may be served by any node, the
+// record is written by the first one seeing its end.
+func (r *SIPCallRecorder) record(ctx context.Context, ss SIPStore, info *livekit.SIPCallInfo) error {
+	switch info.CallStatus {
+	case livekit.SIPCallStatus_SCS_DISCONNECTED, livekit.SIPCallStatus_SCS_ERROR:
+	default:
+		return nil
+	}
+	set, state, err := ss.UpdateSIPCallMetricsState(ctx, info.CallId, map[string]string{
+		sipCallMetricRecorded: "1",
+	})
+	if err != nil {
+		return err
+	}
+	if !set[sipCallMetricRecorded] {
+		return nil
+	}
+
+	rec := &SIPCallRecord{
+		CallID:         info.CallId,
+		TrunkID:        withDefault(state[sipCallMetricTrunk], info.TrunkId),
+		DispatchRuleID: state[sipCallMetricRule],
+		// outbound calls are marked before dialing, so anything else was received
+		Direction: withDefault(state[sipCallMetricDirection], prometheus.SIPDirectionInbound),
+		Answered:  info.StartedAt != 0 || state[sipCallMetricActive] != "",
+		CreatedAt: info.CreatedAt,
+		StartedAt: info.StartedAt,
+		EndedAt:   withDefault(info.EndedAt, time.Now().UnixNano()),
+	}
+	if rec.CreatedAt == 0 {
+		rec.CreatedAt = withDefault(rec.StartedAt, rec.EndedAt)
+	}
+	if info.CallStatus == livekit.SIPCallStatus_SCS_ERROR && !rec.Answered {
+		rec.FailureCode = withDefault(sipStatusCode(info.Error), "unknown")
+	}
+	return r.store.StoreSIPCallRecord(ctx, rec, time.Now().Add(-r.retention))
+}
+
+// ---------------------------------------------
+
+// GetSIPUsageReportRequest is the JSON body of POST /sip_usage/report
+type GetSIPUsageReportRequest struct {
+	// range of the report as unix seconds, calls are counted in the bucket they were placed in
+	StartTime int64 `json:"start_time"`
+	EndTime   int64 `json:"end_time"`
+	// length of the buckets in seconds, one hour by default
+	BucketSeconds int64 `json:"bucket_seconds,omitempty"`
+	// only counts calls of these trunks or dispatch rules when set
+	TrunkIDs        []string `json:"trunk_ids,omitempty"`
+	DispatchRuleIDs []string `json:"dispatch_rule_ids,omitempty"`
+}
+
+type SIPUsageReport struct {
+	StartTime     int64             `json:"start_time"`
+	EndTime       int64             `json:"end_time"`
+	BucketSeconds int64             `json:"bucket_seconds"`
+	Trunks        []*SIPUsageSeries `json:"trunks"`
+	DispatchRules []*SIPUsageSeries `json:"dispatch_rules"`
+}
+
+// SIPUsageSeries is the usage of a trunk or dispatch rule, with a bucket for each interval of the range
+type SIPUsageSeries struct {
+	ID      string      `json:"id"`
+	Total   *SIPUsage   `json:"total"`
+	Buckets []*SIPUsage `json:"buckets"`
+}
+
+type SIPUsage struct {
+	// start of the bucket as unix seconds, unset for totals
+	StartTime     int64   `json:"start_time,omitempty"`
+	Calls         int     `json:"calls"`
+	InboundCalls  int     `json:"inbound_calls"`
+	OutboundCalls int     `json:"outbound_calls"`
+	AnsweredCalls int     `json:"answered_calls"`
+	FailedCalls   int     `json:"failed_calls"`
+	Minutes       float64 `json:"minutes"`
+	// number of failed calls by SIP status code
+	FailureCodes map[string]int `json:"failure_codes,omitempty"`
+}
+
+func (u *SIPUsage) add(rec *SIPCallRecord) {
+	u.Calls++
+	if rec.Direction == prometheus.SIPDirectionOutbound {
+		u.OutboundCalls++
+	} else {
+		u.InboundCalls++
+	}
+	if rec.Answered {
+		u.AnsweredCalls++
+	}
+	if rec.FailureCode != "" {
+		u.FailedCalls++
+		if u.FailureCodes == nil {
+			u.FailureCodes = make(map[string]int)
+		}
+		u.FailureCodes[rec.FailureCode]++
+	}
+	u.Minutes += rec.duration().Minutes()
+}
+
+// SIPUsageService serves time bucketed call volumes, failures and minutes per trunk and dispatch rule, computed
+// from call detail records, for billing and operations dashboards. Calls require SIP admin.
+type SIPUsageService struct {
+	recorder *SIPCallRecorder
+}
+
+func NewSIPUsageService(recorder *SIPCallRecorder) *SIPUsageService {
+	return &SIPUsageService{
+		recorder: recorder,
+	}
+}
+
+func (s *SIPUsageService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/sip_usage/") {
+	case "report":
+		var req GetSIPUsageReportRequest
+		if err = decodeJSONRequest(r, &req, maxSIPUsageRequest); err == nil {
+			res, err = s.GetSIPUsageReport(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *SIPUsageService) GetSIPUsageReport(ctx context.Context, req *GetSIPUsageReportRequest) (*SIPUsageReport, error) {
+	if err := EnsureSIPAdminPermission(ctx); err != nil {
+		return nil, err
+	}
+	if s.recorder == nil {
+		return nil, ErrSIPCallRecordsDisabled
+	}
+	bucket := time.Duration(req.BucketSeconds) * time.Second
+	if bucket == 0 {
+		bucket = defaultSIPUsageBucket
+	}
+	start, end := time.Unix(req.StartTime, 0), time.Unix(req.EndTime, 0)
+	switch {
+	case req.StartTime <= 0 || !end.After(start):
+		return nil, fmt.Errorf("%w: start_time must be before end_time", ErrSIPUsageReportInvalid)
+	case bucket < time.Second:
+		return nil, fmt.Errorf("%w: bucket_seconds must be positive", ErrSIPUsageReportInvalid)
+	}
+	numBuckets := int64((end.Sub(start) + bucket - 1) / bucket)
+	if numBuckets > maxSIPUsageBuckets {
+		return nil, fmt.Errorf("%w: more than %d buckets requested", ErrSIPUsageReportInvalid, maxSIPUsageBuckets)
+	}
+	AppendLogFields(ctx, "start", start, "end", end, "bucket", bucket)
+
+	records, err := s.recorder.store.ListSIPCallRecords(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	trunks := make(map[string]*SIPUsageSeries)
+	rules := make(map[string]*SIPUsageSeries)
+	addTo := func(series map[string]*SIPUsageSeries, id string, i int, rec *SIPCallRecord) {
+		if id == "" {
+			return
+		}
+		ser := series[id]
+		if ser == nil {
+			ser = &SIPUsageSeries{ID: id, Total: &SIPUsage{}, Buckets: make([]*SIPUsage, numBuckets)}
+			for j := range ser.Buckets {
+				ser.Buckets[j] = &SIPUsage{StartTime: start.Add(time.Duration(j) * bucket).Unix()}
+			}
+			series[id] = ser
+		}
+		ser.Total.add(rec)
+		ser.Buckets[i].add(rec)
+	}
+	for _, rec := range records {
+		if len(req.TrunkIDs) != 0 && !slices.Contains(req.TrunkIDs, rec.TrunkID) {
+			continue
+		}
+		if len(req.DispatchRuleIDs) != 0 && !slices.Contains(req.DispatchRuleIDs, rec.DispatchRuleID) {
+			continue
+		}
+		created := time.Unix(0, rec.CreatedAt)
+		if created.Before(start) || !created.Before(end) {
+			continue
+		}
+		i := int(created.Sub(start) / bucket)
+		addTo(trunks, rec.TrunkID, i, rec)
+		addTo(rules, rec.DispatchRuleID, i, rec)
+	}
+
+	return &SIPUsageReport{
+		StartTime:     req.StartTime,
+		EndTime:       req.EndTime,
+		BucketSeconds: int64(bucket / time.Second),
+		Trunks:        sortedSIPUsageSeries(trunks),
+		DispatchRules: sortedSIPUsageSeries(rules),
+	}, nil
+}
+
+func sortedSIPUsageSeries(series map[string]*SIPUsageSeries) []*SIPUsageSeries {
+	out := make([]*SIPUsageSeries, 0, len(series))
+	for _, ser := range series {
+		out = append(out, ser)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
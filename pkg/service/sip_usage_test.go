@@ -0,0 +1,209 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+func TestSIPCallRecords(t *testing.T) {
+	prometheus.Init("test", livekit.NodeType_SERVER)
+	ctx := context.Background()
+
+	// shared call state, fields are only set once like in the store
+	states := make(map[string]map[string]string)
+	ss := &servicefakes.FakeSIPStore{}
+	ss.UpdateSIPCallMetricsStateStub = func(_ context.Context, callID string, fields map[string]string) (map[string]bool, map[string]string, error) {
+		state := states[callID]
+		if state == nil {
+			state = make(map[string]string)
+			states[callID] = state
+		}
+		set := make(map[string]bool)
+		for k, v := range fields {
+			if _, ok := state[k]; !ok {
+				state[k] = v
+				set[k] = true
+			}
+		}
+		out := make(map[string]string, len(state))
+		for k, v := range state {
+			out[k] = v
+		}
+		return set, out, nil
+	}
+	ss.ListSIPInboundTrunkReturns([]*livekit.SIPInboundTrunkInfo{{SipTrunkId: "ST_1"}}, nil)
+	ss.ListSIPDispatchRuleReturns([]*livekit.SIPDispatchRuleInfo{{
+		SipDispatchRuleId: "SDR_1",
+		Rule: &livekit.SIPDispatchRule{
+			Rule: &livekit.SIPDispatchRule_DispatchRuleDirect{
+				DispatchRuleDirect: &livekit.SIPDispatchRuleDirect{RoomName: "lobby"},
+			},
+		},
+	}}, nil)
+
+	crs := &servicefakes.FakeSIPCallRecordStore{}
+	conf := &config.SIPConfig{CallRecords: config.SIPCallRecordsConfig{Enabled: true}}
+	recorder := service.NewSIPCallRecorder(conf, crs)
+	require.NotNil(t, recorder)
+	s, err := service.NewIOInfoService(nil, nil, nil, ss, nil, conf, nil, recorder, nil, nil)
+	require.NoError(t, err)
+
+	update := func(info *livekit.SIPCallInfo) {
+		_, err := s.UpdateSIPCallState(ctx, &rpc.UpdateSIPCallStateRequest{CallInfo: info})
+		require.NoError(t, err)
+	}
+
+	t.Run("answered call", func(t *testing.T) {
+		resp, err := s.EvaluateSIPDispatchRules(ctx, &rpc.EvaluateSIPDispatchRulesRequest{
+			SipCallId:     "SCL_1",
+			CallingNumber: "+15550100",
+			CalledNumber:  "+15550199",
+			SrcAddress:    "10.0.0.1",
+		})
+		require.NoError(t, err)
+		require.Equal(t, rpc.SIPDispatchResult_ACCEPT, resp.Result)
+
+		created := time.Now().Add(-time.Minute)
+		info := &livekit.SIPCallInfo{CallId: "SCL_1", TrunkId: "ST_1", CreatedAt: created.UnixNano()}
+		info.CallStatus = livekit.SIPCallStatus_SCS_CALL_INCOMING
+		update(info)
+		info.CallStatus = livekit.SIPCallStatus_SCS_ACTIVE
+		info.StartedAt = created.Add(time.Second).UnixNano()
+		update(info)
+		info.CallStatus = livekit.SIPCallStatus_SCS_DISCONNECTED
+		info.EndedAt = created.Add(31 * time.Second).UnixNano()
+		update(info)
+		// reported again by another node
+		update(info)
+
+		require.Equal(t, 1, crs.StoreSIPCallRecordCallCount())
+		_, rec, _ := crs.StoreSIPCallRecordArgsForCall(0)
+		require.Equal(t, "SCL_1", rec.CallID)
+		require.Equal(t, "ST_1", rec.TrunkID)
+		require.Equal(t, "SDR_1", rec.DispatchRuleID)
+		require.Equal(t, "inbound", rec.Direction)
+		require.True(t, rec.Answered)
+		require.Empty(t, rec.FailureCode)
+	})
+
+	t.Run("failed call", func(t *testing.T) {
+		update(&livekit.SIPCallInfo{
+			CallId:     "SCL_2",
+			TrunkId:    "ST_1",
+			CallStatus: livekit.SIPCallStatus_SCS_ERROR,
+			Error:      "call failed: sip status: 486: Busy Here",
+		})
+		require.Equal(t, 2, crs.StoreSIPCallRecordCallCount())
+		_, rec, _ := crs.StoreSIPCallRecordArgsForCall(1)
+		require.False(t, rec.Answered)
+		require.Equal(t, "486", rec.FailureCode)
+		require.NotZero(t, rec.CreatedAt)
+	})
+}
+
+func TestSIPUsageReport(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) int64 {
+		return start.Add(d).UnixNano()
+	}
+	crs := &servicefakes.FakeSIPCallRecordStore{}
+	crs.ListSIPCallRecordsReturns([]*service.SIPCallRecord{
+		{CallID: "1", TrunkID: "ST_1", DispatchRuleID: "SDR_1", Direction: "inbound", Answered: true,
+			CreatedAt: at(10 * time.Minute), StartedAt: at(10 * time.Minute), EndedAt: at(13 * time.Minute)},
+		{CallID: "2", TrunkID: "ST_1", DispatchRuleID: "SDR_1", Direction: "inbound", FailureCode: "486",
+			CreatedAt: at(70 * time.Minute), EndedAt: at(70 * time.Minute)},
+		{CallID: "3", TrunkID: "ST_2", Direction: "outbound", Answered: true,
+			CreatedAt: at(80 * time.Minute), StartedAt: at(81 * time.Minute), EndedAt: at(91 * time.Minute)},
+	}, nil)
+	s := service.NewSIPUsageService(service.NewSIPCallRecorder(&config.SIPConfig{
+		CallRecords: config.SIPCallRecordsConfig{Enabled: true},
+	}, crs))
+	adminCtx := service.WithGrants(context.Background(), &auth.ClaimGrants{SIP: &auth.SIPGrant{Admin: true}}, "")
+	req := &service.GetSIPUsageReportRequest{
+		StartTime: start.Unix(),
+		EndTime:   start.Add(2 * time.Hour).Unix(),
+	}
+
+	_, err := s.GetSIPUsageReport(context.Background(), req)
+	require.ErrorIs(t, err, service.ErrPermissionDenied)
+
+	report, err := s.GetSIPUsageReport(adminCtx, req)
+	require.NoError(t, err)
+	require.Equal(t, int64(3600), report.BucketSeconds)
+	_, from, to := crs.ListSIPCallRecordsArgsForCall(0)
+	require.True(t, from.Equal(start))
+	require.True(t, to.Equal(start.Add(2*time.Hour)))
+
+	require.Len(t, report.Trunks, 2)
+	trunk := report.Trunks[0]
+	require.Equal(t, "ST_1", trunk.ID)
+	require.Equal(t, 2, trunk.Total.Calls)
+	require.Equal(t, 1, trunk.Total.FailedCalls)
+	require.Equal(t, map[string]int{"486": 1}, trunk.Total.FailureCodes)
+	require.InDelta(t, 3.0, trunk.Total.Minutes, 0.001)
+	require.Len(t, trunk.Buckets, 2)
+	require.Equal(t, start.Unix(), trunk.Buckets[0].StartTime)
+	require.Equal(t, 1, trunk.Buckets[0].AnsweredCalls)
+	require.Equal(t, 1, trunk.Buckets[1].FailedCalls)
+
+	require.Equal(t, "ST_2", report.Trunks[1].ID)
+	require.Equal(t, 1, report.Trunks[1].Total.OutboundCalls)
+	require.InDelta(t, 10.0, report.Trunks[1].Total.Minutes, 0.001)
+
+	require.Len(t, report.DispatchRules, 1)
+	require.Equal(t, "SDR_1", report.DispatchRules[0].ID)
+	require.Equal(t, 2, report.DispatchRules[0].Total.InboundCalls)
+
+	t.Run("filtered", func(t *testing.T) {
+		report, err := s.GetSIPUsageReport(adminCtx, &service.GetSIPUsageReportRequest{
+			StartTime:     req.StartTime,
+			EndTime:       req.EndTime,
+			BucketSeconds: 600,
+			TrunkIDs:      []string{"ST_2"},
+		})
+		require.NoError(t, err)
+		require.Len(t, report.Trunks, 1)
+		require.Len(t, report.Trunks[0].Buckets, 12)
+		require.Equal(t, 1, report.Trunks[0].Buckets[8].Calls)
+		require.Empty(t, report.DispatchRules)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := s.GetSIPUsageReport(adminCtx, &service.GetSIPUsageReportRequest{StartTime: req.EndTime, EndTime: req.StartTime})
+		require.ErrorIs(t, err, service.ErrSIPUsageReportInvalid)
+		_, err = s.GetSIPUsageReport(adminCtx, &service.GetSIPUsageReportRequest{StartTime: req.StartTime, EndTime: req.EndTime, BucketSeconds: -1})
+		require.ErrorIs(t, err, service.ErrSIPUsageReportInvalid)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		require.Nil(t, service.NewSIPCallRecorder(&config.SIPConfig{}, crs))
+		_, err := service.NewSIPUsageService(nil).GetSIPUsageReport(adminCtx, req)
+		require.ErrorIs(t, err, service.ErrSIPCallRecordsDisabled)
+	})
+}
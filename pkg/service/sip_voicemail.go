@@ -0,0 +1,146 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/livekit/protocol/ingress"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/utils"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	// SIPVoicemailAttribute set to "true" on a dispatch rule records callers instead of joining them to a room.
+	// It is also set on the caller participant so the SIP service and agents can tell voicemail calls apart.
+	SIPVoicemailAttribute = "lk.voicemail"
+	// SIPVoicemailPromptAttribute holds the URL of the prompt played before recording
+	SIPVoicemailPromptAttribute = "lk.voicemail.prompt"
+
+	// SIPVoicemailRoomPrefix is the prefix of rooms created for each voicemail call. Voicemail rooms are
+	// recognized by the record stored at dispatch, see SIPVoicemailRoom, not by their name.
+	SIPVoicemailRoomPrefix = "voicemail_"
+
+	// SIPVoicemailPromptIdentity is the identity of the ingress participant playing the prompt
+	SIPVoicemailPromptIdentity = "voicemail_prompt"
+
+	defaultSIPVoicemailMaxDuration = 2 * time.Minute
+	sipVoicemailHookTimeout        = 10 * time.Second
+	// voicemail records outlive the call so that the recording can be matched once egress completes
+	sipVoicemailRecordGrace = time.Hour
+)
+
+// SIPVoicemailRoom is stored when a call is dispatched to voicemail, marking its room as a voicemail room
+type SIPVoicemailRoom struct {
+	RoomName  string
+	CallID    string
+	RuleID    string
+	PromptURL string
+}
+
+func IsSIPVoicemailRule(rule *livekit.SIPDispatchRuleInfo) bool {
+	return rule != nil && rule.Attributes[SIPVoicemailAttribute] == "true"
+}
+
+// ApplySIPVoicemail turns an accepted dispatch result into a voicemail capture:
+// the caller is placed alone in a dedicated room which is recorded by the voicemail room preset.
+// The returned record should be stored so that the room is handled as a voicemail room.
+func ApplySIPVoicemail(conf config.SIPVoicemailConfig, rule *livekit.SIPDispatchRuleInfo, req *rpc.EvaluateSIPDispatchRulesRequest, resp *rpc.EvaluateSIPDispatchRulesResponse) *SIPVoicemailRoom {
+	resp.RoomName = SIPVoicemailRoomPrefix + req.SipCallId
+
+	prompt := conf.PromptURL
+	if p := rule.Attributes[SIPVoicemailPromptAttribute]; p != "" {
+		prompt = p
+	}
+	if resp.ParticipantAttributes == nil {
+		resp.ParticipantAttributes = make(map[string]string)
+	}
+	resp.ParticipantAttributes[SIPVoicemailAttribute] = "true"
+	if prompt != "" {
+		resp.ParticipantAttributes[SIPVoicemailPromptAttribute] = prompt
+	}
+
+	resp.MaxCallDuration = durationpb.New(sipVoicemailMaxDuration(conf))
+
+	return &SIPVoicemailRoom{
+		RoomName:  resp.RoomName,
+		CallID:    req.SipCallId,
+		RuleID:    rule.SipDispatchRuleId,
+		PromptURL: prompt,
+	}
+}
+
+func sipVoicemailMaxDuration(conf config.SIPVoicemailConfig) time.Duration {
+	if conf.MaxDuration <= 0 {
+		return defaultSIPVoicemailMaxDuration
+	}
+	return conf.MaxDuration
+}
+
+// sipVoicemailRecordTTL is how long the voicemail record of a call is kept
+func sipVoicemailRecordTTL(conf config.SIPVoicemailConfig) time.Duration {
+	return sipVoicemailMaxDuration(conf) + sipVoicemailRecordGrace
+}
+
+// newSIPVoicemailPromptIngress returns the URL pull ingress playing the prompt into a voicemail room
+func newSIPVoicemailPromptIngress(vm *SIPVoicemailRoom) (*livekit.IngressInfo, error) {
+	info := &livekit.IngressInfo{
+		IngressId:           guid.New(utils.IngressPrefix),
+		Name:                "voicemail prompt " + vm.CallID,
+		Url:                 vm.PromptURL,
+		InputType:           livekit.IngressInput_URL_INPUT,
+		RoomName:            vm.RoomName,
+		ParticipantIdentity: SIPVoicemailPromptIdentity,
+		ParticipantName:     "Voicemail",
+		State:               &livekit.IngressState{},
+	}
+	if err := ingress.Validate(info); err != nil {
+		return nil, err
+	}
+	updateEnableTranscoding(info)
+	return info, nil
+}
+
+// SIPVoicemailHook delivers completed voicemail recordings, e.g. to a transcription service.
+type SIPVoicemailHook struct {
+	hook *jsonHook
+}
+
+// NewSIPVoicemailHook returns nil when no transcription endpoint is configured
+func NewSIPVoicemailHook(conf config.SIPVoicemailConfig) *SIPVoicemailHook {
+	if conf.TranscriptionURL == "" {
+		return nil
+	}
+	return &SIPVoicemailHook{
+		hook: newJSONHook("transcription", conf.TranscriptionURL, conf.TranscriptionHeaders, sipVoicemailHookTimeout),
+	}
+}
+
+// Deliver posts the egress info of a completed voicemail recording as JSON
+func (h *SIPVoicemailHook) Deliver(ctx context.Context, info *livekit.EgressInfo) error {
+	body, err := protojson.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return h.hook.post(ctx, body, nil)
+}
@@ -0,0 +1,190 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/utils"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+func TestApplySIPVoicemail(t *testing.T) {
+	rule := &livekit.SIPDispatchRuleInfo{
+		SipDispatchRuleId: "SDR_1",
+		Attributes: map[string]string{
+			service.SIPVoicemailAttribute: "true",
+		},
+	}
+	require.True(t, service.IsSIPVoicemailRule(rule))
+	require.False(t, service.IsSIPVoicemailRule(&livekit.SIPDispatchRuleInfo{}))
+
+	req := &rpc.EvaluateSIPDispatchRulesRequest{SipCallId: "SCL_1"}
+	resp := &rpc.EvaluateSIPDispatchRulesResponse{
+		Result:   rpc.SIPDispatchResult_ACCEPT,
+		RoomName: "room",
+	}
+	conf := config.SIPVoicemailConfig{PromptURL: "https://example.com/prompt.wav"}
+	vm := service.ApplySIPVoicemail(conf, rule, req, resp)
+	require.Equal(t, "voicemail_SCL_1", resp.RoomName)
+	require.Equal(t, &service.SIPVoicemailRoom{
+		RoomName:  "voicemail_SCL_1",
+		CallID:    "SCL_1",
+		RuleID:    "SDR_1",
+		PromptURL: conf.PromptURL,
+	}, vm)
+	require.Equal(t, "true", resp.ParticipantAttributes[service.SIPVoicemailAttribute])
+	require.Equal(t, conf.PromptURL, resp.ParticipantAttributes[service.SIPVoicemailPromptAttribute])
+	require.Equal(t, 2*time.Minute, resp.MaxCallDuration.AsDuration())
+
+	// rule overrides the prompt
+	rule.Attributes[service.SIPVoicemailPromptAttribute] = "https://example.com/custom.wav"
+	conf.MaxDuration = time.Minute
+	vm = service.ApplySIPVoicemail(conf, rule, req, resp)
+	require.Equal(t, "https://example.com/custom.wav", vm.PromptURL)
+	require.Equal(t, "https://example.com/custom.wav", resp.ParticipantAttributes[service.SIPVoicemailPromptAttribute])
+	require.Equal(t, time.Minute, resp.MaxCallDuration.AsDuration())
+}
+
+func TestSIPVoicemailRoomStore(t *testing.T) {
+	ctx := context.Background()
+	rs := redisStore(t)
+
+	roomName := "voicemail_" + guid.New(utils.SIPCallPrefix)
+	vm, err := rs.LoadSIPVoicemailRoom(ctx, roomName)
+	require.NoError(t, err)
+	require.Nil(t, vm)
+
+	stored := &service.SIPVoicemailRoom{
+		RoomName:  roomName,
+		CallID:    "SCL_1",
+		RuleID:    "SDR_1",
+		PromptURL: "https://example.com/prompt.wav",
+	}
+	require.NoError(t, rs.StoreSIPVoicemailRoom(ctx, stored, time.Minute))
+	vm, err = rs.LoadSIPVoicemailRoom(ctx, roomName)
+	require.NoError(t, err)
+	require.Equal(t, stored, vm)
+
+	// the prompt is only played once, whichever node claims it first
+	claimed, err := rs.ClaimSIPVoicemailPrompt(ctx, roomName)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	claimed, err = rs.ClaimSIPVoicemailPrompt(ctx, roomName)
+	require.NoError(t, err)
+	require.False(t, claimed)
+}
+
+func TestEvaluateSIPDispatchRulesVoicemail(t *testing.T) {
+	var decision *service.SIPScreeningResponse
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(decision)
+	}))
+	defer srv.Close()
+
+	ss := &servicefakes.FakeSIPStore{}
+	ss.ListSIPDispatchRuleReturns([]*livekit.SIPDispatchRuleInfo{{
+		SipDispatchRuleId: "SDR_1",
+		Rule: &livekit.SIPDispatchRule{
+			Rule: &livekit.SIPDispatchRule_DispatchRuleDirect{
+				DispatchRuleDirect: &livekit.SIPDispatchRuleDirect{RoomName: "lobby"},
+			},
+		},
+		Attributes: map[string]string{service.SIPVoicemailAttribute: "true"},
+	}}, nil)
+
+	s, err := service.NewIOInfoService(nil, nil, nil, ss, nil, &config.SIPConfig{
+		InboundScreening: config.SIPScreeningConfig{URL: srv.URL},
+		Voicemail:        config.SIPVoicemailConfig{RoomPreset: "voicemail"},
+	}, nil, nil, nil, nil)
+	require.NoError(t, err)
+	req := &rpc.EvaluateSIPDispatchRulesRequest{
+		SipCallId:     "SCL_1",
+		CallingNumber: "+15550100",
+		CalledNumber:  "+15550199",
+		SrcAddress:    "10.0.0.1",
+	}
+
+	t.Run("dispatched to voicemail", func(t *testing.T) {
+		decision = &service.SIPScreeningResponse{Action: service.SIPScreeningActionAccept}
+		resp, err := s.EvaluateSIPDispatchRules(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, rpc.SIPDispatchResult_ACCEPT, resp.Result)
+		require.Equal(t, "voicemail_SCL_1", resp.RoomName)
+
+		require.Equal(t, 1, ss.StoreSIPVoicemailRoomCallCount())
+		_, vm, ttl := ss.StoreSIPVoicemailRoomArgsForCall(0)
+		require.Equal(t, "voicemail_SCL_1", vm.RoomName)
+		require.Equal(t, "SCL_1", vm.CallID)
+		require.Greater(t, ttl, 2*time.Minute)
+	})
+
+	t.Run("screening room override takes precedence", func(t *testing.T) {
+		decision = &service.SIPScreeningResponse{Action: service.SIPScreeningActionAccept, RoomName: "support"}
+		resp, err := s.EvaluateSIPDispatchRules(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, rpc.SIPDispatchResult_ACCEPT, resp.Result)
+		require.Equal(t, "support", resp.RoomName)
+		require.NotContains(t, resp.ParticipantAttributes, service.SIPVoicemailAttribute)
+		require.Equal(t, 1, ss.StoreSIPVoicemailRoomCallCount())
+	})
+}
+
+func TestSIPVoicemailHook(t *testing.T) {
+	require.Nil(t, service.NewSIPVoicemailHook(config.SIPVoicemailConfig{}))
+
+	received := make(chan *livekit.EgressInfo, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "secret", r.Header.Get("Authorization"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		info := &livekit.EgressInfo{}
+		require.NoError(t, protojson.Unmarshal(body, info))
+		received <- info
+	}))
+	defer srv.Close()
+
+	hook := service.NewSIPVoicemailHook(config.SIPVoicemailConfig{
+		TranscriptionURL: srv.URL,
+		TranscriptionHeaders: map[string]string{
+			"Authorization": "secret",
+		},
+	})
+	err := hook.Deliver(context.Background(), &livekit.EgressInfo{
+		EgressId: "EG_1",
+		RoomName: "voicemail_SCL_1",
+		Status:   livekit.EgressStatus_EGRESS_COMPLETE,
+	})
+	require.NoError(t, err)
+
+	info := <-received
+	require.Equal(t, "EG_1", info.EgressId)
+	require.Equal(t, "voicemail_SCL_1", info.RoomName)
+}
@@ -0,0 +1,275 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// egress destinations of config.TokenConfigPolicyConfig, besides the schemes of stream URLs
+const (
+	EgressDestinationS3      = "s3"
+	EgressDestinationGCP     = "gcp"
+	EgressDestinationAzure   = "azure"
+	EgressDestinationAliOSS  = "alioss"
+	EgressDestinationDefault = "default"
+)
+
+// RoomConfigViolation is a setting of the room configuration of a token which is not allowed
+type RoomConfigViolation struct {
+	// path of the setting, e.g. egress.room.stream_outputs[0].urls[1]
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+func (v RoomConfigViolation) String() string {
+	return fmt.Sprintf("%s=%q: %s", v.Field, v.Value, v.Reason)
+}
+
+// TokenConfigPolicy checks the room configuration embedded in tokens against config.TokenConfigPolicyConfig
+type TokenConfigPolicy struct {
+	conf         config.TokenConfigPolicyConfig
+	destinations map[string]struct{}
+	codecs       map[string]struct{}
+}
+
+func NewTokenConfigPolicy(conf config.TokenConfigPolicyConfig) *TokenConfigPolicy {
+	p := &TokenConfigPolicy{conf: conf}
+	if len(conf.AllowedEgressDestinations) != 0 {
+		p.destinations = make(map[string]struct{})
+		for _, destination := range conf.AllowedEgressDestinations {
+			p.destinations[strings.ToLower(destination)] = struct{}{}
+		}
+	}
+	if len(conf.AllowedCodecs) != 0 {
+		p.codecs = make(map[string]struct{})
+		for _, codec := range conf.AllowedCodecs {
+			p.codecs[strings.ToUpper(codec)] = struct{}{}
+		}
+	}
+	return p
+}
+
+func (p *TokenConfigPolicy) Reject() bool {
+	return p.conf.Reject
+}
+
+// Check returns the room configuration without the settings which are not allowed, and the settings removed.
+// The configuration of the token is not modified.
+func (p *TokenConfigPolicy) Check(conf *livekit.RoomConfiguration) (*livekit.RoomConfiguration, []RoomConfigViolation) {
+	if conf == nil {
+		return nil, nil
+	}
+
+	c := &tokenConfigCheck{policy: p}
+	allowed := utils.CloneProto(conf)
+	allowed.Agents = c.checkAgents(allowed.Agents)
+	allowed.Egress = c.checkEgress(allowed.Egress)
+	return allowed, c.violations
+}
+
+type tokenConfigCheck struct {
+	policy     *TokenConfigPolicy
+	violations []RoomConfigViolation
+}
+
+func (c *tokenConfigCheck) violation(field string, value string, reason string) {
+	c.violations = append(c.violations, RoomConfigViolation{Field: field, Value: value, Reason: reason})
+}
+
+func (c *tokenConfigCheck) checkAgents(agents []*livekit.RoomAgentDispatch) []*livekit.RoomAgentDispatch {
+	var allowed []*livekit.RoomAgentDispatch
+	for i, agent := range agents {
+		field := fmt.Sprintf("agents[%d].agent_name", i)
+		if c.policy.conf.DenyAgents {
+			c.violation(field, agent.GetAgentName(), "agents are not allowed")
+			continue
+		}
+		if !c.agentAllowed(agent.GetAgentName()) {
+			c.violation(field, agent.GetAgentName(), "agent is not allowed")
+			continue
+		}
+		allowed = append(allowed, agent)
+	}
+	return allowed
+}
+
+func (c *tokenConfigCheck) agentAllowed(name string) bool {
+	if len(c.policy.conf.AllowedAgents) == 0 {
+		return true
+	}
+	for _, pattern := range c.policy.conf.AllowedAgents {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEgress removes the outputs with destinations or encodings which are not allowed, egresses left without
+// outputs are removed
+func (c *tokenConfigCheck) checkEgress(egress *livekit.RoomEgress) *livekit.RoomEgress {
+	if egress == nil {
+		return nil
+	}
+	if c.policy.conf.DenyEgress {
+		c.violation("egress", "", "egress is not allowed")
+		return nil
+	}
+
+	if room := egress.Room; room != nil {
+		c.checkEncoding("egress.room.advanced", room.GetAdvanced(), func() {
+			room.Options = nil
+		})
+		if file := room.GetFile(); file != nil && !c.checkOutput("egress.room.file", file) {
+			room.Output = nil
+		}
+		if segments := room.GetSegments(); segments != nil && !c.checkOutput("egress.room.segments", segments) {
+			room.Output = nil
+		}
+		if stream := room.GetStream(); stream != nil && !c.checkStream("egress.room.stream", stream) {
+			room.Output = nil
+		}
+		room.FileOutputs = checkOutputs(c, "egress.room.file_outputs", room.FileOutputs)
+		room.SegmentOutputs = checkOutputs(c, "egress.room.segment_outputs", room.SegmentOutputs)
+		room.ImageOutputs = checkOutputs(c, "egress.room.image_outputs", room.ImageOutputs)
+		var streams []*livekit.StreamOutput
+		for i, stream := range room.StreamOutputs {
+			if c.checkStream(fmt.Sprintf("egress.room.stream_outputs[%d]", i), stream) {
+				streams = append(streams, stream)
+			}
+		}
+		room.StreamOutputs = streams
+		if room.Output == nil && len(room.FileOutputs) == 0 && len(room.SegmentOutputs) == 0 &&
+			len(room.ImageOutputs) == 0 && len(room.StreamOutputs) == 0 {
+			egress.Room = nil
+		}
+	}
+
+	if participant := egress.Participant; participant != nil {
+		c.checkEncoding("egress.participant.advanced", participant.GetAdvanced(), func() {
+			participant.Options = nil
+		})
+		participant.FileOutputs = checkOutputs(c, "egress.participant.file_outputs", participant.FileOutputs)
+		participant.SegmentOutputs = checkOutputs(c, "egress.participant.segment_outputs", participant.SegmentOutputs)
+		if len(participant.FileOutputs) == 0 && len(participant.SegmentOutputs) == 0 {
+			egress.Participant = nil
+		}
+	}
+
+	if tracks := egress.Tracks; tracks != nil && !c.checkOutput("egress.tracks", tracks) {
+		egress.Tracks = nil
+	}
+
+	if egress.Room == nil && egress.Participant == nil && egress.Tracks == nil {
+		return nil
+	}
+	return egress
+}
+
+func (c *tokenConfigCheck) checkEncoding(field string, options *livekit.EncodingOptions, strip func()) {
+	if options == nil || c.policy.codecs == nil {
+		return
+	}
+	allowed := true
+	if codec := options.AudioCodec; codec != livekit.AudioCodec_DEFAULT_AC {
+		if _, ok := c.policy.codecs[codec.String()]; !ok {
+			c.violation(field+".audio_codec", codec.String(), "codec is not allowed")
+			allowed = false
+		}
+	}
+	if codec := options.VideoCodec; codec != livekit.VideoCodec_DEFAULT_VC {
+		if _, ok := c.policy.codecs[codec.String()]; !ok {
+			c.violation(field+".video_codec", codec.String(), "codec is not allowed")
+			allowed = false
+		}
+	}
+	if !allowed {
+		// the egress encodes with its default options instead
+		strip()
+	}
+}
+
+type uploadOutput interface {
+	GetS3() *livekit.S3Upload
+	GetGcp() *livekit.GCPUpload
+	GetAzure() *livekit.AzureBlobUpload
+	GetAliOSS() *livekit.AliOSSUpload
+}
+
+func checkOutputs[T uploadOutput](c *tokenConfigCheck, field string, outputs []T) []T {
+	var allowed []T
+	for i, output := range outputs {
+		if c.checkOutput(fmt.Sprintf("%s[%d]", field, i), output) {
+			allowed = append(allowed, output)
+		}
+	}
+	return allowed
+}
+
+func (c *tokenConfigCheck) checkOutput(field string, output uploadOutput) bool {
+	if c.policy.destinations == nil {
+		return true
+	}
+	var destination string
+	switch {
+	case output.GetS3() != nil:
+		destination = EgressDestinationS3
+	case output.GetGcp() != nil:
+		destination = EgressDestinationGCP
+	case output.GetAzure() != nil:
+		destination = EgressDestinationAzure
+	case output.GetAliOSS() != nil:
+		destination = EgressDestinationAliOSS
+	default:
+		destination = EgressDestinationDefault
+	}
+	if _, ok := c.policy.destinations[destination]; !ok {
+		c.violation(field, destination, "egress destination is not allowed")
+		return false
+	}
+	return true
+}
+
+// checkStream removes the URLs which are not allowed, and returns false when none is left
+func (c *tokenConfigCheck) checkStream(field string, stream *livekit.StreamOutput) bool {
+	if c.policy.destinations == nil {
+		return true
+	}
+	var urls []string
+	for i, u := range stream.Urls {
+		scheme := ""
+		if parsed, err := url.Parse(u); err == nil {
+			scheme = strings.ToLower(parsed.Scheme)
+		}
+		if _, ok := c.policy.destinations[scheme]; !ok || scheme == "" {
+			// the URL carries the stream key, only its scheme is reported
+			c.violation(fmt.Sprintf("%s.urls[%d]", field, i), scheme, "egress destination is not allowed")
+			continue
+		}
+		urls = append(urls, u)
+	}
+	stream.Urls = urls
+	return len(urls) != 0
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestTokenConfigPolicy(t *testing.T) {
+	roomConfig := func() *livekit.RoomConfiguration {
+		return &livekit.RoomConfiguration{
+			MaxParticipants: 10,
+			Agents: []*livekit.RoomAgentDispatch{
+				{AgentName: "support-bot"},
+				{AgentName: "scraper"},
+			},
+			Egress: &livekit.RoomEgress{
+				Room: &livekit.RoomCompositeEgressRequest{
+					Options: &livekit.RoomCompositeEgressRequest_Advanced{
+						Advanced: &livekit.EncodingOptions{VideoCodec: livekit.VideoCodec_H264_HIGH},
+					},
+					FileOutputs: []*livekit.EncodedFileOutput{
+						{Output: &livekit.EncodedFileOutput_S3{S3: &livekit.S3Upload{Bucket: "recordings"}}},
+					},
+					StreamOutputs: []*livekit.StreamOutput{
+						{Urls: []string{"rtmps://live.example.com/key", "srt://example.com:9000"}},
+					},
+				},
+				Tracks: &livekit.AutoTrackEgress{
+					Output: &livekit.AutoTrackEgress_Gcp{Gcp: &livekit.GCPUpload{Bucket: "tracks"}},
+				},
+			},
+		}
+	}
+
+	t.Run("no policy", func(t *testing.T) {
+		conf := roomConfig()
+		allowed, violations := service.NewTokenConfigPolicy(config.TokenConfigPolicyConfig{}).Check(conf)
+		require.Empty(t, violations)
+		require.True(t, proto.Equal(conf, allowed))
+	})
+
+	t.Run("strip", func(t *testing.T) {
+		conf := roomConfig()
+		allowed, violations := service.NewTokenConfigPolicy(config.TokenConfigPolicyConfig{
+			AllowedAgents:             []string{"support-*"},
+			AllowedEgressDestinations: []string{"s3", "rtmps"},
+			AllowedCodecs:             []string{"h264_main", "opus"},
+		}).Check(conf)
+
+		require.Equal(t, []service.RoomConfigViolation{
+			{Field: "agents[1].agent_name", Value: "scraper", Reason: "agent is not allowed"},
+			{Field: "egress.room.advanced.video_codec", Value: "H264_HIGH", Reason: "codec is not allowed"},
+			{Field: "egress.room.stream_outputs[0].urls[1]", Value: "srt", Reason: "egress destination is not allowed"},
+			{Field: "egress.tracks", Value: "gcp", Reason: "egress destination is not allowed"},
+		}, violations)
+
+		require.Equal(t, uint32(10), allowed.MaxParticipants)
+		require.Len(t, allowed.Agents, 1)
+		require.Nil(t, allowed.Egress.Room.Options)
+		require.Len(t, allowed.Egress.Room.FileOutputs, 1)
+		require.Equal(t, []string{"rtmps://live.example.com/key"}, allowed.Egress.Room.StreamOutputs[0].Urls)
+		require.Nil(t, allowed.Egress.Tracks)
+
+		// the token is left as is
+		require.True(t, proto.Equal(roomConfig(), conf))
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		allowed, violations := service.NewTokenConfigPolicy(config.TokenConfigPolicyConfig{
+			DenyAgents: true,
+			DenyEgress: true,
+		}).Check(roomConfig())
+		require.Len(t, violations, 3)
+		require.Empty(t, allowed.Agents)
+		require.Nil(t, allowed.Egress)
+	})
+
+	t.Run("egress without outputs", func(t *testing.T) {
+		allowed, violations := service.NewTokenConfigPolicy(config.TokenConfigPolicyConfig{
+			AllowedEgressDestinations: []string{"azure"},
+		}).Check(roomConfig())
+		require.Len(t, violations, 4)
+		require.Nil(t, allowed.Egress)
+	})
+}
@@ -0,0 +1,449 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const maxTokenRevocationRequest = 16 * 1024
+
+// TokenRevocation blocks either a single token, or the tokens of an identity issued until RevokedAt. Times are
+// unix seconds.
+type TokenRevocation struct {
+	// sha256 of the revoked token
+	TokenHash string `json:"token_hash,omitempty"`
+	Identity  string `json:"identity,omitempty"`
+	// when set, only the tokens of the identity for this room are revoked
+	Room      string `json:"room,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	RevokedAt int64  `json:"revoked_at"`
+	// the revocation is dropped once the token expired, or max_token_ttl after the revocation of an identity.
+	// 0 for tokens without expiry
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+}
+
+// ID is unique per token, or per identity and room
+func (r *TokenRevocation) ID() string {
+	if r.TokenHash != "" {
+		return "token:" + r.TokenHash
+	}
+	return "identity:" + r.Room + "/" + r.Identity
+}
+
+func (r *TokenRevocation) expired(now time.Time) bool {
+	return r.ExpiresAt != 0 && now.Unix() >= r.ExpiresAt
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenTimes returns the unix times a token became valid and expires at, 0 when not set. It must only be
+// called with verified tokens.
+func tokenTimes(token string) (issuedAt int64, expiresAt int64) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, 0
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, 0
+	}
+	var claims struct {
+		NotBefore int64 `json:"nbf"`
+		IssuedAt  int64 `json:"iat"`
+		Expiry    int64 `json:"exp"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return 0, 0
+	}
+	return max(claims.NotBefore, claims.IssuedAt), claims.Expiry
+}
+
+// TokenRevocations caches the revocations of the store. The store is checked for revocations made on other
+// nodes once the cache expires, by a single request while the others keep using the cached revocations.
+type TokenRevocations struct {
+	store       TokenRevocationStore
+	cacheTTL    time.Duration
+	maxTokenTTL time.Duration
+
+	cache atomic.Pointer[tokenRevocationCache]
+	// serializes the loads from the store
+	refreshLock sync.Mutex
+}
+
+type tokenRevocationCache struct {
+	tokens     map[string]*TokenRevocation
+	identities map[string]*TokenRevocation
+	version    int64
+	loaded     bool
+	checkedAt  time.Time
+}
+
+// NewTokenRevocations returns nil when token revocation is disabled
+func NewTokenRevocations(conf *config.Config, store TokenRevocationStore) (*TokenRevocations, error) {
+	if !conf.TokenRevocation.Enabled {
+		return nil, nil
+	}
+	if store == nil {
+		return nil, errors.New("token revocation is not supported by the store")
+	}
+	t := &TokenRevocations{
+		store:       store,
+		cacheTTL:    conf.TokenRevocation.CacheTTL,
+		maxTokenTTL: conf.TokenRevocation.MaxTokenTTL,
+	}
+	t.cache.Store(&tokenRevocationCache{})
+	t.refreshLock.Lock()
+	t.refreshLocked(true)
+	t.refreshLock.Unlock()
+	return t, nil
+}
+
+// IsRevoked returns the revocation blocking a verified token, nil when it is not revoked.
+//
+// Revocations of an identity block its tokens issued strictly before the second of the revocation, iat and nbf
+// being whole seconds, a token issued in the same second as the revocation is accepted so that the identity can
+// be issued new tokens right away. Tokens without iat or nbf are considered issued before any revocation, they
+// are refused until the revocation is dropped, max_token_ttl after it was made.
+func (t *TokenRevocations) IsRevoked(token string, grants *auth.ClaimGrants) *TokenRevocation {
+	c := t.cache.Load()
+	if !c.loaded || time.Since(c.checkedAt) >= t.cacheTTL {
+		if t.refreshLock.TryLock() {
+			t.refreshLocked(false)
+			t.refreshLock.Unlock()
+			c = t.cache.Load()
+		}
+	}
+
+	now := time.Now()
+	if r := c.tokens[hashToken(token)]; r != nil && !r.expired(now) {
+		return r
+	}
+	if grants == nil || grants.Identity == "" || len(c.identities) == 0 {
+		return nil
+	}
+	issuedAt, _ := tokenTimes(token)
+	check := func(room string) *TokenRevocation {
+		r := c.identities[(&TokenRevocation{Identity: grants.Identity, Room: room}).ID()]
+		if r != nil && !r.expired(now) && issuedAt < r.RevokedAt {
+			return r
+		}
+		return nil
+	}
+	if r := check(""); r != nil {
+		return r
+	}
+	if grants.Video != nil && grants.Video.Room != "" {
+		return check(grants.Video.Room)
+	}
+	return nil
+}
+
+// newIdentityRevocation revokes the tokens of an identity issued until now, the revocation expires once the
+// tokens issued before it did
+func (t *TokenRevocations) newIdentityRevocation(identity, room, reason string) *TokenRevocation {
+	now := time.Now()
+	return &TokenRevocation{
+		Identity:  identity,
+		Room:      room,
+		Reason:    reason,
+		RevokedAt: now.Unix(),
+		ExpiresAt: now.Add(t.maxTokenTTL).Unix(),
+	}
+}
+
+func (t *TokenRevocations) revoke(ctx context.Context, r *TokenRevocation) error {
+	if err := t.store.StoreTokenRevocation(ctx, r); err != nil {
+		return err
+	}
+	t.refreshLock.Lock()
+	t.refreshLocked(true)
+	t.refreshLock.Unlock()
+	return nil
+}
+
+func (t *TokenRevocations) refreshLocked(force bool) {
+	current := t.cache.Load()
+	if !force && current.loaded && time.Since(current.checkedAt) < t.cacheTTL {
+		return
+	}
+	// cached revocations keep being enforced when the store cannot be reached, it is checked again once the
+	// cache expires
+	next := *current
+	next.checkedAt = time.Now()
+	defer func() { t.cache.Store(&next) }()
+
+	ctx := context.Background()
+	version, err := t.store.LoadTokenRevocationsVersion(ctx)
+	if err != nil {
+		logger.Warnw("could not check token revocations", err)
+		return
+	}
+	if !force && current.loaded && version == current.version {
+		return
+	}
+	revocations, err := t.store.ListTokenRevocations(ctx)
+	if err != nil {
+		logger.Warnw("could not load token revocations", err)
+		return
+	}
+	next.tokens = make(map[string]*TokenRevocation)
+	next.identities = make(map[string]*TokenRevocation)
+	for _, r := range revocations {
+		if r.TokenHash != "" {
+			next.tokens[r.TokenHash] = r
+		} else {
+			next.identities[r.ID()] = r
+		}
+	}
+	next.version = version
+	next.loaded = true
+}
+
+// ---------------------------------------------
+
+// TokenRevocationMiddleware refuses revoked tokens, it runs after APIKeyAuthMiddleware
+type TokenRevocationMiddleware struct {
+	revocations *TokenRevocations
+}
+
+func NewTokenRevocationMiddleware(revocations *TokenRevocations) *TokenRevocationMiddleware {
+	return &TokenRevocationMiddleware{
+		revocations: revocations,
+	}
+}
+
+func (m *TokenRevocationMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if token := getAuthToken(r.Context()); token != "" {
+		if revoked := m.revocations.IsRevoked(token, GetGrants(r.Context())); revoked != nil {
+			handleError(w, r, http.StatusUnauthorized, ErrTokenRevoked)
+			return
+		}
+	}
+	next.ServeHTTP(w, r)
+}
+
+// ---------------------------------------------
+
+// RevokeTokenRequest is the JSON body of POST /token_revocation/revoke_token
+type RevokeTokenRequest struct {
+	Token  string `json:"token"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// RevokeParticipantSessionsRequest is the JSON body of POST /token_revocation/revoke_participant_sessions.
+// Tokens of the identity issued until now are revoked, for the room only when it is set.
+type RevokeParticipantSessionsRequest struct {
+	Identity string `json:"identity"`
+	Room     string `json:"room,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type TokenRevocationResponse struct {
+	Revocation *TokenRevocation `json:"revocation"`
+	// revocation of the tokens the identity was issued for the room until now, made along the one of a token
+	SessionRevocation *TokenRevocation `json:"session_revocation,omitempty"`
+	// rooms the participant was disconnected from
+	Disconnected []string `json:"disconnected,omitempty"`
+}
+
+// TokenRevocationService serves the API revoking tokens. Revoking tokens for a room requires roomAdmin on it,
+// revoking the tokens of an identity in every room requires roomCreate.
+type TokenRevocationService struct {
+	revocations *TokenRevocations
+	keyProvider auth.KeyProvider
+	roomService *RoomService
+}
+
+func NewTokenRevocationService(
+	revocations *TokenRevocations,
+	keyProvider auth.KeyProvider,
+	roomService *RoomService,
+) *TokenRevocationService {
+	return &TokenRevocationService{
+		revocations: revocations,
+		keyProvider: keyProvider,
+		roomService: roomService,
+	}
+}
+
+func (s *TokenRevocationService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res *TokenRevocationResponse
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/token_revocation/") {
+	case "revoke_token":
+		var req RevokeTokenRequest
+		if err = decodeJSONRequest(r, &req, maxTokenRevocationRequest); err == nil {
+			res, err = s.RevokeToken(r.Context(), &req)
+		}
+	case "revoke_participant_sessions":
+		var req RevokeParticipantSessionsRequest
+		if err = decodeJSONRequest(r, &req, maxTokenRevocationRequest); err == nil {
+			res, err = s.RevokeParticipantSessions(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// RevokeToken blocks a token until it expires and disconnects its participant from the room of the token.
+// The server keeps sending refreshed tokens to connected participants, so the tokens of the identity for the
+// room issued until now are revoked as well, the participant could otherwise reconnect with one of them.
+func (s *TokenRevocationService) RevokeToken(ctx context.Context, req *RevokeTokenRequest) (*TokenRevocationResponse, error) {
+	if s.revocations == nil {
+		return nil, ErrTokenRevocationDisabled
+	}
+	v, err := auth.ParseAPIToken(req.Token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenRevocationInvalid, err)
+	}
+	// only tokens signed by a known key are revoked, so that their claims can be trusted
+	grants, err := verifyAPIToken(v, req.Token, s.keyProvider.GetSecret(v.APIKey()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenRevocationInvalid, err)
+	}
+	var room string
+	if grants.Video != nil {
+		room = grants.Video.Room
+	}
+	AppendLogFields(ctx, "room", room, "participant", grants.Identity)
+	if err = s.ensurePermission(ctx, room); err != nil {
+		return nil, err
+	}
+
+	_, expiresAt := tokenTimes(req.Token)
+	r := &TokenRevocation{
+		TokenHash: hashToken(req.Token),
+		Identity:  grants.Identity,
+		Room:      room,
+		Reason:    req.Reason,
+		RevokedAt: time.Now().Unix(),
+		ExpiresAt: expiresAt,
+	}
+	if err = s.revocations.revoke(ctx, r); err != nil {
+		return nil, err
+	}
+	res := &TokenRevocationResponse{Revocation: r}
+	if room != "" && grants.Identity != "" {
+		res.SessionRevocation = s.revocations.newIdentityRevocation(grants.Identity, room, req.Reason)
+		if err = s.revocations.revoke(ctx, res.SessionRevocation); err != nil {
+			return nil, err
+		}
+		res.Disconnected = s.disconnect(ctx, []livekit.RoomName{livekit.RoomName(room)}, grants.Identity)
+	}
+	return res, nil
+}
+
+// RevokeParticipantSessions blocks the tokens of an identity issued until now and disconnects its sessions
+func (s *TokenRevocationService) RevokeParticipantSessions(ctx context.Context, req *RevokeParticipantSessionsRequest) (*TokenRevocationResponse, error) {
+	AppendLogFields(ctx, "room", req.Room, "participant", req.Identity)
+	if s.revocations == nil {
+		return nil, ErrTokenRevocationDisabled
+	}
+	if err := s.ensurePermission(ctx, req.Room); err != nil {
+		return nil, err
+	}
+	if req.Identity == "" {
+		return nil, fmt.Errorf("%w: identity is required", ErrTokenRevocationInvalid)
+	}
+
+	r := s.revocations.newIdentityRevocation(req.Identity, req.Room, req.Reason)
+	if err := s.revocations.revoke(ctx, r); err != nil {
+		return nil, err
+	}
+
+	var rooms []livekit.RoomName
+	if req.Room != "" {
+		rooms = []livekit.RoomName{livekit.RoomName(req.Room)}
+	} else {
+		active, err := s.roomService.roomStore.ListRooms(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, room := range active {
+			rooms = append(rooms, livekit.RoomName(room.Name))
+		}
+	}
+	return &TokenRevocationResponse{
+		Revocation:   r,
+		Disconnected: s.disconnect(ctx, rooms, req.Identity),
+	}, nil
+}
+
+func (s *TokenRevocationService) ensurePermission(ctx context.Context, room string) error {
+	if room != "" && EnsureAdminPermission(ctx, livekit.RoomName(room)) == nil {
+		return nil
+	}
+	return EnsureCreatePermission(ctx)
+}
+
+// disconnect removes the participant from the rooms it is in, returning those it was removed from
+func (s *TokenRevocationService) disconnect(ctx context.Context, rooms []livekit.RoomName, identity string) []string {
+	var disconnected []string
+	for _, room := range rooms {
+		pID := livekit.ParticipantIdentity(identity)
+		if _, err := s.roomService.roomStore.LoadParticipant(ctx, room, pID); err != nil {
+			continue
+		}
+		if _, err := s.roomService.participantClient.RemoveParticipant(
+			ctx,
+			s.roomService.topicFormatter.ParticipantTopic(ctx, room, pID),
+			&livekit.RoomParticipantIdentity{Room: string(room), Identity: identity},
+		); err != nil {
+			if !errors.Is(err, ErrParticipantNotFound) {
+				logger.Warnw("could not disconnect participant of revoked token", err, "room", room, "participant", identity)
+			}
+			continue
+		}
+		disconnected = append(disconnected, string(room))
+	}
+	return disconnected
+}
@@ -0,0 +1,251 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc/rpcfakes"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+func TestTokenRevocation(t *testing.T) {
+	const (
+		apiKey = "APIkey"
+		secret = "somesecretencodedinbase62extendto32bytes"
+	)
+	keyProvider := auth.NewFileBasedKeyProviderFromMap(map[string]string{apiKey: secret})
+	// tokens issued in the same second as a revocation are accepted, those of the tests are issued earlier
+	issueToken := func(t *testing.T, identity string, grants *auth.ClaimGrants) string {
+		sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte(secret)}, nil)
+		require.NoError(t, err)
+		issuedAt := time.Now().Add(-time.Minute)
+		token, err := jwt.Signed(sig).Claims(jwt.Claims{
+			Issuer:    apiKey,
+			Subject:   identity,
+			NotBefore: jwt.NewNumericDate(issuedAt),
+			Expiry:    jwt.NewNumericDate(issuedAt.Add(time.Hour)),
+		}).Claims(grants).CompactSerialize()
+		require.NoError(t, err)
+		return token
+	}
+	newToken := func(t *testing.T, identity, room string) string {
+		return issueToken(t, identity, &auth.ClaimGrants{Video: &auth.VideoGrant{RoomJoin: true, Room: room}})
+	}
+	newRevocations := func(t *testing.T, store service.TokenRevocationStore, cacheTTL time.Duration) *service.TokenRevocations {
+		revocations, err := service.NewTokenRevocations(&config.Config{
+			TokenRevocation: config.TokenRevocationConfig{Enabled: true, CacheTTL: cacheTTL, MaxTokenTTL: 24 * time.Hour},
+		}, store)
+		require.NoError(t, err)
+		return revocations
+	}
+
+	setup := func(t *testing.T) (*service.TokenRevocationService, *service.TokenRevocations, *service.LocalStore, *rpcfakes.FakeTypedParticipantClient) {
+		ctx := context.Background()
+		store := service.NewLocalStore()
+		participantClient := &rpcfakes.FakeTypedParticipantClient{}
+		roomService, err := service.NewRoomService(
+			config.LimitConfig{},
+			config.APIConfig{ExecutionTimeout: 2},
+			&routingfakes.FakeRouter{},
+			&servicefakes.FakeRoomAllocator{},
+			store,
+			nil,
+			&identityTopicFormatter{},
+			&rpcfakes.FakeTypedRoomClient{},
+			participantClient,
+		)
+		require.NoError(t, err)
+		for _, room := range []livekit.RoomName{"main", "side"} {
+			require.NoError(t, store.StoreRoom(ctx, &livekit.Room{Name: string(room)}, nil))
+			require.NoError(t, store.StoreParticipant(ctx, room, &livekit.ParticipantInfo{Identity: "alice"}))
+		}
+
+		revocations := newRevocations(t, store, time.Hour)
+		return service.NewTokenRevocationService(revocations, keyProvider, roomService), revocations, store, participantClient
+	}
+	serve := func(revocations *service.TokenRevocations, token string) int {
+		authn := service.NewAPIKeyAuthMiddleware(keyProvider)
+		revoked := service.NewTokenRevocationMiddleware(revocations)
+		r := &http.Request{Header: http.Header{}}
+		service.SetAuthorizationToken(r, token)
+		w := httptest.NewRecorder()
+		authn.ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+			revoked.ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+		})
+		return w.Code
+	}
+	adminCtx := func(room string, create bool) context.Context {
+		return service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomAdmin: room != "", Room: room, RoomCreate: create},
+		}, apiKey)
+	}
+
+	t.Run("revokes a token", func(t *testing.T) {
+		s, revocations, store, participantClient := setup(t)
+		token := newToken(t, "alice", "main")
+		other := newToken(t, "bob", "main")
+		require.Equal(t, http.StatusOK, serve(revocations, token))
+
+		_, err := s.RevokeToken(adminCtx("side", false), &service.RevokeTokenRequest{Token: token})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+
+		res, err := s.RevokeToken(adminCtx("main", false), &service.RevokeTokenRequest{Token: token, Reason: "leaked"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"main"}, res.Disconnected)
+		require.Equal(t, 1, participantClient.RemoveParticipantCallCount())
+		require.NotZero(t, res.Revocation.ExpiresAt)
+		require.Equal(t, http.StatusUnauthorized, serve(revocations, token))
+		require.Equal(t, http.StatusOK, serve(revocations, other))
+
+		// revocations are shared through the store with the other nodes
+		node := newRevocations(t, store, time.Hour)
+		require.Equal(t, http.StatusUnauthorized, serve(node, token))
+
+		_, err = s.RevokeToken(adminCtx("main", false), &service.RevokeTokenRequest{Token: "invalid"})
+		require.ErrorIs(t, err, service.ErrTokenRevocationInvalid)
+	})
+
+	t.Run("refreshed tokens of a revoked token are refused", func(t *testing.T) {
+		s, revocations, _, _ := setup(t)
+		token := newToken(t, "alice", "main")
+		// issued by the server to the connected participant before the revocation, like RoomManager.refreshToken does
+		refreshed := issueToken(t, "alice", &auth.ClaimGrants{Name: "Alice", Video: &auth.VideoGrant{RoomJoin: true, Room: "main"}})
+		side := newToken(t, "alice", "side")
+
+		res, err := s.RevokeToken(adminCtx("main", false), &service.RevokeTokenRequest{Token: token, Reason: "leaked"})
+		require.NoError(t, err)
+		require.NotNil(t, res.SessionRevocation)
+		require.Equal(t, "main", res.SessionRevocation.Room)
+		require.Equal(t, "alice", res.SessionRevocation.Identity)
+
+		// reconnecting after the disconnect
+		require.Equal(t, http.StatusUnauthorized, serve(revocations, refreshed))
+		require.Equal(t, http.StatusOK, serve(revocations, side))
+	})
+
+	t.Run("tokens issued after the revocation of an identity are accepted", func(t *testing.T) {
+		s, revocations, _, _ := setup(t)
+		before := newToken(t, "alice", "main")
+
+		res, err := s.RevokeParticipantSessions(adminCtx("main", false), &service.RevokeParticipantSessionsRequest{Identity: "alice", Room: "main"})
+		require.NoError(t, err)
+		// dropped once the tokens issued before it expired
+		require.Equal(t, res.Revocation.RevokedAt+int64((24*time.Hour).Seconds()), res.Revocation.ExpiresAt)
+
+		// issued in the same second as the revocation
+		after, err := auth.NewAccessToken(apiKey, secret).
+			SetIdentity("alice").
+			AddGrant(&auth.VideoGrant{RoomJoin: true, Room: "main"}).
+			ToJWT()
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, serve(revocations, before))
+		require.Equal(t, http.StatusOK, serve(revocations, after))
+	})
+
+	t.Run("expired revocations of an identity are dropped", func(t *testing.T) {
+		store := service.NewLocalStore()
+		token := newToken(t, "alice", "main")
+		now := time.Now()
+		require.NoError(t, store.StoreTokenRevocation(context.Background(), &service.TokenRevocation{
+			Identity:  "alice",
+			RevokedAt: now.Unix(),
+			ExpiresAt: now.Add(-time.Second).Unix(),
+		}))
+		require.Equal(t, http.StatusOK, serve(newRevocations(t, store, time.Hour), token))
+	})
+
+	t.Run("checks the store without blocking other requests", func(t *testing.T) {
+		store := &servicefakes.FakeTokenRevocationStore{}
+		store.ListTokenRevocationsReturns([]*service.TokenRevocation{{Identity: "alice", RevokedAt: time.Now().Unix()}}, nil)
+		revocations := newRevocations(t, store, time.Millisecond)
+		alice := newToken(t, "alice", "main")
+
+		release := make(chan struct{})
+		store.LoadTokenRevocationsVersionStub = func(context.Context) (int64, error) {
+			<-release
+			return 0, nil
+		}
+		time.Sleep(2 * time.Millisecond)
+		checked := make(chan int)
+		go func() {
+			checked <- serve(revocations, alice)
+		}()
+		require.Eventually(t, func() bool {
+			return store.LoadTokenRevocationsVersionCallCount() == 2
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// served from the cached revocations while the store is checked
+		require.Equal(t, http.StatusUnauthorized, serve(revocations, alice))
+		require.Equal(t, http.StatusOK, serve(revocations, newToken(t, "bob", "main")))
+		close(release)
+		require.Equal(t, http.StatusUnauthorized, <-checked)
+		require.Equal(t, 2, store.LoadTokenRevocationsVersionCallCount())
+	})
+
+	t.Run("revokes the sessions of an identity", func(t *testing.T) {
+		s, revocations, _, participantClient := setup(t)
+		alice := newToken(t, "alice", "side")
+		bob := newToken(t, "bob", "side")
+
+		_, err := s.RevokeParticipantSessions(adminCtx("main", false), &service.RevokeParticipantSessionsRequest{Identity: "alice"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+
+		res, err := s.RevokeParticipantSessions(adminCtx("", true), &service.RevokeParticipantSessionsRequest{Identity: "alice"})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"main", "side"}, res.Disconnected)
+		require.Equal(t, 2, participantClient.RemoveParticipantCallCount())
+		require.Equal(t, http.StatusUnauthorized, serve(revocations, alice))
+		require.Equal(t, http.StatusOK, serve(revocations, bob))
+	})
+
+	t.Run("revokes the sessions of an identity in a room", func(t *testing.T) {
+		s, revocations, _, participantClient := setup(t)
+		mainToken := newToken(t, "alice", "main")
+		sideToken := newToken(t, "alice", "side")
+
+		res, err := s.RevokeParticipantSessions(adminCtx("side", false), &service.RevokeParticipantSessionsRequest{Identity: "alice", Room: "side"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"side"}, res.Disconnected)
+		require.Equal(t, 1, participantClient.RemoveParticipantCallCount())
+		require.Equal(t, http.StatusUnauthorized, serve(revocations, sideToken))
+		require.Equal(t, http.StatusOK, serve(revocations, mainToken))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		revocations, err := service.NewTokenRevocations(&config.Config{}, nil)
+		require.NoError(t, err)
+		require.Nil(t, revocations)
+		s := service.NewTokenRevocationService(nil, keyProvider, nil)
+		_, err = s.RevokeParticipantSessions(adminCtx("", true), &service.RevokeParticipantSessionsRequest{Identity: "alice"})
+		require.ErrorIs(t, err, service.ErrTokenRevocationDisabled)
+	})
+}
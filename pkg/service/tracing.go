@@ -0,0 +1,57 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/urfave/negroni/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+// TracingMiddleware serves API requests in a span, continuing the trace of the traceparent header when
+// one is sent. Signal connections stay open for the whole session, their joins are traced by RTCService instead.
+func TracingMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	if websocket.IsWebSocketUpgrade(r) {
+		next.ServeHTTP(w, r.WithContext(ctx))
+		return
+	}
+
+	ctx, span := telemetry.StartSpan(ctx, r.Method+" "+r.URL.Path,
+		attribute.String("http.method", r.Method),
+		attribute.String("http.target", r.URL.Path),
+	)
+	defer span.End()
+	if traceID := telemetry.TraceID(ctx); traceID != "" {
+		ctx = utils.ContextWithLogger(ctx, utils.GetLogger(ctx).WithValues("traceID", traceID))
+	}
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+
+	if rw, ok := w.(negroni.ResponseWriter); ok {
+		span.SetAttributes(attribute.Int("http.status_code", rw.Status()))
+		if rw.Status() >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rw.Status()))
+		}
+	}
+}
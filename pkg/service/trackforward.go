@@ -0,0 +1,423 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/utils"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	// EventTrackForwardEnded is sent when a track forward stops, with the forward described as a track egress
+	EventTrackForwardEnded = "track_forward_ended"
+
+	// TrackForwardTransportSRT forwards are MPEG-TS over SRT, muxed by a track composite egress
+	TrackForwardTransportSRT = "srt"
+
+	trackForwardRPCService = "TrackForward"
+	trackForwardRPC        = "TrackForward"
+
+	trackForwardStart = "start"
+	trackForwardStop  = "stop"
+	trackForwardList  = "list"
+
+	maxTrackForwardRequest = 16 * 1024
+)
+
+// trackForwardCommand is carried as JSON in the payload of a user data packet, the response carries
+// the resulting forwards as a JSON list
+type trackForwardCommand struct {
+	Action    string                   `json:"action"`
+	Start     *rtc.TrackForwardRequest `json:"start,omitempty"`
+	ForwardID string                   `json:"forward_id,omitempty"`
+}
+
+// TrackForwardClient reaches the node hosting a room to start, stop and list the forwards of its tracks
+type TrackForwardClient interface {
+	TrackForward(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error)
+}
+
+type TrackForwardServerImpl interface {
+	ForwardTrack(ctx context.Context, roomName livekit.RoomName, req *rtc.TrackForwardRequest) (*rtc.TrackForwardInfo, error)
+	StopTrackForward(ctx context.Context, roomName livekit.RoomName, forwardID string) (*rtc.TrackForwardInfo, error)
+	ListTrackForwards(ctx context.Context, roomName livekit.RoomName) ([]*rtc.TrackForwardInfo, error)
+}
+
+type trackForwardClient struct {
+	client *client.RPCClient
+}
+
+func NewTrackForwardClient(params rpc.ClientParams) (TrackForwardClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: trackForwardRPCService,
+		ID:   rand.NewClientID(),
+	}
+	sd.RegisterMethod(trackForwardRPC, false, false, true, true)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &trackForwardClient{client: rpcClient}, nil
+}
+
+func (c *trackForwardClient) TrackForward(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return client.RequestSingle[*livekit.DataPacket](ctx, c.client, trackForwardRPC, []string{string(room)}, req, opts...)
+}
+
+// trackForwardServer handles track forward commands for a room hosted on this node
+type trackForwardServer struct {
+	svc      TrackForwardServerImpl
+	roomName livekit.RoomName
+	rpc      *server.RPCServer
+}
+
+func newTrackForwardServer(svc TrackForwardServerImpl, roomName livekit.RoomName, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *trackForwardServer {
+	sd := &info.ServiceDefinition{
+		Name: trackForwardRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	sd.RegisterMethod(trackForwardRPC, false, false, true, true)
+	return &trackForwardServer{
+		svc:      svc,
+		roomName: roomName,
+		rpc:      s,
+	}
+}
+
+func (s *trackForwardServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	return server.RegisterHandler(s.rpc, trackForwardRPC, []string{string(room)}, s.handle, nil)
+}
+
+func (s *trackForwardServer) handle(ctx context.Context, req *livekit.DataPacket) (*livekit.DataPacket, error) {
+	var cmd trackForwardCommand
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &cmd); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+
+	var forwards []*rtc.TrackForwardInfo
+	switch cmd.Action {
+	case trackForwardStart:
+		if cmd.Start == nil {
+			return nil, ErrTrackForwardInvalid
+		}
+		fi, err := s.svc.ForwardTrack(ctx, s.roomName, cmd.Start)
+		if err != nil {
+			return nil, err
+		}
+		forwards = append(forwards, fi)
+	case trackForwardStop:
+		fi, err := s.svc.StopTrackForward(ctx, s.roomName, cmd.ForwardID)
+		if err != nil {
+			return nil, err
+		}
+		forwards = append(forwards, fi)
+	case trackForwardList:
+		var err error
+		if forwards, err = s.svc.ListTrackForwards(ctx, s.roomName); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrTrackForwardInvalid
+	}
+	return encodeTrackForward(forwards)
+}
+
+func (s *trackForwardServer) Kill() {
+	s.rpc.Close(true)
+}
+
+func encodeTrackForward(v any) (*livekit.DataPacket, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{Payload: payload},
+		},
+	}, nil
+}
+
+// TrackForwardEgressInfo describes an ended track forward in egress terms for webhooks, the destination is
+// the URL of its stream result and the counters are in the details
+func TrackForwardEgressInfo(fi *rtc.TrackForwardInfo) *livekit.EgressInfo {
+	stream := &livekit.StreamInfo{
+		Url:       fmt.Sprintf("%s://%s", fi.Transport, fi.Address),
+		StartedAt: fi.StartedAt,
+		EndedAt:   fi.EndedAt,
+		Duration:  fi.EndedAt - fi.StartedAt,
+		Status:    livekit.StreamInfo_FINISHED,
+		Error:     fi.Error,
+	}
+	ei := &livekit.EgressInfo{
+		EgressId:  fi.ForwardID,
+		RoomName:  fi.Room,
+		Status:    livekit.EgressStatus_EGRESS_COMPLETE,
+		StartedAt: fi.StartedAt,
+		EndedAt:   fi.EndedAt,
+		UpdatedAt: time.Now().UnixNano(),
+		Details:   fmt.Sprintf("packets_sent=%d bytes_sent=%d write_errors=%d", fi.PacketsSent, fi.BytesSent, fi.WriteErrors),
+		Error:     fi.Error,
+		Request: &livekit.EgressInfo_Track{
+			Track: &livekit.TrackEgressRequest{
+				RoomName: fi.Room,
+				TrackId:  fi.TrackSid,
+			},
+		},
+		StreamResults: []*livekit.StreamInfo{stream},
+	}
+	if fi.Error != "" {
+		ei.Status = livekit.EgressStatus_EGRESS_FAILED
+		stream.Status = livekit.StreamInfo_FAILED
+	}
+	return ei
+}
+
+// ---------------------------------------------
+
+// ForwardTrackRequest is the JSON body of POST /track_forwards/start. Address is host:port for rtp and srtp,
+// and an srt:// URL for srt.
+type ForwardTrackRequest struct {
+	Room string `json:"room"`
+	rtc.TrackForwardRequest
+}
+
+// TrackForwardRequest is the JSON body of POST /track_forwards/{stop,list}, forward_id is only used by stop
+type TrackForwardRequest struct {
+	Room      string `json:"room"`
+	ForwardID string `json:"forward_id,omitempty"`
+}
+
+// TrackForwardService serves the HTTP API forwarding published tracks to external addresses, for broadcast
+// and processing pipelines that are not LiveKit aware. rtp and srtp forwards are sent by the node hosting the
+// room and end with the track, srt forwards are track composite egresses. Calls require roomAdmin, and srt
+// forwards roomRecord too.
+type TrackForwardService struct {
+	roomStore      ServiceStore
+	topicFormatter rpc.TopicFormatter
+	client         TrackForwardClient
+	egressService  *EgressService
+}
+
+func NewTrackForwardService(
+	roomStore ServiceStore,
+	topicFormatter rpc.TopicFormatter,
+	client TrackForwardClient,
+	egressService *EgressService,
+) *TrackForwardService {
+	return &TrackForwardService{
+		roomStore:      roomStore,
+		topicFormatter: topicFormatter,
+		client:         client,
+		egressService:  egressService,
+	}
+}
+
+func (s *TrackForwardService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/track_forwards/") {
+	case trackForwardStart:
+		var req ForwardTrackRequest
+		if err = decodeJSONRequest(r, &req, maxTrackForwardRequest); err == nil {
+			res, err = s.ForwardTrack(r.Context(), &req)
+		}
+	case trackForwardStop:
+		var req TrackForwardRequest
+		if err = decodeJSONRequest(r, &req, maxTrackForwardRequest); err == nil {
+			res, err = s.StopTrackForward(r.Context(), &req)
+		}
+	case trackForwardList:
+		var req TrackForwardRequest
+		if err = decodeJSONRequest(r, &req, maxTrackForwardRequest); err == nil {
+			res, err = s.ListTrackForwards(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *TrackForwardService) ForwardTrack(ctx context.Context, req *ForwardTrackRequest) (*rtc.TrackForwardInfo, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName, "trackID", req.TrackSid, "transport", req.Transport, "address", req.Address)
+	if err := s.ensureRoom(ctx, roomName); err != nil {
+		return nil, err
+	}
+	if req.TrackSid == "" || req.Address == "" {
+		return nil, ErrTrackForwardInvalid
+	}
+
+	if req.Transport == TrackForwardTransportSRT {
+		return s.startSRTForward(ctx, roomName, &req.TrackForwardRequest)
+	}
+	forwards, err := s.send(ctx, roomName, &trackForwardCommand{Action: trackForwardStart, Start: &req.TrackForwardRequest})
+	if err != nil {
+		return nil, err
+	}
+	return forwards[0], nil
+}
+
+func (s *TrackForwardService) StopTrackForward(ctx context.Context, req *TrackForwardRequest) (*rtc.TrackForwardInfo, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName, "forwardID", req.ForwardID)
+	if err := s.ensureRoom(ctx, roomName); err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(req.ForwardID, utils.EgressPrefix) {
+		ei, err := s.egressService.StopEgress(ctx, &livekit.StopEgressRequest{EgressId: req.ForwardID})
+		if err != nil {
+			return nil, err
+		}
+		return &rtc.TrackForwardInfo{
+			ForwardID: ei.EgressId,
+			Room:      ei.RoomName,
+			Transport: TrackForwardTransportSRT,
+			StartedAt: ei.StartedAt,
+			EndedAt:   ei.EndedAt,
+			Error:     ei.Error,
+		}, nil
+	}
+	forwards, err := s.send(ctx, roomName, &trackForwardCommand{Action: trackForwardStop, ForwardID: req.ForwardID})
+	if err != nil {
+		return nil, err
+	}
+	return forwards[0], nil
+}
+
+// ListTrackForwards returns the rtp and srtp forwards of a room with their counters,
+// srt forwards are listed with egresses
+func (s *TrackForwardService) ListTrackForwards(ctx context.Context, req *TrackForwardRequest) ([]*rtc.TrackForwardInfo, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName)
+	if err := s.ensureRoom(ctx, roomName); err != nil {
+		return nil, err
+	}
+	return s.send(ctx, roomName, &trackForwardCommand{Action: trackForwardList})
+}
+
+func (s *TrackForwardService) startSRTForward(ctx context.Context, roomName livekit.RoomName, req *rtc.TrackForwardRequest) (*rtc.TrackForwardInfo, error) {
+	if !strings.HasPrefix(req.Address, "srt://") {
+		return nil, fmt.Errorf("%w: srt forwards require an srt:// address", ErrTrackForwardInvalid)
+	}
+	track, err := s.findTrack(ctx, roomName, req.TrackSid)
+	if err != nil {
+		return nil, err
+	}
+
+	egressReq := &livekit.TrackCompositeEgressRequest{
+		RoomName: string(roomName),
+		StreamOutputs: []*livekit.StreamOutput{{
+			Protocol: livekit.StreamProtocol_SRT,
+			Urls:     []string{req.Address},
+		}},
+	}
+	if track.Type == livekit.TrackType_AUDIO {
+		egressReq.AudioTrackId = req.TrackSid
+	} else {
+		egressReq.VideoTrackId = req.TrackSid
+	}
+	ei, err := s.egressService.StartTrackCompositeEgress(ctx, egressReq)
+	if err != nil {
+		return nil, err
+	}
+	return &rtc.TrackForwardInfo{
+		ForwardID: ei.EgressId,
+		Room:      string(roomName),
+		TrackSid:  req.TrackSid,
+		Transport: TrackForwardTransportSRT,
+		Address:   req.Address,
+		MimeType:  track.MimeType,
+		StartedAt: ei.StartedAt,
+	}, nil
+}
+
+func (s *TrackForwardService) findTrack(ctx context.Context, roomName livekit.RoomName, trackSid string) (*livekit.TrackInfo, error) {
+	participants, err := s.roomStore.ListParticipants(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+	for _, pi := range participants {
+		for _, track := range pi.Tracks {
+			if track.Sid == trackSid {
+				return track, nil
+			}
+		}
+	}
+	return nil, ErrTrackNotFound
+}
+
+func (s *TrackForwardService) send(ctx context.Context, roomName livekit.RoomName, cmd *trackForwardCommand) ([]*rtc.TrackForwardInfo, error) {
+	req, err := encodeTrackForward(cmd)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.TrackForward(ctx, s.topicFormatter.RoomTopic(ctx, roomName), req)
+	if err != nil {
+		return nil, err
+	}
+	var forwards []*rtc.TrackForwardInfo
+	if err = json.Unmarshal(res.GetUser().GetPayload(), &forwards); err != nil {
+		return nil, err
+	}
+	if cmd.Action != trackForwardList && len(forwards) == 0 {
+		return nil, ErrOperationFailed
+	}
+	return forwards, nil
+}
+
+func (s *TrackForwardService) ensureRoom(ctx context.Context, roomName livekit.RoomName) error {
+	if err := EnsureAdminPermission(ctx, roomName); err != nil {
+		return err
+	}
+	// tracks are forwarded by the node hosting the room
+	_, _, err := s.roomStore.LoadRoom(ctx, roomName, false)
+	return err
+}
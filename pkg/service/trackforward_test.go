@@ -0,0 +1,121 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+// testTrackForwardClient answers every command with the forwards it was given
+type testTrackForwardClient struct {
+	commands []map[string]any
+	forwards []*rtc.TrackForwardInfo
+}
+
+func (c *testTrackForwardClient) TrackForward(_ context.Context, _ rpc.RoomTopic, req *livekit.DataPacket, _ ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	var cmd map[string]any
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &cmd); err != nil {
+		return nil, err
+	}
+	c.commands = append(c.commands, cmd)
+	payload, err := json.Marshal(c.forwards)
+	if err != nil {
+		return nil, err
+	}
+	return &livekit.DataPacket{Value: &livekit.DataPacket_User{User: &livekit.UserPacket{Payload: payload}}}, nil
+}
+
+func TestTrackForward(t *testing.T) {
+	store := service.NewLocalStore()
+	client := &testTrackForwardClient{}
+	svc := service.NewTrackForwardService(store, rpc.NewTopicFormatter(), client, nil)
+
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomAdmin: true, Room: "studio"},
+	}, "")
+	require.NoError(t, store.StoreRoom(ctx, &livekit.Room{Name: "studio"}, nil))
+
+	t.Run("requires admin of the room", func(t *testing.T) {
+		other := service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomAdmin: true, Room: "other"},
+		}, "")
+		_, err := svc.ListTrackForwards(other, &service.TrackForwardRequest{Room: "studio"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+	})
+
+	t.Run("validates requests", func(t *testing.T) {
+		_, err := svc.ForwardTrack(ctx, &service.ForwardTrackRequest{
+			Room:                "studio",
+			TrackForwardRequest: rtc.TrackForwardRequest{TrackSid: "TR_video", Transport: rtc.TrackForwardTransportRTP},
+		})
+		require.ErrorIs(t, err, service.ErrTrackForwardInvalid)
+
+		_, err = svc.ForwardTrack(ctx, &service.ForwardTrackRequest{
+			Room: "studio",
+			TrackForwardRequest: rtc.TrackForwardRequest{
+				TrackSid:  "TR_video",
+				Transport: service.TrackForwardTransportSRT,
+				Address:   "10.0.0.1:9000",
+			},
+		})
+		require.ErrorIs(t, err, service.ErrTrackForwardInvalid)
+		require.Empty(t, client.commands)
+	})
+
+	t.Run("starts on the node hosting the room", func(t *testing.T) {
+		client.forwards = []*rtc.TrackForwardInfo{{ForwardID: "TF_1", TrackSid: "TR_video", SSRC: 1234}}
+		fi, err := svc.ForwardTrack(ctx, &service.ForwardTrackRequest{
+			Room: "studio",
+			TrackForwardRequest: rtc.TrackForwardRequest{
+				TrackSid:  "TR_video",
+				Transport: rtc.TrackForwardTransportSRTP,
+				Address:   "10.0.0.1:9000",
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "TF_1", fi.ForwardID)
+		require.Equal(t, "start", client.commands[0]["action"])
+
+		forwards, err := svc.ListTrackForwards(ctx, &service.TrackForwardRequest{Room: "studio"})
+		require.NoError(t, err)
+		require.Len(t, forwards, 1)
+	})
+
+	t.Run("ended forwards are described as egresses", func(t *testing.T) {
+		ei := service.TrackForwardEgressInfo(&rtc.TrackForwardInfo{
+			ForwardID: "TF_1",
+			Room:      "studio",
+			TrackSid:  "TR_video",
+			Transport: rtc.TrackForwardTransportRTP,
+			Address:   "10.0.0.1:9000",
+			Error:     "room closed",
+		})
+		require.Equal(t, livekit.EgressStatus_EGRESS_FAILED, ei.Status)
+		require.Equal(t, "rtp://10.0.0.1:9000", ei.StreamResults[0].Url)
+		require.Equal(t, "TR_video", ei.GetTrack().TrackId)
+	})
+}
@@ -0,0 +1,335 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	// EventTrackRecordingEnded is sent when a track recording ended and its segments are uploaded, with the
+	// recording described as a track egress
+	EventTrackRecordingEnded = "track_recording_ended"
+
+	trackRecordingRPCService = "TrackRecording"
+	trackRecordingRPC        = "TrackRecording"
+
+	trackRecordingStart = "start"
+	trackRecordingStop  = "stop"
+	trackRecordingList  = "list"
+
+	maxTrackRecordingRequest = 16 * 1024
+)
+
+// trackRecordingCommand is carried as JSON in the payload of a user data packet, the response carries
+// the resulting recordings as a JSON list
+type trackRecordingCommand struct {
+	Action      string                     `json:"action"`
+	Start       *rtc.TrackRecordingRequest `json:"start,omitempty"`
+	RecordingID string                     `json:"recording_id,omitempty"`
+}
+
+// TrackRecordingClient reaches the node hosting a room to start, stop and list the recordings of its tracks
+type TrackRecordingClient interface {
+	TrackRecording(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error)
+}
+
+type TrackRecordingServerImpl interface {
+	RecordTrack(ctx context.Context, roomName livekit.RoomName, req *rtc.TrackRecordingRequest) (*rtc.TrackRecordingInfo, error)
+	StopTrackRecording(ctx context.Context, roomName livekit.RoomName, recordingID string) (*rtc.TrackRecordingInfo, error)
+	ListTrackRecordings(ctx context.Context, roomName livekit.RoomName) ([]*rtc.TrackRecordingInfo, error)
+}
+
+type trackRecordingClient struct {
+	client *client.RPCClient
+}
+
+func NewTrackRecordingClient(params rpc.ClientParams) (TrackRecordingClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: trackRecordingRPCService,
+		ID:   rand.NewClientID(),
+	}
+	sd.RegisterMethod(trackRecordingRPC, false, false, true, true)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &trackRecordingClient{client: rpcClient}, nil
+}
+
+func (c *trackRecordingClient) TrackRecording(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return client.RequestSingle[*livekit.DataPacket](ctx, c.client, trackRecordingRPC, []string{string(room)}, req, opts...)
+}
+
+// trackRecordingServer handles track recording commands for a room hosted on this node
+type trackRecordingServer struct {
+	svc      TrackRecordingServerImpl
+	roomName livekit.RoomName
+	rpc      *server.RPCServer
+}
+
+func newTrackRecordingServer(svc TrackRecordingServerImpl, roomName livekit.RoomName, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *trackRecordingServer {
+	sd := &info.ServiceDefinition{
+		Name: trackRecordingRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	sd.RegisterMethod(trackRecordingRPC, false, false, true, true)
+	return &trackRecordingServer{
+		svc:      svc,
+		roomName: roomName,
+		rpc:      s,
+	}
+}
+
+func (s *trackRecordingServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	return server.RegisterHandler(s.rpc, trackRecordingRPC, []string{string(room)}, s.handle, nil)
+}
+
+func (s *trackRecordingServer) handle(ctx context.Context, req *livekit.DataPacket) (*livekit.DataPacket, error) {
+	var cmd trackRecordingCommand
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &cmd); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+
+	var recordings []*rtc.TrackRecordingInfo
+	switch cmd.Action {
+	case trackRecordingStart:
+		if cmd.Start == nil {
+			return nil, ErrTrackRecordingInvalid
+		}
+		ri, err := s.svc.RecordTrack(ctx, s.roomName, cmd.Start)
+		if err != nil {
+			return nil, err
+		}
+		recordings = append(recordings, ri)
+	case trackRecordingStop:
+		ri, err := s.svc.StopTrackRecording(ctx, s.roomName, cmd.RecordingID)
+		if err != nil {
+			return nil, err
+		}
+		recordings = append(recordings, ri)
+	case trackRecordingList:
+		var err error
+		if recordings, err = s.svc.ListTrackRecordings(ctx, s.roomName); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrTrackRecordingInvalid
+	}
+	return jsonDataPacket(recordings)
+}
+
+func (s *trackRecordingServer) Kill() {
+	s.rpc.Close(true)
+}
+
+// TrackRecordingEgressInfo describes an ended track recording in egress terms for webhooks, its segments
+// are the segment results and the other counters are in the details
+func TrackRecordingEgressInfo(ri *rtc.TrackRecordingInfo) *livekit.EgressInfo {
+	ei := &livekit.EgressInfo{
+		EgressId:  ri.RecordingID,
+		RoomName:  ri.Room,
+		Status:    livekit.EgressStatus_EGRESS_COMPLETE,
+		StartedAt: ri.StartedAt,
+		EndedAt:   ri.EndedAt,
+		UpdatedAt: time.Now().UnixNano(),
+		Details: fmt.Sprintf("frames_dropped=%d segments_dropped=%d upload_errors=%d",
+			ri.FramesDropped, ri.SegmentsDropped, ri.UploadErrors),
+		Error: ri.Error,
+		Request: &livekit.EgressInfo_Track{
+			Track: &livekit.TrackEgressRequest{
+				RoomName: ri.Room,
+				TrackId:  ri.TrackSid,
+			},
+		},
+		SegmentResults: []*livekit.SegmentsInfo{{
+			PlaylistLocation: ri.Location,
+			Duration:         ri.Duration,
+			Size:             int64(ri.BytesUploaded),
+			SegmentCount:     int64(ri.Segments),
+			StartedAt:        ri.StartedAt,
+			EndedAt:          ri.EndedAt,
+		}},
+	}
+	if ri.Error != "" || (ri.Segments == 0 && ri.UploadErrors != 0) {
+		ei.Status = livekit.EgressStatus_EGRESS_FAILED
+	}
+	return ei
+}
+
+// ---------------------------------------------
+
+// RecordTrackRequest is the JSON body of POST /track_recordings/start
+type RecordTrackRequest struct {
+	Room string `json:"room"`
+	rtc.TrackRecordingRequest
+}
+
+// TrackRecordingRequest is the JSON body of POST /track_recordings/{stop,list}, recording_id is only used by stop
+type TrackRecordingRequest struct {
+	Room        string `json:"room"`
+	RecordingID string `json:"recording_id,omitempty"`
+}
+
+// TrackRecordingService serves the HTTP API recording single published tracks to object storage, for
+// audio-only and single track archival without an egress. Tracks are recorded by the node hosting the room
+// and recordings end with the track. Calls require roomRecord.
+type TrackRecordingService struct {
+	roomStore      ServiceStore
+	topicFormatter rpc.TopicFormatter
+	client         TrackRecordingClient
+}
+
+func NewTrackRecordingService(
+	roomStore ServiceStore,
+	topicFormatter rpc.TopicFormatter,
+	client TrackRecordingClient,
+) *TrackRecordingService {
+	return &TrackRecordingService{
+		roomStore:      roomStore,
+		topicFormatter: topicFormatter,
+		client:         client,
+	}
+}
+
+func (s *TrackRecordingService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res any
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/track_recordings/") {
+	case trackRecordingStart:
+		var req RecordTrackRequest
+		if err = decodeJSONRequest(r, &req, maxTrackRecordingRequest); err == nil {
+			res, err = s.RecordTrack(r.Context(), &req)
+		}
+	case trackRecordingStop:
+		var req TrackRecordingRequest
+		if err = decodeJSONRequest(r, &req, maxTrackRecordingRequest); err == nil {
+			res, err = s.StopTrackRecording(r.Context(), &req)
+		}
+	case trackRecordingList:
+		var req TrackRecordingRequest
+		if err = decodeJSONRequest(r, &req, maxTrackRecordingRequest); err == nil {
+			res, err = s.ListTrackRecordings(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *TrackRecordingService) RecordTrack(ctx context.Context, req *RecordTrackRequest) (*rtc.TrackRecordingInfo, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName, "trackID", req.TrackSid)
+	if err := s.ensureRoom(ctx, roomName); err != nil {
+		return nil, err
+	}
+	if req.TrackSid == "" {
+		return nil, ErrTrackRecordingInvalid
+	}
+
+	recordings, err := s.send(ctx, roomName, &trackRecordingCommand{Action: trackRecordingStart, Start: &req.TrackRecordingRequest})
+	if err != nil {
+		return nil, err
+	}
+	return recordings[0], nil
+}
+
+// StopTrackRecording stops a recording, the returned counters do not include its last segment, which is
+// uploaded after it stopped
+func (s *TrackRecordingService) StopTrackRecording(ctx context.Context, req *TrackRecordingRequest) (*rtc.TrackRecordingInfo, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName, "recordingID", req.RecordingID)
+	if err := s.ensureRoom(ctx, roomName); err != nil {
+		return nil, err
+	}
+
+	recordings, err := s.send(ctx, roomName, &trackRecordingCommand{Action: trackRecordingStop, RecordingID: req.RecordingID})
+	if err != nil {
+		return nil, err
+	}
+	return recordings[0], nil
+}
+
+// ListTrackRecordings returns the recordings of a room with their counters, including stopped recordings
+// still uploading their last segment
+func (s *TrackRecordingService) ListTrackRecordings(ctx context.Context, req *TrackRecordingRequest) ([]*rtc.TrackRecordingInfo, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName)
+	if err := s.ensureRoom(ctx, roomName); err != nil {
+		return nil, err
+	}
+	return s.send(ctx, roomName, &trackRecordingCommand{Action: trackRecordingList})
+}
+
+func (s *TrackRecordingService) send(ctx context.Context, roomName livekit.RoomName, cmd *trackRecordingCommand) ([]*rtc.TrackRecordingInfo, error) {
+	req, err := jsonDataPacket(cmd)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.TrackRecording(ctx, s.topicFormatter.RoomTopic(ctx, roomName), req)
+	if err != nil {
+		return nil, err
+	}
+	var recordings []*rtc.TrackRecordingInfo
+	if err = json.Unmarshal(res.GetUser().GetPayload(), &recordings); err != nil {
+		return nil, err
+	}
+	if cmd.Action != trackRecordingList && len(recordings) == 0 {
+		return nil, ErrOperationFailed
+	}
+	return recordings, nil
+}
+
+func (s *TrackRecordingService) ensureRoom(ctx context.Context, roomName livekit.RoomName) error {
+	if err := EnsureRecordPermission(ctx); err != nil {
+		return err
+	}
+	// tracks are recorded by the node hosting the room
+	_, _, err := s.roomStore.LoadRoom(ctx, roomName, false)
+	return err
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+// testTrackRecordingClient answers every command with the recordings it was given
+type testTrackRecordingClient struct {
+	commands   []map[string]any
+	recordings []*rtc.TrackRecordingInfo
+}
+
+func (c *testTrackRecordingClient) TrackRecording(_ context.Context, _ rpc.RoomTopic, req *livekit.DataPacket, _ ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	var cmd map[string]any
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &cmd); err != nil {
+		return nil, err
+	}
+	c.commands = append(c.commands, cmd)
+	payload, err := json.Marshal(c.recordings)
+	if err != nil {
+		return nil, err
+	}
+	return &livekit.DataPacket{Value: &livekit.DataPacket_User{User: &livekit.UserPacket{Payload: payload}}}, nil
+}
+
+func TestTrackRecording(t *testing.T) {
+	store := service.NewLocalStore()
+	client := &testTrackRecordingClient{}
+	svc := service.NewTrackRecordingService(store, rpc.NewTopicFormatter(), client)
+
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomRecord: true},
+	}, "")
+	require.NoError(t, store.StoreRoom(ctx, &livekit.Room{Name: "studio"}, nil))
+
+	t.Run("requires record permission", func(t *testing.T) {
+		admin := service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomAdmin: true, Room: "studio"},
+		}, "")
+		_, err := svc.ListTrackRecordings(admin, &service.TrackRecordingRequest{Room: "studio"})
+		require.ErrorIs(t, err, service.ErrPermissionDenied)
+	})
+
+	t.Run("validates requests", func(t *testing.T) {
+		_, err := svc.RecordTrack(ctx, &service.RecordTrackRequest{Room: "studio"})
+		require.ErrorIs(t, err, service.ErrTrackRecordingInvalid)
+		require.Empty(t, client.commands)
+	})
+
+	t.Run("starts on the node hosting the room", func(t *testing.T) {
+		client.recordings = []*rtc.TrackRecordingInfo{{RecordingID: "TREC_1", TrackSid: "TR_audio"}}
+		ri, err := svc.RecordTrack(ctx, &service.RecordTrackRequest{
+			Room:                  "studio",
+			TrackRecordingRequest: rtc.TrackRecordingRequest{TrackSid: "TR_audio"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "TREC_1", ri.RecordingID)
+		require.Equal(t, "start", client.commands[0]["action"])
+
+		_, err = svc.StopTrackRecording(ctx, &service.TrackRecordingRequest{Room: "studio", RecordingID: "TREC_1"})
+		require.NoError(t, err)
+		require.Equal(t, "TREC_1", client.commands[1]["recording_id"])
+	})
+
+	t.Run("ended recordings are described as egresses", func(t *testing.T) {
+		ei := service.TrackRecordingEgressInfo(&rtc.TrackRecordingInfo{
+			RecordingID:   "TREC_1",
+			Room:          "studio",
+			TrackSid:      "TR_audio",
+			Location:      "recordings/studio/TR_audio/TREC_1/",
+			Segments:      3,
+			BytesUploaded: 4096,
+		})
+		require.Equal(t, livekit.EgressStatus_EGRESS_COMPLETE, ei.Status)
+		require.Equal(t, "TR_audio", ei.GetTrack().TrackId)
+		require.EqualValues(t, 3, ei.SegmentResults[0].SegmentCount)
+		require.Equal(t, "recordings/studio/TR_audio/TREC_1/", ei.SegmentResults[0].PlaylistLocation)
+
+		ei = service.TrackRecordingEgressInfo(&rtc.TrackRecordingInfo{RecordingID: "TREC_2", UploadErrors: 2})
+		require.Equal(t, livekit.EgressStatus_EGRESS_FAILED, ei.Status)
+	})
+}
@@ -44,13 +44,13 @@ const (
 	turnMaxPort     = 30000
 )
 
-func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, standalone bool) (*turn.Server, error) {
+func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, sessions *TURNSessions, standalone bool) (*turn.Server, error) {
 	turnConf := conf.TURN
 	if !turnConf.Enabled {
 		return nil, nil
 	}
 
-	if turnConf.TLSPort <= 0 && turnConf.UDPPort <= 0 {
+	if turnConf.TLSPort <= 0 && (turnConf.UDPPort <= 0 || turnConf.TLSOnly) {
 		return nil, errors.New("invalid TURN ports")
 	}
 
@@ -100,6 +100,9 @@ func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, standalone
 			if standalone {
 				tlsListener = telemetry.NewListener(tlsListener)
 			}
+			if sessions != nil {
+				tlsListener = &turnListener{Listener: tlsListener, sessions: sessions, transport: TURNTransportTLS}
+			}
 
 			listenerConfig := turn.ListenerConfig{
 				Listener:              tlsListener,
@@ -114,6 +117,10 @@ func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, standalone
 			if standalone {
 				tcpListener = telemetry.NewListener(tcpListener)
 			}
+			if sessions != nil {
+				// TLS is terminated by the load balancer
+				tcpListener = &turnListener{Listener: tcpListener, sessions: sessions, transport: TURNTransportTLS}
+			}
 
 			listenerConfig := turn.ListenerConfig{
 				Listener:              tcpListener,
@@ -124,7 +131,7 @@ func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, standalone
 		logValues = append(logValues, "turn.portTLS", turnConf.TLSPort, "turn.externalTLS", turnConf.ExternalTLS)
 	}
 
-	if turnConf.UDPPort > 0 {
+	if turnConf.UDPPort > 0 && !turnConf.TLSOnly {
 		udpListener, err := net.ListenPacket("udp4", "0.0.0.0:"+strconv.Itoa(turnConf.UDPPort))
 		if err != nil {
 			return nil, errors.Wrap(err, "could not listen on TURN UDP port")
@@ -133,6 +140,9 @@ func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, standalone
 		if standalone {
 			udpListener = telemetry.NewPacketConn(udpListener, prometheus.Incoming)
 		}
+		if sessions != nil {
+			udpListener = &turnPacketConn{PacketConn: udpListener, sessions: sessions}
+		}
 
 		packetConfig := turn.PacketConnConfig{
 			PacketConn:            udpListener,
@@ -141,6 +151,9 @@ func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, standalone
 		serverConfig.PacketConnConfigs = append(serverConfig.PacketConnConfigs, packetConfig)
 		logValues = append(logValues, "turn.portUDP", turnConf.UDPPort)
 	}
+	if turnConf.Quota.BytesPerSecond > 0 {
+		logValues = append(logValues, "turn.quota", turnConf.Quota.BytesPerSecond)
+	}
 
 	logger.Infow("Starting TURN server", logValues...)
 	return turn.NewServer(serverConfig)
@@ -152,24 +165,33 @@ func getTURNAuthHandlerFunc(handler *TURNAuthHandler) turn.AuthHandler {
 
 type TURNAuthHandler struct {
 	keyProvider auth.KeyProvider
+	ipFilter    *IPFilter
+	sessions    *TURNSessions
 }
 
-func NewTURNAuthHandler(keyProvider auth.KeyProvider) *TURNAuthHandler {
+func NewTURNAuthHandler(keyProvider auth.KeyProvider, ipFilter *IPFilter, sessions *TURNSessions) *TURNAuthHandler {
 	return &TURNAuthHandler{
 		keyProvider: keyProvider,
+		ipFilter:    ipFilter,
+		sessions:    sessions,
 	}
 }
 
-func (h *TURNAuthHandler) CreateUsername(apiKey string, pID livekit.ParticipantID) string {
-	return base62.EncodeToString([]byte(fmt.Sprintf("%s|%s", apiKey, pID)))
+// CreateUsername returns the TURN username of a participant. The room is part of the credentials so that
+// allocations are checked against its IP filter rules.
+func (h *TURNAuthHandler) CreateUsername(apiKey string, pID livekit.ParticipantID, roomName livekit.RoomName) string {
+	return base62.EncodeToString([]byte(fmt.Sprintf("%s|%s|%s", apiKey, pID, roomName)))
 }
 
-func (h *TURNAuthHandler) CreatePassword(apiKey string, pID livekit.ParticipantID) (string, error) {
+func (h *TURNAuthHandler) CreatePassword(apiKey string, pID livekit.ParticipantID, roomName livekit.RoomName) (string, error) {
 	secret := h.keyProvider.GetSecret(apiKey)
 	if secret == "" {
 		return "", ErrInvalidAPIKey
 	}
 	keyInput := fmt.Sprintf("%s|%s", secret, pID)
+	if roomName != "" {
+		keyInput += "|" + string(roomName)
+	}
 	sum := sha256.Sum256([]byte(keyInput))
 	return base62.EncodeToString(sum[:]), nil
 }
@@ -179,14 +201,25 @@ func (h *TURNAuthHandler) HandleAuth(username, realm string, srcAddr net.Addr) (
 	if err != nil {
 		return nil, false
 	}
-	parts := strings.Split(string(decoded), "|")
-	if len(parts) != 2 {
+	// usernames issued before rooms were part of the credentials have no room
+	parts := strings.SplitN(string(decoded), "|", 3)
+	if len(parts) < 2 {
 		return nil, false
 	}
-	password, err := h.CreatePassword(parts[0], livekit.ParticipantID(parts[1]))
+	var roomName livekit.RoomName
+	if len(parts) == 3 {
+		roomName = livekit.RoomName(parts[2])
+	}
+	password, err := h.CreatePassword(parts[0], livekit.ParticipantID(parts[1]), roomName)
 	if err != nil {
 		logger.Warnw("could not create TURN password", err, "username", username)
 		return nil, false
 	}
+	if h.ipFilter != nil {
+		if err := h.ipFilter.CheckTURN(srcAddr, roomName); err != nil {
+			return nil, false
+		}
+	}
+	h.sessions.Authenticated(srcAddr, livekit.ParticipantID(parts[1]), roomName)
 	return turn.GenerateAuthKey(username, LivekitRealm, password), true
 }
@@ -0,0 +1,343 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// transports of TURN sessions, used as metric labels
+const (
+	TURNTransportUDP = "udp"
+	TURNTransportTCP = "tcp"
+	TURNTransportTLS = "tls"
+)
+
+const (
+	defaultTURNUDPSessionTimeout = 10 * time.Minute
+	turnQuotaWindow              = time.Second
+	turnSessionSweepInterval     = time.Minute
+)
+
+type turnSession struct {
+	transport     string
+	remoteAddr    string
+	participantID livekit.ParticipantID
+	roomName      livekit.RoomName
+	usage         *turnUsage
+	startedAt     time.Time
+	lastSeen      time.Time
+	bytesIn       uint64
+	bytesOut      uint64
+}
+
+// turnUsage counts the bytes a participant relayed in the current quota window, over all of its sessions
+type turnUsage struct {
+	sessions int
+	window   int64
+	bytes    int64
+}
+
+// TURNSessions follows the sessions of clients on the embedded TURN server by their address. A session belongs
+// to the participant of the first request it authenticated, it ends with its connection, or once UDP clients
+// stopped sending for udp_session_timeout. Sessions are counted in metrics, reported to telemetry and held to
+// the bandwidth quota of their participant.
+type TURNSessions struct {
+	quota      int64
+	udpTimeout time.Duration
+	telemetry  telemetry.TelemetryService
+
+	lock      sync.Mutex
+	sessions  map[netip.AddrPort]*turnSession
+	usage     map[livekit.ParticipantID]*turnUsage
+	lastSweep time.Time
+}
+
+// NewTURNSessions returns nil when the embedded TURN server is not enabled
+func NewTURNSessions(conf *config.Config, telemetryService telemetry.TelemetryService) *TURNSessions {
+	if !conf.TURN.Enabled {
+		return nil
+	}
+	return &TURNSessions{
+		quota:      conf.TURN.Quota.BytesPerSecond,
+		udpTimeout: withDefault(conf.TURN.UDPSessionTimeout, defaultTURNUDPSessionTimeout),
+		telemetry:  telemetryService,
+		sessions:   make(map[netip.AddrPort]*turnSession),
+		usage:      make(map[livekit.ParticipantID]*turnUsage),
+		lastSweep:  time.Now(),
+	}
+}
+
+func turnAddrPort(addr net.Addr) (netip.AddrPort, bool) {
+	var ap netip.AddrPort
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		ap = a.AddrPort()
+	case *net.TCPAddr:
+		ap = a.AddrPort()
+	default:
+		return ap, false
+	}
+	return netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port()), true
+}
+
+// Authenticated binds the session of srcAddr to the participant its credentials were issued to
+func (s *TURNSessions) Authenticated(srcAddr net.Addr, participantID livekit.ParticipantID, roomName livekit.RoomName) {
+	if s == nil {
+		return
+	}
+	ap, ok := turnAddrPort(srcAddr)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	var ended *telemetry.TURNAllocationEvent
+	s.lock.Lock()
+	session := s.sessions[ap]
+	if session == nil {
+		// TCP sessions are added with their connection
+		session = &turnSession{transport: TURNTransportUDP, remoteAddr: srcAddr.String()}
+		s.sessions[ap] = session
+	}
+	session.lastSeen = now
+	if session.participantID == participantID {
+		s.lock.Unlock()
+		return
+	}
+	if session.participantID != "" {
+		// the address of a client which went away is used by another one
+		ended = s.endLocked(session, now)
+		session.bytesIn, session.bytesOut = 0, 0
+	}
+	session.participantID = participantID
+	session.roomName = roomName
+	session.startedAt = now
+	session.usage = s.usage[participantID]
+	if session.usage == nil {
+		session.usage = &turnUsage{}
+		s.usage[participantID] = session.usage
+	}
+	session.usage.sessions++
+	transport := session.transport
+	s.lock.Unlock()
+
+	if ended != nil {
+		s.telemetry.TURNAllocation(context.Background(), ended)
+	}
+	prometheus.AddTURNAllocation(transport)
+	s.telemetry.TURNAllocation(context.Background(), &telemetry.TURNAllocationEvent{
+		Type:          telemetry.TURNAllocationStarted,
+		ParticipantID: participantID,
+		RoomName:      roomName,
+		Transport:     transport,
+		RemoteAddr:    srcAddr.String(),
+	})
+}
+
+// endLocked releases the participant of a session, returning the event to report once the lock is released
+func (s *TURNSessions) endLocked(session *turnSession, now time.Time) *telemetry.TURNAllocationEvent {
+	if session.participantID == "" {
+		return nil
+	}
+	prometheus.SubTURNAllocation(session.transport)
+	if session.usage.sessions--; session.usage.sessions == 0 {
+		delete(s.usage, session.participantID)
+	}
+	return &telemetry.TURNAllocationEvent{
+		Type:          telemetry.TURNAllocationEnded,
+		ParticipantID: session.participantID,
+		RoomName:      session.roomName,
+		Transport:     session.transport,
+		RemoteAddr:    session.remoteAddr,
+		BytesIn:       session.bytesIn,
+		BytesOut:      session.bytesOut,
+		Duration:      now.Sub(session.startedAt),
+	}
+}
+
+func (s *TURNSessions) connOpened(ap netip.AddrPort, transport string, remoteAddr string) {
+	s.lock.Lock()
+	s.sessions[ap] = &turnSession{transport: transport, remoteAddr: remoteAddr}
+	s.lock.Unlock()
+}
+
+func (s *TURNSessions) connClosed(ap netip.AddrPort) {
+	s.lock.Lock()
+	session := s.sessions[ap]
+	if session == nil {
+		s.lock.Unlock()
+		return
+	}
+	delete(s.sessions, ap)
+	ended := s.endLocked(session, time.Now())
+	s.lock.Unlock()
+
+	if ended != nil {
+		s.telemetry.TURNAllocation(context.Background(), ended)
+	}
+}
+
+// account adds n bytes to the session of ap, and returns how long to wait for the next quota window when the
+// participant of the session went over its quota
+func (s *TURNSessions) account(ap netip.AddrPort, direction prometheus.Direction, n int) time.Duration {
+	now := time.Now()
+	var wait time.Duration
+	var ended []*telemetry.TURNAllocationEvent
+
+	s.lock.Lock()
+	if session := s.sessions[ap]; session != nil {
+		session.lastSeen = now
+		if direction == prometheus.Incoming {
+			session.bytesIn += uint64(n)
+		} else {
+			session.bytesOut += uint64(n)
+		}
+		if usage := session.usage; usage != nil && s.quota > 0 {
+			if window := now.UnixNano() / int64(turnQuotaWindow); usage.window != window {
+				usage.window = window
+				usage.bytes = 0
+			}
+			if usage.bytes += int64(n); usage.bytes > s.quota {
+				wait = turnQuotaWindow - time.Duration(now.UnixNano()%int64(turnQuotaWindow))
+			}
+		}
+	}
+	if now.Sub(s.lastSweep) >= turnSessionSweepInterval {
+		s.lastSweep = now
+		for key, session := range s.sessions {
+			if session.transport == TURNTransportUDP && now.Sub(session.lastSeen) >= s.udpTimeout {
+				delete(s.sessions, key)
+				if event := s.endLocked(session, now); event != nil {
+					ended = append(ended, event)
+				}
+			}
+		}
+	}
+	s.lock.Unlock()
+
+	for _, event := range ended {
+		s.telemetry.TURNAllocation(context.Background(), event)
+	}
+	return wait
+}
+
+// --------------------------------------
+
+type turnListener struct {
+	net.Listener
+	sessions  *TURNSessions
+	transport string
+}
+
+func (l *turnListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	ap, ok := turnAddrPort(conn.RemoteAddr())
+	if !ok {
+		return conn, nil
+	}
+	l.sessions.connOpened(ap, l.transport, conn.RemoteAddr().String())
+	return &turnConn{Conn: conn, sessions: l.sessions, transport: l.transport, addr: ap}, nil
+}
+
+// turnConn delays reads and writes of participants over their quota, the stream cannot lose bytes
+type turnConn struct {
+	net.Conn
+	sessions  *TURNSessions
+	transport string
+	addr      netip.AddrPort
+	closeOnce sync.Once
+}
+
+func (c *turnConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		prometheus.RecordTURNRelayedBytes(c.transport, prometheus.Incoming, n)
+		if wait := c.sessions.account(c.addr, prometheus.Incoming, n); wait > 0 {
+			prometheus.RecordTURNQuotaExceeded(c.transport)
+			time.Sleep(wait)
+		}
+	}
+	return n, err
+}
+
+func (c *turnConn) Write(b []byte) (int, error) {
+	if wait := c.sessions.account(c.addr, prometheus.Outgoing, len(b)); wait > 0 {
+		prometheus.RecordTURNQuotaExceeded(c.transport)
+		time.Sleep(wait)
+	}
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		prometheus.RecordTURNRelayedBytes(c.transport, prometheus.Outgoing, n)
+	}
+	return n, err
+}
+
+func (c *turnConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.sessions.connClosed(c.addr)
+	})
+	return c.Conn.Close()
+}
+
+// turnPacketConn drops packets of participants over their quota
+type turnPacketConn struct {
+	net.PacketConn
+	sessions *TURNSessions
+}
+
+func (c *turnPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	for {
+		n, addr, err := c.PacketConn.ReadFrom(p)
+		if err != nil {
+			return n, addr, err
+		}
+		if ap, ok := turnAddrPort(addr); ok {
+			if wait := c.sessions.account(ap, prometheus.Incoming, n); wait > 0 {
+				prometheus.RecordTURNQuotaExceeded(TURNTransportUDP)
+				continue
+			}
+		}
+		prometheus.RecordTURNRelayedBytes(TURNTransportUDP, prometheus.Incoming, n)
+		return n, addr, err
+	}
+}
+
+func (c *turnPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if ap, ok := turnAddrPort(addr); ok {
+		if wait := c.sessions.account(ap, prometheus.Outgoing, len(p)); wait > 0 {
+			prometheus.RecordTURNQuotaExceeded(TURNTransportUDP)
+			return len(p), nil
+		}
+	}
+	n, err := c.PacketConn.WriteTo(p, addr)
+	if n > 0 {
+		prometheus.RecordTURNRelayedBytes(TURNTransportUDP, prometheus.Outgoing, n)
+	}
+	return n, err
+}
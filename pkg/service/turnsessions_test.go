@@ -0,0 +1,95 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestTURNSessions(t *testing.T) {
+	newSessions := func(quota int64) (*TURNSessions, *telemetryfakes.FakeTelemetryService) {
+		ts := &telemetryfakes.FakeTelemetryService{}
+		conf := &config.Config{TURN: config.TURNConfig{Enabled: true, Quota: config.TURNQuotaConfig{BytesPerSecond: quota}}}
+		return NewTURNSessions(conf, ts), ts
+	}
+	udpAddr := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5000}
+	udpKey, _ := turnAddrPort(udpAddr)
+
+	t.Run("UDP sessions start on authentication", func(t *testing.T) {
+		s, ts := newSessions(0)
+		s.Authenticated(udpAddr, "PA_1", "room")
+		// refreshes are authenticated again
+		s.Authenticated(udpAddr, "PA_1", "room")
+		require.Equal(t, 1, ts.TURNAllocationCallCount())
+		_, event := ts.TURNAllocationArgsForCall(0)
+		require.Equal(t, telemetry.TURNAllocationStarted, event.Type)
+		require.Equal(t, TURNTransportUDP, event.Transport)
+
+		// the address is taken by another participant
+		s.account(udpKey, prometheus.Incoming, 100)
+		s.Authenticated(udpAddr, "PA_2", "room")
+		require.Equal(t, 3, ts.TURNAllocationCallCount())
+		_, event = ts.TURNAllocationArgsForCall(1)
+		require.Equal(t, telemetry.TURNAllocationEnded, event.Type)
+		require.Equal(t, uint64(100), event.BytesIn)
+		require.Len(t, s.usage, 1)
+	})
+
+	t.Run("TCP sessions end with their connection", func(t *testing.T) {
+		s, ts := newSessions(0)
+		tcpAddr := &net.TCPAddr{IP: net.ParseIP("192.0.2.2"), Port: 443}
+		key, _ := turnAddrPort(tcpAddr)
+		s.connOpened(key, TURNTransportTLS, tcpAddr.String())
+		s.Authenticated(tcpAddr, "PA_1", "room")
+		s.connClosed(key)
+		require.Equal(t, 2, ts.TURNAllocationCallCount())
+		_, event := ts.TURNAllocationArgsForCall(1)
+		require.Equal(t, telemetry.TURNAllocationEnded, event.Type)
+		require.Equal(t, TURNTransportTLS, event.Transport)
+		require.Empty(t, s.sessions)
+		require.Empty(t, s.usage)
+	})
+
+	t.Run("quota is shared by the sessions of a participant", func(t *testing.T) {
+		s, _ := newSessions(1000)
+		otherAddr := &net.UDPAddr{IP: net.ParseIP("192.0.2.3"), Port: 5000}
+		otherKey, _ := turnAddrPort(otherAddr)
+		s.Authenticated(udpAddr, "PA_1", "room")
+		s.Authenticated(otherAddr, "PA_1", "room")
+
+		// unless the window ends in between
+		if time.Duration(time.Now().UnixNano()%int64(turnQuotaWindow)) > turnQuotaWindow-100*time.Millisecond {
+			time.Sleep(100 * time.Millisecond)
+		}
+		require.Zero(t, s.account(udpKey, prometheus.Incoming, 600))
+		require.Zero(t, s.account(otherKey, prometheus.Outgoing, 400))
+		wait := s.account(otherKey, prometheus.Incoming, 1)
+		require.Greater(t, wait, time.Duration(0))
+		require.LessOrEqual(t, wait, turnQuotaWindow)
+
+		// addresses without a session are not limited
+		unknown, _ := turnAddrPort(&net.UDPAddr{IP: net.ParseIP("192.0.2.4"), Port: 5000})
+		require.Zero(t, s.account(unknown, prometheus.Incoming, 10000))
+	})
+}
@@ -16,7 +16,9 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"net"
 	"net/http"
 	"regexp"
@@ -24,6 +26,7 @@ import (
 
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc"
 )
 
 func handleError(w http.ResponseWriter, r *http.Request, status int, err error, keysAndValues ...interface{}) {
@@ -38,6 +41,26 @@ func handleError(w http.ResponseWriter, r *http.Request, status int, err error,
 	_, _ = w.Write([]byte(err.Error()))
 }
 
+// decodeJSONRequest decodes the body of the JSON APIs served next to twirp
+func decodeJSONRequest(r *http.Request, req any, maxSize int64) error {
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxSize)).Decode(req); err != nil {
+		return psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+	return nil
+}
+
+// apiErrorStatus maps errors returned by the JSON APIs to HTTP status codes
+func apiErrorStatus(err error) int {
+	if errors.Is(err, ErrPermissionDenied) {
+		return http.StatusUnauthorized
+	}
+	var perr psrpc.Error
+	if errors.As(err, &perr) {
+		return perr.ToHttp()
+	}
+	return http.StatusInternalServerError
+}
+
 func boolValue(s string) bool {
 	return s == "1" || s == "true"
 }
@@ -0,0 +1,319 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	virtualParticipantRPCService = "VirtualParticipant"
+	createVirtualParticipantRPC  = "CreateVirtualParticipant"
+	sendVirtualParticipantRPC    = "SendVirtualParticipantData"
+
+	virtualParticipantHookTimeout = 5 * time.Second
+	maxVirtualParticipantRequest  = 64 * 1024
+)
+
+// VirtualParticipantClient reaches the node hosting a room to manage its virtual participants.
+// Virtual participants are updated and removed through RoomService like any other participant.
+type VirtualParticipantClient interface {
+	CreateVirtualParticipant(ctx context.Context, room rpc.RoomTopic, req *livekit.UpdateParticipantRequest, opts ...psrpc.RequestOption) (*livekit.ParticipantInfo, error)
+	// SendVirtualParticipantData sends a data packet from the virtual participant named by its ParticipantIdentity
+	SendVirtualParticipantData(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.SendDataResponse, error)
+}
+
+type VirtualParticipantServerImpl interface {
+	CreateVirtualParticipant(ctx context.Context, req *livekit.UpdateParticipantRequest) (*livekit.ParticipantInfo, error)
+	SendVirtualParticipantData(ctx context.Context, roomName livekit.RoomName, req *livekit.DataPacket) (*livekit.SendDataResponse, error)
+}
+
+type virtualParticipantClient struct {
+	client *client.RPCClient
+}
+
+func NewVirtualParticipantClient(params rpc.ClientParams) (VirtualParticipantClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: virtualParticipantRPCService,
+		ID:   rand.NewClientID(),
+	}
+	registerVirtualParticipantMethods(sd)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &virtualParticipantClient{client: rpcClient}, nil
+}
+
+func (c *virtualParticipantClient) CreateVirtualParticipant(ctx context.Context, room rpc.RoomTopic, req *livekit.UpdateParticipantRequest, opts ...psrpc.RequestOption) (*livekit.ParticipantInfo, error) {
+	return client.RequestSingle[*livekit.ParticipantInfo](ctx, c.client, createVirtualParticipantRPC, []string{string(room)}, req, opts...)
+}
+
+func (c *virtualParticipantClient) SendVirtualParticipantData(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.SendDataResponse, error) {
+	return client.RequestSingle[*livekit.SendDataResponse](ctx, c.client, sendVirtualParticipantRPC, []string{string(room)}, req, opts...)
+}
+
+// virtualParticipantServer handles virtual participant requests for a room hosted on this node
+type virtualParticipantServer struct {
+	svc      VirtualParticipantServerImpl
+	roomName livekit.RoomName
+	rpc      *server.RPCServer
+}
+
+func newVirtualParticipantServer(svc VirtualParticipantServerImpl, roomName livekit.RoomName, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *virtualParticipantServer {
+	sd := &info.ServiceDefinition{
+		Name: virtualParticipantRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	registerVirtualParticipantMethods(sd)
+	return &virtualParticipantServer{
+		svc:      svc,
+		roomName: roomName,
+		rpc:      s,
+	}
+}
+
+func (s *virtualParticipantServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	if err := server.RegisterHandler(s.rpc, createVirtualParticipantRPC, []string{string(room)}, s.svc.CreateVirtualParticipant, nil); err != nil {
+		return err
+	}
+	return server.RegisterHandler(s.rpc, sendVirtualParticipantRPC, []string{string(room)}, s.sendData, nil)
+}
+
+func (s *virtualParticipantServer) sendData(ctx context.Context, req *livekit.DataPacket) (*livekit.SendDataResponse, error) {
+	// data packets don't carry the room, it is implied by the topic
+	return s.svc.SendVirtualParticipantData(ctx, s.roomName, req)
+}
+
+func (s *virtualParticipantServer) Kill() {
+	s.rpc.Close(true)
+}
+
+func registerVirtualParticipantMethods(sd *info.ServiceDefinition) {
+	sd.RegisterMethod(createVirtualParticipantRPC, false, false, true, true)
+	sd.RegisterMethod(sendVirtualParticipantRPC, false, false, true, true)
+}
+
+// ---------------------------------------------
+
+// CreateVirtualParticipantRequest is the JSON body of POST /virtual_participants/create
+type CreateVirtualParticipantRequest struct {
+	Room       string            `json:"room"`
+	Identity   string            `json:"identity"`
+	Name       string            `json:"name,omitempty"`
+	Metadata   string            `json:"metadata,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// SendVirtualParticipantDataRequest is the JSON body of POST /virtual_participants/send_data
+type SendVirtualParticipantDataRequest struct {
+	Room                  string   `json:"room"`
+	Identity              string   `json:"identity"`
+	Data                  []byte   `json:"data"`
+	Topic                 string   `json:"topic,omitempty"`
+	DestinationIdentities []string `json:"destination_identities,omitempty"`
+	Lossy                 bool     `json:"lossy,omitempty"`
+}
+
+// VirtualParticipantService serves the HTTP API creating virtual participants and sending data on their behalf.
+// Both calls require the roomAdmin grant for the room.
+type VirtualParticipantService struct {
+	limitConf      config.LimitConfig
+	roomStore      ServiceStore
+	topicFormatter rpc.TopicFormatter
+	client         VirtualParticipantClient
+}
+
+func NewVirtualParticipantService(
+	limitConf config.LimitConfig,
+	roomStore ServiceStore,
+	topicFormatter rpc.TopicFormatter,
+	client VirtualParticipantClient,
+) *VirtualParticipantService {
+	return &VirtualParticipantService{
+		limitConf:      limitConf,
+		roomStore:      roomStore,
+		topicFormatter: topicFormatter,
+		client:         client,
+	}
+}
+
+func (s *VirtualParticipantService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res proto.Message
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/virtual_participants/") {
+	case "create":
+		var req CreateVirtualParticipantRequest
+		if err = decodeJSONRequest(r, &req, maxVirtualParticipantRequest); err == nil {
+			res, err = s.CreateVirtualParticipant(r.Context(), &req)
+		}
+	case "send_data":
+		var req SendVirtualParticipantDataRequest
+		if err = decodeJSONRequest(r, &req, maxVirtualParticipantRequest); err == nil {
+			res, err = s.SendData(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	body, err := protojson.Marshal(res)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+func (s *VirtualParticipantService) CreateVirtualParticipant(ctx context.Context, req *CreateVirtualParticipantRequest) (*livekit.ParticipantInfo, error) {
+	roomName := livekit.RoomName(req.Room)
+	if err := EnsureAdminPermission(ctx, roomName); err != nil {
+		return nil, err
+	}
+	if req.Identity == "" {
+		return nil, ErrIdentityEmpty
+	}
+	if !s.limitConf.CheckParticipantNameLength(req.Name) {
+		return nil, fmt.Errorf("%w: max length %d", ErrNameExceedsLimits, s.limitConf.MaxParticipantNameLength)
+	}
+	if !s.limitConf.CheckMetadataSize(req.Metadata) {
+		return nil, fmt.Errorf("%w: max size %d", ErrMetadataExceedsLimits, s.limitConf.MaxMetadataSize)
+	}
+	if !s.limitConf.CheckAttributesSize(req.Attributes) {
+		return nil, fmt.Errorf("%w: max size %d", ErrAttributeExceedsLimits, s.limitConf.MaxAttributesSize)
+	}
+	// the room must be active on a node to host the participant
+	if _, _, err := s.roomStore.LoadRoom(ctx, roomName, false); err != nil {
+		return nil, err
+	}
+
+	return s.client.CreateVirtualParticipant(ctx, s.topicFormatter.RoomTopic(ctx, roomName), &livekit.UpdateParticipantRequest{
+		Room:       req.Room,
+		Identity:   req.Identity,
+		Name:       req.Name,
+		Metadata:   req.Metadata,
+		Attributes: req.Attributes,
+	})
+}
+
+func (s *VirtualParticipantService) SendData(ctx context.Context, req *SendVirtualParticipantDataRequest) (*livekit.SendDataResponse, error) {
+	roomName := livekit.RoomName(req.Room)
+	if err := EnsureAdminPermission(ctx, roomName); err != nil {
+		return nil, err
+	}
+
+	kind := livekit.DataPacket_RELIABLE
+	if req.Lossy {
+		kind = livekit.DataPacket_LOSSY
+	}
+	var topic *string
+	if req.Topic != "" {
+		topic = &req.Topic
+	}
+	return s.client.SendVirtualParticipantData(ctx, s.topicFormatter.RoomTopic(ctx, roomName), &livekit.DataPacket{
+		Kind:                  kind,
+		ParticipantIdentity:   req.Identity,
+		DestinationIdentities: req.DestinationIdentities,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload:               req.Data,
+				DestinationIdentities: req.DestinationIdentities,
+				Topic:                 topic,
+			},
+		},
+	})
+}
+
+// ---------------------------------------------
+
+// VirtualParticipantDataEvent is posted to the virtual participant webhook for every data message a
+// virtual participant receives
+type VirtualParticipantDataEvent struct {
+	Room                string `json:"room"`
+	ParticipantIdentity string `json:"participant_identity"`
+	FromIdentity        string `json:"from_identity,omitempty"`
+	Topic               string `json:"topic,omitempty"`
+	Data                []byte `json:"data"`
+	CreatedAt           int64  `json:"created_at"`
+}
+
+type virtualParticipantHook struct {
+	hook *jsonHook
+}
+
+// newVirtualParticipantHook returns nil when no webhook is configured, data sent to virtual participants is dropped
+func newVirtualParticipantHook(conf config.VirtualParticipantConfig) *virtualParticipantHook {
+	if conf.WebhookURL == "" {
+		return nil
+	}
+	return &virtualParticipantHook{
+		hook: newJSONHook("virtual participant", conf.WebhookURL, conf.WebhookHeaders, virtualParticipantHookTimeout),
+	}
+}
+
+func (h *virtualParticipantHook) deliver(roomName livekit.RoomName, identity livekit.ParticipantIdentity, dp *livekit.DataPacket) {
+	u := dp.GetUser()
+	event := &VirtualParticipantDataEvent{
+		Room:                string(roomName),
+		ParticipantIdentity: string(identity),
+		FromIdentity:        dp.ParticipantIdentity,
+		Topic:               u.GetTopic(),
+		Data:                u.GetPayload(),
+		CreatedAt:           time.Now().Unix(),
+	}
+	go func() {
+		if err := h.hook.postJSON(context.Background(), event, nil); err != nil {
+			logger.Warnw("could not deliver data to virtual participant", err,
+				"room", roomName,
+				"participant", identity,
+				"size", len(event.Data),
+			)
+		}
+	}()
+}
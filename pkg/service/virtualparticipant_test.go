@@ -0,0 +1,110 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+type testVirtualParticipantClient struct {
+	topic   rpc.RoomTopic
+	created *livekit.UpdateParticipantRequest
+	sent    *livekit.DataPacket
+}
+
+func (c *testVirtualParticipantClient) CreateVirtualParticipant(_ context.Context, room rpc.RoomTopic, req *livekit.UpdateParticipantRequest, _ ...psrpc.RequestOption) (*livekit.ParticipantInfo, error) {
+	c.topic = room
+	c.created = req
+	return &livekit.ParticipantInfo{Identity: req.Identity, Name: req.Name, Attributes: req.Attributes}, nil
+}
+
+func (c *testVirtualParticipantClient) SendVirtualParticipantData(_ context.Context, room rpc.RoomTopic, req *livekit.DataPacket, _ ...psrpc.RequestOption) (*livekit.SendDataResponse, error) {
+	c.topic = room
+	c.sent = req
+	return &livekit.SendDataResponse{}, nil
+}
+
+func TestVirtualParticipantService(t *testing.T) {
+	client := &testVirtualParticipantClient{}
+	store := &servicefakes.FakeServiceStore{}
+	svc := service.NewVirtualParticipantService(config.LimitConfig{MaxMetadataSize: 5}, store, rpc.NewTopicFormatter(), client)
+
+	do := func(grant *auth.VideoGrant, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req = req.WithContext(service.WithGrants(req.Context(), &auth.ClaimGrants{Video: grant}, ""))
+		w := httptest.NewRecorder()
+		svc.ServeHTTP(w, req)
+		return w
+	}
+	admin := &auth.VideoGrant{RoomAdmin: true, Room: "room"}
+
+	t.Run("requires room admin", func(t *testing.T) {
+		w := do(&auth.VideoGrant{RoomAdmin: true, Room: "other"}, "/virtual_participants/create", `{"room":"room","identity":"bot"}`)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		require.Nil(t, client.created)
+	})
+
+	t.Run("validates the request", func(t *testing.T) {
+		w := do(admin, "/virtual_participants/create", `{"room":"room"}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = do(admin, "/virtual_participants/create", `{"room":"room","identity":"bot","metadata":"abcdefg"}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Nil(t, client.created)
+	})
+
+	t.Run("create", func(t *testing.T) {
+		w := do(admin, "/virtual_participants/create", `{"room":"room","identity":"bot","name":"Bot","attributes":{"role":"scorekeeper"}}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, rpc.RoomTopic("room"), client.topic)
+		require.Equal(t, "bot", client.created.Identity)
+		require.Equal(t, "scorekeeper", client.created.Attributes["role"])
+
+		var pi livekit.ParticipantInfo
+		require.NoError(t, protojson.Unmarshal(w.Body.Bytes(), &pi))
+		require.Equal(t, "Bot", pi.Name)
+	})
+
+	t.Run("send data", func(t *testing.T) {
+		w := do(admin, "/virtual_participants/send_data", `{"room":"room","identity":"bot","data":"aGVsbG8=","topic":"score","destination_identities":["p1"]}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "bot", client.sent.ParticipantIdentity)
+		require.Equal(t, livekit.DataPacket_RELIABLE, client.sent.Kind)
+		require.Equal(t, []string{"p1"}, client.sent.DestinationIdentities)
+		require.Equal(t, []byte("hello"), client.sent.GetUser().Payload)
+		require.Equal(t, "score", client.sent.GetUser().GetTopic())
+	})
+
+	t.Run("unknown path", func(t *testing.T) {
+		w := do(admin, "/virtual_participants/delete", `{}`)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,239 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+const (
+	waitingRoomRPCService     = "WaitingRoom"
+	listPendingParticipantRPC = "ListPendingParticipants"
+	admitParticipantsRPC      = "AdmitParticipants"
+
+	maxWaitingRoomRequest = 64 * 1024
+)
+
+// WaitingRoomClient reaches the node hosting a room to list and admit the participants waiting in its lobby.
+// AdmitParticipants takes the participants to admit by identity, an empty list admits everyone waiting,
+// and returns the admitted participants.
+type WaitingRoomClient interface {
+	ListPendingParticipants(ctx context.Context, room rpc.RoomTopic, req *livekit.ListParticipantsRequest, opts ...psrpc.RequestOption) (*livekit.ListParticipantsResponse, error)
+	AdmitParticipants(ctx context.Context, room rpc.RoomTopic, req *livekit.ListParticipantsResponse, opts ...psrpc.RequestOption) (*livekit.ListParticipantsResponse, error)
+}
+
+type WaitingRoomServerImpl interface {
+	ListPendingParticipants(ctx context.Context, req *livekit.ListParticipantsRequest) (*livekit.ListParticipantsResponse, error)
+	AdmitParticipants(ctx context.Context, roomName livekit.RoomName, req *livekit.ListParticipantsResponse) (*livekit.ListParticipantsResponse, error)
+}
+
+type waitingRoomClient struct {
+	client *client.RPCClient
+}
+
+func NewWaitingRoomClient(params rpc.ClientParams) (WaitingRoomClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: waitingRoomRPCService,
+		ID:   rand.NewClientID(),
+	}
+	registerWaitingRoomMethods(sd)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &waitingRoomClient{client: rpcClient}, nil
+}
+
+func (c *waitingRoomClient) ListPendingParticipants(ctx context.Context, room rpc.RoomTopic, req *livekit.ListParticipantsRequest, opts ...psrpc.RequestOption) (*livekit.ListParticipantsResponse, error) {
+	return client.RequestSingle[*livekit.ListParticipantsResponse](ctx, c.client, listPendingParticipantRPC, []string{string(room)}, req, opts...)
+}
+
+func (c *waitingRoomClient) AdmitParticipants(ctx context.Context, room rpc.RoomTopic, req *livekit.ListParticipantsResponse, opts ...psrpc.RequestOption) (*livekit.ListParticipantsResponse, error) {
+	return client.RequestSingle[*livekit.ListParticipantsResponse](ctx, c.client, admitParticipantsRPC, []string{string(room)}, req, opts...)
+}
+
+// waitingRoomServer handles waiting room requests for a room hosted on this node
+type waitingRoomServer struct {
+	svc      WaitingRoomServerImpl
+	roomName livekit.RoomName
+	rpc      *server.RPCServer
+}
+
+func newWaitingRoomServer(svc WaitingRoomServerImpl, roomName livekit.RoomName, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *waitingRoomServer {
+	sd := &info.ServiceDefinition{
+		Name: waitingRoomRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	registerWaitingRoomMethods(sd)
+	return &waitingRoomServer{
+		svc:      svc,
+		roomName: roomName,
+		rpc:      s,
+	}
+}
+
+func (s *waitingRoomServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	if err := server.RegisterHandler(s.rpc, listPendingParticipantRPC, []string{string(room)}, s.svc.ListPendingParticipants, nil); err != nil {
+		return err
+	}
+	return server.RegisterHandler(s.rpc, admitParticipantsRPC, []string{string(room)}, s.admit, nil)
+}
+
+func (s *waitingRoomServer) admit(ctx context.Context, req *livekit.ListParticipantsResponse) (*livekit.ListParticipantsResponse, error) {
+	// the list of participants doesn't carry the room, it is implied by the topic
+	return s.svc.AdmitParticipants(ctx, s.roomName, req)
+}
+
+func (s *waitingRoomServer) Kill() {
+	s.rpc.Close(true)
+}
+
+func registerWaitingRoomMethods(sd *info.ServiceDefinition) {
+	sd.RegisterMethod(listPendingParticipantRPC, false, false, true, true)
+	sd.RegisterMethod(admitParticipantsRPC, false, false, true, true)
+}
+
+// ---------------------------------------------
+
+// ListPendingParticipantsRequest is the JSON body of POST /waiting_room/list
+type ListPendingParticipantsRequest struct {
+	Room string `json:"room"`
+}
+
+// AdmitParticipantsRequest is the JSON body of POST /waiting_room/admit, either identities or all must be set
+type AdmitParticipantsRequest struct {
+	Room       string   `json:"room"`
+	Identities []string `json:"identities,omitempty"`
+	All        bool     `json:"all,omitempty"`
+}
+
+// WaitingRoomService serves the HTTP API of room lobbies. Participants joining with rtc.PendingAttribute
+// in their token wait hidden, without any track, until admitted through this API. Calls require roomAdmin.
+type WaitingRoomService struct {
+	roomStore      ServiceStore
+	topicFormatter rpc.TopicFormatter
+	client         WaitingRoomClient
+}
+
+func NewWaitingRoomService(
+	roomStore ServiceStore,
+	topicFormatter rpc.TopicFormatter,
+	client WaitingRoomClient,
+) *WaitingRoomService {
+	return &WaitingRoomService{
+		roomStore:      roomStore,
+		topicFormatter: topicFormatter,
+		client:         client,
+	}
+}
+
+func (s *WaitingRoomService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var (
+		res proto.Message
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, "/waiting_room/") {
+	case "list":
+		var req ListPendingParticipantsRequest
+		if err = decodeJSONRequest(r, &req, maxWaitingRoomRequest); err == nil {
+			res, err = s.ListPendingParticipants(r.Context(), &req)
+		}
+	case "admit":
+		var req AdmitParticipantsRequest
+		if err = decodeJSONRequest(r, &req, maxWaitingRoomRequest); err == nil {
+			res, err = s.AdmitParticipants(r.Context(), &req)
+		}
+	default:
+		handleError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	body, err := protojson.Marshal(res)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+func (s *WaitingRoomService) ListPendingParticipants(ctx context.Context, req *ListPendingParticipantsRequest) (*livekit.ListParticipantsResponse, error) {
+	roomName := livekit.RoomName(req.Room)
+	if err := s.ensureRoom(ctx, roomName); err != nil {
+		return nil, err
+	}
+
+	return s.client.ListPendingParticipants(ctx, s.topicFormatter.RoomTopic(ctx, roomName), &livekit.ListParticipantsRequest{
+		Room: req.Room,
+	})
+}
+
+// AdmitParticipants admits the given participants, or everyone waiting when All is set
+func (s *WaitingRoomService) AdmitParticipants(ctx context.Context, req *AdmitParticipantsRequest) (*livekit.ListParticipantsResponse, error) {
+	roomName := livekit.RoomName(req.Room)
+	AppendLogFields(ctx, "room", roomName, "participants", req.Identities, "all", req.All)
+	if len(req.Identities) == 0 && !req.All {
+		return nil, ErrIdentityEmpty
+	}
+	if err := s.ensureRoom(ctx, roomName); err != nil {
+		return nil, err
+	}
+
+	admit := &livekit.ListParticipantsResponse{}
+	if !req.All {
+		for _, identity := range req.Identities {
+			if identity == "" {
+				return nil, ErrIdentityEmpty
+			}
+			admit.Participants = append(admit.Participants, &livekit.ParticipantInfo{Identity: identity})
+		}
+	}
+	return s.client.AdmitParticipants(ctx, s.topicFormatter.RoomTopic(ctx, roomName), admit)
+}
+
+func (s *WaitingRoomService) ensureRoom(ctx context.Context, roomName livekit.RoomName) error {
+	if err := EnsureAdminPermission(ctx, roomName); err != nil {
+		return err
+	}
+	// the room must be active on a node for participants to wait in it
+	_, _, err := s.roomStore.LoadRoom(ctx, roomName, false)
+	return err
+}
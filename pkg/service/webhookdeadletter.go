@@ -0,0 +1,126 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/livekit-server/pkg/archiver"
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// WebhookDeadLetter is an event that could not be delivered, as written to the dead letter sink
+type WebhookDeadLetter struct {
+	WebhookDelivery
+	Payload json.RawMessage `json:"payload"`
+}
+
+type webhookDeadLetterSink interface {
+	write(d *WebhookDelivery) error
+}
+
+func newWebhookDeadLetterSink(conf config.WebHookDeadLetterConfig, rc redis.UniversalClient) (webhookDeadLetterSink, error) {
+	switch conf.Kind {
+	case config.WebHookDeadLetterFile:
+		return &webhookDeadLetterFile{path: conf.File}, nil
+	case config.WebHookDeadLetterRedis:
+		if rc == nil {
+			return nil, errors.New("webhook dead letter stream requires redis")
+		}
+		return &webhookDeadLetterStream{rc: rc, stream: conf.RedisStream, maxLen: conf.StreamMaxLen}, nil
+	case config.WebHookDeadLetterS3:
+		return &webhookDeadLetterS3{store: archiver.NewS3Store(conf.S3), conf: conf.S3}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func encodeWebhookDeadLetter(d *WebhookDelivery) ([]byte, error) {
+	payload, err := protojson.Marshal(d.event)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&WebhookDeadLetter{WebhookDelivery: *d, Payload: payload})
+}
+
+// webhookDeadLetterFile appends one JSON document per line
+type webhookDeadLetterFile struct {
+	path string
+	lock sync.Mutex
+}
+
+func (s *webhookDeadLetterFile) write(d *WebhookDelivery) error {
+	data, err := encodeWebhookDeadLetter(d)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+type webhookDeadLetterStream struct {
+	rc     redis.UniversalClient
+	stream string
+	maxLen int64
+}
+
+func (s *webhookDeadLetterStream) write(d *WebhookDelivery) error {
+	data, err := encodeWebhookDeadLetter(d)
+	if err != nil {
+		return err
+	}
+	return s.rc.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: s.stream,
+		MaxLen: s.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{"delivery": data},
+	}).Err()
+}
+
+type webhookDeadLetterS3 struct {
+	store *archiver.S3Store
+	conf  config.RoomArchiveConfig
+}
+
+func (s *webhookDeadLetterS3) write(d *WebhookDelivery) error {
+	data, err := encodeWebhookDeadLetter(d)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if s.conf.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.conf.Timeout)
+		defer cancel()
+	}
+	return s.store.Put(ctx, s.conf.Prefix+d.ID+".json", data)
+}
@@ -0,0 +1,475 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	// the event was dead-lettered
+	WebhookDeliveryFailed = "failed"
+
+	webhookDeliveryPrefix = "WD_"
+	numWebhookWorkers     = 10
+	webhookTokenValidity  = 5 * time.Minute
+)
+
+var (
+	errWebhookQueueFull = errors.New("webhook queue is full")
+	errWebhookStopped   = errors.New("webhook notifier stopped")
+)
+
+// WebhookDelivery is the delivery of an event to a URL, listed by POST /node/webhook_deliveries
+type WebhookDelivery struct {
+	ID      string `json:"id"`
+	Event   string `json:"event"`
+	EventID string `json:"event_id"`
+	Room    string `json:"room,omitempty"`
+	URL     string `json:"url"`
+	// trace the event was raised in, its context is sent in the traceparent header
+	TraceID   string    `json:"trace_id,omitempty"`
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	event        *livekit.WebhookEvent
+	traceHeaders map[string]string
+}
+
+// WebhookNotifier delivers webhook events to the configured URLs and to the endpoints whose filters match,
+// which can change when the config is reloaded.
+// Failed deliveries are retried with exponential backoff, events of the same room are delivered in order.
+// Events that could not be delivered are written to the dead letter sink. Events are dropped while no URL
+// is configured.
+type WebhookNotifier struct {
+	provider   auth.KeyProvider
+	deadLetter webhookDeadLetterSink
+	client     *http.Client
+	queues     []chan *WebhookDelivery
+	closing    chan struct{}
+	workers    sync.WaitGroup
+
+	lock     sync.RWMutex
+	conf     config.WebHookConfig
+	secrets  map[string]string
+	handler  func(ctx context.Context, event *livekit.WebhookEvent)
+	log      []*WebhookDelivery
+	logIndex map[string]*WebhookDelivery
+	stopped  bool
+}
+
+func NewWebhookNotifier(conf config.WebHookConfig, provider auth.KeyProvider, rc redis.UniversalClient) (*WebhookNotifier, error) {
+	deadLetter, err := newWebhookDeadLetterSink(conf.DeadLetter, rc)
+	if err != nil {
+		return nil, err
+	}
+	n := &WebhookNotifier{
+		provider:   provider,
+		deadLetter: deadLetter,
+		client:     &http.Client{},
+		closing:    make(chan struct{}),
+		logIndex:   make(map[string]*WebhookDelivery),
+	}
+	if err := n.update(conf); err != nil {
+		return nil, err
+	}
+
+	queueSize := max(conf.QueueSize/numWebhookWorkers, 1)
+	for i := 0; i < numWebhookWorkers; i++ {
+		queue := make(chan *WebhookDelivery, queueSize)
+		n.queues = append(n.queues, queue)
+		n.workers.Add(1)
+		go n.worker(queue)
+	}
+	return n, nil
+}
+
+// OnEvent sets a function called in-process with every event
+func (n *WebhookNotifier) OnEvent(handler func(ctx context.Context, event *livekit.WebhookEvent)) {
+	n.lock.Lock()
+	n.handler = handler
+	n.lock.Unlock()
+}
+
+func (n *WebhookNotifier) QueueNotify(ctx context.Context, event *livekit.WebhookEvent) error {
+	n.lock.RLock()
+	urls := webhookURLs(&n.conf, event)
+	handler := n.handler
+	n.lock.RUnlock()
+	if handler != nil {
+		handler(ctx, event)
+	}
+
+	traceID, traceHeaders := telemetry.TraceID(ctx), telemetry.TraceHeaders(ctx)
+	for _, url := range urls {
+		now := time.Now()
+		n.enqueue(&WebhookDelivery{
+			ID:           guid.New(webhookDeliveryPrefix),
+			Event:        event.Event,
+			EventID:      event.Id,
+			Room:         webhookEventRoom(event),
+			URL:          url,
+			TraceID:      traceID,
+			Status:       WebhookDeliveryPending,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+			event:        event,
+			traceHeaders: traceHeaders,
+		})
+	}
+	return nil
+}
+
+// ListDeliveries returns the recent deliveries of this node, most recent first
+func (n *WebhookNotifier) ListDeliveries(event string, status string, limit int) []*WebhookDelivery {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	var deliveries []*WebhookDelivery
+	for i := len(n.log) - 1; i >= 0; i-- {
+		d := n.log[i]
+		if (event != "" && d.Event != event) || (status != "" && d.Status != status) {
+			continue
+		}
+		c := *d
+		deliveries = append(deliveries, &c)
+		if limit > 0 && len(deliveries) == limit {
+			break
+		}
+	}
+	return deliveries
+}
+
+// Resend queues the event of a recent delivery again for its URL
+func (n *WebhookNotifier) Resend(id string) (*WebhookDelivery, error) {
+	n.lock.RLock()
+	prev, ok := n.logIndex[id]
+	var d *WebhookDelivery
+	if ok {
+		now := time.Now()
+		d = &WebhookDelivery{
+			ID:           guid.New(webhookDeliveryPrefix),
+			Event:        prev.Event,
+			EventID:      prev.EventID,
+			Room:         prev.Room,
+			URL:          prev.URL,
+			TraceID:      prev.TraceID,
+			Status:       WebhookDeliveryPending,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+			event:        prev.event,
+			traceHeaders: prev.traceHeaders,
+		}
+	}
+	n.lock.RUnlock()
+	if !ok {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+
+	n.enqueue(d)
+	// workers update the delivery once it is queued
+	n.lock.RLock()
+	c := *d
+	n.lock.RUnlock()
+	return &c, nil
+}
+
+func (n *WebhookNotifier) ReloadConfig(conf *config.Config) error {
+	return n.update(conf.WebHook)
+}
+
+func (n *WebhookNotifier) update(conf config.WebHookConfig) error {
+	apiKeys := make(map[string]struct{})
+	if len(conf.URLs) != 0 {
+		apiKeys[conf.APIKey] = struct{}{}
+	}
+	for _, e := range conf.Endpoints {
+		apiKeys[webhookAPIKey(&conf, e.URL)] = struct{}{}
+	}
+	secrets := make(map[string]string, len(apiKeys))
+	for apiKey := range apiKeys {
+		secret := n.provider.GetSecret(apiKey)
+		if secret == "" {
+			return ErrWebHookMissingAPIKey
+		}
+		secrets[apiKey] = secret
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	// deliveries already queued keep their URL, and are signed with the new key from their next attempt
+	n.conf = conf
+	n.secrets = secrets
+	return nil
+}
+
+// Stop dead-letters the deliveries still queued once the ones in progress are done
+func (n *WebhookNotifier) Stop() {
+	n.lock.Lock()
+	if n.stopped {
+		n.lock.Unlock()
+		return
+	}
+	n.stopped = true
+	n.lock.Unlock()
+
+	close(n.closing)
+	n.workers.Wait()
+}
+
+func (n *WebhookNotifier) enqueue(d *WebhookDelivery) {
+	n.lock.Lock()
+	stopped := n.stopped
+	n.addToLog(d)
+	n.lock.Unlock()
+	if stopped {
+		n.fail(d, errWebhookStopped)
+		return
+	}
+
+	select {
+	case n.queues[webhookQueueIndex(d.event, len(n.queues))] <- d:
+	default:
+		n.fail(d, errWebhookQueueFull)
+	}
+}
+
+func (n *WebhookNotifier) addToLog(d *WebhookDelivery) {
+	n.log = append(n.log, d)
+	n.logIndex[d.ID] = d
+	if size := max(n.conf.DeliveryLogSize, 1); len(n.log) > size {
+		for _, old := range n.log[:len(n.log)-size] {
+			delete(n.logIndex, old.ID)
+		}
+		n.log = append(n.log[:0], n.log[len(n.log)-size:]...)
+	}
+}
+
+func (n *WebhookNotifier) worker(queue chan *WebhookDelivery) {
+	defer n.workers.Done()
+	for {
+		select {
+		case d := <-queue:
+			n.deliver(d)
+		case <-n.closing:
+			for {
+				select {
+				case d := <-queue:
+					n.fail(d, errWebhookStopped)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (n *WebhookNotifier) deliver(d *WebhookDelivery) {
+	for {
+		err := n.send(d)
+
+		n.lock.Lock()
+		d.Attempts++
+		d.UpdatedAt = time.Now()
+		if err == nil {
+			d.Status = WebhookDeliveryDelivered
+			d.LastError = ""
+		} else {
+			d.LastError = err.Error()
+		}
+		attempts := d.Attempts
+		conf := n.conf
+		n.lock.Unlock()
+
+		fields := []interface{}{"event", d.Event, "id", d.EventID, "url", d.URL, "attempt", attempts}
+		if err == nil {
+			logger.Infow("sent webhook", fields...)
+			return
+		}
+		if attempts >= conf.MaxAttempts {
+			n.fail(d, err)
+			return
+		}
+		logger.Warnw("failed to send webhook, retrying", err, fields...)
+
+		backoff := conf.MaxBackoff
+		if shift := attempts - 1; shift < 32 {
+			if b := conf.InitialBackoff << shift; b > 0 && (conf.MaxBackoff <= 0 || b < conf.MaxBackoff) {
+				backoff = b
+			}
+		}
+		select {
+		case <-time.After(backoff):
+		case <-n.closing:
+			n.fail(d, err)
+			return
+		}
+	}
+}
+
+func (n *WebhookNotifier) send(d *WebhookDelivery) error {
+	encoded, err := protojson.Marshal(d.event)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(encoded)
+
+	n.lock.RLock()
+	apiKey := webhookAPIKey(&n.conf, d.URL)
+	secret, timeout := n.secrets[apiKey], n.conf.Timeout
+	n.lock.RUnlock()
+	if secret == "" {
+		return ErrWebHookMissingAPIKey
+	}
+	token, err := auth.NewAccessToken(apiKey, secret).
+		SetValidFor(webhookTokenValidity).
+		SetSha256(base64.StdEncoding.EncodeToString(sum[:])).
+		ToJWT()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", token)
+	// a custom mime type ensures the signature is checked prior to parsing
+	req.Header.Set("Content-Type", "application/webhook+json")
+	for k, v := range d.traceHeaders {
+		req.Header.Set(k, v)
+	}
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook endpoint returned status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// fail marks the delivery as failed and writes it to the dead letter sink
+func (n *WebhookNotifier) fail(d *WebhookDelivery, err error) {
+	n.lock.Lock()
+	d.Status = WebhookDeliveryFailed
+	d.LastError = err.Error()
+	d.UpdatedAt = time.Now()
+	c := *d
+	n.lock.Unlock()
+
+	logger.Warnw("failed to send webhook", err, "event", d.Event, "id", d.EventID, "url", d.URL, "attempts", c.Attempts)
+	if n.deadLetter == nil {
+		return
+	}
+	if err := n.deadLetter.write(&c); err != nil {
+		logger.Errorw("could not dead-letter webhook", err, "event", d.Event, "id", d.EventID, "url", d.URL)
+	}
+}
+
+// webhookURLs returns the URLs an event is delivered to, once each
+func webhookURLs(conf *config.WebHookConfig, event *livekit.WebhookEvent) []string {
+	urls := slices.Clone(conf.URLs)
+	room := webhookEventRoom(event)
+	for _, e := range conf.Endpoints {
+		if slices.Contains(urls, e.URL) {
+			continue
+		}
+		if len(e.Events) != 0 && !slices.Contains(e.Events, event.Event) {
+			continue
+		}
+		if len(e.Rooms) != 0 && !slices.ContainsFunc(e.Rooms, func(pattern string) bool {
+			ok, _ := path.Match(pattern, room)
+			return room != "" && ok
+		}) {
+			continue
+		}
+		urls = append(urls, e.URL)
+	}
+	return urls
+}
+
+// webhookAPIKey returns the key signing the events of a URL, that of the first endpoint setting one
+func webhookAPIKey(conf *config.WebHookConfig, url string) string {
+	for _, e := range conf.Endpoints {
+		if e.URL == url && e.APIKey != "" {
+			return e.APIKey
+		}
+	}
+	return conf.APIKey
+}
+
+func webhookEventRoom(event *livekit.WebhookEvent) string {
+	switch {
+	case event.Room != nil:
+		return event.Room.Name
+	case event.EgressInfo != nil:
+		return event.EgressInfo.RoomName
+	case event.IngressInfo != nil:
+		return event.IngressInfo.RoomName
+	}
+	return ""
+}
+
+// events of a room, egress or ingress go through the same queue to be delivered in order
+func webhookQueueIndex(event *livekit.WebhookEvent, n int) int {
+	var key string
+	switch {
+	case event.EgressInfo != nil:
+		key = event.EgressInfo.EgressId
+	case event.IngressInfo != nil:
+		key = event.IngressInfo.IngressId
+	case event.Room != nil:
+		key = event.Room.Name
+	case event.Participant != nil:
+		key = event.Participant.Identity
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
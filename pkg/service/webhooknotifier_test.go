@@ -0,0 +1,205 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestWebhookNotifierReload(t *testing.T) {
+	received := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		event := &livekit.WebhookEvent{}
+		_ = json.Unmarshal(body, event)
+		received <- event.Event
+	}))
+	defer server.Close()
+
+	provider := auth.NewFileBasedKeyProviderFromMap(map[string]string{"key": "secret"})
+	n, err := service.NewWebhookNotifier(config.WebHookConfig{}, provider, nil)
+	require.NoError(t, err)
+
+	// no URL configured, events are dropped
+	require.NoError(t, n.QueueNotify(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted}))
+
+	err = n.ReloadConfig(&config.Config{WebHook: config.WebHookConfig{APIKey: "unknown", URLs: []string{server.URL}}})
+	require.ErrorIs(t, err, service.ErrWebHookMissingAPIKey)
+
+	require.NoError(t, n.ReloadConfig(&config.Config{WebHook: config.WebHookConfig{APIKey: "key", URLs: []string{server.URL}}}))
+	require.NoError(t, n.QueueNotify(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomFinished}))
+
+	select {
+	case event := <-received:
+		require.Equal(t, webhook.EventRoomFinished, event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+}
+
+func TestWebhookNotifierOnEvent(t *testing.T) {
+	provider := auth.NewFileBasedKeyProviderFromMap(map[string]string{"key": "secret"})
+	n, err := service.NewWebhookNotifier(config.WebHookConfig{}, provider, nil)
+	require.NoError(t, err)
+
+	// delivered in-process without any URL configured
+	var events []string
+	n.OnEvent(func(_ context.Context, event *livekit.WebhookEvent) {
+		events = append(events, event.Event)
+	})
+	require.NoError(t, n.QueueNotify(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted}))
+	require.Equal(t, []string{webhook.EventRoomStarted}, events)
+}
+
+func TestWebhookNotifierDeadLetter(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Inc()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	deadLetterFile := filepath.Join(t.TempDir(), "webhooks.jsonl")
+	provider := auth.NewFileBasedKeyProviderFromMap(map[string]string{"key": "secret"})
+	n, err := service.NewWebhookNotifier(config.WebHookConfig{
+		APIKey:          "key",
+		URLs:            []string{server.URL},
+		MaxAttempts:     3,
+		InitialBackoff:  time.Millisecond,
+		MaxBackoff:      time.Millisecond,
+		QueueSize:       10,
+		DeliveryLogSize: 10,
+		DeadLetter: config.WebHookDeadLetterConfig{
+			Kind: config.WebHookDeadLetterFile,
+			File: deadLetterFile,
+		},
+	}, provider, nil)
+	require.NoError(t, err)
+	defer n.Stop()
+
+	require.NoError(t, n.QueueNotify(context.Background(), &livekit.WebhookEvent{
+		Id:    "EV_1",
+		Event: webhook.EventRoomStarted,
+		Room:  &livekit.Room{Name: "room"},
+	}))
+
+	var delivery *service.WebhookDelivery
+	require.Eventually(t, func() bool {
+		deliveries := n.ListDeliveries("", service.WebhookDeliveryFailed, 0)
+		if len(deliveries) == 0 {
+			return false
+		}
+		delivery = deliveries[0]
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, 3, delivery.Attempts)
+	require.Equal(t, int32(3), requests.Load())
+	require.Equal(t, "room", delivery.Room)
+
+	// written once the delivery is marked as failed
+	var data []byte
+	require.Eventually(t, func() bool {
+		data, err = os.ReadFile(deadLetterFile)
+		return err == nil && len(data) != 0
+	}, 5*time.Second, 10*time.Millisecond)
+	var deadLetter service.WebhookDeadLetter
+	require.NoError(t, json.Unmarshal(data, &deadLetter))
+	require.Equal(t, delivery.ID, deadLetter.ID)
+	require.Equal(t, "EV_1", deadLetter.EventID)
+	require.Contains(t, string(deadLetter.Payload), webhook.EventRoomStarted)
+
+	_, err = n.Resend("WD_unknown")
+	require.ErrorIs(t, err, service.ErrWebhookDeliveryNotFound)
+
+	resent, err := n.Resend(delivery.ID)
+	require.NoError(t, err)
+	require.NotEqual(t, delivery.ID, resent.ID)
+	require.Eventually(t, func() bool {
+		return len(n.ListDeliveries(webhook.EventRoomStarted, service.WebhookDeliveryFailed, 0)) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Len(t, n.ListDeliveries("", "", 1), 1)
+}
+
+func TestWebhookNotifierEndpoints(t *testing.T) {
+	type received struct {
+		path  string
+		event string
+	}
+	deliveries := make(chan received, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		event := &livekit.WebhookEvent{}
+		_ = json.Unmarshal(body, event)
+		deliveries <- received{path: r.URL.Path, event: event.Event}
+	}))
+	defer server.Close()
+
+	provider := auth.NewFileBasedKeyProviderFromMap(map[string]string{"key": "secret", "media": "media_secret"})
+	n, err := service.NewWebhookNotifier(config.WebHookConfig{
+		APIKey: "key",
+		Endpoints: []config.WebHookEndpoint{
+			{URL: server.URL + "/billing", Events: []string{webhook.EventRoomStarted}},
+			{URL: server.URL + "/media", Events: []string{webhook.EventTrackPublished}, Rooms: []string{"webinar-*"}, APIKey: "media"},
+		},
+		QueueSize:       100,
+		DeliveryLogSize: 10,
+	}, provider, nil)
+	require.NoError(t, err)
+	defer n.Stop()
+
+	ctx := context.Background()
+	require.NoError(t, n.QueueNotify(ctx, &livekit.WebhookEvent{Event: webhook.EventTrackPublished, Room: &livekit.Room{Name: "call"}}))
+	require.NoError(t, n.QueueNotify(ctx, &livekit.WebhookEvent{Event: webhook.EventTrackPublished, Room: &livekit.Room{Name: "webinar-1"}}))
+	require.NoError(t, n.QueueNotify(ctx, &livekit.WebhookEvent{Event: webhook.EventRoomStarted, Room: &livekit.Room{Name: "webinar-1"}}))
+
+	var got []received
+	for len(got) < 2 {
+		select {
+		case d := <-deliveries:
+			got = append(got, d)
+		case <-time.After(5 * time.Second):
+			t.Fatal("webhook was not delivered")
+		}
+	}
+	require.ElementsMatch(t, []received{
+		{path: "/media", event: webhook.EventTrackPublished},
+		{path: "/billing", event: webhook.EventRoomStarted},
+	}, got)
+	require.Len(t, n.ListDeliveries("", "", 0), 2)
+
+	// every endpoint key must be known
+	err = n.ReloadConfig(&config.Config{WebHook: config.WebHookConfig{
+		Endpoints: []config.WebHookEndpoint{{URL: server.URL, APIKey: "unknown"}},
+	}})
+	require.ErrorIs(t, err, service.ErrWebHookMissingAPIKey)
+}
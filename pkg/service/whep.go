@@ -0,0 +1,497 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/pion/webrtc/v3"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/utils/guid"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	whepRPCService = "WHEP"
+	whepRPC        = "WHEP"
+
+	whepPlay = "play"
+	whepStop = "stop"
+)
+
+// whepCommand is carried as JSON in the payload of a user data packet, the response carries a WHEPSession
+type whepCommand struct {
+	Action string `json:"action"`
+	// proto encoded livekit.StartSession of the viewer, with its SDP offer
+	StartSession []byte `json:"start_session,omitempty"`
+	Offer        string `json:"offer,omitempty"`
+	// participant watched, and the source of the video watched
+	Participant string              `json:"participant,omitempty"`
+	Source      livekit.TrackSource `json:"source,omitempty"`
+	Identity    string              `json:"identity,omitempty"`
+	ResourceID  string              `json:"resource_id,omitempty"`
+}
+
+// WHEPSession is a viewer connected with WHEP, its resource ID is its participant SID
+type WHEPSession struct {
+	ResourceID string `json:"resource_id"`
+	Answer     string `json:"answer,omitempty"`
+}
+
+// WHEPClient reaches the node hosting a room to connect and disconnect its WHEP viewers
+type WHEPClient interface {
+	WHEP(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error)
+}
+
+type WHEPServerImpl interface {
+	StartWHEPSession(ctx context.Context, roomName livekit.RoomName, ss *livekit.StartSession, offer string, participant livekit.ParticipantIdentity, source livekit.TrackSource) (*WHEPSession, error)
+	StopWHEPSession(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, resourceID string) error
+}
+
+type whepClient struct {
+	client *client.RPCClient
+}
+
+func NewWHEPClient(params rpc.ClientParams) (WHEPClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: whepRPCService,
+		ID:   rand.NewClientID(),
+	}
+	sd.RegisterMethod(whepRPC, false, false, true, true)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &whepClient{client: rpcClient}, nil
+}
+
+func (c *whepClient) WHEP(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return client.RequestSingle[*livekit.DataPacket](ctx, c.client, whepRPC, []string{string(room)}, req, opts...)
+}
+
+// whepServer handles the WHEP sessions of a room hosted on this node
+type whepServer struct {
+	svc      WHEPServerImpl
+	roomName livekit.RoomName
+	rpc      *server.RPCServer
+}
+
+func newWHEPServer(svc WHEPServerImpl, roomName livekit.RoomName, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *whepServer {
+	sd := &info.ServiceDefinition{
+		Name: whepRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	sd.RegisterMethod(whepRPC, false, false, true, true)
+	return &whepServer{
+		svc:      svc,
+		roomName: roomName,
+		rpc:      s,
+	}
+}
+
+func (s *whepServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	return server.RegisterHandler(s.rpc, whepRPC, []string{string(room)}, s.handle, nil)
+}
+
+func (s *whepServer) handle(ctx context.Context, req *livekit.DataPacket) (*livekit.DataPacket, error) {
+	var cmd whepCommand
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &cmd); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+
+	session := &WHEPSession{ResourceID: cmd.ResourceID}
+	switch cmd.Action {
+	case whepPlay:
+		ss := &livekit.StartSession{}
+		if err := proto.Unmarshal(cmd.StartSession, ss); err != nil {
+			return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+		}
+		var err error
+		if session, err = s.svc.StartWHEPSession(ctx, s.roomName, ss, cmd.Offer, livekit.ParticipantIdentity(cmd.Participant), cmd.Source); err != nil {
+			return nil, err
+		}
+	case whepStop:
+		if err := s.svc.StopWHEPSession(ctx, s.roomName, livekit.ParticipantIdentity(cmd.Identity), cmd.ResourceID); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrWHEPInvalid
+	}
+	return encodeWHIP(session)
+}
+
+func (s *whepServer) Kill() {
+	s.rpc.Close(true)
+}
+
+// ---------------------------------------------
+
+// WHEPService serves WHEP, the WebRTC-HTTP egress protocol, so that players which can't run a client SDK watch
+// rooms. The player posts its receive only SDP offer to /whep with the access token of the viewer as bearer
+// token, the room is the one of the token. It watches the participant given with the participant query parameter,
+// or the first one publishing in the room, e.g. a composite published back into it. The video watched is the
+// camera unless the source query parameter names another one. Tracks published after the answer are not added,
+// and the player stops by deleting the resource returned in the Location header, with the same token. The answer
+// carries every candidate of the node hosting the room: trickle ICE and ICE restarts are not supported, so no
+// Accept-Patch header is sent and PATCH requests are refused.
+type WHEPService struct {
+	rtcService     *RTCService
+	store          ServiceStore
+	topicFormatter rpc.TopicFormatter
+	client         WHEPClient
+}
+
+func NewWHEPService(
+	rtcService *RTCService,
+	store ServiceStore,
+	topicFormatter rpc.TopicFormatter,
+	client WHEPClient,
+) *WHEPService {
+	return &WHEPService{
+		rtcService:     rtcService,
+		store:          store,
+		topicFormatter: topicFormatter,
+		client:         client,
+	}
+}
+
+func (s *WHEPService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resourceID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/whep"), "/")
+	switch {
+	case resourceID == "" && r.Method == http.MethodPost:
+		s.play(w, r)
+	case resourceID == "" && r.Method == http.MethodOptions:
+		w.Header().Set("Allow", "OPTIONS, POST")
+		w.Header().Set("Accept-Post", "application/sdp")
+		w.WriteHeader(http.StatusNoContent)
+	case resourceID != "" && r.Method == http.MethodDelete:
+		s.stop(w, r, resourceID)
+	case resourceID != "" && r.Method == http.MethodPatch:
+		// PATCH is how players trickle candidates and restart ICE
+		w.Header().Set("Allow", "DELETE")
+		handleError(w, r, http.StatusMethodNotAllowed, ErrWHEPPatchNotSupported)
+	default:
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (s *WHEPService) play(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/sdp" {
+		handleError(w, r, http.StatusUnsupportedMediaType, errors.New("offer must be application/sdp"))
+		return
+	}
+
+	source := livekit.TrackSource_CAMERA
+	if name := r.URL.Query().Get("source"); name != "" {
+		v, ok := livekit.TrackSource_value[strings.ToUpper(name)]
+		if !ok || livekit.TrackSource(v) == livekit.TrackSource_UNKNOWN {
+			handleError(w, r, http.StatusBadRequest, ErrWHEPInvalid)
+			return
+		}
+		source = livekit.TrackSource(v)
+	}
+	participant := r.URL.Query().Get("participant")
+
+	roomName, pi, code, err := s.rtcService.validate(r)
+	if err != nil {
+		handleValidateError(w, r, code, err)
+		return
+	}
+	if roomName == "" {
+		handleError(w, r, http.StatusBadRequest, ErrWHEPRoomMissing)
+		return
+	}
+	// viewers do not create rooms
+	if _, _, err = s.store.LoadRoom(r.Context(), roomName, false); err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+	if !pi.Grants.Video.GetCanSubscribe() {
+		handleError(w, r, http.StatusUnauthorized, rtc.ErrPermissionDenied)
+		return
+	}
+	offer, err := io.ReadAll(io.LimitReader(r.Body, maxWHIPOffer+1))
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if len(offer) > maxWHIPOffer {
+		handleError(w, r, http.StatusRequestEntityTooLarge, errors.New("offer is too large"))
+		return
+	}
+	AppendLogFields(r.Context(), "room", roomName, "participant", pi.Identity, "watched", participant, "source", source)
+
+	// players only watch, and are not shown to the room
+	pi.AutoSubscribe = false
+	pi.Grants.Video.SetCanPublish(false)
+	pi.Grants.Video.SetCanPublishData(false)
+	pi.Grants.Video.Hidden = true
+	pi.Client.Protocol = types.CurrentProtocol
+
+	ss, err := pi.ToStartSession(roomName, livekit.ConnectionID(guid.New("CO_")))
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	encoded, err := proto.Marshal(ss)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	session, err := s.send(r.Context(), roomName, &whepCommand{
+		Action:       whepPlay,
+		StartSession: encoded,
+		Offer:        string(offer),
+		Participant:  participant,
+		Source:       source,
+	})
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/sdp")
+	w.Header().Set("Location", "/whep/"+session.ResourceID)
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(session.Answer))
+}
+
+func (s *WHEPService) stop(w http.ResponseWriter, r *http.Request, resourceID string) {
+	roomName, err := EnsureJoinPermission(r.Context())
+	if err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	if roomName == "" {
+		handleError(w, r, http.StatusBadRequest, ErrWHEPRoomMissing)
+		return
+	}
+	identity := livekit.ParticipantIdentity(GetGrants(r.Context()).Identity)
+	AppendLogFields(r.Context(), "room", roomName, "participant", identity, "resourceID", resourceID)
+
+	if _, err = s.send(r.Context(), roomName, &whepCommand{Action: whepStop, Identity: string(identity), ResourceID: resourceID}); err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *WHEPService) send(ctx context.Context, roomName livekit.RoomName, cmd *whepCommand) (*WHEPSession, error) {
+	req, err := encodeWHIP(cmd)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.WHEP(ctx, s.topicFormatter.RoomTopic(ctx, roomName), req, psrpc.WithRequestTimeout(whipRequestTimeout))
+	if err != nil {
+		return nil, err
+	}
+	var session WHEPSession
+	if err = json.Unmarshal(res.GetUser().GetPayload(), &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ---------------------------------------------
+
+// StartWHEPSession joins a WHEP viewer to a room hosted on this node, subscribed to the audio and the video of the
+// watched participant. The viewer is signalled in one shot: the answer to its offer is its only signal message.
+func (r *RoomManager) StartWHEPSession(
+	ctx context.Context,
+	roomName livekit.RoomName,
+	ss *livekit.StartSession,
+	offer string,
+	watched livekit.ParticipantIdentity,
+	source livekit.TrackSource,
+) (*WHEPSession, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	pi, err := routing.ParticipantInitFromStartSession(ss, r.currentNode.Region())
+	if err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+	tracks, err := whepTracks(room, watched, source, offer)
+	if err != nil {
+		return nil, err
+	}
+
+	// the session outlives the request
+	ctx = context.WithoutCancel(ctx)
+	connID := livekit.ConnectionID(ss.ConnectionId)
+	requestSource := routing.NewDefaultMessageChannel(connID)
+	responseSink := routing.NewDefaultMessageChannel(connID)
+	if err = r.StartSession(ctx, *pi, requestSource, responseSink, true); err != nil {
+		requestSource.Close()
+		responseSink.Close()
+		return nil, psrpc.NewError(psrpc.Unavailable, err)
+	}
+	participant := room.GetParticipant(pi.Identity)
+	if participant == nil {
+		requestSource.Close()
+		responseSink.Close()
+		return nil, ErrParticipantNotFound
+	}
+	go r.whepSessionWorker(participant, requestSource, responseSink)
+
+	answer, err := r.answerWHEPOffer(participant, offer, tracks)
+	if err != nil {
+		participant.GetLogger().Warnw("could not answer whep offer", err)
+		room.RemoveParticipant(participant.Identity(), participant.ID(), types.ParticipantCloseReasonNegotiateFailed)
+		return nil, ErrWHEPInvalid
+	}
+	participant.GetLogger().Infow("whep session started", "watched", watched, "tracks", len(tracks))
+	return &WHEPSession{
+		ResourceID: string(participant.ID()),
+		Answer:     answer,
+	}, nil
+}
+
+// answerWHEPOffer subscribes the viewer to the tracks once the offer is set, so that their senders take the
+// receive only transceivers of the offer
+func (r *RoomManager) answerWHEPOffer(participant types.LocalParticipant, offer string, tracks []types.MediaTrack) (string, error) {
+	if err := participant.HandleOffer(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer}); err != nil {
+		return "", err
+	}
+	for _, track := range tracks {
+		participant.SubscribeToTrack(track.ID())
+	}
+	answer, err := participant.GetAnswer()
+	if err != nil {
+		return "", err
+	}
+	return answer.SDP, nil
+}
+
+// whepTracks returns the tracks a viewer watches, one of each kind the offer receives: the microphone of the
+// watched participant and its video of the source
+func whepTracks(room *rtc.Room, watched livekit.ParticipantIdentity, source livekit.TrackSource, offer string) ([]types.MediaTrack, error) {
+	parsed, err := (&webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer}).Unmarshal()
+	if err != nil {
+		return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+	var receiveAudio, receiveVideo bool
+	for _, m := range parsed.MediaDescriptions {
+		switch strings.ToLower(m.MediaName.Media) {
+		case "audio":
+			receiveAudio = true
+		case "video":
+			receiveVideo = true
+		}
+	}
+
+	var participants []types.LocalParticipant
+	if watched != "" {
+		p := room.GetParticipant(watched)
+		if p == nil {
+			return nil, ErrParticipantNotFound
+		}
+		participants = append(participants, p)
+	} else {
+		for _, p := range room.GetParticipants() {
+			if !p.Hidden() && len(p.GetPublishedTracks()) != 0 {
+				participants = append(participants, p)
+			}
+		}
+		slices.SortFunc(participants, func(a, b types.LocalParticipant) int {
+			return a.ConnectedAt().Compare(b.ConnectedAt())
+		})
+	}
+
+	audioSource := livekit.TrackSource_MICROPHONE
+	if source == livekit.TrackSource_SCREEN_SHARE {
+		audioSource = livekit.TrackSource_SCREEN_SHARE_AUDIO
+	}
+	for _, p := range participants {
+		var tracks []types.MediaTrack
+		for _, track := range p.GetPublishedTracks() {
+			switch {
+			case receiveAudio && track.Kind() == livekit.TrackType_AUDIO && track.Source() == audioSource:
+				receiveAudio = false
+			case receiveVideo && track.Kind() == livekit.TrackType_VIDEO && track.Source() == source:
+				receiveVideo = false
+			default:
+				continue
+			}
+			tracks = append(tracks, track)
+		}
+		if len(tracks) != 0 {
+			return tracks, nil
+		}
+	}
+	return nil, ErrTrackNotFound
+}
+
+// StopWHEPSession removes a WHEP viewer, only the participant of the resource can be removed
+func (r *RoomManager) StopWHEPSession(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, resourceID string) error {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return ErrRoomNotFound
+	}
+	participant := room.GetParticipant(identity)
+	if participant == nil || string(participant.ID()) != resourceID {
+		return ErrWHEPSessionNotFound
+	}
+
+	participant.GetLogger().Infow("whep session stopped")
+	room.RemoveParticipant(identity, participant.ID(), types.ParticipantCloseReasonClientRequestLeave)
+	return nil
+}
+
+// whepSessionWorker drops the signal responses of a WHEP viewer, which has no signal connection to read them
+func (r *RoomManager) whepSessionWorker(
+	participant types.LocalParticipant,
+	requestSource *routing.MessageChannel,
+	responseSink *routing.MessageChannel,
+) {
+	defer func() {
+		requestSource.Close()
+		responseSink.Close()
+	}()
+
+	responses := responseSink.ReadChan()
+	for {
+		select {
+		case <-participant.Disconnected():
+			return
+		case _, ok := <-responses:
+			if !ok {
+				responses = nil
+			}
+		}
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+// whepOffer returns a receive only offer with a section of each kind
+func whepOffer(kinds ...string) string {
+	lines := []string{"v=0", "o=- 0 0 IN IP4 127.0.0.1", "s=-", "t=0 0"}
+	for i, kind := range kinds {
+		lines = append(lines,
+			"m="+kind+" 9 UDP/TLS/RTP/SAVPF 96",
+			"c=IN IP4 0.0.0.0",
+			"a=mid:"+string(rune('0'+i)),
+			"a=recvonly",
+		)
+	}
+	return strings.Join(append(lines, ""), "\r\n")
+}
+
+func TestWHEPTracks(t *testing.T) {
+	newPublisher := func(room *rtc.Room, identity livekit.ParticipantIdentity, hidden bool, connectedAt time.Time, sources ...livekit.TrackSource) []types.MediaTrack {
+		p := rtc.NewMockParticipant(identity, types.CurrentProtocol, hidden, true)
+		p.ConnectedAtReturns(connectedAt)
+		var tracks []types.MediaTrack
+		for _, source := range sources {
+			kind := livekit.TrackType_VIDEO
+			if source == livekit.TrackSource_MICROPHONE || source == livekit.TrackSource_SCREEN_SHARE_AUDIO {
+				kind = livekit.TrackType_AUDIO
+			}
+			track := rtc.NewMockTrack(kind, source.String())
+			track.SourceReturns(source)
+			tracks = append(tracks, track)
+		}
+		p.GetPublishedTracksReturns(tracks)
+		require.NoError(t, room.Join(p, nil, nil, nil))
+		return tracks
+	}
+	trackIDs := func(tracks []types.MediaTrack) []livekit.TrackID {
+		var ids []livekit.TrackID
+		for _, track := range tracks {
+			ids = append(ids, track.ID())
+		}
+		return ids
+	}
+
+	room := newTestRoom("main")
+	defer room.Close(types.ParticipantCloseReasonNone)
+	now := time.Now()
+	// joined first, but connected last
+	late := newPublisher(room, "late", false, now, livekit.TrackSource_MICROPHONE, livekit.TrackSource_CAMERA)
+	first := newPublisher(room, "first", false, now.Add(-time.Minute),
+		livekit.TrackSource_MICROPHONE,
+		livekit.TrackSource_CAMERA,
+		livekit.TrackSource_SCREEN_SHARE_AUDIO,
+		livekit.TrackSource_SCREEN_SHARE,
+	)
+	newPublisher(room, "hidden", true, now.Add(-time.Hour), livekit.TrackSource_MICROPHONE, livekit.TrackSource_CAMERA)
+	newPublisher(room, "video", false, now, livekit.TrackSource_CAMERA)
+
+	tests := []struct {
+		name    string
+		watched livekit.ParticipantIdentity
+		source  livekit.TrackSource
+		offer   string
+		tracks  []types.MediaTrack
+		err     error
+		code    psrpc.ErrorCode
+	}{
+		{
+			name:   "first connected publisher",
+			source: livekit.TrackSource_CAMERA,
+			offer:  whepOffer("audio", "video"),
+			tracks: first[:2],
+		},
+		{
+			name:    "watched participant",
+			watched: "late",
+			source:  livekit.TrackSource_CAMERA,
+			offer:   whepOffer("audio", "video"),
+			tracks:  late,
+		},
+		{
+			name:   "screen share with its audio",
+			source: livekit.TrackSource_SCREEN_SHARE,
+			offer:  whepOffer("audio", "video"),
+			tracks: first[2:],
+		},
+		{
+			name:   "audio only offer",
+			source: livekit.TrackSource_CAMERA,
+			offer:  whepOffer("audio"),
+			tracks: first[:1],
+		},
+		{
+			name:   "video only offer",
+			source: livekit.TrackSource_SCREEN_SHARE,
+			offer:  whepOffer("video"),
+			tracks: first[3:],
+		},
+		{
+			name:    "watched participant without audio",
+			watched: "video",
+			source:  livekit.TrackSource_CAMERA,
+			offer:   whepOffer("audio"),
+			err:     ErrTrackNotFound,
+		},
+		{
+			name:    "unknown participant",
+			watched: "unknown",
+			source:  livekit.TrackSource_CAMERA,
+			offer:   whepOffer("audio", "video"),
+			err:     ErrParticipantNotFound,
+		},
+		{
+			name:   "invalid offer",
+			source: livekit.TrackSource_CAMERA,
+			offer:  "offer",
+			code:   psrpc.InvalidArgument,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracks, err := whepTracks(room, test.watched, test.source, test.offer)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				return
+			}
+			if test.code != "" {
+				var psrpcErr psrpc.Error
+				require.ErrorAs(t, err, &psrpcErr)
+				require.Equal(t, test.code, psrpcErr.Code())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, trackIDs(test.tracks), trackIDs(tracks))
+		})
+	}
+}
+
+func TestWHEPService(t *testing.T) {
+	offer := whepOffer("audio", "video")
+	router := &routingfakes.FakeRouter{}
+	router.GetNodeForRoomReturns(nil, routing.ErrNotFound)
+	rtcService := NewRTCService(&config.Config{}, &testRoomAllocator{}, nil, router, nil, &telemetryfakes.FakeTelemetryService{}, nil, nil)
+	store := NewLocalStore()
+	require.NoError(t, store.StoreRoom(context.Background(), &livekit.Room{Name: "main"}, nil))
+	newService := func(svc WHEPServerImpl) *WHEPService {
+		return NewWHEPService(rtcService, store, rpc.NewTopicFormatter(), &testWHEPClient{server: &whepServer{svc: svc, roomName: "main"}})
+	}
+	serve := func(s *WHEPService, method, path, body string, grants *auth.ClaimGrants) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/sdp")
+		r = r.WithContext(WithGrants(r.Context(), grants, "APIkey"))
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+	grants := func(identity, room string, canSubscribe bool) *auth.ClaimGrants {
+		video := &auth.VideoGrant{RoomJoin: true, Room: room}
+		video.SetCanSubscribe(canSubscribe)
+		return &auth.ClaimGrants{Identity: identity, Video: video}
+	}
+
+	t.Run("plays as a hidden viewer", func(t *testing.T) {
+		svc := &testWHEPServerImpl{}
+		w := serve(newService(svc), http.MethodPost, "/whep?participant=speaker&source=screen_share", offer, grants("viewer", "main", true))
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.Equal(t, "/whep/PA_viewer", w.Header().Get("Location"))
+		require.Equal(t, "answer", w.Body.String())
+
+		require.Equal(t, offer, svc.offer)
+		require.Equal(t, livekit.ParticipantIdentity("speaker"), svc.watched)
+		require.Equal(t, livekit.TrackSource_SCREEN_SHARE, svc.source)
+		pi, err := routing.ParticipantInitFromStartSession(svc.session, "")
+		require.NoError(t, err)
+		require.False(t, pi.AutoSubscribe)
+		require.False(t, pi.Grants.Video.GetCanPublish())
+		require.False(t, pi.Grants.Video.GetCanPublishData())
+		require.True(t, pi.Grants.Video.Hidden)
+	})
+
+	t.Run("requires canSubscribe", func(t *testing.T) {
+		svc := &testWHEPServerImpl{}
+		w := serve(newService(svc), http.MethodPost, "/whep", offer, grants("viewer", "main", false))
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		require.Nil(t, svc.session)
+	})
+
+	t.Run("does not create rooms", func(t *testing.T) {
+		svc := &testWHEPServerImpl{}
+		w := serve(newService(svc), http.MethodPost, "/whep", offer, grants("viewer", "other", true))
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.Nil(t, svc.session)
+	})
+
+	t.Run("refuses unknown sources", func(t *testing.T) {
+		svc := &testWHEPServerImpl{}
+		w := serve(newService(svc), http.MethodPost, "/whep?source=unknown", offer, grants("viewer", "main", true))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Nil(t, svc.session)
+	})
+
+	t.Run("does not support trickle ice", func(t *testing.T) {
+		s := newService(&testWHEPServerImpl{})
+		w := serve(s, http.MethodOptions, "/whep", "", grants("viewer", "main", true))
+		require.Equal(t, http.StatusNoContent, w.Code)
+		require.Empty(t, w.Header().Get("Accept-Patch"))
+
+		w = serve(s, http.MethodPatch, "/whep/PA_viewer", "", grants("viewer", "main", true))
+		require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		require.Equal(t, "DELETE", w.Header().Get("Allow"))
+	})
+
+	t.Run("stops the session of the token only", func(t *testing.T) {
+		room := newTestRoom("main")
+		defer room.Close(types.ParticipantCloseReasonNone)
+		viewer := rtc.NewMockParticipant("viewer", types.CurrentProtocol, true, false)
+		other := rtc.NewMockParticipant("other", types.CurrentProtocol, true, false)
+		require.NoError(t, room.Join(viewer, nil, nil, nil))
+		require.NoError(t, room.Join(other, nil, nil, nil))
+		s := newService(&RoomManager{rooms: map[livekit.RoomName]*rtc.Room{"main": room}})
+
+		w := serve(s, http.MethodDelete, "/whep/"+string(other.ID()), "", grants("viewer", "main", true))
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.NotNil(t, room.GetParticipant("other"))
+
+		// the room of the session is the one of the token
+		w = serve(s, http.MethodDelete, "/whep/"+string(viewer.ID()), "", grants("viewer", "", true))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.NotNil(t, room.GetParticipant("viewer"))
+
+		w = serve(s, http.MethodDelete, "/whep/"+string(viewer.ID()), "", grants("viewer", "main", true))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Nil(t, room.GetParticipant("viewer"))
+		require.NotNil(t, room.GetParticipant("other"))
+	})
+}
+
+// testWHEPClient reaches the node of the room in process
+type testWHEPClient struct {
+	server *whepServer
+}
+
+func (c *testWHEPClient) WHEP(ctx context.Context, _ rpc.RoomTopic, req *livekit.DataPacket, _ ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return c.server.handle(ctx, req)
+}
+
+type testWHEPServerImpl struct {
+	session *livekit.StartSession
+	offer   string
+	watched livekit.ParticipantIdentity
+	source  livekit.TrackSource
+}
+
+func (s *testWHEPServerImpl) StartWHEPSession(
+	_ context.Context,
+	_ livekit.RoomName,
+	ss *livekit.StartSession,
+	offer string,
+	watched livekit.ParticipantIdentity,
+	source livekit.TrackSource,
+) (*WHEPSession, error) {
+	s.session, s.offer, s.watched, s.source = ss, offer, watched, source
+	return &WHEPSession{ResourceID: "PA_" + ss.Identity, Answer: "answer"}, nil
+}
+
+func (s *testWHEPServerImpl) StopWHEPSession(context.Context, livekit.RoomName, livekit.ParticipantIdentity, string) error {
+	return ErrWHEPSessionNotFound
+}
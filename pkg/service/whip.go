@@ -0,0 +1,450 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/utils/guid"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	whipRPCService = "WHIP"
+	whipRPC        = "WHIP"
+
+	whipPublish = "publish"
+	whipStop    = "stop"
+
+	maxWHIPOffer = 64 * 1024
+	// the answer is sent once the candidates of the node are gathered
+	whipRequestTimeout      = 10 * time.Second
+	whipStreamCheckInterval = time.Second
+
+	// EventWHIPStreamStarted is sent when the first track of a WHIP publisher is published
+	EventWHIPStreamStarted = "whip_stream_started"
+	// EventWHIPStreamEnded is sent when a WHIP publisher leaves, whether it deleted its session or disconnected
+	EventWHIPStreamEnded = "whip_stream_ended"
+)
+
+// whipCommand is carried as JSON in the payload of a user data packet, the response carries a WHIPSession
+type whipCommand struct {
+	Action string `json:"action"`
+	// proto encoded livekit.StartSession of the publisher, with its SDP offer
+	StartSession []byte `json:"start_session,omitempty"`
+	Offer        string `json:"offer,omitempty"`
+	Identity     string `json:"identity,omitempty"`
+	ResourceID   string `json:"resource_id,omitempty"`
+}
+
+// WHIPSession is a publisher connected with WHIP, its resource ID is its participant SID
+type WHIPSession struct {
+	ResourceID string `json:"resource_id"`
+	Answer     string `json:"answer,omitempty"`
+}
+
+// WHIPClient reaches the node hosting a room to connect and disconnect its WHIP publishers
+type WHIPClient interface {
+	WHIP(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error)
+}
+
+type WHIPServerImpl interface {
+	StartWHIPSession(ctx context.Context, roomName livekit.RoomName, ss *livekit.StartSession, offer string) (*WHIPSession, error)
+	StopWHIPSession(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, resourceID string) error
+}
+
+type whipClient struct {
+	client *client.RPCClient
+}
+
+func NewWHIPClient(params rpc.ClientParams) (WHIPClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: whipRPCService,
+		ID:   rand.NewClientID(),
+	}
+	sd.RegisterMethod(whipRPC, false, false, true, true)
+
+	bus, opts := params.Args()
+	rpcClient, err := client.NewRPCClient(sd, bus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &whipClient{client: rpcClient}, nil
+}
+
+func (c *whipClient) WHIP(ctx context.Context, room rpc.RoomTopic, req *livekit.DataPacket, opts ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return client.RequestSingle[*livekit.DataPacket](ctx, c.client, whipRPC, []string{string(room)}, req, opts...)
+}
+
+// whipServer handles the WHIP sessions of a room hosted on this node
+type whipServer struct {
+	svc      WHIPServerImpl
+	roomName livekit.RoomName
+	rpc      *server.RPCServer
+}
+
+func newWHIPServer(svc WHIPServerImpl, roomName livekit.RoomName, bus psrpc.MessageBus, opts ...psrpc.ServerOption) *whipServer {
+	sd := &info.ServiceDefinition{
+		Name: whipRPCService,
+		ID:   rand.NewServerID(),
+	}
+	s := server.NewRPCServer(sd, bus, opts...)
+	sd.RegisterMethod(whipRPC, false, false, true, true)
+	return &whipServer{
+		svc:      svc,
+		roomName: roomName,
+		rpc:      s,
+	}
+}
+
+func (s *whipServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	return server.RegisterHandler(s.rpc, whipRPC, []string{string(room)}, s.handle, nil)
+}
+
+func (s *whipServer) handle(ctx context.Context, req *livekit.DataPacket) (*livekit.DataPacket, error) {
+	var cmd whipCommand
+	if err := json.Unmarshal(req.GetUser().GetPayload(), &cmd); err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+
+	session := &WHIPSession{ResourceID: cmd.ResourceID}
+	switch cmd.Action {
+	case whipPublish:
+		ss := &livekit.StartSession{}
+		if err := proto.Unmarshal(cmd.StartSession, ss); err != nil {
+			return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+		}
+		var err error
+		if session, err = s.svc.StartWHIPSession(ctx, s.roomName, ss, cmd.Offer); err != nil {
+			return nil, err
+		}
+	case whipStop:
+		if err := s.svc.StopWHIPSession(ctx, s.roomName, livekit.ParticipantIdentity(cmd.Identity), cmd.ResourceID); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrWHIPInvalid
+	}
+	return encodeWHIP(session)
+}
+
+func (s *whipServer) Kill() {
+	s.rpc.Close(true)
+}
+
+func encodeWHIP(v any) (*livekit.DataPacket, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{Payload: payload},
+		},
+	}, nil
+}
+
+// ---------------------------------------------
+
+// WHIPService serves WHIP, the WebRTC-HTTP ingestion protocol, so that OBS and hardware encoders publish into
+// rooms without an ingress deployment. The encoder posts its SDP offer to /whip with the access token of the
+// publisher as bearer token, the room is the one of the token. The answer carries every candidate of the node
+// hosting the room, trickle ICE and ICE restarts are not supported. Published tracks are named after the
+// participant name of the token, its identity when it has none. The encoder leaves the room by deleting the
+// resource returned in the Location header, with the same token.
+type WHIPService struct {
+	rtcService     *RTCService
+	roomAllocator  RoomAllocator
+	router         routing.MessageRouter
+	topicFormatter rpc.TopicFormatter
+	client         WHIPClient
+}
+
+func NewWHIPService(
+	rtcService *RTCService,
+	roomAllocator RoomAllocator,
+	router routing.MessageRouter,
+	topicFormatter rpc.TopicFormatter,
+	client WHIPClient,
+) *WHIPService {
+	return &WHIPService{
+		rtcService:     rtcService,
+		roomAllocator:  roomAllocator,
+		router:         router,
+		topicFormatter: topicFormatter,
+		client:         client,
+	}
+}
+
+func (s *WHIPService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resourceID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/whip"), "/")
+	switch {
+	case resourceID == "" && r.Method == http.MethodPost:
+		s.publish(w, r)
+	case resourceID == "" && r.Method == http.MethodOptions:
+		w.Header().Set("Accept-Post", "application/sdp")
+		w.WriteHeader(http.StatusNoContent)
+	case resourceID != "" && r.Method == http.MethodDelete:
+		s.stop(w, r, resourceID)
+	default:
+		// PATCH is how encoders trickle candidates and restart ICE
+		handleError(w, r, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (s *WHIPService) publish(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/sdp" {
+		handleError(w, r, http.StatusUnsupportedMediaType, errors.New("offer must be application/sdp"))
+		return
+	}
+
+	roomName, pi, code, err := s.rtcService.validate(r)
+	if err != nil {
+		handleValidateError(w, r, code, err)
+		return
+	}
+	if roomName == "" {
+		handleError(w, r, http.StatusBadRequest, ErrWHIPRoomMissing)
+		return
+	}
+	if !pi.Grants.Video.GetCanPublish() {
+		handleError(w, r, http.StatusUnauthorized, rtc.ErrPermissionDenied)
+		return
+	}
+	offer, err := io.ReadAll(io.LimitReader(r.Body, maxWHIPOffer+1))
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if len(offer) > maxWHIPOffer {
+		handleError(w, r, http.StatusRequestEntityTooLarge, errors.New("offer is too large"))
+		return
+	}
+	AppendLogFields(r.Context(), "room", roomName, "participant", pi.Identity)
+
+	// encoders only publish
+	pi.AutoSubscribe = false
+	pi.Grants.Video.SetCanSubscribe(false)
+	pi.Client.Protocol = types.CurrentProtocol
+
+	session, err := s.StartSession(r.Context(), roomName, pi, string(offer))
+	if err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/sdp")
+	w.Header().Set("Location", "/whip/"+session.ResourceID)
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(session.Answer))
+}
+
+// StartSession connects a publisher on the node hosting its room, creating the room when needed
+func (s *WHIPService) StartSession(ctx context.Context, roomName livekit.RoomName, pi routing.ParticipantInit, offer string) (*WHIPSession, error) {
+	if err := s.roomAllocator.SelectRoomNode(ctx, roomName, ""); err != nil {
+		return nil, err
+	}
+	if _, err := s.router.CreateRoom(ctx, pi.CreateRoom); err != nil {
+		return nil, err
+	}
+
+	ss, err := pi.ToStartSession(roomName, livekit.ConnectionID(guid.New("CO_")))
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := proto.Marshal(ss)
+	if err != nil {
+		return nil, err
+	}
+	return s.send(ctx, roomName, &whipCommand{Action: whipPublish, StartSession: encoded, Offer: offer})
+}
+
+func (s *WHIPService) stop(w http.ResponseWriter, r *http.Request, resourceID string) {
+	roomName, err := EnsureJoinPermission(r.Context())
+	if err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	if roomName == "" {
+		handleError(w, r, http.StatusBadRequest, ErrWHIPRoomMissing)
+		return
+	}
+	identity := livekit.ParticipantIdentity(GetGrants(r.Context()).Identity)
+	AppendLogFields(r.Context(), "room", roomName, "participant", identity, "resourceID", resourceID)
+
+	if _, err = s.send(r.Context(), roomName, &whipCommand{Action: whipStop, Identity: string(identity), ResourceID: resourceID}); err != nil {
+		handleError(w, r, apiErrorStatus(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *WHIPService) send(ctx context.Context, roomName livekit.RoomName, cmd *whipCommand) (*WHIPSession, error) {
+	req, err := encodeWHIP(cmd)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.WHIP(ctx, s.topicFormatter.RoomTopic(ctx, roomName), req, psrpc.WithRequestTimeout(whipRequestTimeout))
+	if err != nil {
+		return nil, err
+	}
+	var session WHIPSession
+	if err = json.Unmarshal(res.GetUser().GetPayload(), &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ---------------------------------------------
+
+// StartWHIPSession joins a WHIP publisher to a room hosted on this node. The publisher is signalled in one shot:
+// the answer to its offer is its only signal message.
+func (r *RoomManager) StartWHIPSession(ctx context.Context, roomName livekit.RoomName, ss *livekit.StartSession, offer string) (*WHIPSession, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	pi, err := routing.ParticipantInitFromStartSession(ss, r.currentNode.Region())
+	if err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+
+	// the session outlives the request
+	ctx = context.WithoutCancel(ctx)
+	connID := livekit.ConnectionID(ss.ConnectionId)
+	requestSource := routing.NewDefaultMessageChannel(connID)
+	responseSink := routing.NewDefaultMessageChannel(connID)
+	if err = r.StartSession(ctx, *pi, requestSource, responseSink, true); err != nil {
+		requestSource.Close()
+		responseSink.Close()
+		return nil, psrpc.NewError(psrpc.Unavailable, err)
+	}
+	participant := room.GetParticipant(pi.Identity)
+	if participant == nil {
+		requestSource.Close()
+		responseSink.Close()
+		return nil, ErrParticipantNotFound
+	}
+	go r.whipStreamWorker(ctx, room, participant, requestSource, responseSink)
+
+	answer, err := r.answerWHIPOffer(participant, offer)
+	if err != nil {
+		participant.GetLogger().Warnw("could not answer whip offer", err)
+		room.RemoveParticipant(participant.Identity(), participant.ID(), types.ParticipantCloseReasonNegotiateFailed)
+		if errors.Is(err, rtc.ErrUnsupportedSimulcastRid) {
+			return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+		}
+		return nil, ErrWHIPInvalid
+	}
+	participant.GetLogger().Infow("whip session started")
+	return &WHIPSession{
+		ResourceID: string(participant.ID()),
+		Answer:     answer,
+	}, nil
+}
+
+func (r *RoomManager) answerWHIPOffer(participant types.LocalParticipant, offer string) (string, error) {
+	if err := participant.HandleOffer(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer}); err != nil {
+		return "", err
+	}
+	answer, err := participant.GetAnswer()
+	if err != nil {
+		return "", err
+	}
+	return answer.SDP, nil
+}
+
+// StopWHIPSession removes a WHIP publisher, only the participant of the resource can be removed
+func (r *RoomManager) StopWHIPSession(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, resourceID string) error {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return ErrRoomNotFound
+	}
+	participant := room.GetParticipant(identity)
+	if participant == nil || string(participant.ID()) != resourceID {
+		return ErrWHIPSessionNotFound
+	}
+
+	participant.GetLogger().Infow("whip session stopped")
+	room.RemoveParticipant(identity, participant.ID(), types.ParticipantCloseReasonClientRequestLeave)
+	return nil
+}
+
+// whipStreamWorker drops the signal responses of a WHIP publisher, which has no signal connection to read them,
+// and sends the webhooks of its stream state
+func (r *RoomManager) whipStreamWorker(
+	ctx context.Context,
+	room *rtc.Room,
+	participant types.LocalParticipant,
+	requestSource *routing.MessageChannel,
+	responseSink *routing.MessageChannel,
+) {
+	defer func() {
+		requestSource.Close()
+		responseSink.Close()
+		r.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event:       EventWHIPStreamEnded,
+			Room:        room.ToProto(),
+			Participant: participant.ToProto(),
+		})
+	}()
+
+	ticker := time.NewTicker(whipStreamCheckInterval)
+	defer ticker.Stop()
+
+	responses := responseSink.ReadChan()
+	started := false
+	for {
+		select {
+		case <-participant.Disconnected():
+			return
+		case _, ok := <-responses:
+			if !ok {
+				responses = nil
+			}
+		case <-ticker.C:
+			if started || len(participant.GetPublishedTracks()) == 0 {
+				continue
+			}
+			started = true
+			r.telemetry.NotifyEvent(ctx, &livekit.WebhookEvent{
+				Event:       EventWHIPStreamStarted,
+				Room:        room.ToProto(),
+				Participant: participant.ToProto(),
+			})
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/audio"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestWHIPService(t *testing.T) {
+	const offer = "v=0\r\n"
+
+	router := &routingfakes.FakeRouter{}
+	router.GetNodeForRoomReturns(nil, routing.ErrNotFound)
+	allocator := &testRoomAllocator{}
+	rtcService := NewRTCService(&config.Config{}, allocator, nil, router, nil, &telemetryfakes.FakeTelemetryService{}, nil, nil)
+	newService := func(svc WHIPServerImpl) *WHIPService {
+		return NewWHIPService(rtcService, allocator, router, rpc.NewTopicFormatter(), &testWHIPClient{server: &whipServer{svc: svc, roomName: "main"}})
+	}
+	serve := func(s *WHIPService, method, path, contentType, body string, grants *auth.ClaimGrants) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		r = r.WithContext(WithGrants(r.Context(), grants, "APIkey"))
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+	grants := func(identity string, canPublish bool) *auth.ClaimGrants {
+		video := &auth.VideoGrant{RoomJoin: true, Room: "main"}
+		video.SetCanPublish(canPublish)
+		return &auth.ClaimGrants{Identity: identity, Video: video}
+	}
+
+	t.Run("publishes", func(t *testing.T) {
+		svc := &testWHIPServerImpl{}
+		w := serve(newService(svc), http.MethodPost, "/whip", "application/sdp", offer, grants("encoder", true))
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.Equal(t, "/whip/PA_encoder", w.Header().Get("Location"))
+		require.Equal(t, "application/sdp", w.Header().Get("Content-Type"))
+		require.Equal(t, "answer", w.Body.String())
+
+		require.Equal(t, offer, svc.offer)
+		require.Equal(t, "encoder", svc.session.Identity)
+		require.False(t, svc.session.AutoSubscribe)
+		pi, err := routing.ParticipantInitFromStartSession(svc.session, "")
+		require.NoError(t, err)
+		require.False(t, pi.Grants.Video.GetCanSubscribe())
+	})
+
+	t.Run("requires canPublish", func(t *testing.T) {
+		svc := &testWHIPServerImpl{}
+		w := serve(newService(svc), http.MethodPost, "/whip", "application/sdp", offer, grants("encoder", false))
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		require.Nil(t, svc.session)
+	})
+
+	t.Run("requires an sdp offer", func(t *testing.T) {
+		svc := &testWHIPServerImpl{}
+		w := serve(newService(svc), http.MethodPost, "/whip", "application/json", "{}", grants("encoder", true))
+		require.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+		require.Nil(t, svc.session)
+	})
+
+	t.Run("refuses oversized offers", func(t *testing.T) {
+		svc := &testWHIPServerImpl{}
+		w := serve(newService(svc), http.MethodPost, "/whip", "application/sdp", offer+strings.Repeat("a", maxWHIPOffer), grants("encoder", true))
+		require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		require.Nil(t, svc.session)
+	})
+
+	t.Run("stops the session of the token only", func(t *testing.T) {
+		room := newTestRoom("main")
+		defer room.Close(types.ParticipantCloseReasonNone)
+		encoder := rtc.NewMockParticipant("encoder", types.CurrentProtocol, false, true)
+		other := rtc.NewMockParticipant("other", types.CurrentProtocol, false, true)
+		require.NoError(t, room.Join(encoder, nil, nil, nil))
+		require.NoError(t, room.Join(other, nil, nil, nil))
+		s := newService(&RoomManager{rooms: map[livekit.RoomName]*rtc.Room{"main": room}})
+
+		w := serve(s, http.MethodDelete, "/whip/"+string(other.ID()), "", "", grants("encoder", true))
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.NotNil(t, room.GetParticipant("other"))
+
+		w = serve(s, http.MethodDelete, "/whip/"+string(encoder.ID()), "", "", grants("encoder", true))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Nil(t, room.GetParticipant("encoder"))
+		require.NotNil(t, room.GetParticipant("other"))
+	})
+}
+
+func newTestRoom(name livekit.RoomName) *rtc.Room {
+	return rtc.NewRoom(
+		&livekit.Room{Name: string(name)},
+		nil,
+		rtc.WebRTCConfig{},
+		config.RoomConfig{EmptyTimeout: 5 * 60, DepartureTimeout: 1},
+		&sfu.AudioConfig{AudioLevelConfig: audio.AudioLevelConfig{UpdateInterval: 500}},
+		&livekit.ServerInfo{},
+		&telemetryfakes.FakeTelemetryService{},
+		nil, nil, nil,
+	)
+}
+
+type testRoomAllocator struct{}
+
+func (a *testRoomAllocator) AutoCreateEnabled(context.Context) bool { return true }
+
+func (a *testRoomAllocator) SelectRoomNode(context.Context, livekit.RoomName, livekit.NodeID) error {
+	return nil
+}
+
+func (a *testRoomAllocator) CreateRoom(_ context.Context, req *livekit.CreateRoomRequest, _ bool) (*livekit.Room, *livekit.RoomInternal, bool, error) {
+	return &livekit.Room{Name: req.Name}, nil, true, nil
+}
+
+func (a *testRoomAllocator) ValidateCreateRoom(context.Context, livekit.RoomName) error { return nil }
+
+// testWHIPClient reaches the node of the room in process
+type testWHIPClient struct {
+	server *whipServer
+}
+
+func (c *testWHIPClient) WHIP(ctx context.Context, _ rpc.RoomTopic, req *livekit.DataPacket, _ ...psrpc.RequestOption) (*livekit.DataPacket, error) {
+	return c.server.handle(ctx, req)
+}
+
+type testWHIPServerImpl struct {
+	session *livekit.StartSession
+	offer   string
+}
+
+func (s *testWHIPServerImpl) StartWHIPSession(_ context.Context, _ livekit.RoomName, ss *livekit.StartSession, offer string) (*WHIPSession, error) {
+	s.session, s.offer = ss, offer
+	return &WHIPSession{ResourceID: "PA_" + ss.Identity, Answer: "answer"}, nil
+}
+
+func (s *testWHIPServerImpl) StopWHIPSession(context.Context, livekit.RoomName, livekit.ParticipantIdentity, string) error {
+	return ErrWHIPSessionNotFound
+}
@@ -22,15 +22,18 @@ import (
 	"os"
 
 	"github.com/google/wire"
+	"github.com/nats-io/nats.go"
 	"github.com/pion/turn/v2"
 	"github.com/pkg/errors"
 	"github.com/redis/go-redis/v9"
 	"gopkg.in/yaml.v3"
 
 	"github.com/livekit/livekit-server/pkg/agent"
+	"github.com/livekit/livekit-server/pkg/archiver"
 	"github.com/livekit/livekit-server/pkg/clientconfiguration"
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/protocol/auth"
@@ -43,14 +46,17 @@ import (
 	"github.com/livekit/psrpc"
 )
 
-func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*LivekitServer, error) {
+func InitializeServer(conf *config.Config, currentNode routing.LocalNode, hooks *ServerHooks) (*LivekitServer, error) {
 	wire.Build(
 		getNodeID,
+		NewInternalTLS,
 		createRedisClient,
+		createNatsConn,
 		createStore,
 		wire.Bind(new(ServiceStore), new(ObjectStore)),
 		createKeyProvider,
 		createWebhookNotifier,
+		wire.Bind(new(webhook.QueuedNotifier), new(*WebhookNotifier)),
 		createClientConfiguration,
 		createForwardStats,
 		routing.CreateRouter,
@@ -58,7 +64,7 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		config.DefaultAPIConfig,
 		wire.Bind(new(routing.MessageRouter), new(routing.Router)),
 		wire.Bind(new(livekit.RoomService), new(*RoomService)),
-		telemetry.NewAnalyticsService,
+		createAnalyticsService,
 		telemetry.NewTelemetryService,
 		getMessageBus,
 		NewIOInfoService,
@@ -70,16 +76,91 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		NewEgressService,
 		getIngressStore,
 		getIngressConfig,
+		getIngressHealthStore,
 		NewIngressService,
+		NewIngressHealthService,
 		rpc.NewSIPClient,
 		getSIPStore,
 		getSIPConfig,
 		NewSIPService,
+		getSIPCallRecordStore,
+		NewSIPCallRecorder,
+		NewSIPUsageService,
+		NewSIPUpdateService,
+		NewPolicyWebhook,
+		selector.NewNodeLatencies,
 		NewRoomAllocator,
 		NewRoomService,
 		NewRTCService,
+		NewWHIPClient,
+		NewWHIPService,
+		NewWHEPClient,
+		NewWHEPService,
 		NewAgentService,
 		NewAgentDispatchService,
+		NewVirtualParticipantClient,
+		NewVirtualParticipantService,
+		NewSharedPlaybackClient,
+		NewSharedPlaybackService,
+		NewWaitingRoomClient,
+		NewWaitingRoomService,
+		getRoomScheduleStore,
+		NewRoomScheduleService,
+		getBreakoutStore,
+		NewBreakoutClient,
+		NewBreakoutService,
+		NewRoomMergeService,
+		NewAPIKeyService,
+		getTokenRevocationStore,
+		getRTMPStreamStore,
+		NewTokenRevocations,
+		NewTokenRevocationService,
+		NewOIDCVerifier,
+		NewParticipantRoleClient,
+		NewParticipantRoleService,
+		NewTrackForwardClient,
+		NewTrackForwardService,
+		NewTrackRecordingClient,
+		NewTrackRecordingService,
+		NewRTMPStreamService,
+		getRoomTemplateStore,
+		NewRoomTemplateService,
+		NewBulkParticipantsClient,
+		NewBulkParticipantsService,
+		NewRTPIngestClient,
+		NewRoomRelayClient,
+		NewRTPIngestService,
+		NewParticipantListService,
+		NewRecordingConsentClient,
+		NewRecordingConsentService,
+		NewBandwidthPolicyClient,
+		NewBandwidthPolicyService,
+		NewRoomDebugClient,
+		NewRoomDebugService,
+		NewConformanceService,
+		NewClientDiagnosticsService,
+		NewClientEventsClient,
+		NewClientEventsService,
+		NewRoomStatsClient,
+		NewRoomStatsService,
+		NewRecordingStateClient,
+		NewAbuseDetector,
+		NewSignalRateLimiter,
+		NewIPFilter,
+		NewAbuseService,
+		NewQuotaEnforcer,
+		archiver.NewArchiver,
+		NewAdminLimiter,
+		NewRoomArchiveService,
+		getSessionStore,
+		NewSessionRecorder,
+		NewSessionService,
+		NewNodeAdminService,
+		NewNodeDrainer,
+		NewRegionSettingsService,
+		NewNodeLatencyProber,
+		NewClockSkewMonitor,
+		NewConfigReloader,
 		agent.NewAgentClient,
 		getAgentStore,
 		getSignalRelayConfig,
@@ -95,6 +176,7 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		rpc.NewTypedParticipantClient,
 		rpc.NewTypedAgentDispatchInternalClient,
 		NewLocalRoomManager,
+		NewTURNSessions,
 		NewTURNAuthHandler,
 		getTURNAuthHandlerFunc,
 		newInProcessTurnServer,
@@ -106,7 +188,9 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 
 func InitializeRouter(conf *config.Config, currentNode routing.LocalNode) (routing.Router, error) {
 	wire.Build(
+		NewInternalTLS,
 		createRedisClient,
+		createNatsConn,
 		getNodeID,
 		getMessageBus,
 		getSignalRelayConfig,
@@ -126,7 +210,7 @@ func getNodeID(currentNode routing.LocalNode) livekit.NodeID {
 	return currentNode.NodeID()
 }
 
-func createKeyProvider(conf *config.Config) (auth.KeyProvider, error) {
+func createKeyProvider(conf *config.Config, objectStore ObjectStore) (auth.KeyProvider, error) {
 	// prefer keyfile if set
 	if conf.KeyFile != "" {
 		var otherFilter os.FileMode = 0007
@@ -152,37 +236,88 @@ func createKeyProvider(conf *config.Config) (auth.KeyProvider, error) {
 		return nil, errors.New("one of key-file or keys must be provided in order to support a secure installation")
 	}
 
-	return auth.NewFileBasedKeyProviderFromMap(conf.Keys), nil
+	provider := auth.NewFileBasedKeyProviderFromMap(conf.Keys)
+	if !conf.KeyManagement.Enabled {
+		return provider, nil
+	}
+	store := getAPIKeyStore(objectStore)
+	if store == nil {
+		return nil, errors.New("key management is not supported by the store")
+	}
+	return NewManagedKeyProvider(provider, store, conf.KeyManagement.CacheTTL), nil
 }
 
-func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider) (webhook.QueuedNotifier, error) {
-	wc := conf.WebHook
-	if len(wc.URLs) == 0 {
-		return nil, nil
+func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider, hooks *ServerHooks, rc redis.UniversalClient) (*WebhookNotifier, error) {
+	n, err := NewWebhookNotifier(conf.WebHook, provider, rc)
+	if err != nil {
+		return nil, err
 	}
-	secret := provider.GetSecret(wc.APIKey)
-	if secret == "" {
-		return nil, ErrWebHookMissingAPIKey
+	if hooks != nil && hooks.Webhook != nil {
+		n.OnEvent(hooks.Webhook)
 	}
+	return n, nil
+}
 
-	return webhook.NewDefaultNotifier(wc.APIKey, secret, wc.URLs), nil
+func createAnalyticsService(conf *config.Config, currentNode routing.LocalNode, hooks *ServerHooks) telemetry.AnalyticsService {
+	analytics := telemetry.NewAnalyticsService(conf, currentNode)
+	var sinks []telemetry.AnalyticsService
+	if conf.Kafka.IsConfigured() {
+		sinks = append(sinks, telemetry.NewKafkaExporter(conf, currentNode.NodeID()))
+	}
+	if hooks != nil && hooks.Analytics != nil {
+		sinks = append(sinks, hooks.Analytics)
+	}
+	if len(sinks) == 0 {
+		return analytics
+	}
+	return &analyticsTee{AnalyticsService: analytics, sinks: sinks}
 }
 
-func createRedisClient(conf *config.Config) (redis.UniversalClient, error) {
+func createRedisClient(conf *config.Config, internalTLS *InternalTLS) (redis.UniversalClient, error) {
 	if !conf.Redis.IsConfigured() {
 		return nil, nil
 	}
+	if internalTLS != nil {
+		return internalTLS.RedisClient(&conf.Redis)
+	}
 	return redisLiveKit.GetRedisClient(&conf.Redis)
 }
 
-func createStore(rc redis.UniversalClient) ObjectStore {
+func createStore(conf *config.Config, rc redis.UniversalClient) (ObjectStore, error) {
+	if conf.Store.Kind == config.StoreKindPostgres {
+		return NewPostgresStore(conf.Store.Postgres)
+	}
 	if rc != nil {
-		return NewRedisStore(rc)
+		return NewRedisStore(rc), nil
 	}
-	return NewLocalStore()
+	return NewLocalStore(), nil
 }
 
-func getMessageBus(rc redis.UniversalClient) psrpc.MessageBus {
+func createNatsConn(conf *config.Config, internalTLS *InternalTLS) (*nats.Conn, error) {
+	if conf.Bus.Kind != config.BusKindNATS {
+		return nil, nil
+	}
+	nc := conf.Bus.NATS
+	opts := []nats.Option{nats.Name("livekit-server"), nats.MaxReconnects(-1)}
+	if nc.Username != "" {
+		opts = append(opts, nats.UserInfo(nc.Username, nc.Password))
+	}
+	if nc.Token != "" {
+		opts = append(opts, nats.Token(nc.Token))
+	}
+	if nc.CredsFile != "" {
+		opts = append(opts, nats.UserCredentials(nc.CredsFile))
+	}
+	if internalTLS != nil {
+		opts = append(opts, nats.Secure(internalTLS.ClientConfig()))
+	}
+	return nats.Connect(nc.URL, opts...)
+}
+
+func getMessageBus(rc redis.UniversalClient, nc *nats.Conn) psrpc.MessageBus {
+	if nc != nil {
+		return psrpc.NewNatsMessageBus(nc)
+	}
 	if rc == nil {
 		return psrpc.NewLocalMessageBus()
 	}
@@ -193,6 +328,8 @@ func getEgressStore(s ObjectStore) EgressStore {
 	switch store := s.(type) {
 	case *RedisStore:
 		return store
+	case *PostgresStore:
+		return store
 	default:
 		return nil
 	}
@@ -202,6 +339,19 @@ func getIngressStore(s ObjectStore) IngressStore {
 	switch store := s.(type) {
 	case *RedisStore:
 		return store
+	case *PostgresStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getIngressHealthStore(s ObjectStore) IngressHealthStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *PostgresStore:
+		return store
 	default:
 		return nil
 	}
@@ -211,6 +361,8 @@ func getAgentStore(s ObjectStore) AgentStore {
 	switch store := s.(type) {
 	case *RedisStore:
 		return store
+	case *PostgresStore:
+		return store
 	case *LocalStore:
 		return store
 	default:
@@ -222,6 +374,72 @@ func getIngressConfig(conf *config.Config) *config.IngressConfig {
 	return &conf.Ingress
 }
 
+func getRoomScheduleStore(s ObjectStore) RoomScheduleStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *LocalStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getRoomTemplateStore(s ObjectStore) RoomTemplateStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *LocalStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getBreakoutStore(s ObjectStore) BreakoutStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *LocalStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getAPIKeyStore(s ObjectStore) APIKeyStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *LocalStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getTokenRevocationStore(s ObjectStore) TokenRevocationStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *LocalStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getRTMPStreamStore(s ObjectStore) RTMPStreamStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *LocalStore:
+		return store
+	default:
+		return nil
+	}
+}
+
 func getSIPStore(s ObjectStore) SIPStore {
 	switch store := s.(type) {
 	case *RedisStore:
@@ -231,6 +449,24 @@ func getSIPStore(s ObjectStore) SIPStore {
 	}
 }
 
+func getSIPCallRecordStore(s ObjectStore) SIPCallRecordStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getSessionStore(s ObjectStore) SessionStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	default:
+		return nil
+	}
+}
+
 func getSIPConfig(conf *config.Config) *config.SIPConfig {
 	return &conf.SIP
 }
@@ -266,6 +502,6 @@ func createForwardStats(conf *config.Config) *sfu.ForwardStats {
 	return sfu.NewForwardStats(conf.RTC.ForwardStats.SummaryInterval, conf.RTC.ForwardStats.ReportInterval, conf.RTC.ForwardStats.ReportWindow)
 }
 
-func newInProcessTurnServer(conf *config.Config, authHandler turn.AuthHandler) (*turn.Server, error) {
-	return NewTurnServer(conf, authHandler, false)
+func newInProcessTurnServer(conf *config.Config, authHandler turn.AuthHandler, sessions *TURNSessions) (*turn.Server, error) {
+	return NewTurnServer(conf, authHandler, sessions, false)
 }
@@ -9,9 +9,11 @@ package service
 import (
 	"fmt"
 	"github.com/livekit/livekit-server/pkg/agent"
+	"github.com/livekit/livekit-server/pkg/archiver"
 	"github.com/livekit/livekit-server/pkg/clientconfiguration"
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/protocol/auth"
@@ -20,8 +22,8 @@ import (
 	redis2 "github.com/livekit/protocol/redis"
 	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/protocol/utils"
-	"github.com/livekit/protocol/webhook"
 	"github.com/livekit/psrpc"
+	"github.com/nats-io/nats.go"
 	"github.com/pion/turn/v2"
 	"github.com/pkg/errors"
 	"github.com/redis/go-redis/v9"
@@ -35,15 +37,23 @@ import (
 
 // Injectors from wire.go:
 
-func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*LivekitServer, error) {
+func InitializeServer(conf *config.Config, currentNode routing.LocalNode, hooks *ServerHooks) (*LivekitServer, error) {
 	limitConfig := getLimitConf(conf)
 	apiConfig := config.DefaultAPIConfig()
-	universalClient, err := createRedisClient(conf)
+	internalTLS, err := NewInternalTLS(conf)
+	if err != nil {
+		return nil, err
+	}
+	universalClient, err := createRedisClient(conf, internalTLS)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := createNatsConn(conf, internalTLS)
 	if err != nil {
 		return nil, err
 	}
 	nodeID := getNodeID(currentNode)
-	messageBus := getMessageBus(universalClient)
+	messageBus := getMessageBus(universalClient, conn)
 	signalRelayConfig := getSignalRelayConfig(conf)
 	signalClient, err := routing.NewSignalClient(nodeID, messageBus, signalRelayConfig)
 	if err != nil {
@@ -60,9 +70,23 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	if err != nil {
 		return nil, err
 	}
-	router := routing.CreateRouter(universalClient, currentNode, signalClient, roomManagerClient, keepalivePubSub)
-	objectStore := createStore(universalClient)
-	roomAllocator, err := NewRoomAllocator(conf, router, objectStore)
+	router, err := routing.CreateRouter(conf, universalClient, conn, currentNode, signalClient, roomManagerClient, keepalivePubSub)
+	if err != nil {
+		return nil, err
+	}
+	objectStore, err := createStore(conf, universalClient)
+	if err != nil {
+		return nil, err
+	}
+	policyWebhook := NewPolicyWebhook(conf)
+	abuseDetector := NewAbuseDetector(conf)
+	signalRateLimiter := NewSignalRateLimiter(conf, universalClient)
+	ipFilter, err := NewIPFilter(conf)
+	if err != nil {
+		return nil, err
+	}
+	nodeLatencies := selector.NewNodeLatencies()
+	roomAllocator, err := NewRoomAllocator(conf, router, objectStore, policyWebhook, nodeLatencies)
 	if err != nil {
 		return nil, err
 	}
@@ -73,22 +97,34 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	egressStore := getEgressStore(objectStore)
 	ingressStore := getIngressStore(objectStore)
 	sipStore := getSIPStore(objectStore)
-	keyProvider, err := createKeyProvider(conf)
+	keyProvider, err := createKeyProvider(conf, objectStore)
 	if err != nil {
 		return nil, err
 	}
-	queuedNotifier, err := createWebhookNotifier(conf, keyProvider)
+	webhookNotifier, err := createWebhookNotifier(conf, keyProvider, hooks, universalClient)
 	if err != nil {
 		return nil, err
 	}
-	analyticsService := telemetry.NewAnalyticsService(conf, currentNode)
-	telemetryService := telemetry.NewTelemetryService(queuedNotifier, analyticsService)
-	ioInfoService, err := NewIOInfoService(messageBus, egressStore, ingressStore, sipStore, telemetryService)
+	analyticsService := createAnalyticsService(conf, currentNode, hooks)
+	telemetryService := telemetry.NewTelemetryService(webhookNotifier, analyticsService)
+	quotaEnforcer := NewQuotaEnforcer(conf, objectStore, egressStore, ingressStore, telemetryService)
+	sipConfig := getSIPConfig(conf)
+	ingressClient, err := rpc.NewIngressClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	sipCallRecordStore := getSIPCallRecordStore(objectStore)
+	sipCallRecorder := NewSIPCallRecorder(sipConfig, sipCallRecordStore)
+	recordingStateClient, err := NewRecordingStateClient(clientParams)
 	if err != nil {
 		return nil, err
 	}
-	rtcEgressLauncher := NewEgressLauncher(egressClient, ioInfoService)
 	topicFormatter := rpc.NewTopicFormatter()
+	ioInfoService, err := NewIOInfoService(messageBus, egressStore, ingressStore, sipStore, telemetryService, sipConfig, ingressClient, sipCallRecorder, recordingStateClient, topicFormatter)
+	if err != nil {
+		return nil, err
+	}
+	rtcEgressLauncher := NewEgressLauncher(egressClient, ioInfoService)
 	roomClient, err := rpc.NewTypedRoomClient(clientParams)
 	if err != nil {
 		return nil, err
@@ -106,20 +142,132 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		return nil, err
 	}
 	agentDispatchService := NewAgentDispatchService(agentDispatchInternalClient, topicFormatter, roomAllocator, router)
-	egressService := NewEgressService(egressClient, rtcEgressLauncher, objectStore, ioInfoService, roomService)
-	ingressConfig := getIngressConfig(conf)
-	ingressClient, err := rpc.NewIngressClient(clientParams)
+	virtualParticipantClient, err := NewVirtualParticipantClient(clientParams)
 	if err != nil {
 		return nil, err
 	}
-	ingressService := NewIngressService(ingressConfig, nodeID, messageBus, ingressClient, ingressStore, ioInfoService, telemetryService)
-	sipConfig := getSIPConfig(conf)
+	virtualParticipantService := NewVirtualParticipantService(limitConfig, objectStore, topicFormatter, virtualParticipantClient)
+	sharedPlaybackClient, err := NewSharedPlaybackClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	sharedPlaybackService := NewSharedPlaybackService(objectStore, topicFormatter, sharedPlaybackClient, ingressClient)
+	waitingRoomClient, err := NewWaitingRoomClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	waitingRoomService := NewWaitingRoomService(objectStore, topicFormatter, waitingRoomClient)
+	roomScheduleStore := getRoomScheduleStore(objectStore)
+	roomScheduleService := NewRoomScheduleService(limitConfig, roomScheduleStore, roomService, telemetryService)
+	breakoutStore := getBreakoutStore(objectStore)
+	breakoutClient, err := NewBreakoutClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	breakoutService := NewBreakoutService(limitConfig, breakoutStore, roomService, breakoutClient, telemetryService)
+	roomMergeService := NewRoomMergeService(roomService, breakoutClient, telemetryService)
+	apiKeyService := NewAPIKeyService(keyProvider)
+	tokenRevocationStore := getTokenRevocationStore(objectStore)
+	tokenRevocations, err := NewTokenRevocations(conf, tokenRevocationStore)
+	if err != nil {
+		return nil, err
+	}
+	tokenRevocationService := NewTokenRevocationService(tokenRevocations, keyProvider, roomService)
+	oidcVerifier := NewOIDCVerifier(conf)
+	participantRoleClient, err := NewParticipantRoleClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	participantRoleService := NewParticipantRoleService(objectStore, topicFormatter, participantRoleClient)
+	egressService := NewEgressService(egressClient, rtcEgressLauncher, objectStore, ioInfoService, roomService, quotaEnforcer)
+	trackForwardClient, err := NewTrackForwardClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	trackForwardService := NewTrackForwardService(objectStore, topicFormatter, trackForwardClient, egressService)
+	trackRecordingClient, err := NewTrackRecordingClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	trackRecordingService := NewTrackRecordingService(objectStore, topicFormatter, trackRecordingClient)
+	rtmpStreamStore := getRTMPStreamStore(objectStore)
+	rtmpStreamService := NewRTMPStreamService(conf, rtmpStreamStore, egressService, ioInfoService, telemetryService)
+	roomTemplateStore := getRoomTemplateStore(objectStore)
+	roomTemplateService := NewRoomTemplateService(roomConfig, limitConfig, roomTemplateStore)
+	bulkParticipantsClient, err := NewBulkParticipantsClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	bulkParticipantsService := NewBulkParticipantsService(limitConfig, objectStore, topicFormatter, bulkParticipantsClient)
+	rtpIngestClient, err := NewRTPIngestClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	rtpIngestService := NewRTPIngestService(objectStore, topicFormatter, rtpIngestClient)
+	participantListService := NewParticipantListService(objectStore)
+	recordingConsentClient, err := NewRecordingConsentClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	recordingConsentService := NewRecordingConsentService(topicFormatter, recordingConsentClient)
+	bandwidthPolicyClient, err := NewBandwidthPolicyClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	bandwidthPolicyService := NewBandwidthPolicyService(objectStore, topicFormatter, bandwidthPolicyClient)
+	roomDebugClient, err := NewRoomDebugClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	roomDebugService := NewRoomDebugService(objectStore, topicFormatter, roomDebugClient)
+	conformanceService := NewConformanceService(conf, objectStore, topicFormatter, roomDebugClient)
+	clientDiagnosticsService := NewClientDiagnosticsService(conf, objectStore, topicFormatter, roomDebugClient)
+	clientEventsClient, err := NewClientEventsClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	clientEventsService := NewClientEventsService(topicFormatter, clientEventsClient)
+	roomStatsClient, err := NewRoomStatsClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	roomStatsService := NewRoomStatsService(objectStore, topicFormatter, roomStatsClient)
+	abuseService := NewAbuseService(abuseDetector)
+	archiverArchiver := archiver.NewArchiver(conf)
+	adminLimiter := NewAdminLimiter(conf)
+	roomArchiveService := NewRoomArchiveService(archiverArchiver, adminLimiter)
+	sessionStore := getSessionStore(objectStore)
+	sessionRecorder := NewSessionRecorder(conf, sessionStore, currentNode)
+	sessionService := NewSessionService(sessionRecorder)
+	ingressConfig := getIngressConfig(conf)
+	ingressHealthStore := getIngressHealthStore(objectStore)
+	ingressService := NewIngressService(ingressConfig, nodeID, messageBus, ingressClient, ingressStore, ingressHealthStore, ioInfoService, telemetryService, quotaEnforcer)
+	ingressHealthService := NewIngressHealthService(conf, nodeID, ingressHealthStore, ingressStore, ingressClient, ioInfoService, telemetryService)
 	sipClient, err := rpc.NewSIPClient(messageBus)
 	if err != nil {
 		return nil, err
 	}
-	sipService := NewSIPService(sipConfig, nodeID, messageBus, sipClient, sipStore, roomService, telemetryService)
-	rtcService := NewRTCService(conf, roomAllocator, objectStore, router, currentNode, telemetryService)
+	sipService, err := NewSIPService(sipConfig, nodeID, messageBus, sipClient, sipStore, roomService, telemetryService, quotaEnforcer)
+	if err != nil {
+		return nil, err
+	}
+	sipUsageService := NewSIPUsageService(sipCallRecorder)
+	sipUpdateService := NewSIPUpdateService(sipStore)
+	roomRelayClient, err := NewRoomRelayClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	rtcService := NewRTCService(conf, roomAllocator, objectStore, router, currentNode, telemetryService, policyWebhook, roomRelayClient)
+	whipClient, err := NewWHIPClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	whipService := NewWHIPService(rtcService, roomAllocator, router, topicFormatter, whipClient)
+	whepClient, err := NewWHEPClient(clientParams)
+	if err != nil {
+		return nil, err
+	}
+	whepService := NewWHEPService(rtcService, objectStore, topicFormatter, whepClient)
 	agentService, err := NewAgentService(conf, currentNode, messageBus, keyProvider)
 	if err != nil {
 		return nil, err
@@ -131,22 +279,29 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	}
 	agentStore := getAgentStore(objectStore)
 	timedVersionGenerator := utils.NewDefaultTimedVersionGenerator()
-	turnAuthHandler := NewTURNAuthHandler(keyProvider)
+	turnSessions := NewTURNSessions(conf, telemetryService)
+	turnAuthHandler := NewTURNAuthHandler(keyProvider, ipFilter, turnSessions)
 	forwardStats := createForwardStats(conf)
-	roomManager, err := NewLocalRoomManager(conf, objectStore, currentNode, router, roomAllocator, telemetryService, clientConfigurationManager, client, agentStore, rtcEgressLauncher, timedVersionGenerator, turnAuthHandler, messageBus, forwardStats)
+	regionSettingsService := NewRegionSettingsService(conf, router)
+	roomManager, err := NewLocalRoomManager(conf, objectStore, currentNode, router, roomAllocator, telemetryService, clientConfigurationManager, client, agentStore, rtcEgressLauncher, timedVersionGenerator, turnAuthHandler, messageBus, forwardStats, policyWebhook, abuseDetector, quotaEnforcer, archiverArchiver, sessionRecorder, regionSettingsService, roomRelayClient, adminLimiter)
 	if err != nil {
 		return nil, err
 	}
+	nodeDrainer := NewNodeDrainer(conf, router, roomManager)
+	nodeLatencyProber := NewNodeLatencyProber(conf, router, currentNode, nodeLatencies)
+	clockSkewMonitor := NewClockSkewMonitor(conf, router, currentNode, keepalivePubSub)
+	configReloader := NewConfigReloader(conf, webhookNotifier, roomAllocator, roomService, rtcService, roomManager, regionSettingsService)
+	nodeAdminService := NewNodeAdminService(conf, currentNode, nodeDrainer, roomManager, webhookNotifier, adminLimiter, configReloader)
 	signalServer, err := NewDefaultSignalServer(currentNode, messageBus, signalRelayConfig, router, roomManager)
 	if err != nil {
 		return nil, err
 	}
 	authHandler := getTURNAuthHandlerFunc(turnAuthHandler)
-	server, err := newInProcessTurnServer(conf, authHandler)
+	server, err := newInProcessTurnServer(conf, authHandler, turnSessions)
 	if err != nil {
 		return nil, err
 	}
-	livekitServer, err := NewLivekitServer(conf, roomService, agentDispatchService, egressService, ingressService, sipService, ioInfoService, rtcService, agentService, keyProvider, router, roomManager, signalServer, server, currentNode)
+	livekitServer, err := NewLivekitServer(conf, roomService, agentDispatchService, virtualParticipantService, sharedPlaybackService, waitingRoomService, roomScheduleService, breakoutService, roomMergeService, apiKeyService, tokenRevocationService, oidcVerifier, participantRoleService, trackForwardService, trackRecordingService, rtmpStreamService, roomTemplateService, bulkParticipantsService, rtpIngestService, participantListService, recordingConsentService, bandwidthPolicyService, roomDebugService, conformanceService, clientDiagnosticsService, clientEventsService, roomStatsService, abuseService, abuseDetector, signalRateLimiter, ipFilter, roomArchiveService, sessionService, nodeAdminService, nodeDrainer, regionSettingsService, nodeLatencyProber, clockSkewMonitor, egressService, ingressService, ingressHealthService, sipService, sipUsageService, sipUpdateService, ioInfoService, rtcService, whipService, whepService, agentService, keyProvider, router, roomManager, signalServer, server, currentNode, configReloader, webhookNotifier, analyticsService)
 	if err != nil {
 		return nil, err
 	}
@@ -154,12 +309,20 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 }
 
 func InitializeRouter(conf *config.Config, currentNode routing.LocalNode) (routing.Router, error) {
-	universalClient, err := createRedisClient(conf)
+	internalTLS, err := NewInternalTLS(conf)
+	if err != nil {
+		return nil, err
+	}
+	universalClient, err := createRedisClient(conf, internalTLS)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := createNatsConn(conf, internalTLS)
 	if err != nil {
 		return nil, err
 	}
 	nodeID := getNodeID(currentNode)
-	messageBus := getMessageBus(universalClient)
+	messageBus := getMessageBus(universalClient, conn)
 	signalRelayConfig := getSignalRelayConfig(conf)
 	signalClient, err := routing.NewSignalClient(nodeID, messageBus, signalRelayConfig)
 	if err != nil {
@@ -176,7 +339,10 @@ func InitializeRouter(conf *config.Config, currentNode routing.LocalNode) (routi
 	if err != nil {
 		return nil, err
 	}
-	router := routing.CreateRouter(universalClient, currentNode, signalClient, roomManagerClient, keepalivePubSub)
+	router, err := routing.CreateRouter(conf, universalClient, conn, currentNode, signalClient, roomManagerClient, keepalivePubSub)
+	if err != nil {
+		return nil, err
+	}
 	return router, nil
 }
 
@@ -186,7 +352,7 @@ func getNodeID(currentNode routing.LocalNode) livekit.NodeID {
 	return currentNode.NodeID()
 }
 
-func createKeyProvider(conf *config.Config) (auth.KeyProvider, error) {
+func createKeyProvider(conf *config.Config, objectStore ObjectStore) (auth.KeyProvider, error) {
 
 	if conf.KeyFile != "" {
 		var otherFilter os.FileMode = 0007
@@ -212,37 +378,88 @@ func createKeyProvider(conf *config.Config) (auth.KeyProvider, error) {
 		return nil, errors.New("one of key-file or keys must be provided in order to support a secure installation")
 	}
 
-	return auth.NewFileBasedKeyProviderFromMap(conf.Keys), nil
+	provider := auth.NewFileBasedKeyProviderFromMap(conf.Keys)
+	if !conf.KeyManagement.Enabled {
+		return provider, nil
+	}
+	store := getAPIKeyStore(objectStore)
+	if store == nil {
+		return nil, errors.New("key management is not supported by the store")
+	}
+	return NewManagedKeyProvider(provider, store, conf.KeyManagement.CacheTTL), nil
 }
 
-func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider) (webhook.QueuedNotifier, error) {
-	wc := conf.WebHook
-	if len(wc.URLs) == 0 {
-		return nil, nil
+func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider, hooks *ServerHooks, rc redis.UniversalClient) (*WebhookNotifier, error) {
+	n, err := NewWebhookNotifier(conf.WebHook, provider, rc)
+	if err != nil {
+		return nil, err
 	}
-	secret := provider.GetSecret(wc.APIKey)
-	if secret == "" {
-		return nil, ErrWebHookMissingAPIKey
+	if hooks != nil && hooks.Webhook != nil {
+		n.OnEvent(hooks.Webhook)
 	}
+	return n, nil
+}
 
-	return webhook.NewDefaultNotifier(wc.APIKey, secret, wc.URLs), nil
+func createAnalyticsService(conf *config.Config, currentNode routing.LocalNode, hooks *ServerHooks) telemetry.AnalyticsService {
+	analytics := telemetry.NewAnalyticsService(conf, currentNode)
+	var sinks []telemetry.AnalyticsService
+	if conf.Kafka.IsConfigured() {
+		sinks = append(sinks, telemetry.NewKafkaExporter(conf, currentNode.NodeID()))
+	}
+	if hooks != nil && hooks.Analytics != nil {
+		sinks = append(sinks, hooks.Analytics)
+	}
+	if len(sinks) == 0 {
+		return analytics
+	}
+	return &analyticsTee{AnalyticsService: analytics, sinks: sinks}
 }
 
-func createRedisClient(conf *config.Config) (redis.UniversalClient, error) {
+func createRedisClient(conf *config.Config, internalTLS *InternalTLS) (redis.UniversalClient, error) {
 	if !conf.Redis.IsConfigured() {
 		return nil, nil
 	}
+	if internalTLS != nil {
+		return internalTLS.RedisClient(&conf.Redis)
+	}
 	return redis2.GetRedisClient(&conf.Redis)
 }
 
-func createStore(rc redis.UniversalClient) ObjectStore {
+func createStore(conf *config.Config, rc redis.UniversalClient) (ObjectStore, error) {
+	if conf.Store.Kind == config.StoreKindPostgres {
+		return NewPostgresStore(conf.Store.Postgres)
+	}
 	if rc != nil {
-		return NewRedisStore(rc)
+		return NewRedisStore(rc), nil
 	}
-	return NewLocalStore()
+	return NewLocalStore(), nil
 }
 
-func getMessageBus(rc redis.UniversalClient) psrpc.MessageBus {
+func createNatsConn(conf *config.Config, internalTLS *InternalTLS) (*nats.Conn, error) {
+	if conf.Bus.Kind != config.BusKindNATS {
+		return nil, nil
+	}
+	nc := conf.Bus.NATS
+	opts := []nats.Option{nats.Name("livekit-server"), nats.MaxReconnects(-1)}
+	if nc.Username != "" {
+		opts = append(opts, nats.UserInfo(nc.Username, nc.Password))
+	}
+	if nc.Token != "" {
+		opts = append(opts, nats.Token(nc.Token))
+	}
+	if nc.CredsFile != "" {
+		opts = append(opts, nats.UserCredentials(nc.CredsFile))
+	}
+	if internalTLS != nil {
+		opts = append(opts, nats.Secure(internalTLS.ClientConfig()))
+	}
+	return nats.Connect(nc.URL, opts...)
+}
+
+func getMessageBus(rc redis.UniversalClient, nc *nats.Conn) psrpc.MessageBus {
+	if nc != nil {
+		return psrpc.NewNatsMessageBus(nc)
+	}
 	if rc == nil {
 		return psrpc.NewLocalMessageBus()
 	}
@@ -253,6 +470,8 @@ func getEgressStore(s ObjectStore) EgressStore {
 	switch store := s.(type) {
 	case *RedisStore:
 		return store
+	case *PostgresStore:
+		return store
 	default:
 		return nil
 	}
@@ -262,6 +481,19 @@ func getIngressStore(s ObjectStore) IngressStore {
 	switch store := s.(type) {
 	case *RedisStore:
 		return store
+	case *PostgresStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getIngressHealthStore(s ObjectStore) IngressHealthStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *PostgresStore:
+		return store
 	default:
 		return nil
 	}
@@ -271,6 +503,8 @@ func getAgentStore(s ObjectStore) AgentStore {
 	switch store := s.(type) {
 	case *RedisStore:
 		return store
+	case *PostgresStore:
+		return store
 	case *LocalStore:
 		return store
 	default:
@@ -282,6 +516,72 @@ func getIngressConfig(conf *config.Config) *config.IngressConfig {
 	return &conf.Ingress
 }
 
+func getRoomScheduleStore(s ObjectStore) RoomScheduleStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *LocalStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getRoomTemplateStore(s ObjectStore) RoomTemplateStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *LocalStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getBreakoutStore(s ObjectStore) BreakoutStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *LocalStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getAPIKeyStore(s ObjectStore) APIKeyStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *LocalStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getTokenRevocationStore(s ObjectStore) TokenRevocationStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *LocalStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getRTMPStreamStore(s ObjectStore) RTMPStreamStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *LocalStore:
+		return store
+	default:
+		return nil
+	}
+}
+
 func getSIPStore(s ObjectStore) SIPStore {
 	switch store := s.(type) {
 	case *RedisStore:
@@ -291,6 +591,24 @@ func getSIPStore(s ObjectStore) SIPStore {
 	}
 }
 
+func getSIPCallRecordStore(s ObjectStore) SIPCallRecordStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getSessionStore(s ObjectStore) SessionStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	default:
+		return nil
+	}
+}
+
 func getSIPConfig(conf *config.Config) *config.SIPConfig {
 	return &conf.SIP
 }
@@ -326,6 +644,6 @@ func createForwardStats(conf *config.Config) *sfu.ForwardStats {
 	return sfu.NewForwardStats(conf.RTC.ForwardStats.SummaryInterval, conf.RTC.ForwardStats.ReportInterval, conf.RTC.ForwardStats.ReportWindow)
 }
 
-func newInProcessTurnServer(conf *config.Config, authHandler turn.AuthHandler) (*turn.Server, error) {
-	return NewTurnServer(conf, authHandler, false)
+func newInProcessTurnServer(conf *config.Config, authHandler turn.AuthHandler, sessions *TURNSessions) (*turn.Server, error) {
+	return NewTurnServer(conf, authHandler, sessions, false)
 }
@@ -16,7 +16,9 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"strings"
 	"sync"
 	"time"
@@ -28,6 +30,7 @@ import (
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 
+	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/rtc/types"
 )
 
@@ -37,9 +40,14 @@ const (
 )
 
 type WSSignalConnection struct {
-	conn    types.WebsocketClient
-	mu      sync.Mutex
-	useJSON bool
+	conn         types.WebsocketClient
+	mu           sync.Mutex
+	useJSON      bool
+	writeTimeout time.Duration
+}
+
+type writeDeadlineSetter interface {
+	SetWriteDeadline(deadline time.Time) error
 }
 
 func NewWSSignalConnection(conn types.WebsocketClient) *WSSignalConnection {
@@ -60,6 +68,14 @@ func (c *WSSignalConnection) SetReadDeadline(deadline time.Time) error {
 	return c.conn.SetReadDeadline(deadline)
 }
 
+// SetWriteTimeout bounds the time responses take to be written, a client not reading them in time
+// fails the write with routing.ErrSignalSlowConsumer. The connection can't be used after that.
+func (c *WSSignalConnection) SetWriteTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeTimeout = timeout
+}
+
 func (c *WSSignalConnection) ReadRequest() (*livekit.SignalRequest, int, error) {
 	for {
 		// handle special messages and pass on the rest
@@ -147,7 +163,17 @@ func (c *WSSignalConnection) WriteResponse(msg *livekit.SignalResponse) (int, er
 		return 0, err
 	}
 
-	return len(payload), c.conn.WriteMessage(msgType, payload)
+	if c.writeTimeout > 0 {
+		if wc, ok := c.conn.(writeDeadlineSetter); ok {
+			_ = wc.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+		}
+	}
+	err = c.conn.WriteMessage(msgType, payload)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		err = fmt.Errorf("%w: %w", routing.ErrSignalSlowConsumer, err)
+	}
+	return len(payload), err
 }
 
 func (c *WSSignalConnection) WriteServerMessage(msg *livekit.ServerMessage) (int, error) {
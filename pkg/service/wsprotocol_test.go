@@ -0,0 +1,56 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestWSSignalConnectionSlowConsumer(t *testing.T) {
+	res := &livekit.SignalResponse{Message: &livekit.SignalResponse_Pong{Pong: 1}}
+
+	conn := &typesfakes.FakeWebsocketClient{}
+	sigConn := service.NewWSSignalConnection(conn)
+	sigConn.SetWriteTimeout(time.Second)
+	defer sigConn.Close()
+
+	_, err := sigConn.WriteResponse(res)
+	require.NoError(t, err)
+
+	conn.WriteMessageReturns(timeoutError{})
+	_, err = sigConn.WriteResponse(res)
+	require.ErrorIs(t, err, routing.ErrSignalSlowConsumer)
+
+	conn.WriteMessageReturns(errors.New("broken pipe"))
+	_, err = sigConn.WriteResponse(res)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, routing.ErrSignalSlowConsumer)
+}
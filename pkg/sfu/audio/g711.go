@@ -0,0 +1,124 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audio
+
+import (
+	"math/bits"
+)
+
+// G.711 encodes 8 kHz samples in a byte each, with the µ-law (PCMU) or the A-law (PCMA) companding. Both
+// laws use the same number of levels, converting between them is a table lookup per sample.
+
+const (
+	// encoded silence, the first value ULawToLinear and ALawToLinear decode to 0
+	ULawSilence = 0xFF
+	ALawSilence = 0xD5
+
+	ulawBias = 0x84
+	ulawClip = 32635
+)
+
+var (
+	ulawToALaw [256]byte
+	alawToULaw [256]byte
+)
+
+func init() {
+	for i := 0; i < 256; i++ {
+		ulawToALaw[i] = LinearToALaw(ULawToLinear(byte(i)))
+		alawToULaw[i] = LinearToULaw(ALawToLinear(byte(i)))
+	}
+	// A-law has no zero level, its silence is kept as silence rather than the nearest µ-law level
+	alawToULaw[ALawSilence] = ULawSilence
+}
+
+func LinearToULaw(sample int16) byte {
+	s := int(sample)
+	var sign int
+	if s < 0 {
+		s = -s
+		sign = 0x80
+	}
+	if s > ulawClip {
+		s = ulawClip
+	}
+	s += ulawBias
+	exponent := bits.Len(uint(s>>7)) - 1
+	mantissa := (s >> (exponent + 3)) & 0x0F
+	return ^byte(sign | exponent<<4 | mantissa)
+}
+
+func ULawToLinear(u byte) int16 {
+	u = ^u
+	t := (int(u&0x0F) << 3) + ulawBias
+	t <<= (u & 0x70) >> 4
+	if u&0x80 != 0 {
+		return int16(ulawBias - t)
+	}
+	return int16(t - ulawBias)
+}
+
+func LinearToALaw(sample int16) byte {
+	pcm := int(sample) >> 3
+	mask := 0xD5
+	if pcm < 0 {
+		mask = 0x55
+		pcm = -pcm - 1
+	}
+	// segment ends are 0x1F, 0x3F, ... 0xFFF
+	segment := bits.Len(uint(pcm >> 5))
+	if segment >= 8 {
+		return byte(0x7F ^ mask)
+	}
+	alaw := segment << 4
+	if segment < 2 {
+		alaw |= (pcm >> 1) & 0x0F
+	} else {
+		alaw |= (pcm >> segment) & 0x0F
+	}
+	return byte(alaw ^ mask)
+}
+
+func ALawToLinear(a byte) int16 {
+	a ^= 0x55
+	t := int(a&0x0F) << 4
+	switch segment := int(a&0x70) >> 4; segment {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= segment - 1
+	}
+	if a&0x80 != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
+
+// ULawToALaw converts the µ-law samples of src to A-law in dst, which may be src
+func ULawToALaw(dst, src []byte) {
+	for i, u := range src {
+		dst[i] = ulawToALaw[u]
+	}
+}
+
+// ALawToULaw converts the A-law samples of src to µ-law in dst, which may be src
+func ALawToULaw(dst, src []byte) {
+	for i, a := range src {
+		dst[i] = alawToULaw[a]
+	}
+}
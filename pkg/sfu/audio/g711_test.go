@@ -0,0 +1,72 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audio
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestG711(t *testing.T) {
+	t.Run("silence", func(t *testing.T) {
+		require.Equal(t, byte(ULawSilence), LinearToULaw(0))
+		require.Equal(t, byte(ALawSilence), LinearToALaw(0))
+		require.Equal(t, int16(0), ULawToLinear(ULawSilence))
+		require.Equal(t, int16(8), ALawToLinear(ALawSilence))
+	})
+
+	t.Run("reference values", func(t *testing.T) {
+		require.Equal(t, int16(-32124), ULawToLinear(0x00))
+		require.Equal(t, int16(32124), ULawToLinear(0x80))
+		require.Equal(t, int16(-5504), ALawToLinear(0x00))
+		require.Equal(t, int16(5504), ALawToLinear(0x80))
+		require.Equal(t, byte(0x80), LinearToULaw(32767))
+		require.Equal(t, byte(0x00), LinearToULaw(-32768))
+		require.Equal(t, byte(0xAA), LinearToALaw(32767))
+		require.Equal(t, byte(0x2A), LinearToALaw(-32768))
+	})
+
+	t.Run("decoded samples encode back", func(t *testing.T) {
+		for i := 0; i < 256; i++ {
+			a := byte(i)
+			require.Equal(t, a, LinearToALaw(ALawToLinear(a)))
+			u := byte(i)
+			if u == 0x7F {
+				// negative zero
+				require.Equal(t, byte(ULawSilence), LinearToULaw(ULawToLinear(u)))
+				continue
+			}
+			require.Equal(t, u, LinearToULaw(ULawToLinear(u)))
+		}
+	})
+
+	t.Run("converting between laws", func(t *testing.T) {
+		src := []byte{ULawSilence, 0x00, 0x80, 0x7F, 0xC5}
+		alaw := make([]byte, len(src))
+		ULawToALaw(alaw, src)
+		for i, u := range src {
+			require.InDelta(t, ULawToLinear(u), ALawToLinear(alaw[i]), 1024)
+		}
+
+		// in place
+		ulaw := append([]byte{}, alaw...)
+		ALawToULaw(ulaw, ulaw)
+		require.Equal(t, byte(ULawSilence), ulaw[0])
+		for i, a := range alaw {
+			require.InDelta(t, ALawToLinear(a), ULawToLinear(ulaw[i]), 1024)
+		}
+	})
+}
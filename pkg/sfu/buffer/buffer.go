@@ -139,6 +139,8 @@ type Buffer struct {
 	rtxPktBuf           []byte
 
 	absCaptureTimeExtID uint8
+
+	keyFrameCache *KeyFrameCache
 }
 
 // NewBuffer constructs a new Buffer
@@ -174,6 +176,30 @@ func (b *Buffer) SetPaused(paused bool) {
 	b.paused = paused
 }
 
+// EnableKeyFrameCache keeps the packets of the latest key frame (and the frames following it,
+// up to maxPackets in total) so that they can be replayed to new subscribers.
+func (b *Buffer) EnableKeyFrameCache(maxPackets int) {
+	b.Lock()
+	defer b.Unlock()
+
+	if maxPackets <= 0 {
+		b.keyFrameCache = nil
+		return
+	}
+	b.keyFrameCache = NewKeyFrameCache(maxPackets)
+}
+
+// GetKeyFrameCache returns the cached key frame packets in forwarding order, nil if none is available.
+func (b *Buffer) GetKeyFrameCache() []*ExtPacket {
+	b.RLock()
+	defer b.RUnlock()
+
+	if b.keyFrameCache == nil {
+		return nil
+	}
+	return b.keyFrameCache.Packets()
+}
+
 func (b *Buffer) SetTWCCAndExtID(twcc *twcc.Responder, extID uint8) {
 	b.Lock()
 	defer b.Unlock()
@@ -451,6 +477,9 @@ func (b *Buffer) ReadExtended(buf []byte) (*ExtPacket, error) {
 			if ep == nil {
 				continue
 			}
+			if b.keyFrameCache != nil {
+				b.keyFrameCache.Add(ep)
+			}
 
 			b.Unlock()
 			return ep, nil
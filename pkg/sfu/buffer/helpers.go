@@ -48,6 +48,7 @@ var (
 */
 type VP8 struct {
 	FirstByte byte
+	N         bool /* non-reference frame, can be discarded without affecting other frames */
 	S         bool
 
 	I         bool
@@ -85,6 +86,7 @@ func (v *VP8) Unmarshal(payload []byte) error {
 
 	idx := 0
 	v.FirstByte = payload[idx]
+	v.N = payload[idx]&0x20 > 0
 	v.S = payload[idx]&0x10 > 0
 	// Check for extended bit control
 	if payload[idx]&0x80 > 0 {
@@ -314,6 +316,21 @@ func IsH264KeyFrame(payload []byte) bool {
 
 // -------------------------------------
 
+// IsH264NonReference detects if h264 payload belongs to a picture that is not used for reference,
+// i. e. nal_ref_idc is 0. Aggregation and fragmentation units carry the highest nal_ref_idc of what they hold.
+func IsH264NonReference(payload []byte) bool {
+	if len(payload) < 1 {
+		return false
+	}
+	if payload[0]&0x1F == 0 {
+		// reserved
+		return false
+	}
+	return payload[0]&0x60 == 0
+}
+
+// -------------------------------------
+
 // IsVP9KeyFrame detects if vp9 payload is a keyframe
 // taken from https://github.com/jech/galene/blob/master/codecs/codecs.go
 // all credits belongs to Juliusz Chroboczek @jech and the awesome Galene SFU
@@ -0,0 +1,98 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buffer
+
+import (
+	"github.com/pion/rtp"
+)
+
+// KeyFrameCache holds the packets of the most recent key frame and the frames that followed it,
+// so that a new subscriber can be started without waiting for the publisher to answer a PLI.
+// Frames after the key frame are needed for the decoder to keep its reference chain intact until
+// live packets take over. If the group of pictures grows beyond maxPackets, the cache is dropped
+// until the next key frame.
+type KeyFrameCache struct {
+	maxPackets int
+
+	packets      []*ExtPacket
+	keyFrameTS   uint64
+	haveKeyFrame bool
+	complete     bool
+}
+
+func NewKeyFrameCache(maxPackets int) *KeyFrameCache {
+	return &KeyFrameCache{
+		maxPackets: maxPackets,
+	}
+}
+
+// Add is expected to be called with packets in forwarding order.
+func (k *KeyFrameCache) Add(ep *ExtPacket) {
+	if ep.IsOutOfOrder || len(ep.Packet.Payload) == 0 {
+		return
+	}
+
+	if ep.KeyFrame && (!k.haveKeyFrame || ep.ExtTimestamp != k.keyFrameTS) {
+		k.reset()
+		k.haveKeyFrame = true
+		k.keyFrameTS = ep.ExtTimestamp
+	}
+	if !k.haveKeyFrame {
+		return
+	}
+
+	if len(k.packets) >= k.maxPackets {
+		k.reset()
+		return
+	}
+
+	clone := cloneExtPacket(ep)
+	if clone == nil {
+		k.reset()
+		return
+	}
+	k.packets = append(k.packets, clone)
+	if ep.Packet.Marker && ep.ExtTimestamp == k.keyFrameTS {
+		k.complete = true
+	}
+}
+
+// Packets returns the cached packets if a complete key frame is available.
+func (k *KeyFrameCache) Packets() []*ExtPacket {
+	if !k.complete {
+		return nil
+	}
+	return append([]*ExtPacket(nil), k.packets...)
+}
+
+func (k *KeyFrameCache) reset() {
+	k.packets = nil
+	k.keyFrameTS = 0
+	k.haveKeyFrame = false
+	k.complete = false
+}
+
+func cloneExtPacket(ep *ExtPacket) *ExtPacket {
+	raw := append([]byte(nil), ep.RawPacket...)
+	pkt := &rtp.Packet{}
+	if err := pkt.Unmarshal(raw); err != nil {
+		return nil
+	}
+
+	clone := *ep
+	clone.Packet = pkt
+	clone.RawPacket = raw
+	return &clone
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buffer
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestExtPacket(t *testing.T, sn uint16, ts uint32, keyFrame bool, marker bool) *ExtPacket {
+	pkt := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			SequenceNumber: sn,
+			Timestamp:      ts,
+			Marker:         marker,
+		},
+		Payload: []byte{1, 2, 3},
+	}
+	raw, err := pkt.Marshal()
+	require.NoError(t, err)
+
+	return &ExtPacket{
+		ExtSequenceNumber: uint64(sn),
+		ExtTimestamp:      uint64(ts),
+		Packet:            pkt,
+		RawPacket:         raw,
+		KeyFrame:          keyFrame,
+	}
+}
+
+func TestKeyFrameCache(t *testing.T) {
+	t.Run("waits for key frame", func(t *testing.T) {
+		k := NewKeyFrameCache(10)
+		k.Add(newTestExtPacket(t, 1, 1000, false, true))
+		require.Nil(t, k.Packets())
+
+		k.Add(newTestExtPacket(t, 2, 2000, true, false))
+		require.Nil(t, k.Packets(), "key frame not complete")
+
+		k.Add(newTestExtPacket(t, 3, 2000, false, true))
+		pkts := k.Packets()
+		require.Len(t, pkts, 2)
+		require.Equal(t, uint64(2), pkts[0].ExtSequenceNumber)
+		require.True(t, pkts[0].KeyFrame)
+
+		// following frames are kept to keep reference chain intact
+		k.Add(newTestExtPacket(t, 4, 3000, false, true))
+		require.Len(t, k.Packets(), 3)
+	})
+
+	t.Run("new key frame resets", func(t *testing.T) {
+		k := NewKeyFrameCache(10)
+		k.Add(newTestExtPacket(t, 1, 1000, true, true))
+		k.Add(newTestExtPacket(t, 2, 2000, false, true))
+		k.Add(newTestExtPacket(t, 3, 3000, true, true))
+		pkts := k.Packets()
+		require.Len(t, pkts, 1)
+		require.Equal(t, uint64(3), pkts[0].ExtSequenceNumber)
+	})
+
+	t.Run("overflow drops cache", func(t *testing.T) {
+		k := NewKeyFrameCache(2)
+		k.Add(newTestExtPacket(t, 1, 1000, true, true))
+		k.Add(newTestExtPacket(t, 2, 2000, false, true))
+		require.Len(t, k.Packets(), 2)
+
+		k.Add(newTestExtPacket(t, 3, 3000, false, true))
+		require.Nil(t, k.Packets())
+
+		k.Add(newTestExtPacket(t, 4, 4000, false, true))
+		require.Nil(t, k.Packets())
+	})
+
+	t.Run("packets are copied", func(t *testing.T) {
+		k := NewKeyFrameCache(10)
+		ep := newTestExtPacket(t, 1, 1000, true, true)
+		k.Add(ep)
+		ep.RawPacket[len(ep.RawPacket)-1] = 0xff
+
+		pkts := k.Packets()
+		require.Len(t, pkts, 1)
+		require.Equal(t, []byte{1, 2, 3}, pkts[0].Packet.Payload)
+	})
+}
@@ -24,6 +24,7 @@ var (
 	ErrNotVP8                          = errors.New("not VP8")
 	ErrOutOfOrderVP8PictureIdCacheMiss = errors.New("out-of-order VP8 picture id not found in cache")
 	ErrFilteredVP8TemporalLayer        = errors.New("filtered VP8 temporal layer")
+	ErrFilteredVP8NonReferenceFrame    = errors.New("filtered VP8 non-reference frame")
 )
 
 type CodecMunger interface {
@@ -36,4 +37,7 @@ type CodecMunger interface {
 	UpdateAndGet(extPkt *buffer.ExtPacket, snOutOfOrder bool, snHasGap bool, maxTemporal int32) (int, []byte, error)
 
 	UpdateAndGetPadding(newPicture bool) ([]byte, error)
+
+	// SetFrameDecimation enables dropping of frames that are not used for reference by other frames
+	SetFrameDecimation(enabled bool)
 }
@@ -52,3 +52,6 @@ func (n *Null) UpdateAndGet(_extPkt *buffer.ExtPacket, snOutOfOrder bool, snHasG
 func (n *Null) UpdateAndGetPadding(newPicture bool) ([]byte, error) {
 	return nil, nil
 }
+
+func (n *Null) SetFrameDecimation(_enabled bool) {
+}
@@ -46,6 +46,8 @@ type VP8 struct {
 	keyIdxOffset         uint8
 	keyIdxUsed           bool
 
+	frameDecimation bool
+
 	missingPictureIds  *orderedmap.OrderedMap[int32, int32]
 	droppedPictureIds  *orderedmap.OrderedMap[int32, bool]
 	exemptedPictureIds *orderedmap.OrderedMap[int32, bool]
@@ -226,7 +228,7 @@ func (v *VP8) UpdateAndGet(extPkt *buffer.ExtPacket, snOutOfOrder bool, snHasGap
 		// which layer the missing packets belong to. A layer could have multiple packets. So, keep track
 		// of pictures that are forwarded even though they will be filtered out based on temporal layer
 		// requirements. That allows forwarding of the complete picture.
-		if vp8.T && vp8.TID > uint8(maxTemporalLayer) {
+		if v.filter(&vp8, maxTemporalLayer) != nil {
 			v.exemptedPictureIds.Set(extPictureId, true)
 			// trim cache if necessary
 			for v.exemptedPictureIds.Len() > exemptedPictureIdsThreshold {
@@ -235,7 +237,7 @@ func (v *VP8) UpdateAndGet(extPkt *buffer.ExtPacket, snOutOfOrder bool, snHasGap
 			}
 		}
 	} else {
-		if vp8.T && vp8.TID > uint8(maxTemporalLayer) {
+		if err := v.filter(&vp8, maxTemporalLayer); err != nil {
 			// drop only if not exempted
 			_, ok := v.exemptedPictureIds.Get(extPictureId)
 			if !ok {
@@ -251,7 +253,7 @@ func (v *VP8) UpdateAndGet(extPkt *buffer.ExtPacket, snOutOfOrder bool, snHasGap
 
 					v.pictureIdOffset += 1
 				}
-				return 0, nil, ErrFilteredVP8TemporalLayer
+				return 0, nil, err
 			}
 		}
 	}
@@ -293,6 +295,21 @@ func (v *VP8) UpdateAndGet(extPkt *buffer.ExtPacket, snOutOfOrder bool, snHasGap
 	return vp8.HeaderSize, vp8HeaderBytes, nil
 }
 
+func (v *VP8) SetFrameDecimation(enabled bool) {
+	v.frameDecimation = enabled
+}
+
+// filter returns the reason to drop a packet, nil if the packet should be forwarded
+func (v *VP8) filter(vp8 *buffer.VP8, maxTemporalLayer int32) error {
+	if vp8.T && vp8.TID > uint8(maxTemporalLayer) {
+		return ErrFilteredVP8TemporalLayer
+	}
+	if v.frameDecimation && vp8.N && !vp8.IsKeyFrame {
+		return ErrFilteredVP8NonReferenceFrame
+	}
+	return nil
+}
+
 func (v *VP8) UpdateAndGetPadding(newPicture bool) ([]byte, error) {
 	offset := 0
 	if newPicture {
@@ -332,6 +332,70 @@ func TestTemporalLayerFiltering(t *testing.T) {
 	require.EqualValues(t, 1, v.pictureIdOffset)
 }
 
+func TestFrameDecimation(t *testing.T) {
+	v := newVP8()
+
+	params := &testutils.TestExtPacketParams{
+		SequenceNumber: 23333,
+		Timestamp:      0xabcdef,
+		SSRC:           0x12345678,
+	}
+	vp8 := &buffer.VP8{
+		FirstByte:  0x10,
+		S:          true,
+		I:          true,
+		M:          true,
+		PictureID:  13467,
+		HeaderSize: 4,
+		IsKeyFrame: true,
+	}
+	extPkt, _ := testutils.GetTestExtPacketVP8(params, vp8)
+	v.SetLast(extPkt)
+
+	// non-reference frame is forwarded without decimation
+	nonReference := &buffer.VP8{
+		FirstByte:  0x30,
+		N:          true,
+		S:          true,
+		I:          true,
+		M:          true,
+		PictureID:  13468,
+		HeaderSize: 4,
+	}
+	params.SequenceNumber = 23334
+	extPkt, _ = testutils.GetTestExtPacketVP8(params, nonReference)
+	_, _, err := v.UpdateAndGet(extPkt, false, false, 2)
+	require.NoError(t, err)
+	require.EqualValues(t, 0, v.pictureIdOffset)
+
+	// and dropped with decimation
+	v.SetFrameDecimation(true)
+	nonReference.PictureID = 13469
+	params.SequenceNumber = 23335
+	extPkt, _ = testutils.GetTestExtPacketVP8(params, nonReference)
+	nIn, buf, err := v.UpdateAndGet(extPkt, false, false, 2)
+	require.ErrorIs(t, err, ErrFilteredVP8NonReferenceFrame)
+	require.Equal(t, 0, nIn)
+	require.Nil(t, buf)
+	require.EqualValues(t, 1, v.pictureIdOffset)
+
+	// next reference frame continues the picture ids
+	reference := &buffer.VP8{
+		FirstByte:  0x10,
+		S:          true,
+		I:          true,
+		M:          true,
+		PictureID:  13470,
+		HeaderSize: 4,
+	}
+	params.SequenceNumber = 23336
+	extPkt, _ = testutils.GetTestExtPacketVP8(params, reference)
+	_, buf, err = v.UpdateAndGet(extPkt, false, false, 2)
+	require.NoError(t, err)
+	require.NotNil(t, buf)
+	require.EqualValues(t, 13469, v.extLastPictureId)
+}
+
 func TestGapInSequenceNumberSamePicture(t *testing.T) {
 	v := newVP8()
 
@@ -55,6 +55,8 @@ type ConnectionStatsParams struct {
 	ReceiverProvider   ConnectionStatsReceiverProvider
 	SenderProvider     ConnectionStatsSenderProvider
 	Logger             logger.Logger
+	// optional, the default scorer when unset
+	Scorer Scorer
 }
 
 type ConnectionStats struct {
@@ -83,6 +85,7 @@ func NewConnectionStats(params ConnectionStatsParams) *ConnectionStats {
 			IncludeRTT:         params.IncludeRTT,
 			IncludeJitter:      params.IncludeJitter,
 			EnableBitrateScore: params.EnableBitrateScore,
+			Scorer:             params.Scorer,
 			Logger:             params.Logger,
 		}),
 	}
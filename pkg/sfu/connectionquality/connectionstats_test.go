@@ -901,3 +901,26 @@ func TestConnectionQuality(t *testing.T) {
 		}
 	})
 }
+
+func TestWeightedScorer(t *testing.T) {
+	in := ScoreInputs{
+		Loss:             20,
+		RTT:              100,
+		Jitter:           10,
+		BitrateShortfall: 10,
+	}
+
+	components := WeightedScorer{Weights: DefaultScoreWeights}.Score(in)
+	require.InDelta(t, 78.25, components.Packet, 0.01)
+	require.InDelta(t, 53.95, components.Bitrate, 0.01)
+	require.Equal(t, cMaxScore, components.Layer)
+
+	// ignored impairments don't lower the score
+	components = WeightedScorer{Weights: ScoreWeights{RTT: 1, Jitter: 1}}.Score(in)
+	require.InDelta(t, 98.25, components.Packet, 0.01)
+	require.Equal(t, cMaxScore, components.Bitrate)
+
+	// nothing sent while a bitrate is expected
+	components = WeightedScorer{Weights: DefaultScoreWeights}.Score(ScoreInputs{BitrateShortfall: math.Inf(1)})
+	require.Equal(t, 0.0, components.Bitrate)
+}
@@ -24,6 +24,8 @@ import (
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/utils"
 	"go.uber.org/zap/zapcore"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 )
 
 const (
@@ -69,24 +71,21 @@ type windowStat struct {
 	lastRTCPAt        time.Time
 }
 
-func (w *windowStat) calculatePacketScore(aplw float64, includeRTT bool, includeJitter bool) float64 {
-	// this is based on simplified E-model based on packet loss, rtt, jitter as
-	// outlined at https://www.pingman.com/kb/article/how-is-mos-calculated-in-pingplotter-pro-50.html.
-	effectiveDelay := 0.0
+func (w *windowStat) scoreInputs(aplw float64, includeRTT bool, includeJitter bool, expectedBits int64, enableBitrateScore bool, expectedDistance float64) ScoreInputs {
+	in := ScoreInputs{
+		LayerDistance: expectedDistance,
+	}
+
 	// discount the dependent factors if dependency indicated.
 	// for example,
 	// 1. in the up stream, RTT cannot be measured without RTCP-XR, it is using down stream RTT.
 	// 2. in the down stream, up stream jitter affects it. although jitter can be adjusted to account for up stream
 	//    jitter, this lever can be used to discount jitter in scoring.
 	if includeRTT {
-		effectiveDelay += float64(w.rttMax) / 2.0
+		in.RTT = float64(w.rttMax)
 	}
 	if includeJitter {
-		effectiveDelay += (w.jitterMax * 2.0) / 1000.0
-	}
-	delayEffect := effectiveDelay / 40.0
-	if effectiveDelay > 160.0 {
-		delayEffect = (effectiveDelay - 120.0) / 10.0
+		in.Jitter = w.jitterMax / 1000.0
 	}
 
 	// discount out-of-order packets from loss to deal with a scenario like
@@ -114,42 +113,19 @@ func (w *windowStat) calculatePacketScore(aplw float64, includeRTT bool, include
 	if int32(actualLost) < 0 {
 		actualLost = 0
 	}
-
-	var lossEffect float64
 	if w.packets+w.packetsPadding > 0 {
-		lossEffect = float64(actualLost) * 100.0 / float64(w.packets+w.packetsPadding)
+		in.Loss = float64(actualLost) * 100.0 / float64(w.packets+w.packetsPadding) * aplw
 	}
-	lossEffect *= aplw
 
-	score := cMaxScore - delayEffect - lossEffect
-	if score < 0.0 {
-		score = 0.0
-	}
-
-	return score
-}
-
-func (w *windowStat) calculateBitrateScore(expectedBits int64, isEnabled bool) float64 {
-	if expectedBits == 0 || !isEnabled {
-		// unsupported mode OR all layers stopped
-		return cMaxScore
-	}
-
-	var score float64
-	if w.bytes != 0 {
-		// using the ratio of expectedBits / actualBits
-		// the quality inflection points are approximately
-		// GOOD at ~2.7x, POOR at ~20.1x
-		score = cMaxScore - 20*math.Log(float64(expectedBits)/float64(w.bytes*8))
-		if score > cMaxScore {
-			score = cMaxScore
-		}
-		if score < 0.0 {
-			score = 0.0
+	if expectedBits != 0 && enableBitrateScore {
+		// all layers stopped or unsupported mode otherwise
+		if w.bytes != 0 {
+			in.BitrateShortfall = float64(expectedBits) / float64(w.bytes*8)
+		} else {
+			in.BitrateShortfall = math.Inf(1)
 		}
 	}
-
-	return score
+	return in
 }
 
 func (w *windowStat) String() string {
@@ -189,10 +165,111 @@ func (w *windowStat) MarshalLogObject(e zapcore.ObjectEncoder) error {
 
 // ------------------------------------------
 
+// ScoreInputs are the impairments of a track measured over an analysis window
+type ScoreInputs struct {
+	// percentage of packets lost, weighted by the resilience of the codec and the packet rate of the track
+	Loss float64
+	// milliseconds, 0 when not scored in this direction
+	RTT    float64
+	Jitter float64
+	// expected bits over the bits sent, 0 when the bitrate is not scored and +Inf when nothing was sent
+	BitrateShortfall float64
+	// average number of spatial layers below the expected one
+	LayerDistance float64
+}
+
+// ScoreComponents are the scores of the impairments of a window, from 0 to 100. The lowest one is the score
+// of the window.
+type ScoreComponents struct {
+	Packet  float64
+	Bitrate float64
+	Layer   float64
+}
+
+// Scorer scores the impairments of analysis windows. The score of a track moves towards the score of each
+// window, faster on drops than on recoveries.
+type Scorer interface {
+	Score(in ScoreInputs) ScoreComponents
+}
+
+// ScoreWeights scale the effect of each impairment on the score, 1 is the default model and 0 ignores it
+type ScoreWeights struct {
+	Loss    float64
+	RTT     float64
+	Jitter  float64
+	Bitrate float64
+}
+
+var DefaultScoreWeights = ScoreWeights{
+	Loss:    1,
+	RTT:     1,
+	Jitter:  1,
+	Bitrate: 1,
+}
+
+var defaultScorer Scorer = WeightedScorer{Weights: DefaultScoreWeights}
+
+// SetDefaultScorer sets the scorer of the tracks which don't set one, it is called once, at startup
+func SetDefaultScorer(s Scorer) {
+	defaultScorer = s
+}
+
+// WeightedScorer scores packets on a simplified E-model of loss, RTT and jitter as outlined at
+// https://www.pingman.com/kb/article/how-is-mos-calculated-in-pingplotter-pro-50.html, the bitrate on the
+// shortfall from the expected bitrate and the layers on the distance from the expected layer.
+type WeightedScorer struct {
+	Weights ScoreWeights
+}
+
+func (s WeightedScorer) Score(in ScoreInputs) ScoreComponents {
+	return ScoreComponents{
+		Packet:  s.packetScore(in),
+		Bitrate: s.bitrateScore(in),
+		Layer:   math.Max(math.Min(cMaxScore, cMaxScore-(in.LayerDistance*cDistanceWeight)), 0.0),
+	}
+}
+
+func (s WeightedScorer) packetScore(in ScoreInputs) float64 {
+	effectiveDelay := in.RTT*s.Weights.RTT/2.0 + in.Jitter*s.Weights.Jitter*2.0
+	delayEffect := effectiveDelay / 40.0
+	if effectiveDelay > 160.0 {
+		delayEffect = (effectiveDelay - 120.0) / 10.0
+	}
+
+	score := cMaxScore - delayEffect - in.Loss*s.Weights.Loss
+	if score < 0.0 {
+		score = 0.0
+	}
+
+	return score
+}
+
+func (s WeightedScorer) bitrateScore(in ScoreInputs) float64 {
+	if in.BitrateShortfall == 0 || s.Weights.Bitrate == 0 {
+		return cMaxScore
+	}
+
+	// using the ratio of expectedBits / actualBits
+	// the quality inflection points are approximately
+	// GOOD at ~2.7x, POOR at ~20.1x
+	score := cMaxScore - 20*s.Weights.Bitrate*math.Log(in.BitrateShortfall)
+	if score > cMaxScore {
+		score = cMaxScore
+	}
+	if score < 0.0 {
+		score = 0.0
+	}
+
+	return score
+}
+
+// ------------------------------------------
+
 type qualityScorerParams struct {
 	IncludeRTT         bool
 	IncludeJitter      bool
 	EnableBitrateScore bool
+	Scorer             Scorer
 	Logger             logger.Logger
 }
 
@@ -226,6 +303,9 @@ type qualityScorer struct {
 }
 
 func newQualityScorer(params qualityScorerParams) *qualityScorer {
+	if params.Scorer == nil {
+		params.Scorer = defaultScorer
+	}
 	return &qualityScorer{
 		params: params,
 		score:  cMaxScore,
@@ -415,9 +495,8 @@ func (q *qualityScorer) updateAtLocked(stat *windowStat, at time.Time) {
 			score = qualityTransitionScore[livekit.ConnectionQuality_POOR]
 		}
 	} else {
-		packetScore = stat.calculatePacketScore(aplw, q.params.IncludeRTT, q.params.IncludeJitter)
-		bitrateScore = stat.calculateBitrateScore(expectedBits, q.params.EnableBitrateScore)
-		layerScore = math.Max(math.Min(cMaxScore, cMaxScore-(expectedDistance*cDistanceWeight)), 0.0)
+		components := q.params.Scorer.Score(stat.scoreInputs(aplw, q.params.IncludeRTT, q.params.IncludeJitter, expectedBits, q.params.EnableBitrateScore, expectedDistance))
+		packetScore, bitrateScore, layerScore = components.Packet, components.Bitrate, components.Layer
 
 		minScore := math.Min(packetScore, bitrateScore)
 		minScore = math.Min(minScore, layerScore)
@@ -435,6 +514,7 @@ func (q *qualityScorer) updateAtLocked(stat *windowStat, at time.Time) {
 			reason = "layer"
 			score = layerScore
 		}
+		prometheus.RecordQualityWindow(reason, packetScore, bitrateScore, layerScore)
 
 		factor := cIncreaseFactor
 		if score < q.score {
@@ -36,6 +36,7 @@ import (
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/utils/mono"
 
+	"github.com/livekit/livekit-server/pkg/sfu/audio"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 	"github.com/livekit/livekit-server/pkg/sfu/connectionquality"
 	"github.com/livekit/livekit-server/pkg/sfu/pacer"
@@ -44,6 +45,7 @@ import (
 	pd "github.com/livekit/livekit-server/pkg/sfu/rtpextension/playoutdelay"
 	"github.com/livekit/livekit-server/pkg/sfu/rtpstats"
 	"github.com/livekit/livekit-server/pkg/sfu/utils"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 )
 
 // TrackSender defines an interface send media to remote peer
@@ -54,6 +56,7 @@ type TrackSender interface {
 	UpTrackMaxTemporalLayerSeenChange(maxTemporalLayerSeen int32)
 	UpTrackBitrateReport(availableLayers []int32, bitrates Bitrates)
 	WriteRTP(p *buffer.ExtPacket, layer int32) error
+	WriteKeyFrameCache(pkts []*buffer.ExtPacket, layer int32)
 	Close()
 	IsClosed() bool
 	// ID is the globally unique identifier for this Track.
@@ -84,6 +87,8 @@ const (
 
 	waitBeforeSendPaddingOnMute = 100 * time.Millisecond
 	maxPaddingOnMuteDuration    = 5 * time.Second
+
+	g711SamplesPerFrame = 160
 )
 
 // -------------------------------------------------------------------
@@ -320,6 +325,11 @@ type DownTrack struct {
 	onCodecNegotiated           func(webrtc.RTPCodecCapability)
 
 	createdAt int64
+
+	// ramp up of subscribers joining in a burst, held at the lowest layer until rampHeldUntil
+	rampHeldUntil atomic.Int64
+	rampBurst     atomic.Bool
+	rampPending   atomic.Bool
 }
 
 type bindState int
@@ -406,6 +416,7 @@ func NewDownTrack(params DowntrackParams) (*DownTrack, error) {
 		}
 		go d.maxLayerNotifierWorker()
 		go d.keyFrameRequester()
+		d.rampPending.Store(true)
 	}
 
 	d.params.Receiver.AddOnReady(d.handleReceiverReady)
@@ -781,6 +792,8 @@ func (d *DownTrack) keyFrameRequester() {
 
 	defer timer.Stop()
 
+	// cached key frame is replayed once per layer lock attempt, falling back to PLI if it did not lock
+	replayedLayer := buffer.InvalidLayerSpatial
 	for !d.IsClosed() {
 		timer.Reset(getInterval())
 
@@ -796,7 +809,15 @@ func (d *DownTrack) keyFrameRequester() {
 		}
 
 		locked, layer := d.forwarder.CheckSync()
+		if locked {
+			replayedLayer = buffer.InvalidLayerSpatial
+		}
 		if !locked && layer != buffer.InvalidLayerSpatial && d.writable.Load() {
+			if layer != replayedLayer && d.params.Receiver.ReplayKeyFrame(layer, d) {
+				d.params.Logger.Debugw("replaying cached key frame for layer lock", "layer", layer)
+				replayedLayer = layer
+				continue
+			}
 			d.params.Logger.Debugw("sending PLI for layer lock", "layer", layer)
 			d.params.Receiver.SendPLI(layer, false)
 			d.rtpStats.UpdateLayerLockPliAndTime(1)
@@ -845,6 +866,26 @@ func (d *DownTrack) maxLayerNotifierWorker() {
 	}
 }
 
+// WriteKeyFrameCache writes cached key frame packets to a DownTrack that is waiting for a key frame.
+// It is a no-op if the forwarder is already locked to a layer.
+func (d *DownTrack) WriteKeyFrameCache(pkts []*buffer.ExtPacket, layer int32) {
+	if !d.writable.Load() {
+		return
+	}
+	if locked, _ := d.forwarder.CheckSync(); locked {
+		return
+	}
+
+	d.params.Logger.Debugw("writing cached key frame", "layer", layer, "numPackets", len(pkts))
+	for _, pkt := range pkts {
+		spatialLayer := layer
+		if pkt.Spatial >= 0 {
+			spatialLayer = pkt.Spatial
+		}
+		_ = d.WriteRTP(pkt, spatialLayer)
+	}
+}
+
 // WriteRTP writes an RTP Packet to the DownTrack
 func (d *DownTrack) WriteRTP(extPkt *buffer.ExtPacket, layer int32) error {
 	if !d.writable.Load() {
@@ -963,6 +1004,9 @@ func (d *DownTrack) WriteRTP(extPkt *buffer.ExtPacket, layer int32) error {
 	if extPkt.KeyFrame {
 		d.isNACKThrottled.Store(false)
 		d.rtpStats.UpdateKeyFrame(1)
+		if d.rampPending.Load() {
+			d.maybeRecordRampTime()
+		}
 		d.params.Logger.Debugw(
 			"forwarded key frame",
 			"layer", layer,
@@ -1243,6 +1287,43 @@ func (d *DownTrack) SetMaxSpatialLayer(spatialLayer int32) {
 	}
 }
 
+// HoldRamp keeps the down track at the lowest layer for hold, as it joined the track in a burst of subscribers
+func (d *DownTrack) HoldRamp(hold time.Duration) {
+	if d.kind != webrtc.RTPCodecTypeVideo {
+		return
+	}
+
+	d.rampBurst.Store(true)
+	d.rampHeldUntil.Store(time.Now().Add(hold).UnixNano())
+	d.params.Logger.Debugw("holding ramp up", "hold", hold)
+	time.AfterFunc(hold, func() {
+		if d.IsClosed() {
+			return
+		}
+		// allocated again, without holding
+		if sal := d.getStreamAllocatorListener(); sal != nil {
+			sal.OnSubscriptionChanged(d)
+		}
+	})
+}
+
+func (d *DownTrack) isRampHeld() bool {
+	return time.Now().UnixNano() < d.rampHeldUntil.Load()
+}
+
+// maybeRecordRampTime records the time taken to forward the layer allocated to the down track, once
+func (d *DownTrack) maybeRecordRampTime() {
+	if d.isRampHeld() || d.forwarder.IsDeficient() {
+		return
+	}
+	if current, target := d.forwarder.CurrentLayer(), d.forwarder.TargetLayer(); current.Spatial != target.Spatial {
+		return
+	}
+	if d.rampPending.CompareAndSwap(true, false) {
+		prometheus.RecordTrackRampTime(d.rampBurst.Load(), time.Duration(time.Now().UnixNano()-d.createdAt))
+	}
+}
+
 func (d *DownTrack) SetMaxTemporalLayer(temporalLayer int32) {
 	changed, maxLayer := d.forwarder.SetMaxTemporalLayer(temporalLayer)
 	if !changed {
@@ -1254,10 +1335,20 @@ func (d *DownTrack) SetMaxTemporalLayer(temporalLayer int32) {
 	}
 }
 
+// SetFrameDecimation drops frames not used for reference to lower the frame rate sent to low priority subscribers
+func (d *DownTrack) SetFrameDecimation(enabled bool) {
+	d.forwarder.SetFrameDecimation(enabled)
+}
+
 func (d *DownTrack) MaxLayer() buffer.VideoLayer {
 	return d.forwarder.MaxLayer()
 }
 
+// CurrentLayer returns the layer being forwarded, invalid when nothing is forwarded
+func (d *DownTrack) CurrentLayer() buffer.VideoLayer {
+	return d.forwarder.CurrentLayer()
+}
+
 func (d *DownTrack) GetState() DownTrackState {
 	dts := DownTrackState{
 		RTPStats:                   d.rtpStats,
@@ -1432,7 +1523,7 @@ func (d *DownTrack) DistanceToDesired() float64 {
 
 func (d *DownTrack) AllocateOptimal(allowOvershoot bool, hold bool) VideoAllocation {
 	al, brs := d.params.Receiver.GetLayeredBitrate()
-	allocation := d.forwarder.AllocateOptimal(al, brs, allowOvershoot, hold)
+	allocation := d.forwarder.AllocateOptimal(al, brs, allowOvershoot, hold || d.isRampHeld())
 	d.postKeyFrameRequestEvent()
 	d.maybeAddTransition(allocation.BandwidthNeeded, allocation.DistanceToDesired, allocation.PauseReason)
 	return allocation
@@ -1481,6 +1572,10 @@ func (d *DownTrack) ProvisionalAllocateCommit() VideoAllocation {
 }
 
 func (d *DownTrack) AllocateNextHigher(availableChannelCapacity int64, allowOvershoot bool) (VideoAllocation, bool) {
+	if d.isRampHeld() {
+		return d.forwarder.LastAllocation(), false
+	}
+
 	al, brs := d.params.Receiver.GetLayeredBitrate()
 	allocation, available := d.forwarder.AllocateNextHigher(availableChannelCapacity, al, brs, allowOvershoot)
 	d.postKeyFrameRequestEvent()
@@ -1558,6 +1653,10 @@ func (d *DownTrack) writeBlankFrameRTP(duration float32, generation uint32) chan
 			getBlankFrame = d.getOpusBlankFrame
 		case strings.ToLower(MimeTypeAudioRed):
 			getBlankFrame = d.getOpusRedBlankFrame
+		case strings.ToLower(webrtc.MimeTypePCMU):
+			getBlankFrame = d.getPCMUBlankFrame
+		case strings.ToLower(webrtc.MimeTypePCMA):
+			getBlankFrame = d.getPCMABlankFrame
 		case strings.ToLower(webrtc.MimeTypeVP8):
 			getBlankFrame = d.getVP8BlankFrame
 		case strings.ToLower(webrtc.MimeTypeH264):
@@ -1568,7 +1667,7 @@ func (d *DownTrack) writeBlankFrameRTP(duration float32, generation uint32) chan
 		}
 
 		frameRate := uint32(30)
-		if d.mime == strings.ToLower(webrtc.MimeTypeOpus) || d.mime == strings.ToLower(MimeTypeAudioRed) {
+		if d.mime != strings.ToLower(webrtc.MimeTypeVP8) && d.mime != strings.ToLower(webrtc.MimeTypeH264) {
 			frameRate = 50
 		}
 
@@ -1677,6 +1776,23 @@ func (d *DownTrack) getOpusRedBlankFrame(_frameEndNeeded bool) ([]byte, error) {
 	return payload[:1+len(OpusSilenceFrame)+trailerLen], nil
 }
 
+func (d *DownTrack) getPCMUBlankFrame(_frameEndNeeded bool) ([]byte, error) {
+	return d.getG711BlankFrame(audio.ULawSilence)
+}
+
+func (d *DownTrack) getPCMABlankFrame(_frameEndNeeded bool) ([]byte, error) {
+	return d.getG711BlankFrame(audio.ALawSilence)
+}
+
+func (d *DownTrack) getG711BlankFrame(silence byte) ([]byte, error) {
+	payload := make([]byte, 1000)
+	for i := 0; i < g711SamplesPerFrame; i++ {
+		payload[i] = silence
+	}
+	trailerLen := d.maybeAddTrailer(payload[g711SamplesPerFrame:])
+	return payload[:g711SamplesPerFrame+trailerLen], nil
+}
+
 func (d *DownTrack) getVP8BlankFrame(frameEndNeeded bool) ([]byte, error) {
 	// 8x8 key frame
 	// Used even when closing out a previous frame. Looks like receivers
@@ -218,6 +218,7 @@ type Forwarder struct {
 	muted                 bool
 	pubMuted              bool
 	resumeBehindThreshold float64
+	frameDecimation       bool
 
 	started                 bool
 	preStartTime            time.Time
@@ -313,6 +314,7 @@ func (f *Forwarder) DetermineCodec(codec webrtc.RTPCodecCapability, extensions [
 	switch strings.ToLower(codec.MimeType) {
 	case "video/vp8":
 		f.codecMunger = codecmunger.NewVP8FromNull(f.codecMunger, f.logger)
+		f.codecMunger.SetFrameDecimation(f.frameDecimation)
 		if f.vls != nil {
 			f.vls = videolayerselector.NewSimulcastFromNull(f.vls)
 		} else {
@@ -425,6 +427,21 @@ func (f *Forwarder) SeedState(state *livekit.RTPForwarderState) {
 	f.dummyStartTSOffset = state.DummyStartTimestampOffset
 }
 
+// SetFrameDecimation enables dropping of video frames that the publisher marks as not used for reference,
+// reducing the frame rate without relying on temporal layers. It applies to VP8 and H.264.
+func (f *Forwarder) SetFrameDecimation(enabled bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.kind != webrtc.RTPCodecTypeVideo || f.frameDecimation == enabled {
+		return
+	}
+
+	f.logger.Debugw("setting frame decimation", "enabled", enabled)
+	f.frameDecimation = enabled
+	f.codecMunger.SetFrameDecimation(enabled)
+}
+
 func (f *Forwarder) Mute(muted bool, isSubscribeMutable bool) bool {
 	f.lock.Lock()
 	defer f.lock.Unlock()
@@ -667,6 +684,13 @@ func (f *Forwarder) GetSenderReportParams() (int32, uint64, *livekit.RTCPSenderR
 	return currentLayerSpatial, f.refInfos[refLayer].tsOffset, f.refInfos[refLayer].senderReport
 }
 
+func (f *Forwarder) LastAllocation() VideoAllocation {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.lastAllocation
+}
+
 func (f *Forwarder) isDeficientLocked() bool {
 	return f.lastAllocation.IsDeficient
 }
@@ -2013,9 +2037,9 @@ func (f *Forwarder) translateCodecHeader(extPkt *buffer.ExtPacket, tp *Translati
 	)
 	if err != nil {
 		tp.shouldDrop = true
-		if err == codecmunger.ErrFilteredVP8TemporalLayer || err == codecmunger.ErrOutOfOrderVP8PictureIdCacheMiss {
-			if err == codecmunger.ErrFilteredVP8TemporalLayer {
-				// filtered temporal layer, update sequence number offset to prevent holes
+		if err == codecmunger.ErrFilteredVP8TemporalLayer || err == codecmunger.ErrFilteredVP8NonReferenceFrame || err == codecmunger.ErrOutOfOrderVP8PictureIdCacheMiss {
+			if err != codecmunger.ErrOutOfOrderVP8PictureIdCacheMiss {
+				// filtered temporal layer or frame, update sequence number offset to prevent holes
 				f.rtpMunger.PacketDropped(extPkt)
 			}
 			return nil
@@ -2025,6 +2049,17 @@ func (f *Forwarder) translateCodecHeader(extPkt *buffer.ExtPacket, tp *Translati
 	}
 	tp.incomingHeaderSize = inputSize
 	tp.codecBytes = codecBytes
+
+	// H.264 has no codec header to munge, non-reference pictures are dropped here.
+	// Like filtered VP8 temporal layers, packets following a gap are forwarded.
+	if f.frameDecimation &&
+		!extPkt.KeyFrame &&
+		tp.rtp.snOrdering == SequenceNumberOrderingContiguous &&
+		strings.EqualFold(f.codec.MimeType, webrtc.MimeTypeH264) &&
+		buffer.IsH264NonReference(extPkt.Packet.Payload) {
+		tp.shouldDrop = true
+		f.rtpMunger.PacketDropped(extPkt)
+	}
 	return nil
 }
 
@@ -0,0 +1,222 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sfu
+
+import (
+	"strings"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/audio"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+var (
+	PCMUCodecParameters = webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypePCMU, ClockRate: 8000},
+		PayloadType:        0,
+	}
+	PCMACodecParameters = webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypePCMA, ClockRate: 8000},
+		PayloadType:        8,
+	}
+)
+
+// G711TranscodingConfig converts G.711 tracks between µ-law and A-law for subscribers which only support
+// the other law, SIP legs mostly. A track is converted once for all of its subscribers.
+type G711TranscodingConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// tracks converted at the same time on a node, 0 for no limit
+	MaxTracks int `yaml:"max_tracks,omitempty"`
+}
+
+// G711Transcoder bounds the number of G.711 tracks converted on the node
+type G711Transcoder struct {
+	maxTracks int32
+	tracks    atomic.Int32
+}
+
+// NewG711Transcoder returns nil when G.711 transcoding is disabled
+func NewG711Transcoder(conf G711TranscodingConfig) *G711Transcoder {
+	if !conf.Enabled {
+		return nil
+	}
+	return &G711Transcoder{
+		maxTracks: int32(conf.MaxTracks),
+	}
+}
+
+// CanTranscode returns whether a track of mimeType could be sent as the other law, there could be no room
+// for it once a subscriber binds
+func (t *G711Transcoder) CanTranscode(mimeType string) bool {
+	if _, ok := G711Counterpart(mimeType); !ok || t == nil {
+		return false
+	}
+	return t.maxTracks == 0 || t.tracks.Load() < t.maxTracks
+}
+
+func (t *G711Transcoder) acquire(from, to string) bool {
+	if n := t.tracks.Inc(); t.maxTracks != 0 && n > t.maxTracks {
+		t.tracks.Dec()
+		prometheus.RecordG711TranscodeRejected(from, to)
+		return false
+	}
+	prometheus.AddG711Transcode(from, to)
+	return true
+}
+
+func (t *G711Transcoder) release(from, to string) {
+	t.tracks.Dec()
+	prometheus.SubG711Transcode(from, to)
+}
+
+// G711Counterpart returns the codec of the other law, false for other mime types
+func G711Counterpart(mimeType string) (webrtc.RTPCodecParameters, bool) {
+	switch {
+	case strings.EqualFold(mimeType, webrtc.MimeTypePCMU):
+		return PCMACodecParameters, true
+	case strings.EqualFold(mimeType, webrtc.MimeTypePCMA):
+		return PCMUCodecParameters, true
+	default:
+		return webrtc.RTPCodecParameters{}, false
+	}
+}
+
+// --------------------------------------
+
+// G711Receiver forwards the packets of a G.711 track converted to the other law. Samples are converted with
+// a lookup table, in the receiver's forwarding goroutine, whatever the number of subscribers.
+type G711Receiver struct {
+	TrackReceiver
+	downTrackSpreader *DownTrackSpreader
+	transcoder        *G711Transcoder
+	codec             webrtc.RTPCodecParameters
+	convert           func(dst, src []byte)
+	logger            logger.Logger
+	closed            atomic.Bool
+	payloadBuf        [mtuSize]byte
+}
+
+// NewG711Receiver returns nil when the transcoder has no room for another track
+func NewG711Receiver(receiver TrackReceiver, transcoder *G711Transcoder, dsp DownTrackSpreaderParams) *G711Receiver {
+	from := receiver.Codec().MimeType
+	codec, ok := G711Counterpart(from)
+	if !ok || !transcoder.acquire(from, codec.MimeType) {
+		return nil
+	}
+	convert := audio.ULawToALaw
+	if codec.MimeType == webrtc.MimeTypePCMU {
+		convert = audio.ALawToULaw
+	}
+	return &G711Receiver{
+		TrackReceiver:     receiver,
+		downTrackSpreader: NewDownTrackSpreader(dsp),
+		transcoder:        transcoder,
+		codec:             codec,
+		convert:           convert,
+		logger:            dsp.Logger,
+	}
+}
+
+func (r *G711Receiver) Codec() webrtc.RTPCodecParameters {
+	return r.codec
+}
+
+func (r *G711Receiver) ForwardRTP(pkt *buffer.ExtPacket, spatialLayer int32) int {
+	if r.downTrackSpreader.DownTrackCount() == 0 || len(pkt.Packet.Payload) > len(r.payloadBuf) {
+		return 0
+	}
+
+	payload := r.payloadBuf[:len(pkt.Packet.Payload)]
+	r.convert(payload, pkt.Packet.Payload)
+
+	pPkt := *pkt
+	rtpPacket := *pkt.Packet
+	rtpPacket.PayloadType = uint8(r.codec.PayloadType)
+	rtpPacket.Payload = payload
+	pPkt.Packet = &rtpPacket
+
+	// not modify the ExtPacket.RawPacket here for performance since it is not used by the DownTrack
+	return r.downTrackSpreader.Broadcast(func(dt TrackSender) {
+		_ = dt.WriteRTP(&pPkt, spatialLayer)
+	})
+}
+
+func (r *G711Receiver) AddDownTrack(track TrackSender) error {
+	if r.closed.Load() {
+		return ErrReceiverClosed
+	}
+
+	if r.downTrackSpreader.HasDownTrack(track.SubscriberID()) {
+		r.logger.Infow("subscriberID already exists, replacing downtrack", "subscriberID", track.SubscriberID())
+	}
+
+	r.downTrackSpreader.Store(track)
+	r.logger.Debugw("g711 receiver downtrack added", "subscriberID", track.SubscriberID())
+	return nil
+}
+
+func (r *G711Receiver) DeleteDownTrack(subscriberID livekit.ParticipantID) {
+	if r.closed.Load() {
+		return
+	}
+
+	r.downTrackSpreader.Free(subscriberID)
+	r.logger.Debugw("g711 receiver downtrack deleted", "subscriberID", subscriberID)
+}
+
+func (r *G711Receiver) ResyncDownTracks() {
+	r.downTrackSpreader.Broadcast(func(dt TrackSender) {
+		dt.Resync()
+	})
+}
+
+func (r *G711Receiver) CanClose() bool {
+	return r.closed.Load() || r.downTrackSpreader.DownTrackCount() == 0
+}
+
+func (r *G711Receiver) IsClosed() bool {
+	return r.closed.Load()
+}
+
+func (r *G711Receiver) Close() {
+	if r.closed.Swap(true) {
+		return
+	}
+	r.transcoder.release(r.TrackReceiver.Codec().MimeType, r.codec.MimeType)
+	closeTrackSenders(r.downTrackSpreader.ResetAndGetDownTracks())
+}
+
+// ReadRTP converts retransmitted packets like forwarded ones
+func (r *G711Receiver) ReadRTP(buf []byte, layer uint8, esn uint64) (int, error) {
+	n, err := r.TrackReceiver.ReadRTP(buf, layer, esn)
+	if err != nil {
+		return n, err
+	}
+
+	var pkt rtp.Packet
+	if err = pkt.Unmarshal(buf[:n]); err != nil {
+		return 0, err
+	}
+	r.convert(pkt.Payload, pkt.Payload)
+	pkt.PayloadType = uint8(r.codec.PayloadType)
+	return pkt.MarshalTo(buf)
+}
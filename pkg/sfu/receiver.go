@@ -70,6 +70,8 @@ type AudioConfig struct {
 	ActiveREDEncoding bool `yaml:"active_red_encoding,omitempty"`
 	// enable proxying weakest subscriber loss to publisher in RTCP Receiver Report
 	EnableLossProxying bool `yaml:"enable_loss_proxying,omitempty"`
+	// convert G.711 tracks for subscribers which only support the other law
+	G711Transcoding G711TranscodingConfig `yaml:"g711_transcoding,omitempty"`
 }
 
 var (
@@ -98,6 +100,9 @@ type TrackReceiver interface {
 	GetAudioLevel() (float64, bool)
 
 	SendPLI(layer int32, force bool)
+	// ReplayKeyFrame queues the cached key frame of the layer for delivery to the track,
+	// returns false if no cached key frame is available
+	ReplayKeyFrame(layer int32, track TrackSender) bool
 
 	SetUpTrackPaused(paused bool)
 	SetMaxExpectedSpatialLayer(layer int32)
@@ -169,7 +174,17 @@ type WebRTCReceiver struct {
 	redReceiver     atomic.Pointer[RedReceiver]
 	redPktWriter    atomic.Value // redPktWriteFunc
 
+	g711Transcoder *G711Transcoder
+	g711Receiver   atomic.Pointer[G711Receiver]
+
+	subscribeRamp *subscribeRamp
+
 	forwardStats *ForwardStats
+
+	keyFrameCacheSize int
+	keyFrameReplayMu  sync.Mutex
+	keyFrameReplays   [buffer.DefaultMaxLayerSpatial + 1][]TrackSender
+	keyFrameReplaying [buffer.DefaultMaxLayerSpatial + 1]atomic.Bool
 }
 
 type ReceiverOpts func(w *WebRTCReceiver) *WebRTCReceiver
@@ -217,6 +232,31 @@ func WithForwardStats(forwardStats *ForwardStats) ReceiverOpts {
 	}
 }
 
+// WithKeyFrameCache enables caching of the latest key frame of each video layer, so that
+// new subscribers can be started without a PLI round trip to the publisher
+func WithKeyFrameCache(maxPackets int) ReceiverOpts {
+	return func(w *WebRTCReceiver) *WebRTCReceiver {
+		w.keyFrameCacheSize = maxPackets
+		return w
+	}
+}
+
+// WithG711Transcoder allows forwarding G.711 tracks converted to the other law
+func WithG711Transcoder(transcoder *G711Transcoder) ReceiverOpts {
+	return func(w *WebRTCReceiver) *WebRTCReceiver {
+		w.g711Transcoder = transcoder
+		return w
+	}
+}
+
+// WithSubscribeRamp paces the ramp up of subscribers joining the track in a burst
+func WithSubscribeRamp(conf SubscribeRampConfig) ReceiverOpts {
+	return func(w *WebRTCReceiver) *WebRTCReceiver {
+		w.subscribeRamp = newSubscribeRamp(conf)
+		return w
+	}
+}
+
 // NewWebRTCReceiver creates a new webrtc track receiver
 func NewWebRTCReceiver(
 	receiver *webrtc.RTPReceiver,
@@ -374,6 +414,9 @@ func (w *WebRTCReceiver) AddUpTrack(track *webrtc.TrackRemote, buff *buffer.Buff
 		Config: w.audioConfig.AudioLevelConfig,
 	})
 	buff.SetAudioLossProxying(w.audioConfig.EnableLossProxying)
+	if w.Kind() == webrtc.RTPCodecTypeVideo && w.keyFrameCacheSize > 0 {
+		buff.EnableKeyFrameCache(w.keyFrameCacheSize)
+	}
 	buff.OnRtcpFeedback(w.sendRTCP)
 	buff.OnRtcpSenderReport(func() {
 		srData := buff.GetSenderReportData()
@@ -449,6 +492,14 @@ func (w *WebRTCReceiver) AddDownTrack(track TrackSender) error {
 	track.UpTrackMaxPublishedLayerChange(w.streamTrackerManager.GetMaxPublishedLayer())
 	track.UpTrackMaxTemporalLayerSeenChange(w.streamTrackerManager.GetMaxTemporalLayerSeen())
 
+	if w.kind == webrtc.RTPCodecTypeVideo {
+		if hold := w.subscribeRamp.hold(time.Now()); hold > 0 {
+			if holder, ok := track.(subscribeRampHolder); ok {
+				holder.HoldRamp(hold)
+			}
+		}
+	}
+
 	w.downTrackSpreader.Store(track)
 	w.logger.Debugw("downtrack added", "subscriberID", track.SubscriberID())
 	return nil
@@ -578,6 +629,47 @@ func (w *WebRTCReceiver) SendPLI(layer int32, force bool) {
 	buff.SendPLI(force)
 }
 
+func (w *WebRTCReceiver) ReplayKeyFrame(layer int32, track TrackSender) bool {
+	if w.isSVC {
+		layer = 0
+	}
+	buff := w.getBuffer(layer)
+	if buff == nil || len(buff.GetKeyFrameCache()) == 0 {
+		return false
+	}
+
+	// replay happens on the forwarding goroutine of the layer so that cached packets
+	// are delivered in order and before any newer live packet
+	w.keyFrameReplayMu.Lock()
+	w.keyFrameReplays[layer] = append(w.keyFrameReplays[layer], track)
+	w.keyFrameReplaying[layer].Store(true)
+	w.keyFrameReplayMu.Unlock()
+	return true
+}
+
+func (w *WebRTCReceiver) replayKeyFrames(layer int32, buff *buffer.Buffer) {
+	if !w.keyFrameReplaying[layer].Load() {
+		return
+	}
+
+	w.keyFrameReplayMu.Lock()
+	tracks := w.keyFrameReplays[layer]
+	w.keyFrameReplays[layer] = nil
+	w.keyFrameReplaying[layer].Store(false)
+	w.keyFrameReplayMu.Unlock()
+
+	pkts := buff.GetKeyFrameCache()
+	if len(pkts) == 0 {
+		return
+	}
+	for _, track := range tracks {
+		if track.IsClosed() {
+			continue
+		}
+		track.WriteKeyFrameCache(pkts, layer)
+	}
+}
+
 func (w *WebRTCReceiver) getBuffer(layer int32) *buffer.Buffer {
 	w.bufferMu.RLock()
 	defer w.bufferMu.RUnlock()
@@ -705,6 +797,9 @@ func (w *WebRTCReceiver) forwardRTP(layer int32, buff *buffer.Buffer) {
 			if pr := w.redReceiver.Load(); pr != nil {
 				pr.Close()
 			}
+			if gr := w.g711Receiver.Load(); gr != nil {
+				gr.Close()
+			}
 		})
 
 		w.streamTrackerManager.RemoveTracker(layer)
@@ -740,6 +835,11 @@ func (w *WebRTCReceiver) forwardRTP(layer int32, buff *buffer.Buffer) {
 		if f := w.redPktWriter.Load(); f != nil {
 			writeCount += f.(redPktWriteFunc)(pkt, spatialLayer)
 		}
+		if gr := w.g711Receiver.Load(); gr != nil {
+			writeCount += gr.ForwardRTP(pkt, spatialLayer)
+		}
+
+		w.replayKeyFrames(layer, buff)
 
 		// track delay/jitter
 		if writeCount > 0 && w.forwardStats != nil {
@@ -842,6 +942,28 @@ func (w *WebRTCReceiver) GetRedReceiver() TrackReceiver {
 	return w.redReceiver.Load()
 }
 
+// GetG711Receiver returns the receiver forwarding a G.711 track converted to the other law, nil when
+// the track is not G.711, transcoding is disabled or the node converts as many tracks as it may
+func (w *WebRTCReceiver) GetG711Receiver() TrackReceiver {
+	if w.g711Transcoder == nil || w.closed.Load() {
+		return nil
+	}
+
+	if w.g711Receiver.Load() == nil {
+		gr := NewG711Receiver(w, w.g711Transcoder, DownTrackSpreaderParams{
+			Threshold: w.lbThreshold,
+			Logger:    w.logger,
+		})
+		if gr == nil {
+			return nil
+		}
+		if !w.g711Receiver.CompareAndSwap(nil, gr) {
+			gr.Close()
+		}
+	}
+	return w.g711Receiver.Load()
+}
+
 func (w *WebRTCReceiver) GetTemporalLayerFpsForSpatial(layer int32) []float32 {
 	b := w.getBuffer(layer)
 	if b == nil {
@@ -86,6 +86,7 @@ const (
 	streamAllocatorSignalResume
 	streamAllocatorSignalSetAllowPause
 	streamAllocatorSignalSetChannelCapacity
+	streamAllocatorSignalSetMaxPublisherShare
 	// STREAM-ALLOCATOR-DATA streamAllocatorSignalNACK
 	// STREAM-ALLOCATOR-DATA streamAllocatorSignalRTCPReceiverReport
 	streamAllocatorSignalCongestionStateChange
@@ -113,6 +114,8 @@ func (s streamAllocatorSignal) String() string {
 		return "SET_ALLOW_PAUSE"
 	case streamAllocatorSignalSetChannelCapacity:
 		return "SET_CHANNEL_CAPACITY"
+	case streamAllocatorSignalSetMaxPublisherShare:
+		return "SET_MAX_PUBLISHER_SHARE"
 		/* STREAM-ALLOCATOR-DATA
 		case streamAllocatorSignalNACK:
 			return "NACK"
@@ -155,6 +158,9 @@ type StreamAllocatorConfig struct {
 	MinChannelCapacity               int64                 `yaml:"min_channel_capacity,omitempty"`
 	ProbeController                  ProbeControllerConfig `yaml:"probe_controller,omitempty"`
 	DisableEstimationUnmanagedTracks bool                  `yaml:"disable_etimation_unmanaged_tracks,omitempty"`
+	// fraction of the channel capacity the tracks of a single publisher may use while several publishers
+	// compete for it, 0 disables the limit
+	MaxPublisherShare float64 `yaml:"max_publisher_share,omitempty"`
 }
 
 var (
@@ -179,8 +185,9 @@ type StreamAllocator struct {
 	bwe                    bwe.BWE
 	sendSideBWEInterceptor cc.BandwidthEstimator
 
-	enabled    bool
-	allowPause bool
+	enabled           bool
+	allowPause        bool
+	maxPublisherShare float64
 
 	committedChannelCapacity  int64
 	overriddenChannelCapacity int64
@@ -207,9 +214,10 @@ type StreamAllocator struct {
 
 func NewStreamAllocator(params StreamAllocatorParams, enabled bool, allowPause bool) *StreamAllocator {
 	s := &StreamAllocator{
-		params:     params,
-		enabled:    enabled,
-		allowPause: allowPause,
+		params:            params,
+		enabled:           enabled,
+		allowPause:        allowPause,
+		maxPublisherShare: params.Config.MaxPublisherShare,
 		// STREAM-ALLOCATOR-DATA rateMonitor: NewRateMonitor(),
 		videoTracks: make(map[livekit.TrackID]*Track),
 		eventsQueue: utils.NewTypedOpsQueue[Event](utils.OpsQueueParams{
@@ -345,6 +353,14 @@ func (s *StreamAllocator) SetChannelCapacity(channelCapacity int64) {
 	})
 }
 
+// SetMaxPublisherShare changes the fraction of the channel capacity a single publisher may use, 0 disables the limit
+func (s *StreamAllocator) SetMaxPublisherShare(share float64) {
+	s.postEvent(Event{
+		Signal: streamAllocatorSignalSetMaxPublisherShare,
+		Data:   share,
+	})
+}
+
 func (s *StreamAllocator) resetState() {
 	if s.bwe != nil {
 		s.bwe.Reset()
@@ -660,6 +676,8 @@ func (s *StreamAllocator) postEvent(event Event) {
 			event.handleSignalSetAllowPause(event)
 		case streamAllocatorSignalSetChannelCapacity:
 			event.handleSignalSetChannelCapacity(event)
+		case streamAllocatorSignalSetMaxPublisherShare:
+			event.handleSignalSetMaxPublisherShare(event)
 			/* STREAM-ALLOCATOR-DATA
 			case streamAllocatorSignalNACK:
 				event.s.handleSignalNACK(event)
@@ -784,6 +802,17 @@ func (s *StreamAllocator) handleSignalSetAllowPause(event Event) {
 	s.allowPause = event.Data.(bool)
 }
 
+func (s *StreamAllocator) handleSignalSetMaxPublisherShare(event Event) {
+	share := event.Data.(float64)
+	if s.maxPublisherShare == share {
+		return
+	}
+
+	s.params.Logger.Infow("allocating with max publisher share", "share", share)
+	s.maxPublisherShare = share
+	s.allocateAllTracks()
+}
+
 func (s *StreamAllocator) handleSignalSetChannelCapacity(event Event) {
 	s.overriddenChannelCapacity = event.Data.(int64)
 	if s.overriddenChannelCapacity > 0 {
@@ -1089,11 +1118,19 @@ func (s *StreamAllocator) maybeBoostDeficientTracks() {
 
 	update := NewStreamStateUpdate()
 
+	// a publisher at its share of the capacity is boosted only when it is the only one that is deficient
+	publisherLimit := s.getPublisherLimit(s.getSorted(), s.getAvailableChannelCapacity(true))
+
 	sortedTracks := s.getMaxDistanceSortedDeficient()
 boost_loop:
 	for {
 		for idx, track := range sortedTracks {
-			allocation, boosted := track.AllocateNextHigher(availableChannelCapacity, FlagAllowOvershootInCatchup)
+			trackChannelCapacity := availableChannelCapacity
+			if publisherLimit > 0 && hasOtherPublisher(sortedTracks, track.PublisherID()) {
+				trackChannelCapacity = min(trackChannelCapacity, max(publisherLimit-s.getPublisherUsage(track.PublisherID()), 0))
+			}
+
+			allocation, boosted := track.AllocateNextHigher(trackChannelCapacity, FlagAllowOvershootInCatchup)
 			if !boosted {
 				if idx == len(sortedTracks)-1 {
 					// all tracks tried
@@ -1182,6 +1219,15 @@ func (s *StreamAllocator) allocateAllTracks() {
 			track.ProvisionalAllocatePrepare()
 		}
 
+		//
+		// Once streaming, tracks of a publisher cannot go past the share of the capacity allowed to a publisher,
+		// so that a single publisher (for example a high bitrate screen share) does not starve the others.
+		// The lowest layer a track can stream is not limited to keep tracks from being paused.
+		//
+		publisherLimit := s.getPublisherLimit(sorted, availableChannelCapacity)
+		publisherUsage := make(map[livekit.ParticipantID]int64)
+		streaming := make(map[livekit.TrackID]bool)
+
 		for spatial := int32(0); spatial <= buffer.DefaultMaxLayerSpatial; spatial++ {
 			for temporal := int32(0); temporal <= buffer.DefaultMaxLayerTemporal; temporal++ {
 				layer := buffer.VideoLayer{
@@ -1190,7 +1236,16 @@ func (s *StreamAllocator) allocateAllTracks() {
 				}
 
 				for _, track := range sorted {
-					_, usedChannelCapacity := track.ProvisionalAllocate(availableChannelCapacity, layer, s.allowPause, FlagAllowOvershootWhileDeficient)
+					trackChannelCapacity := availableChannelCapacity
+					if publisherLimit > 0 && streaming[track.ID()] {
+						trackChannelCapacity = min(trackChannelCapacity, max(publisherLimit-publisherUsage[track.PublisherID()], 0))
+					}
+
+					isAllocated, usedChannelCapacity := track.ProvisionalAllocate(trackChannelCapacity, layer, s.allowPause, FlagAllowOvershootWhileDeficient)
+					if isAllocated {
+						streaming[track.ID()] = true
+					}
+					publisherUsage[track.PublisherID()] += usedChannelCapacity
 					availableChannelCapacity -= usedChannelCapacity
 					if availableChannelCapacity < 0 {
 						availableChannelCapacity = 0
@@ -1389,6 +1444,40 @@ func (s *StreamAllocator) getSorted() TrackSorter {
 	return trackSorter
 }
 
+// getPublisherLimit returns the capacity the managed tracks of a single publisher may use out of channelCapacity,
+// 0 when there is no limit, either as it is disabled or as all tracks are from the same publisher
+func (s *StreamAllocator) getPublisherLimit(tracks []*Track, channelCapacity int64) int64 {
+	if s.maxPublisherShare <= 0 || s.maxPublisherShare >= 1 || len(tracks) == 0 {
+		return 0
+	}
+	if !hasOtherPublisher(tracks, tracks[0].PublisherID()) {
+		return 0
+	}
+	return int64(float64(channelCapacity) * s.maxPublisherShare)
+}
+
+func (s *StreamAllocator) getPublisherUsage(publisherID livekit.ParticipantID) int64 {
+	s.videoTracksMu.RLock()
+	defer s.videoTracksMu.RUnlock()
+
+	usage := int64(0)
+	for _, track := range s.videoTracks {
+		if track.IsManaged() && track.PublisherID() == publisherID {
+			usage += track.BandwidthRequested()
+		}
+	}
+	return usage
+}
+
+func hasOtherPublisher(tracks []*Track, publisherID livekit.ParticipantID) bool {
+	for _, track := range tracks {
+		if track.PublisherID() != publisherID {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *StreamAllocator) getMinDistanceSorted(exclude *Track) MinDistanceSorter {
 	s.videoTracksMu.RLock()
 	var minDistanceSorter MinDistanceSorter
@@ -0,0 +1,97 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sfu
+
+import (
+	"sync"
+	"time"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// SubscribeRampConfig paces the subscribers joining a video track in a burst, at the start of a webinar for
+// instance. They start at the lowest layer, for which a single key frame serves all of them, and are let up to
+// the layer they are allocated one after the other over ramp_duration, rather than all asking the publisher
+// for key frames of the higher layers at once.
+type SubscribeRampConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// subscribers joining a track within burst_window for them to be a burst
+	BurstThreshold int           `yaml:"burst_threshold,omitempty"`
+	BurstWindow    time.Duration `yaml:"burst_window,omitempty"`
+	// time over which the subscribers of a burst are let up
+	RampDuration time.Duration `yaml:"ramp_duration,omitempty"`
+}
+
+var DefaultSubscribeRampConfig = SubscribeRampConfig{
+	BurstThreshold: 20,
+	BurstWindow:    2 * time.Second,
+	RampDuration:   10 * time.Second,
+}
+
+// subscribeRampHolder is implemented by the senders which can be held at the lowest layer
+type subscribeRampHolder interface {
+	HoldRamp(hold time.Duration)
+}
+
+type subscribeRamp struct {
+	conf SubscribeRampConfig
+
+	lock        sync.Mutex
+	joins       []time.Time
+	inBurst     bool
+	nextRelease time.Time
+}
+
+// newSubscribeRamp returns nil when subscribers are not paced
+func newSubscribeRamp(conf SubscribeRampConfig) *subscribeRamp {
+	if !conf.Enabled || conf.BurstThreshold <= 0 || conf.BurstWindow <= 0 || conf.RampDuration <= 0 {
+		return nil
+	}
+	return &subscribeRamp{conf: conf}
+}
+
+// hold returns how long a subscriber joining at now is held at the lowest layer, 0 outside of bursts
+func (r *subscribeRamp) hold(now time.Time) time.Duration {
+	if r == nil {
+		return 0
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	start := now.Add(-r.conf.BurstWindow)
+	i := 0
+	for i < len(r.joins) && r.joins[i].Before(start) {
+		i++
+	}
+	r.joins = append(r.joins[i:], now)
+	if len(r.joins) < r.conf.BurstThreshold {
+		r.inBurst = false
+		return 0
+	}
+	if !r.inBurst {
+		r.inBurst = true
+		r.nextRelease = now
+		prometheus.RecordTrackSubscribeBurst()
+	}
+
+	// releases are spread over the ramp duration, starting over from the beginning once it is full
+	interval := r.conf.RampDuration / time.Duration(r.conf.BurstThreshold)
+	r.nextRelease = r.nextRelease.Add(interval)
+	if r.nextRelease.Before(now) || r.nextRelease.After(now.Add(r.conf.RampDuration)) {
+		r.nextRelease = now.Add(interval)
+	}
+	return r.nextRelease.Sub(now)
+}
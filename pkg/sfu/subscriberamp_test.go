@@ -0,0 +1,46 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sfu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeRamp(t *testing.T) {
+	require.Nil(t, newSubscribeRamp(DefaultSubscribeRampConfig))
+
+	conf := DefaultSubscribeRampConfig
+	conf.Enabled = true
+	conf.BurstThreshold = 4
+	r := newSubscribeRamp(conf)
+	interval := conf.RampDuration / 4
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		require.Zero(t, r.hold(now))
+	}
+
+	// the burst starts with the fourth subscriber, releases are spread over the ramp duration
+	for i := 1; i <= 4; i++ {
+		require.Equal(t, time.Duration(i)*interval, r.hold(now))
+	}
+	require.Equal(t, interval, r.hold(now))
+
+	// a subscriber joining once the burst is over is not held
+	require.Zero(t, r.hold(now.Add(conf.BurstWindow+time.Millisecond)))
+}
@@ -27,6 +27,7 @@ import (
 
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/utils"
 )
 
 //counterfeiter:generate . AnalyticsService
@@ -40,16 +41,18 @@ type analyticsService struct {
 	analyticsKey   string
 	nodeID         string
 	sequenceNumber atomic.Uint64
+	retention      *dataRetention
 
 	events    rpc.AnalyticsRecorderService_IngestEventsClient
 	stats     rpc.AnalyticsRecorderService_IngestStatsClient
 	nodeRooms rpc.AnalyticsRecorderService_IngestNodeRoomStatesClient
 }
 
-func NewAnalyticsService(_ *config.Config, currentNode routing.LocalNode) AnalyticsService {
+func NewAnalyticsService(conf *config.Config, currentNode routing.LocalNode) AnalyticsService {
 	return &analyticsService{
 		analyticsKey: "", // TODO: conf.AnalyticsKey
 		nodeID:       string(currentNode.NodeID()),
+		retention:    newDataRetention(conf.DataRetention),
 	}
 }
 
@@ -73,6 +76,9 @@ func (a *analyticsService) SendEvent(_ context.Context, event *livekit.Analytics
 		return
 	}
 
+	if a.retention != nil {
+		event = a.retention.applyToEvent(event)
+	}
 	event.Id = guid.New("AE_")
 	event.NodeId = a.nodeID
 	event.AnalyticsKey = a.analyticsKey
@@ -90,7 +96,7 @@ func (a *analyticsService) SendNodeRoomStates(_ context.Context, nodeRooms *live
 
 	nodeRooms.NodeId = a.nodeID
 	nodeRooms.SequenceNumber = a.sequenceNumber.Add(1)
-	nodeRooms.Timestamp = timestamppb.Now()
+	nodeRooms.Timestamp = timestamppb.New(utils.ClusterNow())
 	if err := a.nodeRooms.Send(nodeRooms); err != nil {
 		logger.Errorw("failed to send node room states", err)
 	}
@@ -0,0 +1,107 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// ClientEventType is a quality of experience measurement only clients can make
+type ClientEventType string
+
+const (
+	// decoding of a subscribed video track stuttered
+	ClientEventDecodeStutter ClientEventType = "decode_stutter"
+	// a subscribed video track froze
+	ClientEventFrozenFrame ClientEventType = "frozen_frame"
+	// samples of a subscribed audio track were concealed
+	ClientEventAudioConcealment ClientEventType = "audio_concealment"
+	// time from starting to join the room to being connected, as measured by the UI
+	ClientEventJoinTime ClientEventType = "join_time"
+
+	MaxClientEvents = 100
+)
+
+var (
+	ErrClientEventType     = errors.New("unknown client event type")
+	ErrClientEventTrack    = errors.New("client event requires a track")
+	ErrTooManyClientEvents = fmt.Errorf("at most %d client events can be reported at once", MaxClientEvents)
+)
+
+// ClientEvent is reported by clients, it is aggregated with the stats the server measures for the subscribed
+// track it is about
+type ClientEvent struct {
+	Type ClientEventType `json:"type"`
+	// subscribed track, for media events
+	TrackID livekit.TrackID `json:"track_id,omitempty"`
+	// occurrences since the previous report
+	Count uint32 `json:"count,omitempty"`
+	// time spent stuttering, frozen or concealing since the previous report, or taken to join, in ms
+	DurationMs uint32 `json:"duration_ms,omitempty"`
+}
+
+func (e *ClientEvent) Validate() error {
+	switch e.Type {
+	case ClientEventDecodeStutter, ClientEventFrozenFrame, ClientEventAudioConcealment:
+		if e.TrackID == "" {
+			return ErrClientEventTrack
+		}
+	case ClientEventJoinTime:
+	default:
+		return fmt.Errorf("%w: %q", ErrClientEventType, e.Type)
+	}
+	return nil
+}
+
+// ValidateClientEvents checks a report of a client before it is recorded
+func ValidateClientEvents(events []*ClientEvent) error {
+	if len(events) > MaxClientEvents {
+		return ErrTooManyClientEvents
+	}
+	for _, e := range events {
+		if err := e.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *telemetryService) ClientEvents(ctx context.Context, participantID livekit.ParticipantID, events []*ClientEvent) {
+	t.enqueue(func() {
+		worker, ok := t.getWorker(participantID)
+		for _, e := range events {
+			if e.Type == ClientEventJoinTime {
+				prometheus.RecordClientJoinTime(e.DurationMs)
+				continue
+			}
+			prometheus.RecordClientEvent(string(e.Type), e.Count, e.DurationMs)
+			if ok {
+				// subscribed tracks are the outgoing series of the subscriber
+				prometheus.RecordTrackClientEvent(prometheus.TrackSeries{
+					Room:        worker.roomName,
+					Participant: worker.participantIdentity,
+					Track:       e.TrackID,
+					Direction:   prometheus.Outgoing,
+				}, string(e.Type), e.Count)
+			}
+		}
+	})
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const hashedValuePrefix = "h_"
+
+// dataRetention records, hashes or drops the personal data of analytics events according to the
+// retention class of their room. Hashes are keyed per class and retention period, so values hashed
+// in different periods can't be linked.
+type dataRetention struct {
+	conf config.DataRetentionConfig
+	key  []byte
+	now  func() time.Time
+}
+
+// newDataRetention returns nil when no class is configured
+func newDataRetention(conf config.DataRetentionConfig) *dataRetention {
+	if len(conf.Classes) == 0 {
+		return nil
+	}
+
+	key := []byte(conf.HashKey)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		_, _ = rand.Read(key)
+	}
+	return &dataRetention{
+		conf: conf,
+		key:  key,
+		now:  time.Now,
+	}
+}
+
+func (d *dataRetention) class(roomName string) (string, config.DataRetentionClass, bool) {
+	name := d.conf.DefaultClass
+	for _, rule := range d.conf.Rooms {
+		if ok, _ := path.Match(rule.Room, roomName); ok {
+			name = rule.Class
+			break
+		}
+	}
+	class, ok := d.conf.Classes[name]
+	return name, class, ok
+}
+
+// applyToEvent returns the event, or a copy of it with the personal data its class does not record
+func (d *dataRetention) applyToEvent(event *livekit.AnalyticsEvent) *livekit.AnalyticsEvent {
+	roomName := event.GetRoom().GetName()
+	if roomName == "" {
+		roomName = event.GetEgress().GetRoomName()
+	}
+	if roomName == "" {
+		roomName = event.GetIngress().GetRoomName()
+	}
+
+	name, class, ok := d.class(roomName)
+	if !ok || (isRecorded(class.IPs) && isRecorded(class.Identities)) {
+		return event
+	}
+
+	event = utils.CloneProto(event)
+	hashKey := d.periodKey(name, class.Retention)
+	ip := func(value string) string {
+		return retain(class.IPs, hashKey, value)
+	}
+	identity := func(value string) string {
+		return retain(class.Identities, hashKey, value)
+	}
+
+	for _, p := range []*livekit.ParticipantInfo{event.Participant, event.Publisher} {
+		if p != nil {
+			p.Identity = identity(p.Identity)
+			p.Name = identity(p.Name)
+		}
+	}
+	if ingress := event.Ingress; ingress != nil {
+		ingress.ParticipantIdentity = identity(ingress.ParticipantIdentity)
+		ingress.ParticipantName = identity(ingress.ParticipantName)
+	}
+	if info := event.ClientInfo; info != nil {
+		info.Address = ip(info.Address)
+	}
+	if meta := event.ClientMeta; meta != nil {
+		meta.ClientAddr = ip(meta.ClientAddr)
+		if !isRecorded(class.IPs) {
+			meta.GeoHash = nil
+		}
+	}
+	return event
+}
+
+// periodKey derives the hash key of a class for the current retention period
+func (d *dataRetention) periodKey(className string, retention time.Duration) []byte {
+	var period int64
+	if retention > 0 {
+		period = d.now().UnixNano() / int64(retention)
+	}
+	mac := hmac.New(sha256.New, d.key)
+	mac.Write([]byte(className))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strconv.FormatInt(period, 10)))
+	return mac.Sum(nil)
+}
+
+func isRecorded(policy string) bool {
+	return policy == "" || policy == config.DataRetentionRecord
+}
+
+func retain(policy string, hashKey []byte, value string) string {
+	if value == "" {
+		return ""
+	}
+	switch policy {
+	case config.DataRetentionHash:
+		mac := hmac.New(sha256.New, hashKey)
+		mac.Write([]byte(value))
+		return hashedValuePrefix + hex.EncodeToString(mac.Sum(nil)[:16])
+	case config.DataRetentionDrop:
+		return ""
+	default:
+		return value
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func newRetentionEvent(roomName string) *livekit.AnalyticsEvent {
+	geoHash := "u4pruydqqvj"
+	return &livekit.AnalyticsEvent{
+		Type:        livekit.AnalyticsEventType_PARTICIPANT_JOINED,
+		Room:        &livekit.Room{Sid: "RM_1", Name: roomName},
+		Participant: &livekit.ParticipantInfo{Sid: "PA_1", Identity: "alice", Name: "Alice"},
+		ClientInfo:  &livekit.ClientInfo{Address: "10.0.0.1"},
+		ClientMeta:  &livekit.AnalyticsClientMeta{ClientAddr: "10.0.0.1", GeoHash: &geoHash},
+	}
+}
+
+func TestDataRetention(t *testing.T) {
+	conf := config.DataRetentionConfig{
+		Classes: map[string]config.DataRetentionClass{
+			"standard": {IPs: config.DataRetentionHash},
+			"gdpr": {
+				IPs:        config.DataRetentionDrop,
+				Identities: config.DataRetentionHash,
+				Retention:  time.Hour,
+			},
+		},
+		Rooms: []config.DataRetentionRule{
+			{Room: "eu-*", Class: "gdpr"},
+		},
+		DefaultClass: "standard",
+		HashKey:      "key",
+	}
+
+	t.Run("default class", func(t *testing.T) {
+		d := newDataRetention(conf)
+		event := newRetentionEvent("us-room")
+		retained := d.applyToEvent(event)
+
+		require.Equal(t, "alice", retained.Participant.Identity)
+		require.True(t, strings.HasPrefix(retained.ClientInfo.Address, hashedValuePrefix))
+		require.Equal(t, retained.ClientInfo.Address, retained.ClientMeta.ClientAddr)
+		require.Nil(t, retained.ClientMeta.GeoHash)
+		// the event of the caller is left untouched
+		require.Equal(t, "10.0.0.1", event.ClientInfo.Address)
+		require.NotNil(t, event.ClientMeta.GeoHash)
+	})
+
+	t.Run("matching room", func(t *testing.T) {
+		d := newDataRetention(conf)
+		retained := d.applyToEvent(newRetentionEvent("eu-room"))
+
+		require.Empty(t, retained.ClientInfo.Address)
+		require.Empty(t, retained.ClientMeta.ClientAddr)
+		require.True(t, strings.HasPrefix(retained.Participant.Identity, hashedValuePrefix))
+		require.NotEqual(t, retained.Participant.Identity, retained.Participant.Name)
+	})
+
+	t.Run("hashes rotate with retention", func(t *testing.T) {
+		d := newDataRetention(conf)
+		now := time.Unix(0, 0)
+		d.now = func() time.Time { return now }
+
+		first := d.applyToEvent(newRetentionEvent("eu-room")).Participant.Identity
+		now = now.Add(30 * time.Minute)
+		require.Equal(t, first, d.applyToEvent(newRetentionEvent("eu-room")).Participant.Identity)
+		now = now.Add(time.Hour)
+		require.NotEqual(t, first, d.applyToEvent(newRetentionEvent("eu-room")).Participant.Identity)
+	})
+
+	t.Run("recorded", func(t *testing.T) {
+		c := conf
+		c.DefaultClass = ""
+		d := newDataRetention(c)
+		event := newRetentionEvent("us-room")
+		require.Same(t, event, d.applyToEvent(event))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		require.Nil(t, newDataRetention(config.DataRetentionConfig{}))
+	})
+}
@@ -16,11 +16,13 @@ package telemetry
 
 import (
 	"context"
-	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	"github.com/livekit/livekit-server/pkg/utils"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/utils/guid"
@@ -32,7 +34,7 @@ func (t *telemetryService) NotifyEvent(ctx context.Context, event *livekit.Webho
 		return
 	}
 
-	event.CreatedAt = time.Now().Unix()
+	event.CreatedAt = utils.ClusterNow().Unix()
 	event.Id = guid.New("EV_")
 
 	if err := t.notifier.QueueNotify(ctx, event); err != nil {
@@ -64,7 +66,7 @@ func (t *telemetryService) RoomEnded(ctx context.Context, room *livekit.Room) {
 
 		t.SendEvent(ctx, &livekit.AnalyticsEvent{
 			Type:      livekit.AnalyticsEventType_ROOM_ENDED,
-			Timestamp: timestamppb.Now(),
+			Timestamp: timestamppb.New(utils.ClusterNow()),
 			RoomId:    room.Sid,
 			Room:      room,
 		})
@@ -80,6 +82,7 @@ func (t *telemetryService) ParticipantJoined(
 	shouldSendEvent bool,
 ) {
 	t.enqueue(func() {
+		ctx := t.participantSpan(ctx, "participant.joined", room, participant)
 		_, found := t.getOrCreateWorker(
 			ctx,
 			livekit.RoomID(room.Sid),
@@ -109,6 +112,7 @@ func (t *telemetryService) ParticipantActive(
 	isMigration bool,
 ) {
 	t.enqueue(func() {
+		ctx := t.participantSpan(ctx, "participant.active", room, participant)
 		if !isMigration {
 			// consider participant joined only when they became active
 			t.NotifyEvent(ctx, &livekit.WebhookEvent{
@@ -154,6 +158,7 @@ func (t *telemetryService) ParticipantResumed(
 		// the corresponding participant's stats worker.
 		//
 		// So, on a successful resume, create the worker if needed.
+		ctx := t.participantSpan(ctx, "participant.resumed", room, participant)
 		_, found := t.getOrCreateWorker(
 			ctx,
 			livekit.RoomID(room.Sid),
@@ -180,6 +185,7 @@ func (t *telemetryService) ParticipantLeft(ctx context.Context,
 	shouldSendEvent bool,
 ) {
 	t.enqueue(func() {
+		ctx := t.participantSpan(ctx, "participant.left", room, participant)
 		isConnected := false
 		if worker, ok := t.getWorker(livekit.ParticipantID(participant.Sid)); ok {
 			isConnected = worker.IsConnected()
@@ -187,6 +193,7 @@ func (t *telemetryService) ParticipantLeft(ctx context.Context,
 				prometheus.SubParticipant()
 			}
 		}
+		prometheus.DeleteParticipantTrackStats(livekit.RoomName(room.GetName()), livekit.ParticipantIdentity(participant.Identity))
 
 		if isConnected && shouldSendEvent {
 			t.NotifyEvent(ctx, &livekit.WebhookEvent{
@@ -228,6 +235,7 @@ func (t *telemetryService) TrackPublished(
 		prometheus.AddPublishSuccess(track.Type.String())
 
 		room := t.getRoomDetails(participantID)
+		ctx := t.trackSpan(ctx, "track.published", room, participantID, track)
 		participant := &livekit.ParticipantInfo{
 			Sid:      string(participantID),
 			Identity: string(identity),
@@ -297,6 +305,7 @@ func (t *telemetryService) TrackSubscribed(
 		}
 
 		room := t.getRoomDetails(participantID)
+		ctx := t.trackSpan(ctx, "track.subscribed", room, participantID, track)
 		ev := newTrackEvent(livekit.AnalyticsEventType_TRACK_SUBSCRIBED, room, participantID, track)
 		ev.Publisher = publisher
 		t.SendEvent(ctx, ev)
@@ -333,6 +342,7 @@ func (t *telemetryService) TrackUnsubscribed(
 
 		if shouldSendEvent {
 			room := t.getRoomDetails(participantID)
+			ctx := t.trackSpan(ctx, "track.unsubscribed", room, participantID, track)
 			t.SendEvent(ctx, newTrackEvent(livekit.AnalyticsEventType_TRACK_UNSUBSCRIBED, room, participantID, track))
 		}
 	})
@@ -352,6 +362,7 @@ func (t *telemetryService) TrackUnpublished(
 		}
 
 		room := t.getRoomDetails(participantID)
+		ctx := t.trackSpan(ctx, "track.unpublished", room, participantID, track)
 		participant := &livekit.ParticipantInfo{
 			Sid:      string(participantID),
 			Identity: string(identity),
@@ -499,6 +510,39 @@ func (t *telemetryService) IngressEnded(ctx context.Context, info *livekit.Ingre
 	})
 }
 
+// participantSpan records a lifecycle event of a participant as a span. Events raised outside of a request
+// are added to the trace the participant joined with, so that webhooks of the participant share its trace ID.
+func (t *telemetryService) participantSpan(ctx context.Context, name string, room *livekit.Room, participant *livekit.ParticipantInfo) context.Context {
+	return t.lifecycleSpan(ctx, name, livekit.ParticipantID(participant.Sid),
+		attribute.String("room", room.GetName()),
+		attribute.String("room_id", room.GetSid()),
+		attribute.String("participant", participant.Identity),
+		attribute.String("participant_id", participant.Sid),
+	)
+}
+
+func (t *telemetryService) trackSpan(ctx context.Context, name string, room *livekit.Room, participantID livekit.ParticipantID, track *livekit.TrackInfo) context.Context {
+	return t.lifecycleSpan(ctx, name, participantID,
+		attribute.String("room", room.GetName()),
+		attribute.String("room_id", room.GetSid()),
+		attribute.String("participant_id", string(participantID)),
+		attribute.String("track_id", track.GetSid()),
+		attribute.String("track_type", track.GetType().String()),
+		attribute.String("track_source", track.GetSource().String()),
+	)
+}
+
+func (t *telemetryService) lifecycleSpan(ctx context.Context, name string, participantID livekit.ParticipantID, attrs ...attribute.KeyValue) context.Context {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		if worker, ok := t.getWorker(participantID); ok {
+			ctx = trace.ContextWithSpanContext(ctx, trace.SpanContextFromContext(worker.ctx))
+		}
+	}
+	ctx, span := StartSpan(ctx, name, attrs...)
+	span.End()
+	return ctx
+}
+
 // returns a livekit.Room with only name and sid filled out
 // returns nil if room is not found
 func (t *telemetryService) getRoomDetails(participantID livekit.ParticipantID) *livekit.Room {
@@ -515,7 +559,7 @@ func (t *telemetryService) getRoomDetails(participantID livekit.ParticipantID) *
 func newRoomEvent(event livekit.AnalyticsEventType, room *livekit.Room) *livekit.AnalyticsEvent {
 	ev := &livekit.AnalyticsEvent{
 		Type:      event,
-		Timestamp: timestamppb.Now(),
+		Timestamp: timestamppb.New(utils.ClusterNow()),
 	}
 	if room != nil {
 		ev.Room = room
@@ -547,7 +591,7 @@ func newTrackEvent(event livekit.AnalyticsEventType, room *livekit.Room, partici
 func newEgressEvent(event livekit.AnalyticsEventType, egress *livekit.EgressInfo) *livekit.AnalyticsEvent {
 	return &livekit.AnalyticsEvent{
 		Type:      event,
-		Timestamp: timestamppb.Now(),
+		Timestamp: timestamppb.New(utils.ClusterNow()),
 		EgressId:  egress.EgressId,
 		RoomId:    egress.RoomId,
 		Egress:    egress,
@@ -557,7 +601,7 @@ func newEgressEvent(event livekit.AnalyticsEventType, egress *livekit.EgressInfo
 func newIngressEvent(event livekit.AnalyticsEventType, ingress *livekit.IngressInfo) *livekit.AnalyticsEvent {
 	return &livekit.AnalyticsEvent{
 		Type:      event,
-		Timestamp: timestamppb.Now(),
+		Timestamp: timestamppb.New(utils.ClusterNow()),
 		IngressId: ingress.IngressId,
 		Ingress:   ingress,
 	}
@@ -0,0 +1,174 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"crypto/tls"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"go.uber.org/atomic"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+const (
+	KafkaHeaderNodeID      = "node_id"
+	KafkaHeaderContentType = "content_type"
+
+	kafkaContentTypeJSON     = "application/json"
+	kafkaContentTypeProtobuf = "application/x-protobuf"
+)
+
+// KafkaExporter is an AnalyticsService publishing analytics to Kafka topics. Messages are written
+// asynchronously in batches, failed batches are logged and dropped.
+type KafkaExporter struct {
+	conf           config.KafkaConfig
+	nodeID         string
+	sequenceNumber atomic.Uint64
+	retention      *dataRetention
+	writer         *kafka.Writer
+}
+
+func NewKafkaExporter(conf *config.Config, nodeID livekit.NodeID) *KafkaExporter {
+	transport := &kafka.Transport{}
+	if conf.Kafka.Username != "" {
+		transport.SASL = plain.Mechanism{
+			Username: conf.Kafka.Username,
+			Password: conf.Kafka.Password,
+		}
+	}
+	if conf.Kafka.TLS {
+		transport.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	return &KafkaExporter{
+		conf:      conf.Kafka,
+		nodeID:    string(nodeID),
+		retention: newDataRetention(conf.DataRetention),
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(conf.Kafka.Brokers...),
+			Balancer:     &kafka.Hash{},
+			BatchTimeout: conf.Kafka.BatchTimeout,
+			Async:        true,
+			Transport:    transport,
+			Completion: func(messages []kafka.Message, err error) {
+				if err != nil {
+					logger.Errorw("failed to export analytics to kafka", err, "messages", len(messages))
+				}
+			},
+		},
+	}
+}
+
+func (k *KafkaExporter) SendStats(ctx context.Context, stats []*livekit.AnalyticsStat) {
+	if k.conf.StatsTopic == "" {
+		return
+	}
+
+	messages := make([]kafka.Message, 0, len(stats))
+	for _, stat := range stats {
+		stat = proto.Clone(stat).(*livekit.AnalyticsStat)
+		stat.Id = guid.New("AS_")
+		stat.Node = k.nodeID
+		if m, ok := k.message(k.conf.StatsTopic, stat.RoomId, stat); ok {
+			messages = append(messages, m)
+		}
+	}
+	k.write(ctx, messages...)
+}
+
+func (k *KafkaExporter) SendEvent(ctx context.Context, event *livekit.AnalyticsEvent) {
+	if k.conf.EventsTopic == "" {
+		return
+	}
+
+	if k.retention != nil {
+		event = k.retention.applyToEvent(event)
+	}
+	event = proto.Clone(event).(*livekit.AnalyticsEvent)
+	event.Id = guid.New("AE_")
+	event.NodeId = k.nodeID
+	if m, ok := k.message(k.conf.EventsTopic, event.RoomId, event); ok {
+		k.write(ctx, m)
+	}
+}
+
+func (k *KafkaExporter) SendNodeRoomStates(ctx context.Context, nodeRooms *livekit.AnalyticsNodeRooms) {
+	if k.conf.NodeRoomsTopic == "" {
+		return
+	}
+
+	nodeRooms = proto.Clone(nodeRooms).(*livekit.AnalyticsNodeRooms)
+	nodeRooms.NodeId = k.nodeID
+	nodeRooms.SequenceNumber = k.sequenceNumber.Add(1)
+	nodeRooms.Timestamp = timestamppb.New(utils.ClusterNow())
+	if m, ok := k.message(k.conf.NodeRoomsTopic, k.nodeID, nodeRooms); ok {
+		k.write(ctx, m)
+	}
+}
+
+// Close sends the messages still batched
+func (k *KafkaExporter) Close() error {
+	return k.writer.Close()
+}
+
+func (k *KafkaExporter) message(topic string, key string, msg proto.Message) (kafka.Message, bool) {
+	var (
+		value       []byte
+		contentType string
+		err         error
+	)
+	if k.conf.Encoding == config.KafkaEncodingProtobuf {
+		value, err = proto.Marshal(msg)
+		contentType = kafkaContentTypeProtobuf
+	} else {
+		value, err = protojson.Marshal(msg)
+		contentType = kafkaContentTypeJSON
+	}
+	if err != nil {
+		logger.Errorw("could not encode analytics for kafka", err, "topic", topic)
+		return kafka.Message{}, false
+	}
+
+	return kafka.Message{
+		Topic: topic,
+		Key:   []byte(key),
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: KafkaHeaderNodeID, Value: []byte(k.nodeID)},
+			{Key: KafkaHeaderContentType, Value: []byte(contentType)},
+		},
+	}, true
+}
+
+func (k *KafkaExporter) write(ctx context.Context, messages ...kafka.Message) {
+	if len(messages) == 0 {
+		return
+	}
+	// async writes only fail when the writer is closed
+	if err := k.writer.WriteMessages(context.WithoutCancel(ctx), messages...); err != nil {
+		logger.Warnw("could not export analytics to kafka", err)
+	}
+}
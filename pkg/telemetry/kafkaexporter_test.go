@@ -0,0 +1,64 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestKafkaExporterMessage(t *testing.T) {
+	event := &livekit.AnalyticsEvent{
+		Type:   livekit.AnalyticsEventType_PARTICIPANT_JOINED,
+		RoomId: "RM_1",
+		Room:   &livekit.Room{Sid: "RM_1", Name: "room"},
+	}
+
+	for _, encoding := range []string{config.KafkaEncodingJSON, config.KafkaEncodingProtobuf} {
+		t.Run(encoding, func(t *testing.T) {
+			conf := &config.Config{Kafka: config.KafkaConfig{
+				Brokers:     []string{"localhost:9092"},
+				EventsTopic: "events",
+				Encoding:    encoding,
+			}}
+			k := NewKafkaExporter(conf, "ND_1")
+			defer k.Close()
+
+			m, ok := k.message("events", event.RoomId, event)
+			require.True(t, ok)
+			require.Equal(t, "events", m.Topic)
+			require.Equal(t, []byte("RM_1"), m.Key)
+
+			decoded := &livekit.AnalyticsEvent{}
+			if encoding == config.KafkaEncodingJSON {
+				require.NoError(t, protojson.Unmarshal(m.Value, decoded))
+				require.Equal(t, kafkaContentTypeJSON, string(m.Headers[1].Value))
+			} else {
+				require.NoError(t, proto.Unmarshal(m.Value, decoded))
+				require.Equal(t, kafkaContentTypeProtobuf, string(m.Headers[1].Value))
+			}
+			require.True(t, proto.Equal(event, decoded))
+			require.Equal(t, KafkaHeaderNodeID, m.Headers[0].Key)
+			require.Equal(t, "ND_1", string(m.Headers[0].Value))
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promAgentTokenRefreshes       *prometheus.CounterVec
+	promAgentTokenRefreshFailures *prometheus.CounterVec
+)
+
+func initAgentStats(nodeID string, nodeType livekit.NodeType) {
+	promAgentTokenRefreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "agent",
+		Name:        "token_refreshes",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"agent_name"})
+	promAgentTokenRefreshFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "agent",
+		Name:        "token_refresh_failures",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"agent_name"})
+
+	prometheus.MustRegister(promAgentTokenRefreshes)
+	prometheus.MustRegister(promAgentTokenRefreshFailures)
+}
+
+// RecordAgentTokenRefresh records the token of a running job being sent again to its worker
+func RecordAgentTokenRefresh(agentName string, err error) {
+	if err != nil {
+		promAgentTokenRefreshFailures.WithLabelValues(agentName).Inc()
+		return
+	}
+	promAgentTokenRefreshes.WithLabelValues(agentName).Inc()
+}
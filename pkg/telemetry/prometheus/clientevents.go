@@ -0,0 +1,65 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promClientEvents        *prometheus.CounterVec
+	promClientEventDuration *prometheus.CounterVec
+	promClientJoinTime      prometheus.Histogram
+)
+
+func initClientEventStats(nodeID string, nodeType livekit.NodeType) {
+	promClientEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "client",
+		Name:        "events",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"type"})
+	promClientEventDuration = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "client",
+		Name:        "event_duration_ms",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"type"})
+	promClientJoinTime = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "client",
+		Name:        "join_time_ms",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Buckets:     []float64{250, 500, 1000, 1500, 2000, 3000, 5000, 10000, 20000},
+	})
+
+	prometheus.MustRegister(promClientEvents)
+	prometheus.MustRegister(promClientEventDuration)
+	prometheus.MustRegister(promClientJoinTime)
+}
+
+// RecordClientEvent records a quality of experience event reported by a client, count occurrences lasting
+// durationMs in total
+func RecordClientEvent(eventType string, count uint32, durationMs uint32) {
+	promClientEvents.WithLabelValues(eventType).Add(float64(count))
+	promClientEventDuration.WithLabelValues(eventType).Add(float64(durationMs))
+}
+
+// RecordClientJoinTime records the time a client measured from starting to join a room to being connected
+func RecordClientJoinTime(durationMs uint32) {
+	promClientJoinTime.Observe(float64(durationMs))
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promClockOffset      prometheus.Gauge
+	promClockPeerOffset  *prometheus.GaugeVec
+	promClockSkewedPeers prometheus.Gauge
+)
+
+func initClockStats(nodeID string, nodeType livekit.NodeType) {
+	promClockOffset = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "node",
+		Name:        "clock_offset_seconds",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Offset of the node clock from the median clock of the cluster.",
+	})
+	promClockPeerOffset = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "node",
+		Name:        "clock_peer_offset_seconds",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Offset of the node clock from the clocks of the other nodes.",
+	}, []string{"peer_id"})
+	promClockSkewedPeers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "node",
+		Name:        "clock_skewed_peers",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Other nodes with a clock offset over the clock skew threshold.",
+	})
+
+	prometheus.MustRegister(promClockOffset)
+	prometheus.MustRegister(promClockPeerOffset)
+	prometheus.MustRegister(promClockSkewedPeers)
+}
+
+// RecordClockOffset records the offset of the node clock from the cluster, and from each other node
+func RecordClockOffset(offset time.Duration, peerOffsets map[string]time.Duration, skewedPeers int) {
+	if !initialized.Load() {
+		return
+	}
+	promClockOffset.Set(offset.Seconds())
+	promClockPeerOffset.Reset()
+	for peerID, o := range peerOffsets {
+		promClockPeerOffset.WithLabelValues(peerID).Set(o.Seconds())
+	}
+	promClockSkewedPeers.Set(float64(skewedPeers))
+}
@@ -15,6 +15,7 @@
 package prometheus
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/mackerelio/go-osstat/memory"
@@ -24,6 +25,8 @@ import (
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/protocol/utils/hwstats"
+
+	"github.com/livekit/livekit-server/pkg/utils"
 )
 
 const (
@@ -48,6 +51,15 @@ var (
 	cpuStats *hwstats.CPUStats
 )
 
+// SetBuildLabels labels the metrics registered afterwards with the build of the node, keeping the metrics of
+// canary nodes apart from the others. It is called once, before Init.
+func SetBuildLabels(version string, canary bool) {
+	prometheus.DefaultRegisterer = prometheus.WrapRegistererWith(
+		prometheus.Labels{"build": version, "canary": strconv.FormatBool(canary)},
+		prometheus.DefaultRegisterer,
+	)
+}
+
 func Init(nodeID string, nodeType livekit.NodeType) error {
 	if initialized.Swap(true) {
 		return nil
@@ -127,6 +139,13 @@ func Init(nodeID string, nodeType livekit.NodeType) error {
 	initRoomStats(nodeID, nodeType)
 	rpc.InitPSRPCStats(prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()})
 	initQualityStats(nodeID, nodeType)
+	initSIPStats(nodeID, nodeType)
+	initSignalStats(nodeID, nodeType)
+	initTrackStats(nodeID, nodeType)
+	initAgentStats(nodeID, nodeType)
+	initClientEventStats(nodeID, nodeType)
+	initTURNStats(nodeID, nodeType)
+	initClockStats(nodeID, nodeType)
 
 	var err error
 	cpuStats, err = hwstats.NewCPUStats(nil)
@@ -181,7 +200,7 @@ func GetUpdatedNodeStats(prev *livekit.NodeStats, prevAverage *livekit.NodeStats
 	forwardLatencyNow := forwardLatency.Load()
 	forwardJitterNow := forwardJitter.Load()
 
-	updatedAt := time.Now().Unix()
+	updatedAt := utils.ClusterNow().Unix()
 	elapsed := updatedAt - prevAverage.UpdatedAt
 	// include sufficient buffer to be sure a stats update had taken place
 	computeAverage := elapsed > int64(statsUpdateInterval.Seconds()+2)
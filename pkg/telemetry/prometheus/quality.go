@@ -24,6 +24,11 @@ var (
 	qualityRating prometheus.Histogram
 	qualityScore  prometheus.Histogram
 	qualityDrop   *prometheus.CounterVec
+
+	qualityWindowScore *prometheus.HistogramVec
+	qualityLimitedBy   *prometheus.CounterVec
+	qualityTransitions *prometheus.CounterVec
+	qualityAlerts      *prometheus.CounterVec
 )
 
 func initQualityStats(nodeID string, nodeType livekit.NodeType) {
@@ -48,9 +53,39 @@ func initQualityStats(nodeID string, nodeType livekit.NodeType) {
 		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
 	}, []string{"direction"})
 
+	qualityWindowScore = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "quality",
+		Name:        "window_score",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Buckets:     []float64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100},
+	}, []string{"component"})
+	qualityLimitedBy = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "quality",
+		Name:        "limited_by",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"reason"})
+	qualityTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "quality",
+		Name:        "transitions",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"from", "to"})
+	qualityAlerts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "quality",
+		Name:        "alerts",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"kind"})
+
 	prometheus.MustRegister(qualityRating)
 	prometheus.MustRegister(qualityScore)
 	prometheus.MustRegister(qualityDrop)
+	prometheus.MustRegister(qualityWindowScore)
+	prometheus.MustRegister(qualityLimitedBy)
+	prometheus.MustRegister(qualityTransitions)
+	prometheus.MustRegister(qualityAlerts)
 }
 
 func RecordQuality(rating livekit.ConnectionQuality, score float32, numUpDrops int, numDownDrops int) {
@@ -59,3 +94,27 @@ func RecordQuality(rating livekit.ConnectionQuality, score float32, numUpDrops i
 	qualityDrop.WithLabelValues("up").Add(float64(numUpDrops))
 	qualityDrop.WithLabelValues("down").Add(float64(numDownDrops))
 }
+
+// RecordQualityWindow records the component scores of a track's analysis window, and the component which
+// lowered the score of the window the most
+func RecordQualityWindow(reason string, packetScore, bitrateScore, layerScore float64) {
+	if !initialized.Load() {
+		return
+	}
+	qualityWindowScore.WithLabelValues("packet").Observe(packetScore)
+	qualityWindowScore.WithLabelValues("bitrate").Observe(bitrateScore)
+	qualityWindowScore.WithLabelValues("layer").Observe(layerScore)
+	qualityLimitedBy.WithLabelValues(reason).Inc()
+}
+
+func RecordQualityTransition(from, to livekit.ConnectionQuality) {
+	qualityTransitions.WithLabelValues(from.String(), to.String()).Inc()
+}
+
+// RecordQualityAlert counts participants whose connection stayed past an alert threshold
+func RecordQualityAlert(kind string) {
+	if !initialized.Load() {
+		return
+	}
+	qualityAlerts.WithLabelValues(kind).Inc()
+}
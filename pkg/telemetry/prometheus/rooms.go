@@ -47,6 +47,13 @@ var (
 	promSessionStartTime       *prometheus.HistogramVec
 	promSessionDuration        *prometheus.HistogramVec
 	promPubSubTime             *prometheus.HistogramVec
+	promTrackNotifierQueue     prometheus.Gauge
+	promTrackNotifierBatch     prometheus.Histogram
+	promTrackLayerDowngrade    *prometheus.CounterVec
+	promTrackBitrateOvershoot  *prometheus.CounterVec
+	promTrackRampTime          *prometheus.HistogramVec
+	promTrackSubscribeBursts   prometheus.Counter
+	promNotifications          *prometheus.CounterVec
 )
 
 func initRoomStats(nodeID string, nodeType livekit.NodeType) {
@@ -116,6 +123,50 @@ func initRoomStats(nodeID string, nodeType livekit.NodeType) {
 		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
 		Buckets:     []float64{100, 200, 500, 700, 1000, 5000, 10000},
 	}, append(promStreamLabels, "sdk", "kind", "count"))
+	promTrackNotifierQueue = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "notifier_queue_depth",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	})
+	promTrackNotifierBatch = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "notifier_batch_size",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Buckets:     []float64{1, 2, 5, 10, 20, 50, 100},
+	})
+	promTrackLayerDowngrade = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "layer_downgrade_counter",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"quality"})
+	promTrackBitrateOvershoot = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "bitrate_overshoot_counter",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"quality"})
+	promTrackRampTime = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "ramp_time_ms",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Buckets:     []float64{100, 200, 500, 1000, 2000, 5000, 10000, 20000, 60000},
+	}, []string{"burst"})
+	promTrackSubscribeBursts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "subscribe_bursts",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	})
+	promNotifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "participant",
+		Name:        "notifications",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"code", "state"})
 
 	prometheus.MustRegister(promRoomCurrent)
 	prometheus.MustRegister(promRoomDuration)
@@ -127,6 +178,13 @@ func initRoomStats(nodeID string, nodeType livekit.NodeType) {
 	prometheus.MustRegister(promSessionStartTime)
 	prometheus.MustRegister(promSessionDuration)
 	prometheus.MustRegister(promPubSubTime)
+	prometheus.MustRegister(promTrackNotifierQueue)
+	prometheus.MustRegister(promTrackNotifierBatch)
+	prometheus.MustRegister(promTrackLayerDowngrade)
+	prometheus.MustRegister(promTrackBitrateOvershoot)
+	prometheus.MustRegister(promTrackRampTime)
+	prometheus.MustRegister(promTrackSubscribeBursts)
+	prometheus.MustRegister(promNotifications)
 }
 
 func RoomStarted() {
@@ -224,3 +282,50 @@ func RecordSessionStartTime(protocolVersion int, d time.Duration) {
 func RecordSessionDuration(protocolVersion int, d time.Duration) {
 	promSessionDuration.WithLabelValues(strconv.Itoa(protocolVersion)).Observe(float64(d.Milliseconds()))
 }
+
+func AddTrackNotifierQueueDepth(delta int) {
+	promTrackNotifierQueue.Add(float64(delta))
+}
+
+func RecordTrackNotifierBatch(size int) {
+	promTrackNotifierBatch.Observe(float64(size))
+}
+
+// RecordTrackLayerDowngrade counts subscriptions served a lower layer than the requested dimensions
+func RecordTrackLayerDowngrade(quality string) {
+	promTrackLayerDowngrade.WithLabelValues(quality).Inc()
+}
+
+// RecordTrackBitrateOvershoot counts video tracks published far above their declared bitrate
+func RecordTrackBitrateOvershoot(quality string) {
+	promTrackBitrateOvershoot.WithLabelValues(quality).Inc()
+}
+
+// RecordNotification counts the notifications of participants, sent or suppressed by deduplication and rate limits
+func RecordNotification(code string, sent bool) {
+	if !initialized.Load() {
+		return
+	}
+	state := "suppressed"
+	if sent {
+		state = "sent"
+	}
+	promNotifications.WithLabelValues(code, state).Inc()
+}
+
+// RecordTrackRampTime records the time a video subscription took to be forwarded the layer it was allocated,
+// burst is whether it joined the track in a burst of subscribers and had its ramp up staggered
+func RecordTrackRampTime(burst bool, d time.Duration) {
+	if !initialized.Load() {
+		return
+	}
+	promTrackRampTime.WithLabelValues(strconv.FormatBool(burst)).Observe(float64(d.Milliseconds()))
+}
+
+// RecordTrackSubscribeBurst counts the bursts of subscribers joining a video track
+func RecordTrackSubscribeBurst() {
+	if !initialized.Load() {
+		return
+	}
+	promTrackSubscribeBursts.Inc()
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const (
+	// the queue of a participant on the media node outgrew signal_relay.max_queue_size
+	SignalSlowConsumerQueue = "queue"
+	// a WebSocket write took longer than limit.signal_write_timeout
+	SignalSlowConsumerWrite = "write"
+)
+
+var (
+	promSignalConnections         prometheus.Gauge
+	promSignalConnectionsRejected prometheus.Counter
+	promSignalWriteQueueDepth     prometheus.Gauge
+	promSignalSlowConsumers       *prometheus.CounterVec
+	promSignalRateLimited         *prometheus.CounterVec
+	promIPFilterBlocked           *prometheus.CounterVec
+)
+
+func initSignalStats(nodeID string, nodeType livekit.NodeType) {
+	promSignalConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "signal",
+		Name:        "connections",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	})
+	promSignalConnectionsRejected = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "signal",
+		Name:        "connections_rejected",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	})
+	promSignalWriteQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "signal",
+		Name:        "write_queue_depth",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	})
+	promSignalSlowConsumers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "signal",
+		Name:        "slow_consumers",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"cause"})
+	promSignalRateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "signal",
+		Name:        "rate_limited",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"limit"})
+	promIPFilterBlocked = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "ip_filter",
+		Name:        "blocked",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"transport", "reason"})
+
+	prometheus.MustRegister(promSignalConnections)
+	prometheus.MustRegister(promSignalConnectionsRejected)
+	prometheus.MustRegister(promSignalWriteQueueDepth)
+	prometheus.MustRegister(promSignalSlowConsumers)
+	prometheus.MustRegister(promSignalRateLimited)
+	prometheus.MustRegister(promIPFilterBlocked)
+}
+
+func AddSignalConnection(delta int) {
+	promSignalConnections.Add(float64(delta))
+}
+
+func RecordSignalConnectionRejected() {
+	promSignalConnectionsRejected.Inc()
+}
+
+// AddSignalWriteQueueDepth tracks the signal messages waiting to be sent to participants of this node
+func AddSignalWriteQueueDepth(delta int) {
+	promSignalWriteQueueDepth.Add(float64(delta))
+}
+
+func RecordSignalSlowConsumer(cause string) {
+	promSignalSlowConsumers.WithLabelValues(cause).Inc()
+}
+
+// RecordSignalRateLimited counts connection attempts refused by a limit of signal_rate_limit
+func RecordSignalRateLimited(limit string) {
+	promSignalRateLimited.WithLabelValues(limit).Inc()
+}
+
+// RecordIPFilterBlocked counts signal connections and TURN allocations refused by ip_filter, transport is signal
+// or turn and reason the kind of rule which refused the address
+func RecordIPFilterBlocked(transport, reason string) {
+	promIPFilterBlocked.WithLabelValues(transport, reason).Inc()
+}
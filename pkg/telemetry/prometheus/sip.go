@@ -0,0 +1,142 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const (
+	SIPDirectionInbound  = "inbound"
+	SIPDirectionOutbound = "outbound"
+)
+
+var (
+	promSIPCallAttempts  *prometheus.CounterVec
+	promSIPCallAnswered  *prometheus.CounterVec
+	promSIPCallFailures  *prometheus.CounterVec
+	promSIPCallsActive   *prometheus.GaugeVec
+	promSIPPostDialDelay *prometheus.HistogramVec
+
+	promG711Transcodes        *prometheus.GaugeVec
+	promG711TranscodeRejected *prometheus.CounterVec
+)
+
+func initSIPStats(nodeID string, nodeType livekit.NodeType) {
+	promSIPCallAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "sip",
+		Name:        "call_attempts",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"trunk_id", "direction"})
+	promSIPCallAnswered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "sip",
+		Name:        "call_answered",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"trunk_id", "direction"})
+	promSIPCallFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "sip",
+		Name:        "call_failures",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"trunk_id", "direction", "code"})
+	promSIPCallsActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "sip",
+		Name:        "calls_active",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"trunk_id", "direction"})
+	promSIPPostDialDelay = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "sip",
+		Name:        "post_dial_delay_ms",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Buckets:     prometheus.ExponentialBucketsRange(100, 60000, 15),
+	}, []string{"trunk_id"})
+
+	promG711Transcodes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "sip",
+		Name:        "g711_transcoded_tracks",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"from", "to"})
+	promG711TranscodeRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "sip",
+		Name:        "g711_transcodes_rejected",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"from", "to"})
+
+	prometheus.MustRegister(promSIPCallAttempts)
+	prometheus.MustRegister(promSIPCallAnswered)
+	prometheus.MustRegister(promSIPCallFailures)
+	prometheus.MustRegister(promSIPCallsActive)
+	prometheus.MustRegister(promSIPPostDialDelay)
+	prometheus.MustRegister(promG711Transcodes)
+	prometheus.MustRegister(promG711TranscodeRejected)
+}
+
+func RecordSIPCallAttempt(trunkID string, direction string) {
+	promSIPCallAttempts.WithLabelValues(trunkID, direction).Inc()
+}
+
+// RecordSIPCallAnswered records an answered call, postDialDelay is only observed for outbound calls
+func RecordSIPCallAnswered(trunkID string, direction string, postDialDelay time.Duration) {
+	promSIPCallAnswered.WithLabelValues(trunkID, direction).Inc()
+	if direction == SIPDirectionOutbound && postDialDelay > 0 {
+		promSIPPostDialDelay.WithLabelValues(trunkID).Observe(float64(postDialDelay.Milliseconds()))
+	}
+}
+
+// RecordSIPCallFailed records a failed call, code is the SIP response code when known
+func RecordSIPCallFailed(trunkID string, direction string, code string) {
+	promSIPCallFailures.WithLabelValues(trunkID, direction, code).Inc()
+}
+
+func AddSIPActiveCall(trunkID string, direction string) {
+	promSIPCallsActive.WithLabelValues(trunkID, direction).Add(1)
+}
+
+func SubSIPActiveCall(trunkID string, direction string) {
+	promSIPCallsActive.WithLabelValues(trunkID, direction).Sub(1)
+}
+
+// AddG711Transcode counts a G.711 track converted between laws, from and to are mime types
+func AddG711Transcode(from, to string) {
+	if !initialized.Load() {
+		return
+	}
+	promG711Transcodes.WithLabelValues(from, to).Add(1)
+}
+
+func SubG711Transcode(from, to string) {
+	if !initialized.Load() {
+		return
+	}
+	promG711Transcodes.WithLabelValues(from, to).Sub(1)
+}
+
+// RecordG711TranscodeRejected counts G.711 tracks not converted as the node converted as many as it may
+func RecordG711TranscodeRejected(from, to string) {
+	if !initialized.Load() {
+		return
+	}
+	promG711TranscodeRejected.WithLabelValues(from, to).Inc()
+}
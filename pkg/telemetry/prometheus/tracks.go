@@ -0,0 +1,251 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"path"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// Track stats are labeled with the room, participant and track they belong to. Each one of them is a new
+// series, they are only recorded for the rooms operators opt in and up to a maximum number of series.
+
+type TrackStatsParams struct {
+	// aggregate the tracks of a participant into a single series
+	PerParticipant bool
+	// path.Match patterns of room names, all rooms when empty
+	Rooms []string
+	// label sets recorded at once, stats of new ones are dropped past it. 0 is unlimited
+	MaxSeries int
+}
+
+type TrackSeries struct {
+	Room        livekit.RoomName
+	Participant livekit.ParticipantIdentity
+	Track       livekit.TrackID
+	Direction   Direction
+}
+
+type TrackStats struct {
+	Bitrate     float64
+	Packets     uint32
+	PacketsLost uint32
+	Nacks       uint32
+	Plis        uint32
+	Jitter      uint32
+}
+
+var (
+	trackStatsParams  *TrackStatsParams
+	trackSeriesLock   sync.Mutex
+	trackSeries       map[TrackSeries]*trackSeriesState
+	promTrackLabels   = []string{"room", "participant", "track", "direction"}
+	promTrackBitrate  *prometheus.GaugeVec
+	promTrackPackets  *prometheus.CounterVec
+	promTrackLost     *prometheus.CounterVec
+	promTrackNacks    *prometheus.CounterVec
+	promTrackPlis     *prometheus.CounterVec
+	promTrackJitter   *prometheus.GaugeVec
+	promTrackDropped  prometheus.Counter
+	promTrackClient   *prometheus.CounterVec
+	promTrackCounters []*prometheus.CounterVec
+)
+
+// EnableTrackStats records the stats of tracks, it is called once, before Init
+func EnableTrackStats(params TrackStatsParams) {
+	trackStatsParams = &params
+	trackSeries = make(map[TrackSeries]*trackSeriesState)
+}
+
+// latest gauges of the tracks aggregated into a series
+type trackSeriesState struct {
+	bitrates map[livekit.TrackID]float64
+	jitters  map[livekit.TrackID]uint32
+}
+
+func (s *trackSeriesState) update(trackID livekit.TrackID, stats TrackStats) (bitrate float64, jitter uint32) {
+	s.bitrates[trackID] = stats.Bitrate
+	s.jitters[trackID] = stats.Jitter
+	for _, b := range s.bitrates {
+		bitrate += b
+	}
+	for _, j := range s.jitters {
+		jitter = max(jitter, j)
+	}
+	return
+}
+
+func initTrackStats(nodeID string, nodeType livekit.NodeType) {
+	if trackStatsParams == nil {
+		return
+	}
+
+	constLabels := prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()}
+	promTrackBitrate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "bitrate",
+		ConstLabels: constLabels,
+	}, promTrackLabels)
+	promTrackPackets = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "packets",
+		ConstLabels: constLabels,
+	}, promTrackLabels)
+	promTrackLost = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "packets_lost",
+		ConstLabels: constLabels,
+	}, promTrackLabels)
+	promTrackNacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "nacks",
+		ConstLabels: constLabels,
+	}, promTrackLabels)
+	promTrackPlis = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "plis",
+		ConstLabels: constLabels,
+	}, promTrackLabels)
+	promTrackJitter = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "jitter_us",
+		ConstLabels: constLabels,
+	}, promTrackLabels)
+	promTrackDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "series_dropped",
+		ConstLabels: constLabels,
+	})
+	promTrackClient = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "client_events",
+		ConstLabels: constLabels,
+	}, append(promTrackLabels, "type"))
+	promTrackCounters = []*prometheus.CounterVec{promTrackPackets, promTrackLost, promTrackNacks, promTrackPlis, promTrackClient}
+
+	prometheus.MustRegister(promTrackBitrate)
+	prometheus.MustRegister(promTrackPackets)
+	prometheus.MustRegister(promTrackLost)
+	prometheus.MustRegister(promTrackNacks)
+	prometheus.MustRegister(promTrackPlis)
+	prometheus.MustRegister(promTrackJitter)
+	prometheus.MustRegister(promTrackDropped)
+	prometheus.MustRegister(promTrackClient)
+}
+
+// TrackStatsEnabled returns whether the stats of the tracks of a room are recorded
+func TrackStatsEnabled(roomName livekit.RoomName) bool {
+	if trackStatsParams == nil {
+		return false
+	}
+	if len(trackStatsParams.Rooms) == 0 {
+		return true
+	}
+	for _, pattern := range trackStatsParams.Rooms {
+		if ok, _ := path.Match(pattern, string(roomName)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func RecordTrackStats(series TrackSeries, stats TrackStats) {
+	if !TrackStatsEnabled(series.Room) {
+		return
+	}
+	trackID := series.Track
+	if trackStatsParams.PerParticipant {
+		series.Track = ""
+	}
+
+	trackSeriesLock.Lock()
+	state, ok := trackSeries[series]
+	if !ok {
+		if trackStatsParams.MaxSeries > 0 && len(trackSeries) >= trackStatsParams.MaxSeries {
+			trackSeriesLock.Unlock()
+			promTrackDropped.Inc()
+			return
+		}
+		state = &trackSeriesState{
+			bitrates: make(map[livekit.TrackID]float64),
+			jitters:  make(map[livekit.TrackID]uint32),
+		}
+		trackSeries[series] = state
+	}
+	// a participant's series sums the bitrates of its tracks and keeps the worst jitter
+	bitrate, jitter := state.update(trackID, stats)
+	trackSeriesLock.Unlock()
+
+	labels := []string{string(series.Room), string(series.Participant), string(series.Track), string(series.Direction)}
+	promTrackBitrate.WithLabelValues(labels...).Set(bitrate)
+	promTrackJitter.WithLabelValues(labels...).Set(float64(jitter))
+	promTrackPackets.WithLabelValues(labels...).Add(float64(stats.Packets))
+	promTrackLost.WithLabelValues(labels...).Add(float64(stats.PacketsLost))
+	promTrackNacks.WithLabelValues(labels...).Add(float64(stats.Nacks))
+	promTrackPlis.WithLabelValues(labels...).Add(float64(stats.Plis))
+}
+
+// RecordTrackClientEvent records events a client reported about a track next to the stats of the track.
+// Events are only recorded for series the server records stats for.
+func RecordTrackClientEvent(series TrackSeries, eventType string, count uint32) {
+	if !TrackStatsEnabled(series.Room) {
+		return
+	}
+	if trackStatsParams.PerParticipant {
+		series.Track = ""
+	}
+
+	trackSeriesLock.Lock()
+	_, ok := trackSeries[series]
+	trackSeriesLock.Unlock()
+	if !ok {
+		return
+	}
+	promTrackClient.WithLabelValues(string(series.Room), string(series.Participant), string(series.Track), string(series.Direction), eventType).Add(float64(count))
+}
+
+// DeleteParticipantTrackStats removes the series of a participant which left, freeing room for new ones
+func DeleteParticipantTrackStats(roomName livekit.RoomName, identity livekit.ParticipantIdentity) {
+	if !TrackStatsEnabled(roomName) {
+		return
+	}
+
+	trackSeriesLock.Lock()
+	for series := range trackSeries {
+		if series.Room == roomName && series.Participant == identity {
+			delete(trackSeries, series)
+		}
+	}
+	trackSeriesLock.Unlock()
+
+	labels := prometheus.Labels{"room": string(roomName), "participant": string(identity)}
+	promTrackBitrate.DeletePartialMatch(labels)
+	promTrackJitter.DeletePartialMatch(labels)
+	for _, c := range promTrackCounters {
+		c.DeletePartialMatch(labels)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestTrackStats(t *testing.T) {
+	EnableTrackStats(TrackStatsParams{
+		PerParticipant: true,
+		Rooms:          []string{"flagship-*"},
+		MaxSeries:      1,
+	})
+	initTrackStats("test", livekit.NodeType_SERVER)
+
+	series := TrackSeries{Room: "flagship-1", Participant: "alice", Track: "TR_1", Direction: Incoming}
+	RecordTrackStats(series, TrackStats{Bitrate: 1000, Packets: 10, Jitter: 20})
+	series.Track = "TR_2"
+	RecordTrackStats(series, TrackStats{Bitrate: 500, Packets: 5, Jitter: 10})
+
+	// tracks of a participant are aggregated
+	require.Equal(t, 1, testutil.CollectAndCount(promTrackBitrate))
+	require.Equal(t, 1500.0, testutil.ToFloat64(promTrackBitrate.WithLabelValues("flagship-1", "alice", "", "incoming")))
+	require.Equal(t, 20.0, testutil.ToFloat64(promTrackJitter.WithLabelValues("flagship-1", "alice", "", "incoming")))
+	require.Equal(t, 15.0, testutil.ToFloat64(promTrackPackets.WithLabelValues("flagship-1", "alice", "", "incoming")))
+
+	// past the maximum, new series are dropped
+	RecordTrackStats(TrackSeries{Room: "flagship-1", Participant: "bob", Track: "TR_3", Direction: Incoming}, TrackStats{Bitrate: 1000})
+	require.Equal(t, 1.0, testutil.ToFloat64(promTrackDropped))
+
+	// rooms outside of the allowlist are not recorded
+	require.False(t, TrackStatsEnabled("meeting"))
+	RecordTrackStats(TrackSeries{Room: "meeting", Participant: "carol", Track: "TR_4", Direction: Incoming}, TrackStats{Bitrate: 1000})
+	require.Equal(t, 1.0, testutil.ToFloat64(promTrackDropped))
+
+	DeleteParticipantTrackStats("flagship-1", "alice")
+	require.Equal(t, 0, testutil.CollectAndCount(promTrackBitrate))
+	RecordTrackStats(TrackSeries{Room: "flagship-1", Participant: "bob", Track: "TR_3", Direction: Incoming}, TrackStats{Bitrate: 1000})
+	require.Equal(t, 1, testutil.CollectAndCount(promTrackBitrate))
+}
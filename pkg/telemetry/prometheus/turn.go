@@ -0,0 +1,93 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promTURNAllocations      *prometheus.GaugeVec
+	promTURNAllocationsTotal *prometheus.CounterVec
+	promTURNRelayedBytes     *prometheus.CounterVec
+	promTURNQuotaExceeded    *prometheus.CounterVec
+)
+
+func initTURNStats(nodeID string, nodeType livekit.NodeType) {
+	promTURNAllocations = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "turn",
+		Name:        "allocations",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"transport"})
+	promTURNAllocationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "turn",
+		Name:        "allocations_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"transport"})
+	promTURNRelayedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "turn",
+		Name:        "relayed_bytes",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"transport", "direction"})
+	promTURNQuotaExceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "turn",
+		Name:        "quota_exceeded",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"transport"})
+
+	prometheus.MustRegister(promTURNAllocations)
+	prometheus.MustRegister(promTURNAllocationsTotal)
+	prometheus.MustRegister(promTURNRelayedBytes)
+	prometheus.MustRegister(promTURNQuotaExceeded)
+}
+
+// AddTURNAllocation counts the TURN sessions of clients on the embedded TURN server, transport is udp, tcp or tls
+func AddTURNAllocation(transport string) {
+	if !initialized.Load() {
+		return
+	}
+	promTURNAllocations.WithLabelValues(transport).Add(1)
+	promTURNAllocationsTotal.WithLabelValues(transport).Inc()
+}
+
+func SubTURNAllocation(transport string) {
+	if !initialized.Load() {
+		return
+	}
+	promTURNAllocations.WithLabelValues(transport).Sub(1)
+}
+
+// RecordTURNRelayedBytes counts the bytes received from (incoming) or sent to (outgoing) TURN clients
+func RecordTURNRelayedBytes(transport string, direction Direction, n int) {
+	if !initialized.Load() {
+		return
+	}
+	promTURNRelayedBytes.WithLabelValues(transport, string(direction)).Add(float64(n))
+}
+
+// RecordTURNQuotaExceeded counts the UDP packets dropped and the TCP reads and writes delayed as their participant
+// was over turn.quota
+func RecordTURNQuotaExceeded(transport string) {
+	if !initialized.Load() {
+		return
+	}
+	promTURNQuotaExceeded.WithLabelValues(transport).Inc()
+}
@@ -94,6 +94,32 @@ func (t *telemetryService) TrackStats(key StatsKey, stat *livekit.AnalyticsStat)
 
 		if worker, ok := t.getWorker(key.participantID); ok {
 			worker.OnTrackStat(key.trackID, key.streamType, stat)
+			if key.track && prometheus.TrackStatsEnabled(worker.roomName) {
+				prometheus.RecordTrackStats(prometheus.TrackSeries{
+					Room:        worker.roomName,
+					Participant: worker.participantIdentity,
+					Track:       key.trackID,
+					Direction:   direction,
+				}, trackStats(stat))
+			}
 		}
 	})
 }
+
+func trackStats(stat *livekit.AnalyticsStat) prometheus.TrackStats {
+	var ts prometheus.TrackStats
+	for _, stream := range stat.Streams {
+		if stream.StartTime != nil && stream.EndTime != nil {
+			if d := stream.EndTime.AsTime().Sub(stream.StartTime.AsTime()); d > 0 {
+				bytes := stream.PrimaryBytes + stream.PaddingBytes + stream.RetransmitBytes
+				ts.Bitrate += float64(bytes*8) / d.Seconds()
+			}
+		}
+		ts.Packets += stream.PrimaryPackets + stream.PaddingPackets + stream.RetransmitPackets
+		ts.PacketsLost += stream.PacketsLost
+		ts.Nacks += stream.Nacks
+		ts.Plis += stream.Plis
+		ts.Jitter = max(ts.Jitter, stream.Jitter)
+	}
+	return ts
+}
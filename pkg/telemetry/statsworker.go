@@ -123,7 +123,7 @@ func (s *StatsWorker) Close() bool {
 
 	ok := s.closedAt.IsZero()
 	if ok {
-		s.closedAt = time.Now()
+		s.closedAt = utils.ClusterNow()
 	}
 	return ok
 }
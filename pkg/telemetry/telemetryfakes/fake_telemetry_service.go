@@ -10,6 +10,13 @@ import (
 )
 
 type FakeTelemetryService struct {
+	ClientEventsStub        func(context.Context, livekit.ParticipantID, []*telemetry.ClientEvent)
+	clientEventsMutex       sync.RWMutex
+	clientEventsArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 []*telemetry.ClientEvent
+	}
 	EgressEndedStub        func(context.Context, *livekit.EgressInfo)
 	egressEndedMutex       sync.RWMutex
 	egressEndedArgsForCall []struct {
@@ -140,6 +147,12 @@ type FakeTelemetryService struct {
 		arg1 context.Context
 		arg2 []*livekit.AnalyticsStat
 	}
+	TURNAllocationStub        func(context.Context, *telemetry.TURNAllocationEvent)
+	tURNAllocationMutex       sync.RWMutex
+	tURNAllocationArgsForCall []struct {
+		arg1 context.Context
+		arg2 *telemetry.TURNAllocationEvent
+	}
 	TrackMaxSubscribedVideoQualityStub        func(context.Context, livekit.ParticipantID, *livekit.TrackInfo, string, livekit.VideoQuality)
 	trackMaxSubscribedVideoQualityMutex       sync.RWMutex
 	trackMaxSubscribedVideoQualityArgsForCall []struct {
@@ -257,6 +270,45 @@ type FakeTelemetryService struct {
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeTelemetryService) ClientEvents(arg1 context.Context, arg2 livekit.ParticipantID, arg3 []*telemetry.ClientEvent) {
+	var arg3Copy []*telemetry.ClientEvent
+	if arg3 != nil {
+		arg3Copy = make([]*telemetry.ClientEvent, len(arg3))
+		copy(arg3Copy, arg3)
+	}
+	fake.clientEventsMutex.Lock()
+	fake.clientEventsArgsForCall = append(fake.clientEventsArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 []*telemetry.ClientEvent
+	}{arg1, arg2, arg3Copy})
+	stub := fake.ClientEventsStub
+	fake.recordInvocation("ClientEvents", []interface{}{arg1, arg2, arg3Copy})
+	fake.clientEventsMutex.Unlock()
+	if stub != nil {
+		fake.ClientEventsStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeTelemetryService) ClientEventsCallCount() int {
+	fake.clientEventsMutex.RLock()
+	defer fake.clientEventsMutex.RUnlock()
+	return len(fake.clientEventsArgsForCall)
+}
+
+func (fake *FakeTelemetryService) ClientEventsCalls(stub func(context.Context, livekit.ParticipantID, []*telemetry.ClientEvent)) {
+	fake.clientEventsMutex.Lock()
+	defer fake.clientEventsMutex.Unlock()
+	fake.ClientEventsStub = stub
+}
+
+func (fake *FakeTelemetryService) ClientEventsArgsForCall(i int) (context.Context, livekit.ParticipantID, []*telemetry.ClientEvent) {
+	fake.clientEventsMutex.RLock()
+	defer fake.clientEventsMutex.RUnlock()
+	argsForCall := fake.clientEventsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
 func (fake *FakeTelemetryService) EgressEnded(arg1 context.Context, arg2 *livekit.EgressInfo) {
 	fake.egressEndedMutex.Lock()
 	fake.egressEndedArgsForCall = append(fake.egressEndedArgsForCall, struct {
@@ -925,6 +977,39 @@ func (fake *FakeTelemetryService) SendStatsArgsForCall(i int) (context.Context,
 	return argsForCall.arg1, argsForCall.arg2
 }
 
+func (fake *FakeTelemetryService) TURNAllocation(arg1 context.Context, arg2 *telemetry.TURNAllocationEvent) {
+	fake.tURNAllocationMutex.Lock()
+	fake.tURNAllocationArgsForCall = append(fake.tURNAllocationArgsForCall, struct {
+		arg1 context.Context
+		arg2 *telemetry.TURNAllocationEvent
+	}{arg1, arg2})
+	stub := fake.TURNAllocationStub
+	fake.recordInvocation("TURNAllocation", []interface{}{arg1, arg2})
+	fake.tURNAllocationMutex.Unlock()
+	if stub != nil {
+		fake.TURNAllocationStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeTelemetryService) TURNAllocationCallCount() int {
+	fake.tURNAllocationMutex.RLock()
+	defer fake.tURNAllocationMutex.RUnlock()
+	return len(fake.tURNAllocationArgsForCall)
+}
+
+func (fake *FakeTelemetryService) TURNAllocationCalls(stub func(context.Context, *telemetry.TURNAllocationEvent)) {
+	fake.tURNAllocationMutex.Lock()
+	defer fake.tURNAllocationMutex.Unlock()
+	fake.TURNAllocationStub = stub
+}
+
+func (fake *FakeTelemetryService) TURNAllocationArgsForCall(i int) (context.Context, *telemetry.TURNAllocationEvent) {
+	fake.tURNAllocationMutex.RLock()
+	defer fake.tURNAllocationMutex.RUnlock()
+	argsForCall := fake.tURNAllocationArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
 func (fake *FakeTelemetryService) TrackMaxSubscribedVideoQuality(arg1 context.Context, arg2 livekit.ParticipantID, arg3 *livekit.TrackInfo, arg4 string, arg5 livekit.VideoQuality) {
 	fake.trackMaxSubscribedVideoQualityMutex.Lock()
 	fake.trackMaxSubscribedVideoQualityArgsForCall = append(fake.trackMaxSubscribedVideoQualityArgsForCall, struct {
@@ -1419,6 +1504,8 @@ func (fake *FakeTelemetryService) TrackUnsubscribedArgsForCall(i int) (context.C
 func (fake *FakeTelemetryService) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.clientEventsMutex.RLock()
+	defer fake.clientEventsMutex.RUnlock()
 	fake.egressEndedMutex.RLock()
 	defer fake.egressEndedMutex.RUnlock()
 	fake.egressStartedMutex.RLock()
@@ -1459,6 +1546,8 @@ func (fake *FakeTelemetryService) Invocations() map[string][][]interface{} {
 	defer fake.sendNodeRoomStatesMutex.RUnlock()
 	fake.sendStatsMutex.RLock()
 	defer fake.sendStatsMutex.RUnlock()
+	fake.tURNAllocationMutex.RLock()
+	defer fake.tURNAllocationMutex.RUnlock()
 	fake.trackMaxSubscribedVideoQualityMutex.RLock()
 	defer fake.trackMaxSubscribedVideoQualityMutex.RUnlock()
 	fake.trackMutedMutex.RLock()
@@ -76,6 +76,10 @@ type TelemetryService interface {
 	IngressUpdated(ctx context.Context, info *livekit.IngressInfo)
 	IngressEnded(ctx context.Context, info *livekit.IngressInfo)
 	LocalRoomState(ctx context.Context, info *livekit.AnalyticsNodeRooms)
+	// ClientEvents - quality of experience events reported by a participant
+	ClientEvents(ctx context.Context, participantID livekit.ParticipantID, events []*ClientEvent)
+	// TURNAllocation - sessions of clients on the embedded TURN server
+	TURNAllocation(ctx context.Context, event *TURNAllocationEvent)
 
 	// helpers
 	AnalyticsService
@@ -132,7 +136,8 @@ func (t *telemetryService) FlushStats() {
 	worker := t.workerList
 	t.workersMu.RUnlock()
 
-	now := time.Now()
+	// stats are timestamped with the clock of the cluster, so that they line up with stats of other nodes
+	now := utils.ClusterNow()
 	var prev, reap *StatsWorker
 	for worker != nil {
 		next := worker.next
@@ -0,0 +1,161 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/tracer"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/metadata"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	tracerName             = "github.com/livekit/livekit-server"
+	tracingShutdownTimeout = 5 * time.Second
+)
+
+// InitTracing exports spans to the configured OTLP collector. Trace context is propagated either way, so that
+// traces started upstream keep going through a node which doesn't export spans. The returned func flushes
+// the spans still batched.
+func InitTracing(conf config.TracingConfig, nodeID livekit.NodeID, version string) (func(), error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if !conf.IsConfigured() {
+		return func() {}, nil
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(conf.Endpoint)}
+	if conf.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	if len(conf.Headers) != 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(conf.Headers))
+	}
+	// the exporter connects lazily, an unreachable collector doesn't prevent the node from starting
+	exporter, err := otlptracegrpc.New(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", conf.ServiceName),
+			attribute.String("service.version", version),
+			attribute.String("service.instance.id", string(nodeID)),
+		)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	tracer.SetTracer(protocolTracer{})
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if err := tp.Shutdown(ctx); err != nil {
+			logger.Warnw("could not flush spans", err)
+		}
+	}, nil
+}
+
+// StartSpan starts a span, child of the span of ctx or of the trace received with the psrpc request being
+// served. The trace context is added to the metadata of psrpc requests made with the returned context.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if head := metadata.IncomingHeader(ctx); head != nil && !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(head.Metadata))
+	}
+	ctx, span := otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+	for k, v := range TraceHeaders(ctx) {
+		ctx = metadata.AppendMetadataToOutgoingContext(ctx, k, v)
+	}
+	return ctx, span
+}
+
+// EndSpan ends the span, marking it as failed when err is set
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceHeaders returns the W3C trace context headers of the span of ctx, nil without span
+func TraceHeaders(ctx context.Context) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil
+	}
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// TraceID returns the ID of the trace of ctx, empty without span
+func TraceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
+// PSRPCTracingInterceptor serves psrpc requests in a span, continuing the trace of the caller
+func PSRPCTracingInterceptor(ctx context.Context, req proto.Message, info psrpc.RPCInfo, handler psrpc.ServerRPCHandler) (proto.Message, error) {
+	ctx, span := StartSpan(ctx, info.Service+"."+info.Method,
+		attribute.String("rpc.system", "psrpc"),
+		attribute.String("rpc.service", info.Service),
+		attribute.String("rpc.method", info.Method),
+	)
+	res, err := handler(ctx, req)
+	EndSpan(span, err)
+	return res, err
+}
+
+// ---------------------------------------------
+
+// protocolTracer exports the spans started by protocol packages
+type protocolTracer struct{}
+
+func (protocolTracer) Start(ctx context.Context, spanName string, _ ...interface{}) (context.Context, tracer.Span) {
+	ctx, span := StartSpan(ctx, spanName)
+	return ctx, protocolSpan{span}
+}
+
+type protocolSpan struct {
+	span trace.Span
+}
+
+func (s protocolSpan) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s protocolSpan) End() {
+	s.span.End()
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/livekit/psrpc/pkg/metadata"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestStartSpanPropagation(t *testing.T) {
+	_, err := InitTracing(config.TracingConfig{}, "ND_1", "test")
+	require.NoError(t, err)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	// the caller starts the trace, its context is sent in psrpc metadata
+	ctx, caller := StartSpan(context.Background(), "caller")
+	headers := TraceHeaders(ctx)
+	require.Contains(t, headers, "traceparent")
+	caller.End()
+
+	// the handler continues the trace received with the request
+	ctx = metadata.NewContextWithIncomingHeader(context.Background(), &metadata.Header{Metadata: headers})
+	ctx, handler := StartSpan(ctx, "handler")
+	handler.End()
+	require.Equal(t, caller.SpanContext().TraceID().String(), TraceID(ctx))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, spans[0].SpanContext().SpanID(), spans[1].Parent().SpanID())
+
+	require.Empty(t, TraceID(context.Background()))
+	require.Nil(t, TraceHeaders(context.Background()))
+}
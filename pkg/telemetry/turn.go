@@ -0,0 +1,63 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+type TURNAllocationEventType string
+
+const (
+	TURNAllocationStarted TURNAllocationEventType = "started"
+	TURNAllocationEnded   TURNAllocationEventType = "ended"
+)
+
+// TURNAllocationEvent is about the session of a client on the embedded TURN server, from the first request
+// it authenticated to the close of its connection, or to its expiry for UDP
+type TURNAllocationEvent struct {
+	Type          TURNAllocationEventType
+	ParticipantID livekit.ParticipantID
+	RoomName      livekit.RoomName
+	// udp, tcp or tls
+	Transport  string
+	RemoteAddr string
+	// for ended sessions, bytes received from and sent to the client
+	BytesIn  uint64
+	BytesOut uint64
+	Duration time.Duration
+}
+
+func (t *telemetryService) TURNAllocation(ctx context.Context, event *TURNAllocationEvent) {
+	t.enqueue(func() {
+		values := []interface{}{
+			"participantID", event.ParticipantID,
+			"room", event.RoomName,
+			"transport", event.Transport,
+			"remoteAddr", event.RemoteAddr,
+		}
+		if worker, ok := t.getWorker(event.ParticipantID); ok {
+			values = append(values, "participant", worker.participantIdentity)
+		}
+		if event.Type == TURNAllocationEnded {
+			values = append(values, "bytesIn", event.BytesIn, "bytesOut", event.BytesOut, "duration", event.Duration)
+		}
+		logger.Infow("TURN allocation "+string(event.Type), values...)
+	})
+}
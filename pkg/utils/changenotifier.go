@@ -16,16 +16,24 @@
 
 package utils
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
 
 type ChangeNotifier struct {
-	lock      sync.Mutex
-	observers map[string]func()
+	key            string
+	lock           sync.Mutex
+	observers      map[string]func()
+	batchObservers map[string]func(changedKeys []string)
+
+	batcher *changeNotifierBatcher
 }
 
 func NewChangeNotifier() *ChangeNotifier {
 	return &ChangeNotifier{
-		observers: make(map[string]func()),
+		observers:      make(map[string]func()),
+		batchObservers: make(map[string]func(changedKeys []string)),
 	}
 }
 
@@ -36,23 +44,41 @@ func (n *ChangeNotifier) AddObserver(key string, onChanged func()) {
 	n.observers[key] = onChanged
 }
 
+// AddBatchObserver registers an observer that is called with the keys of the notifiers that changed.
+// With a batched manager, an observer key registered on several notifiers is called once per batch,
+// so the same handler should be used for a key on all notifiers of the manager.
+func (n *ChangeNotifier) AddBatchObserver(key string, onChanged func(changedKeys []string)) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.batchObservers[key] = onChanged
+}
+
 func (n *ChangeNotifier) RemoveObserver(key string) {
 	n.lock.Lock()
 	defer n.lock.Unlock()
 
 	delete(n.observers, key)
+	delete(n.batchObservers, key)
 }
 
 func (n *ChangeNotifier) HasObservers() bool {
 	n.lock.Lock()
 	defer n.lock.Unlock()
 
-	return len(n.observers) > 0
+	return len(n.observers) > 0 || len(n.batchObservers) > 0
 }
 
 func (n *ChangeNotifier) NotifyChanged() {
+	if n.batcher != nil {
+		if n.HasObservers() {
+			n.batcher.enqueue(n)
+		}
+		return
+	}
+
 	n.lock.Lock()
-	if len(n.observers) == 0 {
+	if len(n.observers) == 0 && len(n.batchObservers) == 0 {
 		n.lock.Unlock()
 		return
 	}
@@ -60,18 +86,142 @@ func (n *ChangeNotifier) NotifyChanged() {
 	for _, f := range n.observers {
 		observers = append(observers, f)
 	}
+	batchObservers := make([]func([]string), 0, len(n.batchObservers))
+	for _, f := range n.batchObservers {
+		batchObservers = append(batchObservers, f)
+	}
 	n.lock.Unlock()
 
 	go func() {
 		for _, f := range observers {
 			f()
 		}
+		for _, f := range batchObservers {
+			f([]string{n.key})
+		}
 	}()
 }
 
+func (n *ChangeNotifier) getObservers() (map[string]func(), map[string]func([]string)) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	observers := make(map[string]func(), len(n.observers))
+	for key, f := range n.observers {
+		observers[key] = f
+	}
+	batchObservers := make(map[string]func([]string), len(n.batchObservers))
+	for key, f := range n.batchObservers {
+		batchObservers[key] = f
+	}
+	return observers, batchObservers
+}
+
+// ------------------------------------------------
+
+type ChangeNotifierBatchParams struct {
+	// how long to collect notifications before delivering them
+	Window time.Duration
+	// called with the change in number of notifiers waiting for delivery
+	OnQueueDepthChanged func(delta int)
+	// called with the number of notifiers delivered in a batch
+	OnBatchDelivered func(size int)
+}
+
+// changeNotifierBatcher coalesces notifications from several notifiers. On delivery, batch observers are
+// called once per observer key with the keys of all the notifiers they observe that changed in the batch.
+// Plain observers are grouped by key and run in a single pass, one call per changed notifier.
+type changeNotifierBatcher struct {
+	params ChangeNotifierBatchParams
+
+	lock    sync.Mutex
+	pending []*ChangeNotifier
+	queued  map[*ChangeNotifier]struct{}
+}
+
+func newChangeNotifierBatcher(params ChangeNotifierBatchParams) *changeNotifierBatcher {
+	return &changeNotifierBatcher{
+		params: params,
+		queued: make(map[*ChangeNotifier]struct{}),
+	}
+}
+
+func (b *changeNotifierBatcher) enqueue(n *ChangeNotifier) {
+	b.lock.Lock()
+	if _, ok := b.queued[n]; ok {
+		b.lock.Unlock()
+		return
+	}
+	b.queued[n] = struct{}{}
+	b.pending = append(b.pending, n)
+	first := len(b.pending) == 1
+	b.lock.Unlock()
+
+	if b.params.OnQueueDepthChanged != nil {
+		b.params.OnQueueDepthChanged(1)
+	}
+	if first {
+		time.AfterFunc(b.params.Window, b.deliver)
+	}
+}
+
+func (b *changeNotifierBatcher) deliver() {
+	b.lock.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.queued = make(map[*ChangeNotifier]struct{})
+	b.lock.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+	if b.params.OnQueueDepthChanged != nil {
+		b.params.OnQueueDepthChanged(-len(pending))
+	}
+	if b.params.OnBatchDelivered != nil {
+		b.params.OnBatchDelivered(len(pending))
+	}
+
+	type batchDelivery struct {
+		onChanged   func([]string)
+		changedKeys []string
+	}
+	byKey := make(map[string][]func())
+	batchByKey := make(map[string]*batchDelivery)
+	for _, n := range pending {
+		observers, batchObservers := n.getObservers()
+		for key, f := range observers {
+			byKey[key] = append(byKey[key], f)
+		}
+		for key, f := range batchObservers {
+			d := batchByKey[key]
+			if d == nil {
+				d = &batchDelivery{}
+				batchByKey[key] = d
+			}
+			d.onChanged = f
+			d.changedKeys = append(d.changedKeys, n.key)
+		}
+	}
+	for _, observers := range byKey {
+		go func(observers []func()) {
+			for _, f := range observers {
+				f()
+			}
+		}(observers)
+	}
+	for _, d := range batchByKey {
+		go d.onChanged(d.changedKeys)
+	}
+}
+
+// ------------------------------------------------
+
 type ChangeNotifierManager struct {
 	lock      sync.Mutex
 	notifiers map[string]*ChangeNotifier
+
+	batcher *changeNotifierBatcher
 }
 
 func NewChangeNotifierManager() *ChangeNotifierManager {
@@ -80,6 +230,15 @@ func NewChangeNotifierManager() *ChangeNotifierManager {
 	}
 }
 
+// NewBatchedChangeNotifierManager returns a manager whose notifiers deliver changes in batches,
+// calling each batch observer once per batch with all notifiers that changed within the window.
+func NewBatchedChangeNotifierManager(params ChangeNotifierBatchParams) *ChangeNotifierManager {
+	return &ChangeNotifierManager{
+		notifiers: make(map[string]*ChangeNotifier),
+		batcher:   newChangeNotifierBatcher(params),
+	}
+}
+
 func (m *ChangeNotifierManager) GetNotifier(key string) *ChangeNotifier {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -96,6 +255,8 @@ func (m *ChangeNotifierManager) GetOrCreateNotifier(key string) *ChangeNotifier
 	}
 
 	notifier := NewChangeNotifier()
+	notifier.key = key
+	notifier.batcher = m.batcher
 	m.notifiers[key] = notifier
 	return notifier
 }
@@ -0,0 +1,124 @@
+/*
+ * Copyright 2024 LiveKit, Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils_test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+func TestBatchedChangeNotifierManager(t *testing.T) {
+	var queueDepth atomic.Int32
+	var batches []int
+	var batchesLock sync.Mutex
+	m := utils.NewBatchedChangeNotifierManager(utils.ChangeNotifierBatchParams{
+		Window: 50 * time.Millisecond,
+		OnQueueDepthChanged: func(delta int) {
+			queueDepth.Add(int32(delta))
+		},
+		OnBatchDelivered: func(size int) {
+			batchesLock.Lock()
+			batches = append(batches, size)
+			batchesLock.Unlock()
+		},
+	})
+
+	// two subscribers observing the same ten tracks
+	var changed sync.Map
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for i := 0; i < 10; i++ {
+		n := m.GetOrCreateNotifier(fmt.Sprintf("track%d", i))
+		for _, sub := range []string{"sub1", "sub2"} {
+			sub := sub
+			n.AddBatchObserver(sub, func(changedKeys []string) {
+				// one call per subscriber per batch, a second call would drive the wait group negative
+				changed.Store(sub, changedKeys)
+				wg.Done()
+			})
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		n := m.GetNotifier(fmt.Sprintf("track%d", i))
+		n.NotifyChanged()
+		// repeated notifications within the window are coalesced
+		n.NotifyChanged()
+	}
+	require.Equal(t, int32(10), queueDepth.Load())
+
+	wg.Wait()
+	require.Equal(t, int32(0), queueDepth.Load())
+	expected := make([]string, 0, 10)
+	for i := 0; i < 10; i++ {
+		expected = append(expected, fmt.Sprintf("track%d", i))
+	}
+	for _, sub := range []string{"sub1", "sub2"} {
+		v, ok := changed.Load(sub)
+		require.True(t, ok)
+		require.ElementsMatch(t, expected, v.([]string))
+	}
+
+	batchesLock.Lock()
+	require.Equal(t, []int{10}, batches)
+	batchesLock.Unlock()
+}
+
+func TestBatchedChangeNotifierNoObservers(t *testing.T) {
+	var queueDepth atomic.Int32
+	m := utils.NewBatchedChangeNotifierManager(utils.ChangeNotifierBatchParams{
+		Window: 10 * time.Millisecond,
+		OnQueueDepthChanged: func(delta int) {
+			queueDepth.Add(int32(delta))
+		},
+	})
+
+	m.GetOrCreateNotifier("track").NotifyChanged()
+	require.Equal(t, int32(0), queueDepth.Load())
+}
+
+func TestBatchedChangeNotifierPartialObservers(t *testing.T) {
+	m := utils.NewBatchedChangeNotifierManager(utils.ChangeNotifierBatchParams{
+		Window: 10 * time.Millisecond,
+	})
+
+	// sub1 observes both tracks, sub2 only the first one
+	calls := make(chan []string, 2)
+	for _, key := range []string{"track1", "track2"} {
+		m.GetOrCreateNotifier(key).AddBatchObserver("sub1", func(changedKeys []string) {
+			calls <- changedKeys
+		})
+	}
+	sub2Calls := make(chan []string, 1)
+	m.GetOrCreateNotifier("track1").AddBatchObserver("sub2", func(changedKeys []string) {
+		sub2Calls <- changedKeys
+	})
+
+	m.GetNotifier("track1").NotifyChanged()
+	m.GetNotifier("track2").NotifyChanged()
+
+	require.ElementsMatch(t, []string{"track1", "track2"}, <-calls)
+	require.Equal(t, []string{"track1"}, <-sub2Calls)
+	require.Never(t, func() bool { return len(calls) != 0 }, 50*time.Millisecond, 10*time.Millisecond)
+}
@@ -0,0 +1,38 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// offset of the local clock from the clock of the cluster, positive when the local clock is ahead
+var clockOffset atomic.Int64
+
+// SetClockOffset sets the estimated offset of the local clock from the clock agreed by the nodes of the cluster
+func SetClockOffset(offset time.Duration) {
+	clockOffset.Store(int64(offset))
+}
+
+func ClockOffset() time.Duration {
+	return time.Duration(clockOffset.Load())
+}
+
+// ClusterNow returns the current time by the clock of the cluster, for times compared with ones of other
+// nodes, such as token expiries and stats timestamps
+func ClusterNow() time.Time {
+	return time.Now().Add(-ClockOffset())
+}
@@ -20,10 +20,15 @@ import (
 	"context"
 
 	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc/pkg/metadata"
 )
 
+// RequestIDMetadataKey carries the request ID in psrpc metadata
+const RequestIDMetadataKey = "request_id"
+
 type attemptKey struct{}
 type loggerKey = struct{}
+type requestIDKey struct{}
 
 func ContextWithAttempt(ctx context.Context, attempt int) context.Context {
 	return context.WithValue(ctx, attemptKey{}, attempt)
@@ -46,3 +51,21 @@ func GetLogger(ctx context.Context) logger.Logger {
 	}
 	return logger.GetLogger()
 }
+
+// ContextWithRequestID sets the ID of the request being served, it is also added to the metadata of psrpc
+// requests made with the returned context
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	ctx = metadata.AppendMetadataToOutgoingContext(ctx, RequestIDMetadataKey, requestID)
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// GetRequestID returns the ID of the request being served, or the one received with a psrpc request
+func GetRequestID(ctx context.Context) string {
+	if requestID, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return requestID
+	}
+	if head := metadata.IncomingHeader(ctx); head != nil {
+		return head.Metadata[RequestIDMetadataKey]
+	}
+	return ""
+}
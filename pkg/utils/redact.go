@@ -0,0 +1,219 @@
+/*
+ * Copyright 2024 LiveKit, Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/livekit/protocol/logger"
+)
+
+const DefaultRedactionReplacement = "[redacted]"
+
+var (
+	// E.164 numbers, and national numbers with optional country code, area code and separators
+	phoneNumberRegexp = regexp.MustCompile(`\+\d{7,15}\b|(?:\+\d{1,3}[\s-]?)?(?:\(\d{1,4}\)[\s-]?|\b\d{2,4}[\s-]?)\d{3,4}[\s-]?\d{3,4}\b`)
+	// user part of sip: and sips: URIs
+	sipUserRegexp = regexp.MustCompile(`(sips?:)[^@;:\s<>"]+@`)
+)
+
+type LogRedactionRules struct {
+	PhoneNumbers bool
+	SIPUsernames bool
+	// participant identities matching any of these expressions are redacted
+	Identities  []*regexp.Regexp
+	Replacement string
+}
+
+// LogRedactor removes personal data from log messages, values and errors
+type LogRedactor struct {
+	rules       LogRedactionRules
+	replacement string
+}
+
+// NewLogRedactor returns nil when no rule is enabled
+func NewLogRedactor(rules LogRedactionRules) *LogRedactor {
+	if !rules.PhoneNumbers && !rules.SIPUsernames && len(rules.Identities) == 0 {
+		return nil
+	}
+	replacement := rules.Replacement
+	if replacement == "" {
+		replacement = DefaultRedactionReplacement
+	}
+	return &LogRedactor{
+		rules:       rules,
+		replacement: replacement,
+	}
+}
+
+func (r *LogRedactor) RedactString(s string) string {
+	if s == "" {
+		return s
+	}
+	for _, re := range r.rules.Identities {
+		s = re.ReplaceAllLiteralString(s, r.replacement)
+	}
+	if r.rules.SIPUsernames {
+		s = sipUserRegexp.ReplaceAllString(s, "${1}"+strings.ReplaceAll(r.replacement, "$", "$$")+"@")
+	}
+	if r.rules.PhoneNumbers {
+		s = phoneNumberRegexp.ReplaceAllLiteralString(s, r.replacement)
+	}
+	return s
+}
+
+// RedactKeysAndValues returns a copy of keysAndValues with string values redacted,
+// values of username keys are replaced entirely when SIP usernames are redacted
+func (r *LogRedactor) RedactKeysAndValues(keysAndValues []any) []any {
+	if len(keysAndValues) == 0 {
+		return keysAndValues
+	}
+	redacted := make([]any, len(keysAndValues))
+	for i, v := range keysAndValues {
+		if i%2 == 0 {
+			redacted[i] = v
+			continue
+		}
+		if r.rules.SIPUsernames {
+			if key, ok := keysAndValues[i-1].(string); ok && strings.Contains(strings.ToLower(key), "username") {
+				redacted[i] = r.replacement
+				continue
+			}
+		}
+		redacted[i] = r.redactValue(v)
+	}
+	return redacted
+}
+
+func (r *LogRedactor) RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if redacted := r.RedactString(msg); redacted != msg {
+		return errors.New(redacted)
+	}
+	return err
+}
+
+func (r *LogRedactor) redactValue(v any) any {
+	switch v := v.(type) {
+	case string:
+		return r.RedactString(v)
+	case []string:
+		redacted := make([]string, len(v))
+		for i, s := range v {
+			redacted[i] = r.RedactString(s)
+		}
+		return redacted
+	case error:
+		return r.RedactError(v)
+	}
+
+	// named string types such as livekit.ParticipantIdentity
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.String {
+		return r.RedactString(rv.String())
+	}
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.String {
+		redacted := make([]string, rv.Len())
+		for i := range redacted {
+			redacted[i] = r.RedactString(rv.Index(i).String())
+		}
+		return redacted
+	}
+	return v
+}
+
+// redactingLogger applies a LogRedactor to everything logged through it and the loggers derived from it
+type redactingLogger struct {
+	logger.Logger
+	// out logs on behalf of the caller, skipping the frame of the wrapper
+	out      logger.Logger
+	redactor *LogRedactor
+}
+
+// NewRedactingLogger wraps l, it returns l when redactor is nil
+func NewRedactingLogger(l logger.Logger, redactor *LogRedactor) logger.Logger {
+	if redactor == nil {
+		return l
+	}
+	return &redactingLogger{
+		Logger:   l,
+		out:      l.WithCallDepth(1),
+		redactor: redactor,
+	}
+}
+
+func (l *redactingLogger) wrap(inner logger.Logger) logger.Logger {
+	return NewRedactingLogger(inner, l.redactor)
+}
+
+func (l *redactingLogger) Debugw(msg string, keysAndValues ...any) {
+	l.out.Debugw(l.redactor.RedactString(msg), l.redactor.RedactKeysAndValues(keysAndValues)...)
+}
+
+func (l *redactingLogger) Infow(msg string, keysAndValues ...any) {
+	l.out.Infow(l.redactor.RedactString(msg), l.redactor.RedactKeysAndValues(keysAndValues)...)
+}
+
+func (l *redactingLogger) Warnw(msg string, err error, keysAndValues ...any) {
+	l.out.Warnw(l.redactor.RedactString(msg), l.redactor.RedactError(err), l.redactor.RedactKeysAndValues(keysAndValues)...)
+}
+
+func (l *redactingLogger) Errorw(msg string, err error, keysAndValues ...any) {
+	l.out.Errorw(l.redactor.RedactString(msg), l.redactor.RedactError(err), l.redactor.RedactKeysAndValues(keysAndValues)...)
+}
+
+func (l *redactingLogger) WithValues(keysAndValues ...any) logger.Logger {
+	return l.wrap(l.Logger.WithValues(l.redactor.RedactKeysAndValues(keysAndValues)...))
+}
+
+// WithUnlikelyValues redacts the values given here, values passed to the returned logger's calls are not redacted
+func (l *redactingLogger) WithUnlikelyValues(keysAndValues ...any) logger.UnlikelyLogger {
+	return l.Logger.WithUnlikelyValues(l.redactor.RedactKeysAndValues(keysAndValues)...)
+}
+
+func (l *redactingLogger) WithName(name string) logger.Logger {
+	return l.wrap(l.Logger.WithName(name))
+}
+
+func (l *redactingLogger) WithComponent(component string) logger.Logger {
+	return l.wrap(l.Logger.WithComponent(component))
+}
+
+func (l *redactingLogger) WithCallDepth(depth int) logger.Logger {
+	return l.wrap(l.Logger.WithCallDepth(depth))
+}
+
+func (l *redactingLogger) WithItemSampler() logger.Logger {
+	return l.wrap(l.Logger.WithItemSampler())
+}
+
+func (l *redactingLogger) WithoutSampler() logger.Logger {
+	return l.wrap(l.Logger.WithoutSampler())
+}
+
+func (l *redactingLogger) WithDeferredValues() (logger.Logger, logger.DeferredFieldResolver) {
+	inner, resolve := l.Logger.WithDeferredValues()
+	return l.wrap(inner), func(args ...any) {
+		resolve(l.redactor.RedactKeysAndValues(args)...)
+	}
+}
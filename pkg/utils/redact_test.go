@@ -0,0 +1,71 @@
+/*
+ * Copyright 2024 LiveKit, Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils_test
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+func TestLogRedactor(t *testing.T) {
+	require.Nil(t, utils.NewLogRedactor(utils.LogRedactionRules{}))
+
+	r := utils.NewLogRedactor(utils.LogRedactionRules{
+		PhoneNumbers: true,
+		SIPUsernames: true,
+		Identities:   []*regexp.Regexp{regexp.MustCompile(`user-[0-9a-f]+`)},
+	})
+	require.NotNil(t, r)
+
+	t.Run("strings", func(t *testing.T) {
+		require.Equal(t, "calling [redacted]", r.RedactString("calling +15105550100"))
+		require.Equal(t, "calling [redacted]", r.RedactString("calling (510) 555-0100"))
+		require.Equal(t, "to sip:[redacted]@example.com", r.RedactString("to sip:alice@example.com"))
+		require.Equal(t, "participant [redacted] joined", r.RedactString("participant user-3f2a joined"))
+		require.Equal(t, "node 10.0.0.1:7880, room RM_abc12345678", r.RedactString("node 10.0.0.1:7880, room RM_abc12345678"))
+	})
+
+	t.Run("keys and values", func(t *testing.T) {
+		redacted := r.RedactKeysAndValues([]any{
+			"participant", livekit.ParticipantIdentity("user-42"),
+			"username", "alice",
+			"numbers", []string{"+15105550100", "none"},
+			"count", 15105550100,
+			"error", errors.New("call to +15105550100 failed"),
+		})
+		require.Equal(t, []any{
+			"participant", "[redacted]",
+			"username", "[redacted]",
+			"numbers", []string{"[redacted]", "none"},
+			"count", 15105550100,
+			"error", errors.New("call to [redacted] failed"),
+		}, redacted)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		err := errors.New("no PII")
+		require.Same(t, err, r.RedactError(err))
+		require.Nil(t, r.RedactError(nil))
+	})
+}
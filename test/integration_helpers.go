@@ -163,7 +163,7 @@ func createSingleNodeServer(configUpdater func(*config.Config)) *service.Livekit
 	}
 	currentNode.SetNodeID(livekit.NodeID(guid.New(nodeID1)))
 
-	s, err := service.InitializeServer(conf, currentNode)
+	s, err := service.InitializeServer(conf, currentNode, nil)
 	if err != nil {
 		panic(fmt.Sprintf("could not create server: %v", err))
 	}
@@ -191,7 +191,7 @@ func createMultiNodeServer(nodeID string, port uint32) *service.LivekitServer {
 	currentNode.SetNodeID(livekit.NodeID(nodeID))
 
 	// redis routing and store
-	s, err := service.InitializeServer(conf, currentNode)
+	s, err := service.InitializeServer(conf, currentNode, nil)
 	if err != nil {
 		panic(fmt.Sprintf("could not create server: %v", err))
 	}
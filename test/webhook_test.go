@@ -139,7 +139,7 @@ func setupServerWithWebhook() (server *service.LivekitServer, testServer *webhoo
 	}
 	currentNode.SetNodeID(livekit.NodeID(guid.New(nodeID1)))
 
-	server, err = service.InitializeServer(conf, currentNode)
+	server, err = service.InitializeServer(conf, currentNode, nil)
 	if err != nil {
 		return
 	}